@@ -15,6 +15,7 @@ import {
   type AgentType,
 } from "../types/agent.js";
 import { type AgentBusyDetails, AgentBusyError } from "../types/errors.js";
+import { isCancellation, throwIfCancelled } from "../utils/cancellation.js";
 
 export abstract class BaseAgent extends EventEmitter implements Agent {
   public readonly id: string;
@@ -149,6 +150,8 @@ export abstract class BaseAgent extends EventEmitter implements Agent {
   }
 
   async process(task: AgentTask): Promise<unknown> {
+    // A task cancelled while it waited never starts
+    throwIfCancelled(task.signal, task.id);
     if (!this.canHandle(task)) {
       const details: AgentBusyDetails = {
         agentId: this.id,
@@ -186,6 +189,10 @@ export abstract class BaseAgent extends EventEmitter implements Agent {
       task.completedAt = Date.now();
 
       this.metrics.tasksProcessed++;
+      if (isCancellation(error)) {
+        this.emit("task:cancelled", { agentId: this.id, task, error });
+        throw error;
+      }
       this.metrics.tasksFailed++;
 
       this.emit("task:failed", { agentId: this.id, task, error });
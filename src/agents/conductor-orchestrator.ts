@@ -26,10 +26,15 @@ import {
   AgentType,
   type ResourceConstraints,
 } from "../types/agent.js";
+import { TaskCancelledError } from "../types/errors.js";
+import { cancellationError, isCancellation, linkedAbortController, throwIfCancelled } from "../utils/cancellation.js";
 import { logger } from "../utils/logger.js";
+import { getLog } from "../utils/structured-log.js";
 import { BaseAgent } from "./base.js";
 import { isEventfulAgent } from "./coordinator.js";
 
+const log = getLog("orchestrator");
+
 interface ConductorConfig {
   resourceConstraints: ResourceConstraints;
   taskQueueLimit: number;
@@ -69,6 +74,24 @@ interface MethodProposal {
   recommended: boolean;
 }
 
+/** Outcome of a delegated subtask, kept so a queued subtask can be collected once it has run */
+export interface DelegatedTaskResult {
+  taskId: string;
+  agent: "dev-agent" | "dora";
+  status: "queued" | "running" | "completed" | "failed" | "cancelled";
+  result?: unknown;
+  error?: string;
+  updatedAt: number;
+}
+
+export interface TaskCancelOutcome {
+  taskId: string;
+  /** False when the task had already finished */
+  cancelled: boolean;
+  /** `cancelling` until a running task reaches its next cancellation check */
+  status: DelegatedTaskResult["status"] | "cancelling";
+}
+
 type ConductorConfigOverrides = Partial<Omit<ConductorConfig, "resourceConstraints">> & {
   resourceConstraints?: Partial<ResourceConstraints>;
 };
@@ -135,7 +158,10 @@ export class ConductorOrchestrator extends BaseAgent implements AgentPool {
   public agents: Map<string, Agent> = new Map();
   private config: ConductorConfig;
   private roundRobinIndex: Map<AgentType, number> = new Map();
-  private pendingTasks: Map<string, AgentTask> = new Map();
+  private pendingTasks: Map<string, { task: AgentTask; targetAgent: "dev-agent" | "dora" }> = new Map();
+  private taskResults: Map<string, DelegatedTaskResult> = new Map();
+  // Tasks and subtasks that can still be cancelled; the subtask controllers hang off their parent's
+  private cancellations: Map<string, AbortController> = new Map();
   private taskComplexityCache: Map<string, TaskComplexityAnalysis> = new Map();
   private methodProposals: Map<string, MethodProposal[]> = new Map();
   private approvalRequired: Set<string> = new Set();
@@ -161,6 +187,8 @@ export class ConductorOrchestrator extends BaseAgent implements AgentPool {
   private performanceMonitorTimer: NodeJS.Timeout | null = null;
   private readonly HEARTBEAT_INTERVAL_MS = 5000;
   private readonly AGENT_STALE_MS = 30000; // 30s without activity => suspect
+  private readonly AGENT_WAIT_MS = 30000; // how long a subtask waits for a busy agent before it is queued
+  private readonly TASK_RESULT_LIMIT = 500;
   private agentLastSeen: Map<string, number> = new Map();
 
   constructor(config: ConductorConfigOverrides = {}) {
@@ -292,10 +320,17 @@ export class ConductorOrchestrator extends BaseAgent implements AgentPool {
     console.log(`[CONDUCTOR] Decomposed into ${subtasks.length} subtasks`);
 
     // Step 4: Delegate subtasks to appropriate agents
+    const controller = linkedAbortController(task.signal);
+    this.cancellations.set(task.id, controller);
     const results = [];
-    for (const subtask of subtasks) {
-      const result = await this.delegateSubtask(task.id, subtask);
-      results.push(result);
+    try {
+      for (const subtask of subtasks) {
+        throwIfCancelled(controller.signal, task.id);
+        const result = await this.delegateSubtask(task.id, subtask, controller.signal);
+        results.push(result);
+      }
+    } finally {
+      this.cancellations.delete(task.id);
     }
 
     // Step 5: Synthesize results
@@ -522,7 +557,7 @@ export class ConductorOrchestrator extends BaseAgent implements AgentPool {
     return subtasks;
   }
 
-  private async delegateSubtask(taskId: string, subtask: SubTask): Promise<unknown> {
+  private async delegateSubtask(taskId: string, subtask: SubTask, signal?: AbortSignal): Promise<unknown> {
     console.log(`[CONDUCTOR] Delegating subtask ${subtask.id} to ${subtask.targetAgent}`);
 
     // Track delegation
@@ -534,25 +569,12 @@ export class ConductorOrchestrator extends BaseAgent implements AgentPool {
       timestamp: Date.now(),
     });
 
-    // Check if target agent is available
-    const agent = this.getAgentByName(subtask.targetAgent);
-    if (!agent) {
-      console.log(`[CONDUCTOR] ${subtask.targetAgent} not available, queuing for later`);
+    const controller = linkedAbortController(signal);
+    this.cancellations.set(subtask.id, controller);
 
-      // Queue for when agent becomes available
-      this.pendingTasks.set(subtask.id, {
-        id: subtask.id,
-        type: subtask.targetAgent === "dora" ? "research" : subtask.payload?.type || "implementation",
-        priority: subtask.priority,
-        payload: subtask.payload || subtask,
-        createdAt: Date.now(),
-      });
-
-      return {
-        status: "queued",
-        message: `Subtask queued for ${subtask.targetAgent}`,
-      };
-    }
+    // A busy agent is waited for, so concurrent tool calls get results rather than a queue receipt
+    const agent =
+      this.getAgentByName(subtask.targetAgent) ?? (await this.waitForAgent(subtask.targetAgent, controller.signal));
 
     // Create agent task - preserve task type from payload if present
     const taskType = subtask.payload?.type || (subtask.targetAgent === "dora" ? "research" : "implementation");
@@ -563,19 +585,123 @@ export class ConductorOrchestrator extends BaseAgent implements AgentPool {
       priority: subtask.priority,
       payload: subtask.payload || subtask,
       createdAt: Date.now(),
+      signal: controller.signal,
     };
 
-    // Process through agent
+    if (!agent && !controller.signal.aborted) {
+      console.log(`[CONDUCTOR] ${subtask.targetAgent} not available, queuing for later`);
+
+      // Queue for when agent becomes available; get_task_result collects the outcome
+      this.pendingTasks.set(subtask.id, { task: agentTask, targetAgent: subtask.targetAgent });
+      this.recordTaskResult(subtask.id, subtask.targetAgent, { status: "queued" });
+      controller.signal.addEventListener("abort", () => this.dropPendingTask(subtask.id), { once: true });
+
+      return {
+        status: "queued",
+        taskId: subtask.id,
+        message: `Subtask queued for ${subtask.targetAgent}; fetch the result with get_task_result`,
+      };
+    }
+
+    if (!agent) {
+      // Cancelled while waiting for the agent
+      this.cancellations.delete(subtask.id);
+      throw cancellationError(controller.signal, subtask.id);
+    }
+
+    return this.runDelegated(agent, agentTask, subtask.targetAgent);
+  }
+
+  private async runDelegated(agent: Agent, agentTask: AgentTask, targetAgent: "dev-agent" | "dora"): Promise<unknown> {
+    this.recordTaskResult(agentTask.id, targetAgent, { status: "running" });
     try {
       const result = await agent.process(agentTask);
-      console.log(`[CONDUCTOR] Subtask ${subtask.id} completed by ${subtask.targetAgent}`);
+      log.debug("subtask completed", { task: agentTask.id, agent: targetAgent });
+      this.recordTaskResult(agentTask.id, targetAgent, { status: "completed", result });
       return result;
     } catch (error) {
-      console.error(`[CONDUCTOR] Subtask ${subtask.id} failed:`, error);
+      if (isCancellation(error)) {
+        log.info("subtask cancelled", { task: agentTask.id, agent: targetAgent });
+        this.recordTaskResult(agentTask.id, targetAgent, { status: "cancelled", error: error.message });
+        throw error;
+      }
+      log.error("subtask failed", { task: agentTask.id, agent: targetAgent, error });
+      this.recordTaskResult(agentTask.id, targetAgent, { status: "failed", error: (error as Error).message });
       throw error;
+    } finally {
+      this.cancellations.delete(agentTask.id);
+      this.drainPendingTasks();
+    }
+  }
+
+  private async waitForAgent(name: "dev-agent" | "dora", signal?: AbortSignal): Promise<Agent | undefined> {
+    // Only worth waiting when an agent of that kind exists; it may just be busy with another task
+    const type = name === "dora" ? AgentType.DORA : AgentType.DEV;
+    const deadline = Date.now() + this.AGENT_WAIT_MS;
+    while (this.getAgentsByType(type).length > 0 && Date.now() < deadline && !signal?.aborted) {
+      await new Promise((resolve) => setTimeout(resolve, 50));
+      const agent = this.getAgentByName(name);
+      if (agent) return agent;
+    }
+    return undefined;
+  }
+
+  /** Run queued subtasks whose agent has become available; results land in taskResults */
+  private drainPendingTasks(): void {
+    for (const [id, { task, targetAgent }] of this.pendingTasks) {
+      const agent = this.getAgentByName(targetAgent);
+      if (!agent) continue;
+      this.pendingTasks.delete(id);
+      this.runDelegated(agent, task, targetAgent).catch(() => {
+        // recorded as failed
+      });
     }
   }
 
+  private recordTaskResult(
+    taskId: string,
+    agent: "dev-agent" | "dora",
+    update: Pick<DelegatedTaskResult, "status" | "result" | "error">,
+  ): void {
+    this.taskResults.delete(taskId);
+    this.taskResults.set(taskId, { taskId, agent, ...update, updatedAt: Date.now() });
+    // Oldest first in insertion order
+    while (this.taskResults.size > this.TASK_RESULT_LIMIT) {
+      const oldest = this.taskResults.keys().next().value;
+      if (oldest === undefined) break;
+      this.taskResults.delete(oldest);
+    }
+  }
+
+  /** Latest known state of a delegated subtask (the `taskId` of a queued response) */
+  getTaskResult(taskId: string): DelegatedTaskResult | undefined {
+    return this.taskResults.get(taskId);
+  }
+
+  /**
+   * Cancel a task or one of its subtasks. A queued subtask is dropped at once; running work stops at
+   * the next batch its agent starts. Undefined when the id is neither running nor recorded.
+   */
+  cancelTask(taskId: string): TaskCancelOutcome | undefined {
+    const controller = this.cancellations.get(taskId);
+    if (controller) {
+      const queued = this.pendingTasks.has(taskId);
+      controller.abort(new TaskCancelledError({ taskId, reason: "cancel_task" }));
+      return { taskId, cancelled: true, status: queued ? "cancelled" : "cancelling" };
+    }
+    const known = this.taskResults.get(taskId);
+    return known ? { taskId, cancelled: false, status: known.status } : undefined;
+  }
+
+  private dropPendingTask(taskId: string): void {
+    const pending = this.pendingTasks.get(taskId);
+    if (!pending) return;
+    this.pendingTasks.delete(taskId);
+    this.cancellations.delete(taskId);
+    const error = pending.task.signal ? cancellationError(pending.task.signal, taskId) : undefined;
+    this.recordTaskResult(taskId, pending.targetAgent, { status: "cancelled", error: error?.message });
+  }
+
   private async synthesizeResults(task: AgentTask, results: unknown[]): Promise<unknown> {
     console.log(`[CONDUCTOR] Synthesizing ${results.length} results for task ${task.id}`);
 
@@ -641,6 +767,7 @@ export class ConductorOrchestrator extends BaseAgent implements AgentPool {
     }
 
     this.emit("agent:registered", agent.id);
+    this.drainPendingTasks();
   }
 
   unregister(agentId: string): void {
@@ -820,12 +947,12 @@ export class ConductorOrchestrator extends BaseAgent implements AgentPool {
   }
 
   private handleTaskCompleted(data: any): void {
-    console.log(`[CONDUCTOR] Task ${data.task.id} completed by agent ${data.agentId}`);
+    log.debug("task completed", { task: data.task.id, agent: data.agentId });
     this.emit("task:routed:completed", data);
   }
 
   private handleTaskFailed(data: any): void {
-    console.error(`[CONDUCTOR] Task ${data.task.id} failed on agent ${data.agentId}:`, data.error);
+    log.error("task failed", { task: data.task.id, agent: data.agentId, error: data.error });
     this.emit("task:routed:failed", data);
   }
 
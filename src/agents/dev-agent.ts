@@ -5,19 +5,74 @@
  */
 
 import { lstatSync, readdirSync } from "node:fs";
-import { extname, join } from "node:path";
+import { readFile } from "node:fs/promises";
+import { extname, isAbsolute, join, relative, resolve } from "node:path";
 import { ConfigLoader, getConfig } from "../config/yaml-config.js";
+import { type CrossFileResolution, resolveCrossFileImports } from "../core/cross-file-resolver.js";
+import { type CSharpAssemblyResolution, resolveCSharpAssemblies } from "../core/csharp-assembly-resolver.js";
+import { type GoPackageResolution, resolveGoPackages } from "../core/go-package-resolver.js";
+import { type GraphqlLinking, linkGraphqlSchema } from "../core/graphql-linker.js";
+import { type HeaderLinking, linkHeaders } from "../core/header-linker.js";
+import { type ImportResolution, resolveImports } from "../core/import-resolver.js";
+import { indexProgress } from "../core/index-progress.js";
 import { type KnowledgeEntry, knowledgeBus } from "../core/knowledge-bus.js";
+import { type OverrideResolution, resolveOverrides } from "../core/override-resolver.js";
+import { buildPackageTree, type PackageTreeBuild } from "../core/package-tree.js";
+import { type PhpNamespaceResolution, resolvePhpNamespaces } from "../core/php-namespace-resolver.js";
+import { linkProtoFiles, type ProtoLinking } from "../core/proto-linker.js";
+import { type RubyConstantResolution, resolveRubyConstants } from "../core/ruby-constant-resolver.js";
+import { linkSqlTables, type SqlLinking } from "../core/sql-linker.js";
+import { linkTests, type TestLinking } from "../core/test-linker.js";
+import { commonRoot, rootOf } from "../core/workspace-roots.js";
+import { indexLanguageOf, isIndexableSourceFile } from "../parsers/content-language.js";
+import { hashFileContent } from "../parsers/incremental-parser.js";
 import { isFileSupported } from "../parsers/language-configs.js";
 import { getGraphStorage } from "../storage/graph-storage-factory.js";
 import { getSQLiteManager } from "../storage/sqlite-manager.js";
 import { type AgentMessage, type AgentTask, AgentType } from "../types/agent.js";
-import type { ParserOptions } from "../types/parser.js";
+import { TaskCancelledError } from "../types/errors.js";
+import type { FileParseErrors, ParserOptions } from "../types/parser.js";
+import { cancellationError } from "../utils/cancellation.js";
+import { type BlameLine, blameFiles, isGitWorkTree } from "../utils/git-blame.js";
+import { headCommit } from "../utils/git-worktree.js";
+import { GitignoreMatcher } from "../utils/gitignore.js";
+import { checkIndexableContent, type SkippedIndexFile, SymlinkGuard } from "../utils/index-file-collection.js";
 import { BaseAgent } from "./base.js";
 import { IndexerAgent } from "./indexer-agent.js";
 // Temporarily disable ParserAgent due to web-tree-sitter ESM issues
 import { ParserAgent } from "./parser-agent.js";
 
+type ContentHashPlan = {
+  /** Changed or new files plus the dependents that must be re-parsed */
+  files: string[];
+  skipped: number;
+  removed: string[];
+  dependents: number;
+};
+
+/**
+ * Phase durations of one index run. Parsing of the next window overlaps writing of the current
+ * one, so parseMs + indexMs can exceed the wall-clock totalMs.
+ */
+type IndexTiming = {
+  totalMs: number;
+  /** File discovery, size/binary checks and change detection */
+  discoverMs: number;
+  parseMs: number;
+  /** Writing entities and per-file relationships */
+  indexMs: number;
+  /** Binding cross-file imports after all files are stored */
+  resolveMs: number;
+};
+
+/** Files per parse/index window in low-memory mode */
+const LOW_MEMORY_WINDOW = 10;
+
+function isWithinDirectory(directory: string, filePath: string): boolean {
+  const rel = relative(resolve(directory), resolve(filePath));
+  return rel !== "" && !rel.startsWith("..") && !isAbsolute(rel);
+}
+
 function getDevAgentConfig() {
   const config = getConfig();
   return {
@@ -154,8 +209,36 @@ export class DevAgent extends BaseAgent {
     // Publish indexing started event (topic, data, source)
     knowledgeBus.publish("indexing:started", result, this.id);
 
+    // Journaled so a run that never reaches its resolution passes is repaired at the next start
+    const storage = await getGraphStorage(getSQLiteManager());
+    const runDirectory = payload.directory ?? (Array.isArray(payload.roots) ? payload.roots[0] : undefined);
+    await storage.startIndexRun({
+      id: task.id,
+      directory: payload.directory ?? null,
+      gitHead: runDirectory ? await headCommit(runDirectory) : null,
+      repository: payload.repository?.url ?? null,
+      ref: payload.repository?.ref ?? null,
+    });
+
     // Perform real indexing using parser and indexer agents
-    const indexingResult = await this.performRealIndexing(payload);
+    let indexingResult: any;
+    try {
+      indexingResult = await this.performRealIndexing(payload, task.signal, task.id);
+    } catch (error) {
+      const cancelled = error instanceof TaskCancelledError;
+      const partial = cancelled ? error.details.partial : undefined;
+      await storage
+        .finishIndexRun(task.id, cancelled ? "cancelled" : "failed", {
+          filesDone: Number(partial?.filesProcessed) || 0,
+          filesTotal: Number(partial?.totalFiles) || 0,
+        })
+        .catch(() => undefined);
+      throw error;
+    }
+    await storage.finishIndexRun(task.id, "completed", {
+      filesDone: indexingResult.filesProcessed,
+      filesTotal: indexingResult.totalFiles,
+    });
 
     return {
       ...result,
@@ -212,21 +295,123 @@ export class DevAgent extends BaseAgent {
     };
   }
 
-  private async performRealIndexing(payload: any): Promise<any> {
+  private async performRealIndexing(payload: any, signal?: AbortSignal, taskId?: string): Promise<any> {
     console.log(`[DevAgent ${this.id}] Performing real indexing...`);
+    const startedAt = Date.now();
 
-    const directory = payload.directory;
+    const roots: string[] = Array.isArray(payload.roots)
+      ? payload.roots.filter((root: unknown) => typeof root === "string" && root).map((root: string) => resolve(root))
+      : [];
+    const directory = payload.directory ?? roots[0];
     const excludePatterns = payload.excludePatterns || [];
+    // Entity ids are derived from paths relative to the indexed directory; with several roots, to
+    // the directory holding all of them, so same-named files in different roots keep distinct ids
+    const rootDir =
+      roots.length > 1
+        ? commonRoot(roots)
+        : typeof directory === "string" && directory
+          ? resolve(directory)
+          : undefined;
 
     const isFullScan = Boolean(payload.fullScan);
     const isIncremental = Boolean(payload.incremental);
+    const useContentHash = payload.changeDetection === "hash" && !isFullScan;
 
-    let files = Array.isArray(payload.files)
+    let files: string[] = Array.isArray(payload.files)
       ? payload.files.filter((file: unknown) => typeof file === "string")
-      : await this.collectFiles(directory, excludePatterns);
+      : roots.length > 1
+        ? await this.collectRootFiles(roots, excludePatterns, payload.respectGitignore !== false)
+        : await this.collectFiles(directory, excludePatterns, payload.respectGitignore !== false);
+    // `languages` narrows the run to those languages: other files are never read, parsed or
+    // embedded (so their grammars never load), and what an earlier run stored for them stays
+    const requestedLanguages: string[] = Array.isArray(payload.languages) ? payload.languages : [];
+    const wantedLanguages = requestedLanguages.length > 0 ? new Set(requestedLanguages) : null;
+    const languageDetection = ConfigLoader.getInstance().isContentLanguageDetectionEnabled();
+    const languages = { requested: requestedLanguages, included: [] as string[], filesSkipped: 0 };
+    if (wantedLanguages) {
+      const included = new Set<string>();
+      files = files.filter((file) => {
+        const language = indexLanguageOf(file, wantedLanguages, languageDetection);
+        if (language) included.add(language);
+        else languages.filesSkipped += 1;
+        return language !== null;
+      });
+      languages.included = [...included].sort();
+    }
+    // Each file is tagged with the innermost root holding it. A single-directory run (update_index,
+    // the watcher) keeps the roots an earlier multi-root index recorded instead of re-tagging them.
+    const tagRoots = roots.length > 1 ? roots : rootDir ? [rootDir, ...(await this.recordedRoots())] : [];
+    const rootFor = (file: string) => rootOf(resolve(file), tagRoots) ?? rootDir;
     const totalDiscovered = files.length;
+    let plan: ContentHashPlan | null = null;
+    let entitiesDeleted = 0;
+    let filesRemoved = 0;
+
+    // Oversized, binary and generated files are dropped before anything reads them in full
+    const configLoader = ConfigLoader.getInstance();
+    const maxFileSizeBytes = configLoader.getIndexMaxFileSizeBytes();
+    const skipGenerated = configLoader.isGeneratedFileSkippingEnabled();
+    const skipped: SkippedIndexFile[] = [];
+    files = files.filter((file: string) => {
+      const skip = checkIndexableContent(file, maxFileSizeBytes, skipGenerated);
+      if (!skip) return true;
+      skipped.push(skip);
+      console.warn(
+        `[DevAgent ${this.id}] Skipping ${file}: ${skip.reason}` +
+          (skip.generated
+            ? ` (${skip.generated.detectedBy}: ${skip.generated.evidence})`
+            : skip.sizeBytes !== undefined && skip.reason === "too_large"
+              ? ` (${skip.sizeBytes} bytes, limit ${maxFileSizeBytes})`
+              : ""),
+      );
+      return false;
+    });
+    if (skipped.length > 0) {
+      // A file that grew past the limit, turned binary or became generated must not keep entities from its last index
+      const storage = await getGraphStorage(getSQLiteManager());
+      for (const { filePath } of skipped) {
+        if (!(await storage.getFileInfo(filePath))) continue;
+        const deleted = await storage.deleteFileData(filePath);
+        entitiesDeleted += deleted.entitiesRemoved;
+      }
+    }
+
+    if (!Array.isArray(payload.files) && !useContentHash) {
+      // A directory scan is the whole of that directory: files indexed earlier that it no longer finds
+      // (deleted, or excluded now) lose their entities. Hash plans find removed files themselves.
+      const storage = await getGraphStorage(getSQLiteManager());
+      const discovered = new Set(files);
+      const scanned = roots.length > 1 ? roots : [directory];
+      const stale = (await storage.listIndexedFiles())
+        .map((info) => info.path)
+        .filter((path) => !path.startsWith("external://") && !discovered.has(path))
+        .filter((path) => scanned.some((dir) => isWithinDirectory(dir, path)))
+        .filter((path) => !wantedLanguages || indexLanguageOf(path, wantedLanguages, languageDetection) !== null);
+      for (const path of stale) {
+        const deleted = await storage.deleteFileData(path);
+        entitiesDeleted += deleted.entitiesRemoved;
+      }
+      if (stale.length > 0) {
+        filesRemoved = stale.length;
+        knowledgeBus.publish("index:files_removed", { files: stale }, this.id);
+      }
+    }
 
-    if (isIncremental && !isFullScan) {
+    if (useContentHash) {
+      const storage = await getGraphStorage(getSQLiteManager());
+      plan = await this.planContentHashUpdate(directory, files);
+
+      for (const removed of plan.removed) {
+        const deleted = await storage.deleteFileData(removed);
+        entitiesDeleted += deleted.entitiesRemoved;
+      }
+      if (plan.removed.length > 0) {
+        knowledgeBus.publish("index:files_removed", { files: plan.removed }, this.id);
+      }
+
+      files = plan.files;
+      filesRemoved = plan.removed.length;
+    } else if (isIncremental && !isFullScan) {
       try {
         const storage = await getGraphStorage(getSQLiteManager());
         const filtered: string[] = [];
@@ -251,41 +436,130 @@ export class DevAgent extends BaseAgent {
     }
 
     console.log(`[DevAgent ${this.id}] Found ${files.length}/${totalDiscovered} files to process`);
+    // Tool calls key progress by their own task id; conductor subtasks carry it in the payload
+    const progressId = payload.progressId ?? taskId;
+    if (progressId) indexProgress.report(progressId, { phase: "parsing", totalFiles: files.length, filesProcessed: 0 });
+
+    // Sorted input keeps the write order, and anything derived from it, independent of discovery order
+    files = [...files].sort();
+    const timing: IndexTiming = {
+      totalMs: 0,
+      discoverMs: Date.now() - startedAt,
+      parseMs: 0,
+      indexMs: 0,
+      resolveMs: 0,
+    };
 
-    const configLoader = ConfigLoader.getInstance();
     const isDebugMode = process.env.MCP_DEBUG_MODE === "1";
+    // Low-memory runs hold one small window at a time and keep no parse cache
+    const lowMemory = configLoader.isLowMemoryIndexing();
     const configuredBatchSize = this.indexBatchSize ?? configLoader.getDevIndexBatchSize();
-    const effectiveBatchSize = isDebugMode ? Math.min(configuredBatchSize, 5) : configuredBatchSize;
+    const effectiveBatchSize = isDebugMode
+      ? Math.min(configuredBatchSize, 5)
+      : lowMemory
+        ? Math.min(configuredBatchSize, LOW_MEMORY_WINDOW)
+        : configuredBatchSize;
+    const parseTimeoutMs = configLoader.getParseTimeoutMs();
     const parseOptions: ParserOptions = isDebugMode
       ? {
           batchSize: Math.max(1, Math.min(3, effectiveBatchSize)),
           useCache: false,
+          timeoutMs: parseTimeoutMs,
         }
-      : {};
+      : lowMemory
+        ? { useCache: false, timeoutMs: parseTimeoutMs }
+        : { timeoutMs: parseTimeoutMs };
+    // Ownership from git blame is opt-in: one blame per file costs far more than parsing it
+    const gitBlame: { enabled: boolean; filesBlamed: number; reason?: string } = {
+      enabled: Boolean(payload.gitBlame ?? configLoader.isGitBlameEnabled()),
+      filesBlamed: 0,
+    };
+    const detectByContent = configLoader.isContentLanguageDetectionEnabled();
+    // `embed: false` re-parses into the graph and leaves the vectors to a later reembed
+    const embed = payload.embed !== false;
+    if (gitBlame.enabled) {
+      const blameRoots = roots.length > 0 ? roots : rootDir ? [rootDir] : [];
+      const inRepo = await Promise.all(blameRoots.map((root) => isGitWorkTree(root)));
+      if (!inRepo.some(Boolean)) {
+        gitBlame.enabled = false;
+        gitBlame.reason = "not a git work tree";
+        console.warn(`[DevAgent ${this.id}] git blame requested but ${directory} is not in a git work tree; skipping`);
+      }
+    }
     let totalEntities = 0;
     let totalRelationships = 0;
+    let relationshipsDeleted = 0;
+    // How the stored entities changed; a re-index of unchanged files creates and updates none
+    const entityChanges = { created: 0, updated: 0, unchanged: 0 };
     let filesProcessed = 0;
+    const indexedFiles: string[] = [];
+    // Files indexed from their parseable parts only
+    const parseErrors: FileParseErrors[] = [];
+
+    // Checked between windows: a file's entities are either fully stored or not touched
+    const stopIfCancelled = () => {
+      if (!signal?.aborted) return;
+      const partial = {
+        filesProcessed,
+        entitiesExtracted: totalEntities,
+        relationshipsCreated: totalRelationships,
+        entitiesDeleted,
+        totalFiles: files.length,
+      };
+      knowledgeBus.publish("indexing:cancelled", { directory, ...partial }, this.id);
+      const reason = cancellationError(signal, taskId);
+      // Everything stored so far stays indexed, so the caller gets the counts along with the error
+      throw reason instanceof TaskCancelledError ? new TaskCancelledError({ ...reason.details, partial }) : reason;
+    };
+
+    const parseWindow = async (start: number): Promise<{ results: any[]; error?: unknown }> => {
+      const parseStarted = Date.now();
+      const parseTask: AgentTask = {
+        id: `parse-${Date.now()}-${start}`,
+        type: "parse:batch",
+        priority: 8,
+        payload: { files: files.slice(start, start + effectiveBatchSize), options: parseOptions },
+        createdAt: Date.now(),
+        signal,
+      };
+      try {
+        const results = (await this.parserAgent!.process(parseTask)) as any[]; // ParseResult[]
+        return { results: results || [] };
+      } catch (error) {
+        return { results: [], error };
+      } finally {
+        timing.parseMs += Date.now() - parseStarted;
+      }
+    };
+    let nextWindow = this.parserAgent && files.length > 0 ? parseWindow(0) : null;
 
     for (let i = 0; i < files.length; i += effectiveBatchSize) {
+      stopIfCancelled();
       const batch = files.slice(i, Math.min(i + effectiveBatchSize, files.length));
 
       try {
         if (this.parserAgent) {
-          const parseTask: AgentTask = {
-            id: `parse-${Date.now()}-${i}`,
-            type: "parse:batch",
-            priority: 8,
-            payload: { files: batch, options: parseOptions },
-            createdAt: Date.now(),
-          };
-
-          const results = (await this.parserAgent.process(parseTask)) as any[]; // ParseResult[]
+          const parsed = await nextWindow!;
+          // The next window parses while this one is written; at most two windows are held at once.
+          // Low-memory runs start it only after this window is stored (see the end of the loop).
+          const hasNext = i + effectiveBatchSize < files.length;
+          nextWindow = hasNext && !lowMemory ? parseWindow(i + effectiveBatchSize) : null;
+          if (parsed.error) throw parsed.error;
+          const results = parsed.results;
 
           const byFile = new Map<string, { entities: any[]; relationships: any[]; fileHash?: string }>();
+          const timedOut: string[] = [];
 
           for (const res of results || []) {
             const fp = res?.filePath;
             if (!fp) continue;
+            if (res.timedOut) {
+              // One pathological file must not stall the run: it is skipped like an oversized one
+              skipped.push({ filePath: fp, reason: "parse_timeout", timeoutMs: parseTimeoutMs });
+              console.warn(`[DevAgent ${this.id}] Skipping ${fp}: parse_timeout (limit ${parseTimeoutMs}ms)`);
+              timedOut.push(fp);
+              continue;
+            }
             let slot = byFile.get(fp);
             if (!slot) {
               slot = { entities: [], relationships: [], fileHash: res?.contentHash };
@@ -293,9 +567,14 @@ export class DevAgent extends BaseAgent {
             } else if (!slot.fileHash && res?.contentHash) {
               slot.fileHash = res.contentHash;
             }
+            if (Array.isArray(res.syntaxErrors) && res.syntaxErrors.length > 0) {
+              parseErrors.push({ filePath: fp, parseErrors: res.syntaxErrors.length, ranges: res.syntaxErrors });
+            }
 
             if (Array.isArray(res.entities)) {
-              slot.entities.push(...res.entities);
+              // Storage guesses the language from the extension, which a file detected by content lacks
+              const language = isFileSupported(fp) ? undefined : res.language;
+              slot.entities.push(...(language ? res.entities.map((e: any) => ({ language, ...e })) : res.entities));
             }
 
             if (Array.isArray(res.relationships)) {
@@ -306,14 +585,21 @@ export class DevAgent extends BaseAgent {
                     to: r.to,
                     type: r.type,
                     targetFile: fp,
+                    metadata: r.metadata,
                   });
                 }
               }
             }
           }
 
-          const replaceFile = isIncremental && !isFullScan;
-          for (const [file, group] of byFile.entries()) {
+          const indexStarted = Date.now();
+          const blames: Map<string, Array<BlameLine | null>> = gitBlame.enabled
+            ? await blameFiles(batch.filter((file) => byFile.has(file)))
+            : new Map();
+          gitBlame.filesBlamed += blames.size;
+          for (const file of batch) {
+            const group = byFile.get(file);
+            if (!group) continue;
             const indexTask: AgentTask = {
               id: `index-entities-${Date.now()}-${i}-${file}`,
               type: "index:entities",
@@ -323,7 +609,11 @@ export class DevAgent extends BaseAgent {
                 relationships: group.relationships,
                 filePath: file,
                 fileHash: group.fileHash,
-                replaceFile,
+                replaceFile: true,
+                rootDir,
+                root: rootFor(file),
+                blame: blames.get(file),
+                embed,
               },
               createdAt: Date.now(),
             };
@@ -334,12 +624,39 @@ export class DevAgent extends BaseAgent {
               if (indexed) {
                 totalEntities += indexed.entitiesIndexed || 0;
                 totalRelationships += indexed.relationshipsCreated || 0;
+                entitiesDeleted += indexed.entitiesRemoved || 0;
+                relationshipsDeleted += indexed.relationshipsRemoved || 0;
+                entityChanges.created += indexed.entitiesCreated || 0;
+                entityChanges.updated += indexed.entitiesUpdated || 0;
+                entityChanges.unchanged += indexed.entitiesUnchanged || 0;
                 filesProcessed += 1;
+                indexedFiles.push(file);
               }
             } catch (err) {
               console.error(`[DevAgent ${this.id}] Indexing failed for file ${file}:`, err);
             }
           }
+          timing.indexMs += Date.now() - indexStarted;
+
+          if (timedOut.length > 0) {
+            // Entities from an earlier parse would describe content that no longer parses
+            const storage = await getGraphStorage(getSQLiteManager());
+            for (const file of timedOut) {
+              if (!(await storage.getFileInfo(file))) continue;
+              const deleted = await storage.deleteFileData(file);
+              entitiesDeleted += deleted.entitiesRemoved;
+            }
+          }
+
+          if (useContentHash) {
+            // A changed file that now yields nothing must not keep its previous entities
+            const storage = await getGraphStorage(getSQLiteManager());
+            for (const file of batch) {
+              if (byFile.has(file)) continue;
+              const deleted = await storage.deleteFileData(file);
+              entitiesDeleted += deleted.entitiesRemoved;
+            }
+          }
 
           if (isDebugMode) {
             global.gc?.();
@@ -349,7 +666,7 @@ export class DevAgent extends BaseAgent {
           const relationships: any[] = [];
 
           for (const file of batch) {
-            if (!isFileSupported(file)) continue;
+            if (!isIndexableSourceFile(file, detectByContent)) continue;
 
             const fileName = file.split("/").pop() || "unknown";
             const fileNameNoExt = fileName.replace(/\.[^/.]+$/, "");
@@ -456,14 +773,21 @@ export class DevAgent extends BaseAgent {
             slot.relationships.push({ from: r.from, to: r.to, type: r.type, targetFile: r.filePath });
           }
 
-          const replaceFile = isIncremental && !isFullScan;
           for (const [file, group] of byFile.entries()) {
             if (!group.entities.length) continue;
             const indexTask: AgentTask = {
               id: `index-entities-${Date.now()}-${i}-${file}`,
               type: "index:entities",
               priority: 7,
-              payload: { entities: group.entities, relationships: group.relationships, filePath: file, replaceFile },
+              payload: {
+                entities: group.entities,
+                relationships: group.relationships,
+                filePath: file,
+                replaceFile: true,
+                rootDir,
+                root: rootFor(file),
+                embed,
+              },
               createdAt: Date.now(),
             };
             try {
@@ -472,6 +796,11 @@ export class DevAgent extends BaseAgent {
               if (indexed) {
                 totalEntities += indexed.entitiesIndexed || 0;
                 totalRelationships += indexed.relationshipsCreated || 0;
+                entitiesDeleted += indexed.entitiesRemoved || 0;
+                relationshipsDeleted += indexed.relationshipsRemoved || 0;
+                entityChanges.created += indexed.entitiesCreated || 0;
+                entityChanges.updated += indexed.entitiesUpdated || 0;
+                entityChanges.unchanged += indexed.entitiesUnchanged || 0;
                 filesProcessed += 1;
               }
             } catch (err) {
@@ -480,24 +809,289 @@ export class DevAgent extends BaseAgent {
           }
         }
       } catch (error) {
+        stopIfCancelled();
         console.error(`[DevAgent ${this.id}] Error processing batch ${i}:`, error);
       }
+      if (this.parserAgent && lowMemory && i + effectiveBatchSize < files.length) {
+        nextWindow = parseWindow(i + effectiveBatchSize);
+      }
+
+      if (progressId) indexProgress.report(progressId, { filesProcessed: i + batch.length });
 
       if ((i + effectiveBatchSize) % 500 === 0 || i + effectiveBatchSize >= files.length) {
         console.log(`[DevAgent ${this.id}] Progress: ${filesProcessed}/${files.length} files processed`);
       }
     }
 
-    return {
+    stopIfCancelled();
+
+    // Cross-file edges are bound only once every file of the run is stored, so the outcome does not
+    // depend on which worker finished first. Batched sessions defer this to their last batch.
+    let crossFile: CrossFileResolution | null = null;
+    let csharp: CSharpAssemblyResolution | null = null;
+    let ruby: RubyConstantResolution | null = null;
+    let php: PhpNamespaceResolution | null = null;
+    let go: GoPackageResolution | null = null;
+    let overrides: OverrideResolution | null = null;
+    let headers: HeaderLinking | null = null;
+    let proto: ProtoLinking | null = null;
+    let sql: SqlLinking | null = null;
+    let graphql: GraphqlLinking | null = null;
+    let tests: TestLinking | null = null;
+    let packageTree: PackageTreeBuild | null = null;
+    let imports: ImportResolution | null = null;
+    const resolveAll = payload.resolveCrossFile === "all";
+    if (this.parserAgent && payload.resolveCrossFile !== false && (indexedFiles.length > 0 || resolveAll)) {
+      if (progressId) indexProgress.report(progressId, { phase: "resolving" });
+      const resolveStarted = Date.now();
+      try {
+        const storage = await getGraphStorage(getSQLiteManager());
+        crossFile = await resolveCrossFileImports(storage, resolveAll ? undefined : indexedFiles, {
+          symbolCacheSize: configLoader.getSymbolCacheSize(),
+          // Roots of this run plus those indexed earlier, so imports may reach either
+          roots: Array.from(new Set([...tagRoots, ...(await storage.listIndexRoots())])),
+        });
+      } catch (error) {
+        console.warn(
+          `[DevAgent ${this.id}] Cross-file resolution failed:`,
+          error instanceof Error ? error.message : String(error),
+        );
+      }
+      try {
+        const storage = await getGraphStorage(getSQLiteManager());
+        csharp = await resolveCSharpAssemblies(storage, resolveAll ? undefined : indexedFiles);
+      } catch (error) {
+        console.warn(
+          `[DevAgent ${this.id}] C# assembly resolution failed:`,
+          error instanceof Error ? error.message : String(error),
+        );
+      }
+      try {
+        const storage = await getGraphStorage(getSQLiteManager());
+        ruby = await resolveRubyConstants(storage, resolveAll ? undefined : indexedFiles);
+      } catch (error) {
+        console.warn(
+          `[DevAgent ${this.id}] Ruby constant resolution failed:`,
+          error instanceof Error ? error.message : String(error),
+        );
+      }
+      try {
+        const storage = await getGraphStorage(getSQLiteManager());
+        php = await resolvePhpNamespaces(storage, resolveAll ? undefined : indexedFiles);
+      } catch (error) {
+        console.warn(
+          `[DevAgent ${this.id}] PHP namespace resolution failed:`,
+          error instanceof Error ? error.message : String(error),
+        );
+      }
+      try {
+        const storage = await getGraphStorage(getSQLiteManager());
+        go = await resolveGoPackages(storage, resolveAll ? undefined : indexedFiles);
+      } catch (error) {
+        console.warn(
+          `[DevAgent ${this.id}] Go package resolution failed:`,
+          error instanceof Error ? error.message : String(error),
+        );
+      }
+      // Overrides need the inheritance edges the resolvers just moved onto real types
+      try {
+        const storage = await getGraphStorage(getSQLiteManager());
+        overrides = await resolveOverrides(storage, resolveAll ? undefined : indexedFiles);
+      } catch (error) {
+        console.warn(
+          `[DevAgent ${this.id}] Override resolution failed:`,
+          error instanceof Error ? error.message : String(error),
+        );
+      }
+      try {
+        const storage = await getGraphStorage(getSQLiteManager());
+        headers = await linkHeaders(storage, resolveAll ? undefined : indexedFiles);
+      } catch (error) {
+        console.warn(
+          `[DevAgent ${this.id}] Header linking failed:`,
+          error instanceof Error ? error.message : String(error),
+        );
+      }
+      try {
+        const storage = await getGraphStorage(getSQLiteManager());
+        proto = await linkProtoFiles(storage, resolveAll ? undefined : indexedFiles);
+      } catch (error) {
+        console.warn(
+          `[DevAgent ${this.id}] Proto linking failed:`,
+          error instanceof Error ? error.message : String(error),
+        );
+      }
+      try {
+        const storage = await getGraphStorage(getSQLiteManager());
+        sql = await linkSqlTables(storage, resolveAll ? undefined : indexedFiles);
+      } catch (error) {
+        console.warn(
+          `[DevAgent ${this.id}] SQL linking failed:`,
+          error instanceof Error ? error.message : String(error),
+        );
+      }
+      try {
+        const storage = await getGraphStorage(getSQLiteManager());
+        graphql = await linkGraphqlSchema(storage, resolveAll ? undefined : indexedFiles);
+      } catch (error) {
+        console.warn(
+          `[DevAgent ${this.id}] GraphQL linking failed:`,
+          error instanceof Error ? error.message : String(error),
+        );
+      }
+      // Tests reach production code through the calls every pass above has bound
+      try {
+        const storage = await getGraphStorage(getSQLiteManager());
+        tests = await linkTests(storage, resolveAll ? undefined : indexedFiles);
+      } catch (error) {
+        console.warn(
+          `[DevAgent ${this.id}] Test linking failed:`,
+          error instanceof Error ? error.message : String(error),
+        );
+      }
+      // Containers hold declarations by id, so they are rebuilt after every pass that (re)stores them
+      try {
+        const storage = await getGraphStorage(getSQLiteManager());
+        packageTree = await buildPackageTree(storage, {
+          roots: Array.from(new Set([...tagRoots, ...(await storage.listIndexRoots())])),
+        });
+      } catch (error) {
+        console.warn(
+          `[DevAgent ${this.id}] Package tree build failed:`,
+          error instanceof Error ? error.message : String(error),
+        );
+      }
+      // Go imports are bound to package containers, so this follows the tree build
+      try {
+        const storage = await getGraphStorage(getSQLiteManager());
+        imports = await resolveImports(storage, resolveAll ? undefined : indexedFiles, {
+          roots: Array.from(new Set([...tagRoots, ...(await storage.listIndexRoots())])),
+        });
+      } catch (error) {
+        console.warn(
+          `[DevAgent ${this.id}] Import resolution failed:`,
+          error instanceof Error ? error.message : String(error),
+        );
+      }
+      timing.resolveMs = Date.now() - resolveStarted;
+    }
+    timing.totalMs = Date.now() - startedAt;
+    console.log(`[DevAgent ${this.id}] Indexing timing (ms):`, timing);
+
+    const result = {
       filesProcessed,
       entitiesExtracted: totalEntities,
       relationshipsCreated: totalRelationships,
+      entitiesCreated: entityChanges.created,
+      entitiesUpdated: entityChanges.updated,
+      entitiesUnchanged: entityChanges.unchanged,
+      entitiesDeleted,
+      relationshipsDeleted,
+      filesRemoved,
       totalFiles: files.length,
+      skipped,
+      parseErrors,
+      crossFile,
+      csharp,
+      ruby,
+      php,
+      go,
+      overrides,
+      headers,
+      proto,
+      sql,
+      graphql,
+      tests,
+      packageTree,
+      imports,
+      gitBlame,
+      languages: wantedLanguages ? languages : undefined,
+      timing,
+    };
+    if (!plan) return result;
+
+    return {
+      ...result,
+      filesReparsed: files.length,
+      filesSkipped: plan.skipped,
+      dependentFiles: plan.dependents,
     };
   }
 
-  private async collectFiles(directory: string, excludePatterns: string[]): Promise<string[]> {
+  /**
+   * Compare each file's content hash with the one stored at its last index. Unchanged files are
+   * skipped, indexed files that no longer exist are reported as removed, and files holding edges
+   * into changed or removed files are re-parsed as well so those relationships get rebuilt.
+   */
+  private async planContentHashUpdate(directory: string, discovered: string[]): Promise<ContentHashPlan> {
+    const storage = await getGraphStorage(getSQLiteManager());
+    const changed: string[] = [];
+    let skipped = 0;
+
+    for (const file of discovered) {
+      let content: string;
+      try {
+        content = await readFile(file, "utf-8");
+      } catch {
+        continue;
+      }
+      const info = await storage.getFileInfo(file);
+      if (info?.hash === hashFileContent(content)) {
+        skipped += 1;
+        continue;
+      }
+      changed.push(file);
+    }
+
+    const removed = (await storage.listIndexedFiles())
+      .map((info) => info.path)
+      .filter((path) => !path.startsWith("external://") && isWithinDirectory(directory, path))
+      .filter((path) => !lstatSync(path, { throwIfNoEntry: false })?.isFile());
+
+    const queued = new Set(changed);
+    const dependents = (await storage.findDependentFiles([...changed, ...removed])).filter(
+      (path) => !queued.has(path) && lstatSync(path, { throwIfNoEntry: false })?.isFile(),
+    );
+
+    console.log(
+      `[DevAgent ${this.id}] Content hash check: ${changed.length} changed, ${skipped} unchanged, ` +
+        `${removed.length} removed, ${dependents.length} dependent files`,
+    );
+
+    return { files: [...changed, ...dependents], skipped, removed, dependents: dependents.length };
+  }
+
+  /** Files of every root, each listed once even where roots nest */
+  private async collectRootFiles(
+    roots: string[],
+    excludePatterns: string[],
+    respectGitignore: boolean,
+  ): Promise<string[]> {
+    const files = new Set<string>();
+    for (const root of roots) {
+      for (const file of await this.collectFiles(root, excludePatterns, respectGitignore)) files.add(file);
+    }
+    return Array.from(files);
+  }
+
+  /** Roots recorded in the index by earlier runs; none when the graph cannot be read */
+  private async recordedRoots(): Promise<string[]> {
+    try {
+      return await (await getGraphStorage(getSQLiteManager())).listIndexRoots();
+    } catch {
+      return [];
+    }
+  }
+
+  private async collectFiles(
+    directory: string,
+    excludePatterns: string[],
+    respectGitignore = true,
+  ): Promise<string[]> {
     const files: string[] = [];
+    const gitignore = respectGitignore ? new GitignoreMatcher(directory) : null;
+    const symlinks = new SymlinkGuard(directory, ConfigLoader.getInstance().getSymlinkPolicy());
+    const detectByContent = ConfigLoader.getInstance().isContentLanguageDetectionEnabled();
     const defaultExcludedDirNames = new Set([
       "node_modules",
       "tmp",
@@ -529,10 +1123,12 @@ export class DevAgent extends BaseAgent {
       scannedFiles: 0,
       includedFiles: 0,
       excludedByPatterns: 0,
+      excludedByGitignore: 0,
       excludedByPruneDir: 0,
       excludedHiddenDir: 0,
-      skippedSymlinks: 0,
+      symlinks: symlinks.stats,
       unsupportedExtensions: 0,
+      detectedByContent: 0,
       unreadableDirs: 0,
     };
 
@@ -548,7 +1144,12 @@ export class DevAgent extends BaseAgent {
       return false;
     }
 
-    function walkDir(dir: string) {
+    // Link targets are walked after the rest of the tree, so files keep their real paths where they have one
+    const linkedDirs: Array<[string, string]> = [];
+    const linkedFiles: Array<[string, string]> = [];
+
+    function walkDir(dir: string, realDir: string) {
+      if (!symlinks.enterDirectory(realDir)) return;
       try {
         const items = readdirSync(dir);
         for (const item of items) {
@@ -564,30 +1165,43 @@ export class DevAgent extends BaseAgent {
           if (!lstat) {
             continue;
           }
-          if (lstat.isSymbolicLink()) {
-            stats.skippedSymlinks += 1;
+          const link = lstat.isSymbolicLink() ? symlinks.resolveLink(fullPath) : null;
+          if (lstat.isSymbolicLink() && !link) continue;
+          const isDirectory = link ? link.isDirectory : lstat.isDirectory();
+          const realPath = link ? link.realPath : join(realDir, item);
+          if (gitignore?.isIgnored(fullPath, isDirectory)) {
+            stats.excludedByGitignore += 1;
             continue;
           }
 
-          if (lstat.isDirectory()) {
+          if (isDirectory) {
             const lowerItem = item.toLowerCase();
             if (defaultExcludedDirNames.has(lowerItem)) {
               stats.excludedByPruneDir += 1;
               continue;
             }
-            if (!item.startsWith(".")) {
-              walkDir(fullPath);
-            } else {
+            if (item.startsWith(".")) {
               stats.excludedHiddenDir += 1;
+            } else if (link) {
+              linkedDirs.push([fullPath, realPath]);
+            } else {
+              walkDir(fullPath, realPath);
             }
-          } else if (lstat.isFile()) {
+          } else if (link || lstat.isFile()) {
             stats.scannedFiles += 1;
             if (!isFileSupported(fullPath)) {
-              stats.unsupportedExtensions += 1;
-              continue;
+              if (!isIndexableSourceFile(fullPath, detectByContent)) {
+                stats.unsupportedExtensions += 1;
+                continue;
+              }
+              stats.detectedByContent += 1;
+            }
+            if (link) {
+              linkedFiles.push([fullPath, realPath]);
+            } else if (symlinks.claimFile(realPath)) {
+              files.push(fullPath);
+              stats.includedFiles += 1;
             }
-            files.push(fullPath);
-            stats.includedFiles += 1;
           }
         }
       } catch (error) {
@@ -596,7 +1210,15 @@ export class DevAgent extends BaseAgent {
       }
     }
 
-    walkDir(directory);
+    walkDir(directory, symlinks.rootRealPath);
+    for (let next = linkedDirs.shift(); next; next = linkedDirs.shift()) {
+      walkDir(next[0], next[1]);
+    }
+    for (const [fullPath, realPath] of linkedFiles) {
+      if (!symlinks.claimFile(realPath, true)) continue;
+      files.push(fullPath);
+      stats.includedFiles += 1;
+    }
     console.log(`[DevAgent ${this.id}] File discovery stats:`, stats);
     return files;
   }
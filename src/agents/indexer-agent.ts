@@ -14,55 +14,161 @@
  */
 
 import { createHash } from "node:crypto";
+import { readFile, stat } from "node:fs/promises";
 import { nanoid } from "nanoid";
 import { getConfig } from "../config/yaml-config.js";
+import { type EdgeDerivation, edgeConfidence } from "../core/edge-confidence.js";
+import type { GoModuleCache } from "../core/go-modules.js";
 import { type KnowledgeEntry, knowledgeBus } from "../core/knowledge-bus.js";
+import { fullyQualifiedName } from "../core/qualified-names.js";
+import { sessionMetrics } from "../core/session-metrics.js";
+import { isTestFile } from "../parsers/test-extractor.js";
 import { BatchOperations } from "../storage/batch-operations.js";
 import { getCacheManager, QueryCacheManager } from "../storage/cache-manager.js";
+import { assignStableEntityIds, externalPlaceholderId, stableRelationshipId } from "../storage/entity-id.js";
 import type { GraphStorageImpl } from "../storage/graph-storage.js";
 import { getGraphStorage } from "../storage/graph-storage-factory.js";
 import type { SQLiteManager } from "../storage/sqlite-manager.js";
 import { type AgentMessage, type AgentTask, AgentType } from "../types/agent.js";
-import type { EntityRelationship, ParsedEntity, ParseResult } from "../types/parser.js";
+import type { AccessKind, CallArgument, EntityRelationship, ParsedEntity, ParseResult } from "../types/parser.js";
 import type {
+  AccessSite,
   BatchResult,
+  CallSite,
   Entity,
   EntityChange,
   FileInfo,
   GraphQuery,
   GraphQueryResult,
   Relationship,
+  UpsertResult,
 } from "../types/storage.js";
 import { EntityType, parsedEntityToEntity, RelationType } from "../types/storage.js";
+import { type BlameLine, lastModifiedIn } from "../utils/git-blame.js";
 import { BaseAgent } from "./base.js";
 
 // =============================================================================
 // 2. CONSTANTS AND CONFIGURATION
 // =============================================================================
+const LOW_MEMORY_FLUSH_SIZE = 200;
+
 function getIndexerConfig() {
   const config = getConfig();
   return {
     maxConcurrency: config.indexer?.maxConcurrency ?? 2,
     memoryLimit: config.indexer?.memoryLimit ?? 512,
     priority: config.indexer?.priority ?? 7,
-    batchSize: config.indexer?.batchSize ?? 1000,
+    // Rows per SQLite transaction; low-memory mode flushes in smaller chunks
+    batchSize: config.indexer?.lowMemory
+      ? Math.min(config.indexer.batchSize ?? 1000, LOW_MEMORY_FLUSH_SIZE)
+      : (config.indexer?.batchSize ?? 1000),
     cacheSize: config.indexer?.cacheSize ?? 50 * 1024 * 1024,
     cacheTTL: config.indexer?.cacheTTL ?? 5 * 60 * 1000,
   };
 }
 
+/** Counts for rows of a test file stored apart from the main graph, which changes nothing in it */
+function storedApart(processed: number): UpsertResult {
+  return { processed, failed: 0, errors: [], timeMs: 0, created: 0, updated: 0, unchanged: 0 };
+}
+
 // =============================================================================
 // 3. STABLE ID HELPERS
 // =============================================================================
-const ID_LENGTH = 12;
-function stableEntityId(base: Omit<Entity, "id" | "createdAt" | "updatedAt">): string {
-  const s = base.location?.start?.index ?? -1;
-  const eIdx = base.location?.end?.index ?? -1;
-  const key = `${base.filePath}|${base.type}|${base.name}|${s}-${eIdx}`;
-  return createHash("sha256").update(key).digest("base64url").slice(0, ID_LENGTH);
+// Entity and relationship ids: see storage/entity-id.ts
+
+/** Edges recorded per call site: a goroutine is spawned by a call */
+const CALL_EDGES = new Set<RelationType>([RelationType.CALLS, RelationType.SPAWNS]);
+/** Edges recorded per access site: a send writes its channel, a receive reads it */
+const ACCESS_EDGES = new Set<RelationType>([RelationType.ACCESSES, RelationType.SENDS, RelationType.RECEIVES]);
+
+function callSiteFrom(meta: Record<string, unknown> | undefined): CallSite | null {
+  if (typeof meta?.line !== "number") return null;
+  const site: CallSite = { line: meta.line };
+  if (typeof meta.column === "number") site.column = meta.column;
+  if (typeof meta.callee === "string") site.callee = meta.callee;
+  if (typeof meta.receiverType === "string") site.receiverType = meta.receiverType;
+  if (Array.isArray(meta.arguments)) site.arguments = meta.arguments as CallArgument[];
+  if (typeof meta.arity === "number") site.arity = meta.arity;
+  return site;
+}
+
+function accessSiteFrom(meta: Record<string, unknown> | undefined): AccessSite | null {
+  if (typeof meta?.line !== "number") return null;
+  const access = meta.access === "write" || meta.access === "readwrite" ? meta.access : "read";
+  const site: AccessSite = { line: meta.line, access };
+  if (typeof meta.column === "number") site.column = meta.column;
+  if (typeof meta.referencedName === "string") site.name = meta.referencedName;
+  if (typeof meta.receiverType === "string") site.receiverType = meta.receiverType;
+  return site;
+}
+
+/** `read` or `write` when every site agrees, `readwrite` otherwise */
+function combinedAccess(sites: AccessSite[]): AccessKind {
+  const kinds = new Set(sites.map((site) => site.access));
+  return kinds.size === 1 ? [...kinds][0]! : "readwrite";
 }
-function stableRelationshipId(fromId: string, toId: string, type: RelationType | string): string {
-  return createHash("sha256").update(`${fromId}|${toId}|${type}`).digest("base64url").slice(0, ID_LENGTH);
+
+function encloses(outer: Entity, inner: Entity): boolean {
+  return (
+    outer.id !== inner.id &&
+    outer.location.start.index <= inner.location.start.index &&
+    inner.location.end.index <= outer.location.end.index
+  );
+}
+
+/** Property `name` of the class whose body holds `member` */
+function classFieldOf(member: Entity, name: string, entities: Entity[]): Entity | undefined {
+  const owner = entities
+    .filter((e) => e.type === EntityType.CLASS && encloses(e, member))
+    .sort((a, b) => b.location.start.index - a.location.start.index)[0];
+  if (!owner) return undefined;
+  return entities.find((e) => String(e.type) === "property" && e.name === name && encloses(owner, e));
+}
+
+/** Variables and constants declared at the top level of a file, outside any function or class */
+function moduleVariables(entities: Entity[]): Map<string, Entity> {
+  const scopes = entities.filter(
+    (e) => e.type === EntityType.FUNCTION || e.type === EntityType.METHOD || e.type === EntityType.CLASS,
+  );
+  const variables = new Map<string, Entity>();
+  for (const e of entities) {
+    if (e.type !== EntityType.VARIABLE && e.type !== EntityType.CONSTANT) continue;
+    if (!variables.has(e.name) && !scopes.some((scope) => encloses(scope, e))) variables.set(e.name, e);
+  }
+  return variables;
+}
+
+/**
+ * Fold `calls` and `accesses` edges that collapsed onto the same id (one caller using the same
+ * target several times) into a single edge carrying every site. Other relationships are passed through.
+ */
+function mergeCallSites(relationships: Relationship[]): Relationship[] {
+  const merged: Relationship[] = [];
+  const sitesById = new Map<string, Relationship>();
+  for (const rel of relationships) {
+    const key = rel.metadata?.callSites ? "callSites" : rel.metadata?.accessSites ? "accessSites" : null;
+    if (!key) {
+      merged.push(rel);
+      continue;
+    }
+    const existing = sitesById.get(rel.id);
+    if (!existing) {
+      sitesById.set(rel.id, rel);
+      merged.push(rel);
+      continue;
+    }
+    const all: Array<CallSite | AccessSite> = [...(existing.metadata?.[key] ?? []), ...(rel.metadata?.[key] ?? [])];
+    all.sort((a, b) => a.line - b.line || (a.column ?? 0) - (b.column ?? 0));
+    // The merged edge is only as certain as its weakest site
+    const weakest =
+      Number(rel.metadata?.confidence ?? 1) < Number(existing.metadata?.confidence ?? 1)
+        ? { confidence: rel.metadata?.confidence, derivation: rel.metadata?.derivation }
+        : {};
+    const access = key === "accessSites" ? { access: combinedAccess(all as AccessSite[]) } : {};
+    existing.metadata = { ...existing.metadata, line: all[0]?.line, [key]: all, ...access, ...weakest };
+  }
+  return merged;
 }
 
 // =============================================================================
@@ -79,7 +185,29 @@ interface ProvidedRelationship {
 
 interface IndexEntitiesOptions {
   fileHash?: string;
+  /** The entities are all the file has; rows and outgoing edges an earlier index stored beyond them are dropped */
   replaceFile?: boolean;
+  /** Root of the indexed tree; entity ids use paths relative to it */
+  rootDir?: string;
+  /** Index root the file belongs to, recorded on every entity as `metadata.root` */
+  root?: string;
+  /** `git blame` of the file; each entity gets the newest commit of its lines as `metadata.lastModified` */
+  blame?: Array<BlameLine | null>;
+  /** False to update only the graph; the vectors of the file stay as they are until it is embedded again */
+  embed?: boolean;
+}
+
+export interface IndexEntitiesResult extends BatchResult {
+  entitiesIndexed: number;
+  /** Of the entities indexed: new ids, stored ones that changed, and stored ones left as they were */
+  entitiesCreated: number;
+  entitiesUpdated: number;
+  entitiesUnchanged: number;
+  relationshipsCreated: number;
+  /** Entities of an earlier index of the file that it no longer contains (replaceFile only) */
+  entitiesRemoved: number;
+  /** Edges dropped with those entities or no longer produced by the file (replaceFile only) */
+  relationshipsRemoved: number;
 }
 
 export interface IndexerTask extends AgentTask {
@@ -94,6 +222,10 @@ export interface IndexerTask extends AgentTask {
     relationships?: EntityRelationship[];
     fileHash?: string;
     replaceFile?: boolean;
+    rootDir?: string;
+    root?: string;
+    blame?: Array<BlameLine | null>;
+    embed?: boolean;
   };
 }
 
@@ -244,7 +376,14 @@ export class IndexerAgent extends BaseAgent {
           indexerTask.payload.entities!,
           indexerTask.payload.filePath!,
           indexerTask.payload.relationships,
-          { fileHash: indexerTask.payload.fileHash, replaceFile: indexerTask.payload.replaceFile },
+          {
+            fileHash: indexerTask.payload.fileHash,
+            replaceFile: indexerTask.payload.replaceFile,
+            rootDir: indexerTask.payload.rootDir,
+            root: indexerTask.payload.root,
+            blame: indexerTask.payload.blame,
+            embed: indexerTask.payload.embed,
+          },
         );
 
       case "index:incremental":
@@ -269,7 +408,7 @@ export class IndexerAgent extends BaseAgent {
     filePath: string,
     providedRelationships?: ProvidedRelationship[],
     options?: IndexEntitiesOptions,
-  ): Promise<BatchResult & { entitiesIndexed: number; relationshipsCreated: number }> {
+  ): Promise<IndexEntitiesResult> {
     const startTime = Date.now();
     console.log(`[${this.id}] Indexing ${entities.length} entities from ${filePath}`);
 
@@ -298,38 +437,54 @@ export class IndexerAgent extends BaseAgent {
           !hasName && isImport ? { ...(parsed as any), name: `import:${parsed.importData?.source}` } : parsed;
 
         const base = parsedEntityToEntity(normalizedParsed, filePath, fileHash);
-        const entity: Entity = {
-          ...base,
-          id: stableEntityId(base),
-          createdAt: Date.now(),
-          updatedAt: Date.now(),
-        };
-
-        storageEntities.push(entity);
+        storageEntities.push({ ...base, id: "", createdAt: Date.now(), updatedAt: Date.now() });
         validParsed.push(normalizedParsed as ParsedEntity);
       } catch (e) {
         preErrors.push({ item: parsed, error: (e as Error).message });
       }
     }
 
-    if (options?.replaceFile) {
-      try {
-        const deleted = await this.graphStorage.deleteFileData(filePath, {
-          preserveEntityIds: storageEntities.map((entity) => entity.id),
-        });
-        this.cacheManager.clear();
-        if (deleted.entitiesDeleted > 0 || deleted.relationshipsDeleted > 0 || deleted.fileInfoDeleted > 0) {
-          console.log(`[${this.id}] Replacing file data; deleted existing rows for ${filePath}`, deleted);
-        }
-      } catch (error) {
-        console.warn(`[${this.id}] Failed to replace file data for ${filePath}:`, error);
+    // Ids need the whole file: same-named entities are numbered in declaration order
+    const entityIds = assignStableEntityIds(storageEntities, options?.rootDir);
+    const goModules: GoModuleCache = new Map();
+    // Lets search and impact analysis keep tests apart from production code (indexer.includeTests)
+    const testFile = isTestFile(filePath);
+    // ...and under includeTests: false they are stored outside the main graph (storage/tests-view.ts)
+    const apart = testFile && getConfig().indexer?.includeTests === false;
+    storageEntities.forEach((entity, i) => {
+      entity.id = entityIds[i]!;
+      const fqn = fullyQualifiedName(entity, { rootDir: options?.rootDir, goModules });
+      if (fqn) entity.metadata.fqn = fqn;
+      if (options?.root) entity.metadata.root = options.root;
+      if (testFile) entity.metadata.isTest = true;
+      if (options?.blame && entity.location) {
+        const lastModified = lastModifiedIn(options.blame, entity.location.start.line, entity.location.end.line);
+        if (lastModified) entity.metadata.lastModified = lastModified;
+      }
+    });
+
+    // Per-entity body hashes let snapshot diffs tell edited entities from untouched ones
+    const source = await readFile(filePath).catch(() => null);
+    if (source) {
+      for (const entity of storageEntities) {
+        const start = entity.location?.start?.index;
+        const end = entity.location?.end?.index;
+        if (typeof start !== "number" || typeof end !== "number" || end <= start || end > source.length) continue;
+        entity.metadata.bodyHash = createHash("sha256").update(source.subarray(start, end)).digest("base64url");
       }
     }
 
-    // Insert entities in batch
-    const entityResult = await this.batchOps.insertEntities(storageEntities, (processed, total) => {
-      console.log(`[${this.id}] Progress: ${processed}/${total} entities`);
-    });
+    // Cleared when the files entry is stored: a write cut short in between is redone after a restart
+    await this.graphStorage.beginFileWrite(filePath);
+    // Stored apart by a run under includeTests: false; the main graph holds it from now on
+    if (testFile && !apart) await this.graphStorage.deleteTestFileData(filePath);
+
+    // Insert entities in batch; a test file stored apart is written whole once its edges are built
+    const entityResult = apart
+      ? storedApart(storageEntities.length)
+      : await this.batchOps.insertEntities(storageEntities, (processed, total) => {
+          console.log(`[${this.id}] Progress: ${processed}/${total} entities`);
+        });
 
     // Build and insert relationships
     let relationships: Relationship[] = [];
@@ -338,16 +493,35 @@ export class IndexerAgent extends BaseAgent {
     if (providedRelationships && providedRelationships.length > 0) {
       const byName = new Map<string, Entity[]>();
       for (const e of storageEntities) {
+        // Named after their prose, comments are never an edge's endpoint
+        if (e.type === EntityType.COMMENT) continue;
         const arr = byName.get(e.name) || [];
         arr.push(e);
         byName.set(e.name, arr);
       }
 
-      function resolveByNameAndLine(name: string, line?: number): string | undefined {
+      // Language analyzers (Rust, Go, ...) reference entities by their parser-assigned ids
+      // such as `src/lib.rs:struct:Foo`; map those to storage ids before falling back to names.
+      const byParsedId = new Map<string, string>();
+      for (let i = 0; i < validParsed.length; i++) {
+        const parsedId = validParsed[i]?.id;
+        const storageId = storageEntities[i]?.id;
+        if (parsedId && storageId && !byParsedId.has(parsedId)) {
+          byParsedId.set(parsedId, storageId);
+        }
+      }
+
+      // A name shared by several declarations of the file is bound to the one nearest the edge's line
+      type Binding = { id: string; derivation: EdgeDerivation };
+      function resolveByNameAndLine(name: string, line?: number): Binding | undefined {
+        const byId = byParsedId.get(name);
+        if (byId) return { id: byId, derivation: "resolved" };
+
         const candidates = byName.get(name);
         if (!candidates || candidates.length === 0) return undefined;
+        if (candidates.length === 1) return { id: candidates[0]!.id, derivation: "resolved" };
 
-        if (line == null) return candidates[0]?.id;
+        if (line == null) return { id: candidates[0]!.id, derivation: "name_match" };
 
         let best: Entity | undefined;
         let bestDelta = Infinity;
@@ -359,7 +533,7 @@ export class IndexerAgent extends BaseAgent {
             bestDelta = d;
           }
         }
-        return best?.id;
+        return best ? { id: best.id, derivation: "name_match" } : undefined;
       }
       const normalizeRelationshipType = (raw: string): RelationType | null => {
         switch (raw) {
@@ -380,9 +554,37 @@ export class IndexerAgent extends BaseAgent {
             return RelationType.IMPLEMENTS;
           case "depends_on":
             return RelationType.DEPENDS_ON;
-          case "decorates":
-          case "overrides":
           case "embeds":
+            return RelationType.EMBEDS;
+          case "decorated_by":
+            return RelationType.DECORATED_BY;
+          case "overrides":
+            return RelationType.OVERRIDES;
+          case "defines":
+            return RelationType.DEFINES;
+          case "renders":
+            return RelationType.RENDERS;
+          case "uses_hook":
+            return RelationType.USES_HOOK;
+          case "handled_by":
+            return RelationType.HANDLED_BY;
+          case "read_by":
+            return RelationType.READ_BY;
+          case "accesses":
+            return RelationType.ACCESSES;
+          case "spawns":
+            return RelationType.SPAWNS;
+          case "sends":
+            return RelationType.SENDS;
+          case "receives":
+            return RelationType.RECEIVES;
+          case "foreign_key":
+            return RelationType.FOREIGN_KEY;
+          case "extension_of":
+            return RelationType.EXTENSION_OF;
+          case "resolves":
+            return RelationType.RESOLVES;
+          case "decorates":
           case "member_of":
             return RelationType.REFERENCES;
           default:
@@ -390,22 +592,40 @@ export class IndexerAgent extends BaseAgent {
         }
       };
       for (const rel of providedRelationships) {
-        const fromId = resolveByNameAndLine(rel.from, rel.metadata?.line);
-        let toId = resolveByNameAndLine(rel.to, rel.metadata?.line);
+        const normalizedType = normalizeRelationshipType(rel.type);
+        const fromId = resolveByNameAndLine(rel.from, rel.metadata?.line)?.id;
+        const target = resolveByNameAndLine(rel.to, rel.metadata?.line);
+        let toId = target?.id;
+        const callSite = normalizedType && CALL_EDGES.has(normalizedType) ? callSiteFrom(rel.metadata) : null;
+        const accessSite = normalizedType && ACCESS_EDGES.has(normalizedType) ? accessSiteFrom(rel.metadata) : null;
 
         if (!toId) {
           const src = rel.targetFile || "unknown";
-          toId = `external:${src}:${rel.to}`;
+          // Unresolved calls and references are kept under the name the source wrote so they stay
+          // queryable by it
+          const calleeName = typeof rel.metadata?.calleeName === "string" ? rel.metadata.calleeName : undefined;
+          const referencedName =
+            typeof rel.metadata?.referencedName === "string" ? rel.metadata.referencedName : undefined;
+          toId = `external:${src}:${(callSite ? calleeName : referencedName) ?? rel.to}`;
         }
 
-        const normalizedType = normalizeRelationshipType(rel.type);
         if (fromId && toId && normalizedType) {
           relationships.push({
             id: stableRelationshipId(fromId, toId, normalizedType),
             fromId,
             toId,
             type: normalizedType,
-            metadata: { line: rel.metadata?.line, context: rel.type, rawType: rel.type },
+            metadata: {
+              line: rel.metadata?.line,
+              context: rel.type,
+              rawType: rel.type,
+              // Go import aliases, `_` and `.` included
+              ...(typeof rel.metadata?.alias === "string" ? { alias: rel.metadata.alias } : {}),
+              ...(rel.metadata?.sideEffect === true ? { sideEffect: true } : {}),
+              ...(callSite ? { callSites: [callSite] } : {}),
+              ...(accessSite ? { access: accessSite.access, accessSites: [accessSite] } : {}),
+              ...edgeConfidence(target?.derivation ?? "heuristic", rel.metadata?.confidence),
+            },
             createdAt: Date.now(),
           } as Relationship);
         }
@@ -421,7 +641,11 @@ export class IndexerAgent extends BaseAgent {
     const seenExternal = new Map<string, string>(); // extKey -> placeholderId
 
     for (const rel of relationships) {
-      if (typeof rel.toId === "string" && rel.toId.startsWith("external:")) {
+      const unresolved = typeof rel.toId === "string" && rel.toId.startsWith("external:");
+      if (rel.metadata?.derivation === undefined) {
+        rel.metadata = { ...rel.metadata, ...edgeConfidence(unresolved ? "heuristic" : "resolved") };
+      }
+      if (unresolved) {
         const parts = rel.toId.split(":");
         const source = parts[1] ?? "unknown";
         const symbol = parts.slice(2).join(":") || "unknown";
@@ -438,7 +662,7 @@ export class IndexerAgent extends BaseAgent {
           hash: `external:${source}:${symbol}`,
         };
 
-        const placeholderId = stableEntityId(placeholderBase);
+        const placeholderId = externalPlaceholderId(source, symbol);
 
         if (!seenExternal.has(rel.toId)) {
           seenExternal.set(rel.toId, placeholderId);
@@ -455,21 +679,50 @@ export class IndexerAgent extends BaseAgent {
 
       rel.id = stableRelationshipId(rel.fromId, rel.toId, rel.type);
     }
-
-    if (externalPlaceholders.length > 0) {
-      await this.batchOps.insertEntities(externalPlaceholders);
+    relationships = mergeCallSites(relationships);
+
+    let relResult: UpsertResult;
+    if (apart) {
+      // Replaces the file's rows in both graphs, so nothing is left to reconcile
+      const stored = [...storageEntities, ...externalPlaceholders];
+      await this.graphStorage.replaceTestFileData(filePath, stored, relationships);
+      relResult = storedApart(relationships.length);
+    } else {
+      if (externalPlaceholders.length > 0) {
+        await this.batchOps.insertEntities(externalPlaceholders);
+      }
+      relResult = await this.batchOps.insertRelationships(relationships, (processed, total) => {
+        console.log(`[${this.id}] Progress: ${processed}/${total} relationships`);
+      });
     }
 
-    const relResult = await this.batchOps.insertRelationships(relationships, (processed, total) => {
-      console.log(`[${this.id}] Progress: ${processed}/${total} relationships`);
-    });
+    // Upserts on stable ids leave what the file no longer yields; drop it so a re-index adds nothing
+    let entitiesRemoved = 0;
+    let relationshipsRemoved = 0;
+    if (options?.replaceFile && !apart) {
+      try {
+        const reconciled = await this.graphStorage.reconcileFileData(filePath, {
+          entityIds: storageEntities.map((entity) => entity.id),
+          relationshipIds: relationships.map((rel) => rel.id),
+        });
+        entitiesRemoved = reconciled.entitiesRemoved;
+        relationshipsRemoved = reconciled.relationshipsRemoved;
+        if (entitiesRemoved > 0 || relationshipsRemoved > 0) {
+          console.log(`[${this.id}] Replacing file data; dropped stale rows for ${filePath}`, reconciled);
+        }
+      } catch (error) {
+        console.warn(`[${this.id}] Failed to replace file data for ${filePath}:`, error);
+      }
+    }
 
-    // Update file info
+    // Update file info; the mtime lets index_status spot edits without hashing every file
+    const mtimeMs = (await stat(filePath).catch(() => null))?.mtimeMs;
     const fileInfo: FileInfo = {
       path: filePath,
       hash: fileHash,
       lastIndexed: Date.now(),
       entityCount: storageEntities.length,
+      mtimeMs: mtimeMs === undefined ? null : Math.trunc(mtimeMs),
     };
     await this.graphStorage.updateFileInfo(fileInfo);
 
@@ -498,12 +751,21 @@ export class IndexerAgent extends BaseAgent {
     );
     console.log(`[IndexerAgent] Published index:complete event`);
 
-    if (validParsed.length) {
-      const entitiesWithPath = validParsed.map((entity) => ({
+    // Parse-only indexing does not tell the semantic agent, so no vector is written or deleted; nor
+    // does a test file stored apart have any, as the vectors are searched with the main graph
+    const embed = options?.embed !== false;
+    if (embed && validParsed.length && !apart) {
+      // Storage ids, so vectors are keyed like the graph rows they describe
+      const entitiesWithPath = validParsed.map((entity, i) => ({
         ...entity,
+        id: storageEntities[i]?.id ?? entity.id,
         filePath: filePath,
+        root: options?.root,
       }));
       knowledgeBus.publish("semantic:new_entities", entitiesWithPath, this.id);
+    } else if (embed) {
+      // Nothing left to embed, so the vectors of what the file used to declare must go
+      knowledgeBus.publish("semantic:files_emptied", { files: [filePath] }, this.id);
     }
 
     console.log(
@@ -512,17 +774,21 @@ export class IndexerAgent extends BaseAgent {
 
     const failed = entityResult.failed + relResult.failed + preErrors.length;
     const errors = [...entityResult.errors, ...relResult.errors, ...preErrors];
+    sessionMetrics.recordIndexedFile(entityResult.processed, relResult.created, entitiesRemoved, indexTime);
 
     // Return complete indexing statistics
-    const { timeMs: _throwAway, ...restBase } = entityResult;
-
     return {
-      ...restBase,
+      processed: entityResult.processed,
       failed,
       errors,
       timeMs: indexTime,
       entitiesIndexed: entityResult.processed,
-      relationshipsCreated: relResult.processed,
+      entitiesCreated: entityResult.created,
+      entitiesUpdated: entityResult.updated,
+      entitiesUnchanged: entityResult.unchanged,
+      relationshipsCreated: relResult.created,
+      entitiesRemoved,
+      relationshipsRemoved,
     };
   }
 
@@ -538,6 +804,20 @@ export class IndexerAgent extends BaseAgent {
       entityMap.set(`${entity.name}:${entity.location.start.line}`, entity.id);
     }
 
+    // Names bound by named imports in this file: local -> module and exported name
+    const importBindings = new Map<string, { source: string; imported: string }>();
+    for (const parsed of parsedEntities) {
+      if (parsed.type !== "import" || !parsed.importData) continue;
+      for (const specifier of parsed.importData.specifiers) {
+        importBindings.set(specifier.local, {
+          source: parsed.importData.source,
+          imported: specifier.imported || specifier.local,
+        });
+      }
+    }
+
+    const topLevelVariables = moduleVariables(storageEntities);
+
     // Create relationships
     const len = Math.min(parsedEntities.length, storageEntities.length);
     for (let i = 0; i < len; i++) {
@@ -561,13 +841,65 @@ export class IndexerAgent extends BaseAgent {
         }
       }
 
-      // Reference / call relationships (within the same file)
+      // Decorators naming an imported symbol or a function/class of this file
+      for (const decorator of parsed.decorators ?? []) {
+        const binding = importBindings.get(decorator.name);
+        const local = binding
+          ? undefined
+          : storageEntities.find(
+              (e) => e.name === decorator.name && (e.type === EntityType.FUNCTION || e.type === EntityType.CLASS),
+            );
+        if (!binding && !local) continue;
+
+        relationships.push({
+          id: nanoid(12),
+          fromId: entity.id,
+          toId: local?.id ?? `external:${binding!.source}:${binding!.imported}`,
+          type: RelationType.DECORATED_BY,
+          metadata: {
+            line: decorator.line ?? parsed.location.start.line,
+            decorator: decorator.name,
+            arguments: decorator.arguments,
+          },
+        });
+      }
+
+      // Base classes and implemented interfaces: a type of this file, an imported symbol, or a bare name
+      const heritage = [
+        ...(parsed.inheritance?.baseClasses ?? []).map((name) => ({ name, type: RelationType.EXTENDS })),
+        ...(parsed.inheritance?.interfaces ?? []).map((name) => ({ name, type: RelationType.IMPLEMENTS })),
+      ];
+      for (const base of heritage) {
+        const binding = importBindings.get(base.name);
+        const local = binding
+          ? undefined
+          : storageEntities.find(
+              (e) =>
+                e.id !== entity.id &&
+                e.name === base.name &&
+                (e.type === EntityType.CLASS || e.type === EntityType.INTERFACE || e.type === EntityType.TYPE),
+            );
+
+        relationships.push({
+          id: nanoid(12),
+          fromId: entity.id,
+          toId:
+            local?.id ??
+            (binding ? `external:${binding.source}:${binding.imported}` : `external:${entity.filePath}:${base.name}`),
+          type: base.type,
+          metadata: { line: parsed.location.start.line, baseType: base.name },
+        });
+      }
+
+      // Reference / call relationships: a declaration of this file, or an imported symbol left at
+      // its placeholder for the cross-file resolver; other names are not recorded
       if (parsed.references && parsed.references.length > 0) {
         for (const ref of parsed.references) {
           // Try to find referenced entity in current file by name
           const refKey = Array.from(entityMap.keys()).find((key) => key.startsWith(`${ref}:`));
           const targetId = refKey ? entityMap.get(refKey)! : undefined;
-          if (!targetId) continue;
+          const binding = targetId ? undefined : importBindings.get(ref);
+          if (!targetId && !binding) continue;
 
           const baseMetadata = {
             line: parsed.location.start.line,
@@ -577,12 +909,14 @@ export class IndexerAgent extends BaseAgent {
           relationships.push({
             id: nanoid(12),
             fromId: entity.id,
-            toId: targetId,
+            toId: targetId ?? `external:${binding!.source}:${binding!.imported}`,
             type: RelationType.REFERENCES,
             metadata: { ...baseMetadata },
           });
+          if (!targetId) continue;
 
-          if (parsed.type === "function" || parsed.type === "method") {
+          // Parsers that report call expressions get exact call edges below instead of this guess
+          if (!parsed.calls && (parsed.type === "function" || parsed.type === "method")) {
             relationships.push({
               id: nanoid(12),
               fromId: entity.id,
@@ -594,6 +928,132 @@ export class IndexerAgent extends BaseAgent {
         }
       }
 
+      // Call relationships: same-file targets by name, everything else as an unresolved callee
+      for (const call of parsed.calls ?? []) {
+        const local = !call.qualifier || call.qualifier === "this" || call.qualifier === "self";
+        const candidates = local
+          ? storageEntities.filter((e) => e.name === call.name && (e.type === "function" || e.type === "method"))
+          : [];
+        const target = candidates[0];
+        const callee = call.qualifier ? `${call.qualifier}.${call.name}` : call.name;
+        const site: CallSite = { line: call.line, column: call.column, callee };
+        if (call.arguments) site.arguments = call.arguments;
+        if (call.arity !== undefined) site.arity = call.arity;
+
+        relationships.push({
+          id: nanoid(12),
+          fromId: entity.id,
+          toId: target?.id ?? `external:${entity.filePath}:${call.name}`,
+          type: RelationType.CALLS,
+          metadata: {
+            line: call.line,
+            column: call.column,
+            callSites: [site],
+            // Several same-name functions in the file (overloads, methods of different classes)
+            ...(candidates.length > 1 ? edgeConfidence("name_match") : {}),
+          },
+        });
+      }
+
+      // Fields of the enclosing class and module-level variables of this file; free names bound
+      // elsewhere (imports, globals) are references or nothing
+      for (const site of parsed.accesses ?? []) {
+        const target =
+          site.qualifier === "this"
+            ? classFieldOf(entity, site.name, storageEntities)
+            : topLevelVariables.get(site.name);
+        if (!target) continue;
+
+        const accessSite: AccessSite = {
+          line: site.line,
+          column: site.column,
+          access: site.access,
+          name: site.qualifier ? `${site.qualifier}.${site.name}` : site.name,
+        };
+        relationships.push({
+          id: nanoid(12),
+          fromId: entity.id,
+          toId: target.id,
+          type: RelationType.ACCESSES,
+          metadata: { line: site.line, column: site.column, access: site.access, accessSites: [accessSite] },
+        });
+      }
+
+      // JSX elements and hook calls of React components: a declaration of this file, an imported
+      // symbol, or the bare name
+      const reactEdges = [
+        ...(parsed.renders ?? []).map((site) => ({ site, type: RelationType.RENDERS })),
+        ...(parsed.hooks ?? []).map((site) => ({ site, type: RelationType.USES_HOOK })),
+      ];
+      for (const { site, type } of reactEdges) {
+        const binding = site.qualifier ? undefined : importBindings.get(site.name);
+        const local =
+          binding || site.qualifier
+            ? undefined
+            : storageEntities.find(
+                (e) => e.name === site.name && (e.type === EntityType.FUNCTION || e.type === EntityType.CLASS),
+              );
+
+        relationships.push({
+          id: nanoid(12),
+          fromId: entity.id,
+          toId:
+            local?.id ??
+            (binding ? `external:${binding.source}:${binding.imported}` : `external:${entity.filePath}:${site.name}`),
+          type,
+          metadata: {
+            line: site.line,
+            column: site.column,
+            ...(site.qualifier ? { qualifier: site.qualifier } : {}),
+          },
+        });
+      }
+
+      // Routes: the handler an imported name binds, or a function of this file (the decorated one
+      // for NestJS controllers, whose methods may share names); unresolved names stay bare
+      if (parsed.handler) {
+        const site = parsed.handler;
+        const binding = site.qualifier ? undefined : importBindings.get(site.name);
+        const candidates =
+          binding || (site.qualifier && site.qualifier !== "this")
+            ? []
+            : storageEntities.filter(
+                (e) => e.name === site.name && (e.type === EntityType.FUNCTION || e.type === EntityType.METHOD),
+              );
+        const local = candidates.find((e) => e.location.start.line === site.line) ?? candidates[0];
+
+        relationships.push({
+          id: nanoid(12),
+          fromId: entity.id,
+          toId:
+            local?.id ??
+            (binding ? `external:${binding.source}:${binding.imported}` : `external:${entity.filePath}:${site.name}`),
+          type: RelationType.HANDLED_BY,
+          metadata: {
+            line: site.line,
+            column: site.column,
+            ...(site.qualifier ? { qualifier: site.qualifier } : {}),
+          },
+        });
+      }
+
+      // Env vars: the functions and methods of this file reading them, by name and start line
+      for (const reader of parsed.readers ?? []) {
+        const candidates = storageEntities.filter(
+          (e) => e.name === reader.name && (e.type === EntityType.FUNCTION || e.type === EntityType.METHOD),
+        );
+        const local = candidates.find((e) => e.location.start.line === reader.line) ?? candidates[0];
+        if (!local) continue;
+
+        relationships.push({
+          id: nanoid(12),
+          fromId: entity.id,
+          toId: local.id,
+          type: RelationType.READ_BY,
+          metadata: { line: reader.line, column: reader.column },
+        });
+      }
+
       // Parent-child relationships
       if (parsed.children) {
         for (const child of parsed.children) {
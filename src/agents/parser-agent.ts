@@ -14,12 +14,14 @@ import type { EventEmitter } from "node:events";
 // =============================================================================
 // 1. IMPORTS AND DEPENDENCIES
 // =============================================================================
-import type { Worker } from "node:worker_threads";
 import { getConfig } from "../config/yaml-config.js";
-import { IncrementalParser } from "../parsers/incremental-parser.js";
-import { isFileSupported } from "../parsers/language-configs.js";
+import { isIndexableSourceFile } from "../parsers/content-language.js";
+import { IncrementalParser, timedOutParseResult } from "../parsers/incremental-parser.js";
+import { ParseWorkerPool, resolveParseWorkerScript, resolveWorkerPoolSize } from "../parsers/parse-worker-pool.js";
 import { type AgentMessage, type AgentTask, AgentType } from "../types/agent.js";
+import { ParseTimeoutError } from "../types/errors.js";
 import type { FileChange, ParseResult, ParserOptions, ParserStats, ParserTask } from "../types/parser.js";
+import { isCancellation } from "../utils/cancellation.js";
 import { BaseAgent } from "./base.js";
 
 // =============================================================================
@@ -33,7 +35,8 @@ function getParserConfig() {
     priority: config.parser.agent?.priority ?? 8,
     batchSize: config.parser.agent?.batchSize ?? 10,
     cacheSize: config.parser.agent?.cacheSize ?? 100 * 1024 * 1024,
-    workerPoolSize: config.parser.agent?.workerPoolSize ?? 2,
+    // A single worker in low-memory mode: every worker holds its own parser and source buffers
+    workerPoolSize: config.indexer?.lowMemory ? 1 : resolveWorkerPoolSize(config.parser.agent?.workerPoolSize),
   };
 }
 
@@ -48,17 +51,17 @@ const TOPICS = {
 // =============================================================================
 // 3. DATA MODELS AND TYPE DEFINITIONS
 // =============================================================================
-// Note: WorkerTask interface would be used for actual worker thread implementation
 
 // =============================================================================
 // 4. UTILITY FUNCTIONS AND HELPERS
 // =============================================================================
 
 /**
- * Filter files to only supported extensions
+ * Filter files to supported extensions, plus files recognised by content when that is enabled
  */
 function filterSupportedFiles(files: string[]): string[] {
-  return files.filter((file) => isFileSupported(file));
+  const detectByContent = getConfig().indexer?.detectLanguageByContent === true;
+  return files.filter((file) => isIndexableSourceFile(file, detectByContent));
 }
 
 // =============================================================================
@@ -70,7 +73,7 @@ function filterSupportedFiles(files: string[]): string[] {
  */
 export class ParserAgent extends BaseAgent {
   private parser: IncrementalParser;
-  private workers: Worker[] = [];
+  private workerPool: ParseWorkerPool | null = null;
   private knowledgeBus: EventEmitter | null = null;
   private handleFileChangeBound!: (event: any) => void;
   private isProcessing = false;
@@ -121,8 +124,7 @@ export class ParserAgent extends BaseAgent {
       this.knowledgeBus.on(TOPICS.FILE_CHANGED, this.handleFileChangeBound);
     }
 
-    const config = getParserConfig();
-    console.log(`[${this.id}] Parser Agent initialized with ${config.workerPoolSize} workers`);
+    console.log(`[${this.id}] Parser Agent initialized with ${this.workerPool?.size ?? 0} workers`);
   }
 
   /**
@@ -132,10 +134,8 @@ export class ParserAgent extends BaseAgent {
     console.log(`[${this.id}] Shutting down Parser Agent...`);
 
     // Terminate workers
-    for (const worker of this.workers) {
-      await worker.terminate();
-    }
-    this.workers = [];
+    await this.workerPool?.terminate();
+    this.workerPool = null;
 
     // Clear caches
     this.parser.clearCache();
@@ -205,7 +205,7 @@ export class ParserAgent extends BaseAgent {
         case "parse:batch":
           // Batch parsing with parallelization
           if (parserTask.payload.files) {
-            results = await this.parseBatch(parserTask.payload.files, parserTask.payload.options);
+            results = await this.parseBatch(parserTask.payload.files, parserTask.payload.options, task.signal);
           }
           break;
 
@@ -341,7 +341,7 @@ export class ParserAgent extends BaseAgent {
   /**
    * Parse files in batch with parallel processing
    */
-  async parseBatch(files: string[], options?: ParserOptions): Promise<ParseResult[]> {
+  async parseBatch(files: string[], options?: ParserOptions, signal?: AbortSignal): Promise<ParseResult[]> {
     // Filter to supported files only
     const supportedFiles = filterSupportedFiles(files);
 
@@ -353,11 +353,11 @@ export class ParserAgent extends BaseAgent {
     console.log(`[${this.id}] Parsing ${supportedFiles.length} files in parallel...`);
 
     // TASK-001: Use worker threads for parallel processing
-    if (this.workers.length > 0 && supportedFiles.length > 20) {
-      return await this.parseWithWorkers(supportedFiles, options);
+    if (this.workerPool && this.workerPool.size > 0 && supportedFiles.length > 1) {
+      return await this.parseWithWorkers(supportedFiles, options, signal);
     } else {
       // Fall back to single-threaded batch processing
-      const result = await this.parser.parseBatch(supportedFiles, options);
+      const result = await this.parser.parseBatch(supportedFiles, options, signal);
       return result.results;
     }
   }
@@ -386,19 +386,44 @@ export class ParserAgent extends BaseAgent {
    * Initialize worker pool for parallel processing
    */
   private async initializeWorkerPool(): Promise<void> {
-    // Worker implementation would be in a separate file
-    // For now, we'll use the main thread parser
-    console.log(`[${this.id}] Worker pool initialization skipped (using main thread)`);
+    // Low-memory debug runs stay on one thread
+    if (process.env.MCP_DEBUG_MODE === "1") {
+      console.log(`[${this.id}] Worker pool disabled in debug mode (using main thread)`);
+      return;
+    }
+    const script = resolveParseWorkerScript();
+    if (!script) {
+      console.log(`[${this.id}] No compiled parse worker found (using main thread)`);
+      return;
+    }
+    try {
+      this.workerPool = new ParseWorkerPool(getParserConfig().workerPoolSize, script);
+    } catch (error) {
+      console.warn(`[${this.id}] Worker pool failed to start (using main thread):`, error);
+    }
   }
 
   /**
    * Parse files using worker threads
    */
-  private async parseWithWorkers(files: string[], options?: ParserOptions): Promise<ParseResult[]> {
-    // For now, fall back to single-threaded processing
-    // Worker implementation would distribute load across threads
-    const result = await this.parser.parseBatch(files, options);
-    return result.results;
+  private async parseWithWorkers(
+    files: string[],
+    options?: ParserOptions,
+    signal?: AbortSignal,
+  ): Promise<ParseResult[]> {
+    const pool = this.workerPool!;
+    // Results keep the input order whatever order the workers finish in
+    return await Promise.all(
+      files.map((file) =>
+        pool.parse(file, options, signal).catch((error) => {
+          if (isCancellation(error)) throw error;
+          // Retrying a file that wedged its worker would wedge the main thread instead
+          if (error instanceof ParseTimeoutError) return timedOutParseResult(error, error.details.timeoutMs);
+          console.warn(`[${this.id}] Worker parse failed for ${file}, retrying on main thread:`, error.message);
+          return this.parser.parseFile(file, undefined, options || {});
+        }),
+      ),
+    );
   }
 
   /**
@@ -25,15 +25,35 @@
  */
 
 import { createHash } from "node:crypto";
+import { readFile } from "node:fs/promises";
+import { basename, resolve } from "node:path";
 import { getConfig } from "../config/yaml-config.js";
 import { type KnowledgeEntry, knowledgeBus } from "../core/knowledge-bus.js";
+import { stripCommentMarkers } from "../parsers/doc-comments.js";
+import { isTestFile } from "../parsers/test-extractor.js";
+import {
+  CHUNK_VECTOR_KIND,
+  type CodeWindow,
+  codeWindows,
+  collapseChunkHits,
+  resolveChunking,
+} from "../semantic/chunking.js";
 import { CodeAnalyzer } from "../semantic/code-analyzer.js";
 import { EmbeddingGenerator } from "../semantic/embedding-generator.js";
+import {
+  composeEmbeddingInput,
+  entityNameParts,
+  resolveEmbeddingInput,
+  surroundingComments,
+  usesField,
+} from "../semantic/embedding-input.js";
 import { HybridSearchEngine } from "../semantic/hybrid-search.js";
 import { SemanticCache } from "../semantic/semantic-cache.js";
-import { VectorStore } from "../semantic/vector-store.js";
+import { BODY_VECTOR_KIND, VectorStore } from "../semantic/vector-store.js";
 import { getGraphStorage } from "../storage/graph-storage-factory.js";
+import { DOC_ENTITY_KINDS } from "../storage/search-filters.js";
 import { type AgentMessage, type AgentTask, AgentType } from "../types/agent.js";
+import { EmbeddingDimensionError } from "../types/errors.js";
 import type { ParsedEntity } from "../types/parser.js";
 import {
   type CloneGroup,
@@ -45,9 +65,11 @@ import {
   type SemanticResult,
   SemanticTaskType,
   type SimilarCode,
+  type SimilarityResult,
   type VectorEmbedding,
 } from "../types/semantic.js";
-import { type Entity, EntityType } from "../types/storage.js";
+import { type Entity, EntityType, type SearchScope } from "../types/storage.js";
+import { throwIfCancelled } from "../utils/cancellation.js";
 // =============================================================================
 // 1. IMPORTS AND DEPENDENCIES
 // =============================================================================
@@ -74,11 +96,13 @@ interface CircuitBreakerConfig {
 
 function getSemanticAgentConfig() {
   const config = getConfig();
+  const fixedBatchSize = config.mcp?.embedding?.batching?.size;
   return {
     maxConcurrency: config.semanticAgent?.maxConcurrency ?? 5,
     memoryLimit: config.semanticAgent?.memoryLimit ?? 240,
     priority: config.semanticAgent?.priority ?? 8,
-    batchSize: config.semanticAgent?.batchSize ?? 8,
+    // A fixed `mcp.embedding.batching.size` wins; otherwise this is where adaptive sizing starts
+    batchSize: typeof fixedBatchSize === "number" ? fixedBatchSize : (config.semanticAgent?.batchSize ?? 8),
     modelPath: config.semanticAgent?.modelPath ?? "./models",
   };
 }
@@ -93,6 +117,75 @@ const CIRCUIT_BREAKER_CONFIG: CircuitBreakerConfig = {
   monitorWindow: 60000, // 1 minute failure window
 };
 
+// Body vectors for find_similar: functions only, and bodies long enough that matching them means
+// shared logic rather than a shared one-line shape (getters, delegating wrappers)
+const BODY_ENTITY_TYPES = new Set<string>([EntityType.FUNCTION, EntityType.METHOD]);
+const MIN_BODY_CHARS = 80;
+
+function entityVectorId(entityId: string): string {
+  return `ent:${entityId}`;
+}
+
+function bodyVectorId(entityId: string): string {
+  return `body:${entityId}`;
+}
+
+// Longest source embedded whole; window chunking reads up to the longer cap and splits it
+const MAX_CODE_CHARS = 10000;
+const MAX_WINDOWED_CODE_CHARS = 200000;
+
+// Embedded by warmup() so the model has run one inference before the first real query
+const WARMUP_PROBE = "function that loads the configuration file";
+
+export type SemanticWarmup = {
+  embedding: ReturnType<EmbeddingGenerator["getProviderInfo"]> & { ms: number };
+  vectors: { count: number; ann: { enabled: boolean; ready: boolean; size: number }; ms: number };
+};
+
+export type ReembedResult = {
+  entities: number;
+  vectors: number;
+  previousDimension: number;
+  dimension: number;
+  provider: ReturnType<EmbeddingGenerator["getProviderInfo"]>;
+};
+
+export type ReembedOptions = {
+  signal?: AbortSignal;
+  onProgress?: (done: number, total: number) => void;
+};
+
+/** Comments above and inside an entity whose source is `code`; "" when its file is unreadable */
+async function readSurroundingComments(e: any, code: string, doc: string): Promise<string> {
+  if (!e?.filePath || typeof e.location?.start?.line !== "number") return "";
+  try {
+    return surroundingComments(await readFile(e.filePath, "utf8"), e.location.start.line, code, doc);
+  } catch {
+    return "";
+  }
+}
+
+/** Source of an entity by its character range (or lines), capped at `maxChars`; "" when unreadable */
+async function readEntityCode(e: any, maxChars = MAX_CODE_CHARS): Promise<string> {
+  if (!e?.filePath) return "";
+  try {
+    const full = await readFile(e.filePath, "utf8");
+    if (typeof e.location?.start?.index === "number" && typeof e.location?.end?.index === "number") {
+      const s = Math.max(0, e.location.start.index);
+      const t = Math.min(full.length, e.location.end.index);
+      return t > s && t - s < maxChars ? full.slice(s, t) : "";
+    }
+    if (typeof e.location?.start?.line === "number" && typeof e.location?.end?.line === "number") {
+      const lines = full.split(/\r?\n/);
+      const sLine = Math.max(0, e.location.start.line - 1);
+      const eLine = Math.min(lines.length, e.location.end.line);
+      const slice = lines.slice(sLine, eLine).join("\n");
+      return slice.length > maxChars ? slice.slice(0, maxChars) : slice;
+    }
+  } catch {}
+  return "";
+}
+
 // =============================================================================
 // 3. DATA MODELS AND TYPE DEFINITIONS
 // =============================================================================
@@ -116,6 +209,19 @@ export class SemanticAgent extends BaseAgent implements SemanticOperations {
   private cache: SemanticCache;
   private codeAnalyzer!: CodeAnalyzer;
   private embeddingDim = 384;
+  private dimensionMismatchWarned = false;
+  private warmupRun: Promise<SemanticWarmup> | null = null;
+  private warmedUp = false;
+  /** `parser.comments.embed`: comment entities are left out of the vector store unless set */
+  private readonly embedComments = getConfig().parser?.comments?.embed === true;
+  /** `mcp.semantic.chunkStrategy` and its window sizes */
+  private readonly chunking = resolveChunking({
+    strategy: getConfig().mcp?.semantic?.chunkStrategy,
+    windowLines: getConfig().mcp?.semantic?.chunkWindowLines,
+    overlapLines: getConfig().mcp?.semantic?.chunkOverlapLines,
+  });
+  /** `mcp.semantic.embeddingInput`: the fields embedded per declaration and their weights */
+  private readonly embeddingInput = resolveEmbeddingInput(getConfig().mcp?.semantic?.embeddingInput);
   private embeddingBatchSize = AGENT_CONFIG.batchSize;
   private readonly defaultMaxConcurrency: number;
   private readonly defaultMemoryLimit: number;
@@ -128,6 +234,31 @@ export class SemanticAgent extends BaseAgent implements SemanticOperations {
   private failureWindow: number[] = [];
   private debugMode = process.env.SEMANTIC_AGENT_DEBUG === "true";
 
+  /**
+   * The error searches fail with while the active provider's dimension differs from the stored vectors'
+   */
+  private dimensionMismatch(): EmbeddingDimensionError | null {
+    const providerDimensions = this.embeddingGen?.getDimension?.() ?? this.embeddingDim;
+    const storedDimensions = this.vectorStore?.getDimensions?.();
+    if (!providerDimensions || !storedDimensions || providerDimensions === storedDimensions) return null;
+    const provider = this.embeddingGen?.getProviderInfo?.();
+    return new EmbeddingDimensionError({
+      storedDimension: storedDimensions,
+      activeDimension: providerDimensions,
+      provider: provider?.provider,
+      model: provider?.model,
+      fallback: provider?.fallback || undefined,
+    });
+  }
+
+  /**
+   * Ensure the active embedding provider matches the dimension of the stored vectors
+   */
+  private assertEmbeddingDimensions(): void {
+    const mismatch = this.dimensionMismatch();
+    if (mismatch) throw mismatch;
+  }
+
   /**
    * Get embedding dimensions by generating a test embedding
    */
@@ -155,6 +286,8 @@ export class SemanticAgent extends BaseAgent implements SemanticOperations {
     vectorsStored: 0,
   };
   private subscriptionIds: string[] = [];
+  // Aborted when an indexing run is cancelled, so embeddings queued for its files stop too
+  private ingestController = new AbortController();
 
   constructor() {
     super(AgentType.SEMANTIC, {
@@ -196,6 +329,10 @@ export class SemanticAgent extends BaseAgent implements SemanticOperations {
       quantized: true,
       localPath: AGENT_CONFIG.modelPath,
       batchSize: this.embeddingBatchSize,
+      initRetries: config.mcp?.embedding?.initRetries,
+      initBackoffMs: config.mcp?.embedding?.initBackoffMs,
+      rateLimit: config.mcp?.embedding?.rateLimit,
+      batching: config.mcp?.embedding?.batching,
       ollama: config.mcp?.embedding?.ollama
         ? {
             baseUrl: config.mcp.embedding.ollama.baseUrl,
@@ -215,6 +352,8 @@ export class SemanticAgent extends BaseAgent implements SemanticOperations {
             timeoutMs: config.mcp.embedding.openai.timeoutMs || config.mcp.embedding.openai.timeout,
             concurrency: config.mcp.embedding.openai.concurrency,
             maxBatchSize: config.mcp.embedding.openai.maxBatchSize,
+            dimensions: config.mcp.embedding.openai.dimensions,
+            maxRetries: config.mcp.embedding.openai.maxRetries,
           }
         : undefined,
       cloudru: config.mcp?.embedding?.cloudru
@@ -227,6 +366,7 @@ export class SemanticAgent extends BaseAgent implements SemanticOperations {
           }
         : undefined,
       memory: config.mcp?.embedding?.memory,
+      custom: config.mcp?.embedding?.custom,
     });
 
     // Get dimensions dynamically from actual embedding
@@ -237,21 +377,61 @@ export class SemanticAgent extends BaseAgent implements SemanticOperations {
     const defaultVectorDbPath = "./.code-graph-rag/vectors.db";
     const dbPath = config.database?.path || defaultVectorDbPath;
     console.log(`[${this.id}] Initializing VectorStore with dbPath: ${dbPath}`);
+    // Only a working primary provider may claim the store; a temporary fallback must not wipe its vectors
+    const source = this.embeddingGen.getProviderInfo?.();
+    if (source?.error) {
+      console.warn(`[${this.id}] Embedding provider fell back to ${source.provider}: ${source.error}`);
+    }
     this.vectorStore = new VectorStore({
       dbPath: dbPath,
       dimensions: dimensions,
+      embeddingSource: source && !source.fallback ? { provider: source.provider, model: source.model } : undefined,
+      onDimensionChange: config.mcp?.embedding?.onDimensionChange,
+      ann: config.mcp?.semantic?.ann,
+      busyTimeoutMs: config.database?.busyTimeoutMs,
     });
 
     // Wait for vector store to be fully initialized
     await this.vectorStore.initialize();
     console.log(`[${this.id}] Vector store initialized successfully`);
 
+    const mismatch = this.dimensionMismatch();
+    if (mismatch) console.warn(`[${this.id}] ${mismatch.message}`);
+
+    await this.attachEmbeddingCache(!!source && !source.fallback);
+
     this.hybridSearch = new HybridSearchEngine(this.vectorStore, this.embeddingGen);
+    // Windows of one declaration fold into a single result, so fetch enough candidates to fill a page
+    if (this.chunking.strategy === "window") this.hybridSearch.setChunkOversample(3);
 
     this.codeAnalyzer = new CodeAnalyzer(this.vectorStore, this.embeddingGen, this.cache);
     (this as any)["embeddingGen.generateBatch"] = (texts: any) => this.embeddingGen.generateBatch(texts);
   }
 
+  /**
+   * Keep generated vectors in the graph database so re-indexing unchanged code skips the provider.
+   * Entries from other providers/models are dropped, but only once the configured provider is live.
+   */
+  private async attachEmbeddingCache(providerIsLive: boolean): Promise<void> {
+    try {
+      const storage = await getGraphStorage();
+      if (typeof storage.getCachedEmbeddings !== "function") return;
+      this.embeddingGen.setPersistentCache?.(storage);
+
+      if (providerIsLive) {
+        const removed = await storage.pruneEmbeddingCache(this.embeddingGen.getCacheKey());
+        if (removed > 0) {
+          console.log(`[${this.id}] Dropped ${removed} cached embeddings from a previous provider/model`);
+        }
+      }
+    } catch (error) {
+      console.warn(
+        `[${this.id}] Persistent embedding cache unavailable:`,
+        error instanceof Error ? error.message : String(error),
+      );
+    }
+  }
+
   /**
    * Async initialization method
    */
@@ -275,9 +455,48 @@ export class SemanticAgent extends BaseAgent implements SemanticOperations {
 
     await this.warmupSemanticCache();
 
+    if (this.vectorStore.getEmbeddingSourceChange?.()) {
+      // Rebuild in the background so startup is not blocked on a full re-embed
+      void this.reembedIndexedEntities().catch((error) => {
+        console.warn(
+          `[${this.id}] Re-embedding after provider change failed:`,
+          error instanceof Error ? error.message : String(error),
+        );
+      });
+    }
+
     console.log(`[${this.id}] Semantic agent initialized with ${this.semanticMetrics.vectorsStored} vectors`);
   }
 
+  /**
+   * Re-embed every indexed entity, used after the vector store was reset for a new provider/model.
+   * Returns how many entities were embedded.
+   */
+  private async reembedIndexedEntities(options: ReembedOptions = {}): Promise<number> {
+    const storage = await getGraphStorage();
+    const total = options.onProgress ? (await storage.countGraph()).entities : 0;
+    const pageSize = 500;
+    let offset = 0;
+    let count = 0;
+
+    while (true) {
+      throwIfCancelled(options.signal);
+      const page = await storage.executeQuery({ type: "entity", limit: pageSize, offset });
+      const entities = page.entities.filter((e) => !e.filePath.startsWith("external://"));
+      if (entities.length > 0) {
+        await this.handleNewEntities(entities as unknown as ParsedEntity[]);
+        count += entities.length;
+      }
+      options.onProgress?.(Math.min(offset + page.entities.length, total), total);
+      if (page.entities.length < pageSize) break;
+      offset += pageSize;
+    }
+
+    knowledgeBus.publish("semantic:reembed:complete", { count }, this.id);
+    console.log(`[${this.id}] Re-embedded ${count} entities for the new embedding provider`);
+    return count;
+  }
+
   // TASK-004B: Circuit breaker implementation methods
 
   /**
@@ -511,9 +730,13 @@ export class SemanticAgent extends BaseAgent implements SemanticOperations {
 
   // SemanticOperations implementation
 
-  async semanticSearch(query: string, limit = 10): Promise<SemanticResult> {
-    // Use cache if available
-    const cacheKey = `search:${query}:${limit}`;
+  async semanticSearch(query: string, limit = 10, scope?: SearchScope): Promise<SemanticResult> {
+    // Fail loudly instead of letting the circuit breaker turn a provider/index mismatch into empty results
+    this.assertEmbeddingDimensions();
+
+    // Keyed by the store's write generation: a result from before a delete may hold removed entities
+    const generation = this.getVectorWriteSignature();
+    const cacheKey = `search:${generation}:${query}:${limit}${scope ? `:${JSON.stringify(scope)}` : ""}`;
     const cached = this.cache.get<SemanticResult>(cacheKey);
     if (cached) {
       this.updateCacheHitRate(true);
@@ -525,7 +748,7 @@ export class SemanticAgent extends BaseAgent implements SemanticOperations {
     // TASK-004B: Execute with circuit breaker protection
     return this.executeWithCircuitBreaker(
       async () => {
-        const result = await this.hybridSearch.semanticSearch(query, limit);
+        const result = await this.hybridSearch.semanticSearch(query, limit, scope);
         // Cache the result
         this.cache.set(cacheKey, result as any, 600000); // 10 minutes TTL
         return result;
@@ -549,6 +772,161 @@ export class SemanticAgent extends BaseAgent implements SemanticOperations {
     return this.codeAnalyzer.findSimilarCode(code, threshold);
   }
 
+  /** How many of the given graph entities have a search vector; body vectors are not counted */
+  async countEmbeddedEntities(entityIds: string[]): Promise<number> {
+    return this.vectorStore.countIds(entityIds.map(entityVectorId));
+  }
+
+  /** Vector ids with the entity each was embedded for, for verify_index */
+  async listVectorEntityRefs(): Promise<Array<{ id: string; entityId: string }>> {
+    return this.vectorStore.listEntityRefs();
+  }
+
+  /** Search vectors of graph entities in batches, for snapshot_graph to keep with a snapshot */
+  entityVectors(batchSize = 500): AsyncGenerator<Array<{ entityId: string; vector: Float32Array }>> {
+    return this.vectorStore.iterateEntityVectors(batchSize);
+  }
+
+  /**
+   * The query embedded the way semanticSearch embeds it, for ranking vectors kept outside the
+   * store (snapshots). Throws when the provider no longer matches the stored vectors' dimension.
+   */
+  async embedQuery(query: string): Promise<Float32Array> {
+    this.assertEmbeddingDimensions();
+    return this.embeddingGen.generateEmbedding(query);
+  }
+
+  /** provider:model:dimension of the active embedder; vectors made by another one do not compare */
+  getEmbeddingKey(): string {
+    return this.embeddingGen.getCacheKey();
+  }
+
+  /** Delete vectors by id; cached search results, which may still hold them, are dropped */
+  async deleteVectors(ids: string[]): Promise<number> {
+    const removed = await this.vectorStore.deleteByIds(ids);
+    if (removed > 0) this.cache.clear();
+    return removed;
+  }
+
+  /** Compared across calls to tell whether any vector was written in between */
+  getVectorWriteSignature(): string {
+    return this.vectorStore ? this.vectorStore.getWriteSignature() : "none";
+  }
+
+  /** File the vector store keeps its embeddings in */
+  getVectorDbPath(): string {
+    return this.vectorStore.getDbPath();
+  }
+
+  /**
+   * Compact the vector store for compact_index. `sharedFile` is the graph database path: a store
+   * kept in that file is vacuumed along with it, so only a store in its own file vacuums here.
+   */
+  async compactVectors(sharedFile: string, vacuum: boolean): Promise<{ annNodesDropped: number; vacuumed: boolean }> {
+    const separate = resolve(this.vectorStore.getDbPath()) !== resolve(sharedFile);
+    return this.vectorStore.compact({ vacuum: vacuum && separate });
+  }
+
+  /** Pick up vectors written to the store's file by import_index; cached search results are dropped */
+  async reloadVectorStore(): Promise<void> {
+    await this.vectorStore.reopen();
+    this.cache.clear();
+    this.warmupRun = null;
+    this.warmedUp = false;
+  }
+
+  /**
+   * Regenerate every vector from the indexed graph for reembed: the store is emptied and recreated
+   * at the provider's dimension, then each entity is embedded again. Parsing is not repeated, and
+   * texts the embedding cache already holds for the current model are not sent to the provider.
+   */
+  async reembedAll(options: ReembedOptions = {}): Promise<ReembedResult> {
+    throwIfCancelled(options.signal);
+    const previousDimension = this.vectorStore.getDimensions();
+    await this.vectorStore.rebuild();
+    this.cache.clear();
+    this.warmupRun = null;
+    this.warmedUp = false;
+    this.dimensionMismatchWarned = false;
+
+    const entities = await this.reembedIndexedEntities(options);
+    const vectors = await this.vectorStore.count();
+    this.semanticMetrics.vectorsStored = vectors;
+    return {
+      entities,
+      vectors,
+      previousDimension,
+      dimension: this.vectorStore.getDimensions(),
+      provider: this.embeddingGen.getProviderInfo(),
+    };
+  }
+
+  /**
+   * Run the embedding model once on a probe query and have the ANN index loaded or built, so the
+   * first semantic_search waits on neither. Later calls share the first run; a failed run is retried.
+   */
+  warmup(): Promise<SemanticWarmup> {
+    this.warmupRun ??= (async () => {
+      const started = Date.now();
+      await this.embeddingGen.generateEmbedding(WARMUP_PROBE);
+      const embedded = Date.now();
+      const ann = await this.vectorStore.warmAnnIndex();
+      this.warmedUp = true;
+      return {
+        embedding: { ...this.embeddingGen.getProviderInfo(), ms: embedded - started },
+        vectors: { count: await this.vectorStore.count(), ann, ms: Date.now() - embedded },
+      };
+    })().catch((error) => {
+      this.warmupRun = null;
+      throw error;
+    });
+    return this.warmupRun;
+  }
+
+  /** Whether a warmup() run has completed since startup or the last vector store reload */
+  isWarm(): boolean {
+    return this.warmedUp;
+  }
+
+  /**
+   * Functions whose body vectors are closest to the body of `entity`, which is left out. Its stored
+   * body vector is used when there is one; otherwise the body is read and embedded now (functions
+   * too short to get a body vector at index time).
+   */
+  async findSimilarBodies(
+    entity: Entity,
+    options: { minScore?: number; limit?: number; scope?: SearchScope } = {},
+  ): Promise<{ matches: SimilarityResult[]; bodyVector: "stored" | "computed" | "none" }> {
+    const id = bodyVectorId(entity.id);
+    const stored = (await this.vectorStore.get(id))?.vector;
+    let vector = stored;
+    if (!vector) {
+      const body = (await readEntityCode(entity)).trim();
+      if (!body) return { matches: [], bodyVector: "none" };
+      vector = await this.embeddingGen.generateEmbedding(body);
+    }
+    const matches = await this.vectorStore.searchBodies(vector, {
+      limit: options.limit,
+      minCosine: options.minScore,
+      excludeIds: [id],
+      scope: options.scope,
+    });
+    return { matches, bodyVector: stored ? "stored" : "computed" };
+  }
+
+  /**
+   * Entities whose search vectors (name, signature, documentation and code as embedded at index
+   * time) are closest to the one of `entity`, which is left out. Empty when it was never embedded.
+   */
+  async findSimilarEntities(entity: Entity, limit = 10): Promise<SimilarityResult[]> {
+    const id = entityVectorId(entity.id);
+    const vector = (await this.vectorStore.get(id))?.vector;
+    if (!vector) return [];
+    const oversample = this.chunking.strategy === "window" ? 3 : 1;
+    const matches = collapseChunkHits(await this.vectorStore.search(vector, (limit + 1) * oversample));
+    return matches.filter((match) => match.id !== id).slice(0, limit);
+  }
+
   async detectClones(minSimilarity = 0.65): Promise<CloneGroup[]> {
     return this.codeAnalyzer.detectClones(minSimilarity);
   }
@@ -718,7 +1096,8 @@ export class SemanticAgent extends BaseAgent implements SemanticOperations {
         try {
           const ents = entry.data as ParsedEntity[];
           if (Array.isArray(ents) && ents.length > 0) {
-            await this.handleNewEntities(ents);
+            // The indexer publishes every entity of a file at once
+            await this.handleNewEntities(ents, { replaceFiles: true });
           }
         } catch (e) {
           if (this.debugMode) console.warn(`[${this.id}] semantic:new_entities failed:`, (e as Error).message);
@@ -726,6 +1105,28 @@ export class SemanticAgent extends BaseAgent implements SemanticOperations {
       }),
     );
 
+    // Deleted files, and files re-indexed without a single entity, keep no vectors
+    for (const topic of ["index:files_removed", "semantic:files_emptied"]) {
+      this.subscriptionIds.push(
+        knowledgeBus.subscribe(this.id, topic, async (entry) => {
+          const files = (entry.data as { files?: string[] } | undefined)?.files;
+          if (!Array.isArray(files) || files.length === 0) return;
+          try {
+            await this.removeFileVectors(files);
+          } catch (e) {
+            console.warn(`[${this.id}] ${topic} failed:`, (e as Error).message);
+          }
+        }),
+      );
+    }
+
+    this.subscriptionIds.push(
+      knowledgeBus.subscribe(this.id, "indexing:cancelled", () => {
+        this.ingestController.abort();
+        this.ingestController = new AbortController();
+      }),
+    );
+
     this.subscriptionIds.push(
       knowledgeBus.subscribe(this.id, "resources:adjusted", this.handleResourceAdjustment.bind(this)),
     );
@@ -768,7 +1169,22 @@ export class SemanticAgent extends BaseAgent implements SemanticOperations {
     console.log(`[${this.id}] Updated embedding for entity: ${e.id}`);
   }
 
-  private async handleNewEntities(entities: ParsedEntity[]): Promise<void> {
+  /** Delete every vector stored for `files`, from the table and the ANN index alike */
+  private async removeFileVectors(files: string[]): Promise<number> {
+    if (typeof this.vectorStore.deleteByPaths !== "function") return 0;
+    const removed = await this.vectorStore.deleteByPaths(files);
+    this.semanticMetrics.vectorsStored = await this.vectorStore.count();
+    console.log(`[${this.id}] Removed ${removed} embeddings for ${files.length} files`);
+    return removed;
+  }
+
+  /**
+   * Embed entities whose embedding input (the fields `mcp.semantic.embeddingInput` picks) changed
+   * since their vector was stored; unchanged entities keep their vector, and an entity that only
+   * moved within its file reuses the vector stored under its old id. With `replaceFiles` the batch
+   * is the complete entity list of its files, so vectors of entities no longer there are deleted.
+   */
+  private async handleNewEntities(entities: ParsedEntity[], options: { replaceFiles?: boolean } = {}): Promise<void> {
     console.log(`[${this.id}] Processing ${entities?.length || 0} new entities for embedding`);
     console.log(`[${this.id}] Entities type: ${typeof entities}, isArray: ${Array.isArray(entities)}`);
 
@@ -776,39 +1192,66 @@ export class SemanticAgent extends BaseAgent implements SemanticOperations {
       console.error(`[${this.id}] ERROR: entities is not an array!`, entities);
       return;
     }
+    if (!this.embedComments) entities = entities.filter((e) => e.type !== "comment");
+    // Vectors of the new dimension cannot join the kept ones; the graph is still indexed
+    const mismatch = this.dimensionMismatch();
+    if (mismatch) {
+      if (!this.dimensionMismatchWarned) console.warn(`[${this.id}] Not embedding new entities: ${mismatch.message}`);
+      this.dimensionMismatchWarned = true;
+      return;
+    }
+    const signal = this.ingestController.signal;
 
-    const fs = await import("node:fs/promises");
-
+    const codes: string[] = [];
+    // Window strategy: the chunks of each declaration longer than a window
+    const windows: CodeWindow[][] = [];
     const texts = await Promise.all(
-      entities.map(async (ent) => {
+      entities.map(async (ent, i) => {
         const e: any = ent;
-        let code = "";
-        try {
-          if (e.filePath) {
-            const full = await fs.readFile(e.filePath, "utf8");
-            if (typeof e.location?.start?.index === "number" && typeof e.location?.end?.index === "number") {
-              const s = Math.max(0, e.location.start.index);
-              const t = Math.min(full.length, e.location.end.index);
-              if (t > s && t - s < 10000) code = full.slice(s, t);
-            } else if (typeof e.location?.start?.line === "number" && typeof e.location?.end?.line === "number") {
-              const lines = full.split(/\r?\n/);
-              const sLine = Math.max(0, e.location.start.line - 1);
-              const eLine = Math.min(lines.length, e.location.end.line);
-              const slice = lines.slice(sLine, eLine).join("\n");
-              code = slice.length > 10000 ? slice.slice(0, 10000) : slice;
-            }
-          }
-        } catch {}
-
-        const header = `${e.name ?? ""} ${e.type ?? ""} ${e.signature ?? ""}`.trim();
-        return `${header}\n${code}`.trim();
+        if (DOC_ENTITY_KINDS.includes(e.type)) {
+          // A section's prose is its documentation; the file and heading path say where it sits
+          codes[i] = "";
+          const headingPath: string[] = e.metadata?.headingPath ?? [e.name ?? ""];
+          const where = [basename(e.filePath ?? e.path ?? ""), ...(e.type === "heading" ? headingPath : [])];
+          const prose = e.documentation ?? e.metadata?.documentation ?? "";
+          return [where.filter(Boolean).join(" > "), prose].filter(Boolean).join("\n").trim();
+        }
+        if (e.type === "comment") {
+          // The prose and the symbol it sits in; the comment's source is that prose again
+          codes[i] = "";
+          const where = [basename(e.filePath ?? e.path ?? ""), e.metadata?.enclosing?.name].filter(Boolean);
+          const prose = stripCommentMarkers(e.documentation ?? e.metadata?.documentation ?? e.name ?? "");
+          return [where.join(" > "), prose].filter(Boolean).join("\n").trim();
+        }
+        const windowed = this.chunking.strategy === "window";
+        const code = await readEntityCode(e, windowed ? MAX_WINDOWED_CODE_CHARS : MAX_CODE_CHARS);
+        codes[i] = code;
+        const signature = String(e.signature ?? e.metadata?.signature ?? "");
+        const header = `${e.name ?? ""} ${e.type ?? ""} ${signature}`.trim();
+        // Parsed entities carry it at the top level, stored ones in metadata
+        const docstring = e.documentation ?? e.metadata?.documentation ?? e.metadata?.docstring;
+        const doc = typeof docstring === "string" ? stripCommentMarkers(docstring) : "";
+        if (windowed) {
+          const startLine = Number(e.location?.start?.line ?? 1) || 1;
+          windows[i] = codeWindows(code, startLine, this.chunking).map((w) => ({ ...w, text: `${header}\n${w.text}` }));
+        }
+        // Windows carry the body of long declarations, the signature strategy none at all
+        const withBody = this.chunking.strategy !== "signature" && (windows[i]?.length ?? 0) === 0;
+        return composeEmbeddingInput(this.embeddingInput, {
+          header,
+          name: e.name ?? "",
+          signature,
+          ...entityNameParts(e),
+          doc,
+          body: withBody && code.length <= MAX_CODE_CHARS ? code : "",
+          comments: usesField(this.embeddingInput, "comments") ? await readSurroundingComments(e, code, doc) : "",
+        });
       }),
     );
 
-    const embeddings = await this.embeddingGen.generateBatch(texts);
-
     const storage = await getGraphStorage();
-    const modelName = (this as any).embeddingGen?.modelName || "default";
+    const source = this.embeddingGen.getProviderInfo?.();
+    const modelName = source?.model || "default";
 
     const entityDataMap = new Map();
     for (const entity of entities) {
@@ -819,11 +1262,11 @@ export class SemanticAgent extends BaseAgent implements SemanticOperations {
       }
     }
 
-    const vectorEmbeddings: VectorEmbedding[] = entities.map((entity, i) => {
+    const records: Array<Omit<VectorEmbedding, "vector">> = entities.map((entity, i) => {
       const x: any = entity as any;
 
       const stableId = x.id
-        ? `ent:${x.id}`
+        ? entityVectorId(x.id)
         : `doc:${createHash("sha256")
             .update(
               `${x.filePath ?? ""}|${x.type}|${x.name}|${x.location?.start?.index ?? -1}-${x.location?.end?.index ?? -1}|${modelName}`,
@@ -835,30 +1278,162 @@ export class SemanticAgent extends BaseAgent implements SemanticOperations {
       const filePath = x.filePath ?? x.path ?? storedEntity?.filePath ?? "";
 
       const language = x.language ?? storedEntity?.language ?? undefined;
+      // Lets semantic_search narrow vector candidates to one index root
+      const root = x.root ?? x.metadata?.root ?? storedEntity?.metadata?.root ?? undefined;
+      // semantic_search leaves these out unless asked for them
+      const anonymous = (x.metadata?.isAnonymous ?? storedEntity?.metadata?.isAnonymous) === true || undefined;
+      // ...and these when tests are kept out of search (indexer.includeTests: false)
+      const test = isTestFile(filePath) || undefined;
+      const visibility = x.metadata?.visibility ?? storedEntity?.metadata?.visibility ?? undefined;
 
       return {
         id: stableId,
         content: texts[i] ?? "",
-        vector: embeddings[i] ?? new Float32Array(this.embeddingDim),
         metadata: {
           path: filePath,
           type: x.type,
           name: x.name,
           language,
+          root,
+          anonymous,
+          test,
+          visibility,
           entityId: x.id ?? undefined,
           start: x.location?.start?.index ?? undefined,
           end: x.location?.end?.index ?? undefined,
+          provider: source?.provider,
           model: modelName,
+          inputHash: createHash("sha256").update(texts[i] ?? "").digest("base64url"),
         },
         createdAt: Date.now(),
       };
     });
 
-    await this.vectorStore.insertBatch(vectorEmbeddings);
+    // Each window points back at the declaration vector search folds it into
+    const chunkRecords: Array<Omit<VectorEmbedding, "vector">> = [];
+    const chunkTexts: string[] = [];
+    for (let i = 0; i < entities.length; i++) {
+      const record = records[i]!;
+      for (const window of windows[i] ?? []) {
+        chunkRecords.push({
+          id: `chunk:${record.id}:${window.index}`,
+          content: window.text,
+          metadata: {
+            ...record.metadata,
+            kind: CHUNK_VECTOR_KIND,
+            parentId: record.id,
+            chunk: { index: window.index, startLine: window.startLine, endLine: window.endLine },
+            inputHash: createHash("sha256").update(window.text).digest("base64url"),
+          },
+          createdAt: record.createdAt,
+        });
+        chunkTexts.push(window.text);
+      }
+    }
+
+    // Functions also get a vector of their code alone, so find_similar compares bodies only
+    const bodyRecords: Array<Omit<VectorEmbedding, "vector">> = [];
+    const bodyTexts: string[] = [];
+    for (let i = 0; i < entities.length; i++) {
+      const x: any = entities[i];
+      const body = codes[i]?.trim() ?? "";
+      if (!x?.id || !BODY_ENTITY_TYPES.has(x.type) || body.length < MIN_BODY_CHARS) continue;
+      if (body.length > MAX_CODE_CHARS) continue;
+      const record = records[i]!;
+      bodyRecords.push({
+        id: bodyVectorId(x.id),
+        content: body,
+        metadata: {
+          ...record.metadata,
+          kind: BODY_VECTOR_KIND,
+          inputHash: createHash("sha256").update(body).digest("base64url"),
+        },
+        createdAt: record.createdAt,
+      });
+      bodyTexts.push(body);
+    }
+    records.push(...chunkRecords, ...bodyRecords);
+    texts.push(...chunkTexts, ...bodyTexts);
+
+    const paths = Array.from(new Set(records.map((r) => String(r.metadata?.path ?? "")).filter(Boolean)));
+    const stored =
+      typeof this.vectorStore.getMetadataByPaths === "function"
+        ? await this.vectorStore.getMetadataByPaths(paths)
+        : new Map<string, Record<string, any>>();
+    const storedByInput = new Map<string, string>();
+    for (const [id, meta] of stored) {
+      if (meta.inputHash && meta.model === modelName) {
+        storedByInput.set(`${meta.kind ?? ""}|${meta.path}|${meta.inputHash}`, id);
+      }
+    }
+
+    const reused: VectorEmbedding[] = [];
+    const pending: number[] = [];
+    let unchanged = 0;
+    for (let i = 0; i < records.length; i++) {
+      const record = records[i]!;
+      const previous = stored.get(record.id);
+      if (previous && previous.model === modelName && previous.inputHash === record.metadata?.inputHash) {
+        if (previous.root === record.metadata?.root) {
+          unchanged++;
+          continue;
+        }
+        // Same input indexed under another root: keep the vector, rewrite the metadata
+        const vector = (await this.vectorStore.get(record.id))?.vector;
+        if (vector) {
+          reused.push({ ...record, vector });
+          continue;
+        }
+      }
+      const movedFrom = storedByInput.get(
+        `${record.metadata?.kind ?? ""}|${record.metadata?.path}|${record.metadata?.inputHash}`,
+      );
+      const vector = movedFrom ? (await this.vectorStore.get(movedFrom))?.vector : undefined;
+      if (vector) {
+        reused.push({ ...record, vector });
+      } else {
+        pending.push(i);
+      }
+    }
+
+    const pendingTexts = pending.map((i) => texts[i] ?? "");
+    const { embeddings, sources } =
+      pending.length === 0
+        ? { embeddings: [], sources: [] }
+        : typeof this.embeddingGen.generateBatchWithSources === "function"
+          ? await this.embeddingGen.generateBatchWithSources(pendingTexts, { signal })
+          : { embeddings: await this.embeddingGen.generateBatch(pendingTexts), sources: [] };
+    const vectorEmbeddings: VectorEmbedding[] = [
+      ...reused,
+      ...pending.map((index, j) => {
+        const record = records[index]!;
+        const from = sources[j];
+        // A vector from the hashing fallback records its own model, so the next run redoes it
+        const metadata = from
+          ? { ...record.metadata, provider: from.provider, model: from.model, fallback: from.fallback || undefined }
+          : record.metadata;
+        return { ...record, metadata, vector: embeddings[j] ?? new Float32Array(this.embeddingDim) };
+      }),
+    ];
+    if (vectorEmbeddings.length > 0) await this.vectorStore.insertBatch(vectorEmbeddings);
+
+    let removed = 0;
+    if (options.replaceFiles && typeof this.vectorStore.deleteByIds === "function") {
+      const current = new Set(records.map((r) => r.id));
+      removed = await this.vectorStore.deleteByIds(Array.from(stored.keys()).filter((id) => !current.has(id)));
+    }
+
     this.semanticMetrics.embeddingsGenerated += embeddings.length;
     this.semanticMetrics.vectorsStored = await this.vectorStore.count();
-    knowledgeBus.publish("semantic:embeddings:complete", { count: embeddings.length }, this.id);
-    console.log(`[${this.id}] Stored ${embeddings.length} new embeddings`);
+    knowledgeBus.publish(
+      "semantic:embeddings:complete",
+      { count: embeddings.length, unchanged, reused: reused.length, removed },
+      this.id,
+    );
+    console.log(
+      `[${this.id}] Stored ${embeddings.length} new embeddings ` +
+        `(${unchanged} unchanged, ${reused.length} reused, ${removed} orphaned removed)`,
+    );
   }
 
   private async warmupSemanticCache(): Promise<void> {
@@ -1138,6 +1713,13 @@ export class SemanticAgent extends BaseAgent implements SemanticOperations {
     return { ...this.semanticMetrics };
   }
 
+  /**
+   * Embedder answering queries; `fallback` is set while the hashing fallback stands in for it
+   */
+  getEmbeddingSource(): ReturnType<EmbeddingGenerator["getProviderInfo"]> | null {
+    return this.embeddingGen?.getProviderInfo?.() ?? null;
+  }
+
   /**
    * Set query agent for hybrid search
    */
@@ -0,0 +1,168 @@
+/**
+ * Per-repository configuration
+ *
+ * A `.code-graph-rag.json`, `.code-graph-rag.yaml` or `.code-graph-rag.yml` file at a repository
+ * root holds the defaults a team wants for that repository, so agents do not have to repeat them
+ * on every call:
+ *
+ *   index:     excludePatterns, languages, respectGitignore, gitBlame, includeTests, maxFileSizeBytes
+ *   embedding: provider, model
+ *   chunking:  strategy, windowLines, overlapLines
+ *   search:    minScore
+ *
+ * The file of the server's root directory is a configuration layer above config/*.yaml, so its
+ * embedding, chunking and search settings apply to the whole server. `index` reads the file of
+ * the directory it indexes for its own options; arguments of the call win over both. Unknown keys
+ * are errors, with the nearest known key suggested.
+ */
+
+import { existsSync, readFileSync } from "node:fs";
+import { join } from "node:path";
+import { parse as parseYaml } from "yaml";
+import { z } from "zod";
+import { SUPPORTED_LANGUAGES } from "../types/parser.js";
+import type { AppConfig } from "./yaml-config.js";
+
+export const REPO_CONFIG_FILES = [".code-graph-rag.json", ".code-graph-rag.yaml", ".code-graph-rag.yml"];
+
+const RepoConfigSchema = z
+  .object({
+    index: z
+      .object({
+        excludePatterns: z.array(z.string()),
+        languages: z.array(z.enum(SUPPORTED_LANGUAGES)),
+        respectGitignore: z.boolean(),
+        gitBlame: z.boolean(),
+        includeTests: z.boolean(),
+        maxFileSizeBytes: z.number().int().min(0),
+      })
+      .partial()
+      .strict(),
+    embedding: z
+      .object({
+        provider: z.enum(["memory", "transformers", "ollama", "openai", "cloudru", "custom"]),
+        model: z.string().min(1),
+      })
+      .partial()
+      .strict(),
+    chunking: z
+      .object({
+        strategy: z.enum(["entity", "signature", "window"]),
+        windowLines: z.number().int().positive(),
+        overlapLines: z.number().int().min(0),
+      })
+      .partial()
+      .strict(),
+    search: z
+      .object({
+        minScore: z.number().min(-1).max(1),
+      })
+      .partial()
+      .strict(),
+  })
+  .partial()
+  .strict();
+
+export type RepoConfig = z.infer<typeof RepoConfigSchema>;
+
+export type LoadedRepoConfig = {
+  path: string;
+  config: RepoConfig;
+};
+
+export class RepoConfigError extends Error {
+  constructor(
+    readonly path: string,
+    readonly errors: string[],
+  ) {
+    super(`Invalid ${path}: ${errors.join("; ")}`);
+    this.name = "RepoConfigError";
+  }
+}
+
+function editDistance(a: string, b: string): number {
+  let previous = Array.from({ length: b.length + 1 }, (_, i) => i);
+  for (let i = 1; i <= a.length; i++) {
+    const current = [i];
+    for (let j = 1; j <= b.length; j++) {
+      const substitution = previous[j - 1]! + (a[i - 1]!.toLowerCase() === b[j - 1]!.toLowerCase() ? 0 : 1);
+      current.push(Math.min(previous[j]! + 1, current[j - 1]! + 1, substitution));
+    }
+    previous = current;
+  }
+  return previous[b.length]!;
+}
+
+/** Keys allowed at `path` of the file, for suggestions */
+function knownKeys(path: Array<string | number>): string[] {
+  let schema: z.ZodTypeAny = RepoConfigSchema;
+  for (const key of path) {
+    while (schema instanceof z.ZodOptional) schema = schema.unwrap();
+    if (!(schema instanceof z.ZodObject)) return [];
+    schema = schema.shape[key];
+  }
+  while (schema instanceof z.ZodOptional) schema = schema.unwrap();
+  return schema instanceof z.ZodObject ? Object.keys(schema.shape) : [];
+}
+
+function describeIssue(issue: z.ZodIssue): string[] {
+  const at = issue.path.join(".");
+  if (issue.code !== "unrecognized_keys") return [`${at || "(root)"}: ${issue.message}`];
+  const known = knownKeys(issue.path);
+  return issue.keys.map((key) => {
+    const nearest = known
+      .map((candidate) => ({ candidate, distance: editDistance(key, candidate) }))
+      .sort((a, b) => a.distance - b.distance)[0];
+    const hint = nearest && nearest.distance <= 3 ? `; did you mean "${nearest.candidate}"?` : "";
+    const where = at ? `${at}.${key}` : key;
+    return `${where}: unknown key (expected one of ${known.join(", ")})${hint}`;
+  });
+}
+
+/**
+ * The repository configuration file of `directory`, validated; null when it has none. Throws a
+ * RepoConfigError listing every problem when the file cannot be parsed or does not validate.
+ */
+export function loadRepoConfig(directory: string): LoadedRepoConfig | null {
+  const present = REPO_CONFIG_FILES.map((name) => join(directory, name)).filter((path) => existsSync(path));
+  if (present.length === 0) return null;
+  const path = present[0]!;
+  if (present.length > 1) {
+    throw new RepoConfigError(path, [`found ${present.join(" and ")}; keep only one`]);
+  }
+
+  let raw: unknown;
+  try {
+    const text = readFileSync(path, "utf8");
+    raw = path.endsWith(".json") ? JSON.parse(text) : parseYaml(text);
+  } catch (error) {
+    throw new RepoConfigError(path, [error instanceof Error ? error.message : String(error)]);
+  }
+  // An empty YAML file parses as null and sets nothing
+  const parsed = RepoConfigSchema.safeParse(raw ?? {});
+  if (!parsed.success) throw new RepoConfigError(path, parsed.error.issues.flatMap(describeIssue));
+  return { path, config: parsed.data };
+}
+
+/**
+ * `yamlConfig` with the server-wide settings of a repository file laid over it: embedding
+ * provider and model, chunking, the default search threshold and the indexer defaults
+ */
+export function withRepoConfig(yamlConfig: Partial<AppConfig>, repo: RepoConfig): Partial<AppConfig> {
+  const embedding = { ...yamlConfig.mcp?.embedding, ...repo.embedding };
+  const semantic = {
+    ...yamlConfig.mcp?.semantic,
+    ...(repo.chunking?.strategy !== undefined ? { chunkStrategy: repo.chunking.strategy } : {}),
+    ...(repo.chunking?.windowLines !== undefined ? { chunkWindowLines: repo.chunking.windowLines } : {}),
+    ...(repo.chunking?.overlapLines !== undefined ? { chunkOverlapLines: repo.chunking.overlapLines } : {}),
+    ...(repo.search?.minScore !== undefined ? { minScore: repo.search.minScore } : {}),
+  };
+  const { gitBlame, includeTests, maxFileSizeBytes } = repo.index ?? {};
+  const indexer = {
+    ...yamlConfig.indexer,
+    ...(gitBlame !== undefined ? { gitBlame } : {}),
+    ...(includeTests !== undefined ? { includeTests } : {}),
+    ...(maxFileSizeBytes !== undefined ? { maxFileSizeBytes } : {}),
+  };
+  return { ...yamlConfig, mcp: { ...yamlConfig.mcp, embedding, semantic }, indexer };
+}
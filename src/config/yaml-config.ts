@@ -13,6 +13,11 @@ import { existsSync, readFileSync } from "node:fs";
 import { homedir } from "node:os";
 import { join, resolve } from "node:path";
 import { parse as parseYaml } from "yaml";
+import type { EmbeddingInputPreset } from "../semantic/embedding-input.js";
+import { SUPPORTED_LANGUAGES } from "../types/parser.js";
+import type { SymlinkPolicy } from "../utils/index-file-collection.js";
+import { parseModuleLevels } from "../utils/structured-log.js";
+import { type LoadedRepoConfig, loadRepoConfig, RepoConfigError, withRepoConfig } from "./repo-config.js";
 
 // =============================================================================
 // 1. CONFIGURATION INTERFACES
@@ -21,10 +26,25 @@ import { parse as parseYaml } from "yaml";
 export interface MCPConfig {
   embedding?: {
     model?: string;
-    provider?: "memory" | "transformers" | "ollama" | "openai" | "cloudru";
+    provider?: "memory" | "transformers" | "ollama" | "openai" | "cloudru" | "custom";
     apiKey?: string;
     enabled?: boolean;
     fallbackToMemory?: boolean;
+    /** Further attempts after a failed provider initialization, with exponential backoff */
+    initRetries?: number;
+    initBackoffMs?: number;
+    /** Shared limits on requests to hosted providers; a 429 pauses all of them regardless */
+    rateLimit?: { maxConcurrency?: number; requestsPerSecond?: number; burst?: number };
+    /** Texts per provider request: fixed, or adapted to observed latency within provider limits */
+    batching?: {
+      size?: number | "auto";
+      min?: number;
+      max?: number;
+      targetLatencyMs?: number;
+      maxTokens?: number;
+    };
+    /** After a provider or model switch changes the dimension: drop and re-embed, or keep and refuse */
+    onDimensionChange?: "reembed" | "refuse";
 
     // NEW:
     ollama?: {
@@ -45,6 +65,8 @@ export interface MCPConfig {
       timeoutMs?: number;
       concurrency?: number;
       maxBatchSize?: number;
+      dimensions?: number;
+      maxRetries?: number;
     };
     cloudru?: {
       baseUrl?: string;
@@ -56,6 +78,7 @@ export interface MCPConfig {
     };
     transformers?: { quantized?: boolean; localPath?: string };
     memory?: { dimension?: number };
+    custom?: { module?: string; options?: Record<string, unknown> };
   };
   server?: { host?: string; port?: number; timeout?: number };
   agents?: {
@@ -67,6 +90,50 @@ export interface MCPConfig {
   semantic?: {
     cacheWarmupLimit?: number;
     popularEntitiesTopic?: string;
+    /** Run the warmup tool's steps once the client connects, before any query asks for them */
+    warmupOnStartup?: boolean; // MCP_WARMUP
+    /** What gets embedded per declaration; see semantic/chunking.ts */
+    chunkStrategy?: "entity" | "signature" | "window"; // MCP_CHUNK_STRATEGY
+    chunkWindowLines?: number; // MCP_CHUNK_WINDOW_LINES
+    chunkOverlapLines?: number; // MCP_CHUNK_OVERLAP_LINES
+    /** semantic_search's minScore when a call does not pass one */
+    minScore?: number; // MCP_SEMANTIC_MIN_SCORE
+    /** Fields of a declaration that make up its embedded text; see semantic/embedding-input.ts */
+    embeddingInput?: {
+      preset?: EmbeddingInputPreset; // MCP_EMBEDDING_INPUT
+      /** Overrides the preset: field names, or `{ field, weight }` to repeat one up to 3 times */
+      fields?: Array<string | { field: string; weight?: number }>;
+    };
+    /** HNSW index for semantic search on large stores */
+    ann?: {
+      enabled?: boolean; // MCP_ANN_ENABLED
+      minVectors?: number;
+      m?: number;
+      efConstruction?: number;
+      efSearch?: number; // MCP_ANN_EF_SEARCH
+      compactRatio?: number;
+    };
+  };
+  /** Results of semantic_search and query, kept until the index changes; 0 in either cap disables it */
+  resultCache?: {
+    maxEntries?: number; // MCP_RESULT_CACHE_ENTRIES
+    maxBytes?: number; // MCP_RESULT_CACHE_BYTES
+  };
+  /** Tool call deadlines in ms; 0 disables one */
+  timeouts?: {
+    defaultMs?: number; // MCP_TOOL_TIMEOUT_MS
+    indexMs?: number; // MCP_INDEX_TIMEOUT_MS; unset falls back to agents.defaultTimeout
+    searchMs?: number; // MCP_SEARCH_TIMEOUT_MS
+    /** How long SIGINT/SIGTERM waits for running work to stop before closing the database */
+    shutdownMs?: number; // MCP_SHUTDOWN_TIMEOUT_MS
+    /** Per tool name, overriding the above */
+    tools?: Record<string, number>;
+  };
+  /** Index runs and other writes wait here for the one writer instead of failing */
+  writeQueue?: {
+    maxQueued?: number; // MCP_WRITE_QUEUE_MAX; calls past this get agent_busy
+    /** Also wait while another server process on the same database is indexing */
+    waitForOtherServers?: boolean; // MCP_WRITE_QUEUE_WAIT_OTHER_SERVERS
   };
 }
 
@@ -95,6 +162,8 @@ export interface EmbeddingConfigResolved {
     timeoutMs?: number;
     concurrency?: number;
     maxBatchSize?: number;
+    dimensions?: number;
+    maxRetries?: number;
   };
   cloudru?: {
     baseUrl?: string;
@@ -106,6 +175,7 @@ export interface EmbeddingConfigResolved {
   };
   transformers?: { quantized?: boolean; localPath?: string };
   memory?: { dimension?: number };
+  custom?: { module?: string; options?: Record<string, unknown> };
 }
 
 export interface DatabaseConfig {
@@ -115,6 +185,8 @@ export interface DatabaseConfig {
   mmapSize?: number;
   synchronous?: "OFF" | "NORMAL" | "FULL";
   tempStore?: "DEFAULT" | "FILE" | "MEMORY";
+  /** Wait for another connection's write lock this long before failing (DATABASE_BUSY_TIMEOUT_MS) */
+  busyTimeoutMs?: number;
 }
 
 export interface LoggingConfig {
@@ -124,6 +196,8 @@ export interface LoggingConfig {
   maxFileSize?: string;
   maxFiles?: number;
   enableConsole?: boolean;
+  /** Levels for single modules (parser, embedding, graph, indexer, orchestrator, server) */
+  modules?: Record<string, "debug" | "info" | "warn" | "error">;
 }
 
 export interface ParserConfig {
@@ -141,8 +215,51 @@ export interface ParserConfig {
     priority?: number;
     batchSize?: number;
     cacheSize?: number;
+    /** Parse worker threads; 0 or unset means one per CPU */
     workerPoolSize?: number;
   };
+  /** Build constraints applied to Go files (file name suffixes and //go:build lines) */
+  go?: {
+    /** `target` skips files the target would not build; `all` indexes every variant, tagged */
+    buildMode?: "target" | "all";
+    /** Defaults to the host platform */
+    goos?: string;
+    goarch?: string;
+    /** Extra build tags treated as set */
+    tags?: string[];
+    cgo?: boolean;
+    /** Index `_test.go` files (default true) */
+    includeTests?: boolean;
+  };
+  /** Extra tree-sitter queries per language, run after the built-in extraction */
+  customQueries?: Record<string, CustomQuerySpec[]>;
+  /** Entity kinds kept per language (`*` for every language); everything is kept when unset */
+  entityKinds?: Record<string, EntityKindRule>;
+  /** Standalone comments as `comment` entities, with TODO/FIXME/HACK markers for `list_todos` */
+  comments?: {
+    /** Extract comments at all (default true) */
+    enabled?: boolean;
+    /** Also embed them for semantic search; off by default, as comments can outnumber declarations */
+    embed?: boolean;
+  };
+}
+
+/**
+ * A user tree-sitter query. With `kind`, each match becomes an entity of that type; with `attribute`,
+ * the match is recorded under `metadata.attributes[attribute]` of the declaration it belongs to.
+ */
+export interface CustomQuerySpec {
+  name: string;
+  /** S-expression query; `@name` and `@value` captures pick the entity name and attribute value */
+  query: string;
+  kind?: string;
+  attribute?: string;
+}
+
+/** With `include`, only those kinds are kept; `exclude` then drops kinds from what is left */
+export interface EntityKindRule {
+  include?: string[];
+  exclude?: string[];
 }
 
 export interface IndexerConfig {
@@ -152,6 +269,31 @@ export interface IndexerConfig {
   batchSize?: number;
   cacheSize?: number;
   cacheTTL?: number;
+  /** Files larger than this are skipped during indexing (0 disables the limit) */
+  maxFileSizeBytes?: number;
+  /** Files whose parse runs longer than this are skipped during indexing (0 disables the timeout) */
+  parseTimeoutMs?: number;
+  /** Skip files carrying a generator header, named like generator output, or minified */
+  skipGeneratedFiles?: boolean;
+  /** Trade indexing speed for a lower peak heap on very large repositories */
+  lowMemory?: boolean;
+  /** Declarations the cross-file resolver keeps in memory before falling back to SQLite */
+  symbolCacheSize?: number;
+  /** Walk into symlinked directories; each real directory is still walked once */
+  followSymlinks?: boolean;
+  /** Index symlinks that point outside the indexed directory */
+  allowSymlinksOutsideRoot?: boolean;
+  /** Record each entity's last-modifying commit and author from `git blame` (slow on large histories) */
+  gitBlame?: boolean;
+  /** Where `index(repository)` keeps its shallow clones, one directory per repository URL */
+  repoCacheDir?: string;
+  /** Index files with unknown extensions whose shebang, modeline or tokens name a supported language */
+  detectLanguageByContent?: boolean;
+  /**
+   * Keep test files in the main graph, where search and impact analysis return them when a tool
+   * call does not say; false stores them apart (storage/tests-view.ts)
+   */
+  includeTests?: boolean;
 }
 
 export interface AgentRuntimeConfig {
@@ -219,6 +361,15 @@ function expandTildePath(input: string | undefined): string | undefined {
   return input;
 }
 
+/** `MCP_EMBEDDING_BATCH_SIZE`: a number of texts or "auto"; anything else is ignored */
+function batchSizeFromEnv(value: string | undefined): number | "auto" | undefined {
+  const trimmed = value?.trim().toLowerCase();
+  if (!trimmed) return undefined;
+  if (trimmed === "auto") return "auto";
+  const n = Number(trimmed);
+  return Number.isFinite(n) && n >= 1 ? Math.floor(n) : undefined;
+}
+
 function selectDatabasePath(
   yamlPath: string | undefined,
   envPath: string | undefined,
@@ -231,6 +382,20 @@ function selectDatabasePath(
   return yamlPath || envPath || defaultPath;
 }
 
+/** Symbol table ceiling applied in low-memory mode, whatever the configured size */
+const LOW_MEMORY_SYMBOL_CACHE_SIZE = 5000;
+
+/** Tools governed by `mcp.timeouts.indexMs` and `searchMs` rather than the default */
+export const INDEXING_TOOLS: ReadonlySet<string> = new Set([
+  "index",
+  "clean_index",
+  "update_index",
+  "reindex_file",
+  "batch_index",
+  "reembed",
+]);
+const SEARCH_TOOLS: ReadonlySet<string> = new Set(["semantic_search"]);
+
 const DEFAULT_CONFIG: AppConfig = {
   mcp: {
     embedding: {
@@ -238,6 +403,11 @@ const DEFAULT_CONFIG: AppConfig = {
       provider: "memory",
       enabled: false,
       fallbackToMemory: true,
+      initRetries: 2,
+      initBackoffMs: 1000,
+      rateLimit: { maxConcurrency: 0, requestsPerSecond: 0 },
+      batching: { size: "auto" },
+      onDimensionChange: "reembed",
     },
     server: {
       host: "localhost",
@@ -254,6 +424,34 @@ const DEFAULT_CONFIG: AppConfig = {
     semantic: {
       cacheWarmupLimit: 50,
       popularEntitiesTopic: "semantic:warmup:entities",
+      warmupOnStartup: false,
+      chunkStrategy: "entity",
+      chunkWindowLines: 60,
+      chunkOverlapLines: 15,
+      minScore: 0.2,
+      embeddingInput: { preset: "default" },
+      ann: {
+        enabled: true,
+        minVectors: 10000,
+        m: 16,
+        efConstruction: 100,
+        efSearch: 64,
+        compactRatio: 0.5,
+      },
+    },
+    resultCache: {
+      maxEntries: 500,
+      maxBytes: 32 * 1024 * 1024,
+    },
+    timeouts: {
+      defaultMs: 300000,
+      searchMs: 60000,
+      shutdownMs: 15000,
+      tools: {},
+    },
+    writeQueue: {
+      maxQueued: 16,
+      waitForOtherServers: true,
     },
   },
   database: {
@@ -265,6 +463,7 @@ const DEFAULT_CONFIG: AppConfig = {
     mmapSize: 268435456, // 256MB
     synchronous: "NORMAL",
     tempStore: "MEMORY",
+    busyTimeoutMs: 10000,
   },
   logging: {
     level: "info",
@@ -287,6 +486,10 @@ const DEFAULT_CONFIG: AppConfig = {
         "rust",
         "go",
         "kotlin",
+        "ruby",
+        "php",
+        "swift",
+        "scala",
         "vba",
       ],
       maxFileSize: 1048576, // 1MB
@@ -304,7 +507,17 @@ const DEFAULT_CONFIG: AppConfig = {
       priority: 8,
       batchSize: 10,
       cacheSize: 104857600, // 100MB
-      workerPoolSize: 2,
+      workerPoolSize: 0, // 0 = one parse worker per CPU
+    },
+    go: {
+      buildMode: "target",
+      tags: [],
+      cgo: true,
+      includeTests: true,
+    },
+    comments: {
+      enabled: true,
+      embed: false,
     },
   },
   indexer: {
@@ -314,6 +527,17 @@ const DEFAULT_CONFIG: AppConfig = {
     batchSize: 1000,
     cacheSize: 52428800, // 50MB
     cacheTTL: 300000, // 5 minutes
+    maxFileSizeBytes: 1048576, // 1MB
+    parseTimeoutMs: 60000,
+    skipGeneratedFiles: true,
+    lowMemory: false,
+    symbolCacheSize: 50000,
+    followSymlinks: false,
+    allowSymlinksOutsideRoot: false,
+    gitBlame: false,
+    repoCacheDir: "~/.code-graph-rag/repos",
+    detectLanguageByContent: false,
+    includeTests: true,
   },
   devAgent: {
     maxConcurrency: 3,
@@ -379,8 +603,11 @@ const DEFAULT_CONFIG: AppConfig = {
 export class ConfigLoader {
   private static instance: ConfigLoader;
   private static overridePath: string | undefined;
+  private static repoRoot: string | undefined;
   private config: AppConfig;
   private configPath: string;
+  private repoConfig: LoadedRepoConfig | null = null;
+  private repoConfigErrors: string[] = [];
 
   private constructor() {
     this.configPath = this.resolveConfigPath();
@@ -405,6 +632,12 @@ export class ConfigLoader {
     }
   }
 
+  /** Take the `.code-graph-rag.*` file of this directory as a layer above the YAML config */
+  public static setRepoRoot(directory?: string): void {
+    ConfigLoader.repoRoot = directory ? resolve(directory) : undefined;
+    ConfigLoader.instance?.reload();
+  }
+
   /**
    * Get current configuration
    */
@@ -438,6 +671,20 @@ export class ConfigLoader {
     return this.config.parser;
   }
 
+  /**
+   * Get the repository configuration file of the server's root directory, if it has a valid one
+   */
+  public getRepoConfig(): LoadedRepoConfig | null {
+    return this.repoConfig;
+  }
+
+  /**
+   * Get the problems found in the root directory's repository configuration file
+   */
+  public getRepoConfigErrors(): string[] {
+    return this.repoConfigErrors;
+  }
+
   /**
    * Get agent-specific configuration
    */
@@ -459,6 +706,137 @@ export class ConfigLoader {
     return this.config.mcp.agents?.devIndexBatch ?? 100;
   }
 
+  /**
+   * Get the size above which files are skipped during indexing (0 means no limit)
+   */
+  public getIndexMaxFileSizeBytes(): number {
+    const value = this.config.indexer?.maxFileSizeBytes;
+    return typeof value === "number" && Number.isFinite(value) && value >= 0
+      ? value
+      : (DEFAULT_CONFIG.indexer.maxFileSizeBytes ?? 0);
+  }
+
+  /**
+   * Get how long one file may take to parse before indexing skips it (0 means no timeout)
+   */
+  public getParseTimeoutMs(): number {
+    const value = this.config.indexer?.parseTimeoutMs;
+    return typeof value === "number" && Number.isFinite(value) && value >= 0
+      ? value
+      : (DEFAULT_CONFIG.indexer.parseTimeoutMs ?? 0);
+  }
+
+  /**
+   * Check if indexing leaves out generated and minified files
+   */
+  public isGeneratedFileSkippingEnabled(): boolean {
+    return this.config.indexer?.skipGeneratedFiles !== false;
+  }
+
+  /**
+   * How file discovery treats symlinks: directory links are followed only when enabled, and links
+   * leaving the indexed directory are skipped unless allowed
+   */
+  public getSymlinkPolicy(): SymlinkPolicy {
+    return {
+      followSymlinks: this.config.indexer?.followSymlinks === true,
+      allowOutsideRoot: this.config.indexer?.allowSymlinksOutsideRoot === true,
+    };
+  }
+
+  /**
+   * Get the directory holding clones of repositories indexed by URL, with `~` expanded
+   */
+  public getRepoCacheDir(): string {
+    const dir = this.config.indexer?.repoCacheDir || DEFAULT_CONFIG.indexer.repoCacheDir || "~/.code-graph-rag/repos";
+    return resolve(expandTildePath(dir) ?? dir);
+  }
+
+  /**
+   * Check if index runs attach `git blame` ownership to entities when a tool call does not say
+   */
+  public isGitBlameEnabled(): boolean {
+    return this.config.indexer?.gitBlame === true;
+  }
+
+  /**
+   * Check if file discovery falls back to a file's content when its extension is not recognised
+   */
+  public isContentLanguageDetectionEnabled(): boolean {
+    return this.config.indexer?.detectLanguageByContent === true;
+  }
+
+  /**
+   * Check if search and impact analysis cover test files when a tool call does not say
+   */
+  public areTestsIncludedByDefault(): boolean {
+    return this.config.indexer?.includeTests !== false;
+  }
+
+  /**
+   * Check if indexing should favour a small memory footprint over throughput
+   */
+  public isLowMemoryIndexing(): boolean {
+    return this.config.indexer?.lowMemory === true;
+  }
+
+  /**
+   * Get how many declarations the cross-file resolver may hold in memory
+   */
+  public getSymbolCacheSize(): number {
+    const value = this.config.indexer?.symbolCacheSize;
+    const size =
+      typeof value === "number" && Number.isFinite(value) && value >= 0
+        ? value
+        : (DEFAULT_CONFIG.indexer.symbolCacheSize ?? 0);
+    return this.isLowMemoryIndexing() ? Math.min(size, LOW_MEMORY_SYMBOL_CACHE_SIZE) : size;
+  }
+
+  /** How long a graceful shutdown waits for running work; never less than a second */
+  public getShutdownTimeoutMs(): number {
+    const value = this.config.mcp.timeouts?.shutdownMs ?? DEFAULT_CONFIG.mcp.timeouts?.shutdownMs;
+    return typeof value === "number" && Number.isFinite(value) ? Math.max(1000, value) : 15000;
+  }
+
+  /** How many writes may wait behind the running one; at least 1 */
+  public getWriteQueueLimit(): number {
+    const value = this.config.mcp.writeQueue?.maxQueued ?? DEFAULT_CONFIG.mcp.writeQueue?.maxQueued;
+    return typeof value === "number" && Number.isFinite(value) ? Math.max(1, Math.floor(value)) : 16;
+  }
+
+  /**
+   * Check if writes wait for index runs of other server processes sharing the database
+   */
+  public isWaitingForOtherServers(): boolean {
+    return this.config.mcp.writeQueue?.waitForOtherServers !== false;
+  }
+
+  /**
+   * Get the deadline for a tool call in ms (0 = none): a per-tool setting first, then the indexing
+   * or search timeout for those tools, then the default
+   */
+  public getToolTimeoutMs(tool: string): number {
+    const timeouts = this.config.mcp.timeouts ?? {};
+    const own = timeouts.tools?.[tool];
+    const byKind = INDEXING_TOOLS.has(tool)
+      ? (timeouts.indexMs ?? this.config.mcp.agents?.defaultTimeout ?? this.config.mcp.server?.timeout)
+      : SEARCH_TOOLS.has(tool)
+        ? timeouts.searchMs
+        : undefined;
+    const value = own ?? byKind ?? timeouts.defaultMs ?? DEFAULT_CONFIG.mcp.timeouts?.defaultMs;
+    return typeof value === "number" && Number.isFinite(value) && value >= 0 ? value : 0;
+  }
+
+  /**
+   * Get semantic_search's minScore for calls that do not pass one, within [-1, 1]
+   */
+  public getSearchMinScore(): number {
+    const value = this.config.mcp.semantic?.minScore;
+    return typeof value === "number" && Number.isFinite(value) && value >= -1 && value <= 1
+      ? value
+      : (DEFAULT_CONFIG.mcp.semantic?.minScore ?? 0.2);
+  }
+
   /**
    * Check if embedding model is available
    */
@@ -482,6 +860,7 @@ export class ConfigLoader {
       cloudru: embeddingConfig.cloudru || undefined,
       transformers: embeddingConfig.transformers || undefined,
       memory: embeddingConfig.memory || undefined,
+      custom: embeddingConfig.custom || undefined,
     };
   }
 
@@ -540,6 +919,21 @@ export class ConfigLoader {
       console.log(`[Config] No YAML config found, using environment variables and defaults`);
     }
 
+    this.repoConfig = null;
+    this.repoConfigErrors = [];
+    if (ConfigLoader.repoRoot) {
+      try {
+        this.repoConfig = loadRepoConfig(ConfigLoader.repoRoot);
+      } catch (error) {
+        if (!(error instanceof RepoConfigError)) throw error;
+        this.repoConfigErrors = error.errors.map((message) => `${error.path}: ${message}`);
+      }
+      if (this.repoConfig) {
+        yamlConfig = withRepoConfig(yamlConfig, this.repoConfig.config);
+        console.log(`[Config] Loaded repository configuration from: ${this.repoConfig.path}`);
+      }
+    }
+
     // Merge with defaults and environment variables
     return this.mergeWithEnvironment(yamlConfig);
   }
@@ -555,7 +949,7 @@ export class ConfigLoader {
             yamlConfig.mcp?.embedding?.model || process.env.MCP_EMBEDDING_MODEL || DEFAULT_CONFIG.mcp.embedding?.model,
           provider: (yamlConfig.mcp?.embedding?.provider ||
             process.env.MCP_EMBEDDING_PROVIDER ||
-            DEFAULT_CONFIG.mcp.embedding?.provider) as "memory" | "transformers" | "ollama" | "openai" | "cloudru",
+            DEFAULT_CONFIG.mcp.embedding?.provider) as NonNullable<MCPConfig["embedding"]>["provider"],
           apiKey:
             yamlConfig.mcp?.embedding?.apiKey ||
             process.env.MCP_EMBEDDING_API_KEY ||
@@ -568,6 +962,55 @@ export class ConfigLoader {
             yamlConfig.mcp?.embedding?.fallbackToMemory !== undefined
               ? yamlConfig.mcp?.embedding?.fallbackToMemory
               : process.env.MCP_EMBEDDING_FALLBACK !== "false" || DEFAULT_CONFIG.mcp.embedding?.fallbackToMemory,
+          initRetries:
+            yamlConfig.mcp?.embedding?.initRetries ??
+            (process.env.MCP_EMBEDDING_INIT_RETRIES !== undefined
+              ? Number(process.env.MCP_EMBEDDING_INIT_RETRIES)
+              : DEFAULT_CONFIG.mcp.embedding?.initRetries),
+          initBackoffMs:
+            yamlConfig.mcp?.embedding?.initBackoffMs ??
+            (process.env.MCP_EMBEDDING_INIT_BACKOFF_MS !== undefined
+              ? Number(process.env.MCP_EMBEDDING_INIT_BACKOFF_MS)
+              : DEFAULT_CONFIG.mcp.embedding?.initBackoffMs),
+          rateLimit: {
+            maxConcurrency:
+              yamlConfig.mcp?.embedding?.rateLimit?.maxConcurrency ??
+              (process.env.MCP_EMBEDDING_MAX_CONCURRENCY !== undefined
+                ? Number(process.env.MCP_EMBEDDING_MAX_CONCURRENCY)
+                : DEFAULT_CONFIG.mcp.embedding?.rateLimit?.maxConcurrency),
+            requestsPerSecond:
+              yamlConfig.mcp?.embedding?.rateLimit?.requestsPerSecond ??
+              (process.env.MCP_EMBEDDING_RPS !== undefined
+                ? Number(process.env.MCP_EMBEDDING_RPS)
+                : DEFAULT_CONFIG.mcp.embedding?.rateLimit?.requestsPerSecond),
+            burst:
+              yamlConfig.mcp?.embedding?.rateLimit?.burst ??
+              (process.env.MCP_EMBEDDING_BURST !== undefined ? Number(process.env.MCP_EMBEDDING_BURST) : undefined),
+          },
+          batching: {
+            size:
+              yamlConfig.mcp?.embedding?.batching?.size ??
+              batchSizeFromEnv(process.env.MCP_EMBEDDING_BATCH_SIZE) ??
+              DEFAULT_CONFIG.mcp.embedding?.batching?.size,
+            min: yamlConfig.mcp?.embedding?.batching?.min,
+            max:
+              yamlConfig.mcp?.embedding?.batching?.max ??
+              (process.env.MCP_EMBEDDING_BATCH_MAX ? Number(process.env.MCP_EMBEDDING_BATCH_MAX) : undefined),
+            targetLatencyMs:
+              yamlConfig.mcp?.embedding?.batching?.targetLatencyMs ??
+              (process.env.MCP_EMBEDDING_BATCH_TARGET_MS
+                ? Number(process.env.MCP_EMBEDDING_BATCH_TARGET_MS)
+                : undefined),
+            maxTokens:
+              yamlConfig.mcp?.embedding?.batching?.maxTokens ??
+              (process.env.MCP_EMBEDDING_BATCH_MAX_TOKENS
+                ? Number(process.env.MCP_EMBEDDING_BATCH_MAX_TOKENS)
+                : undefined),
+          },
+          onDimensionChange:
+            yamlConfig.mcp?.embedding?.onDimensionChange ??
+            (process.env.MCP_EMBEDDING_ON_DIMENSION_CHANGE === "refuse" ? "refuse" : undefined) ??
+            DEFAULT_CONFIG.mcp.embedding?.onDimensionChange,
           ollama: yamlConfig.mcp?.embedding?.ollama || {
             baseUrl: process.env.OLLAMA_BASE_URL || undefined,
             timeout: Number(process.env.OLLAMA_TIMEOUT_MS) || undefined,
@@ -586,6 +1029,8 @@ export class ConfigLoader {
             timeoutMs: Number(process.env.OPENAI_TIMEOUT_MS) || undefined,
             concurrency: Number(process.env.OPENAI_CONCURRENCY) || undefined,
             maxBatchSize: Number(process.env.OPENAI_MAX_BATCH_SIZE) || undefined,
+            dimensions: Number(process.env.OPENAI_EMBEDDING_DIMENSIONS) || undefined,
+            maxRetries: Number(process.env.OPENAI_MAX_RETRIES) || undefined,
           },
           cloudru: yamlConfig.mcp?.embedding?.cloudru || {
             baseUrl: process.env.CLOUDRU_BASE_URL || undefined,
@@ -607,6 +1052,9 @@ export class ConfigLoader {
             dimension:
               yamlConfig.mcp?.embedding?.memory?.dimension || Number(process.env.MEMORY_EMBED_DIM) || undefined,
           },
+          custom:
+            yamlConfig.mcp?.embedding?.custom ||
+            (process.env.MCP_EMBEDDING_MODULE ? { module: process.env.MCP_EMBEDDING_MODULE } : undefined),
         },
         server: {
           host: yamlConfig.mcp?.server?.host || process.env.MCP_SERVER_HOST || DEFAULT_CONFIG.mcp.server?.host,
@@ -645,6 +1093,96 @@ export class ConfigLoader {
             yamlConfig.mcp?.semantic?.popularEntitiesTopic ||
             process.env.MCP_SEMANTIC_WARMUP_TOPIC ||
             DEFAULT_CONFIG.mcp.semantic?.popularEntitiesTopic,
+          warmupOnStartup:
+            yamlConfig.mcp?.semantic?.warmupOnStartup ??
+            (process.env.MCP_WARMUP !== undefined
+              ? process.env.MCP_WARMUP === "1" || process.env.MCP_WARMUP === "true"
+              : DEFAULT_CONFIG.mcp.semantic?.warmupOnStartup),
+          chunkStrategy:
+            yamlConfig.mcp?.semantic?.chunkStrategy ??
+            (process.env.MCP_CHUNK_STRATEGY as "entity" | "signature" | "window" | undefined) ??
+            DEFAULT_CONFIG.mcp.semantic?.chunkStrategy,
+          chunkWindowLines:
+            yamlConfig.mcp?.semantic?.chunkWindowLines ??
+            (process.env.MCP_CHUNK_WINDOW_LINES !== undefined
+              ? Number(process.env.MCP_CHUNK_WINDOW_LINES)
+              : DEFAULT_CONFIG.mcp.semantic?.chunkWindowLines),
+          chunkOverlapLines:
+            yamlConfig.mcp?.semantic?.chunkOverlapLines ??
+            (process.env.MCP_CHUNK_OVERLAP_LINES !== undefined
+              ? Number(process.env.MCP_CHUNK_OVERLAP_LINES)
+              : DEFAULT_CONFIG.mcp.semantic?.chunkOverlapLines),
+          minScore:
+            yamlConfig.mcp?.semantic?.minScore ??
+            (process.env.MCP_SEMANTIC_MIN_SCORE !== undefined
+              ? Number(process.env.MCP_SEMANTIC_MIN_SCORE)
+              : DEFAULT_CONFIG.mcp.semantic?.minScore),
+          embeddingInput: {
+            ...yamlConfig.mcp?.semantic?.embeddingInput,
+            preset:
+              yamlConfig.mcp?.semantic?.embeddingInput?.preset ??
+              (process.env.MCP_EMBEDDING_INPUT as EmbeddingInputPreset | undefined) ??
+              DEFAULT_CONFIG.mcp.semantic?.embeddingInput?.preset,
+          },
+          ann: {
+            ...DEFAULT_CONFIG.mcp.semantic?.ann,
+            ...yamlConfig.mcp?.semantic?.ann,
+            enabled:
+              yamlConfig.mcp?.semantic?.ann?.enabled ??
+              (process.env.MCP_ANN_ENABLED !== undefined
+                ? process.env.MCP_ANN_ENABLED !== "0"
+                : DEFAULT_CONFIG.mcp.semantic?.ann?.enabled),
+            efSearch:
+              yamlConfig.mcp?.semantic?.ann?.efSearch ??
+              (process.env.MCP_ANN_EF_SEARCH !== undefined
+                ? Number(process.env.MCP_ANN_EF_SEARCH)
+                : DEFAULT_CONFIG.mcp.semantic?.ann?.efSearch),
+          },
+        },
+        resultCache: {
+          maxEntries:
+            yamlConfig.mcp?.resultCache?.maxEntries ??
+            (process.env.MCP_RESULT_CACHE_ENTRIES !== undefined
+              ? Number(process.env.MCP_RESULT_CACHE_ENTRIES)
+              : DEFAULT_CONFIG.mcp.resultCache?.maxEntries),
+          maxBytes:
+            yamlConfig.mcp?.resultCache?.maxBytes ??
+            (process.env.MCP_RESULT_CACHE_BYTES !== undefined
+              ? Number(process.env.MCP_RESULT_CACHE_BYTES)
+              : DEFAULT_CONFIG.mcp.resultCache?.maxBytes),
+        },
+        timeouts: {
+          defaultMs:
+            yamlConfig.mcp?.timeouts?.defaultMs ??
+            (process.env.MCP_TOOL_TIMEOUT_MS !== undefined
+              ? Number(process.env.MCP_TOOL_TIMEOUT_MS)
+              : DEFAULT_CONFIG.mcp.timeouts?.defaultMs),
+          indexMs:
+            yamlConfig.mcp?.timeouts?.indexMs ??
+            (process.env.MCP_INDEX_TIMEOUT_MS !== undefined ? Number(process.env.MCP_INDEX_TIMEOUT_MS) : undefined),
+          searchMs:
+            yamlConfig.mcp?.timeouts?.searchMs ??
+            (process.env.MCP_SEARCH_TIMEOUT_MS !== undefined
+              ? Number(process.env.MCP_SEARCH_TIMEOUT_MS)
+              : DEFAULT_CONFIG.mcp.timeouts?.searchMs),
+          shutdownMs:
+            yamlConfig.mcp?.timeouts?.shutdownMs ??
+            (process.env.MCP_SHUTDOWN_TIMEOUT_MS !== undefined
+              ? Number(process.env.MCP_SHUTDOWN_TIMEOUT_MS)
+              : DEFAULT_CONFIG.mcp.timeouts?.shutdownMs),
+          tools: { ...DEFAULT_CONFIG.mcp.timeouts?.tools, ...yamlConfig.mcp?.timeouts?.tools },
+        },
+        writeQueue: {
+          maxQueued:
+            yamlConfig.mcp?.writeQueue?.maxQueued ??
+            (process.env.MCP_WRITE_QUEUE_MAX !== undefined
+              ? Number(process.env.MCP_WRITE_QUEUE_MAX)
+              : DEFAULT_CONFIG.mcp.writeQueue?.maxQueued),
+          waitForOtherServers:
+            yamlConfig.mcp?.writeQueue?.waitForOtherServers ??
+            (process.env.MCP_WRITE_QUEUE_WAIT_OTHER_SERVERS !== undefined
+              ? process.env.MCP_WRITE_QUEUE_WAIT_OTHER_SERVERS !== "false"
+              : DEFAULT_CONFIG.mcp.writeQueue?.waitForOtherServers),
         },
       },
       database: {
@@ -666,6 +1204,11 @@ export class ConfigLoader {
           (yamlConfig.database?.tempStore as any) ||
           (process.env.DATABASE_TEMP_STORE as any) ||
           DEFAULT_CONFIG.database?.tempStore,
+        busyTimeoutMs:
+          yamlConfig.database?.busyTimeoutMs ??
+          (process.env.DATABASE_BUSY_TIMEOUT_MS !== undefined
+            ? Number(process.env.DATABASE_BUSY_TIMEOUT_MS)
+            : DEFAULT_CONFIG.database?.busyTimeoutMs),
       },
       logging: {
         level: (yamlConfig.logging?.level as any) || (process.env.LOG_LEVEL as any) || DEFAULT_CONFIG.logging?.level,
@@ -679,6 +1222,8 @@ export class ConfigLoader {
           yamlConfig.logging?.enableConsole !== undefined
             ? yamlConfig.logging?.enableConsole
             : process.env.LOG_ENABLE_CONSOLE !== "false" || DEFAULT_CONFIG.logging?.enableConsole,
+        // Per module, so `LOG_MODULES=parser=debug` still applies next to levels set in YAML
+        modules: { ...parseModuleLevels(process.env.LOG_MODULES), ...yamlConfig.logging?.modules },
       },
       parser: {
         treeSitter: {
@@ -743,6 +1288,35 @@ export class ConfigLoader {
             Number(process.env.PARSER_AGENT_WORKER_POOL_SIZE) ||
             DEFAULT_CONFIG.parser.agent?.workerPoolSize,
         },
+        go: {
+          buildMode:
+            yamlConfig.parser?.go?.buildMode ||
+            (process.env.PARSER_GO_BUILD_MODE as "target" | "all" | undefined) ||
+            DEFAULT_CONFIG.parser.go?.buildMode,
+          goos: yamlConfig.parser?.go?.goos || process.env.GOOS || DEFAULT_CONFIG.parser.go?.goos,
+          goarch: yamlConfig.parser?.go?.goarch || process.env.GOARCH || DEFAULT_CONFIG.parser.go?.goarch,
+          tags: yamlConfig.parser?.go?.tags || process.env.PARSER_GO_TAGS?.split(",") || DEFAULT_CONFIG.parser.go?.tags,
+          cgo:
+            yamlConfig.parser?.go?.cgo !== undefined
+              ? yamlConfig.parser?.go?.cgo
+              : process.env.CGO_ENABLED !== "0" && DEFAULT_CONFIG.parser.go?.cgo,
+          includeTests:
+            yamlConfig.parser?.go?.includeTests !== undefined
+              ? yamlConfig.parser?.go?.includeTests
+              : process.env.PARSER_GO_INCLUDE_TESTS !== "false" && DEFAULT_CONFIG.parser.go?.includeTests,
+        },
+        customQueries: yamlConfig.parser?.customQueries ?? DEFAULT_CONFIG.parser.customQueries,
+        entityKinds: yamlConfig.parser?.entityKinds ?? DEFAULT_CONFIG.parser.entityKinds,
+        comments: {
+          enabled:
+            yamlConfig.parser?.comments?.enabled !== undefined
+              ? yamlConfig.parser.comments.enabled
+              : process.env.PARSER_COMMENTS !== "false" && DEFAULT_CONFIG.parser.comments?.enabled,
+          embed:
+            yamlConfig.parser?.comments?.embed !== undefined
+              ? yamlConfig.parser.comments.embed
+              : process.env.PARSER_EMBED_COMMENTS === "true" || DEFAULT_CONFIG.parser.comments?.embed,
+        },
       },
       indexer: {
         maxConcurrency:
@@ -769,6 +1343,60 @@ export class ConfigLoader {
           yamlConfig.indexer?.cacheTTL ||
           Number(process.env.INDEXER_AGENT_CACHE_TTL) ||
           DEFAULT_CONFIG.indexer?.cacheTTL,
+        maxFileSizeBytes:
+          yamlConfig.indexer?.maxFileSizeBytes ??
+          (process.env.INDEXER_MAX_FILE_SIZE_BYTES !== undefined
+            ? Number(process.env.INDEXER_MAX_FILE_SIZE_BYTES)
+            : DEFAULT_CONFIG.indexer?.maxFileSizeBytes),
+        parseTimeoutMs:
+          yamlConfig.indexer?.parseTimeoutMs ??
+          (process.env.INDEXER_PARSE_TIMEOUT_MS !== undefined
+            ? Number(process.env.INDEXER_PARSE_TIMEOUT_MS)
+            : DEFAULT_CONFIG.indexer?.parseTimeoutMs),
+        skipGeneratedFiles:
+          yamlConfig.indexer?.skipGeneratedFiles ??
+          (process.env.INDEXER_SKIP_GENERATED_FILES !== undefined
+            ? process.env.INDEXER_SKIP_GENERATED_FILES === "1"
+            : DEFAULT_CONFIG.indexer?.skipGeneratedFiles),
+        lowMemory:
+          // The flag and env can switch it on over a `false` in the YAML file
+          yamlConfig.indexer?.lowMemory === true ||
+          process.env.INDEXER_LOW_MEMORY === "1" ||
+          DEFAULT_CONFIG.indexer?.lowMemory,
+        symbolCacheSize:
+          yamlConfig.indexer?.symbolCacheSize ??
+          (process.env.INDEXER_SYMBOL_CACHE_SIZE !== undefined
+            ? Number(process.env.INDEXER_SYMBOL_CACHE_SIZE)
+            : DEFAULT_CONFIG.indexer?.symbolCacheSize),
+        followSymlinks:
+          yamlConfig.indexer?.followSymlinks ??
+          (process.env.INDEXER_FOLLOW_SYMLINKS !== undefined
+            ? process.env.INDEXER_FOLLOW_SYMLINKS === "1"
+            : DEFAULT_CONFIG.indexer?.followSymlinks),
+        allowSymlinksOutsideRoot:
+          yamlConfig.indexer?.allowSymlinksOutsideRoot ??
+          (process.env.INDEXER_ALLOW_SYMLINKS_OUTSIDE_ROOT !== undefined
+            ? process.env.INDEXER_ALLOW_SYMLINKS_OUTSIDE_ROOT === "1"
+            : DEFAULT_CONFIG.indexer?.allowSymlinksOutsideRoot),
+        gitBlame:
+          yamlConfig.indexer?.gitBlame ??
+          (process.env.INDEXER_GIT_BLAME !== undefined
+            ? process.env.INDEXER_GIT_BLAME === "1"
+            : DEFAULT_CONFIG.indexer?.gitBlame),
+        repoCacheDir:
+          yamlConfig.indexer?.repoCacheDir ||
+          process.env.INDEXER_REPO_CACHE_DIR ||
+          DEFAULT_CONFIG.indexer?.repoCacheDir,
+        detectLanguageByContent:
+          yamlConfig.indexer?.detectLanguageByContent ??
+          (process.env.INDEXER_DETECT_LANGUAGE_BY_CONTENT !== undefined
+            ? process.env.INDEXER_DETECT_LANGUAGE_BY_CONTENT === "1"
+            : DEFAULT_CONFIG.indexer?.detectLanguageByContent),
+        includeTests:
+          yamlConfig.indexer?.includeTests ??
+          (process.env.INDEXER_INCLUDE_TESTS !== undefined
+            ? process.env.INDEXER_INCLUDE_TESTS === "1"
+            : DEFAULT_CONFIG.indexer?.includeTests),
       },
       devAgent: {
         maxConcurrency:
@@ -985,6 +1613,52 @@ export function initializeConfig(): AppConfig {
 // 6. VALIDATION FUNCTIONS
 // =============================================================================
 
+/** Shape of `parser.customQueries`; the query syntax is checked against each grammar by the parser */
+function customQuerySpecErrors(customQueries: Record<string, CustomQuerySpec[]> | undefined): string[] {
+  const errors: string[] = [];
+  for (const [language, specs] of Object.entries(customQueries ?? {})) {
+    if (!(SUPPORTED_LANGUAGES as readonly string[]).includes(language)) {
+      errors.push(`parser.customQueries: unknown language "${language}"`);
+      continue;
+    }
+    if (!Array.isArray(specs)) {
+      errors.push(`parser.customQueries.${language} must be a list of queries`);
+      continue;
+    }
+    specs.forEach((spec, i) => {
+      const label = `parser.customQueries.${language}[${spec?.name ?? i}]`;
+      if (!spec?.name || typeof spec.name !== "string") {
+        errors.push(`${label}: name is required`);
+      } else if (typeof spec.query !== "string" || !spec.query.trim()) {
+        errors.push(`${label}: query is required`);
+      } else if (Boolean(spec.kind) === Boolean(spec.attribute)) {
+        errors.push(`${label}: set exactly one of kind (emit entities) or attribute (annotate entities)`);
+      } else if (spec.kind && !/^[a-z][a-z0-9_]*$/.test(spec.kind)) {
+        errors.push(`${label}: kind must be lower_snake_case, got "${spec.kind}"`);
+      }
+    });
+  }
+  return errors;
+}
+
+/** Shape of `parser.entityKinds`; kind names are not checked, as analyzers and custom queries add their own */
+function entityKindRuleErrors(entityKinds: Record<string, EntityKindRule> | undefined): string[] {
+  const errors: string[] = [];
+  for (const [language, rule] of Object.entries(entityKinds ?? {})) {
+    if (language !== "*" && !(SUPPORTED_LANGUAGES as readonly string[]).includes(language)) {
+      errors.push(`parser.entityKinds: unknown language "${language}"`);
+      continue;
+    }
+    for (const key of ["include", "exclude"] as const) {
+      const kinds = rule?.[key];
+      if (kinds !== undefined && (!Array.isArray(kinds) || kinds.some((kind) => typeof kind !== "string"))) {
+        errors.push(`parser.entityKinds.${language}.${key} must be a list of entity kinds`);
+      }
+    }
+  }
+  return errors;
+}
+
 /**
  * Validate configuration at startup
  */
@@ -1000,11 +1674,20 @@ export function validateConfig(config: AppConfig): { valid: boolean; errors: str
   if (config.mcp.embedding?.enabled && !config.mcp.embedding.provider) {
     errors.push("Embedding provider is required when embedding is enabled");
   }
+  const batchSize = config.mcp.embedding?.batching?.size;
+  if (batchSize !== undefined && batchSize !== "auto" && !(Number.isFinite(batchSize) && batchSize >= 1)) {
+    errors.push('mcp.embedding.batching.size must be a positive number or "auto"');
+  }
 
   // Validate logging configuration
   if (!["debug", "info", "warn", "error"].includes(config.logging.level || "")) {
     errors.push("Invalid logging level");
   }
+  for (const [module, level] of Object.entries(config.logging.modules ?? {})) {
+    if (!["debug", "info", "warn", "error"].includes(level)) {
+      errors.push(`Invalid logging level for module ${module}`);
+    }
+  }
 
   // Validate parser configuration
   if (
@@ -1013,6 +1696,8 @@ export function validateConfig(config: AppConfig): { valid: boolean; errors: str
   ) {
     errors.push("Language configurations required when tree-sitter is enabled");
   }
+  errors.push(...customQuerySpecErrors(config.parser.customQueries));
+  errors.push(...entityKindRuleErrors(config.parser.entityKinds));
 
   return {
     valid: errors.length === 0,
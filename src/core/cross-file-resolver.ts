@@ -0,0 +1,332 @@
+/**
+ * Cross-file Resolver - binds relative imports, and imports of another index root by its package
+ * name, to the entities they name
+ * Runs after every file of an index run has been parsed and stored. Per-file indexing points
+ * imported symbols and calls to them at `external://` placeholders because the target file may not
+ * be indexed yet; once it is, those edges are moved onto the real definition. The outcome depends
+ * only on the stored graph, never on the order files were parsed in.
+ *
+ * Declarations of target files are kept in a bounded symbol table; files that do not fit are
+ * answered by per-name lookups against SQLite, so memory stays flat on very large repositories.
+ */
+
+import { existsSync } from "node:fs";
+import { dirname, join, normalize } from "node:path";
+import { externalPlaceholderId } from "../storage/entity-id.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { type Entity, EntityType, RelationType, type Relationship } from "../types/storage.js";
+import { reboundMetadata } from "./edge-confidence.js";
+import { readWorkspacePackages, type WorkspacePackage } from "./workspace-roots.js";
+
+export interface CrossFileResolution {
+  filesScanned: number;
+  importsResolved: number;
+  relationshipsRetargeted: number;
+}
+
+export interface CrossFileResolveOptions {
+  /** Declarations held in memory across target files (0 always queries SQLite) */
+  symbolCacheSize?: number;
+  /** Roots whose package names bare imports may use (default: every root recorded in the index) */
+  roots?: string[];
+}
+
+export const DEFAULT_SYMBOL_CACHE_SIZE = 50000;
+
+const MODULE_EXTENSIONS = [".ts", ".tsx", ".mts", ".cts", ".js", ".jsx", ".mjs", ".cjs"];
+
+/** Edge kinds that point at an imported symbol through its placeholder */
+const RETARGETED_TYPES = new Set<string>([
+  RelationType.IMPORTS,
+  RelationType.CALLS,
+  RelationType.REFERENCES,
+  RelationType.DECORATED_BY,
+  RelationType.EXTENDS,
+  RelationType.IMPLEMENTS,
+  RelationType.RENDERS,
+  RelationType.USES_HOOK,
+  RelationType.HANDLED_BY,
+]);
+
+/** Kinds an import specifier can name */
+const IMPORTABLE_TYPES = new Set<string>([
+  EntityType.FUNCTION,
+  EntityType.CLASS,
+  EntityType.INTERFACE,
+  EntityType.TYPE,
+  EntityType.VARIABLE,
+  EntityType.CONSTANT,
+]);
+
+/**
+ * The indexed file a relative specifier points at, trying the same candidates as TypeScript's
+ * bundler resolution: the path itself, added extensions, `.js` written for a `.ts` source, and
+ * directory index files.
+ */
+export function resolveRelativeModule(
+  importerPath: string,
+  specifier: string,
+  isIndexed: (path: string) => boolean,
+): string | null {
+  if (!specifier.startsWith("./") && !specifier.startsWith("../")) return null;
+  const base = normalize(join(dirname(importerPath), specifier));
+
+  const candidates = [base, ...MODULE_EXTENSIONS.map((ext) => `${base}${ext}`)];
+  const jsExt = /\.(m|c)?jsx?$/.exec(base);
+  if (jsExt) {
+    const stem = base.slice(0, -jsExt[0].length);
+    const flavour = jsExt[1] ?? "";
+    candidates.push(`${stem}.${flavour}ts`, `${stem}.${flavour}tsx`);
+  }
+  candidates.push(...MODULE_EXTENSIONS.map((ext) => join(base, `index${ext}`)));
+
+  return candidates.find(isIndexed) ?? null;
+}
+
+/**
+ * The indexed file a bare specifier such as `@acme/shared` or `@acme/shared/utils` names when it
+ * is the package name of an index root. The package entry is looked up in the manifest's source,
+ * types, exports, module and main fields, then `src/index` and `index`; a subpath is tried at the
+ * root and under `src/`. Built entry points are usually not indexed, so the fallbacks matter.
+ */
+export function resolveWorkspaceModule(
+  specifier: string,
+  packages: ReadonlyMap<string, WorkspacePackage>,
+  isIndexed: (path: string) => boolean,
+): string | null {
+  if (specifier.startsWith(".") || specifier.startsWith("/")) return null;
+  let pkg: WorkspacePackage | undefined;
+  for (const candidate of packages.values()) {
+    if (specifier !== candidate.name && !specifier.startsWith(`${candidate.name}/`)) continue;
+    if (!pkg || candidate.name.length > pkg.name.length) pkg = candidate;
+  }
+  if (!pkg) return null;
+
+  const subpath = specifier.slice(pkg.name.length + 1);
+  const targets = subpath ? [subpath, `src/${subpath}`] : [...pkg.entries, "src/index", "index"];
+  const manifest = join(pkg.root, "package.json");
+  for (const target of targets) {
+    const found = resolveRelativeModule(manifest, `./${target}`, isIndexed);
+    if (found) return found;
+  }
+  return null;
+}
+
+function isTopLevel(entity: Entity): boolean {
+  const meta = (entity.metadata ?? {}) as Record<string, unknown>;
+  return !meta.parentClass && !meta.className && !meta.receiver && !meta.implType;
+}
+
+/**
+ * Top-level declarations per target file, least recently used files evicted first once the
+ * total number of held declarations would exceed the capacity.
+ */
+export class SymbolTable {
+  private readonly files = new Map<string, Entity[]>();
+  private held = 0;
+
+  constructor(readonly capacity: number) {}
+
+  get size(): number {
+    return this.held;
+  }
+
+  get(filePath: string): Entity[] | undefined {
+    const found = this.files.get(filePath);
+    if (found) {
+      // Re-insert to mark as most recently used
+      this.files.delete(filePath);
+      this.files.set(filePath, found);
+    }
+    return found;
+  }
+
+  /** Returns false when the file alone is larger than the table and was not stored */
+  set(filePath: string, declarations: Entity[]): boolean {
+    if (declarations.length > this.capacity) return false;
+    this.delete(filePath);
+    while (this.held + declarations.length > this.capacity) {
+      const oldest = this.files.keys().next().value;
+      if (oldest === undefined) break;
+      this.delete(oldest);
+    }
+    this.files.set(filePath, declarations);
+    this.held += declarations.length;
+    return true;
+  }
+
+  private delete(filePath: string): void {
+    const existing = this.files.get(filePath);
+    if (!existing) return;
+    this.files.delete(filePath);
+    this.held -= existing.length;
+  }
+}
+
+const IMPORT_PAGE_SIZE = 1000;
+
+/**
+ * Indexed files outside `files` with an import that lands in one of them. Their edges may still
+ * point at placeholders because `files` were stored after them, in an earlier run.
+ */
+async function importersOf(
+  storage: GraphStorageImpl,
+  files: Set<string>,
+  targetOf: (importer: string, source: string) => string | null,
+): Promise<string[]> {
+  const importers = new Set<string>();
+  let afterId: string | undefined;
+  while (true) {
+    const page = await storage.findEntities({
+      type: "entity",
+      filters: { entityType: EntityType.IMPORT },
+      afterId,
+      limit: IMPORT_PAGE_SIZE,
+    });
+    for (const entity of page) {
+      const source = entity.metadata?.importData?.source;
+      if (files.has(entity.filePath) || importers.has(entity.filePath) || typeof source !== "string") continue;
+      const target = targetOf(entity.filePath, source);
+      if (target && files.has(target)) importers.add(entity.filePath);
+    }
+    if (page.length < IMPORT_PAGE_SIZE) return [...importers];
+    afterId = page[page.length - 1]!.id;
+  }
+}
+
+/** `ns` and `fn` of a call site written `ns.fn()`, null for any other callee */
+function namespaceMember(callee: unknown): { namespace: string; member: string } | null {
+  if (typeof callee !== "string") return null;
+  const match = /^([\w$]+)\.([\w$]+)$/.exec(callee);
+  return match ? { namespace: match[1]!, member: match[2]! } : null;
+}
+
+/**
+ * Retarget placeholder edges left by relative and cross-root imports in `files` (every indexed
+ * file when omitted), and in the files importing them. A name is bound only when the target file
+ * has exactly one top-level declaration with it. Named imports bind their specifiers; namespace
+ * imports bind calls written `ns.fn()` whose every call site goes through the namespace. Default
+ * imports are left alone.
+ */
+export async function resolveCrossFileImports(
+  storage: GraphStorageImpl,
+  files?: string[],
+  options: CrossFileResolveOptions = {},
+): Promise<CrossFileResolution> {
+  const indexed = new Set((await storage.listIndexedFiles()).map((info) => info.path));
+  const isIndexed = (path: string) => indexed.has(path) && existsSync(path);
+  const packages = readWorkspacePackages(options.roots ?? (await storage.listIndexRoots()));
+  const targetOf = (importer: string, source: string) =>
+    resolveRelativeModule(importer, source, isIndexed) ?? resolveWorkspaceModule(source, packages, isIndexed);
+
+  const requested = (files ?? Array.from(indexed)).filter((file) => indexed.has(file));
+  // Indexing a and then b in two runs must bind what indexing both at once binds
+  const importers = files
+    ? [...new Set([...requested, ...(await importersOf(storage, new Set(requested), targetOf))])].sort()
+    : requested.sort();
+
+  const symbols = new SymbolTable(Math.max(0, options.symbolCacheSize ?? DEFAULT_SYMBOL_CACHE_SIZE));
+  const oversized = new Set<string>();
+  const declarationsNamed = async (filePath: string, name: string): Promise<Entity[]> => {
+    let found = symbols.get(filePath);
+    if (!found && !oversized.has(filePath)) {
+      const entities = await storage.findEntities({ type: "entity", filters: { filePath }, limit: 10000 });
+      found = entities.filter((e) => IMPORTABLE_TYPES.has(e.type) && isTopLevel(e));
+      if (!symbols.set(filePath, found)) oversized.add(filePath);
+    }
+    if (!found) {
+      // Too large to hold: ask SQLite for just this name
+      const named = await storage.findEntities({ type: "entity", filters: { filePath, name }, limit: 100 });
+      found = named.filter((e) => IMPORTABLE_TYPES.has(e.type) && isTopLevel(e));
+    }
+    return found.filter((e) => e.name === name);
+  };
+
+  const stats: CrossFileResolution = { filesScanned: 0, importsResolved: 0, relationshipsRetargeted: 0 };
+
+  for (const file of importers) {
+    const entities = await storage.findEntities({ type: "entity", filters: { filePath: file }, limit: 10000 });
+    const ownIds = new Set(entities.map((e) => e.id));
+    stats.filesScanned += 1;
+
+    // Calls of this file left at placeholders, by the namespace their call sites go through
+    let namespaceCalls: Map<string, Array<{ rel: Relationship; member: string }>> | undefined;
+    const callsThrough = async (namespace: string) => {
+      if (!namespaceCalls) {
+        namespaceCalls = new Map();
+        for (const own of entities) {
+          for (const rel of await storage.getRelationshipsForEntity(own.id, RelationType.CALLS)) {
+            if (rel.fromId !== own.id) continue;
+            const callees = new Set((rel.metadata?.callSites ?? []).map((site: { callee?: unknown }) => site.callee));
+            const target = callees.size === 1 ? namespaceMember([...callees][0]) : null;
+            if (!target || rel.toId !== externalPlaceholderId(file, target.member)) continue;
+            const group = namespaceCalls.get(target.namespace) ?? [];
+            group.push({ rel, member: target.member });
+            namespaceCalls.set(target.namespace, group);
+          }
+        }
+      }
+      return namespaceCalls.get(namespace) ?? [];
+    };
+
+    for (const entity of entities) {
+      const importData = entity.type === EntityType.IMPORT ? entity.metadata?.importData : undefined;
+      if (!importData) continue;
+      const namespace = importData.isNamespace ? importData.namespace : undefined;
+      if (importData.isDefault && !namespace) continue;
+
+      const relativeTarget = resolveRelativeModule(file, importData.source, isIndexed);
+      const targetFile = relativeTarget ?? resolveWorkspaceModule(importData.source, packages, isIndexed);
+      if (!targetFile) continue;
+      const resolvedFrom = relativeTarget ? "import" : "workspace";
+
+      if (namespace) {
+        const byMember = new Map<string, Relationship[]>();
+        for (const { rel, member } of await callsThrough(namespace)) {
+          byMember.set(member, [...(byMember.get(member) ?? []), rel]);
+        }
+        for (const [member, rels] of byMember) {
+          const matches = await declarationsNamed(targetFile, member);
+          if (matches.length !== 1) continue;
+          const target = matches[0]!;
+          for (const rel of rels) await storage.deleteRelationship(rel.id);
+          await storage.insertRelationships(
+            rels.map((rel) => ({ ...rel, toId: target.id, metadata: reboundMetadata(rel.metadata, resolvedFrom) })),
+          );
+          stats.importsResolved += 1;
+          stats.relationshipsRetargeted += rels.length;
+        }
+        continue;
+      }
+
+      for (const specifier of importData.specifiers ?? []) {
+        const imported = specifier.imported || specifier.local;
+        const matches = await declarationsNamed(targetFile, imported);
+        if (matches.length !== 1) continue;
+        const target = matches[0]!;
+
+        // Import and decorator edges name the module specifier; calls name the importing file and local alias
+        const placeholders = [
+          externalPlaceholderId(importData.source, imported),
+          externalPlaceholderId(file, specifier.local),
+        ];
+        const moved: Relationship[] = [];
+        for (const placeholderId of placeholders) {
+          for (const rel of await storage.getRelationshipsForEntity(placeholderId)) {
+            if (rel.toId !== placeholderId || !ownIds.has(rel.fromId)) continue;
+            if (!RETARGETED_TYPES.has(rel.type)) continue;
+            await storage.deleteRelationship(rel.id);
+            moved.push({ ...rel, toId: target.id, metadata: reboundMetadata(rel.metadata, resolvedFrom) });
+          }
+        }
+        if (moved.length === 0) continue;
+
+        await storage.insertRelationships(moved);
+        stats.importsResolved += 1;
+        stats.relationshipsRetargeted += moved.length;
+      }
+    }
+  }
+
+  return stats;
+}
@@ -0,0 +1,346 @@
+/**
+ * C# Assembly Resolver - binds C# types and calls across the files of an assembly
+ * Runs after cross-file resolution and before override resolution, which needs the heritage
+ * edges bound here. An assembly is the set of files under the nearest directory holding a
+ * `.csproj` (all files without one form a single assembly). Partial types declared in several
+ * files are merged into one entity per qualified name first, then heritage and call edges left
+ * at placeholders are bound to types and methods of the assembly. Names are looked up the way
+ * the compiler does: in the enclosing types and namespaces, then in the file's using
+ * directives, accepting a type name that is unique in the assembly as a last resort.
+ */
+
+import { readdirSync } from "node:fs";
+import { dirname, extname } from "node:path";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { type Entity, type Relationship, RelationType } from "../types/storage.js";
+import { reboundMetadata } from "./edge-confidence.js";
+import { TYPE_KINDS } from "./override-resolver.js";
+
+export interface CSharpAssemblyResolution {
+  filesScanned: number;
+  partialTypesMerged: number;
+  heritageResolved: number;
+  callsResolved: number;
+}
+
+const HERITAGE = new Set<string>([RelationType.EXTENDS, RelationType.IMPLEMENTS]);
+
+function meta(entity: Entity): Record<string, any> {
+  return (entity.metadata ?? {}) as Record<string, any>;
+}
+
+function isPlaceholder(entity: Entity): boolean {
+  return entity.filePath.startsWith("external://");
+}
+
+function qualifiedNameOf(entity: Entity): string {
+  const name = meta(entity).qualifiedName;
+  return typeof name === "string" && name ? name : entity.name;
+}
+
+/** `Shop.Orders.OrderService` for `Shop.Orders.OrderService.Place` */
+function parentName(qualifiedName: string): string {
+  const dot = qualifiedName.lastIndexOf(".");
+  return dot < 0 ? "" : qualifiedName.slice(0, dot);
+}
+
+/** A type name as written, without generic arguments, nullability, array ranks or `global::` */
+function writtenType(type: string): string {
+  return type
+    .replace(/<[\s\S]*$/, "")
+    .replace(/[?[\]\s]/g, "")
+    .replace(/^global::/, "");
+}
+
+function simpleName(type: string): string {
+  return writtenType(type).split(".").pop() ?? type;
+}
+
+/** Types and members of one assembly, by qualified name */
+class AssemblySymbols {
+  private readonly types = new Map<string, Entity[]>();
+  private readonly typesBySimpleName = new Map<string, Entity[]>();
+  private readonly members = new Map<string, Entity[]>();
+
+  add(entity: Entity): void {
+    const qualifiedName = qualifiedNameOf(entity);
+    if (TYPE_KINDS.has(String(entity.type))) {
+      this.types.set(qualifiedName, [...(this.types.get(qualifiedName) ?? []), entity]);
+      this.typesBySimpleName.set(entity.name, [...(this.typesBySimpleName.get(entity.name) ?? []), entity]);
+    } else if (qualifiedName.includes(".")) {
+      const owner = parentName(qualifiedName);
+      this.members.set(owner, [...(this.members.get(owner) ?? []), entity]);
+    }
+  }
+
+  type(qualifiedName: string): Entity | undefined {
+    const found = this.types.get(qualifiedName);
+    return found?.length === 1 ? found[0] : undefined;
+  }
+
+  /**
+   * The type `written` names from inside `scope` (a type or namespace): `scope.written`, then
+   * the same in each enclosing scope, then under each using directive, then, for an unqualified
+   * name, the only type of that name in the assembly
+   */
+  resolveType(written: string, scope: string, usings: string[]): Entity | undefined {
+    const name = writtenType(written);
+    if (!name) return undefined;
+    for (let outer = scope; ; outer = parentName(outer)) {
+      const found = this.type(outer ? `${outer}.${name}` : name);
+      if (found) return found;
+      if (!outer) break;
+    }
+    const imported = new Set(usings.map((using) => this.type(`${using}.${name}`)).filter((e) => e !== undefined));
+    if (imported.size === 1) return [...imported][0];
+    if (imported.size > 1 || name.includes(".")) return undefined;
+    const bySimpleName = this.typesBySimpleName.get(simpleName(name));
+    return bySimpleName?.length === 1 ? bySimpleName[0] : undefined;
+  }
+
+  membersOf(type: Entity): Entity[] {
+    return this.members.get(qualifiedNameOf(type)) ?? [];
+  }
+}
+
+/**
+ * Directory of the nearest `.csproj` above `filePath`, or "" when there is none. Directory
+ * listings are memoized in `cache` for the run.
+ */
+function assemblyOf(filePath: string, cache: Map<string, string>): string {
+  const visited: string[] = [];
+  let found = "";
+  for (let dir = dirname(filePath); ; dir = dirname(dir)) {
+    const known = cache.get(dir);
+    if (known !== undefined) {
+      found = known;
+      break;
+    }
+    visited.push(dir);
+    let entries: string[] = [];
+    try {
+      entries = readdirSync(dir);
+    } catch {
+      // Unreadable or removed directory: keep looking further up
+    }
+    if (entries.some((entry) => extname(entry).toLowerCase() === ".csproj")) {
+      found = dir;
+      break;
+    }
+    if (dirname(dir) === dir) break;
+  }
+  for (const dir of visited) cache.set(dir, found);
+  return found;
+}
+
+/**
+ * Merge partial types and bind C# heritage and calls for `files` (every indexed C# file when
+ * omitted), using the symbols of every indexed file in the same assemblies.
+ */
+export async function resolveCSharpAssemblies(
+  storage: GraphStorageImpl,
+  files?: string[],
+): Promise<CSharpAssemblyResolution> {
+  const indexed = (await storage.listIndexedFiles())
+    .map((info) => info.path)
+    .filter((path) => extname(path).toLowerCase() === ".cs");
+  const indexedSet = new Set(indexed);
+  const targets = [...new Set(files ?? indexed)].filter((file) => indexedSet.has(file)).sort();
+  const stats: CSharpAssemblyResolution = {
+    filesScanned: 0,
+    partialTypesMerged: 0,
+    heritageResolved: 0,
+    callsResolved: 0,
+  };
+  if (targets.length === 0) return stats;
+
+  const projectDirs = new Map<string, string>();
+  const assemblies = new Set(targets.map((file) => assemblyOf(file, projectDirs)));
+  const inScope = indexed.filter((file) => assemblies.has(assemblyOf(file, projectDirs))).sort();
+
+  const entitiesByFile = new Map<string, Entity[]>();
+  for (const file of inScope) {
+    entitiesByFile.set(file, await storage.findEntities({ type: "entity", filters: { filePath: file }, limit: 10000 }));
+  }
+
+  // Partial types: the first fragment in path order takes over the edges of the others
+  const fragments = new Map<string, Entity[]>();
+  for (const file of inScope) {
+    for (const entity of entitiesByFile.get(file) ?? []) {
+      if (!TYPE_KINDS.has(String(entity.type)) || meta(entity).isPartial !== true) continue;
+      const key = [assemblyOf(file, projectDirs), entity.type, qualifiedNameOf(entity)].join("|");
+      fragments.set(key, [...(fragments.get(key) ?? []), entity]);
+    }
+  }
+  const removed = new Set<string>();
+  for (const group of fragments.values()) {
+    if (group.length < 2) continue;
+    group.sort((a, b) => a.filePath.localeCompare(b.filePath) || a.location.start.line - b.location.start.line);
+    const [primary, ...others] = group as [Entity, ...Entity[]];
+
+    const moved: Relationship[] = [];
+    for (const fragment of others) {
+      for (const rel of await storage.getRelationshipsForEntity(fragment.id)) {
+        const fromId = rel.fromId === fragment.id ? primary.id : rel.fromId;
+        const toId = rel.toId === fragment.id ? primary.id : rel.toId;
+        await storage.deleteRelationship(rel.id);
+        if (fromId !== toId) moved.push({ ...rel, fromId, toId });
+      }
+    }
+    if (moved.length > 0) await storage.insertRelationships(moved);
+    for (const fragment of others) {
+      await storage.deleteEntity(fragment.id);
+      removed.add(fragment.id);
+    }
+
+    const partialFiles = [...new Set(group.map((fragment) => fragment.filePath))].sort();
+    await storage.updateEntity(primary.id, { metadata: { ...meta(primary), partialFiles } });
+    primary.metadata = { ...meta(primary), partialFiles };
+    stats.partialTypesMerged += 1;
+  }
+
+  const symbolsByAssembly = new Map<string, AssemblySymbols>();
+  const usingsByFile = new Map<string, string[]>();
+  for (const file of inScope) {
+    const assembly = assemblyOf(file, projectDirs);
+    let symbols = symbolsByAssembly.get(assembly);
+    if (!symbols) {
+      symbols = new AssemblySymbols();
+      symbolsByAssembly.set(assembly, symbols);
+    }
+    const usings = new Set<string>();
+    const remaining = (entitiesByFile.get(file) ?? []).filter((entity) => !removed.has(entity.id));
+    entitiesByFile.set(file, remaining);
+    for (const entity of remaining) {
+      symbols.add(entity);
+      for (const using of meta(entity).usings ?? []) usings.add(using);
+    }
+    usingsByFile.set(file, [...usings]);
+  }
+
+  const outgoing = async (entity: Entity, types: Set<string>) =>
+    (await storage.getRelationshipsForEntity(entity.id)).filter(
+      (rel) => rel.fromId === entity.id && types.has(rel.type),
+    );
+
+  // Heritage first, so calls can follow bases declared in other files
+  for (const file of targets) {
+    stats.filesScanned += 1;
+    const symbols = symbolsByAssembly.get(assemblyOf(file, projectDirs))!;
+    const usings = usingsByFile.get(file) ?? [];
+
+    for (const type of entitiesByFile.get(file) ?? []) {
+      if (!TYPE_KINDS.has(String(type.type))) continue;
+      const edges = await outgoing(type, HERITAGE);
+      const bound = new Set(edges.map((rel) => `${rel.type}|${rel.toId}`));
+      const moved: Relationship[] = [];
+
+      for (const rel of edges) {
+        const placeholder = await storage.getEntity(rel.toId);
+        if (!placeholder || !isPlaceholder(placeholder)) continue;
+        const written =
+          (meta(type).baseTypes as string[] | undefined)?.find((base) => simpleName(base) === placeholder.name) ??
+          placeholder.name;
+        const target = symbols.resolveType(written, parentName(qualifiedNameOf(type)), usings);
+        if (!target || target.id === type.id) continue;
+
+        // The analyzer guesses which base is the class; the declaration settles it
+        let relType = rel.type;
+        if (type.type !== "interface") {
+          relType = target.type === "interface" ? RelationType.IMPLEMENTS : RelationType.EXTENDS;
+        }
+        await storage.deleteRelationship(rel.id);
+        stats.heritageResolved += 1;
+        if (bound.has(`${relType}|${target.id}`)) continue;
+        bound.add(`${relType}|${target.id}`);
+        moved.push({ ...rel, toId: target.id, type: relType, metadata: reboundMetadata(rel.metadata, "assembly") });
+      }
+      if (moved.length > 0) await storage.insertRelationships(moved);
+    }
+  }
+
+  // Bound bases of a type, from the edges stored so far
+  const basesOf = async (type: Entity): Promise<Entity[]> => {
+    const bases: Entity[] = [];
+    for (const rel of await outgoing(type, HERITAGE)) {
+      const base = await storage.getEntity(rel.toId);
+      if (base && !isPlaceholder(base)) bases.push(base);
+    }
+    return bases;
+  };
+
+  // First method named `name` on one of `owners` or their bases
+  const findMethod = async (symbols: AssemblySymbols, owners: Entity[], name: string): Promise<Entity | undefined> => {
+    const seen = new Set<string>();
+    const queue = [...owners];
+    while (queue.length > 0) {
+      const type = queue.shift()!;
+      if (seen.has(type.id)) continue;
+      seen.add(type.id);
+      const method = symbols
+        .membersOf(type)
+        .filter((m) => m.type === "method" && m.name === name && !meta(m).isConstructor)
+        .sort((a, b) => a.filePath.localeCompare(b.filePath) || a.location.start.line - b.location.start.line)[0];
+      if (method) return method;
+      queue.push(...(await basesOf(type)));
+    }
+    return undefined;
+  };
+
+  for (const file of targets) {
+    const symbols = symbolsByAssembly.get(assemblyOf(file, projectDirs))!;
+    const usings = usingsByFile.get(file) ?? [];
+    const moved: Relationship[] = [];
+
+    for (const caller of entitiesByFile.get(file) ?? []) {
+      if (caller.type !== "method" && caller.type !== "property") continue;
+      const scope = parentName(qualifiedNameOf(caller));
+      const owner = symbols.type(scope);
+      if (!owner) continue;
+      const enclosing: Entity[] = [];
+      for (let outer = scope; outer; outer = parentName(outer)) {
+        const type = symbols.type(outer);
+        if (type) enclosing.push(type);
+      }
+
+      for (const rel of await outgoing(caller, new Set([RelationType.CALLS]))) {
+        const callee = await storage.getEntity(rel.toId);
+        if (!callee || !isPlaceholder(callee)) continue;
+
+        const written = String(rel.metadata?.callSites?.[0]?.callee ?? callee.name);
+        const dot = written.lastIndexOf(".");
+        const receiver = dot < 0 ? "" : written.slice(0, dot).replace(/\?$/, "");
+
+        let owners: Entity[] = [];
+        if (!receiver || receiver === "this") {
+          owners = enclosing;
+        } else if (receiver === "base") {
+          owners = await basesOf(owner);
+        } else {
+          const member = receiver.replace(/^this\./, "");
+          const declared = !member.includes(".")
+            ? enclosing.flatMap((type) => symbols.membersOf(type)).find((m) => m.name === member)
+            : undefined;
+          const memberType = declared ? (meta(declared).fieldType ?? meta(declared).propertyType) : undefined;
+          const type =
+            typeof memberType === "string"
+              ? symbols.resolveType(memberType, parentName(qualifiedNameOf(declared!)), usings)
+              : symbols.resolveType(receiver, scope, usings);
+          if (type) owners = [type];
+        }
+
+        const target = await findMethod(symbols, owners, callee.name);
+        if (!target || target.id === rel.toId) continue;
+        await storage.deleteRelationship(rel.id);
+        moved.push({ ...rel, toId: target.id, metadata: reboundMetadata(rel.metadata, "assembly") });
+      }
+    }
+
+    if (moved.length > 0) {
+      await storage.insertRelationships(moved);
+      stats.callsResolved += moved.length;
+    }
+  }
+
+  return stats;
+}
@@ -0,0 +1,57 @@
+import type { Relationship } from "../types/storage.js";
+
+/**
+ * How an edge's target was chosen:
+ * - `resolved`: bound in scope — a parser id, the only declaration of the name in the file, or a
+ *   resolver following imports, namespaces or includes
+ * - `name_match`: picked by name among several candidates, or bound by a name that is unique
+ *   across the project
+ * - `heuristic`: left on an unresolved placeholder, or guessed at query time
+ */
+export type EdgeDerivation = "resolved" | "name_match" | "heuristic";
+
+export type EdgeConfidence = {
+  confidence: number;
+  derivation: EdgeDerivation;
+};
+
+export const CONFIDENCE_BY_DERIVATION: Record<EdgeDerivation, number> = {
+  resolved: 1,
+  name_match: 0.7,
+  heuristic: 0.4,
+};
+
+const DERIVATIONS = new Set<string>(Object.keys(CONFIDENCE_BY_DERIVATION));
+
+/**
+ * Metadata fields for an edge derived this way. A score the analyzer gave the edge (Rust, C#
+ * and Python report their own) can only lower the one the derivation allows.
+ */
+export function edgeConfidence(derivation: EdgeDerivation, reported?: unknown): EdgeConfidence {
+  const ceiling = CONFIDENCE_BY_DERIVATION[derivation];
+  const score = typeof reported === "number" && reported >= 0 ? Math.min(ceiling, reported) : ceiling;
+  return { confidence: score, derivation };
+}
+
+/**
+ * Confidence recorded on a stored edge. Edges written before confidence was tracked count as
+ * resolved unless they still point at an unresolved placeholder.
+ */
+export function confidenceOf(rel: Pick<Relationship, "metadata">, toPlaceholder = false): EdgeConfidence {
+  const derivation = rel.metadata?.derivation;
+  if (typeof derivation === "string" && DERIVATIONS.has(derivation)) {
+    return edgeConfidence(derivation as EdgeDerivation, rel.metadata?.confidence);
+  }
+  return edgeConfidence(toPlaceholder ? "heuristic" : "resolved", rel.metadata?.confidence);
+}
+
+/** Clamp a requested minimum confidence to 0..1; anything unusable means no filter */
+export function minConfidenceOf(value: unknown): number {
+  const n = Number(value);
+  return Number.isFinite(n) ? Math.max(0, Math.min(1, n)) : 0;
+}
+
+/** Metadata for a placeholder edge a resolver has bound to the declaration it names */
+export function reboundMetadata(metadata: Relationship["metadata"], resolvedFrom: string): Relationship["metadata"] {
+  return { ...metadata, resolvedFrom, ...edgeConfidence("resolved") };
+}
@@ -0,0 +1,54 @@
+/**
+ * Go module helpers - import paths of package directories and the names imports bind
+ */
+
+import { existsSync, readFileSync } from "node:fs";
+import { dirname, join } from "node:path";
+
+export type GoModule = { root: string; path: string };
+
+/** Nearest go.mod above a directory, per directory visited; `null` when there is none */
+export type GoModuleCache = Map<string, GoModule | null>;
+
+const GO_MAJOR_VERSION = /^v\d+$/;
+
+/** Package name Go code uses for an import path, by the usual conventions (`gopkg.in/yaml.v3` → yaml) */
+export function goPackageName(importPath: string): string {
+  const segments = importPath.split("/").filter(Boolean);
+  let last = segments.pop() ?? importPath;
+  if (GO_MAJOR_VERSION.test(last) && segments.length > 0) last = segments.pop()!;
+  return last.replace(/\.v\d+$/, "").replace(/^go-/, "").replace(/[-.]go$/, "").replace(/[^\w]/g, "_");
+}
+
+export function findGoModule(dir: string, cache: GoModuleCache): GoModule | null {
+  const visited: string[] = [];
+  let found: GoModule | null = null;
+  for (let at = dir; ; at = dirname(at)) {
+    const cached = cache.get(at);
+    if (cached !== undefined) {
+      found = cached;
+      break;
+    }
+    visited.push(at);
+    const goMod = join(at, "go.mod");
+    if (existsSync(goMod)) {
+      const modulePath = /^module\s+(\S+)/m.exec(readFileSync(goMod, "utf8"))?.[1];
+      found = modulePath ? { root: at, path: modulePath } : null;
+      break;
+    }
+    if (dirname(at) === at) break;
+  }
+  for (const at of visited) cache.set(at, found);
+  return found;
+}
+
+/**
+ * Import path of the Go package in `dir`, derived from the nearest go.mod. Without a go.mod the
+ * directory itself stands in, and imports are matched against it by path suffix (GOPATH layout).
+ */
+export function goImportPath(dir: string, cache: GoModuleCache): string {
+  const mod = findGoModule(dir, cache);
+  if (!mod) return dir;
+  const rel = dir.slice(mod.root.length).replace(/\\/g, "/").replace(/^\/+/, "");
+  return rel ? `${mod.path}/${rel}` : mod.path;
+}
@@ -0,0 +1,319 @@
+/**
+ * Go Package Resolver - binds calls, type references, accesses, goroutine and channel edges and
+ * method receivers across the files of Go packages
+ * A Go file sees every declaration of its package, whichever file declares it, and the exported
+ * declarations of the packages it imports through the name the import binds (its alias, or the
+ * package name). Per-file indexing leaves anything declared in another file at a placeholder
+ * named as written: `Helper`, `pkg.Func`, `pkg.Type`, `Server.users`, and a method whose receiver
+ * type is declared in another file is contained by a placeholder named after the type. Once the
+ * project is indexed, those are bound to the declaration: bare names within the package (then
+ * through dot imports), qualified ones through the file's imports, which are mapped to package
+ * directories by go.mod, fields through the type that declares them, and receivers to the type of
+ * that name in the method's own package. The analyzer only sees one file when it matches struct
+ * method sets against interfaces, so implicit `implements` edges are recomputed here over every
+ * file of the package, with the same pass (see parsers/go-method-sets.ts).
+ */
+
+import { dirname } from "node:path";
+import { goImplementations } from "../parsers/go-method-sets.js";
+import { stableRelationshipId } from "../storage/entity-id.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { type AccessSite, type Entity, type Relationship, RelationType } from "../types/storage.js";
+import { edgeConfidence, reboundMetadata } from "./edge-confidence.js";
+import { type GoModuleCache, goImportPath, goPackageName } from "./go-modules.js";
+
+export interface GoPackageResolution {
+  filesScanned: number;
+  callsResolved: number;
+  referencesResolved: number;
+  accessesResolved: number;
+  spawnsResolved: number;
+  channelOpsResolved: number;
+  /** Methods attached to a receiver type declared in another file of their package */
+  membersResolved: number;
+  /** Interfaces a struct satisfies only through methods or declarations spread over several files */
+  implementationsResolved: number;
+}
+
+const DECLARATION_KINDS = new Set(["function", "class", "interface", "type", "typedef", "constant", "variable"]);
+const BOUND_EDGES = new Set<string>([
+  RelationType.CALLS,
+  RelationType.REFERENCES,
+  RelationType.EMBEDS,
+  RelationType.ACCESSES,
+  RelationType.SPAWNS,
+  RelationType.SENDS,
+  RelationType.RECEIVES,
+  RelationType.CONTAINS,
+]);
+/** Edges whose sites name what they call, and those whose sites name what they access */
+const CALL_EDGES = new Set<string>([RelationType.CALLS, RelationType.SPAWNS]);
+const ACCESS_EDGES = new Set<string>([RelationType.ACCESSES, RelationType.SENDS, RelationType.RECEIVES]);
+/** What a bare or qualified access may name; functions used as values are left alone */
+const VALUE_KINDS = new Set(["variable", "constant"]);
+/** What a method receiver may name */
+const RECEIVER_KINDS = new Set(["class", "interface", "type", "typedef"]);
+const WRITTEN_NAME = /^(?:([A-Za-z_]\w*)\.)?([A-Za-z_]\w*)$/;
+
+type GoFile = {
+  path: string;
+  /** `dir|package`, telling a directory's `foo` and `foo_test` packages apart */
+  packageKey: string;
+  entities: Entity[];
+  /** Package-name bindings of the file's imports to import paths; dot imports under "." */
+  imports: Map<string, string[]>;
+};
+
+type GoPackage = {
+  dir: string;
+  name: string;
+  declarations: Map<string, Entity[]>;
+};
+
+function meta(entity: Entity): Record<string, any> {
+  return (entity.metadata ?? {}) as Record<string, any>;
+}
+
+function isPlaceholder(entity: Entity): boolean {
+  return entity.filePath.startsWith("external://");
+}
+
+function isDeclaration(entity: Entity): boolean {
+  const data = meta(entity);
+  return DECLARATION_KINDS.has(String(entity.type)) && !data.receiver && !data.parent && !data.isPackage;
+}
+
+function isExported(name: string): boolean {
+  return /^[A-Z]/.test(name);
+}
+
+/**
+ * `implements` edges from every struct of a package to every interface of it whose methods the
+ * struct's method set covers, gathered from all of the package's files. Edges the analyzer found
+ * within one file are left alone; ones an earlier run added here and that no longer hold are
+ * removed. Returns how many were added.
+ */
+async function linkImplementations(storage: GraphStorageImpl, files: GoFile[]): Promise<number> {
+  const entities = files.flatMap((file) => file.entities);
+  // Interface methods name their interface by its analyzer id, not the stored one
+  const found = goImplementations(entities, (iface) => `${iface.filePath}:type:${iface.name}`);
+  const interfaceIds = new Set(entities.filter((entity) => entity.type === "interface").map((iface) => iface.id));
+
+  let added = 0;
+  for (const struct of entities) {
+    if (struct.type !== "class" || meta(struct).parent) continue;
+    const existing = (await storage.getRelationshipsForEntity(struct.id, RelationType.IMPLEMENTS)).filter(
+      (rel) => rel.fromId === struct.id && interfaceIds.has(rel.toId),
+    );
+    const holds = found.filter((impl) => impl.struct === struct);
+
+    const inserted: Relationship[] = [];
+    for (const { iface, pointerReceiver, methodCount } of holds) {
+      if (existing.some((rel) => rel.toId === iface.id)) continue;
+      inserted.push({
+        id: stableRelationshipId(struct.id, iface.id, RelationType.IMPLEMENTS),
+        fromId: struct.id,
+        toId: iface.id,
+        type: RelationType.IMPLEMENTS,
+        metadata: {
+          line: struct.location.start.line,
+          implicit: true,
+          satisfiedBy: pointerReceiver ? `*${struct.name}` : struct.name,
+          pointerReceiver,
+          methodCount,
+          resolvedFrom: "package",
+          ...edgeConfidence("resolved"),
+        },
+      });
+    }
+
+    // A method removed from another file leaves an edge added by an earlier run behind
+    for (const rel of existing) {
+      const stale = !holds.some((impl) => impl.iface.id === rel.toId);
+      if (rel.metadata?.resolvedFrom === "package" && stale) await storage.deleteRelationship(rel.id);
+    }
+    if (inserted.length > 0) {
+      await storage.insertRelationships(inserted);
+      added += inserted.length;
+    }
+  }
+  return added;
+}
+
+/**
+ * Bind placeholder calls, type references, embeddings, accesses, spawns, channel operations and
+ * method receivers of `files` (every indexed Go file when omitted), and of the files sharing or
+ * importing their packages, against every indexed Go file. A name is bound only when its package
+ * declares it exactly once.
+ */
+export async function resolveGoPackages(storage: GraphStorageImpl, files?: string[]): Promise<GoPackageResolution> {
+  const indexed = (await storage.listIndexedFiles())
+    .map((info) => info.path)
+    .filter((path) => path.endsWith(".go"))
+    .sort();
+  const stats: GoPackageResolution = {
+    filesScanned: 0,
+    callsResolved: 0,
+    referencesResolved: 0,
+    accessesResolved: 0,
+    spawnsResolved: 0,
+    channelOpsResolved: 0,
+    membersResolved: 0,
+    implementationsResolved: 0,
+  };
+  if (indexed.length === 0) return stats;
+
+  const goFiles = new Map<string, GoFile>();
+  const packages = new Map<string, GoPackage>();
+  // Struct fields by the analyzer id of their struct and their name
+  const fields = new Map<string, Entity>();
+  for (const path of indexed) {
+    const entities = await storage.findEntities({ type: "entity", filters: { filePath: path }, limit: 10000 });
+    const pkg = entities.find((entity) => meta(entity).isPackage);
+    if (!pkg) continue;
+
+    const imports = new Map<string, string[]>();
+    for (const rel of await storage.getRelationshipsForEntity(pkg.id, RelationType.IMPORTS)) {
+      const target = rel.fromId === pkg.id ? await storage.getEntity(rel.toId) : null;
+      if (!target) continue;
+      // Imports already bound to their package container keep the path as written, and the
+      // container knows the name the package declares
+      const bound = !isPlaceholder(target);
+      const importPath = bound ? rel.metadata?.importPath : target.name;
+      if (typeof importPath !== "string") continue;
+      const alias = typeof rel.metadata?.alias === "string" ? rel.metadata.alias : undefined;
+      if (alias === "_") continue;
+      const name = alias ?? (bound ? target.name : goPackageName(importPath));
+      imports.set(name, [...(imports.get(name) ?? []), importPath]);
+    }
+
+    const packageKey = `${dirname(path)}|${pkg.name}`;
+    goFiles.set(path, { path, packageKey, entities, imports });
+    let declared = packages.get(packageKey);
+    if (!declared) {
+      declared = { dir: dirname(path), name: pkg.name, declarations: new Map() };
+      packages.set(packageKey, declared);
+    }
+    for (const entity of entities) {
+      const parent = meta(entity).parent;
+      if (entity.type === "property" && typeof parent === "string") fields.set(`${parent}|${entity.name}`, entity);
+      if (!isDeclaration(entity)) continue;
+      declared.declarations.set(entity.name, [...(declared.declarations.get(entity.name) ?? []), entity]);
+    }
+  }
+
+  // Import path of every package directory; an external test package (`foo_test`) is never imported
+  const goModules: GoModuleCache = new Map();
+  const byImportPath = new Map<string, GoPackage>();
+  for (const pkg of packages.values()) {
+    if (pkg.name.endsWith("_test")) continue;
+    byImportPath.set(goImportPath(pkg.dir, goModules), pkg);
+  }
+  const resolveImport = (importPath: string): GoPackage | undefined => {
+    const exact = byImportPath.get(importPath);
+    if (exact) return exact;
+    const bySuffix = [...byImportPath].filter(([path]) => path.endsWith(`/${importPath}`));
+    return bySuffix.length === 1 ? bySuffix[0]![1] : undefined;
+  };
+
+  // Files whose bindings may have changed: those named, their packages, and packages importing them
+  let targets = [...goFiles.values()];
+  if (files) {
+    const requested = new Set(files);
+    const touched = new Set(targets.filter((file) => requested.has(file.path)).map((file) => file.packageKey));
+    targets = targets.filter(
+      (file) =>
+        touched.has(file.packageKey) ||
+        [...file.imports.values()].flat().some((path) => {
+          const pkg = resolveImport(path);
+          return pkg !== undefined && touched.has(`${pkg.dir}|${pkg.name}`);
+        }),
+    );
+  }
+
+  const only = (candidates: Entity[] | undefined, exportedOnly: boolean): Entity | undefined => {
+    const found = (candidates ?? []).filter((entity) => !exportedOnly || isExported(entity.name));
+    return found.length === 1 ? found[0] : undefined;
+  };
+
+  for (const file of targets) {
+    stats.filesScanned += 1;
+    const own = packages.get(file.packageKey)!;
+    const dotImported = (file.imports.get(".") ?? []).map(resolveImport).filter((pkg) => pkg !== undefined);
+    const lookup = (written: string): Entity | undefined => {
+      const match = WRITTEN_NAME.exec(written);
+      if (!match) return undefined;
+      const qualifier = match[1];
+      const name = match[2]!;
+      if (!qualifier) {
+        return own.declarations.has(name)
+          ? only(own.declarations.get(name), false)
+          : dotImported.map((pkg) => only(pkg.declarations.get(name), true)).find(Boolean);
+      }
+      const imported = file.imports.get(qualifier);
+      if (!imported || imported.length !== 1) return undefined;
+      return only(resolveImport(imported[0]!)?.declarations.get(name), true);
+    };
+
+    // A field through the type its operand has at every site (`Server.users`), a value by its name
+    const lookupAccess = (written: string, sites: AccessSite[]): Entity | undefined => {
+      const receiverTypes = new Set(sites.map((site) => site.receiverType));
+      if (receiverTypes.size !== 1) return undefined;
+      const receiverType = [...receiverTypes][0];
+      if (!receiverType) {
+        const value = lookup(written);
+        return value && VALUE_KINDS.has(String(value.type)) ? value : undefined;
+      }
+      const field = written.slice(receiverType.length + 1);
+      const owner = lookup(receiverType);
+      if (!owner || (receiverType.includes(".") && !isExported(field))) return undefined;
+      return fields.get(`${owner.filePath}:type:${owner.name}|${field}`);
+    };
+
+    const moved: Relationship[] = [];
+    for (const entity of file.entities) {
+      for (const rel of await storage.getRelationshipsForEntity(entity.id)) {
+        if (rel.fromId !== entity.id || !BOUND_EDGES.has(rel.type)) continue;
+        const placeholder = await storage.getEntity(rel.toId);
+        if (!placeholder || !isPlaceholder(placeholder)) continue;
+
+        let target: Entity | undefined;
+        if (rel.type === RelationType.CONTAINS) {
+          // A receiver type is always declared in the method's own package
+          const receiver = only(own.declarations.get(placeholder.name), false);
+          target = receiver && RECEIVER_KINDS.has(String(receiver.type)) ? receiver : undefined;
+        } else if (ACCESS_EDGES.has(rel.type)) {
+          target = lookupAccess(placeholder.name, rel.metadata?.accessSites ?? []);
+        } else {
+          // Calls name their callee at each site; all of them have to agree
+          let written = placeholder.name;
+          if (CALL_EDGES.has(rel.type)) {
+            const callees = new Set((rel.metadata?.callSites ?? []).map((site: { callee?: unknown }) => site.callee));
+            if (callees.size !== 1) continue;
+            written = String([...callees][0]);
+          }
+          target = lookup(written);
+        }
+        if (!target || target.id === entity.id) continue;
+
+        await storage.deleteRelationship(rel.id);
+        moved.push({ ...rel, toId: target.id, metadata: reboundMetadata(rel.metadata, "package") });
+        if (rel.type === RelationType.CALLS) stats.callsResolved += 1;
+        else if (rel.type === RelationType.ACCESSES) stats.accessesResolved += 1;
+        else if (rel.type === RelationType.SPAWNS) stats.spawnsResolved += 1;
+        else if (ACCESS_EDGES.has(rel.type)) stats.channelOpsResolved += 1;
+        else if (rel.type === RelationType.CONTAINS) stats.membersResolved += 1;
+        else stats.referencesResolved += 1;
+      }
+    }
+    if (moved.length > 0) await storage.insertRelationships(moved);
+  }
+
+  const touchedPackages = new Set(targets.map((file) => file.packageKey));
+  for (const packageKey of touchedPackages) {
+    const files = [...goFiles.values()].filter((file) => file.packageKey === packageKey);
+    stats.implementationsResolved += await linkImplementations(storage, files);
+  }
+
+  return stats;
+}
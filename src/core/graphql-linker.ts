@@ -0,0 +1,219 @@
+/**
+ * GraphQL Linker - binds schema types across `.graphql` files and links resolvers to fields
+ * A schema is usually split over several files, so a field whose type is declared elsewhere, an
+ * `implements` clause and an `extend type` are left at `external://` placeholders by per-file
+ * indexing; once every schema file is indexed each is bound to the type of that name (GraphQL has
+ * one global namespace). Resolvers are then found by name: a function or method named like a
+ * field (`user`, `resolveUser`, `resolve_user`) whose owner is named like the field's type
+ * (`Query`, `UserResolver`, `resolvers.Query`) or carries a `@Query()`/`@Mutation()` decorator
+ * gets a `resolves` edge to the field. One only named like a field, in a resolver file or class,
+ * is linked as a heuristic when no other field shares the name.
+ */
+
+import { extname } from "node:path";
+import { isTestFile } from "../parsers/test-extractor.js";
+import { stableRelationshipId } from "../storage/entity-id.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { type Entity, EntityType, type Relationship, RelationType } from "../types/storage.js";
+import { edgeConfidence, reboundMetadata } from "./edge-confidence.js";
+
+export interface GraphqlLinking {
+  filesScanned: number;
+  typesResolved: number;
+  resolversLinked: number;
+}
+
+const TYPE_KINDS = new Set(["type", "interface", "input", "enum", "union", "scalar"]);
+const FIELD_KINDS = new Set(["field", "operation"]);
+const RESOLVER_KINDS = ["function", "method", "property"] as EntityType[];
+const SCHEMA_EXTENSIONS = new Set([".graphql", ".gql", ".graphqls"]);
+
+// Decorators of code-first and schema-first frameworks (NestJS, TypeGraphQL) on resolver methods
+const OPERATION_DECORATORS: Record<string, string> = {
+  Query: "query",
+  Mutation: "mutation",
+  Subscription: "subscription",
+};
+const FIELD_DECORATORS = new Set(["ResolveField", "FieldResolver", "ResolveProperty"]);
+
+function meta(entity: Entity): Record<string, any> {
+  return (entity.metadata ?? {}) as Record<string, any>;
+}
+
+function isPlaceholder(entity: Entity): boolean {
+  return entity.filePath.startsWith("external://");
+}
+
+function isGraphql(filePath: string): boolean {
+  return SCHEMA_EXTENSIONS.has(extname(filePath).toLowerCase());
+}
+
+/** `resolveUser`, `resolve_user` and `user` all name the field `user` */
+function fieldKey(name: string): string {
+  const key = name.toLowerCase().replace(/[^a-z0-9]/g, "");
+  return key.startsWith("resolve") && key.length > "resolve".length ? key.slice("resolve".length) : key;
+}
+
+/** `UserResolver`, `user_resolvers` and `User` all name the type `User` */
+function ownerKey(name: string): string {
+  return name
+    .toLowerCase()
+    .replace(/[^a-z0-9]/g, "")
+    .replace(/resolvers?$/, "");
+}
+
+/** The type or object a resolver candidate is declared in, when the analyzer recorded one */
+function ownerOf(entity: Entity): string | null {
+  const m = meta(entity);
+  for (const field of ["className", "receiver", "implType", "parentClass"]) {
+    if (typeof m[field] === "string" && m[field]) return m[field];
+  }
+  const qualified = typeof m.qualifiedName === "string" ? m.qualifiedName.split(/::|\./) : [];
+  return qualified.length > 1 ? qualified[qualified.length - 2]! : null;
+}
+
+async function* pagedEntities(storage: GraphStorageImpl, types: EntityType[]): AsyncGenerator<Entity[]> {
+  let afterId: string | undefined;
+  for (;;) {
+    const page = await storage.findEntities({ type: "entity", filters: { entityType: types }, limit: 1000, afterId });
+    if (page.length === 0) return;
+    yield page;
+    afterId = page[page.length - 1]!.id;
+  }
+}
+
+/**
+ * Bind the type references of the schema files among `files` (every indexed one when omitted),
+ * then rebuild the `resolves` edges of the resolver candidates they affect: every candidate in
+ * the project when a schema file is among them, else those declared in `files`.
+ */
+export async function linkGraphqlSchema(storage: GraphStorageImpl, files?: string[]): Promise<GraphqlLinking> {
+  const indexedFiles = (await storage.listIndexedFiles()).map((info) => info.path).sort();
+  const indexed = indexedFiles.filter(isGraphql);
+  const wanted = [...new Set(files ?? indexedFiles)];
+  const targets = wanted.filter((file) => indexed.includes(file)).sort();
+  const stats: GraphqlLinking = { filesScanned: targets.length, typesResolved: 0, resolversLinked: 0 };
+  if (indexed.length === 0) return stats;
+
+  const entitiesByFile = new Map<string, Entity[]>();
+  const types = new Map<string, Entity[]>();
+  const fields = new Map<string, Entity[]>();
+  for (const file of indexed) {
+    const entities = await storage.findEntities({ type: "entity", filters: { filePath: file }, limit: 10000 });
+    entitiesByFile.set(file, entities);
+    for (const entity of entities) {
+      const kind = meta(entity).graphqlKind;
+      if (TYPE_KINDS.has(kind) && meta(entity).extension !== true) {
+        types.set(entity.name, [...(types.get(entity.name) ?? []), entity]);
+      } else if (FIELD_KINDS.has(kind)) {
+        const key = fieldKey(entity.name);
+        fields.set(key, [...(fields.get(key) ?? []), entity]);
+      }
+    }
+  }
+
+  for (const file of targets) {
+    const moved: Relationship[] = [];
+    for (const source of entitiesByFile.get(file) ?? []) {
+      if (!meta(source).graphqlKind) continue;
+      const bound = new Set<string>();
+      for (const type of [RelationType.REFERENCES, RelationType.IMPLEMENTS, RelationType.EXTENSION_OF]) {
+        for (const rel of await storage.getRelationshipsForEntity(source.id, type)) {
+          if (rel.fromId !== source.id) continue;
+          const placeholder = await storage.getEntity(rel.toId);
+          if (!placeholder || !isPlaceholder(placeholder)) continue;
+          // A name declared twice is ambiguous; the schema would not build, so neither is picked
+          const declared = types.get(placeholder.name) ?? [];
+          if (declared.length !== 1) continue;
+          const target = declared[0]!;
+
+          await storage.deleteRelationship(rel.id);
+          stats.typesResolved += 1;
+          if (bound.has(`${type}:${target.id}`)) continue;
+          bound.add(`${type}:${target.id}`);
+          moved.push({ ...rel, toId: target.id, metadata: reboundMetadata(rel.metadata, "graphql") });
+        }
+      }
+    }
+    if (moved.length > 0) await storage.insertRelationships(moved);
+  }
+
+  const candidates: Entity[] = [];
+  const keep = (entity: Entity) =>
+    RESOLVER_KINDS.includes(entity.type) &&
+    !isPlaceholder(entity) &&
+    !isGraphql(entity.filePath) &&
+    !isTestFile(entity.filePath) &&
+    fields.has(fieldKey(entity.name));
+  if (files === undefined || targets.length > 0) {
+    for await (const page of pagedEntities(storage, RESOLVER_KINDS)) candidates.push(...page.filter(keep));
+  } else {
+    for (const file of wanted.filter((path) => !isGraphql(path))) {
+      const entities = await storage.findEntities({ type: "entity", filters: { filePath: file }, limit: 10000 });
+      candidates.push(...entities.filter(keep));
+    }
+  }
+  const scanned = new Set(candidates.map((entity) => entity.id));
+  if (files === undefined || targets.length > 0) {
+    // Every candidate is rescanned; edges of resolvers renamed away from their field go too
+    for (let offset = 0; ; offset += 1000) {
+      const page = await storage.findRelationships({
+        type: "relationship",
+        filters: { relationshipType: RelationType.RESOLVES },
+        limit: 1000,
+        offset,
+      });
+      for (const rel of page) scanned.add(rel.fromId);
+      if (page.length < 1000) break;
+    }
+  }
+  for (const id of scanned) {
+    for (const rel of await storage.getRelationshipsForEntity(id, RelationType.RESOLVES)) {
+      if (rel.fromId === id) await storage.deleteRelationship(rel.id);
+    }
+  }
+
+  const links: Relationship[] = [];
+  for (const candidate of candidates) {
+    const named = fields.get(fieldKey(candidate.name)) ?? [];
+    const decorators = (await storage.getRelationshipsForEntity(candidate.id, RelationType.DECORATED_BY))
+      .filter((rel) => rel.fromId === candidate.id)
+      .map((rel) => String(rel.metadata?.decorator ?? ""));
+    const operations = new Set(decorators.map((name) => OPERATION_DECORATORS[name]).filter(Boolean));
+    const owner = ownerOf(candidate);
+    const ownerName = owner ? ownerKey(owner) : null;
+
+    const owned = named.filter(
+      (field) =>
+        (ownerName && ownerKey(String(meta(field).parentType ?? "")) === ownerName) ||
+        operations.has(meta(field).operation),
+    );
+    const inResolver =
+      /resolver/i.test(candidate.filePath) ||
+      /resolver/i.test(owner ?? "") ||
+      operations.size > 0 ||
+      decorators.some((name) => FIELD_DECORATORS.has(name));
+    const matched = owned.length > 0 ? owned : inResolver && named.length === 1 ? named : [];
+
+    for (const field of matched) {
+      links.push({
+        id: stableRelationshipId(candidate.id, field.id, RelationType.RESOLVES),
+        fromId: candidate.id,
+        toId: field.id,
+        type: RelationType.RESOLVES,
+        metadata: {
+          line: candidate.location.start.line,
+          field: meta(field).qualifiedName ?? field.name,
+          match: owned.length > 0 ? "owner" : "name",
+          ...edgeConfidence(owned.length > 0 ? "name_match" : "heuristic"),
+        },
+      });
+    }
+  }
+  if (links.length > 0) {
+    await storage.insertRelationships(links);
+    stats.resolversLinked = links.length;
+  }
+
+  return stats;
+}
@@ -0,0 +1,213 @@
+/**
+ * Header Linker - connects C and C++ translation units through the headers they include
+ * Runs after override resolution. Per-file indexing leaves `#include` edges and calls to functions
+ * declared elsewhere at `external://` placeholders; once the headers are indexed this binds each
+ * include to the header's translation unit, links every definition to the prototypes it
+ * implements with a defines edge, and moves calls onto the definition behind the prototype.
+ * Defines edges of the scanned definitions are rebuilt each run.
+ */
+
+import { basename, dirname, extname, join, normalize } from "node:path";
+import { stableRelationshipId } from "../storage/entity-id.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { type Entity, type Relationship, RelationType } from "../types/storage.js";
+import { reboundMetadata } from "./edge-confidence.js";
+import { isCompatibleOverride, overrideRule } from "./override-resolver.js";
+
+export interface HeaderLinking {
+  filesScanned: number;
+  includesResolved: number;
+  definitionsLinked: number;
+  callsRetargeted: number;
+}
+
+const SOURCE_EXTENSIONS = [".c", ".cpp", ".cc", ".cxx"];
+const HEADER_EXTENSIONS = [".h", ".hpp", ".hh", ".hxx"];
+
+function isCFamily(filePath: string): boolean {
+  const ext = extname(filePath).toLowerCase();
+  return SOURCE_EXTENSIONS.includes(ext) || HEADER_EXTENSIONS.includes(ext);
+}
+
+function isFunction(entity: Entity): boolean {
+  return entity.type === "function" || entity.type === "method";
+}
+
+function isDefinition(entity: Entity): boolean | undefined {
+  const flag = (entity.metadata as Record<string, unknown> | undefined)?.isDefinition;
+  return typeof flag === "boolean" ? flag : undefined;
+}
+
+function isPlaceholder(entity: Entity): boolean {
+  return entity.filePath.startsWith("external://");
+}
+
+/**
+ * Whether `definition` implements `declaration`. A definition written after `using namespace ns`
+ * is named `Widget::draw` while the header declares `ns::Widget::draw`, so a qualified suffix
+ * matches too; C++ sources also compare parameter types, since the name alone may be overloaded.
+ */
+function implementsDeclaration(definition: Entity, declaration: Entity): boolean {
+  if (declaration.name !== definition.name && !declaration.name.endsWith(`::${definition.name}`)) return false;
+  const rule = overrideRule(definition.filePath) === "parameters" ? "parameters" : "name";
+  return isCompatibleOverride({ ...definition, name: declaration.name }, declaration, rule);
+}
+
+/**
+ * Link the C and C++ files among `files` (every indexed one when omitted) to their headers. An
+ * include is resolved against the including file's directory first, then as the only indexed
+ * header whose path ends with it. A source file's own header (`widget.h` for `widget.c`) counts
+ * as included.
+ */
+export async function linkHeaders(storage: GraphStorageImpl, files?: string[]): Promise<HeaderLinking> {
+  const indexed = (await storage.listIndexedFiles()).map((info) => info.path).filter(isCFamily);
+  const indexedSet = new Set(indexed);
+  const targets = [...new Set(files ?? indexed)].filter((file) => indexedSet.has(file)).sort();
+  const stats: HeaderLinking = { filesScanned: 0, includesResolved: 0, definitionsLinked: 0, callsRetargeted: 0 };
+  if (targets.length === 0) return stats;
+
+  const headersByName = new Map<string, string[]>();
+  for (const path of indexed) {
+    if (!HEADER_EXTENSIONS.includes(extname(path).toLowerCase())) continue;
+    const name = basename(path);
+    headersByName.set(name, [...(headersByName.get(name) ?? []), path]);
+  }
+
+  const cache = new Map<string, Entity[]>();
+  const entitiesOf = async (filePath: string): Promise<Entity[]> => {
+    let entities = cache.get(filePath);
+    if (!entities) {
+      entities = await storage.findEntities({ type: "entity", filters: { filePath }, limit: 10000 });
+      cache.set(filePath, entities);
+    }
+    return entities;
+  };
+  const translationUnitOf = async (filePath: string) =>
+    (await entitiesOf(filePath)).find((e) => (e.metadata as Record<string, unknown>)?.translationUnit === true);
+
+  const resolveInclude = (includer: string, spec: string): string | null => {
+    const local = normalize(join(dirname(includer), spec));
+    if (indexedSet.has(local)) return local;
+    const suffix = normalize(spec).replace(/^(\.\.?\/)+/, "");
+    const matches = (headersByName.get(basename(suffix)) ?? []).filter(
+      (path) => path === suffix || path.endsWith(`/${suffix}`),
+    );
+    return matches.length === 1 ? matches[0]! : null;
+  };
+
+  const headersOf = new Map<string, string[]>();
+
+  // Includes and defines edges first, so every definition is known before calls are moved
+  for (const file of targets) {
+    stats.filesScanned += 1;
+    const headers = new Set<string>();
+
+    const unit = await translationUnitOf(file);
+    if (unit) {
+      const moved: Relationship[] = [];
+      for (const rel of await storage.getRelationshipsForEntity(unit.id, RelationType.IMPORTS)) {
+        if (rel.fromId !== unit.id) continue;
+        const target = await storage.getEntity(rel.toId);
+        if (!target) continue;
+        if (!isPlaceholder(target)) {
+          headers.add(target.filePath);
+          continue;
+        }
+        const header = resolveInclude(file, target.name);
+        const headerUnit = header ? await translationUnitOf(header) : undefined;
+        if (!header || !headerUnit) continue;
+        headers.add(header);
+        await storage.deleteRelationship(rel.id);
+        moved.push({ ...rel, toId: headerUnit.id, metadata: reboundMetadata(rel.metadata, "include") });
+      }
+      if (moved.length > 0) {
+        await storage.insertRelationships(moved);
+        stats.includesResolved += moved.length;
+      }
+    }
+
+    const stem = file.slice(0, file.length - extname(file).length);
+    for (const ext of HEADER_EXTENSIONS) {
+      if (indexedSet.has(`${stem}${ext}`) && `${stem}${ext}` !== file) headers.add(`${stem}${ext}`);
+    }
+    headersOf.set(file, [...headers].sort());
+
+    const declarations: Entity[] = [];
+    for (const source of [file, ...headers]) {
+      for (const entity of await entitiesOf(source)) {
+        if (isFunction(entity) && isDefinition(entity) === false) declarations.push(entity);
+      }
+    }
+
+    for (const definition of await entitiesOf(file)) {
+      if (!isFunction(definition) || !isDefinition(definition)) continue;
+
+      const existing = await storage.getRelationshipsForEntity(definition.id, RelationType.DEFINES);
+      for (const rel of existing) if (rel.fromId === definition.id) await storage.deleteRelationship(rel.id);
+
+      const found: Relationship[] = declarations
+        .filter((declaration) => implementsDeclaration(definition, declaration))
+        .map((declaration) => ({
+          id: stableRelationshipId(definition.id, declaration.id, RelationType.DEFINES),
+          fromId: definition.id,
+          toId: declaration.id,
+          type: RelationType.DEFINES,
+          metadata: { line: definition.location.start.line, declaredIn: declaration.filePath },
+        }));
+      if (found.length > 0) {
+        await storage.insertRelationships(found);
+        stats.definitionsLinked += found.length;
+      }
+    }
+  }
+
+  // The definition behind a prototype, when exactly one translation unit defines it
+  const definitionOf = async (declaration: Entity): Promise<string> => {
+    const defines = await storage.getRelationshipsForEntity(declaration.id, RelationType.DEFINES);
+    const definitions = new Set(defines.filter((rel) => rel.toId === declaration.id).map((rel) => rel.fromId));
+    return definitions.size === 1 ? [...definitions][0]! : declaration.id;
+  };
+
+  for (const file of targets) {
+    const headers = headersOf.get(file) ?? [];
+    const moved: Relationship[] = [];
+
+    for (const caller of await entitiesOf(file)) {
+      if (!isFunction(caller)) continue;
+      for (const rel of await storage.getRelationshipsForEntity(caller.id, RelationType.CALLS)) {
+        if (rel.fromId !== caller.id) continue;
+        const callee = await storage.getEntity(rel.toId);
+        if (!callee) continue;
+
+        let candidates: Entity[] = [];
+        if (callee.filePath === `external://${file}`) {
+          for (const header of headers) {
+            for (const entity of await entitiesOf(header)) {
+              if (isFunction(entity) && isDefinition(entity) === false && entity.name === callee.name) {
+                candidates.push(entity);
+              }
+            }
+          }
+        } else if (isFunction(callee) && isDefinition(callee) === false) {
+          candidates = [callee];
+        }
+        if (candidates.length === 0) continue;
+
+        const resolved = new Set<string>();
+        for (const declaration of candidates) resolved.add(await definitionOf(declaration));
+        const target = resolved.size === 1 ? [...resolved][0]! : null;
+        if (!target || target === rel.toId) continue;
+
+        await storage.deleteRelationship(rel.id);
+        moved.push({ ...rel, toId: target, metadata: reboundMetadata(rel.metadata, "header") });
+      }
+    }
+
+    if (moved.length > 0) {
+      await storage.insertRelationships(moved);
+      stats.callsRetargeted += moved.length;
+    }
+  }
+
+  return stats;
+}
@@ -0,0 +1,310 @@
+/**
+ * Import Resolver - binds every import to the file, package or module it loads
+ * Per-file indexing records an import as written: a TS/JS specifier, a Python dotted module, a Go
+ * import path. Once the project is indexed and its package tree built, a TS/JS or Python import
+ * gets an `imports` edge to the module entity standing for the file it loads, and a Go package's
+ * import edge is moved from its placeholder onto the imported package's container. Imports of
+ * anything the index does not hold (third-party packages, the standard library) point at an
+ * `external://` placeholder for the module and are tagged `external`. The names an import binds
+ * are left to the cross-file resolver; this pass is about which module depends on which.
+ */
+
+import { existsSync } from "node:fs";
+import { basename, dirname, extname, join } from "node:path";
+import { externalPlaceholderId, stableEntityId, stableRelationshipId } from "../storage/entity-id.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { type Entity, EntityType, type Relationship, RelationType } from "../types/storage.js";
+import { resolveRelativeModule, resolveWorkspaceModule } from "./cross-file-resolver.js";
+import { edgeConfidence, reboundMetadata } from "./edge-confidence.js";
+import { listContainers } from "./package-tree.js";
+import { readWorkspacePackages } from "./workspace-roots.js";
+
+export interface ImportResolution {
+  filesScanned: number;
+  /** Imports bound to an indexed file or Go package */
+  importsResolved: number;
+  /** Imports of modules the index does not hold */
+  importsExternal: number;
+}
+
+export interface ImportResolveOptions {
+  /** Roots whose package names bare TS/JS imports may use (default: every root recorded in the index) */
+  roots?: string[];
+}
+
+/** Indexed Python modules by every dotted name a path suffix gives them (`mod`, `pkg.mod`, ...) */
+export type PythonModuleIndex = {
+  files: ReadonlySet<string>;
+  byName: ReadonlyMap<string, string[]>;
+};
+
+const SCRIPT_FILE = /\.[cm]?[jt]sx?$/;
+const PYTHON_FILE = /\.pyi?$/;
+const IMPORT_PAGE_SIZE = 1000;
+
+function meta(entity: Entity): Record<string, any> {
+  return (entity.metadata ?? {}) as Record<string, any>;
+}
+
+function isPlaceholder(entity: Entity): boolean {
+  return entity.filePath.startsWith("external://");
+}
+
+/** Id of the module entity standing for a TS/JS or Python file as an import target */
+export function moduleEntityId(filePath: string): string {
+  return stableEntityId({ name: filePath, type: "module" as EntityType, filePath });
+}
+
+export function isModuleEntity(entity: Entity): boolean {
+  return String(entity.type) === "module" && meta(entity).moduleFile === true;
+}
+
+export function pythonModuleIndex(files: readonly string[]): PythonModuleIndex {
+  const byName = new Map<string, string[]>();
+  for (const file of files) {
+    if (!PYTHON_FILE.test(file)) continue;
+    const segments = file.split("/").filter(Boolean);
+    const last = segments.pop()!.replace(PYTHON_FILE, "");
+    if (last !== "__init__") segments.push(last);
+    for (let i = segments.length - 1; i >= 0; i--) {
+      const name = segments.slice(i).join(".");
+      byName.set(name, [...(byName.get(name) ?? []), file]);
+    }
+  }
+  return { files: new Set(files), byName };
+}
+
+/**
+ * The indexed file a Python module name loads: `pkg.mod` as `pkg/mod.py`, its stub, or
+ * `pkg/mod/__init__.py`. An absolute name is looked up from the importing file's directory and
+ * each one above it, as scripts and projects put those on `sys.path`, then as the only indexed
+ * module with that name (a `src/` layout imported from `tests/`). A relative name (`.mod`, `..`)
+ * starts from the importing file's package.
+ */
+export function resolvePythonModule(importerPath: string, module: string, index: PythonModuleIndex): string | null {
+  const relative = /^(\.+)(.*)$/.exec(module);
+  const dotted = relative ? relative[2]! : module;
+  const path = dotted.split(".").filter(Boolean).join("/");
+  const candidates = (dir: string) =>
+    path ? [`${path}.py`, `${path}.pyi`, `${path}/__init__.py`].map((f) => join(dir, f)) : [join(dir, "__init__.py")];
+
+  if (relative) {
+    let dir = dirname(importerPath);
+    for (let level = 1; level < relative[1]!.length; level++) dir = dirname(dir);
+    return candidates(dir).find((file) => index.files.has(file)) ?? null;
+  }
+  if (!path) return null;
+  for (let at = dirname(importerPath); ; at = dirname(at)) {
+    const found = candidates(at).find((file) => index.files.has(file));
+    if (found) return found;
+    if (dirname(at) === at) break;
+  }
+  // A module and its stub are one module
+  const found = index.byName.get(dotted) ?? [];
+  const modules = new Set(found.map((file) => file.replace(/\.pyi$/, ".py")));
+  return modules.size === 1 ? (found.find((file) => file.endsWith(".py")) ?? found[0]!) : null;
+}
+
+/** Module names a Python import loads: each `import` name, or the `from` module and any submodule it names */
+function pythonModulesOf(data: Record<string, any>): Array<{ name: string; optional: boolean }> {
+  const specifiers: Array<{ local: string; imported?: string }> = data.specifiers ?? [];
+  if (typeof data.fromModule !== "string") {
+    return specifiers.map((spec) => ({ name: spec.imported ?? spec.local, optional: false }));
+  }
+  const from = data.fromModule;
+  const submodules = specifiers.map((spec) => ({
+    name: from.endsWith(".") ? `${from}${spec.imported ?? spec.local}` : `${from}.${spec.imported ?? spec.local}`,
+    // `from pkg import name` names a submodule only when there is one
+    optional: true,
+  }));
+  return [{ name: from, optional: false }, ...submodules];
+}
+
+function languageOf(filePath: string): string {
+  if (PYTHON_FILE.test(filePath)) return "python";
+  return /\.[cm]?tsx?$/.test(filePath) ? "typescript" : "javascript";
+}
+
+function moduleEntity(filePath: string, now: number): Entity {
+  const file = basename(filePath, extname(filePath));
+  return {
+    id: moduleEntityId(filePath),
+    name: file === "__init__" || file === "index" ? basename(dirname(filePath)) : file,
+    type: "module" as EntityType,
+    filePath,
+    location: { start: { line: 0, column: 0, index: 0 }, end: { line: 0, column: 0, index: 0 } },
+    metadata: { moduleFile: true } as Entity["metadata"],
+    hash: `module:${filePath}`,
+    createdAt: now,
+    updatedAt: now,
+    language: languageOf(filePath),
+  };
+}
+
+function modulePlaceholder(name: string, now: number): Entity {
+  return {
+    id: externalPlaceholderId(name, name),
+    name,
+    type: EntityType.IMPORT,
+    filePath: `external://${name}`,
+    location: { start: { line: 0, column: 0, index: 0 }, end: { line: 0, column: 0, index: 0 } },
+    metadata: { isExternal: true, source: name, symbol: name } as Entity["metadata"],
+    hash: `external:${name}:${name}`,
+    createdAt: now,
+    updatedAt: now,
+  };
+}
+
+/**
+ * Bind the imports of `files` (every indexed file when omitted), and the imports landing in them,
+ * to what they load. TS/JS specifiers resolve as the cross-file resolver resolves them (relative
+ * paths, then workspace packages); every Go import edge is looked at each run, since a package
+ * that appears binds the imports of files that did not change.
+ */
+export async function resolveImports(
+  storage: GraphStorageImpl,
+  files?: string[],
+  options: ImportResolveOptions = {},
+): Promise<ImportResolution> {
+  const indexedPaths = (await storage.listIndexedFiles()).map((info) => info.path).sort();
+  const indexed = new Set(indexedPaths);
+  const isIndexed = (path: string) => indexed.has(path) && existsSync(path);
+  const packages = readWorkspacePackages(options.roots ?? (await storage.listIndexRoots()));
+  const python = pythonModuleIndex(indexedPaths.filter((path) => PYTHON_FILE.test(path)));
+  const requested = files ? new Set(files.filter((file) => indexed.has(file))) : null;
+  const stats: ImportResolution = { filesScanned: 0, importsResolved: 0, importsExternal: 0 };
+  const now = Date.now();
+
+  // Files an import loads, and the module names it loads that the index does not hold
+  const targetsOf = (entity: Entity): { files: Map<string, string>; external: string[] } | null => {
+    const data = meta(entity).importData;
+    if (!data || typeof data.source !== "string") return null;
+    const loaded = new Map<string, string>();
+    const external: string[] = [];
+    if (SCRIPT_FILE.test(entity.filePath)) {
+      const target =
+        resolveRelativeModule(entity.filePath, data.source, isIndexed) ??
+        resolveWorkspaceModule(data.source, packages, isIndexed);
+      if (target) loaded.set(target, data.source);
+      else if (data.source) external.push(data.source);
+    } else if (PYTHON_FILE.test(entity.filePath)) {
+      for (const { name, optional } of pythonModulesOf(data)) {
+        const target = resolvePythonModule(entity.filePath, name, python);
+        if (target && target !== entity.filePath) {
+          if (!loaded.has(target)) loaded.set(target, name);
+        } else if (!optional && !target) {
+          external.push(name);
+        }
+      }
+    } else {
+      return null;
+    }
+    return { files: loaded, external };
+  };
+
+  const pending: Array<{ entity: Entity; files: Map<string, string>; external: string[] }> = [];
+  let afterId: string | undefined;
+  while (true) {
+    const page = await storage.findEntities({
+      type: "entity",
+      filters: { entityType: EntityType.IMPORT },
+      afterId,
+      limit: IMPORT_PAGE_SIZE,
+    });
+    for (const entity of page) {
+      if (isPlaceholder(entity) || !indexed.has(entity.filePath)) continue;
+      const targets = targetsOf(entity);
+      if (!targets) continue;
+      // Indexing a and then b in two runs must bind what indexing both at once binds
+      const touched =
+        !requested || requested.has(entity.filePath) || [...targets.files.keys()].some((file) => requested.has(file));
+      if (touched) pending.push({ entity, ...targets });
+    }
+    if (page.length < IMPORT_PAGE_SIZE) break;
+    afterId = page[page.length - 1]!.id;
+  }
+
+  const ensured = new Set<string>();
+  const ensure = async (entity: Entity) => {
+    if (ensured.has(entity.id)) return;
+    ensured.add(entity.id);
+    if (!(await storage.getEntity(entity.id))) await storage.insertEntities([entity]);
+  };
+
+  stats.filesScanned += new Set(pending.map(({ entity }) => entity.filePath)).size;
+  for (const { entity, files: loaded, external } of pending) {
+    const line = entity.location?.start?.line;
+    const wanted = new Map<string, Relationship>();
+    const add = (toId: string, metadata: Relationship["metadata"]) => {
+      const id = stableRelationshipId(entity.id, toId, RelationType.IMPORTS);
+      wanted.set(id, { id, fromId: entity.id, toId, type: RelationType.IMPORTS, metadata } as Relationship);
+    };
+    for (const [file, written] of loaded) {
+      await ensure(moduleEntity(file, now));
+      add(moduleEntityId(file), { line, moduleImport: written, resolvedFile: file, ...edgeConfidence("resolved") });
+    }
+    for (const name of external) {
+      await ensure(modulePlaceholder(name, now));
+      const metadata = { line, moduleImport: name, external: true, ...edgeConfidence("heuristic") };
+      add(externalPlaceholderId(name, name), metadata);
+    }
+
+    // Bound modules lose the placeholder edge indexing gave them (Python `import os` names its module)
+    const bound = new Set([...loaded.values()].map((name) => externalPlaceholderId(name, name)));
+    for (const rel of await storage.getRelationshipsForEntity(entity.id, RelationType.IMPORTS)) {
+      if (rel.fromId !== entity.id || wanted.has(rel.id)) continue;
+      if (rel.metadata?.moduleImport !== undefined || bound.has(rel.toId)) await storage.deleteRelationship(rel.id);
+    }
+    if (wanted.size > 0) await storage.insertRelationships([...wanted.values()]);
+    stats.importsResolved += loaded.size > 0 ? 1 : 0;
+    stats.importsExternal += loaded.size === 0 && external.length > 0 ? 1 : 0;
+  }
+
+  // Go: package clauses import placeholders named by import path, bound here to package containers
+  const goPackages = new Map<string, Entity>();
+  for (const container of await listContainers(storage)) {
+    const importPath = meta(container).qualifiedName;
+    if (meta(container).container === "go_package" && typeof importPath === "string") {
+      goPackages.set(importPath, container);
+    }
+  }
+  const resolveGoImport = (importPath: string): Entity | undefined => {
+    const exact = goPackages.get(importPath);
+    if (exact) return exact;
+    const bySuffix = [...goPackages].filter(([path]) => path.endsWith(`/${importPath}`));
+    return bySuffix.length === 1 ? bySuffix[0]![1] : undefined;
+  };
+
+  for (const path of indexedPaths) {
+    if (!path.endsWith(".go")) continue;
+    const entities = await storage.findEntities({ type: "entity", filters: { filePath: path }, limit: 10000 });
+    const clause = entities.find((entity) => meta(entity).isPackage);
+    if (!clause) continue;
+    stats.filesScanned += 1;
+
+    const moved: Relationship[] = [];
+    for (const rel of await storage.getRelationshipsForEntity(clause.id, RelationType.IMPORTS)) {
+      if (rel.fromId !== clause.id) continue;
+      const target = await storage.getEntity(rel.toId);
+      if (!target || !isPlaceholder(target)) continue;
+      const pkg = resolveGoImport(target.name);
+      if (!pkg) {
+        stats.importsExternal += 1;
+        if (rel.metadata?.external !== true) moved.push({ ...rel, metadata: { ...rel.metadata, external: true } });
+        continue;
+      }
+      const { external: _external, ...written } = rel.metadata ?? {};
+      await storage.deleteRelationship(rel.id);
+      moved.push({
+        ...rel,
+        toId: pkg.id,
+        // The path as written, which the package resolver and unused-import check still read
+        metadata: { ...reboundMetadata(written, "import"), importPath: target.name },
+      });
+      stats.importsResolved += 1;
+    }
+    if (moved.length > 0) await storage.insertRelationships(moved);
+  }
+
+  return stats;
+}
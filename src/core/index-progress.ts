@@ -0,0 +1,166 @@
+/**
+ * Progress of indexing runs, keyed by the id of the task the tool call created. The dev agent
+ * reports as it goes; get_index_progress reads it and MCP progress notifications are sent from it.
+ */
+
+export type IndexPhase = "discovering" | "parsing" | "resolving" | "embedding" | "completed" | "failed" | "cancelled";
+
+export interface IndexProgress {
+  taskId: string;
+  phase: IndexPhase;
+  filesProcessed: number;
+  totalFiles: number;
+  percent: number;
+  /** Files per second since parsing began */
+  filesPerSecond: number;
+  /** Estimated time left for the files still to parse; null outside the parsing phase */
+  etaMs: number | null;
+  startedAt: number;
+  updatedAt: number;
+  error?: string;
+}
+
+type ProgressListener = (progress: IndexProgress) => void;
+
+const TERMINAL: ReadonlySet<IndexPhase> = new Set(["completed", "failed", "cancelled"]);
+const RUN_LIMIT = 50;
+
+export class IndexProgressTracker {
+  private runs = new Map<string, IndexProgress & { parseStartedAt?: number }>();
+  private listeners = new Map<string, Set<ProgressListener>>();
+
+  start(taskId: string): void {
+    const now = Date.now();
+    this.runs.set(taskId, {
+      taskId,
+      phase: "discovering",
+      filesProcessed: 0,
+      totalFiles: 0,
+      percent: 0,
+      filesPerSecond: 0,
+      etaMs: null,
+      startedAt: now,
+      updatedAt: now,
+    });
+    // Oldest first in insertion order
+    while (this.runs.size > RUN_LIMIT) {
+      const oldest = this.runs.keys().next().value;
+      if (oldest === undefined) break;
+      this.runs.delete(oldest);
+    }
+    this.notify(taskId);
+  }
+
+  /** Update a run; ids that were never started (tasks not created by a tool call) are ignored */
+  report(taskId: string, update: { phase?: IndexPhase; filesProcessed?: number; totalFiles?: number }): void {
+    const run = this.runs.get(taskId);
+    if (!run || TERMINAL.has(run.phase)) return;
+    const now = Date.now();
+    if (update.phase) run.phase = update.phase;
+    if (update.totalFiles !== undefined) run.totalFiles = update.totalFiles;
+    if (update.filesProcessed !== undefined) run.filesProcessed = Math.min(update.filesProcessed, run.totalFiles);
+    if (run.phase === "parsing" && run.parseStartedAt === undefined) run.parseStartedAt = now;
+
+    const elapsedMs = run.parseStartedAt === undefined ? 0 : now - run.parseStartedAt;
+    run.filesPerSecond = elapsedMs > 0 ? Math.round((run.filesProcessed / elapsedMs) * 10000) / 10 : 0;
+    run.percent = run.totalFiles > 0 ? Math.round((run.filesProcessed / run.totalFiles) * 100) : 0;
+    // Throughput so far is the only estimate there is; it needs at least one finished batch
+    run.etaMs =
+      run.phase === "parsing" && run.filesProcessed > 0
+        ? Math.round(((run.totalFiles - run.filesProcessed) * elapsedMs) / run.filesProcessed)
+        : null;
+    run.updatedAt = now;
+    this.notify(taskId);
+  }
+
+  finish(taskId: string, phase: "completed" | "failed" | "cancelled", error?: string): void {
+    const run = this.runs.get(taskId);
+    if (!run) return;
+    run.phase = phase;
+    run.etaMs = null;
+    run.error = error;
+    if (phase === "completed") {
+      run.filesProcessed = run.totalFiles;
+      run.percent = 100;
+    }
+    run.updatedAt = Date.now();
+    this.notify(taskId);
+  }
+
+  get(taskId: string): IndexProgress | undefined {
+    const run = this.runs.get(taskId);
+    return run ? this.snapshot(run) : undefined;
+  }
+
+  /** The most recently started run */
+  latest(): IndexProgress | undefined {
+    let last: IndexProgress | undefined;
+    for (const run of this.runs.values()) if (!last || run.startedAt >= last.startedAt) last = run;
+    return last ? this.snapshot(last) : undefined;
+  }
+
+  /** The most recent run that has not completed, failed or been cancelled */
+  running(): IndexProgress | undefined {
+    let last: IndexProgress | undefined;
+    for (const run of this.runs.values()) {
+      if (!TERMINAL.has(run.phase) && (!last || run.startedAt >= last.startedAt)) last = run;
+    }
+    return last ? this.snapshot(last) : undefined;
+  }
+
+  /** Call `listener` on every change to the run; returns the unsubscribe function */
+  onProgress(taskId: string, listener: ProgressListener): () => void {
+    let set = this.listeners.get(taskId);
+    if (!set) {
+      set = new Set();
+      this.listeners.set(taskId, set);
+    }
+    set.add(listener);
+    return () => {
+      set.delete(listener);
+      if (set.size === 0) this.listeners.delete(taskId);
+    };
+  }
+
+  private snapshot(run: IndexProgress & { parseStartedAt?: number }): IndexProgress {
+    const { parseStartedAt: _parseStartedAt, ...progress } = run;
+    return progress;
+  }
+
+  private notify(taskId: string): void {
+    const listeners = this.listeners.get(taskId);
+    const run = this.runs.get(taskId);
+    if (!listeners || !run) return;
+    const progress = this.snapshot(run);
+    for (const listener of listeners) {
+      try {
+        listener(progress);
+      } catch {
+        // A failing listener must not interrupt indexing
+      }
+    }
+  }
+}
+
+/**
+ * One number for the whole run, 0-100, that only grows as the run moves on: parsing covers most of
+ * it by files done, the later phases take fixed steps. Null once the run failed or was cancelled.
+ */
+export function overallPercent(progress: IndexProgress): number | null {
+  switch (progress.phase) {
+    case "discovering":
+      return 0;
+    case "parsing":
+      return Math.floor(progress.percent * 0.9);
+    case "resolving":
+      return 92;
+    case "embedding":
+      return 96;
+    case "completed":
+      return 100;
+    default:
+      return null;
+  }
+}
+
+export const indexProgress = new IndexProgressTracker();
@@ -0,0 +1,234 @@
+/**
+ * Index Watcher - keeps the graph current while files are edited
+ * Watches the indexed directory, coalesces bursts of filesystem events and
+ * hands each settled batch of paths to the incremental update pipeline
+ */
+
+import { type FSWatcher, lstatSync, watch } from "node:fs";
+import { dirname, join, resolve } from "node:path";
+import { collectIndexableFiles, createIndexPathFilter } from "../utils/index-file-collection.js";
+
+export interface WatchBatch {
+  /** Files that exist after the burst settled (created or modified) */
+  changed: string[];
+  /** Files that were seen during the burst but no longer exist */
+  deleted: string[];
+  /**
+   * A directory was added, moved or removed. Watchers report only the directory itself in that
+   * case, so the file lists are incomplete and the whole tree should be re-checked.
+   */
+  rescan: boolean;
+}
+
+export interface IndexWatcherOptions {
+  directory: string;
+  excludePatterns: string[];
+  /** Skip paths ignored by .gitignore files (default true) */
+  respectGitignore?: boolean;
+  /** Also watch files recognised by content rather than extension (indexer.detectLanguageByContent) */
+  detectLanguageByContent?: boolean;
+  /** Quiet period after the last event before a batch is flushed */
+  debounceMs?: number;
+  /** Upper bound on how long a continuous stream of events can postpone a flush */
+  maxWaitMs?: number;
+  onBatch: (batch: WatchBatch) => Promise<void>;
+  onError?: (error: Error) => void;
+}
+
+export interface IndexWatcherStatus {
+  directory: string;
+  running: boolean;
+  pending: number;
+  batches: number;
+  filesChanged: number;
+  filesDeleted: number;
+  lastBatchAt?: number;
+  lastError?: string;
+}
+
+const DEFAULT_DEBOUNCE_MS = 500;
+const DEFAULT_MAX_WAIT_MS = 5000;
+
+export class IndexWatcher {
+  private readonly directory: string;
+  private readonly debounceMs: number;
+  private readonly maxWaitMs: number;
+  private readonly excludePatterns: string[];
+  private readonly respectGitignore: boolean;
+  private readonly detectLanguageByContent: boolean;
+  private readonly accepts: (path: string, isDirectory?: boolean) => boolean;
+  private readonly onBatch: IndexWatcherOptions["onBatch"];
+  private readonly onError?: IndexWatcherOptions["onError"];
+
+  private watcher: FSWatcher | null = null;
+  private pending = new Set<string>();
+  private rescan = false;
+  private knownDirs = new Set<string>();
+  private debounceTimer: NodeJS.Timeout | null = null;
+  private burstStartedAt: number | null = null;
+  private flushing: Promise<void> | null = null;
+
+  private batches = 0;
+  private filesChanged = 0;
+  private filesDeleted = 0;
+  private lastBatchAt?: number;
+  private lastError?: string;
+
+  constructor(options: IndexWatcherOptions) {
+    this.directory = resolve(options.directory);
+    this.debounceMs = Math.max(0, options.debounceMs ?? DEFAULT_DEBOUNCE_MS);
+    this.maxWaitMs = Math.max(this.debounceMs, options.maxWaitMs ?? DEFAULT_MAX_WAIT_MS);
+    this.excludePatterns = options.excludePatterns;
+    this.respectGitignore = options.respectGitignore !== false;
+    this.detectLanguageByContent = options.detectLanguageByContent === true;
+    this.accepts = createIndexPathFilter(this.directory, options.excludePatterns, {
+      respectGitignore: this.respectGitignore,
+      detectLanguageByContent: this.detectLanguageByContent,
+    });
+    this.onBatch = options.onBatch;
+    this.onError = options.onError;
+  }
+
+  start(): void {
+    if (this.watcher) return;
+
+    // Remember which directories hold indexable files so their removal can be recognised later
+    const { files } = collectIndexableFiles(this.directory, this.excludePatterns, {
+      respectGitignore: this.respectGitignore,
+      detectLanguageByContent: this.detectLanguageByContent,
+    });
+    for (const file of files) {
+      this.rememberDirs(file);
+    }
+
+    this.watcher = watch(this.directory, { recursive: true, persistent: false }, (_event, filename) => {
+      if (filename) this.record(join(this.directory, filename.toString()));
+    });
+    this.watcher.on("error", (error) => {
+      this.fail(error);
+      this.stop();
+    });
+  }
+
+  stop(): void {
+    this.watcher?.close();
+    this.watcher = null;
+    if (this.debounceTimer) clearTimeout(this.debounceTimer);
+    this.debounceTimer = null;
+    this.burstStartedAt = null;
+    this.pending.clear();
+    this.rescan = false;
+  }
+
+  isRunning(): boolean {
+    return this.watcher !== null;
+  }
+
+  getStatus(): IndexWatcherStatus {
+    return {
+      directory: this.directory,
+      running: this.isRunning(),
+      pending: this.pending.size + (this.rescan ? 1 : 0),
+      batches: this.batches,
+      filesChanged: this.filesChanged,
+      filesDeleted: this.filesDeleted,
+      lastBatchAt: this.lastBatchAt,
+      lastError: this.lastError,
+    };
+  }
+
+  /**
+   * Queue a path as touched. Exposed so callers (and tests) can feed events without a real watcher.
+   */
+  record(filePath: string): void {
+    const path = resolve(filePath);
+    const stat = lstatSync(path, { throwIfNoEntry: false });
+
+    if (stat?.isDirectory() || (!stat && this.knownDirs.has(path))) {
+      if (!this.accepts(path, true)) return;
+      if (stat) this.knownDirs.add(path);
+      else this.knownDirs.delete(path);
+      this.rescan = true;
+    } else if (this.accepts(path)) {
+      this.pending.add(path);
+      this.rememberDirs(path);
+    } else {
+      return;
+    }
+
+    const now = Date.now();
+    this.burstStartedAt ??= now;
+
+    if (this.debounceTimer) clearTimeout(this.debounceTimer);
+    const waited = now - this.burstStartedAt;
+    const delay = Math.max(0, Math.min(this.debounceMs, this.maxWaitMs - waited));
+    this.debounceTimer = setTimeout(() => {
+      this.flush().catch((error) => this.fail(error));
+    }, delay);
+  }
+
+  /**
+   * Process everything queued so far. Only one batch runs at a time; events that arrive while a
+   * batch is running are collected and flushed once it finishes.
+   */
+  async flush(): Promise<void> {
+    if (this.debounceTimer) clearTimeout(this.debounceTimer);
+    this.debounceTimer = null;
+
+    if (this.flushing) {
+      // The running flush picks up whatever is still pending when it completes
+      await this.flushing;
+      return;
+    }
+    if (this.pending.size === 0 && !this.rescan) return;
+
+    const paths = Array.from(this.pending).sort();
+    const rescan = this.rescan;
+    this.pending.clear();
+    this.rescan = false;
+    this.burstStartedAt = null;
+
+    // Classify by what is on disk now, not by event type: an atomic save (write temp file, rename
+    // over the original) and a create-then-delete inside the window both resolve correctly this way
+    const batch: WatchBatch = { changed: [], deleted: [], rescan };
+    for (const path of paths) {
+      if (lstatSync(path, { throwIfNoEntry: false })?.isFile()) batch.changed.push(path);
+      else batch.deleted.push(path);
+    }
+
+    this.flushing = (async () => {
+      try {
+        await this.onBatch(batch);
+        this.batches += 1;
+        this.filesChanged += batch.changed.length;
+        this.filesDeleted += batch.deleted.length;
+        this.lastBatchAt = Date.now();
+      } catch (error) {
+        this.fail(error);
+      }
+    })();
+
+    try {
+      await this.flushing;
+    } finally {
+      this.flushing = null;
+    }
+
+    if ((this.pending.size > 0 || this.rescan) && !this.debounceTimer) {
+      await this.flush();
+    }
+  }
+
+  private rememberDirs(filePath: string): void {
+    for (let dir = dirname(filePath); dir.startsWith(this.directory) && dir !== this.directory; dir = dirname(dir)) {
+      if (this.knownDirs.has(dir)) break;
+      this.knownDirs.add(dir);
+    }
+  }
+
+  private fail(error: unknown): void {
+    const err = error instanceof Error ? error : new Error(String(error));
+    this.lastError = err.message;
+    this.onError?.(err);
+  }
+}
@@ -0,0 +1,291 @@
+/**
+ * Override Resolver - links methods to the base methods they override
+ * Runs after cross-file resolution, once extends, implements and embeds edges point at real types.
+ * A method overrides the nearest same-named method on each ancestor chain of its owning type,
+ * provided the signatures are compatible under the owner's language: Go interface methods must
+ * match exactly, Java-like languages must agree on parameters (return types may be covariant), and
+ * dynamic languages match by name. Overrides edges of the scanned methods are rebuilt each run.
+ */
+
+import { dirname, extname } from "node:path";
+import { stableRelationshipId } from "../storage/entity-id.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { type Entity, type Relationship, RelationType } from "../types/storage.js";
+
+export interface OverrideResolution {
+  methodsScanned: number;
+  overridesFound: number;
+}
+
+/** How a method signature must relate to the base method it overrides */
+export type OverrideRule = "exact" | "parameters" | "name";
+
+/** Edges that make one type a subtype of another; Go embedding counts as inheriting the embedded type */
+export const INHERITANCE_RELATIONSHIPS: readonly string[] = [
+  RelationType.EXTENDS,
+  RelationType.IMPLEMENTS,
+  RelationType.EMBEDS,
+];
+
+/** Entity kinds that can take part in a type hierarchy */
+export const TYPE_KINDS = new Set([
+  "class",
+  "interface",
+  "type",
+  "struct",
+  "trait",
+  "enum",
+  "protocol",
+  "dataclass",
+  "namedtuple",
+  "union",
+  "typedef",
+]);
+
+/** Entity kinds that can be methods; plain functions count when a type encloses them */
+const METHOD_KINDS = new Set([
+  "method",
+  "function",
+  "async_function",
+  "magic_method",
+  "class_method",
+  "static_method",
+  "abstract_method",
+  "property",
+]);
+
+const PARAMETER_RULE_EXTENSIONS = new Set([
+  ".java",
+  ".kt",
+  ".kts",
+  ".cs",
+  ".cpp",
+  ".cc",
+  ".cxx",
+  ".hpp",
+  ".hh",
+  ".swift",
+  ".scala",
+]);
+
+export function overrideRule(filePath: string): OverrideRule {
+  const ext = extname(filePath).toLowerCase();
+  if (ext === ".go") return "exact";
+  return PARAMETER_RULE_EXTENSIONS.has(ext) ? "parameters" : "name";
+}
+
+function isPlaceholder(entity: Entity): boolean {
+  const meta = entity.metadata as Record<string, unknown> | undefined;
+  return Boolean(meta?.isExternal) || entity.filePath.startsWith("external://");
+}
+
+function parameterTypes(entity: Entity): string[] | null {
+  const params = (entity.metadata as Record<string, unknown> | undefined)?.parameters;
+  if (!Array.isArray(params)) return null;
+  return params.map((p) => {
+    // Go reports `name: type` strings, other analyzers `{ name, type }` objects
+    let text = String(p?.type ?? "");
+    if (typeof p === "string") {
+      const idx = p.indexOf(": ");
+      text = idx >= 0 ? p.slice(idx + 2) : p;
+    }
+    return text.replace(/\s+/g, "");
+  });
+}
+
+/** Whether `method` may override `base` under `rule` */
+export function isCompatibleOverride(method: Entity, base: Entity, rule: OverrideRule): boolean {
+  if (method.name !== base.name) return false;
+  if (rule === "name") return true;
+
+  const mine = parameterTypes(method) ?? [];
+  const theirs = parameterTypes(base) ?? [];
+  if (mine.length !== theirs.length) return false;
+  // Untyped entries (missing type information) only constrain the arity
+  if (mine.some((t, i) => t && theirs[i] && t !== theirs[i])) return false;
+  if (rule === "parameters") return true;
+
+  const result = (e: Entity) => String((e.metadata as Record<string, unknown>)?.returnType ?? "").replace(/\s+/g, "");
+  return result(method) === result(base);
+}
+
+/** Whether `outer`'s line range holds `inner`'s; an entity never encloses itself */
+export function encloses(outer: Entity, inner: Entity): boolean {
+  const o = outer.location;
+  const i = inner.location;
+  return o.start.line <= i.start.line && o.end.line >= i.end.line && outer.id !== inner.id;
+}
+
+/**
+ * Loads entities per file and answers which type owns a method: the Go receiver or Rust impl
+ * type (a type of that name in the same directory), otherwise the innermost type whose range
+ * encloses the method in its file.
+ */
+export class MethodOwnership {
+  private readonly files = new Map<string, Entity[]>();
+  private readonly methods = new Map<string, Entity[]>();
+  private directories: Map<string, string[]> | null = null;
+
+  constructor(private readonly storage: GraphStorageImpl) {}
+
+  async entitiesOf(filePath: string): Promise<Entity[]> {
+    let entities = this.files.get(filePath);
+    if (!entities) {
+      entities = await this.storage.findEntities({ type: "entity", filters: { filePath }, limit: 10000 });
+      this.files.set(filePath, entities);
+    }
+    return entities;
+  }
+
+  async ownerOf(method: Entity): Promise<Entity | null> {
+    if (!METHOD_KINDS.has(String(method.type)) || method.name === "constructor") return null;
+    const meta = (method.metadata ?? {}) as Record<string, unknown>;
+    const ownerName = typeof meta.receiver === "string" ? meta.receiver : meta.implType;
+    if (typeof ownerName === "string" && ownerName) {
+      for (const file of await this.siblingsOf(method.filePath)) {
+        // A Swift extension is named after its type but only adds to it
+        const owner = (await this.entitiesOf(file)).find(
+          (e) => e.name === ownerName && TYPE_KINDS.has(String(e.type)) && !e.metadata?.isExtension,
+        );
+        if (owner) return owner;
+      }
+      return null;
+    }
+
+    // A function nested in a method is not a method of the class around both
+    let innermost: Entity | null = null;
+    for (const candidate of await this.entitiesOf(method.filePath)) {
+      const kind = String(candidate.type);
+      if ((!TYPE_KINDS.has(kind) && !METHOD_KINDS.has(kind)) || !encloses(candidate, method)) continue;
+      if (!innermost || encloses(innermost, candidate)) innermost = candidate;
+    }
+    return innermost && TYPE_KINDS.has(String(innermost.type)) ? innermost : null;
+  }
+
+  async methodsOf(type: Entity): Promise<Entity[]> {
+    const cached = this.methods.get(type.id);
+    if (cached) return cached;
+    const methods: Entity[] = [];
+    for (const file of await this.siblingsOf(type.filePath)) {
+      for (const entity of await this.entitiesOf(file)) {
+        if (!METHOD_KINDS.has(String(entity.type))) continue;
+        // Methods outside the type's own file can only belong to it through a receiver
+        const meta = (entity.metadata ?? {}) as Record<string, unknown>;
+        if (file !== type.filePath && meta.receiver !== type.name && meta.implType !== type.name) continue;
+        if ((await this.ownerOf(entity))?.id === type.id) methods.push(entity);
+      }
+    }
+    this.methods.set(type.id, methods);
+    return methods;
+  }
+
+  /** The file itself first, then the other indexed files of its directory (a Go package, a Swift target) */
+  private async siblingsOf(filePath: string): Promise<string[]> {
+    if (![".go", ".rs", ".swift"].some((ext) => filePath.endsWith(ext))) return [filePath];
+    if (!this.directories) {
+      this.directories = new Map();
+      for (const info of await this.storage.listIndexedFiles()) {
+        const dir = dirname(info.path);
+        this.directories.set(dir, [...(this.directories.get(dir) ?? []), info.path]);
+      }
+    }
+    const others = (this.directories.get(dirname(filePath)) ?? []).filter((f) => f !== filePath);
+    return [filePath, ...others];
+  }
+}
+
+/**
+ * Types `type` directly inherits from. Edges that stopped at a placeholder are followed when
+ * exactly one indexed type carries the placeholder's name.
+ */
+export async function directBaseTypes(
+  storage: GraphStorageImpl,
+  type: Entity,
+  uniqueType: (name: string) => Promise<Entity | null>,
+): Promise<Array<{ base: Entity | null; name: string; relation: string; line: number | null }>> {
+  const bases: Array<{ base: Entity | null; name: string; relation: string; line: number | null }> = [];
+  for (const rel of await storage.getRelationshipsForEntity(type.id)) {
+    if (rel.fromId !== type.id || !INHERITANCE_RELATIONSHIPS.includes(String(rel.type))) continue;
+    const target = await storage.getEntity(rel.toId);
+    if (!target) continue;
+    bases.push({
+      base: isPlaceholder(target) ? await uniqueType(target.name) : target,
+      name: target.name,
+      relation: String(rel.type),
+      line: rel.metadata?.line ?? null,
+    });
+  }
+  return bases;
+}
+
+/** Resolves a type name to its only indexed declaration, null when there are none or several */
+export function createTypeLookup(storage: GraphStorageImpl): (name: string) => Promise<Entity | null> {
+  const cache = new Map<string, Entity | null>();
+  return async (name: string) => {
+    if (!cache.has(name)) {
+      const qualified = name.lastIndexOf(".");
+      const bare = qualified > 0 ? name.slice(qualified + 1) : name;
+      const found = await storage.findEntities({ type: "entity", filters: { name: bare }, limit: 1000 });
+      const types = found.filter((e) => TYPE_KINDS.has(String(e.type)) && !isPlaceholder(e));
+      cache.set(name, types.length === 1 ? types[0]! : null);
+    }
+    return cache.get(name) ?? null;
+  };
+}
+
+/**
+ * Rebuild overrides edges for every method declared in `files` (every indexed file when
+ * omitted). Ancestor chains are walked breadth-first and a chain stops at the first ancestor
+ * holding a compatible method, so each edge points at the nearest overridden declaration.
+ */
+export async function resolveOverrides(storage: GraphStorageImpl, files?: string[]): Promise<OverrideResolution> {
+  const targets = files ?? (await storage.listIndexedFiles()).map((info) => info.path);
+  const ownership = new MethodOwnership(storage);
+  const uniqueType = createTypeLookup(storage);
+  const stats: OverrideResolution = { methodsScanned: 0, overridesFound: 0 };
+
+  for (const file of [...new Set(targets)].sort()) {
+    const rule = overrideRule(file);
+    for (const method of await ownership.entitiesOf(file)) {
+      const owner = await ownership.ownerOf(method);
+      if (!owner) continue;
+      stats.methodsScanned += 1;
+
+      const existing = await storage.getRelationshipsForEntity(method.id, RelationType.OVERRIDES);
+      for (const rel of existing) if (rel.fromId === method.id) await storage.deleteRelationship(rel.id);
+
+      const found: Relationship[] = [];
+      const visited = new Set<string>([owner.id]);
+      let frontier = [owner];
+      while (frontier.length > 0) {
+        const next: Entity[] = [];
+        for (const type of frontier) {
+          for (const { base } of await directBaseTypes(storage, type, uniqueType)) {
+            if (!base || visited.has(base.id)) continue;
+            visited.add(base.id);
+            const match = (await ownership.methodsOf(base)).find((m) => isCompatibleOverride(method, m, rule));
+            if (!match) {
+              next.push(base);
+              continue;
+            }
+            found.push({
+              id: stableRelationshipId(method.id, match.id, RelationType.OVERRIDES),
+              fromId: method.id,
+              toId: match.id,
+              type: RelationType.OVERRIDES,
+              metadata: { line: method.location.start.line, baseType: base.name, rule },
+            });
+          }
+        }
+        frontier = next;
+      }
+
+      if (found.length > 0) {
+        await storage.insertRelationships(found);
+        stats.overridesFound += found.length;
+      }
+    }
+  }
+
+  return stats;
+}
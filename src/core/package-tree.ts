@@ -0,0 +1,271 @@
+/**
+ * Package Tree - container entities for the directories, Go packages and Python packages of the index
+ * Every directory holding an indexed file gets one container, and so does every directory between
+ * it and its index root, so the tree is connected from the root down. A container `contains` its
+ * subdirectories' containers and the top-level declarations of its files (a declaration nested in
+ * another one of the same file is left to that one). Containers are derived from the stored graph
+ * only, and rebuilding them rewrites nothing that did not change.
+ */
+
+import { existsSync } from "node:fs";
+import { basename, dirname, join, relative } from "node:path";
+import { stableEntityId, stableRelationshipId } from "../storage/entity-id.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { type Entity, EntityType, type Relationship, RelationType } from "../types/storage.js";
+import { edgeConfidence } from "./edge-confidence.js";
+import { type GoModuleCache, goImportPath } from "./go-modules.js";
+import { commonRoot, rootOf } from "./workspace-roots.js";
+
+/** Go package directory, Python package (a directory with `__init__.py`), or any other directory */
+export type ContainerKind = "go_package" | "python_package" | "directory";
+
+export interface PackageTreeBuild {
+  containers: number;
+  containersAdded: number;
+  containersRemoved: number;
+  edgesAdded: number;
+  edgesRemoved: number;
+}
+
+export interface PackageTreeOptions {
+  /** Index roots the tree hangs from (default: every root recorded in the index) */
+  roots?: string[];
+}
+
+/** Declarations a container lists; members, imports and markers such as Go package clauses are not */
+const MEMBER_KINDS = new Set<string>([
+  EntityType.FUNCTION,
+  EntityType.CLASS,
+  EntityType.INTERFACE,
+  EntityType.TYPE,
+  EntityType.VARIABLE,
+  EntityType.CONSTANT,
+  "typedef",
+  "enum",
+  "struct",
+  "trait",
+  "union",
+]);
+
+const PAGE_SIZE = 1000;
+
+export function isContainer(entity: Entity): boolean {
+  return entity.type === EntityType.PACKAGE && typeof entity.metadata?.container === "string";
+}
+
+function isMemberOfOwner(entity: Entity): boolean {
+  const meta = (entity.metadata ?? {}) as Record<string, unknown>;
+  return Boolean(meta.parentClass || meta.className || meta.receiver || meta.implType || meta.parent);
+}
+
+/**
+ * Declarations of a file not enclosed by another declaration of the file. Analyzers that recurse
+ * into bodies report nested functions and locals without naming their owner, so position decides.
+ */
+function topLevelOf(entities: Entity[]): Entity[] {
+  const candidates = entities
+    .filter((e) => MEMBER_KINDS.has(String(e.type)) && !isMemberOfOwner(e) && !e.name.startsWith("<"))
+    .sort(
+      (a, b) =>
+        (a.location?.start?.index ?? 0) - (b.location?.start?.index ?? 0) ||
+        (b.location?.end?.index ?? 0) - (a.location?.end?.index ?? 0),
+    );
+  const top: Entity[] = [];
+  let reach = -1;
+  for (const entity of candidates) {
+    const start = entity.location?.start?.index ?? 0;
+    const end = entity.location?.end?.index ?? 0;
+    if (end > start && start < reach) continue;
+    top.push(entity);
+    reach = Math.max(reach, end);
+  }
+  return top;
+}
+
+type DirInfo = {
+  dir: string;
+  root: string;
+  members: Entity[];
+  /** Package names of the directory's Go files, by number of files */
+  goPackages: Map<string, number>;
+  languages: Map<string, number>;
+};
+
+function mostCommon(counts: Map<string, number>): string | undefined {
+  let best: string | undefined;
+  for (const [key, count] of counts) {
+    if (best === undefined || count > counts.get(best)!) best = key;
+  }
+  return best;
+}
+
+/** Every container of the index */
+export async function listContainers(storage: GraphStorageImpl): Promise<Entity[]> {
+  const containers: Entity[] = [];
+  let afterId: string | undefined;
+  while (true) {
+    const page = await storage.findEntities({
+      type: "entity",
+      filters: { entityType: EntityType.PACKAGE },
+      afterId,
+      limit: PAGE_SIZE,
+    });
+    containers.push(...page.filter(isContainer));
+    if (page.length < PAGE_SIZE) return containers;
+    afterId = page[page.length - 1]!.id;
+  }
+}
+
+/** Dotted name of a Python package: its directory and every enclosing one with an `__init__.py` */
+function pythonPackageName(dir: string, root: string): string {
+  const parts = [basename(dir)];
+  let at = dirname(dir);
+  while (at !== dirname(at) && rootOf(at, [root]) && existsSync(join(at, "__init__.py"))) {
+    parts.unshift(basename(at));
+    at = dirname(at);
+  }
+  return parts.join(".");
+}
+
+/**
+ * Create, update or drop containers so they match the indexed files, and link each to its
+ * subdirectories and top-level declarations.
+ */
+export async function buildPackageTree(
+  storage: GraphStorageImpl,
+  options: PackageTreeOptions = {},
+): Promise<PackageTreeBuild> {
+  const files = (await storage.listIndexedFiles()).map((info) => info.path).sort();
+  const roots = options.roots ?? (await storage.listIndexRoots());
+  const fallbackRoot = commonRoot(files.filter((file) => !rootOf(file, roots)).map((file) => dirname(file)));
+
+  const dirs = new Map<string, DirInfo>();
+  const dirInfo = (dir: string, root: string): DirInfo => {
+    let info = dirs.get(dir);
+    if (!info) {
+      info = { dir, root, members: [], goPackages: new Map(), languages: new Map() };
+      dirs.set(dir, info);
+    }
+    return info;
+  };
+
+  for (const file of files) {
+    const root = rootOf(file, roots) ?? fallbackRoot;
+    const entities = await storage.findEntities({ type: "entity", filters: { filePath: file }, limit: 10000 });
+    const info = dirInfo(dirname(file), root);
+    info.members.push(...topLevelOf(entities));
+    const goPackage = file.endsWith(".go") ? entities.find((e) => e.metadata?.isPackage)?.name : undefined;
+    if (goPackage && !goPackage.endsWith("_test")) {
+      info.goPackages.set(goPackage, (info.goPackages.get(goPackage) ?? 0) + 1);
+    }
+    const language = entities.find((e) => e.language)?.language;
+    if (language) info.languages.set(language, (info.languages.get(language) ?? 0) + 1);
+
+    // Every directory up to the root, so the tree has no gaps
+    let at = dirname(file);
+    while (at !== root && at !== dirname(at) && rootOf(dirname(at), [root])) {
+      at = dirname(at);
+      dirInfo(at, root);
+    }
+  }
+
+  const goModules: GoModuleCache = new Map();
+  const desired = new Map<string, Entity>();
+  const containerOf = new Map<string, Entity>();
+  for (const info of [...dirs.values()].sort((a, b) => a.dir.localeCompare(b.dir))) {
+    const goPackage = mostCommon(info.goPackages);
+    const python = !goPackage && existsSync(join(info.dir, "__init__.py"));
+    const kind: ContainerKind = goPackage ? "go_package" : python ? "python_package" : "directory";
+    const name = goPackage ?? basename(info.dir);
+    const qualifiedName = goPackage
+      ? goImportPath(info.dir, goModules)
+      : python
+        ? pythonPackageName(info.dir, info.root)
+        : relative(info.root, info.dir).replace(/\\/g, "/") || ".";
+
+    const container: Entity = {
+      id: stableEntityId({ name: info.dir, type: EntityType.PACKAGE, filePath: info.dir }),
+      name,
+      type: EntityType.PACKAGE,
+      filePath: info.dir,
+      location: { start: { line: 0, column: 0, index: 0 }, end: { line: 0, column: 0, index: 0 } },
+      metadata: { container: kind, qualifiedName, root: info.root },
+      hash: `container:${kind}:${qualifiedName}`,
+      createdAt: 0,
+      updatedAt: 0,
+      language: mostCommon(info.languages),
+    };
+    desired.set(container.id, container);
+    containerOf.set(info.dir, container);
+  }
+
+  const wantedEdges = new Map<string, Relationship>();
+  const link = (from: Entity, to: Entity) => {
+    const id = stableRelationshipId(from.id, to.id, RelationType.CONTAINS);
+    wantedEdges.set(id, {
+      id,
+      fromId: from.id,
+      toId: to.id,
+      type: RelationType.CONTAINS,
+      metadata: { containment: "package", ...edgeConfidence("resolved") },
+    } as Relationship);
+  };
+  for (const info of dirs.values()) {
+    const container = containerOf.get(info.dir)!;
+    const parent = info.dir === info.root ? undefined : containerOf.get(dirname(info.dir));
+    if (parent) link(parent, container);
+    for (const member of info.members) link(container, member);
+  }
+
+  const stats: PackageTreeBuild = {
+    containers: desired.size,
+    containersAdded: 0,
+    containersRemoved: 0,
+    edgesAdded: 0,
+    edgesRemoved: 0,
+  };
+
+  const existing = new Map((await listContainers(storage)).map((entity) => [entity.id, entity]));
+  for (const [id, entity] of existing) {
+    const keep = desired.has(id);
+    for (const rel of await storage.getRelationshipsForEntity(id, RelationType.CONTAINS)) {
+      if (keep && (rel.fromId !== id || wantedEdges.has(rel.id))) {
+        wantedEdges.delete(rel.id);
+        continue;
+      }
+      await storage.deleteRelationship(rel.id);
+      stats.edgesRemoved += 1;
+    }
+    if (!keep) {
+      await storage.deleteEntity(id);
+      stats.containersRemoved += 1;
+    }
+  }
+
+  const changed = [...desired.values()].filter((container) => {
+    const stored = existing.get(container.id);
+    return (
+      !stored ||
+      stored.name !== container.name ||
+      JSON.stringify(stored.metadata) !== JSON.stringify(container.metadata) ||
+      stored.language !== container.language
+    );
+  });
+  const now = Date.now();
+  stats.containersAdded = changed.filter((container) => !existing.has(container.id)).length;
+  if (changed.length > 0) {
+    await storage.insertEntities(
+      changed.map((container) => ({
+        ...container,
+        createdAt: existing.get(container.id)?.createdAt ?? now,
+        updatedAt: now,
+      })),
+    );
+  }
+  if (wantedEdges.size > 0) {
+    await storage.insertRelationships([...wantedEdges.values()]);
+    stats.edgesAdded = wantedEdges.size;
+  }
+
+  return stats;
+}
@@ -0,0 +1,163 @@
+/**
+ * PHP Namespace Resolver - binds fully qualified PHP names across files
+ * The PHP analyzer resolves `use` imports and the current namespace while parsing, so every edge
+ * to a class or function declared in another file names it by its fully qualified name
+ * (`App\Models\User`). Once the project is indexed, those placeholders are bound to the declaring
+ * entity (class names are case-insensitive in PHP), and calls whose receiver class is known, or
+ * plain function calls, are bound to the method of that class, its parents, traits and
+ * interfaces, or to the function the call names.
+ */
+
+import { extname } from "node:path";
+import { type PhpImports, phpFunctionCandidates } from "../parsers/php-analyzer.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { type Entity, type Relationship, RelationType } from "../types/storage.js";
+import { reboundMetadata } from "./edge-confidence.js";
+
+export interface PhpNamespaceResolution {
+  filesScanned: number;
+  namesResolved: number;
+  callsResolved: number;
+}
+
+const PHP_EXTENSIONS = new Set([".php", ".phtml"]);
+const TYPE_KINDS = new Set(["class", "interface", "trait", "enum"]);
+const NAME_EDGES = new Set<string>([
+  RelationType.EXTENDS,
+  RelationType.IMPLEMENTS,
+  RelationType.EMBEDS,
+  RelationType.IMPORTS,
+  RelationType.REFERENCES,
+  RelationType.DECORATED_BY,
+]);
+
+function meta(entity: Entity): Record<string, any> {
+  return (entity.metadata ?? {}) as Record<string, any>;
+}
+
+function isPlaceholder(entity: Entity): boolean {
+  return entity.filePath.startsWith("external://");
+}
+
+function qualifiedNameOf(entity: Entity): string {
+  const name = meta(entity).qualifiedName;
+  return typeof name === "string" && name ? name : entity.name;
+}
+
+/** Classes, functions and methods of every indexed PHP file, by lowercased qualified name */
+class PhpSymbols {
+  private readonly types = new Map<string, Entity>();
+  private readonly functions = new Map<string, Entity>();
+  private readonly methods = new Map<string, Entity>();
+
+  add(entity: Entity): void {
+    const key = qualifiedNameOf(entity).toLowerCase();
+    const table = TYPE_KINDS.has(String(entity.type))
+      ? this.types
+      : entity.type === "function"
+        ? this.functions
+        : entity.type === "method"
+          ? this.methods
+          : null;
+    // Declarations guarded by `if (!function_exists(...))` may repeat; the first one stands
+    if (table && !table.has(key)) table.set(key, entity);
+  }
+
+  type(qualifiedName: string): Entity | undefined {
+    return this.types.get(qualifiedName.replace(/^\\/, "").toLowerCase());
+  }
+
+  function(qualifiedName: string): Entity | undefined {
+    return this.functions.get(qualifiedName.replace(/^\\/, "").toLowerCase());
+  }
+
+  /** Method `name` of `owner` or of its parent classes, traits and interfaces, nearest first */
+  findMethod(owner: string, name: string): Entity | undefined {
+    const seen = new Set<string>();
+    const queue = [owner];
+    while (queue.length > 0) {
+      const type = queue.shift()!.toLowerCase();
+      if (seen.has(type)) continue;
+      seen.add(type);
+      const found = this.methods.get(`${type}::${name.toLowerCase()}`);
+      if (found) return found;
+      const declared = this.types.get(type);
+      if (!declared) continue;
+      const { superclass, traits, interfaces } = meta(declared);
+      queue.push(...((traits ?? []) as string[]));
+      if (typeof superclass === "string") queue.push(superclass);
+      queue.push(...((interfaces ?? []) as string[]));
+    }
+    return undefined;
+  }
+}
+
+/**
+ * Bind names and calls of `files` (every indexed PHP file when omitted) against every indexed
+ * PHP file.
+ */
+export async function resolvePhpNamespaces(
+  storage: GraphStorageImpl,
+  files?: string[],
+): Promise<PhpNamespaceResolution> {
+  const indexed = (await storage.listIndexedFiles())
+    .map((info) => info.path)
+    .filter((path) => PHP_EXTENSIONS.has(extname(path).toLowerCase()))
+    .sort();
+  const indexedSet = new Set(indexed);
+  const targets = [...new Set(files ?? indexed)].filter((file) => indexedSet.has(file)).sort();
+  const stats: PhpNamespaceResolution = { filesScanned: targets.length, namesResolved: 0, callsResolved: 0 };
+  if (targets.length === 0) return stats;
+
+  const symbols = new PhpSymbols();
+  const entitiesByFile = new Map<string, Entity[]>();
+  for (const file of indexed) {
+    const entities = await storage.findEntities({ type: "entity", filters: { filePath: file }, limit: 10000 });
+    entitiesByFile.set(file, entities);
+    for (const entity of entities) symbols.add(entity);
+  }
+
+  for (const file of targets) {
+    const moved: Relationship[] = [];
+    for (const source of entitiesByFile.get(file) ?? []) {
+      const outgoing = (await storage.getRelationshipsForEntity(source.id)).filter((rel) => rel.fromId === source.id);
+      const bound = new Set(outgoing.map((rel) => `${rel.type}|${rel.toId}`));
+      // Functions carry the namespace and imports they were declared under; methods use their class's
+      const context = meta(source).namespace !== undefined ? source : symbols.type(String(meta(source).className));
+
+      for (const rel of outgoing) {
+        const placeholder = await storage.getEntity(rel.toId);
+        if (!placeholder || !isPlaceholder(placeholder)) continue;
+
+        let target: Entity | undefined;
+        if (NAME_EDGES.has(rel.type)) {
+          // `use function A\f;` imports a function under the same edge type as a class
+          target = symbols.type(placeholder.name) ?? symbols.function(placeholder.name);
+        } else if (rel.type === RelationType.CALLS) {
+          const site = rel.metadata?.callSites?.[0];
+          const written = String(site?.callee ?? placeholder.name);
+          if (site?.receiverType) {
+            target = symbols.findMethod(site.receiverType, placeholder.name);
+          } else if (!written.includes("->") && !written.includes("::")) {
+            const namespace = String((context && meta(context).namespace) ?? "");
+            const imports: PhpImports = (context && meta(context).imports) ?? { classes: {}, functions: {} };
+            target = phpFunctionCandidates(written, namespace, imports)
+              .map((name) => symbols.function(name))
+              .find(Boolean);
+          }
+        }
+        if (!target) continue;
+
+        await storage.deleteRelationship(rel.id);
+        if (rel.type === RelationType.CALLS) stats.callsResolved += 1;
+        else stats.namesResolved += 1;
+        if (bound.has(`${rel.type}|${target.id}`)) continue;
+        bound.add(`${rel.type}|${target.id}`);
+        moved.push({ ...rel, toId: target.id, metadata: reboundMetadata(rel.metadata, "php") });
+      }
+    }
+    if (moved.length > 0) await storage.insertRelationships(moved);
+  }
+
+  return stats;
+}
@@ -0,0 +1,144 @@
+/**
+ * Proto Linker - connects Protobuf files through their imports and the types they use
+ * Per-file indexing leaves `import "a/b.proto"` edges and field or RPC types declared in another
+ * file at `external://` placeholders. Once every schema is indexed, each import is bound to the
+ * imported file's module entity and each type to the message or enum its full name resolves to,
+ * following the scoping rules of protoc: innermost enclosing scope first, then outwards.
+ */
+
+import { dirname, extname, join, normalize } from "node:path";
+import { protoTypeCandidates } from "../parsers/proto-analyzer.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { type Entity, type Relationship, RelationType } from "../types/storage.js";
+import { reboundMetadata } from "./edge-confidence.js";
+
+export interface ProtoLinking {
+  filesScanned: number;
+  importsResolved: number;
+  typesResolved: number;
+}
+
+const TYPE_KINDS = new Set(["message", "enum"]);
+const USING_KINDS = new Set(["field", "rpc"]);
+
+function meta(entity: Entity): Record<string, any> {
+  return (entity.metadata ?? {}) as Record<string, any>;
+}
+
+function isPlaceholder(entity: Entity): boolean {
+  return entity.filePath.startsWith("external://");
+}
+
+function isProto(filePath: string): boolean {
+  return extname(filePath).toLowerCase() === ".proto";
+}
+
+/**
+ * Link the Protobuf files among `files` (every indexed one when omitted) to the files they import
+ * and the types they use. An import is resolved against the importing file's directory first, then
+ * as the only indexed file whose path ends with it, since protoc reads imports relative to an
+ * include root rather than to the file.
+ */
+export async function linkProtoFiles(storage: GraphStorageImpl, files?: string[]): Promise<ProtoLinking> {
+  const indexed = (await storage.listIndexedFiles())
+    .map((info) => info.path)
+    .filter(isProto)
+    .sort();
+  const indexedSet = new Set(indexed);
+  const targets = [...new Set(files ?? indexed)].filter((file) => indexedSet.has(file)).sort();
+  const stats: ProtoLinking = { filesScanned: targets.length, importsResolved: 0, typesResolved: 0 };
+  if (targets.length === 0) return stats;
+
+  const entitiesByFile = new Map<string, Entity[]>();
+  const types = new Map<string, Entity[]>();
+  for (const file of indexed) {
+    const entities = await storage.findEntities({ type: "entity", filters: { filePath: file }, limit: 10000 });
+    entitiesByFile.set(file, entities);
+    for (const entity of entities) {
+      const name = meta(entity).qualifiedName;
+      if (!TYPE_KINDS.has(meta(entity).protoKind) || typeof name !== "string") continue;
+      types.set(name, [...(types.get(name) ?? []), entity]);
+    }
+  }
+  const moduleOf = (file: string) => entitiesByFile.get(file)?.find((e) => meta(e).protoFile === true);
+
+  const resolveImport = (importer: string, spec: string): string | null => {
+    const local = normalize(join(dirname(importer), spec));
+    if (indexedSet.has(local)) return local;
+    const suffix = normalize(spec).replace(/^(\.\.?\/)+/, "");
+    const matches = indexed.filter((path) => path === suffix || path.endsWith(`/${suffix}`));
+    return matches.length === 1 ? matches[0]! : null;
+  };
+
+  // Imports first: a type name shared by several files is bound to the one the user imports
+  const importsOf = new Map<string, Set<string>>();
+  for (const file of targets) {
+    const imported = new Set<string>();
+    importsOf.set(file, imported);
+    const module = moduleOf(file);
+    if (!module) continue;
+
+    const moved: Relationship[] = [];
+    for (const rel of await storage.getRelationshipsForEntity(module.id, RelationType.IMPORTS)) {
+      if (rel.fromId !== module.id) continue;
+      const target = await storage.getEntity(rel.toId);
+      if (!target) continue;
+      if (!isPlaceholder(target)) {
+        imported.add(target.filePath);
+        continue;
+      }
+      const path = resolveImport(file, target.name);
+      const targetModule = path ? moduleOf(path) : undefined;
+      if (!path || !targetModule) continue;
+      imported.add(path);
+      await storage.deleteRelationship(rel.id);
+      moved.push({ ...rel, toId: targetModule.id, metadata: reboundMetadata(rel.metadata, "proto") });
+    }
+    if (moved.length > 0) {
+      await storage.insertRelationships(moved);
+      stats.importsResolved += moved.length;
+    }
+  }
+
+  // The declaration of a full name as `file` sees it: its own, then an imported file's, then the only one
+  const declarationOf = (file: string, fullName: string): Entity | undefined => {
+    const found = types.get(fullName) ?? [];
+    const imported = importsOf.get(file) ?? new Set<string>();
+    return (
+      found.find((e) => e.filePath === file) ??
+      found.find((e) => imported.has(e.filePath)) ??
+      (found.length === 1 ? found[0] : undefined)
+    );
+  };
+
+  for (const file of targets) {
+    const moved: Relationship[] = [];
+    for (const source of entitiesByFile.get(file) ?? []) {
+      const qualifiedName = meta(source).qualifiedName;
+      if (!USING_KINDS.has(meta(source).protoKind) || typeof qualifiedName !== "string") continue;
+      // Types in a field resolve from its message, in an RPC from its service
+      const scope = qualifiedName.includes(".") ? qualifiedName.slice(0, qualifiedName.lastIndexOf(".")) : "";
+
+      const outgoing = await storage.getRelationshipsForEntity(source.id, RelationType.REFERENCES);
+      const bound = new Set(outgoing.filter((rel) => rel.fromId === source.id).map((rel) => rel.toId));
+      for (const rel of outgoing) {
+        if (rel.fromId !== source.id) continue;
+        const placeholder = await storage.getEntity(rel.toId);
+        if (!placeholder || !isPlaceholder(placeholder)) continue;
+        const target = protoTypeCandidates(placeholder.name, scope)
+          .map((name) => declarationOf(file, name))
+          .find(Boolean);
+        if (!target) continue;
+
+        await storage.deleteRelationship(rel.id);
+        stats.typesResolved += 1;
+        if (bound.has(target.id)) continue;
+        bound.add(target.id);
+        moved.push({ ...rel, toId: target.id, metadata: reboundMetadata(rel.metadata, "proto") });
+      }
+    }
+    if (moved.length > 0) await storage.insertRelationships(moved);
+  }
+
+  return stats;
+}
@@ -0,0 +1,75 @@
+/**
+ * Qualified Names - one project-wide name per declaration, stored as `metadata.fqn`
+ * Short names collide across packages (two `GetUser`, each in its own package), and the
+ * analyzers' own `metadata.qualifiedName` only qualifies within a file or a language's namespace.
+ * The fully qualified name adds where the declaration lives, by one scheme per language:
+ *
+ *   Go          <import path>.<Name>, <import path>.<Type>.<Method> and <import path>.<Type>.<field>;
+ *               the package clause is the import path itself (`example.com/shop/users.Service.Get`)
+ *   all others  <file relative to the index root, without extension>#<qualified name>
+ *               (`src/users/service#UserService.get`)
+ *
+ * The import path comes from the nearest go.mod; without one the package directory, relative to
+ * the index root, stands in. The qualified name after `#` is the one entity ids use (see
+ * storage/entity-id.ts): the analyzer's, else `Owner.name` for members, else the name.
+ */
+
+import { dirname, extname, isAbsolute, relative } from "node:path";
+import { entityQualifiedName } from "../storage/entity-id.js";
+import { type Entity, EntityType } from "../types/storage.js";
+import { findGoModule, type GoModuleCache } from "./go-modules.js";
+
+export type QualifiedNameOptions = {
+  /** Root of the indexed tree; paths in names are relative to it */
+  rootDir?: string;
+  /** go.mod lookups, shared across the files of one index run */
+  goModules: GoModuleCache;
+};
+
+// Not declarations anything resolves to by name
+const UNNAMED_KINDS = new Set<string>([EntityType.COMMENT, EntityType.IMPORT, EntityType.EXPORT]);
+
+function relativePath(path: string, rootDir?: string): string {
+  if (!rootDir || !isAbsolute(path)) return path.replace(/\\/g, "/");
+  const rel = relative(rootDir, path);
+  if (!rel) return ".";
+  if (rel.startsWith("..") || isAbsolute(rel)) return path.replace(/\\/g, "/");
+  return rel.replace(/\\/g, "/");
+}
+
+function goPackagePath(dir: string, options: QualifiedNameOptions): string {
+  const mod = isAbsolute(dir) ? findGoModule(dir, options.goModules) : null;
+  if (!mod) return relativePath(dir, options.rootDir);
+  const rel = relative(mod.root, dir).replace(/\\/g, "/");
+  return rel ? `${mod.path}/${rel}` : mod.path;
+}
+
+/** Go struct fields name their struct through the analyzer id of its declaration (`main.go:type:Server`) */
+function goMemberName(entity: Pick<Entity, "name" | "type" | "filePath" | "metadata">): string {
+  const parent = entity.metadata?.parent;
+  if (typeof parent === "string" && !entity.metadata?.qualifiedName) {
+    return `${parent.split(":").pop()}.${entity.name}`;
+  }
+  return entityQualifiedName(entity);
+}
+
+/**
+ * The fully qualified name of a declaration, or null for entities that are not one (comments,
+ * imports, exports, external placeholders)
+ */
+export function fullyQualifiedName(
+  entity: Pick<Entity, "name" | "type" | "filePath" | "metadata">,
+  options: QualifiedNameOptions,
+): string | null {
+  if (UNNAMED_KINDS.has(String(entity.type)) || entity.filePath.startsWith("external://")) return null;
+  const meta = entity.metadata ?? {};
+
+  if (meta.language === "go" || extname(entity.filePath) === ".go") {
+    const packagePath = goPackagePath(dirname(entity.filePath), options);
+    return meta.isPackage ? packagePath : `${packagePath}.${goMemberName(entity)}`;
+  }
+
+  const file = relativePath(entity.filePath, options.rootDir);
+  const ext = extname(file);
+  return `${ext ? file.slice(0, -ext.length) : file}#${entityQualifiedName(entity)}`;
+}
@@ -0,0 +1,128 @@
+/**
+ * Result Cache - semantic_search and query results, reused until the index changes
+ * Agents repeat the same search within a reasoning loop; while nothing was indexed in between,
+ * the answer is the one already computed. Each lookup reads the index's write signature (rows
+ * written to the graph database, vectors written to the store); when it differs from the last one
+ * seen, the index generation is bumped and every cached result is dropped. Results are kept as
+ * serialized JSON, so a hit hands out a fresh copy and the byte cap counts what is actually held.
+ */
+
+import { LRUCache } from "lru-cache";
+
+export interface ResultCacheOptions {
+  /** Results kept at most; 0 disables the cache */
+  maxEntries: number;
+  /** Serialized bytes kept at most; 0 disables the cache */
+  maxBytes: number;
+}
+
+export interface ResultCacheStats {
+  generation: number;
+  entries: number;
+  bytes: number;
+  hits: number;
+  misses: number;
+  invalidations: number;
+}
+
+type Entry = { generation: number; json: string };
+
+/** Keys in sorted order at every depth, so `{a, b}` and `{b, a}` serialize alike */
+function stableStringify(value: unknown): string {
+  if (Array.isArray(value)) return `[${value.map(stableStringify).join(",")}]`;
+  if (value && typeof value === "object") {
+    const fields = Object.entries(value as Record<string, unknown>)
+      .filter(([, field]) => field !== undefined)
+      .sort(([a], [b]) => (a < b ? -1 : a > b ? 1 : 0));
+    return `{${fields.map(([key, field]) => `${JSON.stringify(key)}:${stableStringify(field)}`).join(",")}}`;
+  }
+  return JSON.stringify(value ?? null);
+}
+
+/**
+ * Cache key of a tool call: the query with surrounding and repeated whitespace collapsed, every
+ * other parameter that shapes the result, and the embedding provider answering it.
+ */
+export function resultCacheKey(tool: string, query: string, params: Record<string, unknown>, source: string): string {
+  const normalized = query.trim().replace(/\s+/g, " ");
+  return `${tool}\u0000${source}\u0000${normalized}\u0000${stableStringify(params)}`;
+}
+
+export class ResultCache {
+  private readonly entries: LRUCache<string, Entry> | null;
+  private generation = 0;
+  private signature: string | null = null;
+  private hits = 0;
+  private misses = 0;
+  private invalidations = 0;
+
+  /** `readSignature` describes what the index holds; any write to it must change its value */
+  constructor(
+    options: ResultCacheOptions,
+    private readonly readSignature: () => string,
+  ) {
+    const maxEntries = Math.max(0, Math.floor(options.maxEntries));
+    const maxBytes = Math.max(0, Math.floor(options.maxBytes));
+    this.entries =
+      maxEntries > 0 && maxBytes > 0
+        ? new LRUCache<string, Entry>({
+            max: maxEntries,
+            maxSize: maxBytes,
+            sizeCalculation: (entry) => Math.max(1, entry.json.length),
+          })
+        : null;
+  }
+
+  /** Generation of the index as it is now; cached results of earlier generations are dropped */
+  currentGeneration(): number {
+    const signature = this.readSignature();
+    if (signature !== this.signature) {
+      if (this.signature !== null) {
+        this.generation += 1;
+        this.invalidations += 1;
+        this.entries?.clear();
+      }
+      this.signature = signature;
+    }
+    return this.generation;
+  }
+
+  get<T>(key: string): T | undefined {
+    if (!this.entries) return undefined;
+    const generation = this.currentGeneration();
+    const entry = this.entries.get(key);
+    if (!entry || entry.generation !== generation) {
+      this.misses += 1;
+      return undefined;
+    }
+    this.hits += 1;
+    return JSON.parse(entry.json) as T;
+  }
+
+  /**
+   * Keep a result computed from the index at `generation`, read before computing it: a result that
+   * overlapped a write may describe either side of it and is not kept. Results larger than the
+   * byte cap are not kept either.
+   */
+  set(key: string, value: unknown, generation: number): void {
+    if (!this.entries || generation !== this.currentGeneration()) return;
+    const json = JSON.stringify(value);
+    if (json === undefined || json.length > this.entries.maxSize) return;
+    this.entries.set(key, { generation, json });
+  }
+
+  clear(): void {
+    this.entries?.clear();
+  }
+
+  getStats(): ResultCacheStats {
+    return {
+      generation: this.generation,
+      entries: this.entries?.size ?? 0,
+      bytes: this.entries?.calculatedSize ?? 0,
+      hits: this.hits,
+      misses: this.misses,
+      invalidations: this.invalidations,
+    };
+  }
+}
@@ -0,0 +1,249 @@
+/**
+ * Ruby Constant Resolver - merges reopened Ruby classes and binds constants across files
+ * Ruby has one constant namespace per program, so a class or module opened in several files is
+ * one declaration: the fragments are merged into a single entity per qualified name, the first in
+ * path order keeping its id. Superclass, mixin and association edges left at placeholders are
+ * then bound by lexical constant lookup from the declaring class, and calls without a receiver,
+ * on `self` or on a constant are bound to methods of the class, its superclasses and mixins.
+ */
+
+import { extname } from "node:path";
+import {
+  RUBY_CONSTANT_PATH,
+  type RubyMixinKind,
+  resolveRubyConstant,
+  rubyOwnerName,
+} from "../parsers/ruby-analyzer.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { type Entity, type Relationship, RelationType } from "../types/storage.js";
+import { reboundMetadata } from "./edge-confidence.js";
+
+export interface RubyConstantResolution {
+  filesScanned: number;
+  reopenedMerged: number;
+  constantsResolved: number;
+  callsResolved: number;
+}
+
+const RUBY_EXTENSIONS = new Set([".rb", ".rake", ".gemspec", ".ru"]);
+const TYPE_KINDS = new Set(["class", "module"]);
+const CONSTANT_EDGES = new Set<string>([RelationType.EXTENDS, RelationType.IMPLEMENTS, RelationType.REFERENCES]);
+
+type Mixin = { kind: RubyMixinKind; module: string };
+
+function meta(entity: Entity): Record<string, any> {
+  return (entity.metadata ?? {}) as Record<string, any>;
+}
+
+function isPlaceholder(entity: Entity): boolean {
+  return entity.filePath.startsWith("external://");
+}
+
+function qualifiedNameOf(entity: Entity): string {
+  const name = meta(entity).qualifiedName;
+  return typeof name === "string" && name ? name : entity.name;
+}
+
+function byPosition(a: Entity, b: Entity): number {
+  return a.filePath.localeCompare(b.filePath) || a.location.start.line - b.location.start.line;
+}
+
+/** Classes, modules and methods of every indexed Ruby file, by qualified name */
+class RubySymbols {
+  private readonly types = new Map<string, Entity>();
+  private readonly methods = new Map<string, Entity>();
+
+  add(entity: Entity): void {
+    const qualifiedName = qualifiedNameOf(entity);
+    const table = TYPE_KINDS.has(String(entity.type)) ? this.types : entity.type === "method" ? this.methods : null;
+    const known = table?.get(qualifiedName);
+    if (table && (!known || byPosition(entity, known) < 0)) table.set(qualifiedName, entity);
+  }
+
+  named(qualifiedName: string): Entity | undefined {
+    return this.types.get(qualifiedName);
+  }
+
+  type(written: string, scope: string): Entity | undefined {
+    return resolveRubyConstant(written, scope, (qualifiedName) => this.types.get(qualifiedName));
+  }
+
+  /**
+   * Method `name` of `type` (a class method when `singleton`) or of its ancestors: mixins
+   * (extended modules for class methods), then the superclass chain
+   */
+  findMethod(type: Entity, singleton: boolean, name: string): Entity | undefined {
+    const seen = new Set<string>();
+    const queue = [{ type, singleton }];
+    while (queue.length > 0) {
+      const next = queue.shift()!;
+      const qualifiedName = qualifiedNameOf(next.type);
+      const key = `${qualifiedName}${next.singleton ? "." : "#"}`;
+      if (seen.has(key)) continue;
+      seen.add(key);
+      const found = this.methods.get(`${key}${name}`);
+      if (found) return found;
+
+      for (const mixin of (meta(next.type).mixins ?? []) as Mixin[]) {
+        if ((mixin.kind === "extend") !== next.singleton) continue;
+        const module = this.type(mixin.module, qualifiedName);
+        if (module) queue.push({ type: module, singleton: false });
+      }
+      const superclass = meta(next.type).superclass;
+      const parent = typeof superclass === "string" ? this.type(superclass, qualifiedName) : undefined;
+      if (parent) queue.push({ type: parent, singleton: next.singleton });
+    }
+    return undefined;
+  }
+}
+
+/**
+ * Merge reopened classes and modules, then bind constants and calls of `files` (every indexed
+ * Ruby file when omitted) against every indexed Ruby file.
+ */
+export async function resolveRubyConstants(
+  storage: GraphStorageImpl,
+  files?: string[],
+): Promise<RubyConstantResolution> {
+  const indexed = (await storage.listIndexedFiles())
+    .map((info) => info.path)
+    .filter((path) => RUBY_EXTENSIONS.has(extname(path).toLowerCase()))
+    .sort();
+  const indexedSet = new Set(indexed);
+  const targets = [...new Set(files ?? indexed)].filter((file) => indexedSet.has(file)).sort();
+  const stats: RubyConstantResolution = {
+    filesScanned: targets.length,
+    reopenedMerged: 0,
+    constantsResolved: 0,
+    callsResolved: 0,
+  };
+  if (targets.length === 0) return stats;
+
+  const entitiesByFile = new Map<string, Entity[]>();
+  for (const file of indexed) {
+    entitiesByFile.set(file, await storage.findEntities({ type: "entity", filters: { filePath: file }, limit: 10000 }));
+  }
+
+  // Reopened classes: the first declaration in path order takes over the edges of the others
+  const fragments = new Map<string, Entity[]>();
+  for (const entity of [...entitiesByFile.values()].flat()) {
+    if (!TYPE_KINDS.has(String(entity.type))) continue;
+    const qualifiedName = qualifiedNameOf(entity);
+    fragments.set(qualifiedName, [...(fragments.get(qualifiedName) ?? []), entity]);
+  }
+  const removed = new Set<string>();
+  for (const group of fragments.values()) {
+    if (group.length < 2) continue;
+    group.sort(byPosition);
+    const [primary, ...others] = group as [Entity, ...Entity[]];
+
+    const moved: Relationship[] = [];
+    for (const fragment of others) {
+      for (const rel of await storage.getRelationshipsForEntity(fragment.id)) {
+        const fromId = rel.fromId === fragment.id ? primary.id : rel.fromId;
+        const toId = rel.toId === fragment.id ? primary.id : rel.toId;
+        await storage.deleteRelationship(rel.id);
+        if (fromId !== toId) moved.push({ ...rel, fromId, toId });
+      }
+    }
+    if (moved.length > 0) await storage.insertRelationships(moved);
+    for (const fragment of others) {
+      await storage.deleteEntity(fragment.id);
+      removed.add(fragment.id);
+    }
+
+    // The merged class carries what every fragment declared about its ancestors
+    const mixins = new Map<string, Mixin>();
+    for (const fragment of group) {
+      for (const mixin of (meta(fragment).mixins ?? []) as Mixin[]) mixins.set(`${mixin.kind}|${mixin.module}`, mixin);
+    }
+    const metadata = {
+      ...meta(primary),
+      superclass: group.map((fragment) => meta(fragment).superclass).find((name) => typeof name === "string"),
+      mixins: [...mixins.values()],
+      reopenedFiles: [...new Set(group.map((fragment) => fragment.filePath))].sort(),
+    };
+    await storage.updateEntity(primary.id, { metadata });
+    primary.metadata = metadata;
+    stats.reopenedMerged += 1;
+  }
+
+  const symbols = new RubySymbols();
+  for (const [file, entities] of entitiesByFile) {
+    const remaining = entities.filter((entity) => !removed.has(entity.id));
+    entitiesByFile.set(file, remaining);
+    for (const entity of remaining) symbols.add(entity);
+  }
+
+  const outgoing = async (entity: Entity, types: Set<string>) =>
+    (await storage.getRelationshipsForEntity(entity.id)).filter(
+      (rel) => rel.fromId === entity.id && types.has(rel.type),
+    );
+
+  // A merged class also carries the edges of fragments in target files declared elsewhere
+  const targetSet = new Set(targets);
+  const typesToBind = [...entitiesByFile.values()]
+    .flat()
+    .filter(
+      (entity) =>
+        TYPE_KINDS.has(String(entity.type)) &&
+        (targetSet.has(entity.filePath) ||
+          ((meta(entity).reopenedFiles ?? []) as string[]).some((file) => targetSet.has(file))),
+    );
+  for (const type of typesToBind) {
+    const edges = await outgoing(type, CONSTANT_EDGES);
+    const bound = new Set(edges.map((rel) => `${rel.type}|${rel.toId}`));
+    const moved: Relationship[] = [];
+
+    for (const rel of edges) {
+      const placeholder = await storage.getEntity(rel.toId);
+      if (!placeholder || !isPlaceholder(placeholder) || !RUBY_CONSTANT_PATH.test(placeholder.name)) continue;
+      const target = symbols.type(placeholder.name, qualifiedNameOf(type));
+      if (!target || target.id === type.id) continue;
+
+      await storage.deleteRelationship(rel.id);
+      stats.constantsResolved += 1;
+      if (bound.has(`${rel.type}|${target.id}`)) continue;
+      bound.add(`${rel.type}|${target.id}`);
+      moved.push({ ...rel, toId: target.id, metadata: reboundMetadata(rel.metadata, "ruby") });
+    }
+    if (moved.length > 0) await storage.insertRelationships(moved);
+  }
+
+  for (const file of targets) {
+    const moved: Relationship[] = [];
+    for (const caller of entitiesByFile.get(file) ?? []) {
+      if (caller.type !== "method") continue;
+      const singleton = meta(caller).isClassMethod === true;
+      const scope = rubyOwnerName(qualifiedNameOf(caller));
+      const owner = symbols.named(scope);
+      if (!owner) continue;
+
+      for (const rel of await outgoing(caller, new Set([RelationType.CALLS]))) {
+        const callee = await storage.getEntity(rel.toId);
+        if (!callee || !isPlaceholder(callee)) continue;
+
+        const written = String(rel.metadata?.callSites?.[0]?.callee ?? callee.name);
+        const dot = written.lastIndexOf(".");
+        const receiver = dot < 0 ? "" : written.slice(0, dot);
+        let target: Entity | undefined;
+        if (!receiver || receiver === "self") {
+          target = symbols.findMethod(owner, singleton, callee.name);
+        } else if (RUBY_CONSTANT_PATH.test(receiver)) {
+          const type = symbols.type(receiver, scope);
+          if (type && callee.name === "new") target = symbols.findMethod(type, false, "initialize");
+          else if (type) target = symbols.findMethod(type, true, callee.name);
+        }
+        if (!target || target.id === rel.toId) continue;
+        await storage.deleteRelationship(rel.id);
+        moved.push({ ...rel, toId: target.id, metadata: reboundMetadata(rel.metadata, "ruby") });
+      }
+    }
+    if (moved.length > 0) {
+      await storage.insertRelationships(moved);
+      stats.callsResolved += moved.length;
+    }
+  }
+
+  return stats;
+}
@@ -0,0 +1,124 @@
+/**
+ * What the server is doing right now, and the single-writer queue behind it. Several clients can
+ * share one server, so tool calls overlap: reads need no coordination (SQLite in WAL mode lets
+ * them run while a write is in progress), but two index runs, or an index run and a compaction,
+ * would interleave their rows or trip over each other's locks. Writes therefore run one at a time
+ * in call order; a call that arrives while another is writing waits its turn instead of failing.
+ */
+
+import { AgentStatus } from "../types/agent.js";
+import { AgentBusyError, TaskCancelledError } from "../types/errors.js";
+
+/** `indexing` while any write holds the writer slot, `querying` while only reads run */
+export type ServerState = "idle" | "indexing" | "querying";
+
+export interface ActivitySnapshot {
+  state: ServerState;
+  writer: { tool: string; startedAt: number; runningMs: number } | null;
+  /** Writes waiting for the writer slot, in the order they will run */
+  queuedWrites: Array<{ tool: string; waitingMs: number }>;
+  activeReads: number;
+  readsByTool: Record<string, number>;
+}
+
+type QueuedWrite = { tool: string; since: number; start: () => void };
+
+export class ServerActivity {
+  private writer: { tool: string; startedAt: number } | null = null;
+  private queue: QueuedWrite[] = [];
+  private reads = new Map<string, number>();
+
+  constructor(private maxQueued = 16) {}
+
+  /** Writes allowed to wait behind the running one; calls past this fail with `queue_full` */
+  setMaxQueued(maxQueued: number): void {
+    this.maxQueued = Math.max(1, maxQueued);
+  }
+
+  /**
+   * Run `work` once every earlier write has finished. Aborting `signal` while the call still waits
+   * takes it out of the queue; once started, stopping is up to `work`.
+   */
+  async write<T>(tool: string, work: () => Promise<T>, signal?: AbortSignal): Promise<T> {
+    await this.acquire(tool, signal);
+    try {
+      return await work();
+    } finally {
+      this.writer = null;
+      this.queue.shift()?.start();
+    }
+  }
+
+  /** Run `work` as a read, counted for `snapshot` but never held back */
+  async read<T>(tool: string, work: () => Promise<T>): Promise<T> {
+    this.reads.set(tool, (this.reads.get(tool) ?? 0) + 1);
+    try {
+      return await work();
+    } finally {
+      const left = (this.reads.get(tool) ?? 1) - 1;
+      if (left > 0) this.reads.set(tool, left);
+      else this.reads.delete(tool);
+    }
+  }
+
+  /** What runs now; `caller` is the read asking, left out so it does not report itself */
+  snapshot(caller?: string): ActivitySnapshot {
+    const now = Date.now();
+    const readsByTool = Object.fromEntries(this.reads);
+    const own = caller ? readsByTool[caller] : undefined;
+    if (caller && own) {
+      if (own > 1) readsByTool[caller] = own - 1;
+      else delete readsByTool[caller];
+    }
+    const activeReads = Object.values(readsByTool).reduce((acc, n) => acc + n, 0);
+    return {
+      state: this.writer ? "indexing" : activeReads > 0 ? "querying" : "idle",
+      writer: this.writer ? { ...this.writer, runningMs: now - this.writer.startedAt } : null,
+      queuedWrites: this.queue.map((queued) => ({ tool: queued.tool, waitingMs: now - queued.since })),
+      activeReads,
+      readsByTool,
+    };
+  }
+
+  private acquire(tool: string, signal?: AbortSignal): Promise<void> {
+    const cancelled = () =>
+      signal?.reason instanceof Error ? signal.reason : new TaskCancelledError({ reason: "client" });
+    if (signal?.aborted) return Promise.reject(cancelled());
+    if (!this.writer && this.queue.length === 0) {
+      this.writer = { tool, startedAt: Date.now() };
+      return Promise.resolve();
+    }
+    if (this.queue.length >= this.maxQueued) {
+      return Promise.reject(
+        new AgentBusyError({
+          agentId: "write-queue",
+          status: AgentStatus.BUSY,
+          reason: "queue_full",
+          queueLength: this.queue.length,
+          maxQueue: this.maxQueued,
+        }),
+      );
+    }
+
+    return new Promise((resolve, reject) => {
+      const onAbort = () => {
+        const at = this.queue.indexOf(queued);
+        if (at !== -1) this.queue.splice(at, 1);
+        reject(cancelled());
+      };
+      const queued: QueuedWrite = {
+        tool,
+        since: Date.now(),
+        start: () => {
+          signal?.removeEventListener("abort", onAbort);
+          this.writer = { tool, startedAt: Date.now() };
+          resolve();
+        },
+      };
+      signal?.addEventListener("abort", onAbort, { once: true });
+      this.queue.push(queued);
+    });
+  }
+}
+
+export const serverActivity = new ServerActivity();
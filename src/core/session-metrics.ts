@@ -0,0 +1,175 @@
+/**
+ * Session Metrics - in-process counters for the server's lifetime, or since the last reset
+ * Tool calls are counted per tool with their latencies; parsing, storing and embedding record
+ * how much work they did and how long it took, so a snapshot shows where time went (embedding
+ * versus parsing) without outside instrumentation. Nothing is persisted; a restart starts over.
+ */
+
+/** Latencies kept per tool for percentiles; older calls still count in the totals */
+const LATENCY_WINDOW = 256;
+
+export type ToolOutcome = { success: boolean; errorType?: string; cached?: boolean; write?: boolean };
+
+export interface ToolMetrics {
+  calls: number;
+  errors: number;
+  errorTypes: Record<string, number>;
+  /** Calls answered from the result cache */
+  cached: number;
+  totalMs: number;
+  avgMs: number;
+  /** Percentiles over the most recent calls */
+  p50Ms: number;
+  p95Ms: number;
+  maxMs: number;
+}
+
+export interface MetricsSnapshot {
+  since: string;
+  uptimeMs: number;
+  /** `queries` are calls that only read the index, `writes` the ones that change it */
+  totals: { calls: number; queries: number; writes: number; errors: number; cached: number; totalMs: number };
+  tools: Record<string, ToolMetrics>;
+  parsing: { files: number; errors: number; totalMs: number; avgMs: number };
+  indexing: { files: number; entities: number; relationships: number; removedEntities: number; totalMs: number };
+  embeddings: {
+    /** generate calls, each covering one text or a batch */
+    calls: number;
+    texts: number;
+    /** Texts answered from the in-memory or persistent cache */
+    cacheHits: number;
+    /** Texts sent to the provider */
+    embedded: number;
+    /** Texts the fallback embedder had to answer */
+    fallbacks: number;
+    hitRate: number;
+    totalMs: number;
+  };
+}
+
+type ToolCounters = {
+  write: boolean;
+  calls: number;
+  errors: number;
+  errorTypes: Map<string, number>;
+  cached: number;
+  totalMs: number;
+  maxMs: number;
+  recent: number[];
+};
+
+function percentile(sorted: number[], p: number): number {
+  if (sorted.length === 0) return 0;
+  return sorted[Math.min(sorted.length - 1, Math.ceil((p / 100) * sorted.length) - 1)] ?? 0;
+}
+
+export class SessionMetrics {
+  private since = Date.now();
+  private tools = new Map<string, ToolCounters>();
+  private parsing = { files: 0, errors: 0, totalMs: 0 };
+  private indexing = { files: 0, entities: 0, relationships: 0, removedEntities: 0, totalMs: 0 };
+  private embeddings = { calls: 0, texts: 0, cacheHits: 0, embedded: 0, fallbacks: 0, totalMs: 0 };
+
+  recordToolCall(tool: string, durationMs: number, outcome: ToolOutcome): void {
+    let counters = this.tools.get(tool);
+    if (!counters) {
+      counters = {
+        write: Boolean(outcome.write),
+        calls: 0,
+        errors: 0,
+        errorTypes: new Map(),
+        cached: 0,
+        totalMs: 0,
+        maxMs: 0,
+        recent: [],
+      };
+      this.tools.set(tool, counters);
+    }
+    counters.calls++;
+    counters.totalMs += durationMs;
+    counters.maxMs = Math.max(counters.maxMs, durationMs);
+    counters.recent.push(durationMs);
+    if (counters.recent.length > LATENCY_WINDOW) counters.recent.shift();
+    if (outcome.cached) counters.cached++;
+    if (!outcome.success) {
+      counters.errors++;
+      const type = outcome.errorType ?? "unknown";
+      counters.errorTypes.set(type, (counters.errorTypes.get(type) ?? 0) + 1);
+    }
+  }
+
+  recordParse(durationMs: number, failed = false): void {
+    this.parsing.files++;
+    this.parsing.totalMs += durationMs;
+    if (failed) this.parsing.errors++;
+  }
+
+  /** One file written to the graph */
+  recordIndexedFile(entities: number, relationships: number, removedEntities: number, durationMs: number): void {
+    this.indexing.files++;
+    this.indexing.entities += entities;
+    this.indexing.relationships += relationships;
+    this.indexing.removedEntities += removedEntities;
+    this.indexing.totalMs += durationMs;
+  }
+
+  /** One embedding request: `texts` asked for, of which `embedded` went to the provider */
+  recordEmbedding(texts: number, embedded: number, fallbacks: number, durationMs: number): void {
+    this.embeddings.calls++;
+    this.embeddings.texts += texts;
+    this.embeddings.embedded += embedded;
+    this.embeddings.cacheHits += Math.max(0, texts - embedded);
+    this.embeddings.fallbacks += fallbacks;
+    this.embeddings.totalMs += durationMs;
+  }
+
+  snapshot(): MetricsSnapshot {
+    const tools: Record<string, ToolMetrics> = {};
+    const totals = { calls: 0, queries: 0, writes: 0, errors: 0, cached: 0, totalMs: 0 };
+    for (const [tool, counters] of [...this.tools].sort(([a], [b]) => a.localeCompare(b))) {
+      const sorted = [...counters.recent].sort((a, b) => a - b);
+      tools[tool] = {
+        calls: counters.calls,
+        errors: counters.errors,
+        errorTypes: Object.fromEntries(counters.errorTypes),
+        cached: counters.cached,
+        totalMs: counters.totalMs,
+        avgMs: Math.round(counters.totalMs / counters.calls),
+        p50Ms: percentile(sorted, 50),
+        p95Ms: percentile(sorted, 95),
+        maxMs: counters.maxMs,
+      };
+      totals.calls += counters.calls;
+      if (counters.write) totals.writes += counters.calls;
+      else totals.queries += counters.calls;
+      totals.errors += counters.errors;
+      totals.cached += counters.cached;
+      totals.totalMs += counters.totalMs;
+    }
+
+    const { texts, cacheHits } = this.embeddings;
+    return {
+      since: new Date(this.since).toISOString(),
+      uptimeMs: Date.now() - this.since,
+      totals,
+      tools,
+      parsing: {
+        ...this.parsing,
+        avgMs: this.parsing.files > 0 ? Math.round(this.parsing.totalMs / this.parsing.files) : 0,
+      },
+      indexing: { ...this.indexing },
+      embeddings: { ...this.embeddings, hitRate: texts > 0 ? cacheHits / texts : 0 },
+    };
+  }
+
+  /** Start counting from zero again */
+  reset(): void {
+    this.since = Date.now();
+    this.tools.clear();
+    this.parsing = { files: 0, errors: 0, totalMs: 0 };
+    this.indexing = { files: 0, entities: 0, relationships: 0, removedEntities: 0, totalMs: 0 };
+    this.embeddings = { calls: 0, texts: 0, cacheHits: 0, embedded: 0, fallbacks: 0, totalMs: 0 };
+  }
+}
+
+export const sessionMetrics = new SessionMetrics();
@@ -0,0 +1,76 @@
+/**
+ * Graceful shutdown. Tool calls and watcher updates run through `track`; on SIGINT/SIGTERM the
+ * server stops accepting work, aborts what is running (index runs stop after the batch they are
+ * writing) and waits up to a timeout for it to settle before the database is closed.
+ */
+
+import { TaskCancelledError } from "../types/errors.js";
+
+export interface ShutdownReport {
+  /** Whether every tracked task settled within the timeout */
+  drained: boolean;
+  /** Tasks still running when the timeout expired */
+  abandoned: number;
+  ms: number;
+}
+
+/** Thrown by `track` once shutdown has begun */
+export class ShuttingDownError extends Error {
+  constructor() {
+    super("Server is shutting down; no new work is accepted");
+    this.name = "ShuttingDownError";
+  }
+}
+
+export class ShutdownCoordinator {
+  private readonly controller = new AbortController();
+  private readonly inFlight = new Set<Promise<unknown>>();
+  private draining: Promise<ShutdownReport> | null = null;
+
+  /** Aborted, with a TaskCancelledError as reason, when shutdown begins */
+  get signal(): AbortSignal {
+    return this.controller.signal;
+  }
+
+  get stopping(): boolean {
+    return this.draining !== null;
+  }
+
+  get pending(): number {
+    return this.inFlight.size;
+  }
+
+  /** Run `work`, which shutdown waits for; refused once shutdown has begun */
+  track<T>(work: () => Promise<T>): Promise<T> {
+    if (this.stopping) return Promise.reject(new ShuttingDownError());
+    const running = work();
+    const settled = running.then(
+      () => undefined,
+      () => undefined,
+    );
+    this.inFlight.add(settled);
+    void settled.then(() => this.inFlight.delete(settled));
+    return running;
+  }
+
+  /**
+   * Abort tracked work and wait up to `timeoutMs` for it to settle. Calling it again returns the
+   * same drain.
+   */
+  shutdown(timeoutMs: number): Promise<ShutdownReport> {
+    if (this.draining) return this.draining;
+    const started = Date.now();
+    this.controller.abort(new TaskCancelledError({ reason: "shutdown" }));
+
+    this.draining = (async () => {
+      let timer: NodeJS.Timeout | undefined;
+      const expired = new Promise<"timeout">((resolve) => {
+        timer = setTimeout(() => resolve("timeout"), Math.max(0, timeoutMs));
+      });
+      const outcome = await Promise.race([Promise.all(this.inFlight).then(() => "drained" as const), expired]);
+      clearTimeout(timer);
+      return { drained: outcome === "drained", abandoned: this.inFlight.size, ms: Date.now() - started };
+    })();
+    return this.draining;
+  }
+}
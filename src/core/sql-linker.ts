@@ -0,0 +1,88 @@
+/**
+ * SQL Linker - connects tables across migration files
+ * A migration's foreign keys, view queries and routine bodies usually name tables created by an
+ * earlier migration, so per-file indexing leaves those edges at `external://` placeholders. Once
+ * every `.sql` file is indexed, each is bound to the table or view its name resolves to.
+ */
+
+import { extname } from "node:path";
+import { matchSqlTable } from "../parsers/sql-analyzer.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { type Entity, EntityType, type Relationship, RelationType } from "../types/storage.js";
+import { reboundMetadata } from "./edge-confidence.js";
+
+export interface SqlLinking {
+  filesScanned: number;
+  tablesResolved: number;
+}
+
+const SOURCE_KINDS = new Set(["table", "view", "function", "procedure"]);
+
+function meta(entity: Entity): Record<string, any> {
+  return (entity.metadata ?? {}) as Record<string, any>;
+}
+
+function isPlaceholder(entity: Entity): boolean {
+  return entity.filePath.startsWith("external://");
+}
+
+function isSql(filePath: string): boolean {
+  return extname(filePath).toLowerCase() === ".sql";
+}
+
+/**
+ * Bind the table references of the SQL files among `files` (every indexed one when omitted).
+ * A table created by several files (dropped and re-created by a later migration) binds to the
+ * definition last in path order, which is the current one for numbered migrations; tables that
+ * are only altered in a file are never targets.
+ */
+export async function linkSqlTables(storage: GraphStorageImpl, files?: string[]): Promise<SqlLinking> {
+  const indexed = (await storage.listIndexedFiles())
+    .map((info) => info.path)
+    .filter(isSql)
+    .sort();
+  const indexedSet = new Set(indexed);
+  const targets = [...new Set(files ?? indexed)].filter((file) => indexedSet.has(file)).sort();
+  const stats: SqlLinking = { filesScanned: targets.length, tablesResolved: 0 };
+  if (targets.length === 0) return stats;
+
+  const tables = new Map<string, Entity>();
+  const entitiesByFile = new Map<string, Entity[]>();
+  for (const file of indexed) {
+    const entities = await storage.findEntities({ type: "entity", filters: { filePath: file }, limit: 10000 });
+    entitiesByFile.set(file, entities);
+    for (const entity of entities) {
+      const name = meta(entity).qualifiedName;
+      const declared = entity.type === EntityType.TABLE || entity.type === EntityType.VIEW;
+      if (!declared || meta(entity).altered === true || typeof name !== "string") continue;
+      tables.set(name, entity);
+    }
+  }
+
+  for (const file of targets) {
+    const moved: Relationship[] = [];
+    for (const source of entitiesByFile.get(file) ?? []) {
+      if (!SOURCE_KINDS.has(meta(source).sqlKind)) continue;
+      const bound = new Set<string>();
+      for (const type of [RelationType.FOREIGN_KEY, RelationType.REFERENCES]) {
+        for (const rel of await storage.getRelationshipsForEntity(source.id, type)) {
+          if (rel.fromId !== source.id) continue;
+          const placeholder = await storage.getEntity(rel.toId);
+          if (!placeholder || !isPlaceholder(placeholder)) continue;
+          const key = matchSqlTable(placeholder.name, tables.keys());
+          const target = key ? tables.get(key) : undefined;
+          if (!target) continue;
+
+          await storage.deleteRelationship(rel.id);
+          stats.tablesResolved += 1;
+          if (bound.has(`${type}:${target.id}`)) continue;
+          bound.add(`${type}:${target.id}`);
+          moved.push({ ...rel, toId: target.id, metadata: reboundMetadata(rel.metadata, "sql") });
+        }
+      }
+    }
+    if (moved.length > 0) await storage.insertRelationships(moved);
+  }
+
+  return stats;
+}
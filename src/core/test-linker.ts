@@ -0,0 +1,147 @@
+/**
+ * Test Linker - links test functions to the production code they exercise
+ * Runs last, once cross-file resolution has moved calls onto their declarations. Every test
+ * declaration in a test file (Go `TestXxx`, pytest `test_*`, JUnit `@Test`, Jest/Vitest `it`)
+ * gets a tests edge to each declaration outside test files that it calls, references or renders,
+ * directly or through helpers defined in test files. Tests edges of the scanned tests are
+ * rebuilt each run. Test files stored apart from the main graph (indexer.includeTests: false) are
+ * linked too; cross-file resolution never sees them, so their edges are bound here, by name.
+ */
+
+import { dirname } from "node:path";
+import { isTestDeclaration, isTestFile, testFramework } from "../parsers/test-extractor.js";
+import { stableRelationshipId } from "../storage/entity-id.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { withTests } from "../storage/tests-view.js";
+import { type Entity, type Relationship, RelationType } from "../types/storage.js";
+import { confidenceOf, edgeConfidence } from "./edge-confidence.js";
+
+export interface TestLinking {
+  testsScanned: number;
+  linksFound: number;
+}
+
+/** Edges through which a test reaches the code it exercises */
+const EXERCISING_RELATIONSHIPS = new Set<string>([
+  RelationType.CALLS,
+  RelationType.REFERENCES,
+  RelationType.RENDERS,
+  RelationType.USES_HOOK,
+]);
+
+/** Hops through test-file helpers (`newTestServer`, fixtures) before giving up on a path */
+const MAX_HELPER_DEPTH = 3;
+
+function isPlaceholder(entity: Entity): boolean {
+  const meta = entity.metadata as Record<string, unknown> | undefined;
+  return Boolean(meta?.isExternal) || entity.filePath.startsWith("external://");
+}
+
+/**
+ * Resolves the name a placeholder stands for to a production declaration: the only one indexed
+ * under that name, or the only one in the test's own directory (its Go package, its Python
+ * package) when several share it.
+ */
+function createProductionLookup(storage: GraphStorageImpl) {
+  const cache = new Map<string, Entity[]>();
+  return async (placeholder: Entity, test: Entity): Promise<Entity | null> => {
+    const bare = placeholder.name.split(/::|\./).pop() ?? placeholder.name;
+    let found = cache.get(bare);
+    if (!found) {
+      const named = await storage.findEntities({ type: "entity", filters: { name: bare }, limit: 1000 });
+      found = named.filter((e) => !isPlaceholder(e) && !isTestFile(e.filePath) && e.type !== "import");
+      cache.set(bare, found);
+    }
+    if (found.length === 1) return found[0]!;
+    const sibling = found.filter((e) => dirname(e.filePath) === dirname(test.filePath));
+    return sibling.length === 1 ? sibling[0]! : null;
+  };
+}
+
+/** Point an edge stored apart from the main graph at `toId`, as cross-file resolution would */
+async function rebind(graph: GraphStorageImpl, rel: Relationship, toId: string): Promise<void> {
+  await graph.deleteTestRelationship(rel.id);
+  await graph.insertTestRelationships([
+    {
+      ...rel,
+      id: stableRelationshipId(rel.fromId, toId, rel.type),
+      toId,
+      metadata: { ...rel.metadata, ...edgeConfidence("name_match") },
+    },
+  ]);
+}
+
+/**
+ * Rebuild tests edges for the tests declared in the test files among `files` (every indexed
+ * file when omitted). Each production declaration is linked once per test, at the line of the
+ * first edge reaching it.
+ */
+export async function linkTests(graph: GraphStorageImpl, files?: string[]): Promise<TestLinking> {
+  const storage = await withTests(graph);
+  const targets = files ?? (await storage.listIndexedFiles()).map((info) => info.path);
+  const production = createProductionLookup(storage);
+  const stats: TestLinking = { testsScanned: 0, linksFound: 0 };
+
+  for (const file of [...new Set(targets)].filter(isTestFile).sort()) {
+    const entities = await storage.findEntities({ type: "entity", filters: { filePath: file }, limit: 10000 });
+    for (const test of entities.filter((e) => isTestDeclaration(e))) {
+      stats.testsScanned += 1;
+      const existing = await storage.getRelationshipsForEntity(test.id, RelationType.TESTS);
+      for (const rel of existing) if (rel.fromId === test.id) await storage.deleteRelationship(rel.id);
+
+      const found = new Map<string, Relationship>();
+      const visited = new Set<string>([test.id]);
+      // Edges found through a helper are attributed to the test's line that calls the helper
+      let frontier: Array<{ entity: Entity; helper: string | null; line: number | null }> = [
+        { entity: test, helper: null, line: null },
+      ];
+      for (let depth = 0; depth < MAX_HELPER_DEPTH && frontier.length > 0; depth++) {
+        const next: typeof frontier = [];
+        for (const { entity, helper, line } of frontier) {
+          const edges = (await storage.getRelationshipsForEntity(entity.id))
+            .filter((rel) => rel.fromId === entity.id && EXERCISING_RELATIONSHIPS.has(String(rel.type)))
+            .sort((a, b) => (a.metadata?.line ?? 0) - (b.metadata?.line ?? 0));
+          for (const rel of edges) {
+            let target = await storage.getEntity(rel.toId);
+            const byName = Boolean(target && isPlaceholder(target));
+            if (target && byName) target = await production(target, test);
+            if (target && byName && (await graph.getTestEntity(entity.id))) {
+              // So impact analysis and graph queries step from the production code onto the test
+              await rebind(graph, rel, target.id);
+            }
+            if (!target || visited.has(target.id)) continue;
+            visited.add(target.id);
+            const at = line ?? rel.metadata?.line ?? test.location.start.line;
+
+            if (isTestFile(target.filePath)) {
+              // A helper of the test suite; the code it calls is exercised on the test's behalf
+              if (!isTestDeclaration(target)) next.push({ entity: target, helper: helper ?? target.name, line: at });
+              continue;
+            }
+            found.set(target.id, {
+              id: stableRelationshipId(test.id, target.id, RelationType.TESTS),
+              fromId: test.id,
+              toId: target.id,
+              type: RelationType.TESTS,
+              metadata: {
+                line: at,
+                via: String(rel.type),
+                framework: testFramework(test),
+                ...(helper ? { helper } : {}),
+                ...edgeConfidence(byName ? "name_match" : confidenceOf(rel).derivation),
+              },
+            });
+          }
+        }
+        frontier = next;
+      }
+
+      if (found.size > 0) {
+        await storage.insertRelationships([...found.values()]);
+        stats.linksFound += found.size;
+      }
+    }
+  }
+
+  return stats;
+}
@@ -0,0 +1,75 @@
+/**
+ * Index roots - the directories one index is built from
+ * Every stored entity records the root its file was indexed from, so searches can be narrowed to
+ * one root. Roots that are packages (a package.json with a name) can be imported by that name from
+ * the other roots; the cross-file resolver binds such imports to the package's indexed sources.
+ */
+
+import { readFileSync } from "node:fs";
+import { dirname, isAbsolute, relative, resolve } from "node:path";
+
+export interface WorkspacePackage {
+  name: string;
+  root: string;
+  /** Entry points named by the manifest, relative to the root and most source-like first */
+  entries: string[];
+}
+
+/** The root containing `filePath`; the innermost one when roots nest */
+export function rootOf(filePath: string, roots: readonly string[]): string | undefined {
+  let found: string | undefined;
+  for (const root of roots) {
+    const rel = relative(root, filePath);
+    if (rel.startsWith("..") || isAbsolute(rel)) continue;
+    if (!found || root.length > found.length) found = root;
+  }
+  return found;
+}
+
+/** Deepest directory containing every root; entity ids of a multi-root index are relative to it */
+export function commonRoot(roots: readonly string[]): string {
+  const [first, ...rest] = roots.map((root) => resolve(root));
+  if (!first) return resolve(".");
+  let common = first;
+  for (const root of rest) {
+    while (!rootOf(root, [common])) {
+      const parent = dirname(common);
+      if (parent === common) break;
+      common = parent;
+    }
+  }
+  return common;
+}
+
+function manifestEntries(manifest: Record<string, unknown>): string[] {
+  const entries: unknown[] = [manifest.source, manifest.types, manifest.typings];
+  const exported = manifest.exports;
+  // `exports` is either a subpath map ({ ".": ... }) or the conditions of the package entry itself
+  const isSubpathMap =
+    typeof exported === "object" && exported !== null && Object.keys(exported).some((key) => key.startsWith("."));
+  const main = isSubpathMap ? (exported as Record<string, unknown>)["."] : exported;
+  if (typeof main === "string") entries.push(main);
+  else if (typeof main === "object" && main !== null) {
+    const conditions = main as Record<string, unknown>;
+    entries.push(conditions.types, conditions.import, conditions.default, conditions.require);
+  }
+  entries.push(manifest.module, manifest.main);
+  return entries
+    .filter((entry): entry is string => typeof entry === "string" && entry.length > 0)
+    .map((entry) => entry.replace(/^\.\//, ""));
+}
+
+/** Named packages among `roots`, keyed by package name; roots without a readable manifest are left out */
+export function readWorkspacePackages(roots: readonly string[]): Map<string, WorkspacePackage> {
+  const packages = new Map<string, WorkspacePackage>();
+  for (const root of roots) {
+    try {
+      const manifest = JSON.parse(readFileSync(resolve(root, "package.json"), "utf8")) as Record<string, unknown>;
+      if (typeof manifest.name !== "string" || !manifest.name) continue;
+      packages.set(manifest.name, { name: manifest.name, root, entries: manifestEntries(manifest) });
+    } catch {
+      // Not a package root
+    }
+  }
+  return packages;
+}
@@ -35,7 +35,7 @@ createSafeEnvironment();
 // Ensure stdout is reserved exclusively for MCP JSON-RPC messages (Codex/VSCode is strict).
 import "./utils/stdio-console.js";
 
-import { existsSync, mkdirSync, readFileSync, rmSync, writeFileSync } from "node:fs";
+import { existsSync, mkdirSync, readFileSync, rmSync, statSync, writeFileSync } from "node:fs";
 import { homedir } from "node:os";
 import { dirname, isAbsolute, join, normalize, relative, resolve } from "node:path";
 import { fileURLToPath } from "node:url";
@@ -48,39 +48,137 @@ import { z } from "zod";
 import { zodToJsonSchema } from "zod-to-json-schema";
 // Import our multi-agent components
 import { ConductorOrchestrator } from "./agents/conductor-orchestrator.js";
+import type { SemanticWarmup } from "./agents/semantic-agent.js";
+import { type LoadedRepoConfig, loadRepoConfig, RepoConfigError } from "./config/repo-config.js";
 // TASK-001: Import new YAML configuration system
-import { type AppConfig, ConfigLoader, initializeConfig, validateConfig } from "./config/yaml-config.js";
+import {
+  type AppConfig,
+  ConfigLoader,
+  INDEXING_TOOLS,
+  initializeConfig,
+  validateConfig,
+} from "./config/yaml-config.js";
+import { type IndexProgress, indexProgress, overallPercent } from "./core/index-progress.js";
+import { IndexWatcher } from "./core/index-watcher.js";
 import { knowledgeBus } from "./core/knowledge-bus.js";
 import { resourceManager } from "./core/resource-manager.js";
-import { getGraphStorage, initializeGraphStorage } from "./storage/graph-storage-factory.js";
+import { ResultCache, resultCacheKey } from "./core/result-cache.js";
+import { serverActivity } from "./core/server-activity.js";
+import { sessionMetrics, type ToolOutcome } from "./core/session-metrics.js";
+import { ShutdownCoordinator, ShuttingDownError } from "./core/shutdown.js";
+import { rootOf } from "./core/workspace-roots.js";
+import { validateCustomQueries } from "./parsers/custom-queries.js";
+import { diagnoseGrammars } from "./parsers/grammar-loader.js";
+import { VISIBILITIES, type Visibility } from "./parsers/visibility.js";
+import { getGraphStorage, initializeGraphStorage, resetGraphStorage } from "./storage/graph-storage-factory.js";
+import { exportIndex, IndexArchiveError, importIndex } from "./storage/index-archive.js";
+import { SchemaMigration } from "./storage/schema-migrations.js";
+import { hasSearchScope } from "./storage/search-filters.js";
 import { getSQLiteManager, type SQLiteManager } from "./storage/sqlite-manager.js";
+import { withTests } from "./storage/tests-view.js";
 import { collectAgentMetrics } from "./tools/agent-metrics.js";
 import { analyzeCodeImpactTraversal } from "./tools/analyze-code-impact.js";
+import { MAX_BATCH_REQUESTS, runBatch } from "./tools/batch.js";
+import { browse } from "./tools/browse.js";
+import { type CallEdge, type CallGraphEntity, listCallees, listCallers } from "./tools/call-graph.js";
+import { findCallPaths } from "./tools/call-path.js";
+import { completeSymbol } from "./tools/complete-symbol.js";
+import { detectCycles } from "./tools/detect-cycles.js";
+import { type DiffRelationship, diffGraph } from "./tools/diff-graph.js";
+import { explainSymbol } from "./tools/explain-symbol.js";
+import { exportGraph } from "./tools/export-graph.js";
+import { fileSummary } from "./tools/file-summary.js";
+import { findByAuthor } from "./tools/find-by-author.js";
+import { findBySignature, parseSignaturePattern } from "./tools/find-by-signature.js";
+import { findDefinitionCandidates } from "./tools/find-definition.js";
+import { findOverrides, type OverrideEntity } from "./tools/find-overrides.js";
+import { findReferences } from "./tools/find-references.js";
+import { findTestedBy, findTestsFor, type TestEntity } from "./tools/find-tests.js";
+import { findUnusedImports } from "./tools/find-unused-imports.js";
+import { DEFAULT_UNUSED_ROOTS, findUnused } from "./tools/find-unused.js";
 import { getEntitySource } from "./tools/get-entity-source.js";
 // Import graph query functions
 import { getGraphStats, queryGraphEntities } from "./tools/graph-query.js";
+import { collectGraphStats } from "./tools/graph-stats.js";
+import {
+  GRAPH_QUERY_EDGE_TYPES,
+  GRAPH_QUERY_LIMITS,
+  type GraphQueryNode,
+  graphQueryProblem,
+  runGraphQuery,
+} from "./tools/graph-traversal.js";
 import { rerankSemanticHits } from "./tools/hybrid-ranking.js";
+import { analyzeImpact, type ImpactEntity } from "./tools/impact-analysis.js";
+import { indexStatus } from "./tools/index-status.js";
+import { type HierarchyEntity, type HierarchyNode, inheritanceHierarchy } from "./tools/inheritance-hierarchy.js";
 import { runJscpdCloneDetection } from "./tools/jscpd.js";
+import {
+  bestBelowThreshold,
+  dropDeletedEntityHits,
+  filterBySimilarity,
+  fuseSearchResults,
+  keywordResults,
+  keywordSearch,
+  type SearchMode,
+} from "./tools/keyword-search.js";
 import { ingestLernaGraph } from "./tools/lerna-graph-ingest.js";
 import { getLernaProjectGraph } from "./tools/lerna-project-graph.js";
 import { listEntityRelationshipsTraversal } from "./tools/list-entity-relationships.js";
+import { type EnvVarRead, listEnvVars } from "./tools/list-env-vars.js";
+import { listMembers } from "./tools/list-members.js";
+import { listRoutes, type RouteEndpoint } from "./tools/list-routes.js";
+import { listTables } from "./tools/list-tables.js";
+import { listTodos } from "./tools/list-todos.js";
+import type { LocatedEdge } from "./tools/located-edge.js";
+import { exportModuleGraph, moduleDependencies } from "./tools/module-dependencies.js";
+import { parseQueryIntent, runQueryIntent } from "./tools/nl-query.js";
+import { listPackageInit } from "./tools/package-init.js";
+import { listPublicApi } from "./tools/public-api.js";
+import { diffFileEntities, loadFileEntities } from "./tools/reindex-file.js";
 import { resolveEntityCandidates } from "./tools/resolve-entity.js";
+import { attachSearchHighlights } from "./tools/search-highlights.js";
+import { SEARCH_SORT_ORDERS, searchSortKeys } from "./tools/search-order.js";
+import { attachSearchSnippets } from "./tools/search-snippets.js";
+import { searchSnapshot, type SnapshotSearchHit, type SnapshotSearchResult } from "./tools/snapshot-search.js";
+import { techDebtReport } from "./tools/tech-debt-report.js";
+import { verifyIndex } from "./tools/verify-index.js";
 import type { AgentTask } from "./types/agent.js";
 import { AgentType } from "./types/agent.js";
-import { AgentBusyError } from "./types/errors.js";
-import type { CloneGroup } from "./types/semantic.js";
-import type { Entity, GraphQuery, Relationship } from "./types/storage.js";
+import {
+  AgentBusyError,
+  EmbeddingDimensionError,
+  type EmbeddingInitDetails,
+  SchemaVersionError,
+  TaskCancelledError,
+  ToolTimeoutError,
+} from "./types/errors.js";
+import { type FileParseErrors, SUPPORTED_LANGUAGES } from "./types/parser.js";
+import type { CloneGroup, SimilarityResult } from "./types/semantic.js";
+import type { Entity, GraphQuery, GraphSnapshot, IndexRecovery, Relationship, SearchScope } from "./types/storage.js";
 import { EntityType } from "./types/storage.js";
-import { decodeCursor, encodeCursor } from "./utils/cursor.js";
+import { linkedAbortController, throwIfCancelled } from "./utils/cancellation.js";
+import { decodeCursor, encodeCursor, pageByScore, type ScoreCursor, toScoreCursor } from "./utils/cursor.js";
+import {
+  checkoutRemote,
+  discardCheckout,
+  isGitUrl,
+  isValidGitRef,
+  redactGitUrl,
+  type RemoteCheckout,
+  RemoteCheckoutError,
+} from "./utils/git-clone.js";
+import { headCommit, originUrl } from "./utils/git-worktree.js";
 import { createRequestId, logger } from "./utils/logger.js";
+import { configureLogging, getLog } from "./utils/structured-log.js";
 import { appendGlobalTmpLog, getGlobalTmpLogDir, getGlobalTmpLogFile } from "./utils/tmp-log.js";
-import { toolFail, toolOk } from "./utils/tool-response.js";
+import { type ToolEnvelope, toolFail, toolOk } from "./utils/tool-response.js";
 
 // Parse command line arguments
 const args = process.argv.slice(2);
 let overrideConfigPath: string | undefined;
 let helpRequested = false;
 let versionRequested = false;
+let watchRequested = false;
 const positionalArgs: string[] = [];
 
 for (let i = 0; i < args.length; i++) {
@@ -104,6 +202,11 @@ for (let i = 0; i < args.length; i++) {
       process.exit(1);
     }
     overrideConfigPath = value;
+  } else if (arg === "--watch" || arg === "-w") {
+    watchRequested = true;
+  } else if (arg === "--low-memory") {
+    // Read by the config loader, which runs after argument parsing
+    process.env.INDEXER_LOW_MEMORY = "1";
   } else if (arg === "--help" || arg === "-h") {
     helpRequested = true;
   } else if (arg === "--version" || arg === "-v") {
@@ -126,12 +229,15 @@ Usage:
 
 Options:
   --config <path>   Use an alternate YAML configuration file
+  --watch, -w       Watch the directory and update the index as files change
+  --low-memory      Index with a bounded memory footprint (slower on large repositories)
   --help, -h        Show this help message and exit
   --version, -v     Print version information and exit
 
 Examples:
   code-graph-rag-mcp /path/to/project
   code-graph-rag-mcp --config config/production.yaml /repo
+  code-graph-rag-mcp --watch /path/to/project
   code-graph-rag-mcp               # use client roots (or cwd)
   code-graph-rag-mcp --version
 `);
@@ -346,6 +452,10 @@ let config: AppConfig | null = null;
 let globalSQLiteManager: SQLiteManager | null = null;
 let runtimeInitPromise: Promise<void> | null = null;
 let runtimeInitialized = false;
+// Tool calls and watcher updates, so SIGINT/SIGTERM can stop them before the database closes
+const lifecycle = new ShutdownCoordinator();
+// What startup repaired after an index that never finished; reported by get_graph_health
+let indexRecovery: IndexRecovery | null = null;
 
 function getConfigOrThrow(): AppConfig {
   if (!config) {
@@ -396,11 +506,15 @@ async function ensureRuntimeInitialized(): Promise<void> {
       if (overrideConfigPath) {
         ConfigLoader.setOverridePath(overrideConfigPath);
       }
+      ConfigLoader.setRepoRoot(directory);
 
       const cfg = initializeConfig();
 
       const validation = validateConfig(cfg);
-      if (!validation.valid) {
+      validation.errors.push(...ConfigLoader.getInstance().getRepoConfigErrors());
+      // A query with a syntax error would otherwise only show up as a log line on the first parse
+      if (validation.errors.length === 0) validation.errors.push(...validateCustomQueries(cfg.parser.customQueries));
+      if (validation.errors.length > 0) {
         console.error("[Config] Configuration validation failed:");
         for (const err of validation.errors) {
           console.error(`  - ${err}`);
@@ -409,14 +523,27 @@ async function ensureRuntimeInitialized(): Promise<void> {
       }
 
       config = cfg;
+      configureLogging(cfg.logging);
 
-      console.error("[Main] Initializing global SQLiteManager with config:", cfg.database.path);
+      const graphLog = getLog("graph");
+      graphLog.info("opening database", { path: cfg.database.path });
       const sqliteManager = getSQLiteManager(cfg.database);
       sqliteManager.initialize();
       globalSQLiteManager = sqliteManager;
-
-      console.error("[Main] Initializing global GraphStorage");
-      await initializeGraphStorage(sqliteManager);
+      serverActivity.setMaxQueued(ConfigLoader.getInstance().getWriteQueueLimit());
+
+      const storage = await initializeGraphStorage(sqliteManager);
+      graphLog.info("graph storage ready");
+
+      // A run killed mid-index leaves half-written files and imports no resolution pass has bound
+      indexRecovery = await storage.recoverInterruptedIndex();
+      if (indexRecovery.droppedFiles.length > 0 || indexRecovery.runs.length > 0) {
+        graphLog.warn("recovered from an interrupted index; run update_index to re-index stale files", {
+          runs: indexRecovery.runs.map((run) => run.id),
+          droppedFiles: indexRecovery.droppedFiles.length,
+          staleFiles: indexRecovery.staleFiles.length,
+        });
+      }
 
       logger.systemEvent("MCP Server Starting", {
         directory,
@@ -483,6 +610,35 @@ async function getSemanticAgent(): Promise<any> {
   return semanticAgentInitPromise;
 }
 
+let resultCache: ResultCache | null = null;
+
+/** semantic_search and query results; any write to the graph database or the vector store drops them */
+function getResultCache(): ResultCache {
+  if (!resultCache) {
+    const { maxEntries = 500, maxBytes = 32 * 1024 * 1024 } = getConfigOrThrow().mcp.resultCache ?? {};
+    resultCache = new ResultCache({ maxEntries, maxBytes }, () => {
+      const vectors = semanticAgentInstance?.getVectorWriteSignature?.() ?? "none";
+      return `${getSQLiteManagerOrThrow().getWriteSignature()}|${vectors}`;
+    });
+  }
+  return resultCache;
+}
+
+/** A cached tool result, with the warnings it was returned with */
+type CachedResult = { data: unknown; warnings?: string[] };
+
+/** Warnings of a search that ran without its semantic side; such results are not cached */
+/** Best matches semantic_search reorders when sorting by something other than score */
+const SORTED_SEARCH_WINDOW = 200;
+
+const DEGRADED_SEARCH = new Set(["embedding_fallback", "semantic_unavailable", "reindex_required"]);
+
+/** Embedding provider and model answering semantic queries, part of every result cache key */
+function embeddingSourceKey(): string {
+  const embedding = getConfigOrThrow().mcp.embedding;
+  return `${embedding?.provider ?? ""}/${embedding?.model ?? ""}`;
+}
+
 let devAgentInstance: any | null = null;
 let devAgentInitPromise: Promise<any> | null = null;
 
@@ -553,6 +709,46 @@ async function ensureSemanticsReady(minVectors = 1, timeoutMs = 15000): Promise<
   return false;
 }
 
+type WarmupReport = {
+  database: { path: string; entities: number; relationships: number; files: number; ms: number };
+  /** Null while semantics are disabled (MCP_DEBUG_DISABLE_SEMANTIC) */
+  semantic: (SemanticWarmup & { agentMs: number }) | null;
+  totalMs: number;
+};
+
+let warmupInFlight: Promise<WarmupReport> | null = null;
+
+/**
+ * Open the graph database and bring up the semantic agent, its embedding model and ANN index, so
+ * the first search pays none of it. Concurrent calls share one run; the agent keeps what it warmed.
+ */
+async function warmupServer(): Promise<WarmupReport> {
+  warmupInFlight ??= (async () => {
+    const started = Date.now();
+    await ensureRuntimeInitialized();
+    const metrics = await (await getGraphStorage(getSQLiteManagerOrThrow())).getMetrics();
+    const database = {
+      path: getSQLiteManagerOrThrow().getPath(),
+      entities: metrics.totalEntities,
+      relationships: metrics.totalRelationships,
+      files: metrics.totalFiles,
+      ms: Date.now() - started,
+    };
+
+    let semantic: WarmupReport["semantic"] = null;
+    if (process.env.MCP_DEBUG_DISABLE_SEMANTIC !== "1") {
+      const agentStarted = Date.now();
+      const agent = await getSemanticAgent();
+      const agentMs = Date.now() - agentStarted;
+      semantic = { ...((await agent.warmup()) as SemanticWarmup), agentMs };
+    }
+    return { database, semantic, totalMs: Date.now() - started };
+  })().finally(() => {
+    warmupInFlight = null;
+  });
+  return warmupInFlight;
+}
+
 function toPosixPath(p?: string): string {
   return (p || "").replace(/\\/g, "/");
 }
@@ -708,11 +904,144 @@ function mapEntitySummary(entity: Entity) {
     name: entity.name,
     type: entity.type,
     filePath: normalizedPath,
+    fqn: typeof entity.metadata?.fqn === "string" ? entity.metadata.fqn : null,
     location: entity.location,
     metadata: entity.metadata,
   };
 }
 
+/** Search scope from tool arguments, with paths resolved the way stored file paths are */
+function toSearchScope(args: {
+  kinds?: string[];
+  languages?: string[];
+  pathPrefix?: string;
+  pathGlob?: string;
+  directory?: string;
+  root?: string;
+  content?: "docs" | "code";
+  /** Only tools that take the flag leave anonymous entities out, and only when it is false */
+  includeAnonymous?: boolean;
+  tests?: TestsOption;
+  visibility?: string[];
+}): SearchScope | undefined {
+  const glob = args.pathGlob?.trim();
+  const prefix = args.pathPrefix?.trim();
+  const subtree = args.directory?.trim();
+  const scope: SearchScope = {
+    kinds: args.kinds?.length ? args.kinds : undefined,
+    languages: args.languages?.length ? args.languages : undefined,
+    pathPrefix: prefix ? normalizeInputPath(prefix) : undefined,
+    // Applied alongside pathPrefix: a hit must lie inside both
+    directory: subtree ? normalizeInputPath(subtree) : undefined,
+    // A relative glob is anchored at the indexed directory, like every other relative path
+    pathGlob:
+      glob && !isAbsolute(glob) && !glob.startsWith("*") ? `${normalize(directory)}/${glob}` : glob || undefined,
+    root: args.root?.trim() ? normalizeInputPath(args.root.trim()) : undefined,
+    content: args.content,
+    excludeAnonymous: args.includeAnonymous === false ? true : undefined,
+    tests: testsScopeOf(args.tests),
+    visibility: args.visibility?.length ? args.visibility : undefined,
+  };
+  return hasSearchScope(scope) ? scope : undefined;
+}
+
+type TestsOption = "include" | "exclude" | "only";
+
+/** A call's `tests` choice, falling back to indexer.includeTests; "include" filters nothing */
+function testsScopeOf(tests: TestsOption | undefined): SearchScope["tests"] {
+  const wanted = tests ?? (ConfigLoader.getInstance().areTestsIncludedByDefault() ? "include" : "exclude");
+  return wanted === "include" ? undefined : wanted;
+}
+
+/** Cursor key of a search hit: its score, tie-broken by the graph entity id when known */
+function searchHitKey(hit: any): ScoreCursor {
+  const entityId = hit?.entityId ?? hit?.metadata?.entityId;
+  return { s: Number(hit?.score ?? hit?.similarity ?? 0), id: String(entityId ?? hit?.id ?? "") };
+}
+
+/** Source of an entity for inline query results; null when the file can no longer be read */
+async function entitySnippet(
+  storage: Awaited<ReturnType<typeof getGraphStorage>>,
+  entity: Entity,
+  maxBytes: number,
+): Promise<{ snippet: string | null; truncated: boolean }> {
+  try {
+    const filePath = normalizeInputPath(entity.filePath) ?? entity.filePath;
+    const extracted = await getEntitySource({ storage, entity, filePath, contextLines: 0, maxBytes });
+    return { snippet: extracted.snippet, truncated: extracted.truncated };
+  } catch {
+    return { snippet: null, truncated: false };
+  }
+}
+
+type SnapshotVectorProblem = "snapshot_without_vectors" | "snapshot_vectors_incompatible" | "semantic_unavailable";
+
+const SNAPSHOT_VECTOR_PROBLEMS: Record<SnapshotVectorProblem, string> = {
+  snapshot_without_vectors: "was taken without search vectors",
+  snapshot_vectors_incompatible: "holds vectors of another embedding model than the active one",
+  semantic_unavailable: "cannot be searched by meaning while the semantic agent is unavailable",
+};
+
+/**
+ * Search a stored snapshot for semantic_search and query with `snapshot`. Its vectors are only
+ * compared with a query embedded by the model that made them; when that cannot be done, semantic
+ * mode fails and hybrid answers from name and signature matches with the reason as a warning.
+ */
+async function searchStoredSnapshot(
+  storage: Awaited<ReturnType<typeof getGraphStorage>>,
+  snapshot: GraphSnapshot,
+  options: { query: string; mode: SearchMode; scope?: SearchScope; minScore?: number; limit: number },
+): Promise<
+  | ({ ok: true; warnings: string[] } & SnapshotSearchResult)
+  | { ok: false; errorType: SnapshotVectorProblem; error: string; details: Record<string, unknown> }
+> {
+  const warnings: string[] = [];
+  let queryVector: Float32Array | null = null;
+  if (options.mode !== "keyword") {
+    let problem: SnapshotVectorProblem | null = null;
+    let activeSource: string | null = null;
+    if (snapshot.vectorCount === 0) {
+      problem = "snapshot_without_vectors";
+    } else if (process.env.MCP_DEBUG_DISABLE_SEMANTIC === "1") {
+      problem = "semantic_unavailable";
+    } else {
+      try {
+        const agent = await getSemanticAgent();
+        activeSource = agent.getEmbeddingKey();
+        if (activeSource === snapshot.vectorSource) queryVector = await agent.embedQuery(options.query);
+        else problem = "snapshot_vectors_incompatible";
+      } catch (error) {
+        problem = error instanceof EmbeddingDimensionError ? "snapshot_vectors_incompatible" : "semantic_unavailable";
+      }
+    }
+    if (problem) {
+      if (options.mode === "semantic") {
+        return {
+          ok: false,
+          errorType: problem,
+          error: `Snapshot ${snapshot.label} ${SNAPSHOT_VECTOR_PROBLEMS[problem]}; use mode keyword or hybrid`,
+          details: { snapshot: snapshot.label, vectorSource: snapshot.vectorSource, activeSource },
+        };
+      }
+      warnings.push(problem);
+    }
+  }
+
+  // A snapshot keeps no index roots; a root filter would reject every entity
+  let scope = options.scope;
+  if (scope?.root) {
+    warnings.push("root_ignored");
+    scope = { ...scope, root: undefined };
+  }
+  const result = await searchSnapshot(storage, snapshot.label, { ...options, scope, queryVector });
+  return { ok: true, warnings, ...result };
+}
+
+/** A snapshot hit in the shape of a live search item; the source is not kept, so there is no snippet */
+function snapshotSearchItem(hit: SnapshotSearchHit) {
+  return { ...hit, filePath: normalizeInputPath(hit.filePath) ?? hit.filePath };
+}
+
 function summarizeRelationships(relationships: Relationship[], neighbors: Map<string, Entity>) {
   return relationships.map((rel) => ({
     id: rel.id,
@@ -748,9 +1077,11 @@ function normalizeEntityTypes(types?: string[]): EntityType[] | undefined {
 // GraphStorage singleton is now managed by graph-storage-factory.ts
 
 import {
+  checkIndexableContent,
   collectIndexableFiles,
   DEFAULT_INDEX_EXCLUDE_PATTERNS,
   DEFAULT_INDEX_PRUNE_DIR_NAMES,
+  type SkippedIndexFile,
 } from "./utils/index-file-collection.js";
 
 function mergeUniqueStrings(a: readonly string[], b: readonly string[]): string[] {
@@ -890,16 +1221,62 @@ function deleteBatchIndexSession(sessionId: string): void {
 }
 
 // Tool schemas
+const IndexLanguagesSchema = z
+  .array(z.enum(SUPPORTED_LANGUAGES))
+  .optional()
+  .describe(
+    "Only index files of these languages (typescript and javascript include their JSX flavours); other files are skipped, not parsed or embedded, and keep whatever an earlier index stored for them",
+  );
+
 const IndexToolSchema = z.object({
-  directory: z.string().describe("Directory to index").optional(),
+  directory: z
+    .string()
+    .describe(
+      "Directory to index, or a git URL (same as repository). A .code-graph-rag.json or .code-graph-rag.yaml there supplies defaults for excludePatterns, languages, respectGitignore and gitBlame (arguments win; result repoConfig names the file)",
+    )
+    .optional(),
+  repository: z
+    .string()
+    .optional()
+    .describe(
+      "Git URL (https, ssh, git@host:owner/repo) to shallow-clone into indexer.repoCacheDir and index; later calls fetch into the cached clone, and the run records URL, ref and commit (get_graph_health lastIndexRun)",
+    ),
+  ref: z.string().optional().describe("Branch, tag or full commit hash of repository (default: its default branch)"),
+  roots: z
+    .array(z.string().min(1))
+    .optional()
+    .describe(
+      "Several root directories to index into one graph (overrides directory); each entity records its root in metadata.root, imports of another root by its package name resolve to that root's sources, and semantic_search takes root to stay inside one",
+    ),
   incremental: z.boolean().describe("Perform incremental indexing").optional().default(false),
   reset: z.boolean().describe("Clear existing graph before indexing").optional().default(false),
   excludePatterns: z
     .array(z.string())
-    .describe("Patterns to exclude (merged with built-in defaults; tmp/ is always excluded)")
+    .describe(
+      "Patterns to exclude (merged with built-in defaults; tmp/ is always excluded; defaults to index.excludePatterns of the repository file)",
+    )
+    .optional(),
+  respectGitignore: z
+    .boolean()
     .optional()
-    .default([...DEFAULT_INDEX_EXCLUDE_PATTERNS]),
+    .describe(
+      "Also exclude paths ignored by .gitignore files (including nested ones and .git/info/exclude); default true",
+    ),
   fullScan: z.boolean().optional().default(false),
+  gitBlame: z
+    .boolean()
+    .optional()
+    .describe(
+      "Record each entity's last-modifying commit, author and date from git blame (slow; defaults to indexer.gitBlame)",
+    ),
+  languages: IndexLanguagesSchema,
+  embed: z
+    .boolean()
+    .optional()
+    .default(true)
+    .describe(
+      "Embed the indexed entities; false only re-parses into the graph and keeps the stored vectors (reembed later)",
+    ),
 });
 
 const BatchIndexSchema = z.object({
@@ -910,6 +1287,11 @@ const BatchIndexSchema = z.object({
     .optional()
     .default([...DEFAULT_INDEX_EXCLUDE_PATTERNS])
     .describe("Patterns to exclude (merged with built-in defaults; tmp/ is always excluded)"),
+  respectGitignore: z
+    .boolean()
+    .optional()
+    .default(true)
+    .describe("Also exclude paths ignored by .gitignore files (including nested ones and .git/info/exclude)"),
   incremental: z.boolean().optional().default(true).describe("Reindex only changed files (mtime-based)"),
   fullScan: z.boolean().optional().default(false).describe("Disable incremental filtering and index everything"),
   reset: z.boolean().optional().default(false).describe("Clear the graph before starting a new session"),
@@ -923,6 +1305,10 @@ const ListEntitiesToolSchema = z.object({
   entityTypes: z.array(z.string()).describe("Types of entities to list").optional(),
 });
 
+const FileSummarySchema = z.object({
+  filePath: z.string().min(1).describe("File to summarize"),
+});
+
 const ListRelationshipsToolSchema = z
   .object({
     entityId: z.string().optional().describe("Exact entity ID to find relationships for"),
@@ -943,6 +1329,16 @@ const ResolveEntitySchema = z.object({
   limit: z.number().int().positive().max(50).optional().default(10).describe("Maximum candidates to return"),
 });
 
+const CompleteSymbolSchema = z.object({
+  prefix: z
+    .string()
+    .min(1)
+    .describe("Start of a name as typed (`getUs`), or the initials of its camel-case words (`GU` for GetUser)"),
+  kinds: z.array(z.string()).optional().describe("Only complete to these entity types (e.g. ['class', 'function'])"),
+  languages: z.array(z.string()).optional().describe("Only complete to entities of these languages"),
+  limit: z.number().int().positive().max(200).optional().default(20).describe("Maximum names to return"),
+});
+
 const GetEntitySourceSchema = z
   .object({
     entityId: z.string().optional().describe("Exact entity ID (preferred)"),
@@ -963,19 +1359,313 @@ const GetEntitySourceSchema = z
     path: ["entityId"],
   });
 
+const ExplainSymbolSchema = z
+  .object({
+    symbol: z
+      .string()
+      .optional()
+      .describe("Symbol to explain; may be qualified as pkg.Name, Type.method, mod::Name or fully (src/users#GetUser)"),
+    entityId: z.string().optional().describe("Exact entity ID (preferred over symbol)"),
+    filePath: z.string().optional().describe("File declaring the symbol or where it was seen; closer declarations win"),
+    package: z.string().optional().describe("Optional package/module/receiver qualifier"),
+    entityType: z.string().optional().describe("Optional kind of the declaration (function, method, class, ...)"),
+    neighbors: z
+      .number()
+      .int()
+      .positive()
+      .max(100)
+      .optional()
+      .default(10)
+      .describe("Callers, callees and similar entities to include, each"),
+    maxBytes: z
+      .number()
+      .int()
+      .min(1024)
+      .max(512000)
+      .optional()
+      .default(16000)
+      .describe("Max bytes of source returned"),
+  })
+  .refine((value) => Boolean(value.entityId || value.symbol), {
+    message: "Provide either entityId or symbol",
+    path: ["symbol"],
+  });
+
+const FindDefinitionSchema = z.object({
+  symbol: z
+    .string()
+    .min(1)
+    .describe(
+      "Symbol name to jump to; may be qualified as pkg.Name, Type.method, mod::Name or fully (src/users#GetUser)",
+    ),
+  filePath: z.string().optional().describe("File the symbol was seen in; closer declarations rank first"),
+  package: z.string().optional().describe("Optional package/module/receiver qualifier"),
+  entityType: z.string().optional().describe("Optional kind of the declaration (function, class, struct, ...)"),
+  contextLines: z.number().int().min(0).max(50).optional().default(0).describe("Extra lines around each snippet"),
+  maxBytes: z
+    .number()
+    .int()
+    .min(1024)
+    .max(512000)
+    .optional()
+    .default(16000)
+    .describe("Max bytes per snippet"),
+  limit: z.number().int().positive().max(50).optional().default(5).describe("Maximum candidates to return"),
+});
+
+const FindReferencesSchema = z.object({
+  symbol: z
+    .string()
+    .min(1)
+    .describe(
+      "Symbol name to look up; may be qualified as pkg.Name, Type.method, mod::Name or fully (src/users#GetUser)",
+    ),
+  filePath: z.string().optional().describe("Optional file declaring the symbol (narrows the definitions)"),
+  package: z.string().optional().describe("Optional package/module/receiver qualifier"),
+  entityType: z
+    .string()
+    .optional()
+    .describe("Optional kind of the declaration (function, method, class, interface, struct, ...)"),
+  referenceKinds: z
+    .array(z.enum(["call", "type_reference", "import", "reference", "access"]))
+    .optional()
+    .describe("Reference kinds to include (default: all)"),
+  access: z
+    .enum(["read", "write"])
+    .optional()
+    .describe("Only accesses reading (or writing) a field or variable; compound assignments count as both"),
+  includeUnresolved: z
+    .boolean()
+    .optional()
+    .default(true)
+    .describe("Include cross-file references that only matched the symbol by name"),
+  limit: z.number().int().positive().max(1000).optional().default(200).describe("Maximum references to return"),
+});
+
+const CallGraphSchema = z.object({
+  symbol: z
+    .string()
+    .min(1)
+    .describe("Function or method name; may be qualified as pkg.Name or Type.method"),
+  filePath: z.string().optional().describe("Optional file declaring the symbol (narrows the definitions)"),
+  package: z.string().optional().describe("Optional package/module/receiver qualifier"),
+  entityType: z.string().optional().describe("Optional kind of the declaration (function, method, ...)"),
+  minConfidence: z
+    .number()
+    .min(0)
+    .max(1)
+    .optional()
+    .describe("Drop calls bound with less confidence (1 resolved in scope, 0.7 name match, 0.4 heuristic)"),
+  argument: z
+    .object({
+      position: z.number().int().positive().describe("1-based argument position"),
+      value: z.string().min(1).describe("Literal as written in the source: nil, null, 0, true, \"json\""),
+    })
+    .optional()
+    .describe("list_callers only: keep call sites passing this literal at this position"),
+  limit: z.number().int().positive().max(1000).optional().default(200).describe("Maximum call edges to return"),
+});
+
+const CallPathSchema = z.object({
+  from: z.string().min(1).describe("Calling function or method: name (may be qualified), fqn or entity ID"),
+  to: z.string().min(1).describe("Function or method to reach, in the same forms"),
+  fromFilePath: z.string().optional().describe("Optional file declaring `from` (narrows its definitions)"),
+  toFilePath: z.string().optional().describe("Optional file declaring `to`"),
+  maxDepth: z.number().int().positive().max(20).optional().default(6).describe("Maximum calls on a path"),
+  minConfidence: z
+    .number()
+    .min(0)
+    .max(1)
+    .optional()
+    .describe("Only follow calls bound with at least this confidence (1 resolved in scope, 0.7 name match)"),
+  limit: z.number().int().positive().max(100).optional().default(10).describe("Maximum shortest paths to return"),
+});
+
+// Shared by the search tools; compiled into SQL predicates so they narrow before ranking and limits
+const SearchScopeFields = {
+  kinds: z.array(z.string()).optional().describe("Only these entity kinds (function, class, interface, struct, ...)"),
+  languages: z.array(z.string()).optional().describe("Only files of these languages (python, typescript, go, ...)"),
+  pathPrefix: z.string().optional().describe("Only entities in this directory or file"),
+  pathGlob: z.string().optional().describe("Only paths matching this glob, e.g. services/**/*.py"),
+  content: z
+    .enum(["docs", "code"])
+    .optional()
+    .describe(
+      "Only documentation sections (one heading entity per Markdown section, with metadata.headingPath) or only code; both by default",
+    ),
+  tests: z
+    .enum(["include", "exclude", "only"])
+    .optional()
+    .describe(
+      "Entities of test files (_test.go, *.test.ts, __tests__/, test_*.py, *Test.java, src/test/): include, exclude or only them (default: indexer.includeTests)",
+    ),
+  visibility: z
+    .array(z.enum(VISIBILITIES as [Visibility, ...Visibility[]]))
+    .optional()
+    .describe(
+      "Only declarations of these visibilities, e.g. [\"public\"] for the exported API; each language's access rules (Go capitalization, export, access keywords) map onto this vocabulary, stored as metadata.visibility",
+    ),
+};
+
 const QueryToolSchema = z.object({
-  query: z.string().describe("Natural language or structured query"),
+  query: z
+    .string()
+    .describe(
+      "Natural language or structured query; a recognized question (\"functions that call parseConfig\", \"types implementing Storage\", \"what's in src/app.ts\", \"where is X defined/used\") runs as a graph traversal, with its matches' source",
+    ),
   limit: z.number().describe("Maximum number of results (page size when cursor is used)").optional().default(10),
-  cursor: z.string().optional().describe("Opaque cursor for pagination"),
+  cursor: z
+    .string()
+    .optional()
+    .describe(
+      "Opaque cursor for pagination (paging.nextCursor); semantic hits page by score then entity id, structural ones by entity id",
+    ),
   pageSize: z.number().int().positive().max(200).optional().describe("Page size (overrides limit)"),
+  includeSnippets: z.boolean().optional().default(true).describe("Attach the source of each structural match"),
+  snippetMaxBytes: z.number().int().positive().max(20000).optional().default(1500).describe("Cap per snippet"),
+  snapshot: z
+    .string()
+    .min(1)
+    .optional()
+    .describe(
+      "Search this snapshot_graph label instead of the current index (mode \"snapshot\"): one fused list of name, signature and vector matches, without graph traversals (warning structured_intent_ignored on a recognized question) or snippets",
+    ),
+  ...SearchScopeFields,
+});
+
+const BatchSchema = z.object({
+  requests: z
+    .array(
+      z.object({
+        tool: z.string().min(1).describe("Tool name, e.g. find_definition"),
+        args: z.record(z.any()).optional().describe("Arguments of that tool"),
+        id: z.string().optional().describe("Label echoed in the matching result"),
+      }),
+    )
+    .min(1)
+    .max(MAX_BATCH_REQUESTS)
+    .describe("Sub-requests; results come back in the same order"),
+  concurrency: z
+    .number()
+    .int()
+    .min(1)
+    .max(16)
+    .optional()
+    .default(4)
+    .describe("Read-only requests running at the same time"),
+});
+
+const GetTaskResultSchema = z.object({
+  taskId: z.string().min(1).describe("taskId of a queued response"),
+});
+
+const GetIndexProgressSchema = z.object({
+  taskId: z.string().min(1).optional().describe("Task id of the indexing run; omit for the most recent run"),
+});
+
+const CancelTaskSchema = z.object({
+  taskId: z.string().min(1).describe("Id of a running task (get_agent_metrics currentTaskId) or of a queued subtask"),
+});
+
+const CompactIndexSchema = z.object({
+  vacuum: z
+    .boolean()
+    .optional()
+    .default(true)
+    .describe("Rebuild the database file to return freed pages; false only checkpoints the write-ahead log"),
+});
+
+const ReembedSchema = z.object({});
+
+const VerifyIndexSchema = z.object({
+  repair: z
+    .boolean()
+    .optional()
+    .default(false)
+    .describe("Delete dangling relationships and orphaned vectors; without it problems are only reported"),
+  sampleLimit: z
+    .number()
+    .int()
+    .positive()
+    .max(1000)
+    .optional()
+    .default(20)
+    .describe("Problem rows listed per check"),
+});
+
+const ExportIndexSchema = z.object({
+  path: z.string().min(1).describe("Archive file to write, e.g. index.cgrag (relative to the indexed directory)"),
+});
+
+const ImportIndexSchema = z.object({
+  path: z.string().min(1).describe("Archive written by export_index"),
+  directory: z
+    .string()
+    .optional()
+    .describe("Local checkout the archived paths are moved to (defaults to the indexed directory)"),
 });
 
 // New semantic tool schemas - TASK-002
 const SemanticSearchSchema = z.object({
   query: z.string().describe("Natural language search query"),
   limit: z.number().optional().default(10).describe("Maximum results to return (page size when cursor is used)"),
-  cursor: z.string().optional().describe("Opaque cursor for pagination"),
+  cursor: z
+    .string()
+    .optional()
+    .describe(
+      "Opaque cursor for pagination (page.nextCursor); results go by score, then entity id, so pages never overlap",
+    ),
   pageSize: z.number().int().positive().max(200).optional().describe("Page size (overrides limit)"),
+  mode: z
+    .enum(["semantic", "keyword", "hybrid"])
+    .optional()
+    .default("hybrid")
+    .describe(
+      "Retrieval mode: 'semantic' (embeddings only), 'keyword' (BM25 over names/qualified names/signatures/docstrings; works without embeddings), or 'hybrid' (both, merged by reciprocal rank fusion, so exact identifiers rank first)",
+    ),
+  snippet: z
+    .enum(["full", "signature", "none"])
+    .optional()
+    .default("full")
+    .describe("Source attached to each hit: declaration and body, declaration only, or none"),
+  maxLines: z.number().int().positive().max(1000).optional().default(60).describe("Maximum lines per snippet"),
+  minScore: z
+    .number()
+    .min(-1)
+    .max(1)
+    .optional()
+    .describe(
+      "Minimum cosine similarity for embedding matches (default mcp.semantic.minScore or search.minScore of the repository's .code-graph-rag file, 0.2); weaker ones are dropped, so an empty list means no good match, with belowThreshold counting the dropped ones and bestBelowThreshold their best cosine. Keyword matches are not affected",
+    ),
+  directory: z
+    .string()
+    .optional()
+    .describe(
+      "Only results inside this subtree, e.g. packages/web/src/ui/ (relative to the indexed directory); with pathPrefix, results must lie inside both. Like every scope filter it applies before ranking, so limit counts matches inside the scope",
+    ),
+  root: z.string().optional().describe("Only entities indexed from this root of a multi-root index (its directory)"),
+  sortBy: z
+    .enum(SEARCH_SORT_ORDERS)
+    .optional()
+    .default("score")
+    .describe(
+      "Order of the matches: 'score' (most relevant first), 'recency' (most recently changed first; needs an index built with gitBlame, else warning no_blame_metadata), 'complexity' (highest cyclomatic complexity first) or 'path' (file path ascending). Other than score it reorders the 200 best matches; ties fall back to entity id and entities lacking the value come last",
+    ),
+  includeAnonymous: z
+    .boolean()
+    .optional()
+    .default(false)
+    .describe(
+      "Also return anonymous functions, closures and other unnamed declarations (left out by default); identical copies within a file are indexed once, with metadata.duplicates",
+    ),
+  snapshot: z
+    .string()
+    .min(1)
+    .optional()
+    .describe(
+      "Search this snapshot_graph label instead of the current index, e.g. the revision of a bug report, with the vectors kept with it. Hits carry a signature but no snippet, sortBy and root are ignored (warnings sort_ignored, root_ignored); a snapshot without vectors from the active model fails in mode semantic and answers from keywords in hybrid",
+    ),
+  ...SearchScopeFields,
 });
 
 const FindSimilarCodeSchema = z.object({
@@ -984,87 +1674,468 @@ const FindSimilarCodeSchema = z.object({
   limit: z.number().optional().default(10).describe("Maximum results to return"),
 });
 
+const FindSimilarSchema = z
+  .object({
+    entityId: z.string().optional().describe("Exact ID of the function or method to compare against"),
+    symbol: z.string().optional().describe("Function or method name to resolve if entityId is not provided"),
+    filePath: z.string().optional().describe("Optional file declaring the symbol (narrows the lookup)"),
+    minScore: z
+      .number()
+      .min(-1)
+      .max(1)
+      .optional()
+      .default(0.85)
+      .describe("Minimum cosine similarity between bodies; near-duplicates usually score above 0.9"),
+    limit: z.number().int().positive().max(200).optional().default(20).describe("Maximum matches to return"),
+    ...SearchScopeFields,
+  })
+  .refine((value) => Boolean(value.entityId || value.symbol), {
+    message: "Provide either entityId or symbol",
+    path: ["entityId"],
+  });
+
 const AnalyzeCodeImpactSchema = z.object({
   entityId: z.string().describe("Entity ID or name to analyze impact for"),
   filePath: z.string().optional().describe("Optional file path hint to disambiguate entity"),
   depth: z.number().optional().default(2).describe("Depth of impact analysis"),
 });
 
-const AnalyzeModuleDependentsSchema = z.object({
-  moduleSource: z.string().describe("Module import source (e.g. ./editorWebWorker.js)"),
-  limit: z.number().optional().default(100).describe("Maximum number of importers to return"),
+const ImpactAnalysisSchema = z.object({
+  symbol: z.string().min(1).describe("Symbol name (may be qualified as pkg.Name or Type.method) or an entity ID"),
+  filePath: z.string().optional().describe("Optional file declaring the symbol (narrows the targets)"),
+  package: z.string().optional().describe("Optional package/module/receiver qualifier"),
+  entityType: z.string().optional().describe("Optional kind of the declaration (function, method, class, ...)"),
+  maxDepth: z.number().int().positive().max(20).optional().default(5).describe("Maximum reverse-edge hops to follow"),
+  relationshipTypes: z
+    .array(z.string())
+    .optional()
+    .describe("Relationship types to walk in reverse (default: calls, references, imports, extends, implements, embeds)"),
+  minConfidence: z
+    .number()
+    .min(0)
+    .max(1)
+    .optional()
+    .describe("Only follow edges at least this certain, e.g. 0.7 to skip heuristic ones (default: follow all)"),
+  tests: SearchScopeFields.tests.describe(
+    "Dependents in test files: include, exclude (not followed either) or only them, e.g. the tests a change breaks (default: indexer.includeTests)",
+  ),
+  limit: z.number().int().positive().max(5000).optional().default(500).describe("Maximum affected entities to return"),
 });
 
-const DetectCodeClonesSchema = z.object({
-  minSimilarity: z.number().optional().default(0.8).describe("Minimum similarity for clones"),
-  scope: z.string().optional().default("all").describe("Scope: all, file, or module"),
-});
+const GraphQueryNodeFields = {
+  name: z.string().optional().describe("Exact entity name; `*` matches any run of characters, e.g. Delete*"),
+  kinds: SearchScopeFields.kinds,
+  languages: SearchScopeFields.languages,
+  pathPrefix: SearchScopeFields.pathPrefix,
+  pathGlob: SearchScopeFields.pathGlob,
+  tests: SearchScopeFields.tests,
+};
 
-const JscpdCloneDetectionSchema = z.object({
-  paths: z
-    .array(z.string())
-    .nonempty()
+const GraphQuerySchema = z.object({
+  start: z
+    .object({ id: z.string().optional().describe("Exact entity ID"), ...GraphQueryNodeFields })
+    .describe("Nodes the walk starts from: an id, or a name and/or path filter (kinds and languages narrow them)"),
+  edges: z
+    .array(z.enum(GRAPH_QUERY_EDGE_TYPES as [string, ...string[]]))
     .optional()
-    .describe("Directories or files to scan. Relative paths resolve against the server root."),
-  pattern: z.string().optional().describe("Glob pattern to apply within each path (default **/*)."),
-  ignore: z.array(z.string()).optional().describe("Glob patterns to exclude from scanning."),
-  formats: z
-    .array(z.string().regex(/^[^.]+$/))
+    .describe("Relationship types to follow (default: all), e.g. calls, references, imports, extends, implements"),
+  direction: z
+    .enum(["out", "in", "both"])
     .optional()
-    .describe("File extensions to include without dots (e.g. ['ts','js'])."),
-  minLines: z.number().int().min(1).optional().describe("Minimum lines per clone block."),
-  maxLines: z.number().int().min(1).optional().describe("Maximum lines per clone block."),
-  minTokens: z.number().int().min(1).optional().describe("Minimum tokens per clone (interpreted as lines)."),
-  ignoreCase: z.boolean().optional().describe("Lowercase tokens before comparison."),
+    .default("out")
+    .describe("out: from a node to what it calls/references; in: to what calls/references it; both: either way"),
+  minDepth: z
+    .number()
+    .int()
+    .min(0)
+    .max(GRAPH_QUERY_LIMITS.maxDepth)
+    .optional()
+    .default(1)
+    .describe("Closest hop to return; 0 includes the start nodes"),
+  maxDepth: z
+    .number()
+    .int()
+    .positive()
+    .max(GRAPH_QUERY_LIMITS.maxDepth)
+    .optional()
+    .default(2)
+    .describe("Hops to follow from the start nodes"),
+  where: z
+    .object(GraphQueryNodeFields)
+    .optional()
+    .describe("Only return reached nodes matching this; the walk still passes through the others"),
+  minConfidence: z
+    .number()
+    .min(0)
+    .max(1)
+    .optional()
+    .describe("Only follow edges at least this certain, e.g. 0.7 to skip heuristic ones (default: follow all)"),
+  returnPaths: z.boolean().optional().default(false).describe("Include each match's full path from its start node"),
+  limit: z
+    .number()
+    .int()
+    .positive()
+    .max(GRAPH_QUERY_LIMITS.maxResults)
+    .optional()
+    .default(100)
+    .describe("Maximum nodes to return, nearest first"),
 });
 
-const SuggestRefactoringSchema = z
-  .object({
-    filePath: z.string().describe("File to analyze for refactoring"),
-    focusArea: z.string().optional().describe("Specific entity name to focus on"),
-    entityId: z.string().optional().describe("Exact entity ID to analyze"),
-    startLine: z.number().int().min(1).optional().describe("1-based start line for manual selection"),
-    endLine: z.number().int().min(1).optional().describe("1-based end line (exclusive)"),
-  })
-  .refine(
-    (v) =>
-      (v.startLine == null && v.endLine == null) ||
-      (v.startLine != null && v.endLine != null && v.startLine < v.endLine),
-    {
-      message: "startLine and endLine must both be provided and startLine < endLine",
-      path: ["startLine"],
-    },
-  );
-
-const CrossLanguageSearchSchema = z.object({
-  query: z.string().describe("Search query"),
-  languages: z.array(z.string()).optional().describe("Languages to search in"),
+const DetectCyclesSchema = z.object({
+  directory: z.string().optional().describe("Only consider files under this directory (default: whole index)"),
+  granularity: z
+    .enum(["file", "directory"])
+    .optional()
+    .default("file")
+    .describe("Group TS/JS imports per file or collapse them to directories; Go always uses packages"),
 });
 
-const AnalyzeHotspotsSchema = z.object({
-  metric: z.string().optional().default("complexity").describe("Metric: complexity, changes, or coupling"),
-  limit: z.number().optional().default(10).describe("Maximum hotspots to return"),
+const ModuleDependenciesSchema = z.object({
+  directory: z.string().optional().describe("Only consider files under this directory (default: whole index)"),
+  granularity: z
+    .enum(["file", "directory"])
+    .optional()
+    .default("file")
+    .describe("Modules are files or directories; Go always uses packages"),
+  minWeight: z.number().int().positive().optional().default(1).describe("Leave out dependencies lighter than this"),
+  format: z
+    .enum(["json", "dot", "graphml"])
+    .optional()
+    .default("json")
+    .describe("json returns the graph inline; dot (Graphviz) and graphml (Gephi/yEd) write it to outputPath"),
+  outputPath: z
+    .string()
+    .optional()
+    .describe("File for dot/graphml, relative to the server root (default .code-graph-rag/modules.<format>)"),
+  limit: z.number().int().positive().max(20000).optional().default(2000).describe("Maximum edges returned inline"),
 });
 
-const FindRelatedConceptsSchema = z.object({
-  entityId: z.string().describe("Entity to find related concepts for"),
-  limit: z.number().optional().default(10).describe("Maximum results to return"),
+const FindUnusedSchema = z.object({
+  directory: z.string().optional().describe("Only consider files under this directory (default: whole index)"),
+  entityTypes: z
+    .array(z.string())
+    .optional()
+    .describe("Entity kinds to check (default: function, method, class, interface, type, struct, enum, trait)"),
+  roots: z
+    .array(z.enum(["exported", "main", "tests", "http_handlers"]))
+    .optional()
+    .default([...DEFAULT_UNUSED_ROOTS])
+    .describe("Entry points never reported as unused (default: all; includes Go exported names and main/init)"),
+  rootNames: z
+    .array(z.string())
+    .optional()
+    .describe("Extra root names or `Type.method` patterns; `*` is a wildcard (e.g. Handle*, *Middleware)"),
+  limit: z.number().int().positive().max(5000).optional().default(500).describe("Maximum entities to return"),
 });
 
-const GetGraphSchema = z.object({
-  query: z.string().optional().describe("Optional search query"),
-  limit: z.number().optional().default(100).describe("Maximum entities to return (page size when cursor is used)"),
-  cursor: z.string().optional().describe("Opaque cursor for pagination"),
-  pageSize: z.number().int().positive().max(500).optional().describe("Page size (overrides limit)"),
+const FindUnusedImportsSchema = z.object({
+  directory: z.string().optional().describe("Only consider files under this directory (default: whole index)"),
+  checkSource: z
+    .boolean()
+    .optional()
+    .default(true)
+    .describe("Keep names the file still mentions outside its imports (uses the graph has no edge for, e.g. types)"),
+  limit: z.number().int().positive().max(5000).optional().default(500).describe("Maximum imports to return"),
 });
 
-const GetGraphStatsSchema = z.object({});
-const GetLernaProjectGraphSchema = z.object({
-  directory: z
+const BrowseSchema = z.object({
+  path: z
     .string()
     .optional()
-    .describe("Workspace directory to run the Lerna graph command from (defaults to server root)."),
-  ingest: z.boolean().optional().default(false).describe("Store package nodes and dependencies in graph storage."),
+    .describe(
+      "Package or directory to open: a directory, file, Go import path, dotted Python package or container id (default: the roots)",
+    ),
+  limit: z.number().int().positive().max(5000).optional().default(500).describe("Maximum children to return"),
+});
+
+const ListComplexFunctionsSchema = z.object({
+  threshold: z.number().int().min(1).optional().default(10).describe("Minimum cyclomatic complexity to report"),
+  directory: z.string().optional().describe("Only consider files under this directory (default: whole index)"),
+  limit: z.number().int().positive().max(1000).optional().default(50).describe("Maximum functions to return"),
+});
+
+const SnapshotGraphSchema = z.object({
+  action: z
+    .enum(["create", "list", "delete"])
+    .optional()
+    .default("create")
+    .describe("create: copy the current index under label; list: show snapshots; delete: drop label"),
+  label: z.string().min(1).optional().describe("Snapshot label, e.g. a git revision or tag (create/delete)"),
+  description: z.string().optional().describe("Free-form note stored with the snapshot"),
+  replace: z.boolean().optional().default(false).describe("Overwrite an existing snapshot with the same label"),
+  vectors: z
+    .boolean()
+    .optional()
+    .default(true)
+    .describe("Keep the entities' search vectors with the snapshot, so semantic_search can rank it later"),
+});
+
+const DiffGraphSchema = z.object({
+  from: z.string().min(1).describe("Snapshot label of the older state"),
+  to: z.string().min(1).optional().describe("Snapshot label of the newer state (default: the current index)"),
+  directory: z.string().optional().describe("Only report changes to files under this directory"),
+  limit: z.number().int().positive().max(10000).optional().default(1000).describe("Maximum changes to list"),
+});
+
+const InheritanceHierarchySchema = z.object({
+  symbol: z.string().min(1).describe("Class, interface, struct or trait name; may be qualified as pkg.Name"),
+  filePath: z.string().optional().describe("Optional file declaring the type (narrows the roots)"),
+  package: z.string().optional().describe("Optional package/module/namespace qualifier"),
+  entityType: z.string().optional().describe("Optional kind of the declaration (class, interface, struct, ...)"),
+  direction: z
+    .enum(["ancestors", "descendants", "both"])
+    .optional()
+    .default("both")
+    .describe("Walk up to base types, down to subtypes, or both"),
+  maxDepth: z.number().int().positive().max(50).optional().default(10).describe("Maximum levels in each tree"),
+  limit: z.number().int().positive().max(5000).optional().default(500).describe("Maximum nodes across the trees"),
+});
+
+const FindOverridesSchema = z.object({
+  symbol: z
+    .string()
+    .min(1)
+    .describe("Base method, optionally qualified by its type (Shape.area), or a type to list its methods"),
+  filePath: z.string().optional().describe("Optional file declaring the method or type"),
+  package: z.string().optional().describe("Optional owning type (for methods) or package qualifier (for types)"),
+  entityType: z.string().optional().describe("Optional kind of the declaration (method, function, class, ...)"),
+  limit: z.number().int().positive().max(5000).optional().default(500).describe("Maximum overriding methods to return"),
+});
+
+const ListMembersSchema = z.object({
+  symbol: z.string().min(1).describe("Class, struct or interface, optionally qualified (shop.Cart)"),
+  filePath: z.string().optional().describe("Optional file declaring the type"),
+  package: z.string().optional().describe("Optional package or namespace qualifier"),
+  kinds: z
+    .array(z.enum(["method", "field", "type"]))
+    .optional()
+    .describe("Only these member kinds: methods, fields/properties, nested types (default all)"),
+  limit: z.number().int().positive().max(5000).optional().default(500).describe("Maximum members per type"),
+});
+
+const ListRoutesSchema = z.object({
+  method: z
+    .string()
+    .optional()
+    .describe("Only routes for this HTTP method (GET, POST, ...; ANY for catch-all routes; RPC for gRPC methods)"),
+  path: z.string().optional().describe("Only routes whose URL path starts with this, e.g. /api/users"),
+  framework: z
+    .string()
+    .optional()
+    .describe("Only routes of this framework (express, koa, nestjs, flask, fastapi, net/http, gin, grpc, ...)"),
+  directory: z.string().optional().describe("Only routes declared in files under this directory"),
+  limit: z.number().int().positive().max(5000).optional().default(500).describe("Maximum routes to return"),
+});
+
+const FindByAuthorSchema = z.object({
+  author: z
+    .string()
+    .optional()
+    .describe("Part of the author's name (case-insensitive) or their exact email; omit to summarise every author"),
+  directory: z.string().optional().describe("Only entities in files under this directory"),
+  kinds: z.array(z.string()).optional().describe("Only these entity kinds (function, class, method, ...)"),
+  since: z.string().optional().describe("Only entities last modified on or after this date (ISO 8601)"),
+  until: z.string().optional().describe("Only entities last modified before this date (ISO 8601)"),
+  sort: z
+    .enum(["recent", "oldest", "author"])
+    .optional()
+    .default("recent")
+    .describe("Order: newest change first, oldest first, or by author then newest"),
+  limit: z.number().int().positive().max(5000).optional().default(200).describe("Maximum entities to return"),
+});
+
+const FindBySignatureSchema = z.object({
+  signature: z
+    .string()
+    .min(1)
+    .describe(
+      "Signature shape, e.g. func(int) (*User, error), (string, *) => Promise<User> or fn(&str) -> Config; * leaves a position open, ... any run of positions, no result part any result",
+    ),
+  directory: z.string().optional().describe("Only functions in files under this directory"),
+  languages: z.array(z.string()).optional().describe("Only files of these languages (python, typescript, go, ...)"),
+  kinds: z.array(z.string()).optional().describe("Entity kinds to match (default functions and methods)"),
+  requireTypes: z
+    .boolean()
+    .optional()
+    .default(false)
+    .describe("Leave out functions that only match because they declare no type at a position"),
+  limit: z.number().int().positive().max(5000).optional().default(100).describe("Maximum matches to return"),
+});
+
+const ListEnvVarsSchema = z.object({
+  name: z.string().optional().describe("Only variables whose name starts with this, e.g. DB_ (case-insensitive)"),
+  directory: z.string().optional().describe("Only reads in files under this directory"),
+  includeUnresolved: z
+    .boolean()
+    .optional()
+    .default(true)
+    .describe("Also list reads whose variable name is computed at run time"),
+  limit: z.number().int().positive().max(5000).optional().default(500).describe("Maximum variables to return"),
+});
+
+const ListTablesSchema = z.object({
+  name: z.string().optional().describe("Only tables whose name contains this, e.g. order (case-insensitive)"),
+  directory: z.string().optional().describe("Only tables declared in files under this directory"),
+  kind: z.enum(["table", "view"]).optional().describe("Only tables or only views (default: both)"),
+  includeColumns: z
+    .boolean()
+    .optional()
+    .default(true)
+    .describe("Include each table's columns; turn off for a shorter overview of a large schema"),
+  limit: z.number().int().positive().max(5000).optional().default(500).describe("Maximum tables to return"),
+});
+
+const ListPackageInitSchema = z.object({
+  package: z
+    .string()
+    .min(1)
+    .describe("Go package: import path, its last elements (store/sql), package name, or a directory or file of it"),
+});
+
+const PublicApiSchema = z.object({
+  target: z
+    .string()
+    .min(1)
+    .describe(
+      "File or directory (its files), or a Go package (import path, its last elements, package name, or a directory holding one; its non-test files). Members of unexported types and test files are left out",
+    ),
+  kinds: z.array(z.string()).optional().describe("Only these entity kinds (function, method, class, type, ...)"),
+  limit: z.number().int().min(1).max(5000).optional().default(500).describe("Maximum declarations to return"),
+});
+
+const ListTodosSchema = z.object({
+  directory: z.string().optional().describe("Only markers in files under this directory"),
+  kinds: z
+    .array(z.enum(["TODO", "FIXME", "HACK", "XXX"]))
+    .optional()
+    .describe("Only these markers (default: all of TODO, FIXME, HACK and XXX)"),
+  assignee: z.string().optional().describe("Only markers naming this person, as in TODO(alice): (case-insensitive)"),
+  query: z.string().optional().describe("Only markers whose text contains this (case-insensitive)"),
+  limit: z.number().int().positive().max(5000).optional().default(500).describe("Maximum markers to return"),
+});
+
+const TechDebtReportSchema = z.object({
+  directory: z.string().optional().describe("Only markers in files under this directory"),
+  kinds: z
+    .array(z.enum(["TODO", "FIXME", "HACK", "XXX"]))
+    .optional()
+    .describe("Only these markers (default: all of TODO, FIXME, HACK and XXX)"),
+  limit: z
+    .number()
+    .int()
+    .positive()
+    .max(5000)
+    .optional()
+    .default(200)
+    .describe("Maximum markers to list, oldest first"),
+});
+
+const FindTestsForSchema = z.object({
+  symbol: z
+    .string()
+    .min(1)
+    .describe("Function, method or type whose tests to find, optionally qualified (Type.method)"),
+  filePath: z.string().optional().describe("Optional file declaring the symbol"),
+  package: z.string().optional().describe("Optional package, namespace or owning type qualifier"),
+  entityType: z.string().optional().describe("Optional kind of the declaration (function, method, class, ...)"),
+  depth: z
+    .number()
+    .int()
+    .min(1)
+    .max(5)
+    .optional()
+    .default(1)
+    .describe("1 = tests exercising the symbol itself; higher also follows its callers up to this many levels"),
+  limit: z.number().int().positive().max(5000).optional().default(500).describe("Maximum tests to return"),
+});
+
+const FindTestedBySchema = z
+  .object({
+    test: z.string().optional().describe("Test function name (TestParse, test_parse) or Jest/Vitest test title"),
+    filePath: z.string().optional().describe("Test file; without `test`, every test it declares"),
+    limit: z.number().int().positive().max(5000).optional().default(500).describe("Maximum covered symbols to return"),
+  })
+  .refine((value) => Boolean(value.test || value.filePath), {
+    message: "Provide either test or filePath",
+    path: ["test"],
+  });
+
+const AnalyzeModuleDependentsSchema = z.object({
+  moduleSource: z.string().describe("Module import source (e.g. ./editorWebWorker.js)"),
+  limit: z.number().optional().default(100).describe("Maximum number of importers to return"),
+});
+
+const DetectCodeClonesSchema = z.object({
+  minSimilarity: z.number().optional().default(0.8).describe("Minimum similarity for clones"),
+  scope: z.string().optional().default("all").describe("Scope: all, file, or module"),
+});
+
+const JscpdCloneDetectionSchema = z.object({
+  paths: z
+    .array(z.string())
+    .nonempty()
+    .optional()
+    .describe("Directories or files to scan. Relative paths resolve against the server root."),
+  pattern: z.string().optional().describe("Glob pattern to apply within each path (default **/*)."),
+  ignore: z.array(z.string()).optional().describe("Glob patterns to exclude from scanning."),
+  formats: z
+    .array(z.string().regex(/^[^.]+$/))
+    .optional()
+    .describe("File extensions to include without dots (e.g. ['ts','js'])."),
+  minLines: z.number().int().min(1).optional().describe("Minimum lines per clone block."),
+  maxLines: z.number().int().min(1).optional().describe("Maximum lines per clone block."),
+  minTokens: z.number().int().min(1).optional().describe("Minimum tokens per clone (interpreted as lines)."),
+  ignoreCase: z.boolean().optional().describe("Lowercase tokens before comparison."),
+});
+
+const SuggestRefactoringSchema = z
+  .object({
+    filePath: z.string().describe("File to analyze for refactoring"),
+    focusArea: z.string().optional().describe("Specific entity name to focus on"),
+    entityId: z.string().optional().describe("Exact entity ID to analyze"),
+    startLine: z.number().int().min(1).optional().describe("1-based start line for manual selection"),
+    endLine: z.number().int().min(1).optional().describe("1-based end line (exclusive)"),
+  })
+  .refine(
+    (v) =>
+      (v.startLine == null && v.endLine == null) ||
+      (v.startLine != null && v.endLine != null && v.startLine < v.endLine),
+    {
+      message: "startLine and endLine must both be provided and startLine < endLine",
+      path: ["startLine"],
+    },
+  );
+
+const CrossLanguageSearchSchema = z.object({
+  query: z.string().describe("Search query"),
+  languages: z.array(z.string()).optional().describe("Languages to search in"),
+});
+
+const AnalyzeHotspotsSchema = z.object({
+  metric: z.string().optional().default("complexity").describe("Metric: complexity, changes, or coupling"),
+  limit: z.number().optional().default(10).describe("Maximum hotspots to return"),
+});
+
+const FindRelatedConceptsSchema = z.object({
+  entityId: z.string().describe("Entity to find related concepts for"),
+  limit: z.number().optional().default(10).describe("Maximum results to return"),
+});
+
+const GetGraphSchema = z.object({
+  query: z.string().optional().describe("Optional search query"),
+  limit: z.number().optional().default(100).describe("Maximum entities to return (page size when cursor is used)"),
+  cursor: z.string().optional().describe("Opaque cursor for pagination"),
+  pageSize: z.number().int().positive().max(500).optional().describe("Page size (overrides limit)"),
+});
+
+const GetGraphStatsSchema = z.object({});
+const GraphStatsSchema = z.object({});
+const GetLernaProjectGraphSchema = z.object({
+  directory: z
+    .string()
+    .optional()
+    .describe("Workspace directory to run the Lerna graph command from (defaults to server root)."),
+  ingest: z.boolean().optional().default(false).describe("Store package nodes and dependencies in graph storage."),
   force: z.boolean().optional().default(false).describe("Bypass caches and force a fresh Lerna graph command."),
 });
 const GetGraphHealthSchema = z.object({
@@ -1073,6 +2144,20 @@ const GetGraphHealthSchema = z.object({
   sample: z.number().optional().default(1).describe("Sample size to fetch for verification"),
 });
 
+const ExportGraphSchema = z.object({
+  format: z
+    .enum(["graphml", "cypher", "dot", "json"])
+    .optional()
+    .default("graphml")
+    .describe(
+      "graphml for Gephi/yEd, cypher for batched idempotent Neo4j MERGE statements, dot for Graphviz, json for scripts",
+    ),
+  outputPath: z
+    .string()
+    .optional()
+    .describe("File to write (relative paths resolve against the server root; default .code-graph-rag/graph.<format>)"),
+});
+
 const GetBusStatsSchema = z.object({});
 const ClearBusTopicSchema = z.object({
   topic: z
@@ -1089,11 +2174,75 @@ const CleanIndexSchema = z.object({
     .describe("Patterns to exclude during indexing (merged with built-in defaults; tmp/ is always excluded)")
     .optional()
     .default([...DEFAULT_INDEX_EXCLUDE_PATTERNS]),
+  respectGitignore: z
+    .boolean()
+    .optional()
+    .default(true)
+    .describe("Also exclude paths ignored by .gitignore files (including nested ones and .git/info/exclude)"),
   fullScan: z.boolean().optional().default(false),
+  languages: IndexLanguagesSchema,
+});
+
+const UpdateIndexSchema = z.object({
+  directory: z.string().describe("Directory to update (defaults to server root)").optional(),
+  excludePatterns: z
+    .array(z.string())
+    .describe("Patterns to exclude (merged with built-in defaults; tmp/ is always excluded)")
+    .optional()
+    .default([...DEFAULT_INDEX_EXCLUDE_PATTERNS]),
+  respectGitignore: z
+    .boolean()
+    .optional()
+    .default(true)
+    .describe("Also exclude paths ignored by .gitignore files (including nested ones and .git/info/exclude)"),
+});
+
+const IndexStatusSchema = z.object({
+  directory: z.string().describe("Indexed directory to check (defaults to server root)").optional(),
+  excludePatterns: z
+    .array(z.string())
+    .describe("Patterns the index was built with (merged with built-in defaults), so excluded files are not reported")
+    .optional()
+    .default([...DEFAULT_INDEX_EXCLUDE_PATTERNS]),
+  respectGitignore: z
+    .boolean()
+    .optional()
+    .default(true)
+    .describe("Leave out paths ignored by .gitignore files, as indexing does"),
+  limit: z.number().int().positive().max(10000).optional().default(200).describe("Maximum stale files to list"),
+});
+
+const ReindexFileSchema = z.object({
+  path: z.string().describe("File to re-parse; relative paths resolve against the server root"),
+});
+
+const StartWatchSchema = z.object({
+  directory: z.string().describe("Directory to watch (defaults to server root)").optional(),
+  excludePatterns: z
+    .array(z.string())
+    .describe("Patterns to ignore (merged with built-in defaults; tmp/ is always excluded)")
+    .optional()
+    .default([...DEFAULT_INDEX_EXCLUDE_PATTERNS]),
+  respectGitignore: z
+    .boolean()
+    .optional()
+    .default(true)
+    .describe("Also exclude paths ignored by .gitignore files (including nested ones and .git/info/exclude)"),
+  debounceMs: z.number().int().min(50).max(60000).describe("Quiet period before a batch is indexed").optional(),
 });
 
 const GetAgentMetricsSchema = z.object({});
 
+const MetricsSchema = z.object({
+  reset: z
+    .boolean()
+    .optional()
+    .default(false)
+    .describe("Zero every counter after taking this snapshot, to measure the next stretch of work on its own"),
+});
+
+const WarmupSchema = z.object({});
+
 // Create MCP server
 const server = new Server(
   {
@@ -1118,6 +2267,14 @@ server.oninitialized = () => {
         await getSemanticAgent();
       }
       console.error("Core agents initialized (background): DevAgent, DoraAgent, SemanticAgent");
+      if (watchRequested) {
+        const status = startIndexWatcher(directory, [...DEFAULT_INDEX_EXCLUDE_PATTERNS]);
+        console.error(`[Watch] Watching ${status.directory} for changes`);
+      }
+      if (getConfigOrThrow().mcp.semantic?.warmupOnStartup) {
+        const report = await warmupServer();
+        console.error(`[Warmup] Ready in ${report.totalMs}ms`);
+      }
     } catch (error) {
       console.error("Background agent init failed:", error);
       logger.error(
@@ -1132,12 +2289,21 @@ server.oninitialized = () => {
 };
 
 // Helper: enforce operation timeouts per SYSTEM_HANG_RECOVERY_PLAN
-async function withTimeout<T>(promise: Promise<T>, ms: number, label: string, requestId: string): Promise<T> {
+async function withTimeout<T>(
+  promise: Promise<T>,
+  ms: number,
+  label: string,
+  requestId: string,
+  onTimeout?: () => void,
+): Promise<T> {
+  // A timeout of 0 is configured as "no deadline"
+  if (!(ms > 0)) return promise;
   let timer: NodeJS.Timeout | undefined;
   const timeout = new Promise<never>((_, reject) => {
     timer = setTimeout(() => {
-      const err = new Error(`${label} timed out after ${ms}ms`);
+      const err = new ToolTimeoutError({ tool: label, timeoutMs: ms });
       logger.incident("Operation timeout", { label, timeoutMs: ms }, requestId, err);
+      onTimeout?.();
       reject(err);
     }, ms);
   });
@@ -1150,6 +2316,102 @@ async function withTimeout<T>(promise: Promise<T>, ms: number, label: string, re
   }
 }
 
+// How long a timed-out indexing run may take to stop at a batch boundary and report its counts
+const INDEX_FLUSH_GRACE_MS = 10000;
+
+/** What an MCP tool request carries besides its arguments */
+interface ToolCallContext {
+  /** Aborted by the SDK when the client sends notifications/cancelled for the request */
+  signal?: AbortSignal;
+  /** Present when the client asked for notifications/progress */
+  progressToken?: string | number;
+}
+
+function sendProgress(progressToken: string | number, progress: number, total: number, message: string): void {
+  server
+    .notification({ method: "notifications/progress", params: { progressToken, progress, total, message } })
+    .catch(() => {
+      // The client may have gone away; the run itself carries on
+    });
+}
+
+/** Forward a run's progress as MCP progress notifications; the value sent never goes down */
+function progressNotifier(progressToken: string | number): (progress: IndexProgress) => void {
+  let last = -1;
+  return (progress) => {
+    const value = overallPercent(progress);
+    if (value === null || value <= last) return;
+    last = value;
+    const eta = progress.etaMs === null ? "" : `, about ${Math.ceil(progress.etaMs / 1000)}s left`;
+    sendProgress(
+      progressToken,
+      value,
+      100,
+      `${progress.phase}: ${progress.filesProcessed}/${progress.totalFiles} files${eta}`,
+    );
+  };
+}
+
+/**
+ * Run an indexing task through the conductor under a deadline, tracking its progress under the
+ * task id for get_index_progress. The task's signal is aborted when the deadline passes or the
+ * client cancels the request, so the agents stop rather than carry on unobserved.
+ */
+async function runIndexTask(
+  cond: ConductorOrchestrator,
+  task: AgentTask,
+  label: string,
+  requestId: string,
+  timeoutMs: number,
+  call: ToolCallContext = {},
+  awaitEmbeddings = true,
+): Promise<unknown> {
+  const controller = linkedAbortController(
+    call.signal,
+    () => new TaskCancelledError({ taskId: task.id, reason: "client" }),
+  );
+  task.signal = controller.signal;
+  // Conductor subtasks get ids of their own; the dev agent reports under the one clients see
+  task.payload = { ...(task.payload as object), progressId: task.id };
+  indexProgress.start(task.id);
+  const stopNotifying =
+    call.progressToken === undefined
+      ? undefined
+      : indexProgress.onProgress(task.id, progressNotifier(call.progressToken));
+
+  const work = cond.process(task);
+  try {
+    const result = await withTimeout(work, timeoutMs, label, requestId, () =>
+      controller.abort(new TaskCancelledError({ taskId: task.id, reason: "timeout" })),
+    ).catch(async (error) => {
+      if (!(error instanceof ToolTimeoutError)) throw error;
+      // The abort stops the run after the batch in flight; give it a moment to report what it stored
+      const stopped = await Promise.race([
+        work.then(
+          (late) => ({ late }),
+          (e) => ({ partial: e instanceof TaskCancelledError ? e.details.partial : undefined }),
+        ),
+        new Promise<{ partial?: undefined }>((resolve) => setTimeout(() => resolve({}), INDEX_FLUSH_GRACE_MS)),
+      ]);
+      if ("late" in stopped) return stopped.late;
+      throw new ToolTimeoutError({ ...error.details, partial: stopped.partial });
+    });
+    if (awaitEmbeddings && process.env.MCP_DEBUG_DISABLE_SEMANTIC !== "1") {
+      indexProgress.report(task.id, { phase: "embedding" });
+      await ensureSemanticsReady(1, 5000);
+    }
+    indexProgress.finish(task.id, "completed");
+    return result;
+  } catch (error) {
+    const message = error instanceof Error ? error.message : String(error);
+    const cancelled = error instanceof TaskCancelledError || error instanceof ToolTimeoutError;
+    indexProgress.finish(task.id, cancelled ? "cancelled" : "failed", message);
+    throw error;
+  } finally {
+    stopNotifying?.();
+  }
+}
+
 // Keep compile-time types light for large Zod schemas (performance/memory).
 const toJsonSchema = (schema: z.ZodTypeAny) => (zodToJsonSchema as any)(schema) as any;
 
@@ -1168,43 +2430,326 @@ function toolMeta(requestId: string, startTime: number, extra?: Record<string, u
   return { requestId, ms: Date.now() - startTime, ...extra };
 }
 
-async function collectRelationsWithin(storage: Awaited<ReturnType<typeof getGraphStorage>>, entityIds: Set<string>) {
-  const rels: Relationship[] = [];
-  const seen = new Set<string>();
-  for (const id of entityIds) {
-    const rs = await storage.getRelationshipsForEntity(id);
-    for (const r of rs) {
-      if (!entityIds.has(r.fromId) || !entityIds.has(r.toId)) continue;
-      const key = r.id || `${r.fromId}|${r.toId}|${r.type}`;
-      if (seen.has(key)) continue;
-      seen.add(key);
-      rels.push(r);
+/**
+ * Files the dev agent declined or gave up parsing (oversized, binary, generated, unreadable, timed
+ * out), gathered from a conductor result that may wrap one entry per subtask.
+ */
+function collectSkippedFiles(result: unknown): SkippedIndexFile[] {
+  const entries: any[] = Array.isArray((result as any)?.results) ? (result as any).results : [result];
+  return entries.flatMap((entry) => (Array.isArray(entry?.skipped) ? (entry.skipped as SkippedIndexFile[]) : []));
+}
+
+/** Files indexed despite syntax errors, with the skipped regions, from a conductor result */
+/** How git blame went in an index run: files blamed over all subtasks, and why it was skipped if it was */
+function collectGitBlame(result: unknown): { enabled: boolean; filesBlamed: number; reason?: string } | null {
+  const entries: any[] = Array.isArray((result as any)?.results) ? (result as any).results : [result];
+  const reports = entries.map((entry) => entry?.gitBlame).filter((report) => report && typeof report === "object");
+  if (reports.length === 0) return null;
+  return {
+    enabled: reports.some((report) => report.enabled),
+    filesBlamed: reports.reduce((acc, report) => acc + (Number(report.filesBlamed) || 0), 0),
+    reason: reports.find((report) => report.reason)?.reason,
+  };
+}
+
+/** Languages a `languages`-restricted run indexed, merged over subtasks; null for unrestricted runs */
+function collectIndexLanguages(
+  result: unknown,
+): { requested: string[]; included: string[]; filesSkipped: number } | null {
+  const entries: any[] = Array.isArray((result as any)?.results) ? (result as any).results : [result];
+  const reports = entries.map((entry) => entry?.languages).filter((report) => report && typeof report === "object");
+  if (reports.length === 0) return null;
+  return {
+    requested: reports[0].requested,
+    included: [...new Set<string>(reports.flatMap((report) => report.included ?? []))].sort(),
+    filesSkipped: reports.reduce((acc, report) => acc + (Number(report.filesSkipped) || 0), 0),
+  };
+}
+
+function mapLocatedEdge(edge: LocatedEdge): LocatedEdge {
+  return edge.file ? { ...edge, file: normalizeInputPath(edge.file) ?? edge.file } : edge;
+}
+
+function collectParseErrors(result: unknown): FileParseErrors[] {
+  const entries: any[] = Array.isArray((result as any)?.results) ? (result as any).results : [result];
+  return entries.flatMap((entry) =>
+    Array.isArray(entry?.parseErrors) ? (entry.parseErrors as FileParseErrors[]) : [],
+  );
+}
+
+/**
+ * Per-phase durations reported by the dev agent, summed over subtasks; null when none reported any.
+ */
+function collectIndexTiming(result: unknown): Record<string, number> | null {
+  const entries: any[] = Array.isArray((result as any)?.results) ? (result as any).results : [result];
+  let timing: Record<string, number> | null = null;
+  for (const entry of entries) {
+    if (!entry?.timing || typeof entry.timing !== "object") continue;
+    timing ??= {};
+    for (const [phase, ms] of Object.entries(entry.timing as Record<string, unknown>)) {
+      if (typeof ms === "number") timing[phase] = (timing[phase] ?? 0) + ms;
     }
   }
-  return rels;
+  return timing;
 }
 
-function annotateStructuralMatch(summary: ReturnType<typeof mapEntitySummary>, q: string) {
-  const queryText = q.trim();
-  const qLower = queryText.toLowerCase();
-  const nameLower = String((summary as any).name ?? "").toLowerCase();
+/**
+ * What an index run did to the stored graph, summed over subtasks. Re-running it over unchanged
+ * files reports only `entitiesUnchanged`.
+ */
+function collectIndexChanges(result: unknown): Record<string, number> {
+  const entries: any[] = Array.isArray((result as any)?.results) ? (result as any).results : [result];
+  const sum = (key: string) => entries.reduce((acc, entry) => acc + (Number(entry?.[key]) || 0), 0);
+  return {
+    created: sum("entitiesCreated"),
+    updated: sum("entitiesUpdated"),
+    unchanged: sum("entitiesUnchanged"),
+    deleted: sum("entitiesDeleted"),
+    relationshipsCreated: sum("relationshipsCreated"),
+    relationshipsDeleted: sum("relationshipsDeleted"),
+    filesRemoved: sum("filesRemoved"),
+  };
+}
 
-  let matchType: "exact_name" | "name_contains" | "query_contains" | "unknown" = "unknown";
-  if (qLower && nameLower === qLower) matchType = "exact_name";
-  else if (qLower && nameLower.includes(qLower)) matchType = "name_contains";
-  else if (qLower?.includes(nameLower) && nameLower.length >= 3) matchType = "query_contains";
+/**
+ * Run a content-hash incremental update through the conductor. With `files` only those paths are
+ * hash-checked; without, the whole directory is. Removed files are detected either way.
+ */
+async function runIncrementalUpdate(
+  targetDir: string,
+  excludePatterns: readonly string[],
+  requestId: string,
+  files?: string[],
+  respectGitignore = true,
+  call: ToolCallContext = {},
+) {
+  if (process.env.MCP_DEBUG_DISABLE_SEMANTIC !== "1") {
+    await getSemanticAgent();
+  }
+  await getDevAgent();
+
+  const task: AgentTask = {
+    id: `update-index-${Date.now()}`,
+    type: "index",
+    priority: 8,
+    payload: {
+      directory: targetDir,
+      files,
+      incremental: true,
+      changeDetection: "hash",
+      fullScan: false,
+      excludePatterns: mergeUniqueStrings(DEFAULT_INDEX_EXCLUDE_PATTERNS, excludePatterns),
+      respectGitignore,
+    },
+    createdAt: Date.now(),
+  };
 
-  return { ...summary, matchType };
+  const cond = await getConductor();
+  await cond.initialize();
+  await getDevAgent();
+  const timeoutMs = ConfigLoader.getInstance().getToolTimeoutMs("update_index");
+  const result = await runIndexTask(cond, task, "update_index", requestId, timeoutMs, call);
+
+  // The conductor wraps per-subtask results; the dev agent's counters live in those entries
+  const subResults: any[] = Array.isArray((result as any)?.results) ? (result as any).results : [result];
+  const sum = (key: string) => subResults.reduce((acc, r) => acc + (Number(r?.[key]) || 0), 0);
+  const summary = {
+    filesReparsed: sum("filesReparsed"),
+    filesSkipped: sum("filesSkipped"),
+    filesRemoved: sum("filesRemoved"),
+    dependentFiles: sum("dependentFiles"),
+    entitiesDeleted: sum("entitiesDeleted"),
+    entitiesExtracted: sum("entitiesExtracted"),
+    relationshipsCreated: sum("relationshipsCreated"),
+  };
+
+  knowledgeBus.publish("index:completed", result, "mcp-server");
+  logger.agentActivity("conductor", "incremental update completed", { directory: targetDir, ...summary }, requestId);
+
+  return { result, summary };
 }
 
-// Handler for listing available tools
-server.setRequestHandler(ListToolsRequestSchema as any, async () => {
+/**
+ * Re-parse one file in place and report how its entities changed. The run rebuilds the file's own
+ * edges and re-resolves its imports; when the file gained or lost declarations, the files with
+ * edges into it or imports of it are re-parsed too, so their edges bind to what it declares now.
+ * A file that no longer exists is removed from the graph. New vectors are computed only for
+ * entities whose content changed.
+ */
+async function reindexFile(filePath: string, roots: string[], requestId: string, call: ToolCallContext = {}) {
+  if (process.env.MCP_DEBUG_DISABLE_SEMANTIC !== "1") {
+    await getSemanticAgent();
+  }
+  await getDevAgent();
+  const storage = await getGraphStorage(globalSQLiteManager);
+  const cond = await getConductor();
+  await cond.initialize();
+
+  const timeoutMs = ConfigLoader.getInstance().getToolTimeoutMs("reindex_file");
+  const deadline = timeoutMs > 0 ? Date.now() + timeoutMs : 0;
+  // Ids of a multi-root index are relative to the directory holding every root; keep them that way
+  const scope = roots.length > 1 ? { roots } : { directory: roots[0] };
+  const run = (files: string[]) =>
+    runIndexTask(
+      cond,
+      {
+        id: `reindex-file-${Date.now()}`,
+        type: "index",
+        priority: 8,
+        payload: { ...scope, files, incremental: false, fullScan: false },
+        createdAt: Date.now(),
+      },
+      "reindex_file",
+      requestId,
+      deadline ? Math.max(1, deadline - Date.now()) : 0,
+      call,
+    );
+
+  const before = await loadFileEntities(storage, filePath);
+  // Edges into the file are rebuilt by the run; collect where they come from first
+  const dependents = await storage.findDependentFiles([filePath]);
+  const exists = statSync(filePath, { throwIfNoEntry: false })?.isFile() ?? false;
+
+  let result: unknown = null;
+  let changes: Record<string, number>;
+  if (exists) {
+    result = await run([filePath]);
+    changes = collectIndexChanges(result);
+  } else {
+    const deleted = await storage.deleteFileData(filePath);
+    knowledgeBus.publish("index:files_removed", { files: [filePath] }, "mcp-server");
+    changes = {
+      ...collectIndexChanges(null),
+      deleted: deleted.entitiesRemoved,
+      relationshipsDeleted: deleted.relationshipsDeleted,
+      filesRemoved: 1,
+    };
+  }
+  const entities = diffFileEntities(before, await loadFileEntities(storage, filePath));
+
+  let reboundFiles: string[] = [];
+  if (entities.added.length > 0 || entities.removed.length > 0) {
+    // Imports of a name the file did not declare before end at placeholders, not in the file
+    const importers = await storage.executeQuery({
+      type: "entity",
+      filters: { entityType: EntityType.IMPORT, importSource: filePath } as any,
+      limit: 1000,
+    });
+    reboundFiles = Array.from(new Set([...dependents, ...importers.entities.map((entity) => entity.filePath)]))
+      .filter((path) => path !== filePath && statSync(path, { throwIfNoEntry: false })?.isFile())
+      .sort();
+    if (reboundFiles.length > 0) await run(reboundFiles);
+  }
+
+  knowledgeBus.publish("index:completed", result, "mcp-server");
+  logger.agentActivity(
+    "conductor",
+    "file re-indexed",
+    {
+      filePath,
+      added: entities.added.length,
+      modified: entities.modified.length,
+      removed: entities.removed.length,
+      reboundFiles: reboundFiles.length,
+    },
+    requestId,
+  );
+
+  return { removed: !exists, entities, changes, reboundFiles, result };
+}
+
+let indexWatcher: IndexWatcher | null = null;
+
+function startIndexWatcher(
+  targetDir: string,
+  excludePatterns: readonly string[],
+  debounceMs?: number,
+  respectGitignore = true,
+) {
+  indexWatcher?.stop();
+
+  const mergedExcludes = mergeUniqueStrings(DEFAULT_INDEX_EXCLUDE_PATTERNS, excludePatterns);
+  const watcher = new IndexWatcher({
+    directory: targetDir,
+    excludePatterns: mergedExcludes,
+    respectGitignore,
+    detectLanguageByContent: ConfigLoader.getInstance().isContentLanguageDetectionEnabled(),
+    debounceMs,
+    onBatch: (batch) =>
+      lifecycle.track(() =>
+        serverActivity.write(
+          "watch",
+          async () => {
+            const requestId = `watch-${Date.now()}`;
+            await waitForForeignIndexRuns(lifecycle.signal);
+            // A directory-level change can hide any number of files, so fall back to a full hash check
+            const files = batch.rescan ? undefined : batch.changed;
+            const { summary } = await runIncrementalUpdate(
+              targetDir,
+              mergedExcludes,
+              requestId,
+              files,
+              respectGitignore,
+              { signal: lifecycle.signal },
+            );
+            logger.info("WATCH", "Applied file changes", { changed: batch.changed.length, ...summary }, requestId);
+          },
+          lifecycle.signal,
+        ),
+      ),
+    onError: (error) => {
+      logger.error("WATCH", "Watch update failed", { directory: targetDir, error: error.message }, undefined, error);
+    },
+  });
+  watcher.start();
+  indexWatcher = watcher;
+  return watcher.getStatus();
+}
+
+function stopIndexWatcher() {
+  const watcher = indexWatcher;
+  indexWatcher = null;
+  watcher?.stop();
+  return watcher?.getStatus() ?? null;
+}
+
+async function collectRelationsWithin(storage: Awaited<ReturnType<typeof getGraphStorage>>, entityIds: Set<string>) {
+  const rels: Relationship[] = [];
+  const seen = new Set<string>();
+  for (const id of entityIds) {
+    const rs = await storage.getRelationshipsForEntity(id);
+    for (const r of rs) {
+      if (!entityIds.has(r.fromId) || !entityIds.has(r.toId)) continue;
+      const key = r.id || `${r.fromId}|${r.toId}|${r.type}`;
+      if (seen.has(key)) continue;
+      seen.add(key);
+      rels.push(r);
+    }
+  }
+  return rels;
+}
+
+function annotateStructuralMatch(summary: ReturnType<typeof mapEntitySummary>, q: string) {
+  const queryText = q.trim();
+  const qLower = queryText.toLowerCase();
+  const nameLower = String((summary as any).name ?? "").toLowerCase();
+
+  let matchType: "exact_name" | "name_contains" | "query_contains" | "unknown" = "unknown";
+  if (qLower && nameLower === qLower) matchType = "exact_name";
+  else if (qLower && nameLower.includes(qLower)) matchType = "name_contains";
+  else if (qLower?.includes(nameLower) && nameLower.length >= 3) matchType = "query_contains";
+
+  return { ...summary, matchType };
+}
+
+// Handler for listing available tools
+server.setRequestHandler(ListToolsRequestSchema as any, async () => {
   return {
     tools: [
       {
         name: "index",
         description:
-          "Use when: you want a one-shot index of a repo and your client can tolerate a long-running tool call. Avoid when: strict transports may time out—use batch_index instead. Typical flow: clean_index → index or batch_index. Output: JSON status + counts; indexing is required for most graph tools.",
+          "Use when: you want a one-shot index of a repo and your client can tolerate a long-running tool call. Avoid when: strict transports may time out—use batch_index instead. Typical flow: index or batch_index; re-running index is safe, clean_index is only needed to start over. Output: JSON status + counts, with changes: entities created/updated/unchanged/deleted and relationships created/deleted (a second run over an unchanged tree reports only unchanged); indexing is required for most graph tools.",
         inputSchema: toJsonSchema(IndexToolSchema),
       },
       {
@@ -1219,47 +2764,113 @@ server.setRequestHandler(ListToolsRequestSchema as any, async () => {
           "Use when: you have a file and need the exact entityId for follow-up graph tools. Typical flow: list_file_entities(filePath) → pick entityId → list_entity_relationships/analyze_code_impact. Output: entity list with locations/metadata; requires indexing.",
         inputSchema: toJsonSchema(ListEntitiesToolSchema),
       },
+      {
+        name: "file_summary",
+        description:
+          "Use when: you open an unfamiliar file and want its public surface at a glance, without a semantic query. Typical flow: file_summary(filePath) → get_entity_source or list_members on an export → find_references. Output: the file's doc comment (package/module documentation or the comment block opening the file), its imports with the names they bind and the indexed files they resolved to (empty for external packages), its exported or public top-level declarations with signatures and line ranges, and counts of private top-level declarations and private/protected members; requires indexing.",
+        inputSchema: toJsonSchema(FileSummarySchema),
+      },
       {
         name: "list_entity_relationships",
         description:
-          "Use when: you need structural neighbors (imports/references/containment) for an entityId/entityName. Typical flow: list_file_entities → list_entity_relationships(depth, relationshipTypes) → analyze_code_impact. Output: entity + relationships; may be sparse if a relationship type is not indexed for that language.",
+          "Use when: you need structural neighbors (imports/references/containment) for an entityId/entityName. Typical flow: list_file_entities → list_entity_relationships(depth, relationshipTypes) → analyze_code_impact. Output: entity + relationships; may be sparse if a relationship type is not indexed for that language. Go concurrency: relationshipTypes [\"spawns\"] lists the goroutines a function starts, [\"sends\", \"receives\"] on a channel entity who sends to or receives from it.",
         inputSchema: toJsonSchema(ListRelationshipsToolSchema),
       },
       {
         name: "resolve_entity",
         description:
-          "Use when: a name is ambiguous and you need the correct entityId before deeper graph tools. Typical flow: resolve_entity(name, filePathHint) → pick entityId → get_entity_source/list_entity_relationships. Output: ranked candidates with reasons; requires indexing.",
+          "Use when: a name is ambiguous and you need the correct entityId before deeper graph tools. Typical flow: resolve_entity(name, filePathHint) → pick entityId → get_entity_source/list_entity_relationships. Output: ranked candidates with reasons; name may be a fully qualified name (an entity's fqn), which ranks the declaration it names first (reason exact_fqn); requires indexing.",
         inputSchema: toJsonSchema(ResolveEntitySchema),
       },
+      {
+        name: "complete_symbol",
+        description:
+          "Use when: you know only the start of a symbol name or its camel-case initials and want the indexed names it could be. Typical flow: complete_symbol(prefix, kinds?, languages?) → resolve_entity/get_entity_source on the chosen name. Output: one entry per name with its match (exact, prefix, prefix_ignore_case, camel_case), kind, definition and usage counts and the best declaration's location; ranked by match, then types before functions, then usage.",
+        inputSchema: toJsonSchema(CompleteSymbolSchema),
+      },
       {
         name: "get_entity_source",
         description:
           "Use when: you need the exact source snippet for an entity to ground answers. Typical flow: resolve_entity/list_file_entities → get_entity_source(entityId, contextLines) → analyze_code_impact. Output: snippet + line ranges; requires file access and indexing.",
         inputSchema: toJsonSchema(GetEntitySourceSchema),
       },
+      {
+        name: "explain_symbol",
+        description:
+          "Use when: you need to understand what a function, method or type does before changing or using it, and want all of its context in one call instead of find_definition + get_entity_source + list_callers + list_callees + find_similar. Typical flow: explain_symbol(symbol, filePath) → explain_symbol(entityId) on a caller, callee or alternative worth a closer look. Output: one context bundle with the declaration (kind, signature, parameters, return type, modifiers, owning type), its doc comment, its source (capped at maxBytes), its callers and callees with call sites and confidence (totals plus the first `neighbors`), the entities closest to it by search vector (null with warning semantic_unavailable when embeddings are off) and the other declarations the name matched; requires indexing.",
+        inputSchema: toJsonSchema(ExplainSymbolSchema),
+      },
+      {
+        name: "find_definition",
+        description:
+          "Use when: you have a symbol name (e.g. from semantic_search) and need its authoritative declaration. Typical flow: semantic_search/query → find_definition(symbol, filePath) → find_references/analyze_code_impact. Output: declaring entities ranked by proximity to filePath, with line range and source snippet; requires indexing, no embeddings. Every entity carries fqn, its project-wide name: Go `<import path>.Type.Method` (import path from go.mod), other languages `path/to/file#Class.method` (relative to the index root, no extension). Two same-named symbols of different packages have different fqns, and passing one as symbol here or to find_references, list_callers and the other symbol tools names exactly that declaration.",
+        inputSchema: toJsonSchema(FindDefinitionSchema),
+      },
+      {
+        name: "find_references",
+        description:
+          "Use when: you need every place a symbol is used (call sites, type references, imports), or where a struct field or variable is read and written before changing it. Typical flow: find_definition → find_references(symbol, filePath/package) → get_entity_source on the referencing entities; for state, find_references(\"Server.users\", access: \"write\"). Output: definitions grouped by containing scope, each with referencing entity, file and line range; accesses (Go fields through the static type of their operand, TS `this` fields, package- and module-level variables) carry read/write/readwrite and every site; every reference has an edge {from, to, type, direction, file, line, column}; reads stored edges, requires indexing.",
+        inputSchema: toJsonSchema(FindReferencesSchema),
+      },
+      {
+        name: "list_callers",
+        description:
+          "Use when: you need to know who calls a function or method before changing its signature or behaviour. Typical flow: find_definition → list_callers(symbol, filePath/package) → get_entity_source on the callers. Output: definitions with their calling functions and the file/line of every call site, each with a confidence and how it was derived (resolved, name_match, heuristic); Go call sites are matched across files of the same package; by-name matches that cannot be attributed are listed as unresolved. Every call has an edge {from, to, type, direction, file, line, column} located at its first call site. Call sites of JS/TS, Go, Java and C/C++ carry their arguments (literals with their value as written, other arguments as expression, `...xs`/`xs...` as spread) and arity (null when a spread hides the count); argument {position, value} keeps only sites passing that literal, e.g. {position: 2, value: \"nil\"}. Pass minConfidence to drop guesses.",
+        inputSchema: toJsonSchema(CallGraphSchema),
+      },
+      {
+        name: "list_callees",
+        description:
+          "Use when: you need to see what a function or method depends on at runtime. Typical flow: find_definition → list_callees(symbol) → list_callees on interesting callees to walk down the call graph. Output: definitions with the functions they call and the file/line of each call site; calls into other packages or libraries are reported unresolved under the callee text.",
+        inputSchema: toJsonSchema(CallGraphSchema),
+      },
+      {
+        name: "call_path",
+        description:
+          "Use when: you need to know whether and how one function reaches another through calls, e.g. how a handler ends up in a database write. Typical flow: find_definition → call_path(from, to) → get_entity_source on the steps. Output: every shortest chain of calls from `from` to `to` (up to limit), each a list of steps with the call to the next step (call sites, confidence, edge {from, to, type, direction, file, line, column}) and the confidence of its weakest call; length, totalPaths, and truncated (reason depth when maxDepth stopped a search that had calls left to follow, limit when paths were dropped). An empty paths list without truncation means no call chain exists. Recursion is followed once, so cycles terminate; from = to finds the shortest recursion. Only calls bound to a declaration are followed (Go calls also by name within the package); requires indexing.",
+        inputSchema: toJsonSchema(CallPathSchema),
+      },
       {
         name: "query",
         description:
-          "Use when: you want a best-effort hybrid answer (semantic + structural) for discovery. Typical flow: query → refine with list_file_entities/list_entity_relationships/analyze_code_impact. Output: combined semantic and structural matches (semantic may be unavailable/disabled).",
+          "Use when: you want to ask the graph in plain words (\"functions that call parseConfig\", \"where is X defined\") or need a best-effort hybrid answer (semantic + structural) for discovery. Typical flow: query → refine with list_file_entities/list_entity_relationships/analyze_code_impact. Output: mode \"structured\" (parsed intent + matching entities) for a recognized question, else combined semantic and structural matches (mode \"hybrid\"; semantic may be unavailable/disabled).",
         inputSchema: toJsonSchema(QueryToolSchema),
       },
       {
         name: "get_metrics",
         description:
-          "Use when: you need runtime resource usage and agent queue snapshots for debugging. Typical flow: get_metrics → get_agent_metrics for deeper agent telemetry. Output: CPU/memory/resource manager + knowledge bus stats.",
+          "Use when: you need runtime resource usage and agent queue snapshots for debugging. Typical flow: get_metrics → get_agent_metrics for deeper agent telemetry. Output: CPU/memory/resource manager + knowledge bus stats, and search result cache hits, size and generation.",
         inputSchema: toJsonSchema(z.object({})),
       },
+      {
+        name: "metrics",
+        description:
+          "Use when: you monitor a session or want to know where time goes, e.g. whether indexing waits on embeddings or on parsing. Typical flow: metrics(reset: true) → run the work → metrics. Output: since when counting runs, tool calls per tool (calls, errors by type, result-cache answers, total/avg/p50/p95/max latency in ms; percentiles over the last 256 calls), totals split into queries (read-only calls) and writes, files parsed and parse time, files/entities/relationships written to the graph and store time, and embedding requests with texts embedded, cache hits, hit rate, fallback answers and embedding time. Counters live in memory since server start; reset zeroes them after the snapshot. Does not require indexing.",
+        inputSchema: toJsonSchema(MetricsSchema),
+      },
+      {
+        name: "warmup",
+        description:
+          "Use when: right after connecting, before the first semantic_search or query, so loading the embedding model (seconds to minutes on a cold model cache) does not run into the client's request timeout. Typical flow: warmup → semantic_search. Opens the graph database, starts the semantic agent, runs the embedding model once on a probe query and loads or builds the ANN index. Output: ready (false only while semantics are disabled, warning semantic_disabled), alreadyWarm (an earlier call or mcp.semantic.warmupOnStartup already did it; the call then returns at once), database path and entity/relationship/file counts, the embedding provider, model, dimension and fallback flag (warning embedding_fallback when the configured model could not load; error and initFailure say why), stored vector count, ann.ready and ann.size (false on stores below mcp.semantic.ann.minVectors, which are scanned exactly) and the milliseconds each step took. Safe to call repeatedly; does not require indexing.",
+        inputSchema: toJsonSchema(WarmupSchema),
+      },
       {
         name: "get_version",
         description:
           "Use when: you need server version/runtime info (node/platform/memory/uptime) for debugging. Output: version + runtime details; does not require indexing.",
         inputSchema: toJsonSchema(z.object({})),
       },
+      {
+        name: "diagnose",
+        description:
+          "Use when: indexing reports 0 entities or a language seems ignored, or to confirm setup before a first index. Loads every tree-sitter grammar the parser supports. Typical flow: diagnose → fix the install (reinstall, rebuild native modules for this Node version) → index. Output: per-language loaded flag, the grammar package path found, the locations checked and the load error; does not require indexing.",
+        inputSchema: toJsonSchema(z.object({})),
+      },
       // New semantic tools - TASK-002
       {
         name: "semantic_search",
         description:
-          "Use when: you want semantic discovery across the codebase. Typical flow: semantic_search → list_file_entities (for exact IDs) → list_entity_relationships. Output: ranked matches; semantic may be disabled; ground results with graph/source follow-ups.",
+          "Use when: you want conceptual or exact-name discovery across the codebase. Typical flow: semantic_search → list_file_entities (for exact IDs) → list_entity_relationships. Output: ranked matches with cosine, path, line range, snippet and highlights; empty with belowThreshold when none pass minScore. Warning embedding_fallback: hashing embeddings in use; error reindex_required: vectors need re-embedding.",
         inputSchema: toJsonSchema(SemanticSearchSchema),
       },
       {
@@ -1268,6 +2879,210 @@ server.setRequestHandler(ListToolsRequestSchema as any, async () => {
           "Use when: you have a snippet and want near-duplicate or conceptually similar code. Typical flow: find_similar_code → open candidate file(s) → suggest_refactoring/detect_code_clones. Output: ranked similar snippets with scores (semantic must be available).",
         inputSchema: toJsonSchema(FindSimilarCodeSchema),
       },
+      {
+        name: "find_similar",
+        description:
+          "Use when: you want functions that duplicate or nearly duplicate the logic of a given one, e.g. before extracting a shared helper. Typical flow: find_similar(entityId or symbol) → get_entity_source on the matches → suggest_refactoring. Output: other functions and methods ranked by cosine similarity of their body embeddings (code only, not names or docs), at least minScore (default 0.85), the target itself excluded; kinds/languages/pathPrefix/pathGlob narrow the candidates. bodyVector says whether the target's stored body vector was used or its body was embedded now; bodies under 80 characters get no vector at index time and are never matched. Requires an index with embeddings; warning embedding_fallback means the scores come from hashing embeddings and only reflect shared words.",
+        inputSchema: toJsonSchema(FindSimilarSchema),
+      },
+      {
+        name: "impact_analysis",
+        description:
+          "Use when: you are about to refactor a function or type and need the full blast radius. Typical flow: find_definition → impact_analysis(symbol, filePath, maxDepth) → list_callers / get_entity_source on the closest affected entities. Output: affected entities grouped by file, each with its depth, shortest dependency path back to the target, the confidence of its weakest edge and the first edge of the path as {from, to, type, direction, file, line, column}, cycles found on the way and a truncation signal when maxDepth or limit cut the walk short; minConfidence keeps heuristic edges out of the blast radius; tests 'exclude' leaves test files out and 'only' reports just the affected tests (default from indexer.includeTests); requires indexing.",
+        inputSchema: toJsonSchema(ImpactAnalysisSchema),
+      },
+      {
+        name: "graph_query",
+        description:
+          "Use when: the canned tools do not cover a graph question, e.g. all functions within 2 hops of calling DeleteUser. Typical flow: find_definition → graph_query(start, edges, direction, maxDepth, where) → get_entity_source on the matches. Output: a bounded breadth-first walk (no SQL): the start nodes, then the nodes reached at minDepth..maxDepth hops (at most 5) that match `where`, nearest first, each with its depth, start node and the edge it was reached by (the whole path with returnPaths), plus truncation notices when the start selector matched more than 50 nodes, the walk reached 10000 nodes or limit cut the matches; requires indexing.",
+        inputSchema: toJsonSchema(GraphQuerySchema),
+      },
+      {
+        name: "detect_cycles",
+        description:
+          "Use when: you need to find circular dependencies between modules or packages, e.g. as a CI gate. Typical flow: index → detect_cycles(granularity) → get_entity_source on the listed import sites to break the cycle. Output: deterministic list of strongly connected components (Go packages by import path, TS/JS and Python files or directories, linked by the files and packages their imports resolve to), each with one ordered cycle and the import statements forming it, plus hasCycles; requires indexing.",
+        inputSchema: toJsonSchema(DetectCyclesSchema),
+      },
+      {
+        name: "module_dependencies",
+        description:
+          "Use when: you review architecture and need which modules depend on which, rather than individual symbols. Typical flow: module_dependencies(granularity=directory) → detect_cycles on the cyclic parts → list_module_importers or find_references for a heavy edge. Output: modules (Go packages, TS/JS and Python files or directories, and files of other languages with bound imports) with fan-in/fan-out, import edges weighted by the symbol-level edges behind them, and the cycles; format=dot or graphml writes the aggregated graph to a file instead; requires indexing.",
+        inputSchema: toJsonSchema(ModuleDependenciesSchema),
+      },
+      {
+        name: "find_unused",
+        description:
+          "Use when: you are looking for dead code — functions and types nothing calls or references. Typical flow: find_unused(directory, roots) → find_references(symbol) to double-check → get_entity_source before deleting. Output: unreferenced entities sorted by file and line, plus how many were kept as roots (exported API, main/init, tests, HTTP handlers, rootNames); reads stored edges only, requires indexing.",
+        inputSchema: toJsonSchema(FindUnusedSchema),
+      },
+      {
+        name: "find_unused_imports",
+        description:
+          "Use when: cleaning up a file or directory and you want imports nothing uses. Typical flow: find_unused_imports(directory) → remove the reported names → reindex_file. Output: unused imported names with file, import (module or package), kind and line, sorted by file and line, plus counts of names checked, side-effect imports (`import \"./styles.css\"`, Go `_` imports; never reported) and imports that cannot be checked (wildcards, Go dot imports, C includes, C# usings). A name is used when an edge from its file calls, references, extends or decorates it, or calls through it (`pkg.Func()`); with checkSource (default) a name the file still mentions outside its imports is kept too, since the graph has no edges for type annotations and passed-around values. Covers JS/TS, Python, Rust and Go; default and namespace JS/TS imports need an index built by this version. Requires indexing.",
+        inputSchema: toJsonSchema(FindUnusedImportsSchema),
+      },
+      {
+        name: "browse",
+        description:
+          "Use when: you are orienting in an unfamiliar repository and want to walk it by package, namespace or directory instead of searching flat symbols. Typical flow: browse() → browse(path of a subpackage) → get_entity_source on a symbol. Output: the container (kind go_package, python_package, directory or module, path and qualified name: Go import path, dotted Python package or path relative to the root) and its immediate children: subpackages and subdirectories first with their child counts, then the top-level declarations of its files with file and line. A file path lists that module's top-level declarations. Containers are built after each index run; requires indexing.",
+        inputSchema: toJsonSchema(BrowseSchema),
+      },
+      {
+        name: "list_complex_functions",
+        description:
+          "Use when: you want to prioritise review or refactoring on the most branching-heavy functions. Typical flow: list_complex_functions(threshold) → get_entity_source on the top entries → suggest_refactoring. Output: functions and methods at or above the threshold, most complex first, with cyclomatic complexity, file and line range; counted at parse time for TypeScript/JavaScript, Go and Python, requires indexing.",
+        inputSchema: toJsonSchema(ListComplexFunctionsSchema),
+      },
+      {
+        name: "snapshot_graph",
+        description:
+          "Use when: you want to keep the current index state to compare against later, e.g. before and after checking out another git revision. Typical flow: index → snapshot_graph(label=base sha) → checkout + index → snapshot_graph(label=head sha) → diff_graph. Output: the stored snapshot with entity and relationship counts, or the snapshot list; action=delete drops one. With vectors (default) the entities' search vectors are kept too (vectorCount, and vectorSource naming the embedding model), so semantic_search and query can search the snapshot later with `snapshot`; warning semantic_disabled or vectors_unavailable means it was stored without them and can only be searched by name.",
+        inputSchema: toJsonSchema(SnapshotGraphSchema),
+      },
+      {
+        name: "diff_graph",
+        description:
+          "Use when: you need to know how the code graph changed between two revisions, for PR review or change summaries. Typical flow: snapshot_graph for each revision → diff_graph(from, to) → get_entity_source on modified entities. Output: added, removed and modified entities (signature or body changed under the same stable id) and added/removed relationships, grouped by file with totals.",
+        inputSchema: toJsonSchema(DiffGraphSchema),
+      },
+      {
+        name: "inheritance_hierarchy",
+        description:
+          "Use when: you need the type hierarchy of a class, interface or struct before changing it. Typical flow: find_definition → inheritance_hierarchy(symbol, direction) → get_entity_source on a base or subtype. Output: ancestor and descendant trees (transitive) over extends, implements and Go embedding/interface satisfaction, each node with file and line; diamonds and multiple inheritance are reported and repeated types are not expanded twice; requires indexing.",
+        inputSchema: toJsonSchema(InheritanceHierarchySchema),
+      },
+      {
+        name: "find_overrides",
+        description:
+          "Use when: you are changing a base or interface method and need every implementation that polymorphic dispatch may reach. Typical flow: inheritance_hierarchy(type) → find_overrides(Type.method) → get_entity_source on the overriding methods. Output: overriding methods (transitive, with owning type, depth, file and line) and the base methods the definition itself overrides; given a type, each of its methods with override counts and the never-overridden ones. Signatures match exactly for Go, by parameters for Java/C#/Kotlin/C++ and by name for TS/JS/Python; requires indexing.",
+        inputSchema: toJsonSchema(FindOverridesSchema),
+      },
+      {
+        name: "list_members",
+        description:
+          "Use when: a search surfaced a class, struct or interface and you need what it declares without reading the file. Typical flow: semantic_search → list_members(Type) → get_entity_source on a member. Output: per matching type, its methods, fields/properties and nested types with kind, signature, file and line range, sorted by position, plus counts per kind; for Go structs every method with that receiver in the package, each marked `receiver: value` or `pointer`. Members come from `contains` edges, Go receivers and the type's own line range; requires indexing.",
+        inputSchema: toJsonSchema(ListMembersSchema),
+      },
+      {
+        name: "list_routes",
+        description:
+          "Use when: you need the HTTP surface of a service, or the code behind an endpoint. Typical flow: list_routes(path or method) → get_entity_source on a handler → list_callees from it. Output: endpoints ordered by path with method, framework, declaring file and line, router or controller, and the handler function (resolved to its declaration when indexed), plus counts by method and framework. Recognises Express/Koa/Fastify/Hono router calls, NestJS controller decorators, Flask/FastAPI route decorators and Go net/http, gorilla, chi, gin and echo registrations; gRPC methods of indexed .proto services are listed as method RPC, framework grpc, path /package.Service/Method, handled by the rpc declaration; requires indexing.",
+        inputSchema: toJsonSchema(ListRoutesSchema),
+      },
+      {
+        name: "find_by_author",
+        description:
+          "Use when: you want to know who owns or last touched code, find a reviewer for a change, or see what someone changed recently. Typical flow: index(gitBlame: true) → find_by_author(author) → get_entity_source on an entity. Output: entities with their last-modifying commit, author, email, date and summary (the newest commit among the entity's lines), plus a per-author summary. Filters by directory, kinds and date range; sorts by recency or author. Requires an index built with git blame enabled (index gitBlame or indexer.gitBlame); returns nothing for directories that are not git repositories.",
+        inputSchema: toJsonSchema(FindByAuthorSchema),
+      },
+      {
+        name: "find_by_signature",
+        description:
+          "Use when: you know the shape of the function you need rather than its name, e.g. everything that fits an interface method or a handler type. Typical flow: find_by_signature(\"func(context.Context, string) (*User, error)\") → get_entity_source on a match → find_references. Output: functions and methods whose parameter and result types match position by position, with their normalized types and signature. Types compare without package or namespace qualifiers, so *User matches *models.User under any import alias; receivers (Go receivers, self, this) are not parameters. Parameters or results without a declared type (plain Python or JavaScript) match any type and the match is marked partial; requireTypes drops those. Requires indexing.",
+        inputSchema: toJsonSchema(FindBySignatureSchema),
+      },
+      {
+        name: "list_env_vars",
+        description:
+          "Use when: you audit configuration, write deployment docs or onboard onto a service and need every environment variable the code reads. Typical flow: list_env_vars() → get_entity_source on a reader → list_env_vars(name prefix) to compare services. Output: variables by name, each read with file, line, accessor (process.env, import.meta.env, os.Getenv, os.environ, os.getenv, ...), any default given at the read, the string constant the key came through and the function reading it; reads whose key is computed at run time are listed as unresolved with their key expression. Covers JS/TS, Go and Python; requires indexing.",
+        inputSchema: toJsonSchema(ListEnvVarsSchema),
+      },
+      {
+        name: "list_tables",
+        description:
+          "Use when: you need the database schema behind the code — which tables exist, their columns and keys, and how they reference each other — e.g. before writing a query, a migration or a data-access change. Typical flow: list_tables(name) → get_entity_source on a table → find_references on it for the views and routines using it. Output: tables and views from indexed .sql files (CREATE TABLE/VIEW, Postgres and MySQL dialects) by schema-qualified name, each with file, line, documentation (leading comments, COMMENT ON, MySQL COMMENT), columns with type, nullability, default and primary-key flag, foreign keys with their referenced table resolved, foreign keys of other tables pointing at it, and files whose ALTER TABLE statements add to it. Table and column names are indexed entities, so semantic_search finds them too; requires indexing.",
+        inputSchema: toJsonSchema(ListTablesSchema),
+      },
+      {
+        name: "list_package_init",
+        description:
+          "Use when: you need to know what runs when a Go package is loaded — startup order, registrations through blank imports, hidden coupling between package-level state — e.g. before moving a var, adding an init or removing an import. Typical flow: list_package_init(package) → get_entity_source on an init function or initializer → list_package_init on a blank-imported package. Output: the package's files, its imports (initialized first, in import path order) with blank `import _` ones marked sideEffect and, when indexed, how many initializers and init functions they bring; package-level variables with an initializer in the order Go initializes them, each with the variables it depends on (directly or through the package functions it calls) and the calls it makes; and init functions in run order, file by file, with their calls. _test.go files are left out; requires indexing.",
+        inputSchema: toJsonSchema(ListPackageInitSchema),
+      },
+      {
+        name: "public_api",
+        description:
+          "Use when: you need what a package or module exposes — before changing or deprecating an API, or reviewing a library's surface. Typical flow: public_api(target) → find_references on a declaration → get_entity_source. Output: exported declarations grouped by file with id, fqn, kind, signature, lines and owner, plus total and truncated; requires indexing.",
+        inputSchema: toJsonSchema(PublicApiSchema),
+      },
+      {
+        name: "list_todos",
+        description:
+          "Use when: you look for known tech debt, unfinished work or workarounds before changing an area, or plan cleanup. Typical flow: list_todos(directory) → get_entity_source on a marker's enclosing declaration → list_todos(kinds: [\"FIXME\"]) to triage. Output: TODO/FIXME/HACK/XXX markers in file and line order, each with its text, any assignee from TODO(name), file, line, the comment entity and the innermost declaration around it; counts per marker kind. Comments are indexed as `comment` entities (keyword-searchable; embedded for semantic_search only with parser.comments.embed); requires indexing.",
+        inputSchema: toJsonSchema(ListTodosSchema),
+      },
+      {
+        name: "tech_debt_report",
+        description:
+          "Use when: you prioritize cleanup and want to know which TODO/FIXME/HACK/XXX markers are oldest and who owns them. Typical flow: tech_debt_report(directory) → list_todos/get_entity_source on the oldest markers → find_by_author for an owner's other code. Output: markers oldest first, each with the commit, author and date git blame gives its line and its age in days; per-author and per-directory counts with oldest and average ages. Blame runs on the files at report time; markers git cannot date fall back to index-time blame (gitBlame: true) or stay undated. Requires indexing.",
+        inputSchema: toJsonSchema(TechDebtReportSchema),
+      },
+      {
+        name: "find_tests_for",
+        description:
+          "Use when: you are about to change a function, method or type and want the tests that exercise it, to run them or to judge how well the change is covered. Typical flow: find_tests_for(symbol) → get_entity_source on the tests → depth=2 when nothing tests it directly. Output: per definition, the covering tests (Go TestXxx/BenchmarkXxx, pytest test_*, JUnit @Test, Jest/Vitest/Mocha it/test cases) with framework, describe suite, file and the line reaching the symbol, the edge it came from and any test helper in between; at depth > 1 also tests of its callers, with the caller they go through. Requires indexing; an empty list means no indexed test reaches the symbol.",
+        inputSchema: toJsonSchema(FindTestsForSchema),
+      },
+      {
+        name: "find_tested_by",
+        description:
+          "Use when: you have a test (failing, slow or new) and need the production code it exercises. Typical flow: find_tested_by(test or test file) → get_entity_source on a covered symbol → find_tests_for(symbol) for its other tests. Output: each matching test with framework and suite, and the declarations outside test files it calls, references or renders (directly or through test helpers) with the line in the test. Requires indexing.",
+        inputSchema: toJsonSchema(FindTestedBySchema),
+      },
+      {
+        name: "batch",
+        description:
+          "Use when: you need several lookups at once, e.g. find_definition + list_callers + list_callees of one symbol, and want one round trip. Typical flow: resolve_entity → batch([{tool, args}, ...]). Output: `results` in request order, each the sub-tool's own envelope (success/data/warnings or errorType/error) plus its index, tool and id; `succeeded`/`failed` counts. Read-only tools run concurrently (up to `concurrency`); indexing and other writing tools run one at a time in their position, after the requests before them. A failed sub-request fails only its item (warning partial_failure). Each sub-request runs under its own tool deadline; batch cannot be nested.",
+        inputSchema: toJsonSchema(BatchSchema),
+      },
+      {
+        name: "get_task_result",
+        description:
+          "Use when: a response carried `status: \"queued\"` and a `taskId` because the agent it needed was not available. Typical flow: index/clean_index → get_task_result(taskId) until status is completed, failed or cancelled. Output: the subtask status (queued, running, completed, failed, cancelled) with its result or error once it has run.",
+        inputSchema: toJsonSchema(GetTaskResultSchema),
+      },
+      {
+        name: "get_index_progress",
+        description:
+          "Use when: an index, clean_index or update_index call is still running and you want to know how far it got or whether it is stuck. Typical flow: start index → get_index_progress() from another call (or pass a progressToken to receive notifications/progress) → cancel_task(taskId) if it stalls. Output: taskId, phase (discovering, parsing, resolving, embedding, completed, failed, cancelled), filesProcessed/totalFiles, percent, filesPerSecond and etaMs estimated from the throughput so far; the last 50 runs are kept.",
+        inputSchema: toJsonSchema(GetIndexProgressSchema),
+      },
+      {
+        name: "cancel_task",
+        description:
+          "Use when: an index, clean_index, batch_index or update_index run is taking too long, or a queued subtask is no longer wanted. Typical flow: get_agent_metrics (currentTaskId of the conductor) or a queued response's taskId → cancel_task(taskId) → get_task_result(taskId) shows cancelled. Output: whether the task was cancelled and its status; a queued subtask is dropped at once, running work stops at its next batch and the original call fails with error type `cancelled`. Files already stored stay indexed.",
+        inputSchema: toJsonSchema(CancelTaskSchema),
+      },
+      {
+        name: "export_index",
+        description:
+          "Use when: you want to build the index once (e.g. on CI) and share it instead of re-indexing on every machine. Typical flow: index → export_index(path) → copy the file → import_index(path) elsewhere. Output: archive path and size plus its manifest: schema version, source directory, repository URL (credentials removed) and commit of the last completed index run, embedding provider/model, row counts and the SHA-256 of each packaged database. The archive holds a consistent copy of the graph database, vectors included; the server keeps answering while it is written. Waits for a running index run to finish first.",
+        inputSchema: toJsonSchema(ExportIndexSchema),
+      },
+      {
+        name: "import_index",
+        description:
+          "Use when: you received an archive from export_index and want its index here without indexing. Typical flow: import_index(path) → index_status → update_index for anything changed since the archived commit. Output: the archive manifest, how many stored paths were moved from the exporting machine's directory to `directory`, and whether the vectors came along. Every file is checked against its SHA-256 and SQLite's quick check before the local index is replaced; errors `checksum_mismatch`, `corrupt_database` and `invalid_archive` leave it untouched, and `incompatible_schema` means the archive comes from a newer server that must be installed first (older archives are migrated). Warnings: source_commit_differs (local HEAD is not the archived commit), embedding_model_mismatch (vectors from another model are dropped; index again to embed), vectors_not_imported. Replaces the current index; waits for a running index run to finish first.",
+        inputSchema: toJsonSchema(ImportIndexSchema),
+      },
+      {
+        name: "compact_index",
+        description:
+          "Use when: the database file (or its -wal file) has grown well past what the indexed code needs, typically after many update_index runs or a reindex that replaced most entities. Typical flow: get_graph_stats → compact_index() → get_graph_stats. Output: file bytes before and after, the write-ahead log size folded back, expired query-cache rows purged and deleted vector-index nodes dropped. Queued behind a running index run; VACUUM holds the write lock, so other tools wait until it finishes.",
+        inputSchema: toJsonSchema(CompactIndexSchema),
+      },
+      {
+        name: "verify_index",
+        description:
+          "Use when: an index run was interrupted or crashed, or tools return edges to missing entities or search hits that cannot be resolved, and you want a recovery path short of clean_index. Typical flow: verify_index() → verify_index(repair: true) if problems are listed → get_graph_health. Output: ok, problems (one line per failed check), dangling relationships (an endpoint id no entity is stored under) with a sample naming the missing end, orphaned vectors (embedded for an entity that is gone; null when semantic search is off) and the schema version against the one this server writes. repair deletes dangling relationships and orphaned vectors and reports how many were removed; a schema mismatch is only reported.",
+        inputSchema: toJsonSchema(VerifyIndexSchema),
+      },
+      {
+        name: "reembed",
+        description:
+          "Use when: the embedding provider, model or chunking settings changed and the vectors need regenerating, but the code did not; much faster than clean_index + index on a large repository because nothing is parsed again. Typical flow: change mcp.embedding or chunking → restart → reembed() → semantic_search. Output: entities embedded, vectors stored, the dimension before and after and the provider/model now recorded as the store's source, so reindex_required clears. All vectors are dropped and rebuilt from the indexed entities; texts the embedding cache already holds for the active model are not sent to the provider again. Reports MCP progress notifications when the call has a progressToken; a cancelled run keeps the vectors embedded so far, and running reembed again completes them. The converse is index(embed: false): re-parse after an extractor change without touching the vectors. Fails with semantic_disabled while semantic search is off. Queued behind a running index run.",
+        inputSchema: toJsonSchema(ReembedSchema),
+      },
       {
         name: "analyze_code_impact",
         description:
@@ -1325,9 +3140,15 @@ server.setRequestHandler(ListToolsRequestSchema as any, async () => {
       {
         name: "get_graph_stats",
         description:
-          "Use when: you need counts/summary stats for the indexed graph. Typical flow: get_graph_stats → get_graph_health if counts look suspicious. Output: counts and DB metrics; requires indexing.",
+          "Use when: you need counts/summary stats for the indexed graph. Typical flow: get_graph_stats → graph_stats or get_graph_health if counts look suspicious. Output: counts by entity and relationship type; requires indexing.",
         inputSchema: toJsonSchema(GetGraphStatsSchema),
       },
+      {
+        name: "graph_stats",
+        description:
+          "Use when: you want to confirm an index is healthy, e.g. after an index run reported 0 entities or searches come back empty. Typical flow: index → graph_stats → fix excludes/grammars (diagnose) or re-run index. Output: entities by kind and language, relationships by type with unresolved ones (external placeholders, dangling ids) counted apart, files indexed, embedding coverage (null while semantic search is off), database size on disk, last update time, warnings for readings that point at extraction gaps, and `server`: state (idle/indexing/querying), the running write, queued writes, active reads and index runs of other server processes on the same database.",
+        inputSchema: toJsonSchema(GraphStatsSchema),
+      },
       {
         name: "lerna_project_graph",
         description:
@@ -1343,15 +3164,51 @@ server.setRequestHandler(ListToolsRequestSchema as any, async () => {
       {
         name: "clean_index",
         description:
-          "Use when: you want a guaranteed clean rebuild (reset + full index). Typical flow: clean_index → query/semantic_search. Output: indexing result; may time out on strict clients—use batch_index if needed.",
+          "Use when: you want a guaranteed clean rebuild (reset + full index). Typical flow: clean_index → query/semantic_search. languages rebuilds a graph of only those languages' files. Output: indexing result, with the requested and included languages when restricted; may time out on strict clients—use batch_index if needed.",
         inputSchema: toJsonSchema(CleanIndexSchema),
       },
+      {
+        name: "update_index",
+        description:
+          "Use when: the repo was indexed before and you want to pick up edits quickly. Re-parses only files whose content hash changed (plus files with edges into them) and drops deleted files. Typical flow: index once → update_index after edits → query/semantic_search. Output: filesReparsed, filesSkipped, entitiesDeleted and indexing counts.",
+        inputSchema: toJsonSchema(UpdateIndexSchema),
+      },
+      {
+        name: "index_status",
+        description:
+          "Use when: results may be out of date—after a pull, branch switch or edits—and you want to know before trusting search or graph answers. Typical flow: index_status → update_index if stale → query/semantic_search. Output: stale flag, counts and the list of files changed, added or deleted since they were indexed (mtime first, confirmed by content hash), the git HEAD the last completed index started from next to the current HEAD with the commits in between, the last index run, and a suggestion to run update_index. Read-only; nothing is re-indexed.",
+        inputSchema: toJsonSchema(IndexStatusSchema),
+      },
+      {
+        name: "reindex_file",
+        description:
+          "Use when: you just edited one file and want the graph to reflect it right away. Re-parses that file even if its hash is unchanged, upserts its entities, drops the ones it no longer declares, re-resolves its edges and re-embeds only changed entities; files that import it are re-parsed when declarations appear or disappear. A deleted file is removed from the graph. Paths outside the indexed roots are rejected. Typical flow: edit → reindex_file → query/list_file_entities. Output: entities (added/modified/removed lists, unchanged count), changes, reboundFiles.",
+        inputSchema: toJsonSchema(ReindexFileSchema),
+      },
+      {
+        name: "start_watch",
+        description:
+          "Use when: you are editing the repo and want the graph to follow along without calling update_index. Watches the directory, waits for bursts of saves to settle, then runs the same hash-based incremental update. Typical flow: index once → start_watch → edit/query → stop_watch. Output: watcher status (directory, pending, batches).",
+        inputSchema: toJsonSchema(StartWatchSchema),
+      },
+      {
+        name: "stop_watch",
+        description:
+          "Use when: live re-indexing is no longer wanted (large refactor, branch switch). Typical flow: stop_watch → update_index when done. Output: final watcher status, or stopped=false if no watcher was running.",
+        inputSchema: toJsonSchema(z.object({})),
+      },
       {
         name: "get_graph_health",
         description:
-          "Use when: you need to verify DB health (counts + sample read). Typical flow: get_graph_health → if unhealthy, clean_index/reset_graph. Output: health status, totals, and sample verification.",
+          "Use when: you need to verify DB health (counts + sample read). Typical flow: get_graph_health → if unhealthy, clean_index/reset_graph. Output: health status, totals, sample verification, the index schema version next to the newest this release migrates to, the last index run, and what startup repaired after an interrupted index (dropped half-written files; stale files to re-index with update_index).",
         inputSchema: toJsonSchema(GetGraphHealthSchema),
       },
+      {
+        name: "export_graph",
+        description:
+          "Use when: you want to open the indexed graph in Gephi/yEd (format=graphml), load it into Neo4j (format=cypher), render it with Graphviz (format=dot) or process it in a script (format=json: {nodes, edges}, one per line). Streams every entity (kind, language, file, line range, qualified name) as a node and every relationship as a typed edge to a file; ids are stable so repeated exports diff cleanly, and the Cypher output MERGEs on qualifiedName so re-import is idempotent. Typical flow: index → export_graph(format, outputPath) → open the file or cypher-shell -f it. Rows are read from paged cursors in id order and written straight to the file, so memory stays flat on graphs of any size and output is deterministic; pass a progressToken to receive notifications/progress with node and edge counts. Output: path, node/edge counts and bytes written (not the graph itself).",
+        inputSchema: toJsonSchema(ExportGraphSchema),
+      },
       {
         name: "get_agent_metrics",
         description:
@@ -1374,7 +3231,132 @@ server.setRequestHandler(ListToolsRequestSchema as any, async () => {
   };
 });
 
-async function executeToolCall(name: string, args: unknown, requestId: string, startTime: number) {
+// Tools that write to the index; they run one at a time so their rows and locks never interleave
+const WRITE_TOOLS: ReadonlySet<string> = new Set([
+  ...INDEXING_TOOLS,
+  "compact_index",
+  "verify_index",
+  "import_index",
+  "export_index",
+]);
+const FOREIGN_RUN_POLL_MS = 500;
+
+/** Hold off while a second server process on the same database is in the middle of an index run */
+async function waitForForeignIndexRuns(signal?: AbortSignal): Promise<void> {
+  if (!ConfigLoader.getInstance().isWaitingForOtherServers() || !globalSQLiteManager) return;
+  const storage = await getGraphStorage(globalSQLiteManager);
+  while ((await storage.listForeignIndexRuns()).length > 0) {
+    throwIfCancelled(signal);
+    await new Promise((resolve) => setTimeout(resolve, FOREIGN_RUN_POLL_MS));
+  }
+}
+
+/** Success, error type and cache use of a tool response, read off the start and end of its JSON */
+function responseOutcome(response: unknown): ToolOutcome {
+  const text = (response as { content?: Array<{ text?: unknown }> })?.content?.[0]?.text;
+  if (typeof text !== "string") return { success: true };
+  const head = text.slice(0, 200);
+  if (/^\{\s*"success": false/.test(head)) {
+    return { success: false, errorType: /"errorType": "([^"]+)"/.exec(head)?.[1] };
+  }
+  // meta, holding the cache flag, is the last field but for warnings
+  return { success: true, cached: text.slice(-400).includes('"cached": true') };
+}
+
+/** Run a tool call and count it, its latency and its outcome in the session metrics */
+async function executeToolCall(
+  name: string,
+  args: unknown,
+  requestId: string,
+  startTime: number,
+  call: ToolCallContext = {},
+) {
+  const write = WRITE_TOOLS.has(name);
+  try {
+    const response = await scheduleToolCall(name, args, requestId, startTime, call);
+    sessionMetrics.recordToolCall(name, Date.now() - startTime, { ...responseOutcome(response), write });
+    return response;
+  } catch (error) {
+    sessionMetrics.recordToolCall(name, Date.now() - startTime, { success: false, errorType: "tool_error", write });
+    throw error;
+  }
+}
+
+/**
+ * Run a tool call as a read or, for tools in WRITE_TOOLS, behind every earlier write. A write that
+ * has to wait is queued rather than refused; its deadline starts once it gets to run.
+ */
+async function scheduleToolCall(
+  name: string,
+  args: unknown,
+  requestId: string,
+  startTime: number,
+  call: ToolCallContext = {},
+) {
+  // A batch is neither: each of its requests is queued or counted on its own
+  if (name === "batch") return runWithDeadline(name, args, requestId, startTime, call);
+  try {
+    if (!WRITE_TOOLS.has(name)) {
+      return await serverActivity.read(name, () => runWithDeadline(name, args, requestId, startTime, call));
+    }
+    return await serverActivity.write(
+      name,
+      async () => {
+        await waitForForeignIndexRuns(call.signal);
+        return runWithDeadline(name, args, requestId, startTime, call);
+      },
+      call.signal,
+    );
+  } catch (error) {
+    // Only the write queue throws here: a full queue, or a call given up while it waited
+    if (error instanceof AgentBusyError) {
+      return asMcpJson(toolFail("agent_busy", error.message, error.details, toolMeta(requestId, startTime)));
+    }
+    if (!(error instanceof TaskCancelledError)) throw error;
+    return asMcpJson(toolFail("cancelled", error.message, error.details, toolMeta(requestId, startTime)));
+  }
+}
+
+/**
+ * Run a tool under its configured deadline (`mcp.timeouts`). On expiry the client gets a `timeout`
+ * error and the call's signal is aborted so agent work stops; indexing tools handle their own
+ * deadline so they can report what was stored before the run stopped.
+ */
+async function runWithDeadline(
+  name: string,
+  args: unknown,
+  requestId: string,
+  startTime: number,
+  call: ToolCallContext = {},
+) {
+  // A batch has no deadline of its own; each of its requests gets the deadline of its tool
+  const timeoutMs =
+    INDEXING_TOOLS.has(name) || name === "batch" ? 0 : ConfigLoader.getInstance().getToolTimeoutMs(name);
+  if (timeoutMs === 0) return dispatchToolCall(name, args, requestId, startTime, call);
+
+  const controller = linkedAbortController(call.signal, () => new TaskCancelledError({ reason: "client" }));
+  try {
+    return await withTimeout(
+      dispatchToolCall(name, args, requestId, startTime, { ...call, signal: controller.signal }),
+      timeoutMs,
+      name,
+      requestId,
+      () => controller.abort(new TaskCancelledError({ reason: "timeout" })),
+    );
+  } catch (error) {
+    // dispatchToolCall turns its own errors into responses; only the deadline ends up here
+    if (!(error instanceof ToolTimeoutError)) throw error;
+    return asMcpJson(toolFail("timeout", error.message, error.details, toolMeta(requestId, startTime)));
+  }
+}
+
+async function dispatchToolCall(
+  name: string,
+  args: unknown,
+  requestId: string,
+  startTime: number,
+  call: ToolCallContext = {},
+) {
   try {
     if (name === "get_version") {
       const versionInfo = getVersionInfo();
@@ -1416,16 +3398,128 @@ async function executeToolCall(name: string, args: unknown, requestId: string, s
       );
     }
 
-    await ensureRuntimeInitialized();
+    if (name === "diagnose") {
+      const grammars = diagnoseGrammars();
+      const failed = grammars.filter((g) => !g.loaded);
 
-    {
+      return asMcpJson(
+        toolOk(
+          {
+            ok: failed.length === 0,
+            runtime: {
+              nodeVersion: process.version,
+              moduleAbi: process.versions.modules,
+              platform: process.platform,
+              arch: process.arch,
+            },
+            grammars,
+          },
+          toolMeta(requestId, startTime),
+          failed.map((g) => `${g.language}: ${g.error}`),
+        ),
+      );
+    }
+
+    await ensureRuntimeInitialized();
+
+    {
       const config = getConfigOrThrow();
       const globalSQLiteManager = getSQLiteManagerOrThrow();
 
       switch (name) {
         case "index": {
-          const { directory: indexDir, incremental, excludePatterns, reset, fullScan } = IndexToolSchema.parse(args);
-          const targetDir = indexDir || directory;
+          const {
+            directory: indexDir,
+            roots: rootArgs,
+            incremental,
+            excludePatterns: excludeArg,
+            respectGitignore: gitignoreArg,
+            reset,
+            fullScan,
+            gitBlame: gitBlameArg,
+            repository: repositoryArg,
+            ref,
+            languages: languagesArg,
+            embed,
+          } = IndexToolSchema.parse(args);
+          const roots = rootArgs?.length ? Array.from(new Set(rootArgs.map((root) => normalizeInputPath(root)))) : [];
+          const missingRoot = roots.find((root) => !statSync(root, { throwIfNoEntry: false })?.isDirectory());
+          if (missingRoot) {
+            return asMcpJson(
+              toolFail(
+                "invalid_args",
+                `Root is not a directory: ${missingRoot}`,
+                { roots },
+                toolMeta(requestId, startTime),
+              ),
+            );
+          }
+          const repository = repositoryArg?.trim() || (indexDir && isGitUrl(indexDir) ? indexDir.trim() : undefined);
+          if (repository && roots.length > 0) {
+            return asMcpJson(
+              toolFail(
+                "invalid_args",
+                "Pass either repository or roots, not both",
+                { repository: redactGitUrl(repository), roots },
+                toolMeta(requestId, startTime),
+              ),
+            );
+          }
+          if (repository && ref && !(await isValidGitRef(ref))) {
+            return asMcpJson(
+              toolFail(
+                "invalid_args",
+                "ref must be a branch, tag or full commit hash",
+                { repository: redactGitUrl(repository), ref },
+                toolMeta(requestId, startTime),
+              ),
+            );
+          }
+          let checkout: RemoteCheckout | null = null;
+          if (repository) {
+            try {
+              checkout = await checkoutRemote(repository, {
+                cacheDir: ConfigLoader.getInstance().getRepoCacheDir(),
+                ref,
+                signal: call.signal,
+              });
+            } catch (error) {
+              if (!(error instanceof RemoteCheckoutError)) throw error;
+              return asMcpJson(toolFail("clone_failed", error.message, error.details, toolMeta(requestId, startTime)));
+            }
+            logger.systemEvent("Repository checked out for indexing", { ...checkout });
+          }
+          const targetDir = checkout?.directory ?? roots[0] ?? (indexDir || directory);
+
+          // The indexed repository's own defaults; arguments of the call still win
+          let repoConfig: LoadedRepoConfig | null;
+          try {
+            repoConfig = loadRepoConfig(targetDir);
+          } catch (error) {
+            if (!(error instanceof RepoConfigError)) throw error;
+            return asMcpJson(
+              toolFail(
+                "invalid_args",
+                error.message,
+                { path: error.path, errors: error.errors },
+                toolMeta(requestId, startTime),
+              ),
+            );
+          }
+          const repoIndex = repoConfig?.config.index ?? {};
+          const excludePatterns = excludeArg ?? repoIndex.excludePatterns ?? [...DEFAULT_INDEX_EXCLUDE_PATTERNS];
+          const respectGitignore = gitignoreArg ?? repoIndex.respectGitignore ?? true;
+          const gitBlame = gitBlameArg ?? repoIndex.gitBlame;
+          const languages = languagesArg ?? repoIndex.languages;
+          // Server-wide settings only take effect from the file of the server's root directory
+          const serverWideIgnored =
+            !!repoConfig &&
+            repoConfig.path !== ConfigLoader.getInstance().getRepoConfig()?.path &&
+            (!!repoConfig.config.embedding ||
+              !!repoConfig.config.chunking ||
+              !!repoConfig.config.search ||
+              repoIndex.includeTests !== undefined ||
+              repoIndex.maxFileSizeBytes !== undefined);
 
           // Optional reset
           if (reset) {
@@ -1447,20 +3541,23 @@ async function executeToolCall(name: string, args: unknown, requestId: string, s
           // Check codebase size and add adaptive patterns
           try {
             const { execSync } = await import("node:child_process");
-            const fileCount = execSync(buildFindSourceFileCountCommand(targetDir), { encoding: "utf8" }).trim();
-            const numFiles = parseInt(fileCount, 10);
+            let numFiles = 0;
+            let projectSizeBytes = 0;
+            for (const dir of roots.length > 0 ? roots : [targetDir]) {
+              const fileCount = execSync(buildFindSourceFileCountCommand(dir), { encoding: "utf8" }).trim();
+              numFiles += parseInt(fileCount, 10);
+              projectSizeBytes += parseInt(execSync(`du -sb "${dir}" | cut -f1`, { encoding: "utf8" }).trim(), 10);
+            }
 
             logger.info(
               "INDEXING",
               `Detected ${numFiles} source files in codebase`,
-              { directory: targetDir, fileCount: numFiles },
+              { directory: targetDir, roots: roots.length > 0 ? roots : undefined, fileCount: numFiles },
               requestId,
             );
 
             // Adjust resource allocation based on codebase size
-            const { execSync: execSync2 } = await import("node:child_process");
-            const projectSizeBytes = execSync2(`du -sb "${targetDir}" | cut -f1`, { encoding: "utf8" }).trim();
-            const projectSizeMB = Math.floor(parseInt(projectSizeBytes, 10) / (1024 * 1024));
+            const projectSizeMB = Math.floor(projectSizeBytes / (1024 * 1024));
             resourceManager.adjustForCodebaseSize(numFiles, projectSizeMB);
 
             // For very large codebases (>2000 files), add more aggressive patterns
@@ -1538,9 +3635,15 @@ async function executeToolCall(name: string, args: unknown, requestId: string, s
             priority: 8,
             payload: {
               directory: targetDir,
+              roots: roots.length > 0 ? roots : undefined,
               incremental,
               fullScan,
               excludePatterns: enhancedExcludePatterns,
+              respectGitignore,
+              gitBlame,
+              languages,
+              embed,
+              repository: checkout ? { url: checkout.url, ref: checkout.ref } : undefined,
             },
             createdAt: Date.now(),
           };
@@ -1548,13 +3651,16 @@ async function executeToolCall(name: string, args: unknown, requestId: string, s
           // Process through conductor with mandatory delegation
           const cond = await getConductor();
           await cond.initialize();
-          const configuredTimeout = config.mcp.agents?.defaultTimeout || config.mcp.server?.timeout || 600000;
-          const timeoutMs = isDebugMode ? Math.max(configuredTimeout, 120000) : configuredTimeout;
-          const result = await withTimeout(cond.process(task), timeoutMs, "index", requestId);
-
-          if (process.env.MCP_DEBUG_DISABLE_SEMANTIC !== "1") {
-            await ensureSemanticsReady(1, 5000);
-          }
+          const configuredTimeout = ConfigLoader.getInstance().getToolTimeoutMs("index");
+          const timeoutMs =
+            isDebugMode && configuredTimeout > 0 ? Math.max(configuredTimeout, 120000) : configuredTimeout;
+          const result = await runIndexTask(cond, task, "index", requestId, timeoutMs, call, embed).catch(
+            async (error) => {
+              // A clone made for this call is not worth keeping if it never got indexed
+              if (checkout) await discardCheckout(checkout);
+              throw error;
+            },
+          );
 
           // Log indexing activity
           logger.agentActivity(
@@ -1562,6 +3668,7 @@ async function executeToolCall(name: string, args: unknown, requestId: string, s
             "indexing completed",
             {
               directory: targetDir,
+              roots: roots.length > 0 ? roots : undefined,
               incremental,
               excludePatterns: enhancedExcludePatterns,
               entitiesFound: Array.isArray((result as any)?.entities) ? (result as any).entities.length : 0,
@@ -1575,7 +3682,29 @@ async function executeToolCall(name: string, args: unknown, requestId: string, s
           const duration = Date.now() - startTime;
           logger.mcpResponse(name, result, duration, requestId);
 
-          return asMcpJson(toolOk({ message: "Indexing completed", result }, toolMeta(requestId, startTime)));
+          const blame = collectGitBlame(result);
+          return asMcpJson(
+            toolOk(
+              {
+                message: "Indexing completed",
+                roots: roots.length > 0 ? roots : undefined,
+                repository: checkout ?? undefined,
+                changes: collectIndexChanges(result),
+                skipped: collectSkippedFiles(result),
+                parseErrors: collectParseErrors(result),
+                timing: collectIndexTiming(result),
+                gitBlame: blame,
+                languages: collectIndexLanguages(result),
+                repoConfig: repoConfig?.path ?? null,
+                result,
+              },
+              toolMeta(requestId, startTime),
+              [
+                ...(blame?.reason ? ["git_blame_unavailable"] : []),
+                ...(serverWideIgnored ? ["repo_config_server_settings_ignored"] : []),
+              ],
+            ),
+          );
         }
 
         case "batch_index": {
@@ -1583,6 +3712,7 @@ async function executeToolCall(name: string, args: unknown, requestId: string, s
             sessionId: sessionIdArg,
             directory: indexDir,
             excludePatterns,
+            respectGitignore,
             incremental,
             fullScan,
             reset,
@@ -1648,7 +3778,11 @@ async function executeToolCall(name: string, args: unknown, requestId: string, s
               stats: { batches: 0, attempted: 0, indexed: 0, skipped: 0 },
             };
 
-            const collected = collectIndexableFiles(targetDir, enhancedExcludePatterns);
+            const collected = collectIndexableFiles(targetDir, enhancedExcludePatterns, {
+              respectGitignore,
+              symlinks: ConfigLoader.getInstance().getSymlinkPolicy(),
+              detectLanguageByContent: ConfigLoader.getInstance().isContentLanguageDetectionEnabled(),
+            });
             const files = collected.files;
             meta.totalFiles = files.length;
 
@@ -1742,16 +3876,20 @@ async function executeToolCall(name: string, args: unknown, requestId: string, s
                 incremental: session.meta.incremental,
                 fullScan: session.meta.fullScan,
                 excludePatterns: session.meta.excludePatterns,
+                // Imports may point at files of later batches; bind them all once the last batch is in
+                resolveCrossFile: cursor + batchFiles.length >= total ? "all" : false,
               },
               createdAt: Date.now(),
             };
 
             const cond = await getConductor();
             await cond.initialize();
+            await getDevAgent();
 
-            const configuredTimeout = config.mcp.agents?.defaultTimeout || config.mcp.server?.timeout || 600000;
-            const timeoutMs = Math.min(configuredTimeout, 12000);
-            const result = await withTimeout(cond.process(task), timeoutMs, "batch_index", requestId);
+            // Each call stays short for strict clients, whatever the indexing timeout
+            const configuredTimeout = ConfigLoader.getInstance().getToolTimeoutMs("batch_index");
+            const timeoutMs = configuredTimeout > 0 ? Math.min(configuredTimeout, 12000) : 12000;
+            const result = await runIndexTask(cond, task, "batch_index", requestId, timeoutMs, call, false);
 
             const batchMs = Date.now() - batchStart;
             const filesProcessed = (() => {
@@ -1800,6 +3938,8 @@ async function executeToolCall(name: string, args: unknown, requestId: string, s
                     attempted: batchFiles.length,
                     filesProcessed,
                     skipped,
+                    skippedFiles: collectSkippedFiles(result),
+                    parseErrors: collectParseErrors(result),
                     ms: batchMs,
                   },
                   progress: {
@@ -1839,7 +3979,8 @@ async function executeToolCall(name: string, args: unknown, requestId: string, s
         }
 
         case "clean_index": {
-          const { directory: indexDir, excludePatterns, fullScan } = CleanIndexSchema.parse(args);
+          const { directory: indexDir, excludePatterns, respectGitignore, fullScan, languages } =
+            CleanIndexSchema.parse(args);
           const targetDir = indexDir || directory;
 
           // Reset graph first
@@ -1937,24 +4078,173 @@ async function executeToolCall(name: string, args: unknown, requestId: string, s
               incremental: false,
               fullScan,
               excludePatterns: enhancedExcludePatterns,
+              respectGitignore,
+              languages,
             },
             createdAt: Date.now(),
           };
 
           const cond = await getConductor();
           await cond.initialize();
-          const timeoutMs = config.mcp.agents?.defaultTimeout || config.mcp.server?.timeout || 600000;
-          const result = await withTimeout(cond.process(task), timeoutMs, "clean_index", requestId);
-
-          if (process.env.MCP_DEBUG_DISABLE_SEMANTIC !== "1") {
-            await ensureSemanticsReady(1, 5000);
-          }
+          await getDevAgent();
+          const timeoutMs = ConfigLoader.getInstance().getToolTimeoutMs("clean_index");
+          const result = await runIndexTask(cond, task, "clean_index", requestId, timeoutMs, call);
 
           knowledgeBus.publish("index:completed", result, "mcp-server");
           const duration = Date.now() - startTime;
           logger.mcpResponse(name, result, duration, requestId);
 
-          return asMcpJson(toolOk({ message: "Clean indexing completed", result }, toolMeta(requestId, startTime)));
+          return asMcpJson(
+            toolOk(
+              {
+                message: "Clean indexing completed",
+                skipped: collectSkippedFiles(result),
+                parseErrors: collectParseErrors(result),
+                timing: collectIndexTiming(result),
+                languages: collectIndexLanguages(result),
+                result,
+              },
+              toolMeta(requestId, startTime),
+            ),
+          );
+        }
+
+        case "update_index": {
+          const { directory: indexDir, excludePatterns, respectGitignore } = UpdateIndexSchema.parse(args);
+          const targetDir = indexDir || directory;
+
+          const { result, summary } = await runIncrementalUpdate(
+            targetDir,
+            excludePatterns,
+            requestId,
+            undefined,
+            respectGitignore,
+            call,
+          );
+          logger.mcpResponse(name, result, Date.now() - startTime, requestId);
+
+          return asMcpJson(
+            toolOk({ message: "Incremental update completed", ...summary, result }, toolMeta(requestId, startTime)),
+          );
+        }
+
+        case "index_status": {
+          const { directory: indexDir, excludePatterns, respectGitignore, limit } = IndexStatusSchema.parse(args ?? {});
+          const targetDir = indexDir ? normalizeInputPath(indexDir) : directory;
+          const configLoader = ConfigLoader.getInstance();
+          const maxFileSizeBytes = configLoader.getIndexMaxFileSizeBytes();
+          // Same discovery as indexing, so files indexing would skip are not reported as added
+          const discovered = collectIndexableFiles(
+            targetDir,
+            mergeUniqueStrings(DEFAULT_INDEX_EXCLUDE_PATTERNS, excludePatterns),
+            {
+              respectGitignore,
+              symlinks: configLoader.getSymlinkPolicy(),
+              detectLanguageByContent: configLoader.isContentLanguageDetectionEnabled(),
+            },
+          ).files.filter(
+            (file) => !checkIndexableContent(file, maxFileSizeBytes, configLoader.isGeneratedFileSkippingEnabled()),
+          );
+
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const status = await indexStatus(storage, { directory: targetDir, discovered, limit });
+          const staleCount = status.counts.changed + status.counts.added + status.counts.deleted;
+          const headMoved = Boolean(status.git?.indexedHead && status.git.indexedHead !== status.git.currentHead);
+
+          const warnings: string[] = [];
+          if (status.stale) warnings.push("index_stale");
+          if (status.truncated) warnings.push("files_truncated");
+          if (status.counts.indexed === 0) warnings.push("not_indexed");
+          return asMcpJson(
+            toolOk(
+              {
+                directory: targetDir,
+                stale: status.stale,
+                counts: { ...status.counts, touched: status.touched },
+                files: status.files.map((file) => ({ ...file, path: normalizeInputPath(file.path) ?? file.path })),
+                git: status.git ? { ...status.git, headMoved } : null,
+                lastIndexRun: status.lastRun,
+                suggestion:
+                  status.counts.indexed === 0
+                    ? "Nothing under this directory is indexed; run index first."
+                    : status.stale
+                      ? `Run update_index to re-index ${staleCount} stale file${staleCount === 1 ? "" : "s"}.`
+                      : null,
+              },
+              toolMeta(requestId, startTime),
+              warnings.length > 0 ? warnings : undefined,
+            ),
+          );
+        }
+
+        case "reindex_file": {
+          const { path } = ReindexFileSchema.parse(args);
+          const filePath = normalizeInputPath(path);
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const recorded = await storage.listIndexRoots();
+          const roots = recorded.length > 0 ? recorded : [normalize(resolve(directory))];
+          const root = rootOf(filePath, roots);
+          if (!root) {
+            return asMcpJson(
+              toolFail(
+                "invalid_args",
+                `${filePath} is outside the indexed roots`,
+                { path: filePath, roots },
+                toolMeta(requestId, startTime),
+              ),
+            );
+          }
+          const exists = statSync(filePath, { throwIfNoEntry: false })?.isFile() ?? false;
+          if (!exists && !(await storage.getFileInfo(filePath))) {
+            return asMcpJson(
+              toolFail(
+                "not_found",
+                `${filePath} does not exist and is not indexed`,
+                { path: filePath },
+                toolMeta(requestId, startTime),
+              ),
+            );
+          }
+
+          const { result, ...outcome } = await reindexFile(filePath, roots, requestId, call);
+          logger.mcpResponse(name, outcome, Date.now() - startTime, requestId);
+
+          return asMcpJson(
+            toolOk(
+              {
+                path: filePath,
+                root,
+                ...outcome,
+                skipped: collectSkippedFiles(result),
+                parseErrors: collectParseErrors(result),
+              },
+              toolMeta(requestId, startTime),
+            ),
+          );
+        }
+
+        case "start_watch": {
+          const { directory: watchDir, excludePatterns, respectGitignore, debounceMs } = StartWatchSchema.parse(args);
+          const targetDir = normalize(resolve(watchDir || directory));
+
+          // Agents are needed by the first batch; start them now so the first save is not slowed down
+          await getDevAgent();
+          const status = startIndexWatcher(targetDir, excludePatterns, debounceMs, respectGitignore);
+          logger.mcpResponse(name, status, Date.now() - startTime, requestId);
+
+          return asMcpJson(toolOk({ message: "Watching for changes", ...status }, toolMeta(requestId, startTime)));
+        }
+
+        case "stop_watch": {
+          const status = stopIndexWatcher();
+          logger.mcpResponse(name, status, Date.now() - startTime, requestId);
+
+          return asMcpJson(
+            toolOk(
+              status ? { stopped: true, ...status, running: false } : { stopped: false, message: "No watcher running" },
+              toolMeta(requestId, startTime),
+            ),
+          );
         }
 
         case "list_file_entities": {
@@ -1997,6 +4287,33 @@ async function executeToolCall(name: string, args: unknown, requestId: string, s
 
           return asMcpJson(toolOk(response, toolMeta(requestId, startTime, { cached: false })));
         }
+        case "file_summary": {
+          const { filePath } = FileSummarySchema.parse(args);
+          const targetFilePath = normalizeInputPath(filePath);
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const summary = await fileSummary(storage, targetFilePath);
+          if (!summary) {
+            return asMcpJson(
+              toolFail(
+                "not_found",
+                `No indexed entities for file: ${targetFilePath}`,
+                { filePath: targetFilePath },
+                toolMeta(requestId, startTime),
+              ),
+            );
+          }
+          const withPath = (file: string) => normalizeInputPath(file);
+          return asMcpJson(
+            toolOk(
+              {
+                ...summary,
+                filePath: withPath(summary.filePath),
+                imports: summary.imports.map((entry) => ({ ...entry, resolved: entry.resolved.map(withPath) })),
+              },
+              toolMeta(requestId, startTime),
+            ),
+          );
+        }
         case "list_entity_relationships": {
           const {
             entityId: directId,
@@ -2080,6 +4397,16 @@ async function executeToolCall(name: string, args: unknown, requestId: string, s
           );
         }
 
+        case "complete_symbol": {
+          const { prefix, kinds, languages, limit } = CompleteSymbolSchema.parse(args);
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const result = await completeSymbol(storage, prefix.trim(), {
+            scope: toSearchScope({ kinds, languages, includeAnonymous: false }),
+            limit,
+          });
+          return asMcpJson(toolOk(result, toolMeta(requestId, startTime)));
+        }
+
         case "get_entity_source": {
           const { entityId, entityName, filePathHint, contextLines, maxBytes } = GetEntitySourceSchema.parse(args);
           const storage = await getGraphStorage(globalSQLiteManager);
@@ -2139,272 +4466,945 @@ async function executeToolCall(name: string, args: unknown, requestId: string, s
           }
         }
 
-        case "query": {
-          const { query, limit, cursor, pageSize } = QueryToolSchema.parse(args);
-          await ensureSemanticsReady(1, 20000);
-          const timeoutMs = config.mcp.agents?.defaultTimeout || config.mcp.server?.timeout || 600000;
-          const effectivePageSize = pageSize ?? limit ?? 10;
-          const cursorState = decodeCursor<{ so?: number; go?: number }>(cursor) ?? {};
-          const semanticOffset = Math.max(0, Number(cursorState.so ?? 0) || 0);
-          const structuralOffset = Math.max(0, Number(cursorState.go ?? 0) || 0);
-
-          let semanticAll: any[] = [];
-          let semanticFailed = false;
-          let semanticProcessingTime: number | undefined;
-
-          try {
-            const semanticAgent = await getSemanticAgent();
-            const fetchLimit = Math.min(500, semanticOffset + effectivePageSize + 1);
-            const semanticResult = await withTimeout(
-              semanticAgent.semanticSearch(query, fetchLimit),
-              timeoutMs,
-              "query:semantic_search",
-              requestId,
-            );
-            semanticAll = Array.isArray(semanticResult) ? semanticResult : ((semanticResult as any)?.results ?? []);
-            semanticProcessingTime = (semanticResult as any)?.processingTime;
-          } catch (error) {
-            logger.warn(
-              "SEMANTIC_QUERY",
-              "Semantic search fallback engaged",
-              { query, error: (error as Error).message },
-              requestId,
+        case "explain_symbol": {
+          const {
+            symbol,
+            entityId,
+            filePath,
+            package: qualifier,
+            entityType,
+            neighbors,
+            maxBytes,
+          } = ExplainSymbolSchema.parse(args);
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const normalizedPath = filePath ? normalizeInputPath(filePath) : undefined;
+          const warnings: string[] = [];
+          const result = await explainSymbol(storage, {
+            symbol,
+            entityId,
+            filePath: normalizedPath,
+            qualifier,
+            entityType,
+            neighbors,
+            maxBytes,
+            resolvePath: (p) => normalizeInputPath(p) ?? p,
+            // No waiting for vectors here: an index without embeddings still explains the graph side
+            findSimilar: async (entity, limit) => {
+              const semanticAgent = await getSemanticAgent();
+              if (semanticAgent.getEmbeddingSource?.()?.fallback) warnings.push("embedding_fallback");
+              const matches: SimilarityResult[] = await semanticAgent.findSimilarEntities(entity, limit);
+              const found: Array<{ entity: Entity; score: number }> = [];
+              for (const match of matches) {
+                const matchId = match.metadata?.entityId;
+                const matched = typeof matchId === "string" ? await storage.getEntity(matchId) : null;
+                if (matched) found.push({ entity: matched, score: match.cosine ?? match.similarity });
+              }
+              return found;
+            },
+          });
+          if (!result) {
+            return asMcpJson(
+              toolFail(
+                "not_found",
+                `Symbol not found: ${entityId ?? symbol ?? ""}`,
+                { symbol: symbol ?? null, entityId: entityId ?? null, filePath: normalizedPath ?? null },
+                toolMeta(requestId, startTime),
+              ),
             );
-            semanticFailed = true;
-            semanticAll = [];
           }
 
-          const storage = await getGraphStorage(globalSQLiteManager);
-          const structuralRaw = await queryGraphEntities(storage, query, effectivePageSize + 1, structuralOffset);
-          const hasMoreStructural = structuralRaw.entities.length > effectivePageSize;
-          const structuralEntitiesPage = hasMoreStructural
-            ? structuralRaw.entities.slice(0, effectivePageSize)
-            : structuralRaw.entities;
-          const structuralFileSet = new Set(
-            structuralEntitiesPage.map((e) => normalizeInputPath(e.filePath) ?? e.filePath).filter(Boolean),
-          );
-          const entityIdSet = new Set(structuralEntitiesPage.map((e) => e.id));
-          const relations = await collectRelationsWithin(storage, entityIdSet);
-
-          const semanticNormalized = rerankSemanticHits(
-            semanticAll,
-            structuralFileSet,
-            (p) => normalizeInputPath(p) ?? p,
-          ).map((ranked) => {
-            const hit: any = ranked.hit as any;
-            const meta: any = hit?.metadata ?? {};
-            const rawPath = String(meta?.path ?? hit?.path ?? hit?.filePath ?? "");
-            const normalizedPath = rawPath ? (normalizeInputPath(rawPath) ?? rawPath) : "";
-            const content = String(hit?.content ?? "");
-            return {
-              id: hit?.id,
-              filePath: normalizedPath || null,
-              score: ranked.rankingSignals.semanticScore,
-              finalScore: ranked.finalScore,
-              excerpt: content ? content.slice(0, 400) : "",
-              metadata: meta ?? null,
-              rankingSignals: ranked.rankingSignals,
-              raw: hit,
-            };
+          const withPath = <T extends { filePath: string }>(item: T): T => ({
+            ...item,
+            filePath: normalizeInputPath(item.filePath) ?? item.filePath,
           });
-
-          const semanticSlice = semanticNormalized.slice(semanticOffset, semanticOffset + effectivePageSize);
-          const hasMoreSemantic = semanticNormalized.length > semanticOffset + effectivePageSize;
-
-          const nextCursor =
-            hasMoreSemantic || hasMoreStructural
-              ? encodeCursor({
-                  so: semanticOffset + (hasMoreSemantic ? effectivePageSize : 0),
-                  go: structuralOffset + (hasMoreStructural ? effectivePageSize : 0),
-                })
-              : null;
+          const mapCall = (call: CallEdge) => ({
+            ...call,
+            entity: call.entity ? withPath(call.entity) : null,
+            callSites: call.callSites.map(withPath),
+            edge: mapLocatedEdge(call.edge),
+          });
+          if (result.source === null) warnings.push("source_unavailable");
+          else if (result.source.truncated) warnings.push("snippet_truncated");
+          if (result.similar === null) warnings.push("semantic_unavailable");
+          if (result.callers.truncated || result.callees.truncated) warnings.push("neighbors_truncated");
 
           return asMcpJson(
             toolOk(
               {
-                semantic: {
-                  items: semanticSlice,
-                  nextCursor,
-                  total: semanticNormalized.length,
-                },
-                structural: {
-                  items: structuralEntitiesPage
-                    .map((entity) => annotateStructuralMatch(mapEntitySummary(entity), query))
-                    .sort(
-                      (a: any, b: any) =>
-                        String(a.filePath).localeCompare(String(b.filePath)) ||
-                        String(a.name).localeCompare(String(b.name)),
-                    ),
-                  nextCursor,
-                  total: structuralRaw.stats.totalEntities,
-                },
-                stats: {
-                  semanticFailed,
-                  semanticProcessingTime,
-                  structural: structuralRaw.stats,
-                  relationsCount: relations.length,
-                },
-                relations,
-                paging: {
-                  cursor: cursor ?? null,
-                  nextCursor,
-                  pageSize: effectivePageSize,
-                  offsets: { semanticOffset, structuralOffset },
-                  hasMoreSemantic,
-                  hasMoreStructural,
-                  semanticFailed,
-                },
+                ...result,
+                symbol: withPath(result.symbol),
+                callers: { ...result.callers, items: result.callers.items.map(mapCall) },
+                callees: { ...result.callees, items: result.callees.items.map(mapCall) },
+                similar: result.similar?.map(withPath) ?? null,
+                alternatives: result.alternatives.map(withPath),
               },
               toolMeta(requestId, startTime),
+              warnings,
             ),
           );
         }
 
-        case "get_metrics": {
-          const resourceStats = resourceManager.getCurrentUsage();
-          const knowledgeStats = knowledgeBus.getStats();
-          const cond = await getConductor();
-          const conductorMetrics = cond.getMetrics();
+        case "find_definition": {
+          const {
+            symbol,
+            filePath,
+            package: qualifier,
+            entityType,
+            contextLines,
+            maxBytes,
+            limit,
+          } = FindDefinitionSchema.parse(args);
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const contextFilePath = filePath ? normalizeInputPath(filePath) : undefined;
+
+          const candidates = await findDefinitionCandidates(storage, {
+            symbol,
+            contextFilePath,
+            qualifier,
+            entityType,
+            limit: limit ?? 5,
+          });
+
+          if (candidates.length === 0) {
+            return asMcpJson(
+              toolFail(
+                "not_found",
+                `Definition not found: ${symbol}`,
+                { symbol, filePath: contextFilePath ?? null, package: qualifier ?? null, entityType: entityType ?? null },
+                toolMeta(requestId, startTime),
+              ),
+            );
+          }
+
+          const warnings = new Set<string>();
+          const definitions = [];
+          for (const candidate of candidates) {
+            const targetFilePath = normalizeInputPath(candidate.entity.filePath) ?? candidate.entity.filePath;
+            let snippet: string | null = null;
+            let snippetRange: { startLine: number; endLine: number } | null = null;
+            try {
+              const extracted = await getEntitySource({
+                storage,
+                entity: candidate.entity,
+                filePath: targetFilePath,
+                contextLines,
+                maxBytes,
+              });
+              snippet = extracted.snippet;
+              snippetRange = extracted.snippetRange;
+              if (extracted.truncated) warnings.add("snippet_truncated");
+            } catch {
+              warnings.add("snippet_unavailable");
+            }
+
+            definitions.push({
+              entity: mapEntitySummary(candidate.entity),
+              filePath: targetFilePath,
+              range: candidate.range,
+              snippetRange,
+              snippet,
+              score: candidate.score,
+              reasons: candidate.reasons,
+            });
+          }
 
           return asMcpJson(
             toolOk(
               {
-                resources: resourceStats,
-                knowledge: knowledgeStats,
-                conductor: conductorMetrics,
-                agents: Array.from(cond.agents.values()).map((agent) => ({
-                  id: agent.id,
-                  type: agent.type,
-                  status: agent.status,
-                  memoryUsage: agent.getMemoryUsage(),
-                  cpuUsage: agent.getCpuUsage(),
-                  queueSize: agent.getTaskQueue().length,
-                })),
+                symbol: symbol.trim(),
+                filePath: contextFilePath ?? null,
+                definitions,
               },
               toolMeta(requestId, startTime),
+              warnings.size > 0 ? Array.from(warnings) : undefined,
             ),
           );
         }
 
-        // New semantic tool handlers - TASK-002
-        case "semantic_search": {
-          const { query, limit, cursor, pageSize } = SemanticSearchSchema.parse(args);
-          await ensureSemanticsReady(1, 20000);
-
-          const effectivePageSize = pageSize ?? limit ?? 10;
-          const cursorState = decodeCursor<{ o?: number }>(cursor) ?? {};
-          const offset = Math.max(0, Number(cursorState.o ?? 0) || 0);
+        case "find_references": {
+          const {
+            symbol,
+            filePath,
+            package: qualifier,
+            entityType,
+            referenceKinds,
+            access,
+            includeUnresolved,
+            limit,
+          } = FindReferencesSchema.parse(args);
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const normalizedPath = filePath ? normalizeInputPath(filePath) : undefined;
+
+          const result = await findReferences(storage, {
+            symbol,
+            filePath: normalizedPath,
+            qualifier,
+            entityType,
+            kinds: referenceKinds,
+            access,
+            includeUnresolved,
+            limit,
+          });
 
-          // Check cache first
-          const cacheKey = `semantic:search:${query}:${effectivePageSize}:${offset}`;
-          const cached = knowledgeBus.query(cacheKey, 1);
-          if (cached.length > 0) {
-            const firstCache = cached[0];
-            if (firstCache && Date.now() - firstCache.timestamp < 30000) {
-              // 30s cache
-              return asMcpJson(toolOk(firstCache.data, toolMeta(requestId, startTime, { cached: true })));
-            }
+          if (result.definitions.length === 0 && result.unresolved.length === 0) {
+            return asMcpJson(
+              toolFail(
+                "not_found",
+                `Symbol not found: ${symbol}`,
+                { symbol, filePath: normalizedPath ?? null, package: qualifier ?? null, entityType: entityType ?? null },
+                toolMeta(requestId, startTime),
+              ),
+            );
           }
 
-          const semanticAgent = await getSemanticAgent();
-          const timeoutMs = config.mcp.agents?.defaultTimeout || config.mcp.server?.timeout || 600000;
-          const fetchLimit = Math.min(500, offset + effectivePageSize + 1);
-          const result = await withTimeout(
-            semanticAgent.semanticSearch(query, fetchLimit),
-            timeoutMs,
-            "semantic_search",
-            requestId,
+          const mapReference = (ref: (typeof result.unresolved)[number]) => ({
+            kind: ref.kind,
+            relationshipType: ref.relationshipType,
+            relationshipId: ref.relationshipId,
+            from: ref.from ? mapEntitySummary(ref.from) : { id: ref.fromId },
+            filePath: ref.filePath ? (normalizeInputPath(ref.filePath) ?? ref.filePath) : null,
+            line: ref.line,
+            range: ref.range,
+            resolved: ref.resolved,
+            ...(ref.access ? { access: ref.access, accessSites: ref.accessSites } : {}),
+            edge: mapLocatedEdge(ref.edge),
+          });
+
+          return asMcpJson(
+            toolOk(
+              {
+                symbol: result.symbol,
+                name: result.name,
+                qualifier: result.qualifier,
+                definitions: result.definitions.map((group) => ({
+                  definition: mapEntitySummary(group.definition),
+                  scope: group.scope,
+                  references: group.references.map(mapReference),
+                })),
+                unresolved: result.unresolved.map(mapReference),
+                stats: {
+                  definitionCount: result.definitions.length,
+                  totalReferences: result.total,
+                },
+              },
+              toolMeta(requestId, startTime),
+              result.truncated ? ["references_truncated"] : undefined,
+            ),
           );
+        }
 
-          // Cache result
-          const all = Array.isArray(result) ? result : ((result as any)?.results ?? []);
-          const items = all.slice(offset, offset + effectivePageSize);
-          const hasMore = all.length > offset + effectivePageSize;
-          const nextCursor = hasMore ? encodeCursor({ o: offset + effectivePageSize }) : null;
+        case "list_callers":
+        case "list_callees": {
+          const {
+            symbol,
+            filePath,
+            package: qualifier,
+            entityType,
+            minConfidence,
+            argument,
+            limit,
+          } = CallGraphSchema.parse(args);
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const normalizedPath = filePath ? normalizeInputPath(filePath) : undefined;
+          const options = { symbol, filePath: normalizedPath, qualifier, entityType, minConfidence, argument, limit };
+          const result =
+            name === "list_callers" ? await listCallers(storage, options) : await listCallees(storage, options);
 
-          const payload = {
-            query,
-            items,
-            page: { offset, pageSize: effectivePageSize, nextCursor },
-            processingTime: (result as any)?.processingTime,
-          };
+          if (result.definitions.length === 0 && result.unresolved.length === 0) {
+            return asMcpJson(
+              toolFail(
+                "not_found",
+                `Symbol not found: ${symbol}`,
+                { symbol, filePath: normalizedPath ?? null, package: qualifier ?? null, entityType: entityType ?? null },
+                toolMeta(requestId, startTime),
+              ),
+            );
+          }
 
-          knowledgeBus.publish(cacheKey, payload, "mcp-server", 30000);
-          return asMcpJson(toolOk(payload, toolMeta(requestId, startTime, { cached: false })));
-        }
+          const mapCallEntity = (entity: CallGraphEntity) => ({
+            ...entity,
+            filePath: normalizeInputPath(entity.filePath) ?? entity.filePath,
+          });
+          const mapCall = (call: CallEdge) => ({
+            ...call,
+            entity: call.entity ? mapCallEntity(call.entity) : null,
+            callSites: call.callSites.map((site) => ({
+              ...site,
+              filePath: normalizeInputPath(site.filePath) ?? site.filePath,
+            })),
+            edge: mapLocatedEdge(call.edge),
+          });
 
-        case "find_similar_code": {
-          const { code, threshold, limit } = FindSimilarCodeSchema.parse(args);
-          await ensureSemanticsReady(1, 20000);
-          const semanticAgent = await getSemanticAgent();
-          const timeoutMs = config.mcp.agents?.defaultTimeout || config.mcp.server?.timeout || 600000;
-          const sim = await withTimeout(
-            semanticAgent.findSimilarCode(code, threshold ?? 0.5),
-            timeoutMs,
-            "find_similar_code",
-            requestId,
+          return asMcpJson(
+            toolOk(
+              {
+                symbol: result.symbol,
+                direction: result.direction,
+                definitions: result.definitions.map((group) => ({
+                  definition: mapCallEntity(group.definition),
+                  calls: group.calls.map(mapCall),
+                })),
+                unresolved: result.unresolved.map(mapCall),
+                stats: {
+                  definitionCount: result.definitions.length,
+                  totalCalls: result.total,
+                },
+              },
+              toolMeta(requestId, startTime),
+              result.truncated ? ["calls_truncated"] : undefined,
+            ),
           );
-          const result = Array.isArray(sim) && limit ? sim.slice(0, limit) : sim;
-
-          return asMcpJson(toolOk(result, toolMeta(requestId, startTime)));
         }
 
-        // analyze_code_impact handled below (single implementation with fallback)
+        case "query": {
+          const parsed = QueryToolSchema.parse(args);
+          const { query, limit, cursor, pageSize, includeSnippets, snippetMaxBytes } = parsed;
+          const scope = toSearchScope(parsed);
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const snippetWarnings = new Set<string>();
+          const withSnippet = async <T extends object>(entity: Entity | null, item: T) => {
+            if (!includeSnippets || !entity) return item;
+            const { snippet, truncated } = await entitySnippet(storage, entity, snippetMaxBytes);
+            if (snippet === null) snippetWarnings.add("snippet_unavailable");
+            if (truncated) snippetWarnings.add("snippet_truncated");
+            return { ...item, snippet };
+          };
+          const effectivePageSize = pageSize ?? limit ?? 10;
+          const cursorState =
+            decodeCursor<{
+              so?: number;
+              ss?: number;
+              sid?: string;
+              sd?: boolean;
+              ga?: string;
+              gd?: boolean;
+              qo?: number;
+            }>(cursor) ?? {};
+
+          if (parsed.snapshot) {
+            const stored = await storage.getSnapshot(parsed.snapshot);
+            if (!stored) {
+              return asMcpJson(
+                toolFail(
+                  "not_found",
+                  `Snapshot not found: ${parsed.snapshot}`,
+                  { snapshot: parsed.snapshot },
+                  toolMeta(requestId, startTime),
+                ),
+              );
+            }
+            const offset = Math.max(0, Number(cursorState.qo ?? 0) || 0);
+            const found = await searchStoredSnapshot(storage, stored, {
+              query,
+              mode: "hybrid",
+              scope,
+              limit: offset + effectivePageSize + 1,
+            });
+            // Hybrid never fails: a snapshot it cannot rank by meaning is matched by name instead
+            const hits = found.ok ? found.hits : [];
+            const warnings = found.ok ? [...found.warnings] : [];
+            // Graph traversals need the live index's relationships and metadata
+            if (parseQueryIntent(query)) warnings.push("structured_intent_ignored");
+            const items = hits.slice(offset, offset + effectivePageSize).map(snapshotSearchItem);
+            const nextCursor =
+              hits.length > offset + effectivePageSize ? encodeCursor({ qo: offset + items.length }) : null;
+            return asMcpJson(
+              toolOk(
+                {
+                  mode: "snapshot",
+                  snapshot: stored,
+                  scope: scope ?? null,
+                  items,
+                  paging: { cursor: cursor ?? null, nextCursor, pageSize: effectivePageSize, offset },
+                },
+                toolMeta(requestId, startTime, { cached: false }),
+                warnings.length > 0 ? warnings : undefined,
+              ),
+            );
+          }
+
+          const results = getResultCache();
+          const cacheKey = resultCacheKey(
+            "query",
+            query,
+            { scope, pageSize: effectivePageSize, cursor, includeSnippets, snippetMaxBytes },
+            embeddingSourceKey(),
+          );
+          const cached = results.get<CachedResult>(cacheKey);
+          if (cached) {
+            return asMcpJson(
+              toolOk(cached.data, toolMeta(requestId, startTime, { cached: true }), cached.warnings),
+            );
+          }
+          const generation = results.currentGeneration();
+
+          // Questions with a known shape are answered by a graph traversal; an intent that finds
+          // nothing (e.g. a misspelled symbol) still falls back to the hybrid search below
+          const intent = parseQueryIntent(query);
+          if (intent) {
+            const offset = Math.max(0, Number(cursorState.qo ?? 0) || 0);
+            // Test files stored apart from the main graph answer too, unless the call leaves tests out
+            const graph = scope?.tests === "exclude" ? storage : await withTests(storage);
+            const structured = await runQueryIntent(graph, intent, offset + effectivePageSize, scope);
+            if (structured.total > 0) {
+              const page = [];
+              for (const item of structured.items.slice(offset)) {
+                const normalized = { ...item, filePath: normalizeInputPath(item.filePath) ?? item.filePath };
+                page.push(await withSnippet(await graph.getEntity(item.id), normalized));
+              }
+              const nextCursor = structured.truncated ? encodeCursor({ qo: offset + effectivePageSize }) : null;
+              const warnings = [...snippetWarnings];
+              if (structured.truncated) warnings.push("results_truncated");
+              const data = {
+                mode: "structured",
+                intent,
+                scope: scope ?? null,
+                structural: {
+                  items: page,
+                  nextCursor,
+                  total: structured.total,
+                },
+                semantic: { items: [], nextCursor: null, total: 0 },
+                unresolved: structured.unresolved,
+                paging: { cursor: cursor ?? null, nextCursor, pageSize: effectivePageSize, offset },
+              };
+              results.set(cacheKey, { data, warnings } satisfies CachedResult, generation);
+              return asMcpJson(
+                toolOk(
+                  data,
+                  toolMeta(requestId, startTime, { cached: false }),
+                  warnings.length > 0 ? warnings : undefined,
+                ),
+              );
+            }
+          }
 
-        case "detect_code_clones": {
-          const { minSimilarity } = DetectCodeClonesSchema.parse(args);
           await ensureSemanticsReady(1, 20000);
-          const semanticAgent = await getSemanticAgent();
           const timeoutMs = config.mcp.agents?.defaultTimeout || config.mcp.server?.timeout || 600000;
-          const semanticResult = await withTimeout<CloneGroup[]>(
-            semanticAgent.detectClones(minSimilarity),
-            timeoutMs,
-            "detect_code_clones",
-            requestId,
+          const semanticOffset = Math.max(0, Number(cursorState.so ?? 0) || 0);
+          const semanticAfter = toScoreCursor({ s: cursorState.ss, id: cursorState.sid });
+          // Structural matches have no score, so they page in entity id order
+          const structuralAfter = typeof cursorState.ga === "string" ? cursorState.ga : "";
+
+          let semanticAll: any[] = [];
+          let semanticFailed = false;
+          let semanticProcessingTime: number | undefined;
+
+          try {
+            const semanticAgent = await getSemanticAgent();
+            const fetchLimit = Math.min(500, semanticOffset + 2 * effectivePageSize + 1);
+            const semanticResult = await withTimeout(
+              semanticAgent.semanticSearch(query, fetchLimit, scope),
+              timeoutMs,
+              "query:semantic_search",
+              requestId,
+            );
+            const retrieved = Array.isArray(semanticResult) ? semanticResult : ((semanticResult as any)?.results ?? []);
+            semanticAll = await dropDeletedEntityHits(storage, retrieved);
+            semanticProcessingTime = (semanticResult as any)?.processingTime;
+          } catch (error) {
+            logger.warn(
+              "SEMANTIC_QUERY",
+              "Semantic search fallback engaged",
+              { query, error: (error as Error).message },
+              requestId,
+            );
+            semanticFailed = true;
+            semanticAll = [];
+          }
+
+          const structuralRaw = await queryGraphEntities(
+            storage,
+            query,
+            effectivePageSize + 1,
+            0,
+            scope,
+            structuralAfter,
+          );
+          // A side that ran out on an earlier page stays empty instead of starting over
+          const structuralFetched = cursorState.gd ? [] : structuralRaw.entities;
+          const hasMoreStructural = structuralFetched.length > effectivePageSize;
+          const structuralEntitiesPage = structuralFetched.slice(0, effectivePageSize);
+          const structuralFileSet = new Set(
+            structuralEntitiesPage.map((e) => normalizeInputPath(e.filePath) ?? e.filePath).filter(Boolean),
           );
+          const entityIdSet = new Set(structuralEntitiesPage.map((e) => e.id));
+          const relations = await collectRelationsWithin(storage, entityIdSet);
 
-          const jscpdResult = await runJscpdCloneDetection({
-            paths: [directory],
-            minTokens: 20,
-            minLines: 3,
-            ignore: [
-              "node_modules/**",
-              "dist/**",
-              "coverage/**",
-              "tmp/**",
-              "**/tmp/**",
-              "**/__tests__/**",
-              "**/tests/**",
-              "**/*.d.ts",
-            ],
+          const semanticNormalized = rerankSemanticHits(
+            semanticAll,
+            structuralFileSet,
+            (p) => normalizeInputPath(p) ?? p,
+          ).map((ranked) => {
+            const hit: any = ranked.hit as any;
+            const meta: any = hit?.metadata ?? {};
+            const rawPath = String(meta?.path ?? hit?.path ?? hit?.filePath ?? "");
+            const normalizedPath = rawPath ? (normalizeInputPath(rawPath) ?? rawPath) : "";
+            const content = String(hit?.content ?? "");
+            return {
+              id: hit?.id,
+              filePath: normalizedPath || null,
+              score: ranked.rankingSignals.semanticScore,
+              finalScore: ranked.finalScore,
+              excerpt: content ? content.slice(0, 400) : "",
+              metadata: meta ?? null,
+              rankingSignals: ranked.rankingSignals,
+              raw: hit,
+            };
           });
 
-          const semanticNormalized = normalizeSemanticCloneGroups(semanticResult, directory);
+          const structuralItems = [];
+          for (const entity of structuralEntitiesPage) {
+            structuralItems.push(
+              await withSnippet(entity, annotateStructuralMatch(mapEntitySummary(entity), query)),
+            );
+          }
+          structuralItems.sort(
+            (a: any, b: any) =>
+              String(a.filePath).localeCompare(String(b.filePath)) || String(a.name).localeCompare(String(b.name)),
+          );
 
-          const combined = {
+          // Pages follow the retrieval score so they stay stable; the structural boost, which depends
+          // on this page's files, only reorders hits within the page
+          const semanticPage = cursorState.sd
+            ? { page: [], next: null }
+            : pageByScore(semanticNormalized, searchHitKey, semanticAfter, effectivePageSize);
+          const semanticSlice = [...semanticPage.page].sort((a, b) => b.finalScore - a.finalScore);
+          const hasMoreSemantic = semanticPage.next !== null;
+
+          const nextCursor =
+            hasMoreSemantic || hasMoreStructural
+              ? encodeCursor({
+                  so: semanticOffset + semanticSlice.length,
+                  ss: semanticPage.next?.s,
+                  sid: semanticPage.next?.id,
+                  sd: hasMoreSemantic ? undefined : true,
+                  ga: structuralEntitiesPage[structuralEntitiesPage.length - 1]?.id ?? structuralAfter,
+                  gd: hasMoreStructural ? undefined : true,
+                })
+              : null;
+
+          const data = {
+            mode: "hybrid",
+            intent,
+            scope: scope ?? null,
             semantic: {
-              totalGroups: semanticNormalized.groups.length,
-              skippedGroups: semanticNormalized.skippedGroups,
-              groups: semanticNormalized.groups,
-              raw: semanticResult,
+              items: semanticSlice,
+              nextCursor,
+              total: semanticNormalized.length,
             },
-            jscpd: {
-              summary: jscpdResult.summary,
-              clones: jscpdResult.summary.clones,
+            structural: {
+              items: structuralItems,
+              nextCursor,
+              total: structuralRaw.stats.totalEntities,
+            },
+            stats: {
+              semanticFailed,
+              semanticProcessingTime,
+              structural: structuralRaw.stats,
+              relationsCount: relations.length,
+            },
+            relations,
+            paging: {
+              cursor: cursor ?? null,
+              nextCursor,
+              pageSize: effectivePageSize,
+              offsets: { semanticOffset, structuralAfter },
+              hasMoreSemantic,
+              hasMoreStructural,
+              semanticFailed,
             },
           };
-
-          return asMcpJson(toolOk(combined, toolMeta(requestId, startTime)));
+          const warnings = [...snippetWarnings];
+          // Without its semantic side the answer is only the structural matches; worth retrying
+          if (!semanticFailed) results.set(cacheKey, { data, warnings } satisfies CachedResult, generation);
+          return asMcpJson(
+            toolOk(
+              data,
+              toolMeta(requestId, startTime, { cached: false }),
+              warnings.length > 0 ? warnings : undefined,
+            ),
+          );
         }
 
-        case "jscpd_detect_clones": {
-          const parsed = JscpdCloneDetectionSchema.parse(args);
-          const rawPaths = parsed.paths && parsed.paths.length > 0 ? parsed.paths : [directory];
-          const resolvedPaths = rawPaths
-            .map((p) => normalizeInputPath(p) ?? directory)
-            .filter((p): p is string => Boolean(p));
-
-          const result = await runJscpdCloneDetection({
+        case "get_metrics": {
+          const resourceStats = resourceManager.getCurrentUsage();
+          const knowledgeStats = knowledgeBus.getStats();
+          const cond = await getConductor();
+          const conductorMetrics = cond.getMetrics();
+
+          return asMcpJson(
+            toolOk(
+              {
+                resources: resourceStats,
+                knowledge: knowledgeStats,
+                resultCache: getResultCache().getStats(),
+                conductor: conductorMetrics,
+                agents: Array.from(cond.agents.values()).map((agent) => ({
+                  id: agent.id,
+                  type: agent.type,
+                  status: agent.status,
+                  memoryUsage: agent.getMemoryUsage(),
+                  cpuUsage: agent.getCpuUsage(),
+                  queueSize: agent.getTaskQueue().length,
+                })),
+              },
+              toolMeta(requestId, startTime),
+            ),
+          );
+        }
+
+        case "metrics": {
+          const { reset } = MetricsSchema.parse(args ?? {});
+          const snapshot = sessionMetrics.snapshot();
+          if (reset) sessionMetrics.reset();
+          return asMcpJson(toolOk({ ...snapshot, reset }, toolMeta(requestId, startTime)));
+        }
+
+        case "warmup": {
+          WarmupSchema.parse(args ?? {});
+          const alreadyWarm = semanticAgentInstance?.isWarm?.() === true;
+          const report = await warmupServer();
+          const dbPath = report.database.path;
+
+          const warnings: string[] = [];
+          if (!report.semantic) warnings.push("semantic_disabled");
+          else if (report.semantic.embedding.fallback) warnings.push("embedding_fallback");
+          logger.info("WARMUP", "Server warmed up", { alreadyWarm, totalMs: report.totalMs }, requestId);
+
+          return asMcpJson(
+            toolOk(
+              {
+                ready: report.semantic !== null,
+                alreadyWarm,
+                database: { ...report.database, path: normalizeInputPath(dbPath) ?? dbPath },
+                ...(report.semantic
+                  ? {
+                      embedding: report.semantic.embedding,
+                      vectors: report.semantic.vectors,
+                      agentMs: report.semantic.agentMs,
+                    }
+                  : {}),
+                totalMs: report.totalMs,
+              },
+              toolMeta(requestId, startTime),
+              warnings.length > 0 ? warnings : undefined,
+            ),
+          );
+        }
+
+        // New semantic tool handlers - TASK-002
+        case "semantic_search": {
+          const parsed = SemanticSearchSchema.parse(args);
+          const { query, limit, cursor, pageSize, mode, snippet, maxLines, sortBy } = parsed;
+          const minScore = parsed.minScore ?? ConfigLoader.getInstance().getSearchMinScore();
+          const scope = toSearchScope(parsed);
+
+          const effectivePageSize = pageSize ?? limit ?? 10;
+          if (parsed.snapshot) {
+            const storage = await getGraphStorage(globalSQLiteManager);
+            const stored = await storage.getSnapshot(parsed.snapshot);
+            if (!stored) {
+              return asMcpJson(
+                toolFail(
+                  "not_found",
+                  `Snapshot not found: ${parsed.snapshot}`,
+                  { snapshot: parsed.snapshot },
+                  toolMeta(requestId, startTime),
+                ),
+              );
+            }
+            const offset = Math.max(0, Number(decodeCursor<{ o?: number }>(cursor)?.o ?? 0) || 0);
+            const found = await searchStoredSnapshot(storage, stored, {
+              query,
+              mode,
+              scope,
+              minScore,
+              limit: offset + effectivePageSize + 1,
+            });
+            if (!found.ok) {
+              return asMcpJson(toolFail(found.errorType, found.error, found.details, toolMeta(requestId, startTime)));
+            }
+            const warnings = [...found.warnings];
+            // Snapshots keep no blame or complexity metadata to order by
+            if (sortBy !== "score") warnings.push("sort_ignored");
+            const items = found.hits.slice(offset, offset + effectivePageSize).map(snapshotSearchItem);
+            const nextCursor =
+              found.hits.length > offset + effectivePageSize ? encodeCursor({ o: offset + items.length }) : null;
+            return asMcpJson(
+              toolOk(
+                {
+                  query,
+                  mode,
+                  snapshot: stored,
+                  sortBy: "score",
+                  scope: scope ?? null,
+                  items,
+                  page: { offset, pageSize: effectivePageSize, nextCursor },
+                  minScore,
+                  belowThreshold: found.belowThreshold,
+                  bestBelowThreshold: found.bestBelowThreshold,
+                  ...(found.hits.length === 0 ? { message: "no matches in snapshot" } : {}),
+                },
+                toolMeta(requestId, startTime, { cached: false }),
+                warnings.length > 0 ? warnings : undefined,
+              ),
+            );
+          }
+
+          const decoded = decodeCursor<{ o?: number; s?: number; id?: string; b?: string }>(cursor) ?? {};
+          // A cursor of another order points into a different sequence; start over
+          const cursorState = (decoded.b ?? "score") === sortBy ? decoded : {};
+          const offset = Math.max(0, Number(cursorState.o ?? 0) || 0);
+          const after = toScoreCursor(cursorState);
+
+          const results = getResultCache();
+          const cacheKey = resultCacheKey(
+            "semantic_search",
+            query,
+            { mode, snippet, maxLines, minScore, sortBy, scope, pageSize: effectivePageSize, cursor },
+            embeddingSourceKey(),
+          );
+          const cached = results.get<CachedResult>(cacheKey);
+          if (cached) {
+            return asMcpJson(
+              toolOk(cached.data, toolMeta(requestId, startTime, { cached: true }), cached.warnings),
+            );
+          }
+          const generation = results.currentGeneration();
+
+          // Extra headroom for matches that moved ahead of the cursor since the previous page. Other
+          // orders reorder a fixed window of the best matches, so every page sorts the same set
+          const fetchLimit =
+            sortBy === "score" ? Math.min(500, offset + 2 * effectivePageSize + 1) : SORTED_SEARCH_WINDOW;
+          const warnings: string[] = [];
+
+          const storage = await getGraphStorage(globalSQLiteManager);
+          let semanticHits: any[] = [];
+          let belowThreshold = 0;
+          let bestBelow: number | null = null;
+          let processingTime: number | undefined;
+          let embeddingError: EmbeddingInitDetails | null = null;
+          if (mode !== "keyword") {
+            try {
+              await ensureSemanticsReady(1, 20000);
+              const semanticAgent = await getSemanticAgent();
+              const timeoutMs = config.mcp.agents?.defaultTimeout || config.mcp.server?.timeout || 600000;
+              const result = await withTimeout(
+                semanticAgent.semanticSearch(query, fetchLimit, scope),
+                timeoutMs,
+                "semantic_search",
+                requestId,
+              );
+              const retrieved = Array.isArray(result) ? result : ((result as any)?.results ?? []);
+              const live = await dropDeletedEntityHits(storage, retrieved);
+              semanticHits = filterBySimilarity(live, minScore);
+              belowThreshold = live.length - semanticHits.length;
+              bestBelow = bestBelowThreshold(live, minScore);
+              processingTime = (result as any)?.processingTime;
+              const embedder = semanticAgent.getEmbeddingSource?.();
+              if (embedder?.fallback) {
+                warnings.push("embedding_fallback");
+                embeddingError = embedder.initFailure ?? null;
+              }
+            } catch (error) {
+              // Pure semantic mode has nothing to fall back on; hybrid degrades to keyword matches
+              if (mode === "semantic") throw error;
+              logger.warn(
+                "SEMANTIC_SEARCH",
+                "Semantic retrieval failed, using keyword matches only",
+                { query, error: (error as Error).message },
+                requestId,
+              );
+              warnings.push(error instanceof EmbeddingDimensionError ? "reindex_required" : "semantic_unavailable");
+            }
+          }
+
+          let all: any[] = semanticHits;
+          if (mode !== "semantic") {
+            const keywordHits = await keywordSearch(storage, query, fetchLimit, scope);
+            all =
+              mode === "keyword"
+                ? keywordResults(keywordHits)
+                : fuseSearchResults(semanticHits, keywordHits, fetchLimit);
+          }
+
+          const order = await searchSortKeys(storage, all, sortBy, searchHitKey);
+          if (sortBy === "recency" && all.length > 0 && order.missing === all.length) {
+            warnings.push("no_blame_metadata");
+          }
+          const { page, next } = pageByScore(all, order.keyOf, after, effectivePageSize);
+          const withSnippets = await attachSearchSnippets(storage, page, {
+            mode: snippet,
+            maxLines,
+            resolvePath: (p) => normalizeInputPath(p) ?? p,
+          });
+          const items = attachSearchHighlights(withSnippets, query);
+          if (items.some((item) => item.snippetTruncated)) warnings.push("snippet_truncated");
+          const nextCursor = next
+            ? encodeCursor({ o: offset + page.length, ...next, ...(sortBy === "score" ? {} : { b: sortBy }) })
+            : null;
+
+          const payload = {
+            query,
+            mode,
+            sortBy,
+            scope: scope ?? null,
+            items,
+            page: { offset, pageSize: effectivePageSize, nextCursor },
+            minScore,
+            belowThreshold,
+            bestBelowThreshold: bestBelow,
+            // Said outright, so an empty page is not read as a failed call or padded with weak hits
+            ...(all.length === 0
+              ? { message: mode === "keyword" ? "no keyword matches" : "no matches above threshold" }
+              : {}),
+            processingTime,
+            embeddingError,
+          };
+
+          // A degraded answer (fallback embedder, keyword matches only) is not worth repeating
+          if (!warnings.some((warning) => DEGRADED_SEARCH.has(warning))) {
+            results.set(cacheKey, { data: payload, warnings } satisfies CachedResult, generation);
+          }
+          return asMcpJson(toolOk(payload, toolMeta(requestId, startTime, { cached: false }), warnings));
+        }
+
+        case "find_similar_code": {
+          const { code, threshold, limit } = FindSimilarCodeSchema.parse(args);
+          await ensureSemanticsReady(1, 20000);
+          const semanticAgent = await getSemanticAgent();
+          const timeoutMs = config.mcp.agents?.defaultTimeout || config.mcp.server?.timeout || 600000;
+          const sim = await withTimeout(
+            semanticAgent.findSimilarCode(code, threshold ?? 0.5),
+            timeoutMs,
+            "find_similar_code",
+            requestId,
+          );
+          const result = Array.isArray(sim) && limit ? sim.slice(0, limit) : sim;
+
+          return asMcpJson(toolOk(result, toolMeta(requestId, startTime)));
+        }
+
+        case "find_similar": {
+          const parsed = FindSimilarSchema.parse(args);
+          const { entityId, symbol, filePath: hintFilePath, minScore, limit } = parsed;
+          const storage = await getGraphStorage(globalSQLiteManager);
+          let entity = entityId ? await storage.getEntity(entityId) : null;
+          if (!entity) {
+            entity = await resolveEntityWithHint(storage, symbol ?? entityId ?? "", normalizeInputPath(hintFilePath));
+          }
+          if (!entity) {
+            return asMcpJson(
+              toolFail(
+                "not_found",
+                `Entity not found: ${entityId ?? symbol ?? ""}`,
+                { entityId: entityId ?? null, symbol: symbol ?? null },
+                toolMeta(requestId, startTime),
+              ),
+            );
+          }
+          if (entity.type !== EntityType.FUNCTION && entity.type !== EntityType.METHOD) {
+            return asMcpJson(
+              toolFail(
+                "invalid_args",
+                `find_similar compares function bodies; ${entity.name} is a ${entity.type}`,
+                { entityId: entity.id, type: entity.type },
+                toolMeta(requestId, startTime),
+              ),
+            );
+          }
+
+          await ensureSemanticsReady(1, 20000);
+          const semanticAgent = await getSemanticAgent();
+          const { matches, bodyVector } = await semanticAgent.findSimilarBodies(entity, {
+            minScore,
+            limit,
+            scope: toSearchScope(parsed),
+          });
+          const summarize = (e: Entity) => ({
+            id: e.id,
+            name: e.name,
+            type: e.type,
+            filePath: normalizeInputPath(e.filePath) ?? e.filePath,
+            startLine: e.location?.start?.line ?? null,
+            endLine: e.location?.end?.line ?? null,
+          });
+          const similar: Array<ReturnType<typeof summarize> & { score: number }> = [];
+          for (const match of matches as SimilarityResult[]) {
+            const matchId = match.metadata?.entityId;
+            const found = typeof matchId === "string" ? await storage.getEntity(matchId) : null;
+            // A vector can outlive its entity until the file is re-embedded
+            if (found) similar.push({ ...summarize(found), score: match.cosine ?? 0 });
+          }
+
+          const warnings: string[] = [];
+          if (semanticAgent.getEmbeddingSource?.()?.fallback) warnings.push("embedding_fallback");
+          return asMcpJson(
+            toolOk(
+              { target: summarize(entity), minScore, bodyVector, matches: similar },
+              toolMeta(requestId, startTime),
+              warnings,
+            ),
+          );
+        }
+
+        // analyze_code_impact handled below (single implementation with fallback)
+
+        case "detect_code_clones": {
+          const { minSimilarity } = DetectCodeClonesSchema.parse(args);
+          await ensureSemanticsReady(1, 20000);
+          const semanticAgent = await getSemanticAgent();
+          const timeoutMs = config.mcp.agents?.defaultTimeout || config.mcp.server?.timeout || 600000;
+          const semanticResult = await withTimeout<CloneGroup[]>(
+            semanticAgent.detectClones(minSimilarity),
+            timeoutMs,
+            "detect_code_clones",
+            requestId,
+          );
+
+          const jscpdResult = await runJscpdCloneDetection({
+            paths: [directory],
+            minTokens: 20,
+            minLines: 3,
+            ignore: [
+              "node_modules/**",
+              "dist/**",
+              "coverage/**",
+              "tmp/**",
+              "**/tmp/**",
+              "**/__tests__/**",
+              "**/tests/**",
+              "**/*.d.ts",
+            ],
+          });
+
+          const semanticNormalized = normalizeSemanticCloneGroups(semanticResult, directory);
+
+          const combined = {
+            semantic: {
+              totalGroups: semanticNormalized.groups.length,
+              skippedGroups: semanticNormalized.skippedGroups,
+              groups: semanticNormalized.groups,
+              raw: semanticResult,
+            },
+            jscpd: {
+              summary: jscpdResult.summary,
+              clones: jscpdResult.summary.clones,
+            },
+          };
+
+          return asMcpJson(toolOk(combined, toolMeta(requestId, startTime)));
+        }
+
+        case "jscpd_detect_clones": {
+          const parsed = JscpdCloneDetectionSchema.parse(args);
+          const rawPaths = parsed.paths && parsed.paths.length > 0 ? parsed.paths : [directory];
+          const resolvedPaths = rawPaths
+            .map((p) => normalizeInputPath(p) ?? directory)
+            .filter((p): p is string => Boolean(p));
+
+          const result = await runJscpdCloneDetection({
             paths: resolvedPaths,
             pattern: parsed.pattern,
             ignore: parsed.ignore,
@@ -2485,354 +5485,1543 @@ async function executeToolCall(name: string, args: unknown, requestId: string, s
 
           const fileText = await readFileSafe(targetFilePath);
 
-          if (!fileText) {
+          if (!fileText) {
+            return asMcpJson(
+              toolFail(
+                "file_read_failed",
+                `Cannot read file: ${targetFilePath}`,
+                { filePath: targetFilePath },
+                toolMeta(requestId, startTime),
+              ),
+            );
+          }
+
+          if (startLine != null && endLine != null) {
+            const { snippet, range } = sliceByLines(fileText, startLine, endLine);
+            const suggestions = await runSuggest(snippet);
+            analyzed.push({ range, suggestions });
+
+            return asMcpJson(
+              toolOk(
+                { filePath: targetFilePath, focus: { mode: "range", range }, analyzed },
+                toolMeta(requestId, startTime),
+              ),
+            );
+          }
+
+          if (entityId) {
+            const ent = await storage.getEntity(entityId);
+            if (!ent) {
+              return asMcpJson(
+                toolFail(
+                  "not_found",
+                  `Entity not found by id: ${entityId}`,
+                  { entityId },
+                  toolMeta(requestId, startTime),
+                ),
+              );
+            }
+
+            const { snippet, range } = sliceByEntity(fileText, ent);
+            const suggestions = await runSuggest(snippet);
+            analyzed.push({ entity: mapEntitySummary(ent), range, suggestions });
+
+            return asMcpJson(
+              toolOk(
+                { filePath: targetFilePath, focus: { mode: "entityId", entityId: ent.id }, analyzed },
+                toolMeta(requestId, startTime),
+              ),
+            );
+          }
+
+          if (focusArea) {
+            const ent = await resolveEntityWithHint(storage, focusArea, targetFilePath);
+            if (ent) {
+              const { snippet, range } = sliceByEntity(fileText, ent);
+              const suggestions = await runSuggest(snippet);
+              analyzed.push({ entity: mapEntitySummary(ent), range, suggestions });
+
+              return asMcpJson(
+                toolOk(
+                  { filePath: targetFilePath, focus: { mode: "focusArea", focusArea }, analyzed },
+                  toolMeta(requestId, startTime),
+                ),
+              );
+            }
+          }
+
+          const query = await storage.executeQuery({
+            type: "entity",
+            filters: { filePath: targetFilePath },
+            limit: 500,
+          });
+
+          const sorted = query.entities
+            .filter((e) => (e as any).location?.start != null && (e as any).location?.end != null)
+            .map((e) => {
+              const s = (e as any).location?.start?.index ?? 0;
+              const ed = (e as any).location?.end?.index ?? 0;
+              return { e, len: Math.max(0, ed - s) };
+            })
+            .sort((a, b) => b.len - a.len)
+            .slice(0, 3)
+            .map((x) => x.e);
+
+          if (sorted.length === 0) {
+            const suggestions = await runSuggest(fileText);
+            analyzed.push({ range: { startIndex: 0, endIndex: Math.min(fileText.length, MAX_SNIPPET) }, suggestions });
+
+            return asMcpJson(
+              toolOk({ filePath: targetFilePath, focus: { mode: "file" }, analyzed }, toolMeta(requestId, startTime)),
+            );
+          }
+
+          for (const ent of sorted) {
+            const { snippet, range } = sliceByEntity(fileText, ent);
+            const suggestions = await runSuggest(snippet);
+            analyzed.push({ entity: mapEntitySummary(ent), range, suggestions });
+          }
+
+          return asMcpJson(
+            toolOk(
+              { filePath: targetFilePath, focus: { mode: "auto-top-entities", count: analyzed.length }, analyzed },
+              toolMeta(requestId, startTime),
+            ),
+          );
+        }
+
+        case "cross_language_search": {
+          const { query, languages } = CrossLanguageSearchSchema.parse(args);
+          await ensureSemanticsReady(1, 20000);
+          const semanticAgent = await getSemanticAgent();
+          const timeoutMs = config.mcp.agents?.defaultTimeout || config.mcp.server?.timeout || 600000;
+          const result = await withTimeout(
+            semanticAgent.crossLanguageSearch(query, languages || []),
+            timeoutMs,
+            "cross_language_search",
+            requestId,
+          );
+
+          return asMcpJson(toolOk(result, toolMeta(requestId, startTime)));
+        }
+
+        case "analyze_hotspots": {
+          const { metric, limit } = AnalyzeHotspotsSchema.parse(args);
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const relationshipSample = await storage.executeQuery({ type: "relationship", limit: 10000 });
+
+          const counts = new Map<string, { incoming: number; outgoing: number }>();
+          for (const rel of relationshipSample.relationships) {
+            const from = counts.get(rel.fromId) ?? { incoming: 0, outgoing: 0 };
+            from.outgoing += 1;
+            counts.set(rel.fromId, from);
+
+            const to = counts.get(rel.toId) ?? { incoming: 0, outgoing: 0 };
+            to.incoming += 1;
+            counts.set(rel.toId, to);
+          }
+
+          const ranked = Array.from(counts.entries())
+            .map(([id, data]) => ({ id, ...data, total: data.incoming + data.outgoing }))
+            .sort((a, b) => b.total - a.total)
+            .slice(0, limit ?? 10);
+
+          const hotspots = [] as Array<{
+            entity: ReturnType<typeof mapEntitySummary>;
+            metrics: { incoming: number; outgoing: number; total: number };
+            score: number;
+          }>;
+
+          for (const entry of ranked) {
+            const entity = await storage.getEntity(entry.id);
+            if (!entity) continue;
+            const baseScore = entry.total * 10 + entry.incoming * 5;
+            const metricScore =
+              metric === "complexity"
+                ? entry.total * 2
+                : metric === "coupling"
+                  ? entry.incoming * 3
+                  : entry.outgoing * 3;
+            hotspots.push({
+              entity: mapEntitySummary(entity),
+              metrics: { incoming: entry.incoming, outgoing: entry.outgoing, total: entry.total },
+              score: Math.round(baseScore + metricScore),
+            });
+          }
+
+          return asMcpJson(
+            toolOk(
+              { metric, limit: limit ?? 10, hotspots, sampleSize: relationshipSample.relationships.length },
+              toolMeta(requestId, startTime),
+            ),
+          );
+        }
+
+        case "find_related_concepts": {
+          const { entityId, limit } = FindRelatedConceptsSchema.parse(args);
+          await ensureSemanticsReady(1, 20000);
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const entity = await resolveEntity(storage, entityId);
+          if (!entity) {
+            return asMcpJson(
+              toolFail("not_found", `Entity not found: ${entityId}`, { entityId }, toolMeta(requestId, startTime)),
+            );
+          }
+
+          // Read code snippet for this entity using stored location
+          const fs = await import("node:fs/promises");
+          const entityFilePath = normalizeInputPath(entity.filePath) ?? entity.filePath;
+          let snippet = "";
+          try {
+            const full = await fs.readFile(entityFilePath, "utf8");
+            const startIdx =
+              typeof (entity as any).location?.start?.index === "number" ? (entity as any).location.start.index : 0;
+            const endIdx =
+              typeof (entity as any).location?.end?.index === "number"
+                ? (entity as any).location.end.index
+                : full.length;
+            if (endIdx > startIdx && endIdx - startIdx < 10000) {
+              snippet = full.slice(startIdx, endIdx);
+            } else {
+              // Fallback to line range if indices are missing
+              const sLine = (entity as any).location?.start?.line ? (entity as any).location.start.line - 1 : 0;
+              const eLine = (entity as any).location?.end?.line ? (entity as any).location.end.line : sLine + 10;
+              const lines = full.split(/\r?\n/);
+              snippet = lines.slice(Math.max(0, sLine), Math.min(lines.length, eLine)).join("\n");
+              if (snippet.length > 10000) snippet = snippet.slice(0, 10000);
+            }
+          } catch {
+            // If read fails, return empty
+            snippet = "";
+          }
+
+          let results: unknown = [];
+          try {
+            const semanticAgent = await getSemanticAgent();
+            const timeoutMs = config.mcp.agents?.defaultTimeout || config.mcp.server?.timeout || 600000;
+            const sim = await withTimeout(
+              semanticAgent.findSimilarCode(snippet, 0.65),
+              timeoutMs,
+              "find_related_concepts",
+              requestId,
+            );
+            results = Array.isArray(sim) && limit ? sim.slice(0, limit) : sim;
+          } catch (error) {
+            logger.warn(
+              "SEMANTIC_RELATED",
+              "Related concepts fallback",
+              { entityId, error: (error as Error).message },
+              requestId,
+            );
+            results = [];
+          }
+
+          return asMcpJson(
+            toolOk(
+              { entity: { id: entity.id, name: entity.name, filePath: entityFilePath }, related: results },
+              toolMeta(requestId, startTime),
+            ),
+          );
+        }
+
+        case "get_graph": {
+          const { query, limit, cursor, pageSize } = GetGraphSchema.parse(args);
+          const effectivePageSize = pageSize ?? limit ?? 100;
+          const cursorState = decodeCursor<{ o?: number }>(cursor) ?? {};
+          const offset = Math.max(0, Number(cursorState.o ?? 0) || 0);
+
+          // Use direct database query instead of going through agents
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const raw = await queryGraphEntities(storage, query, effectivePageSize + 1, offset);
+          const hasMore = raw.entities.length > effectivePageSize;
+          const entities = hasMore ? raw.entities.slice(0, effectivePageSize) : raw.entities;
+          const idSet = new Set(entities.map((e) => e.id));
+          const relations = await collectRelationsWithin(storage, idSet);
+
+          logger.info(
+            "GRAPH_QUERY",
+            `Retrieved graph with ${entities.length} entities and ${relations.length} relationships`,
+            {
+              query,
+              limit: effectivePageSize,
+              offset,
+              stats: raw.stats,
+            },
+            requestId,
+          );
+
+          return asMcpJson(
+            toolOk(
+              {
+                query,
+                page: {
+                  offset,
+                  pageSize: effectivePageSize,
+                  nextCursor: hasMore ? encodeCursor({ o: offset + effectivePageSize }) : null,
+                },
+                entities,
+                relations,
+                stats: {
+                  totalNodes: raw.stats.totalEntities,
+                  totalRelations: raw.stats.totalRelationships,
+                },
+              },
+              toolMeta(requestId, startTime),
+            ),
+          );
+        }
+
+        case "analyze_code_impact": {
+          const { entityId, filePath: hintFilePath, depth } = AnalyzeCodeImpactSchema.parse(args);
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const resolvedHintPath = hintFilePath ? normalizeInputPath(hintFilePath) : undefined;
+
+          let entity = await storage.getEntity(entityId);
+          if (!entity) {
+            entity = await resolveEntityWithHint(storage, entityId, resolvedHintPath);
+          }
+
+          if (!entity) {
+            return asMcpJson(
+              toolFail("not_found", `Entity not found: ${entityId}`, { entityId }, toolMeta(requestId, startTime)),
+            );
+          }
+
+          const traversal = await analyzeCodeImpactTraversal(storage, entity.id, depth ?? 2);
+
+          const directEntities = (
+            await Promise.all(Array.from(traversal.directDependents).map((id) => storage.getEntity(id)))
+          ).filter((e): e is Entity => Boolean(e));
+          const indirectEntities = (
+            await Promise.all(Array.from(traversal.transitiveDependents).map((id) => storage.getEntity(id)))
+          ).filter((e): e is Entity => Boolean(e));
+
+          const visibleDirectEntities = directEntities.filter((e) => !(e.metadata as any)?.isExternal);
+          const visibleIndirectEntities = indirectEntities.filter((e) => !(e.metadata as any)?.isExternal);
+
+          const affectedFiles = new Set<string>();
+          for (const sample of [...visibleDirectEntities, ...visibleIndirectEntities]) {
+            affectedFiles.add(normalizeInputPath(sample.filePath) ?? sample.filePath);
+          }
+
+          const totalImpact = visibleDirectEntities.length + visibleIndirectEntities.length;
+          const riskLevel =
+            totalImpact > 50 ? "critical" : totalImpact > 20 ? "high" : totalImpact > 5 ? "medium" : "low";
+
+          const outboundSummaries = (
+            await Promise.all(
+              Array.from(traversal.outboundDependencies).map(async (id) => {
+                const dep = await storage.getEntity(id);
+                return dep ? mapEntitySummary(dep) : null;
+              }),
+            )
+          ).filter((item): item is ReturnType<typeof mapEntitySummary> => Boolean(item));
+
+          const impact = {
+            source: mapEntitySummary(entity),
+            directImpacts: visibleDirectEntities.map((item) => mapEntitySummary(item)),
+            indirectImpacts: visibleIndirectEntities.map((item) => mapEntitySummary(item)),
+            outboundDependencies: outboundSummaries,
+            affectedFiles: Array.from(affectedFiles),
+            riskLevel,
+            depthUsed: traversal.depthUsed,
+            totals: {
+              direct: visibleDirectEntities.length,
+              indirect: visibleIndirectEntities.length,
+              outbound: traversal.outboundDependencies.size,
+            },
+          };
+
+          return asMcpJson(toolOk(impact, toolMeta(requestId, startTime)));
+        }
+
+        case "call_path": {
+          const { from, to, fromFilePath, toFilePath, maxDepth, minConfidence, limit } = CallPathSchema.parse(args);
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const normalizedFrom = fromFilePath ? normalizeInputPath(fromFilePath) : undefined;
+          const normalizedTo = toFilePath ? normalizeInputPath(toFilePath) : undefined;
+          const result = await findCallPaths(storage, {
+            from,
+            to,
+            fromFilePath: normalizedFrom,
+            toFilePath: normalizedTo,
+            maxDepth,
+            minConfidence,
+            limit,
+          });
+
+          const missing = result.sources.length === 0 ? from : result.targets.length === 0 ? to : null;
+          if (missing !== null) {
+            return asMcpJson(
+              toolFail(
+                "not_found",
+                `Symbol not found: ${missing}`,
+                { from, to, fromFilePath: normalizedFrom ?? null, toFilePath: normalizedTo ?? null },
+                toolMeta(requestId, startTime),
+              ),
+            );
+          }
+
+          const mapCallEntity = (entity: CallGraphEntity) => ({
+            ...entity,
+            filePath: normalizeInputPath(entity.filePath) ?? entity.filePath,
+          });
+          return asMcpJson(
+            toolOk(
+              {
+                from: result.from,
+                to: result.to,
+                sources: result.sources.map(mapCallEntity),
+                targets: result.targets.map(mapCallEntity),
+                paths: result.paths.map((path) => ({
+                  confidence: path.confidence,
+                  steps: path.steps.map((step) => ({
+                    entity: mapCallEntity(step.entity),
+                    call: step.call
+                      ? {
+                          ...step.call,
+                          callSites: step.call.callSites.map((site) => ({
+                            ...site,
+                            filePath: normalizeInputPath(site.filePath) ?? site.filePath,
+                          })),
+                          edge: mapLocatedEdge(step.call.edge),
+                        }
+                      : null,
+                  })),
+                })),
+                truncated: result.truncated,
+                stats: {
+                  length: result.length,
+                  totalPaths: result.totalPaths,
+                  maxDepth: result.maxDepth,
+                  depthReached: result.depthReached,
+                },
+              },
+              toolMeta(requestId, startTime),
+              result.truncated ? [result.truncated.message] : undefined,
+            ),
+          );
+        }
+
+        case "impact_analysis": {
+          const {
+            symbol,
+            filePath,
+            package: qualifier,
+            entityType,
+            maxDepth,
+            relationshipTypes,
+            minConfidence,
+            tests,
+            limit,
+          } = ImpactAnalysisSchema.parse(args);
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const normalizedPath = filePath ? normalizeInputPath(filePath) : undefined;
+
+          const result = await analyzeImpact(storage, {
+            symbol,
+            filePath: normalizedPath,
+            qualifier,
+            entityType,
+            maxDepth,
+            relationshipTypes,
+            minConfidence,
+            tests: testsScopeOf(tests),
+            limit,
+          });
+
+          if (result.targets.length === 0) {
+            return asMcpJson(
+              toolFail(
+                "not_found",
+                `Symbol not found: ${symbol}`,
+                { symbol, filePath: normalizedPath ?? null, package: qualifier ?? null, entityType: entityType ?? null },
+                toolMeta(requestId, startTime),
+              ),
+            );
+          }
+
+          const mapImpactEntity = (entity: ImpactEntity) => ({
+            ...entity,
+            filePath: normalizeInputPath(entity.filePath) ?? entity.filePath,
+          });
+
+          return asMcpJson(
+            toolOk(
+              {
+                symbol: result.symbol,
+                targets: result.targets.map(mapImpactEntity),
+                files: result.files.map((group) => ({
+                  filePath: normalizeInputPath(group.filePath) ?? group.filePath,
+                  entities: group.entities.map((affected) => ({
+                    entity: mapImpactEntity(affected.entity),
+                    depth: affected.depth,
+                    confidence: affected.confidence,
+                    path: affected.path.map((step) => ({ ...step, entity: mapImpactEntity(step.entity) })),
+                    edge: mapLocatedEdge(affected.edge),
+                  })),
+                })),
+                cycles: result.cycles.map((cycle) => ({
+                  ...cycle,
+                  from: mapImpactEntity(cycle.from),
+                  to: mapImpactEntity(cycle.to),
+                })),
+                truncated: result.truncated,
+                stats: {
+                  totalAffected: result.totalAffected,
+                  affectedFiles: result.files.length,
+                  maxDepth: result.maxDepth,
+                  depthReached: result.depthReached,
+                },
+              },
+              toolMeta(requestId, startTime),
+              result.truncated ? [result.truncated.message] : undefined,
+            ),
+          );
+        }
+
+        case "graph_query": {
+          const parsed = GraphQuerySchema.parse(args ?? {});
+          const { id, name, ...startScope } = parsed.start;
+          const spec = {
+            start: { id, name, scope: toSearchScope(startScope) },
+            edges: parsed.edges,
+            direction: parsed.direction,
+            minDepth: parsed.minDepth,
+            maxDepth: parsed.maxDepth,
+            where: parsed.where ? { name: parsed.where.name, scope: toSearchScope(parsed.where) } : undefined,
+            minConfidence: parsed.minConfidence,
+            returnPaths: parsed.returnPaths,
+            limit: parsed.limit,
+          };
+          const problem = graphQueryProblem(spec);
+          if (problem) {
+            return asMcpJson(toolFail("invalid_args", problem, { start: parsed.start }, toolMeta(requestId, startTime)));
+          }
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const result = await runGraphQuery(storage, spec);
+
+          if (result.start.length === 0) {
+            return asMcpJson(
+              toolFail(
+                "not_found",
+                "No entity matches the start selector",
+                { start: parsed.start },
+                toolMeta(requestId, startTime),
+              ),
+            );
+          }
+
+          const mapNode = (node: GraphQueryNode) => ({
+            ...node,
+            filePath: normalizeInputPath(node.filePath) ?? node.filePath,
+          });
+
+          return asMcpJson(
+            toolOk(
+              {
+                start: result.start.map(mapNode),
+                matches: result.matches.map((match) => ({
+                  ...match,
+                  node: mapNode(match.node),
+                  ...(match.path ? { path: match.path.map((step) => ({ ...step, node: mapNode(step.node) })) } : {}),
+                })),
+                truncated: result.truncated,
+                stats: {
+                  total: result.total,
+                  returned: result.matches.length,
+                  visited: result.visited,
+                  maxDepth: parsed.maxDepth,
+                  depthReached: result.depthReached,
+                },
+              },
+              toolMeta(requestId, startTime),
+              result.truncated.length > 0 ? result.truncated.map((t) => t.message) : undefined,
+            ),
+          );
+        }
+
+        case "detect_cycles": {
+          const { directory: inputDir, granularity } = DetectCyclesSchema.parse(args ?? {});
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const pathPrefix = inputDir ? normalizeInputPath(inputDir) : undefined;
+          const result = await detectCycles(storage, { granularity, pathPrefix });
+
+          logger.info(
+            "DETECT_CYCLES",
+            "Dependency cycle scan completed",
+            { modules: result.moduleCount, edges: result.edgeCount, cycles: result.cycles.length },
+            requestId,
+          );
+
+          return asMcpJson(
+            toolOk(
+              { directory: pathPrefix ?? null, ...result },
+              toolMeta(requestId, startTime),
+              result.hasCycles ? [`${result.cycles.length} dependency cycle(s) found`] : undefined,
+            ),
+          );
+        }
+
+        case "module_dependencies": {
+          const { directory: inputDir, granularity, minWeight, format, outputPath, limit } =
+            ModuleDependenciesSchema.parse(args ?? {});
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const pathPrefix = inputDir ? normalizeInputPath(inputDir) : undefined;
+          const result = await moduleDependencies(storage, { granularity, pathPrefix, minWeight });
+          const stats = { modules: result.modules.length, edges: result.edges.length, cycles: result.cycles.length };
+
+          if (format !== "json") {
+            const targetPath =
+              normalizeInputPath(outputPath) ?? join(directory, ".code-graph-rag", `modules.${format}`);
+            const exported = await exportModuleGraph(result, format, targetPath);
+            logger.info("MODULE_DEPENDENCIES", "Exported module graph", { ...exported }, requestId);
+            return asMcpJson(
+              toolOk(
+                { directory: pathPrefix ?? null, ...exported, hasCycles: result.hasCycles },
+                toolMeta(requestId, startTime),
+              ),
+            );
+          }
+
+          return asMcpJson(
+            toolOk(
+              {
+                directory: pathPrefix ?? null,
+                granularity: result.granularity,
+                modules: result.modules,
+                edges: result.edges.slice(0, limit),
+                hasCycles: result.hasCycles,
+                cycles: result.cycles,
+                stats,
+              },
+              toolMeta(requestId, startTime),
+              result.edges.length > limit ? ["edges_truncated"] : undefined,
+            ),
+          );
+        }
+
+        case "find_unused": {
+          const { directory: inputDir, entityTypes, roots, rootNames, limit } = FindUnusedSchema.parse(args ?? {});
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const pathPrefix = inputDir ? normalizeInputPath(inputDir) : undefined;
+          const result = await findUnused(storage, { pathPrefix, entityTypes, roots, rootNames, limit });
+
+          return asMcpJson(
+            toolOk(
+              {
+                directory: pathPrefix ?? null,
+                unused: result.unused.map((entity) => ({
+                  ...entity,
+                  filePath: normalizeInputPath(entity.filePath) ?? entity.filePath,
+                })),
+                stats: {
+                  candidates: result.candidates,
+                  unused: result.total,
+                  keptAsRoots: result.roots,
+                },
+              },
+              toolMeta(requestId, startTime),
+              result.truncated ? ["unused_truncated"] : undefined,
+            ),
+          );
+        }
+
+        case "find_unused_imports": {
+          const { directory: inputDir, checkSource, limit } = FindUnusedImportsSchema.parse(args ?? {});
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const pathPrefix = inputDir ? normalizeInputPath(inputDir) : undefined;
+          const result = await findUnusedImports(storage, { pathPrefix, checkSource, limit });
+
+          return asMcpJson(
+            toolOk(
+              {
+                directory: pathPrefix ?? null,
+                unused: result.unused.map((entry) => ({
+                  ...entry,
+                  filePath: normalizeInputPath(entry.filePath) ?? entry.filePath,
+                })),
+                stats: {
+                  files: result.files,
+                  checked: result.checked,
+                  unused: result.total,
+                  sideEffect: result.sideEffect,
+                  unchecked: result.unchecked,
+                  usedOutsideGraph: result.usedOutsideGraph,
+                },
+              },
+              toolMeta(requestId, startTime),
+              result.truncated ? ["unused_imports_truncated"] : undefined,
+            ),
+          );
+        }
+
+        case "browse": {
+          const { path, limit } = BrowseSchema.parse(args ?? {});
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const resolvedPath = path ? normalizeInputPath(path) : undefined;
+          const result = await browse(storage, { path, resolvedPath, limit });
+          if (!result) {
+            return asMcpJson(
+              toolFail(
+                "not_found",
+                `No package, directory or indexed file: ${path}`,
+                { path },
+                toolMeta(requestId, startTime),
+              ),
+            );
+          }
+
+          return asMcpJson(
+            toolOk(
+              {
+                container: result.container,
+                children: result.children,
+                stats: { total: result.total },
+              },
+              toolMeta(requestId, startTime),
+              result.truncated ? ["children_truncated"] : undefined,
+            ),
+          );
+        }
+
+        case "list_complex_functions": {
+          const { threshold, directory: inputDir, limit } = ListComplexFunctionsSchema.parse(args ?? {});
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const pathPrefix = inputDir ? normalizeInputPath(inputDir) : undefined;
+          const { entities, total } = await storage.getEntitiesByComplexity(threshold, { limit, pathPrefix });
+
+          return asMcpJson(
+            toolOk(
+              {
+                threshold,
+                directory: pathPrefix ?? null,
+                functions: entities.map((entity) => ({
+                  id: entity.id,
+                  name: entity.name,
+                  type: entity.type,
+                  filePath: normalizeInputPath(entity.filePath) ?? entity.filePath,
+                  startLine: entity.location?.start?.line ?? null,
+                  endLine: entity.location?.end?.line ?? null,
+                  complexity: entity.metadata.cyclomaticComplexity ?? null,
+                  language: entity.metadata.language ?? entity.language ?? null,
+                })),
+                total,
+              },
+              toolMeta(requestId, startTime),
+              total > entities.length ? ["functions_truncated"] : undefined,
+            ),
+          );
+        }
+
+        case "snapshot_graph": {
+          const { action, label, description, replace, vectors } = SnapshotGraphSchema.parse(args ?? {});
+          const storage = await getGraphStorage(globalSQLiteManager);
+
+          if (action === "list") {
+            const snapshots = await storage.listSnapshots();
+            return asMcpJson(toolOk({ snapshots }, toolMeta(requestId, startTime)));
+          }
+          if (!label) {
+            return asMcpJson(
+              toolFail(
+                "invalid_args",
+                `label is required for action "${action}"`,
+                { action },
+                toolMeta(requestId, startTime),
+              ),
+            );
+          }
+          if (action === "delete") {
+            if (!(await storage.deleteSnapshot(label))) {
+              return asMcpJson(
+                toolFail("not_found", `Snapshot not found: ${label}`, { label }, toolMeta(requestId, startTime)),
+              );
+            }
+            return asMcpJson(toolOk({ deleted: label }, toolMeta(requestId, startTime)));
+          }
+
+          if (!replace && (await storage.getSnapshot(label))) {
+            return asMcpJson(
+              toolFail(
+                "already_exists",
+                `Snapshot already exists: ${label}; pass replace=true to overwrite it`,
+                { label },
+                toolMeta(requestId, startTime),
+              ),
+            );
+          }
+          let snapshot = await storage.createSnapshot(label, { description, replace });
+          const warnings: string[] = [];
+          if (vectors && process.env.MCP_DEBUG_DISABLE_SEMANTIC !== "1") {
+            try {
+              const agent = await getSemanticAgent();
+              snapshot =
+                (await storage.saveSnapshotVectors(label, agent.getEmbeddingKey(), agent.entityVectors())) ?? snapshot;
+            } catch (error) {
+              logger.warn(
+                "SNAPSHOT_GRAPH",
+                "Snapshot stored without search vectors",
+                { label, error: (error as Error).message },
+                requestId,
+              );
+              warnings.push("vectors_unavailable");
+            }
+          } else if (vectors) {
+            warnings.push("semantic_disabled");
+          }
+          logger.info("SNAPSHOT_GRAPH", `Stored snapshot ${label}`, { ...snapshot }, requestId);
+          return asMcpJson(
+            toolOk({ snapshot }, toolMeta(requestId, startTime), warnings.length > 0 ? warnings : undefined),
+          );
+        }
+
+        case "diff_graph": {
+          const { from, to, directory: inputDir, limit } = DiffGraphSchema.parse(args);
+          const storage = await getGraphStorage(globalSQLiteManager);
+          for (const label of [from, to]) {
+            if (label && !(await storage.getSnapshot(label))) {
+              return asMcpJson(
+                toolFail("not_found", `Snapshot not found: ${label}`, { label }, toolMeta(requestId, startTime)),
+              );
+            }
+          }
+
+          const pathPrefix = inputDir ? normalizeInputPath(inputDir) : undefined;
+          const result = await diffGraph(storage, { from, to, pathPrefix, limit });
+          const withPath = <T extends { filePath: string }>(item: T): T => ({
+            ...item,
+            filePath: normalizeInputPath(item.filePath) ?? item.filePath,
+          });
+          const edgeWithPaths = (rel: DiffRelationship) => ({ ...rel, from: withPath(rel.from), to: withPath(rel.to) });
+          const files = result.files.map((file) => ({
+            ...withPath(file),
+            added: file.added.map(withPath),
+            removed: file.removed.map(withPath),
+            modified: file.modified.map((m) => ({ ...m, before: withPath(m.before), after: withPath(m.after) })),
+            addedRelationships: file.addedRelationships.map(edgeWithPaths),
+            removedRelationships: file.removedRelationships.map(edgeWithPaths),
+          }));
+
+          return asMcpJson(
+            toolOk(
+              { ...result, directory: pathPrefix ?? null, files },
+              toolMeta(requestId, startTime),
+              result.truncated ? ["changes_truncated"] : undefined,
+            ),
+          );
+        }
+
+        case "inheritance_hierarchy": {
+          const {
+            symbol,
+            filePath,
+            package: qualifier,
+            entityType,
+            direction,
+            maxDepth,
+            limit,
+          } = InheritanceHierarchySchema.parse(args);
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const normalizedPath = filePath ? normalizeInputPath(filePath) : undefined;
+
+          const result = await inheritanceHierarchy(storage, {
+            symbol,
+            filePath: normalizedPath,
+            qualifier,
+            entityType,
+            direction,
+            maxDepth,
+            limit,
+          });
+
+          if (result.roots.length === 0) {
             return asMcpJson(
               toolFail(
-                "file_read_failed",
-                `Cannot read file: ${targetFilePath}`,
-                { filePath: targetFilePath },
+                "not_found",
+                `Type not found: ${symbol}`,
+                { symbol, filePath: normalizedPath ?? null, package: qualifier ?? null, entityType: entityType ?? null },
                 toolMeta(requestId, startTime),
               ),
             );
           }
 
-          if (startLine != null && endLine != null) {
-            const { snippet, range } = sliceByLines(fileText, startLine, endLine);
-            const suggestions = await runSuggest(snippet);
-            analyzed.push({ range, suggestions });
+          const mapHierarchyEntity = <T extends HierarchyEntity>(entity: T): T => ({
+            ...entity,
+            filePath: entity.filePath ? (normalizeInputPath(entity.filePath) ?? entity.filePath) : null,
+          });
+          const mapNode = (node: HierarchyNode): HierarchyNode => ({
+            ...mapHierarchyEntity(node),
+            children: node.children.map(mapNode),
+          });
+
+          return asMcpJson(
+            toolOk(
+              {
+                symbol: result.symbol,
+                direction: result.direction,
+                roots: result.roots.map((root) => ({
+                  type: mapHierarchyEntity(root.type),
+                  ancestors: root.ancestors.map(mapNode),
+                  descendants: root.descendants.map(mapNode),
+                })),
+                diamonds: result.diamonds.map((d) => ({
+                  ...d,
+                  entity: mapHierarchyEntity(d.entity),
+                  via: d.via.map(mapHierarchyEntity),
+                })),
+                multipleInheritance: result.multipleInheritance.map((m) => ({
+                  entity: mapHierarchyEntity(m.entity),
+                  bases: m.bases.map(mapHierarchyEntity),
+                })),
+                stats: { totalNodes: result.totalNodes, maxDepth: result.maxDepth },
+              },
+              toolMeta(requestId, startTime),
+              result.truncated ? ["hierarchy_truncated"] : undefined,
+            ),
+          );
+        }
 
+        case "find_overrides": {
+          const { symbol, filePath, package: qualifier, entityType, limit } = FindOverridesSchema.parse(args);
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const normalizedPath = filePath ? normalizeInputPath(filePath) : undefined;
+          const result = await findOverrides(storage, { symbol, filePath: normalizedPath, qualifier, entityType, limit });
+
+          if (result.definitions.length === 0 && result.types.length === 0) {
             return asMcpJson(
-              toolOk(
-                { filePath: targetFilePath, focus: { mode: "range", range }, analyzed },
+              toolFail(
+                "not_found",
+                `Method or type not found: ${symbol}`,
+                { symbol, filePath: normalizedPath ?? null, package: qualifier ?? null, entityType: entityType ?? null },
                 toolMeta(requestId, startTime),
               ),
             );
           }
 
-          if (entityId) {
-            const ent = await storage.getEntity(entityId);
-            if (!ent) {
-              return asMcpJson(
-                toolFail(
-                  "not_found",
-                  `Entity not found by id: ${entityId}`,
-                  { entityId },
-                  toolMeta(requestId, startTime),
-                ),
-              );
-            }
+          const mapOverrideEntity = (entity: OverrideEntity) => ({
+            ...entity,
+            filePath: normalizeInputPath(entity.filePath) ?? entity.filePath,
+          });
 
-            const { snippet, range } = sliceByEntity(fileText, ent);
-            const suggestions = await runSuggest(snippet);
-            analyzed.push({ entity: mapEntitySummary(ent), range, suggestions });
+          return asMcpJson(
+            toolOk(
+              {
+                symbol: result.symbol,
+                definitions: result.definitions.map((d) => ({
+                  method: mapOverrideEntity(d.method),
+                  owner: d.owner ? mapOverrideEntity(d.owner) : null,
+                  overrides: d.overrides.map(mapOverrideEntity),
+                  overriddenBy: d.overriddenBy.map((o) => ({
+                    ...o,
+                    method: mapOverrideEntity(o.method),
+                    owner: o.owner ? mapOverrideEntity(o.owner) : null,
+                  })),
+                })),
+                types: result.types.map((t) => ({
+                  type: mapOverrideEntity(t.type),
+                  methods: t.methods.map((m) => ({
+                    ...m,
+                    method: mapOverrideEntity(m.method),
+                    overrides: m.overrides.map(mapOverrideEntity),
+                  })),
+                  neverOverridden: t.neverOverridden,
+                })),
+                stats: { totalOverrides: result.total },
+              },
+              toolMeta(requestId, startTime),
+              result.truncated ? ["overrides_truncated"] : undefined,
+            ),
+          );
+        }
 
+        case "list_members": {
+          const { symbol, filePath, package: qualifier, kinds, limit } = ListMembersSchema.parse(args);
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const normalizedPath = filePath ? normalizeInputPath(filePath) : undefined;
+          const result = await listMembers(storage, { symbol, filePath: normalizedPath, qualifier, kinds, limit });
+
+          if (result.containers.length === 0) {
             return asMcpJson(
-              toolOk(
-                { filePath: targetFilePath, focus: { mode: "entityId", entityId: ent.id }, analyzed },
+              toolFail(
+                "not_found",
+                `Class, struct or interface not found: ${symbol}`,
+                { symbol, filePath: normalizedPath ?? null, package: qualifier ?? null },
                 toolMeta(requestId, startTime),
               ),
             );
           }
 
-          if (focusArea) {
-            const ent = await resolveEntityWithHint(storage, focusArea, targetFilePath);
-            if (ent) {
-              const { snippet, range } = sliceByEntity(fileText, ent);
-              const suggestions = await runSuggest(snippet);
-              analyzed.push({ entity: mapEntitySummary(ent), range, suggestions });
+          const withPath = <T extends { filePath: string }>(entry: T): T => ({
+            ...entry,
+            filePath: normalizeInputPath(entry.filePath) ?? entry.filePath,
+          });
+          const containers = result.containers.map((c) => ({
+            ...c,
+            container: withPath(c.container),
+            members: c.members.map(withPath),
+          }));
 
-              return asMcpJson(
-                toolOk(
-                  { filePath: targetFilePath, focus: { mode: "focusArea", focusArea }, analyzed },
-                  toolMeta(requestId, startTime),
-                ),
-              );
-            }
-          }
+          return asMcpJson(
+            toolOk(
+              { symbol: result.symbol, containers },
+              toolMeta(requestId, startTime),
+              containers.some((c) => c.truncated) ? ["members_truncated"] : undefined,
+            ),
+          );
+        }
 
-          const query = await storage.executeQuery({
-            type: "entity",
-            filters: { filePath: targetFilePath },
-            limit: 500,
+        case "list_routes": {
+          const { method, path, framework, directory: inputDir, limit } = ListRoutesSchema.parse(args ?? {});
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const pathPrefix = inputDir ? normalizeInputPath(inputDir) : undefined;
+          const result = await listRoutes(storage, { method, path, framework, pathPrefix, limit });
+
+          const mapRoute = (route: RouteEndpoint): RouteEndpoint => ({
+            ...route,
+            filePath: normalizeInputPath(route.filePath) ?? route.filePath,
+            handler: route.handler?.resolved
+              ? { ...route.handler, filePath: normalizeInputPath(route.handler.filePath) ?? route.handler.filePath }
+              : route.handler,
           });
 
-          const sorted = query.entities
-            .filter((e) => (e as any).location?.start != null && (e as any).location?.end != null)
-            .map((e) => {
-              const s = (e as any).location?.start?.index ?? 0;
-              const ed = (e as any).location?.end?.index ?? 0;
-              return { e, len: Math.max(0, ed - s) };
-            })
-            .sort((a, b) => b.len - a.len)
-            .slice(0, 3)
-            .map((x) => x.e);
-
-          if (sorted.length === 0) {
-            const suggestions = await runSuggest(fileText);
-            analyzed.push({ range: { startIndex: 0, endIndex: Math.min(fileText.length, MAX_SNIPPET) }, suggestions });
+          return asMcpJson(
+            toolOk(
+              {
+                directory: pathPrefix ?? null,
+                routes: result.routes.map(mapRoute),
+                stats: { total: result.total, byMethod: result.byMethod, byFramework: result.byFramework },
+              },
+              toolMeta(requestId, startTime),
+              result.truncated ? ["routes_truncated"] : undefined,
+            ),
+          );
+        }
 
+        case "find_by_author": {
+          const parsed = FindByAuthorSchema.parse(args ?? {});
+          const since = parsed.since ? new Date(parsed.since) : undefined;
+          const until = parsed.until ? new Date(parsed.until) : undefined;
+          if ((since && Number.isNaN(since.getTime())) || (until && Number.isNaN(until.getTime()))) {
             return asMcpJson(
-              toolOk({ filePath: targetFilePath, focus: { mode: "file" }, analyzed }, toolMeta(requestId, startTime)),
+              toolFail(
+                "invalid_args",
+                "since and until must be ISO 8601 dates",
+                { since: parsed.since, until: parsed.until },
+                toolMeta(requestId, startTime),
+              ),
             );
           }
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const pathPrefix = parsed.directory ? normalizeInputPath(parsed.directory) : undefined;
+          const result = await findByAuthor(storage, {
+            author: parsed.author,
+            pathPrefix,
+            kinds: parsed.kinds?.length ? parsed.kinds : undefined,
+            since,
+            until,
+            sort: parsed.sort,
+            limit: parsed.limit,
+          });
 
-          for (const ent of sorted) {
-            const { snippet, range } = sliceByEntity(fileText, ent);
-            const suggestions = await runSuggest(snippet);
-            analyzed.push({ entity: mapEntitySummary(ent), range, suggestions });
+          const warnings: string[] = [];
+          if (result.truncated) warnings.push("entities_truncated");
+          if (result.blamed === 0 && !parsed.author) warnings.push("no_blame_metadata");
+          return asMcpJson(
+            toolOk(
+              {
+                author: parsed.author ?? null,
+                directory: pathPrefix ?? null,
+                entities: result.entities.map((entity) => ({
+                  ...entity,
+                  filePath: normalizeInputPath(entity.filePath) ?? entity.filePath,
+                })),
+                authors: result.authors,
+                stats: { total: result.total, returned: result.entities.length },
+              },
+              toolMeta(requestId, startTime),
+              warnings.length > 0 ? warnings : undefined,
+            ),
+          );
+        }
+
+        case "find_by_signature": {
+          const parsed = FindBySignatureSchema.parse(args ?? {});
+          const pattern = parseSignaturePattern(parsed.signature);
+          if (!pattern) {
+            return asMcpJson(
+              toolFail(
+                "invalid_args",
+                "signature must start with a parameter list, e.g. func(int) error or (string) => User",
+                { signature: parsed.signature },
+                toolMeta(requestId, startTime),
+              ),
+            );
           }
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const pathPrefix = parsed.directory ? normalizeInputPath(parsed.directory) : undefined;
+          const result = await findBySignature(storage, {
+            pattern,
+            pathPrefix,
+            languages: parsed.languages?.length ? parsed.languages : undefined,
+            kinds: parsed.kinds?.length ? parsed.kinds : undefined,
+            requireTypes: parsed.requireTypes,
+            limit: parsed.limit,
+          });
 
           return asMcpJson(
             toolOk(
-              { filePath: targetFilePath, focus: { mode: "auto-top-entities", count: analyzed.length }, analyzed },
+              {
+                signature: parsed.signature,
+                pattern,
+                directory: pathPrefix ?? null,
+                matches: result.matches.map((match) => ({
+                  ...match,
+                  filePath: normalizeInputPath(match.filePath) ?? match.filePath,
+                })),
+                stats: { total: result.total, returned: result.matches.length, scanned: result.scanned },
+              },
               toolMeta(requestId, startTime),
+              result.truncated ? ["matches_truncated"] : undefined,
             ),
           );
         }
 
-        case "cross_language_search": {
-          const { query, languages } = CrossLanguageSearchSchema.parse(args);
-          await ensureSemanticsReady(1, 20000);
-          const semanticAgent = await getSemanticAgent();
-          const timeoutMs = config.mcp.agents?.defaultTimeout || config.mcp.server?.timeout || 600000;
-          const result = await withTimeout(
-            semanticAgent.crossLanguageSearch(query, languages || []),
-            timeoutMs,
-            "cross_language_search",
-            requestId,
+        case "list_env_vars": {
+          const { name, directory: inputDir, includeUnresolved, limit } = ListEnvVarsSchema.parse(args ?? {});
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const pathPrefix = inputDir ? normalizeInputPath(inputDir) : undefined;
+          const result = await listEnvVars(storage, { name, pathPrefix, includeUnresolved, limit });
+
+          const mapRead = <T extends EnvVarRead>(read: T): T => ({
+            ...read,
+            filePath: normalizeInputPath(read.filePath) ?? read.filePath,
+            reader: read.reader
+              ? { ...read.reader, filePath: normalizeInputPath(read.reader.filePath) ?? read.reader.filePath }
+              : null,
+          });
+
+          return asMcpJson(
+            toolOk(
+              {
+                directory: pathPrefix ?? null,
+                variables: result.variables.map((v) => ({ ...v, reads: v.reads.map(mapRead) })),
+                unresolved: result.unresolved.map(mapRead),
+                stats: { total: result.total, unresolvedReads: result.unresolved.length },
+              },
+              toolMeta(requestId, startTime),
+              result.truncated ? ["variables_truncated"] : undefined,
+            ),
           );
+        }
 
-          return asMcpJson(toolOk(result, toolMeta(requestId, startTime)));
+        case "list_tables": {
+          const { name, directory: inputDir, kind, includeColumns, limit } = ListTablesSchema.parse(args ?? {});
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const pathPrefix = inputDir ? normalizeInputPath(inputDir) : undefined;
+          const result = await listTables(storage, { name, pathPrefix, kind, includeColumns, limit });
+
+          const mapPath = (file: string) => normalizeInputPath(file) ?? file;
+          return asMcpJson(
+            toolOk(
+              {
+                directory: pathPrefix ?? null,
+                tables: result.tables.map((table) => ({
+                  ...table,
+                  filePath: mapPath(table.filePath),
+                  alteredIn: table.alteredIn.map(mapPath),
+                  foreignKeys: table.foreignKeys.map((fk) =>
+                    fk.resolved ? { ...fk, resolved: { ...fk.resolved, filePath: mapPath(fk.resolved.filePath) } } : fk,
+                  ),
+                })),
+                stats: { total: result.total },
+              },
+              toolMeta(requestId, startTime),
+              result.truncated ? ["tables_truncated"] : undefined,
+            ),
+          );
         }
 
-        case "analyze_hotspots": {
-          const { metric, limit } = AnalyzeHotspotsSchema.parse(args);
+        case "list_package_init": {
+          const { package: query } = ListPackageInitSchema.parse(args);
           const storage = await getGraphStorage(globalSQLiteManager);
-          const relationshipSample = await storage.executeQuery({ type: "relationship", limit: 10000 });
+          const { result, candidates } = await listPackageInit(storage, query, normalizeInputPath(query));
 
-          const counts = new Map<string, { incoming: number; outgoing: number }>();
-          for (const rel of relationshipSample.relationships) {
-            const from = counts.get(rel.fromId) ?? { incoming: 0, outgoing: 0 };
-            from.outgoing += 1;
-            counts.set(rel.fromId, from);
+          if (!result) {
+            return asMcpJson(
+              toolFail(
+                candidates.length > 1 ? "invalid_args" : "not_found",
+                candidates.length > 1
+                  ? `Several Go packages match ${query}; pass an import path or directory`
+                  : `Go package not found: ${query}`,
+                { package: query, candidates },
+                toolMeta(requestId, startTime),
+              ),
+            );
+          }
+          return asMcpJson(toolOk(result, toolMeta(requestId, startTime)));
+        }
 
-            const to = counts.get(rel.toId) ?? { incoming: 0, outgoing: 0 };
-            to.incoming += 1;
-            counts.set(rel.toId, to);
+        case "public_api": {
+          const { target, kinds, limit } = PublicApiSchema.parse(args);
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const { result, candidates } = await listPublicApi(storage, target, normalizeInputPath(target), {
+            kinds,
+            limit,
+          });
+
+          if (!result) {
+            return asMcpJson(
+              toolFail(
+                candidates.length > 1 ? "invalid_args" : "not_found",
+                candidates.length > 1
+                  ? `Several Go packages match ${target}; pass an import path or directory`
+                  : `No indexed package, file or directory: ${target}`,
+                { target, candidates },
+                toolMeta(requestId, startTime),
+              ),
+            );
           }
+          return asMcpJson(
+            toolOk(result, toolMeta(requestId, startTime), result.truncated ? ["public_api_truncated"] : undefined),
+          );
+        }
 
-          const ranked = Array.from(counts.entries())
-            .map(([id, data]) => ({ id, ...data, total: data.incoming + data.outgoing }))
-            .sort((a, b) => b.total - a.total)
-            .slice(0, limit ?? 10);
+        case "list_todos": {
+          const { directory: inputDir, kinds, assignee, query, limit } = ListTodosSchema.parse(args ?? {});
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const pathPrefix = inputDir ? normalizeInputPath(inputDir) : undefined;
+          const result = await listTodos(storage, { pathPrefix, kinds, assignee, query, limit });
+          return asMcpJson(
+            toolOk(
+              {
+                directory: pathPrefix ?? null,
+                todos: result.todos.map((todo) => ({
+                  ...todo,
+                  filePath: normalizeInputPath(todo.filePath) ?? todo.filePath,
+                })),
+                stats: { total: result.total, byKind: result.byKind },
+              },
+              toolMeta(requestId, startTime),
+              result.truncated ? ["todos_truncated"] : undefined,
+            ),
+          );
+        }
 
-          const hotspots = [] as Array<{
-            entity: ReturnType<typeof mapEntitySummary>;
-            metrics: { incoming: number; outgoing: number; total: number };
-            score: number;
-          }>;
+        case "tech_debt_report": {
+          const { directory: inputDir, kinds, limit } = TechDebtReportSchema.parse(args ?? {});
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const pathPrefix = inputDir ? normalizeInputPath(inputDir) : undefined;
+          const report = await techDebtReport(storage, { pathPrefix, kinds, root: pathPrefix ?? directory, limit });
 
-          for (const entry of ranked) {
-            const entity = await storage.getEntity(entry.id);
-            if (!entity) continue;
-            const baseScore = entry.total * 10 + entry.incoming * 5;
-            const metricScore =
-              metric === "complexity"
-                ? entry.total * 2
-                : metric === "coupling"
-                  ? entry.incoming * 3
-                  : entry.outgoing * 3;
-            hotspots.push({
-              entity: mapEntitySummary(entity),
-              metrics: { incoming: entry.incoming, outgoing: entry.outgoing, total: entry.total },
-              score: Math.round(baseScore + metricScore),
-            });
+          const warnings: string[] = [];
+          if (report.truncated) warnings.push("items_truncated");
+          if (report.total > 0 && report.dated === 0) warnings.push("no_git_history");
+          return asMcpJson(
+            toolOk(
+              {
+                directory: pathPrefix ?? null,
+                items: report.items.map((item) => ({
+                  ...item,
+                  filePath: normalizeInputPath(item.filePath) ?? item.filePath,
+                })),
+                byAuthor: report.byAuthor,
+                byDirectory: report.byDirectory,
+                stats: { total: report.total, dated: report.dated, returned: report.items.length },
+              },
+              toolMeta(requestId, startTime),
+              warnings.length > 0 ? warnings : undefined,
+            ),
+          );
+        }
+
+        case "find_tests_for": {
+          const { symbol, filePath, package: qualifier, entityType, depth, limit } = FindTestsForSchema.parse(args);
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const normalizedPath = filePath ? normalizeInputPath(filePath) : undefined;
+          const result = await findTestsFor(storage, {
+            symbol,
+            filePath: normalizedPath,
+            qualifier,
+            entityType,
+            depth,
+            limit,
+          });
+
+          if (result.definitions.length === 0) {
+            return asMcpJson(
+              toolFail(
+                "not_found",
+                `Symbol not found: ${symbol}`,
+                { symbol, filePath: normalizedPath ?? null, package: qualifier ?? null, entityType: entityType ?? null },
+                toolMeta(requestId, startTime),
+              ),
+            );
           }
 
+          const mapTestEntity = (entity: TestEntity) => ({
+            ...entity,
+            filePath: normalizeInputPath(entity.filePath) ?? entity.filePath,
+          });
+
           return asMcpJson(
             toolOk(
-              { metric, limit: limit ?? 10, hotspots, sampleSize: relationshipSample.relationships.length },
+              {
+                symbol: result.symbol,
+                definitions: result.definitions.map((d) => ({
+                  definition: mapTestEntity(d.definition),
+                  tests: d.tests.map((t) => ({
+                    ...t,
+                    test: mapTestEntity(t.test),
+                    through: t.through ? mapTestEntity(t.through) : null,
+                  })),
+                })),
+                stats: { totalTests: result.total, depth },
+              },
               toolMeta(requestId, startTime),
+              result.truncated ? ["tests_truncated"] : undefined,
             ),
           );
         }
 
-        case "find_related_concepts": {
-          const { entityId, limit } = FindRelatedConceptsSchema.parse(args);
-          await ensureSemanticsReady(1, 20000);
+        case "find_tested_by": {
+          const { test, filePath, limit } = FindTestedBySchema.parse(args);
           const storage = await getGraphStorage(globalSQLiteManager);
-          const entity = await resolveEntity(storage, entityId);
-          if (!entity) {
+          const normalizedPath = filePath ? normalizeInputPath(filePath) : undefined;
+          const result = await findTestedBy(storage, { test, filePath: normalizedPath, limit });
+
+          if (result.tests.length === 0) {
             return asMcpJson(
-              toolFail("not_found", `Entity not found: ${entityId}`, { entityId }, toolMeta(requestId, startTime)),
+              toolFail(
+                "not_found",
+                test ? `Test not found: ${test}` : `No tests declared in ${normalizedPath}`,
+                { test: test ?? null, filePath: normalizedPath ?? null },
+                toolMeta(requestId, startTime),
+              ),
             );
           }
 
-          // Read code snippet for this entity using stored location
-          const fs = await import("node:fs/promises");
-          const entityFilePath = normalizeInputPath(entity.filePath) ?? entity.filePath;
-          let snippet = "";
-          try {
-            const full = await fs.readFile(entityFilePath, "utf8");
-            const startIdx =
-              typeof (entity as any).location?.start?.index === "number" ? (entity as any).location.start.index : 0;
-            const endIdx =
-              typeof (entity as any).location?.end?.index === "number"
-                ? (entity as any).location.end.index
-                : full.length;
-            if (endIdx > startIdx && endIdx - startIdx < 10000) {
-              snippet = full.slice(startIdx, endIdx);
-            } else {
-              // Fallback to line range if indices are missing
-              const sLine = (entity as any).location?.start?.line ? (entity as any).location.start.line - 1 : 0;
-              const eLine = (entity as any).location?.end?.line ? (entity as any).location.end.line : sLine + 10;
-              const lines = full.split(/\r?\n/);
-              snippet = lines.slice(Math.max(0, sLine), Math.min(lines.length, eLine)).join("\n");
-              if (snippet.length > 10000) snippet = snippet.slice(0, 10000);
-            }
-          } catch {
-            // If read fails, return empty
-            snippet = "";
-          }
-
-          let results: unknown = [];
-          try {
-            const semanticAgent = await getSemanticAgent();
-            const timeoutMs = config.mcp.agents?.defaultTimeout || config.mcp.server?.timeout || 600000;
-            const sim = await withTimeout(
-              semanticAgent.findSimilarCode(snippet, 0.65),
-              timeoutMs,
-              "find_related_concepts",
-              requestId,
-            );
-            results = Array.isArray(sim) && limit ? sim.slice(0, limit) : sim;
-          } catch (error) {
-            logger.warn(
-              "SEMANTIC_RELATED",
-              "Related concepts fallback",
-              { entityId, error: (error as Error).message },
-              requestId,
-            );
-            results = [];
-          }
+          const mapTestEntity = (entity: TestEntity) => ({
+            ...entity,
+            filePath: normalizeInputPath(entity.filePath) ?? entity.filePath,
+          });
 
           return asMcpJson(
             toolOk(
-              { entity: { id: entity.id, name: entity.name, filePath: entityFilePath }, related: results },
+              {
+                test: result.test,
+                tests: result.tests.map((t) => ({
+                  ...t,
+                  test: mapTestEntity(t.test),
+                  covers: t.covers.map((c) => ({ ...c, entity: mapTestEntity(c.entity) })),
+                })),
+                stats: { totalCovered: result.total },
+              },
               toolMeta(requestId, startTime),
+              result.truncated ? ["covered_truncated"] : undefined,
             ),
           );
         }
 
-        case "get_graph": {
-          const { query, limit, cursor, pageSize } = GetGraphSchema.parse(args);
-          const effectivePageSize = pageSize ?? limit ?? 100;
-          const cursorState = decodeCursor<{ o?: number }>(cursor) ?? {};
-          const offset = Math.max(0, Number(cursorState.o ?? 0) || 0);
-
-          // Use direct database query instead of going through agents
-          const storage = await getGraphStorage(globalSQLiteManager);
-          const raw = await queryGraphEntities(storage, query, effectivePageSize + 1, offset);
-          const hasMore = raw.entities.length > effectivePageSize;
-          const entities = hasMore ? raw.entities.slice(0, effectivePageSize) : raw.entities;
-          const idSet = new Set(entities.map((e) => e.id));
-          const relations = await collectRelationsWithin(storage, idSet);
+        case "batch": {
+          const { requests, concurrency } = BatchSchema.parse(args ?? {});
 
-          logger.info(
-            "GRAPH_QUERY",
-            `Retrieved graph with ${entities.length} entities and ${relations.length} relationships`,
-            {
-              query,
-              limit: effectivePageSize,
-              offset,
-              stats: raw.stats,
+          const batch = await runBatch(
+            requests,
+            async (sub, index) => {
+              if (sub.tool === "batch") {
+                return toolFail("invalid_args", "batch cannot be nested", { index }, toolMeta(requestId, startTime));
+              }
+              const subId = `${requestId}.${index}`;
+              const response = await executeToolCall(sub.tool, sub.args ?? {}, subId, Date.now(), call);
+              return JSON.parse(response.content[0]!.text) as ToolEnvelope<unknown>;
             },
-            requestId,
+            concurrency,
           );
+          const warnings = batch.failed > 0 ? ["partial_failure"] : [];
 
-          return asMcpJson(
-            toolOk(
-              {
-                query,
-                page: {
-                  offset,
-                  pageSize: effectivePageSize,
-                  nextCursor: hasMore ? encodeCursor({ o: offset + effectivePageSize }) : null,
-                },
-                entities,
-                relations,
-                stats: {
-                  totalNodes: raw.stats.totalEntities,
-                  totalRelations: raw.stats.totalRelationships,
-                },
-              },
-              toolMeta(requestId, startTime),
-            ),
-          );
+          return asMcpJson(toolOk(batch, toolMeta(requestId, startTime), warnings));
         }
 
-        case "analyze_code_impact": {
-          const { entityId, filePath: hintFilePath, depth } = AnalyzeCodeImpactSchema.parse(args);
-          const storage = await getGraphStorage(globalSQLiteManager);
-          const resolvedHintPath = hintFilePath ? normalizeInputPath(hintFilePath) : undefined;
+        case "get_task_result": {
+          const { taskId } = GetTaskResultSchema.parse(args);
+          const cond = await getConductor();
+          const record = cond.getTaskResult(taskId);
+          if (!record) {
+            return asMcpJson(
+              toolFail("not_found", `No delegated task with id ${taskId}`, { taskId }, toolMeta(requestId, startTime)),
+            );
+          }
+          return asMcpJson(toolOk(record, toolMeta(requestId, startTime)));
+        }
 
-          let entity = await storage.getEntity(entityId);
-          if (!entity) {
-            entity = await resolveEntityWithHint(storage, entityId, resolvedHintPath);
+        case "get_index_progress": {
+          const { taskId } = GetIndexProgressSchema.parse(args ?? {});
+          const progress = taskId ? indexProgress.get(taskId) : indexProgress.latest();
+          if (!progress) {
+            const message = taskId ? `No indexing run with id ${taskId}` : "No indexing run has started yet";
+            return asMcpJson(toolFail("not_found", message, { taskId }, toolMeta(requestId, startTime)));
           }
+          return asMcpJson(toolOk(progress, toolMeta(requestId, startTime)));
+        }
 
-          if (!entity) {
+        case "cancel_task": {
+          const { taskId } = CancelTaskSchema.parse(args);
+          const cond = await getConductor();
+          const outcome = cond.cancelTask(taskId);
+          if (!outcome) {
             return asMcpJson(
-              toolFail("not_found", `Entity not found: ${entityId}`, { entityId }, toolMeta(requestId, startTime)),
+              toolFail(
+                "not_found",
+                `No running or recorded task with id ${taskId}`,
+                { taskId },
+                toolMeta(requestId, startTime),
+              ),
             );
           }
+          return asMcpJson(toolOk(outcome, toolMeta(requestId, startTime)));
+        }
 
-          const traversal = await analyzeCodeImpactTraversal(storage, entity.id, depth ?? 2);
-
-          const directEntities = (
-            await Promise.all(Array.from(traversal.directDependents).map((id) => storage.getEntity(id)))
-          ).filter((e): e is Entity => Boolean(e));
-          const indirectEntities = (
-            await Promise.all(Array.from(traversal.transitiveDependents).map((id) => storage.getEntity(id)))
-          ).filter((e): e is Entity => Boolean(e));
+        case "export_index":
+        case "import_index": {
+          const graphDb = globalSQLiteManager.getPath();
+          if (graphDb === ":memory:") {
+            return asMcpJson(
+              toolFail(
+                "invalid_args",
+                "An in-memory index cannot be archived",
+                undefined,
+                toolMeta(requestId, startTime),
+              ),
+            );
+          }
+          const vectorDb: string | undefined = semanticAgentInstance?.getVectorDbPath();
 
-          const visibleDirectEntities = directEntities.filter((e) => !(e.metadata as any)?.isExternal);
-          const visibleIndirectEntities = indirectEntities.filter((e) => !(e.metadata as any)?.isExternal);
+          try {
+            if (name === "export_index") {
+              const { path: archivePath } = ExportIndexSchema.parse(args);
+              const storage = await getGraphStorage(globalSQLiteManager);
+              const lastRun = (await storage.listIndexRuns(50)).find((run) => run.status === "completed");
+              // A remote checkout's paths sit under the clone cache, so that is the directory to record
+              const sourceDir = lastRun?.directory ?? directory;
+              const origin = lastRun?.repository ?? (await originUrl(sourceDir));
+              const exported = await exportIndex({
+                path: normalizeInputPath(archivePath),
+                graphDb,
+                vectorDb,
+                source: {
+                  directory: sourceDir,
+                  repository: origin ? redactGitUrl(origin) : null,
+                  commit: lastRun?.gitHead ?? (await headCommit(sourceDir)),
+                },
+              });
+              logger.systemEvent("Index exported", { path: exported.path, bytes: exported.bytes });
+              const warnings = lastRun ? [] : ["no_completed_index_run"];
+              return asMcpJson(toolOk(exported, toolMeta(requestId, startTime), warnings));
+            }
 
-          const affectedFiles = new Set<string>();
-          for (const sample of [...visibleDirectEntities, ...visibleIndirectEntities]) {
-            affectedFiles.add(normalizeInputPath(sample.filePath) ?? sample.filePath);
+            const { path: archivePath, directory: targetArg } = ImportIndexSchema.parse(args);
+            const targetDir = normalizeInputPath(targetArg) ?? directory;
+            const imported = await importIndex({
+              path: normalizeInputPath(archivePath),
+              graphDb,
+              vectorDb,
+              directory: targetDir,
+            });
+            // Fresh storage caches, and migrations for an archive from an older server
+            resetGraphStorage();
+            await getGraphStorage(globalSQLiteManager);
+            if (semanticAgentInstance) await semanticAgentInstance.reloadVectorStore();
+            logger.systemEvent("Index imported", { path: archivePath, ...imported.manifest.counts });
+
+            const warnings: string[] = [];
+            const { source, embedding } = imported.manifest;
+            const localHead = await headCommit(targetDir);
+            if (source.commit && localHead && localHead !== source.commit) warnings.push("source_commit_differs");
+            const embedder = semanticAgentInstance?.getEmbeddingSource?.();
+            if (embedding && embedder?.model && embedder.model !== embedding.model) {
+              warnings.push("embedding_model_mismatch");
+            }
+            if (!imported.vectorsImported) warnings.push("vectors_not_imported");
+            return asMcpJson(
+              toolOk({ ...imported, directory: targetDir, localHead }, toolMeta(requestId, startTime), warnings),
+            );
+          } catch (error) {
+            if (!(error instanceof IndexArchiveError)) throw error;
+            return asMcpJson(toolFail(error.reason, error.message, error.details, toolMeta(requestId, startTime)));
           }
+        }
 
-          const totalImpact = visibleDirectEntities.length + visibleIndirectEntities.length;
-          const riskLevel =
-            totalImpact > 50 ? "critical" : totalImpact > 20 ? "high" : totalImpact > 5 ? "medium" : "low";
-
-          const outboundSummaries = (
-            await Promise.all(
-              Array.from(traversal.outboundDependencies).map(async (id) => {
-                const dep = await storage.getEntity(id);
-                return dep ? mapEntitySummary(dep) : null;
-              }),
-            )
-          ).filter((item): item is ReturnType<typeof mapEntitySummary> => Boolean(item));
+        case "compact_index": {
+          const { vacuum } = CompactIndexSchema.parse(args ?? {});
+          const storage = await getGraphStorage(globalSQLiteManager);
+          // Vectors first: a store in the graph file is vacuumed by the graph compaction below
+          const vectors = semanticAgentInstance
+            ? await semanticAgentInstance.compactVectors(getSQLiteManagerOrThrow().getPath(), vacuum)
+            : undefined;
+          const graph = await storage.compact({ vacuum });
+          logger.systemEvent("Index compacted", { ...graph, vectors });
+          return asMcpJson(
+            toolOk(
+              {
+                ...graph,
+                bytesFreed: Math.max(0, graph.bytesBefore - graph.bytesAfter),
+                annNodesDropped: vectors?.annNodesDropped ?? 0,
+                vectorStoreVacuumed: vectors?.vacuumed ?? false,
+              },
+              toolMeta(requestId, startTime),
+            ),
+          );
+        }
 
-          const impact = {
-            source: mapEntitySummary(entity),
-            directImpacts: visibleDirectEntities.map((item) => mapEntitySummary(item)),
-            indirectImpacts: visibleIndirectEntities.map((item) => mapEntitySummary(item)),
-            outboundDependencies: outboundSummaries,
-            affectedFiles: Array.from(affectedFiles),
-            riskLevel,
-            depthUsed: traversal.depthUsed,
-            totals: {
-              direct: visibleDirectEntities.length,
-              indirect: visibleIndirectEntities.length,
-              outbound: traversal.outboundDependencies.size,
+        case "reembed": {
+          ReembedSchema.parse(args ?? {});
+          if (process.env.MCP_DEBUG_DISABLE_SEMANTIC === "1") {
+            return asMcpJson(
+              toolFail(
+                "semantic_disabled",
+                "Semantic search is disabled; there are no vectors to rebuild",
+                undefined,
+                toolMeta(requestId, startTime),
+              ),
+            );
+          }
+          const semantic = await getSemanticAgent();
+          const reembedded = await semantic.reembedAll({
+            signal: call.signal,
+            onProgress: (done, total) => {
+              const message = `reembed: ${done}/${total} entities`;
+              if (call.progressToken !== undefined) sendProgress(call.progressToken, done, total, message);
+              logger.debug("REEMBED", message, undefined, requestId);
             },
-          };
+          });
+          logger.systemEvent("Vectors rebuilt", reembedded);
+          return asMcpJson(toolOk(reembedded, toolMeta(requestId, startTime)));
+        }
 
-          return asMcpJson(toolOk(impact, toolMeta(requestId, startTime)));
+        case "verify_index": {
+          const { repair, sampleLimit } = VerifyIndexSchema.parse(args ?? {});
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const semantic = semanticAgentInstance;
+          const report = await verifyIndex(storage, {
+            schema: new SchemaMigration(globalSQLiteManager).status(),
+            vectors: semantic
+              ? { list: () => semantic.listVectorEntityRefs(), remove: (ids) => semantic.deleteVectors(ids) }
+              : undefined,
+            repair,
+            sampleLimit,
+          });
+          if (repair && (report.relationships.removed > 0 || (report.vectors?.removed ?? 0) > 0)) {
+            logger.systemEvent("Index repaired", {
+              relationshipsRemoved: report.relationships.removed,
+              vectorsRemoved: report.vectors?.removed ?? 0,
+            });
+          }
+
+          const warnings: string[] = [];
+          if (!report.vectors) warnings.push("vectors_not_checked");
+          if (!report.schema.ok) warnings.push("schema_mismatch");
+          return asMcpJson(toolOk(report, toolMeta(requestId, startTime), warnings));
         }
 
         case "list_module_importers": {
@@ -2867,6 +7056,27 @@ async function executeToolCall(name: string, args: unknown, requestId: string, s
           return asMcpJson(toolOk(payload, toolMeta(requestId, startTime)));
         }
 
+        case "graph_stats": {
+          GraphStatsSchema.parse(args ?? {});
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const semantic = semanticAgentInstance;
+          const stats = await collectGraphStats(storage, {
+            dbPaths: [getSQLiteManagerOrThrow().getPath(), ...(semantic ? [semantic.getVectorDbPath()] : [])],
+            countEmbedded: semantic ? (ids) => semantic.countEmbeddedEntities(ids) : undefined,
+          });
+          const otherServers = (await storage.listForeignIndexRuns()).map(({ id, pid, directory, startedAt }) => ({
+            runId: id,
+            pid,
+            directory,
+            startedAt,
+          }));
+          const server = { ...serverActivity.snapshot(name), otherServers };
+
+          logger.info("GRAPH_STATS", "Collected graph statistics", { warnings: stats.warnings.length }, requestId);
+
+          return asMcpJson(toolOk({ ...stats, server }, toolMeta(requestId, startTime)));
+        }
+
         case "get_graph_stats": {
           const storage = await getGraphStorage(globalSQLiteManager);
           const stats = await getGraphStats(storage);
@@ -2956,6 +7166,38 @@ async function executeToolCall(name: string, args: unknown, requestId: string, s
           );
         }
 
+        case "export_graph": {
+          const { format, outputPath } = ExportGraphSchema.parse(args ?? {});
+          const targetPath = normalizeInputPath(outputPath) ?? join(directory, ".code-graph-rag", `graph.${format}`);
+          const storage = await getGraphStorage(globalSQLiteManager);
+          const totalRelationships = (await storage.getMetrics()).totalRelationships ?? 0;
+
+          const exported = await exportGraph(storage, format, targetPath, {
+            onProgress: (progress) => {
+              const done = progress.nodes + progress.edges;
+              const total = progress.totalNodes + progress.totalEdges;
+              const message =
+                `${progress.phase}: ${progress.nodes}/${progress.totalNodes} nodes, ` +
+                `${progress.edges}/${progress.totalEdges} edges`;
+              if (call.progressToken !== undefined) sendProgress(call.progressToken, done, total, message);
+              logger.debug("EXPORT_GRAPH", message, { outputPath: targetPath, format }, requestId);
+            },
+          });
+          const skippedEdges = Math.max(0, totalRelationships - exported.edges);
+
+          logger.info("EXPORT_GRAPH", "Exported code graph", { ...exported, skippedEdges }, requestId);
+
+          return asMcpJson(
+            toolOk(
+              { ...exported, skippedEdges },
+              toolMeta(requestId, startTime),
+              skippedEdges > 0
+                ? [`${skippedEdges} relationships point at entities that are not indexed and were left out`]
+                : undefined,
+            ),
+          );
+        }
+
         case "get_graph_health": {
           const { minEntities, minRelationships, sample } = GetGraphHealthSchema.parse(args ?? {});
           const storage = await getGraphStorage(globalSQLiteManager);
@@ -2972,6 +7214,7 @@ async function executeToolCall(name: string, args: unknown, requestId: string, s
           const reason = healthy
             ? "OK"
             : `Mismatch: totals e=${metrics.totalEntities}, r=${metrics.totalRelationships}, sample=${sampleQuery.entities.length}`;
+          const { version, latestVersion, modified } = new SchemaMigration(globalSQLiteManager).status();
 
           logger.info(
             "GRAPH_HEALTH",
@@ -3000,6 +7243,9 @@ async function executeToolCall(name: string, args: unknown, requestId: string, s
                   files: metrics.totalFiles,
                 },
                 sampleCount: sampleQuery.entities.length,
+                schema: { version, latestVersion, modified },
+                lastIndexRun: (await storage.listIndexRuns(1))[0] ?? null,
+                recovery: indexRecovery,
               },
               toolMeta(requestId, startTime),
             ),
@@ -3056,6 +7302,26 @@ async function executeToolCall(name: string, args: unknown, requestId: string, s
       return asMcpJson(toolFail("agent_busy", errorMessage, error.details, toolMeta(requestId, startTime)));
     }
 
+    if (error instanceof ToolTimeoutError) {
+      return asMcpJson(toolFail("timeout", errorMessage, error.details, toolMeta(requestId, startTime)));
+    }
+
+    if (error instanceof TaskCancelledError) {
+      logger.info("CANCELLED", `${name} stopped: ${errorMessage}`, { details: error.details }, requestId);
+
+      return asMcpJson(toolFail("cancelled", errorMessage, error.details, toolMeta(requestId, startTime)));
+    }
+
+    if (error instanceof EmbeddingDimensionError) {
+      return asMcpJson(toolFail("reindex_required", errorMessage, error.details, toolMeta(requestId, startTime)));
+    }
+
+    if (error instanceof SchemaVersionError) {
+      logger.error("SCHEMA", errorMessage, { details: error.details }, requestId);
+
+      return asMcpJson(toolFail("schema_mismatch", errorMessage, error.details, toolMeta(requestId, startTime)));
+    }
+
     logger.mcpError(name, error instanceof Error ? error : new Error(errorMessage), requestId);
 
     return asMcpJson(toolFail("tool_error", errorMessage, undefined, toolMeta(requestId, startTime)));
@@ -3063,14 +7329,26 @@ async function executeToolCall(name: string, args: unknown, requestId: string, s
 }
 
 // Handler for tool execution
-server.setRequestHandler(CallToolRequestSchema as any, async (request: any) => {
+server.setRequestHandler(CallToolRequestSchema as any, async (request: any, extra: any) => {
   const { name, arguments: args } = request.params;
   const requestId = createRequestId();
   const startTime = Date.now();
 
   logger.mcpRequest(name, args, requestId);
 
-  return executeToolCall(name, args, requestId, startTime);
+  // Shutdown aborts the call like a client cancellation would
+  const signal = extra?.signal ? AbortSignal.any([extra.signal, lifecycle.signal]) : lifecycle.signal;
+  return lifecycle
+    .track(() =>
+      executeToolCall(name, args, requestId, startTime, {
+        signal,
+        progressToken: request.params?._meta?.progressToken,
+      }),
+    )
+    .catch((error) => {
+      if (!(error instanceof ShuttingDownError)) throw error;
+      return asMcpJson(toolFail("shutting_down", error.message, undefined, toolMeta(requestId, startTime)));
+    });
 });
 
 async function processDebugRequests(requests: DebugRequest[]): Promise<void> {
@@ -3104,22 +7382,51 @@ async function processDebugRequests(requests: DebugRequest[]): Promise<void> {
   }
 }
 
-// Graceful shutdown
-process.on("SIGINT", async () => {
-  console.log("\nShutting down gracefully...");
-  logger.systemEvent("MCP Server Shutdown Initiated");
+/**
+ * Stop accepting work, let running index runs stop after the batch they are writing, then close
+ * the database after a WAL checkpoint. A file whose write the timeout cuts off is journaled and
+ * dropped at the next start; a second signal exits at once.
+ */
+async function shutdownGracefully(signal: NodeJS.Signals): Promise<void> {
+  const serverLog = getLog("server");
+  if (lifecycle.stopping) {
+    serverLog.warn("second signal during shutdown; exiting now", { signal, pending: lifecycle.pending });
+    process.exit(1);
+  }
+  serverLog.info("shutting down", { signal, pending: lifecycle.pending });
+  logger.systemEvent("MCP Server Shutdown Initiated", { signal });
+
+  stopIndexWatcher();
+  const drain = await lifecycle.shutdown(ConfigLoader.getInstance().getShutdownTimeoutMs());
+  if (!drain.drained) {
+    serverLog.warn("running work did not stop in time; interrupted files are recovered at the next start", {
+      ...drain,
+    });
+  }
 
   if (conductor) {
-    await conductor.shutdown();
+    await conductor.shutdown().catch((error) => serverLog.error("conductor shutdown failed", { error }));
     logger.systemEvent("Conductor Shutdown Complete");
   }
 
   resourceManager.stopMonitoring();
   logger.systemEvent("Resource Manager Stopped");
-  logger.systemEvent("MCP Server Shutdown Complete");
+
+  if (globalSQLiteManager?.isOpen()) {
+    try {
+      globalSQLiteManager.checkpoint();
+      globalSQLiteManager.close();
+    } catch (error) {
+      serverLog.error("closing the database failed", { error });
+    }
+  }
+  logger.systemEvent("MCP Server Shutdown Complete", { ...drain });
 
   process.exit(0);
-});
+}
+
+process.on("SIGINT", () => void shutdownGracefully("SIGINT"));
+process.on("SIGTERM", () => void shutdownGracefully("SIGTERM"));
 
 // Debug signal: dump runtime state (aligns with SYSTEM_HANG_RECOVERY_PLAN)
 process.on("SIGUSR1", async () => {
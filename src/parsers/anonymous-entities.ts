@@ -0,0 +1,65 @@
+/**
+ * Anonymous declarations - callbacks, closures, lambdas, object expressions, unnamed C structs - get
+ * placeholder names from the extractors and flood search with look-alike hits. Every one of them is
+ * tagged `metadata.isAnonymous`, so search can leave them out, and copies within a file whose
+ * source is identical up to whitespace are collapsed into the first, which records where the
+ * others were. Named declarations are never collapsed.
+ */
+
+import type { EntityRelationship, ParsedEntity } from "../types/parser.js";
+
+/** Names the extractors give declarations that have none of their own */
+export const ANONYMOUS_NAMES: readonly string[] = ["<anonymous>", "<lambda>", "anonymous"];
+
+export function isAnonymousEntity(entity: { name: string; metadata?: Record<string, unknown> }): boolean {
+  return entity.metadata?.isAnonymous === true || ANONYMOUS_NAMES.includes(entity.name);
+}
+
+function structureKey(entity: ParsedEntity, source: string): string | null {
+  const start = entity.location?.start?.index;
+  const end = entity.location?.end?.index;
+  if (typeof start !== "number" || typeof end !== "number" || end <= start) return null;
+  return `${entity.type}\u0000${source.slice(start, end).replace(/\s+/g, " ").trim()}`;
+}
+
+/**
+ * Tag anonymous entities and keep one of each structurally identical group. Relationships of a
+ * dropped copy are moved to the one kept; `metadata.duplicates` counts the copies and
+ * `metadata.duplicateLines` says where they started.
+ */
+export function collapseAnonymousEntities<R extends Pick<EntityRelationship, "from" | "to">>(
+  entities: ParsedEntity[],
+  relationships: R[],
+  source: string,
+): { entities: ParsedEntity[]; relationships: R[] } {
+  const kept = new Map<string, ParsedEntity>();
+  const movedTo = new Map<string, string>();
+  const result: ParsedEntity[] = [];
+
+  for (const entity of entities) {
+    if (!isAnonymousEntity(entity)) {
+      result.push(entity);
+      continue;
+    }
+    const tagged: ParsedEntity = { ...entity, metadata: { ...entity.metadata, isAnonymous: true } };
+    const key = structureKey(entity, source);
+    const first = key ? kept.get(key) : undefined;
+    if (!first) {
+      if (key) kept.set(key, tagged);
+      result.push(tagged);
+      continue;
+    }
+    first.metadata!.duplicates = Number(first.metadata!.duplicates ?? 0) + 1;
+    first.metadata!.duplicateLines = [
+      ...((first.metadata!.duplicateLines as number[] | undefined) ?? []),
+      entity.location.start.line,
+    ];
+    if (entity.id && first.id) movedTo.set(entity.id, first.id);
+  }
+
+  if (movedTo.size === 0) return { entities: result, relationships };
+  const moved = relationships
+    .map((rel) => ({ ...rel, from: movedTo.get(rel.from) ?? rel.from, to: movedTo.get(rel.to) ?? rel.to }))
+    .filter((rel) => rel.from !== rel.to);
+  return { entities: result, relationships: moved };
+}
@@ -6,13 +6,23 @@
  *  - Structs, unions, enums, typedefs
  *  - Global variables and constants
  *  - Macros and preprocessor directives
- *  - Include relationships
+ *  - Include relationships from the translation unit
+ *  - Calls between functions of the translation unit
  *
  * Implementation follows patterns from CSharpAnalyzer and RustAnalyzer
  * with circuit breakers for safety.
  */
 
 import type { EntityRelationship, ParsedEntity, TreeSitterNode } from "../types/parser.js";
+import {
+  callRelationship,
+  collectCallSites,
+  findFunctionDeclarator,
+  functionParameters,
+  includeRelationship,
+  macroEntity,
+  translationUnitEntity,
+} from "./c-family.js";
 
 // Circuit breaker constants
 const MAX_RECURSION_DEPTH = 50;
@@ -34,6 +44,7 @@ export class CAnalyzer {
     const relationships: EntityRelationship[] = [];
 
     try {
+      entities.push(translationUnitEntity(filePath));
       // Extract all top-level entities
       this.extractEntities(rootNode, filePath, entities, relationships);
     } catch (error) {
@@ -41,9 +52,26 @@ export class CAnalyzer {
       // Return partial results on error
     }
 
+    this.bindCalls(entities, relationships);
     return { entities, relationships };
   }
 
+  /**
+   * Point calls at the function defined in this file; anything else stays a bare name for the
+   * header linker to find
+   */
+  private bindCalls(entities: ParsedEntity[], relationships: EntityRelationship[]): void {
+    const defined = new Map<string, string>();
+    for (const entity of entities) {
+      if (entity.type === "function" && entity.id && entity.metadata?.isDefinition) defined.set(entity.name, entity.id);
+    }
+    for (const rel of relationships) {
+      if (rel.type !== "calls" || rel.metadata?.callType !== "function") continue;
+      const target = defined.get(rel.to);
+      if (target) rel.to = target;
+    }
+  }
+
   /**
    * Extract entities from the AST with recursion protection
    */
@@ -67,8 +95,9 @@ export class CAnalyzer {
     // Process node based on type
     switch (node.type) {
       case "function_definition":
-        this.extractFunction(node, entities);
-        break;
+        // Locals and statements of the body are not entities; only its calls are kept
+        this.extractFunction(node, filePath, entities, relationships);
+        return;
 
       case "declaration":
         this.extractDeclaration(node, entities);
@@ -91,16 +120,17 @@ export class CAnalyzer {
         break;
 
       case "preproc_def":
-        this.extractMacro(node, entities);
-        break;
-
-      case "preproc_include":
-        this.extractInclude(node, filePath, relationships);
+      case "preproc_function_def": {
+        const macro = macroEntity(node);
+        if (macro) entities.push(macro);
         break;
+      }
 
-      case "preproc_function_def":
-        this.extractFunctionMacro(node, entities);
+      case "preproc_include": {
+        const include = includeRelationship(node, filePath);
+        if (include) relationships.push(include);
         break;
+      }
     }
 
     // Recurse through children
@@ -113,9 +143,14 @@ export class CAnalyzer {
   }
 
   /**
-   * Extract function definitions (with body)
+   * Extract function definitions (with body) and the calls made in the body
    */
-  private extractFunction(node: TreeSitterNode, entities: ParsedEntity[]): void {
+  private extractFunction(
+    node: TreeSitterNode,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+  ): void {
     const declaratorNode = node.childForFieldName("declarator");
     if (!declaratorNode) return;
 
@@ -124,13 +159,43 @@ export class CAnalyzer {
 
     const name = this.getNodeText(nameNode);
     const modifiers = this.collectModifiers(node);
+    const id = `${filePath}:function:${name}`;
 
     entities.push({
+      id,
       name,
       type: "function",
       location: this.getNodeLocation(node),
       modifiers: modifiers.length ? modifiers : undefined,
+      parameters: this.parametersOf(declaratorNode),
+      metadata: { isDefinition: true },
     });
+
+    const body = node.childForFieldName("body");
+    if (body) {
+      for (const call of collectCallSites(body)) relationships.push(callRelationship(id, call));
+    }
+  }
+
+  /**
+   * A prototype (`int add(int, int);`), to be linked to its definition once both are indexed
+   */
+  private functionPrototype(node: TreeSitterNode, declarator: TreeSitterNode, mods: string[]): ParsedEntity | null {
+    const fnameNode = this.getFunctionName(declarator);
+    if (!fnameNode) return null;
+    return {
+      name: this.getNodeText(fnameNode),
+      type: "function",
+      location: this.getNodeLocation(node),
+      modifiers: mods.length ? mods : undefined,
+      parameters: this.parametersOf(declarator),
+      metadata: { isDefinition: false },
+    };
+  }
+
+  private parametersOf(declarator: TreeSitterNode): Array<{ name: string; type?: string }> | undefined {
+    const fn = findFunctionDeclarator(declarator);
+    return fn ? functionParameters(fn) : undefined;
   }
 
   /**
@@ -148,15 +213,8 @@ export class CAnalyzer {
         if (!dec) continue;
 
         if (this.isFunctionDeclarator(dec)) {
-          const fnameNode = this.getFunctionName(dec);
-          if (!fnameNode) continue;
-          const name = this.getNodeText(fnameNode);
-          entities.push({
-            name,
-            type: "function",
-            location: this.getNodeLocation(node),
-            modifiers: mods.length ? mods : undefined,
-          });
+          const prototype = this.functionPrototype(node, dec, mods);
+          if (prototype) entities.push(prototype);
         } else {
           const vnameNode = this.getDeclaratorName(dec);
           if (!vnameNode) continue;
@@ -175,15 +233,8 @@ export class CAnalyzer {
     const declaratorNode = node.childForFieldName("declarator");
     if (declaratorNode) {
       if (this.isFunctionDeclarator(declaratorNode)) {
-        const fname = this.getFunctionName(declaratorNode);
-        if (fname) {
-          entities.push({
-            name: this.getNodeText(fname),
-            type: "function",
-            location: this.getNodeLocation(node),
-            modifiers: mods.length ? mods : undefined,
-          });
-        }
+        const prototype = this.functionPrototype(node, declaratorNode, mods);
+        if (prototype) entities.push(prototype);
         return;
       }
       const nameNode = this.getDeclaratorName(declaratorNode);
@@ -323,56 +374,6 @@ export class CAnalyzer {
     }
   }
 
-  /**
-   * Extract macro definitions
-   */
-  private extractMacro(node: TreeSitterNode, entities: ParsedEntity[]): void {
-    const nameNode = node.childForFieldName("name");
-    if (!nameNode) return;
-
-    const name = this.getNodeText(nameNode);
-    entities.push({
-      name: `#define ${name}`,
-      type: "constant",
-      location: this.getNodeLocation(node),
-    });
-  }
-
-  /**
-   * Extract function-like macros
-   */
-  private extractFunctionMacro(node: TreeSitterNode, entities: ParsedEntity[]): void {
-    const nameNode = node.childForFieldName("name");
-    if (!nameNode) return;
-
-    const name = this.getNodeText(nameNode);
-    entities.push({
-      name: `#define ${name}()`,
-      type: "function",
-      location: this.getNodeLocation(node),
-      modifiers: ["macro"],
-    });
-  }
-
-  /**
-   * Extract include directives as relationships
-   */
-  private extractInclude(node: TreeSitterNode, filePath: string, relationships: EntityRelationship[]): void {
-    const pathNode =
-      node.childForFieldName("path") || this.findFirstNamedChildByTypes(node, ["system_lib_string", "string_literal"]);
-    if (!pathNode) return;
-
-    const raw = this.getNodeText(pathNode);
-    const includePath = raw.replace(/[<">]/g, "");
-    relationships.push({
-      from: filePath,
-      to: includePath,
-      type: "imports",
-      sourceFile: filePath,
-      metadata: { line: node.startPosition.row + 1 },
-    });
-  }
-
   /**
    * Helper: Get function name from declarator
    */
@@ -0,0 +1,193 @@
+/**
+ * Extraction shared by the C and C++ analyzers: the translation-unit entity that `#include`
+ * edges start from, macros, function parameters and call sites in function bodies.
+ */
+
+import { basename } from "node:path";
+import type { CallArgument, EntityRelationship, ParsedEntity, TreeSitterNode } from "../types/parser.js";
+import { callArguments } from "./call-arguments.js";
+
+const HEADER_EXTENSIONS = /\.(h|hh|hpp|hxx|h\+\+)$/i;
+
+export function isHeaderFile(filePath: string): boolean {
+  return HEADER_EXTENSIONS.test(filePath);
+}
+
+export function translationUnitId(filePath: string): string {
+  return `${filePath}:translation_unit`;
+}
+
+/** Module entity for the file itself; include edges leave from it and the header linker points them at one */
+export function translationUnitEntity(filePath: string): ParsedEntity {
+  return {
+    id: translationUnitId(filePath),
+    name: basename(filePath),
+    type: "module",
+    location: {
+      start: { line: 1, column: 0, index: 0 },
+      end: { line: 1, column: 0, index: 0 },
+    },
+    metadata: { translationUnit: true, isHeader: isHeaderFile(filePath) },
+  };
+}
+
+/** `#include "path"` / `#include <path>` as an imports edge from the translation unit */
+export function includeRelationship(node: TreeSitterNode, filePath: string): EntityRelationship | null {
+  const pathNode =
+    node.childForFieldName("path") ??
+    node.namedChildren.find((c) => c.type === "system_lib_string" || c.type === "string_literal");
+  if (!pathNode) return null;
+
+  const includePath = pathNode.text.replace(/[<">]/g, "").trim();
+  if (!includePath) return null;
+  return {
+    from: translationUnitId(filePath),
+    to: includePath,
+    type: "imports",
+    sourceFile: filePath,
+    metadata: { line: node.startPosition.row + 1, isSystem: pathNode.type === "system_lib_string" },
+  };
+}
+
+/** `#define NAME ...` and `#define NAME(args) ...` */
+export function macroEntity(node: TreeSitterNode): ParsedEntity | null {
+  const nameNode = node.childForFieldName("name");
+  if (!nameNode) return null;
+  const location = {
+    start: { line: node.startPosition.row + 1, column: node.startPosition.column, index: node.startIndex },
+    end: { line: node.endPosition.row + 1, column: node.endPosition.column, index: node.endIndex },
+  };
+  if (node.type === "preproc_function_def") {
+    return { name: `#define ${nameNode.text}()`, type: "function", location, modifiers: ["macro"] };
+  }
+  return { name: `#define ${nameNode.text}`, type: "constant", location };
+}
+
+const NAME_TYPES = new Set(["identifier", "field_identifier", "qualified_identifier", "destructor_name"]);
+
+// Reference and parenthesized declarators wrap the inner one without naming the field
+function innerDeclarator(node: TreeSitterNode): TreeSitterNode | null {
+  return (
+    node.childForFieldName("declarator") ??
+    node.namedChildren.find((c) => c.type.endsWith("declarator") || NAME_TYPES.has(c.type)) ??
+    null
+  );
+}
+
+/** The `function_declarator` inside pointer, reference and parenthesized declarators */
+export function findFunctionDeclarator(declarator: TreeSitterNode | null): TreeSitterNode | null {
+  for (let node = declarator; node; node = innerDeclarator(node)) {
+    if (node.type === "function_declarator") return node;
+    if (NAME_TYPES.has(node.type)) return null;
+  }
+  return null;
+}
+
+function declaredName(declarator: TreeSitterNode | null): TreeSitterNode | null {
+  for (let node = declarator; node; node = innerDeclarator(node)) {
+    if (NAME_TYPES.has(node.type)) return node;
+    // `char *` and `int []` in a prototype declare no name
+    if (node.type.startsWith("abstract_")) return null;
+  }
+  return null;
+}
+
+/**
+ * Parameters of a function declarator with the type as written minus the parameter name, so
+ * `const char *name` and a prototype's `const char *` compare equal. `(void)` has none.
+ */
+export function functionParameters(functionDeclarator: TreeSitterNode): Array<{ name: string; type?: string }> {
+  const list = functionDeclarator.childForFieldName("parameters");
+  if (!list) return [];
+
+  const params: Array<{ name: string; type?: string }> = [];
+  for (const param of list.namedChildren) {
+    if (param.type === "variadic_parameter" || param.type === "variadic_parameter_declaration") {
+      params.push({ name: "...", type: "..." });
+      continue;
+    }
+    if (param.type !== "parameter_declaration" && param.type !== "optional_parameter_declaration") continue;
+
+    const declarator = param.childForFieldName("declarator");
+    const name = declaredName(declarator);
+    // Default arguments are not part of the type
+    const end = param.childForFieldName("default_value")?.startIndex ?? param.endIndex;
+    let type = param.text.slice(0, end - param.startIndex);
+    if (name) {
+      const from = name.startIndex - param.startIndex;
+      type = type.slice(0, from) + type.slice(from + name.text.length);
+    }
+    type = type.replace(/=\s*$/, "").replace(/\s+/g, " ").trim();
+    if (type === "void" && !declarator && list.namedChildren.length === 1) break;
+    params.push({ name: name?.text ?? "", type });
+  }
+  return params;
+}
+
+export interface CallSite {
+  /** Callee as it would be declared: `helper`, `ns::helper`, or the member name of `obj.run()` */
+  name: string;
+  /** Call target as written, `obj.run` or `ns::helper` */
+  callee: string;
+  /** Object a member function is called on (`obj` in `obj->run()`) */
+  receiver?: string;
+  line: number;
+  column: number;
+  arguments?: CallArgument[];
+  arity?: number;
+}
+
+/**
+ * Calls in a function body, in source order. The body is walked with an explicit stack so deep
+ * expression nesting cannot hit a recursion limit.
+ */
+export function collectCallSites(body: TreeSitterNode): CallSite[] {
+  const calls: CallSite[] = [];
+  const stack: TreeSitterNode[] = [body];
+  while (stack.length > 0) {
+    const node = stack.pop()!;
+    if (node.type === "call_expression") {
+      let fn = node.childForFieldName("function");
+      if (fn?.type === "template_function") fn = fn.childForFieldName("name");
+      const args = callArguments(node.childForFieldName("arguments"));
+      if (fn?.type === "identifier" || fn?.type === "qualified_identifier") {
+        const name = fn.text.replace(/\s+/g, "");
+        calls.push({ name, callee: name, line: fn.startPosition.row + 1, column: fn.startPosition.column, ...args });
+      } else if (fn?.type === "field_expression") {
+        const field = fn.childForFieldName("field");
+        const receiver = fn.childForFieldName("argument")?.text;
+        if (field) {
+          calls.push({
+            name: field.text,
+            callee: fn.text.replace(/\s+/g, ""),
+            receiver,
+            line: field.startPosition.row + 1,
+            column: field.startPosition.column,
+            ...args,
+          });
+        }
+      }
+    }
+    const children = node.namedChildren;
+    for (let i = children.length - 1; i >= 0; i--) stack.push(children[i]!);
+  }
+  return calls;
+}
+
+/** A `calls` edge for a call site; `to` is the declared name until the analyzer binds it */
+export function callRelationship(fromId: string, call: CallSite): EntityRelationship {
+  return {
+    from: fromId,
+    to: call.name,
+    type: "calls",
+    metadata: {
+      callType: call.receiver ? "method" : "function",
+      line: call.line,
+      column: call.column,
+      callee: call.callee,
+      calleeName: call.name,
+      ...(call.arguments ? { arguments: call.arguments } : {}),
+      ...(call.arity !== undefined ? { arity: call.arity } : {}),
+    },
+  };
+}
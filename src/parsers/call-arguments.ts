@@ -0,0 +1,76 @@
+/**
+ * Call arguments - what a call site passes, as far as the syntax alone tells
+ * Literals keep their text, so callers passing `nil` or a given flag can be told apart; anything
+ * computed is only an `expression`; a spread stands for an unknown number of arguments.
+ */
+
+import type { CallArgument, TreeSitterNode } from "../types/parser.js";
+
+// Literal node kinds of the JS/TS, Go, Java and C/C++ grammars
+const LITERAL_NODES = new Set([
+  // JavaScript / TypeScript
+  "number",
+  "string",
+  "regex",
+  "true",
+  "false",
+  "null",
+  "undefined",
+  // Go
+  "int_literal",
+  "float_literal",
+  "imaginary_literal",
+  "rune_literal",
+  "interpreted_string_literal",
+  "raw_string_literal",
+  "nil",
+  // Java
+  "decimal_integer_literal",
+  "hex_integer_literal",
+  "octal_integer_literal",
+  "binary_integer_literal",
+  "decimal_floating_point_literal",
+  "hex_floating_point_literal",
+  "string_literal",
+  "character_literal",
+  "null_literal",
+  "text_block",
+  // C / C++
+  "number_literal",
+  "char_literal",
+  "concatenated_string",
+  "nullptr",
+]);
+
+// `...args` in JS/TS, `args...` in Go
+const SPREAD_NODES = new Set(["spread_element", "variadic_argument"]);
+
+const MAX_LITERAL_LENGTH = 80;
+
+function isLiteral(node: TreeSitterNode): boolean {
+  if (node.type === "template_string") return !node.namedChildren.some((c) => c.type === "template_substitution");
+  // A negated number is still a constant: `-1`
+  if (node.type === "unary_expression" && /^[-+]/.test(node.text)) {
+    const operand = node.namedChildren[0];
+    return !!operand && LITERAL_NODES.has(operand.type) && operand.namedChildCount === 0;
+  }
+  return LITERAL_NODES.has(node.type);
+}
+
+function argumentOf(node: TreeSitterNode): CallArgument {
+  if (SPREAD_NODES.has(node.type)) return { kind: "spread" };
+  if (!isLiteral(node)) return { kind: "expression" };
+  const text = node.text.replace(/\s+/g, " ");
+  const value = text.length > MAX_LITERAL_LENGTH ? `${text.slice(0, MAX_LITERAL_LENGTH)}…` : text;
+  return { kind: "literal", value };
+}
+
+/**
+ * The arguments of a call's argument list node (`arguments`/`argument_list`), with their count
+ * when no spread hides it. Empty when there is no argument list, e.g. a tagged template.
+ */
+export function callArguments(list: TreeSitterNode | null | undefined): { arguments?: CallArgument[]; arity?: number } {
+  if (!list || (list.type !== "arguments" && list.type !== "argument_list")) return {};
+  const args = list.namedChildren.filter((child) => child.type !== "comment").map(argumentOf);
+  return args.some((arg) => arg.kind === "spread") ? { arguments: args } : { arguments: args, arity: args.length };
+}
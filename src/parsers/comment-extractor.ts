@@ -0,0 +1,188 @@
+/**
+ * Comment Extractor - standalone comments as searchable `comment` entities
+ * Every comment of the syntax tree that is not the documentation of a declaration (that one is
+ * stored on the declaration already) becomes an entity, with runs of line comments on consecutive
+ * lines merged into one block. Each records the innermost declaration around it, and TODO, FIXME,
+ * HACK and XXX markers are listed in `metadata.markers` for `list_todos`. Tool directives
+ * (`//go:build`, `// eslint-disable`, `# noqa`, ...) are not prose and are skipped unless marked.
+ */
+
+import type { ParsedEntity, SupportedLanguage, TreeSitterNode } from "../types/parser.js";
+import { leadingDocCommentRange, MAX_DOCUMENTATION_LENGTH, stripCommentMarkers } from "./doc-comments.js";
+
+export type CommentMarkerKind = "TODO" | "FIXME" | "HACK" | "XXX";
+
+export type CommentMarker = {
+  kind: CommentMarkerKind;
+  line: number;
+  /** What follows the marker on its line */
+  text: string;
+  /** `TODO(alice): ...` names who it is for */
+  assignee?: string;
+};
+
+export type EnclosingSymbol = { name: string; type: string; line: number };
+
+const MARKER = /\b(TODO|FIXME|HACK|XXX)\b(?:\(([^)]*)\))?[:\s-]*(.*)$/;
+
+/** Tool directives, after the comment marker */
+const DIRECTIVES = [
+  "go:",
+  "\\+build",
+  "nolint",
+  "eslint-",
+  "@ts-",
+  "prettier-ignore",
+  "biome-ignore",
+  "istanbul ",
+  "c8 ",
+  "noqa",
+  "type:",
+  "pylint:",
+  "fmt:",
+  "-\\*-",
+  "<reference",
+];
+const DIRECTIVE = new RegExp(`^(?:#!|(?:///?|#|/\\*)\\s*(?:${DIRECTIVES.join("|")}))`);
+
+/** Declarations a comment can sit in; extracted side entities (imports, env vars) cannot */
+const SIDE_ENTITIES = new Set(["import", "export", "env_var", "route", "comment", "module", "package"]);
+
+/** Longest a comment's name, its first line of prose, gets */
+const NAME_LENGTH = 80;
+
+function flatten(entities: ParsedEntity[]): ParsedEntity[] {
+  return entities.flatMap((entity) => [entity, ...flatten(entity.children ?? [])]);
+}
+
+function collect(node: TreeSitterNode, into: TreeSitterNode[]): TreeSitterNode[] {
+  if (/comment$/.test(node.type)) into.push(node);
+  else for (const child of node.children) collect(child, into);
+  return into;
+}
+
+function isLineComment(text: string): boolean {
+  return text.startsWith("//") || text.startsWith("#");
+}
+
+function markersIn(text: string, firstLine: number): CommentMarker[] {
+  const markers: CommentMarker[] = [];
+  text.split(/\r?\n/).forEach((line, i) => {
+    const match = MARKER.exec(line);
+    if (!match) return;
+    const marker: CommentMarker = {
+      kind: match[1] as CommentMarkerKind,
+      line: firstLine + i,
+      text: match[3]!.replace(/\s*\*\/\s*$/, "").trim(),
+    };
+    if (match[2]?.trim()) marker.assignee = match[2].trim();
+    markers.push(marker);
+  });
+  return markers;
+}
+
+function enclosingOf(line: number, declarations: ParsedEntity[]): ParsedEntity | undefined {
+  let innermost: ParsedEntity | undefined;
+  for (const entity of declarations) {
+    const { start, end } = entity.location;
+    if (start.line > line || end.line < line) continue;
+    if (!innermost || innermost.location.start.line <= start.line) innermost = entity;
+  }
+  return innermost;
+}
+
+type Block = { nodes: TreeSitterNode[]; trailing: boolean };
+
+/**
+ * Comment entities of a parsed file, located at their first line. `entities` are the file's
+ * extracted declarations: they tell doc comments apart and give each comment its enclosing symbol.
+ */
+export function extractComments(
+  root: TreeSitterNode,
+  language: SupportedLanguage,
+  filePath: string,
+  source: string,
+  entities: ParsedEntity[],
+): ParsedEntity[] {
+  if (language === "markdown") return [];
+  const nodes = collect(root, []);
+  if (nodes.length === 0) return [];
+
+  const lines = source.split(/\r?\n/);
+  const declarations = flatten(entities).filter((e) => e.location && !SIDE_ENTITIES.has(e.type));
+  const documentation = new Set<number>();
+  for (const entity of declarations) {
+    const range = leadingDocCommentRange(lines, entity.location.start.line, language);
+    for (let line = range?.start ?? 0; range && line <= range.end; line++) documentation.add(line);
+  }
+
+  // Line comments on consecutive lines at the same column read as one comment
+  const blocks: Block[] = [];
+  for (const node of nodes) {
+    const trailing = lines[node.startPosition.row]!.slice(0, node.startPosition.column).trim() !== "";
+    const previous = blocks[blocks.length - 1];
+    const last = previous?.nodes[previous.nodes.length - 1];
+    if (
+      previous &&
+      last &&
+      !previous.trailing &&
+      !trailing &&
+      isLineComment(last.text) &&
+      isLineComment(node.text) &&
+      node.startPosition.row === last.endPosition.row + 1 &&
+      node.startPosition.column === previous.nodes[0]!.startPosition.column
+    ) {
+      previous.nodes.push(node);
+    } else {
+      blocks.push({ nodes: [node], trailing });
+    }
+  }
+
+  const comments: ParsedEntity[] = [];
+  const ids = new Set<string>();
+  for (const { nodes: parts, trailing } of blocks) {
+    const first = parts[0]!;
+    const lastPart = parts[parts.length - 1]!;
+    const line = first.startPosition.row + 1;
+    const raw = parts.map((part) => part.text.trim()).join("\n");
+    const markers = markersIn(raw, line);
+    const isDoc = parts.every((part) => documentation.has(part.startPosition.row + 1));
+    if (markers.length === 0 && (isDoc || DIRECTIVE.test(first.text.trim()))) continue;
+
+    const prose = stripCommentMarkers(raw);
+    if (!prose && markers.length === 0) continue;
+
+    let id = `${filePath}:comment:${line}`;
+    if (ids.has(id)) id = `${id}:${first.startPosition.column}`;
+    ids.add(id);
+
+    const enclosing = enclosingOf(line, declarations);
+    const summary = prose.split("\n")[0]!.trim();
+    comments.push({
+      id,
+      name: summary.length > NAME_LENGTH ? `${summary.slice(0, NAME_LENGTH - 1)}…` : summary || markers[0]!.kind,
+      type: "comment",
+      location: {
+        start: { line, column: first.startPosition.column, index: first.startIndex },
+        end: { line: lastPart.endPosition.row + 1, column: lastPart.endPosition.column, index: lastPart.endIndex },
+      },
+      documentation: raw.slice(0, MAX_DOCUMENTATION_LENGTH),
+      metadata: {
+        style: isLineComment(first.text) ? "line" : "block",
+        ...(trailing ? { trailing: true } : {}),
+        ...(isDoc ? { doc: true } : {}),
+        ...(markers.length > 0 ? { markers } : {}),
+        ...(enclosing
+          ? {
+              enclosing: {
+                name: enclosing.name,
+                type: enclosing.type,
+                line: enclosing.location.start.line,
+              } satisfies EnclosingSymbol,
+            }
+          : {}),
+      },
+    });
+  }
+  return comments;
+}
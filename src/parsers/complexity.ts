@@ -0,0 +1,88 @@
+/**
+ * Cyclomatic complexity of a function's syntax tree: 1 + the number of decision points.
+ * Decision points are branches (if/elif, loops, non-default case clauses), exception handlers,
+ * conditional expressions and short-circuit operators. Nested functions and classes are skipped;
+ * they are separate entities with their own count.
+ */
+
+import type { TreeSitterNode } from "../types/parser.js";
+
+export type ComplexityLanguage = "typescript" | "go" | "python";
+
+type ComplexityRules = {
+  /** Node types that each add one path */
+  decisions: Set<string>;
+  /** Binary node types whose `operator` may short-circuit */
+  logicalNodes: Set<string>;
+  logicalOperators: Set<string>;
+  /** Node types that start a new entity and are not walked into */
+  nested: Set<string>;
+};
+
+const RULES: Record<ComplexityLanguage, ComplexityRules> = {
+  typescript: {
+    decisions: new Set([
+      "if_statement",
+      "for_statement",
+      "for_in_statement",
+      "while_statement",
+      "do_statement",
+      "switch_case",
+      "catch_clause",
+      "ternary_expression",
+    ]),
+    logicalNodes: new Set(["binary_expression"]),
+    logicalOperators: new Set(["&&", "||", "??"]),
+    nested: new Set([
+      "function_declaration",
+      "function_expression",
+      "generator_function_declaration",
+      "generator_function",
+      "arrow_function",
+      "method_definition",
+      "class_declaration",
+      "class_expression",
+    ]),
+  },
+  go: {
+    // `default_case` is not a decision; every other case of a switch/type switch/select is
+    decisions: new Set(["if_statement", "for_statement", "expression_case", "type_case", "communication_case"]),
+    logicalNodes: new Set(["binary_expression"]),
+    logicalOperators: new Set(["&&", "||"]),
+    nested: new Set(["func_literal"]),
+  },
+  python: {
+    decisions: new Set([
+      "if_statement",
+      "elif_clause",
+      "for_statement",
+      "while_statement",
+      "except_clause",
+      "conditional_expression",
+      "case_clause",
+      "for_in_clause",
+      "if_clause",
+    ]),
+    logicalNodes: new Set(["boolean_operator"]),
+    logicalOperators: new Set(["and", "or"]),
+    nested: new Set(["function_definition", "async_function_definition", "class_definition", "lambda"]),
+  },
+};
+
+export function cyclomaticComplexity(fn: TreeSitterNode, language: ComplexityLanguage): number {
+  const rules = RULES[language];
+  let complexity = 1;
+  const stack: TreeSitterNode[] = [...fn.namedChildren];
+  while (stack.length > 0) {
+    const node = stack.pop()!;
+    if (rules.nested.has(node.type)) continue;
+    if (rules.decisions.has(node.type)) {
+      complexity++;
+    } else if (rules.logicalNodes.has(node.type)) {
+      const operator = node.childForFieldName("operator");
+      if (operator && rules.logicalOperators.has(operator.type)) complexity++;
+    }
+    stack.push(...node.namedChildren);
+  }
+  return complexity;
+}
@@ -0,0 +1,233 @@
+/**
+ * Language detection from a file's first lines, for files whose extension says nothing: scripts
+ * like `bin/deploy` starting with `#!/usr/bin/env python`, or a `.cgi` file with a vim modeline.
+ * Opt-in through `indexer.detectLanguageByContent`; without it only extensions count.
+ *
+ * Detectors, tried in order:
+ *   shebang   - the interpreter named on a `#!` first line (`env` and its flags are skipped)
+ *   modeline  - an Emacs `-*- mode: x -*-` or vim `vim: set ft=x` comment in the first lines
+ *   heuristic - tokens typical of one language; only for files without any extension, and only
+ *               when one language clearly outscores the others
+ */
+
+import { closeSync, openSync, readSync, statSync } from "node:fs";
+import { basename, extname } from "node:path";
+import type { SupportedLanguage } from "../types/parser.js";
+import { getLog } from "../utils/structured-log.js";
+import { FILE_EXTENSIONS, isFileSupported } from "./language-configs.js";
+
+const log = getLog("parser");
+
+/** Bytes read from the start of a file; enough for a shebang, a modeline and a few declarations */
+const SNIFF_BYTES = 4096;
+const MODELINE_LINES = 5;
+const MIN_HEURISTIC_SCORE = 3;
+const MAX_CACHED_FILES = 10_000;
+
+export type ContentLanguageDetector = "shebang" | "modeline" | "heuristic";
+
+export interface ContentLanguageMatch {
+  language: SupportedLanguage;
+  detector: ContentLanguageDetector;
+}
+
+const INTERPRETERS: Array<[RegExp, SupportedLanguage]> = [
+  [/^python(\d+(\.\d+)*)?$|^pypy\d*$/, "python"],
+  [/^(node|nodejs|bun)$/, "javascript"],
+  [/^(ts-node|tsx|deno)$/, "typescript"],
+  [/^(ruby|jruby|rake)$/, "ruby"],
+  [/^php(\d+(\.\d+)*)?$/, "php"],
+  [/^kotlinc?$|^kscript$/, "kotlin"],
+  [/^swift$/, "swift"],
+  [/^(scala|scala-cli|amm)$/, "scala"],
+  [/^(rust-script|cargo)$/, "rust"],
+];
+
+const MODE_NAMES: Record<string, SupportedLanguage> = {
+  python: "python",
+  py: "python",
+  javascript: "javascript",
+  js: "javascript",
+  typescript: "typescript",
+  ts: "typescript",
+  ruby: "ruby",
+  rb: "ruby",
+  php: "php",
+  go: "go",
+  rust: "rust",
+  rs: "rust",
+  java: "java",
+  kotlin: "kotlin",
+  swift: "swift",
+  scala: "scala",
+  c: "c",
+  cpp: "cpp",
+  "c++": "cpp",
+  cs: "csharp",
+  csharp: "csharp",
+  markdown: "markdown",
+  md: "markdown",
+  proto: "proto",
+  protobuf: "proto",
+  sql: "sql",
+  mysql: "sql",
+  pgsql: "sql",
+  plpgsql: "sql",
+  graphql: "graphql",
+  gql: "graphql",
+};
+
+// Each pattern is worth one point per line it matches
+const HEURISTICS: Array<[SupportedLanguage, RegExp[]]> = [
+  [
+    "python",
+    [
+      /^\s*def \w+\(.*\):\s*$/,
+      /^\s*(from [\w.]+ )?import [\w., ]+$/,
+      /^if __name__ == ["']__main__["']:/,
+      /^\s*elif .*:$/,
+    ],
+  ],
+  [
+    "ruby",
+    [/^\s*require(_relative)? ["'][\w./-]+["']$/, /^\s*def \w+[?!]?(\(.*\))?$/, /^\s*end$/, /^\s*module [A-Z]\w*$/],
+  ],
+  ["php", [/^<\?php\b/, /^\s*\$\w+ = .*;$/, /^\s*(public |private )?function \w+\(.*\)/]],
+  [
+    "javascript",
+    [/^\s*(const|let) \w+ = require\(["'][\w@./-]+["']\);?$/, /^\s*module\.exports\b/, /^\s*process\.exit\(/],
+  ],
+  ["go", [/^package \w+$/, /^func (\(\w+ \*?\w+\) )?\w+\(.*\) ?.*\{$/, /^import \($/]],
+];
+
+/** Language named by a `#!` first line, e.g. `#!/usr/bin/env -S python3 -u` */
+function fromShebang(firstLine: string): SupportedLanguage | null {
+  if (!firstLine.startsWith("#!")) return null;
+  const words = firstLine.slice(2).trim().split(/\s+/);
+  let interpreter = basename(words.shift() ?? "");
+  if (interpreter === "env") {
+    while (words[0]?.startsWith("-") || words[0]?.includes("=")) words.shift();
+    interpreter = basename(words[0] ?? "");
+  }
+  for (const [pattern, language] of INTERPRETERS) {
+    if (pattern.test(interpreter)) return language;
+  }
+  return null;
+}
+
+function modeOf(line: string): string | undefined {
+  // Emacs: `-*- python -*-` or `-*- coding: utf-8; mode: python -*-`
+  const emacs = /-\*-(.*?)-\*-/.exec(line)?.[1];
+  if (emacs !== undefined) {
+    return (/(?:^|;)\s*mode:\s*([\w+#-]+)/i.exec(emacs) ?? /^\s*([\w+#-]+)\s*$/.exec(emacs))?.[1];
+  }
+  // vim: `# vim: set ft=ruby:` or `// vi: filetype=javascript`
+  return /\bvim?:.*\b(?:ft|filetype|syntax)=([\w+#-]+)/.exec(line)?.[1];
+}
+
+function fromModeline(lines: string[]): SupportedLanguage | null {
+  for (const line of lines.slice(0, MODELINE_LINES)) {
+    const language = MODE_NAMES[modeOf(line)?.toLowerCase() ?? ""];
+    if (language) return language;
+  }
+  return null;
+}
+
+function fromTokens(lines: string[]): SupportedLanguage | null {
+  const scores = HEURISTICS.map(([language, patterns]) => ({
+    language,
+    score: lines.filter((line) => patterns.some((pattern) => pattern.test(line))).length,
+  })).sort((a, b) => b.score - a.score);
+  const [best, runnerUp] = scores;
+  if (!best || best.score < MIN_HEURISTIC_SCORE) return null;
+  // A near tie says the file is not clearly either
+  return runnerUp && runnerUp.score * 2 > best.score ? null : best.language;
+}
+
+function hasExtension(filePath: string): boolean {
+  return basename(filePath).lastIndexOf(".") > 0;
+}
+
+/**
+ * Language of source text whose file name did not tell. Token scoring is only trusted when
+ * `filePath` has no extension at all.
+ */
+export function detectLanguageFromContent(content: string, filePath = ""): ContentLanguageMatch | null {
+  const lines = content.slice(0, SNIFF_BYTES).split(/\r?\n/);
+  const shebang = fromShebang(lines[0] ?? "");
+  if (shebang) return { language: shebang, detector: "shebang" };
+  const modeline = fromModeline(lines);
+  if (modeline) return { language: modeline, detector: "modeline" };
+  const tokens = hasExtension(filePath) ? null : fromTokens(lines);
+  return tokens ? { language: tokens, detector: "heuristic" } : null;
+}
+
+// Keyed by path, size and mtime, so the walk, the parser agent and the indexer read a file once
+const detected = new Map<string, ContentLanguageMatch | null>();
+
+/**
+ * Language of a file with an unrecognised extension, read from its first bytes; null for binary,
+ * unreadable and unrecognised files. The first detection of each file is logged with its detector.
+ */
+export function detectFileLanguage(filePath: string): ContentLanguageMatch | null {
+  let key: string;
+  let size: number;
+  try {
+    const stats = statSync(filePath);
+    size = stats.size;
+    key = `${filePath}:${size}:${stats.mtimeMs}`;
+  } catch {
+    return null;
+  }
+  if (detected.has(key)) return detected.get(key)!;
+
+  let match: ContentLanguageMatch | null = null;
+  let fd: number | null = null;
+  try {
+    fd = openSync(filePath, "r");
+    const buffer = Buffer.alloc(Math.min(SNIFF_BYTES, size));
+    const head = buffer.subarray(0, readSync(fd, buffer, 0, buffer.length, 0));
+    if (!head.includes(0)) match = detectLanguageFromContent(head.toString("utf8"), filePath);
+  } catch {
+    match = null;
+  } finally {
+    if (fd !== null) closeSync(fd);
+  }
+
+  if (detected.size >= MAX_CACHED_FILES) detected.clear();
+  detected.set(key, match);
+  if (match) log.info("language detected from content", { filePath, ...match });
+  return match;
+}
+
+/**
+ * Whether discovery should index `filePath`: a supported extension, or, with `detectByContent`,
+ * content in a supported language
+ */
+export function isIndexableSourceFile(filePath: string, detectByContent: boolean): boolean {
+  if (isFileSupported(filePath)) return true;
+  return detectByContent && detectFileLanguage(filePath) !== null;
+}
+
+// Indexing TypeScript or JavaScript takes in its JSX flavour
+const FLAVOUR_OF: Partial<Record<SupportedLanguage, SupportedLanguage>> = { tsx: "typescript", jsx: "javascript" };
+
+/**
+ * Which of `languages` a file to index is in, by extension and, with `detectByContent`, by content
+ * for unrecognised extensions; null when it is in none. A `.h` header counts as C or C++, whichever
+ * is asked for, since only its content tells them apart.
+ */
+export function indexLanguageOf(
+  filePath: string,
+  languages: ReadonlySet<string>,
+  detectByContent: boolean,
+): SupportedLanguage | null {
+  const ext = extname(filePath).slice(1).toLowerCase();
+  if (ext === "h") return languages.has("c") ? "c" : languages.has("cpp") ? "cpp" : null;
+  const language =
+    (FILE_EXTENSIONS[ext] as SupportedLanguage | undefined) ??
+    (detectByContent ? detectFileLanguage(filePath)?.language : undefined);
+  if (!language) return null;
+  const flavourOf = FLAVOUR_OF[language];
+  return languages.has(language) || (flavourOf !== undefined && languages.has(flavourOf)) ? language : null;
+}
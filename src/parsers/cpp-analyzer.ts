@@ -11,6 +11,8 @@
  * - Operator overloading (basic)
  * - Single and multiple inheritance
  * - Friend declarations
+ * - Prototypes, out-of-line `Class::method` definitions and calls between them
+ * - Includes, macros and typedefs
  *
  * Phase 4 - Limited Template Support:
  * - Simple template class definitions
@@ -23,6 +25,15 @@
  */
 
 import type { EntityRelationship, ParsedEntity, TreeSitterNode } from "../types/parser.js";
+import {
+  callRelationship,
+  collectCallSites,
+  findFunctionDeclarator,
+  functionParameters,
+  includeRelationship,
+  macroEntity,
+  translationUnitEntity,
+} from "./c-family.js";
 
 // Circuit breaker constants
 const MAX_RECURSION_DEPTH = 50;
@@ -54,6 +65,10 @@ export class CppAnalyzer {
   // Memoization cache for template patterns
   private templateCache = new Map<string, ParsedEntity>();
 
+  // Calls awaiting binding, with the scope (`ns::Class`) their caller was defined in
+  private pendingCalls: Array<{ relationship: EntityRelationship; scope: string }> = [];
+  private definitionIds = new Set<string>();
+
   /**
    * Helper: Convert tree-sitter position to ParsedEntity location
    */
@@ -85,6 +100,8 @@ export class CppAnalyzer {
     const relationships: EntityRelationship[] = [];
 
     try {
+      entities.push(translationUnitEntity(filePath));
+
       // Phase 1: Syntactic extraction from CST
       this.extractEntities(rootNode, filePath, entities, relationships);
 
@@ -117,6 +134,8 @@ export class CppAnalyzer {
       total: 0,
     };
     this.templateCache.clear();
+    this.pendingCalls = [];
+    this.definitionIds.clear();
   }
 
   /**
@@ -202,7 +221,28 @@ export class CppAnalyzer {
           this.extractEnum(node, filePath, entities, relationships, namespace);
           break;
 
+        case "preproc_include": {
+          const include = includeRelationship(node, filePath);
+          if (include) relationships.push(include);
+          break;
+        }
+
+        case "preproc_def":
+        case "preproc_function_def": {
+          const macro = macroEntity(node);
+          if (macro) entities.push(macro);
+          break;
+        }
+
+        case "type_definition":
+          this.extractTypedef(node, entities, namespace);
+          for (const child of node.children) {
+            this.extractEntities(child, filePath, entities, relationships, namespace);
+          }
+          break;
+
         case "declaration":
+          this.extractPrototype(node, filePath, entities, relationships, namespace);
           // Process declarations that might contain classes, functions, etc.
           for (const child of node.children) {
             this.extractEntities(child, filePath, entities, relationships, namespace);
@@ -212,7 +252,7 @@ export class CppAnalyzer {
         default:
           // Recursively process other node types
           for (const child of node.children) {
-            if (child.type !== "comment" && child.type !== "preproc_include") {
+            if (child.type !== "comment") {
               this.extractEntities(child, filePath, entities, relationships, namespace);
             }
           }
@@ -235,6 +275,14 @@ export class CppAnalyzer {
     const nameNode = node.childForFieldName("name");
     const bodyNode = node.childForFieldName("body");
 
+    // `namespace { ... }` only limits linkage; its contents belong to the enclosing scope
+    if (!nameNode && bodyNode) {
+      for (const child of bodyNode.children) {
+        this.extractEntities(child, filePath, entities, relationships, parentNamespace);
+      }
+      return;
+    }
+
     if (nameNode && bodyNode) {
       const namespaceName = nameNode.text;
       const fullName = parentNamespace ? `${parentNamespace}::${namespaceName}` : namespaceName;
@@ -359,6 +407,11 @@ export class CppAnalyzer {
           break;
 
         case "declaration":
+          // Constructors and conversion operators are declared without a return type
+          if (findFunctionDeclarator(child.childForFieldName("declarator"))) {
+            this.extractMethod(child, className, filePath, entities, relationships, currentAccessSpecifier);
+            break;
+          }
           // Process nested declarations
           for (const decl of child.children) {
             if (decl.type === "function_definition" || decl.type === "function_declaration") {
@@ -385,6 +438,7 @@ export class CppAnalyzer {
   ): void {
     const declaratorNode = node.childForFieldName("declarator");
     if (!declaratorNode) return;
+    const isDefinition = node.type === "function_definition";
     let functionName = this.extractFunctionName(declaratorNode);
     if (!functionName) return;
     functionName = this.canonicalizeOperatorName(functionName, node);
@@ -420,20 +474,26 @@ export class CppAnalyzer {
       entityType = "method"; // No specific constructor/destructor in the type union
     }
 
+    const id = isDefinition ? this.definitionId(_filePath, fullName) : undefined;
     const entity: ParsedEntity = {
+      id,
       name: fullName,
       type: entityType,
       location: this.getNodeLocation(node),
       modifiers,
+      parameters: this.parametersOf(declaratorNode),
+      metadata: { isDefinition },
     };
     entities.push(entity);
 
     // Create relationship to parent class
     relationships.push({
-      from: fullName,
+      from: id ?? fullName,
       to: className,
       type: "contains",
     });
+
+    if (id) this.collectCalls(node, id, className);
   }
 
   /**
@@ -450,6 +510,12 @@ export class CppAnalyzer {
     const declaratorNode = node.childForFieldName("declarator");
     if (!declaratorNode) return;
 
+    // A member function declared here and defined elsewhere
+    if (findFunctionDeclarator(declaratorNode)) {
+      this.extractMethod(node, className, _filePath, entities, relationships, accessSpecifier);
+      return;
+    }
+
     const fieldName = this.extractFieldName(declaratorNode);
     if (!fieldName) return;
 
@@ -503,7 +569,12 @@ export class CppAnalyzer {
 
     functionName = this.canonicalizeOperatorName(functionName, node);
 
-    const fullName = namespace ? `${namespace}::${functionName}` : functionName;
+    // `void Widget::draw() {}` is qualified by the class it was declared in
+    const qualifier = this.declaredQualifier(declaratorNode);
+    const scope = [namespace, qualifier].filter(Boolean).join("::");
+    const fullName = scope ? `${scope}::${functionName}` : functionName;
+    const isDefinition = node.type === "function_definition";
+    const owner = qualifier ? entities.find((e) => e.type === "class" && e.name === scope) : undefined;
 
     // Collect modifiers
     const modifiers: string[] = [];
@@ -511,23 +582,106 @@ export class CppAnalyzer {
     if (node.text.includes("inline")) modifiers.push("inline");
     if (node.text.includes("extern")) modifiers.push("extern");
 
+    const id = isDefinition ? this.definitionId(_filePath, fullName) : undefined;
     const entity: ParsedEntity = {
+      id,
       name: fullName,
-      type: "function",
+      type: owner ? "method" : "function",
       location: this.getNodeLocation(node),
       modifiers,
+      parameters: this.parametersOf(declaratorNode),
+      metadata: { isDefinition },
     };
 
     entities.push(entity);
 
-    // If in namespace, create relationship
-    if (namespace) {
+    // If in namespace or defining a member of a class in this file, create relationship
+    const container = owner ? scope : namespace;
+    if (container) {
       relationships.push({
-        from: fullName,
-        to: namespace,
+        from: id ?? fullName,
+        to: container,
         type: "contains",
       });
     }
+
+    if (id) this.collectCalls(node, id, scope);
+  }
+
+  /**
+   * Extract function prototypes (`declaration`s whose declarator is a function)
+   */
+  private extractPrototype(
+    node: TreeSitterNode,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+    namespace: string,
+  ): void {
+    const declarator = node.childForFieldName("declarator");
+    if (!declarator || !findFunctionDeclarator(declarator)) return;
+    this.extractFunction(node, filePath, entities, relationships, namespace, false);
+  }
+
+  /**
+   * Scope written before a declarator's name, without template arguments: `Widget` for
+   * `Widget<T>::draw`, empty for a plain name
+   */
+  private declaredQualifier(declaratorNode: TreeSitterNode): string {
+    const fn = findFunctionDeclarator(declaratorNode);
+    const nameNode = fn?.childForFieldName("declarator");
+    if (nameNode?.type !== "qualified_identifier") return "";
+    const parts = nameNode.text.replace(/<[^<>]*>/g, "").replace(/\s+/g, "").split("::");
+    parts.pop();
+    return parts.filter(Boolean).join("::");
+  }
+
+  /**
+   * Id of a function definition; overloads after the first get an ordinal
+   */
+  private definitionId(filePath: string, fullName: string): string {
+    const base = `${filePath}:function:${fullName}`;
+    let id = base;
+    for (let n = 2; this.definitionIds.has(id); n++) id = `${base}#${n}`;
+    this.definitionIds.add(id);
+    return id;
+  }
+
+  private parametersOf(declaratorNode: TreeSitterNode): Array<{ name: string; type?: string }> | undefined {
+    const fn = findFunctionDeclarator(declaratorNode);
+    return fn ? functionParameters(fn) : undefined;
+  }
+
+  /**
+   * Record the calls in a function body; they are bound once every entity of the file is known
+   */
+  private collectCalls(node: TreeSitterNode, callerId: string, scope: string): void {
+    const body = node.childForFieldName("body");
+    if (!body) return;
+    for (const call of collectCallSites(body)) {
+      this.pendingCalls.push({ relationship: callRelationship(callerId, call), scope });
+    }
+  }
+
+  /**
+   * Extract `typedef` names, qualified by the enclosing namespace
+   */
+  private extractTypedef(node: TreeSitterNode, entities: ParsedEntity[], namespace: string): void {
+    const typeStart = node.childForFieldName("type")?.startIndex;
+    for (const declarator of node.namedChildren) {
+      if (declarator.startIndex === typeStart || declarator.type === "type_qualifier") continue;
+      // `typedef int (*callback)(int)` nests the name inside function and pointer declarators
+      let nameNode: TreeSitterNode | null = declarator;
+      while (nameNode && nameNode.type !== "type_identifier") {
+        nameNode =
+          nameNode.childForFieldName("declarator") ??
+          nameNode.namedChildren.find((c) => c.type.endsWith("declarator") || c.type === "type_identifier") ??
+          null;
+      }
+      if (!nameNode) continue;
+      const name = namespace ? `${namespace}::${nameNode.text}` : nameNode.text;
+      entities.push({ name, type: "type", location: this.getNodeLocation(node) });
+    }
   }
 
   /**
@@ -573,6 +727,8 @@ export class CppAnalyzer {
     if (declarationNode.type === "function_definition") {
       const d = declarationNode.childForFieldName("declarator");
       declName = this.extractFunctionName(d) || "";
+      const qualifier = d ? this.declaredQualifier(d) : "";
+      if (declName && qualifier) declName = `${qualifier}::${declName}`;
     } else if (declarationNode.type === "class_specifier" || declarationNode.type === "struct_specifier") {
       const n = declarationNode.childForFieldName("name");
       declName = n?.text || "";
@@ -796,10 +952,34 @@ export class CppAnalyzer {
   /**
    * Build semantic graph with lazy evaluation (Phase 2)
    */
-  private buildSemanticGraph(_entities: ParsedEntity[], relationships: EntityRelationship[]): void {
-    // This is where we would build a richer semantic graph
-    // For now, we're keeping the syntactic information
-    // Future enhancement: Add type resolution, symbol tables, etc.
+  private buildSemanticGraph(entities: ParsedEntity[], relationships: EntityRelationship[]): void {
+    // Bind calls to functions of this file by C++ name lookup: an unqualified `helper()` called
+    // from `ns::Widget::draw` is `ns::Widget::helper`, then `ns::helper`, then `::helper`
+    const functions = new Map<string, ParsedEntity>();
+    for (const entity of entities) {
+      if (entity.type !== "function" && entity.type !== "method") continue;
+      const known = functions.get(entity.name);
+      // A definition is a better target than the prototype of the same function
+      if (!known || (!known.id && entity.id)) functions.set(entity.name, entity);
+    }
+    for (const { relationship, scope } of this.pendingCalls) {
+      const receiver = relationship.metadata?.callee?.split(/->|\./)[0];
+      if (relationship.metadata?.callType === "method" && receiver !== "this") {
+        relationships.push(relationship);
+        continue;
+      }
+      const parts = scope ? scope.split("::") : [];
+      for (let i = parts.length; i >= 0; i--) {
+        const candidate = [...parts.slice(0, i), relationship.to].join("::");
+        const target = functions.get(candidate);
+        if (target) {
+          relationship.to = target.id ?? target.name;
+          break;
+        }
+      }
+      relationships.push(relationship);
+    }
+    this.pendingCalls = [];
 
     // Remove invalid relationships
     const validRelationships = relationships.filter((rel) => {
@@ -8,6 +8,11 @@
  * Layer 3: Relationship mapping (inheritance, interfaces, dependencies)
  * Layer 4: Pattern recognition (design patterns, C# idioms)
  *
+ * Declarations are walked from the compilation unit down, so every type and member is named by
+ * its namespace and enclosing types (`Shop.Orders.OrderService.Place`). Heritage and call edges
+ * are bound to declarations of the same file here; the assembly resolver
+ * (src/core/csharp-assembly-resolver.ts) binds the rest and merges partial types across files.
+ *
  * Architecture References:
  * - Python Analyzer Pattern: src/parsers/python-analyzer.ts
  * - Enhanced Parser Types: src/types/parser.ts
@@ -29,6 +34,52 @@ import type {
   TreeSitterNode,
 } from "../types/parser.js";
 
+// =============================================================================
+// 2. TYPES AND CONSTANTS
+// =============================================================================
+
+type Decorator = NonNullable<ParsedEntity["decorators"]>[number];
+
+/** Type declarations, with the segment their ids use and the entity type they produce */
+const TYPE_DECLARATIONS: Record<string, { idKind: string; type: ParsedEntity["type"] }> = {
+  class_declaration: { idKind: "class", type: "class" },
+  interface_declaration: { idKind: "interface", type: "interface" },
+  struct_declaration: { idKind: "struct", type: "struct" },
+  record_declaration: { idKind: "record", type: "class" },
+  record_struct_declaration: { idKind: "record", type: "class" },
+  enum_declaration: { idKind: "enum", type: "enum" },
+};
+
+/** Nodes whose children are declarations at namespace level */
+const CONTAINERS = new Set([
+  "compilation_unit",
+  "declaration_list",
+  "preproc_if",
+  "preproc_elif",
+  "preproc_else",
+  "preproc_region",
+  "ERROR",
+]);
+
+/** A type whose body is being walked */
+interface TypeScope {
+  id: string;
+  name: string;
+  /** Name within the file, `Outer.Inner` */
+  path: string;
+  qualifiedName: string;
+  idKind: string;
+}
+
+/**
+ * A `type` at position `index` of `entity`'s base list, whose extends/implements kind is settled
+ * once every interface of the file is known, or a `call` of `name` from type `owner` on `receiver`
+ * (none, `this`, `base`, a type name, or a field or property of a local type)
+ */
+type PendingTarget =
+  | { kind: "type"; relationship: EntityRelationship; entity: ParsedEntity; declaredAs: string; index: number }
+  | { kind: "call"; relationship: EntityRelationship; name: string; owner: string; receiver?: string };
+
 // =============================================================================
 // 3. C# ENTITY EXTRACTION (Layer 1)
 // =============================================================================
@@ -40,6 +91,19 @@ export class CSharpAnalyzer {
     patternsIdentified: 0,
     parseTime: 0,
   };
+  /** Namespace the declarations being walked belong to */
+  private currentNamespace = "";
+  /** Namespaces brought in by `using` directives of this file */
+  private usings: string[] = [];
+  /** Types declared in this file by in-file path (`Outer.Inner`), qualified name and simple name */
+  private declaredTypes = new Map<string, TypeScope>();
+  /** Methods declared in this file by `TypePath.method` */
+  private declaredMethods = new Map<string, string>();
+  /** Type path -> base types as written, for calls to inherited methods */
+  private basesOf = new Map<string, string[]>();
+  /** `TypePath.member` -> declared type of a field or property, for calls on it */
+  private memberTypes = new Map<string, string>();
+  private pending: PendingTarget[] = [];
 
   /**
    * Main entry point for C# analysis
@@ -58,19 +122,15 @@ export class CSharpAnalyzer {
     const entities: ParsedEntity[] = [];
     const relationships: EntityRelationship[] = [];
     const imports: ImportDependency[] = [];
+    this.resetState();
 
-    // Extract all entity types
-    this.extractNamespaces(node, entities, filePath);
-    this.extractClasses(node, entities, relationships, filePath);
-    this.extractInterfaces(node, entities, relationships, filePath);
-    this.extractStructs(node, entities, filePath);
-    this.extractEnums(node, entities, filePath);
-    this.extractDelegates(node, entities, filePath);
-    this.extractRecords(node, entities, filePath);
-
-    // Extract imports/using statements
+    // Usings first: type entities record them for the assembly resolver
     this.extractUsings(node, imports, filePath);
 
+    // Extract all entity types
+    this.visit(node, filePath, entities, relationships);
+    this.bindLocalTargets();
+
     // Identify patterns (Layer 4)
     const patterns = this.identifyPatterns(node, entities);
 
@@ -90,560 +150,598 @@ export class CSharpAnalyzer {
   }
 
   /**
-   * Extract namespace declarations
+   * Reset analyzer state for new file
    */
-  private extractNamespaces(node: TreeSitterNode, entities: ParsedEntity[], filePath: string): void {
-    const namespaceNodes = this.findNodes(node, "namespace_declaration");
-
-    for (const nsNode of namespaceNodes) {
-      const nameNode = nsNode.childForFieldName("name");
-      if (!nameNode) continue;
-
-      const name = this.getNodeText(nameNode);
-      const location = this.getNodeLocation(nsNode);
+  private resetState(): void {
+    this.currentNamespace = "";
+    this.usings = [];
+    this.declaredTypes.clear();
+    this.declaredMethods.clear();
+    this.basesOf.clear();
+    this.memberTypes.clear();
+    this.pending = [];
+  }
 
-      entities.push({
-        id: `${filePath}:namespace:${name}`,
-        name,
-        type: "module",
-        filePath,
-        location,
-        metadata: {
-          memberCount: this.countMembers(nsNode),
-        },
-      });
-    }
+  /** Name prefixed with the current namespace */
+  private qualify(name: string): string {
+    return this.currentNamespace ? `${this.currentNamespace}.${name}` : name;
   }
 
   /**
-   * Extract class declarations with inheritance and implementation
+   * Walk namespace-level declarations. A file-scoped `namespace X;` applies to the declarations
+   * after it, which the grammar places either inside it or as its siblings.
    */
-  private extractClasses(
+  private visit(
     node: TreeSitterNode,
+    filePath: string,
     entities: ParsedEntity[],
     relationships: EntityRelationship[],
-    filePath: string,
   ): void {
-    const classNodes = this.findNodes(node, "class_declaration");
-
-    for (const classNode of classNodes) {
-      const nameNode = classNode.childForFieldName("name");
-      if (!nameNode) continue;
-
-      const name = this.getNodeText(nameNode);
-      const location = this.getNodeLocation(classNode);
-      const modifiers = this.extractModifiers(classNode);
-      const isAbstract = modifiers.includes("abstract");
-      const isSealed = modifiers.includes("sealed");
-      const isPartial = modifiers.includes("partial");
-      const isStatic = modifiers.includes("static");
-
-      // Extract base types (inheritance and interfaces)
-      const baseList = classNode.childForFieldName("bases");
-      const baseTypes: string[] = [];
-      if (baseList) {
-        const baseNodes = this.findNodes(baseList, "base_type");
-        for (const baseNode of baseNodes) {
-          const baseType = this.getNodeText(baseNode);
-          baseTypes.push(baseType);
-
-          // Create inheritance relationship
-          relationships.push({
-            from: `${filePath}:class:${name}`,
-            to: `${filePath}:type:${baseType}`,
-            type: "extends",
-            sourceFile: filePath,
-            metadata: {},
-          });
-        }
-      }
-
-      // Extract attributes
-      const attributes = this.extractAttributes(classNode);
+    if (node.type === "namespace_declaration" || node.type === "file_scoped_namespace_declaration") {
+      this.extractNamespace(node, filePath, entities, relationships);
+    } else if (node.type === "delegate_declaration") {
+      this.extractDelegate(node, filePath, entities, relationships, null);
+    } else if (TYPE_DECLARATIONS[node.type]) {
+      this.extractType(node, filePath, entities, relationships, null);
+    } else if (CONTAINERS.has(node.type)) {
+      for (const child of this.childrenOf(node)) this.visit(child, filePath, entities, relationships);
+    }
+  }
 
-      // Extract members
-      const methods = this.extractMethods(classNode, name, entities, filePath);
-      const properties = this.extractProperties(classNode, name, entities, filePath);
-      const fields = this.extractFields(classNode, name, entities, filePath);
-      const events = this.extractEvents(classNode, name, entities, filePath);
+  /**
+   * Extract namespace declarations
+   */
+  private extractNamespace(
+    nsNode: TreeSitterNode,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+  ): void {
+    const nameNode = nsNode.childForFieldName("name");
+    if (!nameNode) return;
+
+    const outer = this.currentNamespace;
+    const name = this.qualify(this.getNodeText(nameNode).replace(/\s+/g, ""));
+    entities.push({
+      id: `${filePath}:namespace:${name}`,
+      name,
+      type: "module",
+      filePath,
+      location: this.getNodeLocation(nsNode),
+      metadata: {
+        memberCount: this.countMembers(nsNode),
+        qualifiedName: name,
+      },
+    });
 
-      entities.push({
-        id: `${filePath}:class:${name}`,
-        name,
-        type: "class",
-        filePath,
-        location,
-        metadata: {
-          isAbstract,
-          isSealed,
-          isPartial,
-          isStatic,
-          modifiers,
-          baseTypes,
-          attributes,
-          methodCount: methods.length,
-          propertyCount: properties.length,
-          fieldCount: fields.length,
-          eventCount: events.length,
-        },
-      });
-    }
+    this.currentNamespace = name;
+    const body = nsNode.childForFieldName("body") ?? nsNode;
+    for (const child of this.childrenOf(body)) this.visit(child, filePath, entities, relationships);
+    if (nsNode.type === "namespace_declaration") this.currentNamespace = outer;
   }
 
   /**
-   * Extract interface declarations
+   * Extract a class, interface, struct, record or enum with its members and nested types
    */
-  private extractInterfaces(
+  private extractType(
     node: TreeSitterNode,
+    filePath: string,
     entities: ParsedEntity[],
     relationships: EntityRelationship[],
-    filePath: string,
+    owner: TypeScope | null,
   ): void {
-    const interfaceNodes = this.findNodes(node, "interface_declaration");
+    const nameNode = node.childForFieldName("name");
+    const spec = TYPE_DECLARATIONS[node.type];
+    if (!nameNode || !spec) return;
+
+    const name = this.getNodeText(nameNode);
+    const path = owner ? `${owner.path}.${name}` : name;
+    const scope: TypeScope = {
+      id: `${filePath}:${spec.idKind}:${path}`,
+      name,
+      path,
+      qualifiedName: owner ? `${owner.qualifiedName}.${name}` : this.qualify(name),
+      idKind: spec.idKind,
+    };
+    for (const key of [path, scope.qualifiedName, name]) {
+      if (!this.declaredTypes.has(key)) this.declaredTypes.set(key, scope);
+    }
 
-    for (const intNode of interfaceNodes) {
-      const nameNode = intNode.childForFieldName("name");
-      if (!nameNode) continue;
+    const modifiers = this.extractModifiers(node);
+    const decorators = this.extractDecorators(node);
+    // An enum's base list is its underlying integral type, not heritage
+    const baseTypes = node.type === "enum_declaration" ? [] : this.baseTypeNames(node);
+    const metadata: Record<string, any> = {
+      modifiers,
+      attributes: decorators.map((d) => d.name),
+      qualifiedName: scope.qualifiedName,
+      namespace: this.currentNamespace,
+      ...(owner ? { parentClass: owner.name } : { usings: this.usings }),
+    };
 
-      const name = this.getNodeText(nameNode);
-      const location = this.getNodeLocation(intNode);
-      const modifiers = this.extractModifiers(intNode);
-
-      // Extract base interfaces
-      const baseList = intNode.childForFieldName("bases");
-      const baseInterfaces: string[] = [];
-      if (baseList) {
-        const baseNodes = this.findNodes(baseList, "base_type");
-        for (const baseNode of baseNodes) {
-          const baseInterface = this.getNodeText(baseNode);
-          baseInterfaces.push(baseInterface);
-
-          relationships.push({
-            from: `${filePath}:interface:${name}`,
-            to: `${filePath}:interface:${baseInterface}`,
-            type: "extends",
-            sourceFile: filePath,
-            metadata: {},
-          });
+    switch (node.type) {
+      case "class_declaration":
+      case "record_declaration":
+      case "record_struct_declaration":
+        Object.assign(metadata, {
+          isAbstract: modifiers.includes("abstract"),
+          isSealed: modifiers.includes("sealed"),
+          isPartial: modifiers.includes("partial"),
+          isStatic: modifiers.includes("static"),
+          baseTypes,
+        });
+        if (node.type !== "class_declaration") {
+          Object.assign(metadata, { isRecord: true, isRecordStruct: node.type === "record_struct_declaration" });
         }
+        break;
+      case "interface_declaration":
+        Object.assign(metadata, { isPartial: modifiers.includes("partial"), baseInterfaces: baseTypes });
+        break;
+      case "struct_declaration":
+        Object.assign(metadata, {
+          isReadonly: modifiers.includes("readonly"),
+          isPartial: modifiers.includes("partial"),
+          baseTypes,
+        });
+        break;
+      case "enum_declaration": {
+        const members = this.extractEnumMembers(node);
+        Object.assign(metadata, { members, memberCount: members.length });
+        break;
       }
-
-      // Extract interface members
-      const methods = this.extractInterfaceMethods(intNode, name, entities, filePath);
-      const properties = this.extractInterfaceProperties(intNode, name, entities, filePath);
-
-      entities.push({
-        id: `${filePath}:interface:${name}`,
-        name,
-        type: "interface",
-        filePath,
-        location,
-        metadata: {
-          modifiers,
-          baseInterfaces,
-          methodCount: methods.length,
-          propertyCount: properties.length,
-        },
-      });
     }
-  }
 
-  /**
-   * Extract struct declarations
-   */
-  private extractStructs(node: TreeSitterNode, entities: ParsedEntity[], filePath: string): void {
-    const structNodes = this.findNodes(node, "struct_declaration");
-
-    for (const structNode of structNodes) {
-      const nameNode = structNode.childForFieldName("name");
-      if (!nameNode) continue;
-
-      const name = this.getNodeText(nameNode);
-      const location = this.getNodeLocation(structNode);
-      const modifiers = this.extractModifiers(structNode);
-      const isReadonly = modifiers.includes("readonly");
+    const entity: ParsedEntity = {
+      id: scope.id,
+      name,
+      type: spec.type,
+      filePath,
+      location: this.getNodeLocation(node),
+      modifiers,
+      ...(decorators.length ? { decorators } : {}),
+      metadata,
+    };
+    entities.push(entity);
 
-      entities.push({
-        id: `${filePath}:struct:${name}`,
-        name,
-        type: "struct",
-        filePath,
-        location,
-        metadata: {
-          isReadonly,
-          modifiers,
-        },
-      });
+    if (owner) {
+      relationships.push({ from: scope.id, to: owner.id, type: "contains", metadata: { memberType: "type" } });
     }
-  }
-
-  /**
-   * Extract enum declarations
-   */
-  private extractEnums(node: TreeSitterNode, entities: ParsedEntity[], filePath: string): void {
-    const enumNodes = this.findNodes(node, "enum_declaration");
 
-    for (const enumNode of enumNodes) {
-      const nameNode = enumNode.childForFieldName("name");
-      if (!nameNode) continue;
+    this.basesOf.set(path, baseTypes);
+    // Record structs take part in heritage as structs, only implementing interfaces
+    const declaredAs = node.type === "record_struct_declaration" ? "struct" : spec.idKind;
+    baseTypes.forEach((base, index) => {
+      const relationship: EntityRelationship = {
+        from: scope.id,
+        to: this.simpleTypeName(base),
+        type: "extends",
+        sourceFile: filePath,
+        metadata: { baseType: base.replace(/<[\s\S]*$/, "") },
+      };
+      relationships.push(relationship);
+      this.pending.push({ kind: "type", relationship, entity, declaredAs, index });
+    });
 
-      const name = this.getNodeText(nameNode);
-      const location = this.getNodeLocation(enumNode);
-      const members = this.extractEnumMembers(enumNode);
+    if (node.type === "enum_declaration") return;
+
+    const counts = { method: 0, property: 0, field: 0, event: 0 };
+    const body = node.childForFieldName("body") ?? node;
+    for (const member of this.childrenOf(body)) {
+      switch (member.type) {
+        case "method_declaration":
+        case "constructor_declaration":
+          counts.method += this.extractMethod(member, filePath, entities, relationships, scope);
+          break;
+        case "property_declaration":
+          counts.property += this.extractProperty(member, filePath, entities, relationships, scope);
+          break;
+        case "field_declaration":
+          counts.field += this.extractFields(member, filePath, entities, relationships, scope);
+          break;
+        case "event_declaration":
+        case "event_field_declaration":
+          counts.event += this.extractEvents(member, filePath, entities, relationships, scope);
+          break;
+        case "delegate_declaration":
+          this.extractDelegate(member, filePath, entities, relationships, scope);
+          break;
+        default:
+          if (TYPE_DECLARATIONS[member.type]) this.extractType(member, filePath, entities, relationships, scope);
+      }
+    }
 
-      entities.push({
-        id: `${filePath}:enum:${name}`,
-        name,
-        type: "enum",
-        filePath,
-        location,
-        metadata: {
-          members,
-          memberCount: members.length,
-        },
-      });
+    metadata.methodCount = counts.method;
+    metadata.propertyCount = counts.property;
+    if (spec.idKind !== "interface") {
+      metadata.fieldCount = counts.field;
+      metadata.eventCount = counts.event;
     }
   }
 
   /**
    * Extract delegate declarations
    */
-  private extractDelegates(node: TreeSitterNode, entities: ParsedEntity[], filePath: string): void {
-    const delegateNodes = this.findNodes(node, "delegate_declaration");
-
-    for (const delegateNode of delegateNodes) {
-      const nameNode = delegateNode.childForFieldName("name");
-      if (!nameNode) continue;
-
-      const name = this.getNodeText(nameNode);
-      const location = this.getNodeLocation(delegateNode);
-      const returnType = this.extractReturnType(delegateNode);
-      const parameters = this.extractParameters(delegateNode);
+  private extractDelegate(
+    node: TreeSitterNode,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+    owner: TypeScope | null,
+  ): void {
+    const nameNode = node.childForFieldName("name");
+    if (!nameNode) return;
+
+    const name = this.getNodeText(nameNode);
+    const id = owner ? `${owner.id}:delegate:${name}` : `${filePath}:delegate:${name}`;
+    const returnType = this.extractReturnType(node);
+    const parameters = this.extractParameters(node);
+
+    entities.push({
+      id,
+      name,
+      type: "typedef",
+      filePath,
+      location: this.getNodeLocation(node),
+      returnType,
+      parameters,
+      metadata: {
+        returnType,
+        parameters,
+        csharpKind: "delegate",
+        qualifiedName: owner ? `${owner.qualifiedName}.${name}` : this.qualify(name),
+      },
+    });
+    if (owner) relationships.push({ from: id, to: owner.id, type: "contains", metadata: { memberType: "delegate" } });
+  }
 
-      entities.push({
-        id: `${filePath}:delegate:${name}`,
-        name,
-        type: "typedef",
-        filePath,
-        location,
-        metadata: {
-          returnType,
-          parameters,
-          csharpKind: "delegate",
-        },
-      });
-    }
+  /** The owner field members carry: `interfaceName` inside interfaces, `className` elsewhere */
+  private ownerMetadata(owner: TypeScope): Record<string, any> {
+    return owner.idKind === "interface" ? { interfaceName: owner.name } : { className: owner.name };
   }
 
   /**
-   * Extract record declarations (C# 9+)
+   * Extract a method or constructor declaration; returns the number of entities added
    */
-  private extractRecords(node: TreeSitterNode, entities: ParsedEntity[], filePath: string): void {
-    const recordNodes = this.findNodes(node, "record_declaration");
-
-    for (const recordNode of recordNodes) {
-      const nameNode = recordNode.childForFieldName("name");
-      if (!nameNode) continue;
-
-      const name = this.getNodeText(nameNode);
-      const location = this.getNodeLocation(recordNode);
-      const modifiers = this.extractModifiers(recordNode);
-      const isRecordStruct = recordNode.type === "record_struct_declaration";
-
-      entities.push({
-        id: `${filePath}:record:${name}`,
-        name,
-        type: "class",
-        filePath,
-        location,
-        metadata: {
-          isRecordStruct,
-          modifiers,
-          isRecord: true,
-        },
-      });
+  private extractMethod(
+    methodNode: TreeSitterNode,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+    owner: TypeScope,
+  ): number {
+    const isConstructor = methodNode.type === "constructor_declaration";
+    const nameNode = methodNode.childForFieldName("name");
+    const name = nameNode ? this.getNodeText(nameNode) : isConstructor ? owner.name : "";
+    if (!name) return 0;
+
+    const id = `${owner.id}:${isConstructor ? "constructor" : "method"}:${name}`;
+    const modifiers = this.extractModifiers(methodNode);
+    const returnType = isConstructor ? undefined : this.extractReturnType(methodNode);
+    const parameters = this.extractParameters(methodNode);
+    const decorators = this.extractDecorators(methodNode);
+
+    entities.push({
+      id,
+      name,
+      type: "method",
+      filePath,
+      location: this.getNodeLocation(methodNode),
+      modifiers,
+      returnType,
+      parameters,
+      ...(decorators.length ? { decorators } : {}),
+      metadata: {
+        ...this.ownerMetadata(owner),
+        qualifiedName: `${owner.qualifiedName}.${name}`,
+        modifiers,
+        returnType,
+        parameters,
+        attributes: decorators.map((d) => d.name),
+        isAsync: modifiers.includes("async"),
+        isStatic: modifiers.includes("static"),
+        isVirtual: modifiers.includes("virtual"),
+        isOverride: modifiers.includes("override"),
+        isAbstract: modifiers.includes("abstract"),
+        ...(isConstructor ? { isConstructor: true } : {}),
+        ...(owner.idKind === "interface" ? { isInterfaceMethod: true } : {}),
+      },
+    });
+    if (!isConstructor && !this.declaredMethods.has(`${owner.path}.${name}`)) {
+      this.declaredMethods.set(`${owner.path}.${name}`, id);
     }
+    relationships.push({
+      from: id,
+      to: owner.id,
+      type: "contains",
+      metadata: { memberType: isConstructor ? "constructor" : "method" },
+    });
+
+    for (const body of this.bodiesOf(methodNode)) this.extractCalls(body, id, owner, relationships);
+    return 1;
   }
 
   /**
-   * Extract method declarations
+   * Extract a property declaration; returns the number of entities added
    */
-  private extractMethods(
-    classNode: TreeSitterNode,
-    className: string,
-    entities: ParsedEntity[],
+  private extractProperty(
+    propNode: TreeSitterNode,
     filePath: string,
-  ): ParsedEntity[] {
-    const methods: ParsedEntity[] = [];
-    const methodNodes = this.findNodes(classNode, "method_declaration");
-
-    for (const methodNode of methodNodes) {
-      const nameNode = methodNode.childForFieldName("name");
-      if (!nameNode) continue;
-
-      const name = this.getNodeText(nameNode);
-      const location = this.getNodeLocation(methodNode);
-      const modifiers = this.extractModifiers(methodNode);
-      const returnType = this.extractReturnType(methodNode);
-      const parameters = this.extractParameters(methodNode);
-      const attributes = this.extractAttributes(methodNode);
-
-      const isAsync = modifiers.includes("async");
-      const isStatic = modifiers.includes("static");
-      const isVirtual = modifiers.includes("virtual");
-      const isOverride = modifiers.includes("override");
-      const isAbstract = modifiers.includes("abstract");
-
-      const method: ParsedEntity = {
-        id: `${filePath}:class:${className}:method:${name}`,
-        name,
-        type: "method",
-        filePath,
-        location,
-        metadata: {
-          className,
-          modifiers,
-          returnType,
-          parameters,
-          attributes,
-          isAsync,
-          isStatic,
-          isVirtual,
-          isOverride,
-          isAbstract,
-        },
-      };
-
-      methods.push(method);
-      entities.push(method);
-    }
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+    owner: TypeScope,
+  ): number {
+    const nameNode = propNode.childForFieldName("name");
+    if (!nameNode) return 0;
+
+    const name = this.getNodeText(nameNode);
+    const id = `${owner.id}:property:${name}`;
+    const modifiers = this.extractModifiers(propNode);
+    const decorators = this.extractDecorators(propNode);
+    const type = this.extractPropertyType(propNode);
+    const isInterface = owner.idKind === "interface";
+
+    entities.push({
+      id,
+      name,
+      type: "property",
+      filePath,
+      location: this.getNodeLocation(propNode),
+      modifiers,
+      ...(decorators.length ? { decorators } : {}),
+      metadata: {
+        ...this.ownerMetadata(owner),
+        qualifiedName: `${owner.qualifiedName}.${name}`,
+        modifiers,
+        attributes: decorators.map((d) => d.name),
+        propertyType: type,
+        hasGetter: this.hasAccessor(propNode, "get"),
+        hasSetter: this.hasAccessor(propNode, "set"),
+        hasInit: this.hasAccessor(propNode, "init"),
+        ...(isInterface ? { isInterfaceProperty: true } : {}),
+      },
+    });
+    this.memberTypes.set(`${owner.path}.${name}`, type);
+    relationships.push({ from: id, to: owner.id, type: "contains", metadata: { memberType: "property" } });
 
-    return methods;
+    for (const body of this.bodiesOf(propNode)) this.extractCalls(body, id, owner, relationships);
+    return 1;
   }
 
   /**
-   * Extract property declarations
+   * Extract field declarations; returns the number of entities added
    */
-  private extractProperties(
-    classNode: TreeSitterNode,
-    className: string,
-    entities: ParsedEntity[],
+  private extractFields(
+    fieldNode: TreeSitterNode,
     filePath: string,
-  ): ParsedEntity[] {
-    const properties: ParsedEntity[] = [];
-    const propertyNodes = this.findNodes(classNode, "property_declaration");
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+    owner: TypeScope,
+  ): number {
+    const type = this.extractFieldType(fieldNode);
+    const modifiers = this.extractModifiers(fieldNode);
+    const decorators = this.extractDecorators(fieldNode);
+    let count = 0;
 
-    for (const propNode of propertyNodes) {
-      const nameNode = propNode.childForFieldName("name");
+    for (const declarator of this.variableDeclarators(fieldNode)) {
+      const nameNode = this.declaratorName(declarator);
       if (!nameNode) continue;
 
       const name = this.getNodeText(nameNode);
-      const location = this.getNodeLocation(propNode);
-      const modifiers = this.extractModifiers(propNode);
-      const type = this.extractPropertyType(propNode);
-      const hasGetter = this.hasAccessor(propNode, "get");
-      const hasSetter = this.hasAccessor(propNode, "set");
-      const hasInit = this.hasAccessor(propNode, "init");
-
-      const property: ParsedEntity = {
-        id: `${filePath}:class:${className}:property:${name}`,
+      const id = `${owner.id}:field:${name}`;
+      entities.push({
+        id,
         name,
-        type: "property",
+        type: "field",
         filePath,
-        location,
+        location: this.getNodeLocation(declarator),
+        modifiers,
+        ...(decorators.length ? { decorators } : {}),
         metadata: {
-          className,
+          ...this.ownerMetadata(owner),
+          qualifiedName: `${owner.qualifiedName}.${name}`,
+          fieldType: type,
           modifiers,
-          propertyType: type,
-          hasGetter,
-          hasSetter,
-          hasInit,
+          attributes: decorators.map((d) => d.name),
+          isReadonly: modifiers.includes("readonly"),
+          isConst: modifiers.includes("const"),
+          isStatic: modifiers.includes("static"),
         },
-      };
-
-      properties.push(property);
-      entities.push(property);
-    }
-
-    return properties;
-  }
-
-  /**
-   * Extract field declarations
-   */
-  private extractFields(
-    classNode: TreeSitterNode,
-    className: string,
-    entities: ParsedEntity[],
-    filePath: string,
-  ): ParsedEntity[] {
-    const fields: ParsedEntity[] = [];
-    const fieldNodes = this.findNodes(classNode, "field_declaration");
-
-    for (const fieldNode of fieldNodes) {
-      const declarators = this.findNodes(fieldNode, "variable_declarator");
-      const type = this.extractFieldType(fieldNode);
-      const modifiers = this.extractModifiers(fieldNode);
-
-      for (const declarator of declarators) {
-        const nameNode = declarator.childForFieldName("name");
-        if (!nameNode) continue;
-
-        const name = this.getNodeText(nameNode);
-        const location = this.getNodeLocation(declarator);
-        const isReadonly = modifiers.includes("readonly");
-        const isConst = modifiers.includes("const");
-        const isStatic = modifiers.includes("static");
-
-        const field: ParsedEntity = {
-          id: `${filePath}:class:${className}:field:${name}`,
-          name,
-          type: "field",
-          filePath,
-          location,
-          metadata: {
-            className,
-            fieldType: type,
-            modifiers,
-            isReadonly,
-            isConst,
-            isStatic,
-          },
-        };
-
-        fields.push(field);
-        entities.push(field);
-      }
+      });
+      this.memberTypes.set(`${owner.path}.${name}`, type);
+      relationships.push({ from: id, to: owner.id, type: "contains", metadata: { memberType: "field" } });
+      count++;
     }
-
-    return fields;
+    return count;
   }
 
   /**
-   * Extract event declarations
+   * Extract event declarations, with accessors (`event_declaration`) or without; returns the
+   * number of entities added
    */
   private extractEvents(
-    classNode: TreeSitterNode,
-    className: string,
-    entities: ParsedEntity[],
+    eventNode: TreeSitterNode,
     filePath: string,
-  ): ParsedEntity[] {
-    const events: ParsedEntity[] = [];
-    const eventNodes = this.findNodes(classNode, "event_declaration");
-
-    for (const eventNode of eventNodes) {
-      const nameNode = eventNode.childForFieldName("name");
-      if (!nameNode) continue;
-
-      const name = this.getNodeText(nameNode);
-      const location = this.getNodeLocation(eventNode);
-      const modifiers = this.extractModifiers(eventNode);
-      const eventType = this.extractEventType(eventNode);
-
-      const event: ParsedEntity = {
-        id: `${filePath}:class:${className}:event:${name}`,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+    owner: TypeScope,
+  ): number {
+    const modifiers = this.extractModifiers(eventNode);
+    const nameNode = eventNode.childForFieldName("name");
+    const named: Array<{ nameNode: TreeSitterNode; node: TreeSitterNode }> = nameNode
+      ? [{ nameNode, node: eventNode }]
+      : this.variableDeclarators(eventNode).flatMap((declarator) => {
+          const declared = this.declaratorName(declarator);
+          return declared ? [{ nameNode: declared, node: declarator }] : [];
+        });
+    const eventType = nameNode ? this.extractEventType(eventNode) : this.extractFieldType(eventNode);
+
+    for (const { nameNode: declared, node } of named) {
+      const name = this.getNodeText(declared);
+      const id = `${owner.id}:event:${name}`;
+      entities.push({
+        id,
         name,
         type: "event",
         filePath,
-        location,
+        location: this.getNodeLocation(node),
+        modifiers,
         metadata: {
-          className,
+          ...this.ownerMetadata(owner),
+          qualifiedName: `${owner.qualifiedName}.${name}`,
           eventType,
           modifiers,
         },
-      };
-
-      events.push(event);
-      entities.push(event);
+      });
+      relationships.push({ from: id, to: owner.id, type: "contains", metadata: { memberType: "event" } });
     }
-
-    return events;
+    return named.length;
   }
 
   /**
-   * Extract interface methods
+   * `calls` edges for the invocations in a member body, walked with an explicit stack since
+   * fluent chains nest one invocation per call
    */
-  private extractInterfaceMethods(
-    interfaceNode: TreeSitterNode,
-    interfaceName: string,
-    entities: ParsedEntity[],
-    filePath: string,
-  ): ParsedEntity[] {
-    const methods: ParsedEntity[] = [];
-    const methodNodes = this.findNodes(interfaceNode, "method_declaration");
-
-    for (const methodNode of methodNodes) {
-      const nameNode = methodNode.childForFieldName("name");
-      if (!nameNode) continue;
-
-      const name = this.getNodeText(nameNode);
-      const location = this.getNodeLocation(methodNode);
-      const returnType = this.extractReturnType(methodNode);
-      const parameters = this.extractParameters(methodNode);
-
-      const method: ParsedEntity = {
-        id: `${filePath}:interface:${interfaceName}:method:${name}`,
-        name,
-        type: "method",
-        filePath,
-        location,
-        metadata: {
-          interfaceName,
-          returnType,
-          parameters,
-          isInterfaceMethod: true,
-        },
-      };
-
-      methods.push(method);
-      entities.push(method);
+  private extractCalls(
+    body: TreeSitterNode,
+    callerId: string,
+    owner: TypeScope,
+    relationships: EntityRelationship[],
+  ): void {
+    const stack: TreeSitterNode[] = [body];
+    while (stack.length > 0) {
+      const node = stack.pop()!;
+      if (node.type === "invocation_expression") {
+        const fn = node.childForFieldName("function");
+        const target = fn ? this.callTarget(fn) : null;
+        if (target) {
+          const receiver = target.receiver?.replace(/\s+/g, "");
+          const relationship: EntityRelationship = {
+            from: callerId,
+            to: target.name,
+            type: "calls",
+            metadata: {
+              callType: "method",
+              line: target.node.startPosition.row + 1,
+              column: target.node.startPosition.column,
+              callee: receiver ? `${receiver}.${target.name}` : target.name,
+              calleeName: target.name,
+            },
+          };
+          relationships.push(relationship);
+          this.pending.push({ kind: "call", relationship, name: target.name, owner: owner.path, receiver });
+        }
+      }
+      for (let i = node.childCount - 1; i >= 0; i--) {
+        const child = node.child(i);
+        if (child) stack.push(child);
+      }
     }
+  }
 
-    return methods;
+  /** Method name and receiver of `Run()`, `Run<T>()`, `obj.Run()` and `obj?.Run()` */
+  private callTarget(fn: TreeSitterNode): { name: string; node: TreeSitterNode; receiver?: string } | null {
+    switch (fn.type) {
+      case "identifier":
+        return { name: this.getNodeText(fn), node: fn };
+      case "generic_name":
+        return { name: this.simpleName(fn), node: fn };
+      case "member_access_expression": {
+        const nameNode = fn.childForFieldName("name");
+        const receiver = fn.childForFieldName("expression");
+        return nameNode ? { name: this.simpleName(nameNode), node: nameNode, receiver: receiver?.text } : null;
+      }
+      case "member_binding_expression": {
+        // `obj?.Run()`: the receiver is the condition of the enclosing conditional access
+        const nameNode = fn.childForFieldName("name");
+        const access = fn.parent?.parent;
+        const receiver = (access?.type === "conditional_access_expression" ? access.child(0)?.text : undefined) ?? "?";
+        return nameNode ? { name: this.simpleName(nameNode), node: nameNode, receiver } : null;
+      }
+      default:
+        return null;
+    }
   }
 
   /**
-   * Extract interface properties
+   * Point heritage and call edges at declarations of this file. A class's base list names at
+   * most one class, first; the rest are interfaces. Bases declared here settle which is which,
+   * otherwise the `IName` convention does, and structs and enums only implement. Calls without
+   * a receiver (or on `this`/`base`) look in the calling type, its enclosing types and their
+   * bases declared here; `Type.M()` and calls on a field or property typed with a local type
+   * look in that type. Targets declared elsewhere keep the name from source.
    */
-  private extractInterfaceProperties(
-    interfaceNode: TreeSitterNode,
-    interfaceName: string,
-    entities: ParsedEntity[],
-    filePath: string,
-  ): ParsedEntity[] {
-    const properties: ParsedEntity[] = [];
-    const propertyNodes = this.findNodes(interfaceNode, "property_declaration");
+  private bindLocalTargets(): void {
+    for (const item of this.pending) {
+      const rel = item.relationship;
+      if (item.kind === "type") {
+        const name = rel.metadata?.baseType as string;
+        const local = this.localType(name);
+        const isInterface = local ? local.idKind === "interface" : /^I[A-Z]/.test(rel.to);
+        let type: "extends" | "implements" = "implements";
+        if (item.declaredAs === "interface") type = "extends";
+        else if (item.declaredAs === "class" || item.declaredAs === "record") {
+          type = item.index === 0 && !isInterface ? "extends" : "implements";
+        }
+        rel.type = type;
+        if (local) rel.to = local.id;
+
+        const inheritance = item.entity.inheritance ?? { baseClasses: [] };
+        if (type === "implements" && item.declaredAs !== "interface") {
+          inheritance.interfaces = [...(inheritance.interfaces ?? []), name];
+        } else {
+          inheritance.baseClasses.push(name);
+        }
+        item.entity.inheritance = inheritance;
+        continue;
+      }
 
-    for (const propNode of propertyNodes) {
-      const nameNode = propNode.childForFieldName("name");
-      if (!nameNode) continue;
+      const local = this.findMethod(this.callOwners(item.owner, item.receiver), item.name);
+      if (local) rel.to = local;
+    }
+  }
 
-      const name = this.getNodeText(nameNode);
-      const location = this.getNodeLocation(propNode);
-      const type = this.extractPropertyType(propNode);
-      const hasGetter = this.hasAccessor(propNode, "get");
-      const hasSetter = this.hasAccessor(propNode, "set");
+  /** Types, by in-file path, that a call on `receiver` from inside `owner` may resolve in */
+  private callOwners(owner: string, receiver?: string): string[] {
+    const segments = owner.split(".");
+    const enclosing = segments.map((_, i) => segments.slice(0, segments.length - i).join("."));
+    if (!receiver || receiver === "this") return enclosing;
+    if (receiver === "base") return this.localBases(owner);
+
+    const type = this.declaredTypes.get(receiver);
+    if (type) return [type.path];
+
+    const member = receiver.replace(/^this\./, "");
+    for (const path of enclosing) {
+      const declared = this.memberTypes.get(`${path}.${member}`);
+      const memberType = declared ? this.localType(declared) : undefined;
+      if (memberType) return [memberType.path];
+    }
+    return [];
+  }
 
-      const property: ParsedEntity = {
-        id: `${filePath}:interface:${interfaceName}:property:${name}`,
-        name,
-        type: "property",
-        filePath,
-        location,
-        metadata: {
-          interfaceName,
-          propertyType: type,
-          hasGetter,
-          hasSetter,
-          isInterfaceProperty: true,
-        },
-      };
+  /** Base types of `path` declared in this file */
+  private localBases(path: string): string[] {
+    return (this.basesOf.get(path) ?? []).flatMap((base) => {
+      const type = this.localType(base);
+      return type ? [type.path] : [];
+    });
+  }
 
-      properties.push(property);
-      entities.push(property);
+  /** First of `owners` (or one of their bases declared in this file) that declares `name` */
+  private findMethod(owners: string[], name: string): string | undefined {
+    for (const owner of owners) {
+      const seen = new Set<string>();
+      const queue = [owner];
+      while (queue.length > 0) {
+        const type = queue.shift()!;
+        if (seen.has(type)) continue;
+        seen.add(type);
+        const id = this.declaredMethods.get(`${type}.${name}`);
+        if (id) return id;
+        queue.push(...this.localBases(type));
+      }
     }
-
-    return properties;
+    return undefined;
   }
 
   /**
@@ -653,12 +751,14 @@ export class CSharpAnalyzer {
     const usingNodes = this.findNodes(node, "using_directive");
 
     for (const usingNode of usingNodes) {
-      const nameNode = usingNode.childForFieldName("name");
+      const nameNode = this.usingTarget(usingNode);
       if (!nameNode) continue;
 
       const name = this.getNodeText(nameNode);
       const isStatic = this.hasChild(usingNode, "static");
       const alias = this.extractAlias(usingNode);
+      const isGlobal = this.hasChild(usingNode, "global");
+      if (!alias && !isStatic) this.usings.push(name);
 
       imports.push({
         sourceFile: filePath,
@@ -669,17 +769,18 @@ export class CSharpAnalyzer {
         isUsed: false,
         usageLocations: [],
         type: "import",
-        metadata: { alias, isStatic },
+        metadata: { alias, isStatic, ...(isGlobal ? { isGlobal } : {}) },
       } as ImportDependency);
     }
 
     // Also extract global using statements (C# 10+)
     const globalUsingNodes = this.findNodes(node, "global_using_directive");
     for (const globalUsing of globalUsingNodes) {
-      const nameNode = globalUsing.childForFieldName("name");
+      const nameNode = this.usingTarget(globalUsing);
       if (!nameNode) continue;
 
       const name = this.getNodeText(nameNode);
+      this.usings.push(name);
       imports.push({
         sourceFile: filePath,
         targetModule: name,
@@ -959,28 +1060,122 @@ export class CSharpAnalyzer {
   }
 
   /**
-   * Extract attributes from a declaration
+   * Attributes on a declaration (`[HttpGet("{id}")]`), in the shape decorators have for other
+   * languages: name as written, argument texts and the attribute without brackets. Only the
+   * declaration's own attribute lists count, not those of its members.
    */
-  private extractAttributes(node: TreeSitterNode): string[] {
-    const attributes: string[] = [];
-    const attrLists = this.findNodes(node, "attribute_list");
-    for (const list of attrLists) {
-      const attrs = this.findNodes(list, "attribute");
-      for (const attr of attrs) {
+  private extractDecorators(node: TreeSitterNode): Decorator[] {
+    const decorators: Decorator[] = [];
+    for (const list of this.childrenOf(node)) {
+      if (list.type !== "attribute_list") continue;
+      for (const attr of this.childrenOf(list)) {
+        if (attr.type !== "attribute") continue;
         const nameNode = attr.childForFieldName("name");
-        if (nameNode) {
-          attributes.push(this.getNodeText(nameNode));
-        }
+        if (!nameNode) continue;
+        const args = this.childrenOf(attr).find((c) => c.type === "attribute_argument_list");
+        decorators.push({
+          name: this.getNodeText(nameNode),
+          arguments: args
+            ? this.childrenOf(args)
+                .filter((a) => a.type === "attribute_argument")
+                .map((a) => this.getNodeText(a))
+            : undefined,
+          raw: this.getNodeText(attr) || this.getNodeText(nameNode),
+          line: attr.startPosition.row + 1,
+        });
       }
     }
-    return attributes;
+    return decorators;
+  }
+
+  /**
+   * Base list entries as written: `: ControllerBase, IDisposable` is `["ControllerBase",
+   * "IDisposable"]`; a record's `Base(args)` keeps only the type
+   */
+  private baseTypeNames(node: TreeSitterNode): string[] {
+    const baseList =
+      node.childForFieldName("bases") ?? this.childrenOf(node).find((c) => c.type === "base_list") ?? null;
+    if (!baseList) return [];
+
+    const names: string[] = [];
+    for (const entry of this.childrenOf(baseList)) {
+      if (entry.type === ":" || entry.type === "," || entry.type === "comment") continue;
+      const typeNode =
+        entry.type === "primary_constructor_base_type"
+          ? this.childrenOf(entry).find((c) => c.type !== "argument_list")
+          : entry;
+      const name = typeNode ? this.getNodeText(typeNode).replace(/\s+/g, "") : "";
+      if (name) names.push(name);
+    }
+    return names;
+  }
+
+  /** Type declared in this file that a type name as written refers to */
+  private localType(name: string): TypeScope | undefined {
+    const written = name
+      .replace(/<[\s\S]*$/, "")
+      .replace(/\s+/g, "")
+      .replace(/^global::/, "");
+    return this.declaredTypes.get(written) ?? this.declaredTypes.get(this.simpleTypeName(written));
+  }
+
+  /** `IRepository<User>?`, `Shop.Core.Entity` and `int[]` as the bare type name */
+  private simpleTypeName(type: string): string {
+    const bare = type.replace(/<[\s\S]*$/, "").replace(/[?[\]\s]/g, "");
+    return bare.split(/\.|::/).pop() ?? bare;
+  }
+
+  /** Identifier of a possibly generic name: `Run` for `Run<T>` */
+  private simpleName(node: TreeSitterNode): string {
+    return this.getNodeText(node).replace(/<[\s\S]*$/, "").trim();
+  }
+
+  private childrenOf(node: TreeSitterNode): TreeSitterNode[] {
+    const children: TreeSitterNode[] = [];
+    for (let i = 0; i < node.childCount; i++) {
+      const child = node.child(i);
+      if (child) children.push(child);
+    }
+    return children;
+  }
+
+  /** Block and expression bodies of a method, and accessors of a property */
+  private bodiesOf(node: TreeSitterNode): TreeSitterNode[] {
+    const bodies = this.childrenOf(node).filter(
+      (c) => c.type === "block" || c.type === "arrow_expression_clause" || c.type === "accessor_list",
+    );
+    const body = node.childForFieldName("body");
+    if (bodies.length === 0 && body) bodies.push(body);
+    return bodies;
+  }
+
+  /** Declarators of a field or event field, which the grammar wraps in a `variable_declaration` */
+  private variableDeclarators(node: TreeSitterNode): TreeSitterNode[] {
+    const declaration = this.childrenOf(node).find((c) => c.type === "variable_declaration") ?? node;
+    return this.childrenOf(declaration).filter((c) => c.type === "variable_declarator");
+  }
+
+  private declaratorName(declarator: TreeSitterNode): TreeSitterNode | null {
+    return (
+      declarator.childForFieldName("name") ?? this.childrenOf(declarator).find((c) => c.type === "identifier") ?? null
+    );
+  }
+
+  /** Namespace or type a using directive brings in */
+  private usingTarget(node: TreeSitterNode): TreeSitterNode | null {
+    const named = node.childForFieldName("name");
+    if (named) return named;
+    const names = this.childrenOf(node).filter((c) =>
+      ["qualified_name", "identifier", "generic_name", "alias_qualified_name"].includes(c.type),
+    );
+    return names[names.length - 1] ?? null;
   }
 
   /**
    * Extract return type from a method
    */
   private extractReturnType(node: TreeSitterNode): string {
-    const returnTypeNode = node.childForFieldName("type");
+    const returnTypeNode = node.childForFieldName("returns") ?? node.childForFieldName("type");
     return returnTypeNode ? this.getNodeText(returnTypeNode) : "void";
   }
 
@@ -1020,7 +1215,8 @@ export class CSharpAnalyzer {
    * Extract field type
    */
   private extractFieldType(node: TreeSitterNode): string {
-    const typeNode = node.childForFieldName("type");
+    const declaration = this.childrenOf(node).find((c) => c.type === "variable_declaration");
+    const typeNode = node.childForFieldName("type") ?? declaration?.childForFieldName("type");
     return typeNode ? this.getNodeText(typeNode) : "unknown";
   }
 
@@ -1044,6 +1240,10 @@ export class CSharpAnalyzer {
       if (child && child.type === `${accessor}_accessor`) {
         return true;
       }
+      // Newer grammars use one `accessor_declaration` node led by the accessor keyword
+      if (child?.type === "accessor_declaration" && this.hasChild(child, accessor)) {
+        return true;
+      }
     }
     return false;
   }
@@ -1065,7 +1265,8 @@ export class CSharpAnalyzer {
    * Extract alias from using statement
    */
   private extractAlias(node: TreeSitterNode): string | undefined {
-    const aliasNode = node.childForFieldName("alias");
+    const aliasNode =
+      node.childForFieldName("alias") ?? this.childrenOf(node).find((c) => c.type === "name_equals")?.child(0);
     return aliasNode ? this.getNodeText(aliasNode) : undefined;
   }
 
@@ -0,0 +1,169 @@
+/**
+ * User-supplied tree-sitter queries (`parser.customQueries` in the config), run after the built-in
+ * extraction of a file. A query either turns each match into an entity of its own `kind`, or
+ * records the match as an `attribute` of the declaration it sits in or directly above.
+ *
+ *   parser:
+ *     customQueries:
+ *       go:
+ *         - name: go-generate
+ *           query: '((comment) @directive (#match? @directive "^//go:generate"))'
+ *           kind: go_generate
+ *
+ * Captures named `@name` and `@value` choose an entity's name and an attribute's value; without
+ * them the text of the first capture is used. Every capture's text is kept in `metadata.captures`.
+ */
+
+import Parser from "tree-sitter";
+import type { CustomQuerySpec } from "../config/yaml-config.js";
+import type { ParsedEntity, SupportedLanguage, TreeSitterNode } from "../types/parser.js";
+import { GRAMMAR_MODULES, GrammarLoadError, loadGrammar } from "./grammar-loader.js";
+
+const MAX_CAPTURE_TEXT = 500;
+const MAX_NAME_LENGTH = 120;
+
+// Queries written for a language also run on its JSX flavour
+const QUERY_LANGUAGE: Partial<Record<SupportedLanguage, SupportedLanguage>> = {
+  tsx: "typescript",
+  jsx: "javascript",
+};
+
+export class CustomQueryError extends Error {
+  constructor(
+    readonly language: string,
+    readonly queryName: string,
+    reason: string,
+  ) {
+    super(`Custom query "${queryName}" for ${language}: ${reason}`);
+    this.name = "CustomQueryError";
+  }
+}
+
+export interface CompiledCustomQuery {
+  spec: CustomQuerySpec;
+  query: Parser.Query;
+}
+
+/** Queries configured for `language`, falling back to those of the language it is a flavour of */
+export function customQueriesFor(
+  all: Record<string, CustomQuerySpec[]> | undefined,
+  language: SupportedLanguage,
+): CustomQuerySpec[] {
+  if (!all) return [];
+  return all[language] ?? (QUERY_LANGUAGE[language] ? all[QUERY_LANGUAGE[language]!] : undefined) ?? [];
+}
+
+/** Compile `specs` against a loaded grammar; a query the grammar rejects throws CustomQueryError */
+export function compileCustomQueries(
+  language: SupportedLanguage,
+  grammar: unknown,
+  specs: CustomQuerySpec[],
+): CompiledCustomQuery[] {
+  return specs.map((spec) => {
+    try {
+      return { spec, query: new Parser.Query(grammar as Parser.Language, spec.query) };
+    } catch (error) {
+      // tree-sitter reports the kind of error and its byte offset, e.g. "Invalid node type foo"
+      throw new CustomQueryError(language, spec.name, error instanceof Error ? error.message : String(error));
+    }
+  });
+}
+
+/**
+ * Compile every configured query against its grammar, for startup validation. Each message names
+ * the language, the query and what tree-sitter rejected. Grammars that are not installed are skipped;
+ * their files are not parsed either.
+ */
+export function validateCustomQueries(all: Record<string, CustomQuerySpec[]> | undefined): string[] {
+  const errors: string[] = [];
+  for (const [language, specs] of Object.entries(all ?? {})) {
+    if (!GRAMMAR_MODULES[language as SupportedLanguage]) {
+      errors.push(`parser.customQueries.${language}: ${language} files are not parsed with tree-sitter`);
+      continue;
+    }
+    let grammar: unknown;
+    try {
+      grammar = loadGrammar(language as SupportedLanguage);
+    } catch (error) {
+      if (error instanceof GrammarLoadError) continue;
+      throw error;
+    }
+    for (const spec of specs) {
+      try {
+        compileCustomQueries(language as SupportedLanguage, grammar, [spec]);
+      } catch (error) {
+        errors.push(error instanceof Error ? error.message : String(error));
+      }
+    }
+  }
+  return errors;
+}
+
+function text(node: TreeSitterNode, source: string): string {
+  return source.slice(node.startIndex, Math.min(node.endIndex, node.startIndex + MAX_CAPTURE_TEXT));
+}
+
+function locationOf(node: TreeSitterNode): ParsedEntity["location"] {
+  return {
+    start: { line: node.startPosition.row + 1, column: node.startPosition.column, index: node.startIndex },
+    end: { line: node.endPosition.row + 1, column: node.endPosition.column, index: node.endIndex },
+  };
+}
+
+/** Innermost entity spanning `line`, else the one declared on the line after `endLine` (annotations, comments) */
+function ownerOf(entities: ParsedEntity[], line: number, endLine: number): ParsedEntity | undefined {
+  let owner: ParsedEntity | undefined;
+  for (const entity of entities) {
+    const { start, end } = entity.location;
+    if (start.line > line || end.line < line) continue;
+    if (!owner || end.line - start.line < owner.location.end.line - owner.location.start.line) owner = entity;
+  }
+  return owner ?? entities.find((entity) => entity.location.start.line === endLine + 1);
+}
+
+/**
+ * Run the compiled queries over a parsed file. Returns the entities the `kind` queries produced;
+ * `attribute` queries write into `metadata.attributes` of the entities passed in.
+ */
+export function runCustomQueries(
+  queries: CompiledCustomQuery[],
+  root: TreeSitterNode,
+  source: string,
+  filePath: string,
+  entities: ParsedEntity[],
+): ParsedEntity[] {
+  const created: ParsedEntity[] = [];
+  for (const { spec, query } of queries) {
+    for (const match of query.matches(root as unknown as Parser.SyntaxNode)) {
+      const captures = match.captures as unknown as Array<{ name: string; node: TreeSitterNode }>;
+      if (captures.length === 0) continue;
+      const byName: Record<string, string> = {};
+      for (const { name, node } of captures) byName[name] ??= text(node, source);
+      const anchor = captures[0]!.node;
+
+      if (spec.kind) {
+        const named = captures.find((c) => c.name === "name");
+        const name = (named ? byName.name! : text(anchor, source)).split(/\r?\n/)[0]!.trim().slice(0, MAX_NAME_LENGTH);
+        if (!name) continue;
+        created.push({
+          name,
+          type: spec.kind as ParsedEntity["type"],
+          filePath,
+          location: locationOf(anchor),
+          metadata: { customQuery: spec.name, captures: byName },
+        });
+        continue;
+      }
+
+      const valued = captures.find((c) => c.name === "value") ?? captures[0]!;
+      const owner = ownerOf(entities, valued.node.startPosition.row + 1, valued.node.endPosition.row + 1);
+      if (!owner) continue;
+      owner.metadata ??= {};
+      const attributes: Record<string, string[]> = (owner.metadata.attributes ??= {});
+      const values = (attributes[spec.attribute!] ??= []);
+      const value = byName[valued.name]!.trim();
+      if (!values.includes(value)) values.push(value);
+    }
+  }
+  return created;
+}
@@ -0,0 +1,115 @@
+/**
+ * Doc Comments - documentation written next to a declaration
+ * Leading comment blocks (Go and C-family `//` runs or block comments, JSDoc for JS/TS, Ruby `#`
+ * runs) are read from the source lines above an entity; Python docstrings come from the syntax tree. The raw text,
+ * comment markers included, is what gets stored; `stripCommentMarkers` produces the prose that is
+ * embedded.
+ */
+
+import type { SupportedLanguage, TreeSitterNode } from "../types/parser.js";
+
+/** Longer documentation is cut to this many characters before it is stored */
+export const MAX_DOCUMENTATION_LENGTH = 4000;
+
+const JSDOC_ONLY = new Set<SupportedLanguage>(["javascript", "typescript", "jsx", "tsx"]);
+const C_STYLE = new Set<SupportedLanguage>([
+  "go",
+  "c",
+  "cpp",
+  "csharp",
+  "rust",
+  "java",
+  "kotlin",
+  "php",
+  "swift",
+  "scala",
+]);
+const HASH_STYLE = new Set<SupportedLanguage>(["ruby"]);
+
+/** Attribute and annotation lines that may sit between a doc comment and its declaration */
+const ATTRIBUTE_LINE = /^(@[\w.]+|#\[|\[[A-Z])/;
+
+function truncate(text: string): string {
+  return text.length > MAX_DOCUMENTATION_LENGTH ? text.slice(0, MAX_DOCUMENTATION_LENGTH) : text;
+}
+
+/**
+ * Lines (1-based, inclusive) of the comment block ending on the line directly above `startLine`,
+ * skipping attribute lines. A blank line in between means the comment is not attached to the
+ * declaration.
+ */
+export function leadingDocCommentRange(
+  lines: string[],
+  startLine: number,
+  language: SupportedLanguage,
+): { start: number; end: number } | undefined {
+  if (!JSDOC_ONLY.has(language) && !C_STYLE.has(language) && !HASH_STYLE.has(language)) return undefined;
+
+  let i = startLine - 2;
+  while (i >= 0 && ATTRIBUTE_LINE.test(lines[i]!.trim())) i--;
+  if (i < 0) return undefined;
+
+  const last = lines[i]!.trim();
+  let first = i;
+  if (HASH_STYLE.has(language)) {
+    if (!last.startsWith("#")) return undefined;
+    while (first > 0 && lines[first - 1]!.trim().startsWith("#")) first--;
+  } else if (last.endsWith("*/")) {
+    while (first >= 0 && !lines[first]!.includes("/*")) first--;
+    if (first < 0) return undefined;
+    const opening = lines[first]!.trim();
+    if (JSDOC_ONLY.has(language) ? !opening.startsWith("/**") : !opening.startsWith("/*")) return undefined;
+  } else if (C_STYLE.has(language) && last.startsWith("//")) {
+    while (first > 0 && lines[first - 1]!.trim().startsWith("//")) first--;
+  } else {
+    return undefined;
+  }
+  return { start: first + 1, end: i + 1 };
+}
+
+/** The comment block documenting the declaration at `startLine`, as `leadingDocCommentRange` finds it */
+export function leadingDocComment(
+  lines: string[],
+  startLine: number,
+  language: SupportedLanguage,
+): string | undefined {
+  const range = leadingDocCommentRange(lines, startLine, language);
+  if (!range) return undefined;
+  return truncate(
+    lines
+      .slice(range.start - 1, range.end)
+      .map((line) => line.trim())
+      .join("\n"),
+  );
+}
+
+/** The docstring of a Python function or class, quotes included */
+export function pythonDocstring(node: TreeSitterNode): string | undefined {
+  const body = node.childForFieldName("body") ?? node.namedChildren.find((c) => c.type === "block");
+  const first = body?.namedChildren.find((c) => c.type !== "comment");
+  if (first?.type !== "expression_statement") return undefined;
+  const literal = first.namedChildren[0];
+  return literal?.type === "string" && first.namedChildCount === 1 ? truncate(literal.text) : undefined;
+}
+
+/** Documentation text with comment markers and string quotes removed, for embedding */
+export function stripCommentMarkers(raw: string): string {
+  let text = raw.trim();
+  const quoted = /^[rRuUbB]{0,2}("""|''')([\s\S]*?)\1$/.exec(text) ?? /^[rRuUbB]{0,2}(["'])(.*)\1$/s.exec(text);
+  if (quoted) text = quoted[2]!;
+
+  return text
+    .split(/\r?\n/)
+    .map((line) =>
+      line
+        .trim()
+        .replace(/^\/\*+\s?/, "")
+        .replace(/\s?\*+\/$/, "")
+        .replace(/^\/\/[/!]?\s?/, "")
+        .replace(/^#+\s?/, "")
+        .replace(/^\*\s?/, "")
+        .trimEnd(),
+    )
+    .join("\n")
+    .trim();
+}
@@ -0,0 +1,56 @@
+/**
+ * `parser.entityKinds` in the config: which entity kinds a language's files keep. Applied to each
+ * parse result before it is cached, so a dropped kind never reaches storage or the embedder.
+ *
+ *   parser:
+ *     entityKinds:
+ *       "*":
+ *         exclude: [comment]
+ *       typescript:
+ *         exclude: [variable]
+ *
+ * A rule for the language wins over one for the language it is a flavour of, which wins over `*`.
+ * Dropping a kind drops only those entities: the members of a dropped class are kept.
+ */
+
+import type { EntityKindRule } from "../config/yaml-config.js";
+import type { EntityRelationship, ParsedEntity, SupportedLanguage } from "../types/parser.js";
+
+// A rule written for a language also applies to its JSX flavour
+const RULE_LANGUAGE: Partial<Record<SupportedLanguage, SupportedLanguage>> = {
+  tsx: "typescript",
+  jsx: "javascript",
+};
+
+/** Whether an entity of `kind` is kept, or null when `language` keeps every kind */
+export function entityKindFilter(
+  rules: Record<string, EntityKindRule> | undefined,
+  language: SupportedLanguage,
+): ((kind: string) => boolean) | null {
+  if (!rules) return null;
+  const flavourOf = RULE_LANGUAGE[language];
+  const rule = rules[language] ?? (flavourOf ? rules[flavourOf] : undefined) ?? rules["*"];
+  const include = rule?.include ? new Set(rule.include) : null;
+  const exclude = new Set(rule?.exclude ?? []);
+  if (!include && exclude.size === 0) return null;
+  return (kind) => (!include || include.has(kind)) && !exclude.has(kind);
+}
+
+/** Entities whose kind is kept, and the relationships that do not start or end at a dropped one */
+export function filterEntityKinds<R extends Pick<EntityRelationship, "from" | "to">>(
+  entities: ParsedEntity[],
+  relationships: R[],
+  keep: (kind: string) => boolean,
+): { entities: ParsedEntity[]; relationships: R[] } {
+  const dropped = new Set<string>();
+  const kept = entities.filter((entity) => {
+    if (keep(String(entity.type))) return true;
+    if (entity.id) dropped.add(entity.id);
+    return false;
+  });
+  if (kept.length === entities.length) return { entities, relationships };
+  return {
+    entities: kept,
+    relationships: relationships.filter((rel) => !dropped.has(rel.from) && !dropped.has(rel.to)),
+  };
+}
@@ -0,0 +1,300 @@
+/**
+ * Env Extractor - environment variables read by the code
+ * `process.env.PORT`, `process.env["PORT"]`, `const { PORT } = process.env`, `import.meta.env.X`,
+ * `Deno.env.get("X")` (JS/TS), `os.Getenv("PORT")` / `os.LookupEnv` (Go) and `os.environ["PORT"]`,
+ * `os.environ.get("PORT")`, `os.getenv("PORT")` (Python) are read from the syntax tree. A key held
+ * in a string constant of the same file is resolved through it; keys computed at run time are
+ * kept as unresolved reads named by their expression. Each variable becomes an `env_var` entity
+ * per file, whose `readers` are the declarations reading it.
+ */
+
+import type { EntityRelationship, ParsedEntity, SupportedLanguage, TreeSitterNode } from "../types/parser.js";
+
+export type EnvRead = {
+  line: number;
+  column: number;
+  /** How the variable is read, as written: `process.env`, `os.Getenv`, `os.environ.get` */
+  accessor: string;
+  /** Fallback given at the read (`os.getenv("X", "8080")`, `process.env.X ?? "8080"`) */
+  default?: string;
+  /** String constant the key was read through */
+  constant?: string;
+  /** Innermost function or method around the read */
+  reader?: string;
+};
+
+const JS_ENV_OBJECTS = new Set(["process.env", "import.meta.env", "Bun.env"]);
+const JS_ENV_CALLS = new Set(["Deno.env.get"]);
+const GO_ENV_CALLS = new Set(["os.Getenv", "os.LookupEnv", "syscall.Getenv"]);
+const PYTHON_ENV_MAPPINGS = new Set(["os.environ", "environ"]);
+const PYTHON_ENV_CALLS = new Set(["os.getenv", "getenv", "os.environ.get", "environ.get"]);
+
+const JS_CONSTANT = /\b(?:const|let|var)\s+([A-Za-z_$][\w$]*)\s*(?::\s*string\s*)?=\s*(["'`])([^"'`$\n]*)\2/g;
+const GO_CONSTANT = /(?:^|[\s(])([A-Za-z_]\w*)\s*(?:string\s*)?=\s*"([^"\n]*)"/gm;
+const PYTHON_CONSTANT = /^\s*([A-Za-z_]\w*)\s*(?::\s*str\s*)?=\s*[rbu]?(["'])([^"'\n]*)\2\s*$/gm;
+
+const READER_KINDS = new Set([
+  "function",
+  "method",
+  "async_function",
+  "magic_method",
+  "class_method",
+  "static_method",
+  "abstract_method",
+  "property",
+  "generator",
+]);
+
+function location(node: TreeSitterNode): ParsedEntity["location"] {
+  return {
+    start: { line: node.startPosition.row + 1, column: node.startPosition.column, index: node.startIndex },
+    end: { line: node.endPosition.row + 1, column: node.endPosition.column, index: node.endIndex },
+  };
+}
+
+function walk(node: TreeSitterNode, visit: (node: TreeSitterNode) => void): void {
+  visit(node);
+  for (const child of node.namedChildren) walk(child, visit);
+}
+
+/** Value of a string literal without interpolation; f-strings and templates with `${}` don't count */
+function stringValue(node: TreeSitterNode | null | undefined): string | undefined {
+  if (!node) return undefined;
+  if (node.type === "template_string" && node.namedChildren.some((c) => c.type === "template_substitution")) {
+    return undefined;
+  }
+  const match = /^[rbu]?(["'`])([^"'`]*)\1$/i.exec(node.text.trim());
+  return match ? match[2] : undefined;
+}
+
+/**
+ * Names bound to a single string constant anywhere in the file. A name assigned two different
+ * values (shadowed, reassigned) is left out rather than guessed.
+ */
+function stringConstants(source: string, language: SupportedLanguage): Map<string, string> {
+  const pattern = language === "go" ? GO_CONSTANT : language === "python" ? PYTHON_CONSTANT : JS_CONSTANT;
+  const constants = new Map<string, string>();
+  const conflicting = new Set<string>();
+  for (const match of source.matchAll(pattern)) {
+    const name = match[1]!;
+    const value = language === "go" ? match[2]! : match[3]!;
+    if (constants.has(name) && constants.get(name) !== value) conflicting.add(name);
+    constants.set(name, value);
+  }
+  for (const name of conflicting) constants.delete(name);
+  return constants;
+}
+
+type KeyRead = {
+  node: TreeSitterNode;
+  accessor: string;
+  /** Key expression of `env[key]` and `getenv(key)` */
+  key: TreeSitterNode | null;
+  /** Name read as a property or destructured binding */
+  name?: string;
+  fallback?: string;
+};
+
+/** `X ?? "d"` and `X || "d"` around a JS read */
+function jsFallback(node: TreeSitterNode): string | undefined {
+  const parent = node.parent;
+  if (parent?.type !== "binary_expression" || parent.childForFieldName("left")?.id !== node.id) return undefined;
+  const operator = parent.childForFieldName("operator")?.text;
+  if (operator !== "??" && operator !== "||") return undefined;
+  const right = parent.childForFieldName("right");
+  return stringValue(right) ?? (right?.type === "number" ? right.text : undefined);
+}
+
+function jsReads(root: TreeSitterNode): KeyRead[] {
+  const reads: KeyRead[] = [];
+  walk(root, (node) => {
+    if (node.type === "member_expression" || node.type === "subscript_expression") {
+      const object = node.childForFieldName("object");
+      if (!object || !JS_ENV_OBJECTS.has(object.text)) return;
+      const fallback = jsFallback(node);
+      if (node.type === "member_expression") {
+        const property = node.childForFieldName("property");
+        if (property) reads.push({ node, accessor: object.text, key: null, name: property.text, fallback });
+      } else {
+        reads.push({ node, accessor: object.text, key: node.childForFieldName("index"), fallback });
+      }
+    } else if (node.type === "call_expression") {
+      const callee = node.childForFieldName("function")?.text ?? "";
+      if (!JS_ENV_CALLS.has(callee)) return;
+      const key = node.childForFieldName("arguments")?.namedChildren[0] ?? null;
+      reads.push({ node, accessor: callee, key, fallback: jsFallback(node) });
+    } else if (node.type === "variable_declarator") {
+      // `const { PORT, HOST = "localhost", DB_URL: url } = process.env`
+      const value = node.childForFieldName("value");
+      const pattern = node.childForFieldName("name");
+      if (!value || !JS_ENV_OBJECTS.has(value.text) || pattern?.type !== "object_pattern") return;
+      for (const property of pattern.namedChildren) {
+        if (property.type === "shorthand_property_identifier_pattern") {
+          reads.push({ node: property, accessor: value.text, key: null, name: property.text });
+        } else if (property.type === "object_assignment_pattern") {
+          const left = property.childForFieldName("left");
+          const right = property.childForFieldName("right");
+          if (!left) continue;
+          const fallback = stringValue(right);
+          reads.push({ node: property, accessor: value.text, key: null, name: left.text, fallback });
+        } else if (property.type === "pair_pattern") {
+          const key = property.childForFieldName("key");
+          if (key?.type === "property_identifier") {
+            reads.push({ node: property, accessor: value.text, key: null, name: key.text });
+          } else if (key) {
+            reads.push({ node: property, accessor: value.text, key });
+          }
+        }
+      }
+    }
+  });
+  return reads;
+}
+
+function goReads(root: TreeSitterNode): KeyRead[] {
+  const reads: KeyRead[] = [];
+  walk(root, (node) => {
+    if (node.type !== "call_expression") return;
+    const callee = node.childForFieldName("function")?.text ?? "";
+    if (!GO_ENV_CALLS.has(callee)) return;
+    const key = node.childForFieldName("arguments")?.namedChildren.find((c) => c.type !== "comment") ?? null;
+    reads.push({ node, accessor: callee, key });
+  });
+  return reads;
+}
+
+function pythonReads(root: TreeSitterNode, source: string): KeyRead[] {
+  // Bare `environ` / `getenv` only count when imported from os
+  const imported = (name: string) => new RegExp(`^\\s*from\\s+os\\s+import\\s+[^\\n]*\\b${name}\\b`, "m").test(source);
+  const bareEnviron = imported("environ");
+  const bareGetenv = imported("getenv");
+  const known = (text: string) => (!text.startsWith("environ") || bareEnviron) && (text !== "getenv" || bareGetenv);
+
+  const reads: KeyRead[] = [];
+  walk(root, (node) => {
+    if (node.type === "subscript") {
+      const value = node.childForFieldName("value")?.text ?? "";
+      if (!PYTHON_ENV_MAPPINGS.has(value) || !known(value)) return;
+      reads.push({ node, accessor: value, key: node.childForFieldName("subscript") });
+    } else if (node.type === "call") {
+      const callee = node.childForFieldName("function")?.text ?? "";
+      if (!PYTHON_ENV_CALLS.has(callee) || !known(callee)) return;
+      const args = (node.childForFieldName("arguments")?.namedChildren ?? []).filter((c) => c.type !== "comment");
+      const fallback = args[1] && args[1].type !== "keyword_argument" ? args[1] : undefined;
+      reads.push({
+        node,
+        accessor: callee,
+        key: args[0] ?? null,
+        fallback: stringValue(fallback) ?? (fallback?.type === "integer" ? fallback.text : undefined),
+      });
+    }
+  });
+  return reads;
+}
+
+function flatten(entities: ParsedEntity[]): ParsedEntity[] {
+  return entities.flatMap((entity) => [entity, ...flatten(entity.children ?? [])]);
+}
+
+/** Innermost function or method whose line range contains `node` (analyzers do not all report offsets) */
+function readerOf(node: TreeSitterNode, declarations: ParsedEntity[]): ParsedEntity | undefined {
+  const line = node.startPosition.row + 1;
+  let innermost: ParsedEntity | undefined;
+  for (const entity of declarations) {
+    const { start, end } = entity.location;
+    if (start.line > line || end.line < line) continue;
+    if (!innermost || innermost.location.start.line <= start.line) innermost = entity;
+  }
+  return innermost;
+}
+
+/**
+ * Environment variables read in a parsed file, one `env_var` entity per variable (and per
+ * unresolved key expression) located at its first read, with every read in `metadata.reads`.
+ * `entities` are the file's extracted declarations, used to find the function around each read.
+ */
+export function extractEnvVars(
+  root: TreeSitterNode,
+  language: SupportedLanguage,
+  filePath: string,
+  source: string,
+  entities: ParsedEntity[],
+): ParsedEntity[] {
+  let reads: KeyRead[];
+  switch (language) {
+    case "javascript":
+    case "jsx":
+    case "typescript":
+    case "tsx":
+      reads = jsReads(root);
+      break;
+    case "go":
+      reads = goReads(root);
+      break;
+    case "python":
+      reads = pythonReads(root, source);
+      break;
+    default:
+      return [];
+  }
+  if (reads.length === 0) return [];
+
+  const constants = stringConstants(source, language);
+  const declarations = flatten(entities).filter((e) => READER_KINDS.has(e.type) && e.location);
+  const variables = new Map<string, ParsedEntity>();
+  for (const read of reads) {
+    let name = read.name ?? stringValue(read.key);
+    let constant: string | undefined;
+    if (name === undefined && read.key?.type === "identifier" && constants.has(read.key.text)) {
+      constant = read.key.text;
+      name = constants.get(constant);
+    }
+    const unresolved = name === undefined;
+    const key = name ?? read.key?.text ?? read.node.text;
+
+    const reader = readerOf(read.node, declarations);
+    const at: EnvRead = {
+      line: read.node.startPosition.row + 1,
+      column: read.node.startPosition.column,
+      accessor: read.accessor,
+      ...(read.fallback !== undefined ? { default: read.fallback } : {}),
+      ...(constant ? { constant } : {}),
+      ...(reader ? { reader: reader.name } : {}),
+    };
+
+    const id = `${filePath}:env:${unresolved ? "?" : ""}${key}`;
+    let variable = variables.get(id);
+    if (!variable) {
+      variable = {
+        id,
+        name: key,
+        type: "env_var",
+        location: location(read.node),
+        readers: [],
+        metadata: { variable: unresolved ? null : key, reads: [], ...(unresolved ? { unresolved: true } : {}) },
+      };
+      variables.set(id, variable);
+    }
+    (variable.metadata!.reads as EnvRead[]).push(at);
+    if (reader && !variable.readers!.some((r) => r.name === reader.name && r.line === reader.location.start.line)) {
+      variable.readers!.push({
+        name: reader.name,
+        line: reader.location.start.line,
+        column: reader.location.start.column,
+      });
+    }
+  }
+  return [...variables.values()];
+}
+
+/** `read_by` edges from env vars to their readers, for languages whose analyzers supply the file's edges */
+export function envReaderRelationships(variables: ParsedEntity[]): EntityRelationship[] {
+  const relationships: EntityRelationship[] = [];
+  for (const variable of variables) {
+    if (!variable.id) continue;
+    for (const reader of variable.readers ?? []) {
+      relationships.push({ from: variable.id, to: reader.name, type: "read_by", metadata: { line: reader.line } });
+    }
+  }
+  return relationships;
+}
@@ -7,11 +7,12 @@
  * - Structs and their fields
  * - Interfaces and their methods
  * - Type aliases and constants
- * - Variables (module-level)
+ * - Variables (module-level), with the calls and accesses of their initializers
+ * - init functions, numbered in file order
  * - Goroutines and channels (special Go features)
  *
  * Relationships:
- * - Package imports
+ * - Package imports (blank imports marked as side-effect dependencies)
  * - Function/method calls
  * - Interface satisfaction (Go's implicit implementation)
  * - Struct embedding (composition)
@@ -22,7 +23,11 @@
  * pattern from C++ analyzer with Go-specific adaptations.
  */
 
-import type { EntityRelationship, ParsedEntity, TreeSitterNode } from "../types/parser.js";
+import type { AccessKind, EntityRelationship, ParsedEntity, TreeSitterNode } from "../types/parser.js";
+import { callArguments } from "./call-arguments.js";
+import { cyclomaticComplexity } from "./complexity.js";
+import { type GoBuildTarget, goFileConstraints, resolveGoBuildTarget } from "./go-build-constraints.js";
+import { goImplementations } from "./go-method-sets.js";
 
 // Circuit breaker constants
 const MAX_RECURSION_DEPTH = 50;
@@ -36,15 +41,84 @@ class CircuitBreakerError extends Error {
   }
 }
 
+const GO_PREDECLARED_TYPES = new Set(
+  (
+    "any bool byte comparable complex64 complex128 error float32 float64 int int8 int16 int32 int64 rune string " +
+    "uint uint8 uint16 uint32 uint64 uintptr"
+  ).split(" "),
+);
+
+// Predeclared functions and conversions to predeclared types are not calls into user code
+const GO_BUILTIN_CALLS = new Set([
+  ..."append cap clear close complex copy delete imag len make max min new panic print println real recover".split(" "),
+  ...GO_PREDECLARED_TYPES,
+]);
+
+/** Type parameter of a generic function or type, e.g. `K comparable` */
+interface GoTypeParameter {
+  name: string;
+  constraint?: string;
+}
+
+// Predeclared identifiers that are values, never package-level variables of user code
+const GO_PREDECLARED_VALUES = new Set(["true", "false", "nil", "iota"]);
+
+/** Whether two handles denote the same syntax node; tree-sitter hands out a new object per access */
+function sameNode(a: TreeSitterNode | null | undefined, b: TreeSitterNode): boolean {
+  return !!a && a.startIndex === b.startIndex && a.endIndex === b.endIndex && a.type === b.type;
+}
+
+/** Variables with a statically known type inside one function body, used to resolve `v.Method()` */
+type GoTypeScope = Map<string, string>;
+
+/** Channel type of a declaration: element type, direction and, for `make(chan T, n)`, its buffer */
+interface GoChannelType {
+  elementType: string;
+  direction: "bidirectional" | "send" | "receive";
+  buffered?: boolean;
+  capacity?: string;
+}
+
+const CHANNEL_KEYWORD: Record<GoChannelType["direction"], string> = {
+  bidirectional: "chan",
+  send: "chan<-",
+  receive: "<-chan",
+};
+
+/** Anonymous field embedded into a struct or interface */
+interface GoEmbeddedType {
+  name: string;
+  pointer: boolean;
+}
+
 export class GoAnalyzer {
   private recursionDepth = 0;
   private parseStartTime = 0;
   private currentPackage = "";
+  /** Types and type parameters declared in the file, to tell `Map[int](xs)` from `handlers[i](x)` */
+  private typeNames = new Set<string>();
+  /** Entity id kind (`var`, `const`) of the file's package-level variables and constants */
+  private packageValues = new Map<string, "var" | "const">();
+  private functionNames = new Set<string>();
+  /** `init` functions seen so far in the file; a package may declare any number of them */
+  private initFunctions = 0;
+  /** `var _ = register()` declarations, blank like imports and run for their initializer alone */
+  private blankVariables = 0;
+
+  constructor(private readonly buildTarget: GoBuildTarget = resolveGoBuildTarget()) {}
 
   private isExported(name?: string): boolean {
     return !!name && /^[A-Z]/.test(name);
   }
 
+  /**
+   * net/http handler shape: `(w http.ResponseWriter, r *http.Request)`. Handlers are registered by
+   * value (`mux.HandleFunc("/", h)`), so they rarely show up as call targets.
+   */
+  private isHttpHandler(params: string[]): boolean {
+    return params.some((p) => p.endsWith("http.ResponseWriter")) && params.some((p) => p.endsWith("*http.Request"));
+  }
+
   private collectIdentifiersFromNameField(nameField: TreeSitterNode | null): string[] {
     if (!nameField) return [];
     if (nameField.type === "identifier") return [nameField.text];
@@ -84,9 +158,37 @@ export class GoAnalyzer {
     const entities: ParsedEntity[] = [];
     const relationships: EntityRelationship[] = [];
 
+    // A file the target platform does not compile would only add duplicate declarations
+    const build = goFileConstraints(filePath, this.headerComments(rootNode), this.buildTarget);
+    if (this.buildTarget.mode === "target" && !build.matchesTarget) {
+      const { goos, goarch } = this.buildTarget;
+      console.error(`[GoAnalyzer] Skipping ${filePath}: excluded by build constraints for ${goos}/${goarch}`);
+      return { entities, relationships };
+    }
+
     try {
+      for (const spec of this.findDescendantsByType(rootNode, "type_spec")) {
+        const name = spec.childForFieldName("name")?.text;
+        if (name) this.typeNames.add(name);
+      }
+      for (const decl of this.findDescendantsByType(rootNode, "type_parameter_declaration")) {
+        for (const id of decl.namedChildren) if (id.type === "identifier") this.typeNames.add(id.text);
+      }
+      for (const decl of rootNode.namedChildren) {
+        if (decl.type === "function_declaration") {
+          const name = decl.childForFieldName("name")?.text;
+          if (name) this.functionNames.add(name);
+        }
+        const kind = decl.type === "var_declaration" ? "var" : decl.type === "const_declaration" ? "const" : null;
+        for (const spec of kind ? this.findDescendantsByType(decl, `${kind}_spec`) : []) {
+          for (const id of spec.namedChildren) if (id.type === "identifier") this.packageValues.set(id.text, kind!);
+        }
+      }
+
       // Extract entities and relationships from AST
       this.extractEntities(rootNode, filePath, entities, relationships);
+      // Method sets are only complete once every declaration has been seen
+      this.extractInterfaceImplementations(entities, relationships);
     } catch (error) {
       if (error instanceof CircuitBreakerError) {
         console.warn(`[GoAnalyzer] Circuit breaker triggered for ${filePath}: ${error.message}`);
@@ -96,9 +198,24 @@ export class GoAnalyzer {
       // Return partial results on error
     }
 
+    if (build.expression !== null || build.test) {
+      const goBuild = { constraint: build.expression, test: build.test, matchesTarget: build.matchesTarget };
+      for (const entity of entities) entity.metadata = { ...entity.metadata, goBuild };
+    }
+
     return { entities, relationships };
   }
 
+  /** Comments above the package clause, where build constraint lines live */
+  private headerComments(rootNode: TreeSitterNode): string[] {
+    const comments: string[] = [];
+    for (const child of rootNode.children) {
+      if (child.type === "package_clause") break;
+      if (child.type === "comment") comments.push(...child.text.split(/\r?\n/).map((line) => line.trim()));
+    }
+    return comments;
+  }
+
   /**
    * Reset analyzer state for new file
    */
@@ -106,6 +223,11 @@ export class GoAnalyzer {
     this.recursionDepth = 0;
     this.parseStartTime = Date.now();
     this.currentPackage = "";
+    this.typeNames = new Set();
+    this.packageValues = new Map();
+    this.functionNames = new Set();
+    this.initFunctions = 0;
+    this.blankVariables = 0;
   }
 
   /**
@@ -195,7 +317,7 @@ export class GoAnalyzer {
 
         case "var_declaration":
           // Extract variables
-          this.extractVariable(node, filePath, entities);
+          this.extractVariable(node, filePath, entities, relationships);
           break;
 
         default:
@@ -299,6 +421,9 @@ export class GoAnalyzer {
           metadata: {
             importType: "package",
             alias: aliasNode?.text,
+            // `import _ "pkg"` is there for what initializing the package does
+            ...(aliasNode?.text === "_" ? { sideEffect: true } : {}),
+            line: spec.startPosition.row + 1,
           },
         });
       }
@@ -311,7 +436,7 @@ export class GoAnalyzer {
           from: packageId,
           to: importPath,
           type: "imports",
-          metadata: { importType: "package" },
+          metadata: { importType: "package", line: node.startPosition.row + 1 },
         });
       }
     }
@@ -330,24 +455,35 @@ export class GoAnalyzer {
     const functionName = nameNode?.text;
 
     if (functionName) {
-      const entityId = `${filePath}:function:${functionName}`;
+      // Every `init` runs once, in file order, after the package's variables are initialized
+      const initOrder = functionName === "init" ? ++this.initFunctions : 0;
+      const entityId = `${filePath}:function:${functionName}${initOrder > 1 ? `#${initOrder}` : ""}`;
       const entity: ParsedEntity = {
         id: entityId,
         name: functionName,
         type: "function",
         filePath,
         location: this.getNodeLocation(node),
+        signature: this.declarationSignature(node),
         metadata: {
           isPublic: this.isExported(functionName), // Capital = exported in Go
           package: this.currentPackage,
+          ...(initOrder > 0 ? { goInit: true, initOrder } : {}),
         },
       };
 
+      const typeParameters = this.extractTypeParameters(node);
+      if (typeParameters.length > 0) {
+        const meta = entity.metadata ?? (entity.metadata = {});
+        meta.typeParameters = typeParameters;
+      }
+
       // Extract parameters
       const parameters = node.childForFieldName("parameters");
       if (parameters) {
         const meta = entity.metadata ?? (entity.metadata = {});
         meta.parameters = this.extractParameters(parameters);
+        if (this.isHttpHandler(meta.parameters as string[])) meta.isHttpHandler = true;
       }
 
       // Extract return type
@@ -362,8 +498,17 @@ export class GoAnalyzer {
       // Extract function calls within body
       const body = node.childForFieldName("body");
       if (body) {
-        this.extractFunctionCalls(body, entityId, filePath, relationships);
+        entity.cyclomaticComplexity = cyclomaticComplexity(body, "go");
+        const scope = this.buildTypeScope(parameters, body);
+        this.extractFunctionCalls(body, entityId, filePath, relationships, scope);
+        const locals = this.localNames([parameters, result, body]);
+        this.extractAccesses(body, entityId, filePath, scope, locals, relationships);
+        const owner = { id: entityId, name: functionName };
+        this.extractConcurrency(parameters, body, owner, filePath, scope, locals, entities, relationships);
       }
+
+      const ownTypeParameters = new Set(typeParameters.map((param) => param.name));
+      this.extractTypeReferences([parameters, result, body], entityId, filePath, ownTypeParameters, relationships);
     }
   }
 
@@ -383,6 +528,7 @@ export class GoAnalyzer {
     if (methodName && receiver) {
       // Extract receiver type
       const receiverType = this.extractReceiverType(receiver);
+      const pointerReceiver = this.isPointerReceiver(receiver);
 
       const methodId = `${filePath}:method:${receiverType}:${methodName}`;
       const entity: ParsedEntity = {
@@ -391,18 +537,29 @@ export class GoAnalyzer {
         type: "method",
         filePath,
         location: this.getNodeLocation(node),
+        signature: this.declarationSignature(node),
         metadata: {
           isPublic: this.isExported(methodName),
           receiver: receiverType,
+          pointerReceiver,
           package: this.currentPackage,
+          qualifiedName: receiverType ? `${receiverType}.${methodName}` : methodName,
         },
       };
 
+      // Methods cannot declare type parameters; they reuse the receiver's (`func (l *List[T]) ...`)
+      const receiverTypeParameters = this.receiverTypeParameters(receiver);
+      if (receiverTypeParameters.length > 0) {
+        const meta = entity.metadata ?? (entity.metadata = {});
+        meta.receiverTypeParameters = receiverTypeParameters;
+      }
+
       // Extract parameters
       const parameters = node.childForFieldName("parameters");
       if (parameters) {
         const meta = entity.metadata ?? (entity.metadata = {});
         meta.parameters = this.extractParameters(parameters);
+        if (this.isHttpHandler(meta.parameters as string[])) meta.isHttpHandler = true;
       }
 
       // Extract return type
@@ -414,7 +571,8 @@ export class GoAnalyzer {
 
       entities.push(entity);
 
-      // Create relationship to receiver type
+      // Create relationship to receiver type; one declared in another file of the package is left at
+      // a placeholder named after it for the package resolver
       if (receiverType) {
         relationships.push({
           from: methodId,
@@ -422,6 +580,7 @@ export class GoAnalyzer {
           type: "contains",
           metadata: {
             memberType: "method",
+            referencedName: receiverType,
           },
         });
       }
@@ -429,8 +588,22 @@ export class GoAnalyzer {
       // Extract function calls within body
       const body = node.childForFieldName("body");
       if (body) {
-        this.extractFunctionCalls(body, methodId, filePath, relationships);
+        entity.cyclomaticComplexity = cyclomaticComplexity(body, "go");
+        const scope = this.buildTypeScope(parameters, body);
+        for (const param of receiver.namedChildren) {
+          for (const name of this.collectIdentifiersFromNameField(param.childForFieldName("name"))) {
+            if (receiverType) scope.set(name, receiverType);
+          }
+        }
+        this.extractFunctionCalls(body, methodId, filePath, relationships, scope);
+        const locals = this.localNames([receiver, parameters, result, body]);
+        this.extractAccesses(body, methodId, filePath, scope, locals, relationships);
+        const owner = { id: methodId, name: receiverType ? `${receiverType}.${methodName}` : methodName };
+        this.extractConcurrency(parameters, body, owner, filePath, scope, locals, entities, relationships);
       }
+
+      const ownTypeParameters = new Set(receiverTypeParameters);
+      this.extractTypeReferences([parameters, result, body], methodId, filePath, ownTypeParameters, relationships);
     }
   }
 
@@ -443,17 +616,59 @@ export class GoAnalyzer {
     if (paramDecl) {
       const typeNode = paramDecl.childForFieldName("type");
       if (typeNode) {
-        // Handle pointer receivers (*Type)
+        // Handle pointer receivers (*Type); generic receivers (List[T]) name the List declaration
         if (typeNode.type === "pointer_type") {
           const baseType = typeNode.namedChild(0);
-          return baseType?.text || "";
+          return this.stripTypeArguments(baseType?.text || "");
         }
-        return typeNode.text || "";
+        return this.stripTypeArguments(typeNode.text || "");
       }
     }
     return "";
   }
 
+  /** Type parameter names a method's receiver binds: `(l *List[T])` → `["T"]` */
+  private receiverTypeParameters(receiver: TreeSitterNode): string[] {
+    const text = receiver.namedChild(0)?.childForFieldName("type")?.text ?? "";
+    const open = text.indexOf("[");
+    if (open < 0) return [];
+    return text
+      .slice(open + 1, text.lastIndexOf("]"))
+      .split(",")
+      .map((name) => name.trim())
+      .filter((name) => name.length > 0);
+  }
+
+  /** Type parameter list of a generic function or type declaration */
+  private extractTypeParameters(node: TreeSitterNode): GoTypeParameter[] {
+    const list = node.childForFieldName("type_parameters");
+    if (!list) return [];
+    const params: GoTypeParameter[] = [];
+    for (const decl of list.namedChildren) {
+      if (decl.type !== "type_parameter_declaration") continue;
+      const constraint = decl.childForFieldName("type")?.text;
+      for (const name of decl.namedChildren) {
+        if (name.type === "identifier") params.push({ name: name.text, ...(constraint ? { constraint } : {}) });
+      }
+    }
+    return params;
+  }
+
+  /** Declaration text up to the body, on one line: `func Map[T, U any](xs []T, f func(T) U) []U` */
+  private declarationSignature(node: TreeSitterNode): string {
+    const body = node.childForFieldName("body");
+    const text = body ? node.text.slice(0, body.startIndex - node.startIndex) : node.text;
+    return text.replace(/\s+/g, " ").trim();
+  }
+
+  /**
+   * Whether the receiver is declared as a pointer (`func (s *T) ...`)
+   */
+  private isPointerReceiver(receiver: TreeSitterNode): boolean {
+    const typeNode = receiver.namedChild(0)?.childForFieldName("type");
+    return typeNode?.type === "pointer_type";
+  }
+
   /**
    * Extract type declarations (structs, interfaces, type aliases)
    */
@@ -487,6 +702,16 @@ export class GoAnalyzer {
           },
         };
 
+        const typeParameters = this.extractTypeParameters(typeSpec);
+        const typeParamsText = typeSpec.childForFieldName("type_parameters")?.text ?? "";
+        const kind =
+          typeNode.type === "struct_type" ? "struct" : typeNode.type === "interface_type" ? "interface" : typeNode.text;
+        entity.signature = `type ${typeName}${typeParamsText} ${kind}`.replace(/\s+/g, " ");
+        if (typeParameters.length > 0) {
+          const meta = entity.metadata ?? (entity.metadata = {});
+          meta.typeParameters = typeParameters;
+        }
+
         entities.push(entity);
 
         // Extract struct fields
@@ -497,11 +722,20 @@ export class GoAnalyzer {
         // Extract interface methods
         if (typeNode.type === "interface_type") {
           this.extractInterfaceMethods(typeNode, typeId, filePath, entities, relationships);
+          const embedded = this.extractEmbeddedInterfaces(typeNode, typeId, filePath, relationships);
+          if (embedded.length > 0) {
+            const meta = entity.metadata ?? (entity.metadata = {});
+            meta.embeddedTypes = embedded;
+          }
         }
 
         // Handle type embedding
         if (typeNode.type === "struct_type") {
-          this.extractEmbeddedTypes(typeNode, typeId, filePath, relationships);
+          const embedded = this.extractEmbeddedTypes(typeNode, typeId, filePath, relationships);
+          if (embedded.length > 0) {
+            const meta = entity.metadata ?? (entity.metadata = {});
+            meta.embeddedTypes = embedded;
+          }
         }
       }
     }
@@ -555,6 +789,8 @@ export class GoAnalyzer {
               parent: structId,
             },
           };
+          const channel = this.channelType(typeNode, null);
+          if (channel) fieldEntity.metadata!.channel = channel;
 
           entities.push(fieldEntity);
 
@@ -582,7 +818,8 @@ export class GoAnalyzer {
     entities: ParsedEntity[],
     relationships: EntityRelationship[],
   ): void {
-    const methodSpecs = interfaceNode.namedChildren.filter((c) => c.type === "method_spec");
+    // Older grammars call interface members `method_spec`, newer ones `method_elem`
+    const methodSpecs = interfaceNode.namedChildren.filter((c) => c.type === "method_spec" || c.type === "method_elem");
 
     for (const methodSpec of methodSpecs) {
       const nameNode = methodSpec.childForFieldName("name");
@@ -599,6 +836,7 @@ export class GoAnalyzer {
           metadata: {
             isAbstract: true, // Interface methods are abstract
             parent: interfaceId,
+            package: this.currentPackage,
           },
         };
 
@@ -633,13 +871,17 @@ export class GoAnalyzer {
 
   /**
    * Extract embedded types (struct embedding/composition)
+   *
+   * Anonymous fields such as `User`, `*User` or `io.Reader` embed the type into the
+   * struct. Unqualified names are resolved to the type declared in the same package.
    */
   private extractEmbeddedTypes(
     structNode: TreeSitterNode,
     structId: string,
     filePath: string,
     relationships: EntityRelationship[],
-  ): void {
+  ): GoEmbeddedType[] {
+    const embedded: GoEmbeddedType[] = [];
     const fieldList = structNode.namedChildren.filter((c) => c.type === "field_declaration_list");
 
     for (const list of fieldList) {
@@ -651,21 +893,115 @@ export class GoAnalyzer {
         const typeNode = field.childForFieldName("type");
 
         if (!nameNode && typeNode) {
-          // This is an embedded type
-          const embeddedType = typeNode.text;
+          // `*T` is either a pointer_type node or a bare `*` token before the type, depending on grammar version
+          const isPointer = typeNode.type === "pointer_type" || field.text.trim().startsWith("*");
+          const baseNode = typeNode.type === "pointer_type" ? typeNode.namedChild(0) : typeNode;
+          const embeddedType = this.stripTypeArguments(baseNode?.text ?? "");
           if (embeddedType) {
+            const isQualified = embeddedType.includes(".");
+            const target = isQualified ? embeddedType : `${filePath}:type:${embeddedType}`;
+            embedded.push({ name: embeddedType, pointer: isPointer });
+
             relationships.push({
               from: structId,
-              to: `${filePath}:type:${embeddedType}`,
-              type: "contains",
+              to: target,
+              type: "embeds",
               metadata: {
+                line: field.startPosition.row + 1,
                 embeddingType: "struct",
+                embeddedType,
+                referencedName: embeddedType,
+                isPointer,
+                package: isQualified ? embeddedType.split(".")[0] : this.currentPackage,
               },
             });
           }
         }
       }
     }
+
+    return embedded;
+  }
+
+  /**
+   * Extract interfaces embedded in an interface (`type ReadWriter interface { Reader; Writer }`)
+   */
+  private extractEmbeddedInterfaces(
+    interfaceNode: TreeSitterNode,
+    interfaceId: string,
+    filePath: string,
+    relationships: EntityRelationship[],
+  ): GoEmbeddedType[] {
+    const embedded: GoEmbeddedType[] = [];
+
+    for (const child of interfaceNode.namedChildren) {
+      let nameNode: TreeSitterNode | null = null;
+      if (child.type === "type_elem" || child.type === "constraint_elem") {
+        // Union constraints (`~int | ~string`) are not embeddings
+        if (child.namedChildCount === 1) nameNode = child.namedChild(0);
+      } else if (
+        child.type === "interface_type_name" ||
+        child.type === "type_identifier" ||
+        child.type === "qualified_type"
+      ) {
+        nameNode = child;
+      }
+      const isTypeName =
+        nameNode?.type === "type_identifier" ||
+        nameNode?.type === "qualified_type" ||
+        nameNode?.type === "interface_type_name";
+      if (!nameNode || !isTypeName) continue;
+
+      const embeddedType = nameNode.text;
+      const isQualified = embeddedType.includes(".");
+      embedded.push({ name: embeddedType, pointer: false });
+      relationships.push({
+        from: interfaceId,
+        to: isQualified ? embeddedType : `${filePath}:type:${embeddedType}`,
+        type: "embeds",
+        metadata: {
+          line: child.startPosition.row + 1,
+          embeddingType: "interface",
+          embeddedType,
+          referencedName: embeddedType,
+          isPointer: false,
+          package: isQualified ? embeddedType.split(".")[0] : this.currentPackage,
+        },
+      });
+    }
+
+    return embedded;
+  }
+
+  /**
+   * Drop generic arguments so `List[T]` resolves to the `List` declaration
+   */
+  private stripTypeArguments(typeName: string): string {
+    const idx = typeName.indexOf("[");
+    return (idx >= 0 ? typeName.slice(0, idx) : typeName).trim();
+  }
+
+  /**
+   * Emit `implements` edges for Go's implicit interface satisfaction among the declarations of
+   * this file. The package resolver repeats the pass over every file of the package.
+   */
+  private extractInterfaceImplementations(entities: ParsedEntity[], relationships: EntityRelationship[]): void {
+    const found = goImplementations(entities, (iface) => iface.id ?? "");
+    for (const { struct, iface, pointerReceiver, methodCount } of found) {
+      if (!struct.id || !iface.id) continue;
+      relationships.push({
+        from: struct.id,
+        to: iface.id,
+        type: "implements",
+        metadata: {
+          line: struct.location.start.line,
+          implicit: true,
+          satisfiedBy: pointerReceiver ? `*${struct.name}` : struct.name,
+          pointerReceiver,
+          methodCount,
+        },
+      });
+    }
   }
 
   /**
@@ -698,9 +1034,16 @@ export class GoAnalyzer {
   }
 
   /**
-   * Extract variables
+   * Extract package-level variables. An initializer runs before any `init` function, so the calls
+   * and accesses in it are the variable's own edges; they also give the order Go initializes the
+   * package's variables in.
    */
-  private extractVariable(node: TreeSitterNode, filePath: string, entities: ParsedEntity[]): void {
+  private extractVariable(
+    node: TreeSitterNode,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+  ): void {
     const varSpecs = this.findDescendantsByType(node, "var_spec");
 
     for (const varSpec of varSpecs) {
@@ -733,9 +1076,13 @@ export class GoAnalyzer {
       if (names.length) {
         const typeNode = varSpec.childForFieldName("type");
         const valueNode = varSpec.childForFieldName("value");
-        for (const varName of names) {
+        const values = valueNode?.type === "expression_list" ? valueNode.namedChildren : valueNode ? [valueNode] : [];
+        names.forEach((varName, i) => {
+          const channel = this.channelType(typeNode, values[i] ?? null);
+          const blank = varName === "_" ? ++this.blankVariables : 0;
+          const entityId = `${filePath}:var:${varName}${blank > 1 ? `#${blank}` : ""}`;
           entities.push({
-            id: `${filePath}:var:${varName}`,
+            id: entityId,
             name: varName,
             type: "variable",
             filePath,
@@ -745,13 +1092,443 @@ export class GoAnalyzer {
               variableType: typeNode?.text,
               initialValue: valueNode?.text,
               package: this.currentPackage,
+              ...(channel ? { channel } : {}),
+            },
+          });
+
+          // `var a, b = f()` initializes both from the one call
+          const initializer = values[i] ?? (values.length === 1 ? values[0] : undefined);
+          if (initializer) {
+            const scope = this.buildTypeScope(null, initializer);
+            this.extractFunctionCalls(initializer, entityId, filePath, relationships, scope);
+            const locals = this.localNames([initializer]);
+            this.extractAccesses(initializer, entityId, filePath, scope, locals, relationships);
+          }
+        });
+      }
+    }
+  }
+
+  /**
+   * Types a function's signature and body name, one `references` edge per type. `pkg.Type` keeps
+   * its qualified name for the package resolver; a bare name points at the file's own declaration
+   * and, when the type is declared in another file of the package, falls back to the bare name.
+   * Predeclared types and the function's type parameters are not references.
+   */
+  private extractTypeReferences(
+    nodes: Array<TreeSitterNode | null>,
+    fromId: string,
+    filePath: string,
+    typeParameters: Set<string>,
+    relationships: EntityRelationship[],
+  ): void {
+    const seen = new Set<string>();
+    const stack = nodes.filter((node): node is TreeSitterNode => node !== null);
+    while (stack.length > 0) {
+      const node = stack.pop()!;
+      let name: string | undefined;
+      if (node.type === "qualified_type") {
+        name = node.text.replace(/\s+/g, "");
+      } else if (node.type === "type_identifier") {
+        if (!GO_PREDECLARED_TYPES.has(node.text) && !typeParameters.has(node.text)) name = node.text;
+      } else {
+        for (const child of node.namedChildren) stack.push(child);
+        continue;
+      }
+      if (!name || seen.has(name)) continue;
+      seen.add(name);
+
+      const qualified = name.includes(".");
+      relationships.push({
+        from: fromId,
+        to: qualified ? name : `${filePath}:type:${name}`,
+        type: "references",
+        metadata: {
+          referenceType: "type",
+          referencedName: name,
+          line: node.startPosition.row + 1,
+          column: node.startPosition.column,
+        },
+      });
+    }
+  }
+
+  /**
+   * Names a function declares: receiver, parameters, results and locals, those of its closures
+   * included. Not flow sensitive, like the type scope.
+   */
+  private localNames(nodes: Array<TreeSitterNode | null>): Set<string> {
+    const names = new Set<string>();
+    const stack = nodes.filter((node): node is TreeSitterNode => node !== null);
+    while (stack.length > 0) {
+      const node = stack.pop()!;
+      switch (node.type) {
+        case "parameter_declaration":
+        case "variadic_parameter_declaration":
+        case "var_spec":
+        case "const_spec":
+          for (const id of node.namedChildren) if (id.type === "identifier") names.add(id.text);
+          break;
+        case "short_var_declaration":
+        case "range_clause":
+          for (const id of node.childForFieldName("left")?.namedChildren ?? []) {
+            if (id.type === "identifier") names.add(id.text);
+          }
+          break;
+        case "type_switch_statement":
+          for (const id of node.childForFieldName("alias")?.namedChildren ?? []) {
+            if (id.type === "identifier") names.add(id.text);
+          }
+          break;
+      }
+      for (const child of node.namedChildren) stack.push(child);
+    }
+    return names;
+  }
+
+  /**
+   * How an expression is used: written when it is assigned to, directly or through an index or a
+   * field of it (`s.users[id] = u` writes `s.users`), or cleared by `delete`; read otherwise.
+   */
+  private accessKind(node: TreeSitterNode): AccessKind {
+    let current = node;
+    let parent = current.parent;
+    while (
+      parent &&
+      (parent.type === "parenthesized_expression" ||
+        ((parent.type === "index_expression" ||
+          parent.type === "selector_expression" ||
+          parent.type === "slice_expression") &&
+          sameNode(parent.childForFieldName("operand"), current)))
+    ) {
+      current = parent;
+      parent = current.parent;
+    }
+    if (!parent) return "read";
+    if (parent.type === "inc_statement" || parent.type === "dec_statement") return "readwrite";
+    if (parent.type === "expression_list" && parent.parent?.type === "assignment_statement") {
+      const statement = parent.parent;
+      if (!sameNode(statement.childForFieldName("left"), parent)) return "read";
+      return statement.childForFieldName("operator")?.text === "=" ? "write" : "readwrite";
+    }
+    if (parent.type === "argument_list" && sameNode(parent.namedChildren[0], current)) {
+      const callee = parent.parent?.childForFieldName("function")?.text;
+      if (callee === "delete" || callee === "clear") return "write";
+    }
+    return "read";
+  }
+
+  /**
+   * Struct fields and package-level variables and constants a body reads or writes, one `accesses`
+   * edge per site. A field is known through the static type of its operand (`s.users` with
+   * `s *Server`) or the composite literal it initializes; `pkg.Var` keeps its qualified name for the
+   * package resolver. A bare name that is neither local nor a function or type is taken for a
+   * package-level value, of this file or, left at its written name, of another file of the package.
+   */
+  private extractAccesses(
+    body: TreeSitterNode,
+    fromId: string,
+    filePath: string,
+    scope: GoTypeScope,
+    locals: Set<string>,
+    relationships: EntityRelationship[],
+  ): void {
+    const emit = (node: TreeSitterNode, to: string, name: string, access: AccessKind, receiverType?: string) => {
+      relationships.push({
+        from: fromId,
+        to,
+        type: "accesses",
+        metadata: {
+          access,
+          referencedName: name,
+          ...(receiverType ? { receiverType } : {}),
+          line: node.startPosition.row + 1,
+          column: node.startPosition.column,
+        },
+      });
+    };
+    const emitField = (node: TreeSitterNode, type: string, field: string, access: AccessKind) => {
+      const to = type.includes(".") ? `${type}.${field}` : `${filePath}:type:${type}:field:${field}`;
+      emit(node, to, `${type}.${field}`, access, type);
+    };
+    // Called names are calls, not accesses
+    const isCallee = (node: TreeSitterNode) =>
+      node.parent?.type === "call_expression" && sameNode(node.parent.childForFieldName("function"), node);
+
+    const stack: TreeSitterNode[] = [body];
+    while (stack.length > 0) {
+      const node = stack.pop()!;
+      switch (node.type) {
+        case "selector_expression": {
+          const operand = node.childForFieldName("operand");
+          const field = node.childForFieldName("field");
+          if (operand?.type !== "identifier") {
+            if (operand) stack.push(operand);
+            continue;
+          }
+          const type = scope.get(operand.text);
+          if (locals.has(operand.text) || this.packageValues.has(operand.text)) {
+            if (type && field && !isCallee(node)) emitField(node, type, field.text, this.accessKind(node));
+            else stack.push(operand);
+          } else if (field && !isCallee(node)) {
+            emit(node, node.text, node.text, this.accessKind(node));
+          }
+          continue;
+        }
+        case "composite_literal": {
+          const type = this.namedType(node.childForFieldName("type"));
+          for (const element of type ? (node.childForFieldName("body")?.namedChildren ?? []) : []) {
+            const key = this.literalKey(element);
+            if (key) emitField(key, type!, key.text, "write");
+          }
+          break;
+        }
+        case "keyed_element":
+          // Struct literal keys are field names, handled with their literal; the values are expressions
+          for (const child of node.namedChildren.slice(this.literalKey(node) ? 1 : 0)) stack.push(child);
+          continue;
+        case "identifier": {
+          const name = node.text;
+          if (
+            name === "_" ||
+            locals.has(name) ||
+            isCallee(node) ||
+            GO_PREDECLARED_VALUES.has(name) ||
+            GO_BUILTIN_CALLS.has(name) ||
+            this.functionNames.has(name) ||
+            this.typeNames.has(name)
+          ) {
+            continue;
+          }
+          emit(node, `${filePath}:${this.packageValues.get(name) ?? "var"}:${name}`, name, this.accessKind(node));
+          continue;
+        }
+      }
+      for (const child of node.namedChildren) stack.push(child);
+    }
+  }
+
+  /**
+   * Channel type of a declaration, from its type (`chan T`, `<-chan T`, `chan<- T`) or its
+   * initializer (`make(chan T, n)`); undefined when the declaration is not a channel.
+   */
+  private channelType(typeNode: TreeSitterNode | null, value: TreeSitterNode | null): GoChannelType | undefined {
+    let type = typeNode?.type === "channel_type" ? typeNode : null;
+    let capacity: TreeSitterNode | undefined;
+    if (!type && value?.type === "call_expression" && value.childForFieldName("function")?.text === "make") {
+      const args = value.childForFieldName("arguments")?.namedChildren ?? [];
+      if (args[0]?.type === "channel_type") {
+        type = args[0];
+        capacity = args[1];
+      }
+    }
+    if (!type) return undefined;
+    const text = type.text.replace(/\s+/g, "");
+    const direction = text.startsWith("<-") ? "receive" : text.startsWith("chan<-") ? "send" : "bidirectional";
+    const channel: GoChannelType = {
+      elementType: type.childForFieldName("value")?.text ?? text.replace(/^(<-)?chan(<-)?/, ""),
+      direction,
+    };
+    if (value) {
+      channel.buffered = capacity !== undefined && capacity.text !== "0";
+      if (capacity) channel.capacity = capacity.text;
+    }
+    return channel;
+  }
+
+  /**
+   * Channels a function declares as parameters or locals, one `channel` entity each, keyed by
+   * name. Like the type scope this is not flow sensitive: a name shadowed in an inner block keeps
+   * the first channel declared under it.
+   */
+  private declareChannels(
+    nodes: Array<TreeSitterNode | null>,
+    owner: { id: string; name: string },
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+  ): Map<string, string> {
+    const channels = new Map<string, string>();
+    const declare = (name: string, at: TreeSitterNode, scope: string, channel: GoChannelType | undefined) => {
+      if (!channel || name === "_" || channels.has(name)) return;
+      const id = `${owner.id}:chan:${name}`;
+      channels.set(name, id);
+      entities.push({
+        id,
+        name,
+        type: "channel",
+        filePath,
+        location: this.getNodeLocation(at),
+        signature: `${name} ${CHANNEL_KEYWORD[channel.direction]} ${channel.elementType}`,
+        metadata: { ...channel, scope, owner: owner.id, package: this.currentPackage },
+      });
+      relationships.push({ from: id, to: owner.id, type: "contains", metadata: { memberType: "channel" } });
+    };
+
+    const stack = nodes.filter((node): node is TreeSitterNode => node !== null);
+    while (stack.length > 0) {
+      const node = stack.pop()!;
+      if (node.type === "parameter_declaration" || node.type === "var_spec") {
+        const values = node.childForFieldName("value")?.namedChildren ?? [];
+        const names = node.namedChildren.filter((child) => child.type === "identifier");
+        names.forEach((name, i) => {
+          const scope = node.type === "parameter_declaration" ? "parameter" : "local";
+          declare(name.text, node, scope, this.channelType(node.childForFieldName("type"), values[i] ?? null));
+        });
+      } else if (node.type === "short_var_declaration") {
+        const right = node.childForFieldName("right")?.namedChildren ?? [];
+        (node.childForFieldName("left")?.namedChildren ?? []).forEach((name, i) => {
+          if (name.type === "identifier") declare(name.text, node, "local", this.channelType(null, right[i] ?? null));
+        });
+      }
+      for (const child of node.namedChildren) stack.push(child);
+    }
+    return channels;
+  }
+
+  /**
+   * Goroutines and channel operations of a function. `go f(x)` is a `spawns` edge to `f`; `go
+   * func() { ... }()` spawns an anonymous goroutine entity (`Owner.func1`, as the runtime names
+   * it) that owns the sends and receives in its body. Sends (`ch <- v`) and receives (`<-ch`,
+   * `range ch`) point at the function's own channel, a struct field through the operand's static
+   * type, or a package-level variable, left at its written name when declared in another file.
+   */
+  private extractConcurrency(
+    parameters: TreeSitterNode | null,
+    body: TreeSitterNode,
+    owner: { id: string; name: string },
+    filePath: string,
+    scope: GoTypeScope,
+    locals: Set<string>,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+  ): void {
+    const channels = this.declareChannels([parameters, body], owner, filePath, entities, relationships);
+    const site = (node: TreeSitterNode) => ({ line: node.startPosition.row + 1, column: node.startPosition.column });
+
+    type ChannelTarget = { to: string; name: string; receiverType?: string };
+    const channelOf = (expr: TreeSitterNode | null): ChannelTarget | undefined => {
+      while (expr?.type === "parenthesized_expression") expr = expr.namedChild(0);
+      if (expr?.type === "identifier") {
+        const local = channels.get(expr.text);
+        if (local) return { to: local, name: expr.text };
+        if (locals.has(expr.text)) return undefined;
+        return { to: `${filePath}:${this.packageValues.get(expr.text) ?? "var"}:${expr.text}`, name: expr.text };
+      }
+      if (expr?.type === "selector_expression") {
+        const operand = expr.childForFieldName("operand");
+        const field = expr.childForFieldName("field");
+        if (operand?.type !== "identifier" || !field) return undefined;
+        const type = scope.get(operand.text);
+        if (type && !type.includes(".")) {
+          const to = `${filePath}:type:${type}:field:${field.text}`;
+          return { to, name: `${type}.${field.text}`, receiverType: type };
+        }
+        if (!locals.has(operand.text) && !this.packageValues.has(operand.text)) {
+          return { to: expr.text, name: expr.text };
+        }
+      }
+      return undefined;
+    };
+
+    // Sends write the channel and receives read it, so access filters apply to both
+    type Operation = "sends" | "receives";
+    const operation = (actor: string, type: Operation, target: ChannelTarget | undefined, node: TreeSitterNode) => {
+      if (!target) return;
+      relationships.push({
+        from: actor,
+        to: target.to,
+        type,
+        metadata: {
+          access: type === "sends" ? "write" : "read",
+          referencedName: target.name,
+          ...(target.receiverType ? { receiverType: target.receiverType } : {}),
+          ...site(node),
+        },
+      });
+    };
+
+    let goroutines = 0;
+    const stack: Array<{ node: TreeSitterNode; actor: string }> = [{ node: body, actor: owner.id }];
+    while (stack.length > 0) {
+      const { node, actor } = stack.pop()!;
+      const children = [...node.namedChildren];
+
+      if (node.type === "go_statement") {
+        const call = node.namedChildren.find((child) => child.type === "call_expression");
+        const fn = call?.childForFieldName("function");
+        if (fn?.type === "func_literal") {
+          goroutines += 1;
+          const name = `${owner.name}.func${goroutines}`;
+          const id = `${filePath}:goroutine:${name}`;
+          entities.push({
+            id,
+            name,
+            type: "function",
+            filePath,
+            location: this.getNodeLocation(fn),
+            signature: `go ${fn.text.split("{")[0]!.trim()}`,
+            metadata: { goroutine: true, anonymous: true, spawnedBy: owner.id, package: this.currentPackage },
+          });
+          relationships.push({ from: actor, to: id, type: "spawns", metadata: { anonymous: true, ...site(node) } });
+          // The arguments are evaluated by the spawning function, the body runs in the goroutine
+          const args = call?.childForFieldName("arguments");
+          const literalBody = fn.childForFieldName("body");
+          if (literalBody) stack.push({ node: literalBody, actor: id });
+          if (args) stack.push({ node: args, actor });
+          continue;
+        }
+        // Named like the calls `extractFunctionCalls` records, so the package resolver binds both alike
+        const operand = fn?.childForFieldName("operand");
+        const field = fn?.childForFieldName("field");
+        if (fn?.type === "identifier" || (fn?.type === "selector_expression" && operand && field)) {
+          const receiverType = operand?.type === "identifier" ? scope.get(operand.text) : undefined;
+          relationships.push({
+            from: actor,
+            to:
+              receiverType && field
+                ? `${filePath}:method:${receiverType}:${field.text}`
+                : `${filePath}:function:${fn.text}`,
+            type: "spawns",
+            metadata: {
+              callee: fn.text,
+              calleeName: field?.text ?? fn.text,
+              ...(receiverType ? { receiverType } : {}),
+              ...site(node),
             },
           });
         }
+      } else if (node.type === "send_statement") {
+        operation(actor, "sends", channelOf(node.childForFieldName("channel")), node);
+      } else if (node.type === "unary_expression" && node.childForFieldName("operator")?.text === "<-") {
+        operation(actor, "receives", channelOf(node.childForFieldName("operand")), node);
+      } else if (node.type === "range_clause") {
+        // Ranging over a value is a receive only when it is known to be a channel
+        const right = node.childForFieldName("right");
+        if (right?.type === "identifier" && channels.has(right.text)) {
+          operation(actor, "receives", channelOf(right), node);
+        }
       }
+
+      for (let i = children.length - 1; i >= 0; i--) stack.push({ node: children[i]!, actor });
     }
   }
 
+  /** Field name keying an element of a struct literal (`users:` in `Server{users: m}`) */
+  private literalKey(element: TreeSitterNode): TreeSitterNode | null {
+    if (element.type !== "keyed_element") return null;
+    const first = element.namedChildren[0];
+    const key = first?.type === "literal_element" ? first.namedChildren[0] : first;
+    return key && (key.type === "field_identifier" || key.type === "identifier") ? key : null;
+  }
+
+  /** Whether an index expression's index names a type, making the expression an instantiation */
+  private isTypeName(node: TreeSitterNode): boolean {
+    if (node.type === "identifier") return GO_PREDECLARED_TYPES.has(node.text) || this.typeNames.has(node.text);
+    // Composite types (`[]int`, `*T`, `List[int]`) cannot be an ordinary index
+    return /_type$/.test(node.type);
+  }
+
   /**
    * Extract function parameters
    */
@@ -775,29 +1552,141 @@ export class GoAnalyzer {
   }
 
   /**
-   * Extract function calls to create relationships
+   * Named type of a type expression with pointers stripped (`*Server` → `Server`, `List[T]` → `List`)
+   */
+  private namedType(typeNode: TreeSitterNode | null): string | undefined {
+    if (!typeNode) return undefined;
+    switch (typeNode.type) {
+      case "pointer_type":
+        return this.namedType(typeNode.namedChild(0));
+      case "generic_type":
+        return this.namedType(typeNode.childForFieldName("type"));
+      case "type_identifier":
+      case "qualified_type":
+        return typeNode.text;
+      default:
+        return undefined;
+    }
+  }
+
+  /**
+   * Type of an initializer when it is evident from the expression (`T{}`, `&T{}`, `new(T)`)
+   */
+  private typeOfExpression(expr: TreeSitterNode | null): string | undefined {
+    if (!expr) return undefined;
+    if (expr.type === "composite_literal") return this.namedType(expr.childForFieldName("type"));
+    if (expr.type === "unary_expression" && expr.childForFieldName("operator")?.text === "&") {
+      return this.typeOfExpression(expr.childForFieldName("operand"));
+    }
+    if (expr.type === "call_expression" && expr.childForFieldName("function")?.text === "new") {
+      const args = expr.childForFieldName("arguments");
+      return this.namedType(args?.namedChild(0) ?? null);
+    }
+    return undefined;
+  }
+
+  /**
+   * Collect parameter and local variable types for a function body. Not flow sensitive: a name
+   * keeps the first type it is declared with, which is right for the vast majority of Go code.
+   */
+  private buildTypeScope(parameters: TreeSitterNode | null, body: TreeSitterNode): GoTypeScope {
+    const scope: GoTypeScope = new Map();
+    const declare = (name: string, type: string | undefined) => {
+      if (type && name !== "_" && !scope.has(name)) scope.set(name, type);
+    };
+
+    for (const param of parameters?.namedChildren ?? []) {
+      const type = this.namedType(param.childForFieldName("type"));
+      for (const name of this.collectIdentifiersFromNameField(param.childForFieldName("name"))) declare(name, type);
+    }
+
+    const stack: TreeSitterNode[] = [body];
+    while (stack.length > 0) {
+      const current = stack.pop()!;
+      if (current.type === "var_spec") {
+        const names = this.collectIdentifiersFromNameField(current.childForFieldName("name"));
+        const declared = this.namedType(current.childForFieldName("type"));
+        const values = current.childForFieldName("value")?.namedChildren ?? [];
+        names.forEach((name, i) => declare(name, declared ?? this.typeOfExpression(values[i] ?? null)));
+      } else if (current.type === "short_var_declaration") {
+        const left = current.childForFieldName("left")?.namedChildren ?? [];
+        const right = current.childForFieldName("right")?.namedChildren ?? [];
+        left.forEach((name, i) => {
+          if (name.type === "identifier") declare(name.text, this.typeOfExpression(right[i] ?? null));
+        });
+      }
+      for (const child of current.namedChildren) stack.push(child);
+    }
+
+    return scope;
+  }
+
+  /**
+   * Extract function calls to create relationships. Method calls on a variable whose type is known
+   * are pointed at that type's method; other qualified calls (`fmt.Println`, `obj.Do` on an
+   * unknown type) keep their textual callee so the indexer can record them as unresolved.
    */
   private extractFunctionCalls(
     node: TreeSitterNode,
     callerId: string,
     filePath: string,
     relationships: EntityRelationship[],
+    scope: GoTypeScope,
   ): void {
     this.recursionDepth++;
     this.checkCircuitBreakers();
 
     try {
       if (node.type === "call_expression") {
-        const functionNode = node.childForFieldName("function");
-        if (functionNode) {
-          const functionName = functionNode.text;
-          if (functionName) {
+        let functionNode = node.childForFieldName("function");
+        const site = {
+          line: node.startPosition.row + 1,
+          column: node.startPosition.column,
+          ...callArguments(node.childForFieldName("arguments")),
+        };
+
+        // An instantiation (`Map[int, string](xs)`) calls the generic declaration itself. A single
+        // type argument parses as an index expression, so it needs a type name as the index.
+        let typeArguments = node.childForFieldName("type_arguments")?.text;
+        const index = functionNode?.type === "index_expression" ? functionNode.childForFieldName("index") : null;
+        if (index && this.isTypeName(index)) {
+          typeArguments = `[${index.text}]`;
+          functionNode = functionNode?.childForFieldName("operand") ?? null;
+        }
+        const instantiation = typeArguments ? { typeArguments } : {};
+
+        if (functionNode?.type === "identifier" && !GO_BUILTIN_CALLS.has(functionNode.text)) {
+          relationships.push({
+            from: callerId,
+            to: `${filePath}:function:${functionNode.text}`,
+            type: "calls",
+            metadata: {
+              callType: "function",
+              callee: functionNode.text,
+              calleeName: functionNode.text,
+              ...instantiation,
+              ...site,
+            },
+          });
+        } else if (functionNode?.type === "selector_expression") {
+          const operand = functionNode.childForFieldName("operand");
+          const field = functionNode.childForFieldName("field");
+          const receiverType = operand?.type === "identifier" ? scope.get(operand.text) : undefined;
+          if (operand && field) {
             relationships.push({
               from: callerId,
-              to: `${filePath}:function:${functionName}`,
+              to: receiverType
+                ? `${filePath}:method:${receiverType}:${field.text}`
+                : `${filePath}:function:${functionNode.text}`,
               type: "calls",
               metadata: {
-                callType: "function",
+                callType: receiverType ? "method" : "qualified",
+                callee: functionNode.text,
+                calleeName: field.text,
+                qualifier: operand.text,
+                ...(receiverType ? { receiverType } : {}),
+                ...instantiation,
+                ...site,
               },
             });
           }
@@ -808,7 +1697,7 @@ export class GoAnalyzer {
       for (let i = 0; i < node.childCount; i++) {
         const child = node.child(i);
         if (child) {
-          this.extractFunctionCalls(child, callerId, filePath, relationships);
+          this.extractFunctionCalls(child, callerId, filePath, relationships, scope);
         }
       }
     } finally {
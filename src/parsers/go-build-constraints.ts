@@ -0,0 +1,218 @@
+/**
+ * Go build constraints: `_GOOS`/`_GOARCH` file name suffixes and `//go:build` (or legacy
+ * `// +build`) lines, evaluated the way `go build` does for one target platform.
+ */
+
+import type { ParserConfig } from "../config/yaml-config.js";
+
+/** `target` drops files the target platform would not compile; `all` keeps them, tagged */
+export type GoBuildMode = "target" | "all";
+
+export interface GoBuildTarget {
+  mode: GoBuildMode;
+  goos: string;
+  goarch: string;
+  /** Extra satisfied tags, e.g. `cgo`, `integration` */
+  tags: string[];
+  includeTests: boolean;
+}
+
+/** Constraints found on one file, with whether the configured target builds it */
+export interface GoFileConstraints {
+  /** Combined constraint as a `//go:build` expression; null when the file always builds */
+  expression: string | null;
+  test: boolean;
+  matchesTarget: boolean;
+}
+
+type BuildExpr =
+  | { op: "tag"; tag: string }
+  | { op: "not"; x: BuildExpr }
+  | { op: "and" | "or"; a: BuildExpr; b: BuildExpr };
+
+const KNOWN_OS = new Set(
+  (
+    "aix android darwin dragonfly freebsd hurd illumos ios js linux nacl netbsd openbsd plan9 solaris wasip1 " +
+    "windows zos"
+  ).split(" "),
+);
+
+const UNIX_OS = new Set(
+  "aix android darwin dragonfly freebsd hurd illumos ios linux netbsd openbsd solaris".split(" "),
+);
+
+const KNOWN_ARCH = new Set(
+  (
+    "386 amd64 amd64p32 arm armbe arm64 arm64be loong64 mips mipsle mips64 mips64le mips64p32 mips64p32le " +
+    "ppc ppc64 ppc64le riscv riscv64 s390 s390x sparc sparc64 wasm"
+  ).split(" "),
+);
+
+// GOOS values that also satisfy the tag of the platform they extend
+const IMPLIED_OS: Record<string, string> = { android: "linux", illumos: "solaris", ios: "darwin" };
+
+const NODE_PLATFORM_TO_GOOS: Record<string, string> = { win32: "windows", sunos: "solaris", cygwin: "windows" };
+const NODE_ARCH_TO_GOARCH: Record<string, string> = { x64: "amd64", ia32: "386", mipsel: "mipsle" };
+
+/** Target from parser config, falling back to GOOS/GOARCH and then to the host platform */
+export function resolveGoBuildTarget(config?: ParserConfig["go"]): GoBuildTarget {
+  const tags = config?.tags ?? [];
+  return {
+    mode: config?.buildMode === "all" ? "all" : "target",
+    goos: config?.goos || NODE_PLATFORM_TO_GOOS[process.platform] || process.platform,
+    goarch: config?.goarch || NODE_ARCH_TO_GOARCH[process.arch] || process.arch,
+    // cgo is on unless switched off, as with the go command on a native build
+    tags: config?.cgo === false ? tags : [...tags, "cgo"],
+    includeTests: config?.includeTests ?? true,
+  };
+}
+
+function tagSatisfied(tag: string, target: GoBuildTarget): boolean {
+  if (tag === target.goos || tag === target.goarch || tag === "gc") return true;
+  if (tag === "unix") return UNIX_OS.has(target.goos);
+  if (IMPLIED_OS[target.goos] === tag) return true;
+  // Release tags: every go1.N up to the toolchain in use, which is assumed to be recent
+  if (/^go1\.\d+$/.test(tag)) return true;
+  return target.tags.includes(tag);
+}
+
+function evaluate(expr: BuildExpr, target: GoBuildTarget): boolean {
+  switch (expr.op) {
+    case "tag":
+      return tagSatisfied(expr.tag, target);
+    case "not":
+      return !evaluate(expr.x, target);
+    case "and":
+      return evaluate(expr.a, target) && evaluate(expr.b, target);
+    case "or":
+      return evaluate(expr.a, target) || evaluate(expr.b, target);
+  }
+}
+
+function format(expr: BuildExpr, parent?: BuildExpr["op"]): string {
+  switch (expr.op) {
+    case "tag":
+      return expr.tag;
+    case "not":
+      return `!${format(expr.x, "not")}`;
+    default: {
+      const text = `${format(expr.a, expr.op)} ${expr.op === "and" ? "&&" : "||"} ${format(expr.b, expr.op)}`;
+      return parent && parent !== expr.op ? `(${text})` : text;
+    }
+  }
+}
+
+/** Parse a `//go:build` expression; null when it is malformed (the go command rejects such files) */
+export function parseGoBuildExpression(text: string): BuildExpr | null {
+  const tokens = text.match(/&&|\|\||[!()]|[\w.]+|\S/g) ?? [];
+  let pos = 0;
+
+  const parseOr = (): BuildExpr | null => {
+    let left = parseAnd();
+    while (left && tokens[pos] === "||") {
+      pos++;
+      const right = parseAnd();
+      left = right ? { op: "or", a: left, b: right } : null;
+    }
+    return left;
+  };
+  const parseAnd = (): BuildExpr | null => {
+    let left = parseNot();
+    while (left && tokens[pos] === "&&") {
+      pos++;
+      const right = parseNot();
+      left = right ? { op: "and", a: left, b: right } : null;
+    }
+    return left;
+  };
+  const parseNot = (): BuildExpr | null => {
+    const token = tokens[pos++];
+    if (token === "!") {
+      const x = parseNot();
+      return x ? { op: "not", x } : null;
+    }
+    if (token === "(") {
+      const inner = parseOr();
+      return inner && tokens[pos++] === ")" ? inner : null;
+    }
+    return token && /^[\w.]+$/.test(token) ? { op: "tag", tag: token } : null;
+  };
+
+  const expr = parseOr();
+  return expr && pos === tokens.length ? expr : null;
+}
+
+/** Legacy `// +build a,b c` line: spaces separate alternatives, commas join requirements */
+function parsePlusBuildLine(text: string): BuildExpr | null {
+  let result: BuildExpr | null = null;
+  for (const option of text.trim().split(/\s+/)) {
+    let all: BuildExpr | null = null;
+    for (const term of option.split(",")) {
+      const tag = term.replace(/^!/, "");
+      if (!/^[\w.]+$/.test(tag)) return null;
+      const x: BuildExpr = term.startsWith("!") ? { op: "not", x: { op: "tag", tag } } : { op: "tag", tag };
+      all = all ? { op: "and", a: all, b: x } : x;
+    }
+    if (!all) return null;
+    result = result ? { op: "or", a: result, b: all } : all;
+  }
+  return result;
+}
+
+/** Constraint implied by a `_GOOS`, `_GOARCH` or `_GOOS_GOARCH` file name suffix */
+function fileNameConstraint(filePath: string): { expr: BuildExpr | null; test: boolean } {
+  const base = filePath.replace(/\\/g, "/").split("/").pop() ?? "";
+  let stem = base.replace(/\.go$/, "");
+  const test = stem.endsWith("_test");
+  if (test) stem = stem.slice(0, -"_test".length);
+
+  // Only the part after the first underscore counts, so `linux.go` carries no constraint
+  const underscore = stem.indexOf("_");
+  if (underscore < 0) return { expr: null, test };
+  const parts = stem.slice(underscore).split("_");
+  const last = parts[parts.length - 1] ?? "";
+  const prev = parts[parts.length - 2] ?? "";
+
+  if (parts.length >= 3 && KNOWN_OS.has(prev) && KNOWN_ARCH.has(last)) {
+    return { expr: { op: "and", a: { op: "tag", tag: prev }, b: { op: "tag", tag: last } }, test };
+  }
+  if (KNOWN_OS.has(last) || KNOWN_ARCH.has(last)) return { expr: { op: "tag", tag: last }, test };
+  return { expr: null, test };
+}
+
+/**
+ * Constraints of a Go file given its path and the comments before its package clause. A
+ * malformed `//go:build` line excludes the file from every target, as it fails to build.
+ */
+export function goFileConstraints(
+  filePath: string,
+  headerComments: string[],
+  target: GoBuildTarget,
+): GoFileConstraints {
+  const fromName = fileNameConstraint(filePath);
+
+  let fromLines: BuildExpr | null = null;
+  let malformed = false;
+  const goBuild = headerComments.find((c) => /^\/\/go:build\s/.test(c));
+  if (goBuild) {
+    fromLines = parseGoBuildExpression(goBuild.replace(/^\/\/go:build\s+/, ""));
+    malformed = fromLines === null;
+  } else {
+    // Without a //go:build line every `// +build` line must hold
+    for (const line of headerComments.filter((c) => /^\/\/\s*\+build\s/.test(c))) {
+      const expr = parsePlusBuildLine(line.replace(/^\/\/\s*\+build\s+/, ""));
+      if (!expr) {
+        malformed = true;
+        continue;
+      }
+      fromLines = fromLines ? { op: "and", a: fromLines, b: expr } : expr;
+    }
+  }
+
+  const parts = [fromName.expr, fromLines].filter((x): x is BuildExpr => x !== null);
+  const combined = parts.reduce<BuildExpr | null>((acc, x) => (acc ? { op: "and", a: acc, b: x } : x), null);
+  const matchesTarget =
+    !malformed && (combined === null || evaluate(combined, target)) && (target.includeTests || !fromName.test);
+
+  return { expression: combined ? format(combined) : null, test: fromName.test, matchesTarget };
+}
@@ -0,0 +1,137 @@
+/**
+ * Go method sets and implicit interface satisfaction over the types and methods of one package.
+ * The analyzer runs this over the declarations of the file it parses; the package resolver runs it
+ * again over every file of the package once the project is indexed.
+ */
+
+/** What the pass reads of a type or method; both parsed and stored entities carry it */
+export interface GoMethodSetEntity {
+  id?: string;
+  name: string;
+  type: string;
+  metadata?: Record<string, any>;
+}
+
+/** A struct whose method set covers every method an interface of its package requires */
+export interface GoImplementation<T extends GoMethodSetEntity> {
+  struct: T;
+  iface: T;
+  /** Only `*T` satisfies the interface, because some method has a pointer receiver */
+  pointerReceiver: boolean;
+  /** Methods the interface requires, embedded interfaces included */
+  methodCount: number;
+}
+
+type MethodSet = Map<string, { signature: string; pointer: boolean }>;
+
+/**
+ * Comparable method signature: parameter types (names and spacing dropped) and result
+ */
+export function goMethodSignature(method: GoMethodSetEntity): string {
+  const params: unknown[] = Array.isArray(method.metadata?.parameters) ? method.metadata.parameters : [];
+  const types = params.map((p) => {
+    const text = String(p);
+    const idx = text.indexOf(": ");
+    return (idx >= 0 ? text.slice(idx + 2) : text).replace(/\s+/g, "");
+  });
+  const result = String(method.metadata?.returnType ?? "").replace(/\s+/g, "");
+  return `(${types.join(",")})${result}`;
+}
+
+/**
+ * Every struct and interface of one package where the struct implements the interface: its method
+ * set covers every method of the interface, matched by name, parameter types and result.
+ *
+ * Value receivers belong to both `T` and `*T`; pointer receivers only to `*T`, so each pair records
+ * which form satisfies the interface. Methods promoted through embedded fields and interfaces
+ * embedded in interfaces are taken into account; an interface embedding a type the package does not
+ * declare is skipped. Interface methods name their interface through `metadata.parent`, matched
+ * against `interfaceKey`, and methods name their receiver type through `metadata.receiver`.
+ */
+export function goImplementations<T extends GoMethodSetEntity>(
+  entities: T[],
+  interfaceKey: (iface: T) => string,
+): GoImplementation<T>[] {
+  const types = new Map<string, T>();
+  const declared = new Map<string, MethodSet>();
+  const addMethod = (key: string, method: T) => {
+    const set = declared.get(key) ?? new Map();
+    set.set(method.name, { signature: goMethodSignature(method), pointer: method.metadata?.pointerReceiver === true });
+    declared.set(key, set);
+  };
+
+  for (const entity of entities) {
+    const data = entity.metadata ?? {};
+    if ((entity.type === "class" || entity.type === "interface") && !data.parent) {
+      types.set(entity.name, entity);
+    } else if (entity.type === "method") {
+      if (data.isAbstract && typeof data.parent === "string") addMethod(`interface:${data.parent}`, entity);
+      else if (typeof data.receiver === "string") addMethod(`receiver:${data.receiver}`, entity);
+    }
+  }
+
+  const resolved = new Map<string, MethodSet | null>();
+  // Full method set of a type; null when it depends on a type declared outside the package
+  const methodSetOf = (type: T, visiting: Set<string>): MethodSet | null => {
+    if (resolved.has(type.name)) return resolved.get(type.name) ?? null;
+    if (visiting.has(type.name)) return new Map();
+    visiting.add(type.name);
+
+    const own =
+      type.type === "interface"
+        ? declared.get(`interface:${interfaceKey(type)}`)
+        : declared.get(`receiver:${type.name}`);
+    const set: MethodSet = new Map(own ?? []);
+    let complete = true;
+
+    const embedded: Array<{ name: string; pointer: boolean }> = Array.isArray(type.metadata?.embeddedTypes)
+      ? type.metadata.embeddedTypes
+      : [];
+    for (const emb of embedded) {
+      const target = types.get(emb.name);
+      const promoted = target ? methodSetOf(target, visiting) : null;
+      if (!promoted) {
+        complete = false;
+        continue;
+      }
+      for (const [name, method] of promoted) {
+        // Shallower declarations win; embedding `*T` promotes pointer methods to the value type too
+        if (set.has(name)) continue;
+        set.set(name, { signature: method.signature, pointer: emb.pointer ? false : method.pointer });
+      }
+    }
+
+    visiting.delete(type.name);
+    // An incomplete struct set can still satisfy interfaces; an incomplete interface cannot be checked
+    const result = complete || type.type !== "interface" ? set : null;
+    resolved.set(type.name, result);
+    return result;
+  };
+
+  const structs = [...types.values()].filter((type) => type.type === "class");
+  const interfaces = [...types.values()].filter((type) => type.type === "interface");
+  const found: GoImplementation<T>[] = [];
+
+  for (const struct of structs) {
+    const methods = methodSetOf(struct, new Set());
+    if (!methods || methods.size === 0) continue;
+
+    for (const iface of interfaces) {
+      const required = methodSetOf(iface, new Set());
+      if (!required || required.size === 0) continue;
+
+      let satisfied = true;
+      let pointerReceiver = false;
+      for (const [name, { signature }] of required) {
+        const method = methods.get(name);
+        if (!method || method.signature !== signature) {
+          satisfied = false;
+          break;
+        }
+        pointerReceiver ||= method.pointer;
+      }
+      if (satisfied) found.push({ struct, iface, pointerReceiver, methodCount: required.size });
+    }
+  }
+  return found;
+}
@@ -0,0 +1,139 @@
+/**
+ * Locating and loading tree-sitter grammar packages. Grammars are resolved with Node module
+ * resolution from this package's own location first, so a global or npx install finds the copies
+ * it ships with whatever the working directory is; the entry script and the working directory are
+ * only fallbacks for layouts where the grammars are hoisted elsewhere.
+ */
+
+import { createRequire } from "node:module";
+import { dirname, join } from "node:path";
+import type { SupportedLanguage } from "../types/parser.js";
+
+interface GrammarSpec {
+  module: string;
+  /** Export holding the language when the package ships several (tree-sitter-typescript, tree-sitter-php) */
+  pick?: "typescript" | "tsx" | "php";
+}
+
+export const GRAMMAR_MODULES: Partial<Record<SupportedLanguage, GrammarSpec>> = {
+  javascript: { module: "tree-sitter-javascript" },
+  jsx: { module: "tree-sitter-javascript" },
+  typescript: { module: "tree-sitter-typescript", pick: "typescript" },
+  tsx: { module: "tree-sitter-typescript", pick: "tsx" },
+  python: { module: "tree-sitter-python" },
+  c: { module: "tree-sitter-c" },
+  cpp: { module: "tree-sitter-cpp" },
+  rust: { module: "tree-sitter-rust" },
+  csharp: { module: "tree-sitter-c-sharp" },
+  go: { module: "tree-sitter-go" },
+  java: { module: "tree-sitter-java" },
+  kotlin: { module: "tree-sitter-kotlin" },
+  ruby: { module: "tree-sitter-ruby" },
+  // `php` rather than `php_only`: templates keep their HTML between `<?php` blocks
+  php: { module: "tree-sitter-php", pick: "php" },
+  swift: { module: "tree-sitter-swift" },
+  scala: { module: "tree-sitter-scala" },
+};
+
+export interface GrammarDiagnostic {
+  language: SupportedLanguage;
+  module: string;
+  loaded: boolean;
+  /** Entry file the grammar resolved to; null when no install location had it */
+  path: string | null;
+  /** Package locations tried, in order */
+  checked: string[];
+  error?: string;
+}
+
+export class GrammarLoadError extends Error {
+  constructor(
+    readonly language: SupportedLanguage,
+    readonly module: string,
+    readonly checked: string[],
+    reason: string,
+  ) {
+    super(`Grammar for '${language}' (${module}) ${reason}; checked: ${checked.join(", ") || "(no locations)"}`);
+    this.name = "GrammarLoadError";
+  }
+}
+
+/** Resolution bases in priority order: this module, the entry script, the working directory */
+function resolutionBases(): string[] {
+  const bases = [import.meta.url];
+  if (process.argv[1]) bases.push(join(dirname(process.argv[1]), "noop.js"));
+  bases.push(join(process.cwd(), "noop.js"));
+  return bases;
+}
+
+type Require = ReturnType<typeof createRequire>;
+
+function resolveGrammar(module: string): { path: string | null; requireFrom: Require | null; checked: string[] } {
+  const checked: string[] = [];
+  for (const base of resolutionBases()) {
+    const req = createRequire(base);
+    for (const dir of req.resolve.paths(module) ?? []) {
+      const candidate = join(dir, module);
+      if (!checked.includes(candidate)) checked.push(candidate);
+    }
+    try {
+      return { path: req.resolve(module), requireFrom: req, checked };
+    } catch {
+      // Not installed relative to this base; try the next one
+    }
+  }
+  return { path: null, requireFrom: null, checked };
+}
+
+const loaded = new Map<SupportedLanguage, { language: any; path: string }>();
+
+/** Load the grammar for `language`, naming the language and every location checked on failure */
+export function loadGrammar(language: SupportedLanguage): any {
+  const hit = loaded.get(language);
+  if (hit) return hit.language;
+
+  const spec = GRAMMAR_MODULES[language];
+  if (!spec) throw new Error(`Unsupported language: ${language}`);
+
+  const { path, requireFrom, checked } = resolveGrammar(spec.module);
+  if (!path || !requireFrom) throw new GrammarLoadError(language, spec.module, checked, "is not installed");
+
+  let m: any;
+  try {
+    m = requireFrom(path);
+  } catch (e: any) {
+    // Usually a native binding built for another Node ABI
+    throw new GrammarLoadError(language, spec.module, [path], `failed to load: ${e?.message || e}`);
+  }
+  const lang = spec.pick ? (m[spec.pick] ?? m.default?.[spec.pick]) : (m.default ?? m);
+  if (!lang) throw new GrammarLoadError(language, spec.module, [path], "exports no language");
+
+  loaded.set(language, { language: lang, path });
+  return lang;
+}
+
+/** Where the grammar for `language` was loaded from; null until it has been loaded */
+export function grammarPath(language: SupportedLanguage): string | null {
+  return loaded.get(language)?.path ?? null;
+}
+
+/** Try every grammar and report which loaded and where each was found */
+export function diagnoseGrammars(): GrammarDiagnostic[] {
+  return (Object.entries(GRAMMAR_MODULES) as Array<[SupportedLanguage, GrammarSpec]>).map(([language, spec]) => {
+    try {
+      loadGrammar(language);
+      const path = loaded.get(language)?.path ?? null;
+      return { language, module: spec.module, loaded: true, path, checked: path ? [path] : [] };
+    } catch (e: any) {
+      const resolved = resolveGrammar(spec.module);
+      return {
+        language,
+        module: spec.module,
+        loaded: false,
+        path: resolved.path,
+        checked: resolved.checked,
+        error: e?.message || String(e),
+      };
+    }
+  });
+}
@@ -0,0 +1,730 @@
+/**
+ * GraphQL Analyzer - `.graphql`/`.gql` schemas (SDL) as part of the code graph
+ * Read by a small hand-written parser, like `.proto` files: the type system language is a handful
+ * of definitions with a regular shape. Each file becomes a `module` entity holding its object
+ * types (`class`), interfaces (`interface`), input types (`struct`), enums and their values
+ * (`enum`, `enum_variant`), unions (`union`), custom scalars (`typedef`) and fields (`field`).
+ * Fields of the root operation types (Query, Mutation, Subscription, or the ones a `schema` block
+ * in the file names) are `method` entities, one per operation, with their arguments as parameters.
+ *
+ * Edges lead from each field to the type it returns and from arguments to theirs (`references`,
+ * with the role), from object types to the interfaces they implement, from unions to their
+ * members and from `extend type` blocks to the type they extend (`extension_of`). Types declared
+ * in the same file are bound here; the rest are left on placeholders for the GraphQL linker.
+ * Executable documents (operations and fragments) are skipped.
+ */
+
+import { basename } from "node:path";
+import type { EntityRelationship, ParsedEntity } from "../types/parser.js";
+
+export type GraphqlKind =
+  | "type"
+  | "interface"
+  | "input"
+  | "enum"
+  | "enum_value"
+  | "union"
+  | "scalar"
+  | "field"
+  | "input_field"
+  | "operation";
+
+export type GraphqlOperation = "query" | "mutation" | "subscription";
+
+const BUILTIN_SCALARS = new Set(["Int", "Float", "String", "Boolean", "ID"]);
+
+const DEFAULT_ROOTS: Record<GraphqlOperation, string> = {
+  query: "Query",
+  mutation: "Mutation",
+  subscription: "Subscription",
+};
+
+// Words that start a top-level definition; a body-less type ends where one of them begins
+const DEFINITION_KEYWORDS = new Set([
+  "type",
+  "interface",
+  "input",
+  "enum",
+  "union",
+  "scalar",
+  "directive",
+  "schema",
+  "extend",
+  "query",
+  "mutation",
+  "subscription",
+  "fragment",
+]);
+
+type Token = {
+  kind: "name" | "string" | "number" | "punct" | "eof";
+  text: string;
+  line: number;
+  column: number;
+  index: number;
+  end: { line: number; column: number; index: number };
+};
+
+type Comment = { text: string; startLine: number; endLine: number };
+
+type TypeRef = { written: string; name: string };
+
+export function graphqlFileId(filePath: string): string {
+  return `${filePath}:graphql_file`;
+}
+
+/** Common indentation and blank first and last lines removed, as the spec reads block strings */
+function blockStringValue(raw: string): string {
+  const lines = raw.replace(/\\"""/g, '"""').split(/\r?\n/);
+  let indent = Number.POSITIVE_INFINITY;
+  for (const line of lines.slice(1)) {
+    const width = line.length - line.trimStart().length;
+    if (width < line.length) indent = Math.min(indent, width);
+  }
+  const out = lines.map((line, i) => (i > 0 && Number.isFinite(indent) ? line.slice(indent) : line));
+  while (out.length > 0 && out[0]!.trim() === "") out.shift();
+  while (out.length > 0 && out[out.length - 1]!.trim() === "") out.pop();
+  return out.join("\n");
+}
+
+function tokenize(content: string): { tokens: Token[]; comments: Comment[] } {
+  const tokens: Token[] = [];
+  const comments: Comment[] = [];
+  let line = 1;
+  let lineStart = 0;
+  let i = 0;
+
+  const position = (index: number) => ({ line, column: index - lineStart, index });
+  const advance = (to: number) => {
+    for (; i < to; i++) {
+      if (content[i] === "\n") {
+        line++;
+        lineStart = i + 1;
+      }
+    }
+  };
+
+  while (i < content.length) {
+    const ch = content[i]!;
+    // Commas are insignificant, like whitespace
+    if (/\s/.test(ch) || ch === "," || ch === "﻿") {
+      advance(i + 1);
+      continue;
+    }
+    const start = position(i);
+    if (ch === "#") {
+      const close = content.indexOf("\n", i);
+      const end = close === -1 ? content.length : close;
+      comments.push({ text: content.slice(i + 1, end).trim(), startLine: start.line, endLine: start.line });
+      advance(end);
+      continue;
+    }
+
+    let end = i + 1;
+    let kind: Token["kind"] = "punct";
+    let text = ch;
+    if (content.startsWith('"""', i)) {
+      let close = content.indexOf('"""', i + 3);
+      while (close !== -1 && content[close - 1] === "\\") close = content.indexOf('"""', close + 3);
+      end = close === -1 ? content.length : close + 3;
+      kind = "string";
+      text = blockStringValue(content.slice(i + 3, close === -1 ? content.length : close));
+    } else if (ch === '"') {
+      while (end < content.length && content[end] !== '"' && content[end] !== "\n") {
+        end += content[end] === "\\" ? 2 : 1;
+      }
+      end = Math.min(content.length, end + 1);
+      kind = "string";
+      text = content.slice(i + 1, end - 1);
+    } else if (/[A-Za-z_]/.test(ch)) {
+      while (end < content.length && /\w/.test(content[end]!)) end++;
+      kind = "name";
+      text = content.slice(i, end);
+    } else if (/[-0-9]/.test(ch)) {
+      while (end < content.length && /[\w.+-]/.test(content[end]!)) end++;
+      kind = "number";
+      text = content.slice(i, end);
+    } else if (content.startsWith("...", i)) {
+      end = i + 3;
+      text = "...";
+    }
+    advance(end);
+    tokens.push({ kind, text, ...start, end: position(end) });
+  }
+  const eof = position(content.length);
+  tokens.push({ kind: "eof", text: "", ...eof, end: eof });
+  return { tokens, comments };
+}
+
+type Scope = { id: string; name: string; kind: GraphqlKind; operation?: GraphqlOperation };
+
+type PendingReference = {
+  from: string;
+  to: string;
+  type: "references" | "implements" | "extension_of";
+  line: number;
+  metadata?: Record<string, unknown>;
+};
+
+class GraphqlParser {
+  private pos = 0;
+  readonly entities: ParsedEntity[] = [];
+  readonly relationships: EntityRelationship[] = [];
+  private readonly declared = new Map<string, string>();
+  private readonly references: PendingReference[] = [];
+  private readonly commentsByLine = new Map<number, Comment>();
+  private readonly roots: Record<GraphqlOperation, string>;
+  private readonly file: Scope;
+
+  constructor(
+    private readonly tokens: Token[],
+    comments: Comment[],
+    private readonly content: string,
+    private readonly filePath: string,
+  ) {
+    for (const comment of comments) this.commentsByLine.set(comment.endLine, comment);
+    this.roots = { ...DEFAULT_ROOTS, ...this.schemaRoots() };
+    this.file = { id: graphqlFileId(filePath), name: "", kind: "type" };
+  }
+
+  private peek(offset = 0): Token {
+    return this.tokens[Math.min(this.pos + offset, this.tokens.length - 1)]!;
+  }
+
+  private next(): Token {
+    const token = this.peek();
+    if (token.kind !== "eof") this.pos++;
+    return token;
+  }
+
+  private accept(text: string): Token | undefined {
+    return this.peek().kind === "punct" && this.peek().text === text ? this.next() : undefined;
+  }
+
+  private isName(token = this.peek()): boolean {
+    return token.kind === "name";
+  }
+
+  /** Root operation types a `schema { query: ... }` block anywhere in the file renames */
+  private schemaRoots(): Partial<Record<GraphqlOperation, string>> {
+    const roots: Partial<Record<GraphqlOperation, string>> = {};
+    for (let i = 0; i < this.tokens.length; i++) {
+      if (this.tokens[i]!.text !== "schema" || this.tokens[i]!.kind !== "name") continue;
+      let j = i + 1;
+      while (j < this.tokens.length && this.tokens[j]!.text !== "{" && this.tokens[j]!.kind !== "eof") j++;
+      for (j++; j + 2 < this.tokens.length && this.tokens[j]!.text !== "}"; j++) {
+        const op = this.tokens[j]!.text as GraphqlOperation;
+        if (op in DEFAULT_ROOTS && this.tokens[j + 1]!.text === ":" && this.tokens[j + 2]!.kind === "name") {
+          roots[op] = this.tokens[j + 2]!.text;
+          j += 2;
+        }
+      }
+    }
+    return roots;
+  }
+
+  private operationOf(typeName: string): GraphqlOperation | undefined {
+    return (Object.keys(this.roots) as GraphqlOperation[]).find((op) => this.roots[op] === typeName);
+  }
+
+  /** Past a balanced `(...)`, `[...]` or `{...}` group opening at the cursor, or one token */
+  private skipGroup(): Token {
+    const open = this.next();
+    const close = { "(": ")", "[": "]", "{": "}" }[open.text];
+    if (open.kind !== "punct" || !close) return open;
+    let depth = 1;
+    for (;;) {
+      const token = this.next();
+      if (token.kind === "eof") return token;
+      if (token.kind !== "punct") continue;
+      if (token.text === open.text) depth++;
+      else if (token.text === close && --depth === 0) return token;
+    }
+  }
+
+  /** A value (argument or default): a literal, variable, list or input object */
+  private skipValue(): Token {
+    if (this.accept("$")) return this.next();
+    return this.skipGroup();
+  }
+
+  /** Directives at the cursor; `@deprecated(reason: ...)` is reported separately */
+  private directives(): { names: string[]; deprecated?: string | true; last?: Token } {
+    const names: string[] = [];
+    let deprecated: string | true | undefined;
+    let last: Token | undefined;
+    while (this.peek().text === "@" && this.peek().kind === "punct") {
+      this.next();
+      const name = this.next();
+      names.push(name.text);
+      last = name;
+      if (name.text === "deprecated") deprecated = true;
+      if (this.peek().text === "(" && this.peek().kind === "punct") {
+        const reason = this.peek(1).text === "reason" && this.peek(3).kind === "string" ? this.peek(3).text : null;
+        if (name.text === "deprecated" && reason !== null) deprecated = reason;
+        last = this.skipGroup();
+      }
+    }
+    return { names, deprecated, last };
+  }
+
+  private typeRef(): TypeRef | null {
+    const start = this.pos;
+    let depth = 0;
+    while (this.accept("[")) depth++;
+    if (!this.isName()) {
+      this.pos = start;
+      return null;
+    }
+    const name = this.next().text;
+    let written = name;
+    if (this.accept("!")) written += "!";
+    for (; depth > 0; depth--) {
+      this.accept("]");
+      written = `[${written}]`;
+      if (this.accept("!")) written += "!";
+    }
+    return { written, name };
+  }
+
+  /** A description string at the cursor */
+  private description(): string | undefined {
+    return this.peek().kind === "string" ? this.next().text : undefined;
+  }
+
+  /** `#` comments on the lines directly above `line`, for definitions without a description */
+  private commentsAbove(line: number): string | undefined {
+    const block: string[] = [];
+    let at = line - 1;
+    for (let comment = this.commentsByLine.get(at); comment; comment = this.commentsByLine.get(at)) {
+      block.unshift(comment.text);
+      at = comment.startLine - 1;
+    }
+    return block.length > 0 ? block.join("\n") : undefined;
+  }
+
+  private declare(
+    graphqlKind: GraphqlKind,
+    type: ParsedEntity["type"],
+    nameToken: Token,
+    start: Token,
+    qualifiedName: string,
+    parent: Scope,
+    documentation: string | undefined,
+    extra: Partial<ParsedEntity> = {},
+    metadata: Record<string, unknown> = {},
+  ): ParsedEntity {
+    const entity: ParsedEntity = {
+      id: `${this.filePath}:graphql_${graphqlKind}:${qualifiedName}${metadata.extension ? ":extend" : ""}`,
+      name: nameToken.text,
+      type,
+      filePath: this.filePath,
+      location: {
+        start: { line: start.line, column: start.column, index: start.index },
+        end: { ...nameToken.end },
+      },
+      documentation: documentation ?? this.commentsAbove(start.line),
+      ...extra,
+      metadata: {
+        graphqlKind,
+        qualifiedName,
+        ...(parent.name ? { parentType: parent.name } : {}),
+        ...metadata,
+      },
+    };
+    this.entities.push(entity);
+    this.relationships.push({
+      from: parent.id,
+      to: entity.id!,
+      type: "contains",
+      metadata: { line: start.line, confidence: 1, isDirectRelation: true },
+    });
+    return entity;
+  }
+
+  private close(entity: ParsedEntity, last: Token | undefined): void {
+    if (last) entity.location.end = { ...last.end };
+  }
+
+  private reference(from: ParsedEntity, ref: TypeRef, line: number, metadata: Record<string, unknown>): void {
+    if (BUILTIN_SCALARS.has(ref.name)) return;
+    this.references.push({ from: from.id!, to: ref.name, type: "references", line, metadata });
+  }
+
+  parse(): void {
+    const module: ParsedEntity = {
+      id: this.file.id,
+      name: basename(this.filePath),
+      type: "module",
+      filePath: this.filePath,
+      location: { start: { line: 1, column: 0, index: 0 }, end: { line: 1, column: 0, index: 0 } },
+      metadata: { graphqlFile: true },
+    };
+    this.entities.push(module);
+
+    while (this.peek().kind !== "eof") {
+      const before = this.pos;
+      const documentation = this.description();
+      const start = this.peek();
+      const extension = start.text === "extend" && start.kind === "name";
+      if (extension) this.next();
+      const keyword = this.peek();
+
+      switch (keyword.kind === "name" ? keyword.text : "") {
+        case "type":
+        case "interface":
+        case "input":
+          this.objectLike(start, documentation, extension);
+          break;
+        case "enum":
+          this.enumeration(start, documentation, extension);
+          break;
+        case "union":
+          this.union(start, documentation, extension);
+          break;
+        case "scalar":
+          this.scalar(start, documentation, extension);
+          break;
+        default:
+          // schema, directive definitions, operations, fragments and anything not understood
+          this.skipDefinition();
+      }
+      if (this.pos === before) this.next();
+    }
+
+    module.metadata = { ...module.metadata, roots: this.roots };
+    this.bindReferences();
+  }
+
+  /** Past a definition this analyzer does not model: up to its body's closing brace */
+  private skipDefinition(): void {
+    this.next();
+    for (;;) {
+      const token = this.peek();
+      if (token.kind === "eof") return;
+      if (token.kind === "punct" && (token.text === "{" || token.text === "(")) {
+        const last = this.skipGroup();
+        if (last.text === "}") return;
+        continue;
+      }
+      if (token.kind === "name" && DEFINITION_KEYWORDS.has(token.text)) return;
+      if (token.kind === "string" && DEFINITION_KEYWORDS.has(this.peek(1).text)) return;
+      this.next();
+    }
+  }
+
+  private typeHeader(extension: boolean): { keyword: string; nameToken: Token } | null {
+    const keyword = this.next().text;
+    const nameToken = this.peek();
+    if (!this.isName(nameToken)) return null;
+    this.next();
+    return { keyword: extension ? `extend ${keyword}` : keyword, nameToken };
+  }
+
+  private extensionEdge(entity: ParsedEntity, name: string, line: number): void {
+    this.references.push({ from: entity.id!, to: name, type: "extension_of", line });
+  }
+
+  private objectLike(start: Token, documentation: string | undefined, extension: boolean): void {
+    const header = this.typeHeader(extension);
+    if (!header) {
+      this.skipDefinition();
+      return;
+    }
+    const { nameToken } = header;
+    const keyword = header.keyword.replace(/^extend /, "");
+    const graphqlKind: GraphqlKind = keyword === "type" ? "type" : keyword === "interface" ? "interface" : "input";
+    const entityType = graphqlKind === "type" ? "class" : graphqlKind === "interface" ? "interface" : "struct";
+
+    const interfaces: string[] = [];
+    if (this.peek().text === "implements" && this.isName()) {
+      this.next();
+      for (;;) {
+        this.accept("&");
+        const token = this.peek();
+        if (!this.isName(token) || DEFINITION_KEYWORDS.has(token.text)) break;
+        interfaces.push(this.next().text);
+      }
+    }
+    const directives = this.directives();
+    const operation = graphqlKind === "type" ? this.operationOf(nameToken.text) : undefined;
+    const implemented = interfaces.length ? ` implements ${interfaces.join(" & ")}` : "";
+    const signature = `${header.keyword} ${nameToken.text}${implemented}`;
+    const entity = this.declare(
+      graphqlKind,
+      entityType,
+      nameToken,
+      start,
+      nameToken.text,
+      this.file,
+      documentation,
+      { signature },
+      {
+        ...(operation ? { rootOperation: operation } : {}),
+        ...(interfaces.length ? { implements: interfaces } : {}),
+        ...(directives.names.length ? { directives: directives.names } : {}),
+        ...(extension ? { extension: true } : {}),
+      },
+    );
+    if (!extension) this.declared.set(nameToken.text, entity.id!);
+    else this.extensionEdge(entity, nameToken.text, start.line);
+    for (const iface of interfaces) {
+      this.references.push({ from: entity.id!, to: iface, type: "implements", line: start.line });
+    }
+    this.close(entity, directives.last ?? nameToken);
+
+    if (!this.accept("{")) return;
+    const scope: Scope = { id: entity.id!, name: nameToken.text, kind: graphqlKind, operation };
+    for (;;) {
+      const token = this.peek();
+      if (token.kind === "eof") return;
+      if (token.kind === "punct" && token.text === "}") {
+        this.close(entity, this.next());
+        return;
+      }
+      const before = this.pos;
+      this.field(scope);
+      if (this.pos === before) this.next();
+    }
+  }
+
+  /** `"doc" name(arg: Type = default): Type @directive` inside a type, interface or input */
+  private field(scope: Scope): void {
+    const documentation = this.description();
+    const start = this.peek();
+    if (!this.isName(start)) return;
+    this.next();
+
+    const args: Array<{ name: string; type: string; defaultValue?: string; description?: string }> = [];
+    const argRefs: Array<{ ref: TypeRef; name: string; line: number }> = [];
+    if (this.accept("(")) {
+      for (;;) {
+        if (this.peek().kind === "eof" || this.accept(")")) break;
+        const argDoc = this.description();
+        const argName = this.peek();
+        if (!this.isName(argName) || this.peek(1).text !== ":") {
+          this.next();
+          continue;
+        }
+        this.next();
+        this.next();
+        const ref = this.typeRef();
+        if (!ref) continue;
+        let defaultValue: string | undefined;
+        if (this.accept("=")) {
+          const from = this.peek().index;
+          const last = this.skipValue();
+          defaultValue = this.value(from, last);
+        }
+        this.directives();
+        args.push({
+          name: argName.text,
+          type: ref.written,
+          ...(defaultValue !== undefined ? { defaultValue } : {}),
+          ...(argDoc ? { description: argDoc } : {}),
+        });
+        argRefs.push({ ref, name: argName.text, line: argName.line });
+      }
+    }
+    if (!this.accept(":")) return;
+    const ref = this.typeRef();
+    if (!ref) return;
+    let defaultValue: string | undefined;
+    let last: Token = this.tokens[this.pos - 1]!;
+    if (this.accept("=")) {
+      const from = this.peek().index;
+      last = this.skipValue();
+      defaultValue = this.value(from, last);
+    }
+    const directives = this.directives();
+    last = directives.last ?? last;
+
+    const argText = args
+      .map((a) => `${a.name}: ${a.type}${a.defaultValue !== undefined ? ` = ${a.defaultValue}` : ""}`)
+      .join(", ");
+    const signature = [
+      `${start.text}${args.length ? `(${argText})` : ""}: ${ref.written}`,
+      ...(defaultValue !== undefined ? [defaultValue] : []),
+    ].join(" = ");
+    const operation = scope.operation;
+    const graphqlKind: GraphqlKind = operation ? "operation" : scope.kind === "input" ? "input_field" : "field";
+    const entity = this.declare(
+      graphqlKind,
+      operation ? "method" : "field",
+      start,
+      start,
+      `${scope.name}.${start.text}`,
+      scope,
+      documentation,
+      {
+        signature,
+        returnType: ref.written,
+        ...(args.length ? { parameters: args.map((a) => ({ name: a.name, type: a.type })) } : {}),
+      },
+      {
+        fieldType: ref.written,
+        ...(operation ? { operation } : {}),
+        ...(args.length ? { arguments: args } : {}),
+        ...(defaultValue !== undefined ? { defaultValue } : {}),
+        ...(directives.names.length ? { directives: directives.names } : {}),
+        ...(directives.deprecated !== undefined ? { deprecated: directives.deprecated } : {}),
+      },
+    );
+    this.close(entity, last);
+    this.reference(entity, ref, start.line, { role: operation ? "return" : "type" });
+    for (const arg of argRefs) this.reference(entity, arg.ref, arg.line, { role: "argument", argument: arg.name });
+  }
+
+  /** Source text of a value from offset `from` to the end of `last`, on one line */
+  private value(from: number, last: Token): string {
+    return this.content.slice(from, last.end.index).replace(/\s+/g, " ").trim();
+  }
+
+  private enumeration(start: Token, documentation: string | undefined, extension: boolean): void {
+    const header = this.typeHeader(extension);
+    if (!header) {
+      this.skipDefinition();
+      return;
+    }
+    const { nameToken } = header;
+    const directives = this.directives();
+    const entity = this.declare(
+      "enum",
+      "enum",
+      nameToken,
+      start,
+      nameToken.text,
+      this.file,
+      documentation,
+      { signature: `${header.keyword} ${nameToken.text}` },
+      extension ? { extension: true } : {},
+    );
+    if (!extension) this.declared.set(nameToken.text, entity.id!);
+    else this.extensionEdge(entity, nameToken.text, start.line);
+    this.close(entity, directives.last ?? nameToken);
+    if (!this.accept("{")) return;
+
+    const scope: Scope = { id: entity.id!, name: nameToken.text, kind: "enum" };
+    for (;;) {
+      const valueDoc = this.description();
+      const token = this.peek();
+      if (token.kind === "eof") return;
+      if (token.kind === "punct" && token.text === "}") {
+        this.close(entity, this.next());
+        return;
+      }
+      if (!this.isName(token)) {
+        this.next();
+        continue;
+      }
+      this.next();
+      const valueDirectives = this.directives();
+      const member = this.declare(
+        "enum_value",
+        "enum_variant",
+        token,
+        token,
+        `${nameToken.text}.${token.text}`,
+        scope,
+        valueDoc,
+        { signature: token.text },
+        valueDirectives.deprecated !== undefined ? { deprecated: valueDirectives.deprecated } : {},
+      );
+      this.close(member, valueDirectives.last ?? token);
+    }
+  }
+
+  private union(start: Token, documentation: string | undefined, extension: boolean): void {
+    const header = this.typeHeader(extension);
+    if (!header) {
+      this.skipDefinition();
+      return;
+    }
+    const { nameToken } = header;
+    const directives = this.directives();
+    const members: string[] = [];
+    let last: Token = directives.last ?? nameToken;
+    if (this.accept("=")) {
+      this.accept("|");
+      while (this.isName() && !DEFINITION_KEYWORDS.has(this.peek().text)) {
+        last = this.next();
+        members.push(last.text);
+        if (!this.accept("|")) break;
+      }
+    }
+    const entity = this.declare(
+      "union",
+      "union",
+      nameToken,
+      start,
+      nameToken.text,
+      this.file,
+      documentation,
+      { signature: `${header.keyword} ${nameToken.text}${members.length ? ` = ${members.join(" | ")}` : ""}` },
+      { members, ...(extension ? { extension: true } : {}) },
+    );
+    this.close(entity, last);
+    if (!extension) this.declared.set(nameToken.text, entity.id!);
+    else this.extensionEdge(entity, nameToken.text, start.line);
+    for (const member of members) {
+      this.reference(entity, { written: member, name: member }, start.line, { role: "member" });
+    }
+  }
+
+  private scalar(start: Token, documentation: string | undefined, extension: boolean): void {
+    const header = this.typeHeader(extension);
+    if (!header) {
+      this.skipDefinition();
+      return;
+    }
+    const { nameToken } = header;
+    const directives = this.directives();
+    const entity = this.declare(
+      "scalar",
+      "typedef",
+      nameToken,
+      start,
+      nameToken.text,
+      this.file,
+      documentation,
+      { signature: `${header.keyword} ${nameToken.text}` },
+      extension ? { extension: true } : {},
+    );
+    this.close(entity, directives.last ?? nameToken);
+    if (!extension) this.declared.set(nameToken.text, entity.id!);
+    else this.extensionEdge(entity, nameToken.text, start.line);
+  }
+
+  /** Types declared in this file bind to their entity; the rest wait for the GraphQL linker */
+  private bindReferences(): void {
+    for (const ref of this.references) {
+      const local = this.declared.get(ref.to);
+      this.relationships.push({
+        from: ref.from,
+        to: local ?? ref.to,
+        type: ref.type,
+        sourceFile: this.filePath,
+        metadata: {
+          line: ref.line,
+          referencedName: ref.to,
+          ...ref.metadata,
+          ...(local ? { confidence: 1 } : {}),
+        },
+      });
+    }
+  }
+}
+
+export class GraphqlAnalyzer {
+  analyze(content: string, filePath: string): { entities: ParsedEntity[]; relationships: EntityRelationship[] } {
+    const { tokens, comments } = tokenize(content);
+    const parser = new GraphqlParser(tokens, comments, content, filePath);
+    try {
+      parser.parse();
+    } catch (error) {
+      // Keep what was read before the definition it could not follow
+      console.error(`[GraphqlAnalyzer] Error analyzing ${filePath}:`, error);
+    }
+    return { entities: parser.entities, relationships: parser.relationships };
+  }
+}
@@ -16,6 +16,8 @@ import { createHash } from "node:crypto";
 import { promises as fs } from "node:fs";
 import { extname } from "node:path";
 import { LRUCache } from "lru-cache";
+import { sessionMetrics } from "../core/session-metrics.js";
+import { ParseTimeoutError } from "../types/errors.js";
 import type {
   CacheEntry,
   FileChange,
@@ -24,6 +26,8 @@ import type {
   ParserStats,
   SupportedLanguage,
 } from "../types/parser.js";
+import { throwIfCancelled } from "../utils/cancellation.js";
+import { detectLanguageFromPath } from "./language-configs.js";
 import { TreeSitterParser } from "./tree-sitter-parser.js";
 
 // =============================================================================
@@ -31,7 +35,8 @@ import { TreeSitterParser } from "./tree-sitter-parser.js";
 // =============================================================================
 const DEFAULT_CACHE_SIZE = 100 * 1024 * 1024; // 100MB
 const DEFAULT_BATCH_SIZE = 10;
-const DEFAULT_TIMEOUT_MS = 5000;
+// Generous: the timeout is there to stop pathological files (giant generated bundles), not slow ones
+export const DEFAULT_PARSE_TIMEOUT_MS = 60000;
 
 // =============================================================================
 // 3. DATA MODELS AND TYPE DEFINITIONS
@@ -57,13 +62,38 @@ interface BatchResult {
 // Removed: stringToUint8Array - no longer needed with native crypto
 
 /**
- * Create a timeout promise
+ * Content hash stored per file in the graph; incremental indexing compares against it
  */
-function timeout<T>(promise: Promise<T>, ms: number): Promise<T> {
-  return Promise.race([
-    promise,
-    new Promise<T>((_, reject) => setTimeout(() => reject(new Error(`Timeout after ${ms}ms`)), ms)),
-  ]);
+export function hashFileContent(content: string): string {
+  return createHash("sha256").update(content).digest("hex").substring(0, 16);
+}
+
+/**
+ * Reject with a ParseTimeoutError once `ms` pass; 0 leaves the promise unbounded
+ */
+function timeout<T>(promise: Promise<T>, filePath: string, ms: number): Promise<T> {
+  if (ms <= 0) return promise;
+  let timer: NodeJS.Timeout | undefined;
+  const expired = new Promise<T>((_, reject) => {
+    timer = setTimeout(() => reject(new ParseTimeoutError({ filePath, timeoutMs: ms })), ms);
+  });
+  return Promise.race([promise, expired]).finally(() => clearTimeout(timer));
+}
+
+/**
+ * Result for a file abandoned at the parse timeout: no entities, and marked so indexing skips it
+ */
+export function timedOutParseResult(error: ParseTimeoutError, parseTimeMs: number): ParseResult {
+  return {
+    filePath: error.details.filePath,
+    language: detectLanguageFromPath(error.details.filePath),
+    entities: [],
+    contentHash: "",
+    timestamp: Date.now(),
+    parseTimeMs,
+    errors: [{ message: error.message }],
+    timedOut: true,
+  };
 }
 
 // =============================================================================
@@ -115,9 +145,7 @@ export class IncrementalParser {
     await this.parser.initialize();
 
     // Initialize native crypto hash function for fast hashing
-    this.hashFunction = (content: string) => {
-      return createHash("sha256").update(content).digest("hex").substring(0, 16);
-    };
+    this.hashFunction = hashFileContent;
 
     console.log("[IncrementalParser] Initialization complete");
   }
@@ -128,7 +156,7 @@ export class IncrementalParser {
   computeFileHash(content: string): string {
     if (!this.hashFunction) {
       // Direct fallback to crypto hash
-      return createHash("sha256").update(content).digest("hex").substring(0, 16);
+      return hashFileContent(content);
     }
 
     return this.hashFunction(content);
@@ -163,10 +191,12 @@ export class IncrementalParser {
       this.stats.cacheMisses++;
 
       let result: ParseResult;
+      const timeoutMs = options.timeoutMs ?? DEFAULT_PARSE_TIMEOUT_MS;
       try {
         result = await timeout(
-          this.parser.parse(filePath, content, contentHash),
-          options.timeoutMs || DEFAULT_TIMEOUT_MS,
+          this.parser.parse(filePath, content, contentHash, undefined, timeoutMs),
+          filePath,
+          timeoutMs,
         );
       } catch (parseError) {
         console.error(`[IncrementalParser] Parser.parse failed for ${filePath}:`, parseError);
@@ -194,6 +224,7 @@ export class IncrementalParser {
 
       // Update stats
       this.updateStats(result.parseTimeMs);
+      sessionMetrics.recordParse(result.parseTimeMs, Boolean(result.errors?.length));
 
       // Store hash for incremental updates
       this.fileHashes.set(filePath, contentHash);
@@ -201,11 +232,14 @@ export class IncrementalParser {
       return result;
     } catch (error) {
       this.stats.errorCount++;
+      sessionMetrics.recordParse(Date.now() - startTime, true);
       console.error(`[IncrementalParser] Error parsing ${filePath}:`, error);
+      // Not cached: the same content may parse within a longer timeout
+      if (error instanceof ParseTimeoutError) return timedOutParseResult(error, Date.now() - startTime);
 
       const errorResult: ParseResult = {
         filePath,
-        language: "javascript",
+        language: detectLanguageFromPath(filePath),
         entities: [],
         contentHash: "",
         timestamp: Date.now(),
@@ -224,9 +258,9 @@ export class IncrementalParser {
   }
 
   /**
-   * Process files in batches for optimal performance
+   * Process files in batches for optimal performance; an aborted `signal` stops before the next batch
    */
-  async parseBatch(files: string[], options: ParserOptions = {}): Promise<BatchResult> {
+  async parseBatch(files: string[], options: ParserOptions = {}, signal?: AbortSignal): Promise<BatchResult> {
     const batchSize = options.batchSize || DEFAULT_BATCH_SIZE;
     const results: ParseResult[] = [];
     const errors: Array<{ file: string; error: Error }> = [];
@@ -237,6 +271,7 @@ export class IncrementalParser {
 
     // Process in batches
     for (let i = 0; i < files.length; i += batchSize) {
+      throwIfCancelled(signal);
       const batch = files.slice(i, i + batchSize);
 
       // TASK-001: Process batch in parallel for maximum throughput
@@ -10,14 +10,15 @@
  * - Fields and constants
  * - Lambda expressions and method references
  * - Generic type parameters
+ * - Annotations on declarations, kept as decorators
  *
  * Relationships:
  * - Class inheritance (extends)
  * - Interface implementation (implements)
- * - Method calls and field access
+ * - Method calls, bound to methods of the same file where the callee is declared there
  * - Package organization
  * - Generic type usage
- * - Annotation usage
+ * - Annotation usage (decorated_by, for local and imported annotation types)
  * - Inner class relationships
  *
  * Implementation uses circuit breakers for safety and follows the proven
@@ -25,6 +26,7 @@
  */
 
 import type { EntityRelationship, ParsedEntity, TreeSitterNode } from "../types/parser.js";
+import { callArguments } from "./call-arguments.js";
 
 // Circuit breaker constants
 const MAX_RECURSION_DEPTH = 50;
@@ -38,11 +40,33 @@ class CircuitBreakerError extends Error {
   }
 }
 
+type Decorator = NonNullable<ParsedEntity["decorators"]>[number];
+
+/**
+ * A `type` reference named as written (`Inner`, `Outer.Inner`), or a `call` of method `name` from
+ * type `owner` on `receiver` (none, `this`, `super` or a type name), resolved once every type and
+ * method of the file is declared
+ */
+type PendingTarget =
+  | { kind: "type"; relationship: EntityRelationship; name: string }
+  | { kind: "call"; relationship: EntityRelationship; name: string; owner: string; receiver?: string };
+
 export class JavaAnalyzer {
   private recursionDepth = 0;
   private parseStartTime = 0;
   private currentPackage = "";
   private currentClass: string | null = null;
+  /** Parsed id of the type whose body is being walked */
+  private currentTypeId: string | null = null;
+  /** Types declared in this file by dotted in-file name (`Outer.Inner`) and by simple name */
+  private declaredTypes = new Map<string, string>();
+  /** Methods declared in this file by `Owner.method` */
+  private declaredMethods = new Map<string, string>();
+  /** In-file class name -> superclass as written, for calls to inherited methods */
+  private superclassOf = new Map<string, string>();
+  /** Simple name -> fully qualified name, from single-type imports */
+  private importedTypes = new Map<string, string>();
+  private pending: PendingTarget[] = [];
 
   /**
    * Helper: Convert tree-sitter position to ParsedEntity location
@@ -102,6 +126,8 @@ export class JavaAnalyzer {
     try {
       // Extract entities and relationships from AST
       this.extractEntities(rootNode, filePath, entities, relationships);
+      this.bindLocalTargets();
+      this.linkDecorators(entities, relationships);
     } catch (error) {
       if (error instanceof CircuitBreakerError) {
         console.warn(`[JavaAnalyzer] Circuit breaker triggered for ${filePath}: ${error.message}`);
@@ -122,6 +148,103 @@ export class JavaAnalyzer {
     this.parseStartTime = Date.now();
     this.currentPackage = "";
     this.currentClass = null;
+    this.currentTypeId = null;
+    this.declaredTypes.clear();
+    this.declaredMethods.clear();
+    this.superclassOf.clear();
+    this.importedTypes.clear();
+    this.pending = [];
+  }
+
+  /** Name prefixed with the file's package, as written in Java source elsewhere */
+  private qualify(name: string): string {
+    return this.currentPackage ? `${this.currentPackage}.${name}` : name;
+  }
+
+  /** Record a type declared in this file so later edges can target its id */
+  private declareType(fullName: string, id: string): void {
+    this.declaredTypes.set(fullName, id);
+    const simple = fullName.split(".").pop()!;
+    if (!this.declaredTypes.has(simple)) this.declaredTypes.set(simple, id);
+  }
+
+  /** Heritage edge to a type named in source; bound to the local declaration once the file is walked */
+  private pushTypeEdge(relationships: EntityRelationship[], relationship: EntityRelationship): void {
+    relationships.push(relationship);
+    this.pending.push({ kind: "type", relationship, name: relationship.to });
+  }
+
+  /**
+   * Point heritage and call edges at declarations of this compilation unit. Types are matched by
+   * their in-file name, then by simple name; calls without a receiver (or on `this`/`super`) look
+   * in the calling type, its enclosing types and their superclasses declared here, and
+   * `Type.method()` looks in `Type`. Targets
+   * declared elsewhere keep the name from source, with the import recorded as `targetFile`.
+   */
+  private bindLocalTargets(): void {
+    for (const item of this.pending) {
+      const rel = item.relationship;
+      if (item.kind === "type") {
+        const local = this.declaredTypes.get(item.name) ?? this.declaredTypes.get(item.name.split(".").pop()!);
+        if (local) {
+          rel.to = local;
+          continue;
+        }
+        const imported = this.importedTypes.get(item.name);
+        if (imported) rel.targetFile = imported.slice(0, imported.lastIndexOf("."));
+        continue;
+      }
+
+      let owners: string[] = [];
+      if (!item.receiver || item.receiver === "this" || item.receiver === "super") {
+        const segments = item.owner.split(".");
+        owners = segments.map((_, i) => segments.slice(0, segments.length - i).join("."));
+      } else if (this.declaredTypes.has(item.receiver)) {
+        owners = [item.receiver];
+      }
+      const local = this.findMethod(owners, item.name);
+      if (local) rel.to = local;
+    }
+  }
+
+  /** First of `owners` (or a superclass of one, declared in this file) that declares `name` */
+  private findMethod(owners: string[], name: string): string | undefined {
+    for (const owner of owners) {
+      const seen = new Set<string>();
+      for (let type: string | undefined = owner; type && !seen.has(type); type = this.superclassOf.get(type)) {
+        seen.add(type);
+        const id = this.declaredMethods.get(`${type}.${name}`);
+        if (id) return id;
+      }
+    }
+    return undefined;
+  }
+
+  /**
+   * `decorated_by` edges from annotated declarations to annotation types declared in this file or
+   * brought in by a single-type import. `java.lang` annotations such as `@Override` need no edge.
+   */
+  private linkDecorators(entities: ParsedEntity[], relationships: EntityRelationship[]): void {
+    for (const entity of entities) {
+      if (!entity.id) continue;
+      for (const decorator of entity.decorators ?? []) {
+        const local = this.declaredTypes.get(decorator.name);
+        const imported = this.importedTypes.get(decorator.name);
+        if (!local && !imported) continue;
+        relationships.push({
+          from: entity.id,
+          to: local ?? decorator.name.split(".").pop()!,
+          type: "decorated_by",
+          ...(local ? {} : { targetFile: imported!.slice(0, imported!.lastIndexOf(".")) }),
+          metadata: {
+            line: decorator.line,
+            decorator: decorator.name,
+            arguments: decorator.arguments,
+            raw: decorator.raw,
+          },
+        });
+      }
+    }
   }
 
   /**
@@ -215,7 +338,8 @@ export class JavaAnalyzer {
           break;
 
         case "field_declaration":
-          // Extract fields
+        case "constant_declaration":
+          // Extract fields (and interface constants)
           if (this.currentClass) {
             this.extractField(node, filePath, entities, relationships, this.currentClass);
           }
@@ -252,6 +376,7 @@ export class JavaAnalyzer {
         location: this.getNodeLocation(node),
         metadata: {
           isPackage: true,
+          qualifiedName: packageName,
         },
       });
     }
@@ -272,6 +397,10 @@ export class JavaAnalyzer {
     if (importPath) {
       const path = importPath.text;
       const isWildcard = !!asterisk;
+      const isStatic = node.children.some((c) => c.type === "static");
+      if (!isWildcard && !isStatic) {
+        this.importedTypes.set(path.slice(path.lastIndexOf(".") + 1), path);
+      }
 
       const fromId = this.ensurePackageEntity(filePath, entities);
 
@@ -281,7 +410,7 @@ export class JavaAnalyzer {
         type: "imports",
         metadata: {
           isWildcard,
-          isStatic: node.text.includes("static"),
+          isStatic,
         },
       });
     }
@@ -306,6 +435,10 @@ export class JavaAnalyzer {
 
       const modifiers = this.extractModifiers(node);
       const classId = `${filePath}:class:${fullClassName}`;
+      const superclass = this.typeNames(node.childForFieldName("superclass"));
+      const interfaces = this.typeNames(node.childForFieldName("interfaces"));
+      this.declareType(fullClassName, classId);
+      if (superclass[0]) this.superclassOf.set(fullClassName, superclass[0]);
 
       const entity: ParsedEntity = {
         id: classId,
@@ -314,6 +447,7 @@ export class JavaAnalyzer {
         filePath,
         location: this.getNodeLocation(node),
         modifiers,
+        ...this.declarationDetails(node, superclass, interfaces),
         metadata: {
           isAbstract: modifiers.includes("abstract"),
           isFinal: modifiers.includes("final"),
@@ -322,43 +456,17 @@ export class JavaAnalyzer {
           isPrivate: modifiers.includes("private"),
           isProtected: modifiers.includes("protected"),
           package: this.currentPackage,
+          qualifiedName: this.qualify(fullClassName),
           isInnerClass: !!previousClass,
         },
       };
 
       entities.push(entity);
-
-      // Extract superclass
-      const superclass = node.childForFieldName("superclass");
-      if (superclass) {
-        const superclassName = superclass.namedChildren[0]?.text;
-        if (superclassName) {
-          relationships.push({
-            from: classId,
-            to: `${filePath}:class:${superclassName}`,
-            type: "extends",
-            metadata: {
-              inheritanceType: "extends",
-            },
-          });
-        }
-      }
-
-      // Extract interfaces
-      const interfaces = node.childForFieldName("interfaces");
-      if (interfaces) {
-        const interfaceList = interfaces.namedChildren.filter((c) => c.type === "type_identifier");
-        for (const iface of interfaceList) {
-          relationships.push({
-            from: classId,
-            to: `${filePath}:interface:${iface.text}`,
-            type: "implements",
-            metadata: {},
-          });
-        }
-      }
+      this.pushHeritage(relationships, classId, superclass, interfaces);
 
       // Process class body
+      const previousTypeId = this.currentTypeId;
+      this.currentTypeId = classId;
       const body = node.childForFieldName("body");
       if (body) {
         for (let i = 0; i < body.childCount; i++) {
@@ -371,6 +479,7 @@ export class JavaAnalyzer {
 
       // Restore previous class context
       this.currentClass = previousClass;
+      this.currentTypeId = previousTypeId;
     }
   }
 
@@ -391,6 +500,9 @@ export class JavaAnalyzer {
       const previousClass = this.currentClass;
       const fullInterfaceName = previousClass ? `${previousClass}.${interfaceName}` : interfaceName;
       const interfaceId = `${filePath}:interface:${fullInterfaceName}`;
+      // `interface A extends B, C` has no field name for its extends clause
+      const extended = this.typeNames(node.namedChildren.find((c) => c.type === "extends_interfaces") ?? null);
+      this.declareType(fullInterfaceName, interfaceId);
 
       const entity: ParsedEntity = {
         id: interfaceId,
@@ -399,32 +511,21 @@ export class JavaAnalyzer {
         filePath,
         location: this.getNodeLocation(node),
         modifiers,
+        ...this.declarationDetails(node, extended, []),
         metadata: {
           isPublic: modifiers.includes("public"),
           package: this.currentPackage,
+          qualifiedName: this.qualify(fullInterfaceName),
         },
       };
 
       entities.push(entity);
-
-      // Extract extended interfaces
-      const extendsList = node.childForFieldName("extends");
-      if (extendsList) {
-        const interfaces = extendsList.namedChildren.filter((c) => c.type === "type_identifier");
-        for (const iface of interfaces) {
-          relationships.push({
-            from: interfaceId,
-            to: `${filePath}:interface:${iface.text}`,
-            type: "extends",
-            metadata: {
-              inheritanceType: "extends",
-            },
-          });
-        }
-      }
+      this.pushHeritage(relationships, interfaceId, extended, []);
 
       // Store current class context and process interface body
       this.currentClass = fullInterfaceName;
+      const previousTypeId = this.currentTypeId;
+      this.currentTypeId = interfaceId;
 
       const body = node.childForFieldName("body");
       if (body) {
@@ -437,6 +538,7 @@ export class JavaAnalyzer {
       }
 
       this.currentClass = previousClass;
+      this.currentTypeId = previousTypeId;
     }
   }
 
@@ -457,6 +559,8 @@ export class JavaAnalyzer {
       const previousClass = this.currentClass;
       const fullEnumName = previousClass ? `${previousClass}.${enumName}` : enumName;
       const enumId = `${filePath}:enum:${fullEnumName}`;
+      const interfaces = this.typeNames(node.childForFieldName("interfaces"));
+      this.declareType(fullEnumName, enumId);
 
       const entity: ParsedEntity = {
         id: enumId,
@@ -465,15 +569,20 @@ export class JavaAnalyzer {
         filePath,
         location: this.getNodeLocation(node),
         modifiers,
+        ...this.declarationDetails(node, [], interfaces),
         metadata: {
           isPublic: modifiers.includes("public"),
           package: this.currentPackage,
+          qualifiedName: this.qualify(fullEnumName),
         },
       };
 
       entities.push(entity);
+      this.pushHeritage(relationships, enumId, [], interfaces);
 
       this.currentClass = fullEnumName;
+      const previousTypeId = this.currentTypeId;
+      this.currentTypeId = enumId;
 
       // Extract enum constants
       const body = node.childForFieldName("body");
@@ -492,6 +601,7 @@ export class JavaAnalyzer {
               metadata: {
                 parent: enumId,
                 enumValue: true,
+                qualifiedName: this.qualify(`${fullEnumName}.${constantName}`),
               },
             };
 
@@ -518,6 +628,7 @@ export class JavaAnalyzer {
 
       // Restore previous class context
       this.currentClass = previousClass;
+      this.currentTypeId = previousTypeId;
     }
   }
 
@@ -538,6 +649,8 @@ export class JavaAnalyzer {
       const previousClass = this.currentClass;
       const fullRecordName = previousClass ? `${previousClass}.${recordName}` : recordName;
       const recordId = `${filePath}:record:${fullRecordName}`;
+      const interfaces = this.typeNames(node.childForFieldName("interfaces"));
+      this.declareType(fullRecordName, recordId);
 
       const entity: ParsedEntity = {
         id: recordId,
@@ -546,22 +659,30 @@ export class JavaAnalyzer {
         filePath,
         location: this.getNodeLocation(node),
         modifiers,
+        ...this.declarationDetails(node, [], interfaces),
         metadata: {
           isRecord: true,
           isPublic: modifiers.includes("public"),
           isFinal: true, // Records are implicitly final
           package: this.currentPackage,
+          qualifiedName: this.qualify(fullRecordName),
         },
       };
 
       entities.push(entity);
+      this.pushHeritage(relationships, recordId, [], interfaces);
 
       this.currentClass = fullRecordName;
+      const previousTypeId = this.currentTypeId;
+      this.currentTypeId = recordId;
 
       // Extract record components
       const parameters = node.childForFieldName("parameters");
       if (parameters) {
-        const components = parameters.namedChildren.filter((c) => c.type === "record_component");
+        // Components parse as formal parameters in current grammars
+        const components = parameters.namedChildren.filter(
+          (c) => c.type === "record_component" || c.type === "formal_parameter",
+        );
         for (const component of components) {
           const componentName = component.childForFieldName("name")?.text;
           const componentType = component.childForFieldName("type")?.text;
@@ -578,6 +699,7 @@ export class JavaAnalyzer {
                 parent: recordId,
                 fieldType: componentType,
                 isRecordComponent: true,
+                qualifiedName: this.qualify(`${fullRecordName}.${componentName}`),
               },
             };
 
@@ -606,6 +728,7 @@ export class JavaAnalyzer {
       }
 
       this.currentClass = previousClass;
+      this.currentTypeId = previousTypeId;
     }
   }
 
@@ -621,6 +744,7 @@ export class JavaAnalyzer {
       const previousClass = this.currentClass;
       const fullAnnotationName = previousClass ? `${previousClass}.${annotationName}` : annotationName;
       const annotationId = `${filePath}:annotation:${fullAnnotationName}`;
+      this.declareType(fullAnnotationName, annotationId);
 
       const entity: ParsedEntity = {
         id: annotationId,
@@ -629,10 +753,12 @@ export class JavaAnalyzer {
         filePath,
         location: this.getNodeLocation(node),
         modifiers,
+        ...this.declarationDetails(node, [], []),
         metadata: {
           isAnnotation: true,
           isPublic: modifiers.includes("public"),
           package: this.currentPackage,
+          qualifiedName: this.qualify(fullAnnotationName),
         },
       };
 
@@ -666,6 +792,7 @@ export class JavaAnalyzer {
         location: this.getNodeLocation(node),
         modifiers,
         returnType,
+        ...this.declarationDetails(node, [], []),
         metadata: {
           isPublic: modifiers.includes("public"),
           isPrivate: modifiers.includes("private"),
@@ -675,6 +802,7 @@ export class JavaAnalyzer {
           isAbstract: modifiers.includes("abstract"),
           isSynchronized: modifiers.includes("synchronized"),
           parent: parentClass,
+          qualifiedName: this.qualify(`${parentClass}.${methodName}`),
         },
       };
 
@@ -684,19 +812,15 @@ export class JavaAnalyzer {
         entity.parameters = this.extractParameters(parameters);
       }
 
-      // Extract annotations
-      const annotations = this.extractAnnotations(node);
-      if (annotations.length > 0) {
-        entity.metadata ??= {};
-        (entity.metadata as any).annotations = annotations;
-      }
-
       entities.push(entity);
+      if (!this.declaredMethods.has(`${parentClass}.${methodName}`)) {
+        this.declaredMethods.set(`${parentClass}.${methodName}`, methodId);
+      }
 
       // Create relationship to parent class
       relationships.push({
         from: methodId,
-        to: `${filePath}:class:${parentClass}`,
+        to: this.currentTypeId ?? `${filePath}:class:${parentClass}`,
         type: "contains",
         metadata: {
           memberType: "method",
@@ -706,7 +830,7 @@ export class JavaAnalyzer {
       // Extract method calls within body
       const body = node.childForFieldName("body");
       if (body) {
-        this.extractMethodCalls(body, methodId, filePath, relationships);
+        this.extractMethodCalls(body, methodId, parentClass, relationships);
       }
     }
   }
@@ -735,12 +859,14 @@ export class JavaAnalyzer {
         filePath,
         location: this.getNodeLocation(node),
         modifiers,
+        ...this.declarationDetails(node, [], []),
         metadata: {
           isConstructor: true,
           isPublic: modifiers.includes("public"),
           isPrivate: modifiers.includes("private"),
           isProtected: modifiers.includes("protected"),
           parent: parentClass,
+          qualifiedName: this.qualify(`${parentClass}.${constructorName}`),
         },
       };
 
@@ -755,12 +881,17 @@ export class JavaAnalyzer {
       // Create relationship to parent class
       relationships.push({
         from: constructorId,
-        to: `${filePath}:class:${parentClass}`,
+        to: this.currentTypeId ?? `${filePath}:class:${parentClass}`,
         type: "contains",
         metadata: {
           memberType: "constructor",
         },
       });
+
+      const body = node.childForFieldName("body");
+      if (body) {
+        this.extractMethodCalls(body, constructorId, parentClass, relationships);
+      }
     }
   }
 
@@ -776,6 +907,10 @@ export class JavaAnalyzer {
   ): void {
     const typeNode = node.childForFieldName("type");
     const fieldType = typeNode?.text;
+    const modifiers = this.extractModifiers(node);
+    const details = this.declarationDetails(node, [], []);
+    // Interface fields are implicitly public static final
+    const isConstant = modifiers.includes("final") || node.type === "constant_declaration";
 
     // Extract all variable declarators
     const declarators = node.namedChildren.filter((c) => c.type === "variable_declarator");
@@ -785,17 +920,17 @@ export class JavaAnalyzer {
       const fieldName = nameNode?.text;
 
       if (fieldName) {
-        const modifiers = this.extractModifiers(node);
         const valueNode = declarator.childForFieldName("value");
         const fieldId = `${filePath}:class:${parentClass}:field:${fieldName}`;
 
         const entity: ParsedEntity = {
           id: fieldId,
           name: fieldName,
-          type: modifiers.includes("final") ? "constant" : "property",
+          type: isConstant ? "constant" : "property",
           filePath,
           location: this.getNodeLocation(declarator),
           modifiers,
+          ...details,
           metadata: {
             fieldType,
             isPublic: modifiers.includes("public"),
@@ -807,6 +942,7 @@ export class JavaAnalyzer {
             isTransient: modifiers.includes("transient"),
             initialValue: valueNode?.text,
             parent: parentClass,
+            qualifiedName: this.qualify(`${parentClass}.${fieldName}`),
           },
         };
 
@@ -815,7 +951,7 @@ export class JavaAnalyzer {
         // Create relationship to parent class
         relationships.push({
           from: fieldId,
-          to: `${filePath}:class:${parentClass}`,
+          to: this.currentTypeId ?? `${filePath}:class:${parentClass}`,
           type: "contains",
           metadata: {
             memberType: "field",
@@ -830,12 +966,12 @@ export class JavaAnalyzer {
    */
   private extractModifiers(node: TreeSitterNode): string[] {
     const modifiers: string[] = [];
-    const modifiersNode = node.childForFieldName("modifiers");
+    const modifiersNode = this.modifiersNode(node);
 
     if (modifiersNode) {
       for (let i = 0; i < modifiersNode.childCount; i++) {
         const child = modifiersNode.child(i);
-        if (child && child.type !== "annotation") {
+        if (child && child.type !== "annotation" && child.type !== "marker_annotation") {
           modifiers.push(child.text);
         }
       }
@@ -844,23 +980,77 @@ export class JavaAnalyzer {
     return modifiers;
   }
 
+  /** The grammar puts `modifiers` first in a declaration without giving it a field name */
+  private modifiersNode(node: TreeSitterNode): TreeSitterNode | null {
+    return node.childForFieldName("modifiers") ?? node.namedChildren.find((c) => c.type === "modifiers") ?? null;
+  }
+
   /**
-   * Extract annotations from a node
+   * Annotations on a declaration (`@Override`, `@GetMapping("/users")`), in the shape decorators
+   * have for other languages: name as written, argument texts and the expression without `@`
    */
-  private extractAnnotations(node: TreeSitterNode): string[] {
-    const annotations: string[] = [];
-    const modifiersNode = node.childForFieldName("modifiers");
-
-    if (modifiersNode) {
-      const annotationNodes = modifiersNode.namedChildren.filter(
-        (c) => c.type === "annotation" || c.type === "marker_annotation",
-      );
-      for (const annotation of annotationNodes) {
-        annotations.push(annotation.text);
-      }
+  private extractDecorators(node: TreeSitterNode): Decorator[] {
+    const modifiersNode = this.modifiersNode(node);
+    if (!modifiersNode) return [];
+
+    const decorators: Decorator[] = [];
+    for (const annotation of modifiersNode.namedChildren) {
+      if (annotation.type !== "annotation" && annotation.type !== "marker_annotation") continue;
+      const name = annotation.childForFieldName("name")?.text;
+      if (!name) continue;
+      const args = annotation.childForFieldName("arguments");
+      decorators.push({
+        name,
+        arguments: args ? args.namedChildren.filter((a) => a.type !== "comment").map((a) => a.text) : undefined,
+        raw: annotation.text.replace(/^@\s*/, ""),
+        line: annotation.startPosition.row + 1,
+      });
     }
+    return decorators;
+  }
 
-    return annotations;
+  /** Type names listed in a `superclass`, `super_interfaces` or `extends_interfaces` clause */
+  private typeNames(clause: TreeSitterNode | null): string[] {
+    if (!clause) return [];
+    const list = clause.namedChildren.find((c) => c.type === "type_list");
+    return (list ?? clause).namedChildren
+      .filter((c) => c.type !== "comment")
+      .map((c) => c.text.replace(/<[\s\S]*$/, "").trim())
+      .filter((name) => name.length > 0);
+  }
+
+  /** Annotations and heritage as entity fields, omitted when there are none */
+  private declarationDetails(
+    node: TreeSitterNode,
+    baseClasses: string[],
+    interfaces: string[],
+  ): Pick<ParsedEntity, "decorators" | "inheritance"> {
+    const decorators = this.extractDecorators(node);
+    return {
+      ...(decorators.length ? { decorators } : {}),
+      ...(baseClasses.length || interfaces.length
+        ? { inheritance: { baseClasses, ...(interfaces.length ? { interfaces } : {}) } }
+        : {}),
+    };
+  }
+
+  private pushHeritage(
+    relationships: EntityRelationship[],
+    fromId: string,
+    baseClasses: string[],
+    interfaces: string[],
+  ): void {
+    for (const name of baseClasses) {
+      this.pushTypeEdge(relationships, {
+        from: fromId,
+        to: name,
+        type: "extends",
+        metadata: { inheritanceType: "extends" },
+      });
+    }
+    for (const name of interfaces) {
+      this.pushTypeEdge(relationships, { from: fromId, to: name, type: "implements", metadata: {} });
+    }
   }
 
   /**
@@ -888,42 +1078,43 @@ export class JavaAnalyzer {
   }
 
   /**
-   * Extract method calls to create relationships
+   * Extract method calls to create relationships. The body is walked with an explicit stack:
+   * long builder chains nest one invocation per call and would trip the recursion breaker.
    */
   private extractMethodCalls(
-    node: TreeSitterNode,
+    body: TreeSitterNode,
     callerId: string,
-    filePath: string,
+    owner: string,
     relationships: EntityRelationship[],
   ): void {
-    this.recursionDepth++;
     this.checkCircuitBreakers();
-
-    try {
+    const stack: TreeSitterNode[] = [body];
+    while (stack.length > 0) {
+      const node = stack.pop()!;
       if (node.type === "method_invocation") {
         const nameNode = node.childForFieldName("name");
         if (nameNode) {
-          const methodName = nameNode.text;
-          relationships.push({
+          const receiver = node.childForFieldName("object")?.text;
+          const relationship: EntityRelationship = {
             from: callerId,
-            to: methodName,
+            to: nameNode.text,
             type: "calls",
             metadata: {
               callType: "method",
+              line: nameNode.startPosition.row + 1,
+              column: nameNode.startPosition.column,
+              callee: receiver ? `${receiver}.${nameNode.text}` : nameNode.text,
+              calleeName: nameNode.text,
+              ...callArguments(node.childForFieldName("arguments")),
             },
-          });
+          };
+          relationships.push(relationship);
+          this.pending.push({ kind: "call", relationship, name: nameNode.text, owner, receiver });
         }
       }
 
-      // Recursively search for calls in children
-      for (let i = 0; i < node.childCount; i++) {
-        const child = node.child(i);
-        if (child) {
-          this.extractMethodCalls(child, callerId, filePath, relationships);
-        }
-      }
-    } finally {
-      this.recursionDepth--;
+      const children = node.namedChildren;
+      for (let i = children.length - 1; i >= 0; i--) stack.push(children[i]!);
     }
   }
 }
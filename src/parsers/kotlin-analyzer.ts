@@ -5,7 +5,8 @@
  *
  * Declarations:
  * - Packages and imports (with aliases)
- * - Classes (regular, data, sealed, inner, value, annotation, enum)
+ * - Classes (regular, data, sealed, inner, value, annotation, enum); data classes list their
+ *   components in `componentN` order
  * - Interfaces (regular and functional/SAM)
  * - Object declarations (singletons)
  * - Companion objects
@@ -36,6 +37,8 @@
  * - Companion relationships
  * - Type constraints
  * - Type alias relationships
+ * - Extension functions and properties to their receiver type (extension_of)
+ * - Annotations to annotation classes declared in the same file (decorated_by)
  *
  * Implementation uses circuit breakers for safety and follows the proven
  * pattern from Java analyzer with Kotlin-specific adaptations.
@@ -140,6 +143,7 @@ export class KotlinAnalyzer {
     try {
       log("DEBUG", "Starting entity extraction...");
       this.extractEntities(rootNode, filePath, entities, relationships);
+      this.linkAnnotations(entities, relationships);
       log(
         "INFO",
         `<<< ANALYZE END: ${filePath} - Extracted ${entities.length} entities, ${relationships.length} relationships`,
@@ -504,12 +508,28 @@ export class KotlinAnalyzer {
       },
     };
 
+    const annotations = this.extractAnnotations(node);
+    if (annotations.length > 0) {
+      (entity.metadata as any).annotations = annotations;
+    }
+
     entities.push(entity);
 
     // Extract primary constructor if present
     const primaryConstructor = node.namedChildren.find((c) => c.type === "primary_constructor");
     if (primaryConstructor) {
-      this.extractPrimaryConstructor(primaryConstructor, filePath, entities, relationships, fullClassName);
+      const components = this.extractPrimaryConstructor(
+        primaryConstructor,
+        filePath,
+        entities,
+        relationships,
+        fullClassName,
+        isData,
+      );
+      // What `val (a, b) = instance` destructures to, and what copy() takes
+      if (isData) {
+        (entity.metadata as any).components = components;
+      }
     }
 
     // Extract inheritance (delegation_specifier)
@@ -582,6 +602,11 @@ export class KotlinAnalyzer {
       },
     };
 
+    const annotations = this.extractAnnotations(node);
+    if (annotations.length > 0) {
+      (entity.metadata as any).annotations = annotations;
+    }
+
     entities.push(entity);
 
     // Extract inheritance
@@ -688,8 +713,11 @@ export class KotlinAnalyzer {
     const modifiers = this.extractModifiers(node);
 
     // Check for extension function (receiver_type)
-    const receiverType = this.extractReceiverType(node);
+    const receiverType = this.extractReceiverType(node, nameNode);
     const isExtension = !!receiverType;
+    const receiverName = receiverType ? this.receiverName(receiverType) : undefined;
+    // Extensions of different types may share a name, so the receiver is part of the id
+    const declaredName = receiverName ? `${receiverName}.${functionName}` : functionName;
 
     // Extract function modifiers
     const isSuspend = modifiers.includes("suspend");
@@ -717,10 +745,10 @@ export class KotlinAnalyzer {
       functionId = `${filePath}:function:${fullFunctionName}`;
     } else if (this.currentClass) {
       fullFunctionName = `${this.currentClass}.${functionName}`;
-      functionId = `${filePath}:class:${this.currentClass}:method:${functionName}`;
+      functionId = `${filePath}:class:${this.currentClass}:method:${declaredName}`;
     } else {
       fullFunctionName = functionName;
-      functionId = `${filePath}:function:${functionName}`;
+      functionId = `${filePath}:function:${declaredName}`;
     }
 
     // Extract return type
@@ -750,6 +778,7 @@ export class KotlinAnalyzer {
       metadata: {
         isExtension,
         receiverType,
+        receiverName,
         isSuspend,
         isInline,
         isInfix,
@@ -789,6 +818,10 @@ export class KotlinAnalyzer {
       });
     }
 
+    if (receiverType && receiverName) {
+      this.linkReceiver(functionId, receiverType, receiverName, "method", relationships);
+    }
+
     // Create overrides relationship if applicable
     if (isOverride && this.currentClass) {
       relationships.push({
@@ -832,8 +865,9 @@ export class KotlinAnalyzer {
     const nameNode = node.namedChildren.find((c) => c.type === "variable_declaration");
 
     let propertyName: string | undefined;
+    let identNode: TreeSitterNode | undefined;
     if (nameNode) {
-      const identNode = nameNode.namedChildren.find((c) => c.type === "simple_identifier");
+      identNode = nameNode.namedChildren.find((c) => c.type === "simple_identifier");
       propertyName = identNode?.text;
     }
 
@@ -853,8 +887,10 @@ export class KotlinAnalyzer {
     const isVar = nodeText.trimStart().startsWith("var ");
 
     // Check for extension property
-    const receiverType = this.extractReceiverType(node);
+    const receiverType = this.extractReceiverType(node, identNode ?? nameNode);
     const isExtension = !!receiverType;
+    const receiverName = receiverType ? this.receiverName(receiverType) : undefined;
+    const declaredName = receiverName ? `${receiverName}.${propertyName}` : propertyName;
 
     // Check modifiers
     const isConst = modifiers.includes("const");
@@ -888,9 +924,9 @@ export class KotlinAnalyzer {
     let propertyId: string;
 
     if (this.currentClass) {
-      propertyId = `${filePath}:class:${this.currentClass}:property:${propertyName}`;
+      propertyId = `${filePath}:class:${this.currentClass}:property:${declaredName}`;
     } else {
-      propertyId = `${filePath}:property:${propertyName}`;
+      propertyId = `${filePath}:property:${declaredName}`;
     }
 
     const entityType = isConst ? "constant" : "property";
@@ -908,6 +944,7 @@ export class KotlinAnalyzer {
         isVar,
         isExtension,
         receiverType,
+        receiverName,
         isConst,
         isLateinit,
         isOverride,
@@ -943,6 +980,10 @@ export class KotlinAnalyzer {
       });
     }
 
+    if (receiverType && receiverName) {
+      this.linkReceiver(propertyId, receiverType, receiverName, "property", relationships);
+    }
+
     // Create delegates_to relationship if delegated
     if (isDelegated && delegateExpression) {
       relationships.push({
@@ -958,7 +999,8 @@ export class KotlinAnalyzer {
   }
 
   /**
-   * Extract primary constructor
+   * Extract primary constructor. Returns the val/var parameters in order, which for a data class
+   * are its components.
    */
   private extractPrimaryConstructor(
     node: TreeSitterNode,
@@ -966,7 +1008,8 @@ export class KotlinAnalyzer {
     entities: ParsedEntity[],
     relationships: EntityRelationship[],
     parentClass: string,
-  ): void {
+    isDataClass: boolean,
+  ): Array<{ name: string; type?: string; index: number }> {
     const modifiers = this.extractModifiers(node);
     const constructorId = `${filePath}:class:${parentClass}:constructor:primary`;
 
@@ -985,6 +1028,7 @@ export class KotlinAnalyzer {
       isVal?: boolean;
       isVar?: boolean;
     }> = [];
+    const components: Array<{ name: string; type?: string; index: number }> = [];
 
     for (const param of paramNodes) {
       if (param.type !== "class_parameter") continue;
@@ -1014,6 +1058,8 @@ export class KotlinAnalyzer {
       // If it's a property (val/var), create a property entity
       if (isProperty) {
         const propertyId = `${filePath}:class:${parentClass}:property:${paramName}`;
+        components.push({ name: paramName, type: paramType, index: components.length + 1 });
+        const annotations = this.extractAnnotations(param);
 
         entities.push({
           id: propertyId,
@@ -1027,6 +1073,8 @@ export class KotlinAnalyzer {
             isVar,
             isConstructorProperty: true,
             parent: parentClass,
+            ...(isDataClass ? { componentIndex: components.length } : {}),
+            ...(annotations.length > 0 ? { annotations } : {}),
           },
         });
 
@@ -1067,6 +1115,8 @@ export class KotlinAnalyzer {
         memberType: "constructor",
       },
     });
+
+    return components;
   }
 
   /**
@@ -1650,25 +1700,15 @@ export class KotlinAnalyzer {
 
     for (const child of modifiersNode.namedChildren) {
       if (child.type === "annotation") {
-        const annotationText = child.text;
-
-        // Parse use-site target if present (e.g., @field:Inject)
-        const targetMatch = annotationText.match(/@(\w+):(\w+)/);
-        const simpleMatch = annotationText.match(/@(\w+)/);
-
-        if (targetMatch?.[1] && targetMatch[2]) {
-          annotations.push({
-            target: targetMatch[1],
-            name: targetMatch[2],
-          });
-        } else if (simpleMatch?.[1]) {
-          // Check for arguments
-          const argsMatch = annotationText.match(/@\w+\(([^)]*)\)/);
-          annotations.push({
-            name: simpleMatch[1],
-            arguments: argsMatch?.[1] ? argsMatch[1].split(",").map((a) => a.trim()) : undefined,
-          });
-        }
+        // Optional use-site target (@field:Inject), a possibly qualified name, then arguments
+        const match = child.text.match(/^@(?:(\w+):)?([\w.]+)\s*(?:\(([^)]*)\))?/);
+        if (!match?.[2]) continue;
+
+        annotations.push({
+          name: match[2],
+          ...(match[1] ? { target: match[1] } : {}),
+          ...(match[3] ? { arguments: match[3].split(",").map((a) => a.trim()) } : {}),
+        });
       }
     }
 
@@ -1774,14 +1814,15 @@ export class KotlinAnalyzer {
   /**
    * Extract receiver type for extension functions/properties
    */
-  private extractReceiverType(node: TreeSitterNode): string | null {
+  private extractReceiverType(node: TreeSitterNode, nameNode?: TreeSitterNode): string | null {
     const receiverNode = node.namedChildren.find((c) => c.type === "receiver_type");
     if (receiverNode) {
       return receiverNode.text;
     }
 
-    // Alternative: check node text for pattern "Type.name"
-    const nodeText = node.text;
+    // Alternative: check the declaration head for pattern "Type.name"; the body may declare
+    // extensions of its own
+    const nodeText = nameNode ? node.text.slice(0, nameNode.endIndex - node.startIndex) : node.text;
     const funMatch = nodeText.match(/fun\s+(?:<[^>]+>\s+)?(\w+(?:<[^>]+>)?)\./);
     const valVarMatch = nodeText.match(/(?:val|var)\s+(\w+(?:<[^>]+>)?)\./);
 
@@ -1790,4 +1831,59 @@ export class KotlinAnalyzer {
 
     return null;
   }
+
+  /** Type an extension is declared on: `List` for `List<T>?`, `String` for `kotlin.String` */
+  private receiverName(receiverType: string): string {
+    const bare = receiverType.replace(/<.*>/s, "").replace(/[\s?()]/g, "");
+    return bare.split(".").pop() || bare;
+  }
+
+  /** `extension_of` edge from an extension function or property to the type it is declared on */
+  private linkReceiver(
+    fromId: string,
+    receiverType: string,
+    receiverName: string,
+    memberType: "method" | "property",
+    relationships: EntityRelationship[],
+  ): void {
+    relationships.push({
+      from: fromId,
+      to: receiverName,
+      type: "extension_of",
+      metadata: {
+        memberType,
+        receiverType,
+      },
+    });
+  }
+
+  /**
+   * `decorated_by` edges from annotated declarations to the annotation classes this file declares.
+   * Annotations from libraries (@Serializable, @Composable) stay metadata only.
+   */
+  private linkAnnotations(entities: ParsedEntity[], relationships: EntityRelationship[]): void {
+    const declared = new Map<string, string>();
+    for (const entity of entities) {
+      if (entity.id && entity.metadata?.isAnnotation) declared.set(entity.name.split(".").pop()!, entity.id);
+    }
+    if (declared.size === 0) return;
+
+    for (const entity of entities) {
+      const annotations = (entity.metadata?.annotations ?? []) as Array<{ name: string; arguments?: string[] }>;
+      for (const annotation of annotations) {
+        const target = declared.get(annotation.name.split(".").pop()!);
+        if (!target || !entity.id) continue;
+        relationships.push({
+          from: entity.id,
+          to: target,
+          type: "decorated_by",
+          metadata: {
+            line: entity.location?.start.line,
+            decorator: annotation.name,
+            arguments: annotation.arguments,
+          },
+        });
+      }
+    }
+  }
 }
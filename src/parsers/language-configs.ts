@@ -43,6 +43,17 @@ export const FILE_EXTENSIONS: Record<string, SupportedLanguage> = {
   md: "markdown",
   mdx: "markdown",
 
+  // Protocol Buffers
+  proto: "proto",
+
+  // SQL
+  sql: "sql",
+
+  // GraphQL
+  graphql: "graphql",
+  gql: "graphql",
+  graphqls: "graphql",
+
   // Python
   py: "python",
   pyi: "python",
@@ -78,6 +89,24 @@ export const FILE_EXTENSIONS: Record<string, SupportedLanguage> = {
   kt: "kotlin",
   kts: "kotlin",
 
+  // Ruby
+  rb: "ruby",
+  rake: "ruby",
+  gemspec: "ruby",
+  ru: "ruby",
+
+  // PHP
+  php: "php",
+  phtml: "php",
+
+  // Swift
+  swift: "swift",
+
+  // Scala (sbt build definitions are Scala too)
+  scala: "scala",
+  sc: "scala",
+  sbt: "scala",
+
   // VBA
   vba: "vba",
   bas: "vba",
@@ -106,6 +135,30 @@ export const LANGUAGE_KEYWORDS: Record<
     types: [],
   },
 
+  proto: {
+    functions: ["rpc"],
+    classes: ["message", "service", "enum"],
+    imports: ["import"],
+    exports: [],
+    types: ["double", "float", "int32", "int64", "uint32", "uint64", "bool", "string", "bytes"],
+  },
+
+  sql: {
+    functions: ["function", "procedure"],
+    classes: ["table", "view"],
+    imports: [],
+    exports: [],
+    types: ["integer", "bigint", "numeric", "text", "varchar", "boolean", "timestamp", "date", "json"],
+  },
+
+  graphql: {
+    functions: [],
+    classes: ["type", "interface", "input", "enum", "union", "scalar"],
+    imports: [],
+    exports: [],
+    types: ["Int", "Float", "String", "Boolean", "ID"],
+  },
+
   javascript: {
     functions: ["function", "async", "generator", "=>"],
     classes: ["class", "constructor", "extends"],
@@ -267,6 +320,38 @@ export const LANGUAGE_KEYWORDS: Record<
     ],
   },
 
+  ruby: {
+    functions: ["def"],
+    classes: ["class", "module"],
+    imports: ["require", "require_relative", "include", "extend", "prepend"],
+    exports: ["public"], // Methods are public unless `private`/`protected` applies
+    types: ["Integer", "Float", "String", "Symbol", "Array", "Hash", "NilClass", "TrueClass", "FalseClass"],
+  },
+
+  php: {
+    functions: ["function", "fn"],
+    classes: ["class", "interface", "trait", "enum"],
+    imports: ["use", "namespace", "require", "require_once", "include", "include_once"],
+    exports: ["public"],
+    types: ["int", "float", "string", "bool", "array", "object", "mixed", "void", "never", "iterable", "callable"],
+  },
+
+  swift: {
+    functions: ["func", "init", "deinit", "subscript"],
+    classes: ["class", "struct", "enum", "protocol", "extension", "actor"],
+    imports: ["import"],
+    exports: ["public", "open"],
+    types: ["typealias", "Int", "Double", "Float", "Bool", "String", "Character", "Array", "Dictionary", "Set", "Void"],
+  },
+
+  scala: {
+    functions: ["def", "given", "extension"],
+    classes: ["class", "trait", "object", "enum", "case"],
+    imports: ["import", "package"],
+    exports: ["export"],
+    types: ["type", "Int", "Long", "Double", "Boolean", "String", "Unit", "Any", "Nothing", "Option", "List", "Map"],
+  },
+
   vba: {
     functions: ["Sub", "Function", "Property"],
     classes: ["Class", "Type", "Enum"],
@@ -1263,6 +1348,276 @@ const KOTLIN_CONFIG: LanguageConfig = {
   },
 };
 
+/**
+ * Ruby language configuration
+ */
+const RUBY_CONFIG: LanguageConfig = {
+  language: "ruby",
+  extensions: ["rb", "rake", "gemspec", "ru"],
+  keywords: LANGUAGE_KEYWORDS.ruby,
+  nodeTypes: {
+    functions: ["method", "singleton_method", "lambda"],
+    classes: ["class", "module", "singleton_class"],
+    methods: ["method", "singleton_method"],
+    imports: ["call"], // `require`, `include` and friends are plain method calls
+    exports: [],
+    variables: ["assignment", "operator_assignment"],
+    types: ["constant", "scope_resolution"],
+    interfaces: ["module"],
+  },
+  extractors: {
+    extractName: (nodeType: string) => {
+      switch (nodeType) {
+        case "class":
+        case "module":
+          return ["constant", "scope_resolution"];
+        case "method":
+        case "singleton_method":
+          return ["identifier", "constant", "setter", "operator"];
+        default:
+          return ["identifier", "constant"];
+      }
+    },
+    extractModifiers: (nodeType: string) => {
+      switch (nodeType) {
+        case "method":
+        case "singleton_method":
+          return ["private", "protected", "public", "module_function"];
+        default:
+          return [];
+      }
+    },
+    extractParameters: true,
+    extractReturnType: false,
+    extractReferences: true,
+  },
+};
+
+/**
+ * PHP language configuration
+ */
+const PHP_CONFIG: LanguageConfig = {
+  language: "php",
+  extensions: ["php", "phtml"],
+  keywords: LANGUAGE_KEYWORDS.php,
+  nodeTypes: {
+    functions: ["function_definition", "method_declaration", "anonymous_function", "arrow_function"],
+    classes: ["class_declaration", "interface_declaration", "trait_declaration", "enum_declaration"],
+    methods: ["method_declaration"],
+    imports: ["namespace_use_declaration", "include_expression", "require_once_expression"],
+    exports: [],
+    variables: ["property_declaration", "const_declaration", "assignment_expression"],
+    types: ["named_type", "optional_type", "union_type", "primitive_type"],
+    interfaces: ["interface_declaration"],
+  },
+  extractors: {
+    extractName: (nodeType: string) => {
+      switch (nodeType) {
+        case "class_declaration":
+        case "interface_declaration":
+        case "trait_declaration":
+        case "enum_declaration":
+        case "function_definition":
+        case "method_declaration":
+          return ["name"];
+        default:
+          return ["name", "variable_name"];
+      }
+    },
+    extractModifiers: (nodeType: string) => {
+      switch (nodeType) {
+        case "method_declaration":
+        case "property_declaration":
+          return ["visibility_modifier", "static_modifier", "abstract_modifier", "final_modifier", "readonly_modifier"];
+        case "class_declaration":
+          return ["abstract_modifier", "final_modifier", "readonly_modifier"];
+        default:
+          return [];
+      }
+    },
+    extractParameters: true,
+    extractReturnType: true,
+    extractReferences: true,
+  },
+};
+
+/**
+ * Swift language configuration
+ */
+const SWIFT_CONFIG: LanguageConfig = {
+  language: "swift",
+  extensions: ["swift"],
+  keywords: LANGUAGE_KEYWORDS.swift,
+  nodeTypes: {
+    functions: ["function_declaration", "lambda_literal"],
+    classes: ["class_declaration", "protocol_declaration"],
+    methods: ["function_declaration", "init_declaration", "deinit_declaration", "subscript_declaration"],
+    imports: ["import_declaration"],
+    exports: [], // Swift uses access modifiers, not exports
+    variables: ["property_declaration"],
+    types: ["typealias_declaration", "user_type", "optional_type", "array_type", "dictionary_type"],
+    interfaces: ["protocol_declaration"],
+  },
+  extractors: {
+    extractName: (nodeType: string) => {
+      switch (nodeType) {
+        case "class_declaration":
+        case "protocol_declaration":
+        case "typealias_declaration":
+          return ["type_identifier", "user_type"];
+        case "function_declaration":
+          return ["simple_identifier"];
+        case "property_declaration":
+          return ["pattern", "simple_identifier"];
+        default:
+          return ["simple_identifier", "identifier"];
+      }
+    },
+    extractModifiers: (nodeType: string) => {
+      switch (nodeType) {
+        case "function_declaration":
+        case "init_declaration":
+        case "property_declaration":
+          return [
+            "visibility_modifier",
+            "member_modifier",
+            "property_modifier",
+            "mutation_modifier",
+            "function_modifier",
+            "inheritance_modifier",
+            "ownership_modifier",
+            "property_behavior_modifier",
+          ];
+        case "class_declaration":
+          return ["visibility_modifier", "inheritance_modifier"];
+        default:
+          return ["visibility_modifier"];
+      }
+    },
+    extractParameters: true,
+    extractReturnType: true,
+    extractReferences: true,
+  },
+};
+
+/**
+ * Scala language configuration
+ */
+const SCALA_CONFIG: LanguageConfig = {
+  language: "scala",
+  extensions: ["scala", "sc", "sbt"],
+  keywords: LANGUAGE_KEYWORDS.scala,
+  nodeTypes: {
+    functions: ["function_definition", "function_declaration", "lambda_expression"],
+    classes: ["class_definition", "object_definition", "trait_definition", "enum_definition"],
+    methods: ["function_definition", "function_declaration"],
+    imports: ["import_declaration"],
+    exports: ["export_declaration"],
+    variables: ["val_definition", "val_declaration", "var_definition", "var_declaration", "given_definition"],
+    types: ["type_definition", "type_identifier", "generic_type", "compound_type"],
+    interfaces: ["trait_definition"],
+  },
+  extractors: {
+    extractName: (nodeType: string) => {
+      switch (nodeType) {
+        case "val_definition":
+        case "var_definition":
+          return ["identifier"];
+        default:
+          return ["identifier", "type_identifier"];
+      }
+    },
+    extractModifiers: (nodeType: string) => {
+      switch (nodeType) {
+        case "class_definition":
+        case "object_definition":
+        case "trait_definition":
+        case "function_definition":
+        case "val_definition":
+        case "var_definition":
+          return ["modifiers", "access_modifier", "annotation"];
+        default:
+          return ["modifiers"];
+      }
+    },
+    extractParameters: true,
+    extractReturnType: true,
+    extractReferences: true,
+  },
+};
+
+/**
+ * SQL configuration (lightweight; parsing handled by SqlAnalyzer)
+ */
+const SQL_CONFIG: LanguageConfig = {
+  language: "sql",
+  extensions: ["sql"],
+  keywords: LANGUAGE_KEYWORDS.sql,
+  nodeTypes: {
+    functions: [],
+    classes: [],
+    methods: [],
+    imports: [],
+    exports: [],
+    variables: [],
+    types: [],
+    interfaces: [],
+  },
+  extractors: {
+    extractName: () => ["identifier"],
+    extractReturnType: true,
+    extractReferences: true,
+  },
+};
+
+/**
+ * GraphQL SDL configuration (lightweight; parsing handled by GraphqlAnalyzer)
+ */
+const GRAPHQL_CONFIG: LanguageConfig = {
+  language: "graphql",
+  extensions: ["graphql", "gql", "graphqls"],
+  keywords: LANGUAGE_KEYWORDS.graphql,
+  nodeTypes: {
+    functions: [],
+    classes: [],
+    methods: [],
+    imports: [],
+    exports: [],
+    variables: [],
+    types: [],
+    interfaces: [],
+  },
+  extractors: {
+    extractName: () => ["identifier"],
+    extractReturnType: true,
+    extractReferences: true,
+  },
+};
+
+/**
+ * Protocol Buffers configuration (lightweight; parsing handled by ProtoAnalyzer)
+ */
+const PROTO_CONFIG: LanguageConfig = {
+  language: "proto",
+  extensions: ["proto"],
+  keywords: LANGUAGE_KEYWORDS.proto,
+  nodeTypes: {
+    functions: [],
+    classes: [],
+    methods: [],
+    imports: [],
+    exports: [],
+    variables: [],
+    types: [],
+    interfaces: [],
+  },
+  extractors: {
+    extractName: () => ["identifier"],
+    extractReturnType: true,
+    extractReferences: true,
+  },
+};
+
 /**
  * VBA language configuration
  */
@@ -1344,6 +1699,9 @@ export const LANGUAGE_CONFIGS: Record<SupportedLanguage, LanguageConfig> = {
   jsx: JSX_CONFIG,
   tsx: TSX_CONFIG,
   markdown: MARKDOWN_CONFIG,
+  proto: PROTO_CONFIG,
+  sql: SQL_CONFIG,
+  graphql: GRAPHQL_CONFIG,
   python: PYTHON_CONFIG,
   c: C_CONFIG,
   cpp: CPP_CONFIG,
@@ -1352,6 +1710,10 @@ export const LANGUAGE_CONFIGS: Record<SupportedLanguage, LanguageConfig> = {
   go: GO_CONFIG,
   java: JAVA_CONFIG,
   kotlin: KOTLIN_CONFIG,
+  ruby: RUBY_CONFIG,
+  php: PHP_CONFIG,
+  swift: SWIFT_CONFIG,
+  scala: SCALA_CONFIG,
   vba: VBA_CONFIG,
 };
 
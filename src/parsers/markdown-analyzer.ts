@@ -1,5 +1,6 @@
 import { basename } from "node:path";
 import type { EntityRelationship, ParsedEntity } from "../types/parser.js";
+import { MAX_DOCUMENTATION_LENGTH } from "./doc-comments.js";
 
 function computeLineStartIndices(text: string): number[] {
   const starts = [0];
@@ -28,6 +29,49 @@ function slugify(input: string): string {
     .replace(/^-|-$/g, "");
 }
 
+interface Heading {
+  line: number;
+  level: number;
+  title: string;
+}
+
+// ATX headings outside fenced code blocks; a `# comment` in a shell sample is not a section
+function findHeadings(lines: string[]): Heading[] {
+  const headings: Heading[] = [];
+  let fence: string | null = null;
+  for (let i = 0; i < lines.length; i++) {
+    const line = lines[i] ?? "";
+    const marker = /^\s{0,3}(`{3,}|~{3,})/.exec(line)?.[1];
+    if (marker) {
+      if (!fence) fence = marker;
+      else if (marker[0] === fence[0] && marker.length >= fence.length) fence = null;
+      continue;
+    }
+    if (fence) continue;
+    const match = /^(#{1,6})\s+(.+?)(?:\s+#+)?\s*$/.exec(line);
+    if (match) headings.push({ line: i + 1, level: match[1]!.length, title: match[2]! });
+  }
+  return headings;
+}
+
+/** Lines `from`..`to` (1-based, inclusive) without surrounding blank lines, cut to the stored length */
+function sectionText(lines: string[], from: number, to: number): string {
+  const text = lines.slice(from - 1, to).join("\n").trim();
+  return text.length > MAX_DOCUMENTATION_LENGTH ? text.slice(0, MAX_DOCUMENTATION_LENGTH) : text;
+}
+
+/** Last non-blank line in `from`..`to`, or `from` when all are blank */
+function lastContentLine(lines: string[], from: number, to: number): number {
+  let line = to;
+  while (line > from && !(lines[line - 1] ?? "").trim()) line--;
+  return line;
+}
+
+/**
+ * Markdown files as a `document` entity holding one `heading` entity per section. A section runs
+ * from its heading to the next heading of any level, and its prose is stored as the entity's
+ * documentation, so keyword and semantic search find what the section says and not only its title.
+ */
 export class MarkdownAnalyzer {
   analyze(content: string, filePath: string): { entities: ParsedEntity[]; relationships: EntityRelationship[] } {
     const entities: ParsedEntity[] = [];
@@ -35,8 +79,10 @@ export class MarkdownAnalyzer {
 
     const lines = content.split(/\r?\n/);
     const lineStarts = computeLineStartIndices(content);
+    const headings = findHeadings(lines);
 
     const docId = `${filePath}:document`;
+    const intro = sectionText(lines, 1, (headings[0]?.line ?? lines.length + 1) - 1);
     entities.push({
       id: docId,
       name: basename(filePath),
@@ -46,35 +92,38 @@ export class MarkdownAnalyzer {
         start: { line: 1, column: 0, index: 0 },
         end: { line: Math.max(1, lines.length), column: 0, index: content.length },
       },
-      metadata: { language: "markdown" },
+      documentation: intro || undefined,
+      metadata: { language: "markdown", sections: headings.length },
     } as any);
 
-    const headingStack: Array<{ id: string; level: number }> = [];
+    const headingStack: Array<{ id: string; level: number; title: string }> = [];
 
-    for (let i = 0; i < lines.length; i++) {
-      const lineNum = i + 1;
-      const line = lines[i] ?? "";
-      const match = /^(#{1,6})\s+(.+?)\s*$/.exec(line);
-      if (!match) continue;
-
-      const level = match[1]!.length;
-      const title = match[2]!;
+    headings.forEach(({ line: lineNum, level, title }, i) => {
       const slug = slugify(title) || `heading-${lineNum}`;
       const headingId = `${filePath}:heading:${slug}:${lineNum}`;
+      const nextLine = headings[i + 1]?.line ?? lines.length + 1;
+      const endLine = lastContentLine(lines, lineNum, nextLine - 1);
+      const endText = lines[endLine - 1] ?? "";
+
+      while (headingStack.length && headingStack[headingStack.length - 1]!.level >= level) {
+        headingStack.pop();
+      }
+      const headingPath = [...headingStack.map((h) => h.title), title];
 
+      const body = sectionText(lines, lineNum + 1, endLine);
       entities.push({
         id: headingId,
         name: title,
         type: "heading",
         filePath,
-        location: locationForLine(lineStarts, lineNum, line),
-        metadata: { level, slug, language: "markdown" },
+        location: {
+          start: locationForLine(lineStarts, lineNum, lines[lineNum - 1] ?? "").start,
+          end: locationForLine(lineStarts, endLine, endText).end,
+        },
+        documentation: body || undefined,
+        metadata: { level, slug, headingPath, language: "markdown" },
       } as any);
 
-      while (headingStack.length && headingStack[headingStack.length - 1]!.level >= level) {
-        headingStack.pop();
-      }
-
       const parentId = headingStack.length ? headingStack[headingStack.length - 1]!.id : docId;
       relationships.push({
         from: parentId,
@@ -83,8 +132,8 @@ export class MarkdownAnalyzer {
         metadata: { line: lineNum, confidence: 1, isDirectRelation: true },
       });
 
-      headingStack.push({ id: headingId, level });
-    }
+      headingStack.push({ id: headingId, level, title });
+    });
 
     return { entities, relationships };
   }
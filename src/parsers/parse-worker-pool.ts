@@ -0,0 +1,172 @@
+/**
+ * Parse Worker Pool - parses files on worker threads
+ * Each worker owns its own tree-sitter parser. Files are handed to whichever worker is idle, so one
+ * slow file holds up only its own worker; callers bound memory by how many files they submit.
+ * A worker still busy well past the job's `timeoutMs` is terminated and replaced.
+ */
+
+import { existsSync } from "node:fs";
+import { availableParallelism } from "node:os";
+import { fileURLToPath } from "node:url";
+import { Worker } from "node:worker_threads";
+import { ParseTimeoutError } from "../types/errors.js";
+import type { ParseResult, ParserOptions } from "../types/parser.js";
+import { cancellationError } from "../utils/cancellation.js";
+import { DEFAULT_PARSE_TIMEOUT_MS } from "./incremental-parser.js";
+
+// The worker reports its own timeouts; the pool steps in only when the worker cannot (a wedged thread)
+const TIMEOUT_GRACE_MS = 1000;
+
+export interface ParseWorkerRequest {
+  id: number;
+  filePath: string;
+  options: ParserOptions;
+}
+
+export interface ParseWorkerResponse {
+  id: number;
+  result?: ParseResult;
+  error?: string;
+}
+
+interface ParseJob {
+  request: ParseWorkerRequest;
+  resolve: (result: ParseResult) => void;
+  reject: (error: Error) => void;
+  signal?: AbortSignal;
+  timer?: NodeJS.Timeout;
+}
+
+/**
+ * Worker count for a configured size: positive values are used as given, anything else means one
+ * worker per available CPU.
+ */
+export function resolveWorkerPoolSize(configured?: number): number {
+  if (typeof configured === "number" && Number.isFinite(configured) && configured > 0) {
+    return Math.floor(configured);
+  }
+  return Math.max(1, availableParallelism());
+}
+
+/**
+ * Location of the compiled worker entry. It sits next to this module in both the bundle and the
+ * tsc output; running from TypeScript sources there is none, and parsing stays on the main thread.
+ */
+export function resolveParseWorkerScript(): URL | null {
+  const url = new URL("./parse-worker.js", import.meta.url);
+  return url.protocol === "file:" && existsSync(fileURLToPath(url)) ? url : null;
+}
+
+export class ParseWorkerPool {
+  private readonly workers: Worker[] = [];
+  private readonly idle: Worker[] = [];
+  private readonly running = new Map<Worker, ParseJob>();
+  private readonly queue: ParseJob[] = [];
+  private nextId = 0;
+
+  constructor(
+    size: number,
+    private readonly script: URL,
+  ) {
+    for (let i = 0; i < size; i++) this.spawn();
+  }
+
+  get size(): number {
+    return this.workers.length;
+  }
+
+  /** Parse on the next idle worker; a job whose `signal` is aborted before it is handed out is rejected */
+  parse(filePath: string, options: ParserOptions = {}, signal?: AbortSignal): Promise<ParseResult> {
+    if (this.workers.length === 0) return Promise.reject(new Error("Parse worker pool has no workers"));
+    return new Promise((resolve, reject) => {
+      this.queue.push({ request: { id: this.nextId++, filePath, options }, resolve, reject, signal });
+      this.dispatch();
+    });
+  }
+
+  async terminate(): Promise<void> {
+    const error = new Error("Parse worker pool terminated");
+    for (const job of this.queue.splice(0)) job.reject(error);
+    for (const job of this.running.values()) {
+      clearTimeout(job.timer);
+      job.reject(error);
+    }
+    this.running.clear();
+    const workers = this.workers.splice(0);
+    this.idle.length = 0;
+    await Promise.all(workers.map((worker) => worker.terminate()));
+  }
+
+  private spawn(): void {
+    // Worker stdout would otherwise land on the process stdout, which belongs to the MCP transport
+    const worker = new Worker(this.script, { stdout: true });
+    worker.stdout.pipe(process.stderr);
+    worker.unref();
+
+    worker.on("message", (response: ParseWorkerResponse) => {
+      const job = this.running.get(worker);
+      this.running.delete(worker);
+      this.idle.push(worker);
+      if (job) {
+        clearTimeout(job.timer);
+        if (response.result) job.resolve(response.result);
+        else job.reject(new Error(response.error ?? `No result for ${job.request.filePath}`));
+      }
+      this.dispatch();
+    });
+    worker.on("error", (error) => this.retire(worker, error));
+    worker.on("exit", (code) => this.retire(worker, new Error(`Parse worker exited with code ${code}`)));
+
+    this.workers.push(worker);
+    this.idle.push(worker);
+  }
+
+  /** Drop a dead worker; its job fails, and queued jobs fail too once no worker is left */
+  private retire(worker: Worker, error: Error): void {
+    const index = this.workers.indexOf(worker);
+    if (index === -1) return;
+    this.workers.splice(index, 1);
+    const idleIndex = this.idle.indexOf(worker);
+    if (idleIndex !== -1) this.idle.splice(idleIndex, 1);
+
+    const job = this.running.get(worker);
+    this.running.delete(worker);
+    if (job) {
+      clearTimeout(job.timer);
+      job.reject(error);
+    }
+    if (this.workers.length === 0) {
+      for (const job of this.queue.splice(0)) job.reject(error);
+    }
+  }
+
+  /** Replace a worker stuck on one file; terminating it means its exit no longer finds it in the pool */
+  private expire(worker: Worker, job: ParseJob, timeoutMs: number): void {
+    if (this.running.get(worker) !== job) return;
+    const index = this.workers.indexOf(worker);
+    if (index !== -1) this.workers.splice(index, 1);
+    this.running.delete(worker);
+    void worker.terminate();
+    this.spawn();
+    job.reject(new ParseTimeoutError({ filePath: job.request.filePath, timeoutMs }));
+    this.dispatch();
+  }
+
+  private dispatch(): void {
+    while (this.idle.length > 0 && this.queue.length > 0) {
+      const job = this.queue.shift()!;
+      if (job.signal?.aborted) {
+        job.reject(cancellationError(job.signal));
+        continue;
+      }
+      const worker = this.idle.pop()!;
+      this.running.set(worker, job);
+      const timeoutMs = job.request.options.timeoutMs ?? DEFAULT_PARSE_TIMEOUT_MS;
+      if (timeoutMs > 0) {
+        job.timer = setTimeout(() => this.expire(worker, job, timeoutMs), timeoutMs + TIMEOUT_GRACE_MS);
+        job.timer.unref();
+      }
+      worker.postMessage(job.request);
+    }
+  }
+}
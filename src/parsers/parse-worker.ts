@@ -0,0 +1,29 @@
+/**
+ * Parse Worker - worker-thread entry for ParseWorkerPool
+ * Owns one IncrementalParser and answers one file per message.
+ */
+
+import { parentPort } from "node:worker_threads";
+import { IncrementalParser } from "./incremental-parser.js";
+import type { ParseWorkerRequest, ParseWorkerResponse } from "./parse-worker-pool.js";
+
+const parser = new IncrementalParser();
+const ready = parser.initialize();
+
+parentPort?.on("message", async (request: ParseWorkerRequest) => {
+  let response: ParseWorkerResponse;
+  try {
+    await ready;
+    const result = await parser.parseFile(request.filePath, undefined, request.options);
+    response = { id: request.id, result };
+  } catch (error) {
+    response = { id: request.id, error: error instanceof Error ? error.message : String(error) };
+  }
+
+  try {
+    parentPort?.postMessage(response);
+  } catch (error) {
+    // Results that cannot be cloned are re-parsed on the main thread by the pool's caller
+    parentPort?.postMessage({ id: request.id, error: `Unclonable parse result: ${(error as Error).message}` });
+  }
+});
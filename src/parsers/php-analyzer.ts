@@ -0,0 +1,779 @@
+/**
+ * PHP Language Analyzer
+ *
+ * Declarations:
+ * - Namespaces (braced and statement form) and `use` imports, including grouped,
+ *   `use function` and `use const` imports
+ * - Classes, interfaces, traits and enums, qualified by namespace (`App\Models\User`)
+ * - Functions, methods, properties (promoted constructor parameters included), class constants
+ *   and enum cases
+ * - PHP 8 attributes, kept as decorators
+ *
+ * Relationships:
+ * - Inheritance (extends), interface implementation (implements) and trait use (embeds)
+ * - Imports from the namespace to the imported name
+ * - Attribute usage (decorated_by) and instantiation with `new` (references)
+ * - Function and method calls; `$this->`, `self::`, `static::`, `parent::`, `Class::` and calls on
+ *   typed properties are bound to methods of the same file where declared there
+ *
+ * Names are resolved the way PHP does at compile time, so edges to declarations in other files
+ * carry the fully qualified name; the PHP namespace resolver binds them once the project is
+ * indexed. Files mixing HTML with several `<?php` blocks parse as a whole: the HTML is text
+ * between the blocks.
+ */
+
+import type { EntityRelationship, ParsedEntity, TreeSitterNode } from "../types/parser.js";
+
+const MAX_RECURSION_DEPTH = 50;
+const PARSE_TIMEOUT_MS = 5000;
+
+class CircuitBreakerError extends Error {
+  constructor(message: string) {
+    super(message);
+    this.name = "CircuitBreakerError";
+  }
+}
+
+type Decorator = NonNullable<ParsedEntity["decorators"]>[number];
+
+/** Imports of one namespace, by lowercased alias */
+export interface PhpImports {
+  classes: Record<string, string>;
+  functions: Record<string, string>;
+}
+
+const TYPE_DECLARATIONS: Record<string, "class" | "interface" | "trait" | "enum"> = {
+  class_declaration: "class",
+  interface_declaration: "interface",
+  trait_declaration: "trait",
+  enum_declaration: "enum",
+};
+
+const CALLS = new Set([
+  "function_call_expression",
+  "member_call_expression",
+  "nullsafe_member_call_expression",
+  "scoped_call_expression",
+]);
+
+/** Type names that never name a class */
+const BUILTIN_TYPES = new Set([
+  "array",
+  "bool",
+  "callable",
+  "false",
+  "float",
+  "int",
+  "iterable",
+  "mixed",
+  "never",
+  "null",
+  "object",
+  "string",
+  "true",
+  "void",
+]);
+
+/**
+ * Fully qualified name of a class named `written` in `namespace`: `\A\B` as is, `namespace\B`
+ * in the current namespace, a first segment imported by `use` replaced by its target, anything
+ * else prefixed with the namespace. `self`, `static` and `parent` are left to the caller.
+ */
+export function resolvePhpName(written: string, namespace: string, imports: Record<string, string>): string {
+  if (written.startsWith("\\")) return written.slice(1);
+  const inNamespace = (name: string) => (namespace ? `${namespace}\\${name}` : name);
+  if (/^namespace\\/i.test(written)) return inNamespace(written.slice("namespace\\".length));
+  const slash = written.indexOf("\\");
+  const first = slash < 0 ? written : written.slice(0, slash);
+  const imported = imports[first.toLowerCase()];
+  if (imported) return slash < 0 ? imported : `${imported}${written.slice(slash)}`;
+  return inNamespace(written);
+}
+
+/**
+ * Functions an unqualified call `written` may name, in lookup order: the imported one, else the one
+ * in `namespace` and then the global one. Qualified calls name exactly one.
+ */
+export function phpFunctionCandidates(written: string, namespace: string, imports: PhpImports): string[] {
+  if (written.startsWith("\\")) return [written.slice(1)];
+  if (written.includes("\\")) return [resolvePhpName(written, namespace, imports.classes)];
+  const imported = imports.functions[written.toLowerCase()];
+  if (imported) return [imported];
+  return namespace ? [`${namespace}\\${written}`, written] : [written];
+}
+
+/** The class a type declaration names (`?User`, `\App\User`), or undefined for scalars and unions */
+export function phpClassType(type: string | undefined): string | undefined {
+  const name = type?.trim().replace(/^\?/, "");
+  if (!name || /[|&()]/.test(name) || BUILTIN_TYPES.has(name.toLowerCase())) return undefined;
+  return name;
+}
+
+/** A class or interface, trait or enum whose body is being walked */
+interface TypeScope {
+  id: string;
+  qualifiedName: string;
+  /** Fully qualified parent class, for `parent::` */
+  parent?: string;
+}
+
+/**
+ * A `type` named by its fully qualified class name, a method `call` on an instance of class `owner`
+ * (looked up through its parents and traits), or a `function` call tried against the namespaced
+ * name before the global one
+ */
+type PendingTarget =
+  | { kind: "type"; relationship: EntityRelationship; qualifiedName: string }
+  | { kind: "call"; relationship: EntityRelationship; name: string; owner?: string }
+  | { kind: "function"; relationship: EntityRelationship; candidates: string[] };
+
+export class PhpAnalyzer {
+  private recursionDepth = 0;
+  private parseStartTime = 0;
+  private namespace = "";
+  private imports: PhpImports = { classes: {}, functions: {} };
+  private namespaceId: string | null = null;
+  /** Types declared in this file by lowercased qualified name */
+  private declaredTypes = new Map<string, { id: string; entity: ParsedEntity }>();
+  /** Methods by lowercased `Type::method`, functions by lowercased qualified name */
+  private declaredMethods = new Map<string, string>();
+  private declaredFunctions = new Map<string, string>();
+  /** Class of each typed property by lowercased `Type::property` */
+  private propertyTypes = new Map<string, string>();
+  private pending: PendingTarget[] = [];
+
+  async analyze(
+    rootNode: TreeSitterNode,
+    filePath: string,
+  ): Promise<{ entities: ParsedEntity[]; relationships: EntityRelationship[] }> {
+    this.resetState();
+
+    const entities: ParsedEntity[] = [];
+    const relationships: EntityRelationship[] = [];
+
+    try {
+      this.visit(rootNode, filePath, entities, relationships);
+      this.bindLocalTargets();
+    } catch (error) {
+      if (error instanceof CircuitBreakerError) {
+        console.warn(`[PhpAnalyzer] Circuit breaker triggered for ${filePath}: ${error.message}`);
+      } else {
+        console.error(`[PhpAnalyzer] Error analyzing ${filePath}:`, error);
+      }
+      // Return partial results on error
+    }
+
+    return { entities, relationships };
+  }
+
+  private resetState(): void {
+    this.recursionDepth = 0;
+    this.parseStartTime = Date.now();
+    this.namespace = "";
+    this.imports = { classes: {}, functions: {} };
+    this.namespaceId = null;
+    this.declaredTypes.clear();
+    this.declaredMethods.clear();
+    this.declaredFunctions.clear();
+    this.propertyTypes.clear();
+    this.pending = [];
+  }
+
+  private checkCircuitBreakers(): void {
+    if (this.recursionDepth > MAX_RECURSION_DEPTH) {
+      throw new CircuitBreakerError(`Maximum recursion depth ${MAX_RECURSION_DEPTH} exceeded`);
+    }
+    if (Date.now() - this.parseStartTime > PARSE_TIMEOUT_MS) {
+      throw new CircuitBreakerError(`Parse timeout ${PARSE_TIMEOUT_MS}ms exceeded`);
+    }
+  }
+
+  private getNodeLocation(node: TreeSitterNode) {
+    return {
+      start: { line: node.startPosition.row + 1, column: node.startPosition.column, index: node.startIndex },
+      end: { line: node.endPosition.row + 1, column: node.endPosition.column, index: node.endIndex },
+    };
+  }
+
+  private qualify(name: string): string {
+    return this.namespace ? `${this.namespace}\\${name}` : name;
+  }
+
+  /** Fully qualified name of a class written in the current namespace, `self`/`parent` included */
+  private className(written: string, scope: TypeScope | null): string | undefined {
+    const lower = written.toLowerCase();
+    if (lower === "self" || lower === "static") return scope?.qualifiedName;
+    if (lower === "parent") return scope?.parent;
+    return resolvePhpName(written, this.namespace, this.imports.classes);
+  }
+
+  /** Namespace and imports in effect, stored on top-level declarations for the namespace resolver */
+  private nameContext(): Record<string, unknown> {
+    return { namespace: this.namespace, imports: structuredClone(this.imports) };
+  }
+
+  /** Statements at file or namespace level; declarations inside conditionals still count */
+  private visit(
+    node: TreeSitterNode,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+  ): void {
+    this.recursionDepth++;
+    this.checkCircuitBreakers();
+
+    try {
+      for (const child of node.namedChildren) {
+        const kind = TYPE_DECLARATIONS[child.type];
+        if (kind) {
+          this.extractType(child, kind, filePath, entities, relationships);
+          continue;
+        }
+        switch (child.type) {
+          case "namespace_definition":
+            this.extractNamespace(child, filePath, entities, relationships);
+            break;
+          case "namespace_use_declaration":
+            this.extractUse(child, filePath, entities, relationships);
+            break;
+          case "function_definition":
+            this.extractFunction(child, filePath, entities, relationships);
+            break;
+          case "comment":
+          case "text":
+          case "php_tag":
+            break;
+          default:
+            this.visit(child, filePath, entities, relationships);
+        }
+      }
+    } finally {
+      this.recursionDepth--;
+    }
+  }
+
+  /**
+   * `namespace A\B { ... }` scopes its body; `namespace A\B;` applies to the statements after it
+   * up to the next namespace declaration. Imports never carry over from one namespace to another.
+   */
+  private extractNamespace(
+    node: TreeSitterNode,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+  ): void {
+    this.namespace = node.childForFieldName("name")?.text ?? "";
+    this.imports = { classes: {}, functions: {} };
+    this.namespaceId = this.ensureNamespaceEntity(node, filePath, entities);
+
+    const body = node.childForFieldName("body");
+    if (body) {
+      this.visit(body, filePath, entities, relationships);
+      this.namespace = "";
+      this.imports = { classes: {}, functions: {} };
+      this.namespaceId = null;
+    }
+  }
+
+  private ensureNamespaceEntity(node: TreeSitterNode | null, filePath: string, entities: ParsedEntity[]): string {
+    const name = this.namespace || "(global)";
+    const id = `${filePath}:namespace:${name}`;
+    if (!entities.some((e) => e.id === id)) {
+      entities.push({
+        id,
+        name,
+        type: "module",
+        filePath,
+        location: node
+          ? this.getNodeLocation(node)
+          : { start: { line: 1, column: 0, index: 0 }, end: { line: 1, column: 0, index: 0 } },
+        metadata: { isNamespace: true, qualifiedName: this.namespace },
+      });
+    }
+    return id;
+  }
+
+  /** `use A\B;`, `use A\{B, C as D};`, `use function A\f;` and `use const A\X;` */
+  private extractUse(
+    node: TreeSitterNode,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+  ): void {
+    const kindOf = (n: TreeSitterNode) => n.children.find((c) => c.type === "function" || c.type === "const")?.type;
+    const declarationKind = kindOf(node);
+    const prefix = node.namedChildren.find((c) => c.type === "namespace_name")?.text;
+    const group = node.namedChildren.find((c) => c.type === "namespace_use_group");
+    const clauses = (group ?? node).namedChildren.filter(
+      (c) => c.type === "namespace_use_clause" || c.type === "namespace_use_group_clause",
+    );
+    this.namespaceId ??= this.ensureNamespaceEntity(null, filePath, entities);
+
+    for (const clause of clauses) {
+      const nameNode = clause.namedChildren.find(
+        (c) => c.type === "qualified_name" || c.type === "name" || c.type === "namespace_name",
+      );
+      if (!nameNode) continue;
+      const written = nameNode.text.replace(/^\\/, "");
+      const target = prefix ? `${prefix.replace(/^\\/, "")}\\${written}` : written;
+      const aliasNode =
+        clause.childForFieldName("alias") ??
+        clause.namedChildren.find((c) => c.type === "namespace_aliasing_clause")?.namedChildren[0];
+      const alias = aliasNode?.text ?? target.slice(target.lastIndexOf("\\") + 1);
+      const kind = kindOf(clause) ?? declarationKind ?? "class";
+
+      if (kind === "class") this.imports.classes[alias.toLowerCase()] = target;
+      else if (kind === "function") this.imports.functions[alias.toLowerCase()] = target;
+
+      relationships.push({
+        from: this.namespaceId,
+        to: target,
+        type: "imports",
+        metadata: {
+          line: clause.startPosition.row + 1,
+          importKind: kind,
+          ...(aliasNode ? { alias } : {}),
+        },
+      });
+      if (kind === "class") {
+        this.pending.push({ kind: "type", relationship: relationships.at(-1)!, qualifiedName: target });
+      }
+    }
+  }
+
+  private extractType(
+    node: TreeSitterNode,
+    kind: "class" | "interface" | "trait" | "enum",
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+  ): void {
+    const name = node.childForFieldName("name")?.text;
+    if (!name) return;
+    const qualifiedName = this.qualify(name);
+    const id = `${filePath}:${kind}:${name}`;
+    const modifiers = node.namedChildren
+      .filter((c) => c.type === "abstract_modifier" || c.type === "final_modifier" || c.type === "readonly_modifier")
+      .map((c) => c.text);
+
+    const bases = this.typeNames(node.namedChildren.find((c) => c.type === "base_clause"));
+    const interfaceNames = this.typeNames(node.namedChildren.find((c) => c.type === "class_interface_clause"));
+    // An interface's `extends` list names interfaces; a class has at most one parent class
+    const superclass = kind === "class" ? bases[0] : undefined;
+    const extended = kind === "interface" ? bases : superclass ? [superclass] : [];
+    const scope: TypeScope = { id, qualifiedName, parent: superclass ? this.className(superclass, null) : undefined };
+    const traits: string[] = [];
+
+    const decorators = this.extractDecorators(node);
+    const entity: ParsedEntity = {
+      id,
+      name,
+      type: kind,
+      filePath,
+      location: this.getNodeLocation(node),
+      modifiers,
+      ...(decorators.length ? { decorators } : {}),
+      ...(extended.length || interfaceNames.length
+        ? {
+            inheritance: {
+              baseClasses: extended,
+              ...(interfaceNames.length ? { interfaces: interfaceNames } : {}),
+            },
+          }
+        : {}),
+      metadata: {
+        qualifiedName,
+        ...this.nameContext(),
+        ...(scope.parent ? { superclass: scope.parent } : {}),
+        interfaces: interfaceNames.map((n) => this.className(n, null)!),
+        traits,
+        isAbstract: modifiers.includes("abstract"),
+        isFinal: modifiers.includes("final"),
+      },
+    };
+    entities.push(entity);
+    this.declaredTypes.set(qualifiedName.toLowerCase(), { id, entity });
+
+    for (const base of extended) this.pushTypeEdge(relationships, id, base, "extends", node, null);
+    for (const base of interfaceNames) this.pushTypeEdge(relationships, id, base, "implements", node, null);
+    this.linkDecorators(id, decorators, relationships);
+
+    const body = node.childForFieldName("body");
+    for (const member of body?.namedChildren ?? []) {
+      switch (member.type) {
+        case "method_declaration":
+          this.extractMethod(member, scope, filePath, entities, relationships);
+          break;
+        case "property_declaration":
+          this.extractProperties(member, scope, filePath, entities, relationships);
+          break;
+        case "const_declaration":
+          for (const element of member.namedChildren.filter((c) => c.type === "const_element")) {
+            const constName = element.namedChildren.find((c) => c.type === "name")?.text;
+            if (constName) this.pushMember(element, scope, "constant", constName, filePath, entities, relationships);
+          }
+          break;
+        case "enum_case": {
+          const caseName = member.childForFieldName("name")?.text;
+          if (caseName) this.pushMember(member, scope, "constant", caseName, filePath, entities, relationships);
+          break;
+        }
+        case "use_declaration":
+          for (const trait of member.namedChildren.filter((c) => c.type === "name" || c.type === "qualified_name")) {
+            traits.push(this.className(trait.text, scope)!);
+            this.pushTypeEdge(relationships, id, trait.text, "embeds", trait, scope, { trait: true });
+          }
+          break;
+      }
+    }
+  }
+
+  private extractFunction(
+    node: TreeSitterNode,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+  ): void {
+    const name = node.childForFieldName("name")?.text;
+    if (!name) return;
+    const qualifiedName = this.qualify(name);
+    const id = `${filePath}:function:${name}`;
+    const decorators = this.extractDecorators(node);
+
+    entities.push({
+      id,
+      name,
+      type: "function",
+      filePath,
+      location: this.getNodeLocation(node),
+      parameters: this.extractParameters(node.childForFieldName("parameters")),
+      returnType: node.childForFieldName("return_type")?.text,
+      ...(decorators.length ? { decorators } : {}),
+      metadata: { qualifiedName, ...this.nameContext() },
+    });
+    if (!this.declaredFunctions.has(qualifiedName.toLowerCase())) {
+      this.declaredFunctions.set(qualifiedName.toLowerCase(), id);
+    }
+    this.linkDecorators(id, decorators, relationships);
+
+    const body = node.childForFieldName("body");
+    if (body) this.extractCalls(body, id, null, relationships);
+  }
+
+  private extractMethod(
+    node: TreeSitterNode,
+    scope: TypeScope,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+  ): void {
+    const name = node.childForFieldName("name")?.text;
+    if (!name) return;
+    const id = `${scope.id}:method:${name}`;
+    const modifiers = this.extractModifiers(node);
+    const parametersNode = node.childForFieldName("parameters");
+    const decorators = this.extractDecorators(node);
+
+    entities.push({
+      id,
+      name,
+      type: "method",
+      filePath,
+      location: this.getNodeLocation(node),
+      modifiers,
+      parameters: this.extractParameters(parametersNode),
+      returnType: node.childForFieldName("return_type")?.text,
+      ...(decorators.length ? { decorators } : {}),
+      metadata: {
+        qualifiedName: `${scope.qualifiedName}::${name}`,
+        className: scope.qualifiedName,
+        isStatic: modifiers.includes("static"),
+        isAbstract: modifiers.includes("abstract"),
+        isConstructor: name.toLowerCase() === "__construct",
+      },
+    });
+    const key = `${scope.qualifiedName}::${name}`.toLowerCase();
+    if (!this.declaredMethods.has(key)) this.declaredMethods.set(key, id);
+    relationships.push({ from: id, to: scope.id, type: "contains", metadata: { memberType: "method" } });
+    this.linkDecorators(id, decorators, relationships);
+
+    // `public function __construct(private Repo $repo)` declares the property `repo`
+    for (const param of parametersNode?.namedChildren ?? []) {
+      if (param.type !== "property_promotion_parameter") continue;
+      const propertyName = param.childForFieldName("name")?.text.replace(/^\$/, "");
+      if (!propertyName) continue;
+      this.pushMember(param, scope, "property", propertyName, filePath, entities, relationships, {
+        propertyType: this.propertyType(param.childForFieldName("type")?.text, scope),
+        modifiers: this.extractModifiers(param),
+        isPromoted: true,
+      });
+    }
+
+    const body = node.childForFieldName("body");
+    if (body) this.extractCalls(body, id, scope, relationships);
+  }
+
+  private extractProperties(
+    node: TreeSitterNode,
+    scope: TypeScope,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+  ): void {
+    const modifiers = this.extractModifiers(node);
+    const propertyType = this.propertyType(node.childForFieldName("type")?.text, scope);
+    for (const element of node.namedChildren.filter((c) => c.type === "property_element")) {
+      const variable =
+        element.childForFieldName("name") ?? element.namedChildren.find((c) => c.type === "variable_name");
+      const name = variable?.text.replace(/^\$/, "");
+      if (!name) continue;
+      this.pushMember(element, scope, "property", name, filePath, entities, relationships, {
+        propertyType,
+        modifiers,
+        isStatic: modifiers.includes("static"),
+      });
+    }
+  }
+
+  /** Fully qualified class of a property type, recorded so calls through the property can be bound */
+  private propertyType(type: string | undefined, scope: TypeScope): string | undefined {
+    const written = phpClassType(type);
+    return written ? this.className(written, scope) : undefined;
+  }
+
+  private pushMember(
+    node: TreeSitterNode,
+    scope: TypeScope,
+    type: "property" | "constant",
+    name: string,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+    metadata: Record<string, unknown> = {},
+  ): void {
+    const id = `${scope.id}:${type}:${name}`;
+    const { modifiers, ...rest } = metadata as { modifiers?: string[] } & Record<string, unknown>;
+    entities.push({
+      id,
+      name,
+      type,
+      filePath,
+      location: this.getNodeLocation(node),
+      ...(modifiers ? { modifiers } : {}),
+      metadata: { qualifiedName: `${scope.qualifiedName}::${name}`, className: scope.qualifiedName, ...rest },
+    });
+    if (type === "property" && typeof rest.propertyType === "string") {
+      this.propertyTypes.set(`${scope.qualifiedName}::${name}`.toLowerCase(), rest.propertyType);
+    }
+    relationships.push({ from: id, to: scope.id, type: "contains", metadata: { memberType: type } });
+  }
+
+  /** Calls and instantiations in a body, closures included, nested declarations excluded */
+  private extractCalls(
+    body: TreeSitterNode,
+    callerId: string,
+    scope: TypeScope | null,
+    relationships: EntityRelationship[],
+  ): void {
+    const stack = [...body.namedChildren].reverse();
+    while (stack.length > 0) {
+      const node = stack.pop()!;
+      if (TYPE_DECLARATIONS[node.type] || node.type === "function_definition") continue;
+      stack.push(...[...node.namedChildren].reverse());
+
+      if (node.type === "object_creation_expression") {
+        const created = node.namedChildren.find((c) => c.type === "name" || c.type === "qualified_name");
+        if (created) this.pushTypeEdge(relationships, callerId, created.text, "references", created, scope);
+        continue;
+      }
+      if (!CALLS.has(node.type)) continue;
+
+      const nameNode = node.childForFieldName(node.type === "function_call_expression" ? "function" : "name");
+      if (!nameNode || (nameNode.type !== "name" && nameNode.type !== "qualified_name")) continue;
+      const written = nameNode.text;
+      const name = written.slice(written.lastIndexOf("\\") + 1);
+
+      let callee = written;
+      let receiverType: string | undefined;
+      if (node.type === "scoped_call_expression") {
+        const target = node.childForFieldName("scope")?.text ?? "";
+        callee = `${target}::${name}`;
+        receiverType = this.className(target, scope);
+      } else if (node.type !== "function_call_expression") {
+        const object = node.childForFieldName("object")?.text ?? "";
+        callee = `${object}${node.type === "nullsafe_member_call_expression" ? "?->" : "->"}${name}`;
+        receiverType = this.receiverType(object, scope);
+      }
+
+      const relationship: EntityRelationship = {
+        from: callerId,
+        to: name,
+        type: "calls",
+        metadata: {
+          line: nameNode.startPosition.row + 1,
+          column: nameNode.startPosition.column,
+          callee,
+          calleeName: name,
+          ...(receiverType ? { receiverType } : {}),
+        },
+      };
+      relationships.push(relationship);
+      if (node.type === "function_call_expression") {
+        const candidates = phpFunctionCandidates(written, this.namespace, this.imports);
+        this.pending.push({ kind: "function", relationship, candidates });
+      } else {
+        this.pending.push({ kind: "call", relationship, name, owner: receiverType });
+      }
+    }
+  }
+
+  /** Class of `$this` or of a typed property read through `$this` (`$this->repo`) */
+  private receiverType(object: string, scope: TypeScope | null): string | undefined {
+    if (!scope) return undefined;
+    if (object === "$this") return scope.qualifiedName;
+    const property = /^\$this(?:\?)?->(\w+)$/.exec(object)?.[1];
+    return property ? this.propertyTypes.get(`${scope.qualifiedName}::${property}`.toLowerCase()) : undefined;
+  }
+
+  /** Edge to a class named in source, carrying its fully qualified name until bound */
+  private pushTypeEdge(
+    relationships: EntityRelationship[],
+    from: string,
+    written: string,
+    type: EntityRelationship["type"],
+    node: TreeSitterNode,
+    scope: TypeScope | null,
+    metadata: Record<string, unknown> = {},
+  ): void {
+    const qualifiedName = this.className(written, scope);
+    if (!qualifiedName) return;
+    const relationship: EntityRelationship = {
+      from,
+      to: qualifiedName,
+      type,
+      metadata: { line: node.startPosition.row + 1, ...metadata },
+    };
+    relationships.push(relationship);
+    this.pending.push({ kind: "type", relationship, qualifiedName });
+  }
+
+  /**
+   * Point type, call and function edges at declarations of this file. Methods are looked up on
+   * the receiver's class, its parent classes and the traits they use, as far as they are
+   * declared here; the rest keep fully qualified names for the namespace resolver.
+   */
+  private bindLocalTargets(): void {
+    for (const item of this.pending) {
+      const rel = item.relationship;
+      if (item.kind === "type") {
+        const local = this.declaredTypes.get(item.qualifiedName.toLowerCase());
+        if (local) rel.to = local.id;
+      } else if (item.kind === "function") {
+        const local = item.candidates.map((c) => this.declaredFunctions.get(c.toLowerCase())).find(Boolean);
+        if (local) rel.to = local;
+      } else if (item.owner) {
+        const local = this.findMethod(item.owner, item.name);
+        if (local) rel.to = local;
+      }
+    }
+  }
+
+  private findMethod(owner: string, name: string): string | undefined {
+    const seen = new Set<string>();
+    const queue = [owner];
+    while (queue.length > 0) {
+      const type = queue.shift()!;
+      if (seen.has(type.toLowerCase())) continue;
+      seen.add(type.toLowerCase());
+      const found = this.declaredMethods.get(`${type}::${name}`.toLowerCase());
+      if (found) return found;
+      const declared = this.declaredTypes.get(type.toLowerCase())?.entity.metadata;
+      if (!declared) continue;
+      queue.push(...(declared.traits as string[]));
+      if (declared.superclass) queue.push(declared.superclass);
+    }
+    return undefined;
+  }
+
+  /** `decorated_by` edges from a declaration to the classes of its attributes */
+  private linkDecorators(from: string, decorators: Decorator[], relationships: EntityRelationship[]): void {
+    for (const decorator of decorators) {
+      const qualifiedName = this.className(decorator.name, null);
+      if (!qualifiedName) continue;
+      const relationship: EntityRelationship = {
+        from,
+        to: qualifiedName,
+        type: "decorated_by",
+        metadata: {
+          line: decorator.line,
+          decorator: decorator.name,
+          arguments: decorator.arguments,
+          raw: decorator.raw,
+        },
+      };
+      relationships.push(relationship);
+      this.pending.push({ kind: "type", relationship, qualifiedName });
+    }
+  }
+
+  /** `#[Route('/users', methods: ['GET'])]` groups attached to a declaration */
+  private extractDecorators(node: TreeSitterNode): Decorator[] {
+    const lists = node.namedChildren.filter((c) => c.type === "attribute_list");
+    const decorators: Decorator[] = [];
+    for (const attribute of lists.flatMap((list) => this.descendants(list, "attribute"))) {
+      const name = attribute.namedChildren.find((c) => c.type === "name" || c.type === "qualified_name")?.text;
+      if (!name) continue;
+      const args =
+        attribute.childForFieldName("parameters") ?? attribute.namedChildren.find((c) => c.type === "arguments");
+      decorators.push({
+        name,
+        arguments: args ? args.namedChildren.filter((a) => a.type !== "comment").map((a) => a.text) : undefined,
+        raw: attribute.text,
+        line: attribute.startPosition.row + 1,
+      });
+    }
+    return decorators;
+  }
+
+  private descendants(node: TreeSitterNode, type: string): TreeSitterNode[] {
+    return node.namedChildren.flatMap((c) => (c.type === type ? [c] : this.descendants(c, type)));
+  }
+
+  /** Base names listed by `extends`/`implements`, as written */
+  private typeNames(clause: TreeSitterNode | undefined): string[] {
+    return (clause?.namedChildren ?? [])
+      .filter((c) => c.type === "name" || c.type === "qualified_name")
+      .map((c) => c.text);
+  }
+
+  private extractModifiers(node: TreeSitterNode): string[] {
+    const modifiers = node.namedChildren.filter((c) =>
+      [
+        "visibility_modifier",
+        "static_modifier",
+        "abstract_modifier",
+        "final_modifier",
+        "readonly_modifier",
+        "var_modifier",
+      ].includes(c.type),
+    );
+    return modifiers.map((c) => (c.type === "var_modifier" ? "public" : c.text.toLowerCase()));
+  }
+
+  private extractParameters(node: TreeSitterNode | null): NonNullable<ParsedEntity["parameters"]> {
+    const parameters: NonNullable<ParsedEntity["parameters"]> = [];
+    for (const param of node?.namedChildren ?? []) {
+      if (!param.type.endsWith("_parameter")) continue;
+      const name = param.childForFieldName("name")?.text.replace(/^&?\$/, "");
+      if (!name) continue;
+      const defaultValue = param.childForFieldName("default_value")?.text;
+      parameters.push({
+        name: param.type === "variadic_parameter" ? `...${name}` : name,
+        type: param.childForFieldName("type")?.text,
+        ...(defaultValue !== undefined || param.type === "variadic_parameter" ? { optional: true } : {}),
+        ...(defaultValue !== undefined ? { defaultValue } : {}),
+      });
+    }
+    return parameters;
+  }
+}
@@ -0,0 +1,579 @@
+/**
+ * Protobuf Analyzer - `.proto` schemas as part of the code graph
+ * Read by a small hand-written parser: the schema language is a few regular statements, so no
+ * grammar is loaded for it. Each file becomes a `module` entity holding its messages (`struct`),
+ * their fields (`field`), enums and their values (`enum`, `enum_variant`), services (`interface`)
+ * and RPC methods (`method`). Every RPC is also a `route` named `RPC /package.Service/Method`,
+ * handled by its declaration, so list_routes shows the gRPC surface next to HTTP endpoints.
+ *
+ * Field and RPC types declared in the same file are bound here; imports and types from other
+ * files are left on placeholders, which the proto linker binds once every file is indexed.
+ */
+
+import { basename } from "node:path";
+import type { EntityRelationship, ParsedEntity } from "../types/parser.js";
+
+/** `metadata.httpMethod` of the route entity of an RPC */
+export const GRPC_METHOD = "RPC";
+
+export type ProtoKind = "message" | "field" | "enum" | "enum_value" | "service" | "rpc";
+
+const SCALAR_TYPES = new Set([
+  "double",
+  "float",
+  "int32",
+  "int64",
+  "uint32",
+  "uint64",
+  "sint32",
+  "sint64",
+  "fixed32",
+  "fixed64",
+  "sfixed32",
+  "sfixed64",
+  "bool",
+  "string",
+  "bytes",
+]);
+const FIELD_LABELS = new Set(["repeated", "optional", "required"]);
+
+type Token = {
+  kind: "ident" | "string" | "number" | "symbol" | "eof";
+  text: string;
+  line: number;
+  column: number;
+  index: number;
+  end: { line: number; column: number; index: number };
+};
+
+type Comment = { text: string; startLine: number; endLine: number; ownLine: boolean };
+
+export function protoFileId(filePath: string): string {
+  return `${filePath}:proto_file`;
+}
+
+/**
+ * Full names a type written in `scope` may refer to, innermost first: `Bar` used in `pkg.Foo` is
+ * `pkg.Foo.Bar`, then `pkg.Bar`, then `Bar`. A leading dot means the name is already complete.
+ */
+export function protoTypeCandidates(written: string, scope: string): string[] {
+  if (written.startsWith(".")) return [written.slice(1)];
+  const parts = scope ? scope.split(".") : [];
+  const candidates: string[] = [];
+  for (let i = parts.length; i >= 0; i--) candidates.push([...parts.slice(0, i), written].join("."));
+  return candidates;
+}
+
+function tokenize(content: string): { tokens: Token[]; comments: Comment[] } {
+  const tokens: Token[] = [];
+  const comments: Comment[] = [];
+  let line = 1;
+  let lineStart = 0;
+  let lastTokenLine = 0;
+  let i = 0;
+
+  const position = (index: number) => ({ line, column: index - lineStart, index });
+  const advance = (to: number) => {
+    for (; i < to; i++) {
+      if (content[i] === "\n") {
+        line++;
+        lineStart = i + 1;
+      }
+    }
+  };
+
+  while (i < content.length) {
+    const ch = content[i]!;
+    if (/\s/.test(ch)) {
+      advance(i + 1);
+      continue;
+    }
+    const start = position(i);
+    if (ch === "/" && (content[i + 1] === "/" || content[i + 1] === "*")) {
+      const close = content[i + 1] === "/" ? content.indexOf("\n", i) : content.indexOf("*/", i + 2);
+      const end = close === -1 ? content.length : content[i + 1] === "/" ? close : close + 2;
+      const text = content.slice(i, end);
+      advance(end);
+      comments.push({ text, startLine: start.line, endLine: line, ownLine: lastTokenLine !== start.line });
+      continue;
+    }
+
+    let end = i + 1;
+    let kind: Token["kind"] = "symbol";
+    let text = ch;
+    if (ch === '"' || ch === "'") {
+      while (end < content.length && content[end] !== ch && content[end] !== "\n") {
+        end += content[end] === "\\" ? 2 : 1;
+      }
+      end = Math.min(content.length, end + 1);
+      kind = "string";
+      text = content.slice(i + 1, end - 1);
+    } else if (/[A-Za-z_]/.test(ch) || (ch === "." && /[A-Za-z_]/.test(content[i + 1] ?? ""))) {
+      // Dotted names (`google.protobuf.Timestamp`, `.pkg.Type`) are one token
+      while (end < content.length && /[\w.]/.test(content[end]!)) end++;
+      kind = "ident";
+      text = content.slice(i, end);
+    } else if (/[0-9]/.test(ch)) {
+      while (end < content.length && /[\w.]/.test(content[end]!)) end++;
+      kind = "number";
+      text = content.slice(i, end);
+    }
+    advance(end);
+    tokens.push({ kind, text, ...start, end: position(end) });
+    lastTokenLine = line;
+  }
+  const eof = position(content.length);
+  tokens.push({ kind: "eof", text: "", ...eof, end: eof });
+  return { tokens, comments };
+}
+
+type Scope = { id: string; name: string; fullName: string };
+
+type PendingReference = { from: string; written: string; scope: string; line: number; role?: string };
+
+class ProtoParser {
+  private pos = 0;
+  private pkg = "";
+  private syntax: string | undefined;
+  readonly entities: ParsedEntity[] = [];
+  readonly relationships: EntityRelationship[] = [];
+  private readonly declared = new Map<string, string>();
+  private readonly references: PendingReference[] = [];
+  private readonly docsByLine = new Map<number, Comment>();
+
+  constructor(
+    private readonly tokens: Token[],
+    comments: Comment[],
+    private readonly filePath: string,
+  ) {
+    for (const comment of comments) {
+      if (comment.ownLine) this.docsByLine.set(comment.endLine, comment);
+    }
+  }
+
+  private peek(offset = 0): Token {
+    return this.tokens[Math.min(this.pos + offset, this.tokens.length - 1)]!;
+  }
+
+  private next(): Token {
+    const token = this.peek();
+    if (token.kind !== "eof") this.pos++;
+    return token;
+  }
+
+  private accept(text: string): Token | undefined {
+    return this.peek().text === text && this.peek().kind !== "string" ? this.next() : undefined;
+  }
+
+  /** Past the end of the statement at the cursor: its `;`, or the block it opens */
+  private skipStatement(): Token {
+    let depth = 0;
+    for (;;) {
+      const token = this.next();
+      if (token.kind === "eof") return token;
+      if (token.kind !== "symbol") continue;
+      if (token.text === "{" || token.text === "[" || token.text === "(") depth++;
+      else if (token.text === "}" || token.text === "]" || token.text === ")") {
+        depth--;
+        if (depth <= 0 && token.text === "}") return token;
+      } else if (token.text === ";" && depth <= 0) return token;
+    }
+  }
+
+  /** Comments on the lines directly above `line`, as written */
+  private documentationAt(line: number): string | undefined {
+    const block: string[] = [];
+    let at = line - 1;
+    for (let comment = this.docsByLine.get(at); comment; comment = this.docsByLine.get(at)) {
+      block.unshift(comment.text);
+      at = comment.startLine - 1;
+    }
+    return block.length > 0 ? block.join("\n") : undefined;
+  }
+
+  private declare(
+    protoKind: ProtoKind,
+    type: ParsedEntity["type"],
+    nameToken: Token,
+    start: Token,
+    fullName: string,
+    parent: Scope,
+    extra: Partial<ParsedEntity> = {},
+    metadata: Record<string, unknown> = {},
+  ): ParsedEntity {
+    const entity: ParsedEntity = {
+      id: `${this.filePath}:${protoKind}:${fullName}`,
+      name: nameToken.text,
+      type,
+      filePath: this.filePath,
+      location: {
+        start: { line: start.line, column: start.column, index: start.index },
+        end: { ...start.end },
+      },
+      documentation: this.documentationAt(start.line),
+      ...extra,
+      metadata: {
+        protoKind,
+        qualifiedName: fullName,
+        ...(this.pkg ? { package: this.pkg } : {}),
+        ...(parent.name ? { className: parent.name } : {}),
+        ...metadata,
+      },
+    };
+    this.entities.push(entity);
+    this.relationships.push({
+      from: parent.id,
+      to: entity.id!,
+      type: "contains",
+      metadata: { line: start.line, confidence: 1, isDirectRelation: true },
+    });
+    return entity;
+  }
+
+  private close(entity: ParsedEntity, last: Token): void {
+    entity.location.end = { ...last.end };
+  }
+
+  private qualify(scope: Scope, name: string): string {
+    return scope.fullName ? `${scope.fullName}.${name}` : name;
+  }
+
+  private reference(from: ParsedEntity, written: string, scope: Scope, line: number, role?: string): void {
+    if (SCALAR_TYPES.has(written)) return;
+    this.references.push({ from: from.id!, written, scope: scope.fullName, line, role });
+  }
+
+  parse(): void {
+    const file: Scope = { id: protoFileId(this.filePath), name: "", fullName: "" };
+    const module: ParsedEntity = {
+      id: file.id,
+      name: basename(this.filePath),
+      type: "module",
+      filePath: this.filePath,
+      location: { start: { line: 1, column: 0, index: 0 }, end: { line: 1, column: 0, index: 0 } },
+      metadata: { protoFile: true },
+    };
+    this.entities.push(module);
+
+    while (this.peek().kind !== "eof") {
+      const token = this.peek();
+      switch (token.kind === "ident" ? token.text : "") {
+        case "syntax":
+        case "edition":
+          this.next();
+          this.accept("=");
+          if (this.peek().kind === "string") this.syntax = this.next().text;
+          this.skipStatement();
+          break;
+        case "package":
+          this.next();
+          if (this.peek().kind === "ident") {
+            this.pkg = this.next().text;
+            file.fullName = this.pkg;
+          }
+          this.skipStatement();
+          break;
+        case "import": {
+          this.next();
+          const modifier = this.peek().text === "public" || this.peek().text === "weak" ? this.next().text : undefined;
+          const path = this.peek().kind === "string" ? this.next().text : undefined;
+          if (path) {
+            this.relationships.push({
+              from: file.id,
+              to: path,
+              type: "imports",
+              sourceFile: this.filePath,
+              metadata: { line: token.line, ...(modifier ? { importKind: modifier } : {}) },
+            });
+          }
+          this.skipStatement();
+          break;
+        }
+        case "message":
+          this.message(file);
+          break;
+        case "enum":
+          this.enumeration(file);
+          break;
+        case "service":
+          this.service(file);
+          break;
+        default:
+          // option, extend and anything not understood
+          this.skipStatement();
+      }
+    }
+
+    module.metadata = { ...module.metadata, ...(this.pkg ? { package: this.pkg } : {}), syntax: this.syntax };
+    this.bindReferences();
+  }
+
+  private message(parent: Scope): void {
+    const start = this.next();
+    const nameToken = this.next();
+    if (nameToken.kind !== "ident" || !this.accept("{")) {
+      this.skipStatement();
+      return;
+    }
+    const fullName = this.qualify(parent, nameToken.text);
+    const entity = this.declare("message", "struct", nameToken, start, fullName, parent);
+    this.declared.set(fullName, entity.id!);
+    const scope: Scope = { id: entity.id!, name: nameToken.text, fullName };
+    this.close(entity, this.messageBody(scope));
+  }
+
+  /** Fields and nested declarations up to the closing brace, which is returned */
+  private messageBody(scope: Scope, oneof?: string): Token {
+    for (;;) {
+      const token = this.peek();
+      if (token.kind === "eof") return token;
+      if (token.kind === "symbol") {
+        this.next();
+        if (token.text === "}") return token;
+        continue;
+      }
+      switch (token.text) {
+        case "message":
+          this.message(scope);
+          break;
+        case "enum":
+          this.enumeration(scope);
+          break;
+        case "oneof":
+          // Its fields belong to the message; the group is recorded on each of them
+          if (this.peek(2).text === "{" && !oneof) {
+            this.next();
+            const name = this.next().text;
+            this.next();
+            this.messageBody(scope, name);
+          } else this.skipStatement();
+          break;
+        case "option":
+        case "reserved":
+        case "extensions":
+        case "extend":
+          this.skipStatement();
+          break;
+        default:
+          this.field(scope, oneof);
+      }
+    }
+  }
+
+  private field(scope: Scope, oneof?: string): void {
+    const start = this.peek();
+    const labels: string[] = [];
+    while (FIELD_LABELS.has(this.peek().text)) labels.push(this.next().text);
+
+    let fieldType: string;
+    const written: string[] = [];
+    if (this.peek().text === "map" && this.peek(1).text === "<") {
+      this.next();
+      this.next();
+      const key = this.next().text;
+      this.accept(",");
+      const value = this.next().text;
+      this.accept(">");
+      fieldType = `map<${key}, ${value}>`;
+      written.push(value);
+    } else {
+      const typeToken = this.next();
+      fieldType = typeToken.text;
+      written.push(typeToken.text);
+    }
+
+    const nameToken = this.peek();
+    // `group` fields (proto2) and anything else that is not `type name = number` are skipped whole
+    if (fieldType === "group" || nameToken.kind !== "ident" || this.peek(1).text !== "=") {
+      this.skipStatement();
+      return;
+    }
+    this.next();
+    this.next();
+    const number = this.next().text;
+    const end = this.skipStatement();
+
+    const signature = [...labels, fieldType, nameToken.text, "=", number].join(" ");
+    const entity = this.declare(
+      "field",
+      "field",
+      nameToken,
+      start,
+      this.qualify(scope, nameToken.text),
+      scope,
+      { signature, modifiers: labels.length > 0 ? labels : undefined },
+      { fieldType, fieldNumber: Number(number), ...(oneof ? { oneof } : {}) },
+    );
+    this.close(entity, end);
+    for (const type of written) this.reference(entity, type, scope, start.line);
+  }
+
+  private enumeration(parent: Scope): void {
+    const start = this.next();
+    const nameToken = this.next();
+    if (nameToken.kind !== "ident" || !this.accept("{")) {
+      this.skipStatement();
+      return;
+    }
+    const fullName = this.qualify(parent, nameToken.text);
+    const entity = this.declare("enum", "enum", nameToken, start, fullName, parent);
+    this.declared.set(fullName, entity.id!);
+    const scope: Scope = { id: entity.id!, name: nameToken.text, fullName };
+
+    for (;;) {
+      const token = this.peek();
+      if (token.kind === "eof") break;
+      if (token.text === "}" && token.kind === "symbol") {
+        this.close(entity, this.next());
+        break;
+      }
+      if (token.kind !== "ident" || token.text === "option" || token.text === "reserved" || this.peek(1).text !== "=") {
+        this.skipStatement();
+        continue;
+      }
+      this.next();
+      this.next();
+      const negative = this.accept("-") ? "-" : "";
+      const value = `${negative}${this.next().text}`;
+      const end = this.skipStatement();
+      const member = this.declare(
+        "enum_value",
+        "enum_variant",
+        token,
+        token,
+        this.qualify(scope, token.text),
+        scope,
+        { signature: `${token.text} = ${value}` },
+        { value: Number(value) },
+      );
+      this.close(member, end);
+    }
+  }
+
+  private service(parent: Scope): void {
+    const start = this.next();
+    const nameToken = this.next();
+    if (nameToken.kind !== "ident" || !this.accept("{")) {
+      this.skipStatement();
+      return;
+    }
+    const fullName = this.qualify(parent, nameToken.text);
+    const entity = this.declare("service", "interface", nameToken, start, fullName, parent);
+    const scope: Scope = { id: entity.id!, name: nameToken.text, fullName };
+
+    for (;;) {
+      const token = this.peek();
+      if (token.kind === "eof") break;
+      if (token.text === "}" && token.kind === "symbol") {
+        this.close(entity, this.next());
+        break;
+      }
+      if (token.text === "rpc") this.rpc(scope);
+      else this.skipStatement();
+    }
+  }
+
+  /** `rpc Name (stream Req) returns (stream Res);` or with an options block */
+  private rpc(service: Scope): void {
+    const start = this.next();
+    const nameToken = this.next();
+    const side = () => {
+      if (!this.accept("(")) return null;
+      const streaming = this.peek().text === "stream" && this.peek(1).kind === "ident" ? !!this.next() : false;
+      const type = this.next().text;
+      this.accept(")");
+      return { type, streaming };
+    };
+    const request = nameToken.kind === "ident" ? side() : null;
+    const response = request && this.accept("returns") ? side() : null;
+    const end = this.skipStatement();
+    if (!request || !response) return;
+
+    const grpcPath = `/${service.fullName}/${nameToken.text}`;
+    const stream = (s: { type: string; streaming: boolean }) => (s.streaming ? `stream ${s.type}` : s.type);
+    const entity = this.declare(
+      "rpc",
+      "method",
+      nameToken,
+      start,
+      this.qualify(service, nameToken.text),
+      service,
+      {
+        signature: `rpc ${nameToken.text}(${stream(request)}) returns (${stream(response)})`,
+        parameters: [{ name: "request", type: stream(request) }],
+        returnType: stream(response),
+      },
+      {
+        requestType: request.type,
+        responseType: response.type,
+        clientStreaming: request.streaming,
+        serverStreaming: response.streaming,
+        grpcPath,
+      },
+    );
+    this.close(entity, end);
+    this.reference(entity, request.type, service, start.line, "request");
+    this.reference(entity, response.type, service, start.line, "response");
+
+    const routeName = `${GRPC_METHOD} ${grpcPath}`;
+    const route: ParsedEntity = {
+      id: `${this.filePath}:route:${start.line}:${routeName}`,
+      name: routeName,
+      type: "route",
+      filePath: this.filePath,
+      location: entity.location,
+      metadata: {
+        httpMethod: GRPC_METHOD,
+        path: grpcPath,
+        framework: "grpc",
+        router: service.fullName,
+        handler: entity.metadata?.qualifiedName,
+        ...(request.streaming || response.streaming
+          ? { streaming: request.streaming && response.streaming ? "bidi" : request.streaming ? "client" : "server" }
+          : {}),
+      },
+    };
+    this.entities.push(route);
+    this.relationships.push({
+      from: route.id!,
+      to: entity.id!,
+      type: "handled_by",
+      metadata: { line: start.line, confidence: 1 },
+    });
+  }
+
+  /** Types declared in this file bind to their entity; the rest wait for the proto linker */
+  private bindReferences(): void {
+    for (const ref of this.references) {
+      const local = protoTypeCandidates(ref.written, ref.scope)
+        .map((name) => this.declared.get(name))
+        .find(Boolean);
+      this.relationships.push({
+        from: ref.from,
+        to: local ?? ref.written,
+        type: "references",
+        sourceFile: this.filePath,
+        metadata: {
+          line: ref.line,
+          referencedName: ref.written,
+          ...(ref.role ? { role: ref.role } : {}),
+          ...(local ? { confidence: 1 } : {}),
+        },
+      });
+    }
+  }
+}
+
+export class ProtoAnalyzer {
+  analyze(content: string, filePath: string): { entities: ParsedEntity[]; relationships: EntityRelationship[] } {
+    const { tokens, comments } = tokenize(content);
+    const parser = new ProtoParser(tokens, comments, filePath);
+    try {
+      parser.parse();
+    } catch (error) {
+      // Keep what was read before the statement it could not follow
+      console.error(`[ProtoAnalyzer] Error analyzing ${filePath}:`, error);
+    }
+    return { entities: parser.entities, relationships: parser.relationships };
+  }
+}
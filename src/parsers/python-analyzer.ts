@@ -40,6 +40,8 @@ import type {
   PythonParserMetrics,
   TreeSitterNode,
 } from "../types/parser.js";
+import { cyclomaticComplexity } from "./complexity.js";
+import { pythonDocstring } from "./doc-comments.js";
 
 // =============================================================================
 // 2. CONSTANTS AND CONFIGURATION
@@ -119,6 +121,16 @@ interface AnalysisContext {
   metrics: PythonParserMetrics;
 }
 
+interface PythonDecorator {
+  /** Decorator callee as written, e.g. `dataclass`, `app.route`, `pytest.fixture` */
+  name: string;
+  /** Argument source text, present for call-style decorators */
+  arguments?: string[];
+  /** Full decorator expression without the `@` */
+  raw: string;
+  line: number;
+}
+
 // =============================================================================
 // 4. UTILITY FUNCTIONS AND HELPERS
 // =============================================================================
@@ -346,13 +358,10 @@ export class PythonAnalyzer {
       case "lambda":
         this.analyzeEnhancedLambda(node, context);
         break;
-
-      case "decorated_definition":
-        this.analyzeDecoratedDefinition(node, context);
-        break;
     }
 
-    // Recursively process children
+    // Recursively process children; the function or class under a decorated_definition is reached
+    // here and reads its decorators from that parent
     for (const child of node.namedChildren) {
       this.traverseNodeForLayer1(child, context);
     }
@@ -394,6 +403,8 @@ export class PythonAnalyzer {
       decorators: decorators.map((d) => ({
         name: d.name,
         arguments: d.arguments,
+        raw: d.raw,
+        line: d.line,
         isBuiltin: isBuiltinDecorator(d.name),
       })),
       asyncInfo: {
@@ -404,6 +415,8 @@ export class PythonAnalyzer {
       parameters,
       returnType,
       modifiers: this.extractEnhancedModifiers(node, decorators),
+      cyclomaticComplexity: cyclomaticComplexity(node, "python"),
+      documentation: pythonDocstring(node),
     };
 
     context.entities.push(entity);
@@ -414,7 +427,7 @@ export class PythonAnalyzer {
       classification: methodType,
       isAsync,
       isGenerator: entity.asyncInfo?.isGenerator || false,
-      decorators: decorators.map((d) => ({ ...d, line: node.startPosition.row + 1 })),
+      decorators,
       magicType: isMagicMethod(name) ? MAGIC_METHOD_TYPES[name as keyof typeof MAGIC_METHOD_TYPES] : undefined,
       location: convertPosition(node),
     };
@@ -446,12 +459,15 @@ export class PythonAnalyzer {
       decorators: decorators.map((d) => ({
         name: d.name,
         arguments: d.arguments,
+        raw: d.raw,
+        line: d.line,
         isBuiltin: isBuiltinDecorator(d.name),
       })),
       inheritance: {
         baseClasses,
         isAbstract: this.isAbstractClass(node),
       },
+      documentation: pythonDocstring(node),
       children: [],
     };
 
@@ -500,39 +516,49 @@ export class PythonAnalyzer {
   }
 
   /**
-   * Extract decorators with chaining analysis
+   * Extract decorators with chaining analysis, in source order (top-most first). Call-style
+   * decorators such as `@app.route("/x")` keep their callee as the name and their argument text.
    */
-  private extractDecoratorsWithChaining(
-    node: TreeSitterNode,
-    context: AnalysisContext,
-  ): Array<{ name: string; arguments?: string[] }> {
-    const decorators: Array<{ name: string; arguments?: string[] }> = [];
+  private extractDecoratorsWithChaining(node: TreeSitterNode, context: AnalysisContext): PythonDecorator[] {
+    const decorators: PythonDecorator[] = [];
 
     // Look for decorated_definition parent
     let current = node.parent;
     while (current && current.type === "decorated_definition") {
-      const decoratorNodes = current.children.filter((child) => child.type === "decorator");
-
-      for (const decoratorNode of decoratorNodes) {
-        const nameNode = decoratorNode.namedChildren.find(
-          (child) => child.type === "identifier" || child.type === "attribute",
-        );
-
-        if (nameNode) {
-          const decorator = {
-            name: nameNode.text,
-            arguments: this.extractDecoratorArguments(decoratorNode, context),
-          };
-          decorators.unshift(decorator); // Add to front to maintain order
+      const level: PythonDecorator[] = [];
+      for (const decoratorNode of current.children.filter((child) => child.type === "decorator")) {
+        const decorator = this.parseDecorator(decoratorNode, context);
+        if (decorator) {
+          level.push(decorator);
           context.metrics.basicParsing.decoratorsExtracted++;
         }
       }
+      decorators.unshift(...level); // Outer definitions wrap inner ones
       current = current.parent;
     }
 
     return decorators;
   }
 
+  private parseDecorator(node: TreeSitterNode, context: AnalysisContext): PythonDecorator | null {
+    const expression = node.namedChildren.find((child) => child.type !== "comment");
+    if (!expression) return null;
+
+    const raw = getNodeText(expression, context.source).trim();
+    if (expression.type === "call") {
+      const callee = expression.childForFieldName("function") ?? expression.namedChildren[0];
+      if (!callee) return null;
+      return {
+        name: callee.text,
+        arguments: this.extractDecoratorArguments(expression, context),
+        raw,
+        line: node.startPosition.row + 1,
+      };
+    }
+    // Bare names and attributes, plus arbitrary expressions allowed since Python 3.9
+    return { name: expression.text, raw, line: node.startPosition.row + 1 };
+  }
+
   /**
    * Extract complex function parameters with type hints
    */
@@ -705,6 +731,7 @@ export class PythonAnalyzer {
         this.analyzeMethodOverrides(context);
         this.analyzeImportDependencies(rootNode, context);
         this.createCrossReferences(context);
+        this.analyzeDecoratorReferences(context);
         this.analyzeMethodResolutionOrder(context);
       },
       context.metrics,
@@ -805,15 +832,15 @@ export class PythonAnalyzer {
   /**
    * Extract decorator arguments from decorator node
    */
-  private extractDecoratorArguments(node: TreeSitterNode, context: AnalysisContext): string[] {
+  private extractDecoratorArguments(call: TreeSitterNode, context: AnalysisContext): string[] {
     const args: string[] = [];
 
-    // Look for argument_list child
-    const argumentList = node.namedChildren.find((child) => child.type === "argument_list");
+    // Look for argument_list child of the decorator call
+    const argumentList = call.namedChildren.find((child) => child.type === "argument_list");
     if (!argumentList) return args;
 
     for (const arg of argumentList.namedChildren) {
-      if (arg.type !== ",") {
+      if (arg.type !== "comment") {
         args.push(getNodeText(arg, context.source).trim());
       }
     }
@@ -1084,19 +1111,6 @@ export class PythonAnalyzer {
     return params;
   }
 
-  /**
-   * Analyze decorated definitions
-   */
-  private analyzeDecoratedDefinition(node: TreeSitterNode, context: AnalysisContext): void {
-    // The actual function/class is the last child
-    const definition = node.namedChildren[node.namedChildren.length - 1];
-
-    if (definition) {
-      // Process the underlying definition
-      this.traverseNodeForLayer1(definition, context);
-    }
-  }
-
   // =============================================================================
   // LAYER 2: ADVANCED FEATURE ANALYSIS - COMPLETE IMPLEMENTATION
   // =============================================================================
@@ -1342,6 +1356,75 @@ export class PythonAnalyzer {
     }
   }
 
+  /**
+   * Link decorated functions and classes to their decorators when the decorator names a symbol
+   * defined in this file or brought in by an import. Unresolvable decorators (`app.route` on a
+   * module-level `app = Flask()`) stay in entity metadata only.
+   */
+  private analyzeDecoratorReferences(context: AnalysisContext): void {
+    const defined = new Set(
+      context.entities.filter((e) => e.type !== "import" && e.type !== "lambda").map((e) => e.name),
+    );
+    // Local name -> module, plus the imported symbol for `from x import y`
+    const imported = new Map<string, { module: string; symbol?: string }>();
+    for (const entity of context.entities) {
+      const importData = entity.type === "import" ? entity.importData : undefined;
+      if (!importData) continue;
+      for (const spec of importData.specifiers) {
+        if (importData.fromModule) {
+          imported.set(spec.local, { module: importData.fromModule, symbol: spec.imported ?? spec.local });
+        } else {
+          imported.set(spec.local, { module: spec.imported ?? spec.local });
+        }
+      }
+    }
+
+    for (const entity of context.entities) {
+      for (const decorator of entity.decorators ?? []) {
+        const target = this.resolveDecoratorTarget(decorator.name, defined, imported);
+        if (!target) continue;
+        context.relationships.push({
+          from: entity.name,
+          to: target.symbol,
+          type: "decorated_by",
+          sourceFile: context.filePath,
+          targetFile: target.source,
+          metadata: {
+            line: entity.location?.start?.line,
+            decorator: decorator.name,
+            arguments: decorator.arguments,
+            raw: decorator.raw,
+          },
+        });
+      }
+    }
+  }
+
+  /**
+   * `fixture` after `from pytest import fixture` and `pytest.fixture` after `import pytest` both
+   * name `fixture` in module `pytest`; a from-imported object (`@app.route` after
+   * `from web import app`) names the object itself.
+   */
+  private resolveDecoratorTarget(
+    name: string,
+    defined: Set<string>,
+    imported: Map<string, { module: string; symbol?: string }>,
+  ): { symbol: string; source?: string } | null {
+    if (defined.has(name)) return { symbol: name };
+
+    const segments = name.split(".");
+    for (let k = segments.length; k >= 1; k--) {
+      const binding = imported.get(segments.slice(0, k).join("."));
+      if (!binding) continue;
+      if (binding.symbol) return { symbol: binding.symbol, source: binding.module };
+      const rest = segments.slice(k).join(".");
+      return rest ? { symbol: rest, source: binding.module } : null;
+    }
+
+    const head = segments[0];
+    return head && segments.length > 1 && defined.has(head) ? { symbol: head } : null;
+  }
+
   private createCrossReferences(context: AnalysisContext): void {
     // Create cross-references between entities
     for (const entity of context.entities) {
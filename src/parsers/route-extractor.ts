@@ -0,0 +1,412 @@
+/**
+ * Route Extractor - HTTP endpoints declared through web framework conventions
+ * Router calls such as `app.get("/users/:id", show)` (Express, Koa, Fastify, Hono) and Go mux
+ * registrations such as `mux.HandleFunc("GET /users/{id}", show)` (net/http, gorilla, chi, gin,
+ * echo) are read from the syntax tree. NestJS and Flask/FastAPI routes come from the decorators
+ * already extracted on controller methods and view functions. Every endpoint becomes a `route`
+ * entity named `METHOD /path`, whose `handler` is the function that serves it.
+ */
+
+import type {
+  EntityRelationship,
+  ParsedCallSite,
+  ParsedEntity,
+  SupportedLanguage,
+  TreeSitterNode,
+} from "../types/parser.js";
+
+/** Method recorded for registrations that accept every HTTP method (`app.all`, `http.HandleFunc`) */
+export const ANY_METHOD = "ANY";
+
+const HTTP_METHODS = ["GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"];
+
+const JS_ROUTE_METHODS = new Map<string, string>([
+  ...HTTP_METHODS.map((m) => [m.toLowerCase(), m] as [string, string]),
+  ["del", "DELETE"],
+  ["all", ANY_METHOD],
+]);
+// `axios.get("/users", config)` looks like a route; only receivers named like a server or router count
+const JS_ROUTER = /^(app|router|server|routes?|fastify|hono|\w*(Router|App|Server|Routes))$/;
+const JS_FRAMEWORKS: Array<[RegExp, string]> = [
+  [/["'](koa-router|@koa\/router)["']/, "koa"],
+  [/["']fastify["']/, "fastify"],
+  [/["']hono["']/, "hono"],
+];
+
+const GO_ROUTE_METHODS = new Map<string, string>([
+  ...HTTP_METHODS.flatMap((m) => [
+    [m, m] as [string, string],
+    [`${m[0]}${m.slice(1).toLowerCase()}`, m] as [string, string],
+  ]),
+  ["Any", ANY_METHOD],
+  ["Handle", ANY_METHOD],
+  ["HandleFunc", ANY_METHOD],
+]);
+const GO_FRAMEWORKS: Array<[RegExp, string]> = [
+  [/"github\.com\/gin-gonic\/gin"/, "gin"],
+  [/"github\.com\/labstack\/echo/, "echo"],
+  [/"github\.com\/go-chi\/chi/, "chi"],
+  [/"github\.com\/gorilla\/mux"/, "gorilla"],
+  [/"github\.com\/gofiber\/fiber/, "fiber"],
+];
+
+const NEST_ROUTE_DECORATORS = new Map<string, string>([
+  ...HTTP_METHODS.map((m) => [`${m[0]}${m.slice(1).toLowerCase()}`, m] as [string, string]),
+  ["All", ANY_METHOD],
+]);
+
+const PYTHON_ROUTE_DECORATOR = /^([\w.]+)\.(route|api_route|get|post|put|patch|delete|head|options)$/;
+const PYTHON_ROUTERS = /^\s*(\w+)\s*(?::\s*[\w.]+\s*)?=\s*(?:[\w.]+\.)?(APIRouter|Blueprint)\((.*)$/gm;
+
+function location(node: TreeSitterNode): ParsedEntity["location"] {
+  return {
+    start: { line: node.startPosition.row + 1, column: node.startPosition.column, index: node.startIndex },
+    end: { line: node.endPosition.row + 1, column: node.endPosition.column, index: node.endIndex },
+  };
+}
+
+function walk(node: TreeSitterNode, visit: (node: TreeSitterNode) => void): void {
+  visit(node);
+  for (const child of node.namedChildren) walk(child, visit);
+}
+
+/** Value of a string literal without interpolation, as written in any of the supported languages */
+function stringValue(node: TreeSitterNode | null | undefined): string | undefined {
+  if (!node) return undefined;
+  if (node.type === "template_string" && node.namedChildren.some((c) => c.type === "template_substitution")) {
+    return undefined;
+  }
+  const match = /^[rbu]?(["'`])([\s\S]*)\1$/i.exec(node.text.trim());
+  return match ? match[2] : undefined;
+}
+
+function literalText(text: string | undefined): string | undefined {
+  const match = /^[rbu]?(["'`])([^"'`]*)\1$/i.exec(text?.trim() ?? "");
+  return match ? match[2] : undefined;
+}
+
+/** `users` + `:id` → `/users/:id`; empty parts are dropped and the result always starts with `/` */
+export function joinRoutePath(...parts: Array<string | undefined>): string {
+  const segments = parts.flatMap((part) => (part ?? "").split("/")).filter(Boolean);
+  const trailing = parts.at(-1)?.endsWith("/") && segments.length > 0 ? "/" : "";
+  return `/${segments.join("/")}${trailing}`;
+}
+
+function isRoutePath(path: string | undefined): path is string {
+  return path !== undefined && (path.startsWith("/") || path === "*");
+}
+
+function frameworkOf(source: string, table: Array<[RegExp, string]>, fallback: string): string {
+  return table.find(([pattern]) => pattern.test(source))?.[1] ?? fallback;
+}
+
+function routeEntity(
+  filePath: string,
+  method: string,
+  path: string,
+  at: ParsedEntity["location"],
+  framework: string,
+  handler: ParsedCallSite | undefined,
+  extra: Record<string, unknown> = {},
+): ParsedEntity {
+  const name = `${method} ${path}`;
+  return {
+    id: `${filePath}:route:${at.start.line}:${name}`,
+    name,
+    type: "route",
+    location: at,
+    ...(handler ? { handler } : {}),
+    metadata: {
+      httpMethod: method,
+      path,
+      framework,
+      ...(handler ? { handler: handler.qualifier ? `${handler.qualifier}.${handler.name}` : handler.name } : {}),
+      ...extra,
+    },
+  };
+}
+
+type HandlerArgument = { site?: ParsedCallSite; inline: boolean };
+
+/**
+ * The function a router call dispatches to: a name (`show`, `users.show`), an inline function,
+ * or the innermost argument of a wrapper such as `asyncHandler(show)` / `http.HandlerFunc(show)`.
+ * Anything else (objects, strings) means the call is not a route registration.
+ */
+function handlerArgument(node: TreeSitterNode | undefined, language: SupportedLanguage): HandlerArgument | undefined {
+  if (!node) return undefined;
+  const site = (name: TreeSitterNode | null, qualifier?: TreeSitterNode | null): HandlerArgument => ({
+    inline: false,
+    site: {
+      name: name?.text ?? node.text,
+      ...(qualifier ? { qualifier: qualifier.text } : {}),
+      line: node.startPosition.row + 1,
+      column: node.startPosition.column,
+    },
+  });
+
+  switch (node.type) {
+    case "identifier":
+      return site(node);
+    case "member_expression":
+      return site(node.childForFieldName("property"), node.childForFieldName("object"));
+    case "selector_expression":
+      return site(node.childForFieldName("field"), node.childForFieldName("operand"));
+    case "arrow_function":
+    case "function":
+    case "function_expression":
+    case "func_literal":
+      return { inline: true };
+    case "call_expression": {
+      const args = node.childForFieldName("arguments")?.namedChildren.filter((c) => c.type !== "comment") ?? [];
+      const inner = handlerArgument(args.at(-1), language);
+      // A Go handler may be any expression of a Handler type, such as a factory call
+      return inner ?? (language === "go" ? { inline: false } : undefined);
+    }
+    default:
+      return language === "go" ? { inline: false } : undefined;
+  }
+}
+
+/** Path given to `router.route("/users")` at the root of a `.get(list).post(create)` chain */
+function routeChainPath(node: TreeSitterNode | null): { path: string; router: string } | undefined {
+  let current = node;
+  while (current?.type === "call_expression") {
+    const callee = current.childForFieldName("function");
+    if (callee?.type !== "member_expression") return undefined;
+    const property = callee.childForFieldName("property")?.text ?? "";
+    const object = callee.childForFieldName("object");
+    if (property === "route") {
+      const path = stringValue(current.childForFieldName("arguments")?.namedChildren[0]);
+      const router = object?.text ?? "";
+      return isRoutePath(path) && JS_ROUTER.test(router.split(".").pop() ?? "") ? { path, router } : undefined;
+    }
+    if (!JS_ROUTE_METHODS.has(property)) return undefined;
+    current = object;
+  }
+  return undefined;
+}
+
+function extractJsCallRoutes(root: TreeSitterNode, filePath: string, source: string): ParsedEntity[] {
+  const framework = frameworkOf(source, JS_FRAMEWORKS, "express");
+  const routes: ParsedEntity[] = [];
+  walk(root, (node) => {
+    if (node.type !== "call_expression") return;
+    const callee = node.childForFieldName("function");
+    if (callee?.type !== "member_expression") return;
+    const method = JS_ROUTE_METHODS.get(callee.childForFieldName("property")?.text ?? "");
+    const object = callee.childForFieldName("object");
+    if (!method || !object) return;
+    const args = node.childForFieldName("arguments")?.namedChildren.filter((c) => c.type !== "comment") ?? [];
+
+    let path: string | undefined;
+    let router: string;
+    let handlers: TreeSitterNode[];
+    const chain = routeChainPath(object);
+    if (chain) {
+      ({ path, router } = chain);
+      handlers = args;
+    } else {
+      router = object.text;
+      if (!JS_ROUTER.test(router.split(".").pop() ?? "")) return;
+      // Koa names routes with an optional first argument: `router.get("user", "/users/:id", show)`
+      const pathIndex = args.findIndex((arg, i) => i < 2 && isRoutePath(stringValue(arg)));
+      if (pathIndex < 0) return;
+      path = stringValue(args[pathIndex]);
+      handlers = args.slice(pathIndex + 1);
+    }
+    // Middleware come first; the last function answers the request
+    const handler = handlerArgument(handlers.at(-1), "javascript");
+    if (!path || !handler) return;
+    routes.push(
+      routeEntity(filePath, method, path, location(node), framework, handler.site, {
+        router,
+        ...(handler.inline ? { inlineHandler: true } : {}),
+      }),
+    );
+  });
+  return routes;
+}
+
+/** Methods listed by gorilla's `.Methods("GET", "POST")` chained onto a registration */
+function gorillaMethods(call: TreeSitterNode): string[] {
+  const selector = call.parent;
+  if (selector?.type !== "selector_expression" || selector.childForFieldName("field")?.text !== "Methods") return [];
+  const outer = selector.parent;
+  if (outer?.type !== "call_expression") return [];
+  return (outer.childForFieldName("arguments")?.namedChildren ?? [])
+    .map((arg) => stringValue(arg)?.toUpperCase())
+    .filter((m): m is string => !!m);
+}
+
+function extractGoRoutes(root: TreeSitterNode, filePath: string, source: string): ParsedEntity[] {
+  const framework = frameworkOf(source, GO_FRAMEWORKS, "net/http");
+  const routes: ParsedEntity[] = [];
+  walk(root, (node) => {
+    if (node.type !== "call_expression") return;
+    const callee = node.childForFieldName("function");
+    if (callee?.type !== "selector_expression") return;
+    const name = callee.childForFieldName("field")?.text ?? "";
+    const args = node.childForFieldName("arguments")?.namedChildren.filter((c) => c.type !== "comment") ?? [];
+
+    // chi's `r.Method("GET", "/x", h)` names the method first
+    const explicit = name === "Method" || name === "MethodFunc" ? stringValue(args[0])?.toUpperCase() : undefined;
+    const rest = explicit ? args.slice(1) : args;
+    let method = explicit ?? GO_ROUTE_METHODS.get(name);
+    let path = stringValue(rest[0]);
+    if (!method || !path || rest.length < 2) return;
+
+    // Go 1.22 ServeMux patterns carry the method: `mux.HandleFunc("GET /users/{id}", show)`
+    const pattern = /^([A-Z]+)\s+(\/\S*)$/.exec(path);
+    if (pattern && method === ANY_METHOD) {
+      method = pattern[1]!;
+      path = pattern[2]!;
+    }
+    if (!isRoutePath(path)) return;
+
+    const handler = handlerArgument(rest.at(-1), "go");
+    const router = callee.childForFieldName("operand")?.text ?? "";
+    const listed = method === ANY_METHOD ? gorillaMethods(node) : [];
+    for (const each of listed.length ? listed : [method]) {
+      routes.push(
+        routeEntity(filePath, each, path, location(node), framework, handler?.site, {
+          router,
+          ...(handler?.inline ? { inlineHandler: true } : {}),
+        }),
+      );
+    }
+  });
+  return routes;
+}
+
+function declarationSite(entity: ParsedEntity, qualifier?: string): ParsedCallSite {
+  return {
+    name: entity.name,
+    ...(qualifier ? { qualifier } : {}),
+    line: entity.location.start.line,
+    column: entity.location.start.column,
+  };
+}
+
+/** `@Controller("users")` and `@Get(":id")` on its methods */
+function extractNestRoutes(entities: ParsedEntity[], filePath: string): ParsedEntity[] {
+  const routes: ParsedEntity[] = [];
+  for (const controller of entities) {
+    const decorator = controller.type === "class" ? controller.decorators?.find((d) => d.name === "Controller") : null;
+    if (!decorator) continue;
+    const argument = decorator.arguments?.[0];
+    const prefix = literalText(argument) ?? literalText(/\bpath\s*:\s*(["'`][^"'`]*["'`])/.exec(argument ?? "")?.[1]);
+
+    for (const method of controller.children ?? []) {
+      for (const route of method.decorators ?? []) {
+        const httpMethod = NEST_ROUTE_DECORATORS.get(route.name);
+        if (!httpMethod) continue;
+        const path = joinRoutePath(prefix, literalText(route.arguments?.[0]));
+        routes.push(
+          routeEntity(filePath, httpMethod, path, method.location, "nestjs", declarationSite(method), {
+            controller: controller.name,
+          }),
+        );
+      }
+    }
+  }
+  return routes;
+}
+
+/**
+ * `@app.route("/x", methods=[...])` and `@router.get("/x")`, under the `prefix`/`url_prefix` of
+ * the `APIRouter` or `Blueprint` the decorator belongs to
+ */
+function extractPythonRoutes(entities: ParsedEntity[], filePath: string, source: string): ParsedEntity[] {
+  const prefixes = new Map<string, string>();
+  for (const match of source.matchAll(PYTHON_ROUTERS)) {
+    const prefix = /\b(?:url_)?prefix\s*=\s*["']([^"']*)["']/.exec(match[3] ?? "")?.[1];
+    if (match[1] && prefix) prefixes.set(match[1], prefix);
+  }
+  const usesFastApi = /^\s*(from|import)\s+fastapi\b/m.test(source);
+
+  const routes: ParsedEntity[] = [];
+  for (const view of entities) {
+    if (view.type === "class") continue;
+    for (const decorator of view.decorators ?? []) {
+      const match = PYTHON_ROUTE_DECORATOR.exec(decorator.name);
+      if (!match) continue;
+      const [, router = "", kind = ""] = match;
+      const args = decorator.arguments ?? [];
+      const written =
+        literalText(args.find((arg) => !arg.includes("=")) ?? "") ??
+        literalText(/^path\s*=\s*(.*)$/.exec(args.find((arg) => /^path\s*=/.test(arg)) ?? "")?.[1]);
+      if (!isRoutePath(written)) continue;
+
+      // `route`/`api_route` take `methods=[...]` and default to GET
+      const listed = /^methods\s*=\s*\[(.*)\]$/s.exec(args.find((arg) => /^methods\s*=/.test(arg)) ?? "")?.[1];
+      const methods =
+        kind === "route" || kind === "api_route"
+          ? [...(listed ?? "").matchAll(/["'](\w+)["']/g)].map((m) => m[1]!.toUpperCase())
+          : [kind.toUpperCase()];
+      const framework = usesFastApi || kind === "api_route" ? "fastapi" : "flask";
+      const path = joinRoutePath(prefixes.get(router), written);
+      for (const method of methods.length ? methods : ["GET"]) {
+        routes.push(
+          routeEntity(filePath, method, path, view.location, framework, declarationSite(view), { router }),
+        );
+      }
+    }
+  }
+  return routes;
+}
+
+/**
+ * Route entities of a parsed file, each endpoint once. `entities` are the file's extracted
+ * declarations, which decorator-based frameworks are read from.
+ */
+export function extractRoutes(
+  root: TreeSitterNode,
+  language: SupportedLanguage,
+  filePath: string,
+  source: string,
+  entities: ParsedEntity[],
+): ParsedEntity[] {
+  let routes: ParsedEntity[];
+  switch (language) {
+    case "javascript":
+    case "jsx":
+    case "typescript":
+    case "tsx":
+      routes = [...extractJsCallRoutes(root, filePath, source), ...extractNestRoutes(entities, filePath)];
+      break;
+    case "go":
+      routes = extractGoRoutes(root, filePath, source);
+      break;
+    case "python":
+      routes = extractPythonRoutes(entities, filePath, source);
+      break;
+    default:
+      return [];
+  }
+
+  // Exported declarations can be reported twice; a registration repeated on the same line is one route
+  const seen = new Set<string>();
+  return routes.filter((route) => {
+    const key = `${route.name}:${route.location.start.line}`;
+    if (seen.has(key)) return false;
+    seen.add(key);
+    return true;
+  });
+}
+
+/** `handled_by` edges from routes to their handlers, for languages whose analyzers supply the file's edges */
+export function routeHandlerRelationships(routes: ParsedEntity[]): EntityRelationship[] {
+  const relationships: EntityRelationship[] = [];
+  for (const route of routes) {
+    const handler = route.handler;
+    if (!route.id || !handler) continue;
+    relationships.push({
+      from: route.id,
+      to: handler.name,
+      type: "handled_by",
+      metadata: { line: handler.line, ...(handler.qualifier ? { qualifier: handler.qualifier } : {}) },
+    });
+  }
+  return relationships;
+}
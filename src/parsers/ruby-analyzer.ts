@@ -0,0 +1,601 @@
+/**
+ * Ruby Language Analyzer
+ *
+ * Declarations:
+ * - Modules and classes, qualified by lexical nesting (`Admin::User`); a class or module
+ *   reopened later in the same file extends the first declaration instead of adding another
+ * - Instance methods (`Admin::User#name`) and class methods (`def self.x`, `class << self`,
+ *   `Admin::User.x`), with the visibility set by `private`/`protected`/`public`
+ * - `attr_reader`/`attr_writer`/`attr_accessor` attributes as properties
+ *
+ * Relationships:
+ * - Superclass (extends) and `include`/`extend`/`prepend` mixins (implements)
+ * - Rails association macros (`has_many`, `has_one`, `belongs_to`, `has_and_belongs_to_many`) as
+ *   references to the associated class, from `class_name:` or the inflected association name
+ * - Method calls, bound to methods of the same file where the receiver allows it
+ *
+ * Constants declared in other files keep their name as written; the Ruby constant resolver binds
+ * them, and merges classes reopened across files, once the project is indexed.
+ */
+
+import type { EntityRelationship, ParsedEntity, TreeSitterNode } from "../types/parser.js";
+
+const MAX_RECURSION_DEPTH = 50;
+const PARSE_TIMEOUT_MS = 5000;
+
+class CircuitBreakerError extends Error {
+  constructor(message: string) {
+    super(message);
+    this.name = "CircuitBreakerError";
+  }
+}
+
+export type RubyMixinKind = "include" | "extend" | "prepend";
+
+/** Association macro -> whether its name is plural, so the class is its singular */
+const ASSOCIATIONS = new Map([
+  ["has_many", true],
+  ["has_and_belongs_to_many", true],
+  ["has_one", false],
+  ["belongs_to", false],
+]);
+
+const ATTRIBUTE_MACROS = new Map([
+  ["attr_reader", "reader"],
+  ["attr_writer", "writer"],
+  ["attr_accessor", "accessor"],
+]);
+
+const VISIBILITIES = new Set(["private", "protected", "public"]);
+
+/** A constant path as written: `Order`, `Admin::User`, `::Api` */
+export const RUBY_CONSTANT_PATH = /^(::)?[A-Z]\w*(::[A-Z]\w*)*$/;
+
+/** A class or module whose body is being walked */
+interface Scope {
+  id: string;
+  qualifiedName: string;
+  /** Inside `class << self`, where `def` declares class methods */
+  singleton: boolean;
+}
+
+/**
+ * A `constant` looked up lexically from the module nesting `scope`, or a `call` of `name` from
+ * `owner` on `receiver` (none, `self`, or a constant such as `User` in `User.find`); `singleton`
+ * marks calls made inside `def self.` or `class << self`
+ */
+type PendingTarget =
+  | { kind: "constant"; relationship: EntityRelationship; name: string; scope: string }
+  | {
+      kind: "call";
+      relationship: EntityRelationship;
+      name: string;
+      owner: string;
+      singleton: boolean;
+      receiver?: string;
+    };
+
+/** `Admin::User` for `Admin::User#name` and `Admin::User.find`; "" for a top-level name */
+export function rubyOwnerName(qualifiedName: string): string {
+  const member = Math.max(qualifiedName.lastIndexOf("#"), qualifiedName.lastIndexOf("."));
+  if (member >= 0) return qualifiedName.slice(0, member);
+  const scope = qualifiedName.lastIndexOf("::");
+  return scope < 0 ? "" : qualifiedName.slice(0, scope);
+}
+
+/** `order_items` -> `OrderItem` (when plural): the class Rails infers for an association */
+export function associationClassName(name: string, plural: boolean): string {
+  let singular = name;
+  if (plural) {
+    if (/ies$/.test(name)) singular = name.replace(/ies$/, "y");
+    else if (/(ss|sh|ch|x|z)es$/.test(name)) singular = name.replace(/es$/, "");
+    else if (/[^s]s$/.test(name)) singular = name.slice(0, -1);
+  }
+  return singular
+    .split("/")
+    .map((part) =>
+      part
+        .split("_")
+        .map((word) => word.charAt(0).toUpperCase() + word.slice(1))
+        .join(""),
+    )
+    .join("::");
+}
+
+/**
+ * The constant `written` names from inside `scope`: `scope::written`, then the same in each
+ * enclosing scope, then `written` at the top level. A leading `::` skips the lexical lookup.
+ */
+export function resolveRubyConstant<T>(
+  written: string,
+  scope: string,
+  lookup: (qualifiedName: string) => T | undefined,
+): T | undefined {
+  if (written.startsWith("::")) return lookup(written.slice(2));
+  for (let outer = scope; outer; outer = rubyOwnerName(outer)) {
+    const found = lookup(`${outer}::${written}`);
+    if (found !== undefined) return found;
+  }
+  return lookup(written);
+}
+
+export class RubyAnalyzer {
+  private recursionDepth = 0;
+  private parseStartTime = 0;
+  /** Classes and modules declared in this file by qualified name */
+  private declaredTypes = new Map<string, ParsedEntity>();
+  /** Methods declared in this file by `Owner#name` and `Owner.name` */
+  private declaredMethods = new Map<string, string>();
+  private pending: PendingTarget[] = [];
+
+  async analyze(
+    rootNode: TreeSitterNode,
+    filePath: string,
+  ): Promise<{ entities: ParsedEntity[]; relationships: EntityRelationship[] }> {
+    this.resetState();
+
+    const entities: ParsedEntity[] = [];
+    const relationships: EntityRelationship[] = [];
+
+    try {
+      this.visit(rootNode, null, filePath, entities, relationships);
+      this.bindLocalTargets();
+    } catch (error) {
+      if (error instanceof CircuitBreakerError) {
+        console.warn(`[RubyAnalyzer] Circuit breaker triggered for ${filePath}: ${error.message}`);
+      } else {
+        console.error(`[RubyAnalyzer] Error analyzing ${filePath}:`, error);
+      }
+      // Return partial results on error
+    }
+
+    return { entities, relationships };
+  }
+
+  private resetState(): void {
+    this.recursionDepth = 0;
+    this.parseStartTime = Date.now();
+    this.declaredTypes.clear();
+    this.declaredMethods.clear();
+    this.pending = [];
+  }
+
+  private checkCircuitBreakers(): void {
+    if (this.recursionDepth > MAX_RECURSION_DEPTH) {
+      throw new CircuitBreakerError(`Maximum recursion depth ${MAX_RECURSION_DEPTH} exceeded`);
+    }
+    if (Date.now() - this.parseStartTime > PARSE_TIMEOUT_MS) {
+      throw new CircuitBreakerError(`Parse timeout ${PARSE_TIMEOUT_MS}ms exceeded`);
+    }
+  }
+
+  private getNodeLocation(node: TreeSitterNode) {
+    return {
+      start: { line: node.startPosition.row + 1, column: node.startPosition.column, index: node.startIndex },
+      end: { line: node.endPosition.row + 1, column: node.endPosition.column, index: node.endIndex },
+    };
+  }
+
+  /**
+   * Walk the statements of a body. Declarations nested in conditionals and blocks (such as a
+   * concern's `included do ... end`) belong to the enclosing class; method bodies are not walked
+   * for declarations.
+   */
+  private visit(
+    node: TreeSitterNode,
+    scope: Scope | null,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+  ): void {
+    this.recursionDepth++;
+    this.checkCircuitBreakers();
+
+    try {
+      // A bare `private` applies to the methods defined after it in the same body
+      let visibility = "public";
+      for (const child of node.namedChildren) {
+        switch (child.type) {
+          case "module":
+          case "class":
+            this.extractType(child, scope, filePath, entities, relationships);
+            break;
+
+          case "singleton_class":
+            if (scope && child.childForFieldName("value")?.type === "self") {
+              const body = child.childForFieldName("body") ?? child;
+              this.visit(body, { ...scope, singleton: true }, filePath, entities, relationships);
+            }
+            break;
+
+          case "method":
+            this.extractMethod(child, scope, scope?.singleton ?? false, visibility, filePath, entities, relationships);
+            break;
+
+          case "singleton_method": {
+            const owner = this.singletonOwner(child, scope);
+            if (owner) this.extractMethod(child, owner, true, "public", filePath, entities, relationships);
+            break;
+          }
+
+          case "identifier":
+            if (scope && VISIBILITIES.has(child.text)) visibility = child.text;
+            break;
+
+          case "call":
+            if (!this.extractMacro(child, scope, filePath, entities, relationships)) {
+              this.visit(child, scope, filePath, entities, relationships);
+            }
+            break;
+
+          case "comment":
+            break;
+
+          default:
+            this.visit(child, scope, filePath, entities, relationships);
+        }
+      }
+    } finally {
+      this.recursionDepth--;
+    }
+  }
+
+  /**
+   * A class or module; `class A::B` inside `module M` declares `M::A::B`. Reopening one
+   * declared earlier in this file adds to that entity.
+   */
+  private extractType(
+    node: TreeSitterNode,
+    scope: Scope | null,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+  ): void {
+    const nameNode = node.childForFieldName("name");
+    if (!nameNode) return;
+    const written = nameNode.text;
+    const qualifiedName = written.startsWith("::")
+      ? written.slice(2)
+      : scope
+        ? `${scope.qualifiedName}::${written}`
+        : written;
+    const kind = node.type === "module" ? "module" : "class";
+    const superclassNode = node.childForFieldName("superclass");
+    const superclass = superclassNode ? this.constantName(superclassNode.namedChildren[0]) : undefined;
+
+    let entity = this.declaredTypes.get(qualifiedName);
+    if (!entity) {
+      entity = {
+        id: `${filePath}:${kind}:${qualifiedName}`,
+        name: qualifiedName.split("::").pop()!,
+        type: kind,
+        filePath,
+        location: this.getNodeLocation(node),
+        metadata: {
+          qualifiedName,
+          namespace: rubyOwnerName(qualifiedName),
+          ...(scope ? { parentClass: scope.qualifiedName } : {}),
+          mixins: [],
+        },
+      };
+      entities.push(entity);
+      this.declaredTypes.set(qualifiedName, entity);
+      if (scope) {
+        relationships.push({ from: entity.id!, to: scope.id, type: "contains", metadata: { memberType: kind } });
+      }
+    } else {
+      entity.metadata!.reopenedAt = [...(entity.metadata!.reopenedAt ?? []), node.startPosition.row + 1];
+    }
+
+    const self: Scope = { id: entity.id!, qualifiedName, singleton: false };
+    if (superclass && !entity.metadata!.superclass) {
+      entity.metadata!.superclass = superclass;
+      this.pushConstantEdge(relationships, self, superclass, "extends", superclassNode!);
+    }
+
+    this.visit(node.childForFieldName("body") ?? node, self, filePath, entities, relationships);
+  }
+
+  /** The class of `def self.x` (the current one) or `def Admin::User.x`/`def User.x` */
+  private singletonOwner(node: TreeSitterNode, scope: Scope | null): Scope | null {
+    const object = node.childForFieldName("object");
+    if (!object) return null;
+    if (object.type === "self") return scope;
+    const name = this.constantName(object);
+    if (!name) return null;
+    const qualifiedName =
+      resolveRubyConstant(name, scope?.qualifiedName ?? "", (qn) => (this.declaredTypes.has(qn) ? qn : undefined)) ??
+      name;
+    const declared = this.declaredTypes.get(qualifiedName);
+    return declared ? { id: declared.id!, qualifiedName, singleton: true } : null;
+  }
+
+  private extractMethod(
+    node: TreeSitterNode,
+    scope: Scope | null,
+    isClassMethod: boolean,
+    visibility: string,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+  ): void {
+    const name = node.childForFieldName("name")?.text;
+    if (!name) return;
+
+    const qualifiedName = scope ? `${scope.qualifiedName}${isClassMethod ? "." : "#"}${name}` : name;
+    const kind = isClassMethod ? "classmethod" : "method";
+    const id = scope ? `${scope.id}:${kind}:${name}` : `${filePath}:function:${name}`;
+    const parameters = node.childForFieldName("parameters");
+
+    entities.push({
+      id,
+      name,
+      type: scope ? "method" : "function",
+      filePath,
+      location: this.getNodeLocation(node),
+      modifiers: [visibility, ...(isClassMethod ? ["static"] : [])],
+      parameters: parameters ? this.extractParameters(parameters) : [],
+      metadata: {
+        qualifiedName,
+        ...(scope ? { className: scope.qualifiedName } : {}),
+        isClassMethod,
+        isPrivate: visibility === "private",
+        isProtected: visibility === "protected",
+        isConstructor: name === "initialize" && !isClassMethod,
+      },
+    });
+    if (!this.declaredMethods.has(qualifiedName)) this.declaredMethods.set(qualifiedName, id);
+    if (scope) {
+      relationships.push({ from: id, to: scope.id, type: "contains", metadata: { memberType: "method" } });
+    }
+
+    const body = node.childForFieldName("body");
+    if (body) this.extractCalls(body, id, scope?.qualifiedName ?? "", isClassMethod, relationships);
+  }
+
+  /**
+   * Class-body macros: mixins, associations, attributes and `private def ...`. Returns false for
+   * other calls, whose blocks are walked for declarations.
+   */
+  private extractMacro(
+    node: TreeSitterNode,
+    scope: Scope | null,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+  ): boolean {
+    const macro = node.childForFieldName("method")?.text;
+    if (!scope || !macro || node.childForFieldName("receiver")) return false;
+    const args = node.childForFieldName("arguments")?.namedChildren ?? [];
+
+    if (macro === "include" || macro === "extend" || macro === "prepend") {
+      const type = this.declaredTypes.get(scope.qualifiedName)!;
+      for (const arg of args) {
+        const module = this.constantName(arg);
+        if (!module) continue;
+        type.metadata!.mixins.push({ kind: macro, module });
+        this.pushConstantEdge(relationships, scope, module, "implements", arg, { mixin: macro });
+      }
+      return true;
+    }
+
+    const plural = ASSOCIATIONS.get(macro);
+    if (plural !== undefined) {
+      const association = args[0]?.type === "simple_symbol" ? args[0].text.slice(1) : undefined;
+      const options = this.keywordOptions(args);
+      if (!association || options.get("polymorphic") === "true") return true;
+      const className = options.get("class_name")?.replace(/^["']|["']$/g, "");
+      const target = className || associationClassName(association, plural);
+      const type = this.declaredTypes.get(scope.qualifiedName)!;
+      type.metadata!.associations = [
+        ...(type.metadata!.associations ?? []),
+        { macro, name: association, className: target },
+      ];
+      this.pushConstantEdge(relationships, scope, target, "references", node, { association: macro });
+      return true;
+    }
+
+    const accessor = ATTRIBUTE_MACROS.get(macro);
+    if (accessor) {
+      for (const arg of args) {
+        if (arg.type !== "simple_symbol") continue;
+        const name = arg.text.slice(1);
+        const id = `${scope.id}:property:${name}`;
+        entities.push({
+          id,
+          name,
+          type: "property",
+          filePath,
+          location: this.getNodeLocation(arg),
+          metadata: { qualifiedName: `${scope.qualifiedName}#${name}`, className: scope.qualifiedName, accessor },
+        });
+        relationships.push({ from: id, to: scope.id, type: "contains", metadata: { memberType: "property" } });
+      }
+      return true;
+    }
+
+    if (VISIBILITIES.has(macro) && args.some((arg) => arg.type === "method")) {
+      for (const arg of args) {
+        if (arg.type !== "method") continue;
+        this.extractMethod(arg, scope, scope.singleton, macro, filePath, entities, relationships);
+      }
+      return true;
+    }
+    return false;
+  }
+
+  /** `key: value` arguments by key, values as written */
+  private keywordOptions(args: TreeSitterNode[]): Map<string, string> {
+    const options = new Map<string, string>();
+    for (const arg of args) {
+      const pairs = arg.type === "pair" ? [arg] : arg.type === "hash" ? arg.namedChildren : [];
+      for (const pair of pairs) {
+        const key = pair.childForFieldName("key")?.text.replace(/^:|:$/g, "");
+        const value = pair.childForFieldName("value")?.text;
+        if (key && value !== undefined) options.set(key, value);
+      }
+    }
+    return options;
+  }
+
+  /** Calls made by a method, without descending into nested declarations */
+  private extractCalls(
+    body: TreeSitterNode,
+    callerId: string,
+    owner: string,
+    singleton: boolean,
+    relationships: EntityRelationship[],
+  ): void {
+    const stack = [...body.namedChildren].reverse();
+    while (stack.length > 0) {
+      const node = stack.pop()!;
+      if (node.type === "method" || node.type === "singleton_method" || node.type === "class") continue;
+      if (node.type === "module" || node.type === "singleton_class") continue;
+      stack.push(...[...node.namedChildren].reverse());
+      if (node.type !== "call") continue;
+
+      const method = node.childForFieldName("method");
+      if (!method) continue;
+      const receiver = node.childForFieldName("receiver")?.text;
+      const relationship: EntityRelationship = {
+        from: callerId,
+        to: method.text,
+        type: "calls",
+        metadata: {
+          line: method.startPosition.row + 1,
+          column: method.startPosition.column,
+          callee: receiver ? `${receiver}.${method.text}` : method.text,
+          calleeName: method.text,
+        },
+      };
+      relationships.push(relationship);
+      this.pending.push({ kind: "call", relationship, name: method.text, owner, singleton, receiver });
+    }
+  }
+
+  /** Edge to a constant named in source; bound to a declaration of this file once the file is walked */
+  private pushConstantEdge(
+    relationships: EntityRelationship[],
+    scope: Scope,
+    name: string,
+    type: EntityRelationship["type"],
+    node: TreeSitterNode,
+    metadata: Record<string, unknown> = {},
+  ): void {
+    const relationship: EntityRelationship = {
+      from: scope.id,
+      to: name,
+      type,
+      metadata: { line: node.startPosition.row + 1, ...metadata },
+    };
+    relationships.push(relationship);
+    this.pending.push({ kind: "constant", relationship, name, scope: scope.qualifiedName });
+  }
+
+  /**
+   * Point constant and call edges at declarations of this file. Calls without a receiver or on
+   * `self` look in the calling class, its superclasses and its mixins declared here (extended
+   * modules for class methods), then among top-level methods; `Const.x` looks for class method
+   * `x` of `Const`, and `Const.new` for its `initialize`.
+   */
+  private bindLocalTargets(): void {
+    const typeNamed = (written: string, scope: string) =>
+      resolveRubyConstant(written, scope, (qn) => (this.declaredTypes.has(qn) ? qn : undefined));
+
+    for (const item of this.pending) {
+      const rel = item.relationship;
+      if (item.kind === "constant") {
+        const local = typeNamed(item.name, item.scope);
+        if (local) rel.to = this.declaredTypes.get(local)!.id!;
+        continue;
+      }
+
+      let start: { type: string; singleton: boolean } | undefined;
+      let name = item.name;
+      if (!item.receiver || item.receiver === "self") {
+        if (item.owner) start = { type: item.owner, singleton: item.singleton };
+      } else {
+        const type = RUBY_CONSTANT_PATH.test(item.receiver) ? typeNamed(item.receiver, item.owner) : undefined;
+        if (type && name === "new") {
+          name = "initialize";
+          start = { type, singleton: false };
+        } else if (type) {
+          start = { type, singleton: true };
+        }
+      }
+      let local = start ? this.findMethod(start.type, start.singleton, name) : undefined;
+      // Methods defined at the top level are private methods of every object
+      if (!local && (!item.receiver || item.receiver === "self")) local = this.declaredMethods.get(name);
+      if (local) rel.to = local;
+    }
+  }
+
+  /** Method `name` on `type` or its ancestors declared in this file, searched in Ruby's order */
+  private findMethod(type: string, singleton: boolean, name: string): string | undefined {
+    const seen = new Set<string>();
+    const queue = [{ type, singleton }];
+    while (queue.length > 0) {
+      const next = queue.shift()!;
+      const key = `${next.type}${next.singleton ? "." : "#"}`;
+      if (seen.has(key)) continue;
+      seen.add(key);
+      const found = this.declaredMethods.get(`${key}${name}`);
+      if (found) return found;
+
+      const entity = this.declaredTypes.get(next.type);
+      if (!entity) continue;
+      const scope = next.type;
+      const resolve = (written: string) =>
+        resolveRubyConstant(written, scope, (qn) => (this.declaredTypes.has(qn) ? qn : undefined));
+      const mixins = (entity.metadata?.mixins ?? []) as Array<{ kind: RubyMixinKind; module: string }>;
+      for (const mixin of mixins) {
+        if ((mixin.kind === "extend") !== next.singleton) continue;
+        const module = resolve(mixin.module);
+        if (module) queue.push({ type: module, singleton: false });
+      }
+      const superclass = entity.metadata?.superclass ? resolve(entity.metadata.superclass) : undefined;
+      if (superclass) queue.push({ type: superclass, singleton: next.singleton });
+    }
+    return undefined;
+  }
+
+  /**
+   * A constant path as written (`ActiveRecord::Base`, `::Api`); the receiver of `Migration[7.1]`
+   * names the superclass. Undefined for anything else.
+   */
+  private constantName(node: TreeSitterNode | null | undefined): string | undefined {
+    if (node?.type === "element_reference") return this.constantName(node.childForFieldName("object"));
+    return node && RUBY_CONSTANT_PATH.test(node.text) ? node.text : undefined;
+  }
+
+  private extractParameters(node: TreeSitterNode): NonNullable<ParsedEntity["parameters"]> {
+    const parameters: NonNullable<ParsedEntity["parameters"]> = [];
+    for (const param of node.namedChildren) {
+      switch (param.type) {
+        case "identifier":
+          parameters.push({ name: param.text });
+          break;
+        case "optional_parameter":
+        case "keyword_parameter": {
+          const name = param.childForFieldName("name")?.text;
+          const value = param.childForFieldName("value")?.text;
+          if (!name) break;
+          parameters.push({
+            name,
+            ...(value !== undefined ? { optional: true, defaultValue: value } : {}),
+          });
+          break;
+        }
+        case "splat_parameter":
+        case "hash_splat_parameter":
+        case "block_parameter": {
+          const name = param.childForFieldName("name")?.text;
+          const prefix = param.type === "splat_parameter" ? "*" : param.type === "block_parameter" ? "&" : "**";
+          parameters.push({ name: `${prefix}${name ?? ""}`, optional: true });
+          break;
+        }
+      }
+    }
+    return parameters;
+  }
+}
@@ -109,9 +109,11 @@ export class RustAnalyzer {
       const location = this.getNodeLocation(modNode);
       const visibility = this.extractVisibility(modNode);
       const isInline = this.hasBody(modNode);
+      const modulePath = this.getModulePath(modNode);
+      const qualifiedName = this.qualifyName(modulePath, name);
 
       entities.push({
-        id: `${filePath}:module:${name}`,
+        id: `${filePath}:module:${qualifiedName}`,
         name,
         type: "module",
         filePath,
@@ -119,6 +121,8 @@ export class RustAnalyzer {
         metadata: {
           visibility,
           isInline,
+          modulePath,
+          qualifiedName,
         },
       });
 
@@ -128,7 +132,7 @@ export class RustAnalyzer {
         if (body) {
           const nestedCount = this.countNestedItems(body);
           relationships.push({
-            from: `${filePath}:module:${name}`,
+            from: `${filePath}:module:${qualifiedName}`,
             to: filePath,
             type: "contains",
             sourceFile: filePath,
@@ -145,7 +149,7 @@ export class RustAnalyzer {
   private extractStructs(
     node: TreeSitterNode,
     entities: ParsedEntity[],
-    relationships: EntityRelationship[],
+    _relationships: EntityRelationship[],
     filePath: string,
   ): void {
     const structNodes = this.findNodes(node, "struct_item");
@@ -163,9 +167,11 @@ export class RustAnalyzer {
       // Determine struct type
       const isTuple = this.isTupleStruct(structNode);
       const isUnit = fields.length === 0 && !isTuple;
+      const modulePath = this.getModulePath(structNode);
+      const qualifiedName = this.qualifyName(modulePath, name);
 
       entities.push({
-        id: `${filePath}:struct:${name}`,
+        id: `${filePath}:struct:${qualifiedName}`,
         name,
         type: "struct",
         filePath,
@@ -178,12 +184,11 @@ export class RustAnalyzer {
           fieldCount: fields.length,
           isTuple,
           isUnit,
+          modulePath,
+          qualifiedName,
           rustType: "struct",
         },
       });
-
-      // Extract trait implementations for this struct
-      this.extractImplBlocks(node, name, entities, relationships, filePath);
     }
   }
 
@@ -207,9 +212,11 @@ export class RustAnalyzer {
       const lifetimes = this.extractLifetimes(enumNode);
       const derives = this.extractDerives(enumNode);
       const variants = this.extractEnumVariants(enumNode, name, entities, filePath);
+      const modulePath = this.getModulePath(enumNode);
+      const qualifiedName = this.qualifyName(modulePath, name);
 
       entities.push({
-        id: `${filePath}:enum:${name}`,
+        id: `${filePath}:enum:${qualifiedName}`,
         name,
         type: "enum",
         filePath,
@@ -221,6 +228,8 @@ export class RustAnalyzer {
           derives,
           variants,
           variantCount: variants.length,
+          modulePath,
+          qualifiedName,
           rustType: "enum",
         },
       });
@@ -250,9 +259,11 @@ export class RustAnalyzer {
       // Extract trait methods
       const methods = this.extractTraitMethods(traitNode, name, entities, filePath);
       const associatedTypes = this.extractAssociatedTypes(traitNode, name, entities, filePath);
+      const modulePath = this.getModulePath(traitNode);
+      const qualifiedName = this.qualifyName(modulePath, name);
 
       entities.push({
-        id: `${filePath}:trait:${name}`,
+        id: `${filePath}:trait:${qualifiedName}`,
         name,
         type: "trait",
         filePath,
@@ -264,6 +275,8 @@ export class RustAnalyzer {
           supertraits,
           methodCount: methods.length,
           associatedTypeCount: associatedTypes.length,
+          modulePath,
+          qualifiedName,
           rustType: "trait",
         },
       });
@@ -271,8 +284,8 @@ export class RustAnalyzer {
       // Create relationships for supertraits
       for (const supertrait of supertraits) {
         relationships.push({
-          from: `${filePath}:trait:${name}`,
-          to: `${filePath}:trait:${supertrait}`,
+          from: `${filePath}:trait:${qualifiedName}`,
+          to: `${filePath}:trait:${this.qualifyName(modulePath, supertrait)}`,
           type: "extends",
           sourceFile: filePath,
         });
@@ -298,9 +311,11 @@ export class RustAnalyzer {
       const lifetimes = this.extractLifetimes(fnNode);
       const parameters = this.extractFunctionParameters(fnNode);
       const returnType = this.extractReturnType(fnNode);
+      const modulePath = this.getModulePath(fnNode);
+      const qualifiedName = this.qualifyName(modulePath, name);
 
       entities.push({
-        id: `${filePath}:function:${name}`,
+        id: `${filePath}:function:${qualifiedName}`,
         name,
         type: "function",
         filePath,
@@ -314,6 +329,8 @@ export class RustAnalyzer {
           lifetimes,
           parameters,
           returnType,
+          modulePath,
+          qualifiedName,
           rustType: "function",
         },
       });
@@ -681,95 +698,6 @@ export class RustAnalyzer {
     return types;
   }
 
-  /**
-   * Extract impl blocks for a type
-   */
-  private extractImplBlocks(
-    node: TreeSitterNode,
-    typeName: string,
-    entities: ParsedEntity[],
-    relationships: EntityRelationship[],
-    filePath: string,
-  ): void {
-    const implNodes = this.findNodes(node, "impl_item");
-
-    for (const implNode of implNodes) {
-      const typeNode = implNode.childForFieldName("type");
-      if (!typeNode) continue;
-
-      const implType = this.getNodeText(typeNode);
-      if (!implType.includes(typeName)) continue;
-
-      // Check if it's a trait implementation
-      const traitNode = implNode.childForFieldName("trait");
-      if (traitNode) {
-        const traitName = this.getNodeText(traitNode);
-
-        relationships.push({
-          from: `${filePath}:struct:${typeName}`,
-          to: `${filePath}:trait:${traitName}`,
-          type: "implements",
-          sourceFile: filePath,
-        });
-
-        // Extract implemented methods
-        const body = implNode.childForFieldName("body");
-        if (body) {
-          const methods = this.findNodes(body, "function_item");
-          for (const method of methods) {
-            const nameNode = method.childForFieldName("name");
-            if (!nameNode) continue;
-
-            const methodName = this.getNodeText(nameNode);
-            const location = this.getNodeLocation(method);
-
-            entities.push({
-              id: `${filePath}:impl:${typeName}:${traitName}:${methodName}`,
-              name: methodName,
-              type: "method",
-              filePath,
-              location,
-              metadata: {
-                implType: typeName,
-                traitName,
-                isTraitImpl: true,
-                rustType: "impl_method",
-              },
-            });
-          }
-        }
-      } else {
-        // Inherent implementation
-        const body = implNode.childForFieldName("body");
-        if (body) {
-          const methods = this.findNodes(body, "function_item");
-          for (const method of methods) {
-            const nameNode = method.childForFieldName("name");
-            if (!nameNode) continue;
-
-            const methodName = this.getNodeText(nameNode);
-            const location = this.getNodeLocation(method);
-            const visibility = this.extractVisibility(method);
-
-            entities.push({
-              id: `${filePath}:impl:${typeName}:${methodName}`,
-              name: methodName,
-              type: "method",
-              filePath,
-              location,
-              metadata: {
-                implType: typeName,
-                visibility,
-                isInherent: true,
-                rustType: "impl_method",
-              },
-            });
-          }
-        }
-      }
-    }
-  }
-
   /**
    * Extract impl blocks globally (even when no struct node provided)
    */
@@ -785,14 +713,21 @@ export class RustAnalyzer {
       const traitNode = implNode.childForFieldName("trait");
       const typeName = typeNode ? this.getNodeText(typeNode) : undefined;
       const traitName = traitNode ? this.getNodeText(traitNode) : undefined;
+      const modulePath = this.getModulePath(implNode);
 
-      // Relationship for trait impl
+      // Relationship for trait impl. Point at the local type/trait entity ids when
+      // they exist so the indexer links them; otherwise fall back to the bare name
+      // (e.g. `impl Display for Foo`) and let it become an external placeholder.
       if (typeName && traitName) {
+        const baseType = this.stripTypeArguments(typeName);
+        const typeId = this.findLocalEntityId(entities, ["struct", "enum", "typedef"], modulePath, baseType);
+        const traitId = this.findLocalEntityId(entities, ["trait"], modulePath, this.stripTypeArguments(traitName));
         relationships.push({
-          from: `${filePath}:struct:${typeName}`,
-          to: `${filePath}:trait:${traitName}`,
+          from: typeId ?? `${filePath}:struct:${this.qualifyName(modulePath, baseType)}`,
+          to: traitId ?? traitName,
           type: "implements",
           sourceFile: filePath,
+          metadata: { line: implNode.startPosition.row + 1, implType: typeName, traitName },
         });
       }
 
@@ -804,9 +739,10 @@ export class RustAnalyzer {
           if (!nameNode) continue;
           const methodName = this.getNodeText(nameNode);
           const location = this.getNodeLocation(method);
+          const implPath = this.qualifyName(modulePath, typeName || "unknown");
 
           entities.push({
-            id: `${filePath}:impl:${typeName || "unknown"}:${traitName || "inherent"}:${methodName}`,
+            id: `${filePath}:impl:${implPath}:${traitName || "inherent"}:${methodName}`,
             name: methodName,
             type: "method",
             filePath,
@@ -814,8 +750,11 @@ export class RustAnalyzer {
             metadata: {
               implType: typeName || "",
               traitName,
+              visibility: this.extractVisibility(method),
               isTraitImpl: Boolean(traitName),
               isInherent: !traitName,
+              modulePath,
+              qualifiedName: this.qualifyName(modulePath, `${typeName || "unknown"}::${methodName}`),
               rustType: "impl_method",
             },
           });
@@ -1510,6 +1449,56 @@ export class RustAnalyzer {
     return paths;
   }
 
+  /**
+   * Names of the inline `mod` blocks enclosing a node, outermost first.
+   */
+  private getModulePath(node: TreeSitterNode): string[] {
+    const path: string[] = [];
+    let current = node.parent;
+    while (current) {
+      if (current.type === "mod_item") {
+        const name = this.resolveName(current);
+        if (name) path.unshift(name);
+      }
+      current = current.parent;
+    }
+    return path;
+  }
+
+  /**
+   * Build a `::`-separated name scoped by the enclosing module path
+   */
+  private qualifyName(modulePath: string[], name: string): string {
+    return modulePath.length > 0 ? `${modulePath.join("::")}::${name}` : name;
+  }
+
+  /**
+   * Drop generic arguments so `Wrapper<T>` matches the `Wrapper` declaration
+   */
+  private stripTypeArguments(typeName: string): string {
+    const idx = typeName.indexOf("<");
+    return (idx >= 0 ? typeName.slice(0, idx) : typeName).trim();
+  }
+
+  /**
+   * Find an already extracted entity by (possibly path-qualified) name, preferring
+   * the declaration in the same module as the reference.
+   */
+  private findLocalEntityId(
+    entities: ParsedEntity[],
+    types: string[],
+    modulePath: string[],
+    name: string,
+  ): string | undefined {
+    const candidates = entities.filter((e) => types.includes(e.type));
+    const scoped = name.includes("::") ? name : this.qualifyName(modulePath, name);
+    const match =
+      candidates.find((e) => e.metadata?.qualifiedName === scoped) ??
+      candidates.find((e) => e.metadata?.qualifiedName === name) ??
+      candidates.find((e) => e.name === name);
+    return match?.id;
+  }
+
   /**
    * Resolve entity name robustly:
    * 1) field name via childForFieldName("name")
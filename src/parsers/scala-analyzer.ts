@@ -0,0 +1,904 @@
+/**
+ * Scala Language Analyzer
+ *
+ * Declarations:
+ * - Package clauses (module entities; chained clauses `package a` / `package b` make `a.b`)
+ * - Classes, case classes, traits, objects, case objects and Scala 3 enums with their cases,
+ *   qualified by nesting (`Cart.Line`)
+ * - Methods (`def`), values and variables (`val`/`var`), type members, and the `val`/`var`
+ *   parameters of a class constructor (every parameter of a case class) as properties
+ * - Givens and the members of Scala 3 `extension` blocks
+ * - Imports, with selectors (`import a.{B, C => D}`) and wildcards
+ * - Annotations and `implicit` as metadata: implicit parameter lists (`using` included), implicit
+ *   values and defs, and implicit conversions (an implicit def or class taking one value)
+ *
+ * Relationships:
+ * - The first parent after `extends` (extends) and the mixins after `with` (implements); the
+ *   parents of a trait all extend it
+ * - Companion objects to the class, trait or enum of the same name in the same scope
+ *   (references), with `companionOf` on the object and `companion` on the type
+ * - Calls, bound to declarations of this file where the receiver allows it
+ */
+
+import type { EntityRelationship, ParsedEntity, TreeSitterNode } from "../types/parser.js";
+
+const MAX_RECURSION_DEPTH = 50;
+const PARSE_TIMEOUT_MS = 5000;
+
+class CircuitBreakerError extends Error {
+  constructor(message: string) {
+    super(message);
+    this.name = "CircuitBreakerError";
+  }
+}
+
+export type ScalaTypeKind = "class" | "trait" | "object" | "enum";
+
+const ENTITY_TYPES: Record<ScalaTypeKind, ParsedEntity["type"]> = {
+  class: "class",
+  trait: "trait",
+  // A singleton: a class-like entity holding its members
+  object: "class",
+  enum: "enum",
+};
+
+const TYPE_NODES: Record<string, ScalaTypeKind> = {
+  class_definition: "class",
+  trait_definition: "trait",
+  object_definition: "object",
+  enum_definition: "enum",
+};
+
+/** Kinds of type a companion object can accompany */
+const COMPANION_KINDS = new Set<ScalaTypeKind>(["class", "trait", "enum"]);
+
+/** A type, object or extension block whose body is being walked */
+interface Scope {
+  id: string;
+  qualifiedName: string;
+  kind: ScalaTypeKind | "extension";
+}
+
+type ScalaParameter = NonNullable<ParsedEntity["parameters"]>[number];
+
+/**
+ * A `type` named as written from package or template `scope`, or a `call` of `name` from `owner`
+ * on `receiver` (none, `this`, or an object path such as `Cart.Line`), which may also land on a
+ * companion's member or an `apply`
+ */
+type PendingTarget =
+  | { kind: "type"; relationship: EntityRelationship; name: string; scope: string }
+  | { kind: "call"; relationship: EntityRelationship; name: string; owner?: Scope; receiver?: string };
+
+/** Parents as written, sorted into superclass and mixins once every trait of the file is known */
+interface PendingParents {
+  entity: ParsedEntity;
+  kind: ScalaTypeKind;
+  scope: string;
+  parents: Array<{ name: string; line: number }>;
+}
+
+/** `Cart` for `Cart.Line` and `Cart.Line.empty`; "" for a top-level name */
+export function scalaOwnerName(qualifiedName: string): string {
+  const dot = qualifiedName.lastIndexOf(".");
+  return dot < 0 ? "" : qualifiedName.slice(0, dot);
+}
+
+/** A type as written without type arguments or constructor arguments: `Repo` for `Repo[User](db)` */
+export function scalaTypeName(written: string): string {
+  return written
+    .replace(/\[.*\]/s, "")
+    .replace(/\(.*\)/s, "")
+    .replace(/^_root_\./, "")
+    .replace(/\s/g, "");
+}
+
+/**
+ * The type `written` names from inside `scope`: `scope.written`, then the same in each enclosing
+ * type, then `written` at the top level.
+ */
+export function resolveScalaType<T>(
+  written: string,
+  scope: string,
+  lookup: (qualifiedName: string) => T | undefined,
+): T | undefined {
+  for (let outer = scope; outer; outer = scalaOwnerName(outer)) {
+    const found = lookup(`${outer}.${written}`);
+    if (found !== undefined) return found;
+  }
+  return lookup(written);
+}
+
+export class ScalaAnalyzer {
+  private recursionDepth = 0;
+  private parseStartTime = 0;
+  private currentPackage = "";
+  /** Classes, traits and enums declared in this file by qualified name */
+  private declaredTypes = new Map<string, ParsedEntity>();
+  /** Objects declared in this file by qualified name; a companion shares its class's name */
+  private declaredObjects = new Map<string, ParsedEntity>();
+  /** Every class, trait, enum and object of this file by id */
+  private definitions = new Map<string, ParsedEntity>();
+  /** Members by `<scope id>#name`, top-level definitions by name */
+  private declaredMembers = new Map<string, string>();
+  private usedIds = new Set<string>();
+  private parents: PendingParents[] = [];
+  private pending: PendingTarget[] = [];
+
+  async analyze(
+    rootNode: TreeSitterNode,
+    filePath: string,
+  ): Promise<{ entities: ParsedEntity[]; relationships: EntityRelationship[] }> {
+    this.resetState();
+
+    const entities: ParsedEntity[] = [];
+    const relationships: EntityRelationship[] = [];
+
+    try {
+      this.visit(rootNode, null, filePath, entities, relationships);
+      this.classifyParents(relationships);
+      this.linkCompanions(relationships);
+      this.bindLocalTargets();
+    } catch (error) {
+      if (error instanceof CircuitBreakerError) {
+        console.warn(`[ScalaAnalyzer] Circuit breaker triggered for ${filePath}: ${error.message}`);
+      } else {
+        console.error(`[ScalaAnalyzer] Error analyzing ${filePath}:`, error);
+      }
+      // Return partial results on error
+    }
+
+    return { entities, relationships };
+  }
+
+  private resetState(): void {
+    this.recursionDepth = 0;
+    this.parseStartTime = Date.now();
+    this.currentPackage = "";
+    this.declaredTypes.clear();
+    this.declaredObjects.clear();
+    this.definitions.clear();
+    this.declaredMembers.clear();
+    this.usedIds.clear();
+    this.parents = [];
+    this.pending = [];
+  }
+
+  private checkCircuitBreakers(): void {
+    if (this.recursionDepth > MAX_RECURSION_DEPTH) {
+      throw new CircuitBreakerError(`Maximum recursion depth ${MAX_RECURSION_DEPTH} exceeded`);
+    }
+    if (Date.now() - this.parseStartTime > PARSE_TIMEOUT_MS) {
+      throw new CircuitBreakerError(`Parse timeout ${PARSE_TIMEOUT_MS}ms exceeded`);
+    }
+  }
+
+  private getNodeLocation(node: TreeSitterNode) {
+    return {
+      start: { line: node.startPosition.row + 1, column: node.startPosition.column, index: node.startIndex },
+      end: { line: node.endPosition.row + 1, column: node.endPosition.column, index: node.endIndex },
+    };
+  }
+
+  /** `id`, or `id@line` when an overload already took it */
+  private uniqueId(id: string, node: TreeSitterNode): string {
+    const unique = this.usedIds.has(id) ? `${id}@${node.startPosition.row + 1}` : id;
+    this.usedIds.add(unique);
+    return unique;
+  }
+
+  /** Walk the definitions of a file, package block or template body; method bodies are only read for calls */
+  private visit(
+    node: TreeSitterNode,
+    scope: Scope | null,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+  ): void {
+    this.recursionDepth++;
+    this.checkCircuitBreakers();
+
+    try {
+      for (const child of node.namedChildren) {
+        switch (child.type) {
+          case "package_clause":
+            this.extractPackage(child, scope, filePath, entities, relationships);
+            break;
+
+          case "class_definition":
+          case "trait_definition":
+          case "object_definition":
+          case "enum_definition":
+            this.extractType(child, scope, filePath, entities, relationships);
+            break;
+
+          case "function_definition":
+          case "function_declaration":
+            this.extractFunction(child, scope, filePath, entities, relationships);
+            break;
+
+          case "val_definition":
+          case "val_declaration":
+          case "var_definition":
+          case "var_declaration":
+          case "given_definition":
+            this.extractValue(child, scope, filePath, entities, relationships);
+            break;
+
+          case "type_definition":
+            this.extractTypeMember(child, scope, filePath, entities, relationships);
+            break;
+
+          case "enum_case_definitions":
+          case "simple_enum_case":
+          case "full_enum_case":
+            if (scope) this.extractEnumCases(child, scope, filePath, entities, relationships);
+            break;
+
+          case "extension_definition":
+            this.extractExtension(child, scope, filePath, entities, relationships);
+            break;
+
+          case "import_declaration":
+            this.extractImport(child, filePath, entities);
+            break;
+        }
+      }
+    } finally {
+      this.recursionDepth--;
+    }
+  }
+
+  /**
+   * `package a.b`, once per clause. A clause with a body (`package a { ... }`) scopes its
+   * definitions; one without applies to the rest of the file.
+   */
+  private extractPackage(
+    node: TreeSitterNode,
+    scope: Scope | null,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+  ): void {
+    const written = (node.childForFieldName("name") ?? node.namedChildren.find((c) => c.type === "package_identifier"))
+      ?.text.replace(/\s/g, "");
+    if (!written) return;
+    const outer = this.currentPackage;
+    this.currentPackage = outer ? `${outer}.${written}` : written;
+
+    entities.push({
+      id: this.uniqueId(`${filePath}:package:${this.currentPackage}`, node),
+      name: this.currentPackage,
+      type: "module",
+      filePath,
+      location: this.getNodeLocation(node),
+      metadata: { isPackage: true },
+    });
+
+    const body = node.childForFieldName("body");
+    if (body) {
+      this.visit(body, scope, filePath, entities, relationships);
+      this.currentPackage = outer;
+    }
+  }
+
+  /** A class, trait, object or enum; nested ones are qualified by the type around them */
+  private extractType(
+    node: TreeSitterNode,
+    scope: Scope | null,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+  ): void {
+    const kind = TYPE_NODES[node.type]!;
+    const name = node.childForFieldName("name")?.text;
+    if (!name) return;
+
+    const qualifiedName = scope && scope.kind !== "extension" ? `${scope.qualifiedName}.${name}` : name;
+    const id = this.uniqueId(`${filePath}:${kind}:${qualifiedName}`, node);
+    const isCase = node.children.some((c) => c.type === "case");
+    const modifiers = [...this.extractModifiers(node), ...(isCase ? ["case"] : [])];
+    const annotations = this.extractAnnotations(node);
+    const { parameters, groups } = this.extractParameterGroups(node, "class_parameters");
+    const implicitParameters = groups.filter((g) => g.implicit).flatMap((g) => g.parameters);
+    const isImplicit = modifiers.includes("implicit");
+    const explicit = groups.filter((g) => !g.implicit).flatMap((g) => g.parameters);
+
+    const entity: ParsedEntity = {
+      id,
+      name,
+      type: ENTITY_TYPES[kind],
+      filePath,
+      location: this.getNodeLocation(node),
+      modifiers,
+      ...(kind === "class" ? { parameters } : {}),
+      metadata: {
+        qualifiedName,
+        scalaKind: isCase ? `case ${kind}` : kind,
+        ...(this.currentPackage ? { package: this.currentPackage } : {}),
+        ...(scope && scope.kind !== "extension" ? { parentClass: scope.qualifiedName } : {}),
+        ...(isCase ? { isCase: true } : {}),
+        ...(modifiers.includes("sealed") ? { isSealed: true } : {}),
+        ...(modifiers.includes("abstract") ? { isAbstract: true } : {}),
+        ...(isImplicit ? { isImplicit: true } : {}),
+        // `implicit class RichInt(n: Int)` converts an Int into a RichInt wherever it is in scope
+        ...(isImplicit && explicit.length === 1 && explicit[0]!.type
+          ? { implicitConversion: { from: explicit[0]!.type, to: name } }
+          : {}),
+        ...(implicitParameters.length > 0 ? { implicitParameters } : {}),
+        ...(annotations.length > 0 ? { annotations } : {}),
+      },
+    };
+    entities.push(entity);
+    this.definitions.set(id, entity);
+    const registry = kind === "object" ? this.declaredObjects : this.declaredTypes;
+    if (!registry.has(qualifiedName)) registry.set(qualifiedName, entity);
+    if (scope) {
+      relationships.push({ from: id, to: scope.id, type: "contains", metadata: { memberType: kind } });
+    }
+
+    const parents = this.parentTypes(node);
+    if (parents.length > 0) {
+      this.parents.push({ entity, kind, scope: scope?.qualifiedName ?? "", parents });
+    }
+
+    const typeScope: Scope = { id, qualifiedName, kind };
+    // Constructor parameters declared `val`/`var`, and all of a case class's, are members
+    if (kind === "class") this.extractClassParameters(node, typeScope, isCase, filePath, entities, relationships);
+
+    const body = node.childForFieldName("body");
+    if (body) this.visit(body, typeScope, filePath, entities, relationships);
+  }
+
+  /** The types named after `extends`, `with` and `,` in a definition, in order */
+  private parentTypes(node: TreeSitterNode): Array<{ name: string; line: number }> {
+    const clause = node.childForFieldName("extend") ?? node.namedChildren.find((c) => c.type === "extends_clause");
+    if (!clause) return [];
+    return clause.namedChildren
+      .filter((c) => c.type !== "arguments" && c.type !== "comment")
+      .map((c) => ({ name: scalaTypeName(c.text), line: c.startPosition.row + 1 }))
+      .filter((parent) => /^[A-Za-z_][\w.]*$/.test(parent.name));
+  }
+
+  /** `val`/`var` constructor parameters as properties; a case class makes every parameter a `val` */
+  private extractClassParameters(
+    node: TreeSitterNode,
+    scope: Scope,
+    isCase: boolean,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+  ): void {
+    const lists = node.namedChildren.filter((c) => c.type === "class_parameters");
+    lists.forEach((list, index) => {
+      for (const parameter of list.namedChildren) {
+        if (parameter.type !== "class_parameter") continue;
+        const binding = parameter.children.find((c) => c.type === "val" || c.type === "var")?.type;
+        // Only the first parameter list of a case class holds its fields
+        if (!binding && !(isCase && index === 0)) continue;
+        const name = parameter.childForFieldName("name")?.text;
+        if (!name) continue;
+        const type = parameter.childForFieldName("type")?.text;
+        const id = this.uniqueId(`${scope.id}:property:${name}`, parameter);
+
+        entities.push({
+          id,
+          name,
+          type: "property",
+          filePath,
+          location: this.getNodeLocation(parameter),
+          modifiers: this.extractModifiers(parameter),
+          metadata: {
+            qualifiedName: `${scope.qualifiedName}.${name}`,
+            className: scope.qualifiedName,
+            mutability: binding ?? "val",
+            ...(type ? { propertyType: type } : {}),
+            isConstructorParameter: true,
+          },
+        });
+        this.declareMember(scope, name, id);
+        relationships.push({ from: id, to: scope.id, type: "contains", metadata: { memberType: "property" } });
+      }
+    });
+  }
+
+  /**
+   * Parameter lists of a def or class constructor, flattened, and each list on its own marked
+   * when it is `implicit` or `using`
+   */
+  private extractParameterGroups(
+    node: TreeSitterNode,
+    listType: "parameters" | "class_parameters",
+  ): {
+    parameters: ScalaParameter[];
+    groups: Array<{ implicit: boolean; parameters: ScalaParameter[] }>;
+  } {
+    const groups: Array<{ implicit: boolean; parameters: ScalaParameter[] }> = [];
+    for (const list of node.namedChildren) {
+      if (list.type !== listType) continue;
+      const implicit = list.children.some((c) => c.type === "implicit" || c.type === "using");
+      const parameters: ScalaParameter[] = [];
+      for (const parameter of list.namedChildren) {
+        if (parameter.type !== "parameter" && parameter.type !== "class_parameter") continue;
+        const name = parameter.childForFieldName("name")?.text;
+        const type = parameter.childForFieldName("type")?.text;
+        // `(using Ordering[A])` names no parameter
+        if (!name && !type) continue;
+        const defaultValue = parameter.childForFieldName("default_value")?.text;
+        parameters.push({
+          name: name ?? "_",
+          ...(type ? { type } : {}),
+          ...(defaultValue !== undefined ? { optional: true, defaultValue } : {}),
+        });
+      }
+      groups.push({ implicit, parameters });
+    }
+    return { parameters: groups.flatMap((g) => g.parameters), groups };
+  }
+
+  /** `def` definitions and abstract declarations */
+  private extractFunction(
+    node: TreeSitterNode,
+    scope: Scope | null,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+  ): void {
+    const name = node.childForFieldName("name")?.text;
+    if (!name) return;
+
+    const { parameters, groups } = this.extractParameterGroups(node, "parameters");
+    const modifiers = this.extractModifiers(node);
+    const annotations = this.extractAnnotations(node);
+    const returnType = node.childForFieldName("return_type")?.text;
+    const implicitParameters = groups.filter((g) => g.implicit).flatMap((g) => g.parameters);
+    const explicitGroups = groups.filter((g) => !g.implicit);
+    const isImplicit = modifiers.includes("implicit");
+    const member = scope && scope.kind !== "extension";
+    const qualifiedName = member ? `${scope.qualifiedName}.${name}` : name;
+    const id = this.uniqueId(member ? `${scope.id}:method:${name}` : `${filePath}:function:${name}`, node);
+    // `implicit def toJson(u: User): Json` lets a User stand in wherever a Json is expected
+    const single = explicitGroups.length === 1 && explicitGroups[0]!.parameters.length === 1;
+    const from = single ? explicitGroups[0]!.parameters[0]!.type : undefined;
+    const conversion = isImplicit && from && returnType ? { from, to: returnType } : undefined;
+
+    entities.push({
+      id,
+      name,
+      type: member ? "method" : "function",
+      filePath,
+      location: this.getNodeLocation(node),
+      modifiers,
+      parameters,
+      ...(returnType ? { returnType } : {}),
+      metadata: {
+        qualifiedName,
+        ...(this.currentPackage ? { package: this.currentPackage } : {}),
+        ...(member ? { className: scope.qualifiedName } : {}),
+        ...(scope?.kind === "extension" ? { isExtension: true, extensionOf: scope.qualifiedName } : {}),
+        // Members of an object are called without an instance
+        isStatic: scope?.kind === "object",
+        isConstructor: name === "this",
+        isAbstract: node.type === "function_declaration",
+        isOverride: modifiers.includes("override"),
+        ...(explicitGroups.length > 1 ? { parameterLists: groups.length } : {}),
+        ...(isImplicit ? { isImplicit: true } : {}),
+        ...(conversion ? { implicitConversion: conversion } : {}),
+        ...(implicitParameters.length > 0 ? { implicitParameters } : {}),
+        ...(annotations.length > 0 ? { annotations } : {}),
+      },
+    });
+    this.declareMember(scope, name, id);
+    if (member) {
+      relationships.push({ from: id, to: scope.id, type: "contains", metadata: { memberType: "method" } });
+    }
+
+    const body = node.childForFieldName("body");
+    if (body) this.extractCalls(body, id, member ? scope : undefined, relationships);
+  }
+
+  /** `val`, `var` and `given` definitions and declarations; `val (a, b) = ...` binds each name */
+  private extractValue(
+    node: TreeSitterNode,
+    scope: Scope | null,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+  ): void {
+    const isGiven = node.type === "given_definition";
+    const mutability = isGiven ? "given" : node.type.startsWith("var") ? "var" : "val";
+    const modifiers = this.extractModifiers(node);
+    const annotations = this.extractAnnotations(node);
+    const valueType = (node.childForFieldName("type") ?? node.childForFieldName("return_type"))?.text;
+    const isImplicit = isGiven || modifiers.includes("implicit");
+    const member = scope && scope.kind !== "extension";
+
+    const pattern = node.childForFieldName("pattern") ?? node.childForFieldName("name");
+    // Inside a pattern a capitalized name is a constant to match, not a binding: `val (Some(a), b) = ...`
+    const names = !pattern
+      ? []
+      : pattern.type === "identifier"
+        ? [pattern.text]
+        : pattern.descendantsOfType("identifier").map((n) => n.text).filter((n) => /^[a-z_$]/.test(n));
+    // `given Ordering[Int] = ...` names nothing
+    const bound = [...new Set(names.filter((n) => /^[A-Za-z_$][\w$]*$/.test(n)))];
+    // Scala 2 inferred names of anonymous givens: `given_Ordering_Int`
+    if (bound.length === 0 && isGiven && valueType) bound.push(`given_${scalaTypeName(valueType)}`);
+
+    for (const name of bound) {
+      const topLevelKind = mutability === "var" ? "variable" : "constant";
+      const id = this.uniqueId(member ? `${scope.id}:property:${name}` : `${filePath}:${topLevelKind}:${name}`, node);
+      entities.push({
+        id,
+        name,
+        type: member ? "property" : topLevelKind,
+        filePath,
+        location: this.getNodeLocation(node),
+        modifiers,
+        metadata: {
+          qualifiedName: member ? `${scope.qualifiedName}.${name}` : name,
+          ...(this.currentPackage ? { package: this.currentPackage } : {}),
+          ...(member ? { className: scope.qualifiedName } : {}),
+          mutability,
+          ...(valueType ? { propertyType: valueType } : {}),
+          isStatic: scope?.kind === "object",
+          isLazy: modifiers.includes("lazy"),
+          isAbstract: node.type.endsWith("_declaration"),
+          ...(isImplicit ? { isImplicit: true } : {}),
+          ...(annotations.length > 0 ? { annotations } : {}),
+        },
+      });
+      this.declareMember(scope, name, id);
+      if (member) {
+        relationships.push({ from: id, to: scope.id, type: "contains", metadata: { memberType: "property" } });
+      }
+    }
+
+    const value = node.childForFieldName("value") ?? node.childForFieldName("body");
+    const first = bound[0] ? this.declaredMembers.get(this.memberKey(scope, bound[0])) : undefined;
+    if (value && first) this.extractCalls(value, first, member ? scope : undefined, relationships);
+  }
+
+  /** `type Id = String` and abstract `type T` members */
+  private extractTypeMember(
+    node: TreeSitterNode,
+    scope: Scope | null,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+  ): void {
+    const name = node.childForFieldName("name")?.text;
+    if (!name) return;
+    const qualifiedName = scope ? `${scope.qualifiedName}.${name}` : name;
+    const id = this.uniqueId(`${filePath}:type:${qualifiedName}`, node);
+    const value = node.childForFieldName("type")?.text;
+    const modifiers = this.extractModifiers(node);
+
+    entities.push({
+      id,
+      name,
+      type: "typedef",
+      filePath,
+      location: this.getNodeLocation(node),
+      modifiers,
+      metadata: {
+        qualifiedName,
+        ...(scope ? { parentClass: scope.qualifiedName } : {}),
+        ...(value ? { aliasOf: value } : {}),
+        ...(modifiers.includes("opaque") ? { isOpaque: true } : {}),
+      },
+    });
+    if (scope) {
+      relationships.push({ from: id, to: scope.id, type: "contains", metadata: { memberType: "type" } });
+    }
+  }
+
+  /** Scala 3 `case Red, Green` and `case Rgb(r: Int) extends Color`: one enum_variant per case */
+  private extractEnumCases(
+    node: TreeSitterNode,
+    scope: Scope,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+  ): void {
+    const cases =
+      node.type === "enum_case_definitions"
+        ? node.namedChildren.filter((c) => c.type === "simple_enum_case" || c.type === "full_enum_case")
+        : [node];
+    for (const item of cases) {
+      const name = item.childForFieldName("name")?.text;
+      if (!name) continue;
+      const id = this.uniqueId(`${scope.id}:case:${name}`, item);
+      const { parameters } = this.extractParameterGroups(item, "class_parameters");
+
+      entities.push({
+        id,
+        name,
+        type: "enum_variant",
+        filePath,
+        location: this.getNodeLocation(item),
+        ...(parameters.length > 0 ? { parameters } : {}),
+        metadata: { qualifiedName: `${scope.qualifiedName}.${name}`, className: scope.qualifiedName },
+      });
+      this.declareMember(scope, name, id);
+      relationships.push({ from: id, to: scope.id, type: "contains", metadata: { memberType: "case" } });
+    }
+  }
+
+  /** `extension (c: Circle) def area: Double = ...`: the members are functions of the extended type */
+  private extractExtension(
+    node: TreeSitterNode,
+    scope: Scope | null,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+  ): void {
+    const receiver = node.namedChildren.find((c) => c.type === "parameters")?.namedChildren[0];
+    const written = receiver?.childForFieldName("type")?.text;
+    if (!written) return;
+    const extension: Scope = { id: scope?.id ?? filePath, qualifiedName: scalaTypeName(written), kind: "extension" };
+    const body = node.childForFieldName("body");
+    if (!body) return;
+    // A single extension method is the body itself
+    if (body.type === "function_definition") this.extractFunction(body, extension, filePath, entities, relationships);
+    else this.visit(body, extension, filePath, entities, relationships);
+  }
+
+  /**
+   * `import a.b.C`, `import a.b.{C, D => E}`, `import a.b._` and Scala 3's `import a.b.*` and
+   * `import a.b.C as E`; one entity per imported path
+   */
+  private extractImport(node: TreeSitterNode, filePath: string, entities: ParsedEntity[]): void {
+    const text = node.text.replace(/^import\s+/, "").replace(/\/\/.*$/gm, "");
+    for (const clause of this.splitImportClauses(text)) {
+      const braces = clause.match(/^([\w.`]+)\.\{(.*)\}$/s);
+      const base = (braces ? braces[1]! : clause).replace(/`/g, "");
+      const specifiers: Array<{ local: string; imported?: string; alias?: string }> = [];
+      let source = base;
+      let isNamespace = false;
+
+      if (braces) {
+        for (const selector of braces[2]!.split(",").map((s) => s.trim())) {
+          const [imported, alias] = selector.split(/\s*(?:=>|\bas\b)\s*/);
+          if (!imported) continue;
+          if (imported === "_" || imported === "*") isNamespace = true;
+          else if (alias !== "_") {
+            specifiers.push({ local: alias || imported, imported, ...(alias ? { alias } : {}) });
+          }
+        }
+      } else {
+        const renamed = base.match(/^([\w.]+)\s+as\s+(\w+)$/);
+        const path = renamed ? renamed[1]! : base;
+        const dot = path.lastIndexOf(".");
+        const last = path.slice(dot + 1);
+        if (last === "_" || last === "*") {
+          source = path.slice(0, dot);
+          isNamespace = true;
+        } else {
+          source = dot < 0 ? path : path.slice(0, dot);
+          specifiers.push({ local: renamed?.[2] ?? last, imported: last, ...(renamed ? { alias: renamed[2] } : {}) });
+        }
+      }
+      if (!/^[\w.]+$/.test(source)) continue;
+
+      entities.push({
+        id: this.uniqueId(`${filePath}:import:${clause.replace(/\s+/g, "")}`, node),
+        name: source,
+        type: "import",
+        filePath,
+        location: this.getNodeLocation(node),
+        importData: { source, specifiers, isRelative: false, ...(isNamespace ? { isNamespace: true } : {}) },
+        metadata: { ...(isNamespace ? { isWildcard: true } : {}) },
+      });
+    }
+  }
+
+  /** `a.B, c.{D, E}` into its clauses, keeping the commas inside braces */
+  private splitImportClauses(text: string): string[] {
+    const clauses: string[] = [];
+    let depth = 0;
+    let start = 0;
+    for (let i = 0; i < text.length; i++) {
+      const ch = text[i];
+      if (ch === "{") depth++;
+      else if (ch === "}") depth--;
+      else if (ch === "," && depth === 0) {
+        clauses.push(text.slice(start, i));
+        start = i + 1;
+      }
+    }
+    clauses.push(text.slice(start));
+    return clauses.map((c) => c.replace(/\s+/g, " ").replace(/\s*([.{}])\s*/g, "$1").trim()).filter(Boolean);
+  }
+
+  /** Modifiers as written: `private[shop]`, `sealed`, `implicit`, `lazy`, `override`, `final`, ... */
+  private extractModifiers(node: TreeSitterNode): string[] {
+    const modifiers: string[] = [];
+    for (const holder of node.namedChildren) {
+      if (holder.type !== "modifiers") continue;
+      for (const child of holder.children) {
+        if (child.type !== "annotation") modifiers.push(child.text.replace(/\s+/g, ""));
+      }
+    }
+    return modifiers.filter(Boolean);
+  }
+
+  /** `@name` of each annotation on a definition: `deprecated`, `tailrec`, `JsonCodec`, ... */
+  private extractAnnotations(node: TreeSitterNode): string[] {
+    const annotations: string[] = [];
+    for (const child of node.namedChildren) {
+      const found = child.type === "annotation" ? [child] : child.type === "modifiers" ? child.namedChildren : [];
+      for (const annotation of found) {
+        const match = annotation.text.match(/^@([\w.]+)/);
+        if (annotation.type === "annotation" && match?.[1]) annotations.push(match[1]);
+      }
+    }
+    return annotations;
+  }
+
+  private memberKey(scope: Scope | null, name: string): string {
+    return scope && scope.kind !== "extension" ? `${scope.id}#${name}` : name;
+  }
+
+  private declareMember(scope: Scope | null, name: string, id: string): void {
+    const key = this.memberKey(scope, name);
+    if (!this.declaredMembers.has(key)) this.declaredMembers.set(key, id);
+  }
+
+  /** Calls made by a method or value initializer, without descending into nested definitions */
+  private extractCalls(
+    body: TreeSitterNode,
+    callerId: string,
+    owner: Scope | undefined,
+    relationships: EntityRelationship[],
+  ): void {
+    const stack = [body];
+    while (stack.length > 0) {
+      const node = stack.pop()!;
+      if (node !== body && TYPE_NODES[node.type]) continue;
+      stack.push(...[...node.namedChildren].reverse());
+      if (node.type !== "call_expression") continue;
+
+      let callee = node.childForFieldName("function") ?? node.namedChildren[0];
+      if (callee?.type === "generic_function") callee = callee.childForFieldName("function") ?? callee;
+      let name: string | undefined;
+      let receiver: string | undefined;
+      if (callee?.type === "identifier") {
+        name = callee.text;
+      } else if (callee?.type === "field_expression") {
+        name = callee.childForFieldName("field")?.text;
+        receiver = callee.childForFieldName("value")?.text;
+      }
+      if (!name || !/^[A-Za-z_]\w*$/.test(name)) continue;
+
+      const relationship: EntityRelationship = {
+        from: callerId,
+        to: name,
+        type: "calls",
+        metadata: {
+          line: node.startPosition.row + 1,
+          column: node.startPosition.column,
+          callee: receiver ? `${receiver}.${name}` : name,
+          calleeName: name,
+        },
+      };
+      relationships.push(relationship);
+      this.pending.push({ kind: "call", relationship, name, owner, receiver });
+    }
+  }
+
+  /** Edge to a type named in source; bound to a declaration of this file once the file is walked */
+  private pushTypeEdge(
+    relationships: EntityRelationship[],
+    fromId: string,
+    scope: string,
+    name: string,
+    type: EntityRelationship["type"],
+    line: number,
+    metadata: Record<string, unknown> = {},
+  ): void {
+    const relationship: EntityRelationship = { from: fromId, to: name, type, metadata: { line, ...metadata } };
+    relationships.push(relationship);
+    this.pending.push({ kind: "type", relationship, name, scope });
+  }
+
+  private localType(written: string, scope: string): ParsedEntity | undefined {
+    return resolveScalaType(written, scope, (qn) => this.declaredTypes.get(qn));
+  }
+
+  /**
+   * Extends and mixin edges. A class or object extends its first parent unless that is a trait of
+   * this file, which it only mixes in; every parent after `with` (or a Scala 3 comma) is a mixin.
+   */
+  private classifyParents(relationships: EntityRelationship[]): void {
+    for (const { entity, kind, scope, parents } of this.parents) {
+      const mixins: string[] = [];
+      parents.forEach(({ name, line }, index) => {
+        const isTrait = this.localType(name, scope)?.metadata?.scalaKind === "trait";
+        if (kind === "trait" || (index === 0 && !isTrait)) {
+          if (index === 0) entity.metadata!.superclass = name;
+          else mixins.push(name);
+          this.pushTypeEdge(relationships, entity.id!, scope, name, "extends", line);
+        } else {
+          mixins.push(name);
+          this.pushTypeEdge(relationships, entity.id!, scope, name, "implements", line, { mixin: true });
+        }
+      });
+      if (mixins.length > 0) entity.metadata!.mixins = mixins;
+    }
+  }
+
+  /** An object sharing its name with a class, trait or enum of the same scope is that type's companion */
+  private linkCompanions(relationships: EntityRelationship[]): void {
+    for (const [qualifiedName, object] of this.declaredObjects) {
+      const type = this.declaredTypes.get(qualifiedName);
+      if (!type || !COMPANION_KINDS.has(type.metadata!.scalaKind.replace(/^case /, ""))) continue;
+      object.metadata!.isCompanion = true;
+      object.metadata!.companionOf = type.id;
+      type.metadata!.companion = object.id;
+      relationships.push({
+        from: object.id!,
+        to: type.id!,
+        type: "references",
+        metadata: { line: object.location.start.line, companion: true },
+      });
+    }
+  }
+
+  /**
+   * Point type and call edges at declarations of this file. Calls without a receiver or on `this`
+   * look in the calling type and its parents, then its companion, then among top-level
+   * definitions; `Name.x` looks in object `Name`, and `Name(...)` for its `apply` or the class.
+   */
+  private bindLocalTargets(): void {
+    for (const item of this.pending) {
+      const rel = item.relationship;
+      if (item.kind === "type") {
+        const local = this.localType(item.name, item.scope);
+        if (local) rel.to = local.id!;
+        continue;
+      }
+
+      const scope = item.owner?.qualifiedName ?? "";
+      let local: string | undefined;
+      if (!item.receiver || item.receiver === "this") {
+        if (item.owner) local = this.findMember(item.owner.id, item.name);
+        if (!local && item.owner) {
+          const companion = this.declaredObjects.get(item.owner.qualifiedName);
+          if (companion && companion.id !== item.owner.id) local = this.findMember(companion.id!, item.name);
+        }
+        if (!local && !item.receiver) {
+          const object = resolveScalaType(item.name, scope, (qn) => this.declaredObjects.get(qn));
+          const type = this.localType(item.name, scope);
+          local = (object && this.findMember(object.id!, "apply")) ?? type?.id ?? object?.id;
+        }
+        if (!local && !item.receiver) local = this.declaredMembers.get(item.name);
+      } else if (/^[A-Z]\w*(\.[A-Z]\w*)*$/.test(item.receiver)) {
+        const object = resolveScalaType(item.receiver, scope, (qn) => this.declaredObjects.get(qn));
+        if (object) local = this.findMember(object.id!, item.name);
+      }
+      if (local) rel.to = local;
+    }
+  }
+
+  /** Member `name` of the type or object `id`, or of its parents declared in this file */
+  private findMember(id: string, name: string): string | undefined {
+    const seen = new Set<string>();
+    const queue = [id];
+    while (queue.length > 0) {
+      const next = queue.shift()!;
+      if (seen.has(next)) continue;
+      seen.add(next);
+      const found = this.declaredMembers.get(`${next}#${name}`);
+      if (found) return found;
+
+      const entity = this.definitions.get(next);
+      const scope = scalaOwnerName(String(entity?.metadata?.qualifiedName ?? ""));
+      for (const parent of [entity?.metadata?.superclass, ...(entity?.metadata?.mixins ?? [])]) {
+        const local = typeof parent === "string" ? this.localType(parent, scope) : undefined;
+        if (local) queue.push(local.id!);
+      }
+    }
+    return undefined;
+  }
+}
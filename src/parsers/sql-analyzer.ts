@@ -0,0 +1,1018 @@
+/**
+ * SQL Analyzer - DDL in `.sql` files (migrations, schema dumps) as part of the code graph
+ * Read by a hand-written statement splitter and parser covering the Postgres and MySQL DDL that
+ * repositories keep; statements it does not model (DML, indexes, grants, `SET`) are skipped.
+ * `CREATE TABLE` becomes a `table` entity containing one `field` per column, `CREATE VIEW` a `view`,
+ * and `CREATE FUNCTION` / `CREATE PROCEDURE` a `function` told apart by `metadata.sqlKind`.
+ * Foreign keys are `foreign_key` edges from a table to the table it references, and views and
+ * routines reference the tables they read or write.
+ *
+ * `ALTER TABLE ... ADD` on a table created in the same file extends it; on a table created in
+ * another file it yields a table entity marked `altered`, which list_tables merges with the
+ * definition. References to tables of other files are left on placeholders for the SQL linker.
+ */
+
+import type { EntityRelationship, ParsedEntity } from "../types/parser.js";
+
+export type SqlKind = "table" | "view" | "column" | "function" | "procedure";
+
+export interface SqlColumn {
+  name: string;
+  dataType: string;
+  nullable: boolean;
+  primaryKey?: boolean;
+  unique?: boolean;
+  default?: string;
+  autoIncrement?: boolean;
+  comment?: string;
+}
+
+export interface SqlForeignKey {
+  columns: string[];
+  /** Referenced table, normalised like `metadata.qualifiedName` */
+  table: string;
+  referencedColumns: string[];
+  constraint?: string;
+  onDelete?: string;
+  onUpdate?: string;
+}
+
+type Token = {
+  kind: "word" | "quoted" | "string" | "number" | "symbol" | "body" | "end" | "eof";
+  text: string;
+  line: number;
+  column: number;
+  index: number;
+  end: { line: number; column: number; index: number };
+};
+
+type Comment = { text: string; startLine: number; endLine: number; ownLine: boolean };
+
+type SqlName = { key: string; written: string; last: string; schema?: string; start: Token; end: Token };
+
+const CREATE_KINDS = new Set([
+  "table",
+  "view",
+  "function",
+  "procedure",
+  "index",
+  "trigger",
+  "sequence",
+  "type",
+  "schema",
+  "extension",
+  "database",
+  "event",
+  "domain",
+  "role",
+  "user",
+  "policy",
+]);
+const CREATE_MODIFIERS = new Set(["temporary", "temp", "unlogged", "materialized", "recursive"]);
+// Words that end a column's data type and start its constraints
+const COLUMN_CLAUSES = new Set([
+  "not",
+  "null",
+  "default",
+  "primary",
+  "references",
+  "unique",
+  "check",
+  "constraint",
+  "collate",
+  "generated",
+  "auto_increment",
+  "autoincrement",
+  "comment",
+  "on",
+  "as",
+  "charset",
+  "storage",
+  "compression",
+  "visible",
+  "invisible",
+]);
+const TABLE_CONSTRAINTS = new Set([
+  "constraint",
+  "primary",
+  "foreign",
+  "unique",
+  "check",
+  "key",
+  "index",
+  "fulltext",
+  "spatial",
+  "exclude",
+  "like",
+  "period",
+]);
+// Words after a routine's RETURNS type
+const ROUTINE_CLAUSES = new Set([
+  "language",
+  "as",
+  "immutable",
+  "stable",
+  "volatile",
+  "strict",
+  "called",
+  "security",
+  "leakproof",
+  "parallel",
+  "cost",
+  "rows",
+  "set",
+  "window",
+  "begin",
+  "return",
+  "deterministic",
+  "not",
+  "reads",
+  "modifies",
+  "contains",
+  "no",
+  "sql",
+  "comment",
+]);
+const PARAMETER_MODES = new Set(["in", "out", "inout", "variadic"]);
+// First words of multi-word types, so `double precision` is not read as parameter `double`
+const MULTIWORD_TYPES = new Set(["double", "character", "char", "national", "timestamp", "time", "bit", "interval"]);
+// Opening parentheses after these hold a query rather than a function's arguments
+const QUERY_WORDS = new Set([
+  "from",
+  "join",
+  "in",
+  "exists",
+  "as",
+  "union",
+  "intersect",
+  "except",
+  "all",
+  "any",
+  "some",
+  "lateral",
+  "select",
+  "where",
+  "and",
+  "or",
+  "not",
+  "on",
+  "then",
+  "else",
+  "return",
+  "query",
+  "values",
+]);
+const NOT_TABLES = new Set(["select", "lateral", "only", "unnest", "values", "dual"]);
+// Words that end a FROM list item, so they are not read as its alias
+const FROM_CLAUSES = new Set([
+  "where",
+  "join",
+  "inner",
+  "left",
+  "right",
+  "full",
+  "cross",
+  "natural",
+  "on",
+  "using",
+  "group",
+  "order",
+  "having",
+  "limit",
+  "window",
+  "union",
+  "intersect",
+  "except",
+  "set",
+  "returning",
+  "for",
+  "select",
+  "values",
+  "default",
+]);
+// `END IF` and the like close blocks whose openers are not counted
+const BLOCK_ENDS = new Set(["if", "loop", "while", "repeat"]);
+
+/** Normalised name of an identifier chain: unquoted parts fold to lower case, as both dialects compare them */
+function nameKey(parts: Array<{ text: string; quoted: boolean }>): string {
+  return parts.map((part) => (part.quoted ? part.text : part.text.toLowerCase())).join(".");
+}
+
+/**
+ * The table among `tables` (normalised names) that `written` refers to: the same name, else the
+ * only table whose unqualified name matches when either side leaves the schema out, since a search
+ * path or the connection's database supplies it.
+ */
+export function matchSqlTable(written: string, tables: Iterable<string>): string | undefined {
+  const keys = [...tables];
+  if (keys.includes(written)) return written;
+  const last = (key: string) => key.slice(key.lastIndexOf(".") + 1);
+  const qualified = written.includes(".");
+  const matches = keys.filter((key) => last(key) === last(written) && (!qualified || !key.includes(".")));
+  return matches.length === 1 ? matches[0] : undefined;
+}
+
+function tokenize(content: string): { tokens: Token[]; comments: Comment[] } {
+  const tokens: Token[] = [];
+  const comments: Comment[] = [];
+  let line = 1;
+  let lineStart = 0;
+  let lastTokenLine = 0;
+  let delimiter = ";";
+  let i = 0;
+
+  const position = (index: number) => ({ line, column: index - lineStart, index });
+  const advance = (to: number) => {
+    for (; i < to; i++) {
+      if (content[i] === "\n") {
+        line++;
+        lineStart = i + 1;
+      }
+    }
+  };
+  const push = (kind: Token["kind"], text: string, start: ReturnType<typeof position>, end: number) => {
+    advance(end);
+    tokens.push({ kind, text, ...start, end: position(end) });
+    lastTokenLine = line;
+  };
+
+  while (i < content.length) {
+    const ch = content[i]!;
+    if (/\s/.test(ch)) {
+      advance(i + 1);
+      continue;
+    }
+    const start = position(i);
+    const lineEnd = content.indexOf("\n", i) === -1 ? content.length : content.indexOf("\n", i);
+
+    // `DELIMITER //` is a mysql client command: it changes what ends a statement until the next one
+    const directive =
+      (ch === "d" || ch === "D") && !content.slice(lineStart, i).trim()
+        ? /^delimiter[ \t]+(\S+)/i.exec(content.slice(i, lineEnd))
+        : null;
+    if (directive) {
+      push("end", "DELIMITER", start, lineEnd);
+      delimiter = directive[1]!;
+      continue;
+    }
+    if (delimiter !== ";" && content.startsWith(delimiter, i)) {
+      push("end", delimiter, start, i + delimiter.length);
+      continue;
+    }
+
+    if ((ch === "-" && content[i + 1] === "-") || (ch === "/" && content[i + 1] === "*")) {
+      const close = ch === "-" ? lineEnd : content.indexOf("*/", i + 2);
+      const end = close === -1 ? content.length : ch === "-" ? close : close + 2;
+      const text = content.slice(i, end);
+      advance(end);
+      comments.push({ text, startLine: start.line, endLine: line, ownLine: lastTokenLine !== start.line });
+      continue;
+    }
+
+    if (ch === "'" || ch === '"' || ch === "`") {
+      let end = i + 1;
+      let text = "";
+      while (end < content.length) {
+        const c = content[end]!;
+        if (c === ch && content[end + 1] === ch) {
+          text += ch;
+          end += 2;
+        } else if (c === ch) {
+          end++;
+          break;
+        } else if (c === "\\" && ch === "'" && end + 1 < content.length) {
+          text += content[end + 1];
+          end += 2;
+        } else {
+          text += c;
+          end++;
+        }
+      }
+      push(ch === "'" ? "string" : "quoted", text, start, end);
+      continue;
+    }
+
+    // Postgres dollar quoting: `$$ ... $$`, `$body$ ... $body$`
+    const dollar = ch === "$" ? /^\$([A-Za-z_]\w*)?\$/.exec(content.slice(i, i + 64)) : null;
+    if (dollar) {
+      const tag = dollar[0];
+      const close = content.indexOf(tag, i + tag.length);
+      const end = close === -1 ? content.length : close + tag.length;
+      push("body", content.slice(i + tag.length, close === -1 ? content.length : close), start, end);
+      continue;
+    }
+
+    let end = i + 1;
+    let kind: Token["kind"] = "symbol";
+    if (/[A-Za-z_\u0080-\uffff]/.test(ch)) {
+      while (end < content.length && /[\w$\u0080-\uffff]/.test(content[end]!)) end++;
+      kind = "word";
+    } else if (/[0-9]/.test(ch)) {
+      while (end < content.length && /[\w.]/.test(content[end]!)) end++;
+      kind = "number";
+    } else if (ch === ";" && delimiter === ";") {
+      kind = "end";
+    }
+    push(kind, content.slice(i, end), start, end);
+  }
+  const eof = position(content.length);
+  tokens.push({ kind: "eof", text: "", ...eof, end: eof });
+  return { tokens, comments };
+}
+
+/**
+ * Statements of a token stream. A routine written without DELIMITER keeps the `;` of its
+ * BEGIN ... END body, so those are closed by counting blocks instead.
+ */
+function splitStatements(tokens: Token[]): Token[][] {
+  const statements: Token[][] = [];
+  let current: Token[] = [];
+  let routine: boolean | undefined;
+  let depth = 0;
+  for (let i = 0; i < tokens.length; i++) {
+    const token = tokens[i]!;
+    // Only a `;` can sit inside a block; DELIMITER and the delimiter it sets always end the statement
+    if (token.kind === "eof" || (token.kind === "end" && (depth === 0 || token.text !== ";"))) {
+      if (current.length > 0) statements.push(current);
+      current = [];
+      routine = undefined;
+      depth = 0;
+      continue;
+    }
+    if (token.kind === "end") {
+      current.push({ ...token, kind: "symbol", text: ";" });
+      continue;
+    }
+    current.push(token);
+    if (token.kind !== "word") continue;
+
+    const word = token.text.toLowerCase();
+    if (word !== "begin" && word !== "case" && word !== "end") continue;
+    routine ??=
+      current[0]?.text.toLowerCase() === "create" &&
+      current.some((t) => t.kind === "word" && /^(function|procedure|trigger|event)$/i.test(t.text));
+    if (!routine) continue;
+    if (word !== "end") {
+      depth++;
+      continue;
+    }
+    const after = tokens[i + 1];
+    if (after?.kind === "word" && BLOCK_ENDS.has(after.text.toLowerCase())) continue;
+    depth = Math.max(0, depth - 1);
+    if (after?.kind === "word" && after.text.toLowerCase() === "case") {
+      current.push(after);
+      i++;
+    }
+  }
+  return statements;
+}
+
+/** Tokens back as SQL text, spaced the way people write it */
+function render(tokens: Token[]): string {
+  let out = "";
+  tokens.forEach((token, i) => {
+    const text =
+      token.kind === "string"
+        ? `'${token.text.replace(/'/g, "''")}'`
+        : token.kind === "body"
+          ? `$$${token.text}$$`
+          : token.text;
+    const prev = tokens[i - 1];
+    const before = tokens[i - 2];
+    const symbol = token.kind === "symbol";
+    const tight =
+      !prev ||
+      (symbol && ")],.:[".includes(token.text)) ||
+      (symbol && token.text === "(" && (prev.kind === "word" || prev.kind === "quoted")) ||
+      (prev.kind === "symbol" && "(.[:".includes(prev.text)) ||
+      // A sign: `DEFAULT -1`
+      (prev.kind === "symbol" && "-+".includes(prev.text) && (!before || before.kind === "word"));
+    out += tight ? text : ` ${text}`;
+  });
+  return out;
+}
+
+class Cursor {
+  pos = 0;
+
+  constructor(readonly tokens: Token[]) {}
+
+  peek(offset = 0): Token | undefined {
+    return this.tokens[this.pos + offset];
+  }
+
+  next(): Token | undefined {
+    const token = this.tokens[this.pos];
+    if (token) this.pos++;
+    return token;
+  }
+
+  done(): boolean {
+    return this.pos >= this.tokens.length;
+  }
+
+  /** The keyword at `offset`, lower-cased; "" for anything else */
+  word(offset = 0): string {
+    const token = this.peek(offset);
+    return token?.kind === "word" ? token.text.toLowerCase() : "";
+  }
+
+  /** Consume `words` if they come next, in order */
+  acceptWords(...words: string[]): boolean {
+    if (!words.every((word, i) => this.word(i) === word)) return false;
+    this.pos += words.length;
+    return true;
+  }
+
+  accept(symbol: string): boolean {
+    const token = this.peek();
+    if (token?.kind !== "symbol" || token.text !== symbol) return false;
+    this.pos++;
+    return true;
+  }
+
+  /** `schema.table`, with either part quoted */
+  name(): SqlName | null {
+    const parts: Array<{ text: string; quoted: boolean; token: Token }> = [];
+    for (;;) {
+      const token = this.peek();
+      if (token?.kind !== "word" && token?.kind !== "quoted") break;
+      parts.push({ text: token.text, quoted: token.kind === "quoted", token });
+      this.pos++;
+      if (this.peek()?.text !== "." || this.peek()?.kind !== "symbol") break;
+      this.pos++;
+    }
+    if (parts.length === 0) return null;
+    const key = nameKey(parts);
+    return {
+      key,
+      written: parts.map((part) => part.text).join("."),
+      last: parts[parts.length - 1]!.text,
+      schema: parts.length > 1 ? key.slice(0, key.lastIndexOf(".")) : undefined,
+      start: parts[0]!.token,
+      end: parts[parts.length - 1]!.token,
+    };
+  }
+
+  /** Past a token, or past a whole parenthesised group */
+  skip(): Token | undefined {
+    const first = this.next();
+    if (first?.kind !== "symbol" || first.text !== "(") return first;
+    let depth = 1;
+    let last = first;
+    while (depth > 0 && !this.done()) {
+      last = this.next()!;
+      if (last.kind !== "symbol") continue;
+      if (last.text === "(") depth++;
+      else if (last.text === ")") depth--;
+    }
+    return last;
+  }
+
+  /** Tokens up to where `stop` holds outside parentheses, or to the end */
+  until(stop: () => boolean): Token[] {
+    const start = this.pos;
+    while (!this.done() && !stop()) this.skip();
+    return this.tokens.slice(start, this.pos);
+  }
+
+  /** At `(`: the comma-separated items up to its `)` */
+  list(): Token[][] | null {
+    const open = this.peek();
+    if (open?.kind !== "symbol" || open.text !== "(") return null;
+    const start = this.pos + 1;
+    const close = this.skip();
+    const closed = close !== open && close?.kind === "symbol" && close.text === ")";
+    return splitList(this.tokens.slice(start, closed ? this.pos - 1 : this.pos));
+  }
+}
+
+function splitList(tokens: Token[]): Token[][] {
+  const items: Token[][] = [];
+  const cursor = new Cursor(tokens);
+  while (!cursor.done()) {
+    const item = cursor.until(() => cursor.peek()?.kind === "symbol" && cursor.peek()?.text === ",");
+    if (item.length > 0) items.push(item);
+    cursor.accept(",");
+  }
+  return items;
+}
+
+/** At a table constraint rather than a column: `KEY idx (a)` is an index, `key varchar(255)` a column */
+function atConstraint(def: Cursor): boolean {
+  const word = def.word();
+  if (!TABLE_CONSTRAINTS.has(word)) return false;
+  if (word === "primary" || word === "foreign") return def.word(1) === "key";
+  if (word !== "key" && word !== "index") return true;
+  return def.peek(1)?.text === "(" || (def.peek(2)?.text === "(" && def.peek(3)?.kind !== "number");
+}
+
+/** Column names of `(a, b DESC, c(10))` */
+function columnNames(items: Token[][] | null): string[] {
+  return (items ?? []).map((item) => item[0]?.text ?? "").filter(Boolean);
+}
+
+/** Tables a query reads or writes: after FROM, JOIN, INSERT INTO and UPDATE, outside function calls */
+function tableReferences(tokens: Token[]): Array<{ key: string; line: number }> {
+  const found = new Map<string, number>();
+  const calls: boolean[] = [];
+  const ctes = new Set<string>();
+  const cursor = new Cursor(tokens);
+  while (!cursor.done()) {
+    const prev = cursor.tokens[cursor.pos - 1];
+    const prevWord = prev?.kind === "word" ? prev.text.toLowerCase() : "";
+    const token = cursor.next()!;
+    if (token.kind === "symbol") {
+      if (token.text === "(") calls.push(Boolean(prevWord) && !QUERY_WORDS.has(prevWord));
+      else if (token.text === ")") calls.pop();
+      continue;
+    }
+    if ((token.kind === "word" || token.kind === "quoted") && cursor.word() === "as" && cursor.peek(1)?.text === "(") {
+      ctes.add(token.kind === "quoted" ? token.text : token.text.toLowerCase());
+      continue;
+    }
+    if (token.kind !== "word" || calls[calls.length - 1]) continue;
+
+    const word = token.text.toLowerCase();
+    const opens =
+      (word === "from" && prevWord !== "distinct") ||
+      word === "join" ||
+      (word === "into" && ["insert", "ignore", "replace", "merge"].includes(prevWord)) ||
+      (word === "update" && !["on", "for", "key"].includes(prevWord));
+    if (!opens) continue;
+    // FROM a, b AS x, c: each item of the list, past its alias
+    do {
+      cursor.acceptWords("only");
+      if (NOT_TABLES.has(cursor.word())) break;
+      const name = cursor.name();
+      // `FROM generate_series(...)` is a function; `INSERT INTO t (a, b)` lists columns
+      if (!name || (cursor.peek()?.text === "(" && word !== "into")) break;
+      if (!ctes.has(name.key) && !found.has(name.key)) found.set(name.key, name.start.line);
+      cursor.acceptWords("as");
+      const alias = cursor.peek();
+      if (alias?.kind === "quoted" || (alias?.kind === "word" && !FROM_CLAUSES.has(cursor.word()))) cursor.next();
+    } while (word === "from" && cursor.accept(","));
+  }
+  return [...found].map(([key, line]) => ({ key, line }));
+}
+
+type TableState = {
+  entity: ParsedEntity;
+  kind: "table" | "view";
+  written: string;
+  columns: SqlColumn[];
+  columnEntities: Map<string, ParsedEntity>;
+  foreignKeys: SqlForeignKey[];
+  primaryKey: string[];
+};
+
+type PendingReference = {
+  from: string;
+  key: string;
+  line: number;
+  type: "foreign_key" | "references";
+};
+
+class SqlParser {
+  readonly entities: ParsedEntity[] = [];
+  readonly relationships: EntityRelationship[] = [];
+  private readonly tables = new Map<string, TableState>();
+  private readonly declared: TableState[] = [];
+  private readonly references: PendingReference[] = [];
+  private readonly docsByLine = new Map<number, Comment>();
+  private readonly trailingByLine = new Map<number, Comment>();
+
+  constructor(
+    comments: Comment[],
+    private readonly filePath: string,
+  ) {
+    for (const comment of comments) {
+      if (comment.ownLine) this.docsByLine.set(comment.endLine, comment);
+      else this.trailingByLine.set(comment.startLine, comment);
+    }
+  }
+
+  /** Comments on the lines directly above `line`, as written */
+  private documentationAt(line: number): string | undefined {
+    const block: string[] = [];
+    let at = line - 1;
+    for (let comment = this.docsByLine.get(at); comment; comment = this.docsByLine.get(at)) {
+      block.unshift(comment.text);
+      at = comment.startLine - 1;
+    }
+    return block.length > 0 ? block.join("\n") : undefined;
+  }
+
+  private entity(
+    sqlKind: SqlKind,
+    type: ParsedEntity["type"],
+    name: SqlName,
+    first: Token,
+    last: Token,
+    extra: Partial<ParsedEntity> = {},
+    metadata: Record<string, unknown> = {},
+  ): ParsedEntity {
+    const entity: ParsedEntity = {
+      id: `${this.filePath}:${sqlKind}:${name.key}:${first.line}`,
+      name: name.last,
+      type,
+      filePath: this.filePath,
+      location: {
+        start: { line: first.line, column: first.column, index: first.index },
+        end: { ...last.end },
+      },
+      documentation: this.documentationAt(first.line),
+      ...extra,
+      metadata: {
+        sqlKind,
+        qualifiedName: name.key,
+        ...(name.schema ? { schema: name.schema } : {}),
+        ...metadata,
+      },
+    };
+    this.entities.push(entity);
+    return entity;
+  }
+
+  statement(tokens: Token[]): void {
+    const cursor = new Cursor(tokens);
+    if (cursor.word() === "create") this.create(cursor);
+    else if (cursor.acceptWords("alter", "table")) this.alterTable(cursor);
+    else if (cursor.acceptWords("comment", "on")) this.commentOn(cursor);
+  }
+
+  private create(cursor: Cursor): void {
+    const first = cursor.next()!;
+    const modifiers: string[] = [];
+    if (cursor.acceptWords("or", "replace")) modifiers.push("or replace");
+    // MySQL puts DEFINER = ..., ALGORITHM = ... and SQL SECURITY ... before the kind
+    while (!cursor.done() && !CREATE_KINDS.has(cursor.word())) {
+      if (CREATE_MODIFIERS.has(cursor.word())) modifiers.push(cursor.word() === "temp" ? "temporary" : cursor.word());
+      cursor.skip();
+    }
+    const kind = cursor.next()?.text.toLowerCase();
+    cursor.acceptWords("if", "not", "exists");
+    const name = cursor.name();
+    const last = cursor.tokens[cursor.tokens.length - 1]!;
+    if (!name) return;
+    if (kind === "table") this.createTable(cursor, name, first, last, modifiers);
+    else if (kind === "view") this.createView(cursor, name, first, last, modifiers);
+    else if (kind === "function" || kind === "procedure") this.createRoutine(cursor, kind, name, first, last);
+  }
+
+  private createTable(cursor: Cursor, name: SqlName, first: Token, last: Token, modifiers: string[]): void {
+    const entity = this.entity("table", "table", name, first, last, modifiers.length ? { modifiers } : {});
+    const table = this.track(entity, "table", name);
+    for (const item of cursor.list() ?? []) {
+      const def = new Cursor(item);
+      if (atConstraint(def)) this.tableConstraint(table, def);
+      else this.column(table, def);
+    }
+
+    const rest = cursor.tokens.slice(cursor.pos);
+    // MySQL table options: COMMENT = '...'
+    const comment = rest.findIndex((t) => t.kind === "word" && t.text.toLowerCase() === "comment");
+    const text = rest.slice(comment + 1).find((t) => t.kind !== "symbol");
+    if (comment !== -1 && text?.kind === "string") entity.documentation = text.text;
+    // CREATE TABLE ... AS SELECT, or MySQL's CREATE TABLE ... SELECT
+    if (rest.some((t) => t.kind === "word" && t.text.toLowerCase() === "select")) this.referenceTables(entity, rest);
+  }
+
+  private createView(cursor: Cursor, name: SqlName, first: Token, last: Token, modifiers: string[]): void {
+    const columns = columnNames(cursor.list());
+    const query = cursor.tokens.slice(cursor.pos);
+    const entity = this.entity(
+      "view",
+      "view",
+      name,
+      first,
+      last,
+      {
+        signature: `${modifiers.includes("materialized") ? "MATERIALIZED VIEW" : "VIEW"} ${name.written}`,
+        ...(modifiers.length ? { modifiers } : {}),
+      },
+      modifiers.includes("materialized") ? { materialized: true } : {},
+    );
+    const view = this.track(entity, "view", name);
+    view.columns.push(...columns.map((column) => ({ name: column, dataType: "", nullable: true })));
+    this.referenceTables(entity, query);
+  }
+
+  private createRoutine(
+    cursor: Cursor,
+    sqlKind: "function" | "procedure",
+    name: SqlName,
+    first: Token,
+    last: Token,
+  ): void {
+    const parameters = cursor.list() ?? [];
+    const returns = cursor.acceptWords("returns")
+      ? render(cursor.until(() => ROUTINE_CLAUSES.has(cursor.word()) || cursor.peek()?.kind === "body"))
+      : undefined;
+    const rest = cursor.tokens.slice(cursor.pos);
+    const language = rest.findIndex((t) => t.kind === "word" && t.text.toLowerCase() === "language");
+    const languageName = language === -1 ? undefined : rest[language + 1]?.text.toLowerCase();
+    // Postgres bodies are one dollar-quoted (or plain) string; MySQL ones are the statement's tail
+    const quoted =
+      rest.find((t) => t.kind === "body") ??
+      rest.find((t, i) => t.kind === "string" && rest[i - 1]?.text.toLowerCase() === "as");
+
+    const params = parameters.map((item) => {
+      const param = new Cursor(item);
+      // The mode (IN, OUT, ...) stays in the signature
+      if (PARAMETER_MODES.has(param.word())) param.next();
+      const second = param.peek(1);
+      const named =
+        item.length - param.pos >= 2 &&
+        !MULTIWORD_TYPES.has(param.word()) &&
+        !(second?.kind === "symbol" && "([.".includes(second.text));
+      const paramName = named ? param.next()!.text : "";
+      const type = param.until(() => param.word() === "default" || param.peek()?.text === "=");
+      const defaultValue = param.next() ? render(param.until(() => false)) : undefined;
+      return {
+        name: paramName,
+        type: render(type),
+        ...(defaultValue ? { defaultValue, optional: true } : {}),
+      };
+    });
+    const signature = `${sqlKind.toUpperCase()} ${name.written}(${parameters.map(render).join(", ")})`;
+    const entity = this.entity(
+      sqlKind,
+      "function",
+      name,
+      first,
+      last,
+      {
+        signature: returns ? `${signature} RETURNS ${returns}` : signature,
+        parameters: params,
+        ...(returns ? { returnType: returns } : {}),
+      },
+      // `metadata.language` is the file's
+      languageName ? { routineLanguage: languageName } : {},
+    );
+    if (quoted) this.referenceTables(entity, tokenize(quoted.text).tokens, quoted.line - 1);
+    else this.referenceTables(entity, rest);
+  }
+
+  /** The table ALTER TABLE changes: the one created above, else a stand-in marked `altered` */
+  private alterTable(cursor: Cursor): void {
+    const first = cursor.tokens[0]!;
+    cursor.acceptWords("if", "exists");
+    cursor.acceptWords("only");
+    const name = cursor.name();
+    if (!name) return;
+    const last = cursor.tokens[cursor.tokens.length - 1]!;
+    // Created only for what list_tables shows: `OWNER TO` or `DROP COLUMN` on an unknown table adds nothing
+    let table = this.tables.get(name.key);
+    const target = () =>
+      (table ??= this.track(this.entity("table", "table", name, first, last, {}, { altered: true }), "table", name));
+    for (const item of splitList(cursor.tokens.slice(cursor.pos))) {
+      const action = new Cursor(item);
+      if (!action.acceptWords("add")) continue;
+      if (atConstraint(action)) {
+        const kind = action.word(action.word() === "constraint" ? 2 : 0);
+        if (kind === "primary" || kind === "foreign") this.tableConstraint(target(), action);
+      } else {
+        action.acceptWords("column");
+        action.acceptWords("if", "not", "exists");
+        this.column(target(), action);
+      }
+    }
+  }
+
+  /** COMMENT ON TABLE / VIEW / COLUMN ... IS '...' */
+  private commentOn(cursor: Cursor): void {
+    const target = cursor.next()?.text.toLowerCase();
+    const name = cursor.name();
+    const text = cursor.acceptWords("is") ? cursor.next() : undefined;
+    if (!name || text?.kind !== "string") return;
+    if (target === "table" || target === "view") {
+      const tableKey = matchSqlTable(name.key, this.tables.keys());
+      const table = tableKey ? this.tables.get(tableKey) : undefined;
+      if (table) table.entity.documentation = text.text;
+    } else if (target === "column") {
+      const dot = name.key.lastIndexOf(".");
+      const tableKey = matchSqlTable(name.key.slice(0, dot), this.tables.keys());
+      const table = tableKey ? this.tables.get(tableKey) : undefined;
+      const column = name.key.slice(dot + 1);
+      const entity = table?.columnEntities.get(column);
+      if (!table || !entity) return;
+      entity.documentation = text.text;
+      const info = table.columns.find((c) => c.name.toLowerCase() === column || c.name === column);
+      if (info) info.comment = text.text;
+    }
+  }
+
+  private track(entity: ParsedEntity, kind: "table" | "view", name: SqlName): TableState {
+    const state: TableState = {
+      entity,
+      kind,
+      written: name.written,
+      columns: [],
+      columnEntities: new Map(),
+      foreignKeys: [],
+      primaryKey: [],
+    };
+    this.tables.set(name.key, state);
+    this.declared.push(state);
+    return state;
+  }
+
+  private column(table: TableState, def: Cursor): void {
+    const nameToken = def.next();
+    if (nameToken?.kind !== "word" && nameToken?.kind !== "quoted") return;
+    const typeEnds = () => COLUMN_CLAUSES.has(def.word()) || (def.word() === "character" && def.word(1) === "set");
+    const type = def.until(typeEnds);
+    const column: SqlColumn = { name: nameToken.text, dataType: render(type), nullable: true };
+    if (/^(small|big)?serial\b/i.test(column.dataType)) column.autoIncrement = true;
+
+    while (!def.done()) {
+      if (def.acceptWords("constraint")) def.next();
+      else if (def.acceptWords("not", "null")) column.nullable = false;
+      else if (def.acceptWords("null")) column.nullable = true;
+      else if (def.acceptWords("primary", "key")) {
+        column.primaryKey = true;
+        column.nullable = false;
+        table.primaryKey.push(column.name);
+      } else if (def.acceptWords("unique")) {
+        def.acceptWords("key");
+        column.unique = true;
+      } else if (def.acceptWords("default")) {
+        // At least one token, so that `DEFAULT NULL` keeps its NULL
+        const at = def.pos;
+        def.skip();
+        def.until(() => COLUMN_CLAUSES.has(def.word()));
+        column.default = render(def.tokens.slice(at, def.pos));
+      } else if (def.acceptWords("references")) {
+        this.foreignKey(table, def, [column.name], undefined, nameToken.line);
+      } else if (def.word() === "auto_increment" || def.word() === "autoincrement") {
+        def.next();
+        column.autoIncrement = true;
+      } else if (def.acceptWords("generated")) {
+        // ALWAYS AS IDENTITY, BY DEFAULT AS IDENTITY (...), ALWAYS AS (expr) STORED
+        const clause = def.until(() => ["not", "null", "primary", "references", "unique"].includes(def.word()));
+        if (clause.some((t) => t.kind === "word" && t.text.toLowerCase() === "identity")) column.autoIncrement = true;
+      } else if (def.acceptWords("comment")) {
+        const text = def.next();
+        if (text?.kind === "string") column.comment = text.text;
+      } else def.skip();
+    }
+    table.columns.push(column);
+
+    const first = def.tokens[0]!;
+    const last = def.tokens[def.tokens.length - 1]!;
+    const tableName = table.entity.metadata?.qualifiedName as string;
+    const key = nameKey([{ text: column.name, quoted: nameToken.kind === "quoted" }]);
+    const trailing = this.trailingByLine.get(last.line) ?? this.trailingByLine.get(last.end.line);
+    const entity: ParsedEntity = {
+      id: `${table.entity.id}.${key}`,
+      name: column.name,
+      type: "field",
+      filePath: this.filePath,
+      location: {
+        start: { line: first.line, column: first.column, index: first.index },
+        end: { ...last.end },
+      },
+      signature: render(def.tokens),
+      documentation: column.comment ?? this.documentationAt(first.line) ?? trailing?.text,
+      metadata: {
+        sqlKind: "column",
+        qualifiedName: `${tableName}.${key}`,
+        className: table.entity.name,
+        table: tableName,
+        dataType: column.dataType,
+        nullable: column.nullable,
+        ...(column.primaryKey ? { primaryKey: true } : {}),
+        ...(column.unique ? { unique: true } : {}),
+        ...(column.default !== undefined ? { default: column.default } : {}),
+        ...(column.autoIncrement ? { autoIncrement: true } : {}),
+      },
+    };
+    this.entities.push(entity);
+    table.columnEntities.set(key, entity);
+    this.relationships.push({
+      from: table.entity.id!,
+      to: entity.id!,
+      type: "contains",
+      metadata: { line: first.line, confidence: 1, isDirectRelation: true },
+    });
+  }
+
+  /** PRIMARY KEY (...) / FOREIGN KEY (...) REFERENCES ... at table level; others are skipped */
+  private tableConstraint(table: TableState, def: Cursor): void {
+    const line = def.peek()?.line ?? table.entity.location.start.line;
+    const constraint = def.acceptWords("constraint") ? def.next()?.text : undefined;
+    if (def.acceptWords("primary", "key")) {
+      const columns = columnNames(def.list());
+      table.primaryKey.push(...columns);
+      const named = new Set(columns.map((column) => column.toLowerCase()));
+      for (const column of table.columns) {
+        if (named.has(column.name.toLowerCase())) Object.assign(column, { primaryKey: true, nullable: false });
+      }
+      for (const entity of table.columnEntities.values()) {
+        if (!named.has(entity.name.toLowerCase())) continue;
+        entity.metadata = { ...entity.metadata, primaryKey: true, nullable: false };
+      }
+    } else if (def.acceptWords("foreign", "key")) {
+      // MySQL may name the index: FOREIGN KEY fk_name (col)
+      if (def.peek()?.text !== "(") def.next();
+      const columns = columnNames(def.list());
+      if (def.acceptWords("references")) this.foreignKey(table, def, columns, constraint, line);
+    }
+  }
+
+  /** REFERENCES table [(columns)] [ON DELETE ...] [ON UPDATE ...], after the keyword */
+  private foreignKey(
+    table: TableState,
+    def: Cursor,
+    columns: string[],
+    constraint: string | undefined,
+    line: number,
+  ): void {
+    const target = def.name();
+    if (!target) return;
+    const foreignKey: SqlForeignKey = {
+      columns,
+      table: target.key,
+      referencedColumns: columnNames(def.list()),
+      ...(constraint ? { constraint } : {}),
+    };
+    for (;;) {
+      if (def.acceptWords("on", "delete")) foreignKey.onDelete = this.referentialAction(def);
+      else if (def.acceptWords("on", "update")) foreignKey.onUpdate = this.referentialAction(def);
+      else if (def.acceptWords("match") || def.acceptWords("initially")) def.next();
+      else if (!def.acceptWords("deferrable")) break;
+    }
+    table.foreignKeys.push(foreignKey);
+    this.references.push({ from: table.entity.id!, key: target.key, line, type: "foreign_key" });
+  }
+
+  private referentialAction(def: Cursor): string {
+    const words = [def.next()?.text ?? ""];
+    if (words[0]!.toLowerCase() === "set" || words[0]!.toLowerCase() === "no") words.push(def.next()?.text ?? "");
+    return words.join(" ").toUpperCase();
+  }
+
+  /** `lineOffset` places tokens read from a routine's quoted body */
+  private referenceTables(from: ParsedEntity, tokens: Token[], lineOffset = 0): void {
+    for (const { key, line } of tableReferences(tokens)) {
+      this.references.push({ from: from.id!, key, line: line + lineOffset, type: "references" });
+    }
+  }
+
+  /** Fill in table metadata and bind references to tables of this file; the rest wait for the SQL linker */
+  finish(): void {
+    for (const table of this.declared) {
+      const entity = table.entity;
+      entity.metadata = {
+        ...entity.metadata,
+        columns: table.columns,
+        ...(table.kind === "table"
+          ? { primaryKey: [...new Set(table.primaryKey)], foreignKeys: table.foreignKeys }
+          : {}),
+      };
+      if (table.kind === "table") {
+        const columns = table.columns.map((column) => `${column.name} ${column.dataType}`.trim()).join(", ");
+        entity.signature = `${entity.metadata.altered ? "ALTER TABLE" : "TABLE"} ${table.written}(${columns})`;
+      }
+    }
+
+    // The stand-in of a table altered here is not where it is declared
+    const declared = new Map([...this.tables].filter(([, table]) => table.entity.metadata?.altered !== true));
+    const seen = new Set<string>();
+    for (const ref of this.references) {
+      const local = matchSqlTable(ref.key, declared.keys());
+      // Unresolved targets are not bare names, which the indexer would bind to a same-named column or
+      // stand-in of this file; the placeholder is named after `referencedName`
+      const to = local ? declared.get(local)!.entity.id! : `sql:${ref.key}`;
+      if (local && to === ref.from && ref.type === "references") continue;
+      const edge = `${ref.type}|${ref.from}|${to}`;
+      if (seen.has(edge)) continue;
+      seen.add(edge);
+      this.relationships.push({
+        from: ref.from,
+        to,
+        type: ref.type,
+        sourceFile: this.filePath,
+        metadata: { line: ref.line, referencedName: ref.key, ...(local ? { confidence: 1 } : {}) },
+      });
+    }
+  }
+}
+
+export class SqlAnalyzer {
+  analyze(content: string, filePath: string): { entities: ParsedEntity[]; relationships: EntityRelationship[] } {
+    const { tokens, comments } = tokenize(content);
+    const parser = new SqlParser(comments, filePath);
+    for (const statement of splitStatements(tokens)) {
+      try {
+        parser.statement(statement);
+      } catch (error) {
+        // One statement it cannot follow does not cost the rest of the file
+        console.error(`[SqlAnalyzer] Error analyzing a statement at ${filePath}:${statement[0]?.line}:`, error);
+      }
+    }
+    parser.finish();
+    return { entities: parser.entities, relationships: parser.relationships };
+  }
+}
@@ -0,0 +1,889 @@
+/**
+ * Swift Language Analyzer
+ *
+ * Declarations:
+ * - Classes, actors, structs, enums (with their cases) and protocols (with their requirements),
+ *   qualified by nesting (`Checkout.Step`)
+ * - Extensions, one entity each; their members belong to the extended type (`implType`), so a
+ *   type's methods are found across the files of its module
+ * - Functions, methods, initializers, subscripts, properties and type aliases; methods are told
+ *   apart by their argument labels (`move(to:animated:)`)
+ * - Imports, `@testable` ones marked
+ * - Attributes (`@MainActor`, `@available(iOS 16, *)`) on every declaration, and the property
+ *   wrappers of properties (`@Published`, `@State`), as metadata
+ *
+ * Relationships:
+ * - Superclass (extends), protocol conformance (implements) and protocol inheritance (extends)
+ * - Extensions to the type they extend (extension_of), with the conformances they add
+ * - Property wrappers and result builders declared in the file (decorated_by)
+ * - Calls, bound to declarations of this file where the receiver allows it
+ *
+ * Swift spells a superclass and protocols the same way (`class A: B, P`). The first inherited
+ * type of a class is its superclass unless it is a protocol: one declared in this file, or one
+ * recognized by name (the standard library's, `...Delegate`, `...DataSource`).
+ */
+
+import type { EntityRelationship, ParsedEntity, TreeSitterNode } from "../types/parser.js";
+
+const MAX_RECURSION_DEPTH = 50;
+const PARSE_TIMEOUT_MS = 5000;
+
+class CircuitBreakerError extends Error {
+  constructor(message: string) {
+    super(message);
+    this.name = "CircuitBreakerError";
+  }
+}
+
+export type SwiftTypeKind = "class" | "actor" | "struct" | "enum" | "protocol" | "extension";
+
+const ENTITY_TYPES: Record<SwiftTypeKind, ParsedEntity["type"]> = {
+  class: "class",
+  actor: "class",
+  struct: "struct",
+  enum: "enum",
+  protocol: "interface",
+  // Like Rust impl blocks: a class-like entity holding the members it adds
+  extension: "class",
+};
+
+const KNOWN_PROTOCOLS = new Set([
+  "Codable",
+  "Decodable",
+  "Encodable",
+  "Equatable",
+  "Hashable",
+  "Comparable",
+  "Identifiable",
+  "Sendable",
+  "Error",
+  "LocalizedError",
+  "CaseIterable",
+  "RawRepresentable",
+  "CustomStringConvertible",
+  "CustomDebugStringConvertible",
+  "Sequence",
+  "Collection",
+  "IteratorProtocol",
+  "ObservableObject",
+  "View",
+  "App",
+  "Scene",
+  "NSObjectProtocol",
+  "NSCoding",
+  "NSCopying",
+]);
+
+/** Raw value types of an enum such as `enum Tab: String` */
+const RAW_VALUE_TYPES = new Set([
+  "String",
+  "Character",
+  "Int",
+  "Int8",
+  "Int16",
+  "Int32",
+  "Int64",
+  "UInt",
+  "UInt8",
+  "UInt16",
+  "UInt32",
+  "UInt64",
+  "Double",
+  "Float",
+]);
+
+/** Attributes the compiler defines; any other attribute on a property is a property wrapper */
+const BUILTIN_ATTRIBUTES = new Set([
+  "available",
+  "objc",
+  "nonobjc",
+  "objcMembers",
+  "NSManaged",
+  "IBOutlet",
+  "IBInspectable",
+  "GKInspectable",
+  "MainActor",
+  "preconcurrency",
+  "inlinable",
+  "usableFromInline",
+  "frozen",
+  "discardableResult",
+  "dynamicCallable",
+  "dynamicMemberLookup",
+  "unknown",
+  "Sendable",
+]);
+
+/** Attributes marking a type as usable as an attribute itself */
+const ATTRIBUTE_TYPES = new Set(["propertyWrapper", "resultBuilder", "globalActor"]);
+
+const MODIFIER_NODES = new Set([
+  "visibility_modifier",
+  "member_modifier",
+  "property_modifier",
+  "mutation_modifier",
+  "function_modifier",
+  "inheritance_modifier",
+  "ownership_modifier",
+  "property_behavior_modifier",
+  "parameter_modifier",
+]);
+
+export type SwiftAttribute = { name: string; arguments?: string[] };
+
+/** A type or extension whose body is being walked */
+interface Scope {
+  id: string;
+  /** Qualified name of the type; an extension's is the extended type's */
+  qualifiedName: string;
+  kind: SwiftTypeKind;
+}
+
+/**
+ * A `type` named as written from `scope`, or a `call` of `name` from type `owner` on `receiver`
+ * (none, `self`/`Self`, or a type path), which may land on an extension, a protocol extension or
+ * an initializer declared in this file
+ */
+type PendingTarget =
+  | { kind: "type"; relationship: EntityRelationship; name: string; scope: string }
+  | { kind: "call"; relationship: EntityRelationship; name: string; owner: string; receiver?: string };
+
+/** Inherited types as written, sorted into superclass and conformances once the file is walked */
+interface PendingInheritance {
+  entity: ParsedEntity;
+  kind: SwiftTypeKind;
+  scope: string;
+  inherited: Array<{ name: string; line: number }>;
+}
+
+/** `Checkout` for `Checkout.Step` and `Checkout.Step.next`; "" for a top-level name */
+export function swiftOwnerName(qualifiedName: string): string {
+  const dot = qualifiedName.lastIndexOf(".");
+  return dot < 0 ? "" : qualifiedName.slice(0, dot);
+}
+
+/** A type as written without generic arguments, optionality or `any`/`some`: `Store` for `any Store<Item>?` */
+export function swiftTypeName(written: string): string {
+  return written
+    .replace(/<.*>/s, "")
+    .replace(/^(any|some)\s+/, "")
+    .replace(/[\s?!]/g, "");
+}
+
+/** `move(to:animated:)` for `func move(to point: CGPoint, animated: Bool)`; unlabeled arguments are `_` */
+export function swiftSelector(name: string, labels: string[]): string {
+  return `${name}(${labels.map((label) => `${label}:`).join("")})`;
+}
+
+/**
+ * The type `written` names from inside `scope`: `scope.written`, then the same in each enclosing
+ * type, then `written` at the top level.
+ */
+export function resolveSwiftType<T>(
+  written: string,
+  scope: string,
+  lookup: (qualifiedName: string) => T | undefined,
+): T | undefined {
+  for (let outer = scope; outer; outer = swiftOwnerName(outer)) {
+    const found = lookup(`${outer}.${written}`);
+    if (found !== undefined) return found;
+  }
+  return lookup(written);
+}
+
+/** Protocol by name alone: the standard library's and Cocoa's naming conventions */
+function looksLikeProtocol(name: string): boolean {
+  const bare = name.split(".").pop() ?? name;
+  return KNOWN_PROTOCOLS.has(bare) || /(Delegate|DataSource|Protocol|Representable|Convertible)$/.test(bare);
+}
+
+export class SwiftAnalyzer {
+  private recursionDepth = 0;
+  private parseStartTime = 0;
+  /** Types declared in this file by qualified name; extensions are not types */
+  private declaredTypes = new Map<string, ParsedEntity>();
+  /** Members declared in this file (extensions included) by `Owner.name`, top-level functions by name */
+  private declaredMembers = new Map<string, string>();
+  private usedIds = new Set<string>();
+  private inheritance: PendingInheritance[] = [];
+  private pending: PendingTarget[] = [];
+
+  async analyze(
+    rootNode: TreeSitterNode,
+    filePath: string,
+  ): Promise<{ entities: ParsedEntity[]; relationships: EntityRelationship[] }> {
+    this.resetState();
+
+    const entities: ParsedEntity[] = [];
+    const relationships: EntityRelationship[] = [];
+
+    try {
+      this.visit(rootNode, null, filePath, entities, relationships);
+      this.classifyInheritance(relationships);
+      this.bindLocalTargets();
+      this.linkAttributeTypes(entities, relationships);
+    } catch (error) {
+      if (error instanceof CircuitBreakerError) {
+        console.warn(`[SwiftAnalyzer] Circuit breaker triggered for ${filePath}: ${error.message}`);
+      } else {
+        console.error(`[SwiftAnalyzer] Error analyzing ${filePath}:`, error);
+      }
+      // Return partial results on error
+    }
+
+    return { entities, relationships };
+  }
+
+  private resetState(): void {
+    this.recursionDepth = 0;
+    this.parseStartTime = Date.now();
+    this.declaredTypes.clear();
+    this.declaredMembers.clear();
+    this.usedIds.clear();
+    this.inheritance = [];
+    this.pending = [];
+  }
+
+  private checkCircuitBreakers(): void {
+    if (this.recursionDepth > MAX_RECURSION_DEPTH) {
+      throw new CircuitBreakerError(`Maximum recursion depth ${MAX_RECURSION_DEPTH} exceeded`);
+    }
+    if (Date.now() - this.parseStartTime > PARSE_TIMEOUT_MS) {
+      throw new CircuitBreakerError(`Parse timeout ${PARSE_TIMEOUT_MS}ms exceeded`);
+    }
+  }
+
+  private getNodeLocation(node: TreeSitterNode) {
+    return {
+      start: { line: node.startPosition.row + 1, column: node.startPosition.column, index: node.startIndex },
+      end: { line: node.endPosition.row + 1, column: node.endPosition.column, index: node.endIndex },
+    };
+  }
+
+  /** `id`, or `id@line` when an overload with the same labels already took it */
+  private uniqueId(id: string, node: TreeSitterNode): string {
+    const unique = this.usedIds.has(id) ? `${id}@${node.startPosition.row + 1}` : id;
+    this.usedIds.add(unique);
+    return unique;
+  }
+
+  /** Walk the declarations of a file or a type body; function bodies are only read for calls */
+  private visit(
+    node: TreeSitterNode,
+    scope: Scope | null,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+  ): void {
+    this.recursionDepth++;
+    this.checkCircuitBreakers();
+
+    try {
+      for (const child of node.namedChildren) {
+        switch (child.type) {
+          case "class_declaration":
+          case "protocol_declaration":
+            this.extractType(child, scope, filePath, entities, relationships);
+            break;
+
+          case "function_declaration":
+          case "protocol_function_declaration":
+          case "init_declaration":
+          case "deinit_declaration":
+          case "subscript_declaration":
+            this.extractFunction(child, scope, filePath, entities, relationships);
+            break;
+
+          case "property_declaration":
+          case "protocol_property_declaration":
+            this.extractProperty(child, scope, filePath, entities, relationships);
+            break;
+
+          case "typealias_declaration":
+          case "associatedtype_declaration":
+            this.extractTypeAlias(child, scope, filePath, entities, relationships);
+            break;
+
+          case "enum_entry":
+            if (scope) this.extractEnumCases(child, scope, filePath, entities, relationships);
+            break;
+
+          case "import_declaration":
+            this.extractImport(child, filePath, entities);
+            break;
+        }
+      }
+    } finally {
+      this.recursionDepth--;
+    }
+  }
+
+  /** `class`, `struct`, `enum`, `actor`, `extension` or `protocol` of a declaration */
+  private declarationKind(node: TreeSitterNode): SwiftTypeKind | undefined {
+    if (node.type === "protocol_declaration") return "protocol";
+    const kinds = ["class", "actor", "struct", "enum", "extension"];
+    const keyword =
+      node.childForFieldName("declaration_kind")?.text ?? node.children.find((c) => kinds.includes(c.type))?.type;
+    return keyword && kinds.includes(keyword) ? (keyword as SwiftTypeKind) : undefined;
+  }
+
+  /**
+   * A type or extension. Nested types are qualified by the type around them, also when they are
+   * declared in an extension of it: `extension Checkout { enum Step {} }` declares `Checkout.Step`.
+   */
+  private extractType(
+    node: TreeSitterNode,
+    scope: Scope | null,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+  ): void {
+    const kind = this.declarationKind(node);
+    const nameNode = node.childForFieldName("name");
+    if (!kind || !nameNode) return;
+
+    const written = swiftTypeName(nameNode.text);
+    const qualifiedName = kind === "extension" || !scope ? written : `${scope.qualifiedName}.${written}`;
+    const name = qualifiedName.split(".").pop()!;
+    const id = this.uniqueId(`${filePath}:${kind}:${qualifiedName}`, node);
+    const attributes = this.extractAttributes(node);
+    const constraints = node.namedChildren.find((c) => c.type === "type_constraints")?.text;
+
+    const entity: ParsedEntity = {
+      id,
+      name,
+      type: ENTITY_TYPES[kind],
+      filePath,
+      location: this.getNodeLocation(node),
+      modifiers: [...this.extractModifiers(node), ...(kind === "extension" ? ["extension"] : [])],
+      metadata: {
+        qualifiedName,
+        swiftKind: kind,
+        ...(scope && kind !== "extension" ? { parentClass: scope.qualifiedName } : {}),
+        ...(kind === "extension" ? { isExtension: true, extendedType: qualifiedName } : {}),
+        ...(constraints ? { whereClause: constraints } : {}),
+        ...(attributes.length > 0 ? { attributes } : {}),
+      },
+    };
+    entities.push(entity);
+    if (kind !== "extension" && !this.declaredTypes.has(qualifiedName)) this.declaredTypes.set(qualifiedName, entity);
+    if (scope && kind !== "extension") {
+      relationships.push({ from: id, to: scope.id, type: "contains", metadata: { memberType: kind } });
+    }
+
+    if (kind === "extension") {
+      this.pushTypeEdge(relationships, id, "", written, "extension_of", nameNode, { extendedType: qualifiedName });
+    }
+    const inherited = this.inheritedTypes(node);
+    if (inherited.length > 0) {
+      // An extension's inherited types are looked up from inside the extended type
+      const lookupScope = kind === "extension" ? qualifiedName : (scope?.qualifiedName ?? "");
+      this.inheritance.push({ entity, kind, scope: lookupScope, inherited });
+    }
+
+    const body = node.childForFieldName("body");
+    if (body) this.visit(body, { id, qualifiedName, kind }, filePath, entities, relationships);
+  }
+
+  /** The types after `:` in a type declaration, as written without generic arguments */
+  private inheritedTypes(node: TreeSitterNode): Array<{ name: string; line: number }> {
+    const specifiers: TreeSitterNode[] = [];
+    for (const child of node.namedChildren) {
+      if (child.type === "inheritance_specifier") specifiers.push(child);
+      else if (child.type === "type_inheritance_clause") {
+        specifiers.push(...child.namedChildren.filter((c) => c.type === "inheritance_specifier"));
+      }
+    }
+    return specifiers
+      .map((specifier) => ({
+        name: swiftTypeName((specifier.childForFieldName("inherits_from") ?? specifier).text),
+        line: specifier.startPosition.row + 1,
+      }))
+      .filter((item) => item.name);
+  }
+
+  /**
+   * Functions, methods, initializers, deinitializers and subscripts. The id carries the argument
+   * labels, so overloads such as `init(id:)` and `init(from:)` stay apart.
+   */
+  private extractFunction(
+    node: TreeSitterNode,
+    scope: Scope | null,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+  ): void {
+    const name =
+      node.type === "init_declaration"
+        ? "init"
+        : node.type === "deinit_declaration"
+          ? "deinit"
+          : node.type === "subscript_declaration"
+            ? "subscript"
+            : node.childForFieldName("name")?.text;
+    if (!name) return;
+
+    const { parameters, labels } = this.extractParameters(node);
+    const selector = node.type === "deinit_declaration" ? name : swiftSelector(name, labels);
+    const modifiers = this.extractModifiers(node);
+    // `class func` spells the keyword outside the modifiers
+    if (node.children.some((c) => c.type === "class") && !modifiers.includes("class")) modifiers.push("class");
+    const attributes = this.extractAttributes(node);
+    const returnType = node.childForFieldName("return_type")?.text;
+    const qualifiedName = scope ? `${scope.qualifiedName}.${name}` : name;
+    const id = this.uniqueId(scope ? `${scope.id}:method:${selector}` : `${filePath}:function:${selector}`, node);
+    const isStatic = modifiers.includes("static") || modifiers.includes("class");
+
+    entities.push({
+      id,
+      name,
+      type: scope ? "method" : "function",
+      filePath,
+      location: this.getNodeLocation(node),
+      modifiers: [...modifiers, ...(isStatic && !modifiers.includes("static") ? ["static"] : [])],
+      parameters,
+      ...(returnType ? { returnType } : {}),
+      metadata: {
+        qualifiedName,
+        selector,
+        ...(scope ? { className: scope.qualifiedName } : {}),
+        ...(scope?.kind === "extension" ? this.extensionOwner(scope) : {}),
+        ...(scope?.kind === "protocol" ? { isProtocolRequirement: node.type !== "function_declaration" } : {}),
+        isStatic,
+        isConstructor: name === "init",
+        isAsync: node.children.some((c) => c.type === "async"),
+        throws: node.children.some((c) => c.type === "throws"),
+        isMutating: modifiers.includes("mutating"),
+        isOverride: modifiers.includes("override"),
+        ...(attributes.length > 0 ? { attributes } : {}),
+      },
+    });
+    const key = scope ? `${scope.qualifiedName}.${name}` : name;
+    if (!this.declaredMembers.has(key)) this.declaredMembers.set(key, id);
+    if (scope) {
+      relationships.push({ from: id, to: scope.id, type: "contains", metadata: { memberType: "method" } });
+    }
+
+    const body = node.childForFieldName("body");
+    if (body) this.extractCalls(body, id, scope?.qualifiedName ?? "", relationships);
+  }
+
+  /**
+   * Parameters with their types and default values, and the argument label of each: the external
+   * name, else the parameter name; `_` for unlabeled arguments.
+   */
+  private extractParameters(node: TreeSitterNode): {
+    parameters: NonNullable<ParsedEntity["parameters"]>;
+    labels: string[];
+  } {
+    const parameters: NonNullable<ParsedEntity["parameters"]> = [];
+    const labels: string[] = [];
+    const children = node.children;
+    for (let i = 0; i < children.length; i++) {
+      const child = children[i]!;
+      // Default values follow their parameter: `_ limit: Int = 20`
+      if (child.type === "=") {
+        const last = parameters[parameters.length - 1];
+        const value = children[i + 1];
+        if (last && value && children[i - 1]?.type === "parameter") {
+          last.optional = true;
+          last.defaultValue = value.text;
+        }
+        continue;
+      }
+      if (child.type !== "parameter") continue;
+      const name = child.childForFieldName("name")?.text;
+      if (!name) continue;
+      const external = child.childForFieldName("external_name")?.text;
+      const type = child.childForFieldName("type")?.text;
+      const variadic = child.children.some((c) => c.type === "..." || c.type === "three_dot_operator");
+      labels.push(external ?? name);
+      parameters.push({
+        name,
+        ...(type ? { type: variadic ? `${type}...` : type } : {}),
+      });
+    }
+    return { parameters, labels };
+  }
+
+  /** Stored and computed properties, and protocol property requirements */
+  private extractProperty(
+    node: TreeSitterNode,
+    scope: Scope | null,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+  ): void {
+    const binding = node.namedChildren.find((c) => c.type === "value_binding_pattern")?.text.trim();
+    const mutability = binding?.endsWith("let") ? "let" : "var";
+    const modifiers = this.extractModifiers(node);
+    const attributes = this.extractAttributes(node);
+    const propertyWrappers = attributes.map((a) => a.name).filter((a) => !BUILTIN_ATTRIBUTES.has(a));
+    const annotation = node.namedChildren.find((c) => c.type === "type_annotation");
+    const propertyType = annotation
+      ? (annotation.childForFieldName("type")?.text ?? annotation.text.replace(/^:\s*/, ""))
+      : undefined;
+    const computed = node.namedChildren.find(
+      (c) => c.type === "computed_property" || c.type === "protocol_property_requirements",
+    );
+    const isStatic = modifiers.includes("static") || modifiers.includes("class");
+
+    for (const pattern of node.namedChildren) {
+      if (pattern.type !== "pattern") continue;
+      const name = (pattern.childForFieldName("bound_identifier") ?? pattern).text.replace(/`/g, "");
+      if (!/^[A-Za-z_]\w*$/.test(name)) continue;
+
+      const topLevelKind = mutability === "let" ? "constant" : "variable";
+      const id = this.uniqueId(scope ? `${scope.id}:property:${name}` : `${filePath}:${topLevelKind}:${name}`, pattern);
+      entities.push({
+        id,
+        name,
+        type: scope ? "property" : topLevelKind,
+        filePath,
+        location: this.getNodeLocation(node),
+        modifiers,
+        metadata: {
+          qualifiedName: scope ? `${scope.qualifiedName}.${name}` : name,
+          ...(scope ? { className: scope.qualifiedName } : {}),
+          ...(scope?.kind === "extension" ? this.extensionOwner(scope) : {}),
+          mutability,
+          ...(propertyType ? { propertyType } : {}),
+          isStatic,
+          isComputed: Boolean(computed) && computed?.type === "computed_property",
+          ...(computed?.type === "protocol_property_requirements" ? { accessors: computed.text } : {}),
+          ...(propertyWrappers.length > 0 ? { propertyWrappers } : {}),
+          ...(attributes.length > 0 ? { attributes } : {}),
+        },
+      });
+      const key = scope ? `${scope.qualifiedName}.${name}` : name;
+      if (!this.declaredMembers.has(key)) this.declaredMembers.set(key, id);
+      if (scope) {
+        relationships.push({ from: id, to: scope.id, type: "contains", metadata: { memberType: "property" } });
+      }
+    }
+
+    if (computed?.type === "computed_property" && scope) {
+      const owner = this.declaredMembers.get(`${scope.qualifiedName}.${this.firstPatternName(node)}`);
+      if (owner) this.extractCalls(computed, owner, scope.qualifiedName, relationships);
+    }
+  }
+
+  private firstPatternName(node: TreeSitterNode): string {
+    const pattern = node.namedChildren.find((c) => c.type === "pattern");
+    return pattern ? (pattern.childForFieldName("bound_identifier") ?? pattern).text.replace(/`/g, "") : "";
+  }
+
+  /** `typealias` declarations and a protocol's `associatedtype` requirements */
+  private extractTypeAlias(
+    node: TreeSitterNode,
+    scope: Scope | null,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+  ): void {
+    const name = node.childForFieldName("name")?.text;
+    if (!name) return;
+    const qualifiedName = scope ? `${scope.qualifiedName}.${name}` : name;
+    const id = this.uniqueId(`${filePath}:typealias:${qualifiedName}`, node);
+    const value = node.childForFieldName("value")?.text;
+
+    entities.push({
+      id,
+      name,
+      type: "typedef",
+      filePath,
+      location: this.getNodeLocation(node),
+      modifiers: this.extractModifiers(node),
+      metadata: {
+        qualifiedName,
+        ...(scope ? { parentClass: scope.qualifiedName } : {}),
+        ...(value ? { aliasOf: value } : {}),
+        ...(node.type === "associatedtype_declaration" ? { isAssociatedType: true } : {}),
+      },
+    });
+    if (scope) {
+      relationships.push({ from: id, to: scope.id, type: "contains", metadata: { memberType: "typealias" } });
+    }
+  }
+
+  /** `case a, b(String), c = "c"`: one enum_variant per case */
+  private extractEnumCases(
+    node: TreeSitterNode,
+    scope: Scope,
+    filePath: string,
+    entities: ParsedEntity[],
+    relationships: EntityRelationship[],
+  ): void {
+    const children = node.children;
+    for (let i = 0; i < children.length; i++) {
+      const child = children[i]!;
+      if (child.type !== "simple_identifier") continue;
+      // What follows a case name up to the next comma: its associated values or `= rawValue`
+      let associatedValues: string | undefined;
+      let rawValue: string | undefined;
+      for (let j = i + 1; j < children.length; j++) {
+        const part = children[j]!;
+        if (part.type === "," || part.type === "simple_identifier") break;
+        if (part.type === "enum_type_parameters") associatedValues = part.text;
+        else if (part.text !== "=" && part.text !== ";") rawValue = part.text;
+      }
+      const id = this.uniqueId(`${scope.id}:case:${child.text}`, child);
+
+      entities.push({
+        id,
+        name: child.text,
+        type: "enum_variant",
+        filePath,
+        location: this.getNodeLocation(child),
+        metadata: {
+          qualifiedName: `${scope.qualifiedName}.${child.text}`,
+          className: scope.qualifiedName,
+          ...(associatedValues ? { associatedValues } : {}),
+          ...(rawValue !== undefined ? { rawValue } : {}),
+        },
+      });
+      const key = `${scope.qualifiedName}.${child.text}`;
+      if (!this.declaredMembers.has(key)) this.declaredMembers.set(key, id);
+      relationships.push({ from: id, to: scope.id, type: "contains", metadata: { memberType: "case" } });
+    }
+  }
+
+  /** `import UIKit`, `@testable import App`, `import struct Foundation.URL` */
+  private extractImport(node: TreeSitterNode, filePath: string, entities: ParsedEntity[]): void {
+    const match = node.text.match(
+      /\bimport\s+(?:(typealias|struct|class|enum|protocol|let|var|func)\s+)?([A-Za-z_][\w]*(?:\.[A-Za-z_][\w]*)*)/,
+    );
+    if (!match?.[2]) return;
+    const path = match[2];
+    const kind = match[1];
+    const module = kind ? path.slice(0, path.lastIndexOf(".")) || path : path;
+    const attributes = this.extractAttributes(node).map((a) => a.name);
+
+    entities.push({
+      id: this.uniqueId(`${filePath}:import:${path}`, node),
+      name: module,
+      type: "import",
+      filePath,
+      location: this.getNodeLocation(node),
+      importData: {
+        source: module,
+        specifiers: kind ? [{ local: path.split(".").pop()!, imported: path.split(".").pop()! }] : [],
+        isRelative: false,
+      },
+      metadata: {
+        ...(kind ? { importKind: kind } : {}),
+        ...(attributes.includes("testable") ? { isTestable: true } : {}),
+        ...(attributes.includes("_exported") ? { isExported: true } : {}),
+      },
+    });
+  }
+
+  /**
+   * Members of an extension belong to the extended type: `implType` is its simple name, as type
+   * entities are named, and `extensionOf` the qualified one
+   */
+  private extensionOwner(scope: Scope): { implType: string; extensionOf: string } {
+    return { implType: scope.qualifiedName.split(".").pop()!, extensionOf: scope.qualifiedName };
+  }
+
+  /** Declaration modifiers as written: `public`, `private(set)`, `static`, `override`, `final`, ... */
+  private extractModifiers(node: TreeSitterNode): string[] {
+    const modifiersNode = node.namedChildren.find((c) => c.type === "modifiers");
+    if (!modifiersNode) return [];
+    return modifiersNode.namedChildren
+      .filter((c) => MODIFIER_NODES.has(c.type))
+      .map((c) => c.text.replace(/\s+/g, ""));
+  }
+
+  /** `@name` and `@name(arguments)` attributes of a declaration */
+  private extractAttributes(node: TreeSitterNode): SwiftAttribute[] {
+    const attributes: SwiftAttribute[] = [];
+    for (const holder of node.namedChildren.filter((c) => c.type === "modifiers")) {
+      for (const child of holder.namedChildren) {
+        if (child.type !== "attribute") continue;
+        const match = child.text.match(/^@([\w.]+)\s*(?:\((.*)\))?/s);
+        if (!match?.[1]) continue;
+        attributes.push({
+          name: match[1],
+          ...(match[2] ? { arguments: match[2].split(",").map((a) => a.trim()) } : {}),
+        });
+      }
+    }
+    return attributes;
+  }
+
+  /** Calls made by a function, property accessor or initializer, without descending into nested types */
+  private extractCalls(
+    body: TreeSitterNode,
+    callerId: string,
+    owner: string,
+    relationships: EntityRelationship[],
+  ): void {
+    const stack = [...body.namedChildren].reverse();
+    while (stack.length > 0) {
+      const node = stack.pop()!;
+      if (node.type === "class_declaration" || node.type === "protocol_declaration") continue;
+      stack.push(...[...node.namedChildren].reverse());
+      if (node.type !== "call_expression") continue;
+
+      const callee = node.namedChildren[0];
+      let name: string | undefined;
+      let receiver: string | undefined;
+      if (callee?.type === "simple_identifier") {
+        name = callee.text;
+      } else if (callee?.type === "navigation_expression") {
+        const suffix = callee.childForFieldName("suffix");
+        name = (suffix?.childForFieldName("suffix") ?? suffix)?.text.replace(/^\./, "");
+        receiver = callee.childForFieldName("target")?.text;
+      }
+      if (!name || !/^[A-Za-z_]\w*$/.test(name)) continue;
+
+      const relationship: EntityRelationship = {
+        from: callerId,
+        to: name,
+        type: "calls",
+        metadata: {
+          line: node.startPosition.row + 1,
+          column: node.startPosition.column,
+          callee: receiver ? `${receiver}.${name}` : name,
+          calleeName: name,
+        },
+      };
+      relationships.push(relationship);
+      this.pending.push({ kind: "call", relationship, name, owner, receiver });
+    }
+  }
+
+  /** Edge to a type named in source; bound to a declaration of this file once the file is walked */
+  private pushTypeEdge(
+    relationships: EntityRelationship[],
+    fromId: string,
+    scope: string,
+    name: string,
+    type: EntityRelationship["type"],
+    node: { startPosition: { row: number } } | number,
+    metadata: Record<string, unknown> = {},
+  ): void {
+    const line = typeof node === "number" ? node : node.startPosition.row + 1;
+    const relationship: EntityRelationship = { from: fromId, to: name, type, metadata: { line, ...metadata } };
+    relationships.push(relationship);
+    this.pending.push({ kind: "type", relationship, name, scope });
+  }
+
+  private localType(written: string, scope: string): ParsedEntity | undefined {
+    return resolveSwiftType(written, scope, (qn) => this.declaredTypes.get(qn));
+  }
+
+  /**
+   * Superclass, conformance and protocol inheritance edges, now that every protocol of the file is
+   * known. An enum's first inherited type may be its raw value type, which is no conformance.
+   */
+  private classifyInheritance(relationships: EntityRelationship[]): void {
+    for (const { entity, kind, scope, inherited } of this.inheritance) {
+      const conformances: string[] = [];
+      inherited.forEach(({ name, line }, index) => {
+        const local = this.localType(name, scope);
+        const isProtocol = local ? local.metadata?.swiftKind === "protocol" : looksLikeProtocol(name);
+
+        if (index === 0 && kind === "enum" && RAW_VALUE_TYPES.has(name)) {
+          entity.metadata!.rawType = name;
+        } else if (kind === "protocol" && name === "AnyObject") {
+          entity.metadata!.classOnly = true;
+        } else if (index === 0 && kind === "class" && !isProtocol) {
+          entity.metadata!.superclass = name;
+          this.pushTypeEdge(relationships, entity.id!, scope, name, "extends", line);
+        } else {
+          conformances.push(name);
+          const type = kind === "protocol" ? "extends" : "implements";
+          this.pushTypeEdge(relationships, entity.id!, scope, name, type, line);
+        }
+      });
+      if (conformances.length > 0) entity.metadata!.conformances = conformances;
+    }
+  }
+
+  /**
+   * Point type and call edges at declarations of this file. Calls without a receiver or on
+   * `self`/`Self` look in the calling type, its extensions, superclasses and protocols declared
+   * here, then among top-level functions; `Type.x` looks for member `x` of `Type`, and `Type(...)`
+   * for its initializers.
+   */
+  private bindLocalTargets(): void {
+    for (const item of this.pending) {
+      const rel = item.relationship;
+      if (item.kind === "type") {
+        const local = this.localType(item.name, item.scope);
+        if (local) rel.to = local.id!;
+        continue;
+      }
+
+      let local: string | undefined;
+      if (!item.receiver || item.receiver === "self" || item.receiver === "Self") {
+        if (item.owner) local = this.findMember(item.owner, item.name);
+        if (!local && !item.receiver) {
+          const type = this.localType(item.name, item.owner);
+          local = type ? (this.findMember(type.metadata!.qualifiedName, "init") ?? type.id) : undefined;
+        }
+        if (!local && !item.receiver) local = this.declaredMembers.get(item.name);
+      } else {
+        const named = /^[A-Z]\w*(\.[A-Z]\w*)*$/.test(item.receiver);
+        const type = named ? this.localType(item.receiver, item.owner) : undefined;
+        if (type) local = this.findMember(type.metadata!.qualifiedName, item.name);
+      }
+      if (local) rel.to = local;
+    }
+  }
+
+  /** Member `name` of `type`, its superclasses or its protocols (protocol extensions included) */
+  private findMember(type: string, name: string): string | undefined {
+    const seen = new Set<string>();
+    const queue = [type];
+    while (queue.length > 0) {
+      const next = queue.shift()!;
+      if (seen.has(next)) continue;
+      seen.add(next);
+      const found = this.declaredMembers.get(`${next}.${name}`);
+      if (found) return found;
+
+      const entity = this.declaredTypes.get(next);
+      const scope = swiftOwnerName(next);
+      const bases = [entity?.metadata?.superclass, ...(entity?.metadata?.conformances ?? [])];
+      for (const base of bases) {
+        const local = typeof base === "string" ? this.localType(base, scope) : undefined;
+        if (local) queue.push(local.metadata!.qualifiedName);
+      }
+    }
+    return undefined;
+  }
+
+  /**
+   * `decorated_by` edges from declarations to the property wrappers, result builders and global
+   * actors this file declares. Wrappers from frameworks (`@Published`, `@State`) stay metadata only.
+   */
+  private linkAttributeTypes(entities: ParsedEntity[], relationships: EntityRelationship[]): void {
+    const declared = new Map<string, string>();
+    for (const entity of entities) {
+      const attributes = (entity.metadata?.attributes ?? []) as SwiftAttribute[];
+      if (entity.id && attributes.some((a) => ATTRIBUTE_TYPES.has(a.name))) declared.set(entity.name, entity.id);
+    }
+    if (declared.size === 0) return;
+
+    for (const entity of entities) {
+      const attributes = (entity.metadata?.attributes ?? []) as SwiftAttribute[];
+      for (const attribute of attributes) {
+        const target = declared.get(attribute.name.split(".").pop()!);
+        if (!target || !entity.id) continue;
+        relationships.push({
+          from: entity.id,
+          to: target,
+          type: "decorated_by",
+          metadata: {
+            line: entity.location?.start.line,
+            decorator: attribute.name,
+            arguments: attribute.arguments,
+          },
+        });
+      }
+    }
+  }
+}
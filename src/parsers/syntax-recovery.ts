@@ -0,0 +1,100 @@
+/**
+ * Error recovery for files tree-sitter cannot parse cleanly. Tree-sitter keeps going past a syntax
+ * error but folds the surrounding code into ERROR nodes, where the extractors no longer recognise
+ * declarations. Blanking the broken tokens and parsing again lets the intact parts come out as
+ * ordinary nodes, while every offset and line number still matches the original text.
+ */
+
+import type Parser from "tree-sitter";
+import type { SyntaxErrorRange } from "../types/parser.js";
+
+type SyntaxNode = Parser.SyntaxNode;
+
+// Each pass can expose a new error behind the one it removed; a few passes settle almost every file
+const MAX_RECOVERY_PASSES = 3;
+const MAX_REPORTED_ERRORS = 50;
+
+function hasErrorInside(node: SyntaxNode): boolean {
+  return node.type === "ERROR" || node.descendantsOfType("ERROR").length > 0;
+}
+
+/** ERROR nodes not nested in another ERROR node */
+function outermostErrors(root: SyntaxNode): SyntaxNode[] {
+  if (root.type === "ERROR") return [root];
+  return root.descendantsOfType("ERROR").filter((node) => {
+    for (let p = node.parent; p; p = p.parent) if (p.type === "ERROR") return false;
+    return true;
+  });
+}
+
+/**
+ * Spans of an ERROR node that have to go: stray tokens and broken subtrees. Complete subtrees
+ * inside it (a whole function after an unbalanced brace) are kept so the next parse sees them.
+ */
+function brokenSpans(error: SyntaxNode, out: Array<[number, number]>): void {
+  for (const child of error.children) {
+    if (child.childCount > 0 && !hasErrorInside(child)) continue;
+    if (child.childCount > 0) brokenSpans(child, out);
+    else if (child.endIndex > child.startIndex) out.push([child.startIndex, child.endIndex]);
+  }
+}
+
+/** Replace the spans with spaces, keeping line breaks so positions do not move */
+function blankSpans(text: string, spans: Array<[number, number]>): string {
+  let out = "";
+  let pos = 0;
+  for (const [start, end] of [...spans].sort((a, b) => a[0] - b[0])) {
+    if (end <= pos) continue;
+    const from = Math.max(start, pos);
+    out += text.slice(pos, from) + text.slice(from, end).replace(/[^\r\n]/g, " ");
+    pos = end;
+  }
+  return out + text.slice(pos);
+}
+
+function toRange(text: string, node: SyntaxNode): SyntaxErrorRange {
+  return {
+    startByte: Buffer.byteLength(text.slice(0, node.startIndex), "utf8"),
+    endByte: Buffer.byteLength(text.slice(0, node.endIndex), "utf8"),
+    startLine: node.startPosition.row + 1,
+    endLine: node.endPosition.row + 1,
+  };
+}
+
+/**
+ * Parse `content`, recovering from syntax errors. Returns the tree to extract from, the text it
+ * was parsed from (equal to `content` unless something was blanked) and the regions that could
+ * not be parsed, in original byte offsets.
+ */
+export function parseWithRecovery(
+  parse: (text: string) => Parser.Tree,
+  content: string,
+  firstTree: Parser.Tree,
+): { tree: Parser.Tree; source: string; syntaxErrors: SyntaxErrorRange[] } {
+  let tree = firstTree;
+  let source = content;
+  const found: SyntaxErrorRange[] = [];
+
+  for (let pass = 0; pass < MAX_RECOVERY_PASSES; pass++) {
+    const errors = outermostErrors(tree.rootNode);
+    if (errors.length === 0) break;
+    for (const error of errors) {
+      // Later passes mostly re-find what is left of an error already reported
+      const range = toRange(content, error);
+      if (found.some((r) => r.startByte <= range.startByte && range.endByte <= r.endByte)) continue;
+      found.push(range);
+    }
+
+    const spans: Array<[number, number]> = [];
+    for (const error of errors) brokenSpans(error, spans);
+    const blanked = blankSpans(source, spans);
+    if (blanked === source) break;
+    source = blanked;
+    tree = parse(source);
+  }
+
+  const syntaxErrors = found
+    .sort((a, b) => a.startByte - b.startByte || a.endByte - b.endByte)
+    .slice(0, MAX_REPORTED_ERRORS);
+  return { tree, source, syntaxErrors };
+}
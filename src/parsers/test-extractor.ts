@@ -0,0 +1,150 @@
+/**
+ * Test Extractor - test files and the test cases declared in them
+ * Test files are recognised by the conventions of their runner: Go `_test.go`, Jest/Vitest/Mocha
+ * `*.test.ts` / `*.spec.js` and `__tests__/`, pytest `test_*.py` / `*_test.py` and `conftest.py`,
+ * JUnit `src/test/` and `*Test.java`. Go, Python and JUnit tests are functions and methods their
+ * analyzers already extract; JS/TS tests are `it("...", () => ...)` calls, which become `test`
+ * entities named by their title with the calls and JSX of the callback attached, so the indexer
+ * links them like any function body.
+ */
+
+import type { ParsedCallSite, ParsedEntity, TreeSitterNode } from "../types/parser.js";
+
+const JS_TEST_FILE = /(\.(test|spec)\.[cm]?[jt]sx?$)|(^|\/)__tests__\/.+\.[cm]?[jt]sx?$/;
+const GO_TEST_FILE = /_test\.go$/;
+const PYTHON_TEST_FILE = /(^|\/)(test_[^/]*|[^/]*_test|conftest)\.py$/;
+const JVM_TEST_FILE = /(^|\/)src\/test\/|Tests?\.(java|kt)$/;
+
+/** `TestParse`, `BenchmarkParse`, `FuzzParse`, `Example`, `ExampleParse_second`; not `Testify` */
+const GO_TEST_FUNCTION = /^(Test|Benchmark|Fuzz|Example)($|[A-Z0-9_])/;
+
+const JVM_TEST_ANNOTATIONS = new Set(["Test", "ParameterizedTest", "RepeatedTest", "TestFactory"]);
+
+const JS_TEST_CALLEES = new Set(["it", "test", "specify"]);
+const JS_SUITE_CALLEES = new Set(["describe", "context", "suite"]);
+// `it.only`, `test.skip`, `describe.concurrent`: same declaration, different run mode
+const JS_TEST_MODIFIERS = new Set(["only", "skip", "todo", "concurrent", "failing"]);
+const FUNCTION_NODES = new Set(["arrow_function", "function_expression", "function"]);
+
+export function isTestFile(filePath: string): boolean {
+  const path = filePath.replace(/\\/g, "/");
+  return [JS_TEST_FILE, GO_TEST_FILE, PYTHON_TEST_FILE, JVM_TEST_FILE].some((pattern) => pattern.test(path));
+}
+
+type DeclarationLike = { name: string; type: string; filePath: string; metadata?: unknown };
+
+/** Declared in a test file: tagged `metadata.isTest` at index time, or recognised by its path */
+export function isTestEntity(entity: { filePath: string; metadata?: unknown }): boolean {
+  return (entity.metadata as Record<string, unknown> | undefined)?.isTest === true || isTestFile(entity.filePath);
+}
+
+/** Go test/benchmark/fuzz/example functions, pytest `test_*`, JUnit `@Test` methods and JS `test` entities */
+export function isTestDeclaration(entity: DeclarationLike): boolean {
+  if (entity.type === "test") return true;
+  const path = entity.filePath.replace(/\\/g, "/");
+  const meta = (entity.metadata as Record<string, any> | undefined) ?? {};
+  if (GO_TEST_FILE.test(path)) {
+    return entity.type === "function" && !meta.receiver && GO_TEST_FUNCTION.test(entity.name);
+  }
+  const decorators: Array<{ name?: string }> = Array.isArray(meta.decorators) ? meta.decorators : [];
+  if (decorators.some((d) => JVM_TEST_ANNOTATIONS.has(String(d?.name)))) return true;
+  if (PYTHON_TEST_FILE.test(path)) {
+    return /function|method/.test(entity.type) && entity.name.startsWith("test");
+  }
+  return false;
+}
+
+/** Runner a test declaration belongs to */
+export function testFramework(entity: DeclarationLike): string | null {
+  const framework = (entity.metadata as Record<string, unknown> | undefined)?.framework;
+  if (entity.type === "test" && typeof framework === "string") return framework;
+  if (entity.filePath.endsWith(".go")) return "go";
+  if (entity.filePath.endsWith(".py")) return "pytest";
+  return /\.(java|kt)$/.test(entity.filePath) ? "junit" : null;
+}
+
+function location(node: TreeSitterNode): ParsedEntity["location"] {
+  return {
+    start: { line: node.startPosition.row + 1, column: node.startPosition.column, index: node.startIndex },
+    end: { line: node.endPosition.row + 1, column: node.endPosition.column, index: node.endIndex },
+  };
+}
+
+function title(node: TreeSitterNode | undefined): string | undefined {
+  if (!node) return undefined;
+  if (node.type === "template_string" && node.namedChildren.some((c) => c.type === "template_substitution")) {
+    // `it(\`handles ${kind}\`)`: keep the wording, the substitution is only known at run time
+    return node.text.slice(1, -1);
+  }
+  const match = /^(["'`])([\s\S]*)\1$/.exec(node.text.trim());
+  return match ? match[2] : undefined;
+}
+
+/** `it`, `it.only`, `test.skip` → the base callee and its modifier */
+function calleeOf(call: TreeSitterNode): { name: string; modifier?: string } | undefined {
+  const callee = call.childForFieldName("function");
+  if (callee?.type === "identifier") return { name: callee.text };
+  if (callee?.type !== "member_expression") return undefined;
+  const object = callee.childForFieldName("object");
+  const property = callee.childForFieldName("property");
+  if (object?.type !== "identifier" || !property || !JS_TEST_MODIFIERS.has(property.text)) return undefined;
+  return { name: object.text, modifier: property.text };
+}
+
+type BodyAnalysis = Pick<ParsedEntity, "calls" | "renders">;
+
+/** `expect(x).toBe(y)` and nested `it` calls exercise the runner, not the code under test */
+function isRunnerCall(site: ParsedCallSite): boolean {
+  if (site.qualifier) return /^expect\b/.test(site.qualifier);
+  return site.name === "expect" || JS_TEST_CALLEES.has(site.name) || JS_SUITE_CALLEES.has(site.name);
+}
+
+/**
+ * Test cases of a JS/TS test file. `describe` blocks nest the titles into `metadata.suite`; the
+ * callback body is handed to `analyzeBody` for the calls and rendered components it contains.
+ */
+export function extractTestCases(
+  root: TreeSitterNode,
+  filePath: string,
+  source: string,
+  analyzeBody: (body: TreeSitterNode) => BodyAnalysis,
+): ParsedEntity[] {
+  if (!JS_TEST_FILE.test(filePath.replace(/\\/g, "/"))) return [];
+  const framework = /["']vitest["']/.test(source) ? "vitest" : /["'](mocha|chai)["']/.test(source) ? "mocha" : "jest";
+  const tests: ParsedEntity[] = [];
+
+  const visit = (node: TreeSitterNode, suite: string[]) => {
+    if (node.type === "call_expression") {
+      const callee = calleeOf(node);
+      const args = node.childForFieldName("arguments")?.namedChildren ?? [];
+      const name = title(args[0]);
+      const callback = args.find((arg) => FUNCTION_NODES.has(arg.type));
+      if (callee && name !== undefined && JS_SUITE_CALLEES.has(callee.name)) {
+        if (callback) visit(callback, [...suite, name]);
+        return;
+      }
+      if (callee && name !== undefined && JS_TEST_CALLEES.has(callee.name)) {
+        const body = callback?.childForFieldName("body");
+        const analysis = body ? analyzeBody(body) : {};
+        const calls = analysis.calls?.filter((site) => !isRunnerCall(site));
+        tests.push({
+          name,
+          type: "test",
+          location: location(node),
+          ...(calls?.length ? { calls } : {}),
+          ...(analysis.renders?.length ? { renders: analysis.renders } : {}),
+          metadata: {
+            framework,
+            suite,
+            fullName: [...suite, name].join(" "),
+            ...(callee.modifier ? { modifier: callee.modifier } : {}),
+          },
+        });
+        return;
+      }
+    }
+    for (const child of node.namedChildren) visit(child, suite);
+  };
+  visit(root, []);
+  return tests;
+}
@@ -10,21 +10,57 @@
  */
 
 import { createHash } from "node:crypto";
-import { createRequire } from "node:module";
 import { LRUCache } from "lru-cache";
 import Parser from "tree-sitter";
-import { ConfigLoader } from "../config/yaml-config.js";
-import type { ParsedEntity, ParseResult, SupportedLanguage } from "../types/parser.js";
+import { ConfigLoader, type CustomQuerySpec, type EntityKindRule } from "../config/yaml-config.js";
+import { ParseTimeoutError } from "../types/errors.js";
+import type {
+  AccessKind,
+  ParsedAccessSite,
+  ParsedCallSite,
+  ParsedEntity,
+  ParseResult,
+  SupportedLanguage,
+  SyntaxErrorRange,
+} from "../types/parser.js";
+import { getLog } from "../utils/structured-log.js";
+import { collapseAnonymousEntities } from "./anonymous-entities.js";
 import { CAnalyzer } from "./c-analyzer.js";
+import { callArguments } from "./call-arguments.js";
+import { extractComments } from "./comment-extractor.js";
+import { cyclomaticComplexity } from "./complexity.js";
+import { detectLanguageFromContent } from "./content-language.js";
 import { CppAnalyzer } from "./cpp-analyzer.js";
 import { CSharpAnalyzer } from "./csharp-analyzer.js";
+import {
+  type CompiledCustomQuery,
+  compileCustomQueries,
+  customQueriesFor,
+  runCustomQueries,
+} from "./custom-queries.js";
+import { leadingDocComment } from "./doc-comments.js";
+import { entityKindFilter, filterEntityKinds } from "./entity-kinds.js";
+import { envReaderRelationships, extractEnvVars } from "./env-extractor.js";
 import { GoAnalyzer } from "./go-analyzer.js";
+import { resolveGoBuildTarget } from "./go-build-constraints.js";
+import { GRAMMAR_MODULES, grammarPath, loadGrammar } from "./grammar-loader.js";
+import { GraphqlAnalyzer } from "./graphql-analyzer.js";
 import { JavaAnalyzer } from "./java-analyzer.js";
 import { KotlinAnalyzer } from "./kotlin-analyzer.js";
 import { MarkdownAnalyzer } from "./markdown-analyzer.js";
+import { PhpAnalyzer } from "./php-analyzer.js";
+import { ProtoAnalyzer } from "./proto-analyzer.js";
 import { createPythonAnalyzer } from "./python-analyzer.js";
+import { extractRoutes, routeHandlerRelationships } from "./route-extractor.js";
+import { RubyAnalyzer } from "./ruby-analyzer.js";
 import { RustAnalyzer } from "./rust-analyzer.js";
+import { ScalaAnalyzer } from "./scala-analyzer.js";
+import { SqlAnalyzer } from "./sql-analyzer.js";
+import { SwiftAnalyzer } from "./swift-analyzer.js";
+import { parseWithRecovery } from "./syntax-recovery.js";
+import { extractTestCases } from "./test-extractor.js";
 import { VbaAnalyzer } from "./vba-analyzer.js";
+import { withVisibility } from "./visibility.js";
 
 type TreeSitterNode = Parser.SyntaxNode;
 type TreeSitterTree = Parser.Tree;
@@ -33,58 +69,7 @@ type TreeSitterEdit = Parser.Edit;
 const CACHE_MAX_SIZE = 100 * 1024 * 1024; // 100MB
 const CACHE_TTL = 1000 * 60 * 60; // 1h
 
-const requireModule = createRequire(import.meta.url);
-
-const LANGUAGE_LOADERS: Partial<Record<SupportedLanguage, () => Promise<any>>> = {
-  javascript: async () => {
-    const m: any = requireModule("tree-sitter-javascript");
-    return m.default ?? m;
-  },
-  jsx: async () => {
-    const m: any = requireModule("tree-sitter-javascript");
-    return m.default ?? m;
-  },
-  typescript: async () => {
-    const m: any = requireModule("tree-sitter-typescript");
-    return m.typescript ?? m.default?.typescript;
-  },
-  tsx: async () => {
-    const m: any = requireModule("tree-sitter-typescript");
-    return m.tsx ?? m.default?.tsx;
-  },
-  python: async () => {
-    const m: any = requireModule("tree-sitter-python");
-    return m.default ?? m;
-  },
-  c: async () => {
-    const m: any = requireModule("tree-sitter-c");
-    return m.default ?? m;
-  },
-  cpp: async () => {
-    const m: any = requireModule("tree-sitter-cpp");
-    return m.default ?? m;
-  },
-  rust: async () => {
-    const m: any = requireModule("tree-sitter-rust");
-    return m.default ?? m;
-  },
-  csharp: async () => {
-    const m: any = requireModule("tree-sitter-c-sharp");
-    return m.default ?? m;
-  },
-  go: async () => {
-    const m: any = requireModule("tree-sitter-go");
-    return m.default ?? m;
-  },
-  java: async () => {
-    const m: any = requireModule("tree-sitter-java");
-    return m.default ?? m;
-  },
-  kotlin: async () => {
-    const m: any = requireModule("tree-sitter-kotlin");
-    return m.default ?? m;
-  },
-};
+const log = getLog("parser");
 
 interface ParseCacheEntry {
   tree: TreeSitterTree | null;
@@ -92,6 +77,7 @@ interface ParseCacheEntry {
   hash: string;
   timestamp: number;
   relationships?: any[];
+  syntaxErrors?: SyntaxErrorRange[];
 }
 
 function ds(node: TreeSitterNode, types: string | string[]): TreeSitterNode[] {
@@ -110,7 +96,11 @@ function ds(node: TreeSitterNode, types: string | string[]): TreeSitterNode[] {
   return out;
 }
 
-function detectLanguage(filePath: string): SupportedLanguage {
+// `.h` is shared by C and C++; these only parse as C++
+const CPP_HEADER_MARKERS =
+  /^\s*(namespace\s+[\w:]*\s*{|class\s+\w+[^;(]*[{:]|template\s*<|using\s+namespace\s|(public|private|protected)\s*:)/m;
+
+function detectLanguage(filePath: string, content?: string, byContent = false): SupportedLanguage {
   const ext = filePath.split(".").pop()?.toLowerCase();
   switch (ext) {
     case "js":
@@ -128,6 +118,14 @@ function detectLanguage(filePath: string): SupportedLanguage {
     case "md":
     case "mdx":
       return "markdown";
+    case "proto":
+      return "proto";
+    case "sql":
+      return "sql";
+    case "graphql":
+    case "gql":
+    case "graphqls":
+      return "graphql";
     case "py":
     case "pyi":
     case "pyw":
@@ -148,8 +146,23 @@ function detectLanguage(filePath: string): SupportedLanguage {
     case "kt":
     case "kts":
       return "kotlin";
+    case "rb":
+    case "rake":
+    case "gemspec":
+    case "ru":
+      return "ruby";
+    case "php":
+    case "phtml":
+      return "php";
+    case "swift":
+      return "swift";
+    case "scala":
+    case "sc":
+    case "sbt":
+      return "scala";
     case "h":
-      return filePath.includes("++") || filePath.includes("cpp") || filePath.includes("cxx") ? "cpp" : "c";
+      if (filePath.includes("++") || filePath.includes("cpp") || filePath.includes("cxx")) return "cpp";
+      return content !== undefined && CPP_HEADER_MARKERS.test(content) ? "cpp" : "c";
     case "hpp":
     case "hxx":
     case "hh":
@@ -162,8 +175,10 @@ function detectLanguage(filePath: string): SupportedLanguage {
     case "cls":
     case "frm":
       return "vba";
-    default:
-      return "javascript";
+    default: {
+      const detected = byContent && content !== undefined ? detectLanguageFromContent(content, filePath) : null;
+      return detected?.language ?? "javascript";
+    }
   }
 }
 
@@ -174,6 +189,319 @@ function convertPosition(node: TreeSitterNode) {
   };
 }
 
+// Nested functions are extracted as entities of their own, so their calls are not attributed to the outer one
+const NESTED_FUNCTION_TYPES = new Set([
+  "function_declaration",
+  "function_expression",
+  "arrow_function",
+  "method_definition",
+]);
+
+/**
+ * Call expressions inside a JS/TS function body: `foo()`, `this.save()`, `api.client.get()`.
+ */
+function collectCallSites(body: TreeSitterNode): ParsedCallSite[] {
+  const calls: ParsedCallSite[] = [];
+  const stack: TreeSitterNode[] = [...body.namedChildren].reverse();
+  while (stack.length > 0) {
+    const current = stack.pop()!;
+    if (NESTED_FUNCTION_TYPES.has(current.type)) continue;
+
+    if (current.type === "call_expression") {
+      const callee = current.childForFieldName("function");
+      const line = current.startPosition.row + 1;
+      const column = current.startPosition.column;
+      const args = callArguments(current.childForFieldName("arguments"));
+      if (callee?.type === "identifier") {
+        calls.push({ name: callee.text, line, column, ...args });
+      } else if (callee?.type === "member_expression") {
+        const property = callee.childForFieldName("property");
+        const object = callee.childForFieldName("object");
+        if (property) calls.push({ name: property.text, qualifier: object?.text, line, column, ...args });
+      }
+    }
+
+    const children = current.namedChildren;
+    for (let i = children.length - 1; i >= 0; i--) stack.push(children[i]!);
+  }
+  return calls;
+}
+
+/** Whether two handles denote the same syntax node */
+function sameNode(a: TreeSitterNode | null | undefined, b: TreeSitterNode): boolean {
+  return !!a && a.startIndex === b.startIndex && a.endIndex === b.endIndex && a.type === b.type;
+}
+
+/**
+ * How an expression is used: written when assigned to, directly or through a member or index of it
+ * (`this.items[i] = x` writes `this.items`), or deleted from; read otherwise.
+ */
+function jsAccessKind(node: TreeSitterNode): AccessKind {
+  let current = node;
+  let parent = current.parent;
+  while (
+    parent &&
+    (parent.type === "parenthesized_expression" ||
+      parent.type === "non_null_expression" ||
+      ((parent.type === "member_expression" || parent.type === "subscript_expression") &&
+        sameNode(parent.childForFieldName("object"), current)))
+  ) {
+    current = parent;
+    parent = current.parent;
+  }
+  if (!parent) return "read";
+  const assigned = sameNode(parent.childForFieldName("left"), current);
+  if (parent.type === "assignment_expression" && assigned) return "write";
+  if (parent.type === "augmented_assignment_expression" && assigned) return "readwrite";
+  if (parent.type === "update_expression") return "readwrite";
+  if (parent.type === "unary_expression" && parent.childForFieldName("operator")?.text === "delete") return "write";
+  return "read";
+}
+
+/** Names a JS/TS pattern binds: `x`, `{ a, b: c }`, `[first, ...rest]` */
+function patternNames(pattern: TreeSitterNode | null, names: Set<string>): void {
+  const stack = pattern ? [pattern] : [];
+  while (stack.length > 0) {
+    const current = stack.pop()!;
+    if (current.type === "identifier" || current.type === "shorthand_property_identifier_pattern") {
+      names.add(current.text);
+    }
+    // Default values in a pattern are expressions, not bindings
+    const children =
+      current.type === "assignment_pattern" ? [current.childForFieldName("left")] : current.namedChildren;
+    for (const child of children) if (child) stack.push(child);
+  }
+}
+
+/**
+ * Fields of the enclosing class (`this.items`) and free names a JS/TS function body reads or
+ * writes. Parameters, names the body declares and called names are left out; which of the free
+ * names are module-level variables is decided by the indexer, which sees the file's declarations.
+ */
+function collectAccessSites(fn: TreeSitterNode, body: TreeSitterNode): ParsedAccessSite[] {
+  const locals = new Set<string>();
+  patternNames(fn.childForFieldName("parameters") ?? fn.childForFieldName("parameter"), locals);
+  const declarations: TreeSitterNode[] = [body];
+  while (declarations.length > 0) {
+    const current = declarations.pop()!;
+    if (current.type === "variable_declarator") patternNames(current.childForFieldName("name"), locals);
+    else if (current.type === "catch_clause") patternNames(current.childForFieldName("parameter"), locals);
+    else if (current.type === "function_declaration" || current.type === "class_declaration") {
+      const name = current.childForFieldName("name")?.text;
+      if (name) locals.add(name);
+    }
+    if (NESTED_FUNCTION_TYPES.has(current.type)) continue;
+    for (const child of current.namedChildren) declarations.push(child);
+  }
+
+  const isCallee = (node: TreeSitterNode) =>
+    (node.parent?.type === "call_expression" && sameNode(node.parent.childForFieldName("function"), node)) ||
+    (node.parent?.type === "new_expression" && sameNode(node.parent.childForFieldName("constructor"), node));
+
+  const sites: ParsedAccessSite[] = [];
+  const stack: TreeSitterNode[] = [...body.namedChildren].reverse();
+  while (stack.length > 0) {
+    const current = stack.pop()!;
+    if (NESTED_FUNCTION_TYPES.has(current.type)) continue;
+    const line = current.startPosition.row + 1;
+    const column = current.startPosition.column;
+
+    if (current.type === "member_expression" && current.childForFieldName("object")?.type === "this") {
+      const property = current.childForFieldName("property");
+      if (property && !isCallee(current)) {
+        sites.push({ name: property.text, qualifier: "this", line, column, access: jsAccessKind(current) });
+      }
+      continue;
+    }
+    if (
+      (current.type === "identifier" || current.type === "shorthand_property_identifier") &&
+      !locals.has(current.text) &&
+      !isCallee(current) &&
+      !current.parent?.type.startsWith("jsx_")
+    ) {
+      sites.push({ name: current.text, line, column, access: jsAccessKind(current) });
+      continue;
+    }
+
+    const children = current.namedChildren;
+    for (let i = children.length - 1; i >= 0; i--) stack.push(children[i]!);
+  }
+  return sites;
+}
+
+/** React hooks by convention: `useState`, `useCart`, `use3D` */
+const HOOK_NAME = /^use[A-Z0-9]/;
+const COMPONENT_BASE = /^(React\.)?(Pure)?Component$/;
+/** Wrappers whose result is still the component they wrap: `const Card = memo(() => ...)` */
+const COMPONENT_WRAPPER = /^(React\.)?(memo|forwardRef)$/;
+/** `React.FC<Props>` and its aliases on the variable holding a component */
+const FC_ANNOTATION = /^:?\s*(?:React\.)?(?:FC|FunctionComponent|VFC)\s*<([\s\S]+)>\s*$/;
+// Inline callbacks such as `items.map((item) => <Row />)` render on behalf of the component around them
+const RENDER_BOUNDARIES = new Set(["function_declaration", "method_definition", "class_declaration", "class"]);
+
+/**
+ * The variable declarator an anonymous function initializes, through `memo`/`forwardRef`:
+ * `const Card = () => ...` and `const Card = memo(function () { ... })` both declare `Card`.
+ */
+function declaringVariable(node: TreeSitterNode): TreeSitterNode | undefined {
+  let current = node.parent;
+  while (current?.type === "arguments" && current.parent?.type === "call_expression") {
+    const callee = current.parent.childForFieldName("function");
+    if (!callee || !COMPONENT_WRAPPER.test(callee.text)) return undefined;
+    current = current.parent.parent;
+  }
+  return current?.type === "variable_declarator" ? current : undefined;
+}
+
+function declaredName(node: TreeSitterNode): string | undefined {
+  const name = declaringVariable(node)?.childForFieldName("name");
+  return name?.type === "identifier" ? name.text : undefined;
+}
+
+/**
+ * JSX in a component body: whether there is any, and the components it renders (`<Canvas />`,
+ * `<UI.Panel>`), first use of each. Host elements (`<div>`) and fragments are not components.
+ */
+function collectJsx(body: TreeSitterNode): { hasJsx: boolean; rendered: ParsedCallSite[] } {
+  const rendered = new Map<string, ParsedCallSite>();
+  let hasJsx = false;
+  const stack: TreeSitterNode[] = [body];
+  while (stack.length > 0) {
+    const current = stack.pop()!;
+    if (current !== body && RENDER_BOUNDARIES.has(current.type)) continue;
+
+    if (current.type === "jsx_opening_element" || current.type === "jsx_self_closing_element") {
+      hasJsx = true;
+      const written = current.childForFieldName("name")?.text;
+      const dot = written?.lastIndexOf(".") ?? -1;
+      const name = written && dot >= 0 ? written.slice(dot + 1) : written;
+      const isComponent = !!written && (/^[A-Z]/.test(name!) || (dot >= 0 && /^[A-Z]/.test(written)));
+      if (isComponent && name !== "Fragment" && !rendered.has(written!)) {
+        rendered.set(written!, {
+          name: name!,
+          ...(dot >= 0 ? { qualifier: written!.slice(0, dot) } : {}),
+          line: current.startPosition.row + 1,
+          column: current.startPosition.column,
+        });
+      }
+    } else if (current.type === "jsx_fragment" || current.type === "jsx_element") {
+      hasJsx = true;
+    }
+
+    const children = current.namedChildren;
+    for (let i = children.length - 1; i >= 0; i--) stack.push(children[i]!);
+  }
+  return { hasJsx, rendered: [...rendered.values()] };
+}
+
+/** Props type of a function component: its first parameter's annotation, else `React.FC<Props>` */
+function functionPropsType(node: TreeSitterNode): string | undefined {
+  const first = node.childForFieldName("parameters")?.namedChildren.find((c) => c.type !== "comment");
+  const annotation = first?.childForFieldName("type")?.text.replace(/^:\s*/, "");
+  if (annotation) return annotation;
+  return FC_ANNOTATION.exec(declaringVariable(node)?.childForFieldName("type")?.text ?? "")?.[1]?.trim();
+}
+
+type JsDecorator = NonNullable<ParsedEntity["decorators"]>[number];
+
+/**
+ * Decorators applied to a JS/TS class, method or field. Depending on the grammar they are children
+ * of the declaration, of its `export` statement, or (methods in TypeScript) preceding siblings in the
+ * class body.
+ */
+function collectJsDecorators(node: TreeSitterNode): JsDecorator[] {
+  const nodes: TreeSitterNode[] = [];
+  for (let prev = node.previousSibling; prev?.type === "decorator"; prev = prev.previousSibling) nodes.unshift(prev);
+  if (node.parent?.type === "export_statement") {
+    nodes.push(...node.parent.children.filter((c) => c.type === "decorator"));
+  }
+  nodes.push(...node.children.filter((c) => c.type === "decorator"));
+
+  const decorators: JsDecorator[] = [];
+  for (const decorator of nodes) {
+    const expression = decorator.namedChildren[0];
+    if (!expression) continue;
+    const callee = expression.type === "call_expression" ? expression.childForFieldName("function") : expression;
+    if (!callee) continue;
+    const args = expression.type === "call_expression" ? expression.childForFieldName("arguments") : null;
+    decorators.push({
+      name: callee.text,
+      arguments: args ? args.namedChildren.filter((a) => a.type !== "comment").map((a) => a.text) : undefined,
+      raw: expression.text,
+      line: decorator.startPosition.row + 1,
+    });
+  }
+  return decorators;
+}
+
+/** Generic type parameters such as `<T extends Base = Default>` on a JS/TS declaration */
+function collectTypeParameters(node: TreeSitterNode): Array<{ name: string; constraint?: string; default?: string }> {
+  const list = node.namedChildren.find((c) => c.type === "type_parameters");
+  if (!list) return [];
+  const params: Array<{ name: string; constraint?: string; default?: string }> = [];
+  for (const param of list.namedChildren) {
+    if (param.type !== "type_parameter") continue;
+    const name = param.namedChildren.find((c) => c.type === "type_identifier");
+    if (!name) continue;
+    const constraint = param.namedChildren.find((c) => c.type === "constraint");
+    const value = param.namedChildren.find((c) => c.type === "default_type");
+    params.push({
+      name: name.text,
+      constraint: constraint?.namedChildren[0]?.text,
+      default: value?.namedChildren[0]?.text,
+    });
+  }
+  return params;
+}
+
+/** Type name without its type arguments: `Repository<User>` names `Repository` */
+function heritageName(node: TreeSitterNode): string {
+  return node.text.replace(/<[\s\S]*$/, "").trim();
+}
+
+/**
+ * Base classes and implemented interfaces of a JS/TS class (`class_heritage`) or the extended
+ * interfaces of a TS interface (`extends_type_clause`); undefined when there are none.
+ */
+function collectJsHeritage(node: TreeSitterNode): ParsedEntity["inheritance"] {
+  const baseClasses: string[] = [];
+  const interfaces: string[] = [];
+  const names = (clause: TreeSitterNode) =>
+    clause.namedChildren
+      .filter((c) => c.type !== "type_arguments" && c.type !== "comment")
+      .map(heritageName)
+      .filter((name) => name.length > 0);
+
+  for (const child of node.namedChildren) {
+    if (child.type === "extends_type_clause") baseClasses.push(...names(child));
+    if (child.type !== "class_heritage") continue;
+    // TypeScript wraps the clauses; the JavaScript grammar puts the base expression directly inside
+    const clauses = child.namedChildren.filter((c) => c.type === "extends_clause" || c.type === "implements_clause");
+    if (clauses.length === 0) baseClasses.push(...names(child));
+    for (const clause of clauses) {
+      (clause.type === "extends_clause" ? baseClasses : interfaces).push(...names(clause));
+    }
+  }
+
+  if (baseClasses.length === 0 && interfaces.length === 0) return undefined;
+  return { baseClasses, ...(interfaces.length ? { interfaces } : {}) };
+}
+
+/** TypeScript `private`/`protected`/`public` on a class member */
+function accessibilityOf(node: TreeSitterNode): string[] {
+  return node.children.filter((c) => c.type === "accessibility_modifier").map((c) => c.text);
+}
+
+/** Decorators and type parameters as entity fields, omitted when there are none */
+function jsDeclarationDetails(node: TreeSitterNode): Pick<ParsedEntity, "decorators" | "metadata"> {
+  const decorators = collectJsDecorators(node);
+  const typeParameters = collectTypeParameters(node);
+  return {
+    ...(decorators.length ? { decorators } : {}),
+    ...(typeParameters.length ? { metadata: { typeParameters } } : {}),
+  };
+}
+
 export class TreeSitterParser {
   private parser: Parser | null = null;
   private languages: Map<SupportedLanguage, any> = new Map();
@@ -186,19 +514,36 @@ export class TreeSitterParser {
   private rustAnalyzer = new RustAnalyzer();
   private cAnalyzer = new CAnalyzer();
   private cppAnalyzer = new CppAnalyzer();
-  private goAnalyzer = new GoAnalyzer();
+  private goAnalyzer: GoAnalyzer;
   private javaAnalyzer = new JavaAnalyzer();
   private kotlinAnalyzer = new KotlinAnalyzer();
+  private rubyAnalyzer = new RubyAnalyzer();
+  private phpAnalyzer = new PhpAnalyzer();
+  private swiftAnalyzer = new SwiftAnalyzer();
+  private scalaAnalyzer = new ScalaAnalyzer();
   private vbaAnalyzer = new VbaAnalyzer();
   private markdownAnalyzer = new MarkdownAnalyzer();
+  private protoAnalyzer = new ProtoAnalyzer();
+  private sqlAnalyzer = new SqlAnalyzer();
+  private graphqlAnalyzer = new GraphqlAnalyzer();
 
   private cacheHits = 0;
   private cacheMisses = 0;
   private bufferSize: number;
+  private customQuerySpecs: Record<string, CustomQuerySpec[]> | undefined;
+  private entityKindRules: Record<string, EntityKindRule> | undefined;
+  private detectByContent: boolean;
+  private extractCommentEntities: boolean;
+  private customQueries: Map<SupportedLanguage, CompiledCustomQuery[]> = new Map();
 
   constructor() {
     const config = ConfigLoader.getInstance();
     this.bufferSize = config.getParserConfig().treeSitter?.bufferSize || 1024 * 1024;
+    this.goAnalyzer = new GoAnalyzer(resolveGoBuildTarget(config.getParserConfig().go));
+    this.customQuerySpecs = config.getParserConfig().customQueries;
+    this.entityKindRules = config.getParserConfig().entityKinds;
+    this.detectByContent = config.isContentLanguageDetectionEnabled();
+    this.extractCommentEntities = config.getParserConfig().comments?.enabled !== false;
     this.disableCache = process.env.PARSER_DISABLE_CACHE === "1" || process.env.NODE_ENV === "test";
 
     this.cache = new LRUCache<string, ParseCacheEntry>({
@@ -215,7 +560,7 @@ export class TreeSitterParser {
     if (this.initialized) return;
     this.parser = new Parser();
     this.initialized = true;
-    console.error("[TreeSitterParser] Initialization complete");
+    log.info("parser initialized");
   }
 
   private getFromCache(key: string): ParseCacheEntry | undefined {
@@ -232,27 +577,38 @@ export class TreeSitterParser {
     const cached = this.languages.get(language);
     if (cached) return cached;
 
-    const loader = LANGUAGE_LOADERS[language];
-    if (!loader) {
+    if (!GRAMMAR_MODULES[language]) {
       throw new Error(`Unsupported language: ${language}`);
     }
 
-    try {
-      const lang = await loader();
-      if (!lang) throw new Error(`Language loader returned empty result for ${language}`);
-      this.languages.set(language, lang);
-      console.error(`[TreeSitterParser] Loaded language: ${language}`);
-      return lang;
-    } catch (e: any) {
-      throw new Error(`[TreeSitterParser] Language '${language}' is not available: ${e?.message || e}`);
+    const lang = loadGrammar(language);
+    this.languages.set(language, lang);
+    log.info("loaded grammar", { language, grammar: grammarPath(language) });
+
+    const specs = customQueriesFor(this.customQuerySpecs, language);
+    if (specs.length > 0) {
+      try {
+        this.customQueries.set(language, compileCustomQueries(language, lang, specs));
+      } catch (error) {
+        // Startup validation reports these; a parser built without it still parses, minus the queries
+        log.error("custom queries disabled", { language, error: error instanceof Error ? error.message : error });
+      }
     }
+    return lang;
   }
 
-  async parse(filePath: string, content: string, contentHash: string, oldTree?: TreeSitterTree): Promise<ParseResult> {
+  /** `timeoutMs` bounds the tree-sitter passes over the file; past it a ParseTimeoutError is thrown */
+  async parse(
+    filePath: string,
+    content: string,
+    contentHash: string,
+    oldTree?: TreeSitterTree,
+    timeoutMs = 0,
+  ): Promise<ParseResult> {
     if (!this.initialized || !this.parser) throw new Error("Parser not initialized");
 
     const startTime = Date.now();
-    const language = detectLanguage(filePath);
+    const language = detectLanguage(filePath, content, this.detectByContent);
 
     const internalHash = createHash("sha1").update(content).digest("hex");
     const cacheKey = `${filePath}:${internalHash}`;
@@ -268,6 +624,7 @@ export class TreeSitterParser {
         parseTimeMs: 0,
         fromCache: true,
       };
+      if (cached.syntaxErrors?.length) result.syntaxErrors = cached.syntaxErrors;
       if (cached.relationships?.length) {
         (result as any).relationships = cached.relationships;
       }
@@ -276,18 +633,23 @@ export class TreeSitterParser {
 
     if (language === "vba") {
       const vbaAnalysis = await this.vbaAnalyzer.analyze(content, filePath);
-      let entities = vbaAnalysis.entities || [];
-      const relationships = vbaAnalysis.relationships || [];
-
-      entities = entities.map((e) => ({ ...e, language }));
+      const { entities, relationships } = this.keepEntityKinds(
+        language,
+        (vbaAnalysis.entities || []).map((e) => ({ ...e, language })),
+        vbaAnalysis.relationships || [],
+      );
 
       this.cacheMisses++;
       this.setCache(cacheKey, { tree: null, entities, hash: internalHash, timestamp: Date.now(), relationships });
 
       const parseTimeMs = Date.now() - startTime;
-      console.error(
-        `[TreeSitterParser] VBA analysis: ${entities.length} entities, ${relationships.length} relationships`,
-      );
+      log.debug("parsed file", {
+        file: filePath,
+        language,
+        entities: entities.length,
+        relationships: relationships.length,
+        ms: parseTimeMs,
+      });
 
       const result: ParseResult = {
         filePath,
@@ -304,10 +666,24 @@ export class TreeSitterParser {
       return result;
     }
 
-    if (language === "markdown") {
-      const md = this.markdownAnalyzer.analyze(content, filePath);
-      const entities = (md.entities || []).map((e) => ({ ...e, language }));
-      const relationships = md.relationships || [];
+    // None of these has a tree-sitter grammar; their analyzers read the text
+    const textAnalyzer =
+      language === "markdown"
+        ? this.markdownAnalyzer
+        : language === "proto"
+          ? this.protoAnalyzer
+          : language === "sql"
+            ? this.sqlAnalyzer
+            : language === "graphql"
+              ? this.graphqlAnalyzer
+              : null;
+    if (textAnalyzer) {
+      const analysis = textAnalyzer.analyze(content, filePath);
+      const { entities, relationships } = this.keepEntityKinds(
+        language,
+        (analysis.entities || []).map((e) => ({ ...e, language })),
+        analysis.relationships || [],
+      );
 
       this.cacheMisses++;
       this.setCache(cacheKey, { tree: null, entities, hash: internalHash, timestamp: Date.now(), relationships });
@@ -331,81 +707,140 @@ export class TreeSitterParser {
     this.parser.setLanguage(lang);
 
     const options = { bufferSize: this.bufferSize };
-    const tree: TreeSitterTree = oldTree
-      ? (this.parser.parse(content, oldTree, options) as TreeSitterTree)
-      : (this.parser.parse(content, undefined, options) as TreeSitterTree);
+    const parser = this.parser;
+    // Recovery passes share the budget of the first parse. Tree-sitter gives up at the timeout and
+    // returns no tree; the parser must then be reset, or its next parse resumes the abandoned one.
+    const deadline = timeoutMs > 0 ? startTime + timeoutMs : 0;
+    const parseText = (text: string, previous?: TreeSitterTree): TreeSitterTree => {
+      const remainingMs = deadline ? deadline - Date.now() : 0;
+      if (deadline && remainingMs <= 0) throw new ParseTimeoutError({ filePath, timeoutMs });
+      parser.setTimeoutMicros(remainingMs * 1000);
+      const tree = parser.parse(text, previous, options) as TreeSitterTree | null;
+      if (!tree) {
+        parser.reset();
+        throw new ParseTimeoutError({ filePath, timeoutMs });
+      }
+      return tree;
+    };
+    const firstTree = parseText(content, oldTree);
+    // `source` is `content` with unparseable regions blanked; offsets and lines are unchanged
+    const { tree, source, syntaxErrors } = parseWithRecovery((text) => parseText(text), content, firstTree);
+    if (syntaxErrors.length > 0) {
+      log.warn("skipped regions with syntax errors", { file: filePath, language, regions: syntaxErrors.length });
+    }
 
     let entities: ParsedEntity[] = [];
     let relationships: any[] = [];
 
     if (language === "python") {
-      const py = await this.pythonAnalyzer.analyzePythonCode(filePath, tree.rootNode as any, content);
+      const py = await this.pythonAnalyzer.analyzePythonCode(filePath, tree.rootNode as any, source);
       entities = py.entities;
       relationships = py.relationships || [];
-      console.error(
-        `[TreeSitterParser] Python analysis: ${entities.length} entities, ${relationships.length} relationships`,
-      );
     } else if (language === "csharp") {
       const cs = await this.csharpAnalyzer.analyze(tree.rootNode as any, filePath);
       entities = cs.entities || [];
       relationships = cs.relationships || [];
-      console.error(
-        `[TreeSitterParser] C# analysis: ${entities.length} entities, ${relationships.length} relationships, ${(cs as any).patterns?.length || 0} patterns`,
-      );
     } else if (language === "rust") {
       const ru = await this.rustAnalyzer.analyze(tree.rootNode as any, filePath);
       entities = ru.entities || [];
       relationships = ru.relationships || [];
-      console.error(
-        `[TreeSitterParser] Rust analysis: ${entities.length} entities, ${relationships.length} relationships, ${(ru as any).patterns?.length || 0} patterns`,
-      );
     } else if (language === "c") {
       const ca = await this.cAnalyzer.analyze(tree.rootNode as any, filePath);
       entities = ca.entities || [];
       relationships = ca.relationships || [];
-      console.error(
-        `[TreeSitterParser] C analysis: ${entities.length} entities, ${relationships.length} relationships`,
-      );
     } else if (language === "cpp") {
       const cp = await this.cppAnalyzer.analyze(tree.rootNode as any, filePath);
       entities = cp.entities || [];
       relationships = cp.relationships || [];
-      console.error(
-        `[TreeSitterParser] C++ analysis: ${entities.length} entities, ${relationships.length} relationships`,
-      );
     } else if (language === "go") {
       const ga = await this.goAnalyzer.analyze(tree.rootNode as any, filePath);
       entities = ga.entities || [];
       relationships = ga.relationships || [];
-      console.error(
-        `[TreeSitterParser] Go analysis: ${entities.length} entities, ${relationships.length} relationships`,
-      );
     } else if (language === "java") {
       const ja = await this.javaAnalyzer.analyze(tree.rootNode as any, filePath);
       entities = ja.entities || [];
       relationships = ja.relationships || [];
-      console.error(
-        `[TreeSitterParser] Java analysis: ${entities.length} entities, ${relationships.length} relationships`,
-      );
     } else if (language === "kotlin") {
       const ko = await this.kotlinAnalyzer.analyze(tree.rootNode as any, filePath);
       entities = ko.entities || [];
       relationships = ko.relationships || [];
-      console.error(
-        `[TreeSitterParser] Kotlin analysis: ${entities.length} entities, ${relationships.length} relationships`,
-      );
+    } else if (language === "ruby") {
+      const rb = await this.rubyAnalyzer.analyze(tree.rootNode as any, filePath);
+      entities = rb.entities || [];
+      relationships = rb.relationships || [];
+    } else if (language === "php") {
+      const php = await this.phpAnalyzer.analyze(tree.rootNode as any, filePath);
+      entities = php.entities || [];
+      relationships = php.relationships || [];
+    } else if (language === "swift") {
+      const sw = await this.swiftAnalyzer.analyze(tree.rootNode as any, filePath);
+      entities = sw.entities || [];
+      relationships = sw.relationships || [];
+    } else if (language === "scala") {
+      const sc = await this.scalaAnalyzer.analyze(tree.rootNode as any, filePath);
+      entities = sc.entities || [];
+      relationships = sc.relationships || [];
     } else {
       // Default parser for JS/TS/etc
-      entities = await this.extractEntities(tree.rootNode as any, content);
+      entities = await this.extractEntities(tree.rootNode as any, source);
+      // `it("...")` cases of Jest/Vitest/Mocha files; Go and pytest tests are functions already
+      const tests = extractTestCases(tree.rootNode as any, filePath, source, (body) => ({
+        calls: collectCallSites(body),
+        renders: collectJsx(body).rendered,
+      }));
+      if (tests.length > 0) entities = [...entities, ...tests];
     }
 
-    entities = entities.map((entity) => ({
-      ...entity,
-      language,
-    }));
+    // HTTP endpoints, read from the router calls and decorators of the declarations above
+    const routes = extractRoutes(tree.rootNode as any, language, filePath, source, entities);
+    if (routes.length > 0) {
+      entities = [...entities, ...routes];
+      // Without analyzer edges the indexer builds the file's edges itself, handlers included
+      if (relationships.length > 0) relationships.push(...routeHandlerRelationships(routes));
+    }
+
+    // Environment variables, linked to the declarations above that read them
+    const envVars = extractEnvVars(tree.rootNode as any, language, filePath, source, entities);
+    if (envVars.length > 0) {
+      entities = [...entities, ...envVars];
+      if (relationships.length > 0) relationships.push(...envReaderRelationships(envVars));
+    }
+
+    // `parser.customQueries`: user entities, and attributes on the entities found so far
+    const custom = this.customQueries.get(language);
+    if (custom) {
+      const created = runCustomQueries(custom, tree.rootNode as any, source, filePath, entities);
+      if (created.length > 0) entities = [...entities, ...created];
+    }
+
+    // Standalone comments, placed in the declarations above; doc comments stay on their declaration
+    if (this.extractCommentEntities) {
+      const comments = extractComments(tree.rootNode as any, language, filePath, source, entities);
+      if (comments.length > 0) entities = [...entities, ...comments];
+    }
+
+    // Python docstrings come from its analyzer; other languages document with leading comments
+    const lines = language === "python" ? [] : source.split(/\r?\n/);
+    entities = entities.map((entity) => {
+      const documentation =
+        entity.documentation ??
+        (entity.location ? leadingDocComment(lines, entity.location.start.line, language) : undefined);
+      return documentation ? { ...entity, language, documentation } : { ...entity, language };
+    });
+    ({ entities, relationships } = collapseAnonymousEntities(entities, relationships, source));
+    ({ entities, relationships } = this.keepEntityKinds(language, entities, relationships));
+    entities = withVisibility(entities, language);
 
     this.cacheMisses++;
-    this.setCache(cacheKey, { tree, entities, hash: internalHash, timestamp: Date.now(), relationships });
+    this.setCache(cacheKey, {
+      // A recovered tree describes the blanked text, so later edits cannot be applied to it
+      tree: source === content ? tree : null,
+      entities,
+      hash: internalHash,
+      timestamp: Date.now(),
+      relationships,
+      syntaxErrors,
+    });
 
     const result: ParseResult = {
       filePath,
@@ -416,12 +851,32 @@ export class TreeSitterParser {
       parseTimeMs: Date.now() - startTime,
       fromCache: false,
     };
+    log.debug("parsed file", {
+      file: filePath,
+      language,
+      entities: entities.length,
+      relationships: relationships.length,
+      syntaxErrors: syntaxErrors.length,
+      grammar: grammarPath(language),
+      ms: result.parseTimeMs,
+    });
+    if (syntaxErrors.length) result.syntaxErrors = syntaxErrors;
     if (relationships.length) {
       (result as any).relationships = relationships;
     }
     return result;
   }
 
+  /** `parser.entityKinds`: drop the kinds the language is configured without, and their edges */
+  private keepEntityKinds<R extends { from: string; to: string }>(
+    language: SupportedLanguage,
+    entities: ParsedEntity[],
+    relationships: R[],
+  ): { entities: ParsedEntity[]; relationships: R[] } {
+    const keep = entityKindFilter(this.entityKindRules, language);
+    return keep ? filterEntityKinds(entities, relationships, keep) : { entities, relationships };
+  }
+
   async parseIncremental(
     filePath: string,
     newContent: string,
@@ -492,6 +947,12 @@ export class TreeSitterParser {
       case "lexical_declaration":
         entities.push(...this.extractVariables(node, source));
         break;
+      case "public_field_definition":
+      case "field_definition": {
+        const field = this.extractClassField(node, source);
+        if (field) entities.push(field);
+        break;
+      }
 
       // Python
       case "async_function_definition":
@@ -582,15 +1043,22 @@ export class TreeSitterParser {
   }
 
   private extractFunction(node: TreeSitterNode, source: string): ParsedEntity {
-    const nameNode = node.namedChildren.find((c) => c.type === "identifier" || c.type === "property_identifier");
-    const name = nameNode?.text || "<anonymous>";
+    // An arrow function's identifier children are parameters, never its name
+    const nameNode =
+      node.type === "arrow_function"
+        ? undefined
+        : node.namedChildren.find((c) => c.type === "identifier" || c.type === "property_identifier");
+    const name = nameNode?.text || declaredName(node) || "<anonymous>";
     const modifiers: string[] = [];
     if (source.substring(node.startIndex, node.startIndex + 5) === "async") modifiers.push("async");
+    modifiers.push(...accessibilityOf(node));
     const parameters = this.extractParameters(node, source);
     const position = convertPosition(node);
     const signature = source.substring(position.start.index, Math.min(position.end.index, position.start.index + 200));
 
     const body = node.namedChildren.find((c) => c.type === "statement_block" || c.type === "class_body");
+    const calls = body ? collectCallSites(body) : [];
+    const accesses = body ? collectAccessSites(node, body) : [];
     const references = new Set<string>();
     if (body) {
       const queue: TreeSitterNode[] = [...body.namedChildren];
@@ -608,6 +1076,20 @@ export class TreeSitterParser {
       }
     }
 
+    // Function components are capitalized and return JSX; hooks follow the `use` naming convention
+    const jsxBody = node.childForFieldName("body");
+    const jsx = jsxBody && /^[A-Z]/.test(name) ? collectJsx(jsxBody) : undefined;
+    const isComponent = !!jsx?.hasJsx;
+    const isHook = HOOK_NAME.test(name);
+    const details = jsDeclarationDetails(node);
+    const propsType = isComponent ? functionPropsType(node) : undefined;
+    const react = isComponent
+      ? { componentKind: "function", ...(propsType ? { propsType } : {}) }
+      : isHook
+        ? { isHook: true }
+        : undefined;
+    const hooks = react ? calls.filter((c) => HOOK_NAME.test(c.name) && (!c.qualifier || c.qualifier === "React")) : [];
+
     return {
       name,
       type: node.parent?.type === "method_definition" ? "method" : "function",
@@ -616,11 +1098,19 @@ export class TreeSitterParser {
       parameters,
       signature: signature.trim(),
       references: references.size ? Array.from(references) : undefined,
+      calls: body ? calls : undefined,
+      ...(accesses.length ? { accesses } : {}),
+      cyclomaticComplexity: body ? cyclomaticComplexity(node, "typescript") : undefined,
+      ...details,
+      ...(react ? { metadata: { ...details.metadata, ...react } } : {}),
+      ...(isComponent && jsx?.rendered.length ? { renders: jsx.rendered } : {}),
+      ...(hooks.length ? { hooks } : {}),
     };
   }
 
   private extractClass(node: TreeSitterNode, source: string, _depth: number): ParsedEntity {
-    const nameNode = node.namedChildren.find((c) => c.type === "identifier");
+    // JavaScript names classes with an identifier, TypeScript with a type_identifier
+    const nameNode = node.namedChildren.find((c) => c.type === "identifier" || c.type === "type_identifier");
     const name = nameNode?.text || "<anonymous>";
     const bodyNode = node.namedChildren.find((c) => c.type === "class_body");
     const children: ParsedEntity[] = [];
@@ -631,19 +1121,69 @@ export class TreeSitterParser {
         }
       }
     }
-    return { name, type: "class", location: convertPosition(node), children };
+    const inheritance = collectJsHeritage(node);
+    const details = jsDeclarationDetails(node);
+    // Class components extend `Component`/`PureComponent`; `Component<Props, State>` names the props
+    let component: Pick<ParsedEntity, "metadata" | "renders"> = {};
+    if (inheritance?.baseClasses.some((base) => COMPONENT_BASE.test(base))) {
+      const heritage = node.namedChildren.find((c) => c.type === "class_heritage");
+      const propsType = (heritage ? ds(heritage, "type_arguments")[0] : undefined)?.namedChildren[0]?.text;
+      const rendered = new Map<string, ParsedCallSite>();
+      for (const method of bodyNode?.namedChildren ?? []) {
+        const methodBody = method.type === "method_definition" ? method.childForFieldName("body") : null;
+        for (const site of methodBody ? collectJsx(methodBody).rendered : []) {
+          const written = site.qualifier ? `${site.qualifier}.${site.name}` : site.name;
+          if (!rendered.has(written)) rendered.set(written, site);
+        }
+      }
+      component = {
+        metadata: { ...details.metadata, componentKind: "class", ...(propsType ? { propsType } : {}) },
+        ...(rendered.size ? { renders: [...rendered.values()] } : {}),
+      };
+    }
+    return {
+      name,
+      type: "class",
+      location: convertPosition(node),
+      children,
+      inheritance,
+      ...details,
+      ...component,
+    };
   }
 
   private extractInterface(node: TreeSitterNode, _source: string): ParsedEntity {
     const nameNode = node.namedChildren.find((c) => c.type === "type_identifier");
     const name = nameNode?.text || "<anonymous>";
-    return { name, type: "interface", location: convertPosition(node) };
+    return {
+      name,
+      type: "interface",
+      location: convertPosition(node),
+      inheritance: collectJsHeritage(node),
+      ...jsDeclarationDetails(node),
+    };
   }
 
   private extractTypeAlias(node: TreeSitterNode, _source: string): ParsedEntity {
     const nameNode = node.namedChildren.find((c) => c.type === "type_identifier");
     const name = nameNode?.text || "<anonymous>";
-    return { name, type: "type", location: convertPosition(node) };
+    return { name, type: "type", location: convertPosition(node), ...jsDeclarationDetails(node) };
+  }
+
+  /** Class fields (`@Input() name: string;`), kept mainly for their decorators */
+  private extractClassField(node: TreeSitterNode, _source: string): ParsedEntity | null {
+    const nameNode =
+      node.childForFieldName("name") ??
+      node.namedChildren.find((c) => c.type === "property_identifier" || c.type === "private_property_identifier");
+    if (!nameNode) return null;
+    const modifiers = accessibilityOf(node);
+    return {
+      name: nameNode.text,
+      type: "property",
+      location: convertPosition(node),
+      ...(modifiers.length ? { modifiers } : {}),
+      ...jsDeclarationDetails(node),
+    };
   }
 
   private extractImport(node: TreeSitterNode, _source: string): ParsedEntity {
@@ -658,11 +1198,22 @@ export class TreeSitterParser {
     }
     const defaultImport = ds(node, "identifier")[0];
     const isDefault = !!defaultImport && specifiers.length === 0;
+    const clause = node.namedChildren.find((c) => c.type === "import_clause");
+    const local = clause?.namedChildren.find((c) => c.type === "identifier")?.text;
+    const namespace = clause?.namedChildren
+      .find((c) => c.type === "namespace_import")
+      ?.namedChildren.find((c) => c.type === "identifier")?.text;
     return {
       name: importSource,
       type: "import",
       location: convertPosition(node),
-      importData: { source: importSource, specifiers, isDefault },
+      importData: {
+        source: importSource,
+        specifiers,
+        isDefault,
+        ...(local ? { local } : {}),
+        ...(namespace ? { isNamespace: true, namespace } : {}),
+      },
     };
   }
 
@@ -692,6 +1243,12 @@ export class TreeSitterParser {
         case "class_declaration":
           entity = this.extractClass(declaration, source, 0);
           break;
+        case "interface_declaration":
+          entity = this.extractInterface(declaration, source);
+          break;
+        case "type_alias_declaration":
+          entity = this.extractTypeAlias(declaration, source);
+          break;
         case "variable_declaration":
         case "lexical_declaration":
           entity = this.extractVariables(declaration, source)[0] || null;
@@ -751,7 +1308,14 @@ export class TreeSitterParser {
     const decorators = this.extractPythonDecorators(node);
     modifiers.push(...decorators);
     const parameters = this.extractPythonParameters(node, source);
-    return { name, type: "function", location: convertPosition(node), modifiers, parameters };
+    return {
+      name,
+      type: "function",
+      location: convertPosition(node),
+      modifiers,
+      parameters,
+      cyclomaticComplexity: cyclomaticComplexity(node, "python"),
+    };
   }
 
   private extractPythonLambda(node: TreeSitterNode, source: string): ParsedEntity {
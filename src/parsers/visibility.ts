@@ -0,0 +1,140 @@
+/**
+ * Visibility - who can use a declaration, in one vocabulary across languages
+ * Every declaration gets `metadata.visibility`: `public` (exported: usable from other packages or
+ * modules), `protected` (subclasses), `internal` (its own package, module, assembly or crate) or
+ * `private` (its file, type or module). Each language's rule:
+ *   Go           capitalized names are exported, everything else is package-private
+ *   JS/TS        `export` makes a top-level declaration public, otherwise it stays in its module;
+ *                class members are public unless `private`/`protected` or a `#name`
+ *   Java         `public`/`protected`/`private`; no keyword is package-private (`internal`)
+ *   C#           `public`/`protected`/`internal`/`private`; no keyword is internal for types and
+ *                private for members
+ *   Kotlin/Scala/PHP/Ruby/VBA  public unless marked otherwise (Kotlin `internal` and VBA
+ *                `Friend` stay within the module or project)
+ *   Swift        `open`/`public` are public, `fileprivate`/`private` private, no keyword internal
+ *   Rust         `pub` is public, `pub(crate)`/`pub(super)`/`pub(in ..)` internal, no keyword private
+ *   Python       a leading underscore (not a dunder) marks a name private
+ *   C/C++        `static` functions and variables are private to their file; C++ members keep
+ *                the access specifier of their section
+ */
+
+import type { ParsedEntity } from "../types/parser.js";
+
+export type Visibility = "public" | "protected" | "internal" | "private";
+
+export const VISIBILITIES: readonly Visibility[] = ["public", "protected", "internal", "private"];
+
+// Not declarations anyone could use, or not written in a language with access rules
+const UNCLASSIFIED_KINDS = new Set([
+  "import",
+  "comment",
+  "module",
+  "package",
+  "file",
+  "document",
+  "heading",
+  "route",
+  "env_var",
+  "test",
+]);
+
+const MEMBER_KINDS = new Set(["method", "property", "field", "constructor"]);
+const TYPE_KINDS = new Set(["class", "interface", "type", "struct", "enum", "record", "trait"]);
+
+type Classifiable = Pick<ParsedEntity, "name" | "type" | "modifiers" | "metadata">;
+
+function modifiersOf(entity: Classifiable): string[] {
+  const own = entity.modifiers ?? [];
+  const fromMetadata = entity.metadata?.modifiers;
+  return [...own, ...(Array.isArray(fromMetadata) ? (fromMetadata as string[]) : [])].map((m) => m.toLowerCase());
+}
+
+/** The first access keyword among `modifiers`, in their strongest-first order */
+function keyword(modifiers: string[], order: Record<string, Visibility>): Visibility | undefined {
+  for (const [word, visibility] of Object.entries(order)) {
+    if (modifiers.includes(word)) return visibility;
+  }
+  return undefined;
+}
+
+const ACCESS_KEYWORDS: Record<string, Visibility> = {
+  private: "private",
+  protected: "protected",
+  internal: "internal",
+  public: "public",
+};
+
+function rustVisibility(entity: Classifiable, modifiers: string[]): Visibility {
+  const raw = typeof entity.metadata?.visibility === "string" ? entity.metadata.visibility : undefined;
+  if (raw === undefined) return modifiers.includes("pub") ? "public" : "private";
+  if ((VISIBILITIES as readonly string[]).includes(raw)) return raw as Visibility;
+  if (raw === "pub") return "public";
+  return raw.startsWith("pub") ? "internal" : "private";
+}
+
+/**
+ * Visibility of `entity` under the rules of `language`, or undefined for entities that are not
+ * declarations (imports, comments, documentation) and languages without access control.
+ */
+export function classifyVisibility(entity: Classifiable, language: string): Visibility | undefined {
+  if (UNCLASSIFIED_KINDS.has(String(entity.type))) return undefined;
+  const modifiers = modifiersOf(entity);
+  const member = MEMBER_KINDS.has(String(entity.type));
+
+  switch (language) {
+    case "go":
+      return /^\p{Lu}/u.test(entity.name) ? "public" : "internal";
+    case "typescript":
+    case "tsx":
+    case "javascript":
+    case "jsx":
+      if (entity.type === "export" || modifiers.includes("export")) return "public";
+      if (entity.name.startsWith("#")) return "private";
+      return keyword(modifiers, ACCESS_KEYWORDS) ?? (member ? "public" : "private");
+    case "java":
+      return keyword(modifiers, ACCESS_KEYWORDS) ?? "internal";
+    case "csharp":
+      // `protected internal` reaches further than either; `private protected` less
+      if (modifiers.includes("protected") && modifiers.includes("internal")) return "protected";
+      if (modifiers.includes("private") && modifiers.includes("protected")) return "private";
+      return keyword(modifiers, ACCESS_KEYWORDS) ?? (TYPE_KINDS.has(String(entity.type)) ? "internal" : "private");
+    case "kotlin":
+    case "scala":
+    case "php":
+    case "ruby":
+      return keyword(modifiers, ACCESS_KEYWORDS) ?? "public";
+    case "vba":
+      // `Friend` procedures are visible to the whole project, not to other ones
+      return keyword(modifiers, { ...ACCESS_KEYWORDS, friend: "internal" }) ?? "public";
+    case "swift":
+      if (modifiers.includes("open") || modifiers.includes("public")) return "public";
+      if (modifiers.includes("private") || modifiers.includes("fileprivate")) return "private";
+      return "internal";
+    case "rust":
+      return rustVisibility(entity, modifiers);
+    case "python":
+      return /^_(?!_.*__$)/.test(entity.name) ? "private" : "public";
+    case "c":
+    case "cpp":
+      return (
+        keyword(modifiers, { private: "private", protected: "protected" }) ??
+        (modifiers.includes("static") && !member ? "private" : "public")
+      );
+    default:
+      return undefined;
+  }
+}
+
+/**
+ * Tag each entity of a file with its visibility. Rust's own `pub(crate)`-style text moves to
+ * `metadata.visibilityModifier`, so `metadata.visibility` reads the same in every language.
+ */
+export function withVisibility(entities: ParsedEntity[], language: string): ParsedEntity[] {
+  return entities.map((entity) => {
+    const visibility = classifyVisibility(entity, language);
+    if (!visibility) return entity;
+    const raw = entity.metadata?.visibility;
+    const kept = language === "rust" && typeof raw === "string" && raw !== visibility;
+    return { ...entity, metadata: { ...entity.metadata, ...(kept ? { visibilityModifier: raw } : {}), visibility } };
+  });
+}
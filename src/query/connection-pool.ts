@@ -235,7 +235,7 @@ export class ConnectionPool extends EventEmitter {
 
         // Try to repair connection
         try {
-          connection.db.close();
+          if (!this.sqliteManager.isOpen()) this.sqliteManager.initialize();
           connection.db = this.sqliteManager.getConnection();
           repaired++;
           console.log(`[ConnectionPool] Connection ${connection.id} repaired`);
@@ -281,15 +281,7 @@ export class ConnectionPool extends EventEmitter {
       await new Promise((resolve) => setTimeout(resolve, 100));
     }
 
-    // Close all connections
-    for (const connection of this.connections) {
-      try {
-        connection.db.close();
-      } catch (error) {
-        console.error(`[ConnectionPool] Error closing connection ${connection.id}:`, error);
-      }
-    }
-
+    // Drop the slots; the shared connection stays open for the other agents and tools
     this.connections = [];
     this.emit("shutdown");
 
@@ -300,17 +292,15 @@ export class ConnectionPool extends EventEmitter {
   // 4. HELPER METHODS
   // =============================================================================
 
+  /**
+   * A slot over the manager's connection. better-sqlite3 runs each statement to completion, so
+   * concurrent tool calls share the one connection safely; the slots only bound how many queries
+   * are in flight. The manager owns the connection and its PRAGMAs, so the pool never closes it.
+   */
   private createConnection(id: string): PooledConnection {
-    this.sqliteManager.initialize();
+    if (!this.sqliteManager.isOpen()) this.sqliteManager.initialize();
     const db = this.sqliteManager.getConnection();
 
-    // Apply optimizations
-    db.pragma("journal_mode = WAL");
-    db.pragma("synchronous = NORMAL");
-    db.pragma("cache_size = -64000"); // 64MB
-    db.pragma("temp_store = MEMORY");
-    db.pragma("mmap_size = 30000000000"); // 30GB
-
     return {
       id,
       db,
@@ -349,22 +339,17 @@ export class ConnectionPool extends EventEmitter {
     }
 
     for (const connection of toClose) {
-      try {
-        connection.db.close();
-        const index = this.connections.indexOf(connection);
-        if (index > -1) {
-          this.connections.splice(index, 1);
-        }
+      const index = this.connections.indexOf(connection);
+      if (index > -1) {
+        this.connections.splice(index, 1);
+      }
 
-        this.emit("connection:closed", {
-          id: connection.id,
-          reason: "idle_timeout",
-        });
+      this.emit("connection:closed", {
+        id: connection.id,
+        reason: "idle_timeout",
+      });
 
-        console.log(`[ConnectionPool] Closed idle connection ${connection.id}`);
-      } catch (error) {
-        console.error(`[ConnectionPool] Error closing connection ${connection.id}:`, error);
-      }
+      console.log(`[ConnectionPool] Closed idle connection ${connection.id}`);
     }
   }
 
@@ -0,0 +1,172 @@
+/**
+ * Adaptive Batching - how many texts go to the embedding provider per request
+ * Throughput depends on batch size in opposite ways per provider: a local model keeps the CPU or
+ * GPU busy with larger batches until it saturates, a hosted API saves round trips up to its
+ * request limit. The sizer starts from the configured size and, after every provider call,
+ * doubles while calls stay well under the target latency and texts per second keep improving,
+ * halves when a call runs over the target, and steps back to the previous size once growing
+ * stops paying off. A request the provider rejects as too large is split and sets the ceiling.
+ * With a fixed `batching.size` none of this runs and every batch has that size.
+ */
+
+import type { EmbeddingBatchingConfig } from "../types/semantic.js";
+import { HttpError } from "./providers/http-engine.js";
+
+export type BatchLimits = {
+  /** Largest batch this provider is sent */
+  max: number;
+  /** Calls slower than this shrink the batch; calls under half of it may grow it */
+  targetLatencyMs: number;
+  /** Estimated tokens per request the provider accepts; undefined for no limit */
+  maxTokens?: number;
+};
+
+/**
+ * Starting bounds per provider kind. Local models stop gaining from larger batches early and run
+ * one call at a time, so they get a short target; OpenAI takes up to 2048 inputs and 300k tokens
+ * per request.
+ */
+export const PROVIDER_BATCH_LIMITS: Record<string, BatchLimits> = {
+  memory: { max: 512, targetLatencyMs: 250 },
+  transformers: { max: 64, targetLatencyMs: 1500 },
+  ollama: { max: 64, targetLatencyMs: 2000 },
+  openai: { max: 2048, targetLatencyMs: 4000, maxTokens: 300_000 },
+  cloudru: { max: 256, targetLatencyMs: 4000 },
+  custom: { max: 256, targetLatencyMs: 2000 },
+};
+
+export function batchLimitsFor(provider: string): BatchLimits {
+  return PROVIDER_BATCH_LIMITS[provider] ?? PROVIDER_BATCH_LIMITS.custom!;
+}
+
+// Texts per second must beat the smaller size's by this much for a larger batch to be kept
+const GROWTH_GAIN = 1.1;
+
+// Code tokenizes densely; three characters per token errs on the side of smaller requests
+const CHARS_PER_TOKEN = 3;
+
+export function estimateTokens(text: string): number {
+  return Math.ceil(text.length / CHARS_PER_TOKEN);
+}
+
+const TOO_LARGE = /too (large|long|many)|maximum context|context length|token limit|max(imum)?[ _-]?tokens|payload/i;
+
+/** Whether a provider error rejects the request for its size, so a smaller one may pass */
+export function isBatchTooLarge(error: unknown): boolean {
+  if (error instanceof HttpError) {
+    return error.status === 413 || (error.status === 400 && TOO_LARGE.test(error.message));
+  }
+  return TOO_LARGE.test((error as Error)?.message ?? "");
+}
+
+/** Consecutive groups of at most `size` texts and `maxTokens` estimated tokens, in order */
+export function splitBatch(texts: string[], size: number, maxTokens?: number): string[][] {
+  const groups: string[][] = [];
+  let group: string[] = [];
+  let tokens = 0;
+  for (const text of texts) {
+    const cost = estimateTokens(text);
+    const full = group.length >= size || (maxTokens !== undefined && tokens + cost > maxTokens);
+    if (group.length > 0 && full) {
+      groups.push(group);
+      group = [];
+      tokens = 0;
+    }
+    group.push(text);
+    tokens += cost;
+  }
+  if (group.length > 0) groups.push(group);
+  return groups;
+}
+
+export type BatchSample = { size: number; elapsedMs: number; next: number; reason: string };
+
+export class AdaptiveBatchSizer {
+  private size: number;
+  private readonly min: number;
+  private max: number;
+  readonly adaptive: boolean;
+  readonly targetLatencyMs: number;
+  readonly maxTokens: number | undefined;
+  // Size and throughput before the last growth step, to step back to when it did not pay off
+  private previous: { size: number; perSecond: number } | null = null;
+  private settled = false;
+  // Smallest request the provider refused; fixed sizing is held under it too
+  private refused = Number.POSITIVE_INFINITY;
+
+  constructor(config: EmbeddingBatchingConfig & { initial: number }, limits: BatchLimits, providerMax?: number) {
+    this.adaptive = config.size === undefined || config.size === "auto";
+    this.max = Math.max(1, Math.floor(Math.min(config.max ?? limits.max, providerMax ?? Number.POSITIVE_INFINITY)));
+    this.min = Math.max(1, Math.min(this.max, Math.floor(config.min ?? 1)));
+    this.targetLatencyMs = Math.max(1, config.targetLatencyMs ?? limits.targetLatencyMs);
+    this.maxTokens = config.maxTokens ?? limits.maxTokens;
+    this.size = this.clamp(typeof config.size === "number" ? config.size : config.initial);
+  }
+
+  private clamp(size: number): number {
+    const whole = Number.isFinite(size) ? Math.floor(size) : this.min;
+    return this.adaptive ? Math.max(this.min, Math.min(this.max, whole)) : Math.max(1, whole);
+  }
+
+  /** Texts to take for the next batch */
+  current(): number {
+    return this.size;
+  }
+
+  bounds(): { min: number; max: number } {
+    return { min: this.min, max: this.max };
+  }
+
+  /** Whether a request of `size` texts may be sent without repeating a known rejection */
+  fits(size: number): boolean {
+    return size < this.refused;
+  }
+
+  /** Start over from `size`, as after a resource adjustment; fixed sizing just takes it */
+  reset(size: number): void {
+    this.size = this.clamp(size);
+    this.previous = null;
+    this.settled = false;
+  }
+
+  /**
+   * Account for one provider call of `size` texts that took `elapsedMs`. Calls well below the
+   * current size (a batch mostly served from cache, the tail of a run) say little about larger
+   * ones and only count when they were slow.
+   */
+  record(size: number, elapsedMs: number): BatchSample {
+    const sample = (reason: string): BatchSample => ({ size, elapsedMs, next: this.size, reason });
+    if (!this.adaptive) return sample("fixed");
+    if (elapsedMs > this.targetLatencyMs && size > this.min) {
+      this.size = this.clamp(Math.min(this.size, size) / 2);
+      this.previous = null;
+      this.settled = false;
+      return sample("slow");
+    }
+    if (size < this.size / 2) return sample("partial");
+
+    const perSecond = size / Math.max(1, elapsedMs);
+    if (this.previous && perSecond < this.previous.perSecond * GROWTH_GAIN) {
+      // The provider was already saturated at the smaller size
+      this.size = this.previous.size;
+      this.previous = null;
+      this.settled = true;
+      return sample("saturated");
+    }
+    if (!this.settled && elapsedMs < this.targetLatencyMs / 2 && this.size < this.max) {
+      this.previous = { size: this.size, perSecond };
+      this.size = this.clamp(this.size * 2);
+      return sample("grow");
+    }
+    this.previous = null;
+    return sample("hold");
+  }
+
+  /** The provider refused `size` texts in one request: never send that many again */
+  rejected(size: number): void {
+    this.refused = Math.min(this.refused, size);
+    this.max = Math.max(1, Math.min(this.max, size - 1));
+    this.size = Math.max(1, Math.min(this.size, Math.floor(size / 2)));
+    this.previous = null;
+  }
+}
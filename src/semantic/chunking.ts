@@ -0,0 +1,86 @@
+/**
+ * Chunking - how declarations are cut into the texts that get embedded
+ * - entity: one vector per declaration over its header, documentation and code (the default)
+ * - signature: header and documentation only, so the vector says what the declaration is for
+ *   rather than averaging every statement of a long body
+ * - window: declarations longer than a window keep a header-and-documentation vector and get one
+ *   more per window of lines over their body, overlapping so a statement at a window edge is whole
+ *   in one of them. Window vectors point back at their declaration's vector (`metadata.parentId`) and search
+ *   folds them into it, so a hit on any window returns the declaration once.
+ */
+
+import type { SimilarityResult } from "../types/semantic.js";
+
+export type ChunkStrategy = "entity" | "signature" | "window";
+
+export const CHUNK_STRATEGIES: readonly ChunkStrategy[] = ["entity", "signature", "window"];
+
+/** `metadata.kind` of window vectors */
+export const CHUNK_VECTOR_KIND = "chunk";
+
+export interface ChunkingOptions {
+  strategy: ChunkStrategy;
+  /** Lines per window */
+  windowLines: number;
+  /** Lines shared by consecutive windows */
+  overlapLines: number;
+}
+
+export interface CodeWindow {
+  index: number;
+  text: string;
+  /** 1-based, in the file */
+  startLine: number;
+  endLine: number;
+}
+
+/** Chunking of the config, with sizes clamped so every window moves forward */
+export function resolveChunking(options: Partial<ChunkingOptions> = {}): ChunkingOptions {
+  const strategy = CHUNK_STRATEGIES.includes(options.strategy as ChunkStrategy) ? options.strategy! : "entity";
+  const windowLines = Math.max(5, Math.floor(Number(options.windowLines) || 60));
+  const overlapLines = Math.min(windowLines - 1, Math.max(0, Math.floor(Number(options.overlapLines) || 0)));
+  return { strategy, windowLines, overlapLines };
+}
+
+/**
+ * Windows over `code`, which starts at `firstLine` of its file. Code that fits in one window yields
+ * none: the declaration's own vector covers it. A last window that would mostly repeat the one
+ * before is merged into it.
+ */
+export function codeWindows(code: string, firstLine: number, options: ChunkingOptions): CodeWindow[] {
+  const lines = code.split(/\r?\n/);
+  if (lines.length <= options.windowLines) return [];
+  const step = options.windowLines - options.overlapLines;
+  const windows: CodeWindow[] = [];
+  for (let start = 0; start < lines.length; start += step) {
+    let end = Math.min(lines.length, start + options.windowLines);
+    if (lines.length - end < step / 2) end = lines.length;
+    const text = lines.slice(start, end).join("\n").trim();
+    if (text) windows.push({ index: windows.length, text, startLine: firstLine + start, endLine: firstLine + end - 1 });
+    if (end === lines.length) break;
+  }
+  return windows;
+}
+
+/**
+ * Fold window hits into their declaration: the best-scoring hit per declaration is kept, and a window
+ * hit takes the id of the declaration's vector (`metadata.parentId`), with the window it matched in
+ * `metadata.matchedChunk`. `hits` are best first.
+ */
+export function collapseChunkHits(hits: SimilarityResult[]): SimilarityResult[] {
+  const seen = new Set<string>();
+  const collapsed: SimilarityResult[] = [];
+  for (const hit of hits) {
+    const isChunk = hit.metadata?.kind === CHUNK_VECTOR_KIND && typeof hit.metadata.parentId === "string";
+    const key = isChunk ? (hit.metadata.parentId as string) : hit.id;
+    if (seen.has(key)) continue;
+    seen.add(key);
+    if (!isChunk) {
+      collapsed.push(hit);
+      continue;
+    }
+    const { kind: _kind, chunk, parentId, ...metadata } = hit.metadata;
+    collapsed.push({ ...hit, id: parentId as string, metadata: { ...metadata, matchedChunk: chunk } });
+  }
+  return collapsed;
+}
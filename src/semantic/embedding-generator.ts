@@ -25,10 +25,18 @@
 // =============================================================================
 // 1. IMPORTS AND DEPENDENCIES
 // =============================================================================
-import { type EmbeddingConfig, VECTOR_DIMENSIONS } from "../types/semantic.js";
-import type { EmbeddingProvider } from "./providers/base.js";
+import { createHash } from "node:crypto";
+import { sessionMetrics } from "../core/session-metrics.js";
+import { EmbeddingInitError, type EmbeddingInitDetails, type EmbeddingInitPhase } from "../types/errors.js";
+import { type EmbeddingCacheStore, type EmbeddingConfig, VECTOR_DIMENSIONS } from "../types/semantic.js";
+import { throwIfCancelled } from "../utils/cancellation.js";
+import { getLog } from "../utils/structured-log.js";
+import { AdaptiveBatchSizer, batchLimitsFor, isBatchTooLarge, splitBatch } from "./adaptive-batching.js";
+import { type EmbeddingProvider, ProviderInitError } from "./providers/base.js";
 import { createProvider } from "./providers/factory.js";
-import { MemoryProvider } from "./providers/memory-provider.js";
+import { HttpError, isTransientStatus } from "./providers/http-engine.js";
+import { MEMORY_MODEL, MemoryProvider } from "./providers/memory-provider.js";
+import { RequestLimiter } from "./providers/request-limiter.js";
 
 // =============================================================================
 // 2. CONSTANTS AND CONFIGURATION
@@ -42,9 +50,14 @@ const DEFAULT_CONFIG: EmbeddingConfig = {
   provider: "memory",
 };
 
+const DEFAULT_INIT_RETRIES = 2;
+const DEFAULT_INIT_BACKOFF_MS = 1000;
+
 const DEFAULT_TTL_MS = 60 * 60 * 1000;
 const MAX_CACHE_ENTRIES = 5000;
 
+const log = getLog("embedding");
+
 // =============================================================================
 // 3. DATA MODELS AND TYPE DEFINITIONS
 // =============================================================================
@@ -52,26 +65,45 @@ interface EmbeddingCache {
   text: string;
   embedding: Float32Array;
   timestamp: number;
-  providerKey: string;
+  source: EmbeddingSource;
+}
+
+/** Which embedder produced a vector; `fallback` marks hashing vectors standing in for the configured one */
+export interface EmbeddingSource {
+  provider: string;
+  model: string;
+  fallback: boolean;
 }
 
 // =============================================================================
 // 4. UTILITY FUNCTIONS AND HELPERS
 // =============================================================================
 function hashText(text: string): string {
-  let hash = 0;
-  for (let i = 0; i < text.length; i++) {
-    const char = text.charCodeAt(i);
-    hash = (hash << 5) - hash + char;
-    hash = hash & hash;
-  }
-  return hash.toString(36);
+  // Full digest so two distinct texts cannot share a cache slot (the old 32-bit rolling hash could)
+  return createHash("sha1").update(text).digest("base64url");
 }
 
 function normalizeText(text: string): string {
   return text.trim().replace(/\s+/g, " ").slice(0, 512);
 }
 
+function sleep(ms: number): Promise<void> {
+  return new Promise((r) => setTimeout(r, ms));
+}
+
+const NETWORK_ERROR = /fetch|network|ENOTFOUND|ECONNRESET|ECONNREFUSED|ETIMEDOUT|EAI_AGAIN|socket hang up/i;
+
+/** Stage and retry-worthiness of a provider initialization error */
+function classifyInitFailure(error: unknown): { phase: EmbeddingInitPhase; retriable: boolean } {
+  if (error instanceof ProviderInitError) return { phase: error.phase, retriable: error.retriable };
+  if (error instanceof HttpError) return { phase: "download", retriable: isTransientStatus(error.status) };
+  const code = (error as { code?: string })?.code;
+  if (code === "MODULE_NOT_FOUND" || code === "ERR_MODULE_NOT_FOUND") return { phase: "load", retriable: false };
+  const message = (error as Error)?.message ?? String(error);
+  if (NETWORK_ERROR.test(message)) return { phase: "download", retriable: true };
+  return { phase: "load", retriable: true };
+}
+
 function ensureEmbedding(embedding: Float32Array | undefined, dimension: number): Float32Array {
   return embedding ?? new Float32Array(dimension);
 }
@@ -86,20 +118,30 @@ export class EmbeddingGenerator {
   private cache: Map<string, EmbeddingCache> = new Map();
   private config: EmbeddingConfig;
   private initPromise: Promise<void> | null = null;
+  private initError: string | null = null;
+  private initFailure: EmbeddingInitDetails | null = null;
+  private persistentCache: EmbeddingCacheStore | null = null;
+  // One budget for every provider request this generator makes, whichever caller started it
+  private limiter: RequestLimiter;
+  private fallbackWarned = false;
+  // Texts per provider request; set up once the provider it sizes for is known
+  private sizer: AdaptiveBatchSizer | null = null;
 
   private cacheHits = 0;
   private cacheMisses = 0;
+  private persistentHits = 0;
   private debugMode = process.env.EMBEDDING_DEBUG === "true";
 
   constructor(config: Partial<EmbeddingConfig> = {}) {
     this.config = { ...DEFAULT_CONFIG, ...config };
+    this.limiter = new RequestLimiter(this.config.rateLimit);
   }
 
   private providerKey(): string {
     const info = this.provider?.info ?? this.fallback?.info;
     const dim = this.provider?.getDimension?.() ?? this.fallback?.getDimension?.() ?? VECTOR_DIMENSIONS;
     const name = info?.name ?? "memory";
-    const model = info?.model ?? "deterministic-hash";
+    const model = info?.model ?? MEMORY_MODEL;
     return `${name}:${model}:${dim}`;
   }
 
@@ -113,21 +155,23 @@ export class EmbeddingGenerator {
       // Base fallback uses deterministic hash embeddings with the base dimension.
       this.fallback = new MemoryProvider({ dimension: baseDimension });
 
-      const mainProvider = createProvider({
-        provider: providerName,
-        modelName: this.config.modelName ?? DEFAULT_MODEL,
-        transformers: {
-          quantized: this.config.quantized,
-          localPath: this.config.localPath,
-        },
-        ollama: this.config.ollama,
-        openai: this.config.openai,
-        cloudru: this.config.cloudru,
-        memory: { dimension: baseDimension, ...this.config.memory },
-      });
-
       try {
-        await mainProvider.initialize();
+        const mainProvider = createProvider({
+          provider: providerName,
+          modelName: this.config.modelName ?? DEFAULT_MODEL,
+          transformers: {
+            quantized: this.config.quantized,
+            localPath: this.config.localPath,
+          },
+          ollama: this.config.ollama,
+          openai: this.config.openai,
+          cloudru: this.config.cloudru,
+          custom: this.config.custom,
+          memory: { dimension: baseDimension, ...this.config.memory },
+          limiter: this.limiter,
+        });
+
+        await this.initializeWithRetry(mainProvider);
 
         const detectedDim = mainProvider.getDimension?.() ?? this.config.memory?.dimension ?? baseDimension;
 
@@ -135,24 +179,194 @@ export class EmbeddingGenerator {
         this.fallback = new MemoryProvider({ dimension: detectedDim });
         this.provider = mainProvider;
 
-        if (this.debugMode) {
-          console.log(
-            `[EmbeddingGenerator] Provider initialized: ${this.provider.info.name} (${this.provider.info.model}), dim=${detectedDim}`,
-          );
-        }
+        log.info("provider initialized", {
+          provider: this.provider.info.name,
+          model: this.provider.info.model,
+          dimension: detectedDim,
+        });
       } catch (e) {
-        console.warn("[EmbeddingGenerator] Provider init failed, using memory fallback:", (e as Error)?.message || e);
+        this.initError = (e as Error)?.message || String(e);
+        this.initFailure = e instanceof EmbeddingInitError ? e.details : null;
+        this.warnFallback(`${providerName} provider unavailable: ${this.initError}`);
         this.provider = this.fallback;
       }
 
       if (!this.provider) {
         this.provider = this.fallback;
       }
+      this.sizer = this.createSizer(this.provider);
     })();
 
     return this.initPromise;
   }
 
+  /**
+   * Initialize `provider`, retrying transient failures with exponential backoff. Throws an
+   * EmbeddingInitError naming the failed stage once retries are exhausted or pointless.
+   */
+  private async initializeWithRetry(provider: EmbeddingProvider): Promise<void> {
+    const retries = Math.max(0, this.config.initRetries ?? DEFAULT_INIT_RETRIES);
+    const backoffMs = Math.max(0, this.config.initBackoffMs ?? DEFAULT_INIT_BACKOFF_MS);
+    for (let attempt = 1; ; attempt++) {
+      try {
+        await provider.initialize();
+        return;
+      } catch (e) {
+        const { phase, retriable } = classifyInitFailure(e);
+        const cause = (e as Error)?.message || String(e);
+        if (!retriable || attempt > retries) {
+          const details = { provider: String(provider.info.name), model: provider.info.model, attempts: attempt };
+          throw new EmbeddingInitError({ ...details, phase, retriable, cause });
+        }
+        const delay = backoffMs * 2 ** (attempt - 1);
+        log.warn("provider initialization failed, retrying", {
+          provider: provider.info.name,
+          phase,
+          attempt,
+          attempts: retries + 1,
+          delayMs: delay,
+          cause,
+        });
+        await sleep(delay);
+      }
+    }
+  }
+
+  private createSizer(provider: EmbeddingProvider): AdaptiveBatchSizer {
+    const kind = provider === this.fallback ? "memory" : (this.config.provider ?? "memory");
+    const sizer = new AdaptiveBatchSizer(
+      { ...this.config.batching, initial: this.config.batchSize ?? 8 },
+      batchLimitsFor(kind),
+      provider.info.maxBatchSize,
+    );
+    log.debug("batch sizing", {
+      provider: kind,
+      adaptive: sizer.adaptive,
+      batchSize: sizer.current(),
+      ...sizer.bounds(),
+      targetLatencyMs: sizer.targetLatencyMs,
+      maxTokens: sizer.maxTokens,
+    });
+    return sizer;
+  }
+
+  /**
+   * Batch sizing in effect: whether it adapts, the size of the next batch and the bounds it moves
+   * within; null before initialization
+   */
+  getBatching(): { adaptive: boolean; batchSize: number; min: number; max: number } | null {
+    if (!this.sizer) return null;
+    return { adaptive: this.sizer.adaptive, batchSize: this.sizer.current(), ...this.sizer.bounds() };
+  }
+
+  /**
+   * `texts` through the provider's batch call, in requests within the token budget; a request
+   * the provider rejects as too large is retried in halves, and later ones are split before they
+   * are sent. Each call's latency feeds the sizer.
+   */
+  private async embedSized(
+    provider: EmbeddingProvider,
+    texts: string[],
+    sizes: number[],
+    signal?: AbortSignal,
+  ): Promise<Float32Array[]> {
+    const sizer = this.sizer;
+    const embedBatch = provider.embedBatch!.bind(provider);
+    const send = async (group: string[]): Promise<Float32Array[]> => {
+      if (sizer && group.length > 1 && !sizer.fits(group.length)) {
+        // Another part of this batch already found the limit
+        const out: Float32Array[] = [];
+        for (const part of splitBatch(group, sizer.current())) out.push(...(await send(part)));
+        return out;
+      }
+      const started = Date.now();
+      try {
+        const vectors = (await embedBatch(group, { signal })) ?? [];
+        sizes.push(group.length);
+        const sample = sizer?.record(group.length, Date.now() - started);
+        if (sample && log.enabled("debug")) log.debug("embedding batch", { provider: provider.info.name, ...sample });
+        return vectors;
+      } catch (e) {
+        throwIfCancelled(signal);
+        if (group.length < 2 || !isBatchTooLarge(e)) throw e;
+        sizer?.rejected(group.length);
+        log.debug("embedding batch rejected as too large, splitting", {
+          provider: provider.info.name,
+          size: group.length,
+          error: (e as Error)?.message || String(e),
+        });
+        const half = Math.ceil(group.length / 2);
+        return [...(await send(group.slice(0, half))), ...(await send(group.slice(half)))];
+      }
+    };
+
+    const out: Float32Array[] = [];
+    for (const group of splitBatch(texts, texts.length, sizer?.maxTokens)) out.push(...(await send(group)));
+    return out;
+  }
+
+  private sourceOf(provider: EmbeddingProvider): EmbeddingSource {
+    return { provider: String(provider.info.name), model: provider.info.model, fallback: provider === this.fallback };
+  }
+
+  // Logged once: per-text failures after this are expected and would only repeat it
+  private warnFallback(reason: string): void {
+    if (this.fallbackWarned) return;
+    this.fallbackWarned = true;
+    log.warn(
+      `${reason}. Falling back to low-quality hashing embeddings (${MEMORY_MODEL}); ` +
+        "semantic search only matches shared words until the configured provider works.",
+    );
+  }
+
+  /**
+   * Back the in-memory cache with a durable store so vectors survive restarts and re-index runs
+   */
+  setPersistentCache(store: EmbeddingCacheStore | null): void {
+    this.persistentCache = store;
+  }
+
+  /**
+   * Provider/model/dimension identity that cached vectors are filed under
+   */
+  getCacheKey(): string {
+    return this.providerKey();
+  }
+
+  // Fallback vectors must never be stored under the primary provider's key
+  private durableCache(provider: EmbeddingProvider): EmbeddingCacheStore | null {
+    return provider !== this.fallback ? this.persistentCache : null;
+  }
+
+  /**
+   * Identity of the provider producing vectors; stored alongside them so a switch can be detected
+   */
+  getProviderInfo(): {
+    provider: string;
+    model: string;
+    dimension?: number;
+    fallback: boolean;
+    error?: string;
+    initFailure?: EmbeddingInitDetails;
+  } {
+    const active = this.provider ?? this.fallback;
+    return {
+      provider: String(active?.info.name ?? "memory"),
+      model: active?.info.model ?? MEMORY_MODEL,
+      dimension: this.getDimension(),
+      fallback: active !== null && active === this.fallback,
+      error: this.initError ?? undefined,
+      initFailure: this.initFailure ?? undefined,
+    };
+  }
+
+  /**
+   * Dimension reported by the active provider, or undefined before initialization
+   */
+  getDimension(): number | undefined {
+    return this.provider?.getDimension?.() ?? this.fallback?.getDimension?.();
+  }
+
   /**
    * Generate embedding for a single text
    */
@@ -170,45 +384,61 @@ export class EmbeddingGenerator {
     const fallback = this.fallback ?? provider;
 
     const normalized = normalizeText(text);
-    const key = `${this.providerKey()}:${hashText(normalized)}`;
+    const providerKey = this.providerKey();
+    const hash = hashText(normalized);
+    const key = `${providerKey}:${hash}`;
 
+    const started = Date.now();
     const cached = this.cache.get(key);
     if (cached && Date.now() - cached.timestamp < DEFAULT_TTL_MS) {
       this.cacheHits++;
+      sessionMetrics.recordEmbedding(1, 0, 0, Date.now() - started);
       return cached.embedding;
     }
-    this.cacheMisses++;
-
-    const dimension = provider.getDimension?.() ?? fallback.getDimension?.() ?? VECTOR_DIMENSIONS;
 
-    let embedding: Float32Array | undefined;
-    try {
-      embedding = await provider.embed(normalized);
-    } catch (e) {
-      console.warn("[EmbeddingGenerator] embed failed, using fallback:", (e as Error)?.message || e);
-      embedding = await fallback.embed(normalized);
-    }
-
-    embedding = ensureEmbedding(embedding, dimension);
+    const store = this.durableCache(provider);
+    let embedding = (await store?.getCachedEmbeddings(providerKey, [hash]))?.get(hash);
+    if (embedding) {
+      this.persistentHits++;
+      sessionMetrics.recordEmbedding(1, 0, 0, Date.now() - started);
+    } else {
+      this.cacheMisses++;
+      const dimension = provider.getDimension?.() ?? fallback.getDimension?.() ?? VECTOR_DIMENSIONS;
+      let fromProvider = true;
+      try {
+        embedding = await provider.embed(normalized);
+      } catch (e) {
+        log.warn("embed failed, using fallback", { error: (e as Error)?.message || String(e) });
+        embedding = await fallback.embed(normalized);
+        fromProvider = false;
+      }
 
-    // Cache with simple LRU eviction
-    if (this.cache.size >= MAX_CACHE_ENTRIES) {
-      this.evictOldestCacheEntry();
+      embedding = ensureEmbedding(embedding, dimension);
+      sessionMetrics.recordEmbedding(1, 1, fromProvider ? 0 : 1, Date.now() - started);
+      if (store && fromProvider) await store.putCachedEmbeddings(providerKey, [{ hash, vector: embedding }]);
+      if (!fromProvider) return embedding;
     }
-    this.cache.set(key, {
-      text: normalized,
-      embedding,
-      timestamp: Date.now(),
-      providerKey: this.providerKey(),
-    });
 
+    this.rememberEmbedding(key, normalized, embedding, this.sourceOf(provider));
     return embedding;
   }
 
   /**
    * Generate embeddings for multiple texts in batch
    */
-  async generateBatch(texts: string[]): Promise<Float32Array[]> {
+  async generateBatch(texts: string[], opts: { signal?: AbortSignal } = {}): Promise<Float32Array[]> {
+    return (await this.generateBatchWithSources(texts, opts)).embeddings;
+  }
+
+  /**
+   * Like generateBatch, also reporting the embedder behind each vector so stored vectors can
+   * record it and fallback ones can be redone once the configured provider works. An aborted
+   * `signal` stops before the next batch and cancels the provider request in flight.
+   */
+  async generateBatchWithSources(
+    texts: string[],
+    opts: { signal?: AbortSignal } = {},
+  ): Promise<{ embeddings: Float32Array[]; sources: EmbeddingSource[] }> {
     if (!this.provider) {
       await (this.initPromise ?? this.initialize());
     }
@@ -221,83 +451,143 @@ export class EmbeddingGenerator {
     const fallback = this.fallback ?? provider;
 
     const results: Float32Array[] = [];
-    const batchSize = this.config.batchSize ?? 8;
-
-    for (let i = 0; i < texts.length; i += batchSize) {
-      const batch = texts.slice(i, Math.min(i + batchSize, texts.length));
-
-      const toProcess: { index: number; text: string; key: string }[] = [];
+    const sources: EmbeddingSource[] = [];
+    const primarySource = this.sourceOf(provider);
+    const fallbackSource = this.sourceOf(fallback);
+    const started = Date.now();
+    let embedded = 0;
+    let fallbacks = 0;
+    const sizes: number[] = [];
+
+    for (let i = 0; i < texts.length; ) {
+      throwIfCancelled(opts.signal);
+      // The sizer may have moved after the previous batch
+      const batchSize = this.sizer?.current() ?? this.config.batchSize ?? 8;
+      const batch = texts.slice(i, i + batchSize);
+      i += batch.length;
+
+      let toProcess: { index: number; text: string; key: string; hash: string }[] = [];
       const batchResults: (Float32Array | null)[] = Array(batch.length).fill(null);
+      const batchSources: EmbeddingSource[] = Array(batch.length).fill(primarySource);
+      const providerKey = this.providerKey();
 
       for (let j = 0; j < batch.length; j++) {
         const normalized = normalizeText(batch[j] ?? "");
-        const key = `${this.providerKey()}:${hashText(normalized)}`;
+        const hash = hashText(normalized);
+        const key = `${providerKey}:${hash}`;
         const cached = this.cache.get(key);
 
         if (cached && Date.now() - cached.timestamp < DEFAULT_TTL_MS) {
           this.cacheHits++;
           batchResults[j] = cached.embedding;
+          batchSources[j] = cached.source;
         } else {
-          this.cacheMisses++;
-          toProcess.push({ index: j, text: normalized, key });
+          toProcess.push({ index: j, text: normalized, key, hash });
         }
       }
 
+      // Memory misses may still have been embedded by an earlier run; only the rest go to the provider
+      const store = this.durableCache(provider);
+      if (store && toProcess.length > 0) {
+        const stored = await store.getCachedEmbeddings(providerKey, toProcess.map((t) => t.hash));
+        toProcess = toProcess.filter((item) => {
+          const embedding = stored.get(item.hash);
+          if (!embedding) return true;
+          this.persistentHits++;
+          batchResults[item.index] = embedding;
+          this.rememberEmbedding(item.key, item.text, embedding, primarySource);
+          return false;
+        });
+      }
+      this.cacheMisses += toProcess.length;
+      embedded += toProcess.length;
+
       // Process uncached
       if (toProcess.length > 0) {
         const dimension = provider.getDimension?.() ?? fallback.getDimension?.() ?? VECTOR_DIMENSIONS;
         let embeddings: Float32Array[] = [];
+        const viaFallback = new Set<number>();
         try {
           if (typeof provider.embedBatch === "function") {
-            embeddings = (await provider.embedBatch(toProcess.map((t) => t.text))) ?? [];
+            embeddings = await this.embedSized(
+              provider,
+              toProcess.map((t) => t.text),
+              sizes,
+              opts.signal,
+            );
           } else {
             // sequential fallback
             embeddings = [];
-            for (const item of toProcess) {
+            for (const [k, item] of toProcess.entries()) {
               try {
                 let emb: Float32Array | undefined;
-                emb = await provider.embed(item.text);
+                emb = await provider.embed(item.text, { signal: opts.signal });
                 if (!emb) {
                   emb = await fallback.embed(item.text);
+                  viaFallback.add(k);
                 }
                 embeddings.push(ensureEmbedding(emb, dimension));
               } catch (e) {
-                console.warn("[EmbeddingGenerator] embed failed in batch, using fallback:", (e as Error)?.message || e);
+                throwIfCancelled(opts.signal);
+                log.warn("embed failed in batch, using fallback", { error: (e as Error)?.message || String(e) });
                 const fallbackEmbedding = await fallback.embed(item.text);
                 embeddings.push(fallbackEmbedding);
+                viaFallback.add(k);
               }
             }
           }
         } catch (e) {
-          console.warn("[EmbeddingGenerator] embedBatch failed, using fallback batch:", (e as Error)?.message || e);
+          // An aborted request is not a provider failure and must not be papered over by the fallback
+          throwIfCancelled(opts.signal);
+          log.warn("embedBatch failed, using fallback batch", { error: (e as Error)?.message || String(e) });
           const fallbackBatch = fallback.embedBatch
             ? await fallback.embedBatch(toProcess.map((t) => t.text))
             : undefined;
           embeddings = fallbackBatch?.map((emb) => ensureEmbedding(emb, dimension)) ?? [];
+          toProcess.forEach((_, k) => viaFallback.add(k));
         }
 
+        fallbacks += viaFallback.size;
+        const durable: Array<{ hash: string; vector: Float32Array }> = [];
         toProcess.forEach((item, k) => {
           const embedding = ensureEmbedding(embeddings[k], dimension);
           batchResults[item.index] = embedding;
-          if (this.cache.size >= MAX_CACHE_ENTRIES) this.evictOldestCacheEntry();
-          this.cache.set(item.key, {
-            text: item.text,
-            embedding,
-            timestamp: Date.now(),
-            providerKey: this.providerKey(),
-          });
+          if (viaFallback.has(k)) {
+            // Not cached, so the next request for this text tries the configured provider again
+            batchSources[item.index] = fallbackSource;
+            return;
+          }
+          this.rememberEmbedding(item.key, item.text, embedding, primarySource);
+          if (embeddings[k]) durable.push({ hash: item.hash, vector: embedding });
         });
+        if (store) await store.putCachedEmbeddings(providerKey, durable);
       }
 
-      results.push(...batchResults.filter((r): r is Float32Array => r !== null));
+      batchResults.forEach((r, j) => {
+        if (r === null) return;
+        results.push(r);
+        sources.push(batchSources[j] ?? primarySource);
+      });
     }
 
-    return results;
+    sessionMetrics.recordEmbedding(texts.length, embedded, fallbacks, Date.now() - started);
+    if (sizes.length > 0 && log.enabled("debug")) {
+      log.debug("embedded texts", {
+        texts: texts.length,
+        requests: sizes.length,
+        meanBatch: Math.round(sizes.reduce((a, b) => a + b, 0) / sizes.length),
+        largestBatch: Math.max(...sizes),
+        nextBatch: this.sizer?.current(),
+        elapsedMs: Date.now() - started,
+      });
+    }
+    return { embeddings: results, sources };
   }
 
   setBatchSize(size: number): void {
     const normalized = Number.isFinite(size) ? Math.max(1, Math.floor(size)) : (this.config.batchSize ?? 8);
     this.config.batchSize = normalized;
+    this.sizer?.reset(normalized);
     if (this.debugMode) {
       console.log(`[EmbeddingGenerator] Batch size updated to ${normalized}`);
     }
@@ -321,6 +611,12 @@ export class EmbeddingGenerator {
     return processed.replace(/\s+/g, " ").trim().slice(0, 512);
   }
 
+  // In-memory cache with simple LRU eviction
+  private rememberEmbedding(key: string, text: string, embedding: Float32Array, source: EmbeddingSource): void {
+    if (this.cache.size >= MAX_CACHE_ENTRIES) this.evictOldestCacheEntry();
+    this.cache.set(key, { text, embedding, timestamp: Date.now(), source });
+  }
+
   private evictOldestCacheEntry(): void {
     let oldestKey: string | null = null;
     let oldestTime = Infinity;
@@ -337,16 +633,18 @@ export class EmbeddingGenerator {
     this.cache.clear();
     this.cacheHits = 0;
     this.cacheMisses = 0;
+    this.persistentHits = 0;
     console.log("[EmbeddingGenerator] Cache cleared");
   }
 
-  getCacheStats(): { size: number; hits: number; misses: number; hitRate: number } {
-    const total = this.cacheHits + this.cacheMisses;
+  getCacheStats(): { size: number; hits: number; persistentHits: number; misses: number; hitRate: number } {
+    const total = this.cacheHits + this.persistentHits + this.cacheMisses;
     return {
       size: this.cache.size,
       hits: this.cacheHits,
+      persistentHits: this.persistentHits,
       misses: this.cacheMisses,
-      hitRate: total > 0 ? this.cacheHits / total : 0,
+      hitRate: total > 0 ? (this.cacheHits + this.persistentHits) / total : 0,
     };
   }
 
@@ -357,6 +655,10 @@ export class EmbeddingGenerator {
     this.provider = null;
     this.fallback = null;
     this.initPromise = null;
+    this.initError = null;
+    this.initFailure = null;
+    this.fallbackWarned = false;
+    this.sizer = null;
     console.log("[EmbeddingGenerator] Cleaned up");
   }
 }
@@ -0,0 +1,141 @@
+/**
+ * Embedding input - which parts of a declaration make up the text embedded for it, and in what order
+ * - header: name, kind and signature on one line (`parseConfig function (path: string): Config`)
+ * - name, signature: each on its own
+ * - qualifiedName: the analyzer's qualified name, else the owning class or receiver and the name
+ * - path: the file and its directory (`ui/Canvas.tsx`), for codebases whose file names say what code is for
+ * - doc: the documentation comment or docstring
+ * - body: the source, left out when it is longer than the embedding cap
+ * - comments: comment lines directly above the declaration and inside its body, other than the doc
+ * A field's weight repeats it that many times (at most 3), so it pulls the vector further its way.
+ * Changing the composition changes every entity's input hash: files indexed afterwards are
+ * re-embedded, the rest keep their old vectors until they are reindexed.
+ */
+
+import { basename, dirname } from "node:path";
+import { stripCommentMarkers } from "../parsers/doc-comments.js";
+
+export type EmbeddingField = "header" | "name" | "qualifiedName" | "path" | "signature" | "doc" | "body" | "comments";
+
+export const EMBEDDING_FIELDS: readonly EmbeddingField[] = [
+  "header",
+  "name",
+  "qualifiedName",
+  "path",
+  "signature",
+  "doc",
+  "body",
+  "comments",
+];
+
+export type EmbeddingInputPreset = "default" | "name-doc" | "full-body" | "signature-heavy";
+
+export interface EmbeddingFieldSpec {
+  field: EmbeddingField;
+  weight: number;
+}
+
+const MAX_WEIGHT = 3;
+
+/** Lines looked at above a declaration for the comments sitting on it */
+const MAX_LEADING_COMMENT_LINES = 20;
+
+const one = (...fields: EmbeddingField[]): EmbeddingFieldSpec[] => fields.map((field) => ({ field, weight: 1 }));
+
+export const EMBEDDING_INPUT_PRESETS: Record<EmbeddingInputPreset, EmbeddingFieldSpec[]> = {
+  // The text embedded before compositions were configurable, so existing vectors stay current
+  default: one("header", "doc", "body"),
+  "name-doc": one("path", "qualifiedName", "header", "doc"),
+  "full-body": one("header", "doc", "comments", "body"),
+  "signature-heavy": [
+    { field: "qualifiedName", weight: 1 },
+    { field: "signature", weight: 2 },
+    { field: "doc", weight: 1 },
+    { field: "body", weight: 1 },
+  ],
+};
+
+export const EMBEDDING_INPUT_PRESET_NAMES = Object.keys(EMBEDDING_INPUT_PRESETS) as EmbeddingInputPreset[];
+
+export interface EmbeddingInputOptions {
+  preset?: string;
+  /** Field names, or `{ field, weight }`; overrides the preset when any entry is valid */
+  fields?: Array<string | { field?: string; weight?: number }>;
+}
+
+/** The declaration's parts a composition picks from; missing ones are empty */
+export type EmbeddingInputParts = Record<EmbeddingField, string>;
+
+/** Composition of the config; unknown fields are dropped and weights clamped to 0..3, 0 leaving a field out */
+export function resolveEmbeddingInput(options: EmbeddingInputOptions = {}): EmbeddingFieldSpec[] {
+  const fields: EmbeddingFieldSpec[] = [];
+  for (const entry of options.fields ?? []) {
+    const spec = typeof entry === "string" ? { field: entry, weight: 1 } : entry;
+    if (!EMBEDDING_FIELDS.includes(spec?.field as EmbeddingField)) continue;
+    const weight = Math.min(MAX_WEIGHT, Math.max(0, Math.floor(Number(spec.weight ?? 1))));
+    if (weight > 0) fields.push({ field: spec.field as EmbeddingField, weight });
+  }
+  if (fields.length > 0) return fields;
+  const preset = EMBEDDING_INPUT_PRESET_NAMES.includes(options.preset as EmbeddingInputPreset)
+    ? (options.preset as EmbeddingInputPreset)
+    : "default";
+  return EMBEDDING_INPUT_PRESETS[preset];
+}
+
+export function usesField(composition: EmbeddingFieldSpec[], field: EmbeddingField): boolean {
+  return composition.some((spec) => spec.field === field);
+}
+
+/** The fields in order, each repeated by its weight, one per line; empty fields are skipped */
+export function composeEmbeddingInput(composition: EmbeddingFieldSpec[], parts: EmbeddingInputParts): string {
+  const lines: string[] = [];
+  for (const { field, weight } of composition) {
+    const value = parts[field]?.trim();
+    if (!value) continue;
+    for (let i = 0; i < weight; i++) lines.push(value);
+  }
+  return lines.join("\n").trim();
+}
+
+/** `qualifiedName` and `path` of an entity as parsed or stored */
+export function entityNameParts(e: any): Pick<EmbeddingInputParts, "qualifiedName" | "path"> {
+  const meta = e?.metadata ?? {};
+  const owner = [meta.className, meta.parentClass, meta.receiver, meta.implType].find(
+    (value) => typeof value === "string" && value,
+  );
+  const qualified =
+    typeof meta.qualifiedName === "string" && meta.qualifiedName
+      ? meta.qualifiedName
+      : owner && e?.name
+        ? `${owner}.${e.name}`
+        : (e?.name ?? "");
+  const file = e?.filePath ?? e?.path ?? "";
+  const dir = file ? basename(dirname(file)) : "";
+  return { qualifiedName: qualified, path: file ? [dir, basename(file)].filter((p) => p && p !== ".").join("/") : "" };
+}
+
+// `#` but not shebangs, Rust attributes or C directives; `--` and `*` only as comment markers, not `--i` or `*p = x`
+const COMMENT_LINE = /^\s*(?:\/\/|#(?![![]|include|define|if|endif|pragma)|--(?:\s|$)|\/\*|\*(?:\s|\/|$))/;
+
+/**
+ * Comment lines directly above line `startLine` (1-based) of `source` and inside `code`, markers
+ * stripped; text that repeats `doc` is left out.
+ */
+export function surroundingComments(source: string, startLine: number, code: string, doc = ""): string {
+  const lines = source.split(/\r?\n/);
+  const above: string[] = [];
+  for (let i = startLine - 2; i >= 0 && above.length < MAX_LEADING_COMMENT_LINES; i--) {
+    const line = lines[i] ?? "";
+    if (!COMMENT_LINE.test(line)) break;
+    above.unshift(line);
+  }
+  const inside = code
+    .split(/\r?\n/)
+    .slice(1)
+    .filter((line) => COMMENT_LINE.test(line));
+  const docText = stripCommentMarkers(doc);
+  return [above, inside]
+    .map((block) => stripCommentMarkers(block.join("\n")))
+    .filter((text) => text && text !== docText)
+    .join("\n");
+}
@@ -0,0 +1,430 @@
+/**
+ * Approximate nearest-neighbour index over embedding vectors (HNSW)
+ *
+ * A layered proximity graph after Malkov & Yashunin, "Efficient and robust approximate nearest
+ * neighbor search using Hierarchical Navigable Small World graphs" (2016). Vectors are compared by
+ * cosine; they are normalised on insert so a dot product is enough. Queries visit a few hundred
+ * nodes instead of every vector, at the cost of sometimes missing a true neighbour: `efSearch`
+ * trades that recall against latency.
+ *
+ * Removal only marks a node deleted - it keeps routing queries but is never returned - and the
+ * graph is rebuilt from the live nodes once the deleted ones pass `compactRatio` of all nodes.
+ */
+
+export interface HnswOptions {
+  /** Links per node on the upper layers; layer 0 keeps twice as many */
+  m?: number;
+  /** Candidate list size while linking a new node; larger builds slower and recalls better */
+  efConstruction?: number;
+  /** Seed for the layer assignment, so identical inserts build identical graphs */
+  seed?: number;
+  /** Share of deleted nodes above which removal rebuilds the graph (default 0.5) */
+  compactRatio?: number;
+}
+
+export interface HnswMatch {
+  id: string;
+  cosine: number;
+}
+
+const FORMAT_VERSION = 1;
+
+/** Binary heap ordered by `before` (the node that should pop first) */
+class Heap {
+  private items: Array<{ node: number; distance: number }> = [];
+
+  constructor(private readonly before: (a: number, b: number) => boolean) {}
+
+  get size(): number {
+    return this.items.length;
+  }
+
+  peek(): { node: number; distance: number } | undefined {
+    return this.items[0];
+  }
+
+  push(node: number, distance: number): void {
+    const items = this.items;
+    items.push({ node, distance });
+    let i = items.length - 1;
+    while (i > 0) {
+      const parent = (i - 1) >> 1;
+      if (!this.before(items[i]!.distance, items[parent]!.distance)) break;
+      [items[i], items[parent]] = [items[parent]!, items[i]!];
+      i = parent;
+    }
+  }
+
+  pop(): { node: number; distance: number } | undefined {
+    const items = this.items;
+    const top = items[0];
+    const last = items.pop();
+    if (items.length > 0 && last) {
+      items[0] = last;
+      let i = 0;
+      for (;;) {
+        const left = 2 * i + 1;
+        const right = left + 1;
+        let next = i;
+        if (left < items.length && this.before(items[left]!.distance, items[next]!.distance)) next = left;
+        if (right < items.length && this.before(items[right]!.distance, items[next]!.distance)) next = right;
+        if (next === i) break;
+        [items[i], items[next]] = [items[next]!, items[i]!];
+        i = next;
+      }
+    }
+    return top;
+  }
+}
+
+function mulberry32(seed: number): () => number {
+  let state = seed >>> 0;
+  return () => {
+    state = (state + 0x6d2b79f5) >>> 0;
+    let t = state;
+    t = Math.imul(t ^ (t >>> 15), t | 1);
+    t ^= t + Math.imul(t ^ (t >>> 7), t | 61);
+    return ((t ^ (t >>> 14)) >>> 0) / 4294967296;
+  };
+}
+
+function normalized(vector: Float32Array): Float32Array {
+  let norm = 0;
+  for (let i = 0; i < vector.length; i++) norm += vector[i]! * vector[i]!;
+  const out = new Float32Array(vector.length);
+  if (norm === 0) return out;
+  const scale = 1 / Math.sqrt(norm);
+  for (let i = 0; i < vector.length; i++) out[i] = vector[i]! * scale;
+  return out;
+}
+
+export class HnswIndex {
+  readonly dimensions: number;
+  readonly m: number;
+  readonly efConstruction: number;
+  readonly compactRatio: number;
+  private readonly levelFactor: number;
+  private random: () => number;
+
+  private ids: string[] = [];
+  private slots = new Map<string, number>();
+  private vectors: Float32Array[] = [];
+  /** links[node][layer] = neighbour nodes */
+  private links: number[][][] = [];
+  private deleted = new Set<number>();
+  private entry = -1;
+  private maxLayer = -1;
+  private visited = new Uint32Array(0);
+  private visitEpoch = 0;
+
+  constructor(dimensions: number, options: HnswOptions = {}) {
+    this.dimensions = dimensions;
+    this.m = Math.max(2, options.m ?? 16);
+    this.efConstruction = Math.max(this.m, options.efConstruction ?? 100);
+    this.compactRatio = Math.min(0.95, Math.max(0.05, options.compactRatio ?? 0.5));
+    this.levelFactor = 1 / Math.log(this.m);
+    this.random = mulberry32(options.seed ?? 42);
+  }
+
+  /** Live (not deleted) vectors */
+  get size(): number {
+    return this.slots.size;
+  }
+
+  /** Removed vectors still held as routing nodes until the next compaction */
+  get deletedCount(): number {
+    return this.deleted.size;
+  }
+
+  has(id: string): boolean {
+    return this.slots.has(id);
+  }
+
+  /** Insert `vector` under `id`, replacing any vector already stored for it */
+  add(id: string, vector: Float32Array): void {
+    if (vector.length !== this.dimensions) {
+      throw new Error(`HNSW index expects ${this.dimensions} dimensions, got ${vector.length}`);
+    }
+    this.remove(id);
+    const node = this.ids.length;
+    const layer = Math.floor(-Math.log(1 - this.random()) * this.levelFactor);
+    this.ids.push(id);
+    this.slots.set(id, node);
+    this.vectors.push(normalized(vector));
+    this.links.push(Array.from({ length: layer + 1 }, () => []));
+    this.link(node, layer);
+  }
+
+  remove(id: string): boolean {
+    const node = this.slots.get(id);
+    if (node === undefined) return false;
+    this.slots.delete(id);
+    this.deleted.add(node);
+    // Tombstones still cost a visit on every walk through them; a few dozen are not worth a rebuild
+    const nodes = this.deleted.size + this.slots.size;
+    if (this.deleted.size > nodes * this.compactRatio && this.deleted.size > 64) this.compact();
+    return true;
+  }
+
+  /**
+   * The `k` stored vectors closest to `query` by cosine, best first. `efSearch` is the size of the
+   * candidate list walked on the bottom layer (at least `k`); `accept` drops ids from the results
+   * without stopping the walk through them.
+   */
+  search(query: Float32Array, k: number, efSearch = 64, accept?: (id: string) => boolean): HnswMatch[] {
+    if (this.entry < 0 || k <= 0 || query.length !== this.dimensions) return [];
+    const q = normalized(query);
+    let ep = this.entry;
+    for (let layer = this.maxLayer; layer > 0; layer--) ep = this.greedy(q, ep, layer);
+    const include = (node: number) => !this.deleted.has(node) && (!accept || accept(this.ids[node]!));
+    const found = this.searchLayer(q, ep, Math.max(efSearch, k), 0, include);
+    return found.slice(0, k).map(({ node, distance }) => ({ id: this.ids[node]!, cosine: 1 - distance }));
+  }
+
+  /**
+   * Binary snapshot of the index. `meta` is stored with it and handed back by `deserialize`, for
+   * the owner to tell whether the snapshot still matches its data.
+   */
+  serialize(meta: Record<string, unknown> = {}): Buffer {
+    const count = this.ids.length;
+    const linkWords: number[] = [];
+    for (const layers of this.links) {
+      linkWords.push(layers.length);
+      for (const neighbours of layers) linkWords.push(neighbours.length, ...neighbours);
+    }
+    const header = Buffer.from(
+      JSON.stringify({
+        version: FORMAT_VERSION,
+        dimensions: this.dimensions,
+        m: this.m,
+        efConstruction: this.efConstruction,
+        entry: this.entry,
+        maxLayer: this.maxLayer,
+        ids: this.ids,
+        deleted: [...this.deleted],
+        linkWords: linkWords.length,
+        meta,
+      }),
+      "utf8",
+    );
+    // Keep the float section 4-byte aligned
+    const headerBytes = Math.ceil((4 + header.length) / 4) * 4;
+    const out = Buffer.alloc(headerBytes + count * this.dimensions * 4 + linkWords.length * 4);
+    out.writeUInt32LE(header.length, 0);
+    header.copy(out, 4);
+    let offset = headerBytes;
+    for (const vector of this.vectors) {
+      Buffer.from(vector.buffer, vector.byteOffset, vector.byteLength).copy(out, offset);
+      offset += vector.byteLength;
+    }
+    for (const word of linkWords) {
+      out.writeUInt32LE(word, offset);
+      offset += 4;
+    }
+    return out;
+  }
+
+  /** Rebuild an index from `serialize` output; throws on a truncated or foreign snapshot */
+  static deserialize(
+    buffer: Buffer,
+    options: Pick<HnswOptions, "compactRatio"> = {},
+  ): { index: HnswIndex; meta: Record<string, unknown> } {
+    const headerLength = buffer.readUInt32LE(0);
+    const header = JSON.parse(buffer.subarray(4, 4 + headerLength).toString("utf8"));
+    if (header.version !== FORMAT_VERSION) throw new Error(`Unsupported HNSW snapshot version ${header.version}`);
+    const ids: string[] = header.ids;
+    const dimensions: number = header.dimensions;
+    const headerBytes = Math.ceil((4 + headerLength) / 4) * 4;
+    const vectorBytes = ids.length * dimensions * 4;
+    if (buffer.length !== headerBytes + vectorBytes + header.linkWords * 4) {
+      throw new Error("HNSW snapshot is truncated");
+    }
+
+    const index = new HnswIndex(dimensions, {
+      m: header.m,
+      efConstruction: header.efConstruction,
+      seed: ids.length,
+      compactRatio: options.compactRatio,
+    });
+    // Copy into fresh memory: the file buffer may not be aligned for Float32Array views
+    const start = buffer.byteOffset + headerBytes;
+    const floats = new Float32Array(buffer.buffer.slice(start, start + vectorBytes));
+    const words = new Uint32Array(buffer.buffer.slice(start + vectorBytes, buffer.byteOffset + buffer.length));
+    let w = 0;
+    for (let node = 0; node < ids.length; node++) {
+      index.vectors.push(floats.subarray(node * dimensions, (node + 1) * dimensions));
+      const layers: number[][] = [];
+      const layerCount = words[w++]!;
+      for (let layer = 0; layer < layerCount; layer++) {
+        const n = words[w++]!;
+        layers.push(Array.from(words.subarray(w, w + n)));
+        w += n;
+      }
+      index.links.push(layers);
+    }
+    index.ids = ids;
+    index.deleted = new Set(header.deleted);
+    ids.forEach((id, node) => {
+      if (!index.deleted.has(node)) index.slots.set(id, node);
+    });
+    index.entry = header.entry;
+    index.maxLayer = header.maxLayer;
+    return { index, meta: header.meta ?? {} };
+  }
+
+  private distance(a: Float32Array, node: number): number {
+    const b = this.vectors[node]!;
+    // Four accumulators: most of the build and query time is spent in this loop
+    let d0 = 0;
+    let d1 = 0;
+    let d2 = 0;
+    let d3 = 0;
+    const end = a.length - (a.length % 4);
+    let i = 0;
+    for (; i < end; i += 4) {
+      d0 += a[i]! * b[i]!;
+      d1 += a[i + 1]! * b[i + 1]!;
+      d2 += a[i + 2]! * b[i + 2]!;
+      d3 += a[i + 3]! * b[i + 3]!;
+    }
+    for (; i < a.length; i++) d0 += a[i]! * b[i]!;
+    return 1 - (d0 + d1 + d2 + d3);
+  }
+
+  private link(node: number, layer: number): void {
+    if (this.entry < 0) {
+      this.entry = node;
+      this.maxLayer = layer;
+      return;
+    }
+    const q = this.vectors[node]!;
+    let ep = this.entry;
+    for (let l = this.maxLayer; l > layer; l--) ep = this.greedy(q, ep, l);
+    for (let l = Math.min(layer, this.maxLayer); l >= 0; l--) {
+      const candidates = this.searchLayer(q, ep, this.efConstruction, l, () => true);
+      const maxLinks = l === 0 ? this.m * 2 : this.m;
+      const neighbours = this.selectNeighbours(candidates, this.m);
+      this.links[node]![l] = neighbours;
+      for (const other of neighbours) {
+        const theirs = this.links[other]![l]!;
+        theirs.push(node);
+        // An overfull neighbour keeps its nearest links; the diversity heuristic here would
+        // double the build time for no measurable recall
+        if (theirs.length > maxLinks) {
+          const base = this.vectors[other]!;
+          const ranked = theirs.map((n) => ({ node: n, distance: this.distance(base, n) }));
+          ranked.sort((a, b) => a.distance - b.distance);
+          this.links[other]![l] = ranked.slice(0, maxLinks).map((r) => r.node);
+        }
+      }
+      if (candidates[0]) ep = candidates[0].node;
+    }
+    if (layer > this.maxLayer) {
+      this.entry = node;
+      this.maxLayer = layer;
+    }
+  }
+
+  /**
+   * Keep a candidate only when it is closer to the new node than to every neighbour kept so far,
+   * which spreads links across directions; top up with the nearest rejects when that leaves too few.
+   */
+  private selectNeighbours(sorted: Array<{ node: number; distance: number }>, max: number): number[] {
+    const kept: number[] = [];
+    const rejected: number[] = [];
+    for (const candidate of sorted) {
+      if (kept.length >= max) break;
+      const base = this.vectors[candidate.node]!;
+      const diverse = kept.every((other) => this.distance(base, other) > candidate.distance);
+      (diverse ? kept : rejected).push(candidate.node);
+    }
+    for (const node of rejected) {
+      if (kept.length >= max) break;
+      kept.push(node);
+    }
+    return kept;
+  }
+
+  private greedy(q: Float32Array, start: number, layer: number): number {
+    let current = start;
+    let best = this.distance(q, current);
+    for (let improved = true; improved; ) {
+      improved = false;
+      for (const next of this.links[current]![layer] ?? []) {
+        const d = this.distance(q, next);
+        if (d < best) {
+          best = d;
+          current = next;
+          improved = true;
+        }
+      }
+    }
+    return current;
+  }
+
+  /** Best-first walk of one layer; returns up to `ef` included nodes, nearest first */
+  private searchLayer(
+    q: Float32Array,
+    start: number,
+    ef: number,
+    layer: number,
+    include: (node: number) => boolean,
+  ): Array<{ node: number; distance: number }> {
+    if (this.visited.length < this.ids.length) this.visited = new Uint32Array(this.ids.length * 2);
+    if (++this.visitEpoch === 0xffffffff) {
+      this.visited.fill(0);
+      this.visitEpoch = 1;
+    }
+    const epoch = this.visitEpoch;
+    const candidates = new Heap((a, b) => a < b);
+    const results = new Heap((a, b) => a > b);
+
+    const startDistance = this.distance(q, start);
+    this.visited[start] = epoch;
+    candidates.push(start, startDistance);
+    if (include(start)) results.push(start, startDistance);
+
+    while (candidates.size > 0) {
+      const current = candidates.pop()!;
+      if (results.size >= ef && current.distance > results.peek()!.distance) break;
+      for (const next of this.links[current.node]![layer] ?? []) {
+        if (this.visited[next] === epoch) continue;
+        this.visited[next] = epoch;
+        const d = this.distance(q, next);
+        if (results.size < ef || d < results.peek()!.distance) {
+          candidates.push(next, d);
+          if (include(next)) {
+            results.push(next, d);
+            if (results.size > ef) results.pop();
+          }
+        }
+      }
+    }
+
+    const out: Array<{ node: number; distance: number }> = [];
+    while (results.size > 0) out.push(results.pop()!);
+    return out.reverse();
+  }
+
+  /** Re-link the live nodes into a fresh graph, dropping the deleted ones */
+  compact(): void {
+    const live = [...this.slots.entries()].sort((a, b) => a[1] - b[1]);
+    const vectors = this.vectors;
+    this.ids = [];
+    this.slots = new Map();
+    this.vectors = [];
+    this.links = [];
+    this.deleted = new Set();
+    this.entry = -1;
+    this.maxLayer = -1;
+    for (const [id, node] of live) {
+      const newNode = this.ids.length;
+      const layer = Math.floor(-Math.log(1 - this.random()) * this.levelFactor);
+      this.ids.push(id);
+      this.slots.set(id, newNode);
+      this.vectors.push(vectors[node]!);
+      this.links.push(Array.from({ length: layer + 1 }, () => []));
+      this.link(newNode, layer);
+    }
+  }
+}
@@ -16,6 +16,8 @@
 
 import type { QueryAgent } from "../agents/query-agent.js";
 import type { FusionOptions, HybridResult, SemanticResult, SimilarityResult } from "../types/semantic.js";
+import type { SearchScope } from "../types/storage.js";
+import { collapseChunkHits } from "./chunking.js";
 import type { EmbeddingGenerator } from "./embedding-generator.js";
 // =============================================================================
 // 1. IMPORTS AND DEPENDENCIES
@@ -85,6 +87,8 @@ export class HybridSearchEngine {
   private vectorStore: VectorStore;
   private embeddingGen: EmbeddingGenerator;
   private queryAgent: QueryAgent | null = null;
+  /** Candidates fetched per result wanted; above 1 when windows of one declaration may fold into it */
+  private chunkOversample = 1;
   private searchMetrics = {
     totalSearches: 0,
     avgSearchTime: 0,
@@ -97,6 +101,11 @@ export class HybridSearchEngine {
     this.queryAgent = queryAgent || null;
   }
 
+  /** Fetch this many candidates per result, for stores holding several window vectors per declaration */
+  setChunkOversample(factor: number): void {
+    this.chunkOversample = Math.max(1, Math.floor(factor));
+  }
+
   /**
    * Set the query agent for structural search
    */
@@ -258,12 +267,13 @@ export class HybridSearchEngine {
   /**
    * Perform pure semantic search without structural component
    */
-  async semanticSearch(query: string, limit = 10): Promise<SemanticResult> {
+  async semanticSearch(query: string, limit = 10, scope?: SearchScope): Promise<SemanticResult> {
     const startTime = Date.now();
 
     try {
       const queryEmbedding = await this.embeddingGen.generateEmbedding(query);
-      const results = await this.vectorStore.search(queryEmbedding, limit);
+      const candidates = await this.vectorStore.search(queryEmbedding, limit * this.chunkOversample, scope);
+      const results = collapseChunkHits(candidates).slice(0, limit);
 
       const processingTime = Date.now() - startTime;
 
@@ -1,4 +1,6 @@
-export type ProviderKind = "memory" | "transformers" | "ollama" | "openai" | "cloudru";
+import type { EmbeddingInitPhase } from "../../types/errors.js";
+
+export type ProviderKind = "memory" | "transformers" | "ollama" | "openai" | "cloudru" | "custom";
 
 export interface ProviderInfo {
   name: ProviderKind | string;
@@ -28,3 +30,31 @@ export interface EmbeddingProvider {
   embedBatch?(texts: string[], opts?: EmbedOptions): Promise<Float32Array[]>;
   close?(): Promise<void>;
 }
+
+/**
+ * Minimal contract for plugging in an embedding backend that does not ship with the server.
+ * A module referenced by `mcp.embedding.custom.module` default-exports either an object of this
+ * shape or a (possibly async) factory returning one; CustomProvider adapts it to EmbeddingProvider.
+ */
+export interface TextEmbedder {
+  readonly dimension: number;
+  readonly name?: string;
+  readonly model?: string;
+  embed(texts: string[]): Promise<number[][]>;
+  close?(): Promise<void>;
+}
+
+/**
+ * Initialization failure tagged with the stage it happened in, so the generator can tell a
+ * flaky download worth retrying from a model that will never load
+ */
+export class ProviderInitError extends Error {
+  constructor(
+    readonly phase: EmbeddingInitPhase,
+    message: string,
+    readonly retriable = true,
+  ) {
+    super(message);
+    this.name = "ProviderInitError";
+  }
+}
@@ -1,6 +1,7 @@
 import type { EmbeddingProvider, EmbedOptions, ProviderInfo, ProviderLogger } from "./base.js";
 import { createHttpEmbeddingMethods } from "./http-embedding-helpers.js";
 import { HttpEngine } from "./http-engine.js";
+import type { RequestLimiter } from "./request-limiter.js";
 
 export interface CloudRUOptions {
   baseUrl?: string;
@@ -9,6 +10,7 @@ export interface CloudRUOptions {
   timeoutMs?: number;
   concurrency?: number;
   maxBatchSize?: number;
+  limiter?: RequestLimiter;
   logger?: ProviderLogger;
 }
 
@@ -38,6 +40,7 @@ export class CloudRUProvider implements EmbeddingProvider {
       timeoutMs: this.opts.timeoutMs ?? 10000,
       concurrency: this.opts.concurrency ?? 4,
       defaultHeaders: headers,
+      limiter: this.opts.limiter,
     });
 
     this.embedMethods = createHttpEmbeddingMethods({
@@ -0,0 +1,117 @@
+import { isAbsolute, resolve } from "node:path";
+import { pathToFileURL } from "node:url";
+import type { EmbeddingProvider, EmbedOptions, ProviderInfo, ProviderLogger, TextEmbedder } from "./base.js";
+
+export interface CustomOptions {
+  /** Embedder instance supplied programmatically (takes precedence over `module`) */
+  embedder?: TextEmbedder;
+  /** Path or package specifier of a module whose default export is a TextEmbedder or a factory for one */
+  module?: string;
+  /** Passed to the module's factory export */
+  options?: Record<string, unknown>;
+  logger?: ProviderLogger;
+}
+
+type EmbedderFactory = (options?: Record<string, unknown>) => TextEmbedder | Promise<TextEmbedder>;
+
+function isTextEmbedder(value: unknown): value is TextEmbedder {
+  return (
+    typeof value === "object" &&
+    value !== null &&
+    typeof (value as TextEmbedder).embed === "function" &&
+    typeof (value as TextEmbedder).dimension === "number"
+  );
+}
+
+function toSpecifier(module: string): string {
+  // Relative and absolute paths are resolved from the working directory; bare names go through node resolution
+  if (module.startsWith(".") || isAbsolute(module)) {
+    return pathToFileURL(resolve(module)).href;
+  }
+  return module;
+}
+
+export class CustomProvider implements EmbeddingProvider {
+  public info: ProviderInfo;
+  private embedder: TextEmbedder | null;
+  private log?: ProviderLogger;
+
+  constructor(private opts: CustomOptions) {
+    this.log = opts.logger;
+    this.embedder = opts.embedder ?? null;
+    this.info = {
+      name: opts.embedder?.name ?? "custom",
+      model: opts.embedder?.model ?? opts.module ?? "custom",
+      dimension: opts.embedder?.dimension,
+      supportsBatch: true,
+    };
+  }
+
+  async initialize(): Promise<void> {
+    if (!this.embedder) {
+      if (!this.opts.module) {
+        throw new Error("Custom embedding provider requires an embedder instance or a module path");
+      }
+      this.log?.info("initialize", { module: this.opts.module });
+
+      const mod: any = await import(toSpecifier(this.opts.module));
+      const exported = mod?.default ?? mod?.createEmbedder ?? mod;
+      const candidate =
+        typeof exported === "function" ? await (exported as EmbedderFactory)(this.opts.options) : exported;
+      if (!isTextEmbedder(candidate)) {
+        throw new Error(
+          `Module ${this.opts.module} must export a TextEmbedder ({ dimension, embed(texts) }) ` +
+            "or a factory returning one",
+        );
+      }
+      this.embedder = candidate;
+    }
+
+    const dimension = this.embedder.dimension;
+    if (!Number.isInteger(dimension) || dimension <= 0) {
+      throw new Error(`Custom embedding provider reported an invalid dimension: ${dimension}`);
+    }
+
+    this.info = {
+      ...this.info,
+      name: this.embedder.name ?? this.info.name,
+      model: this.embedder.model ?? this.info.model,
+      dimension,
+    };
+  }
+
+  getDimension(): number | undefined {
+    return this.info.dimension;
+  }
+
+  async embed(text: string, opts?: EmbedOptions): Promise<Float32Array> {
+    const [embedding] = await this.embedBatch([text], opts);
+    if (!embedding) throw new Error("Custom embedding provider returned no vector");
+    return embedding;
+  }
+
+  async embedBatch(texts: string[], _opts?: EmbedOptions): Promise<Float32Array[]> {
+    if (!this.embedder) throw new Error("Custom embedding provider not initialized");
+
+    const vectors = await this.embedder.embed(texts);
+    const count = Array.isArray(vectors) ? vectors.length : 0;
+    if (count !== texts.length) {
+      throw new Error(`Custom embedding provider returned ${count} vectors for ${texts.length} texts`);
+    }
+
+    const dimension = this.embedder.dimension;
+    return vectors.map((vector, i) => {
+      if (vector?.length !== dimension) {
+        throw new Error(
+          `Custom embedding provider returned a ${vector?.length ?? 0}-dimensional vector at index ${i}, ` +
+            `expected ${dimension}`,
+        );
+      }
+      return Float32Array.from(vector);
+    });
+  }
+
+  async close(): Promise<void> {
+    await this.embedder?.close?.();
+  }
+}
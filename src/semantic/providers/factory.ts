@@ -1,5 +1,6 @@
 import type {
   CloudRUProviderConfig,
+  CustomProviderConfig,
   MemoryProviderConfig,
   OllamaProviderConfig,
   OpenAIProviderConfig,
@@ -8,9 +9,11 @@ import { logger as appLogger } from "../../utils/logger.js";
 import { makeProviderLogger } from "../../utils/provider-logger.js";
 import type { EmbeddingProvider, ProviderKind } from "./base.js";
 import { CloudRUProvider } from "./cloudru-provider.js";
+import { CustomProvider } from "./custom-provider.js";
 import { MemoryProvider } from "./memory-provider.js";
 import { OllamaProvider } from "./ollama-provider.js";
 import { OpenAIProvider } from "./openai-provider.js";
+import type { RequestLimiter } from "./request-limiter.js";
 import { TransformersProvider } from "./transformers-provider.js";
 
 export interface ProviderFactoryOptions {
@@ -21,6 +24,16 @@ export interface ProviderFactoryOptions {
   memory?: MemoryProviderConfig;
   openai?: OpenAIProviderConfig;
   cloudru?: CloudRUProviderConfig;
+  custom?: CustomProviderConfig;
+  /** Shared by the hosted providers' requests; local and custom embedders are not limited */
+  limiter?: RequestLimiter;
+}
+
+const DEFAULT_OPENAI_MODEL = "text-embedding-3-small";
+
+// "default" and the bundled local model name mean "no model chosen" for remote providers
+function isPlaceholderModel(modelName: string | undefined): boolean {
+  return !modelName || modelName === "default" || modelName.startsWith("Xenova/");
 }
 
 export function createProvider(opts: ProviderFactoryOptions): EmbeddingProvider {
@@ -44,19 +57,22 @@ export function createProvider(opts: ProviderFactoryOptions): EmbeddingProvider
         warmupText: opts.ollama?.warmupText,
         checkServer: opts.ollama?.checkServer,
         pullTimeoutMs: opts.ollama?.pullTimeoutMs,
+        limiter: opts.limiter,
         logger: makeProviderLogger(appLogger, "PROVIDER_OLLAMA"),
       });
 
     case "openai":
-      if (!opts.openai?.apiKey) throw new Error("OpenAI apiKey is required");
+      // A missing key is reported by initialize() so the generator can log it and fall back
       return new OpenAIProvider({
-        model: opts.modelName,
-        apiKey: opts.openai.apiKey,
-        baseUrl: opts.openai.baseUrl,
-        timeoutMs: opts.openai.timeoutMs,
-        concurrency: opts.openai.concurrency,
-        dimensions: opts.openai.dimensions,
-        maxBatchSize: opts.openai.maxBatchSize,
+        model: isPlaceholderModel(opts.modelName) ? DEFAULT_OPENAI_MODEL : opts.modelName,
+        apiKey: opts.openai?.apiKey,
+        baseUrl: opts.openai?.baseUrl,
+        timeoutMs: opts.openai?.timeoutMs,
+        concurrency: opts.openai?.concurrency,
+        dimensions: opts.openai?.dimensions,
+        maxBatchSize: opts.openai?.maxBatchSize,
+        maxRetries: opts.openai?.maxRetries,
+        limiter: opts.limiter,
         logger: makeProviderLogger(appLogger, "PROVIDER_OPENAI"),
       });
 
@@ -68,8 +84,20 @@ export function createProvider(opts: ProviderFactoryOptions): EmbeddingProvider
         timeoutMs: opts.cloudru?.timeoutMs,
         concurrency: opts.cloudru?.concurrency,
         maxBatchSize: opts.cloudru?.maxBatchSize,
+        limiter: opts.limiter,
         logger: makeProviderLogger(appLogger, "PROVIDER_CLOUDRU"),
       });
+
+    case "custom":
+      if (!opts.custom || (!opts.custom.embedder && !opts.custom.module)) {
+        throw new Error("Custom provider requires custom.module or custom.embedder");
+      }
+      return new CustomProvider({
+        embedder: opts.custom.embedder,
+        module: opts.custom.module,
+        options: opts.custom.options,
+        logger: makeProviderLogger(appLogger, "PROVIDER_CUSTOM"),
+      });
     default:
       return new MemoryProvider({ dimension: opts.memory?.dimension });
   }
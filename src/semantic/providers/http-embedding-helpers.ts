@@ -1,5 +1,5 @@
 import type { EmbedOptions, ProviderLogger } from "./base.js";
-import type { HttpEngine, RequestConfig } from "./http-engine.js";
+import { type HttpEngine, HttpError, isTransientStatus, type RequestConfig } from "./http-engine.js";
 
 function chunkArray<T>(items: T[], size: number): T[][] {
   const safeSize = Math.max(1, size);
@@ -40,7 +40,11 @@ export async function embedBatchWithEngine(
 
   try {
     return await engine.callSingle(request, texts, parseBatch, { signal: opts?.signal });
-  } catch {
+  } catch (err) {
+    // Per-item requests only help when the batch form itself was rejected, not for auth/quota failures
+    if (err instanceof HttpError && (err.status === 401 || err.status === 403 || isTransientStatus(err.status))) {
+      throw err;
+    }
     return engine.callBatch(request, texts, parseSingle, { signal: opts?.signal });
   }
 }
@@ -1,4 +1,5 @@
 import pLimit from "p-limit";
+import type { RequestLimiter } from "./request-limiter.js";
 
 export class HttpError extends Error {
   constructor(
@@ -10,6 +11,14 @@ export class HttpError extends Error {
   }
 }
 
+export function isTransientStatus(status: number): boolean {
+  return status === 429 || (status >= 500 && status < 600);
+}
+
+function sleep(ms: number): Promise<void> {
+  return new Promise((r) => setTimeout(r, ms));
+}
+
 export interface HttpEngineOptions {
   baseUrl: string;
   timeoutMs?: number;
@@ -17,6 +26,8 @@ export interface HttpEngineOptions {
   maxRetries?: number;
   backoffMs?: number;
   defaultHeaders?: Record<string, string>;
+  /** Shared rate and concurrency limits; a 429 then pauses every request going through it */
+  limiter?: RequestLimiter;
 }
 
 export interface RequestConfig<TBody = any> {
@@ -33,6 +44,7 @@ export class HttpEngine {
   private backoffMs: number;
   private defaultHeaders: Record<string, string>;
   private limit: ReturnType<typeof pLimit>;
+  private limiter?: RequestLimiter;
 
   constructor(opts: HttpEngineOptions) {
     this.baseUrl = opts.baseUrl.replace(/\/$/, "");
@@ -41,6 +53,14 @@ export class HttpEngine {
     this.backoffMs = opts.backoffMs ?? 200;
     this.defaultHeaders = opts.defaultHeaders ?? { "Content-Type": "application/json" };
     this.limit = pLimit(Math.max(1, opts.concurrency ?? 4));
+    this.limiter = opts.limiter;
+  }
+
+  /** Exponential backoff (base * 2^(attempt-1)), honouring a server-provided Retry-After when present */
+  private retryDelay(attempt: number, retryAfter?: string | null): number {
+    const seconds = retryAfter ? Number(retryAfter) : Number.NaN;
+    if (Number.isFinite(seconds) && seconds >= 0) return Math.min(seconds * 1000, 60000);
+    return this.backoffMs * 2 ** Math.max(0, attempt - 1);
   }
 
   private async fetchWithRetry(path: string, init: RequestInit): Promise<Response> {
@@ -48,6 +68,8 @@ export class HttpEngine {
     const url = `${this.baseUrl}${path}`;
 
     while (true) {
+      // Every attempt, retries included, waits its turn; the timeout starts once it is sent
+      const release = await this.limiter?.acquire(init.signal ?? undefined);
       const controller = new AbortController();
       const id = setTimeout(() => controller.abort(), this.timeoutMs);
 
@@ -56,21 +78,31 @@ export class HttpEngine {
           ...init,
           signal: init.signal ?? controller.signal,
         });
+        // The slot covers the request in flight, not backoff sleeps
+        release?.();
 
         if (res.ok) return res;
 
-        if ((res.status === 429 || (res.status >= 500 && res.status < 600)) && attempt < this.maxRetries) {
+        const retryAfter = res.headers?.get?.("retry-after");
+        // A 429 means the account is over its limit, not just this request: hold back all of them
+        if (res.status === 429) this.limiter?.pause(this.retryDelay(attempt + 1, retryAfter));
+
+        if (isTransientStatus(res.status) && attempt < this.maxRetries) {
           attempt++;
-          await new Promise((r) => setTimeout(r, this.backoffMs * attempt));
+          // The limiter's pause is this retry's backoff
+          if (res.status !== 429 || !this.limiter) await sleep(this.retryDelay(attempt, retryAfter));
           continue;
         }
 
         const body = await res.text().catch(() => "");
         throw new HttpError(res.status, res.statusText, body);
       } catch (err) {
-        if (attempt < this.maxRetries) {
+        release?.();
+        // Client errors (bad key, bad request) will not succeed on retry
+        const retriable = !(err instanceof HttpError) || isTransientStatus(err.status);
+        if (retriable && attempt < this.maxRetries) {
           attempt++;
-          await new Promise((r) => setTimeout(r, this.backoffMs * attempt));
+          await sleep(this.retryDelay(attempt));
           continue;
         }
         throw err;
@@ -6,14 +6,42 @@ export interface MemoryOptions {
 
 const DEFAULT_DIM = 384;
 
-function hash32(text: string): number {
-  let hash = 0;
+/** Model id of the hashing embedder; bumped whenever the feature scheme changes */
+export const MEMORY_MODEL = "feature-hash-v1";
+
+// Sub-word trigrams catch renamed or inflected identifiers; whole words still weigh more
+const WORD_WEIGHT = 1;
+const TRIGRAM_WEIGHT = 0.5;
+
+function fnv1a(text: string): number {
+  let hash = 0x811c9dc5;
   for (let i = 0; i < text.length; i++) {
-    hash = ((hash << 5) - hash + text.charCodeAt(i)) | 0;
+    hash ^= text.charCodeAt(i);
+    hash = Math.imul(hash, 0x01000193);
   }
   return hash >>> 0;
 }
 
+/** Lowercased words, with identifiers split on camelCase, snake_case and digits */
+function words(text: string): string[] {
+  const out: string[] = [];
+  for (const token of text.match(/[A-Za-z0-9_$]+/g) ?? []) {
+    const parts = token
+      .replace(/([a-z0-9])([A-Z])/g, "$1 $2")
+      .replace(/([A-Z]+)([A-Z][a-z])/g, "$1 $2")
+      .split(/[\s_$]+/)
+      .filter(Boolean);
+    for (const part of parts) out.push(part.toLowerCase());
+    if (parts.length > 1) out.push(token.toLowerCase());
+  }
+  return out;
+}
+
+/**
+ * Embedder that needs no model: words and their trigrams are hashed into signed buckets (the
+ * hashing trick), so texts sharing vocabulary land close together. Far weaker than a trained
+ * model, but deterministic and always available.
+ */
 export class MemoryProvider implements EmbeddingProvider {
   public info: ProviderInfo;
 
@@ -21,7 +49,7 @@ export class MemoryProvider implements EmbeddingProvider {
     const dimension = opts?.dimension ?? DEFAULT_DIM;
     this.info = {
       name: "memory",
-      model: "deterministic-hash",
+      model: MEMORY_MODEL,
       dimension,
       supportsBatch: true,
     };
@@ -36,17 +64,29 @@ export class MemoryProvider implements EmbeddingProvider {
   private generate(text: string): Float32Array {
     const dim = this.info.dimension ?? DEFAULT_DIM;
     const embedding = new Float32Array(dim);
-    let state = hash32(text) || 1;
-    for (let i = 0; i < dim; i++) {
-      state = (state * 1664525 + 1013904223) >>> 0;
-      embedding[i] = state / 0xffffffff - 0.5;
+    const add = (feature: string, weight: number) => {
+      const h = fnv1a(feature);
+      // The top bit picks the sign so colliding features tend to cancel rather than pile up
+      embedding[h % dim] = (embedding[h % dim] ?? 0) + (h & 0x80000000 ? -weight : weight);
+    };
+
+    for (const word of words(text)) {
+      add(`w:${word}`, WORD_WEIGHT);
+      const padded = `^${word}$`;
+      for (let i = 0; i + 3 <= padded.length; i++) add(`t:${padded.slice(i, i + 3)}`, TRIGRAM_WEIGHT);
     }
+
     let norm = 0;
     for (let i = 0; i < dim; i++) {
       const v = embedding[i] ?? 0;
       norm += v * v;
     }
-    norm = Math.sqrt(norm) || 1;
+    if (norm === 0) {
+      // No words at all (punctuation only); still return a unit vector so cosine stays defined
+      embedding[fnv1a(text) % dim] = 1;
+      return embedding;
+    }
+    norm = Math.sqrt(norm);
     for (let i = 0; i < dim; i++) {
       const v = embedding[i] ?? 0;
       embedding[i] = v / norm;
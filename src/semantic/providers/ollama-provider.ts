@@ -1,4 +1,5 @@
 import type { EmbeddingProvider, EmbedOptions, ProviderInfo, ProviderLogger } from "./base.js";
+import type { RequestLimiter } from "./request-limiter.js";
 
 export interface OllamaOptions {
   model: string;
@@ -10,6 +11,7 @@ export interface OllamaOptions {
   warmupText?: string;
   checkServer?: boolean;
   pullTimeoutMs?: number;
+  limiter?: RequestLimiter;
   logger?: ProviderLogger;
 }
 
@@ -21,10 +23,12 @@ export class OllamaProvider implements EmbeddingProvider {
   private concurrency: number;
   private headers: Record<string, string>;
   private opts: Required<Pick<OllamaOptions, "autoPull" | "warmupText" | "checkServer">>;
+  private limiter?: RequestLimiter;
   private log?: ProviderLogger;
 
   constructor(opts: OllamaOptions) {
     this.log = opts.logger;
+    this.limiter = opts.limiter;
     this.baseUrl = opts.baseUrl ?? "http://127.0.0.1:11434";
     this.timeoutMs = opts.timeoutMs ?? 10_000;
     this.pullTimeoutMs = opts.pullTimeoutMs ?? 120_000;
@@ -143,6 +147,7 @@ export class OllamaProvider implements EmbeddingProvider {
   async embed(text: string, opts?: EmbedOptions): Promise<Float32Array> {
     this.log?.debug("embed()", { len: text?.length }, opts?.requestId);
 
+    const release = await this.limiter?.acquire(opts?.signal);
     const controller = new AbortController();
     const id = setTimeout(() => controller.abort(), this.timeoutMs);
 
@@ -153,8 +158,12 @@ export class OllamaProvider implements EmbeddingProvider {
         body: JSON.stringify({ model: this.info.model, prompt: text }),
         signal: opts?.signal ?? controller.signal,
       });
+      release?.();
 
       if (!res.ok) {
+        // A proxy in front of Ollama may rate-limit; Retry-After is in seconds
+        const retryAfter = Number(res.headers?.get?.("retry-after")) || 1;
+        if (res.status === 429) this.limiter?.pause(Math.min(retryAfter * 1000, 60000));
         const body = await res.text().catch(() => "");
         throw new Error(`Ollama HTTP ${res.status}: ${body}`);
       }
@@ -169,6 +178,7 @@ export class OllamaProvider implements EmbeddingProvider {
       return arr;
     } finally {
       clearTimeout(id);
+      release?.();
     }
   }
 
@@ -1,18 +1,37 @@
 import type { EmbeddingProvider, EmbedOptions, ProviderInfo, ProviderLogger } from "./base.js";
 import { createHttpEmbeddingMethods } from "./http-embedding-helpers.js";
 import { HttpEngine } from "./http-engine.js";
+import type { RequestLimiter } from "./request-limiter.js";
 
 export interface OpenAIOptions {
   baseUrl?: string;
-  apiKey: string;
+  apiKey?: string;
   model: string;
   timeoutMs?: number;
   concurrency?: number;
   dimensions?: number;
   maxBatchSize?: number;
+  maxRetries?: number;
+  backoffMs?: number;
+  limiter?: RequestLimiter;
   logger?: ProviderLogger;
 }
 
+// The embeddings endpoint accepts at most 2048 inputs per request
+export const OPENAI_MAX_BATCH_INPUTS = 2048;
+
+// Native output size per model; text-embedding-3-* can be shortened with the `dimensions` parameter
+export const OPENAI_MODEL_DIMENSIONS: Record<string, number> = {
+  "text-embedding-3-small": 1536,
+  "text-embedding-3-large": 3072,
+  "text-embedding-ada-002": 1536,
+};
+
+export function resolveOpenAIApiKey(explicit?: string): string | undefined {
+  const key = explicit?.trim() || process.env.OPENAI_API_KEY?.trim() || process.env.MCP_EMBEDDING_API_KEY?.trim();
+  return key || undefined;
+}
+
 export class OpenAIProvider implements EmbeddingProvider {
   public info: ProviderInfo;
   private engine: HttpEngine;
@@ -21,23 +40,27 @@ export class OpenAIProvider implements EmbeddingProvider {
   private embedMethods: ReturnType<typeof createHttpEmbeddingMethods>;
 
   constructor(opts: OpenAIOptions) {
-    this.opts = { baseUrl: "https://api.openai.com", ...opts };
+    this.opts = { baseUrl: "https://api.openai.com", ...opts, apiKey: resolveOpenAIApiKey(opts.apiKey) };
     this.log = opts.logger;
 
     this.info = {
       name: "openai",
       model: opts.model,
+      dimension: opts.dimensions ?? OPENAI_MODEL_DIMENSIONS[opts.model],
       supportsBatch: true,
-      maxBatchSize: opts.maxBatchSize,
+      maxBatchSize: Math.min(OPENAI_MAX_BATCH_INPUTS, Math.max(1, opts.maxBatchSize ?? OPENAI_MAX_BATCH_INPUTS)),
     };
 
     this.engine = new HttpEngine({
       baseUrl: this.opts.baseUrl!,
       timeoutMs: this.opts.timeoutMs ?? 10000,
       concurrency: this.opts.concurrency ?? 4,
+      maxRetries: this.opts.maxRetries ?? 4,
+      backoffMs: this.opts.backoffMs ?? 500,
+      limiter: this.opts.limiter,
       defaultHeaders: {
         "Content-Type": "application/json",
-        Authorization: `Bearer ${this.opts.apiKey}`,
+        Authorization: `Bearer ${this.opts.apiKey ?? ""}`,
       },
     });
 
@@ -51,7 +74,20 @@ export class OpenAIProvider implements EmbeddingProvider {
     });
   }
 
-  async initialize(): Promise<void> {}
+  async initialize(): Promise<void> {
+    if (!this.opts.apiKey) {
+      throw new Error(
+        "OpenAI embedding provider requires an API key: set mcp.embedding.openai.apiKey or the OPENAI_API_KEY " +
+          "environment variable",
+      );
+    }
+    this.log?.info("initialize", {
+      model: this.info.model,
+      baseUrl: this.opts.baseUrl,
+      dimension: this.info.dimension,
+      maxBatchSize: this.info.maxBatchSize,
+    });
+  }
 
   getDimension(): number | undefined {
     return this.info.dimension;
@@ -63,19 +99,31 @@ export class OpenAIProvider implements EmbeddingProvider {
     return body;
   };
 
+  /** Trust the vectors the API actually returned over the static model table */
+  private recordDimension(length: number): void {
+    if (length > 0 && this.info.dimension !== length) {
+      if (this.info.dimension) {
+        this.log?.warn("dimension_changed", { expected: this.info.dimension, actual: length });
+      }
+      this.info.dimension = length;
+    }
+  }
+
   private parseSingle = (json: any): Float32Array => {
     if (!json || !Array.isArray(json.data) || !Array.isArray(json.data[0]?.embedding)) {
       throw new Error("OpenAI invalid embedding response");
     }
     const arr = new Float32Array(json.data[0].embedding);
-    this.info.dimension = this.info.dimension ?? arr.length;
+    this.recordDimension(arr.length);
     return arr;
   };
 
   private parseBatch = (json: any): Float32Array[] => {
     if (!json || !Array.isArray(json.data)) throw new Error("OpenAI invalid batch response");
-    const out = json.data.map((d: any) => new Float32Array(d.embedding));
-    if (!this.info.dimension && out[0]) this.info.dimension = out[0].length;
+    // Results carry their input position; don't rely on response order
+    const ordered = [...json.data].sort((a: any, b: any) => (a?.index ?? 0) - (b?.index ?? 0));
+    const out = ordered.map((d: any) => new Float32Array(d.embedding));
+    if (out[0]) this.recordDimension(out[0].length);
     return out;
   };
 
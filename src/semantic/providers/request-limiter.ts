@@ -0,0 +1,134 @@
+import type { EmbeddingRateLimitConfig } from "../../types/semantic.js";
+import { cancellationError, throwIfCancelled } from "../../utils/cancellation.js";
+import { getLog } from "../../utils/structured-log.js";
+
+const log = getLog("embedding");
+
+function sleep(ms: number, signal?: AbortSignal): Promise<void> {
+  return new Promise((resolve, reject) => {
+    const onAbort = () => {
+      clearTimeout(id);
+      reject(cancellationError(signal!));
+    };
+    const id = setTimeout(() => {
+      signal?.removeEventListener("abort", onAbort);
+      resolve();
+    }, ms);
+    signal?.addEventListener("abort", onAbort, { once: true });
+  });
+}
+
+/**
+ * Gate in front of every request to a hosted embedding provider. One instance is shared by all
+ * requests of a generator, so indexing batches, re-embeds and searches draw from the same budget:
+ * a concurrency cap, a token bucket smoothing bursts to `requestsPerSecond`, and a pause that
+ * holds every caller back after the provider answers 429.
+ */
+export class RequestLimiter {
+  private readonly maxConcurrency: number;
+  private readonly rate: number;
+  private readonly capacity: number;
+  private tokens: number;
+  private refilledAt = Date.now();
+  private resumeAt = 0;
+  private active = 0;
+  private waiters: Array<() => void> = [];
+  // Token waits are served one at a time so callers start in arrival order
+  private tokenTurn: Promise<void> = Promise.resolve();
+
+  constructor(opts: EmbeddingRateLimitConfig = {}) {
+    this.maxConcurrency = Math.max(0, Math.floor(opts.maxConcurrency ?? 0));
+    this.rate = Math.max(0, opts.requestsPerSecond ?? 0);
+    this.capacity = Math.max(1, Math.floor(opts.burst ?? Math.ceil(this.rate)));
+    this.tokens = this.capacity;
+  }
+
+  /**
+   * Wait for a concurrency slot, a token and the end of any rate-limit pause. Resolves to the
+   * function releasing the slot; rejects with the cancellation error when `signal` aborts.
+   */
+  async acquire(signal?: AbortSignal): Promise<() => void> {
+    await this.takeSlot(signal);
+    try {
+      const turn = this.tokenTurn.then(() => this.takeToken(signal));
+      this.tokenTurn = turn.catch(() => undefined);
+      await turn;
+    } catch (error) {
+      this.releaseSlot();
+      throw error;
+    }
+    let released = false;
+    return () => {
+      if (released) return;
+      released = true;
+      this.releaseSlot();
+    };
+  }
+
+  /** Run `task` under the limits */
+  async schedule<T>(task: () => Promise<T>, signal?: AbortSignal): Promise<T> {
+    const release = await this.acquire(signal);
+    try {
+      return await task();
+    } finally {
+      release();
+    }
+  }
+
+  /** Hold back every request for `ms`, as a 429 asks; overlapping pauses keep the later end */
+  pause(ms: number): void {
+    const until = Date.now() + Math.max(0, ms);
+    if (until <= this.resumeAt) return;
+    this.resumeAt = until;
+    // Drain the bucket so requests resume at the sustained rate instead of a fresh burst
+    this.tokens = 0;
+    this.refilledAt = until;
+    log.warn("embedding provider rate limited, pausing requests", { pauseMs: Math.round(ms) });
+  }
+
+  private async takeSlot(signal?: AbortSignal): Promise<void> {
+    throwIfCancelled(signal);
+    if (this.maxConcurrency === 0 || this.active < this.maxConcurrency) {
+      this.active++;
+      return;
+    }
+    await new Promise<void>((resolve, reject) => {
+      const onAbort = () => {
+        this.waiters = this.waiters.filter((waiter) => waiter !== grant);
+        reject(cancellationError(signal!));
+      };
+      const grant = () => {
+        signal?.removeEventListener("abort", onAbort);
+        resolve();
+      };
+      this.waiters.push(grant);
+      signal?.addEventListener("abort", onAbort, { once: true });
+    });
+  }
+
+  // A freed slot passes straight to the next waiter, so `active` only drops when nobody waits
+  private releaseSlot(): void {
+    const next = this.waiters.shift();
+    if (next) next();
+    else this.active--;
+  }
+
+  private async takeToken(signal?: AbortSignal): Promise<void> {
+    for (;;) {
+      throwIfCancelled(signal);
+      const now = Date.now();
+      if (now < this.resumeAt) {
+        await sleep(this.resumeAt - now, signal);
+        continue;
+      }
+      if (this.rate === 0) return;
+      this.tokens = Math.min(this.capacity, this.tokens + ((now - this.refilledAt) / 1000) * this.rate);
+      this.refilledAt = now;
+      if (this.tokens >= 1) {
+        this.tokens -= 1;
+        return;
+      }
+      await sleep(Math.ceil(((1 - this.tokens) / this.rate) * 1000), signal);
+    }
+  }
+}
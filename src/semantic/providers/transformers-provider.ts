@@ -1,4 +1,10 @@
-import type { EmbeddingProvider, EmbedOptions, ProviderInfo, ProviderLogger } from "./base.js";
+import {
+  type EmbeddingProvider,
+  type EmbedOptions,
+  type ProviderInfo,
+  ProviderInitError,
+  type ProviderLogger,
+} from "./base.js";
 
 export interface TransformersOptions {
   model: string; //'Xenova/all-MiniLM-L6-v2'
@@ -7,6 +13,45 @@ export interface TransformersOptions {
   logger?: ProviderLogger;
 }
 
+type LoadedPipeline = { pipeline: any; dimension: number | undefined };
+
+// One pipeline per model for the process: concurrent or repeated initializations share the load
+const PIPELINES = new Map<string, Promise<LoadedPipeline>>();
+
+const NETWORK_ERROR = /fetch|network|ENOTFOUND|ECONNRESET|ECONNREFUSED|ETIMEDOUT|EAI_AGAIN|socket|HTTP|status/i;
+
+async function loadPipeline(opts: TransformersOptions): Promise<LoadedPipeline> {
+  let mod: any;
+  try {
+    mod = await import("@xenova/transformers");
+  } catch (e: any) {
+    throw new ProviderInitError("load", `@xenova/transformers is not installed: ${e?.message || e}`, false);
+  }
+  const pipeFactory = mod.pipeline as (task: string, model: string, options?: any) => Promise<any>;
+
+  let pipeline: any;
+  try {
+    pipeline = await pipeFactory("feature-extraction", opts.model, {
+      quantized: opts.quantized !== false,
+      progress_callback: undefined,
+      local_files_only: !!opts.localPath,
+    });
+  } catch (e: any) {
+    const message = e?.message || String(e);
+    // With local files only nothing is downloaded, so any failure is a load failure
+    const phase = !opts.localPath && NETWORK_ERROR.test(message) ? "download" : "load";
+    throw new ProviderInitError(phase, message);
+  }
+
+  try {
+    const out = await pipeline("warm up", { pooling: "mean", normalize: true });
+    return { pipeline, dimension: out?.data?.length };
+  } catch (e: any) {
+    pipeline?.dispose?.();
+    throw new ProviderInitError("runtime", e?.message || String(e));
+  }
+}
+
 export class TransformersProvider implements EmbeddingProvider {
   public info: ProviderInfo;
   private pipeline: any | null = null;
@@ -22,23 +67,24 @@ export class TransformersProvider implements EmbeddingProvider {
   }
 
   async initialize(): Promise<void> {
+    if (this.pipeline) return;
     this.log?.info("initialize", {
       model: this.opts.model,
       quantized: this.opts.quantized,
       localPath: this.opts.localPath,
     });
 
-    const mod: any = await import("@xenova/transformers");
-    const pipeFactory = mod.pipeline as (task: string, model: string, options?: any) => Promise<any>;
-
-    this.pipeline = await pipeFactory("feature-extraction", this.opts.model, {
-      quantized: this.opts.quantized !== false,
-      progress_callback: undefined,
-      local_files_only: !!this.opts.localPath,
-    });
-
-    const out = await this.pipeline("warm up", { pooling: "mean", normalize: true });
-    this.info.dimension = out?.data?.length ?? this.info.dimension;
+    const key = `${this.opts.model}|${this.opts.quantized !== false}|${this.opts.localPath ?? ""}`;
+    let loading = PIPELINES.get(key);
+    if (!loading) {
+      loading = loadPipeline(this.opts);
+      PIPELINES.set(key, loading);
+      // A failed load must not stick; the next initialize() tries again
+      loading.catch(() => PIPELINES.delete(key));
+    }
+    const { pipeline, dimension } = await loading;
+    this.pipeline = pipeline;
+    this.info.dimension = dimension ?? this.info.dimension;
 
     this.log?.info("initialized", { dimension: this.info.dimension });
   }
@@ -66,9 +112,7 @@ export class TransformersProvider implements EmbeddingProvider {
   }
 
   async close(): Promise<void> {
-    try {
-      (this.pipeline as any)?.dispose?.();
-    } catch {}
+    // The pipeline is shared with other providers for the same model, so it stays loaded
     this.pipeline = null;
   }
 }
@@ -26,10 +26,22 @@
 // =============================================================================
 // 1. IMPORTS AND DEPENDENCIES
 // =============================================================================
-import { existsSync, mkdirSync } from "node:fs";
+import { existsSync, mkdirSync, readFileSync, renameSync, rmSync, writeFileSync } from "node:fs";
 import { dirname } from "node:path";
 import Database from "better-sqlite3";
-import type { SimilarityResult, VectorEmbedding, VectorStoreConfig } from "../types/semantic.js";
+import { ANONYMOUS_NAMES } from "../parsers/anonymous-entities.js";
+import { isTestFile } from "../parsers/test-extractor.js";
+import { hasSearchScope, searchScopeSql } from "../storage/search-filters.js";
+import { EmbeddingDimensionError } from "../types/errors.js";
+import type {
+  AnnIndexConfig,
+  EmbeddingSource,
+  SimilarityResult,
+  VectorEmbedding,
+  VectorStoreConfig,
+} from "../types/semantic.js";
+import type { SearchScope } from "../types/storage.js";
+import { HnswIndex } from "./hnsw-index.js";
 
 // =============================================================================
 // 2. CONSTANTS AND CONFIGURATION
@@ -39,8 +51,31 @@ const DEFAULT_CONFIG: Partial<VectorStoreConfig> = {
   dimensions: 384,
   cacheSize: 64000, // 256MB cache
   walMode: true,
+  busyTimeoutMs: 10000,
 };
 
+/** `metadata.kind` of function-body vectors; only searchBodies returns them */
+export const BODY_VECTOR_KIND = "body";
+const NOT_BODY_SQL = `json_extract(e.metadata, '$.kind') IS NOT '${BODY_VECTOR_KIND}'`;
+
+const DEFAULT_ANN: Required<AnnIndexConfig> = {
+  enabled: true,
+  minVectors: 10000,
+  m: 16,
+  efConstruction: 100,
+  efSearch: 64,
+  compactRatio: 0.5,
+};
+// Counts writes to the store, so a saved ANN index can tell it missed some (a crash before saving)
+const BUMP_GENERATION_SQL = `
+  INSERT INTO vector_store_meta (key, value) VALUES ('ann_generation', '1')
+  ON CONFLICT(key) DO UPDATE SET value = CAST(value AS INTEGER) + 1
+`;
+// The index file is rewritten at most this often while vectors change, and on close
+const ANN_SAVE_DELAY_MS = 30000;
+// Rows linked per event-loop turn while building from an existing store
+const ANN_BUILD_CHUNK = 500;
+
 // =============================================================================
 // 3. DATA MODELS AND TYPE DEFINITIONS
 // =============================================================================
@@ -64,6 +99,15 @@ function bufferToFloat32Array(buffer: Buffer): Float32Array {
   return new Float32Array(buffer.buffer, buffer.byteOffset, buffer.byteLength / 4);
 }
 
+function sameEmbeddingSource(a: EmbeddingSource, b: EmbeddingSource): boolean {
+  if (a.provider !== b.provider || a.model !== b.model) return false;
+  return a.dimension == null || b.dimension == null || a.dimension === b.dimension;
+}
+
+function isBodyRow(metadata: string | null): boolean {
+  return metadata !== null && JSON.parse(metadata)?.kind === BODY_VECTOR_KIND;
+}
+
 function dedupeById(items: VectorEmbedding[]): VectorEmbedding[] {
   const map = new Map<string, VectorEmbedding>();
   for (const e of items) map.set(e.id, e);
@@ -80,6 +124,7 @@ export class VectorStore {
   private deleteStmt: Database.Statement | null = null;
   private insertVecStmt: Database.Statement | null = null;
   private deleteVecByIdStmt: Database.Statement | null = null;
+  private bumpGenerationStmt: Database.Statement | null = null;
 
   // TASK-004B: Initialization state management
   private isInitialized = false;
@@ -89,6 +134,22 @@ export class VectorStore {
   private readonly MAX_EXTENSION_LOAD_ATTEMPTS = 3;
   private debugMode = process.env.VECTOR_STORE_DEBUG === "true";
   private sqliteVecEnabled = false;
+  private sourceChange: { previous: EmbeddingSource | null; current: EmbeddingSource } | null = null;
+  // Stored vectors kept at a dimension the active provider does not produce
+  private dimensionChange: { stored: number; active: number } | null = null;
+
+  // ANN index over the searchable (non-body) vectors; queried only once `annReady`
+  private readonly annConfig: Required<AnnIndexConfig>;
+  private ann: HnswIndex | null = null;
+  private annReady = false;
+  private annBuild: Promise<void> | null = null;
+  /** Ids written or deleted while a build runs; the build must not overwrite them with older rows */
+  private annTouched: Set<string> | null = null;
+  private annDirty = false;
+  private annSaveTimer: NodeJS.Timeout | null = null;
+  private searchableCount: number | null = null;
+  /** Times the database was reconnected; an imported file may carry any generation */
+  private reopens = 0;
 
   constructor(config: Partial<VectorStoreConfig> = {}) {
     this.config = {
@@ -96,7 +157,11 @@ export class VectorStore {
       dimensions: config.dimensions || DEFAULT_CONFIG.dimensions!,
       cacheSize: config.cacheSize || DEFAULT_CONFIG.cacheSize,
       walMode: config.walMode ?? DEFAULT_CONFIG.walMode,
+      busyTimeoutMs: config.busyTimeoutMs ?? DEFAULT_CONFIG.busyTimeoutMs,
+      embeddingSource: config.embeddingSource,
+      onDimensionChange: config.onDimensionChange ?? "reembed",
     };
+    this.annConfig = { ...DEFAULT_ANN, ...config.ann };
   }
 
   /**
@@ -152,7 +217,8 @@ export class VectorStore {
       }
 
       // Create database connection
-      this.db = new Database(this.config.dbPath);
+      // The graph store usually shares this file; its index writes must not fail these statements
+      this.db = new Database(this.config.dbPath, { timeout: this.config.busyTimeoutMs });
 
       // TASK-004B: Load sqlite-vec extension with improved error handling
       await this.loadSqliteVecExtension();
@@ -169,15 +235,9 @@ export class VectorStore {
       const hasVecExtension = this.checkVecExtension();
       this.sqliteVecEnabled = hasVecExtension;
       console.log(`[VectorStore] hasVecExtension: ${hasVecExtension}`);
+      this.reconcileEmbeddingSource();
       if (hasVecExtension) {
-        const existingDims = this.getExistingVecDimensions();
-        if (existingDims != null && existingDims !== this.config.dimensions) {
-          console.warn(
-            `[VectorStore] Existing vec_doc_embeddings uses dimension ${existingDims}, ` +
-              `overriding configured ${this.config.dimensions}`,
-          );
-          this.config.dimensions = existingDims;
-        }
+        this.keepExistingDimension(this.getExistingVecDimensions());
 
         // Create optimized table using sqlite-vec virtual table
         this.db.exec(`
@@ -204,6 +264,8 @@ export class VectorStore {
             created_at INTEGER NOT NULL
           );
         `);
+
+        this.keepExistingDimension(this.getExistingBlobDimensions());
       }
 
       // Create indexes
@@ -214,9 +276,22 @@ export class VectorStore {
         CREATE INDEX IF NOT EXISTS idx_doc_embeddings_content
         ON doc_embeddings(content);
       `);
+      this.tagTestVectors();
+
+      // Kept vectors still belong to the source that built them, so only a matching one is recorded
+      if (this.config.embeddingSource && !this.dimensionChange) {
+        const source: EmbeddingSource = { ...this.config.embeddingSource, dimension: this.config.dimensions };
+        this.db
+          .prepare("INSERT OR REPLACE INTO vector_store_meta (key, value) VALUES ('embedding_source', ?)")
+          .run(JSON.stringify(source));
+      }
+      this.db
+        .prepare("INSERT OR REPLACE INTO vector_store_meta (key, value) VALUES ('dimension', ?)")
+        .run(String(this.config.dimensions));
 
       // Prepare statements for better performance
       this.prepareStatements();
+      this.loadAnnIndex();
 
       console.log(`[VectorStore] Initialized with ${this.config.dimensions} dimensions`);
     } catch (error) {
@@ -225,6 +300,116 @@ export class VectorStore {
     }
   }
 
+  /**
+   * Compare the provider/model that produced the stored vectors against the configured one and
+   * drop the embedding tables when they differ. Vectors from different models live in unrelated
+   * spaces, so keeping them would make similarity scores meaningless.
+   */
+  private reconcileEmbeddingSource(): void {
+    if (!this.db) return;
+
+    this.db.exec(`
+      CREATE TABLE IF NOT EXISTS vector_store_meta (
+        key TEXT PRIMARY KEY,
+        value TEXT NOT NULL
+      );
+    `);
+
+    const current = this.config.embeddingSource;
+    if (!current) return;
+
+    const row = this.db.prepare("SELECT value FROM vector_store_meta WHERE key = 'embedding_source'").get() as
+      | { value?: string }
+      | undefined;
+    let previous: EmbeddingSource | null = null;
+    try {
+      previous = row?.value ? (JSON.parse(row.value) as EmbeddingSource) : null;
+    } catch {
+      previous = null;
+    }
+
+    const wanted: EmbeddingSource = { ...current, dimension: this.config.dimensions };
+    const existingDims = this.sqliteVecEnabled ? this.getExistingVecDimensions() : this.getExistingBlobDimensions();
+    // Stores written before the source was recorded can only be judged by their dimension
+    const changed = previous
+      ? !sameEmbeddingSource(previous, wanted)
+      : existingDims != null && existingDims !== this.config.dimensions;
+    if (!changed) return;
+    // With `refuse`, vectors of another dimension stay; keepExistingDimension then flags the store
+    const storedDims = existingDims ?? previous?.dimension;
+    if (this.config.onDimensionChange === "refuse" && storedDims != null && storedDims !== this.config.dimensions) {
+      return;
+    }
+
+    try {
+      this.db.exec("DROP TABLE IF EXISTS vec_doc_embeddings");
+    } catch (error) {
+      // Dropping a vec0 table needs the extension; without it the table is unused anyway
+      if (this.debugMode) console.warn("[VectorStore] Could not drop vec_doc_embeddings:", error);
+    }
+    this.db.exec("DROP TABLE IF EXISTS doc_embeddings");
+    this.db.exec(BUMP_GENERATION_SQL);
+
+    this.sourceChange = { previous, current: wanted };
+    const from = previous ? `${previous.provider}/${previous.model}` : `unknown (${existingDims} dims)`;
+    console.warn(
+      `[VectorStore] Embedding source changed from ${from} to ${wanted.provider}/${wanted.model}; ` +
+        "stored vectors were discarded and need to be re-embedded",
+    );
+  }
+
+  /**
+   * Tag the vectors of test files embedded before the `test` tag existed, once per store, so that
+   * `tests: "exclude"` leaves them out like the ones embedded since
+   */
+  private tagTestVectors(): void {
+    const db = this.db;
+    if (!db || db.prepare("SELECT 1 FROM vector_store_meta WHERE key = 'test_tags'").get()) return;
+
+    const paths = db
+      .prepare("SELECT DISTINCT json_extract(metadata, '$.path') FROM doc_embeddings WHERE json_valid(metadata)")
+      .pluck()
+      .all() as Array<string | null>;
+    const tag = db.prepare(`
+      UPDATE doc_embeddings SET metadata = json_set(metadata, '$.test', json('true'))
+      WHERE json_valid(metadata) AND json_extract(metadata, '$.path') = ?
+    `);
+    db.transaction(() => {
+      for (const path of paths) {
+        if (path && isTestFile(path)) tag.run(path);
+      }
+      db.prepare("INSERT OR REPLACE INTO vector_store_meta (key, value) VALUES ('test_tags', '1')").run();
+    })();
+  }
+
+  /**
+   * Keep the dimension of vectors already in the store over the configured one, remembering the
+   * mismatch: searches and writes at the configured dimension are then refused
+   */
+  private keepExistingDimension(existingDims: number | null): void {
+    if (existingDims == null || existingDims === this.config.dimensions) return;
+    this.dimensionChange = { stored: existingDims, active: this.config.dimensions };
+    console.warn(
+      `[VectorStore] Stored vectors have dimension ${existingDims} but the embedding provider produces ` +
+        `${this.config.dimensions}; semantic search is disabled until the vectors are rebuilt`,
+    );
+    this.config.dimensions = existingDims;
+  }
+
+  /**
+   * Stored and active dimensions when they differ, in which case searches and writes are refused
+   */
+  getDimensionChange(): { stored: number; active: number } | null {
+    return this.dimensionChange;
+  }
+
+  /**
+   * Provider switch detected during initialization, if any.
+   */
+  getEmbeddingSourceChange(): { previous: EmbeddingSource | null; current: EmbeddingSource } | null {
+    return this.sourceChange;
+  }
+
   /**
    * Detect existing sqlite-vec table dimensions from PRAGMA metadata.
    */
@@ -249,6 +434,49 @@ export class VectorStore {
     }
   }
 
+  /**
+   * Detect the dimension of vectors already stored as Float32 blobs (fallback mode).
+   */
+  private getExistingBlobDimensions(): number | null {
+    if (!this.db) return null;
+
+    try {
+      const row = this.db
+        .prepare("SELECT length(vector) AS bytes FROM doc_embeddings WHERE vector IS NOT NULL LIMIT 1")
+        .get() as { bytes?: number } | undefined;
+      if (!row?.bytes) return null;
+      return row.bytes % 4 === 0 ? row.bytes / 4 : null;
+    } catch {
+      return null;
+    }
+  }
+
+  /**
+   * Dimension of the vectors this store holds (the existing table's, when one was found).
+   */
+  getDimensions(): number {
+    return this.config.dimensions;
+  }
+
+  /**
+   * Reject query vectors whose dimension differs from the stored vectors. Such queries would
+   * either error inside sqlite-vec or compare unrelated embedding spaces in the fallback path.
+   */
+  private assertQueryDimension(queryVector: Float32Array): void {
+    const stored = this.config.dimensions;
+    if (stored && queryVector.length !== stored) throw this.dimensionError(queryVector.length);
+  }
+
+  private dimensionError(activeDimension: number): EmbeddingDimensionError {
+    const source = this.config.embeddingSource;
+    return new EmbeddingDimensionError({
+      storedDimension: this.config.dimensions,
+      activeDimension,
+      provider: source?.provider,
+      model: source?.model,
+    });
+  }
+
   /**
    * Prepare SQL statements for reuse
    */
@@ -291,16 +519,19 @@ export class VectorStore {
     }
 
     this.deleteStmt = this.db.prepare(`DELETE FROM doc_embeddings WHERE id = ?`);
+    this.bumpGenerationStmt = this.db.prepare(BUMP_GENERATION_SQL);
   }
 
   /**
-   * Ensure vector matches the configured dimension (pad or truncate if needed).
+   * Ensure vector matches the configured dimension (pad or truncate if needed). Vectors from a
+   * provider whose dimension differs from the kept ones are refused: padded, they would be noise.
    */
   private ensureVectorDimension(vector: Float32Array, id: string): Float32Array {
     const targetDim = this.config.dimensions;
     if (!targetDim || vector.length === targetDim) {
       return vector;
     }
+    if (this.dimensionChange) throw this.dimensionError(vector.length);
 
     const adjusted = new Float32Array(targetDim);
     const len = Math.min(vector.length, targetDim);
@@ -421,20 +652,19 @@ export class VectorStore {
       const metadataStr = embedding.metadata ? JSON.stringify(embedding.metadata) : null;
       const timestamp = embedding.createdAt || Date.now();
 
-      if (hasVec && this.insertVecStmt && this.deleteVecByIdStmt) {
-        const tx = this.db.transaction((e: VectorEmbedding) => {
-          this.deleteVecByIdStmt?.run(e.id);
-          const vec = this.ensureVectorDimension(e.vector, e.id);
-          const vectorJson = JSON.stringify(Array.from(vec));
-          this.insertVecStmt?.run(e.id, vectorJson);
+      const vec = this.ensureVectorDimension(embedding.vector, embedding.id);
+      const tx = this.db.transaction((e: VectorEmbedding) => {
+        if (hasVec && this.insertVecStmt && this.deleteVecByIdStmt) {
+          this.deleteVecByIdStmt.run(e.id);
+          this.insertVecStmt.run(e.id, JSON.stringify(Array.from(vec)));
           this.insertStmt?.run(e.id, e.content, metadataStr, timestamp);
-        });
-        tx(embedding);
-      } else {
-        const vec = this.ensureVectorDimension(embedding.vector, embedding.id);
-        const vectorBuffer = float32ArrayToBuffer(vec);
-        this.insertStmt.run(embedding.id, embedding.content, vectorBuffer, metadataStr, timestamp);
-      }
+        } else {
+          this.insertStmt?.run(e.id, e.content, float32ArrayToBuffer(vec), metadataStr, timestamp);
+        }
+        this.bumpGenerationStmt?.run();
+      });
+      tx(embedding);
+      this.annPut(embedding.id, vec, embedding.metadata);
     } catch (error) {
       const message = error instanceof Error ? error.message : String(error);
       console.error("[VectorStore] Insert failed:", message);
@@ -451,27 +681,31 @@ export class VectorStore {
     const hasVec = this.sqliteVecEnabled;
     const unique = dedupeById(embeddings);
 
+    const stored: Float32Array[] = [];
     const insertMany = this.db.transaction((items: VectorEmbedding[]) => {
+      stored.length = 0;
       for (const e of items) {
         const metadataStr = e.metadata ? JSON.stringify(e.metadata) : null;
         const timestamp = e.createdAt || Date.now();
+        const vec = this.ensureVectorDimension(e.vector, e.id);
+        stored.push(vec);
 
         if (hasVec && this.insertVecStmt && this.deleteVecByIdStmt) {
           this.deleteVecByIdStmt.run(e.id);
-          const vec = this.ensureVectorDimension(e.vector, e.id);
           const vectorJson = JSON.stringify(Array.from(vec));
           this.insertVecStmt.run(e.id, vectorJson);
           this.insertStmt?.run(e.id, e.content, metadataStr, timestamp);
         } else {
-          const vec = this.ensureVectorDimension(e.vector, e.id);
           const vectorBuffer = float32ArrayToBuffer(vec);
           this.insertStmt?.run(e.id, e.content, vectorBuffer, metadataStr, timestamp);
         }
       }
+      this.bumpGenerationStmt?.run();
     });
 
     try {
       insertMany(unique);
+      unique.forEach((e, i) => this.annPut(e.id, stored[i]!, e.metadata));
       console.log(`[VectorStore] Inserted batch of ${unique.length} embeddings`);
     } catch (error) {
       const message = error instanceof Error ? error.message : String(error);
@@ -483,35 +717,61 @@ export class VectorStore {
   /**
    * Search for similar vectors using cosine similarity
    */
-  async search(queryVector: Float32Array, limit = 10): Promise<SimilarityResult[]> {
+  async search(queryVector: Float32Array, limit = 10, scope?: SearchScope): Promise<SimilarityResult[]> {
     if (!this.db) throw new Error("Vector store not initialized");
+    this.assertQueryDimension(queryVector);
+
+    // Anonymous entities are few among the nearest matches, so leaving them out alone keeps the
+    // index lookup and filters its results; an exact scan only runs when they crowd out too many
+    if (scope?.excludeAnonymous && !hasSearchScope({ ...scope, excludeAnonymous: false })) {
+      const nearest = await this.search(queryVector, limit * 2);
+      const named = nearest.filter(
+        (hit) => hit.metadata?.anonymous !== true && !ANONYMOUS_NAMES.includes(String(hit.metadata?.name ?? "")),
+      );
+      if (named.length >= limit || nearest.length < limit * 2) return named.slice(0, limit);
+    }
+
+    // A KNN query picks its k rows before any WHERE on the joined table applies, so scoped
+    // searches rank the SQL-filtered candidates exactly instead
+    if (hasSearchScope(scope)) return this.fallbackSearch(queryVector, limit, scope);
 
     try {
+      const ann = this.readyAnnIndex();
+      if (ann) return this.annSearch(ann, queryVector, limit);
+
       const hasVecExtension = this.checkVecExtension();
 
       if (hasVecExtension) {
         const stmt = this.db.prepare(`
-        SELECT e.id, e.content, e.metadata, distance
+        SELECT e.id, e.content, e.metadata, v.embedding AS vector, distance
         FROM vec_doc_embeddings v
         JOIN doc_embeddings e ON v.id = e.id
         WHERE v.embedding MATCH vec_f32(?) AND k = ?
         ORDER BY distance
       `);
 
+        // Body vectors share the KNN index; fetch past them, and rank exactly when they crowd out
+        // the requested number of matches
+        const k = limit * 2;
         const vectorJson = JSON.stringify(Array.from(queryVector));
-        const rows = stmt.all(vectorJson, limit) as Array<{
+        const rows = stmt.all(vectorJson, k) as Array<{
           id: string;
           content: string;
           metadata: string | null;
+          vector: Buffer;
           distance: number;
         }>;
+        const docs = rows.filter((row) => !isBodyRow(row.metadata));
+        if (docs.length < limit && rows.length === k) return this.fallbackSearch(queryVector, limit);
 
-        return rows.map((row) => {
+        return docs.slice(0, limit).map((row) => {
           const sim = 1 / (1 + row.distance);
           return {
             id: row.id,
             content: row.content,
             similarity: sim,
+            // The L2 distance is not comparable across queries; the cosine is
+            cosine: this.cosineSimilarity(queryVector, bufferToFloat32Array(row.vector)),
             metadata: row.metadata ? JSON.parse(row.metadata) : undefined,
           };
         });
@@ -526,22 +786,65 @@ export class VectorStore {
     }
   }
 
+  /** Approximate top `limit` by the HNSW index, completed with content and metadata from the table */
+  private annSearch(ann: HnswIndex, queryVector: Float32Array, limit: number): SimilarityResult[] {
+    if (!this.db) throw new Error("Database not initialized");
+    const matches = ann.search(queryVector, limit, Math.max(this.annConfig.efSearch, limit));
+    if (matches.length === 0) return [];
+
+    const rows = this.db
+      .prepare(`SELECT id, content, metadata FROM doc_embeddings WHERE id IN (${matches.map(() => "?").join(", ")})`)
+      .all(...matches.map((match) => match.id)) as Array<{ id: string; content: string; metadata: string | null }>;
+    const byId = new Map(rows.map((row) => [row.id, row]));
+
+    const results: SimilarityResult[] = [];
+    for (const match of matches) {
+      const row = byId.get(match.id);
+      if (!row) continue;
+      results.push({
+        id: row.id,
+        content: row.content,
+        similarity: (match.cosine + 1) / 2,
+        cosine: match.cosine,
+        metadata: row.metadata ? JSON.parse(row.metadata) : undefined,
+      });
+    }
+    return results;
+  }
+
   /**
    * Fallback search implementation without sqlite-vec
    */
-  private async fallbackSearch(queryVector: Float32Array, limit: number): Promise<SimilarityResult[]> {
+  private async fallbackSearch(
+    queryVector: Float32Array,
+    limit: number,
+    scope?: SearchScope,
+  ): Promise<SimilarityResult[]> {
     if (!this.db) throw new Error("Database not initialized");
 
+    const scoped = searchScopeSql(scope, {
+      kind: "json_extract(e.metadata, '$.type')",
+      path: "json_extract(e.metadata, '$.path')",
+      root: "json_extract(e.metadata, '$.root')",
+      name: "json_extract(e.metadata, '$.name')",
+      anonymous: "json_extract(e.metadata, '$.anonymous')",
+      test: "json_extract(e.metadata, '$.test')",
+      visibility: "json_extract(e.metadata, '$.visibility')",
+    });
+    const where = `WHERE ${NOT_BODY_SQL}${scoped ? ` AND ${scoped.sql}` : ""}`;
+    const params = scoped?.params ?? [];
+
     let rows: VectorRow[] = [];
     if (this.sqliteVecEnabled) {
       const stmt = this.db.prepare(`
       SELECT e.id, e.content, e.metadata, v.embedding as vector
       FROM doc_embeddings e JOIN vec_doc_embeddings v ON v.id = e.id
+      ${where}
     `);
-      rows = stmt.all() as any;
+      rows = stmt.all(...params) as any;
     } else {
-      const stmt = this.db.prepare(`SELECT id, content, vector, metadata FROM doc_embeddings`);
-      rows = stmt.all() as any;
+      const stmt = this.db.prepare(`SELECT e.id, e.content, e.vector, e.metadata FROM doc_embeddings e ${where}`);
+      rows = stmt.all(...params) as any;
     }
 
     const results: Array<SimilarityResult & { score: number }> = [];
@@ -553,6 +856,7 @@ export class VectorStore {
         id: row.id,
         content: row.content,
         similarity,
+        cosine: cos,
         score: similarity,
         metadata: row.metadata ? JSON.parse(row.metadata) : undefined,
       });
@@ -575,6 +879,7 @@ export class VectorStore {
     } = {},
   ): Promise<SimilarityResult[]> {
     if (!this.db) throw new Error("Vector store not initialized");
+    this.assertQueryDimension(queryVector);
 
     const { limit = 10, threshold = 0.0, metadataFilter, dateRange } = options;
 
@@ -599,6 +904,7 @@ export class VectorStore {
             condVals.push(value);
           }
         }
+        if (metadataFilter?.kind === undefined) conditions.push(NOT_BODY_SQL);
 
         const whereClause = conditions.length > 0 ? `AND ${conditions.join(" AND ")}` : "";
         const stmt = this.db.prepare(`
@@ -683,6 +989,7 @@ export class VectorStore {
 
     const results: Array<SimilarityResult & { score: number }> = [];
     for (const row of rows) {
+      if (metadataFilter?.kind === undefined && isBodyRow(row.metadata)) continue;
       if (metadataFilter && row.metadata) {
         const md = JSON.parse(row.metadata);
         let ok = true;
@@ -712,6 +1019,225 @@ export class VectorStore {
     return results.slice(0, limit);
   }
 
+  /**
+   * Function-body vectors ranked by cosine similarity to `queryVector`, exact over every body
+   * vector in `scope`. Matches below `minCosine` and the ids in `excludeIds` are left out; ties
+   * are ordered by id.
+   */
+  async searchBodies(
+    queryVector: Float32Array,
+    options: { limit?: number; minCosine?: number; excludeIds?: string[]; scope?: SearchScope } = {},
+  ): Promise<SimilarityResult[]> {
+    if (!this.db) throw new Error("Vector store not initialized");
+    this.assertQueryDimension(queryVector);
+    const { limit = 20, minCosine = -1 } = options;
+
+    const scoped = searchScopeSql(options.scope, {
+      kind: "json_extract(e.metadata, '$.type')",
+      path: "json_extract(e.metadata, '$.path')",
+      root: "json_extract(e.metadata, '$.root')",
+      name: "json_extract(e.metadata, '$.name')",
+      anonymous: "json_extract(e.metadata, '$.anonymous')",
+      test: "json_extract(e.metadata, '$.test')",
+      visibility: "json_extract(e.metadata, '$.visibility')",
+    });
+    const where = `WHERE json_extract(e.metadata, '$.kind') = ?${scoped ? ` AND ${scoped.sql}` : ""}`;
+    const params = [BODY_VECTOR_KIND, ...(scoped?.params ?? [])];
+    const rows = (
+      this.sqliteVecEnabled
+        ? this.db.prepare(`
+      SELECT e.id, e.content, e.metadata, v.embedding as vector
+      FROM doc_embeddings e JOIN vec_doc_embeddings v ON v.id = e.id
+      ${where}
+    `)
+        : this.db.prepare(`SELECT e.id, e.content, e.vector, e.metadata FROM doc_embeddings e ${where}`)
+    ).all(...params) as VectorRow[];
+
+    const excluded = new Set(options.excludeIds ?? []);
+    const results: SimilarityResult[] = [];
+    for (const row of rows) {
+      if (excluded.has(row.id)) continue;
+      const cos = this.cosineSimilarity(queryVector, bufferToFloat32Array(row.vector as unknown as Buffer));
+      if (cos < minCosine) continue;
+      results.push({
+        id: row.id,
+        content: row.content,
+        similarity: (cos + 1) / 2,
+        cosine: cos,
+        metadata: row.metadata ? JSON.parse(row.metadata) : undefined,
+      });
+    }
+
+    results.sort((a, b) => (b.cosine ?? 0) - (a.cosine ?? 0) || (a.id < b.id ? -1 : a.id > b.id ? 1 : 0));
+    return results.slice(0, limit);
+  }
+
+  /**
+   * The ANN index when searches should use it. A store that has grown past `minVectors` without
+   * one gets it built in the background; searches scan exactly until it is ready.
+   */
+  private readyAnnIndex(): HnswIndex | null {
+    if (!this.annConfig.enabled) return null;
+    if (this.ann && this.annReady) return this.ann;
+    if (!this.annBuild && this.countSearchable() >= this.annConfig.minVectors) {
+      this.annBuild = this.buildAnnIndex().finally(() => {
+        this.annBuild = null;
+      });
+    }
+    return null;
+  }
+
+  /**
+   * Load or build the ANN index now rather than on the first search that could use it. Stores
+   * below `minVectors` get none and report `ready: false`; they are scanned exactly.
+   */
+  async warmAnnIndex(): Promise<{ enabled: boolean; ready: boolean; size: number }> {
+    this.readyAnnIndex();
+    if (this.annBuild) await this.annBuild;
+    const ready = this.ann !== null && this.annReady;
+    return { enabled: this.annConfig.enabled, ready, size: ready ? this.ann!.size : 0 };
+  }
+
+  private countSearchable(): number {
+    if (!this.db) return 0;
+    if (this.searchableCount === null) {
+      const row = this.db.prepare(`SELECT COUNT(*) AS count FROM doc_embeddings e WHERE ${NOT_BODY_SQL}`).get() as {
+        count: number;
+      };
+      this.searchableCount = row.count;
+    }
+    return this.searchableCount;
+  }
+
+  /** Link every stored searchable vector into a new index, a chunk per event-loop turn */
+  private async buildAnnIndex(): Promise<void> {
+    const started = Date.now();
+    const index = new HnswIndex(this.config.dimensions, {
+      m: this.annConfig.m,
+      efConstruction: this.annConfig.efConstruction,
+      compactRatio: this.annConfig.compactRatio,
+    });
+    this.ann = index;
+    this.annReady = false;
+    const touched = new Set<string>();
+    this.annTouched = touched;
+
+    try {
+      let lastRowid = 0;
+      for (;;) {
+        // A clear or close replaced the index while this build was waiting
+        if (this.ann !== index || !this.db) return;
+        const sql = this.sqliteVecEnabled
+          ? `SELECT e.rowid AS rowid, e.id, v.embedding AS vector
+             FROM doc_embeddings e JOIN vec_doc_embeddings v ON v.id = e.id
+             WHERE e.rowid > ? AND ${NOT_BODY_SQL} ORDER BY e.rowid LIMIT ?`
+          : `SELECT e.rowid AS rowid, e.id, e.vector FROM doc_embeddings e
+             WHERE e.rowid > ? AND ${NOT_BODY_SQL} ORDER BY e.rowid LIMIT ?`;
+        const rows = this.db.prepare(sql).all(lastRowid, ANN_BUILD_CHUNK) as Array<{
+          rowid: number;
+          id: string;
+          vector: Buffer;
+        }>;
+        if (rows.length === 0) break;
+        for (const row of rows) {
+          if (!touched.has(row.id)) index.add(row.id, bufferToFloat32Array(row.vector));
+        }
+        lastRowid = rows[rows.length - 1]!.rowid;
+        await new Promise((resolve) => setImmediate(resolve));
+      }
+
+      this.annReady = true;
+      console.log(`[VectorStore] Built ANN index over ${index.size} vectors in ${Date.now() - started}ms`);
+      this.annDirty = true;
+      this.saveAnnIndex();
+    } catch (error) {
+      console.warn("[VectorStore] ANN index build failed, searches stay exact:", error);
+      if (this.ann === index) this.ann = null;
+    } finally {
+      if (this.annTouched === touched) this.annTouched = null;
+    }
+  }
+
+  /** Mirror a stored vector into the ANN index; body vectors are only searched exactly */
+  private annPut(id: string, vector: Float32Array, metadata?: Record<string, unknown>): void {
+    this.searchableCount = null;
+    this.annTouched?.add(id);
+    if (!this.ann) return;
+    if (metadata?.kind === BODY_VECTOR_KIND) this.ann.remove(id);
+    else this.ann.add(id, vector);
+    this.scheduleAnnSave();
+  }
+
+  private annRemove(ids: string[]): void {
+    this.searchableCount = null;
+    if (ids.length === 0) return;
+    for (const id of ids) this.annTouched?.add(id);
+    if (!this.ann) return;
+    for (const id of ids) this.ann.remove(id);
+    this.scheduleAnnSave();
+  }
+
+  private dropAnnIndex(): void {
+    this.ann = null;
+    this.annReady = false;
+    this.annDirty = false;
+    this.searchableCount = null;
+    const path = this.annPath();
+    if (path) rmSync(path, { force: true });
+  }
+
+  /** Saved next to the database; in-memory stores keep the index in memory only */
+  private annPath(): string | null {
+    return this.config.dbPath === ":memory:" ? null : `${this.config.dbPath}.hnsw`;
+  }
+
+  private storeGeneration(): number {
+    const row = this.db?.prepare("SELECT value FROM vector_store_meta WHERE key = 'ann_generation'").get() as
+      | { value: string }
+      | undefined;
+    return Number(row?.value ?? 0);
+  }
+
+  /** Use the saved index when it was written after the store's last change */
+  private loadAnnIndex(): void {
+    const path = this.annPath();
+    if (!this.annConfig.enabled || !path || !existsSync(path)) return;
+    try {
+      const { index, meta } = HnswIndex.deserialize(readFileSync(path), { compactRatio: this.annConfig.compactRatio });
+      if (meta.generation !== this.storeGeneration() || index.dimensions !== this.config.dimensions) {
+        console.log("[VectorStore] Saved ANN index is out of date; it will be rebuilt");
+        return;
+      }
+      this.ann = index;
+      this.annReady = true;
+    } catch (error) {
+      console.warn("[VectorStore] Ignoring unreadable ANN index:", error);
+    }
+  }
+
+  private scheduleAnnSave(): void {
+    this.annDirty = true;
+    if (this.annSaveTimer || !this.annPath()) return;
+    this.annSaveTimer = setTimeout(() => {
+      this.annSaveTimer = null;
+      this.saveAnnIndex();
+    }, ANN_SAVE_DELAY_MS);
+    this.annSaveTimer.unref?.();
+  }
+
+  private saveAnnIndex(): void {
+    const path = this.annPath();
+    if (!path || !this.db || !this.ann || !this.annReady || !this.annDirty) return;
+    try {
+      // Write-then-rename, so a crash mid-write leaves the previous snapshot
+      writeFileSync(`${path}.tmp`, this.ann.serialize({ generation: this.storeGeneration() }));
+      renameSync(`${path}.tmp`, path);
+      this.annDirty = false;
+    } catch (error) {
+      console.warn("[VectorStore] Could not save ANN index:", error);
+    }
+  }
+
   /**
    * Compute cosine similarity between two vectors
    */
@@ -798,17 +1324,18 @@ export class VectorStore {
     if (!this.db || !this.updateStmt) throw new Error("Vector store not initialized");
     const metadataStr = metadata ? JSON.stringify(metadata) : null;
 
-    if (this.sqliteVecEnabled && this.insertVecStmt && this.deleteVecByIdStmt) {
-      const tx = this.db.transaction(() => {
-        this.deleteVecByIdStmt?.run(id);
-        this.insertVecStmt?.run(id, JSON.stringify(Array.from(vector)));
+    const tx = this.db.transaction(() => {
+      if (this.sqliteVecEnabled && this.insertVecStmt && this.deleteVecByIdStmt) {
+        this.deleteVecByIdStmt.run(id);
+        this.insertVecStmt.run(id, JSON.stringify(Array.from(vector)));
         this.updateStmt?.run(metadataStr, Date.now(), id);
-      });
-      tx();
-    } else {
-      const vectorBuffer = float32ArrayToBuffer(vector);
-      this.updateStmt.run(vectorBuffer, metadataStr, Date.now(), id);
-    }
+      } else {
+        this.updateStmt?.run(float32ArrayToBuffer(vector), metadataStr, Date.now(), id);
+      }
+      this.bumpGenerationStmt?.run();
+    });
+    tx();
+    this.annPut(id, vector, metadata);
   }
 
   /**
@@ -822,9 +1349,142 @@ export class VectorStore {
         this.deleteVecByIdStmt.run(theId);
       }
       this.deleteStmt?.run(theId);
+      this.bumpGenerationStmt?.run();
     });
 
     tx(id);
+    this.annRemove([id]);
+  }
+
+  /**
+   * Delete every embedding whose metadata points at one of the given files
+   */
+  async deleteByPaths(paths: string[]): Promise<number> {
+    if (!this.db || !this.deleteStmt) throw new Error("Vector store not initialized");
+    if (paths.length === 0) return 0;
+
+    const selectIds = this.db.prepare("SELECT id FROM doc_embeddings WHERE json_extract(metadata, '$.path') = ?");
+    const ids: string[] = [];
+    const tx = this.db.transaction((files: string[]) => {
+      let removed = 0;
+      for (const file of files) {
+        for (const row of selectIds.all(file) as Array<{ id: string }>) {
+          if (this.sqliteVecEnabled && this.deleteVecByIdStmt) {
+            this.deleteVecByIdStmt.run(row.id);
+          }
+          removed += this.deleteStmt?.run(row.id).changes ?? 0;
+          ids.push(row.id);
+        }
+      }
+      this.bumpGenerationStmt?.run();
+      return removed;
+    });
+
+    const removed = tx(paths);
+    this.annRemove(ids);
+    return removed;
+  }
+
+  /**
+   * Metadata of every embedding stored for the given files, keyed by embedding id
+   */
+  async getMetadataByPaths(paths: string[]): Promise<Map<string, Record<string, any>>> {
+    if (!this.db) throw new Error("Vector store not initialized");
+    const result = new Map<string, Record<string, any>>();
+    if (paths.length === 0) return result;
+
+    const select = this.db.prepare(
+      "SELECT id, metadata FROM doc_embeddings WHERE json_extract(metadata, '$.path') = ?",
+    );
+    for (const file of new Set(paths)) {
+      for (const row of select.all(file) as Array<{ id: string; metadata: string | null }>) {
+        try {
+          result.set(row.id, row.metadata ? JSON.parse(row.metadata) : {});
+        } catch {
+          result.set(row.id, {});
+        }
+      }
+    }
+    return result;
+  }
+
+  /**
+   * Delete several embeddings in one transaction
+   */
+  async deleteByIds(ids: string[]): Promise<number> {
+    if (!this.db || !this.deleteStmt) throw new Error("Vector store not initialized");
+    if (ids.length === 0) return 0;
+
+    const tx = this.db.transaction((items: string[]) => {
+      let removed = 0;
+      for (const id of items) {
+        if (this.sqliteVecEnabled && this.deleteVecByIdStmt) {
+          this.deleteVecByIdStmt.run(id);
+        }
+        removed += this.deleteStmt?.run(id).changes ?? 0;
+      }
+      this.bumpGenerationStmt?.run();
+      return removed;
+    });
+
+    const removed = tx(ids);
+    this.annRemove(ids);
+    return removed;
+  }
+
+  /** Every embedding whose metadata names the entity it was made for, with that entity's id */
+  async listEntityRefs(): Promise<Array<{ id: string; entityId: string }>> {
+    if (!this.db) throw new Error("Vector store not initialized");
+    return this.db
+      .prepare(`
+        SELECT id, json_extract(metadata, '$.entityId') AS entityId FROM doc_embeddings
+        WHERE json_extract(metadata, '$.entityId') IS NOT NULL ORDER BY id
+      `)
+      .all() as Array<{ id: string; entityId: string }>;
+  }
+
+  /**
+   * Entity search vectors (`ent:` ids) with the entity each was made for, a page at a time in id
+   * order; body and chunk vectors are left out
+   */
+  async *iterateEntityVectors(pageSize = 500): AsyncGenerator<Array<{ entityId: string; vector: Float32Array }>> {
+    if (!this.db) throw new Error("Vector store not initialized");
+    const sql = this.sqliteVecEnabled
+      ? `SELECT e.id, json_extract(e.metadata, '$.entityId') AS entityId, v.embedding AS vector
+         FROM doc_embeddings e JOIN vec_doc_embeddings v ON v.id = e.id
+         WHERE e.id > ? AND e.id LIKE 'ent:%' ORDER BY e.id LIMIT ?`
+      : `SELECT e.id, json_extract(e.metadata, '$.entityId') AS entityId, e.vector FROM doc_embeddings e
+         WHERE e.id > ? AND e.id LIKE 'ent:%' ORDER BY e.id LIMIT ?`;
+    let after = "";
+    for (;;) {
+      if (!this.db) return;
+      const rows = this.db.prepare(sql).all(after, pageSize) as Array<{
+        id: string;
+        entityId: string | null;
+        vector: Buffer;
+      }>;
+      if (rows.length === 0) return;
+      yield rows.map((row) => ({
+        entityId: row.entityId ?? row.id.slice("ent:".length),
+        vector: bufferToFloat32Array(row.vector),
+      }));
+      if (rows.length < pageSize) return;
+      after = rows[rows.length - 1]!.id;
+    }
+  }
+
+  /** How many of `ids` have an embedding stored */
+  async countIds(ids: string[]): Promise<number> {
+    if (!this.db) throw new Error("Vector store not initialized");
+    let found = 0;
+    for (let i = 0; i < ids.length; i += 900) {
+      const chunk = ids.slice(i, i + 900);
+      const row = this.db
+        .prepare(`SELECT COUNT(*) as count FROM doc_embeddings WHERE id IN (${chunk.map(() => "?").join(", ")})`)
+        .get(...chunk) as { count: number };
+      found += row.count;
+    }
+    return found;
   }
 
   /**
@@ -850,16 +1510,93 @@ export class VectorStore {
         this.db?.exec("DELETE FROM vec_doc_embeddings");
       }
       this.db?.exec("DELETE FROM doc_embeddings");
+      this.bumpGenerationStmt?.run();
     });
 
     tx();
+    this.dropAnnIndex();
     console.log("[VectorStore] Cleared all embeddings");
   }
 
+  /**
+   * Drop the ANN index's deleted nodes and save it, then checkpoint the write-ahead log and, with
+   * `vacuum`, rebuild the database file. `vacuum` is meant for a store in a file of its own; when
+   * the graph store shares the file, its compaction already covers these tables.
+   */
+  async compact(options: { vacuum?: boolean } = {}): Promise<{ annNodesDropped: number; vacuumed: boolean }> {
+    if (!this.db) throw new Error("Vector store not initialized");
+    let annNodesDropped = 0;
+    if (this.ann && this.annReady && this.ann.deletedCount > 0) {
+      annNodesDropped = this.ann.deletedCount;
+      this.ann.compact();
+      this.annDirty = true;
+      this.saveAnnIndex();
+    }
+    this.db.pragma("wal_checkpoint(TRUNCATE)");
+    if (options.vacuum) {
+      this.db.exec("VACUUM");
+      this.db.pragma("wal_checkpoint(TRUNCATE)");
+    }
+    return { annNodesDropped, vacuumed: options.vacuum === true };
+  }
+
+  getDbPath(): string {
+    return this.config.dbPath;
+  }
+
+  /** Changes with every write to the store, and when reopen() reconnects it to a replaced file */
+  getWriteSignature(): string {
+    return `${this.reopens}:${this.db ? this.storeGeneration() : "closed"}`;
+  }
+
+  /**
+   * Drop every stored vector and recreate the tables at the active provider's dimension, recording
+   * the configured embedding source as theirs. For a full re-embed; the graph is not touched.
+   */
+  async rebuild(): Promise<void> {
+    if (!this.db) throw new Error("Vector store not initialized");
+    try {
+      this.db.exec("DROP TABLE IF EXISTS vec_doc_embeddings");
+    } catch (error) {
+      if (this.debugMode) console.warn("[VectorStore] Could not drop vec_doc_embeddings:", error);
+    }
+    this.db.exec("DROP TABLE IF EXISTS doc_embeddings");
+    this.db.exec(BUMP_GENERATION_SQL);
+    this.db.prepare("DELETE FROM vector_store_meta WHERE key = 'embedding_source'").run();
+    this.sourceChange = null;
+    await this.reopen();
+    console.log(`[VectorStore] Rebuilt empty at ${this.config.dimensions} dimensions`);
+  }
+
+  /**
+   * Reconnect after the database file was replaced (import_index). The ANN index described the old
+   * vectors and is dropped, and the stored embedding source is checked against the configured one
+   * again, as on startup.
+   */
+  async reopen(): Promise<void> {
+    if (this.annSaveTimer) clearTimeout(this.annSaveTimer);
+    this.annSaveTimer = null;
+    this.dropAnnIndex();
+    this.db?.close();
+    this.db = null;
+    this.reopens += 1;
+    this.isInitialized = false;
+    this.initializationPromise = null;
+    // The new file is judged against the provider's dimension, not the one kept from the old file
+    if (this.dimensionChange) this.config.dimensions = this.dimensionChange.active;
+    this.dimensionChange = null;
+    await this.initialize();
+  }
+
   /**
    * Close the database connection
    */
   async close(): Promise<void> {
+    if (this.annSaveTimer) clearTimeout(this.annSaveTimer);
+    this.annSaveTimer = null;
+    this.saveAnnIndex();
+    this.ann = null;
+    this.annReady = false;
     if (this.db) {
       this.db.close();
       this.db = null;
@@ -15,8 +15,9 @@ import { createHash } from "node:crypto";
 // 1. IMPORTS AND DEPENDENCIES
 // =============================================================================
 import type Database from "better-sqlite3";
-import type { BatchResult, Entity, ParsedEntity, Relationship } from "../types/storage.js";
+import type { BatchResult, Entity, ParsedEntity, Relationship, UpsertResult } from "../types/storage.js";
 import { RelationType } from "../types/storage.js";
+import { assignStableEntityIds, compareIds } from "./entity-id.js";
 
 // =============================================================================
 // 2. CONSTANTS AND CONFIGURATION
@@ -44,16 +45,13 @@ export class BatchOperations {
   // Helpers: stable keys/ids
   // ---------------------------------------------------------------------------
 
-  private entityKey(e: Entity): string {
-    const s = e.location?.start?.index ?? -1;
-    const eIdx = e.location?.end?.index ?? -1;
-    const key = `${e.filePath}|${e.type}|${e.name}|${s}-${eIdx}`;
-    return key;
-  }
-
-  private stableEntityId(e: Entity): string {
-    const key = this.entityKey(e);
-    return createHash("sha256").update(key).digest("base64url").slice(0, ID_LENGTH);
+  /**
+   * Ids for a batch: the caller's id when set, otherwise the stable id derived from the entity's
+   * identity within the batch (see entity-id.ts)
+   */
+  private entityIds(entities: Entity[]): string[] {
+    const derived = assignStableEntityIds(entities);
+    return entities.map((e, i) => e.id || derived[i]!);
   }
 
   private relationshipKey(r: { fromId: string; toId: string; type: RelationType }): string {
@@ -66,16 +64,20 @@ export class BatchOperations {
 
   /**
    * Insert entities in batches with transaction support
-   * - Local deduplication by entityKey
-   * - Stable IDs based on key
+   * - Local deduplication by id
+   * - Stable IDs for entities that arrive without one
+   * - Written in id order
+   * - Rows whose name, type, file, location and metadata are unchanged are not rewritten, so
+   *   re-indexing an unchanged file keeps their updated_at (and their file hash from that index)
    */
   async insertEntities(
     entities: Entity[],
     onProgress?: (processed: number, total: number) => void,
-  ): Promise<BatchResult> {
+  ): Promise<UpsertResult> {
     const start = Date.now();
     const errors: Array<{ item: unknown; error: string }> = [];
     let totalProcessed = 0;
+    const counts = { created: 0, updated: 0, unchanged: 0 };
 
     // Log database path for debugging
     console.log("[BatchOperations] Database path:", this.db.name || "unknown");
@@ -92,17 +94,25 @@ export class BatchOperations {
         metadata = excluded.metadata,
         hash = COALESCE(excluded.hash, entities.hash),
         updated_at = excluded.updated_at
+      WHERE entities.name IS NOT excluded.name
+        OR entities.type IS NOT excluded.type
+        OR entities.file_path IS NOT excluded.file_path
+        OR entities.location IS NOT excluded.location
+        OR entities.metadata IS NOT excluded.metadata
     `);
+    const existsStmt = this.db.prepare("SELECT 1 FROM entities WHERE id = ?");
 
+    const ids = this.entityIds(entities);
     const seen = new Set<string>();
     const uniq: Entity[] = [];
-    for (const e of entities) {
-      const key = this.entityKey(e);
-      if (!seen.has(key)) {
-        seen.add(key);
-        uniq.push(e);
+    for (let i = 0; i < entities.length; i++) {
+      const id = ids[i]!;
+      if (!seen.has(id)) {
+        seen.add(id);
+        uniq.push({ ...entities[i]!, id });
       }
     }
+    uniq.sort((a, b) => compareIds(a.id, b.id));
 
     // Process in batches
     for (let i = 0; i < uniq.length; i += this.batchSize) {
@@ -115,12 +125,13 @@ export class BatchOperations {
       while (attempts < RETRY_ATTEMPTS && !batchSuccess) {
         try {
           const transaction = this.db.transaction((batch: Entity[]) => {
+            const batchCounts = { created: 0, updated: 0, unchanged: 0 };
             for (const entity of batch) {
               const now = Date.now();
-              const id = this.stableEntityId(entity);
+              const existed = existsStmt.get(entity.id) !== undefined;
 
-              insertStmt.run(
-                id,
+              const { changes } = insertStmt.run(
+                entity.id,
                 entity.name,
                 entity.type,
                 entity.filePath,
@@ -130,10 +141,17 @@ export class BatchOperations {
                 entity.createdAt || now,
                 entity.updatedAt || now,
               );
+              if (!existed) batchCounts.created++;
+              else if (changes > 0) batchCounts.updated++;
+              else batchCounts.unchanged++;
             }
+            return batchCounts;
           });
 
-          transaction(batch);
+          const batchCounts = transaction(batch);
+          counts.created += batchCounts.created;
+          counts.updated += batchCounts.updated;
+          counts.unchanged += batchCounts.unchanged;
           totalProcessed += batch.length;
           batchSuccess = true;
 
@@ -165,21 +183,24 @@ export class BatchOperations {
       failed: errors.length,
       errors,
       timeMs: Date.now() - start,
+      ...counts,
     };
   }
 
   /**
    * Insert relationships in batches
    * - Local deduplication by relationshipKey
-   * - Stable IDs based on key
+   * - Stable IDs based on key, written in id order
+   * - Existing edges are only rewritten when their metadata changed
    */
   async insertRelationships(
     relationships: Relationship[],
     onProgress?: (processed: number, total: number) => void,
-  ): Promise<BatchResult> {
+  ): Promise<UpsertResult> {
     const start = Date.now();
     const errors: Array<{ item: unknown; error: string }> = [];
     let totalProcessed = 0;
+    const counts = { created: 0, updated: 0, unchanged: 0 };
 
     const insertStmt = this.db.prepare(`
       INSERT INTO relationships
@@ -187,7 +208,9 @@ export class BatchOperations {
       VALUES (?, ?, ?, ?, ?)
       ON CONFLICT(id) DO UPDATE SET
         metadata = COALESCE(excluded.metadata, relationships.metadata)
+      WHERE excluded.metadata IS NOT NULL AND excluded.metadata IS NOT relationships.metadata
     `);
+    const existsStmt = this.db.prepare("SELECT 1 FROM relationships WHERE id = ?");
 
     const seen = new Set<string>();
     const uniq: Relationship[] = [];
@@ -198,6 +221,8 @@ export class BatchOperations {
         uniq.push(r);
       }
     }
+    const ids = new Map(uniq.map((r) => [r, this.stableRelationshipId(r)]));
+    uniq.sort((a, b) => compareIds(ids.get(a)!, ids.get(b)!));
 
     // Process in batches
     for (let i = 0; i < uniq.length; i += this.batchSize) {
@@ -209,13 +234,23 @@ export class BatchOperations {
       while (attempts < RETRY_ATTEMPTS && !batchSuccess) {
         try {
           const transaction = this.db.transaction((batch: Relationship[]) => {
+            const batchCounts = { created: 0, updated: 0, unchanged: 0 };
             for (const rel of batch) {
               const id = this.stableRelationshipId({ fromId: rel.fromId, toId: rel.toId, type: rel.type });
-              insertStmt.run(id, rel.fromId, rel.toId, rel.type, rel.metadata ? JSON.stringify(rel.metadata) : null);
+              const existed = existsStmt.get(id) !== undefined;
+              const metadata = rel.metadata ? JSON.stringify(rel.metadata) : null;
+              const { changes } = insertStmt.run(id, rel.fromId, rel.toId, rel.type, metadata);
+              if (!existed) batchCounts.created++;
+              else if (changes > 0) batchCounts.updated++;
+              else batchCounts.unchanged++;
             }
+            return batchCounts;
           });
 
-          transaction(batch);
+          const batchCounts = transaction(batch);
+          counts.created += batchCounts.created;
+          counts.updated += batchCounts.updated;
+          counts.unchanged += batchCounts.unchanged;
           totalProcessed += batch.length;
           batchSuccess = true;
 
@@ -245,6 +280,7 @@ export class BatchOperations {
       failed: errors.length,
       errors,
       timeMs: Date.now() - start,
+      ...counts,
     };
   }
 
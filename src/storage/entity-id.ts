@@ -0,0 +1,116 @@
+/**
+ * Stable entity ids
+ *
+ * An entity id is the first 12 base64url characters of the SHA-256 of its identity key:
+ *
+ *   <language>|<file>|<kind>|<qualified name>#<ordinal>
+ *
+ * - language: `entity.language` or `metadata.language`, empty when neither is known
+ * - file: path relative to the indexed root with `/` separators; the path as stored when no root
+ *   is known (external placeholders keep their `external://` path)
+ * - kind: entity type
+ * - qualified name: `metadata.qualifiedName` when the analyzer provides one, otherwise
+ *   `Owner.name` for members of a class, struct, impl block or receiver type, otherwise the name
+ * - ordinal: position in declaration order among the file's entities with the same language,
+ *   kind and qualified name (0 for the first); it tells overloads and same-named locals apart
+ *
+ * Offsets are left out of the key, so an edit elsewhere in the file does not renumber the
+ * entities after it, and a relative path gives the same id on every checkout of the repository.
+ * Relationship ids hash `fromId|toId|type`, so they follow entity ids.
+ */
+
+import { createHash } from "node:crypto";
+import { isAbsolute, relative } from "node:path";
+import { type Entity, EntityType } from "../types/storage.js";
+
+export const ENTITY_ID_LENGTH = 12;
+
+type IdentitySource = Pick<Entity, "name" | "type" | "filePath"> &
+  Partial<Pick<Entity, "location" | "metadata" | "language">>;
+
+const OWNER_FIELDS = ["receiver", "implType", "parentClass", "className"] as const;
+
+export function entityQualifiedName(entity: IdentitySource): string {
+  const meta = (entity.metadata ?? {}) as Record<string, unknown>;
+  if (typeof meta.qualifiedName === "string" && meta.qualifiedName) return meta.qualifiedName;
+  for (const field of OWNER_FIELDS) {
+    const owner = meta[field];
+    if (typeof owner === "string" && owner && owner !== entity.name) return `${owner}.${entity.name}`;
+  }
+  return entity.name;
+}
+
+function identityPath(filePath: string, rootDir?: string): string {
+  if (!rootDir || !isAbsolute(filePath)) return filePath.replace(/\\/g, "/");
+  const rel = relative(rootDir, filePath);
+  // Files outside the root keep their own path rather than a `../` chain that depends on the root
+  if (!rel || rel.startsWith("..") || isAbsolute(rel)) return filePath.replace(/\\/g, "/");
+  return rel.replace(/\\/g, "/");
+}
+
+/**
+ * Identity key without the ordinal; entities sharing it are told apart by declaration order
+ */
+export function entityIdentityBase(entity: IdentitySource, rootDir?: string): string {
+  const language = entity.language ?? (entity.metadata as Record<string, unknown> | undefined)?.language;
+  return [
+    typeof language === "string" ? language : "",
+    identityPath(entity.filePath, rootDir),
+    String(entity.type),
+    entityQualifiedName(entity),
+  ].join("|");
+}
+
+export function stableEntityId(entity: IdentitySource, ordinal = 0, rootDir?: string): string {
+  const key = `${entityIdentityBase(entity, rootDir)}#${ordinal}`;
+  return createHash("sha256").update(key).digest("base64url").slice(0, ENTITY_ID_LENGTH);
+}
+
+/**
+ * Order of ids as SQLite compares them (bytewise). Rows are written and read in this order so the
+ * stored graph does not reflect which file, or which worker, came first.
+ */
+export function compareIds(a: string, b: string): number {
+  return a < b ? -1 : a > b ? 1 : 0;
+}
+
+/** Relationship ids depend only on their endpoints and kind, so re-indexing rewrites the same rows */
+export function stableRelationshipId(fromId: string, toId: string, type: string): string {
+  return createHash("sha256").update(`${fromId}|${toId}|${type}`).digest("base64url").slice(0, ENTITY_ID_LENGTH);
+}
+
+/**
+ * Id of the placeholder entity standing in for `symbol` from `source` when a relationship target
+ * could not be bound inside its own file. `source` is a module specifier for imports and the
+ * referencing file for unresolved calls.
+ */
+export function externalPlaceholderId(source: string, symbol: string): string {
+  return stableEntityId({ name: symbol, type: EntityType.IMPORT, filePath: `external://${source}` });
+}
+
+/**
+ * Ids for a set of entities, index-aligned with the input. Ordinals are assigned in source order
+ * (start offset, then outermost first), so the result does not depend on the order analyzers
+ * emitted the entities in.
+ */
+export function assignStableEntityIds(entities: IdentitySource[], rootDir?: string): string[] {
+  const order = entities.map((_, i) => i);
+  order.sort((a, b) => {
+    const x = entities[a]!.location;
+    const y = entities[b]!.location;
+    return (
+      (x?.start?.index ?? 0) - (y?.start?.index ?? 0) || (y?.end?.index ?? 0) - (x?.end?.index ?? 0) || a - b
+    );
+  });
+
+  const seen = new Map<string, number>();
+  const ids: string[] = new Array(entities.length);
+  for (const i of order) {
+    const entity = entities[i]!;
+    const base = entityIdentityBase(entity, rootDir);
+    const ordinal = seen.get(base) ?? 0;
+    seen.set(base, ordinal + 1);
+    ids[i] = stableEntityId(entity, ordinal, rootDir);
+  }
+  return ids;
+}
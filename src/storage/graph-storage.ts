@@ -21,16 +21,28 @@ import type Database from "better-sqlite3";
 import { nanoid } from "nanoid";
 import type {
   BatchResult,
+  CompactionResult,
+  DanglingRelationship,
   Entity,
   EntityType,
   FileInfo,
+  GraphContentBreakdown,
   GraphQuery,
   GraphQueryResult,
+  GraphSnapshot,
   GraphStorage,
+  IndexRecovery,
+  IndexRun,
+  IndexRunStatus,
   Relationship,
   RelationType,
+  SearchScope,
+  SnapshotEntity,
+  SnapshotRelationship,
   StorageMetrics,
 } from "../types/storage.js";
+import { assignStableEntityIds, compareIds, stableEntityId } from "./entity-id.js";
+import { searchScopeSql } from "./search-filters.js";
 import type { SQLiteManager } from "./sqlite-manager.js";
 
 // =============================================================================
@@ -40,8 +52,25 @@ const ID_LENGTH = 12;
 const DEFAULT_QUERY_LIMIT = 100;
 const MAX_QUERY_LIMIT = 1000;
 const MAX_SUBGRAPH_DEPTH = 5;
+/** The main graph's tables, or those of the test files indexed apart from it (indexer.includeTests) */
+type EntityTable = "entities" | "test_entities";
+type RelationshipTable = "relationships" | "test_relationships";
 const SIMPLE_LITERAL_REGEX_META_CHARS = new Set([".", "*", "+", "?", "^", "$", "{", "}", "(", ")", "|", "[", "]"]);
 
+/**
+ * Whether another process with this pid is running. Our own pid counts as gone: recovery runs at
+ * startup, so a journal row carrying it was left by an earlier process (containers reuse pid 1).
+ */
+function processAlive(pid: number): boolean {
+  if (pid === process.pid) return false;
+  try {
+    process.kill(pid, 0);
+    return true;
+  } catch (error) {
+    return (error as NodeJS.ErrnoException).code === "EPERM";
+  }
+}
+
 // =============================================================================
 // 3. GRAPH STORAGE IMPLEMENTATION
 // =============================================================================
@@ -151,8 +180,8 @@ export class GraphStorageImpl implements GraphStorage {
 
     this.statements.updateFile = this.db.prepare(`
       INSERT OR REPLACE INTO files
-      (path, hash, last_indexed, entity_count)
-      VALUES (?, ?, ?, ?)
+      (path, hash, last_indexed, entity_count, mtime_ms)
+      VALUES (?, ?, ?, ?, ?)
     `);
 
     this.statements.getFile = this.db.prepare(`
@@ -176,7 +205,7 @@ export class GraphStorageImpl implements GraphStorage {
       () => {
         const now = Date.now();
 
-        const id = this.stableEntityId(entity);
+        const id = entity.id || stableEntityId(entity);
 
         // Calculate complexity score and language if not provided
         const complexityScore = entity.complexityScore ?? this.calculateComplexity(entity);
@@ -211,21 +240,23 @@ export class GraphStorageImpl implements GraphStorage {
         const errors: Array<{ item: unknown; error: string }> = [];
         let processed = 0;
 
+        const derived = assignStableEntityIds(entities);
         const seen = new Set<string>();
         const uniq: Entity[] = [];
-        for (const e of entities) {
-          const key = this.entityKey(e);
-          if (!seen.has(key)) {
-            seen.add(key);
-            uniq.push(e);
+        for (let i = 0; i < entities.length; i++) {
+          const id = entities[i]!.id || derived[i]!;
+          if (!seen.has(id)) {
+            seen.add(id);
+            uniq.push({ ...entities[i]!, id });
           }
         }
+        uniq.sort((a, b) => compareIds(a.id, b.id));
 
         const tx = this.db.transaction((items: Entity[]) => {
           for (const entity of items) {
             try {
               const now = Date.now();
-              const id = this.stableEntityId(entity);
+              const id = entity.id;
 
               // Calculate enhanced fields
               const complexityScore = entity.complexityScore ?? this.calculateComplexity(entity);
@@ -313,6 +344,124 @@ export class GraphStorageImpl implements GraphStorage {
     return row ? this.rowToEntity(row) : null;
   }
 
+  /**
+   * BM25-ranked lookup in the `entity_search` full-text index. `match` is an FTS5 query string.
+   * Scores are negated bm25 values, so higher means more relevant.
+   */
+  async searchEntitiesByKeyword(
+    match: string,
+    limit = 50,
+    scope?: SearchScope,
+  ): Promise<Array<{ entity: Entity; score: number }>> {
+    this.ensureReady();
+    try {
+      const scoped = searchScopeSql(scope, {
+        kind: "e.type",
+        path: "e.file_path",
+        root: "json_extract(e.metadata, '$.root')",
+        name: "e.name",
+        anonymous: "json_extract(e.metadata, '$.isAnonymous')",
+        test: "json_extract(e.metadata, '$.isTest')",
+        visibility: "json_extract(e.metadata, '$.visibility')",
+      });
+      // Column weights: name, qualified_name, signature, docstring
+      const rows = this.db
+        .prepare(`
+        SELECT e.*, bm25(entity_search, 10.0, 6.0, 2.0, 1.0) AS rank
+        FROM entity_search
+        JOIN entities e ON e.rowid = entity_search.rowid
+        WHERE entity_search MATCH ? ${scoped ? `AND ${scoped.sql}` : ""}
+        ORDER BY rank
+        LIMIT ?
+      `)
+        .all(match, ...(scoped?.params ?? []), Math.min(Math.max(1, limit), MAX_QUERY_LIMIT)) as any[];
+      return rows.map((row) => ({ entity: this.rowToEntity(row), score: -Number(row.rank) }));
+    } catch (error) {
+      // Databases that predate the index, or a query FTS5 refuses to parse
+      console.warn("[GraphStorage] Keyword search unavailable:", error instanceof Error ? error.message : error);
+      return [];
+    }
+  }
+
+  /**
+   * Look up previously generated vectors by content hash for one provider/model identity.
+   * Only hashes that were found appear in the returned map.
+   */
+  async getCachedEmbeddings(providerKey: string, hashes: string[]): Promise<Map<string, Float32Array>> {
+    this.ensureReady();
+    const found = new Map<string, Float32Array>();
+    if (hashes.length === 0) return found;
+
+    try {
+      const select = this.db.prepare(
+        "SELECT content_hash, dimension, vector FROM embedding_cache WHERE provider_key = ? AND content_hash = ?",
+      );
+      const touch = this.db.prepare(
+        "UPDATE embedding_cache SET last_used_at = ? WHERE provider_key = ? AND content_hash = ?",
+      );
+      const now = Date.now();
+      const lookup = this.db.transaction((keys: string[]) => {
+        for (const hash of new Set(keys)) {
+          const row = select.get(providerKey, hash) as
+            | { content_hash: string; dimension: number; vector: Buffer }
+            | undefined;
+          if (!row || row.vector.byteLength !== row.dimension * Float32Array.BYTES_PER_ELEMENT) continue;
+          // Copy out of the driver's buffer; it may be a view over a shared pool
+          found.set(row.content_hash, new Float32Array(new Uint8Array(row.vector).buffer));
+          touch.run(now, providerKey, hash);
+        }
+      });
+      lookup(hashes);
+    } catch (error) {
+      // Databases that predate the cache table simply miss
+      console.warn("[GraphStorage] Embedding cache lookup failed:", error instanceof Error ? error.message : error);
+    }
+    return found;
+  }
+
+  async putCachedEmbeddings(
+    providerKey: string,
+    entries: Array<{ hash: string; vector: Float32Array }>,
+  ): Promise<void> {
+    this.ensureReady();
+    if (entries.length === 0) return;
+
+    try {
+      const upsert = this.db.prepare(`
+        INSERT INTO embedding_cache (provider_key, content_hash, dimension, vector, created_at, last_used_at)
+        VALUES (?, ?, ?, ?, ?, ?)
+        ON CONFLICT(provider_key, content_hash) DO UPDATE SET
+          dimension = excluded.dimension,
+          vector = excluded.vector,
+          last_used_at = excluded.last_used_at
+      `);
+      const now = Date.now();
+      const store = this.db.transaction((items: Array<{ hash: string; vector: Float32Array }>) => {
+        for (const { hash, vector } of items) {
+          const blob = Buffer.from(vector.buffer, vector.byteOffset, vector.byteLength);
+          upsert.run(providerKey, hash, vector.length, blob, now, now);
+        }
+      });
+      store(entries);
+    } catch (error) {
+      console.warn("[GraphStorage] Embedding cache write failed:", error instanceof Error ? error.message : error);
+    }
+  }
+
+  /**
+   * Drop cached vectors produced by any other provider/model; they can never be reused.
+   * Returns the number of rows removed.
+   */
+  async pruneEmbeddingCache(keepProviderKey: string): Promise<number> {
+    this.ensureReady();
+    try {
+      return this.db.prepare("DELETE FROM embedding_cache WHERE provider_key <> ?").run(keepProviderKey).changes;
+    } catch (error) {
+      console.warn("[GraphStorage] Embedding cache prune failed:", error instanceof Error ? error.message : error);
+      return 0;
+    }
+  }
+
   private isRegexSourceCharEscaped(source: string, index: number): boolean {
     let backslashes = 0;
     for (let i = index - 1; i >= 0 && source[i] === "\\"; i--) {
@@ -363,8 +512,13 @@ export class GraphStorageImpl implements GraphStorage {
   }
 
   async findEntities(query: GraphQuery): Promise<Entity[]> {
+    return this.selectEntities("entities", query);
+  }
+
+  /** `findEntities` over the main graph or over the test files kept apart from it */
+  private selectEntities(table: EntityTable, query: GraphQuery): Entity[] {
     this.ensureReady();
-    let sql = "SELECT * FROM entities WHERE 1=1";
+    let sql = `SELECT * FROM ${table} WHERE 1=1`;
     const params: any[] = [];
     let postFilterNameRegex: RegExp | undefined;
 
@@ -410,8 +564,37 @@ export class GraphStorageImpl implements GraphStorage {
           params.push(query.filters.name);
         }
       }
+
+      const scope = searchScopeSql(query.filters.scope, {
+        kind: "type",
+        path: "file_path",
+        root: "json_extract(metadata, '$.root')",
+        name: "name",
+        anonymous: "json_extract(metadata, '$.isAnonymous')",
+        test: "json_extract(metadata, '$.isTest')",
+        visibility: "json_extract(metadata, '$.visibility')",
+      });
+      if (scope) {
+        sql += ` AND ${scope.sql}`;
+        params.push(...scope.params);
+      }
+
+      const author = query.filters.author?.trim().toLowerCase();
+      if (author) {
+        sql +=
+          " AND (LOWER(json_extract(metadata, '$.lastModified.author')) LIKE ? ESCAPE '\\'" +
+          " OR LOWER(json_extract(metadata, '$.lastModified.email')) = ?)";
+        params.push(`%${this.escapeSqlLikeLiteral(author)}%`, author);
+      }
     }
 
+    if (query.afterId !== undefined) {
+      sql += " AND id > ?";
+      params.push(query.afterId);
+    }
+    // Row order follows insertion, which follows whichever file was indexed first; ids do not
+    sql += " ORDER BY id";
+
     // Apply limit and offset
     const requestedLimit = Math.min(query.limit || DEFAULT_QUERY_LIMIT, MAX_QUERY_LIMIT);
     const requestedOffset = query.offset || 0;
@@ -467,6 +650,8 @@ export class GraphStorageImpl implements GraphStorage {
         uniq.push(r);
       }
     }
+    const ids = new Map(uniq.map((r) => [r, this.stableRelationshipId(r)]));
+    uniq.sort((a, b) => compareIds(ids.get(a)!, ids.get(b)!));
 
     const tx = this.db.transaction((rels: Relationship[]) => {
       for (const r of rels) {
@@ -509,9 +694,13 @@ export class GraphStorageImpl implements GraphStorage {
   }
 
   async getRelationshipsForEntity(entityId: string, type?: RelationType): Promise<Relationship[]> {
+    return this.selectRelationshipsFor("relationships", entityId, type);
+  }
+
+  private selectRelationshipsFor(table: RelationshipTable, entityId: string, type?: RelationType): Relationship[] {
     this.ensureReady();
     let sql = `
-      SELECT * FROM relationships 
+      SELECT * FROM ${table} 
       WHERE (from_id = ? OR to_id = ?)
     `;
     const params: any[] = [entityId, entityId];
@@ -520,14 +709,19 @@ export class GraphStorageImpl implements GraphStorage {
       sql += " AND type = ?";
       params.push(type);
     }
+    sql += " ORDER BY id";
 
     const rows = this.db.prepare(sql).all(...params) as any[];
     return rows.map((row) => this.rowToRelationship(row));
   }
 
   async findRelationships(query: GraphQuery): Promise<Relationship[]> {
+    return this.selectRelationships("relationships", query);
+  }
+
+  private selectRelationships(table: RelationshipTable, query: GraphQuery): Relationship[] {
     this.ensureReady();
-    let sql = "SELECT * FROM relationships WHERE 1=1";
+    let sql = `SELECT * FROM ${table} WHERE 1=1`;
     const params: any[] = [];
 
     // Apply filters
@@ -543,7 +737,7 @@ export class GraphStorageImpl implements GraphStorage {
 
     // Apply limit and offset
     const limit = Math.min(query.limit || DEFAULT_QUERY_LIMIT, MAX_QUERY_LIMIT);
-    sql += " LIMIT ? OFFSET ?";
+    sql += " ORDER BY id LIMIT ? OFFSET ?";
     params.push(limit, query.offset || 0);
 
     const rows = this.db.prepare(sql).all(...params) as any[];
@@ -554,23 +748,20 @@ export class GraphStorageImpl implements GraphStorage {
   // 6. FILE OPERATIONS
   // =============================================================================
 
+  /** The files entry is written last, so storing it also closes the file's journaled write */
   async updateFileInfo(info: FileInfo): Promise<void> {
     this.ensureReady();
-    this.statements.updateFile?.run(info.path, info.hash, info.lastIndexed, info.entityCount);
+    this.db.transaction(() => {
+      this.statements.updateFile?.run(info.path, info.hash, info.lastIndexed, info.entityCount, info.mtimeMs ?? null);
+      this.db.prepare("DELETE FROM pending_file_writes WHERE path = ?").run(info.path);
+    })();
   }
 
   async getFileInfo(path: string): Promise<FileInfo | null> {
     this.ensureReady();
     const row = this.statements.getFile?.get(path) as any;
 
-    return row
-      ? {
-          path: row.path,
-          hash: row.hash,
-          lastIndexed: row.last_indexed,
-          entityCount: row.entity_count,
-        }
-      : null;
+    return row ? this.rowToFileInfo(row) : null;
   }
 
   async getOutdatedFiles(since: number): Promise<FileInfo[]> {
@@ -583,12 +774,174 @@ export class GraphStorageImpl implements GraphStorage {
     `)
       .all(since) as any[];
 
-    return rows.map((row) => ({
+    return rows.map((row) => this.rowToFileInfo(row));
+  }
+
+  async listIndexedFiles(): Promise<FileInfo[]> {
+    this.ensureReady();
+    const rows = this.db.prepare("SELECT * FROM files ORDER BY path").all() as any[];
+
+    return rows.map((row) => this.rowToFileInfo(row));
+  }
+
+  private rowToFileInfo(row: any): FileInfo {
+    return {
       path: row.path,
       hash: row.hash,
       lastIndexed: row.last_indexed,
       entityCount: row.entity_count,
-    }));
+      mtimeMs: row.mtime_ms ?? null,
+    };
+  }
+
+  /** Directories entities were indexed from, as recorded in their `root` metadata tag */
+  async listIndexRoots(): Promise<string[]> {
+    this.ensureReady();
+    const rows = this.db
+      .prepare(`
+      SELECT DISTINCT json_extract(metadata, '$.root') AS root FROM entities
+      WHERE json_extract(metadata, '$.root') IS NOT NULL
+      ORDER BY root
+    `)
+      .all() as Array<{ root: string }>;
+    return rows.map((row) => row.root);
+  }
+
+  /**
+   * Functions and methods whose parser-computed cyclomatic complexity is at least `minComplexity`,
+   * most complex first. Entities from parsers that do not compute it are never returned.
+   */
+  async getEntitiesByComplexity(
+    minComplexity: number,
+    options: { limit?: number; pathPrefix?: string } = {},
+  ): Promise<{ entities: Entity[]; total: number }> {
+    this.ensureReady();
+    const complexity = "CAST(json_extract(metadata, '$.cyclomaticComplexity') AS INTEGER)";
+    const prefix = options.pathPrefix?.replace(/[/\\]+$/, "");
+    const where = `${complexity} >= ?${prefix ? " AND (file_path = ? OR file_path LIKE ? ESCAPE '\\')" : ""}`;
+    const params: unknown[] = [minComplexity];
+    if (prefix) params.push(prefix, `${prefix.replace(/[\\%_]/g, (c) => `\\${c}`)}/%`);
+
+    const counted = this.db.prepare(`SELECT COUNT(*) AS count FROM entities WHERE ${where}`).get(...params) as {
+      count: number;
+    };
+    const rows = this.db
+      .prepare(
+        `SELECT * FROM entities WHERE ${where}
+         ORDER BY ${complexity} DESC, file_path, CAST(json_extract(location, '$.start.line') AS INTEGER)
+         LIMIT ?`,
+      )
+      .all(...params, Math.min(options.limit ?? DEFAULT_QUERY_LIMIT, MAX_QUERY_LIMIT)) as any[];
+
+    return { entities: rows.map((row) => this.rowToEntity(row)), total: counted.count };
+  }
+
+  /**
+   * Walk every entity in id order, one page at a time. Keyset paging keeps memory flat on big
+   * graphs and never holds a statement open between pages, so writers are not blocked.
+   */
+  async *iterateEntities(pageSize = 1000): AsyncGenerator<Entity> {
+    this.ensureReady();
+    const page = this.db.prepare("SELECT * FROM entities WHERE id > ? ORDER BY id LIMIT ?");
+    let after = "";
+    while (true) {
+      const rows = page.all(after, pageSize) as any[];
+      for (const row of rows) yield this.rowToEntity(row);
+      if (rows.length < pageSize) return;
+      after = rows[rows.length - 1].id;
+    }
+  }
+
+  /**
+   * Walk relationships in id order like `iterateEntities`. Only edges whose endpoints both exist
+   * are returned; unresolved targets are left out.
+   */
+  async *iterateRelationships(pageSize = 1000): AsyncGenerator<Relationship> {
+    this.ensureReady();
+    const page = this.db.prepare(`
+      SELECT r.* FROM relationships r
+      WHERE r.id > ?
+        AND EXISTS (SELECT 1 FROM entities WHERE id = r.from_id)
+        AND EXISTS (SELECT 1 FROM entities WHERE id = r.to_id)
+      ORDER BY r.id
+      LIMIT ?
+    `);
+    let after = "";
+    while (true) {
+      const rows = page.all(after, pageSize) as any[];
+      for (const row of rows) yield this.rowToRelationship(row);
+      if (rows.length < pageSize) return;
+      after = rows[rows.length - 1].id;
+    }
+  }
+
+  /** Entities, and relationships whose endpoints both exist: what the iterators above walk */
+  async countGraph(): Promise<{ entities: number; relationships: number }> {
+    this.ensureReady();
+    const entities = (this.db.prepare("SELECT COUNT(*) AS count FROM entities").get() as { count: number }).count;
+    const relationships = (
+      this.db
+        .prepare(`
+      SELECT COUNT(*) AS count FROM relationships r
+      WHERE EXISTS (SELECT 1 FROM entities WHERE id = r.from_id)
+        AND EXISTS (SELECT 1 FROM entities WHERE id = r.to_id)
+    `)
+        .get() as { count: number }
+    ).count;
+    return { entities, relationships };
+  }
+
+  /**
+   * `<file>::<qualified name, or name>` keys held by more than one entity. Counted in SQL so a
+   * caller disambiguating them does not have to keep a key for every entity in memory.
+   */
+  async duplicateEntityKeys(): Promise<Set<string>> {
+    this.ensureReady();
+    const rows = this.db
+      .prepare(`
+      SELECT key FROM (
+        SELECT file_path || '::' || CASE
+          WHEN json_type(metadata, '$.qualifiedName') = 'text' AND json_extract(metadata, '$.qualifiedName') <> ''
+          THEN json_extract(metadata, '$.qualifiedName')
+          ELSE name
+        END AS key
+        FROM entities
+      )
+      GROUP BY key
+      HAVING COUNT(*) > 1
+    `)
+      .all() as Array<{ key: string }>;
+    return new Set(rows.map((row) => row.key));
+  }
+
+  /**
+   * Files outside `filePaths` that own relationships pointing at entities inside them.
+   * Re-indexing those files rebuilds edges into code that changed or disappeared.
+   */
+  async findDependentFiles(filePaths: string[]): Promise<string[]> {
+    this.ensureReady();
+    const targets = new Set(filePaths);
+    const dependents = new Set<string>();
+    const chunkSize = 900;
+
+    for (let i = 0; i < filePaths.length; i += chunkSize) {
+      const chunk = filePaths.slice(i, i + chunkSize);
+      const placeholders = chunk.map(() => "?").join(", ");
+      const rows = this.db
+        .prepare(`
+        SELECT DISTINCT src.file_path AS path
+        FROM relationships r
+        JOIN entities tgt ON tgt.id = r.to_id
+        JOIN entities src ON src.id = r.from_id
+        WHERE tgt.file_path IN (${placeholders})
+      `)
+        .all(...chunk) as Array<{ path: string }>;
+      for (const row of rows) {
+        if (!targets.has(row.path)) dependents.add(row.path);
+      }
+    }
+
+    return Array.from(dependents).sort();
   }
 
   /**
@@ -602,6 +955,7 @@ export class GraphStorageImpl implements GraphStorage {
     },
   ): Promise<{
     entitiesDeleted: number;
+    entitiesRemoved: number;
     relationshipsDeleted: number;
     fileInfoDeleted: number;
   }> {
@@ -640,10 +994,15 @@ export class GraphStorageImpl implements GraphStorage {
       }
 
       const ent = this.db.prepare("DELETE FROM entities WHERE file_path = ?").run(fp) as any;
+      this.deleteTestRows(fp);
       const file = this.db.prepare("DELETE FROM files WHERE path = ?").run(fp) as any;
+      this.db.prepare("DELETE FROM pending_file_writes WHERE path = ?").run(fp);
 
+      const entitiesDeleted = ent?.changes ?? 0;
       return {
-        entitiesDeleted: ent?.changes ?? 0,
+        entitiesDeleted,
+        // Rows that will not be re-inserted (deleted minus the preserved ids)
+        entitiesRemoved: removed ? removed.length : entitiesDeleted,
         relationshipsDeleted,
         fileInfoDeleted: file?.changes ?? 0,
       };
@@ -652,6 +1011,327 @@ export class GraphStorageImpl implements GraphStorage {
     return tx(filePath, removedIds);
   }
 
+  /**
+   * Drop what an earlier index of `filePath` stored and the current one no longer produces: entities
+   * missing from `entityIds` (with every edge touching them) and edges leaving the file's entities
+   * that are missing from `relationshipIds`. Called after the current rows were upserted.
+   */
+  async reconcileFileData(
+    filePath: string,
+    current: { entityIds: string[]; relationshipIds: string[] },
+  ): Promise<{ entitiesRemoved: number; relationshipsRemoved: number }> {
+    this.ensureReady();
+
+    const keepEntities = new Set(current.entityIds);
+    const keepRelationships = new Set(current.relationshipIds);
+    const staleEntities = (
+      this.db.prepare("SELECT id FROM entities WHERE file_path = ?").all(filePath) as Array<{ id: string }>
+    )
+      .map((row) => row.id)
+      .filter((id) => !keepEntities.has(id));
+    const staleRelationships = (
+      this.db
+        .prepare("SELECT id FROM relationships WHERE from_id IN (SELECT id FROM entities WHERE file_path = ?)")
+        .all(filePath) as Array<{ id: string }>
+    )
+      .map((row) => row.id)
+      .filter((id) => !keepRelationships.has(id));
+
+    const tx = this.db.transaction(() => {
+      let relationshipsRemoved = 0;
+      const deleteRelationship = this.db.prepare("DELETE FROM relationships WHERE id = ?");
+      for (const id of staleRelationships) relationshipsRemoved += deleteRelationship.run(id).changes;
+
+      const edgesOf = this.db.prepare("DELETE FROM relationships WHERE from_id = ? OR to_id = ?");
+      const deleteEntity = this.db.prepare("DELETE FROM entities WHERE id = ?");
+      let entitiesRemoved = 0;
+      for (const id of staleEntities) {
+        relationshipsRemoved += edgesOf.run(id, id).changes;
+        entitiesRemoved += deleteEntity.run(id).changes;
+      }
+      return { entitiesRemoved, relationshipsRemoved };
+    });
+
+    return tx();
+  }
+
+  /**
+   * Journal that `filePath`'s rows are about to be rewritten. Until `updateFileInfo` stores its
+   * files entry, a crash leaves the journal row behind for `recoverInterruptedIndex`.
+   */
+  async beginFileWrite(filePath: string): Promise<void> {
+    this.ensureReady();
+    this.db
+      .prepare("INSERT OR REPLACE INTO pending_file_writes (path, pid, started_at) VALUES (?, ?, ?)")
+      .run(filePath, process.pid, Date.now());
+  }
+
+  /**
+   * Store a test file's rows apart from the main graph (indexer.includeTests: false), in place of
+   * what the file stored before in either. The placeholders its edges point at are stored with them.
+   */
+  async replaceTestFileData(filePath: string, entities: Entity[], relationships: Relationship[]): Promise<void> {
+    this.ensureReady();
+    const insertEntity = this.db.prepare(`
+      INSERT OR REPLACE INTO test_entities
+      (id, name, type, file_path, location, metadata, hash, created_at, updated_at,
+       complexity_score, language, size_bytes)
+      VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    `);
+    const insertRelationship = this.db.prepare(`
+      INSERT OR REPLACE INTO test_relationships (id, from_id, to_id, type, metadata, weight, created_at)
+      VALUES (?, ?, ?, ?, ?, ?, ?)
+    `);
+
+    this.db.transaction(() => {
+      // Left by an index run that kept tests in the main graph
+      this.db
+        .prepare(
+          `DELETE FROM relationships
+          WHERE from_id IN (SELECT id FROM entities WHERE file_path = ?)
+            OR to_id IN (SELECT id FROM entities WHERE file_path = ?)`,
+        )
+        .run(filePath, filePath);
+      this.db.prepare("DELETE FROM entities WHERE file_path = ?").run(filePath);
+      this.deleteTestRows(filePath);
+
+      const now = Date.now();
+      for (const entity of entities) {
+        insertEntity.run(
+          entity.id,
+          entity.name,
+          entity.type,
+          entity.filePath,
+          JSON.stringify(entity.location),
+          JSON.stringify(entity.metadata),
+          entity.hash,
+          entity.createdAt || now,
+          entity.updatedAt || now,
+          entity.complexityScore ?? this.calculateComplexity(entity),
+          entity.language ?? this.detectLanguage(entity.filePath),
+          entity.sizeBytes ?? 0,
+        );
+      }
+      for (const r of relationships) {
+        insertRelationship.run(
+          this.stableRelationshipId(r),
+          r.fromId,
+          r.toId,
+          r.type,
+          r.metadata ? JSON.stringify(r.metadata) : null,
+          r.weight ?? 1.0,
+          r.createdAt ?? now,
+        );
+      }
+    })();
+  }
+
+  /** Drop what `filePath` stored apart from the main graph; the count of entities removed */
+  async deleteTestFileData(filePath: string): Promise<number> {
+    this.ensureReady();
+    return this.db.transaction(() => this.deleteTestRows(filePath))();
+  }
+
+  /** Whether any test file is stored apart from the main graph */
+  async hasTestsApart(): Promise<boolean> {
+    this.ensureReady();
+    return this.db.prepare("SELECT 1 FROM test_entities LIMIT 1").get() !== undefined;
+  }
+
+  async getTestEntity(id: string): Promise<Entity | null> {
+    this.ensureReady();
+    const row = this.db.prepare("SELECT * FROM test_entities WHERE id = ?").get(id) as any;
+    return row ? this.rowToEntity(row) : null;
+  }
+
+  /** `findEntities` over the test files stored apart from the main graph */
+  async findTestEntities(query: GraphQuery): Promise<Entity[]> {
+    return this.selectEntities("test_entities", query);
+  }
+
+  async getTestRelationshipsForEntity(entityId: string, type?: RelationType): Promise<Relationship[]> {
+    return this.selectRelationshipsFor("test_relationships", entityId, type);
+  }
+
+  async findTestRelationships(query: GraphQuery): Promise<Relationship[]> {
+    return this.selectRelationships("test_relationships", query);
+  }
+
+  /** Store edges leaving test entities kept apart, such as the test linker's; the count stored */
+  async insertTestRelationships(relationships: Relationship[]): Promise<number> {
+    this.ensureReady();
+    const insert = this.db.prepare(`
+      INSERT OR REPLACE INTO test_relationships (id, from_id, to_id, type, metadata, weight, created_at)
+      VALUES (?, ?, ?, ?, ?, ?, ?)
+    `);
+    const now = Date.now();
+    return this.db.transaction(() => {
+      let stored = 0;
+      for (const r of relationships) {
+        const metadata = r.metadata ? JSON.stringify(r.metadata) : null;
+        const id = this.stableRelationshipId(r);
+        stored += insert.run(id, r.fromId, r.toId, r.type, metadata, r.weight ?? 1.0, r.createdAt ?? now).changes;
+      }
+      return stored;
+    })();
+  }
+
+  async deleteTestRelationship(id: string): Promise<void> {
+    this.ensureReady();
+    this.db.prepare("DELETE FROM test_relationships WHERE id = ?").run(id);
+  }
+
+  private deleteTestRows(filePath: string): number {
+    this.db
+      .prepare(
+        `DELETE FROM test_relationships
+        WHERE from_id IN (SELECT id FROM test_entities WHERE file_path = ?)
+          OR to_id IN (SELECT id FROM test_entities WHERE file_path = ?)`,
+      )
+      .run(filePath, filePath);
+    return this.db.prepare("DELETE FROM test_entities WHERE file_path = ?").run(filePath).changes;
+  }
+
+  async startIndexRun(run: {
+    id: string;
+    directory?: string | null;
+    gitHead?: string | null;
+    repository?: string | null;
+    ref?: string | null;
+  }): Promise<void> {
+    this.ensureReady();
+    this.db
+      .prepare(`
+      INSERT OR REPLACE INTO index_runs (id, directory, pid, status, started_at, git_head, repository, ref)
+      VALUES (?, ?, ?, 'running', ?, ?, ?, ?)
+    `)
+      .run(
+        run.id,
+        run.directory ?? null,
+        process.pid,
+        Date.now(),
+        run.gitHead ?? null,
+        run.repository ?? null,
+        run.ref ?? null,
+      );
+  }
+
+  async finishIndexRun(
+    id: string,
+    status: Exclude<IndexRunStatus, "running" | "interrupted">,
+    counts: { filesDone: number; filesTotal: number },
+  ): Promise<void> {
+    this.ensureReady();
+    this.db
+      .prepare("UPDATE index_runs SET status = ?, finished_at = ?, files_done = ?, files_total = ? WHERE id = ?")
+      .run(status, Date.now(), counts.filesDone, counts.filesTotal, id);
+  }
+
+  /** The latest runs, newest first */
+  async listIndexRuns(limit = 20): Promise<IndexRun[]> {
+    this.ensureReady();
+    const rows = this.db
+      .prepare("SELECT * FROM index_runs ORDER BY started_at DESC, id LIMIT ?")
+      .all(Math.max(1, limit)) as any[];
+    return rows.map((row) => this.rowToIndexRun(row));
+  }
+
+  /** Runs another live process (a second server on this database) is still writing */
+  async listForeignIndexRuns(
+    isAlive: (pid: number) => boolean = processAlive,
+  ): Promise<Array<IndexRun & { pid: number }>> {
+    this.ensureReady();
+    const rows = this.db
+      .prepare("SELECT * FROM index_runs WHERE status = 'running' AND pid IS NOT NULL ORDER BY started_at, id")
+      .all() as any[];
+    return rows.filter((row) => isAlive(row.pid)).map((row) => ({ ...this.rowToIndexRun(row), pid: row.pid }));
+  }
+
+  /**
+   * Repair what runs that never finished left behind. Files whose write was cut short lose their
+   * partial rows; files those held edges into, and files an unfinished run wrote before the
+   * cross-file passes could bind them, get their hash cleared so the next update re-indexes them.
+   * Runs and writes owned by another live process (a second server on the same database) are
+   * left alone; `isAlive` is replaceable for tests.
+   */
+  async recoverInterruptedIndex(isAlive: (pid: number) => boolean = processAlive): Promise<IndexRecovery> {
+    this.ensureReady();
+    const now = Date.now();
+    const abandoned = (pid: number | null) => pid === null || !isAlive(pid);
+
+    const droppedFiles = (
+      this.db.prepare("SELECT path, pid FROM pending_file_writes ORDER BY path").all() as Array<{
+        path: string;
+        pid: number | null;
+      }>
+    )
+      .filter((row) => abandoned(row.pid))
+      .map((row) => row.path);
+    const unfinished = this.db.prepare(`
+      SELECT * FROM index_runs WHERE status != 'completed' AND recovered_at IS NULL ORDER BY started_at, id
+    `);
+    const runs = (unfinished.all() as any[]).filter((row) => row.status !== "running" || abandoned(row.pid ?? null));
+
+    const stale = new Set(await this.findDependentFiles(droppedFiles));
+    const writtenDuring = this.db.prepare("SELECT path FROM files WHERE last_indexed >= ? AND last_indexed <= ?");
+    for (const run of runs) {
+      for (const row of writtenDuring.all(run.started_at, run.finished_at ?? now) as Array<{ path: string }>) {
+        stale.add(row.path);
+      }
+    }
+    for (const path of droppedFiles) {
+      stale.delete(path);
+      await this.deleteFileData(path);
+    }
+    const staleFiles = Array.from(stale)
+      .filter((path) => !path.startsWith("external://"))
+      .sort();
+
+    this.db.transaction(() => {
+      const invalidate = this.db.prepare(
+        "UPDATE files SET hash = '', last_indexed = 0, mtime_ms = NULL WHERE path = ?",
+      );
+      for (const path of staleFiles) invalidate.run(path);
+      const markRecovered = this.db.prepare(`
+        UPDATE index_runs
+        SET recovered_at = ?,
+          status = CASE status WHEN 'running' THEN 'interrupted' ELSE status END,
+          finished_at = COALESCE(finished_at, ?)
+        WHERE id = ?
+      `);
+      for (const run of runs) markRecovered.run(now, now, run.id);
+    })();
+
+    return {
+      droppedFiles,
+      staleFiles,
+      runs: runs.map((row) =>
+        this.rowToIndexRun({
+          ...row,
+          status: row.status === "running" ? "interrupted" : row.status,
+          finished_at: row.finished_at ?? now,
+          recovered_at: now,
+        }),
+      ),
+    };
+  }
+
+  private rowToIndexRun(row: any): IndexRun {
+    return {
+      id: row.id,
+      directory: row.directory ?? null,
+      status: row.status,
+      startedAt: row.started_at,
+      finishedAt: row.finished_at ?? null,
+      filesTotal: row.files_total,
+      filesDone: row.files_done,
+      recoveredAt: row.recovered_at ?? null,
+      gitHead: row.git_head ?? null,
+      repository: row.repository ?? null,
+      ref: row.ref ?? null,
+    };
+  }
+
   // =============================================================================
   // 7. QUERY OPERATIONS
   // =============================================================================
@@ -791,6 +1471,181 @@ export class GraphStorageImpl implements GraphStorage {
     );
   }
 
+  /**
+   * Copy the current entities and relationships under `label`. Relationships whose endpoints are
+   * gone are left out, as in `iterateRelationships`. An existing snapshot with the same label is
+   * only overwritten when `replace` is set.
+   */
+  async createSnapshot(
+    label: string,
+    options: { description?: string; replace?: boolean } = {},
+  ): Promise<GraphSnapshot> {
+    this.ensureReady();
+    if (!options.replace && this.db.prepare("SELECT 1 FROM graph_snapshots WHERE label = ?").get(label)) {
+      throw new Error(`Snapshot "${label}" already exists`);
+    }
+
+    const copy = this.db.transaction((): GraphSnapshot => {
+      this.deleteSnapshotRows(label);
+      const entityCount = this.db
+        .prepare(`
+        INSERT INTO snapshot_entities (label, id, name, type, file_path, start_line, end_line, signature, body_hash)
+        SELECT ?, id, name, type, file_path,
+          CAST(json_extract(location, '$.start.line') AS INTEGER),
+          CAST(json_extract(location, '$.end.line') AS INTEGER),
+          json_extract(metadata, '$.signature'),
+          json_extract(metadata, '$.bodyHash')
+        FROM entities
+      `)
+        .run(label).changes;
+      const relationshipCount = this.db
+        .prepare(`
+        INSERT INTO snapshot_relationships (label, id, from_id, to_id, type)
+        SELECT ?, r.id, r.from_id, r.to_id, r.type FROM relationships r
+        WHERE EXISTS (SELECT 1 FROM entities WHERE id = r.from_id)
+          AND EXISTS (SELECT 1 FROM entities WHERE id = r.to_id)
+      `)
+        .run(label).changes;
+
+      const snapshot: GraphSnapshot = {
+        label,
+        description: options.description ?? null,
+        createdAt: Date.now(),
+        entityCount,
+        relationshipCount,
+        vectorCount: 0,
+        vectorSource: null,
+      };
+      this.db
+        .prepare(`
+        INSERT INTO graph_snapshots (label, description, created_at, entity_count, relationship_count)
+        VALUES (?, ?, ?, ?, ?)
+      `)
+        .run(label, snapshot.description, snapshot.createdAt, entityCount, relationshipCount);
+      return snapshot;
+    });
+
+    return copy();
+  }
+
+  async getSnapshot(label: string): Promise<GraphSnapshot | null> {
+    this.ensureReady();
+    const row = this.db.prepare("SELECT * FROM graph_snapshots WHERE label = ?").get(label) as any;
+    return row ? this.rowToSnapshot(row) : null;
+  }
+
+  async listSnapshots(): Promise<GraphSnapshot[]> {
+    this.ensureReady();
+    const rows = this.db.prepare("SELECT * FROM graph_snapshots ORDER BY created_at, label").all() as any[];
+    return rows.map((row) => this.rowToSnapshot(row));
+  }
+
+  async deleteSnapshot(label: string): Promise<boolean> {
+    this.ensureReady();
+    return this.db.transaction(() => this.deleteSnapshotRows(label))();
+  }
+
+  async getSnapshotEntities(label: string): Promise<SnapshotEntity[]> {
+    this.ensureReady();
+    const rows = this.db.prepare("SELECT * FROM snapshot_entities WHERE label = ? ORDER BY id").all(label) as any[];
+    return rows.map((row) => ({
+      id: row.id,
+      name: row.name,
+      type: row.type,
+      filePath: row.file_path,
+      startLine: row.start_line ?? null,
+      endLine: row.end_line ?? null,
+      signature: row.signature ?? null,
+      bodyHash: row.body_hash ?? null,
+    }));
+  }
+
+  async getSnapshotRelationships(label: string): Promise<SnapshotRelationship[]> {
+    this.ensureReady();
+    const rows = this.db
+      .prepare("SELECT * FROM snapshot_relationships WHERE label = ? ORDER BY id")
+      .all(label) as any[];
+    return rows.map((row) => ({ id: row.id, fromId: row.from_id, toId: row.to_id, type: row.type }));
+  }
+
+  /**
+   * Keep search vectors with snapshot `label`, so it can still be searched by meaning once the
+   * live index has moved on. Vectors of entities the snapshot does not hold are skipped and earlier
+   * ones are replaced; `source` names the embedder that made them (provider:model:dimension).
+   */
+  async saveSnapshotVectors(
+    label: string,
+    source: string,
+    batches: AsyncIterable<Array<{ entityId: string; vector: Float32Array }>>,
+  ): Promise<GraphSnapshot | null> {
+    this.ensureReady();
+    if (!this.db.prepare("SELECT 1 FROM graph_snapshots WHERE label = ?").get(label)) return null;
+
+    const insert = this.db.prepare(`
+      INSERT OR REPLACE INTO snapshot_vectors (label, entity_id, vector)
+      SELECT ?, ?, ? WHERE EXISTS (SELECT 1 FROM snapshot_entities WHERE label = ? AND id = ?)
+    `);
+    const write = this.db.transaction((batch: Array<{ entityId: string; vector: Float32Array }>) => {
+      for (const { entityId, vector } of batch) {
+        const blob = Buffer.from(vector.buffer, vector.byteOffset, vector.byteLength);
+        insert.run(label, entityId, blob, label, entityId);
+      }
+    });
+
+    this.db.prepare("DELETE FROM snapshot_vectors WHERE label = ?").run(label);
+    for await (const batch of batches) write(batch);
+    const { count } = this.db.prepare("SELECT COUNT(*) AS count FROM snapshot_vectors WHERE label = ?").get(label) as {
+      count: number;
+    };
+    this.db
+      .prepare("UPDATE graph_snapshots SET vector_count = ?, vector_source = ? WHERE label = ?")
+      .run(count, count > 0 ? source : null, label);
+    return this.getSnapshot(label);
+  }
+
+  /** Search vectors kept with snapshot `label`, in entity id order */
+  async *iterateSnapshotVectors(
+    label: string,
+    pageSize = 1000,
+  ): AsyncGenerator<{ entityId: string; vector: Float32Array }> {
+    this.ensureReady();
+    const page = this.db.prepare(`
+      SELECT entity_id, vector FROM snapshot_vectors
+      WHERE label = ? AND entity_id > ?
+      ORDER BY entity_id
+      LIMIT ?
+    `);
+    let after = "";
+    while (true) {
+      const rows = page.all(label, after, pageSize) as Array<{ entity_id: string; vector: Buffer }>;
+      for (const row of rows) {
+        const vector = new Float32Array(row.vector.buffer, row.vector.byteOffset, row.vector.byteLength / 4);
+        yield { entityId: row.entity_id, vector };
+      }
+      if (rows.length < pageSize) return;
+      after = rows[rows.length - 1]!.entity_id;
+    }
+  }
+
+  private deleteSnapshotRows(label: string): boolean {
+    this.db.prepare("DELETE FROM snapshot_entities WHERE label = ?").run(label);
+    this.db.prepare("DELETE FROM snapshot_relationships WHERE label = ?").run(label);
+    this.db.prepare("DELETE FROM snapshot_vectors WHERE label = ?").run(label);
+    return this.db.prepare("DELETE FROM graph_snapshots WHERE label = ?").run(label).changes > 0;
+  }
+
+  private rowToSnapshot(row: any): GraphSnapshot {
+    return {
+      label: row.label,
+      description: row.description ?? null,
+      createdAt: row.created_at,
+      entityCount: row.entity_count,
+      relationshipCount: row.relationship_count,
+      vectorCount: row.vector_count ?? 0,
+      vectorSource: row.vector_source ?? null,
+    };
+  }
+
   // =============================================================================
   // 8. MAINTENANCE OPERATIONS
   // =============================================================================
@@ -805,6 +1660,187 @@ export class GraphStorageImpl implements GraphStorage {
     this.sqliteManager.analyze();
   }
 
+  async compact(options: { vacuum?: boolean } = {}): Promise<CompactionResult> {
+    this.ensureReady();
+    const columns = this.db.prepare("PRAGMA table_info(query_cache)").all() as Array<{ name: string }>;
+    // The query layer's L3 cache can own the table under its own layout (timestamp + ttl)
+    const cacheRowsPurged = columns.some((c) => c.name === "expires_at")
+      ? this.db.prepare("DELETE FROM query_cache WHERE expires_at <= ?").run(Date.now()).changes
+      : 0;
+    return { ...this.sqliteManager.compact(options), cacheRowsPurged };
+  }
+
+  async getContentBreakdown(): Promise<GraphContentBreakdown> {
+    this.ensureReady();
+    const breakdown: GraphContentBreakdown = {
+      files: (this.db.prepare("SELECT COUNT(*) AS count FROM files").get() as { count: number }).count,
+      entitiesByType: {},
+      entitiesByLanguage: {},
+      placeholderEntities: 0,
+      relationshipsByType: {},
+      unresolvedRelationships: { external: 0, dangling: 0, byType: {} },
+      lastUpdatedAt: null,
+    };
+    const add = (counts: Record<string, number>, key: string, n: number) => {
+      counts[key] = (counts[key] ?? 0) + n;
+    };
+
+    // Grouped by file as well: entities stored without a language get the one of their file's extension
+    const entityRows = this.db
+      .prepare(`
+      SELECT type, language, file_path, COUNT(*) AS count FROM entities
+      GROUP BY type, language, file_path
+    `)
+      .all() as Array<{ type: string; language: string | null; file_path: string; count: number }>;
+    for (const row of entityRows) {
+      if (row.file_path.startsWith("external://")) {
+        breakdown.placeholderEntities += row.count;
+        continue;
+      }
+      add(breakdown.entitiesByType, row.type, row.count);
+      add(breakdown.entitiesByLanguage, row.language ?? this.detectLanguage(row.file_path), row.count);
+    }
+
+    const relationshipRows = this.db
+      .prepare(`
+      SELECT r.type,
+        CASE WHEN t.id IS NULL THEN 'dangling' WHEN t.file_path LIKE 'external://%' THEN 'external' END AS unresolved,
+        COUNT(*) AS count
+      FROM relationships r LEFT JOIN entities t ON t.id = r.to_id
+      GROUP BY r.type, unresolved
+    `)
+      .all() as Array<{ type: string; unresolved: "dangling" | "external" | null; count: number }>;
+    for (const row of relationshipRows) {
+      add(breakdown.relationshipsByType, row.type, row.count);
+      if (!row.unresolved) continue;
+      breakdown.unresolvedRelationships[row.unresolved] += row.count;
+      add(breakdown.unresolvedRelationships.byType, row.type, row.count);
+    }
+
+    const updated = this.db
+      .prepare(`
+      SELECT MAX(
+        COALESCE((SELECT MAX(last_indexed) FROM files), 0),
+        COALESCE((SELECT MAX(updated_at) FROM entities), 0)
+      ) AS at
+    `)
+      .get() as { at: number };
+    breakdown.lastUpdatedAt = updated.at > 0 ? updated.at : null;
+    return breakdown;
+  }
+
+  /**
+   * Relationships whose source or target id is not stored, which foreign keys normally prevent: an
+   * index cut short while they were off, or rows written by an older release. Returns the first
+   * `limit` by id along with the total.
+   */
+  async findDanglingRelationships(limit = 100): Promise<{ total: number; relationships: DanglingRelationship[] }> {
+    this.ensureReady();
+    const dangling = `
+      FROM relationships r
+      LEFT JOIN entities f ON f.id = r.from_id
+      LEFT JOIN entities t ON t.id = r.to_id
+      WHERE f.id IS NULL OR t.id IS NULL
+    `;
+    const { total } = this.db.prepare(`SELECT COUNT(*) AS total ${dangling}`).get() as { total: number };
+    const rows = this.db
+      .prepare(
+        `SELECT r.id, r.type, r.from_id, r.to_id, f.id IS NULL AS no_from, t.id IS NULL AS no_to
+        ${dangling} ORDER BY r.id LIMIT ?`,
+      )
+      .all(Math.max(0, limit)) as Array<{
+      id: string;
+      type: string;
+      from_id: string;
+      to_id: string;
+      no_from: number;
+      no_to: number;
+    }>;
+    return {
+      total,
+      relationships: rows.map((row) => ({
+        id: row.id,
+        type: row.type,
+        fromId: row.from_id,
+        toId: row.to_id,
+        missing: row.no_from && row.no_to ? "both" : row.no_from ? "from" : "to",
+      })),
+    };
+  }
+
+  /** Delete every relationship findDanglingRelationships reports; returns how many were removed */
+  async deleteDanglingRelationships(): Promise<number> {
+    this.ensureReady();
+    return this.db
+      .prepare(`
+      DELETE FROM relationships
+      WHERE from_id NOT IN (SELECT id FROM entities) OR to_id NOT IN (SELECT id FROM entities)
+    `)
+      .run().changes;
+  }
+
+  /** The ids among `ids` that an entity is stored under */
+  async filterExistingEntityIds(ids: string[]): Promise<Set<string>> {
+    this.ensureReady();
+    const found = new Set<string>();
+    for (let i = 0; i < ids.length; i += 900) {
+      const chunk = ids.slice(i, i + 900);
+      const rows = this.db
+        .prepare(`SELECT id FROM entities WHERE id IN (${chunk.map(() => "?").join(", ")})`)
+        .all(...chunk) as Array<{ id: string }>;
+      for (const row of rows) found.add(row.id);
+    }
+    return found;
+  }
+
+  /**
+   * Entities whose name matches a LIKE pattern that opens with a literal (`get%`, `g%u%`), any
+   * case. The opening literal seeks in the case-folded name index; rows come in name order.
+   * Placeholders for names outside the index are left out.
+   */
+  async findEntitiesByNamePattern(
+    pattern: string,
+    options: { scope?: SearchScope; limit?: number } = {},
+  ): Promise<Entity[]> {
+    this.ensureReady();
+    const scoped = searchScopeSql(options.scope, {
+      kind: "type",
+      path: "file_path",
+      root: "json_extract(metadata, '$.root')",
+      name: "name",
+      anonymous: "json_extract(metadata, '$.isAnonymous')",
+      test: "json_extract(metadata, '$.isTest')",
+      visibility: "json_extract(metadata, '$.visibility')",
+    });
+    const rows = this.db
+      .prepare(
+        `SELECT * FROM entities
+        WHERE name LIKE ? AND substr(file_path, 1, 11) != 'external://' ${scoped ? `AND ${scoped.sql}` : ""}
+        ORDER BY name COLLATE NOCASE
+        LIMIT ?`,
+      )
+      .all(pattern, ...(scoped?.params ?? []), Math.min(Math.max(1, options.limit ?? 1000), MAX_QUERY_LIMIT)) as any[];
+    return rows.map((row) => this.rowToEntity(row));
+  }
+
+  /** Relationships other than containment pointing at each of `ids`; ids with none are absent */
+  async countIncomingRelationships(ids: string[]): Promise<Map<string, number>> {
+    this.ensureReady();
+    const counts = new Map<string, number>();
+    for (let i = 0; i < ids.length; i += 900) {
+      const chunk = ids.slice(i, i + 900);
+      const rows = this.db
+        .prepare(
+          `SELECT to_id, COUNT(*) AS uses FROM relationships
+          WHERE to_id IN (${chunk.map(() => "?").join(", ")}) AND type != ?
+          GROUP BY to_id`,
+        )
+        .all(...chunk, RelationType.CONTAINS) as Array<{ to_id: string; uses: number }>;
+      for (const row of rows) counts.set(row.to_id, row.uses);
+    }
+    return counts;
+  }
+
   async getMetrics(): Promise<StorageMetrics> {
     return this.measureOperation("get_metrics", async () => {
       const baseMetrics = await this.sqliteManager.getMetrics();
@@ -887,23 +1923,6 @@ export class GraphStorageImpl implements GraphStorage {
     return nanoid(ID_LENGTH);
   }
 
-  /**
-   * Generate stable entity key
-   */
-  private entityKey(e: Entity): string {
-    const s = e.location?.start?.index ?? -1;
-    const eIdx = e.location?.end?.index ?? -1;
-    return `${e.filePath}|${e.type}|${e.name}|${s}-${eIdx}`;
-  }
-
-  /**
-   * Generate stable entity ID
-   */
-  private stableEntityId(e: Entity): string {
-    const key = this.entityKey(e);
-    return createHash("sha256").update(key).digest("base64url").slice(0, ID_LENGTH);
-  }
-
   /**
    * Generate stable relationship key
    */
@@ -1035,11 +2054,33 @@ export class GraphStorageImpl implements GraphStorage {
       case "kt":
       case "kts":
         return "kotlin";
+      case "rb":
+      case "rake":
+      case "gemspec":
+      case "ru":
+        return "ruby";
+      case "php":
+      case "phtml":
+        return "php";
+      case "swift":
+        return "swift";
+      case "scala":
+      case "sc":
+      case "sbt":
+        return "scala";
       case "vba":
       case "bas":
       case "cls":
       case "frm":
         return "vba";
+      case "proto":
+        return "proto";
+      case "sql":
+        return "sql";
+      case "graphql":
+      case "gql":
+      case "graphqls":
+        return "graphql";
       default:
         return "unknown";
     }
@@ -1088,6 +2129,8 @@ export class GraphStorageImpl implements GraphStorage {
     const transaction = this.db.transaction(() => {
       this.db.exec("DELETE FROM relationships");
       this.db.exec("DELETE FROM entities");
+      this.db.exec("DELETE FROM test_relationships");
+      this.db.exec("DELETE FROM test_entities");
       this.db.exec("DELETE FROM files");
       this.db.exec("DELETE FROM query_cache");
     });
@@ -0,0 +1,435 @@
+/**
+ * Portable index archives for export_index / import_index: a copy of the graph database (which
+ * normally holds the vectors too) and, when the vector store lives in a file of its own, that
+ * file, behind a JSON manifest recording the schema version, the embedding model and the
+ * repository and commit the index was built from.
+ *
+ * Layout, gzip-compressed as a whole:
+ *
+ *   "CGRAGIDX" | manifest length (u32 BE) | manifest JSON | file bodies in manifest order
+ *
+ * Every file is listed with its size and SHA-256, and import checks both before touching the live
+ * database. Paths in the index are absolute, so import moves them from the exporting machine's
+ * directory to the local one.
+ */
+
+import { createHash } from "node:crypto";
+import { once } from "node:events";
+import { createReadStream, createWriteStream, existsSync } from "node:fs";
+import { mkdtemp, rename, rm, stat } from "node:fs/promises";
+import { basename, dirname, join, resolve } from "node:path";
+import type { Readable } from "node:stream";
+import { createGunzip, createGzip } from "node:zlib";
+import Database from "better-sqlite3";
+import { migrations } from "./schema-migrations.js";
+
+export const INDEX_ARCHIVE_FORMAT = 1;
+
+const MAGIC = Buffer.from("CGRAGIDX");
+const MAX_MANIFEST_BYTES = 1024 * 1024;
+const GRAPH_FILE = "graph.db";
+const VECTORS_FILE = "vectors.db";
+
+export interface IndexArchiveManifest {
+  format: number;
+  createdAt: string;
+  /** Highest migration applied to the archived graph database */
+  schemaVersion: number;
+  source: {
+    /** Directory the index was built from; the prefix import rewrites */
+    directory: string;
+    /** Remote URL without credentials; null when unknown */
+    repository: string | null;
+    commit: string | null;
+  };
+  /** Provider and model of the stored vectors; null when the index has none */
+  embedding: { provider: string; model: string; dimension: number } | null;
+  counts: { files: number; entities: number; relationships: number; vectors: number };
+  files: Array<{ name: string; bytes: number; sha256: string }>;
+}
+
+export type IndexArchiveErrorReason =
+  | "invalid_archive"
+  | "checksum_mismatch"
+  | "incompatible_schema"
+  | "corrupt_database";
+
+export class IndexArchiveError extends Error {
+  constructor(
+    message: string,
+    readonly reason: IndexArchiveErrorReason,
+    readonly details: Record<string, unknown> = {},
+  ) {
+    super(message);
+    this.name = "IndexArchiveError";
+  }
+}
+
+/** Schema version this build migrates databases up to */
+export function supportedSchemaVersion(): number {
+  return Math.max(0, ...migrations.map((m) => m.version));
+}
+
+function hasTable(db: Database.Database, table: string): boolean {
+  return Boolean(db.prepare("SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?").get(table));
+}
+
+function countRows(db: Database.Database, table: string): number {
+  if (!hasTable(db, table)) return 0;
+  return (db.prepare(`SELECT COUNT(*) AS count FROM ${table}`).get() as { count: number }).count;
+}
+
+function schemaVersionOf(db: Database.Database): number {
+  if (!hasTable(db, "migrations")) return 0;
+  const row = db.prepare("SELECT MAX(version) AS version FROM migrations").get() as { version: number | null };
+  return row.version ?? 0;
+}
+
+function embeddingOf(db: Database.Database): IndexArchiveManifest["embedding"] {
+  if (!hasTable(db, "vector_store_meta")) return null;
+  const row = db.prepare("SELECT value FROM vector_store_meta WHERE key = 'embedding_source'").get() as
+    | { value: string }
+    | undefined;
+  try {
+    const source = row ? JSON.parse(row.value) : null;
+    return source?.model ? { provider: source.provider, model: source.model, dimension: source.dimension } : null;
+  } catch {
+    return null;
+  }
+}
+
+async function sha256Of(path: string): Promise<string> {
+  const hash = createHash("sha256");
+  for await (const chunk of createReadStream(path)) hash.update(chunk as Buffer);
+  return hash.digest("hex");
+}
+
+/** Consistent copy of a live database, including pages still in its write-ahead log */
+async function snapshot(source: string, target: string): Promise<void> {
+  const db = new Database(source, { readonly: true, fileMustExist: true });
+  try {
+    await db.backup(target);
+  } finally {
+    db.close();
+  }
+}
+
+export interface ExportIndexOptions {
+  /** Archive to write; replaced only once it is complete */
+  path: string;
+  graphDb: string;
+  /** Vector store file when it is not the graph database */
+  vectorDb?: string;
+  source: IndexArchiveManifest["source"];
+}
+
+/** Write an archive of the index; the live databases stay usable while it is taken */
+export async function exportIndex(
+  options: ExportIndexOptions,
+): Promise<{ path: string; bytes: number; manifest: IndexArchiveManifest }> {
+  const target = resolve(options.path);
+  const work = await mkdtemp(join(dirname(target), ".index-export-"));
+  try {
+    const copies: Array<{ name: string; path: string }> = [{ name: GRAPH_FILE, path: join(work, GRAPH_FILE) }];
+    await snapshot(options.graphDb, copies[0]!.path);
+    const separateVectors = options.vectorDb && resolve(options.vectorDb) !== resolve(options.graphDb);
+    if (separateVectors && existsSync(options.vectorDb!)) {
+      copies.push({ name: VECTORS_FILE, path: join(work, VECTORS_FILE) });
+      await snapshot(options.vectorDb!, copies[1]!.path);
+    }
+
+    // Copies are opened writable: a WAL-mode file cannot always be opened read-only without its -shm
+    const graph = new Database(copies[0]!.path);
+    const vectors = copies[1] ? new Database(copies[1].path) : graph;
+    let manifest: IndexArchiveManifest;
+    try {
+      manifest = {
+        format: INDEX_ARCHIVE_FORMAT,
+        createdAt: new Date().toISOString(),
+        schemaVersion: schemaVersionOf(graph),
+        source: { ...options.source, directory: resolve(options.source.directory) },
+        embedding: embeddingOf(vectors),
+        counts: {
+          files: countRows(graph, "files"),
+          entities: countRows(graph, "entities"),
+          relationships: countRows(graph, "relationships"),
+          vectors: countRows(vectors, "doc_embeddings"),
+        },
+        files: [],
+      };
+    } finally {
+      if (vectors !== graph) vectors.close();
+      graph.close();
+    }
+    for (const copy of copies) {
+      manifest.files.push({ name: copy.name, bytes: (await stat(copy.path)).size, sha256: await sha256Of(copy.path) });
+    }
+
+    const partial = join(work, basename(target));
+    const gzip = createGzip();
+    const out = createWriteStream(partial);
+    gzip.pipe(out);
+    const closed = once(out, "close");
+    const header = Buffer.from(JSON.stringify(manifest));
+    const length = Buffer.alloc(4);
+    length.writeUInt32BE(header.length);
+    gzip.write(Buffer.concat([MAGIC, length, header]));
+    for (const copy of copies) {
+      for await (const chunk of createReadStream(copy.path)) {
+        if (!gzip.write(chunk)) await once(gzip, "drain");
+      }
+    }
+    gzip.end();
+    await closed;
+
+    await rename(partial, target);
+    return { path: target, bytes: (await stat(target)).size, manifest };
+  } finally {
+    await rm(work, { recursive: true, force: true });
+  }
+}
+
+/** Reads exact byte counts from a stream of chunks */
+class ChunkReader {
+  private buffered = Buffer.alloc(0);
+
+  constructor(private readonly chunks: AsyncIterator<Buffer>) {}
+
+  private async fill(): Promise<boolean> {
+    const next = await this.chunks.next();
+    if (next.done) return false;
+    this.buffered = this.buffered.length ? Buffer.concat([this.buffered, next.value]) : next.value;
+    return true;
+  }
+
+  async read(bytes: number): Promise<Buffer> {
+    while (this.buffered.length < bytes) {
+      if (!(await this.fill())) throw new IndexArchiveError("Archive is truncated", "invalid_archive");
+    }
+    const out = this.buffered.subarray(0, bytes);
+    this.buffered = this.buffered.subarray(bytes);
+    return out;
+  }
+
+  /** `bytes` bytes as they arrive, without holding them all in memory */
+  async *take(bytes: number): AsyncGenerator<Buffer> {
+    let left = bytes;
+    while (left > 0) {
+      if (this.buffered.length === 0 && !(await this.fill())) {
+        throw new IndexArchiveError("Archive is truncated", "invalid_archive");
+      }
+      const part = this.buffered.subarray(0, left);
+      this.buffered = this.buffered.subarray(part.length);
+      left -= part.length;
+      yield part;
+    }
+  }
+}
+
+async function openArchive(
+  path: string,
+): Promise<{ stream: Readable; reader: ChunkReader; manifest: IndexArchiveManifest }> {
+  const stream = createReadStream(path).pipe(createGunzip());
+  const reader = new ChunkReader(stream[Symbol.asyncIterator]());
+  let manifest: IndexArchiveManifest;
+  try {
+    if (!(await reader.read(MAGIC.length)).equals(MAGIC)) throw new Error("missing archive header");
+    const length = (await reader.read(4)).readUInt32BE(0);
+    if (length > MAX_MANIFEST_BYTES) throw new Error("manifest too large");
+    manifest = JSON.parse((await reader.read(length)).toString("utf8"));
+  } catch (error) {
+    stream.destroy();
+    throw new IndexArchiveError(
+      `${path} is not an index archive: ${error instanceof Error ? error.message : String(error)}`,
+      "invalid_archive",
+    );
+  }
+  if (manifest.format !== INDEX_ARCHIVE_FORMAT || !Array.isArray(manifest.files)) {
+    stream.destroy();
+    throw new IndexArchiveError(`Unsupported index archive format ${manifest.format}`, "invalid_archive", {
+      format: manifest.format,
+      supported: INDEX_ARCHIVE_FORMAT,
+    });
+  }
+  return { stream, reader, manifest };
+}
+
+/** Manifest of an archive, without unpacking it */
+export async function readIndexArchiveManifest(path: string): Promise<IndexArchiveManifest> {
+  const { stream, manifest } = await openArchive(path);
+  stream.destroy();
+  return manifest;
+}
+
+/** A directory and everything below it moved from `from` to `to`, in one path column or JSON field */
+function rebaseSql(table: string, column: string, field?: string): string {
+  const value = field ? `json_extract(${column}, '${field}')` : column;
+  const moved = `@to || substr(${value}, length(@from) + 1)`;
+  const set = field ? `json_set(${column}, '${field}', ${moved})` : moved;
+  const within = `${value} = @from OR substr(${value}, 1, length(@from) + 1) = @from || '/'`;
+  return `UPDATE ${table} SET ${column} = ${set} WHERE ${within}`;
+}
+
+// Path columns and JSON path fields recorded at index time; tables an old schema lacks are skipped
+const PATH_COLUMNS: Array<[table: string, column: string, field?: string]> = [
+  ["files", "path"],
+  ["entities", "file_path"],
+  ["entities", "metadata", "$.root"],
+  ["snapshot_entities", "file_path"],
+  ["test_entities", "file_path"],
+  ["test_entities", "metadata", "$.root"],
+  ["index_runs", "directory"],
+  ["doc_embeddings", "metadata", "$.path"],
+  ["doc_embeddings", "metadata", "$.root"],
+];
+
+function hasColumn(db: Database.Database, table: string, column: string): boolean {
+  return (db.prepare(`PRAGMA table_info(${table})`).all() as Array<{ name: string }>).some((c) => c.name === column);
+}
+
+function rebasePaths(db: Database.Database, from: string, to: string): number {
+  let rows = 0;
+  db.transaction(() => {
+    for (const [table, column, field] of PATH_COLUMNS) {
+      if (!hasTable(db, table) || !hasColumn(db, table, column)) continue;
+      rows += db.prepare(rebaseSql(table, column, field)).run({ from, to }).changes;
+    }
+  })();
+  return rows;
+}
+
+function checkDatabase(path: string, name: string): void {
+  let db: Database.Database;
+  try {
+    db = new Database(path, { fileMustExist: true });
+  } catch {
+    throw new IndexArchiveError(`${name} in the archive is not a SQLite database`, "corrupt_database", { file: name });
+  }
+  try {
+    const result = db.pragma("quick_check", { simple: true });
+    if (result !== "ok") {
+      throw new IndexArchiveError(`${name} in the archive is damaged: ${result}`, "corrupt_database", { file: name });
+    }
+  } catch (error) {
+    if (error instanceof IndexArchiveError) throw error;
+    throw new IndexArchiveError(`${name} in the archive is not a SQLite database`, "corrupt_database", { file: name });
+  } finally {
+    db.close();
+  }
+}
+
+/** Copy `source` over the live database at `target` in one step, so no reader sees half of it */
+async function restore(source: string, target: string): Promise<void> {
+  const db = new Database(source);
+  try {
+    await db.backup(target, { progress: () => 0 });
+  } finally {
+    db.close();
+  }
+  // The saved ANN index described the replaced vectors
+  await rm(`${target}.hnsw`, { force: true });
+}
+
+export interface ImportIndexOptions {
+  path: string;
+  graphDb: string;
+  vectorDb?: string;
+  /** Local directory the archived paths are moved to */
+  directory: string;
+}
+
+export interface ImportIndexResult {
+  manifest: IndexArchiveManifest;
+  /** null when the archive was built from the same directory */
+  rebased: { from: string; to: string; rows: number } | null;
+  /** false when the archive carried a separate vector file and the vectors here share the graph database */
+  vectorsImported: boolean;
+}
+
+/**
+ * Verify an archive and replace the local index with it. Nothing live is touched until every file
+ * matches its checksum, passes SQLite's quick check and carries a schema this build can read; an
+ * older schema is migrated when the graph storage is next opened.
+ */
+export async function importIndex(options: ImportIndexOptions): Promise<ImportIndexResult> {
+  const { stream, reader, manifest } = await openArchive(options.path);
+  const supported = supportedSchemaVersion();
+  if (manifest.schemaVersion > supported) {
+    stream.destroy();
+    throw new IndexArchiveError(
+      `Archive has schema version ${manifest.schemaVersion}, newer than the ${supported} this server supports; ` +
+        "upgrade the server to import it",
+      "incompatible_schema",
+      { archiveVersion: manifest.schemaVersion, supportedVersion: supported },
+    );
+  }
+
+  const graphDb = resolve(options.graphDb);
+  const work = await mkdtemp(join(dirname(graphDb), ".index-import-"));
+  try {
+    for (const file of manifest.files) {
+      if (file.name !== GRAPH_FILE && file.name !== VECTORS_FILE) {
+        throw new IndexArchiveError(`Archive lists an unknown file ${file.name}`, "invalid_archive");
+      }
+      const hash = createHash("sha256");
+      const out = createWriteStream(join(work, file.name));
+      for await (const chunk of reader.take(file.bytes)) {
+        hash.update(chunk);
+        if (!out.write(chunk)) await once(out, "drain");
+      }
+      out.end();
+      await once(out, "close");
+      if (hash.digest("hex") !== file.sha256) {
+        throw new IndexArchiveError(
+          `${file.name} does not match its checksum; the archive is damaged`,
+          "checksum_mismatch",
+          { file: file.name },
+        );
+      }
+      checkDatabase(join(work, file.name), file.name);
+    }
+    if (!manifest.files.some((file) => file.name === GRAPH_FILE)) {
+      throw new IndexArchiveError("Archive has no graph database", "invalid_archive");
+    }
+
+    const graphCopy = join(work, GRAPH_FILE);
+    const vectorsCopy = manifest.files.some((file) => file.name === VECTORS_FILE) ? join(work, VECTORS_FILE) : null;
+    const from = manifest.source.directory;
+    const to = resolve(options.directory);
+    let rebased: ImportIndexResult["rebased"] = null;
+    for (const copy of [graphCopy, vectorsCopy]) {
+      if (!copy) continue;
+      const db = new Database(copy);
+      try {
+        if (copy === graphCopy && schemaVersionOf(db) !== manifest.schemaVersion) {
+          throw new IndexArchiveError("Archive manifest does not match its graph database", "invalid_archive", {
+            manifestVersion: manifest.schemaVersion,
+            databaseVersion: schemaVersionOf(db),
+          });
+        }
+        if (from !== to) {
+          const rows = rebasePaths(db, from, to);
+          rebased = { from, to, rows: (rebased?.rows ?? 0) + rows };
+        }
+        // Journal and caches of the exporting machine mean nothing here
+        if (hasTable(db, "pending_file_writes")) db.exec("DELETE FROM pending_file_writes");
+        if (hasTable(db, "query_cache")) db.exec("DELETE FROM query_cache");
+      } finally {
+        db.close();
+      }
+    }
+
+    await restore(graphCopy, graphDb);
+    const vectorDb = options.vectorDb ? resolve(options.vectorDb) : graphDb;
+    let vectorsImported = vectorsCopy === null;
+    if (vectorDb !== graphDb && (vectorsCopy || manifest.counts.vectors > 0)) {
+      // Vectors archived inside the graph database reach a separate store as a copy of that database
+      await restore(vectorsCopy ?? graphCopy, vectorDb);
+      vectorsImported = true;
+    }
+    return { manifest, rebased, vectorsImported };
+  } finally {
+    stream.destroy();
+    await rm(work, { recursive: true, force: true });
+  }
+}
@@ -14,13 +14,14 @@
 // =============================================================================
 import { createHash } from "node:crypto";
 import type Database from "better-sqlite3";
+import { isTestFile } from "../parsers/test-extractor.js";
+import { SchemaVersionError } from "../types/errors.js";
 import type { SQLiteManager } from "./sqlite-manager.js";
 
 // =============================================================================
 // 2. CONSTANTS AND CONFIGURATION
 // =============================================================================
 const MIGRATIONS_TABLE = "migrations";
-const CURRENT_VERSION = 2;
 
 // =============================================================================
 // 3. DATA MODELS AND TYPE DEFINITIONS
@@ -29,8 +30,10 @@ const CURRENT_VERSION = 2;
 export interface Migration {
   version: number;
   description: string;
-  up: string;
+  /** SQL, or a function for steps SQLite cannot express idempotently (adding a column) */
+  up: string | ((db: Database.Database) => void);
   down?: string;
+  /** Recorded checksum when it cannot be derived from `up`, e.g. SQL later rewritten as a function */
   checksum?: string;
 }
 
@@ -40,6 +43,25 @@ export interface MigrationRecord {
   checksum: string;
 }
 
+export interface SchemaStatus {
+  /** Newest migration recorded in the database; 0 for a new one */
+  version: number;
+  /** Newest migration this release defines */
+  latestVersion: number;
+  /** Defined migrations not applied yet, in the order they will run */
+  pending: number[];
+  /** Applied migrations whose recorded checksum no longer matches their definition */
+  modified: number[];
+}
+
+/** ALTER TABLE ADD COLUMN has no IF NOT EXISTS; databases created before versioning may have the column */
+export function addColumnIfMissing(db: Database.Database, table: string, column: string, definition: string): void {
+  const columns = db.prepare(`PRAGMA table_info(${table})`).all() as Array<{ name: string }>;
+  if (!columns.some((c) => c.name === column)) {
+    db.exec(`ALTER TABLE ${table} ADD COLUMN ${column} ${definition}`);
+  }
+}
+
 // =============================================================================
 // 4. MIGRATION DEFINITIONS
 // =============================================================================
@@ -157,102 +179,101 @@ export const migrations: Migration[] = [
   {
     version: 2,
     description: "Enhanced schema with optimized indexing and vector integration",
-    up: `
-      -- Enhanced entities table with performance optimizations
-      ALTER TABLE entities ADD COLUMN complexity_score INTEGER DEFAULT 0;
-      ALTER TABLE entities ADD COLUMN language TEXT;
-      ALTER TABLE entities ADD COLUMN size_bytes INTEGER DEFAULT 0;
-
-      -- Additional performance indexes
-      CREATE INDEX IF NOT EXISTS idx_entities_complexity ON entities(complexity_score);
-      CREATE INDEX IF NOT EXISTS idx_entities_language ON entities(language);
-      CREATE INDEX IF NOT EXISTS idx_entities_size ON entities(size_bytes);
-
-      -- Compound indexes for common query patterns
-      CREATE INDEX IF NOT EXISTS idx_entities_file_type ON entities(file_path, type);
-      CREATE INDEX IF NOT EXISTS idx_entities_lang_type ON entities(language, type);
-      CREATE INDEX IF NOT EXISTS idx_entities_updated_type ON entities(updated_at, type);
-
-      -- Enhanced relationships with metadata indexing
-      ALTER TABLE relationships ADD COLUMN weight REAL DEFAULT 1.0;
-      ALTER TABLE relationships ADD COLUMN created_at INTEGER DEFAULT 0;
-
-      -- Performance indexes for relationships
-      CREATE INDEX IF NOT EXISTS idx_rel_weight ON relationships(weight);
-      CREATE INDEX IF NOT EXISTS idx_rel_created ON relationships(created_at);
-      CREATE INDEX IF NOT EXISTS idx_rel_from_to_type ON relationships(from_id, to_id, type);
-
-      -- Vector embeddings table (separate from metadata)
-      DROP TABLE IF EXISTS embeddings;
-      CREATE TABLE IF NOT EXISTS embeddings (
-        id TEXT PRIMARY KEY,
-        entity_id TEXT NOT NULL,
-        content TEXT NOT NULL,
-        metadata TEXT,
-        vector_data BLOB,
-        model_name TEXT NOT NULL DEFAULT 'default',
-        created_at INTEGER NOT NULL,
-        FOREIGN KEY (entity_id) REFERENCES entities(id) ON DELETE CASCADE
-      );
-
-      -- Indexes for embeddings
-      CREATE INDEX IF NOT EXISTS idx_embeddings_entity ON embeddings(entity_id);
-      CREATE INDEX IF NOT EXISTS idx_embeddings_model ON embeddings(model_name);
-      CREATE INDEX IF NOT EXISTS idx_embeddings_created ON embeddings(created_at);
-
-      -- Performance monitoring table
-      CREATE TABLE IF NOT EXISTS performance_metrics (
-        id TEXT PRIMARY KEY,
-        operation TEXT NOT NULL,
-        duration_ms INTEGER NOT NULL,
-        entity_count INTEGER DEFAULT 0,
-        memory_usage INTEGER DEFAULT 0,
-        created_at INTEGER NOT NULL
-      );
-
-      -- Index for performance metrics
-      CREATE INDEX IF NOT EXISTS idx_perf_operation ON performance_metrics(operation);
-      CREATE INDEX IF NOT EXISTS idx_perf_created ON performance_metrics(created_at);
-
-      -- Enhanced query cache with statistics
-      ALTER TABLE query_cache ADD COLUMN miss_count INTEGER DEFAULT 0;
-      ALTER TABLE query_cache ADD COLUMN last_accessed INTEGER;
-
-      -- Index for cache performance analysis
-      CREATE INDEX IF NOT EXISTS idx_cache_accessed ON query_cache(last_accessed);
-      CREATE INDEX IF NOT EXISTS idx_cache_hits ON query_cache(hit_count);
-
-      -- Update existing data with default values
-      UPDATE entities SET
-        complexity_score = 1,
-        language = CASE
-          WHEN file_path LIKE '%.ts' THEN 'typescript'
-          WHEN file_path LIKE '%.js' THEN 'javascript'
-          WHEN file_path LIKE '%.py' THEN 'python'
-          WHEN file_path LIKE '%.java' THEN 'java'
-          WHEN file_path LIKE '%.c' THEN 'c'
-          WHEN file_path LIKE '%.cpp' OR file_path LIKE '%.cc' THEN 'cpp'
-          ELSE 'unknown'
-        END,
-        size_bytes = 0
-      WHERE complexity_score IS NULL OR language IS NULL;
-
-      UPDATE relationships SET
-        weight = 1.0,
-        created_at = 0
-      WHERE weight IS NULL OR created_at IS NULL;
-
-      UPDATE query_cache SET
-        miss_count = 0,
-        last_accessed = created_at
-      WHERE miss_count IS NULL OR last_accessed IS NULL;
-
-      -- Note: PRAGMA settings are applied outside of transaction
-      -- to avoid "Safety level may not be changed inside a transaction" error
-
-      -- Note: ANALYZE commands removed to avoid transaction issues
-      -- They will be run after migrations complete
-    `,
+    up: (db) => {
+      // Enhanced entities table with performance optimizations
+      addColumnIfMissing(db, "entities", "complexity_score", "INTEGER DEFAULT 0");
+      addColumnIfMissing(db, "entities", "language", "TEXT");
+      addColumnIfMissing(db, "entities", "size_bytes", "INTEGER DEFAULT 0");
+
+      // Enhanced relationships with metadata indexing
+      addColumnIfMissing(db, "relationships", "weight", "REAL DEFAULT 1.0");
+      addColumnIfMissing(db, "relationships", "created_at", "INTEGER DEFAULT 0");
+
+      // Enhanced query cache with statistics
+      addColumnIfMissing(db, "query_cache", "miss_count", "INTEGER DEFAULT 0");
+      addColumnIfMissing(db, "query_cache", "last_accessed", "INTEGER");
+
+      db.exec(`
+        -- Additional performance indexes
+        CREATE INDEX IF NOT EXISTS idx_entities_complexity ON entities(complexity_score);
+        CREATE INDEX IF NOT EXISTS idx_entities_language ON entities(language);
+        CREATE INDEX IF NOT EXISTS idx_entities_size ON entities(size_bytes);
+
+        -- Compound indexes for common query patterns
+        CREATE INDEX IF NOT EXISTS idx_entities_file_type ON entities(file_path, type);
+        CREATE INDEX IF NOT EXISTS idx_entities_lang_type ON entities(language, type);
+        CREATE INDEX IF NOT EXISTS idx_entities_updated_type ON entities(updated_at, type);
+
+        -- Performance indexes for relationships
+        CREATE INDEX IF NOT EXISTS idx_rel_weight ON relationships(weight);
+        CREATE INDEX IF NOT EXISTS idx_rel_created ON relationships(created_at);
+        CREATE INDEX IF NOT EXISTS idx_rel_from_to_type ON relationships(from_id, to_id, type);
+
+        -- Vector embeddings table (separate from metadata)
+        DROP TABLE IF EXISTS embeddings;
+        CREATE TABLE IF NOT EXISTS embeddings (
+          id TEXT PRIMARY KEY,
+          entity_id TEXT NOT NULL,
+          content TEXT NOT NULL,
+          metadata TEXT,
+          vector_data BLOB,
+          model_name TEXT NOT NULL DEFAULT 'default',
+          created_at INTEGER NOT NULL,
+          FOREIGN KEY (entity_id) REFERENCES entities(id) ON DELETE CASCADE
+        );
+
+        -- Indexes for embeddings
+        CREATE INDEX IF NOT EXISTS idx_embeddings_entity ON embeddings(entity_id);
+        CREATE INDEX IF NOT EXISTS idx_embeddings_model ON embeddings(model_name);
+        CREATE INDEX IF NOT EXISTS idx_embeddings_created ON embeddings(created_at);
+
+        -- Performance monitoring table
+        CREATE TABLE IF NOT EXISTS performance_metrics (
+          id TEXT PRIMARY KEY,
+          operation TEXT NOT NULL,
+          duration_ms INTEGER NOT NULL,
+          entity_count INTEGER DEFAULT 0,
+          memory_usage INTEGER DEFAULT 0,
+          created_at INTEGER NOT NULL
+        );
+
+        -- Index for performance metrics
+        CREATE INDEX IF NOT EXISTS idx_perf_operation ON performance_metrics(operation);
+        CREATE INDEX IF NOT EXISTS idx_perf_created ON performance_metrics(created_at);
+
+        -- Index for cache performance analysis
+        CREATE INDEX IF NOT EXISTS idx_cache_accessed ON query_cache(last_accessed);
+        CREATE INDEX IF NOT EXISTS idx_cache_hits ON query_cache(hit_count);
+
+        -- Update existing data with default values
+        UPDATE entities SET
+          complexity_score = 1,
+          language = CASE
+            WHEN file_path LIKE '%.ts' THEN 'typescript'
+            WHEN file_path LIKE '%.js' THEN 'javascript'
+            WHEN file_path LIKE '%.py' THEN 'python'
+            WHEN file_path LIKE '%.java' THEN 'java'
+            WHEN file_path LIKE '%.c' THEN 'c'
+            WHEN file_path LIKE '%.cpp' OR file_path LIKE '%.cc' THEN 'cpp'
+            ELSE 'unknown'
+          END,
+          size_bytes = 0
+        WHERE complexity_score IS NULL OR language IS NULL;
+
+        UPDATE relationships SET
+          weight = 1.0,
+          created_at = 0
+        WHERE weight IS NULL OR created_at IS NULL;
+
+        UPDATE query_cache SET
+          miss_count = 0,
+          last_accessed = created_at
+        WHERE miss_count IS NULL OR last_accessed IS NULL;
+      `);
+      // PRAGMA settings and ANALYZE are left to the caller; neither may run inside the migration's transaction
+    },
+    // Checksum of the plain SQL this step used to be, which indexes migrated by earlier releases recorded
+    checksum: "a93eb4135fcc372a00d58f53ba2fde6de0d61cbaee400ba6e4265cf9abe337f7",
     down: `
       -- Remove performance monitoring
       DROP TABLE IF EXISTS performance_metrics;
@@ -292,6 +313,278 @@ export const migrations: Migration[] = [
       DROP INDEX IF EXISTS idx_cache_hits;
     `,
   },
+  {
+    version: 3,
+    description: "Full-text keyword index over entity names, qualified names, signatures and docstrings",
+    up: `
+      -- FTS5 index keyed by the entity rowid; kept in sync by triggers so every write path is covered
+      CREATE VIRTUAL TABLE IF NOT EXISTS entity_search USING fts5(
+        name,
+        qualified_name,
+        signature,
+        docstring,
+        tokenize = 'unicode61'
+      );
+
+      INSERT INTO entity_search (rowid, name, qualified_name, signature, docstring)
+      SELECT rowid, name,
+        COALESCE(json_extract(metadata, '$.qualifiedName'), ''),
+        COALESCE(json_extract(metadata, '$.signature'), ''),
+        COALESCE(json_extract(metadata, '$.docstring'), json_extract(metadata, '$.documentation'), '')
+      FROM entities;
+
+      CREATE TRIGGER IF NOT EXISTS entity_search_insert AFTER INSERT ON entities BEGIN
+        INSERT INTO entity_search (rowid, name, qualified_name, signature, docstring)
+        VALUES (
+          new.rowid,
+          new.name,
+          COALESCE(json_extract(new.metadata, '$.qualifiedName'), ''),
+          COALESCE(json_extract(new.metadata, '$.signature'), ''),
+          COALESCE(json_extract(new.metadata, '$.docstring'), json_extract(new.metadata, '$.documentation'), '')
+        );
+      END;
+
+      CREATE TRIGGER IF NOT EXISTS entity_search_delete AFTER DELETE ON entities BEGIN
+        DELETE FROM entity_search WHERE rowid = old.rowid;
+      END;
+
+      CREATE TRIGGER IF NOT EXISTS entity_search_update AFTER UPDATE ON entities BEGIN
+        DELETE FROM entity_search WHERE rowid = old.rowid;
+        INSERT INTO entity_search (rowid, name, qualified_name, signature, docstring)
+        VALUES (
+          new.rowid,
+          new.name,
+          COALESCE(json_extract(new.metadata, '$.qualifiedName'), ''),
+          COALESCE(json_extract(new.metadata, '$.signature'), ''),
+          COALESCE(json_extract(new.metadata, '$.docstring'), json_extract(new.metadata, '$.documentation'), '')
+        );
+      END;
+    `,
+    down: `
+      DROP TRIGGER IF EXISTS entity_search_insert;
+      DROP TRIGGER IF EXISTS entity_search_delete;
+      DROP TRIGGER IF EXISTS entity_search_update;
+      DROP TABLE IF EXISTS entity_search;
+    `,
+  },
+  {
+    version: 4,
+    description: "Persistent embedding cache keyed by provider identity and content hash",
+    up: `
+      -- Vectors survive graph resets so re-indexing unchanged code skips the embedding provider
+      CREATE TABLE IF NOT EXISTS embedding_cache (
+        provider_key TEXT NOT NULL,
+        content_hash TEXT NOT NULL,
+        dimension INTEGER NOT NULL,
+        vector BLOB NOT NULL,
+        created_at INTEGER NOT NULL,
+        last_used_at INTEGER NOT NULL,
+        PRIMARY KEY (provider_key, content_hash)
+      ) WITHOUT ROWID;
+
+      CREATE INDEX IF NOT EXISTS idx_embedding_cache_used ON embedding_cache(last_used_at);
+    `,
+    down: `
+      DROP INDEX IF EXISTS idx_embedding_cache_used;
+      DROP TABLE IF EXISTS embedding_cache;
+    `,
+  },
+  {
+    version: 5,
+    description: "Labelled graph snapshots for diffing index states",
+    up: `
+      CREATE TABLE IF NOT EXISTS graph_snapshots (
+        label TEXT PRIMARY KEY,
+        description TEXT,
+        created_at INTEGER NOT NULL,
+        entity_count INTEGER NOT NULL DEFAULT 0,
+        relationship_count INTEGER NOT NULL DEFAULT 0
+      );
+
+      -- Only what a diff compares: identity, location, signature and body hash
+      CREATE TABLE IF NOT EXISTS snapshot_entities (
+        label TEXT NOT NULL,
+        id TEXT NOT NULL,
+        name TEXT NOT NULL,
+        type TEXT NOT NULL,
+        file_path TEXT NOT NULL,
+        start_line INTEGER,
+        end_line INTEGER,
+        signature TEXT,
+        body_hash TEXT,
+        PRIMARY KEY (label, id)
+      ) WITHOUT ROWID;
+
+      CREATE TABLE IF NOT EXISTS snapshot_relationships (
+        label TEXT NOT NULL,
+        id TEXT NOT NULL,
+        from_id TEXT NOT NULL,
+        to_id TEXT NOT NULL,
+        type TEXT NOT NULL,
+        PRIMARY KEY (label, id)
+      ) WITHOUT ROWID;
+    `,
+    down: `
+      DROP TABLE IF EXISTS snapshot_relationships;
+      DROP TABLE IF EXISTS snapshot_entities;
+      DROP TABLE IF EXISTS graph_snapshots;
+    `,
+  },
+  {
+    version: 6,
+    description: "Journal of index runs and in-flight file writes, for recovery after an interrupted index",
+    up: `
+      -- status: running until the run ends as completed, cancelled or failed; interrupted when it never did
+      CREATE TABLE IF NOT EXISTS index_runs (
+        id TEXT PRIMARY KEY,
+        directory TEXT,
+        pid INTEGER,
+        status TEXT NOT NULL,
+        started_at INTEGER NOT NULL,
+        finished_at INTEGER,
+        files_total INTEGER NOT NULL DEFAULT 0,
+        files_done INTEGER NOT NULL DEFAULT 0,
+        recovered_at INTEGER
+      );
+
+      -- A row lives from the first write of a file's rows to its files entry; one left over was cut short
+      CREATE TABLE IF NOT EXISTS pending_file_writes (
+        path TEXT PRIMARY KEY,
+        pid INTEGER,
+        started_at INTEGER NOT NULL
+      ) WITHOUT ROWID;
+    `,
+    down: `
+      DROP TABLE IF EXISTS pending_file_writes;
+      DROP TABLE IF EXISTS index_runs;
+    `,
+  },
+  {
+    version: 7,
+    description: "File modification times and the git HEAD of each index run, for detecting a stale index",
+    up: (db) => {
+      addColumnIfMissing(db, "files", "mtime_ms", "INTEGER");
+      addColumnIfMissing(db, "index_runs", "git_head", "TEXT");
+    },
+    down: `
+      ALTER TABLE index_runs DROP COLUMN git_head;
+      ALTER TABLE files DROP COLUMN mtime_ms;
+    `,
+  },
+  {
+    version: 8,
+    description: "Remote repository and ref an index run was cloned from",
+    up: (db) => {
+      addColumnIfMissing(db, "index_runs", "repository", "TEXT");
+      addColumnIfMissing(db, "index_runs", "ref", "TEXT");
+    },
+    down: `
+      ALTER TABLE index_runs DROP COLUMN ref;
+      ALTER TABLE index_runs DROP COLUMN repository;
+    `,
+  },
+  {
+    version: 9,
+    description: "Case-folded entity name index for symbol completion",
+    up: `
+      -- Lets a case-insensitive LIKE 'prefix%' seek instead of scanning every entity
+      CREATE INDEX IF NOT EXISTS idx_entities_name_nocase ON entities(name COLLATE NOCASE);
+    `,
+    down: `
+      DROP INDEX IF EXISTS idx_entities_name_nocase;
+    `,
+  },
+  {
+    version: 10,
+    description: "Search vectors kept with graph snapshots, for searching a past index state",
+    up: (db) => {
+      db.exec(`
+        CREATE TABLE IF NOT EXISTS snapshot_vectors (
+          label TEXT NOT NULL,
+          entity_id TEXT NOT NULL,
+          vector BLOB NOT NULL,
+          PRIMARY KEY (label, entity_id)
+        ) WITHOUT ROWID;
+      `);
+      addColumnIfMissing(db, "graph_snapshots", "vector_count", "INTEGER NOT NULL DEFAULT 0");
+      // provider:model:dimension of the embedder that made them; a query must be embedded alike
+      addColumnIfMissing(db, "graph_snapshots", "vector_source", "TEXT");
+    },
+    down: `
+      ALTER TABLE graph_snapshots DROP COLUMN vector_source;
+      ALTER TABLE graph_snapshots DROP COLUMN vector_count;
+      DROP TABLE IF EXISTS snapshot_vectors;
+    `,
+  },
+  {
+    version: 11,
+    description: "Test-file tag on entities indexed before the tag existed",
+    up: (db) => {
+      // The old trigger dropped the FTS row by rowid after the content row had changed, which FTS5
+      // reports as corruption once metadata is rewritten; the 'delete' command passes the old values
+      db.exec(`
+        DROP TRIGGER IF EXISTS entities_fts_update;
+        CREATE TRIGGER entities_fts_update AFTER UPDATE ON entities BEGIN
+          INSERT INTO entities_fts(entities_fts, rowid, id, name, type, file_path, metadata)
+          VALUES ('delete', old.rowid, old.id, old.name, old.type, old.file_path, old.metadata);
+          INSERT INTO entities_fts(rowid, id, name, type, file_path, metadata)
+          VALUES (new.rowid, new.id, new.name, new.type, new.file_path, new.metadata);
+        END;
+        INSERT INTO entities_fts(entities_fts) VALUES ('rebuild');
+      `);
+
+      // The indexer tags entities of test files from their path; SQL search filters on the tag alone
+      const tag = db.prepare(`
+        UPDATE entities SET metadata = json_set(COALESCE(metadata, '{}'), '$.isTest', json('true'))
+        WHERE file_path = ? AND json_valid(COALESCE(metadata, '{}'))
+      `);
+      const paths = db.prepare("SELECT DISTINCT file_path FROM entities").pluck().all() as string[];
+      for (const path of paths.filter(isTestFile)) tag.run(path);
+    },
+    down: `
+      -- Nothing to undo: the indexer writes the same tag on every reindex, and the fixed trigger suits every version
+    `,
+  },
+  {
+    version: 12,
+    description: "Test files indexed apart from the main graph, for indexer.includeTests: false",
+    up: `
+      -- Rows of entities, without its search index: only the tests view reads them
+      CREATE TABLE IF NOT EXISTS test_entities (
+        id TEXT PRIMARY KEY,
+        name TEXT NOT NULL,
+        type TEXT NOT NULL,
+        file_path TEXT NOT NULL,
+        location TEXT NOT NULL,
+        metadata TEXT,
+        hash TEXT NOT NULL,
+        created_at INTEGER NOT NULL,
+        updated_at INTEGER NOT NULL,
+        complexity_score INTEGER DEFAULT 0,
+        language TEXT,
+        size_bytes INTEGER DEFAULT 0
+      );
+      CREATE INDEX IF NOT EXISTS idx_test_entities_file ON test_entities(file_path);
+      CREATE INDEX IF NOT EXISTS idx_test_entities_name ON test_entities(name);
+
+      -- Edges leaving a test entity; the target may be a declaration of the main graph, so no foreign keys
+      CREATE TABLE IF NOT EXISTS test_relationships (
+        id TEXT PRIMARY KEY,
+        from_id TEXT NOT NULL,
+        to_id TEXT NOT NULL,
+        type TEXT NOT NULL,
+        metadata TEXT,
+        weight REAL DEFAULT 1.0,
+        created_at INTEGER NOT NULL
+      );
+      CREATE INDEX IF NOT EXISTS idx_test_relationships_from ON test_relationships(from_id);
+      CREATE INDEX IF NOT EXISTS idx_test_relationships_to ON test_relationships(to_id);
+    `,
+    down: `
+      DROP TABLE IF EXISTS test_relationships;
+      DROP TABLE IF EXISTS test_entities;
+    `,
+  },
 ];
 
 // =============================================================================
@@ -300,9 +593,17 @@ export const migrations: Migration[] = [
 
 export class SchemaMigration {
   private db: Database.Database;
+  private dbPath: string;
+  private definitions: Migration[];
 
-  constructor(sqliteManager: SQLiteManager) {
+  constructor(sqliteManager: SQLiteManager, definitions: Migration[] = migrations) {
     this.db = sqliteManager.getConnection();
+    this.dbPath = sqliteManager.getPath();
+    this.definitions = [...definitions].sort((a, b) => a.version - b.version);
+  }
+
+  private get latestVersion(): number {
+    return this.definitions.at(-1)?.version ?? 0;
   }
 
   /**
@@ -353,10 +654,34 @@ export class SchemaMigration {
    * Calculate checksum for migration
    */
   private calculateChecksum(migration: Migration): string {
-    const content = `${migration.version}:${migration.description}:${migration.up}`;
+    if (migration.checksum) return migration.checksum;
+    // A function's source changes with every build, so only its identity is hashed
+    const body = typeof migration.up === "string" ? migration.up : "";
+    const content = `${migration.version}:${migration.description}:${body}`;
     return createHash("sha256").update(content).digest("hex");
   }
 
+  /**
+   * Compare the database with the migrations this release defines
+   */
+  status(): SchemaStatus {
+    const history = this.getHistory();
+    const applied = new Set(history.map((record) => record.version));
+    const modified = history
+      .filter((record) => {
+        const migration = this.definitions.find((m) => m.version === record.version);
+        return migration !== undefined && record.checksum !== this.calculateChecksum(migration);
+      })
+      .map((record) => record.version);
+
+    return {
+      version: history[0]?.version ?? 0,
+      latestVersion: this.latestVersion,
+      pending: this.definitions.filter((m) => !applied.has(m.version)).map((m) => m.version),
+      modified: modified.sort((a, b) => a - b),
+    };
+  }
+
   /**
    * Apply a single migration
    */
@@ -367,8 +692,11 @@ export class SchemaMigration {
 
     // Execute migration in a transaction
     const transaction = this.db.transaction(() => {
-      // Run migration SQL
-      this.db.exec(migration.up);
+      if (typeof migration.up === "string") {
+        this.db.exec(migration.up);
+      } else {
+        migration.up(this.db);
+      }
 
       // Record migration
       this.db
@@ -417,31 +745,56 @@ export class SchemaMigration {
 
   /**
    * Run pending migrations
+   *
+   * Refuses a database written by a newer release instead of guessing at its layout, and turns a
+   * failed step into a SchemaVersionError naming it; the step's transaction leaves the schema as it was.
    */
   migrate(): void {
     this.initMigrationsTable();
 
-    const currentVersion = this.getCurrentVersion();
-    const pendingMigrations = migrations.filter((m) => m.version > currentVersion);
+    const status = this.status();
+    if (status.version > status.latestVersion) {
+      throw new SchemaVersionError({
+        dbPath: this.dbPath,
+        databaseVersion: status.version,
+        supportedVersion: status.latestVersion,
+        reason: "newer_schema",
+      });
+    }
 
-    if (pendingMigrations.length === 0) {
+    if (status.modified.length > 0) {
+      console.warn(
+        `[SchemaMigration] Migrations ${status.modified.join(", ")} changed since they were applied; ` +
+          "the index keeps the schema they produced then",
+      );
+    }
+
+    if (status.pending.length === 0) {
       console.log("[SchemaMigration] Database is up to date");
       return;
     }
 
-    console.log(`[SchemaMigration] Running ${pendingMigrations.length} pending migrations`);
-
-    // Sort by version to ensure correct order
-    pendingMigrations.sort((a, b) => a.version - b.version);
-
-    for (const migration of pendingMigrations) {
-      this.applyMigration(migration);
+    console.log(`[SchemaMigration] Running ${status.pending.length} pending migrations`);
+
+    for (const migration of this.definitions.filter((m) => status.pending.includes(m.version))) {
+      try {
+        this.applyMigration(migration);
+      } catch (error) {
+        throw new SchemaVersionError({
+          dbPath: this.dbPath,
+          databaseVersion: this.getCurrentVersion(),
+          supportedVersion: status.latestVersion,
+          reason: "migration_failed",
+          failedMigration: migration.version,
+          cause: error instanceof Error ? error.message : String(error),
+        });
+      }
     }
 
     // Update database version pragma
-    this.db.pragma(`user_version = ${CURRENT_VERSION}`);
+    this.db.pragma(`user_version = ${status.latestVersion}`);
 
-    console.log(`[SchemaMigration] All migrations completed. Database at version ${CURRENT_VERSION}`);
+    console.log(`[SchemaMigration] All migrations completed. Database at version ${status.latestVersion}`);
   }
 
   /**
@@ -455,7 +808,7 @@ export class SchemaMigration {
       return;
     }
 
-    const migrationsToRollback = migrations
+    const migrationsToRollback = this.definitions
       .filter((m) => m.version > targetVersion && m.version <= currentVersion)
       .sort((a, b) => b.version - a.version); // Reverse order for rollback
 
@@ -509,7 +862,7 @@ export class SchemaMigration {
     let valid = true;
 
     for (const record of history) {
-      const migration = migrations.find((m) => m.version === record.version);
+      const migration = this.definitions.find((m) => m.version === record.version);
       if (!migration) {
         console.error(`[SchemaMigration] Migration ${record.version} not found in definitions`);
         valid = false;
@@ -543,7 +896,5 @@ export function runMigrations(sqliteManager: SQLiteManager): void {
  * Check if database needs migration
  */
 export function needsMigration(sqliteManager: SQLiteManager): boolean {
-  const migration = new SchemaMigration(sqliteManager);
-  const currentVersion = migration.getCurrentVersion();
-  return currentVersion < CURRENT_VERSION;
+  return new SchemaMigration(sqliteManager).status().pending.length > 0;
 }
@@ -0,0 +1,184 @@
+/**
+ * Scope filters shared by keyword, vector and structural search. Each filter compiles to a SQL
+ * predicate so it narrows the candidates before ranking and limits are applied; the same rules
+ * are available in memory for results that do not come from a single query.
+ */
+
+import { ANONYMOUS_NAMES, isAnonymousEntity } from "../parsers/anonymous-entities.js";
+import { FILE_EXTENSIONS } from "../parsers/language-configs.js";
+import { isTestEntity } from "../parsers/test-extractor.js";
+import type { SearchScope } from "../types/storage.js";
+
+// Asking for a language should not miss its JSX flavour
+const LANGUAGE_ALIASES: Record<string, string[]> = {
+  typescript: ["typescript", "tsx"],
+  javascript: ["javascript", "jsx"],
+};
+
+/** Entity kinds `content: "docs"` selects; the Markdown analyzer emits these */
+export const DOC_ENTITY_KINDS: readonly string[] = ["document", "heading"];
+
+export function hasSearchScope(scope?: SearchScope | null): scope is SearchScope {
+  return Boolean(
+    scope?.kinds?.length ||
+      scope?.languages?.length ||
+      scope?.pathPrefix ||
+      scope?.directory ||
+      scope?.pathGlob ||
+      scope?.root ||
+      scope?.content ||
+      scope?.excludeAnonymous ||
+      scope?.tests ||
+      scope?.visibility?.length,
+  );
+}
+
+/** File extensions (without the dot) of the requested languages; unknown languages add none */
+export function languageExtensions(languages: string[]): string[] {
+  const wanted = new Set(languages.flatMap((l) => LANGUAGE_ALIASES[l.toLowerCase()] ?? [l.toLowerCase()]));
+  return Object.entries(FILE_EXTENSIONS)
+    .filter(([, language]) => wanted.has(language))
+    .map(([ext]) => ext);
+}
+
+/**
+ * SQLite GLOB for a path glob. `**` segments collapse into `*` (which already crosses
+ * directories in GLOB), and a glob with no leading `/` or `*` matches at any depth.
+ */
+export function toSqliteGlob(glob: string): string {
+  const collapsed = glob
+    .replace(/\\/g, "/")
+    .replace(/\*\*\/?/g, "*")
+    .replace(/\*+/g, "*");
+  return collapsed.startsWith("/") || collapsed.startsWith("*") ? collapsed : `*/${collapsed}`;
+}
+
+function prefixOf(pathPrefix: string): string {
+  return pathPrefix.replace(/\\/g, "/").replace(/\/+$/, "");
+}
+
+/** The path prefixes a scope sets: its pathPrefix and its directory */
+function prefixesOf(scope: SearchScope): string[] {
+  return [scope.pathPrefix, scope.directory].filter((p): p is string => !!p).map(prefixOf);
+}
+
+/**
+ * WHERE fragment (joined with AND, without a leading AND) enforcing `scope` on the given kind,
+ * path, root, name, anonymous-tag, test-tag and visibility columns or expressions. Returns null
+ * when the scope sets no filter; a root, `tests: "only"` or visibility filter without its column
+ * matches nothing.
+ */
+export function searchScopeSql(
+  scope: SearchScope | undefined,
+  columns: {
+    kind: string;
+    path: string;
+    root?: string;
+    name?: string;
+    anonymous?: string;
+    test?: string;
+    visibility?: string;
+  },
+): { sql: string; params: unknown[] } | null {
+  if (!hasSearchScope(scope)) return null;
+  const clauses: string[] = [];
+  const params: unknown[] = [];
+
+  if (scope.kinds?.length) {
+    clauses.push(`${columns.kind} IN (${scope.kinds.map(() => "?").join(",")})`);
+    params.push(...scope.kinds);
+  }
+  if (scope.content) {
+    const kinds = DOC_ENTITY_KINDS.map(() => "?").join(",");
+    // Vectors without a kind are not documentation
+    clauses.push(
+      scope.content === "docs" ? `${columns.kind} IN (${kinds})` : `COALESCE(${columns.kind}, '') NOT IN (${kinds})`,
+    );
+    params.push(...DOC_ENTITY_KINDS);
+  }
+  if (scope.languages?.length) {
+    const exts = languageExtensions(scope.languages);
+    if (exts.length === 0) {
+      clauses.push("0");
+    } else {
+      // GLOB is case-sensitive, so `.C` (C++) and `.c` (C) stay apart
+      clauses.push(`(${exts.map(() => `${columns.path} GLOB ?`).join(" OR ")})`);
+      params.push(...exts.map((ext) => `*.${ext}`));
+    }
+  }
+  for (const prefix of prefixesOf(scope)) {
+    clauses.push(`(${columns.path} = ? OR substr(${columns.path}, 1, ?) = ?)`);
+    params.push(prefix, prefix.length + 1, `${prefix}/`);
+  }
+  if (scope.pathGlob) {
+    clauses.push(`${columns.path} GLOB ?`);
+    params.push(toSqliteGlob(scope.pathGlob));
+  }
+  if (scope.root) {
+    if (columns.root) {
+      clauses.push(`${columns.root} = ?`);
+      params.push(prefixOf(scope.root));
+    } else {
+      clauses.push("0");
+    }
+  }
+  if (scope.excludeAnonymous) {
+    if (columns.name) {
+      clauses.push(`COALESCE(${columns.name}, '') NOT IN (${ANONYMOUS_NAMES.map(() => "?").join(",")})`);
+      params.push(...ANONYMOUS_NAMES);
+    }
+    if (columns.anonymous) clauses.push(`COALESCE(${columns.anonymous}, 0) = 0`);
+  }
+  if (scope.tests) {
+    // The tag is set from the file path when indexing (parsers/test-extractor.ts)
+    if (columns.test) clauses.push(`COALESCE(${columns.test}, 0) ${scope.tests === "only" ? "=" : "<>"} 1`);
+    else if (scope.tests === "only") clauses.push("0");
+  }
+  if (scope.visibility?.length) {
+    if (columns.visibility) {
+      // Entities without the tag (imports, docs, languages without access rules) never match
+      clauses.push(`${columns.visibility} IN (${scope.visibility.map(() => "?").join(",")})`);
+      params.push(...scope.visibility);
+    } else {
+      clauses.push("0");
+    }
+  }
+
+  return clauses.length > 0 ? { sql: clauses.join(" AND "), params } : null;
+}
+
+function globRegExp(glob: string): RegExp {
+  let source = "";
+  for (const ch of toSqliteGlob(glob)) {
+    if (ch === "*") source += ".*";
+    else if (ch === "?") source += ".";
+    else source += ch.replace(/[.+^${}()|[\]\\]/g, "\\$&");
+  }
+  return new RegExp(`^${source}$`);
+}
+
+/** In-memory twin of searchScopeSql for results assembled from several queries */
+export function matchesSearchScope(
+  entity: { type: string; name?: string; filePath: string; metadata?: Record<string, unknown> },
+  scope?: SearchScope,
+): boolean {
+  if (!hasSearchScope(scope)) return true;
+  const path = entity.filePath.replace(/\\/g, "/");
+  if (scope.kinds?.length && !scope.kinds.includes(String(entity.type))) return false;
+  if (scope.content && DOC_ENTITY_KINDS.includes(String(entity.type)) !== (scope.content === "docs")) return false;
+  if (scope.languages?.length) {
+    const exts = languageExtensions(scope.languages);
+    if (!exts.some((ext) => path.endsWith(`.${ext}`))) return false;
+  }
+  for (const prefix of prefixesOf(scope)) {
+    if (path !== prefix && !path.startsWith(`${prefix}/`)) return false;
+  }
+  if (scope.pathGlob && !globRegExp(scope.pathGlob).test(path)) return false;
+  if (scope.root && entity.metadata?.root !== prefixOf(scope.root)) return false;
+  if (scope.excludeAnonymous && isAnonymousEntity({ name: entity.name ?? "", metadata: entity.metadata })) {
+    return false;
+  }
+  if (scope.tests && isTestEntity(entity) !== (scope.tests === "only")) return false;
+  if (scope.visibility?.length && !scope.visibility.includes(String(entity.metadata?.visibility))) return false;
+  return true;
+}
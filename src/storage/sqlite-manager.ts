@@ -13,7 +13,7 @@
  */
 
 import { spawnSync } from "node:child_process";
-import { existsSync, mkdirSync } from "node:fs";
+import { existsSync, mkdirSync, statSync } from "node:fs";
 import { createRequire } from "node:module";
 import { homedir } from "node:os";
 import { dirname, join } from "node:path";
@@ -32,7 +32,7 @@ const WAL_AUTOCHECKPOINT = 1000; // Pages before auto-checkpoint
 const CACHE_SIZE_KB = 64000; // 64MB cache
 const MMAP_SIZE = 30000000000; // 30GB mmap
 const PAGE_SIZE = 4096; // 4KB pages
-const BUSY_TIMEOUT = 5000; // 5 seconds
+const BUSY_TIMEOUT = 10000; // 10 seconds; index batches and VACUUM can hold the write lock this long
 
 // =============================================================================
 // 3. DATA MODELS AND TYPE DEFINITIONS
@@ -43,7 +43,11 @@ export interface SQLiteConfig {
   readonly?: boolean;
   memory?: boolean;
   verbose?: boolean;
+  /** How long a statement waits for another connection's lock before failing with SQLITE_BUSY */
   timeout?: number;
+  busyTimeoutMs?: number;
+  mode?: "WAL" | "DELETE" | "TRUNCATE";
+  synchronous?: "OFF" | "NORMAL" | "FULL";
 }
 
 export interface DatabaseInfo {
@@ -56,6 +60,15 @@ export interface DatabaseInfo {
   walCheckpoint: number;
 }
 
+export interface CompactionStats {
+  /** Database file plus its write-ahead log, before and after */
+  bytesBefore: number;
+  bytesAfter: number;
+  walBytesBefore: number;
+  vacuumed: boolean;
+  durationMs: number;
+}
+
 // =============================================================================
 // 4. SQLITE MANAGER IMPLEMENTATION
 // =============================================================================
@@ -73,9 +86,11 @@ function wrapWithTiming<F extends (...a: any[]) => any>(fn: F, ctx: any, record:
 
 export class SQLiteManager {
   private db: Database.Database | null = null;
-  private config: Required<SQLiteConfig>;
+  private config: Required<Omit<SQLiteConfig, "busyTimeoutMs">>;
   private queryCount = 0;
   private totalQueryTime = 0;
+  /** Connections opened so far, so a reopened database never repeats an earlier write signature */
+  private opened = 0;
 
   private static cachedModule: typeof Database | null = null;
   private static rebuildAttempted = false;
@@ -86,7 +101,9 @@ export class SQLiteManager {
       readonly: config.readonly || false,
       memory: config.memory || false,
       verbose: config.verbose || false,
-      timeout: config.timeout || BUSY_TIMEOUT,
+      timeout: config.busyTimeoutMs ?? config.timeout ?? BUSY_TIMEOUT,
+      mode: config.mode || "WAL",
+      synchronous: config.synchronous || "NORMAL",
     };
   }
 
@@ -114,6 +131,7 @@ export class SQLiteManager {
     const dbPath = this.config.memory ? ":memory:" : this.config.path;
     const DatabaseModule = SQLiteManager.loadBetterSqlite3();
 
+    this.opened += 1;
     this.db = new DatabaseModule(dbPath, {
       readonly: this.config.readonly,
       verbose: this.config.verbose ? console.log : undefined,
@@ -263,11 +281,15 @@ export class SQLiteManager {
 
     // WAL mode and related PRAGMAs require a writable connection.
     if (!this.config.readonly && !this.config.memory) {
-      // WAL mode for concurrent reads during writes
-      this.db.pragma("journal_mode = WAL");
+      // WAL lets queries read while an index run writes; other modes lock readers out during writes
+      const mode = this.db.pragma(`journal_mode = ${this.config.mode}`, { simple: true }) as string;
+      if (mode.toUpperCase() !== this.config.mode) {
+        // e.g. WAL on a network file system that lacks shared memory
+        console.warn(`[SQLiteManager] journal_mode ${this.config.mode} unavailable, using ${mode}`);
+      }
 
       // NORMAL synchronous for balance between safety and speed
-      this.db.pragma("synchronous = NORMAL");
+      this.db.pragma(`synchronous = ${this.config.synchronous}`);
 
       // 4KB page size (optimal for most systems)
       this.db.pragma(`page_size = ${PAGE_SIZE}`);
@@ -365,6 +387,40 @@ export class SQLiteManager {
     console.log("[SQLiteManager] WAL checkpoint completed", result);
   }
 
+  /**
+   * Fold the write-ahead log back into the database and, with `vacuum`, rebuild the file without
+   * the pages freed by deleted entities. VACUUM holds the write lock for its whole run.
+   */
+  compact(options: { vacuum?: boolean } = {}): CompactionStats {
+    const db = this.getConnection();
+    const start = Date.now();
+    const walBytesBefore = this.fileBytes(`${this.config.path}-wal`);
+    const bytesBefore = this.fileBytes(this.config.path) + walBytesBefore;
+
+    db.pragma("wal_checkpoint(TRUNCATE)");
+    const vacuumed = options.vacuum !== false;
+    if (vacuumed) {
+      db.exec("VACUUM");
+      // VACUUM in WAL mode writes the rebuilt pages to the log first
+      db.pragma("wal_checkpoint(TRUNCATE)");
+    }
+    db.pragma("optimize");
+
+    const bytesAfter = this.fileBytes(this.config.path) + this.fileBytes(`${this.config.path}-wal`);
+    const durationMs = Date.now() - start;
+    console.log(`[SQLiteManager] Compacted ${bytesBefore} -> ${bytesAfter} bytes in ${durationMs}ms`);
+    return { bytesBefore, bytesAfter, walBytesBefore, vacuumed, durationMs };
+  }
+
+  private fileBytes(path: string): number {
+    if (this.config.memory) return 0;
+    try {
+      return statSync(path).size;
+    } catch {
+      return 0;
+    }
+  }
+
   /**
    * Get database information
    */
@@ -425,6 +481,22 @@ export class SQLiteManager {
     }
   }
 
+  /**
+   * Changes whenever the database is written: rows changed through this connection, commits of
+   * other connections to the same file (`data_version`), and reopening. Comparing two signatures
+   * tells whether anything was written in between without reading any table.
+   */
+  getWriteSignature(): string {
+    if (!this.db?.open) return `${this.opened}:closed`;
+    const changes = this.db.prepare("SELECT total_changes() AS n").get() as { n: number };
+    return `${this.opened}:${this.db.pragma("data_version", { simple: true })}:${changes.n}`;
+  }
+
+  /** File the connection opens; ":memory:" for an in-memory database */
+  getPath(): string {
+    return this.config.memory ? ":memory:" : this.config.path;
+  }
+
   /**
    * Check if database is open
    */
@@ -0,0 +1,152 @@
+/**
+ * The graph as a walk's `tests` choice sees it. Under indexer.includeTests: false the indexer
+ * stores test files apart from the main graph (GraphStorageImpl.replaceTestFileData), so the main
+ * graph has no tests and `withTests` puts them back for walks that want them. Test files indexed
+ * into the main graph are tagged (`metadata.isTest`, parsers/test-extractor.ts) and `withoutTests`
+ * hides them, so a traversal cannot step onto a test, or through one back into production code.
+ */
+
+import { isTestEntity } from "../parsers/test-extractor.js";
+import { DEFAULT_QUERY_LIMIT } from "../types/query.js";
+import type {
+  BatchResult,
+  Entity,
+  GraphQuery,
+  GraphQueryResult,
+  Relationship,
+  RelationType,
+} from "../types/storage.js";
+import { compareIds } from "./entity-id.js";
+import type { GraphStorageImpl } from "./graph-storage.js";
+
+/**
+ * `storage` as seen without its test entities. Reads of entities and relationships are filtered;
+ * every other member, writes included, goes to `storage` unchanged.
+ */
+export function withoutTests(storage: GraphStorageImpl): GraphStorageImpl {
+  // Entity id → declared in a test file; edges are dropped by either endpoint
+  const tests = new Map<string, boolean>();
+  const note = (entity: Entity): boolean => {
+    const test = isTestEntity(entity);
+    tests.set(entity.id, test);
+    return test;
+  };
+  const isTest = async (id: string): Promise<boolean> => {
+    if (!tests.has(id)) {
+      const entity = await storage.getEntity(id);
+      tests.set(id, entity ? isTestEntity(entity) : false);
+    }
+    return tests.get(id) === true;
+  };
+
+  const entities = (found: Entity[]) => found.filter((entity) => !note(entity));
+  const relationships = async (found: Relationship[]) => {
+    const kept: Relationship[] = [];
+    for (const rel of found) {
+      if (!(await isTest(rel.fromId)) && !(await isTest(rel.toId))) kept.push(rel);
+    }
+    return kept;
+  };
+  // Lets the SQL filter narrow the rows before limits are applied
+  const scoped = (query: GraphQuery): GraphQuery => ({
+    ...query,
+    filters: { ...query.filters, scope: { ...query.filters?.scope, tests: "exclude" } },
+  });
+
+  const reads: Partial<GraphStorageImpl> = {
+    async getEntity(id: string) {
+      const entity = await storage.getEntity(id);
+      return entity && !note(entity) ? entity : null;
+    },
+    async findEntities(query: GraphQuery) {
+      return entities(await storage.findEntities(scoped(query)));
+    },
+    async getRelationshipsForEntity(entityId: string, type?: RelationType) {
+      return relationships(await storage.getRelationshipsForEntity(entityId, type));
+    },
+    async findRelationships(query: GraphQuery) {
+      return relationships(await storage.findRelationships(query));
+    },
+    async executeQuery(query: GraphQuery): Promise<GraphQueryResult> {
+      const result = await storage.executeQuery(scoped(query));
+      return {
+        ...result,
+        entities: entities(result.entities),
+        relationships: await relationships(result.relationships),
+      };
+    },
+  };
+
+  return view(storage, reads);
+}
+
+/**
+ * `storage` with the test files stored apart put back: reads return their entities and edges
+ * next to the main graph's, and edges leaving a stored-apart test (the test linker's) are written
+ * with it. `storage` itself when no test file is stored apart.
+ */
+export async function withTests(storage: GraphStorageImpl): Promise<GraphStorageImpl> {
+  if (!(await storage.hasTestsApart())) return storage;
+
+  // Both tables are read in id order, so a page of either merges into a page of the two
+  const page = (main: Entity[], tests: Entity[], offset: number, limit: number) =>
+    [...main, ...tests].sort((a, b) => compareIds(a.id, b.id)).slice(offset, offset + limit);
+
+  const reads: Partial<GraphStorageImpl> = {
+    async getEntity(id: string) {
+      return (await storage.getEntity(id)) ?? (await storage.getTestEntity(id));
+    },
+    async findEntities(query: GraphQuery) {
+      const offset = query.offset ?? 0;
+      const limit = query.limit || DEFAULT_QUERY_LIMIT;
+      const wide = { ...query, offset: 0, limit: offset + limit };
+      return page(await storage.findEntities(wide), await storage.findTestEntities(wide), offset, limit);
+    },
+    async getRelationshipsForEntity(entityId: string, type?: RelationType) {
+      return [
+        ...(await storage.getRelationshipsForEntity(entityId, type)),
+        ...(await storage.getTestRelationshipsForEntity(entityId, type)),
+      ];
+    },
+    async findRelationships(query: GraphQuery) {
+      return [...(await storage.findRelationships(query)), ...(await storage.findTestRelationships(query))];
+    },
+    async executeQuery(query: GraphQuery): Promise<GraphQueryResult> {
+      const result = await storage.executeQuery(query);
+      // Import lookups by source take their own path through the main graph
+      if ((query.filters as { importSource?: string } | undefined)?.importSource) return result;
+      return {
+        ...result,
+        entities: page(result.entities, await storage.findTestEntities(query), 0, query.limit || DEFAULT_QUERY_LIMIT),
+        relationships: [...result.relationships, ...(await storage.findTestRelationships(query))],
+      };
+    },
+    async insertRelationships(relationships: Relationship[]): Promise<BatchResult> {
+      const apart: Relationship[] = [];
+      const main: Relationship[] = [];
+      for (const rel of relationships) {
+        if (await storage.getTestEntity(rel.fromId)) apart.push(rel);
+        else main.push(rel);
+      }
+      const result = await storage.insertRelationships(main);
+      return { ...result, processed: result.processed + (await storage.insertTestRelationships(apart)) };
+    },
+    async deleteRelationship(id: string) {
+      await storage.deleteRelationship(id);
+      await storage.deleteTestRelationship(id);
+    },
+  };
+
+  return view(storage, reads);
+}
+
+/** `storage` with `overrides` in place of its own members */
+function view(storage: GraphStorageImpl, overrides: Partial<GraphStorageImpl>): GraphStorageImpl {
+  return new Proxy(storage, {
+    get(target, property) {
+      if (Object.hasOwn(overrides, property)) return overrides[property as keyof typeof overrides];
+      const value = Reflect.get(target, property, target);
+      return typeof value === "function" ? value.bind(target) : value;
+    },
+  });
+}
@@ -17,6 +17,8 @@ interface AgentSummary {
   };
   metrics: AgentMetrics;
   currentTaskType?: string;
+  /** Pass to cancel_task to stop the task */
+  currentTaskId?: string;
   lastActivity: number;
 }
 
@@ -77,6 +79,7 @@ function normalizeAgent(agent: Agent): AgentSummary {
     },
     metrics,
     currentTaskType: (agent as any).currentTask?.type,
+    currentTaskId: (agent as any).currentTask?.id,
     lastActivity: metrics.lastActivity,
   };
 }
@@ -0,0 +1,140 @@
+import pLimit from "p-limit";
+import type { ToolEnvelope } from "../utils/tool-response.js";
+
+export const MAX_BATCH_REQUESTS = 50;
+
+/**
+ * Tools that only read the graph, vectors or files, so a batch may run them side by side. Anything
+ * else (indexing, watching, compaction, cancellation, bus and snapshot writes) runs alone, after
+ * the requests before it have finished and before the ones after it start. `metrics` is left out
+ * because it may reset the counters; isReadOnlyRequest looks at its arguments.
+ */
+export const READ_ONLY_TOOLS: ReadonlySet<string> = new Set([
+  "get_version",
+  "diagnose",
+  "index_status",
+  "list_file_entities",
+  "file_summary",
+  "list_entity_relationships",
+  "resolve_entity",
+  "complete_symbol",
+  "get_entity_source",
+  "explain_symbol",
+  "find_definition",
+  "find_references",
+  "list_callers",
+  "list_callees",
+  "call_path",
+  "query",
+  "get_metrics",
+  "semantic_search",
+  "find_similar_code",
+  "find_similar",
+  "detect_code_clones",
+  "jscpd_detect_clones",
+  "suggest_refactoring",
+  "cross_language_search",
+  "analyze_hotspots",
+  "find_related_concepts",
+  "get_graph",
+  "analyze_code_impact",
+  "impact_analysis",
+  "graph_query",
+  "detect_cycles",
+  "module_dependencies",
+  "find_unused",
+  "find_unused_imports",
+  "browse",
+  "list_complex_functions",
+  "diff_graph",
+  "inheritance_hierarchy",
+  "find_overrides",
+  "list_members",
+  "list_routes",
+  "find_by_author",
+  "find_by_signature",
+  "list_env_vars",
+  "list_tables",
+  "list_package_init",
+  "public_api",
+  "list_todos",
+  "tech_debt_report",
+  "find_tests_for",
+  "find_tested_by",
+  "get_task_result",
+  "get_index_progress",
+  "list_module_importers",
+  "graph_stats",
+  "get_graph_stats",
+  "get_graph_health",
+  "get_agent_metrics",
+  "get_bus_stats",
+]);
+
+export type BatchRequest = {
+  tool: string;
+  args?: Record<string, unknown>;
+  /** Caller's label for the request, echoed in its result */
+  id?: string;
+};
+
+/** Whether `request` may run beside others: a read-only tool, or `metrics` without its counter reset */
+export function isReadOnlyRequest(request: BatchRequest): boolean {
+  if (request.tool === "metrics") return request.args?.reset !== true;
+  return READ_ONLY_TOOLS.has(request.tool);
+}
+
+/** A request's envelope (its `meta` carries its own requestId and timing), tagged with its position */
+export type BatchItemResult = {
+  index: number;
+  id?: string;
+  tool: string;
+} & ToolEnvelope<unknown>;
+
+export type BatchResult = {
+  results: BatchItemResult[];
+  succeeded: number;
+  failed: number;
+};
+
+/**
+ * Run `requests` through `execute` and return their envelopes in request order. Consecutive
+ * read-only requests run concurrently, at most `concurrency` at a time; every other request waits
+ * for the ones before it and holds back the ones after it. A failed request fails only its item.
+ */
+export async function runBatch(
+  requests: BatchRequest[],
+  execute: (request: BatchRequest, index: number) => Promise<ToolEnvelope<unknown>>,
+  concurrency = 4,
+): Promise<BatchResult> {
+  const results: BatchItemResult[] = new Array(requests.length);
+  const run = async (index: number) => {
+    const request = requests[index]!;
+    let envelope: ToolEnvelope<unknown>;
+    try {
+      envelope = await execute(request, index);
+    } catch (error) {
+      const message = error instanceof Error ? error.message : String(error);
+      envelope = { success: false, errorType: "tool_error", error: message };
+    }
+    results[index] = { index, ...(request.id ? { id: request.id } : {}), tool: request.tool, ...envelope };
+  };
+
+  const limit = pLimit(Math.max(1, concurrency));
+  let start = 0;
+  while (start < requests.length) {
+    if (!isReadOnlyRequest(requests[start]!)) {
+      await run(start++);
+      continue;
+    }
+    let end = start;
+    while (end < requests.length && isReadOnlyRequest(requests[end]!)) end++;
+    const group: Array<Promise<void>> = [];
+    for (let index = start; index < end; index++) group.push(limit(() => run(index)));
+    await Promise.all(group);
+    start = end;
+  }
+
+  const succeeded = results.filter((result) => result.success).length;
+  return { results, succeeded, failed: results.length - succeeded };
+}
@@ -0,0 +1,147 @@
+import { basename, dirname } from "node:path";
+import { type ContainerKind, isContainer, listContainers } from "../core/package-tree.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { type Entity, RelationType } from "../types/storage.js";
+
+export type BrowseContainer = {
+  /** null for a module, which is not stored as a container of its own */
+  id: string | null;
+  name: string;
+  kind: ContainerKind | "module";
+  /** Directory of a package or directory, the file itself for a module */
+  path: string;
+  /** Go import path, dotted Python package name, or the directory relative to its root */
+  qualifiedName: string;
+};
+
+export type BrowseChild = {
+  id: string;
+  name: string;
+  type: string;
+  /** Set for subpackages and subdirectories */
+  kind?: ContainerKind;
+  filePath: string;
+  line: number | null;
+  /** Immediate children of a subpackage or subdirectory */
+  children?: number;
+};
+
+export type BrowseResult = {
+  /** null when listing the roots of the tree */
+  container: BrowseContainer | null;
+  children: BrowseChild[];
+  total: number;
+  truncated: boolean;
+};
+
+export type BrowseOptions = {
+  /**
+   * Container to open: its id, directory, Go import path or dotted Python package name; an
+   * indexed file lists its own top-level declarations. Omitted, the roots of the tree are listed.
+   */
+  path?: string;
+  /** `path` made absolute against the working directory, tried as a directory or file */
+  resolvedPath?: string;
+  limit?: number;
+};
+
+function describe(container: Entity): BrowseContainer {
+  return {
+    id: container.id,
+    name: container.name,
+    kind: container.metadata.container as ContainerKind,
+    path: container.filePath,
+    qualifiedName: String(container.metadata.qualifiedName ?? container.filePath),
+  };
+}
+
+async function childrenOf(storage: GraphStorageImpl, container: Entity): Promise<Entity[]> {
+  const children: Entity[] = [];
+  for (const rel of await storage.getRelationshipsForEntity(container.id, RelationType.CONTAINS)) {
+    if (rel.fromId !== container.id) continue;
+    const child = await storage.getEntity(rel.toId);
+    if (child) children.push(child);
+  }
+  return children;
+}
+
+async function toChild(storage: GraphStorageImpl, entity: Entity): Promise<BrowseChild> {
+  const child: BrowseChild = {
+    id: entity.id,
+    name: entity.name,
+    type: String(entity.type),
+    filePath: entity.filePath,
+    line: isContainer(entity) ? null : (entity.location?.start?.line ?? null),
+  };
+  if (isContainer(entity)) {
+    child.kind = entity.metadata.container as ContainerKind;
+    child.children = (await storage.getRelationshipsForEntity(entity.id, RelationType.CONTAINS)).filter(
+      (rel) => rel.fromId === entity.id,
+    ).length;
+  }
+  return child;
+}
+
+/** Subcontainers first by name, then declarations by file and line */
+function byTreeOrder(a: Entity, b: Entity): number {
+  const rank = (entity: Entity) => (isContainer(entity) ? 0 : 1);
+  return (
+    rank(a) - rank(b) ||
+    (isContainer(a) ? a.name.localeCompare(b.name) : 0) ||
+    a.filePath.localeCompare(b.filePath) ||
+    (a.location?.start?.line ?? 0) - (b.location?.start?.line ?? 0)
+  );
+}
+
+/**
+ * Immediate children of a package, namespace or directory in the container tree built at index
+ * time: its subpackages and subdirectories, then the top-level declarations of its files. Returns
+ * null when nothing matches `path`.
+ */
+export async function browse(storage: GraphStorageImpl, options: BrowseOptions = {}): Promise<BrowseResult | null> {
+  const limit = Math.max(1, Math.min(5000, Number(options.limit ?? 500) || 500));
+  const containers = await listContainers(storage);
+
+  let container: BrowseContainer | null = null;
+  let entries: Entity[];
+  if (!options.path) {
+    // Roots: containers no other container holds
+    const held = new Set<string>();
+    for (const candidate of containers) {
+      for (const rel of await storage.getRelationshipsForEntity(candidate.id, RelationType.CONTAINS)) {
+        if (rel.fromId === candidate.id) held.add(rel.toId);
+      }
+    }
+    entries = containers.filter((candidate) => !held.has(candidate.id));
+  } else {
+    const wanted = [options.path, options.resolvedPath].filter((value): value is string => Boolean(value));
+    const match =
+      containers.find((candidate) => candidate.id === options.path) ??
+      containers.find((candidate) => wanted.includes(candidate.filePath)) ??
+      containers.find((candidate) => candidate.metadata.qualifiedName === options.path);
+    if (match) {
+      container = describe(match);
+      entries = await childrenOf(storage, match);
+    } else {
+      // A file: the declarations its directory's container holds from it
+      const file = wanted.find((candidate) => containers.some((c) => c.filePath === dirname(candidate)));
+      const parent = file ? containers.find((c) => c.filePath === dirname(file)) : undefined;
+      if (!file || !parent) return null;
+      entries = (await childrenOf(storage, parent)).filter((child) => child.filePath === file);
+      if (entries.length === 0) return null;
+      container = {
+        id: null,
+        name: basename(file),
+        kind: "module",
+        path: file,
+        qualifiedName: `${describe(parent).qualifiedName}/${basename(file)}`,
+      };
+    }
+  }
+
+  entries.sort(byTreeOrder);
+  const page = entries.slice(0, limit);
+  const children: BrowseChild[] = [];
+  for (const entity of page) children.push(await toChild(storage, entity));
+  return { container, children, total: entries.length, truncated: entries.length > limit };
+}
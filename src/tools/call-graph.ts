@@ -0,0 +1,439 @@
+import { dirname } from "node:path";
+import { confidenceOf, type EdgeDerivation, edgeConfidence, minConfidenceOf } from "../core/edge-confidence.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import type { CallArgument } from "../types/parser.js";
+import { type CallSite, type Entity, type Relationship, RelationType } from "../types/storage.js";
+import { findSymbolDefinitions, isExternalPlaceholder, splitQualifiedSymbol } from "./find-references.js";
+import { type LocatedEdge, locatedEdge } from "./located-edge.js";
+
+export type CallResolution = "direct" | "package" | "unresolved";
+
+export type CallGraphEntity = {
+  id: string;
+  name: string;
+  type: string;
+  filePath: string;
+  startLine: number | null;
+  endLine: number | null;
+  receiver: string | null;
+};
+
+export type CallSiteLocation = {
+  filePath: string;
+  line: number;
+  column: number | null;
+  callee: string | null;
+  /** Arguments passed, literals with their value; null when the index did not record them */
+  arguments: CallArgument[] | null;
+  /** Argument count; null when unrecorded or when a spread argument makes it unknown */
+  arity: number | null;
+};
+
+export type CallEdge = {
+  /** Caller (list_callers) or callee (list_callees); null when the callee could not be resolved */
+  entity: CallGraphEntity | null;
+  /** Entity name, or the callee text as written when unresolved */
+  name: string;
+  resolution: CallResolution;
+  /** 0..1; how certain it is that the call reaches this entity */
+  confidence: number;
+  derivation: EdgeDerivation;
+  callSites: CallSiteLocation[];
+  /** Caller to callee, located at the first call site (else at the caller) */
+  edge: LocatedEdge;
+};
+
+type PendingCall = Omit<CallEdge, "edge">;
+type PendingDefinition = { definition: CallGraphEntity; calls: PendingCall[] };
+
+export type CallGraphDefinition = {
+  definition: CallGraphEntity;
+  calls: CallEdge[];
+};
+
+export type CallGraphResult = {
+  symbol: string;
+  direction: "callers" | "callees";
+  definitions: CallGraphDefinition[];
+  /** Callers only: by-name call sites that could not be attributed to one of the definitions */
+  unresolved: CallEdge[];
+  total: number;
+  truncated: boolean;
+};
+
+export type CallGraphOptions = {
+  symbol: string;
+  filePath?: string;
+  qualifier?: string;
+  entityType?: string;
+  /** Drop calls bound with less confidence than this (0..1) */
+  minConfidence?: number;
+  /** Callers only: keep call sites passing this literal (`nil`, `0`, `"json"`) at this 1-based position */
+  argument?: { position: number; value: string };
+  limit?: number;
+};
+
+function summarize(entity: Entity): CallGraphEntity {
+  const receiver = (entity.metadata as Record<string, unknown> | undefined)?.receiver;
+  return {
+    id: entity.id,
+    name: entity.name,
+    type: String(entity.type),
+    filePath: entity.filePath,
+    startLine: entity.location?.start?.line ?? null,
+    endLine: entity.location?.end?.line ?? null,
+    receiver: typeof receiver === "string" && receiver ? receiver : null,
+  };
+}
+
+function callSitesOf(rel: Relationship): CallSite[] {
+  if (Array.isArray(rel.metadata?.callSites)) return rel.metadata.callSites;
+  return typeof rel.metadata?.line === "number" ? [{ line: rel.metadata.line }] : [];
+}
+
+export function located(site: CallSite, filePath: string): CallSiteLocation {
+  return {
+    filePath,
+    line: site.line,
+    column: site.column ?? null,
+    callee: site.callee ?? null,
+    arguments: site.arguments ?? null,
+    arity: site.arity ?? null,
+  };
+}
+
+/** Whether a call site passes the literal `value` at `position`; unrecorded arguments never match */
+function passesArgument(site: CallSite, filter: CallGraphOptions["argument"]): boolean {
+  if (!filter) return true;
+  const arg = site.arguments?.[filter.position - 1];
+  // Past a spread the positions of the following arguments are unknown
+  if (site.arguments?.slice(0, filter.position - 1).some((a) => a.kind === "spread")) return false;
+  return arg?.kind === "literal" && arg.value === filter.value.trim();
+}
+
+/**
+ * Go resolves unqualified names and method calls across every file of a package (one directory),
+ * so a call site the indexer could not bind inside its own file may still name a declaration next
+ * door. Other languages bind across files through imports, which are not followed here.
+ */
+function resolvesInPackage(def: Entity, site: CallSite, callerFile: string): boolean {
+  if (!callerFile.endsWith(".go") || dirname(def.filePath) !== dirname(callerFile)) return false;
+  const receiver = (def.metadata as Record<string, unknown> | undefined)?.receiver;
+  if (site.receiverType) return receiver === site.receiverType;
+  // `pkg.Func` or a method on a value of unknown type
+  if (site.callee?.includes(".")) return false;
+  return !receiver && def.type === "function";
+}
+
+function withEdges(
+  definitions: PendingDefinition[],
+  unresolved: PendingCall[],
+  direction: CallGraphResult["direction"],
+): Pick<CallGraphResult, "definitions" | "unresolved"> {
+  const locate = (call: PendingCall, definition: CallGraphEntity | null): CallEdge => {
+    // Callers always have an entity, and so does the definition whose callees are listed
+    const caller = (direction === "callers" ? call.entity : definition)!;
+    const callee = direction === "callers" ? definition : call.entity;
+    const site = call.callSites[0];
+    return {
+      ...call,
+      edge: locatedEdge({
+        from: caller.id,
+        to: callee?.id ?? null,
+        type: RelationType.CALLS,
+        direction: direction === "callers" ? "incoming" : "outgoing",
+        file: site?.filePath ?? caller.filePath,
+        line: site?.line,
+        column: site?.column,
+      }),
+    };
+  };
+  return {
+    definitions: definitions.map((group) => ({
+      definition: group.definition,
+      calls: group.calls.map((call) => locate(call, group.definition)),
+    })),
+    unresolved: unresolved.map((call) => locate(call, null)),
+  };
+}
+
+function limitOf(value: number | undefined): number {
+  return Math.max(1, Math.min(1000, Number(value ?? 200) || 200));
+}
+
+function applyLimit(
+  result: Omit<CallGraphResult, "truncated" | "total">,
+  limit: number,
+  minConfidence: number,
+): CallGraphResult {
+  const confident = (edge: CallEdge) => edge.confidence >= minConfidence;
+  let total = 0;
+  let remaining = limit;
+  for (const group of result.definitions) {
+    group.calls = group.calls.filter(confident);
+    total += group.calls.length;
+    group.calls = group.calls.slice(0, Math.max(0, remaining));
+    remaining -= group.calls.length;
+  }
+  const kept = result.unresolved.filter(confident);
+  total += kept.length;
+  const unresolved = kept.slice(0, Math.max(0, remaining));
+  return { ...result, unresolved, total, truncated: total > limit };
+}
+
+function createEntityLoader(storage: GraphStorageImpl) {
+  const cache = new Map<string, Entity | null>();
+  return async (id: string): Promise<Entity | null> => {
+    if (!cache.has(id)) cache.set(id, await storage.getEntity(id));
+    return cache.get(id) ?? null;
+  };
+}
+
+async function placeholdersNamed(storage: GraphStorageImpl, name: string): Promise<Entity[]> {
+  const query = await storage.executeQuery({ type: "entity", filters: { name }, limit: 1000 });
+  return query.entities.filter(isExternalPlaceholder);
+}
+
+async function callEdgesOf(storage: GraphStorageImpl, entityId: string, direction: "in" | "out") {
+  const rels = await storage.getRelationshipsForEntity(entityId, RelationType.CALLS);
+  return rels.filter((rel) => (direction === "in" ? rel.toId === entityId : rel.fromId === entityId));
+}
+
+/**
+ * Callers bound to `def` only at query time: by-name call sites in other files of its Go package.
+ */
+export async function packageCallersOf(
+  storage: GraphStorageImpl,
+  def: Entity,
+): Promise<Array<{ caller: Entity; callSites: CallSite[] }>> {
+  const loadEntity = createEntityLoader(storage);
+  const callers: Array<{ caller: Entity; callSites: CallSite[] }> = [];
+  for (const placeholder of await placeholdersNamed(storage, def.name)) {
+    for (const rel of await callEdgesOf(storage, placeholder.id, "in")) {
+      const caller = await loadEntity(rel.fromId);
+      if (!caller) continue;
+      const sites = callSitesOf(rel).filter((site) => resolvesInPackage(def, site, caller.filePath));
+      if (sites.length > 0) callers.push({ caller, callSites: sites });
+    }
+  }
+  return callers;
+}
+
+export type BoundCall = {
+  callee: Entity;
+  resolution: Exclude<CallResolution, "unresolved">;
+  confidence: number;
+  derivation: EdgeDerivation;
+  callSites: CallSite[];
+};
+
+/**
+ * Memoized lookup of the declarations an entity calls: edges bound at index time, plus Go call
+ * sites left by name that resolve to exactly one declaration of the caller's package. Calls that
+ * stay unresolved are left out, so walks over the result only follow calls known to land.
+ */
+export function createCalleeResolver(storage: GraphStorageImpl): (caller: Entity) => Promise<BoundCall[]> {
+  const loadEntity = createEntityLoader(storage);
+  const candidatesByName = new Map<string, Entity[]>();
+  const resolved = new Map<string, BoundCall[]>();
+
+  const candidatesNamed = async (name: string): Promise<Entity[]> => {
+    if (!candidatesByName.has(name)) {
+      const query = await storage.executeQuery({ type: "entity", filters: { name }, limit: 1000 });
+      candidatesByName.set(name, query.entities.filter((e) => !isExternalPlaceholder(e)));
+    }
+    return candidatesByName.get(name) ?? [];
+  };
+
+  return async (caller) => {
+    const cached = resolved.get(caller.id);
+    if (cached) return cached;
+    const calls: BoundCall[] = [];
+    const byTarget = new Map<string, BoundCall>();
+    const add = (callee: Entity, binding: Omit<BoundCall, "callee" | "callSites">, sites: CallSite[]) => {
+      const existing = byTarget.get(callee.id);
+      if (existing) {
+        existing.callSites.push(...sites);
+        return;
+      }
+      const call = { callee, ...binding, callSites: [...sites] };
+      byTarget.set(callee.id, call);
+      calls.push(call);
+    };
+
+    for (const rel of await callEdgesOf(storage, caller.id, "out")) {
+      const target = await loadEntity(rel.toId);
+      if (!target) continue;
+      if (!isExternalPlaceholder(target)) {
+        add(target, { resolution: "direct", ...confidenceOf(rel) }, callSitesOf(rel));
+        continue;
+      }
+      for (const site of callSitesOf(rel)) {
+        const matches = (await candidatesNamed(target.name)).filter((c) => resolvesInPackage(c, site, caller.filePath));
+        if (matches.length === 1) add(matches[0]!, { resolution: "package", ...edgeConfidence("name_match") }, [site]);
+      }
+    }
+    resolved.set(caller.id, calls);
+    return calls;
+  };
+}
+
+/**
+ * Functions and methods that call each definition of `symbol`, with the file and line of every
+ * call site. Edges bound at index time are `direct`; Go call sites bound across files of the same
+ * package are `package`; remaining by-name call sites are returned as `unresolved`.
+ */
+export async function listCallers(storage: GraphStorageImpl, options: CallGraphOptions): Promise<CallGraphResult> {
+  const loadEntity = createEntityLoader(storage);
+  const defs = await findSymbolDefinitions(storage, options);
+  const { name } = splitQualifiedSymbol(options.symbol);
+
+  const definitions: PendingDefinition[] = [];
+  for (const def of defs) {
+    const calls: PendingCall[] = [];
+    for (const rel of await callEdgesOf(storage, def.id, "in")) {
+      const caller = await loadEntity(rel.fromId);
+      if (!caller) continue;
+      const sites = callSitesOf(rel)
+        .filter((site) => passesArgument(site, options.argument))
+        .map((site) => located(site, caller.filePath));
+      if (sites.length === 0 && options.argument) continue;
+      calls.push({
+        entity: summarize(caller),
+        name: caller.name,
+        resolution: "direct",
+        ...confidenceOf(rel),
+        callSites: sites,
+      });
+    }
+    definitions.push({ definition: summarize(def), calls });
+  }
+
+  const unresolved: PendingCall[] = [];
+  for (const placeholder of await placeholdersNamed(storage, name)) {
+    for (const rel of await callEdgesOf(storage, placeholder.id, "in")) {
+      const caller = await loadEntity(rel.fromId);
+      if (!caller) continue;
+
+      const leftover: CallSite[] = [];
+      for (const site of callSitesOf(rel).filter((s) => passesArgument(s, options.argument))) {
+        const matches = defs.filter((def) => resolvesInPackage(def, site, caller.filePath));
+        const group = matches.length === 1 ? definitions[defs.indexOf(matches[0]!)] : undefined;
+        if (!group) {
+          leftover.push(site);
+          continue;
+        }
+        const existing = group.calls.find((c) => c.entity?.id === caller.id && c.resolution === "package");
+        const location = located(site, caller.filePath);
+        if (existing) {
+          existing.callSites.push(location);
+        } else {
+          group.calls.push({
+            entity: summarize(caller),
+            name: caller.name,
+            resolution: "package",
+            ...edgeConfidence("name_match"),
+            callSites: [location],
+          });
+        }
+      }
+
+      if (leftover.length > 0) {
+        unresolved.push({
+          entity: summarize(caller),
+          name: caller.name,
+          resolution: "unresolved",
+          ...edgeConfidence("heuristic"),
+          callSites: leftover.map((site) => located(site, caller.filePath)),
+        });
+      }
+    }
+  }
+
+  return applyLimit(
+    { symbol: options.symbol.trim(), direction: "callers", ...withEdges(definitions, unresolved, "callers") },
+    limitOf(options.limit),
+    minConfidenceOf(options.minConfidence),
+  );
+}
+
+/**
+ * What each definition of `symbol` calls. Calls the indexer left unresolved are re-checked against
+ * the caller's Go package; anything still unknown (other packages, external libraries, methods on
+ * values of unknown type) is reported with the callee text as written.
+ */
+export async function listCallees(storage: GraphStorageImpl, options: CallGraphOptions): Promise<CallGraphResult> {
+  const loadEntity = createEntityLoader(storage);
+  const defs = await findSymbolDefinitions(storage, options);
+  const candidatesByName = new Map<string, Entity[]>();
+
+  const definitions: PendingDefinition[] = [];
+  for (const def of defs) {
+    const calls: PendingCall[] = [];
+    const byTarget = new Map<string, PendingCall>();
+    const add = (key: string, edge: Omit<PendingCall, "callSites">, site?: CallSiteLocation) => {
+      let existing = byTarget.get(key);
+      if (!existing) {
+        existing = { ...edge, callSites: [] };
+        byTarget.set(key, existing);
+        calls.push(existing);
+      }
+      if (site) existing.callSites.push(site);
+    };
+
+    for (const rel of await callEdgesOf(storage, def.id, "out")) {
+      const target = await loadEntity(rel.toId);
+      if (!target) continue;
+      const sites = callSitesOf(rel);
+      const direct: Omit<PendingCall, "callSites"> = {
+        entity: summarize(target),
+        name: target.name,
+        resolution: "direct",
+        ...confidenceOf(rel),
+      };
+      if (sites.length === 0 && !isExternalPlaceholder(target)) add(target.id, direct);
+
+      for (const site of sites) {
+        const location = located(site, def.filePath);
+        if (!isExternalPlaceholder(target)) {
+          add(target.id, direct, location);
+          continue;
+        }
+
+        if (!candidatesByName.has(target.name)) {
+          const query = await storage.executeQuery({ type: "entity", filters: { name: target.name }, limit: 1000 });
+          candidatesByName.set(target.name, query.entities.filter((e) => !isExternalPlaceholder(e)));
+        }
+        const candidates = candidatesByName.get(target.name) ?? [];
+        const matches = candidates.filter((c) => resolvesInPackage(c, site, def.filePath));
+        if (matches.length === 1) {
+          const resolved = matches[0]!;
+          const edge: Omit<PendingCall, "callSites"> = {
+            entity: summarize(resolved),
+            name: resolved.name,
+            resolution: "package",
+            ...edgeConfidence("name_match"),
+          };
+          add(resolved.id, edge, location);
+        } else {
+          const callee = site.callee ?? target.name;
+          const edge: Omit<PendingCall, "callSites"> = {
+            entity: null,
+            name: callee,
+            resolution: "unresolved",
+            ...edgeConfidence("heuristic"),
+          };
+          add(`unresolved:${callee}`, edge, location);
+        }
+      }
+    }
+
+    definitions.push({ definition: summarize(def), calls });
+  }
+
+  return applyLimit(
+    { symbol: options.symbol.trim(), direction: "callees", ...withEdges(definitions, [], "callees") },
+    limitOf(options.limit),
+    minConfidenceOf(options.minConfidence),
+  );
+}
@@ -0,0 +1,223 @@
+import { type EdgeDerivation, minConfidenceOf } from "../core/edge-confidence.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { type Entity, RelationType } from "../types/storage.js";
+import {
+  type BoundCall,
+  type CallGraphEntity,
+  type CallResolution,
+  type CallSiteLocation,
+  createCalleeResolver,
+  located,
+} from "./call-graph.js";
+import { findSymbolDefinitions, isExternalPlaceholder } from "./find-references.js";
+import { type LocatedEdge, locatedEdge } from "./located-edge.js";
+
+export type CallPathHop = {
+  resolution: Exclude<CallResolution, "unresolved">;
+  confidence: number;
+  derivation: EdgeDerivation;
+  callSites: CallSiteLocation[];
+  /** This step to the next one, located at the first call site */
+  edge: LocatedEdge;
+};
+
+export type CallPathStep = {
+  entity: CallGraphEntity;
+  /** The call leading to the next step; null on the final step */
+  call: CallPathHop | null;
+};
+
+export type CallPath = {
+  /** Confidence of the least certain call on the path */
+  confidence: number;
+  steps: CallPathStep[];
+};
+
+export type CallPathTruncation = {
+  reason: "depth" | "limit";
+  depth: number;
+  message: string;
+};
+
+export type CallPathResult = {
+  from: string;
+  to: string;
+  sources: CallGraphEntity[];
+  targets: CallGraphEntity[];
+  /** Every shortest path, most certain first; empty when none was found */
+  paths: CallPath[];
+  /** Calls on a shortest path; null when none was found */
+  length: number | null;
+  totalPaths: number;
+  maxDepth: number;
+  depthReached: number;
+  /** Set when maxDepth stopped the search with calls left to follow, or limit dropped paths */
+  truncated: CallPathTruncation | null;
+};
+
+export type CallPathOptions = {
+  /** Symbol name (optionally qualified) or an exact entity id of the caller */
+  from: string;
+  to: string;
+  fromFilePath?: string;
+  toFilePath?: string;
+  maxDepth?: number;
+  /** Only follow calls bound with at least this confidence (0..1) */
+  minConfidence?: number;
+  /** Maximum paths to return */
+  limit?: number;
+};
+
+/** A call that reaches `to` on a shortest route, from an entity one step closer to the sources */
+type Arrival = { from: Entity; call: BoundCall };
+
+function summarize(entity: Entity): CallGraphEntity {
+  const receiver = (entity.metadata as Record<string, unknown> | undefined)?.receiver;
+  return {
+    id: entity.id,
+    name: entity.name,
+    type: String(entity.type),
+    filePath: entity.filePath,
+    startLine: entity.location?.start?.line ?? null,
+    endLine: entity.location?.end?.line ?? null,
+    receiver: typeof receiver === "string" && receiver ? receiver : null,
+  };
+}
+
+function clamp(value: number | undefined, fallback: number, max: number): number {
+  return Math.max(1, Math.min(max, Math.floor(Number(value ?? fallback) || fallback)));
+}
+
+async function resolveSymbol(storage: GraphStorageImpl, symbol: string, filePath?: string): Promise<Entity[]> {
+  const byId = await storage.getEntity(symbol.trim());
+  if (byId && !isExternalPlaceholder(byId)) return [byId];
+  return findSymbolDefinitions(storage, { symbol, filePath });
+}
+
+function hopOf(from: Entity, call: BoundCall): CallPathHop {
+  const callSites = call.callSites.map((site) => located(site, from.filePath));
+  return {
+    resolution: call.resolution,
+    confidence: call.confidence,
+    derivation: call.derivation,
+    callSites,
+    edge: locatedEdge({
+      from: from.id,
+      to: call.callee.id,
+      type: RelationType.CALLS,
+      direction: "outgoing",
+      file: from.filePath,
+      line: callSites[0]?.line,
+      column: callSites[0]?.column,
+    }),
+  };
+}
+
+/**
+ * The shortest chains of calls from `from` to `to`, found breadth-first over the calls each
+ * function makes (bound at index time, or by name within a Go package). Every function is entered
+ * once, at its shortest distance, which keeps recursion and mutual recursion from looping; all
+ * routes of that shortest length are kept. Asking for a path from a function to itself finds the
+ * shortest recursion through it.
+ */
+export async function findCallPaths(storage: GraphStorageImpl, options: CallPathOptions): Promise<CallPathResult> {
+  const maxDepth = clamp(options.maxDepth, 6, 20);
+  const limit = clamp(options.limit, 10, 100);
+  const minConfidence = minConfidenceOf(options.minConfidence);
+  const calleesOf = createCalleeResolver(storage);
+  const followedFrom = async (entity: Entity) =>
+    (await calleesOf(entity)).filter((call) => call.confidence >= minConfidence);
+
+  const sources = await resolveSymbol(storage, options.from, options.fromFilePath);
+  const targets = await resolveSymbol(storage, options.to, options.toFilePath);
+  const targetIds = new Set(targets.map((t) => t.id));
+
+  // Shortest distance from a source, and the calls reaching each entity at that distance
+  const distance = new Map<string, number>(sources.map((s) => [s.id, 0]));
+  const parents = new Map<string, Arrival[]>();
+  const arrivals = new Map<string, Arrival[]>();
+  let frontier = sources;
+  let depthReached = 0;
+  let length: number | null = null;
+
+  for (let depth = 1; depth <= maxDepth && frontier.length > 0 && length === null; depth++) {
+    const next: Entity[] = [];
+    for (const caller of frontier) {
+      for (const call of await followedFrom(caller)) {
+        const id = call.callee.id;
+        if (targetIds.has(id)) {
+          arrivals.set(id, [...(arrivals.get(id) ?? []), { from: caller, call }]);
+          length = depth;
+        }
+        const known = distance.get(id);
+        if (known === undefined) {
+          distance.set(id, depth);
+          parents.set(id, [{ from: caller, call }]);
+          next.push(call.callee);
+        } else if (known === depth) {
+          parents.get(id)!.push({ from: caller, call });
+        }
+      }
+    }
+    if (next.length > 0 || length !== null) depthReached = depth;
+    frontier = next;
+  }
+
+  let truncated: CallPathTruncation | null = null;
+  if (length === null && depthReached === maxDepth) {
+    // Only a cut-off if the last layer still calls something new (or the target)
+    for (const caller of frontier) {
+      const calls = await followedFrom(caller);
+      if (calls.some((call) => targetIds.has(call.callee.id) || !distance.has(call.callee.id))) {
+        truncated = { reason: "depth", depth: maxDepth, message: `no path within depth ${maxDepth}` };
+        break;
+      }
+    }
+  }
+
+  // Routes into each entity; sources start one
+  const routes = new Map<string, number>();
+  const routesTo = (id: string): number => {
+    if (distance.get(id) === 0) return 1;
+    if (!routes.has(id)) routes.set(id, (parents.get(id) ?? []).reduce((sum, p) => sum + routesTo(p.from.id), 0));
+    return routes.get(id)!;
+  };
+
+  const paths: CallPath[] = [];
+  let totalPaths = 0;
+  // Parents sit one step closer to a source, so walking back always ends at one
+  const walkBack = (arrival: Arrival, tail: CallPathStep[]) => {
+    if (paths.length >= limit) return;
+    const steps = [{ entity: summarize(arrival.from), call: hopOf(arrival.from, arrival.call) }, ...tail];
+    if (distance.get(arrival.from.id) === 0) {
+      paths.push({ confidence: Math.min(...steps.map((step) => step.call?.confidence ?? 1)), steps });
+      return;
+    }
+    for (const parent of parents.get(arrival.from.id) ?? []) walkBack(parent, steps);
+  };
+
+  for (const target of targets) {
+    for (const arrival of arrivals.get(target.id) ?? []) {
+      totalPaths += routesTo(arrival.from.id);
+      walkBack(arrival, [{ entity: summarize(target), call: null }]);
+    }
+  }
+  paths.sort((a, b) => b.confidence - a.confidence);
+  if (!truncated && totalPaths > paths.length) {
+    const message = `returned ${paths.length} of ${totalPaths} shortest paths`;
+    truncated = { reason: "limit", depth: length ?? depthReached, message };
+  }
+
+  return {
+    from: options.from.trim(),
+    to: options.to.trim(),
+    sources: sources.map(summarize),
+    targets: targets.map(summarize),
+    paths,
+    length,
+    totalPaths,
+    maxDepth,
+    depthReached,
+    truncated,
+  };
+}
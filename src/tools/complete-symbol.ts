@@ -0,0 +1,158 @@
+import { TYPE_KINDS } from "../core/override-resolver.js";
+import { isAnonymousEntity } from "../parsers/anonymous-entities.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import type { SearchScope } from "../storage/search-filters.js";
+import type { Entity } from "../types/storage.js";
+import { isExternalPlaceholder } from "./find-references.js";
+
+export type CompletionMatch = "exact" | "prefix" | "prefix_ignore_case" | "camel_case";
+
+export type SymbolCompletion = {
+  name: string;
+  /** Kind of the declaration shown; other declarations of the name may differ */
+  type: string;
+  match: CompletionMatch;
+  /** Indexed declarations carrying the name */
+  definitions: number;
+  /** Relationships other than containment pointing at any of them */
+  usages: number;
+  id: string;
+  filePath: string;
+  startLine: number | null;
+};
+
+export type SymbolCompletions = {
+  prefix: string;
+  completions: SymbolCompletion[];
+  /** Distinct names that matched, before the limit */
+  total: number;
+  truncated: boolean;
+};
+
+const MATCH_ORDER: CompletionMatch[] = ["exact", "prefix", "prefix_ignore_case", "camel_case"];
+
+// Rows fetched per name query; enough for a completion list, bounded for huge indexes
+const CANDIDATE_LIMIT = 1000;
+
+const CALLABLE_KINDS = new Set(["function", "method", "constructor", "arrow_function", "async_function"]);
+
+// Not symbols a reader would complete to
+const SKIPPED_KINDS = new Set(["import", "export", "comment", "file"]);
+
+const SEPARATORS = /[_.\-$\s]/g;
+
+function kindRank(type: string): number {
+  if (TYPE_KINDS.has(type)) return 0;
+  if (CALLABLE_KINDS.has(type)) return 1;
+  return 2;
+}
+
+/** Words of an identifier: camel-case humps, acronyms, digit runs, split on `_ . - $` too */
+function identifierWords(name: string): string[] {
+  return name.match(/\p{Lu}+(?!\p{Ll})|\p{Lu}?\p{Ll}+|\p{N}+/gu) ?? [];
+}
+
+/**
+ * Whether `query` picks out the words of `name` in order: each query run must open a word, the
+ * first on the name's first word, and words may be skipped (`GU` and `getUsBy` match `GetUserById`,
+ * `UB` does not). Case and separators in the query are ignored.
+ */
+export function camelCaseMatch(query: string, name: string): boolean {
+  const q = query.replace(SEPARATORS, "").toLowerCase();
+  const words = identifierWords(name).map((word) => word.toLowerCase());
+  if (!q || words.length === 0) return false;
+
+  const from = (qi: number, wi: number, anchored: boolean): boolean => {
+    if (qi === q.length) return true;
+    for (let w = wi; w < (anchored ? wi + 1 : words.length); w++) {
+      const word = words[w]!;
+      for (let k = 0; k < word.length && qi + k < q.length && word[k] === q[qi + k]; k++) {
+        if (from(qi + k + 1, w + 1, false)) return true;
+      }
+    }
+    return false;
+  };
+  return from(0, 0, true);
+}
+
+function classify(prefix: string, name: string): CompletionMatch | null {
+  if (name === prefix) return "exact";
+  if (name.startsWith(prefix)) return "prefix";
+  if (name.toLowerCase().startsWith(prefix.toLowerCase())) return "prefix_ignore_case";
+  return camelCaseMatch(prefix, name) ? "camel_case" : null;
+}
+
+/**
+ * Entity names completing `prefix`, either as typed (any case) or as a camel-case abbreviation.
+ * One entry per name, ranked by how it matched, then types before callables before the rest,
+ * then by how often the name's declarations are used, shorter names first on ties.
+ */
+export async function completeSymbol(
+  storage: GraphStorageImpl,
+  prefix: string,
+  options: { scope?: SearchScope; limit?: number } = {},
+): Promise<SymbolCompletions> {
+  const typed = prefix.replace(/%/g, "");
+  const limit = options.limit ?? 20;
+  if (!typed) return { prefix, completions: [], total: 0, truncated: false };
+
+  // Both LIKE patterns open with a literal so the case-folded name index serves them; `_` in the
+  // query is a one-character wildcard there, which stays a superset of what classify accepts
+  const candidates = new Map<string, Entity>();
+  const subsequence = `${[...typed.replace(SEPARATORS, (sep) => (sep === "_" ? "_" : ""))].join("%")}%`;
+  for (const pattern of [`${typed}%`, subsequence]) {
+    for (const entity of await storage.findEntitiesByNamePattern(pattern, {
+      scope: options.scope,
+      limit: CANDIDATE_LIMIT,
+    })) {
+      candidates.set(entity.id, entity);
+    }
+  }
+
+  const byName = new Map<string, { match: CompletionMatch; entities: Entity[] }>();
+  for (const entity of candidates.values()) {
+    if (SKIPPED_KINDS.has(String(entity.type)) && !options.scope?.kinds?.length) continue;
+    if (isExternalPlaceholder(entity) || isAnonymousEntity(entity)) continue;
+    const match = classify(typed, entity.name);
+    if (!match) continue;
+    const group = byName.get(entity.name) ?? { match, entities: [] };
+    group.entities.push(entity);
+    byName.set(entity.name, group);
+  }
+
+  const usages = await storage.countIncomingRelationships([...candidates.keys()]);
+  const completions: SymbolCompletion[] = [];
+  for (const [name, group] of byName) {
+    const best = [...group.entities].sort(
+      (a, b) =>
+        kindRank(String(a.type)) - kindRank(String(b.type)) ||
+        (usages.get(b.id) ?? 0) - (usages.get(a.id) ?? 0) ||
+        a.filePath.localeCompare(b.filePath),
+    )[0]!;
+    completions.push({
+      name,
+      type: String(best.type),
+      match: group.match,
+      definitions: group.entities.length,
+      usages: group.entities.reduce((sum, entity) => sum + (usages.get(entity.id) ?? 0), 0),
+      id: best.id,
+      filePath: best.filePath,
+      startLine: best.location?.start?.line ?? null,
+    });
+  }
+
+  completions.sort(
+    (a, b) =>
+      MATCH_ORDER.indexOf(a.match) - MATCH_ORDER.indexOf(b.match) ||
+      kindRank(a.type) - kindRank(b.type) ||
+      b.usages - a.usages ||
+      a.name.length - b.name.length ||
+      a.name.localeCompare(b.name),
+  );
+  return {
+    prefix,
+    completions: completions.slice(0, limit),
+    total: completions.length,
+    truncated: completions.length > limit,
+  };
+}
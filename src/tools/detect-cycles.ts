@@ -0,0 +1,318 @@
+import { dirname, resolve } from "node:path";
+import { resolveRelativeModule } from "../core/cross-file-resolver.js";
+import { type GoModuleCache, goImportPath } from "../core/go-modules.js";
+import { isContainer } from "../core/package-tree.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { type Entity, RelationType } from "../types/storage.js";
+import { isExternalPlaceholder } from "./find-references.js";
+
+/** How TS/JS and Python files are grouped into modules; Go always uses package import paths */
+export type CycleGranularity = "file" | "directory";
+
+export type ModuleKind = "package" | "file" | "directory";
+
+export type ImportSite = {
+  filePath: string;
+  line: number | null;
+  source: string;
+};
+
+export type ModuleEdge = {
+  from: string;
+  to: string;
+  imports: ImportSite[];
+};
+
+export type DependencyCycle = {
+  kind: ModuleKind;
+  /** A shortest cycle through the component starting at its smallest module; the last one imports the first */
+  modules: string[];
+  /** The import edges along `modules`, including the closing edge */
+  edges: ModuleEdge[];
+  /** Every module of the strongly connected component, sorted */
+  members: string[];
+  /** Number of module-level import edges inside the component */
+  internalEdges: number;
+};
+
+export type DetectCyclesResult = {
+  granularity: CycleGranularity;
+  moduleCount: number;
+  edgeCount: number;
+  hasCycles: boolean;
+  cycles: DependencyCycle[];
+};
+
+export type DetectCyclesOptions = {
+  granularity?: CycleGranularity;
+  /** Only consider files under this path */
+  pathPrefix?: string;
+};
+
+const SCRIPT_EXTENSION_RE = /\.(?:[cm]?[jt]sx?)$/;
+const PYTHON_EXTENSION_RE = /\.pyi?$/;
+
+// Code-unit order rather than localeCompare, so CI runs on any locale produce identical output
+const byCodeUnit = (a: string, b: string) => (a < b ? -1 : a > b ? 1 : 0);
+
+/** Module-level import graph: from -> to -> import sites */
+export class ModuleGraph {
+  readonly edges = new Map<string, Map<string, ImportSite[]>>();
+  readonly kinds = new Map<string, ModuleKind>();
+
+  addModule(name: string, kind: ModuleKind): void {
+    if (!this.kinds.has(name)) this.kinds.set(name, kind);
+    if (!this.edges.has(name)) this.edges.set(name, new Map());
+  }
+
+  addEdge(from: string, to: string, site: ImportSite): void {
+    // Collapsing files into directories produces self-imports; those are not cycles
+    if (from === to) return;
+    const targets = this.edges.get(from);
+    if (!targets) return;
+    const sites = targets.get(to) ?? [];
+    sites.push(site);
+    targets.set(to, sites);
+  }
+
+  neighbors(name: string): string[] {
+    return Array.from(this.edges.get(name)?.keys() ?? []).sort();
+  }
+
+  edgeCount(): number {
+    let count = 0;
+    for (const targets of this.edges.values()) count += targets.size;
+    return count;
+  }
+}
+
+/**
+ * Tarjan's strongly connected components, iterative so deep import chains cannot overflow the
+ * stack. Nodes and neighbours are visited in sorted order, which makes the output deterministic.
+ */
+export function stronglyConnectedComponents(nodes: string[], neighbors: (node: string) => string[]): string[][] {
+  const index = new Map<string, number>();
+  const lowLink = new Map<string, number>();
+  const onStack = new Set<string>();
+  const stack: string[] = [];
+  const components: string[][] = [];
+  let counter = 0;
+
+  for (const root of [...nodes].sort()) {
+    if (index.has(root)) continue;
+
+    const work: Array<{ node: string; next: string[]; i: number }> = [];
+    const enter = (node: string) => {
+      index.set(node, counter);
+      lowLink.set(node, counter);
+      counter++;
+      stack.push(node);
+      onStack.add(node);
+      work.push({ node, next: neighbors(node), i: 0 });
+    };
+    enter(root);
+
+    while (work.length > 0) {
+      const frame = work[work.length - 1]!;
+      if (frame.i < frame.next.length) {
+        const target = frame.next[frame.i++]!;
+        if (!index.has(target)) {
+          enter(target);
+        } else if (onStack.has(target)) {
+          lowLink.set(frame.node, Math.min(lowLink.get(frame.node)!, index.get(target)!));
+        }
+        continue;
+      }
+
+      work.pop();
+      const parent = work[work.length - 1];
+      if (parent) lowLink.set(parent.node, Math.min(lowLink.get(parent.node)!, lowLink.get(frame.node)!));
+
+      if (lowLink.get(frame.node) === index.get(frame.node)) {
+        const component: string[] = [];
+        let member: string | undefined;
+        do {
+          member = stack.pop()!;
+          onStack.delete(member);
+          component.push(member);
+        } while (member !== frame.node);
+        components.push(component.sort());
+      }
+    }
+  }
+
+  return components;
+}
+
+/** Shortest path from `start` back to itself that stays inside `members` */
+function shortestCycle(start: string, members: Set<string>, neighbors: (node: string) => string[]): string[] {
+  const previous = new Map<string, string>();
+  const queue = [start];
+  for (let head = 0; head < queue.length; head++) {
+    const node = queue[head]!;
+    for (const target of neighbors(node)) {
+      if (!members.has(target)) continue;
+      if (target === start) {
+        const path = [node];
+        for (let at = node; at !== start; ) {
+          at = previous.get(at)!;
+          path.push(at);
+        }
+        return path.reverse();
+      }
+      if (previous.has(target)) continue;
+      previous.set(target, node);
+      queue.push(target);
+    }
+  }
+  return [start];
+}
+
+export type LoadedModuleGraph = {
+  graph: ModuleGraph;
+  /** Module a Go, TS/JS or Python file of the graph belongs to */
+  moduleOf: (filePath: string) => string | null;
+};
+
+/**
+ * Group files into modules and link them by their imports. Go files are grouped by package import
+ * path and linked by their package imports; TS/JS and Python files, per file or collapsed to their
+ * directory, are linked by the files the import resolver bound their imports to. TS/JS relative
+ * imports not bound yet (an index taken before that pass ran) are resolved here.
+ */
+export async function loadModuleGraph(
+  storage: GraphStorageImpl,
+  options: DetectCyclesOptions = {},
+): Promise<LoadedModuleGraph> {
+  const granularity = options.granularity ?? "file";
+  const prefix = options.pathPrefix ? resolve(options.pathPrefix) : null;
+  const inScope = (filePath: string) => !prefix || filePath === prefix || filePath.startsWith(`${prefix}/`);
+
+  const goPackages = new Map<string, Entity>();
+  const importPaths = new Map<string, string>();
+  const scriptImports: Entity[] = [];
+  for await (const entity of storage.iterateEntities()) {
+    if (isExternalPlaceholder(entity)) {
+      const symbol = (entity.metadata as Record<string, unknown>)?.symbol;
+      importPaths.set(entity.id, typeof symbol === "string" ? symbol : entity.name);
+      continue;
+    }
+    if (isContainer(entity)) {
+      const qualifiedName = entity.metadata?.qualifiedName;
+      if (entity.metadata?.container === "go_package" && typeof qualifiedName === "string") {
+        importPaths.set(entity.id, qualifiedName);
+      }
+      continue;
+    }
+    if (!inScope(entity.filePath)) continue;
+    if (entity.filePath.endsWith(".go")) {
+      if ((entity.metadata as Record<string, unknown>)?.isPackage) goPackages.set(entity.id, entity);
+    } else if (entity.type === "import" && entity.metadata?.importData?.source) {
+      scriptImports.push(entity);
+    }
+  }
+
+  const graph = new ModuleGraph();
+  const goModules: GoModuleCache = new Map();
+  const packageDirs = new Map<string, string>();
+  for (const pkg of goPackages.values()) {
+    const dir = dirname(pkg.filePath);
+    const importPath = goImportPath(dir, goModules);
+    packageDirs.set(dir, importPath);
+    graph.addModule(importPath, "package");
+  }
+
+  const resolveGoImport = (path: string): string | null => {
+    if (graph.kinds.get(path) === "package") return path;
+    const bySuffix = Array.from(packageDirs.values()).filter((p) => p.endsWith(`/${path}`));
+    return bySuffix.length === 1 ? bySuffix[0]! : null;
+  };
+
+  // Files each import entity was bound to by the import resolver
+  const resolvedFiles = new Map<string, string[]>();
+  for await (const rel of storage.iterateRelationships()) {
+    if (rel.type !== RelationType.IMPORTS) continue;
+    const resolvedFile = rel.metadata?.resolvedFile;
+    if (typeof resolvedFile === "string") {
+      resolvedFiles.set(rel.fromId, [...(resolvedFiles.get(rel.fromId) ?? []), resolvedFile]);
+      continue;
+    }
+    const pkg = goPackages.get(rel.fromId);
+    const source = importPaths.get(rel.toId);
+    if (!pkg || !source) continue;
+    const target = resolveGoImport(source);
+    if (!target) continue;
+    const from = packageDirs.get(dirname(pkg.filePath))!;
+    graph.addEdge(from, target, { filePath: pkg.filePath, line: rel.metadata?.line ?? null, source });
+  }
+
+  const sourceFiles = new Set<string>();
+  for (const file of await storage.listIndexedFiles()) {
+    const linked = SCRIPT_EXTENSION_RE.test(file.path) || PYTHON_EXTENSION_RE.test(file.path);
+    if (linked && inScope(file.path)) sourceFiles.add(file.path);
+  }
+  const fileModule = (filePath: string) => (granularity === "directory" ? dirname(filePath) : filePath);
+  for (const file of sourceFiles) graph.addModule(fileModule(file), granularity);
+
+  const isSourceFile = (path: string) => sourceFiles.has(path);
+  for (const entity of scriptImports) {
+    if (!sourceFiles.has(entity.filePath)) continue;
+    const source = entity.metadata.importData!.source;
+    const fallback = SCRIPT_EXTENSION_RE.test(entity.filePath)
+      ? resolveRelativeModule(entity.filePath, source, isSourceFile)
+      : null;
+    const targets = resolvedFiles.get(entity.id) ?? (fallback ? [fallback] : []);
+    for (const target of targets) {
+      if (!sourceFiles.has(target)) continue;
+      graph.addEdge(fileModule(entity.filePath), fileModule(target), {
+        filePath: entity.filePath,
+        line: entity.location?.start?.line ?? null,
+        source,
+      });
+    }
+  }
+
+  const moduleOf = (filePath: string): string | null => {
+    if (filePath.endsWith(".go")) return packageDirs.get(dirname(filePath)) ?? null;
+    return sourceFiles.has(filePath) ? fileModule(filePath) : null;
+  };
+  return { graph, moduleOf };
+}
+
+/**
+ * Find import cycles between the modules of `loadModuleGraph`. Each strongly connected component
+ * with more than one module is reported once, with a concrete cycle through it and the import
+ * statements that form that cycle.
+ */
+export async function detectCycles(
+  storage: GraphStorageImpl,
+  options: DetectCyclesOptions = {},
+): Promise<DetectCyclesResult> {
+  const granularity = options.granularity ?? "file";
+  const { graph } = await loadModuleGraph(storage, options);
+  const neighbors = (node: string) => graph.neighbors(node);
+  const cycles: DependencyCycle[] = [];
+  for (const members of stronglyConnectedComponents(Array.from(graph.edges.keys()), neighbors)) {
+    if (members.length < 2) continue;
+    const memberSet = new Set(members);
+    const modules = shortestCycle(members[0]!, memberSet, neighbors);
+    const edges = modules.map((from, i) => {
+      const to = modules[(i + 1) % modules.length]!;
+      const imports = [...(graph.edges.get(from)?.get(to) ?? [])].sort(
+        (a, b) => byCodeUnit(a.filePath, b.filePath) || (a.line ?? 0) - (b.line ?? 0),
+      );
+      return { from, to, imports };
+    });
+    const internalEdges = members.reduce((sum, m) => sum + neighbors(m).filter((t) => memberSet.has(t)).length, 0);
+    cycles.push({ kind: graph.kinds.get(members[0]!)!, modules, edges, members, internalEdges });
+  }
+  cycles.sort((a, b) => byCodeUnit(a.members[0]!, b.members[0]!));
+
+  return {
+    granularity,
+    moduleCount: graph.edges.size,
+    edgeCount: graph.edgeCount(),
+    hasCycles: cycles.length > 0,
+    cycles,
+  };
+}
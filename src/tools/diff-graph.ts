@@ -0,0 +1,222 @@
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import type { Entity, SnapshotEntity, SnapshotRelationship } from "../types/storage.js";
+
+export type EntityChangeKind = "signature" | "body";
+
+export type ModifiedEntity = {
+  before: SnapshotEntity;
+  after: SnapshotEntity;
+  changes: EntityChangeKind[];
+};
+
+export type DiffEndpoint = {
+  id: string;
+  name: string;
+  type: string;
+  filePath: string;
+};
+
+export type DiffRelationship = {
+  id: string;
+  type: string;
+  from: DiffEndpoint;
+  to: DiffEndpoint;
+};
+
+export type FileDiff = {
+  filePath: string;
+  added: SnapshotEntity[];
+  removed: SnapshotEntity[];
+  modified: ModifiedEntity[];
+  /** Relationships whose source entity lives in this file */
+  addedRelationships: DiffRelationship[];
+  removedRelationships: DiffRelationship[];
+};
+
+export type GraphDiffSummary = {
+  entitiesAdded: number;
+  entitiesRemoved: number;
+  entitiesModified: number;
+  relationshipsAdded: number;
+  relationshipsRemoved: number;
+  filesChanged: number;
+};
+
+export type GraphDiffResult = {
+  from: string;
+  /** Snapshot label, or null for the live index */
+  to: string | null;
+  summary: GraphDiffSummary;
+  files: FileDiff[];
+  truncated: boolean;
+};
+
+export type DiffGraphOptions = {
+  from: string;
+  /** Snapshot to compare against; the live index when omitted */
+  to?: string;
+  /** Only report changes to files under this path */
+  pathPrefix?: string;
+  /** Maximum number of listed changes (entities plus relationships) */
+  limit?: number;
+};
+
+type GraphState = {
+  entities: Map<string, SnapshotEntity>;
+  relationships: Map<string, SnapshotRelationship>;
+};
+
+const byCodeUnit = (a: string, b: string) => (a < b ? -1 : a > b ? 1 : 0);
+
+const isPlaceholder = (entity: SnapshotEntity) => entity.filePath.startsWith("external://");
+
+async function loadSnapshot(storage: GraphStorageImpl, label: string): Promise<GraphState> {
+  const entities = new Map((await storage.getSnapshotEntities(label)).map((e) => [e.id, e]));
+  const relationships = new Map((await storage.getSnapshotRelationships(label)).map((r) => [r.id, r]));
+  return { entities, relationships };
+}
+
+/** The fields of a stored entity that snapshots keep and diffs compare */
+export function toSnapshotEntity(entity: Entity): SnapshotEntity {
+  return {
+    id: entity.id,
+    name: entity.name,
+    type: String(entity.type),
+    filePath: entity.filePath,
+    startLine: entity.location?.start?.line ?? null,
+    endLine: entity.location?.end?.line ?? null,
+    signature: typeof entity.metadata?.signature === "string" ? entity.metadata.signature : null,
+    bodyHash: typeof entity.metadata?.bodyHash === "string" ? entity.metadata.bodyHash : null,
+  };
+}
+
+async function loadCurrent(storage: GraphStorageImpl): Promise<GraphState> {
+  const entities = new Map<string, SnapshotEntity>();
+  for await (const entity of storage.iterateEntities()) {
+    entities.set(entity.id, toSnapshotEntity(entity));
+  }
+  const relationships = new Map<string, SnapshotRelationship>();
+  for await (const rel of storage.iterateRelationships()) {
+    relationships.set(rel.id, { id: rel.id, fromId: rel.fromId, toId: rel.toId, type: String(rel.type) });
+  }
+  return { entities, relationships };
+}
+
+export function changesBetween(before: SnapshotEntity, after: SnapshotEntity): EntityChangeKind[] {
+  const changes: EntityChangeKind[] = [];
+  if ((before.signature ?? null) !== (after.signature ?? null)) changes.push("signature");
+  // Entities indexed without a readable file carry no body hash; those can only differ by signature
+  if (before.bodyHash && after.bodyHash && before.bodyHash !== after.bodyHash) changes.push("body");
+  return changes;
+}
+
+function changeCount(diff: FileDiff): number {
+  const { added, removed, modified, addedRelationships, removedRelationships } = diff;
+  return added.length + removed.length + modified.length + addedRelationships.length + removedRelationships.length;
+}
+
+function endpoint(state: GraphState, id: string): DiffEndpoint {
+  const entity = state.entities.get(id);
+  return {
+    id,
+    name: entity?.name ?? id,
+    type: entity?.type ?? "unknown",
+    filePath: entity?.filePath ?? "",
+  };
+}
+
+/**
+ * Compare two index states. Entities are matched by their stable id: ids only in `to` are added,
+ * ids only in `from` are removed, and ids in both whose signature or body hash differ are modified.
+ * Relationships are matched by id, which is derived from both endpoints and the type. Changes are
+ * grouped by file; a relationship belongs to the file of its source entity.
+ */
+export async function diffGraph(storage: GraphStorageImpl, options: DiffGraphOptions): Promise<GraphDiffResult> {
+  const limit = Math.max(1, Math.min(10000, Number(options.limit ?? 1000) || 1000));
+  const prefix = options.pathPrefix?.replace(/[/\\]+$/, "");
+  const inScope = (filePath: string) => !prefix || filePath === prefix || filePath.startsWith(`${prefix}/`);
+
+  const before = await loadSnapshot(storage, options.from);
+  const after = options.to ? await loadSnapshot(storage, options.to) : await loadCurrent(storage);
+
+  const files = new Map<string, FileDiff>();
+  const fileFor = (filePath: string): FileDiff => {
+    let diff = files.get(filePath);
+    if (!diff) {
+      diff = { filePath, added: [], removed: [], modified: [], addedRelationships: [], removedRelationships: [] };
+      files.set(filePath, diff);
+    }
+    return diff;
+  };
+
+  for (const entity of after.entities.values()) {
+    if (isPlaceholder(entity) || !inScope(entity.filePath)) continue;
+    const previous = before.entities.get(entity.id);
+    if (!previous) {
+      fileFor(entity.filePath).added.push(entity);
+      continue;
+    }
+    const changes = changesBetween(previous, entity);
+    if (changes.length > 0) fileFor(entity.filePath).modified.push({ before: previous, after: entity, changes });
+  }
+  for (const entity of before.entities.values()) {
+    if (isPlaceholder(entity) || !inScope(entity.filePath) || after.entities.has(entity.id)) continue;
+    fileFor(entity.filePath).removed.push(entity);
+  }
+
+  const relationshipChanges = (
+    side: GraphState,
+    other: GraphState,
+    key: "addedRelationships" | "removedRelationships",
+  ) => {
+    for (const rel of side.relationships.values()) {
+      if (other.relationships.has(rel.id)) continue;
+      const from = endpoint(side, rel.fromId);
+      if (!inScope(from.filePath)) continue;
+      fileFor(from.filePath)[key].push({ id: rel.id, type: rel.type, from, to: endpoint(side, rel.toId) });
+    }
+  };
+  relationshipChanges(after, before, "addedRelationships");
+  relationshipChanges(before, after, "removedRelationships");
+
+  const byLine = (a: { startLine: number | null }, b: { startLine: number | null }) =>
+    (a.startLine ?? 0) - (b.startLine ?? 0);
+  const byEdge = (a: DiffRelationship, b: DiffRelationship) =>
+    byCodeUnit(a.from.name, b.from.name) || byCodeUnit(a.type, b.type) || byCodeUnit(a.to.name, b.to.name);
+
+  const summary: GraphDiffSummary = {
+    entitiesAdded: 0,
+    entitiesRemoved: 0,
+    entitiesModified: 0,
+    relationshipsAdded: 0,
+    relationshipsRemoved: 0,
+    filesChanged: files.size,
+  };
+  let remaining = limit;
+  let truncated = false;
+  const take = <T>(items: T[]): T[] => {
+    const kept = items.slice(0, Math.max(0, remaining));
+    remaining -= kept.length;
+    if (kept.length < items.length) truncated = true;
+    return kept;
+  };
+
+  const ordered = Array.from(files.values()).sort((a, b) => byCodeUnit(a.filePath, b.filePath));
+  for (const diff of ordered) {
+    summary.entitiesAdded += diff.added.length;
+    summary.entitiesRemoved += diff.removed.length;
+    summary.entitiesModified += diff.modified.length;
+    summary.relationshipsAdded += diff.addedRelationships.length;
+    summary.relationshipsRemoved += diff.removedRelationships.length;
+
+    diff.added = take(diff.added.sort(byLine));
+    diff.removed = take(diff.removed.sort(byLine));
+    diff.modified = take(diff.modified.sort((a, b) => byLine(a.after, b.after)));
+    diff.addedRelationships = take(diff.addedRelationships.sort(byEdge));
+    diff.removedRelationships = take(diff.removedRelationships.sort(byEdge));
+  }
+
+  // Files whose every change fell past the limit are dropped rather than listed empty
+  const listed = ordered.filter((d) => changeCount(d) > 0);
+  return { from: options.from, to: options.to ?? null, summary, files: listed, truncated };
+}
@@ -0,0 +1,180 @@
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import type { Entity } from "../types/storage.js";
+import { type CallEdge, type CallGraphResult, listCallees, listCallers } from "./call-graph.js";
+import { rankDefinitionsByProximity } from "./find-definition.js";
+import { findSymbolDefinitions } from "./find-references.js";
+import { getEntitySource } from "./get-entity-source.js";
+
+export type ExplainedEntity = {
+  id: string;
+  name: string;
+  type: string;
+  filePath: string;
+  startLine: number | null;
+  endLine: number | null;
+};
+
+export type ExplainedSymbol = ExplainedEntity & {
+  language: string | null;
+  signature: string | null;
+  parameters: Array<{ name: string; type?: string; optional?: boolean; defaultValue?: string }>;
+  returnType: string | null;
+  modifiers: string[];
+  /** Go receiver type, or the class or struct a member belongs to */
+  owner: string | null;
+};
+
+export type ExplainedCalls = {
+  items: CallEdge[];
+  total: number;
+  truncated: boolean;
+};
+
+export type ExplainSymbolResult = {
+  symbol: ExplainedSymbol;
+  /** Doc comment or docstring as written */
+  documentation: string | null;
+  /** null when the file could not be read */
+  source: { snippet: string; startLine: number; endLine: number; truncated: boolean } | null;
+  callers: ExplainedCalls;
+  callees: ExplainedCalls;
+  /** Closest entities by search vector; null when semantic search was not available */
+  similar: Array<ExplainedEntity & { score: number }> | null;
+  /** Other declarations the name matched; pass one's id as entityId to explain it instead */
+  alternatives: ExplainedEntity[];
+};
+
+export type SimilarEntityFinder = (entity: Entity, limit: number) => Promise<Array<{ entity: Entity; score: number }>>;
+
+export type ExplainSymbolOptions = {
+  symbol?: string;
+  entityId?: string;
+  /** File declaring the symbol, or where it was seen; closer declarations are preferred */
+  filePath?: string;
+  qualifier?: string;
+  entityType?: string;
+  /** Callers, callees and similar entities returned at most, each */
+  neighbors?: number;
+  maxBytes?: number;
+  /** Path the source is read from, for stored paths that are not readable as they are */
+  resolvePath?: (filePath: string) => string;
+  /** Omitted or failing, `similar` is null */
+  findSimilar?: SimilarEntityFinder;
+};
+
+/** Names that match a symbol without declaring it */
+const NOT_DECLARATIONS = new Set(["import", "export", "comment"]);
+
+function summarize(entity: Entity): ExplainedEntity {
+  return {
+    id: entity.id,
+    name: entity.name,
+    type: String(entity.type),
+    filePath: entity.filePath,
+    startLine: entity.location?.start?.line ?? null,
+    endLine: entity.location?.end?.line ?? null,
+  };
+}
+
+function describe(entity: Entity): ExplainedSymbol {
+  const meta = (entity.metadata ?? {}) as Record<string, any>;
+  // `parent` is a class name for some analyzers and the id of the owning type for others
+  const parent = typeof meta.parent === "string" && !meta.parent.includes(":") ? meta.parent : undefined;
+  const owner = [meta.receiver, meta.className, parent].find((v) => typeof v === "string" && v);
+  return {
+    ...summarize(entity),
+    language: typeof meta.language === "string" ? meta.language : null,
+    signature: typeof meta.signature === "string" ? meta.signature : null,
+    parameters: Array.isArray(meta.parameters) ? meta.parameters : [],
+    returnType: typeof meta.returnType === "string" ? meta.returnType : null,
+    modifiers: Array.isArray(meta.modifiers) ? meta.modifiers : [],
+    owner: owner ?? null,
+  };
+}
+
+/** The calls of `entity` itself among those of every declaration sharing its name and file */
+function callsOf(result: CallGraphResult, entity: Entity, limit: number): ExplainedCalls {
+  const calls = result.definitions.find((group) => group.definition.id === entity.id)?.calls ?? [];
+  return { items: calls.slice(0, limit), total: calls.length, truncated: calls.length > limit };
+}
+
+async function resolveTarget(
+  storage: GraphStorageImpl,
+  options: ExplainSymbolOptions,
+): Promise<{ entity: Entity; alternatives: Entity[] } | null> {
+  if (options.entityId) {
+    const entity = await storage.getEntity(options.entityId);
+    if (entity) return { entity, alternatives: [] };
+  }
+  const symbol = options.symbol ?? options.entityId;
+  if (!symbol) return null;
+  // The file only ranks the declarations, so the others stay listed as alternatives
+  const found = await findSymbolDefinitions(storage, {
+    symbol,
+    qualifier: options.qualifier,
+    entityType: options.entityType,
+  });
+  const declarations = found.filter((entity) => !NOT_DECLARATIONS.has(String(entity.type)));
+  const ranked = rankDefinitionsByProximity(declarations.length > 0 ? declarations : found, options.filePath);
+  if (ranked.length === 0) return null;
+  return { entity: ranked[0]!.entity, alternatives: ranked.slice(1).map((candidate) => candidate.entity) };
+}
+
+/**
+ * Everything needed to understand one symbol in a single bundle: its declaration and signature,
+ * documentation, source, the functions calling it and called by it, and the entities closest to it
+ * in the vector index. A name matching several declarations explains the closest to `filePath` and
+ * lists the others. Returns null when nothing matches.
+ */
+export async function explainSymbol(
+  storage: GraphStorageImpl,
+  options: ExplainSymbolOptions,
+): Promise<ExplainSymbolResult | null> {
+  const target = await resolveTarget(storage, options);
+  if (!target) return null;
+  const { entity } = target;
+  const neighbors = Math.max(1, Math.min(100, Number(options.neighbors ?? 10) || 10));
+
+  let source: ExplainSymbolResult["source"] = null;
+  try {
+    const extracted = await getEntitySource({
+      storage,
+      entity,
+      filePath: options.resolvePath?.(entity.filePath) ?? entity.filePath,
+      contextLines: 0,
+      maxBytes: options.maxBytes,
+    });
+    source = { snippet: extracted.snippet, ...extracted.snippetRange, truncated: extracted.truncated };
+  } catch {
+    // Deleted or moved since it was indexed
+  }
+
+  // Every call edge of the declaration, so totals count them all before the page is cut
+  const lookup = { symbol: entity.name, filePath: entity.filePath, entityType: String(entity.type), limit: 1000 };
+  const callers = callsOf(await listCallers(storage, lookup), entity, neighbors);
+  const callees = callsOf(await listCallees(storage, lookup), entity, neighbors);
+
+  let similar: ExplainSymbolResult["similar"] = null;
+  if (options.findSimilar) {
+    try {
+      const matches = await options.findSimilar(entity, neighbors);
+      similar = matches
+        .filter((match) => match.entity.id !== entity.id)
+        .slice(0, neighbors)
+        .map((match) => ({ ...summarize(match.entity), score: match.score }));
+    } catch {
+      similar = null;
+    }
+  }
+
+  const documentation = (entity.metadata as Record<string, unknown> | undefined)?.documentation;
+  return {
+    symbol: describe(entity),
+    documentation: typeof documentation === "string" && documentation ? documentation : null,
+    source,
+    callers,
+    callees,
+    similar,
+    alternatives: target.alternatives.slice(0, neighbors).map(summarize),
+  };
+}
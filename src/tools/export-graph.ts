@@ -0,0 +1,487 @@
+/**
+ * Serialize the indexed graph for external tooling (GraphML for Gephi/yEd, Cypher for Neo4j, DOT
+ * for Graphviz, JSON for scripts). Every format streams rows from keyset-paged cursors straight to
+ * disk, so memory stays flat however large the graph is.
+ */
+
+import { once } from "node:events";
+import { createWriteStream } from "node:fs";
+import { mkdir, rename, rm } from "node:fs/promises";
+import { dirname } from "node:path";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import type { Entity, Relationship } from "../types/storage.js";
+
+export type GraphExportFormat = "graphml" | "cypher" | "dot" | "json";
+
+export type GraphExportResult = {
+  format: GraphExportFormat;
+  outputPath: string;
+  nodes: number;
+  edges: number;
+  bytes: number;
+};
+
+export type GraphExportProgress = {
+  phase: "nodes" | "edges";
+  nodes: number;
+  edges: number;
+  totalNodes: number;
+  totalEdges: number;
+};
+
+export type GraphExportOptions = {
+  /** Called every PROGRESS_INTERVAL rows and once at the end of each phase */
+  onProgress?: (progress: GraphExportProgress) => void;
+};
+
+const PROGRESS_INTERVAL = 5000;
+
+/** Counts rows as they are written and reports them; totals are only counted when someone listens */
+class ProgressTracker {
+  nodes = 0;
+  edges = 0;
+
+  private constructor(
+    private readonly totals: { entities: number; relationships: number },
+    private readonly onProgress?: (progress: GraphExportProgress) => void,
+  ) {}
+
+  static async start(storage: GraphStorageImpl, options: GraphExportOptions): Promise<ProgressTracker> {
+    const totals = options.onProgress ? await storage.countGraph() : { entities: 0, relationships: 0 };
+    return new ProgressTracker(totals, options.onProgress);
+  }
+
+  node(): void {
+    this.nodes += 1;
+    if (this.nodes % PROGRESS_INTERVAL === 0) this.report("nodes");
+  }
+
+  edge(): void {
+    this.edges += 1;
+    if (this.edges % PROGRESS_INTERVAL === 0) this.report("edges");
+  }
+
+  report(phase: GraphExportProgress["phase"]): void {
+    this.onProgress?.({
+      phase,
+      nodes: this.nodes,
+      edges: this.edges,
+      // The graph may grow while it is exported; never report more done than there is
+      totalNodes: Math.max(this.totals.entities, this.nodes),
+      totalEdges: Math.max(this.totals.relationships, this.edges),
+    });
+  }
+}
+
+type GraphMLKey = { id: string; for: "node" | "edge"; name: string; type: "string" | "int" | "double" };
+
+const GRAPHML_KEYS: GraphMLKey[] = [
+  { id: "n_name", for: "node", name: "name", type: "string" },
+  { id: "n_kind", for: "node", name: "kind", type: "string" },
+  { id: "n_language", for: "node", name: "language", type: "string" },
+  { id: "n_file", for: "node", name: "file", type: "string" },
+  { id: "n_start_line", for: "node", name: "startLine", type: "int" },
+  { id: "n_end_line", for: "node", name: "endLine", type: "int" },
+  { id: "n_qualified_name", for: "node", name: "qualifiedName", type: "string" },
+  { id: "e_type", for: "edge", name: "type", type: "string" },
+  { id: "e_weight", for: "edge", name: "weight", type: "double" },
+  { id: "e_line", for: "edge", name: "line", type: "int" },
+];
+
+// Characters XML 1.0 does not allow at all, even escaped
+// biome-ignore lint/suspicious/noControlCharactersInRegex: stripping control characters is the point
+const INVALID_XML_CHARS = /[\u0000-\u0008\u000B\u000C\u000E-\u001F\uFFFE\uFFFF]/g;
+
+export function escapeXml(value: string): string {
+  return value
+    .replace(INVALID_XML_CHARS, "")
+    .replace(/&/g, "&amp;")
+    .replace(/</g, "&lt;")
+    .replace(/>/g, "&gt;")
+    .replace(/"/g, "&quot;");
+}
+
+function dataElement(key: string, value: unknown): string {
+  if (value === undefined || value === null || value === "") return "";
+  if (typeof value === "number" && !Number.isFinite(value)) return "";
+  return `<data key="${key}">${escapeXml(String(value))}</data>`;
+}
+
+function nodeElement(entity: Entity): string {
+  const meta = (entity.metadata ?? {}) as Record<string, unknown>;
+  const language = entity.language ?? meta.language;
+  const data = [
+    dataElement("n_name", entity.name),
+    dataElement("n_kind", entity.type),
+    dataElement("n_language", typeof language === "string" ? language : undefined),
+    dataElement("n_file", entity.filePath),
+    dataElement("n_start_line", entity.location?.start?.line),
+    dataElement("n_end_line", entity.location?.end?.line),
+    dataElement("n_qualified_name", typeof meta.qualifiedName === "string" ? meta.qualifiedName : undefined),
+  ].join("");
+  return `    <node id="${escapeXml(entity.id)}">${data}</node>\n`;
+}
+
+function edgeElement(rel: Relationship): string {
+  const data = [
+    dataElement("e_type", rel.type),
+    dataElement("e_weight", rel.weight),
+    dataElement("e_line", rel.metadata?.line),
+  ].join("");
+  const attrs = `id="${escapeXml(rel.id)}" source="${escapeXml(rel.fromId)}" target="${escapeXml(rel.toId)}"`;
+  return `    <edge ${attrs}>${data}</edge>\n`;
+}
+
+export type Emit = (chunk: string) => Promise<void>;
+
+/**
+ * Stream `body` into a temp file next to `outputPath` and rename it into place once complete, so
+ * a failed or interrupted export never leaves a truncated file behind. Returns bytes written.
+ */
+export async function writeAtomically(outputPath: string, body: (emit: Emit) => Promise<void>): Promise<number> {
+  await mkdir(dirname(outputPath), { recursive: true });
+  const tempPath = `${outputPath}.${process.pid}.tmp`;
+  const stream = createWriteStream(tempPath, { encoding: "utf8" });
+  const failed = new Promise<never>((_, reject) => stream.once("error", reject));
+  failed.catch(() => {});
+
+  const emit: Emit = async (chunk) => {
+    if (!stream.write(chunk)) await once(stream, "drain");
+  };
+  const run = async () => {
+    await body(emit);
+    stream.end();
+    await once(stream, "finish");
+  };
+
+  try {
+    await Promise.race([run(), failed]);
+    await rename(tempPath, outputPath);
+  } catch (error) {
+    stream.destroy();
+    await rm(tempPath, { force: true });
+    throw error;
+  }
+  return stream.bytesWritten;
+}
+
+/**
+ * Write entities as nodes and relationships as typed edges. Both are emitted in id order and ids
+ * are the stored (content-derived) ids, so re-exporting an unchanged graph yields identical files.
+ */
+export async function exportGraphML(
+  storage: GraphStorageImpl,
+  outputPath: string,
+  options: GraphExportOptions = {},
+): Promise<GraphExportResult> {
+  const progress = await ProgressTracker.start(storage, options);
+  const bytes = await writeAtomically(outputPath, async (emit) => {
+    await emit('<?xml version="1.0" encoding="UTF-8"?>\n');
+    await emit('<graphml xmlns="http://graphml.graphdrawing.org/xmlns">\n');
+    for (const key of GRAPHML_KEYS) {
+      await emit(`  <key id="${key.id}" for="${key.for}" attr.name="${key.name}" attr.type="${key.type}"/>\n`);
+    }
+    await emit('  <graph id="code-graph" edgedefault="directed">\n');
+
+    for await (const entity of storage.iterateEntities()) {
+      await emit(nodeElement(entity));
+      progress.node();
+    }
+    progress.report("nodes");
+    for await (const rel of storage.iterateRelationships()) {
+      await emit(edgeElement(rel));
+      progress.edge();
+    }
+    progress.report("edges");
+
+    await emit("  </graph>\n</graphml>\n");
+  });
+
+  return { format: "graphml", outputPath, nodes: progress.nodes, edges: progress.edges, bytes };
+}
+
+// =============================================================================
+// Cypher (Neo4j)
+// =============================================================================
+
+const CYPHER_BATCH_SIZE = 500;
+const CYPHER_BASE_LABEL = "CodeEntity";
+
+const CYPHER_HEADER = `// Code graph export for Neo4j. Run with: cypher-shell -f <file>
+// Re-running the file is idempotent: nodes are MERGEd on qualifiedName, edges on (source, type, target).
+//
+// Nodes  (:${CYPHER_BASE_LABEL}:<Kind>, e.g. :${CYPHER_BASE_LABEL}:Function, :${CYPHER_BASE_LABEL}:Class)
+//   qualifiedName  merge key: "<file>::<qualified or plain name>", suffixed "@<line>:<column>" only
+//                  when several entities in one file share that name
+//   id             stored entity id (used to connect edges within this export)
+//   name           entity name as written in source
+//   kind           entity type (function, class, method, ...)
+//   language       source language, when known
+//   file           absolute path of the defining file
+//   startLine, endLine
+// Edges  [:<TYPE>] with the relationship type upper-cased (CALLS, IMPORTS, CONTAINS, ...)
+//   id, line, weight
+`;
+
+type CypherValue = string | number | boolean | null | undefined;
+type CypherRow = { [key: string]: CypherValue | CypherRow };
+
+function cypherLiteral(value: CypherValue | CypherRow): string {
+  if (value === null || value === undefined) return "null";
+  if (typeof value === "string") return JSON.stringify(value);
+  if (typeof value === "number") return Number.isFinite(value) ? String(value) : "null";
+  if (typeof value === "boolean") return String(value);
+  const fields = Object.entries(value)
+    .filter(([, v]) => v !== undefined && v !== null)
+    .map(([k, v]) => `${k}: ${cypherLiteral(v)}`);
+  return `{${fields.join(", ")}}`;
+}
+
+/** `function` → `Function`, `depends_on` → `DependsOn`; anything outside [A-Za-z0-9_] is dropped */
+function cypherLabel(kind: string): string {
+  const label = kind
+    .split(/[^A-Za-z0-9]+/)
+    .filter(Boolean)
+    .map((part) => part[0]!.toUpperCase() + part.slice(1))
+    .join("");
+  return `\`${label || "Unknown"}\``;
+}
+
+function cypherRelType(type: string): string {
+  const relType = type.replace(/[^A-Za-z0-9]+/g, "_").toUpperCase();
+  return `\`${relType || "RELATED_TO"}\``;
+}
+
+function entityKeyBase(entity: Entity): string {
+  const qualified = (entity.metadata as Record<string, unknown> | undefined)?.qualifiedName;
+  return `${entity.filePath}::${typeof qualified === "string" && qualified ? qualified : entity.name}`;
+}
+
+/**
+ * Buffers rows per label (or relationship type) and flushes each group as one UNWIND statement
+ * once it reaches the batch size, so imports issue a handful of statements instead of one per row.
+ */
+class CypherBatcher {
+  private readonly groups = new Map<string, CypherRow[]>();
+
+  constructor(
+    private readonly emit: Emit,
+    private readonly statementFor: (group: string) => string,
+  ) {}
+
+  async add(group: string, row: CypherRow): Promise<void> {
+    const rows = this.groups.get(group) ?? [];
+    rows.push(row);
+    this.groups.set(group, rows);
+    if (rows.length >= CYPHER_BATCH_SIZE) await this.flushGroup(group);
+  }
+
+  async flush(): Promise<void> {
+    for (const group of Array.from(this.groups.keys()).sort()) {
+      await this.flushGroup(group);
+    }
+  }
+
+  private async flushGroup(group: string): Promise<void> {
+    const rows = this.groups.get(group);
+    if (!rows || rows.length === 0) return;
+    this.groups.delete(group);
+    const list = rows.map((row) => `  ${cypherLiteral(row)}`).join(",\n");
+    await this.emit(`UNWIND [\n${list}\n] AS row\n${this.statementFor(group)};\n\n`);
+  }
+}
+
+/**
+ * Write the graph as batched, idempotent Cypher. Nodes go first (all of them, so every edge finds
+ * both endpoints), then edges matched by entity id.
+ */
+export async function exportCypher(
+  storage: GraphStorageImpl,
+  outputPath: string,
+  options: GraphExportOptions = {},
+): Promise<GraphExportResult> {
+  // Names that are not unique within their file get their position appended to the key
+  const duplicateKeys = await storage.duplicateEntityKeys();
+  const progress = await ProgressTracker.start(storage, options);
+  const bytes = await writeAtomically(outputPath, async (emit) => {
+    await emit(CYPHER_HEADER);
+    await emit(
+      `\nCREATE CONSTRAINT code_entity_key IF NOT EXISTS FOR (n:${CYPHER_BASE_LABEL}) ` +
+        "REQUIRE n.qualifiedName IS UNIQUE;\n",
+    );
+    await emit(`CREATE INDEX code_entity_id IF NOT EXISTS FOR (n:${CYPHER_BASE_LABEL}) ON (n.id);\n\n`);
+
+    const nodeBatches = new CypherBatcher(
+      emit,
+      (label) => `MERGE (n:${CYPHER_BASE_LABEL} {qualifiedName: row.qualifiedName})\nSET n:${label}, n += row`,
+    );
+    for await (const entity of storage.iterateEntities()) {
+      const meta = (entity.metadata ?? {}) as Record<string, unknown>;
+      const language = entity.language ?? meta.language;
+      const base = entityKeyBase(entity);
+      const start = entity.location?.start;
+      const qualifiedName = duplicateKeys.has(base) ? `${base}@${start?.line}:${start?.column}` : base;
+
+      await nodeBatches.add(cypherLabel(entity.type), {
+        qualifiedName,
+        id: entity.id,
+        name: entity.name,
+        kind: entity.type,
+        language: typeof language === "string" ? language : undefined,
+        file: entity.filePath,
+        startLine: start?.line,
+        endLine: entity.location?.end?.line,
+      });
+      progress.node();
+    }
+    await nodeBatches.flush();
+    progress.report("nodes");
+
+    const edgeBatches = new CypherBatcher(
+      emit,
+      (relType) =>
+        `MATCH (a:${CYPHER_BASE_LABEL} {id: row.from})\nMATCH (b:${CYPHER_BASE_LABEL} {id: row.to})\n` +
+        `MERGE (a)-[r:${relType}]->(b)\nSET r += row.props`,
+    );
+    for await (const rel of storage.iterateRelationships()) {
+      await edgeBatches.add(cypherRelType(rel.type), {
+        from: rel.fromId,
+        to: rel.toId,
+        props: { id: rel.id, line: rel.metadata?.line, weight: rel.weight },
+      });
+      progress.edge();
+    }
+    await edgeBatches.flush();
+    progress.report("edges");
+  });
+
+  return { format: "cypher", outputPath, nodes: progress.nodes, edges: progress.edges, bytes };
+}
+
+// =============================================================================
+// DOT (Graphviz) and JSON
+// =============================================================================
+
+export function dotId(value: string): string {
+  return `"${value.replace(/\\/g, "\\\\").replace(/"/g, '\\"').replace(/\n/g, "\\n")}"`;
+}
+
+/** The node attributes every format but Cypher writes, absent values left out */
+function nodeFields(entity: Entity): Record<string, string | number> {
+  const meta = (entity.metadata ?? {}) as Record<string, unknown>;
+  const language = entity.language ?? meta.language;
+  const fields: Record<string, string | number | undefined> = {
+    name: entity.name,
+    kind: entity.type,
+    language: typeof language === "string" ? language : undefined,
+    file: entity.filePath,
+    startLine: entity.location?.start?.line,
+    endLine: entity.location?.end?.line,
+    qualifiedName: typeof meta.qualifiedName === "string" ? meta.qualifiedName : undefined,
+  };
+  return Object.fromEntries(
+    Object.entries(fields).filter((entry): entry is [string, string | number] => {
+      const value = entry[1];
+      return typeof value === "string" ? value !== "" : typeof value === "number" && Number.isFinite(value);
+    }),
+  );
+}
+
+function dotAttributes(fields: Record<string, string | number | undefined>): string {
+  return Object.entries(fields)
+    .filter(([, value]) => value !== undefined)
+    .map(([key, value]) => `${key}=${typeof value === "number" ? value : dotId(String(value))}`)
+    .join(", ");
+}
+
+/**
+ * Write a Graphviz digraph, nodes labelled with their names and edges with their types. Graphviz
+ * itself struggles past a few thousand nodes; for big graphs the file is meant for other DOT
+ * readers or for cutting down first.
+ */
+export async function exportDot(
+  storage: GraphStorageImpl,
+  outputPath: string,
+  options: GraphExportOptions = {},
+): Promise<GraphExportResult> {
+  const progress = await ProgressTracker.start(storage, options);
+  const bytes = await writeAtomically(outputPath, async (emit) => {
+    await emit('digraph "code-graph" {\n  node [shape=box, fontsize=10];\n');
+    for await (const entity of storage.iterateEntities()) {
+      const { name, ...fields } = nodeFields(entity);
+      await emit(`  ${dotId(entity.id)} [${dotAttributes({ label: name, ...fields })}];\n`);
+      progress.node();
+    }
+    progress.report("nodes");
+    for await (const rel of storage.iterateRelationships()) {
+      const line = rel.metadata?.line;
+      const attrs = dotAttributes({
+        label: rel.type,
+        id: rel.id,
+        weight: typeof rel.weight === "number" && Number.isFinite(rel.weight) ? rel.weight : undefined,
+        line: typeof line === "number" ? line : undefined,
+      });
+      await emit(`  ${dotId(rel.fromId)} -> ${dotId(rel.toId)} [${attrs}];\n`);
+      progress.edge();
+    }
+    progress.report("edges");
+    await emit("}\n");
+  });
+
+  return { format: "dot", outputPath, nodes: progress.nodes, edges: progress.edges, bytes };
+}
+
+/**
+ * Write `{"nodes": [...], "edges": [...]}` with one node or edge per line, so the file is both
+ * one JSON document and easy to diff or cut with line tools.
+ */
+export async function exportJson(
+  storage: GraphStorageImpl,
+  outputPath: string,
+  options: GraphExportOptions = {},
+): Promise<GraphExportResult> {
+  const progress = await ProgressTracker.start(storage, options);
+  const bytes = await writeAtomically(outputPath, async (emit) => {
+    await emit('{\n"nodes": [\n');
+    for await (const entity of storage.iterateEntities()) {
+      const separator = progress.nodes === 0 ? "" : ",\n";
+      await emit(`${separator}${JSON.stringify({ id: entity.id, ...nodeFields(entity) })}`);
+      progress.node();
+    }
+    progress.report("nodes");
+    await emit(progress.nodes === 0 ? '],\n"edges": [\n' : '\n],\n"edges": [\n');
+    for await (const rel of storage.iterateRelationships()) {
+      const separator = progress.edges === 0 ? "" : ",\n";
+      const edge = {
+        id: rel.id,
+        source: rel.fromId,
+        target: rel.toId,
+        type: rel.type,
+        weight: typeof rel.weight === "number" && Number.isFinite(rel.weight) ? rel.weight : undefined,
+        line: typeof rel.metadata?.line === "number" ? rel.metadata.line : undefined,
+      };
+      await emit(`${separator}${JSON.stringify(edge)}`);
+      progress.edge();
+    }
+    progress.report("edges");
+    await emit(progress.edges === 0 ? "]\n}\n" : "\n]\n}\n");
+  });
+
+  return { format: "json", outputPath, nodes: progress.nodes, edges: progress.edges, bytes };
+}
+
+const EXPORTERS = {
+  graphml: exportGraphML,
+  cypher: exportCypher,
+  dot: exportDot,
+  json: exportJson,
+} satisfies Record<GraphExportFormat, typeof exportGraphML>;
+
+export async function exportGraph(
+  storage: GraphStorageImpl,
+  format: GraphExportFormat,
+  outputPath: string,
+  options: GraphExportOptions = {},
+): Promise<GraphExportResult> {
+  return EXPORTERS[format](storage, outputPath, options);
+}
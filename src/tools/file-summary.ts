@@ -0,0 +1,198 @@
+import { readFile } from "node:fs/promises";
+import { encloses } from "../core/override-resolver.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { type Entity, RelationType } from "../types/storage.js";
+import { isExternalPlaceholder } from "./find-references.js";
+import { isExported } from "./find-unused.js";
+import { signatureOf } from "./list-members.js";
+
+export type FileImport = {
+  /** Module, package or path as written in the import */
+  source: string;
+  /** Names the import binds in the file; empty for side-effect and wildcard imports */
+  names: string[];
+  line: number | null;
+  /** Indexed files the import was bound to; empty when it points outside the index */
+  resolved: string[];
+};
+
+export type FileSurfaceEntity = {
+  id: string;
+  name: string;
+  type: string;
+  signature: string | null;
+  startLine: number | null;
+  endLine: number | null;
+};
+
+export type FileSummary = {
+  filePath: string;
+  language: string | null;
+  /** The file's own doc comment: package or module documentation, else a comment block opening the file */
+  documentation: string | null;
+  imports: FileImport[];
+  /** Exported or public top-level declarations, in source order */
+  exports: FileSurfaceEntity[];
+  /** Top-level declarations that are not exported, and members marked private or protected */
+  private: { topLevel: number; members: number };
+  /** Entities stored for the file, imports and comments included */
+  entities: number;
+};
+
+// Entities that are not declarations of the file
+const NON_DECLARATIONS = new Set(["import", "comment", "module", "package", "namespace", "file"]);
+
+const LICENSE_HEADER = /copyright|SPDX-License-Identifier|licensed under/i;
+
+function meta(entity: Entity): Record<string, any> {
+  return (entity.metadata as Record<string, any> | undefined) ?? {};
+}
+
+const PRIVATE_MODIFIERS = new Set(["private", "protected", "fileprivate"]);
+
+function isPrivateMember(entity: Entity): boolean {
+  const m = meta(entity);
+  if (m.isPublic === false) return true;
+  if (Array.isArray(m.modifiers) && m.modifiers.some((mod: string) => PRIVATE_MODIFIERS.has(mod))) return true;
+  if (typeof m.visibility === "string" && m.visibility) return !m.visibility.startsWith("pub");
+  return entity.name.startsWith("#") || (entity.filePath.endsWith(".py") && entity.name.startsWith("_"));
+}
+
+function byPosition(a: Entity, b: Entity): number {
+  return (a.location?.start?.line ?? 0) - (b.location?.start?.line ?? 0) || a.id.localeCompare(b.id);
+}
+
+function importNames(entity: Entity): string[] {
+  const data = meta(entity).importData;
+  if (!data) return [];
+  const names: string[] = [];
+  for (const specifier of data.specifiers ?? []) {
+    if (typeof specifier?.local === "string") names.push(specifier.local);
+  }
+  for (const local of [data.local, data.namespace]) {
+    if (typeof local === "string" && local) names.push(local);
+  }
+  return [...new Set(names)];
+}
+
+/**
+ * The comment block a file opens with (after a shebang and license headers), when a blank line
+ * separates it from the code below; one directly above a declaration documents that declaration.
+ * Python module docstrings count either way.
+ */
+export function leadingFileComment(source: string): string | null {
+  const lines = source.split(/\r?\n/);
+  let i = lines[0]?.startsWith("#!") ? 1 : 0;
+  while (i < lines.length) {
+    while (i < lines.length && !lines[i]!.trim()) i++;
+    const first = lines[i]?.trim() ?? "";
+    let end = i;
+    const docstring = first.startsWith('"""') || first.startsWith("'''");
+    if (first.startsWith("/*")) {
+      while (end < lines.length && !lines[end]!.includes("*/")) end++;
+    } else if (docstring) {
+      const quote = first.slice(0, 3);
+      if (first.length < 6 || !first.endsWith(quote)) {
+        end++;
+        while (end < lines.length && !lines[end]!.includes(quote)) end++;
+      }
+    } else if (/^(\/\/|#(?![!\[]|include|define|if|pragma|import))/.test(first)) {
+      const marker = first.startsWith("//") ? "//" : "#";
+      while (end + 1 < lines.length && lines[end + 1]!.trim().startsWith(marker)) end++;
+    } else {
+      return null;
+    }
+    if (end >= lines.length) return null;
+    const block = lines.slice(i, end + 1).join("\n");
+    const next = lines[end + 1];
+    if (!docstring && next !== undefined && next.trim()) return null;
+    if (!LICENSE_HEADER.test(block)) return block;
+    i = end + 1;
+  }
+  return null;
+}
+
+/** First line of a declaration, without its opening brace */
+function declarationLine(lines: string[], entity: Entity): string | null {
+  const line = lines[(entity.location?.start?.line ?? 0) - 1]?.trim();
+  return line ? line.replace(/\s*[{:]?\s*$/, "") || null : null;
+}
+
+/**
+ * The public surface of one file: its imports with the files they were bound to, its exported or
+ * public top-level declarations with signatures, how many declarations are private, and its doc
+ * comment. A declaration is top-level when no other declaration of the file encloses it.
+ */
+export async function fileSummary(storage: GraphStorageImpl, filePath: string): Promise<FileSummary | null> {
+  const entities = (await storage.findEntities({ type: "entity", filters: { filePath }, limit: 10000 }))
+    .filter((entity) => !isExternalPlaceholder(entity))
+    .sort(byPosition);
+  if (entities.length === 0) return null;
+
+  let source: string | null = null;
+  try {
+    source = await readFile(filePath, "utf8");
+  } catch {
+    // Summaries still come from the graph when the file is gone or unreadable
+  }
+  const lines = source?.split(/\r?\n/) ?? [];
+
+  const imports: FileImport[] = [];
+  for (const entity of entities.filter((e) => String(e.type) === "import")) {
+    const resolved = new Set<string>();
+    for (const rel of await storage.getRelationshipsForEntity(entity.id, RelationType.IMPORTS)) {
+      if (rel.fromId !== entity.id) continue;
+      const resolvedFile = rel.metadata?.resolvedFile;
+      if (typeof resolvedFile === "string") {
+        resolved.add(resolvedFile);
+        continue;
+      }
+      const target = await storage.getEntity(rel.toId);
+      if (target && !isExternalPlaceholder(target) && target.filePath !== filePath) resolved.add(target.filePath);
+    }
+    imports.push({
+      source: typeof meta(entity).importData?.source === "string" ? meta(entity).importData.source : entity.name,
+      names: importNames(entity),
+      line: entity.location?.start?.line ?? null,
+      resolved: [...resolved].sort(),
+    });
+  }
+
+  const declarations = entities.filter((e) => !NON_DECLARATIONS.has(String(e.type)));
+  const exports: FileSurfaceEntity[] = [];
+  const privates = { topLevel: 0, members: 0 };
+  for (const entity of declarations) {
+    const topLevel = !declarations.some((outer) => encloses(outer, entity) && !encloses(entity, outer));
+    if (!topLevel) {
+      if (isPrivateMember(entity)) privates.members += 1;
+      continue;
+    }
+    if (String(entity.type) === "export" || isExported(entity)) {
+      exports.push({
+        id: entity.id,
+        name: entity.name,
+        type: String(entity.type),
+        signature: signatureOf(entity) ?? declarationLine(lines, entity),
+        startLine: entity.location?.start?.line ?? null,
+        endLine: entity.location?.end?.line ?? null,
+      });
+    } else {
+      privates.topLevel += 1;
+    }
+  }
+
+  const documented = entities.find((e) => String(e.type) === "module" && e.metadata?.documentation);
+  const documentation =
+    (typeof documented?.metadata?.documentation === "string" ? documented.metadata.documentation : null) ??
+    (source !== null ? leadingFileComment(source) : null);
+
+  return {
+    filePath,
+    language: entities.map((e) => e.language ?? e.metadata?.language).find(Boolean) ?? null,
+    documentation,
+    imports,
+    exports,
+    private: privates,
+    entities: entities.length,
+  };
+}
@@ -0,0 +1,135 @@
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import type { Entity } from "../types/storage.js";
+import type { LastModified } from "../utils/git-blame.js";
+
+export type AuthoredEntity = {
+  id: string;
+  name: string;
+  type: string;
+  filePath: string;
+  startLine: number | null;
+  endLine: number | null;
+  lastModified: LastModified;
+};
+
+export type AuthorSummary = {
+  author: string;
+  email: string;
+  entities: number;
+  /** ISO date of the author's newest change among the matched entities */
+  lastDate: string;
+};
+
+export type FindByAuthorResult = {
+  entities: AuthoredEntity[];
+  /** Everyone who last modified a matched entity, most entities first */
+  authors: AuthorSummary[];
+  total: number;
+  truncated: boolean;
+  /** Matched entities before the date filter; 0 without an author filter means the index has no blame data */
+  blamed: number;
+};
+
+export type FindByAuthorOptions = {
+  /** Part of the author name (case-insensitive) or the exact email; omit to list every author */
+  author?: string;
+  pathPrefix?: string;
+  kinds?: string[];
+  /** Only entities last modified at or after / before this date */
+  since?: Date;
+  until?: Date;
+  sort?: "recent" | "oldest" | "author";
+  limit?: number;
+};
+
+const PAGE_SIZE = 1000;
+
+function lastModifiedOf(entity: Entity): LastModified | null {
+  const value = (entity.metadata as Record<string, unknown> | undefined)?.lastModified as LastModified | undefined;
+  return value && typeof value.time === "number" && typeof value.author === "string" ? value : null;
+}
+
+async function loadEntities(storage: GraphStorageImpl, options: FindByAuthorOptions): Promise<Entity[]> {
+  const entities: Entity[] = [];
+  const scope =
+    options.pathPrefix || options.kinds?.length ? { pathPrefix: options.pathPrefix, kinds: options.kinds } : undefined;
+  let afterId: string | undefined;
+  while (true) {
+    const page = await storage.findEntities({
+      type: "entity",
+      filters: { scope, author: options.author },
+      afterId,
+      limit: PAGE_SIZE,
+    });
+    entities.push(...page);
+    if (page.length < PAGE_SIZE) return entities;
+    afterId = page[page.length - 1]!.id;
+  }
+}
+
+/**
+ * Entities by who last changed them, from the `git blame` ownership recorded at index time
+ * (`gitBlame: true`). Newest changes come first unless another order is asked for.
+ */
+export async function findByAuthor(
+  storage: GraphStorageImpl,
+  options: FindByAuthorOptions = {},
+): Promise<FindByAuthorResult> {
+  const limit = Math.max(1, Math.min(5000, Number(options.limit ?? 200) || 200));
+  const since = options.since ? options.since.getTime() / 1000 : -Infinity;
+  const until = options.until ? options.until.getTime() / 1000 : Infinity;
+
+  let blamed = 0;
+  const matched: AuthoredEntity[] = [];
+  for (const entity of await loadEntities(storage, options)) {
+    const lastModified = lastModifiedOf(entity);
+    if (!lastModified) continue;
+    blamed++;
+    if (lastModified.time < since || lastModified.time >= until) continue;
+    matched.push({
+      id: entity.id,
+      name: entity.name,
+      type: String(entity.type),
+      filePath: entity.filePath,
+      startLine: entity.location?.start?.line ?? null,
+      endLine: entity.location?.end?.line ?? null,
+      lastModified,
+    });
+  }
+
+  const byAuthor = new Map<string, AuthorSummary & { time: number }>();
+  for (const { lastModified } of matched) {
+    const key = lastModified.email || lastModified.author;
+    const summary = byAuthor.get(key);
+    if (!summary) {
+      byAuthor.set(key, { ...lastModified, entities: 1, lastDate: lastModified.date });
+    } else {
+      summary.entities++;
+      if (lastModified.time > summary.time) {
+        summary.time = lastModified.time;
+        summary.lastDate = lastModified.date;
+      }
+    }
+  }
+  const authors = [...byAuthor.values()]
+    .sort((a, b) => b.entities - a.entities || a.author.localeCompare(b.author))
+    .map(({ author, email, entities, lastDate }) => ({ author, email, entities, lastDate }));
+
+  const sort = options.sort ?? "recent";
+  matched.sort((a, b) => {
+    if (sort === "author") {
+      const byName = a.lastModified.author.localeCompare(b.lastModified.author);
+      if (byName !== 0) return byName;
+    }
+    const byTime = a.lastModified.time - b.lastModified.time;
+    return (sort === "oldest" ? byTime : -byTime) || a.id.localeCompare(b.id);
+  });
+
+  return {
+    entities: matched.slice(0, limit),
+    authors,
+    total: matched.length,
+    truncated: matched.length > limit,
+    blamed,
+  };
+}
@@ -0,0 +1,291 @@
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import type { Entity } from "../types/storage.js";
+
+/** One position of a signature pattern: a normalized type, `*` for any one type, `...` for any run */
+export type SignatureSlot = string;
+
+export type SignaturePattern = {
+  parameters: SignatureSlot[];
+  /** Result types; null when the pattern leaves them open */
+  returns: SignatureSlot[] | null;
+};
+
+export type SignatureMatch = {
+  id: string;
+  name: string;
+  type: string;
+  filePath: string;
+  startLine: number | null;
+  endLine: number | null;
+  signature: string;
+  /** Normalized parameter types; null entries are parameters without a declared type */
+  parameters: Array<string | null>;
+  /** Normalized result types; null when the language left the return type undeclared */
+  returns: string[] | null;
+  /** Some position only matched because the entity declares no type there */
+  partial: boolean;
+};
+
+export type FindBySignatureResult = {
+  matches: SignatureMatch[];
+  total: number;
+  truncated: boolean;
+  scanned: number;
+};
+
+export type FindBySignatureOptions = {
+  pattern: SignaturePattern;
+  pathPrefix?: string;
+  languages?: string[];
+  kinds?: string[];
+  /** Leave out matches that rely on undeclared parameter or return types */
+  requireTypes?: boolean;
+  limit?: number;
+};
+
+const PAGE_SIZE = 1000;
+
+const CALLABLE_KINDS = [
+  "function",
+  "method",
+  "async_function",
+  "magic_method",
+  "class_method",
+  "static_method",
+  "abstract_method",
+  "constructor",
+  "arrow_function",
+];
+
+/** Leading words that belong to a type rather than naming a parameter (`chan int`, `unsigned long`) */
+const TYPE_WORDS = new Set([
+  "chan",
+  "func",
+  "map",
+  "struct",
+  "interface",
+  "const",
+  "unsigned",
+  "signed",
+  "long",
+  "short",
+  "volatile",
+  "final",
+  "readonly",
+  "ref",
+  "out",
+  "in",
+  "params",
+  "mut",
+  "dyn",
+  "impl",
+  "keyof",
+  "typeof",
+  "unique",
+]);
+
+/** Receivers that analyzers list as the first parameter */
+const RECEIVER_PARAMETERS = new Set(["self", "&self", "&mut self", "mut self", "cls", "this"]);
+
+/** Split at commas outside brackets, parentheses, braces and generics */
+function splitTopLevel(text: string): string[] {
+  const parts: string[] = [];
+  let depth = 0;
+  let start = 0;
+  for (let i = 0; i < text.length; i++) {
+    const ch = text[i];
+    if (ch === "(" || ch === "[" || ch === "{" || ch === "<") depth++;
+    else if ((ch === ")" || ch === "]" || ch === "}" || ch === ">") && text[i - 1] !== "=" && text[i - 1] !== "-")
+      depth--;
+    else if (ch === "," && depth === 0) {
+      parts.push(text.slice(start, i));
+      start = i + 1;
+    }
+  }
+  parts.push(text.slice(start));
+  return parts.map((part) => part.trim()).filter((part) => part.length > 0);
+}
+
+/** Index of the parenthesis closing the one at `open`, or -1 */
+function closingParen(text: string, open: number): number {
+  let depth = 0;
+  for (let i = open; i < text.length; i++) {
+    if (text[i] === "(") depth++;
+    else if (text[i] === ")" && --depth === 0) return i;
+  }
+  return -1;
+}
+
+/** `(a, b)` around the whole text, as Go result lists and Rust tuples are written */
+function tupleItems(text: string): string[] | null {
+  const trimmed = text.trim();
+  if (!trimmed.startsWith("(") || closingParen(trimmed, 0) !== trimmed.length - 1) return null;
+  return splitTopLevel(trimmed.slice(1, -1));
+}
+
+/**
+ * A type as compared across files: package, module and namespace qualifiers stripped so
+ * `*models.User`, `*m.User` and `*User` agree whatever the import alias, whitespace removed.
+ */
+export function normalizeSignatureType(text: string): string {
+  const type = text.replace(/(?<![\w$.])(?:[A-Za-z_$][\w$]*(?:\.|::))+(?=[A-Za-z_$])/g, "").replace(/\s+/g, "");
+  return type === "interface{}" ? "any" : type;
+}
+
+/** The type of a `name: type` or Go-style `name type` entry; anything else is returned as is */
+function withoutName(text: string): string {
+  const trimmed = text.trim();
+  const named = /^[A-Za-z_$][\w$]*\??\s*:(?!:)\s*(\S[\s\S]*)$/.exec(trimmed);
+  if (named) return named[1]!;
+  const goNamed = /^([A-Za-z_]\w*)\s+([A-Za-z_*[.(<][\s\S]*)$/.exec(trimmed);
+  return goNamed && !TYPE_WORDS.has(goNamed[1]!) ? goNamed[2]! : trimmed;
+}
+
+function patternSlot(text: string): SignatureSlot {
+  const trimmed = text.trim();
+  if (trimmed === "*" || trimmed === "_") return "*";
+  return trimmed === "..." ? trimmed : normalizeSignatureType(withoutName(trimmed));
+}
+
+/**
+ * Read a signature shape such as `func(int) (*User, error)`, `(string, *) => Promise<User>` or
+ * `fn(&str) -> Result<Config>`. Returns null when the text has no parameter list.
+ */
+export function parseSignaturePattern(text: string): SignaturePattern | null {
+  const trimmed = text.trim().replace(/^(?:func|function|fn|def)\b\s*/, "");
+  const open = trimmed.indexOf("(");
+  if (open !== 0) return null;
+  const close = closingParen(trimmed, open);
+  if (close === -1) return null;
+
+  const parameters = splitTopLevel(trimmed.slice(open + 1, close)).map(patternSlot);
+  const rest = trimmed
+    .slice(close + 1)
+    .trim()
+    .replace(/^(?:->|=>|:)\s*/, "");
+  if (!rest) return { parameters, returns: null };
+  if (rest === "void") return { parameters, returns: [] };
+  const items = tupleItems(rest);
+  return { parameters, returns: (items ?? [rest]).map(patternSlot) };
+}
+
+/** Declared parameter types of a stored entity, receivers left out */
+function parameterTypesOf(entity: Entity): Array<string | null> {
+  const params = (entity.metadata as Record<string, unknown> | undefined)?.parameters;
+  if (!Array.isArray(params)) return [];
+  const types = params.map((p, i): string | null | undefined => {
+    // Go reports `name: type` strings, other analyzers `{ name, type }` objects
+    if (typeof p === "string") return normalizeSignatureType(withoutName(p));
+    const param = (p ?? {}) as { name?: string; type?: string };
+    if (i === 0 && param.name && RECEIVER_PARAMETERS.has(param.name)) return undefined;
+    return param.type ? normalizeSignatureType(param.type) : null;
+  });
+  return types.filter((type): type is string | null => type !== undefined);
+}
+
+/** Declared result types; Go and Rust spell "nothing" as a missing or `()` result */
+function returnTypesOf(entity: Entity): string[] | null {
+  const returnType = (entity.metadata as Record<string, unknown> | undefined)?.returnType;
+  if (typeof returnType !== "string" || !returnType.trim()) return entity.filePath.endsWith(".go") ? [] : null;
+  if (returnType.trim() === "void") return [];
+  const items = tupleItems(returnType);
+  // Go names its results (`(n int, err error)`)
+  return items ? items.map((item) => normalizeSignatureType(withoutName(item))) : [normalizeSignatureType(returnType)];
+}
+
+/**
+ * Whether `actual` fits `slots`; an undeclared actual type fits any slot but marks the match partial.
+ * `...` takes any run of positions, so the search backtracks over where each run ends.
+ */
+function matchSlots(slots: SignatureSlot[], actual: Array<string | null>): { partial: boolean } | null {
+  const visit = (s: number, a: number): { partial: boolean } | null => {
+    if (s === slots.length) return a === actual.length ? { partial: false } : null;
+    const slot = slots[s]!;
+    if (slot === "...") {
+      for (let end = a; end <= actual.length; end++) {
+        const rest = visit(s + 1, end);
+        if (rest) return rest;
+      }
+      return null;
+    }
+    if (a === actual.length) return null;
+    const type = actual[a];
+    if (slot !== "*" && type !== null && type !== slot) return null;
+    const rest = visit(s + 1, a + 1);
+    return rest && { partial: rest.partial || (slot !== "*" && type === null) };
+  };
+  return visit(0, 0);
+}
+
+function render(entity: Entity, parameters: Array<string | null>, returns: string[] | null): string {
+  const list = `${entity.name}(${parameters.map((type) => type ?? "?").join(", ")})`;
+  if (returns === null || returns.length === 0) return list;
+  return returns.length === 1 ? `${list} ${returns[0]}` : `${list} (${returns.join(", ")})`;
+}
+
+/**
+ * Functions and methods whose parameter and result types fit a signature pattern. Types compare
+ * after normalization, so aliases and qualifiers do not matter; positions may be left open with
+ * `*` or `...`. Entities without declared types (plain Python or JavaScript) match on arity only
+ * and come back marked partial.
+ */
+export async function findBySignature(
+  storage: GraphStorageImpl,
+  options: FindBySignatureOptions,
+): Promise<FindBySignatureResult> {
+  const limit = Math.max(1, Math.min(5000, Number(options.limit ?? 100) || 100));
+  const scope = {
+    kinds: options.kinds?.length ? options.kinds : CALLABLE_KINDS,
+    pathPrefix: options.pathPrefix,
+    languages: options.languages,
+  };
+  const { pattern } = options;
+
+  const matches: SignatureMatch[] = [];
+  let scanned = 0;
+  let afterId: string | undefined;
+  while (true) {
+    const page = await storage.findEntities({ type: "entity", filters: { scope }, afterId, limit: PAGE_SIZE });
+    for (const entity of page) {
+      scanned++;
+      const parameters = parameterTypesOf(entity);
+      const paramMatch = matchSlots(pattern.parameters, parameters);
+      if (!paramMatch) continue;
+      const returns = returnTypesOf(entity);
+      let partial = paramMatch.partial;
+      if (pattern.returns) {
+        if (returns === null) {
+          partial = true;
+        } else {
+          const returnMatch = matchSlots(pattern.returns, returns);
+          if (!returnMatch) continue;
+          partial ||= returnMatch.partial;
+        }
+      }
+      if (partial && options.requireTypes) continue;
+      matches.push({
+        id: entity.id,
+        name: entity.name,
+        type: String(entity.type),
+        filePath: entity.filePath,
+        startLine: entity.location?.start?.line ?? null,
+        endLine: entity.location?.end?.line ?? null,
+        signature: render(entity, parameters, returns),
+        parameters,
+        returns,
+        partial,
+      });
+    }
+    if (page.length < PAGE_SIZE) break;
+    afterId = page[page.length - 1]!.id;
+  }
+
+  // Fully typed matches first
+  matches.sort(
+    (a, b) =>
+      Number(a.partial) - Number(b.partial) ||
+      a.filePath.localeCompare(b.filePath) ||
+      (a.startLine ?? 0) - (b.startLine ?? 0),
+  );
+  return { matches: matches.slice(0, limit), total: matches.length, truncated: matches.length > limit, scanned };
+}
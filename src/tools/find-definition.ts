@@ -0,0 +1,81 @@
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import type { Entity } from "../types/storage.js";
+import { findSymbolDefinitions } from "./find-references.js";
+
+export type DefinitionCandidate = {
+  entity: Entity;
+  score: number;
+  reasons: string[];
+  range: { startLine: number; endLine: number };
+};
+
+function pathSegments(filePath: string): string[] {
+  return filePath.replace(/\\/g, "/").split("/").filter(Boolean);
+}
+
+function sharedDirectoryDepth(a: string[], b: string[]): number {
+  const max = Math.min(a.length, b.length) - 1; // ignore the file name itself
+  let depth = 0;
+  while (depth < max && a[depth] === b[depth]) depth++;
+  return depth;
+}
+
+/**
+ * Rank declarations by how close they live to the caller's file: same file, then
+ * same directory, then the longest shared directory prefix.
+ */
+export function rankDefinitionsByProximity(definitions: Entity[], contextFilePath?: string): DefinitionCandidate[] {
+  const contextSegments = contextFilePath ? pathSegments(contextFilePath) : null;
+  const contextDir = contextSegments ? contextSegments.slice(0, -1).join("/") : null;
+
+  return definitions
+    .map((entity) => {
+      const reasons: string[] = [];
+      let score = 0;
+
+      if (contextSegments) {
+        const segments = pathSegments(entity.filePath);
+        if (segments.join("/") === contextSegments.join("/")) {
+          score += 100;
+          reasons.push("same_file");
+        } else if (segments.slice(0, -1).join("/") === contextDir) {
+          score += 50;
+          reasons.push("same_directory");
+        } else {
+          const shared = sharedDirectoryDepth(segments, contextSegments);
+          if (shared > 0) {
+            score += Math.min(40, shared * 5);
+            reasons.push(`shared_path_depth_${shared}`);
+          }
+        }
+      }
+
+      // Prefer declarations over imports/re-exports of the same name
+      if (entity.type !== "import" && entity.type !== "export") {
+        score += 10;
+        reasons.push("declaration");
+      }
+
+      const startLine = Number(entity.location?.start?.line ?? 1) || 1;
+      const endLine = Number(entity.location?.end?.line ?? startLine) || startLine;
+      return { entity, score, reasons, range: { startLine, endLine } };
+    })
+    .sort(
+      (a, b) =>
+        b.score - a.score ||
+        a.entity.filePath.localeCompare(b.entity.filePath) ||
+        a.range.startLine - b.range.startLine,
+    );
+}
+
+export async function findDefinitionCandidates(
+  storage: GraphStorageImpl,
+  options: { symbol: string; contextFilePath?: string; qualifier?: string; entityType?: string; limit: number },
+): Promise<DefinitionCandidate[]> {
+  const definitions = await findSymbolDefinitions(storage, {
+    symbol: options.symbol,
+    qualifier: options.qualifier,
+    entityType: options.entityType,
+  });
+  return rankDefinitionsByProximity(definitions, options.contextFilePath).slice(0, options.limit);
+}
@@ -0,0 +1,188 @@
+import { MethodOwnership, TYPE_KINDS } from "../core/override-resolver.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { type Entity, RelationType } from "../types/storage.js";
+import { findSymbolDefinitions, isExternalPlaceholder, splitQualifiedSymbol } from "./find-references.js";
+
+export type OverrideEntity = {
+  id: string;
+  name: string;
+  type: string;
+  filePath: string;
+  startLine: number | null;
+  endLine: number | null;
+};
+
+export type OverridingMethod = {
+  method: OverrideEntity;
+  owner: OverrideEntity | null;
+  /** 1 for direct overrides, 2 for overrides of those, ... */
+  depth: number;
+  /** Id of the method this one directly overrides */
+  overrides: string;
+  /** Signature rule the edge was matched under (exact, parameters, name) */
+  rule: string | null;
+};
+
+export type OverrideDefinition = {
+  method: OverrideEntity;
+  owner: OverrideEntity | null;
+  /** Base methods this definition itself overrides */
+  overrides: OverrideEntity[];
+  overriddenBy: OverridingMethod[];
+};
+
+export type TypeMethodOverrides = {
+  method: OverrideEntity;
+  overrides: OverrideEntity[];
+  overriddenBy: number;
+};
+
+export type TypeOverrides = {
+  type: OverrideEntity;
+  methods: TypeMethodOverrides[];
+  /** Names of methods no subtype overrides */
+  neverOverridden: string[];
+};
+
+export type FindOverridesResult = {
+  symbol: string;
+  /** Filled when the symbol names a method */
+  definitions: OverrideDefinition[];
+  /** Filled when the symbol names a type: every method with its override counts */
+  types: TypeOverrides[];
+  total: number;
+  truncated: boolean;
+};
+
+export type FindOverridesOptions = {
+  /** Method name, optionally qualified by its type (`Animal.speak`), or a type name */
+  symbol: string;
+  filePath?: string;
+  qualifier?: string;
+  entityType?: string;
+  limit?: number;
+};
+
+function summarize(entity: Entity): OverrideEntity {
+  return {
+    id: entity.id,
+    name: entity.name,
+    type: String(entity.type),
+    filePath: entity.filePath,
+    startLine: entity.location?.start?.line ?? null,
+    endLine: entity.location?.end?.line ?? null,
+  };
+}
+
+function byLocation(a: OverrideEntity, b: OverrideEntity): number {
+  return a.filePath.localeCompare(b.filePath) || (a.startLine ?? 0) - (b.startLine ?? 0);
+}
+
+function ownerMatches(owner: Entity, qualifier: string): boolean {
+  return owner.name === qualifier || qualifier.endsWith(`.${owner.name}`) || qualifier.endsWith(`::${owner.name}`);
+}
+
+/**
+ * Implementations overriding a base method, found by walking stored overrides edges backwards
+ * (transitively, each method once). Given a type instead, lists its methods with how often each
+ * is overridden, so never-overridden base methods stand out.
+ */
+export async function findOverrides(
+  storage: GraphStorageImpl,
+  options: FindOverridesOptions,
+): Promise<FindOverridesResult> {
+  const limit = Math.max(1, Math.min(5000, Number(options.limit ?? 500) || 500));
+  const split = splitQualifiedSymbol(options.symbol);
+  const qualifier = options.qualifier?.trim() || split.qualifier;
+  const ownership = new MethodOwnership(storage);
+
+  const edges = async (id: string, direction: "in" | "out") => {
+    const rels = await storage.getRelationshipsForEntity(id, RelationType.OVERRIDES);
+    return rels.filter((r) => (direction === "in" ? r.toId === id : r.fromId === id));
+  };
+  const baseMethodsOf = async (method: Entity): Promise<OverrideEntity[]> => {
+    const bases: OverrideEntity[] = [];
+    for (const rel of await edges(method.id, "out")) {
+      const base = await storage.getEntity(rel.toId);
+      if (base && !isExternalPlaceholder(base)) bases.push(summarize(base));
+    }
+    return bases.sort(byLocation);
+  };
+
+  // Methods are narrowed by their owning type below; types by the usual package/namespace rules
+  const named = await findSymbolDefinitions(storage, {
+    symbol: split.name,
+    filePath: options.filePath,
+    entityType: options.entityType,
+  });
+  const methodDefs = named.filter((e) => !TYPE_KINDS.has(String(e.type)));
+  const typeDefs = (await findSymbolDefinitions(storage, options)).filter((e) => TYPE_KINDS.has(String(e.type)));
+
+  const types: TypeOverrides[] = [];
+  const definitions: OverrideDefinition[] = [];
+  let total = 0;
+  let truncated = false;
+
+  for (const entity of typeDefs) {
+    const methods: TypeMethodOverrides[] = [];
+    for (const method of await ownership.methodsOf(entity)) {
+      methods.push({
+        method: summarize(method),
+        overrides: await baseMethodsOf(method),
+        overriddenBy: (await edges(method.id, "in")).length,
+      });
+    }
+    methods.sort((a, b) => byLocation(a.method, b.method));
+    types.push({
+      type: summarize(entity),
+      methods,
+      neverOverridden: methods.filter((m) => m.overriddenBy === 0).map((m) => m.method.name),
+    });
+  }
+
+  for (const entity of methodDefs) {
+    const owner = await ownership.ownerOf(entity);
+    if (!owner || (qualifier && !ownerMatches(owner, qualifier))) continue;
+
+    const overriddenBy: OverridingMethod[] = [];
+    const visited = new Set<string>([entity.id]);
+    let frontier = [entity];
+    for (let depth = 1; frontier.length > 0 && !truncated; depth++) {
+      const next: Entity[] = [];
+      for (const base of frontier) {
+        for (const rel of await edges(base.id, "in")) {
+          if (visited.has(rel.fromId)) continue;
+          visited.add(rel.fromId);
+          const method = await storage.getEntity(rel.fromId);
+          if (!method) continue;
+          if (total >= limit) {
+            truncated = true;
+            break;
+          }
+          total += 1;
+          const methodOwner = await ownership.ownerOf(method);
+          overriddenBy.push({
+            method: summarize(method),
+            owner: methodOwner ? summarize(methodOwner) : null,
+            depth,
+            overrides: base.id,
+            rule: typeof rel.metadata?.rule === "string" ? rel.metadata.rule : null,
+          });
+          next.push(method);
+        }
+        if (truncated) break;
+      }
+      frontier = next;
+    }
+
+    overriddenBy.sort((a, b) => a.depth - b.depth || byLocation(a.method, b.method));
+    definitions.push({
+      method: summarize(entity),
+      owner: summarize(owner),
+      overrides: await baseMethodsOf(entity),
+      overriddenBy,
+    });
+  }
+
+  return { symbol: options.symbol, definitions, types, total, truncated };
+}
@@ -0,0 +1,307 @@
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import type { AccessKind } from "../types/parser.js";
+import type { AccessSite, Entity, Relationship } from "../types/storage.js";
+import { type LocatedEdge, locatedEdge } from "./located-edge.js";
+
+export type ReferenceKind = "call" | "type_reference" | "import" | "reference" | "access";
+
+export const REFERENCE_KINDS_BY_RELATIONSHIP: Record<string, ReferenceKind> = {
+  calls: "call",
+  extends: "type_reference",
+  implements: "type_reference",
+  embeds: "type_reference",
+  imports: "import",
+  references: "reference",
+  handled_by: "reference",
+  accesses: "access",
+  // A goroutine start calls its function; channel sends and receives write and read the channel
+  spawns: "call",
+  sends: "access",
+  receives: "access",
+};
+
+export type SymbolReference = {
+  kind: ReferenceKind;
+  relationshipId: string;
+  relationshipType: string;
+  fromId: string;
+  from: Entity | null;
+  filePath: string | null;
+  line: number | null;
+  range: { startLine: number; endLine: number } | null;
+  /** false when the edge only points at an unresolved by-name placeholder for the symbol */
+  resolved: boolean;
+  /** Reads and writes of a field or variable: `readwrite` when its sites do both */
+  access?: AccessKind;
+  accessSites?: AccessSite[];
+  /** Referencing entity to the symbol, located at the reference or the first of its call or access sites */
+  edge: LocatedEdge;
+};
+
+export type SymbolDefinitionReferences = {
+  definition: Entity;
+  scope: {
+    filePath: string;
+    package: string | null;
+    qualifiedName: string | null;
+    /** Project-wide name, unique across packages; see core/qualified-names.ts */
+    fqn: string | null;
+    container: string | null;
+  };
+  references: SymbolReference[];
+};
+
+export type FindReferencesResult = {
+  symbol: string;
+  name: string;
+  qualifier: string | null;
+  definitions: SymbolDefinitionReferences[];
+  unresolved: SymbolReference[];
+  total: number;
+  truncated: boolean;
+};
+
+/**
+ * Split `pkg.Name`, `mod::Name`, `Type.method` or a fully qualified `path/to/file#Type.method`
+ * into qualifier and bare name.
+ */
+export function splitQualifiedSymbol(symbol: string): { name: string; qualifier: string | null } {
+  const trimmed = symbol.trim();
+  // The file part of a fully qualified name may hold dots of its own (`user.service#get`)
+  const hashIdx = trimmed.lastIndexOf("#");
+  if (hashIdx > 0 && hashIdx < trimmed.length - 1) {
+    const member = splitQualifiedSymbol(trimmed.slice(hashIdx + 1));
+    return { name: member.name, qualifier: `${trimmed.slice(0, hashIdx)}#${member.qualifier ?? ""}` };
+  }
+  const rustIdx = trimmed.lastIndexOf("::");
+  if (rustIdx > 0) {
+    return { name: trimmed.slice(rustIdx + 2), qualifier: trimmed.slice(0, rustIdx) };
+  }
+  const dotIdx = trimmed.lastIndexOf(".");
+  if (dotIdx > 0 && dotIdx < trimmed.length - 1) {
+    return { name: trimmed.slice(dotIdx + 1), qualifier: trimmed.slice(0, dotIdx) };
+  }
+  return { name: trimmed, qualifier: null };
+}
+
+export function isExternalPlaceholder(entity: Entity): boolean {
+  return Boolean((entity.metadata as any)?.isExternal) || entity.filePath.startsWith("external://");
+}
+
+/** Type declaring a struct field, from the analyzer id its field records (`main.go:type:Server`) */
+function fieldOwner(meta: Record<string, any> | undefined): string | undefined {
+  return typeof meta?.parent === "string" ? meta.parent.split(":").pop() : undefined;
+}
+
+function matchesQualifier(entity: Entity, symbol: string, qualifier: string): boolean {
+  const meta = entity.metadata as Record<string, any>;
+  const modulePath = Array.isArray(meta?.modulePath) ? meta.modulePath.join("::") : undefined;
+  const candidates = [
+    meta?.package,
+    meta?.receiver,
+    meta?.implType,
+    meta?.parentClass,
+    fieldOwner(meta),
+    meta?.namespace,
+    modulePath,
+  ].filter((v): v is string => typeof v === "string" && v.length > 0);
+
+  if (meta?.qualifiedName === symbol || meta?.fqn === symbol) return true;
+  if (candidates.some((c) => c === qualifier || c.endsWith(`::${qualifier}`) || c.endsWith(`.${qualifier}`))) {
+    return true;
+  }
+
+  // Fall back to the declaring file/directory name (`utils.format` → utils.ts, utils/)
+  const path = entity.filePath.replace(/\\/g, "/");
+  const lastSegment = qualifier.split(/::|\./).pop() ?? qualifier;
+  return path.includes(`/${lastSegment}/`) || new RegExp(`/${escapeRegExp(lastSegment)}\\.[^/]+$`).test(path);
+}
+
+/**
+ * Find declarations of a symbol in the stored entity table.
+ */
+export async function findSymbolDefinitions(
+  storage: GraphStorageImpl,
+  options: { symbol: string; filePath?: string; qualifier?: string; entityType?: string },
+): Promise<Entity[]> {
+  const split = splitQualifiedSymbol(options.symbol);
+  const qualifier = options.qualifier?.trim() || split.qualifier;
+  const entityType = options.entityType?.trim().toLowerCase();
+
+  const query = await storage.executeQuery({
+    type: "entity",
+    filters: { name: split.name },
+    limit: 1000,
+  });
+
+  let defs = query.entities.filter((e) => !isExternalPlaceholder(e));
+  if (entityType) {
+    defs = defs.filter((e) => String(e.type).toLowerCase() === entityType);
+  }
+  if (options.filePath) {
+    const wanted = options.filePath.replace(/\\/g, "/");
+    defs = defs.filter((e) => {
+      const p = e.filePath.replace(/\\/g, "/");
+      return p === wanted || p.endsWith(`/${wanted}`) || wanted.endsWith(`/${p}`);
+    });
+  }
+  // A fully qualified name (core/qualified-names.ts) picks one declaration out of same-named ones
+  const exact = defs.filter((e) => e.metadata?.fqn === options.symbol.trim());
+  if (exact.length > 0) {
+    defs = exact;
+  } else if (qualifier) {
+    const qualified = defs.filter((e) => matchesQualifier(e, options.symbol.trim(), qualifier));
+    // A qualifier that matches nothing is more likely a naming mismatch than "no symbol"
+    if (qualified.length > 0) defs = qualified;
+  }
+
+  return defs.sort(
+    (a, b) => a.filePath.localeCompare(b.filePath) || (a.location?.start?.line ?? 0) - (b.location?.start?.line ?? 0),
+  );
+}
+
+/**
+ * Collect incoming call/type/import/access edges for each definition of a symbol.
+ *
+ * Cross-file references that the indexer could not bind to a declaration are stored
+ * against `external:` placeholder entities named after the symbol; those are returned
+ * separately as `unresolved` since they cannot be attributed to one definition.
+ * Accesses can be narrowed to the reads or the writes of a field or variable.
+ */
+export async function findReferences(
+  storage: GraphStorageImpl,
+  options: {
+    symbol: string;
+    filePath?: string;
+    qualifier?: string;
+    entityType?: string;
+    kinds?: ReferenceKind[];
+    /** Keep only accesses reading (or only writing) the symbol; `readwrite` sites match both */
+    access?: "read" | "write";
+    includeUnresolved?: boolean;
+    limit?: number;
+  },
+): Promise<FindReferencesResult> {
+  const { name, qualifier } = splitQualifiedSymbol(options.symbol);
+  const limit = Math.max(1, Math.min(1000, Number(options.limit ?? 200) || 200));
+  const kindFilter = options.kinds?.length ? new Set(options.kinds) : null;
+  const fromCache = new Map<string, Entity | null>();
+  let total = 0;
+
+  const loadEntity = async (id: string): Promise<Entity | null> => {
+    if (!fromCache.has(id)) fromCache.set(id, await storage.getEntity(id));
+    return fromCache.get(id) ?? null;
+  };
+
+  const toReference = async (rel: Relationship, kind: ReferenceKind, resolved: boolean): Promise<SymbolReference> => {
+    const from = await loadEntity(rel.fromId);
+    const line = typeof rel.metadata?.line === "number" ? rel.metadata.line : null;
+    const startLine = line ?? from?.location?.start?.line ?? null;
+    const endLine = line ?? from?.location?.end?.line ?? startLine;
+    const sites: unknown = rel.metadata?.accessSites ?? rel.metadata?.callSites;
+    const site = Array.isArray(sites) ? (sites[0] as { line?: number; column?: number } | undefined) : undefined;
+    const reference: SymbolReference = {
+      kind,
+      relationshipId: rel.id,
+      relationshipType: rel.type,
+      fromId: rel.fromId,
+      from,
+      filePath: from?.filePath ?? null,
+      line,
+      range: startLine != null ? { startLine, endLine: endLine ?? startLine } : null,
+      resolved,
+      edge: locatedEdge({
+        from: rel.fromId,
+        // A placeholder stands for the name, not for a declaration of it
+        to: resolved ? rel.toId : null,
+        type: rel.type,
+        direction: "incoming",
+        file: from?.filePath,
+        line: line ?? site?.line,
+        column: typeof rel.metadata?.column === "number" ? rel.metadata.column : site?.column,
+      }),
+    };
+    if (kind === "access") {
+      reference.access = (rel.metadata?.access as AccessKind | undefined) ?? "read";
+      if (rel.metadata?.accessSites) reference.accessSites = rel.metadata.accessSites;
+    }
+    return reference;
+  };
+
+  const collectIncoming = async (targetId: string, resolved: boolean): Promise<SymbolReference[]> => {
+    const rels = await storage.getRelationshipsForEntity(targetId);
+    const out: SymbolReference[] = [];
+    for (const rel of rels) {
+      if (rel.toId !== targetId || rel.fromId === targetId) continue;
+      const kind = REFERENCE_KINDS_BY_RELATIONSHIP[rel.type];
+      if (!kind || (kindFilter && !kindFilter.has(kind))) continue;
+      if (options.access && !(kind === "access" && matchesAccess(rel, options.access))) continue;
+      total++;
+      out.push(await toReference(rel, kind, resolved));
+    }
+    return out;
+  };
+
+  const defs = await findSymbolDefinitions(storage, {
+    symbol: options.symbol,
+    filePath: options.filePath,
+    qualifier: options.qualifier,
+    entityType: options.entityType,
+  });
+
+  const definitions: SymbolDefinitionReferences[] = [];
+  for (const def of defs) {
+    const meta = def.metadata as Record<string, any>;
+    definitions.push({
+      definition: def,
+      scope: {
+        filePath: def.filePath,
+        package: typeof meta?.package === "string" && meta.package ? meta.package : null,
+        qualifiedName: typeof meta?.qualifiedName === "string" ? meta.qualifiedName : null,
+        fqn: typeof meta?.fqn === "string" ? meta.fqn : null,
+        container: meta?.receiver ?? meta?.implType ?? meta?.parentClass ?? fieldOwner(meta) ?? null,
+      },
+      references: await collectIncoming(def.id, true),
+    });
+  }
+
+  const unresolved: SymbolReference[] = [];
+  if (options.includeUnresolved !== false) {
+    // Go accesses to another file's field stay under the qualified name (`Server.users`)
+    const names = [...new Set([name, options.symbol.trim()])];
+    for (const placeholderName of names) {
+      const filters = { name: placeholderName };
+      const placeholders = await storage.executeQuery({ type: "entity", filters, limit: 1000 });
+      for (const placeholder of placeholders.entities.filter(isExternalPlaceholder)) {
+        unresolved.push(...(await collectIncoming(placeholder.id, false)));
+      }
+    }
+  }
+
+  // Apply the limit across all groups in order, definitions first
+  let remaining = limit;
+  for (const group of definitions) {
+    group.references = group.references.slice(0, Math.max(0, remaining));
+    remaining -= group.references.length;
+  }
+  const unresolvedPage = unresolved.slice(0, Math.max(0, remaining));
+
+  return {
+    symbol: options.symbol.trim(),
+    name,
+    qualifier: options.qualifier?.trim() || qualifier,
+    definitions,
+    unresolved: unresolvedPage,
+    total,
+    truncated: total > limit,
+  };
+}
+
+function matchesAccess(rel: Relationship, wanted: "read" | "write"): boolean {
+  const access = rel.metadata?.access ?? "read";
+  return access === wanted || access === "readwrite";
+}
+
+function escapeRegExp(input: string): string {
+  return input.replace(/[.*+?^${}()|[\]\\]/g, "\\$&");
+}
@@ -0,0 +1,231 @@
+import { isTestDeclaration, isTestFile, testFramework } from "../parsers/test-extractor.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { withTests } from "../storage/tests-view.js";
+import { type Entity, RelationType } from "../types/storage.js";
+import { findSymbolDefinitions, isExternalPlaceholder } from "./find-references.js";
+
+export type TestEntity = {
+  id: string;
+  name: string;
+  type: string;
+  filePath: string;
+  startLine: number | null;
+  endLine: number | null;
+};
+
+export type CoveringTest = {
+  test: TestEntity;
+  framework: string | null;
+  /** Enclosing `describe` titles of a Jest/Vitest/Mocha test */
+  suite: string[];
+  /** Line in the test where the exercised code is reached */
+  line: number | null;
+  /** Edge the link was derived from (calls, references, renders, uses_hook) */
+  via: string | null;
+  /** Test-file helper the test reaches the code through */
+  helper: string | null;
+  /** 1 when the test exercises the definition itself, 2 when it exercises a direct caller, ... */
+  depth: number;
+  /** The caller the test exercises when depth > 1 */
+  through: TestEntity | null;
+};
+
+export type DefinitionTests = {
+  definition: TestEntity;
+  tests: CoveringTest[];
+};
+
+export type FindTestsForResult = {
+  symbol: string;
+  definitions: DefinitionTests[];
+  total: number;
+  truncated: boolean;
+};
+
+export type FindTestsForOptions = {
+  symbol: string;
+  filePath?: string;
+  qualifier?: string;
+  entityType?: string;
+  /** Also report tests reaching the symbol through up to `depth - 1` production callers (default 1) */
+  depth?: number;
+  limit?: number;
+};
+
+export type TestedSymbol = {
+  entity: TestEntity;
+  line: number | null;
+  via: string | null;
+  helper: string | null;
+};
+
+export type TestCoverage = {
+  test: TestEntity;
+  framework: string | null;
+  suite: string[];
+  covers: TestedSymbol[];
+};
+
+export type FindTestedByResult = {
+  test: string | null;
+  tests: TestCoverage[];
+  total: number;
+  truncated: boolean;
+};
+
+export type FindTestedByOptions = {
+  /** Test function name (`TestParse`, `test_parse`) or Jest test title */
+  test?: string;
+  /** Test file; alone, every test it declares */
+  filePath?: string;
+  limit?: number;
+};
+
+function summarize(entity: Entity): TestEntity {
+  return {
+    id: entity.id,
+    name: entity.name,
+    type: String(entity.type),
+    filePath: entity.filePath,
+    startLine: entity.location?.start?.line ?? null,
+    endLine: entity.location?.end?.line ?? null,
+  };
+}
+
+function byLocation(a: TestEntity, b: TestEntity): number {
+  return a.filePath.localeCompare(b.filePath) || (a.startLine ?? 0) - (b.startLine ?? 0);
+}
+
+function suiteOf(entity: Entity): string[] {
+  const suite = (entity.metadata as Record<string, unknown> | undefined)?.suite;
+  return Array.isArray(suite) ? suite.map(String) : [];
+}
+
+function pathMatches(filePath: string, wanted: string): boolean {
+  const p = filePath.replace(/\\/g, "/");
+  const w = wanted.replace(/\\/g, "/");
+  return p === w || p.endsWith(`/${w}`) || w.endsWith(`/${p}`);
+}
+
+const stringOrNull = (value: unknown) => (typeof value === "string" ? value : null);
+
+/**
+ * Tests covering each definition of a symbol, read from the tests edges the post-index test
+ * linker stores. With `depth` above 1 the walk continues up the production callers of the
+ * symbol, so tests of the code that calls it are reported too, nearest first.
+ */
+export async function findTestsFor(
+  graph: GraphStorageImpl,
+  options: FindTestsForOptions,
+): Promise<FindTestsForResult> {
+  // Test files stored apart from the main graph keep their tests edges with them
+  const storage = await withTests(graph);
+  const limit = Math.max(1, Math.min(5000, Number(options.limit ?? 500) || 500));
+  const maxDepth = Math.max(1, Math.min(5, Number(options.depth ?? 1) || 1));
+  const definitions: DefinitionTests[] = [];
+  let total = 0;
+  let truncated = false;
+
+  for (const definition of await findSymbolDefinitions(storage, options)) {
+    const tests = new Map<string, CoveringTest>();
+    const visited = new Set<string>([definition.id]);
+    let frontier = [definition];
+    for (let depth = 1; depth <= maxDepth && frontier.length > 0 && !truncated; depth++) {
+      const next: Entity[] = [];
+      for (const covered of frontier) {
+        for (const rel of await storage.getRelationshipsForEntity(covered.id)) {
+          if (rel.toId !== covered.id) continue;
+          if (rel.type === RelationType.TESTS) {
+            if (tests.has(rel.fromId)) continue;
+            const test = await storage.getEntity(rel.fromId);
+            if (!test) continue;
+            if (total >= limit) {
+              truncated = true;
+              break;
+            }
+            total += 1;
+            tests.set(rel.fromId, {
+              test: summarize(test),
+              framework: stringOrNull(rel.metadata?.framework) ?? testFramework(test),
+              suite: suiteOf(test),
+              line: rel.metadata?.line ?? null,
+              via: stringOrNull(rel.metadata?.via),
+              helper: stringOrNull(rel.metadata?.helper),
+              depth,
+              through: depth > 1 ? summarize(covered) : null,
+            });
+          } else if (rel.type === RelationType.CALLS && depth < maxDepth && !visited.has(rel.fromId)) {
+            visited.add(rel.fromId);
+            const caller = await storage.getEntity(rel.fromId);
+            if (caller && !isExternalPlaceholder(caller) && !isTestFile(caller.filePath)) next.push(caller);
+          }
+        }
+        if (truncated) break;
+      }
+      frontier = next;
+    }
+
+    definitions.push({
+      definition: summarize(definition),
+      tests: [...tests.values()].sort((a, b) => a.depth - b.depth || byLocation(a.test, b.test)),
+    });
+  }
+
+  return { symbol: options.symbol, definitions, total, truncated };
+}
+
+/**
+ * The production code a test exercises, by following its tests edges forwards. A test is
+ * looked up by function name or test title, or every test of `filePath` is listed.
+ */
+export async function findTestedBy(
+  graph: GraphStorageImpl,
+  options: FindTestedByOptions,
+): Promise<FindTestedByResult> {
+  const storage = await withTests(graph);
+  const limit = Math.max(1, Math.min(5000, Number(options.limit ?? 500) || 500));
+  const name = options.test?.trim();
+
+  let candidates: Entity[];
+  if (name) {
+    // Titles may contain dots, so the name is tried as written before as a qualified symbol
+    const exact = await storage.findEntities({ type: "entity", filters: { name }, limit: 1000 });
+    candidates = exact.length > 0 ? exact : await findSymbolDefinitions(storage, { symbol: name });
+  } else if (options.filePath) {
+    candidates = await storage.findEntities({ type: "entity", filters: { filePath: options.filePath }, limit: 10000 });
+  } else {
+    candidates = [];
+  }
+  const wanted = options.filePath;
+  const testEntities = candidates
+    .filter((e) => !isExternalPlaceholder(e) && isTestDeclaration(e) && (!wanted || pathMatches(e.filePath, wanted)))
+    .sort((a, b) => byLocation(summarize(a), summarize(b)));
+
+  const tests: TestCoverage[] = [];
+  let total = 0;
+  let truncated = false;
+  for (const test of testEntities) {
+    const covers: TestedSymbol[] = [];
+    for (const rel of await storage.getRelationshipsForEntity(test.id, RelationType.TESTS)) {
+      if (rel.fromId !== test.id) continue;
+      const target = await storage.getEntity(rel.toId);
+      if (!target) continue;
+      if (total >= limit) {
+        truncated = true;
+        break;
+      }
+      total += 1;
+      covers.push({
+        entity: summarize(target),
+        line: rel.metadata?.line ?? null,
+        via: stringOrNull(rel.metadata?.via),
+        helper: stringOrNull(rel.metadata?.helper),
+      });
+    }
+    covers.sort((a, b) => (a.line ?? 0) - (b.line ?? 0) || byLocation(a.entity, b.entity));
+    tests.push({ test: summarize(test), framework: testFramework(test), suite: suiteOf(test), covers });
+    if (truncated) break;
+  }
+
+  return { test: name ?? null, tests, total, truncated };
+}
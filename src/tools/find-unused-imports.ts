@@ -0,0 +1,228 @@
+import { readFile } from "node:fs/promises";
+import { goPackageName } from "../core/go-modules.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import type { Entity } from "../types/storage.js";
+import { isExternalPlaceholder } from "./find-references.js";
+
+export type UnusedImport = {
+  filePath: string;
+  /** Module or package the import names */
+  import: string;
+  /** Name the import binds in the file */
+  name: string;
+  kind: "named" | "default" | "namespace" | "module" | "package";
+  line: number | null;
+};
+
+export type FindUnusedImportsResult = {
+  unused: UnusedImport[];
+  /** Files with at least one import that was checked */
+  files: number;
+  /** Imported names checked */
+  checked: number;
+  /** Imports run only for their side effects (`import "./styles.css"`, Go `_`), never reported */
+  sideEffect: number;
+  /** Imports whose bound names are not known (wildcards, Go dot imports, includes, usings) */
+  unchecked: number;
+  /** Names with no edge pointing at them but still mentioned in the file's source */
+  usedOutsideGraph: number;
+  total: number;
+  truncated: boolean;
+};
+
+export type FindUnusedImportsOptions = {
+  /** Only consider files under this path */
+  pathPrefix?: string;
+  /** Read each file with unused candidates and keep names it mentions outside import statements (default true) */
+  checkSource?: boolean;
+  limit?: number;
+};
+
+type ImportBinding = Omit<UnusedImport, "filePath">;
+
+type FileImports = {
+  bindings: ImportBinding[];
+  /** Lines holding import statements, left out of the source check */
+  importLines: Array<[start: number, end: number]>;
+};
+
+/** Name code refers to an import by: `os` for `import os.path`, `Read` for `use std::io::Read` */
+function boundName(local: string): string | null {
+  if (/[{}*]/.test(local)) return null;
+  if (local.includes("::")) return local.split("::").pop() || null;
+  return local.split(".")[0] || null;
+}
+
+function bindingsOf(entity: Entity): ImportBinding[] | "side_effect" | "unchecked" {
+  const data = (entity.metadata as Record<string, any> | undefined)?.importData;
+  if (!data || typeof data.source !== "string") return "unchecked";
+  const line = entity.location?.start?.line ?? null;
+  const path = entity.filePath;
+  // Includes and usings bring in everything a header or namespace declares
+  if (/\.(c|h|cc|cpp|cxx|hh|hpp|hxx|cs)$/i.test(path)) return "unchecked";
+
+  const bindings: ImportBinding[] = [];
+  const add = (local: string | undefined, kind: ImportBinding["kind"]) => {
+    const name = local ? boundName(local) : null;
+    if (name) bindings.push({ import: data.source, name, kind, line });
+  };
+  const python = path.endsWith(".py") || path.endsWith(".pyi");
+  for (const specifier of data.specifiers ?? []) {
+    const kind = python && !data.fromModule ? "module" : "named";
+    add(specifier?.local, kind);
+  }
+  add(data.local, "default");
+  add(data.namespace, "namespace");
+
+  if (bindings.length > 0) return bindings;
+  // A TS/JS import with no bindings exists for its side effects; anything else is a wildcard
+  return /\.[cm]?[jt]sx?$/.test(path) && !data.isDefault ? "side_effect" : "unchecked";
+}
+
+/** Names an edge shows its file using: the target's, a call's callee and its root (`pkg` of `pkg.Func`) */
+function usedNames(meta: Record<string, any>, target: string | undefined): string[] {
+  const names: string[] = [];
+  const addQualified = (value: unknown) => {
+    if (typeof value !== "string" || !value) return;
+    names.push(value, value.split(/\.|::/)[0]!);
+  };
+  addQualified(target);
+  for (const site of Array.isArray(meta.callSites) ? meta.callSites : []) addQualified(site?.callee);
+  addQualified(meta.qualifier);
+  addQualified(meta.baseType);
+  addQualified(meta.decorator);
+  return names;
+}
+
+function escapeRegExp(text: string): string {
+  return text.replace(/[.*+?^${}()|[\]\\]/g, "\\$&");
+}
+
+/** `source` with its import statements blanked out, so only uses of a name remain */
+function withoutImports(source: string, importLines: FileImports["importLines"]): string {
+  const lines = source.split(/\r?\n/);
+  for (const [start, end] of importLines) {
+    for (let line = Math.max(1, start); line <= end && line <= lines.length; line++) lines[line - 1] = "";
+  }
+  return lines.join("\n");
+}
+
+function mentions(source: string, name: string): boolean {
+  return new RegExp(`(^|[^\\w$.])${escapeRegExp(name)}(?![\\w$])`, "m").test(source);
+}
+
+/**
+ * Imported names nothing in their file uses. A name counts as used when an edge from an entity of
+ * the same file targets it or names it as a callee, qualifier, base type or decorator (`pkg` of
+ * `pkg.Func()` included). Edges do not cover every use (type annotations, values passed around),
+ * so by default a candidate is kept if its file still mentions the name outside import statements.
+ * Side-effect imports are never reported; JS/TS, Python, Rust and Go imports are checked.
+ */
+export async function findUnusedImports(
+  storage: GraphStorageImpl,
+  options: FindUnusedImportsOptions = {},
+): Promise<FindUnusedImportsResult> {
+  const limit = Math.max(1, Math.min(5000, Number(options.limit ?? 500) || 500));
+  const prefix = options.pathPrefix?.replace(/[/\\]+$/, "");
+  const inScope = (filePath: string) => !prefix || filePath === prefix || filePath.startsWith(`${prefix}/`);
+
+  const byFile = new Map<string, FileImports>();
+  const fileOf = (filePath: string) => {
+    let file = byFile.get(filePath);
+    if (!file) {
+      file = { bindings: [], importLines: [] };
+      byFile.set(filePath, file);
+    }
+    return file;
+  };
+
+  const entityFile = new Map<string, string>();
+  const names = new Map<string, string>();
+  const importIds = new Set<string>();
+  let sideEffect = 0;
+  let unchecked = 0;
+  for await (const entity of storage.iterateEntities()) {
+    names.set(entity.id, entity.name);
+    if (isExternalPlaceholder(entity)) continue;
+    entityFile.set(entity.id, entity.filePath);
+    if (String(entity.type) !== "import" || !inScope(entity.filePath)) continue;
+    importIds.add(entity.id);
+    const file = fileOf(entity.filePath);
+    file.importLines.push([entity.location?.start?.line ?? 0, entity.location?.end?.line ?? 0]);
+    const bindings = bindingsOf(entity);
+    if (bindings === "side_effect") sideEffect++;
+    else if (bindings === "unchecked") unchecked++;
+    else file.bindings.push(...bindings);
+  }
+
+  const used = new Map<string, Set<string>>();
+  for await (const rel of storage.iterateRelationships()) {
+    const filePath = entityFile.get(rel.fromId);
+    if (!filePath || !inScope(filePath) || importIds.has(rel.fromId)) continue;
+    const meta = (rel.metadata as Record<string, any> | undefined) ?? {};
+    if (String(rel.type) === "imports") {
+      // Go records imports as package → import path edges rather than import entities; once bound
+      // to the imported package, the edge keeps the path and its target the declared package name
+      if (!filePath.endsWith(".go")) continue;
+      const packageName = typeof meta.importPath === "string" ? names.get(rel.toId) : undefined;
+      const importPath = typeof meta.importPath === "string" ? meta.importPath : names.get(rel.toId);
+      if (!importPath) continue;
+      const line = typeof meta.line === "number" ? meta.line : null;
+      const file = fileOf(filePath);
+      if (line !== null) file.importLines.push([line, line]);
+      const alias = typeof meta.alias === "string" ? meta.alias : undefined;
+      const name = alias ?? packageName ?? goPackageName(importPath);
+      if (alias === "_") sideEffect++;
+      else if (alias === ".") unchecked++;
+      else file.bindings.push({ import: importPath, name, kind: "package", line });
+      continue;
+    }
+    let fileNames = used.get(filePath);
+    if (!fileNames) {
+      fileNames = new Set();
+      used.set(filePath, fileNames);
+    }
+    for (const name of usedNames(meta, names.get(rel.toId))) fileNames.add(name);
+  }
+
+  const unused: UnusedImport[] = [];
+  let checked = 0;
+  let files = 0;
+  let usedOutsideGraph = 0;
+  for (const [filePath, file] of byFile) {
+    if (file.bindings.length === 0) continue;
+    files++;
+    checked += file.bindings.length;
+    const fileNames = used.get(filePath) ?? new Set<string>();
+    const candidates = file.bindings.filter((binding) => !fileNames.has(binding.name));
+    if (candidates.length === 0) continue;
+
+    const source = options.checkSource === false ? null : await readFile(filePath, "utf8").catch(() => null);
+    const body = source === null ? null : withoutImports(source, file.importLines);
+    for (const binding of candidates) {
+      if (body !== null && mentions(body, binding.name)) {
+        usedOutsideGraph++;
+        continue;
+      }
+      unused.push({ filePath, ...binding });
+    }
+  }
+
+  unused.sort(
+    (a, b) =>
+      (a.filePath < b.filePath ? -1 : a.filePath > b.filePath ? 1 : 0) ||
+      (a.line ?? 0) - (b.line ?? 0) ||
+      a.name.localeCompare(b.name),
+  );
+
+  return {
+    unused: unused.slice(0, limit),
+    files,
+    checked,
+    sideEffect,
+    unchecked,
+    usedOutsideGraph,
+    total: unused.length,
+    truncated: unused.length > limit,
+  };
+}
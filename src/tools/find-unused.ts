@@ -0,0 +1,207 @@
+import { dirname } from "node:path";
+import { VISIBILITIES } from "../parsers/visibility.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import type { Entity } from "../types/storage.js";
+import { isExternalPlaceholder, REFERENCE_KINDS_BY_RELATIONSHIP } from "./find-references.js";
+
+/** Kinds of entry points that keep an otherwise unreferenced entity alive */
+export type UnusedRootKind = "exported" | "main" | "tests" | "http_handlers";
+
+export const DEFAULT_UNUSED_ROOTS: UnusedRootKind[] = ["exported", "main", "tests", "http_handlers"];
+
+export const DEFAULT_UNUSED_ENTITY_TYPES = [
+  "function",
+  "method",
+  "class",
+  "interface",
+  "type",
+  "typedef",
+  "struct",
+  "enum",
+  "trait",
+];
+
+export type UnusedEntity = {
+  id: string;
+  name: string;
+  type: string;
+  filePath: string;
+  startLine: number | null;
+  endLine: number | null;
+};
+
+export type FindUnusedResult = {
+  unused: UnusedEntity[];
+  /** Candidates considered after type and path filtering */
+  candidates: number;
+  /** Unreferenced candidates kept because they matched a root rule */
+  roots: Record<UnusedRootKind | "names", number>;
+  total: number;
+  truncated: boolean;
+};
+
+export type FindUnusedOptions = {
+  /** Only consider files under this path */
+  pathPrefix?: string;
+  entityTypes?: string[];
+  roots?: UnusedRootKind[];
+  /** Extra root names; `*` matches any run of characters, `Type.method` matches methods by receiver/class */
+  rootNames?: string[];
+  limit?: number;
+};
+
+const GO_TEST_FUNCTION = /^(Test|Benchmark|Example|Fuzz)([A-Z_]|$)/;
+const SCRIPT_TEST_FILE = /(\.(test|spec)\.[cm]?[jt]sx?$)|([/\\]__tests__[/\\])/;
+const PYTHON_TEST_FILE = /([/\\]test_[^/\\]*\.py$)|(_test\.py$)/;
+
+function meta(entity: Entity): Record<string, any> {
+  return (entity.metadata as Record<string, any> | undefined) ?? {};
+}
+
+/** Declared public: an export or visibility modifier, Go capitalization, or no leading `_` in Python */
+export function isExported(entity: Entity): boolean {
+  const m = meta(entity);
+  // Classified at parse time by the language's own rules; the checks below cover older indexes
+  if ((VISIBILITIES as readonly unknown[]).includes(m.visibility)) return m.visibility === "public";
+  if (typeof m.isPublic === "boolean") return m.isPublic;
+  if (Array.isArray(m.modifiers) && m.modifiers.some((mod: string) => mod === "export" || mod === "public")) {
+    return true;
+  }
+  if (typeof m.visibility === "string") return m.visibility.startsWith("pub");
+  // Python has no export keyword; a leading underscore is the only privacy marker
+  if (entity.filePath.endsWith(".py")) return !entity.name.startsWith("_");
+  return false;
+}
+
+function isMain(entity: Entity): boolean {
+  if (entity.name === "main") return entity.type === "function";
+  return entity.name === "init" && entity.type === "function" && entity.filePath.endsWith(".go");
+}
+
+function isTest(entity: Entity): boolean {
+  const file = entity.filePath;
+  if (file.endsWith("_test.go")) return GO_TEST_FUNCTION.test(entity.name) || entity.name === "TestMain";
+  if (PYTHON_TEST_FILE.test(file)) return true;
+  if (SCRIPT_TEST_FILE.test(file)) return true;
+  return (meta(entity).decorators ?? []).some((d: { name?: string }) => d?.name === "Test");
+}
+
+function isHttpHandler(entity: Entity): boolean {
+  const m = meta(entity);
+  if (m.isHttpHandler) return true;
+  // Go http.Handler implementations are reached through the interface
+  if (entity.type === "method" && entity.name === "ServeHTTP") return true;
+  // Express-style `(req, res)` callbacks
+  const params = Array.isArray(m.parameters) ? m.parameters.map((p: { name?: string }) => p?.name) : [];
+  return (params[0] === "req" && params[1] === "res") || (params[0] === "request" && params[1] === "response");
+}
+
+const ROOT_RULES: Record<UnusedRootKind, (entity: Entity) => boolean> = {
+  exported: isExported,
+  main: isMain,
+  tests: isTest,
+  http_handlers: isHttpHandler,
+};
+
+function namePattern(pattern: string): RegExp {
+  const escaped = pattern.replace(/[.+?^${}()|[\]\\]/g, "\\$&").replace(/\*/g, ".*");
+  return new RegExp(`^${escaped}$`);
+}
+
+function summarize(entity: Entity): UnusedEntity {
+  return {
+    id: entity.id,
+    name: entity.name,
+    type: String(entity.type),
+    filePath: entity.filePath,
+    startLine: entity.location?.start?.line ?? null,
+    endLine: entity.location?.end?.line ?? null,
+  };
+}
+
+/**
+ * Functions and types nothing points at. An entity counts as used when another entity has a call
+ * or reference edge to it, or to an unresolved by-name placeholder with the same name; in Go the
+ * by-name use has to come from the same package, since unexported names cannot be reached from
+ * anywhere else. Entities matching a root rule are never reported.
+ */
+export async function findUnused(
+  storage: GraphStorageImpl,
+  options: FindUnusedOptions = {},
+): Promise<FindUnusedResult> {
+  const limit = Math.max(1, Math.min(5000, Number(options.limit ?? 500) || 500));
+  const types = new Set((options.entityTypes?.length ? options.entityTypes : DEFAULT_UNUSED_ENTITY_TYPES).map(String));
+  const rootKinds = options.roots ?? DEFAULT_UNUSED_ROOTS;
+  const rootNames = (options.rootNames ?? []).map(namePattern);
+  const prefix = options.pathPrefix?.replace(/[/\\]+$/, "");
+  const inScope = (filePath: string) => !prefix || filePath === prefix || filePath.startsWith(`${prefix}/`);
+
+  const candidates: Entity[] = [];
+  const files = new Map<string, string>();
+  const placeholderNames = new Map<string, string>();
+  for await (const entity of storage.iterateEntities()) {
+    if (isExternalPlaceholder(entity)) {
+      placeholderNames.set(entity.id, entity.name);
+      continue;
+    }
+    files.set(entity.id, entity.filePath);
+    if (types.has(String(entity.type)) && inScope(entity.filePath)) candidates.push(entity);
+  }
+
+  const referenced = new Set<string>();
+  const byNameCallers = new Map<string, string[]>();
+  for await (const rel of storage.iterateRelationships()) {
+    if (!(String(rel.type) in REFERENCE_KINDS_BY_RELATIONSHIP) || rel.fromId === rel.toId) continue;
+    const name = placeholderNames.get(rel.toId);
+    if (name === undefined) {
+      referenced.add(rel.toId);
+      continue;
+    }
+    const callerFile = files.get(rel.fromId);
+    if (!callerFile) continue;
+    const callers = byNameCallers.get(name) ?? [];
+    callers.push(callerFile);
+    byNameCallers.set(name, callers);
+  }
+
+  const usedByName = (entity: Entity) => {
+    const callers = byNameCallers.get(entity.name) ?? [];
+    if (!entity.filePath.endsWith(".go")) return callers.length > 0;
+    const dir = dirname(entity.filePath);
+    return callers.some((file) => file.endsWith(".go") && dirname(file) === dir);
+  };
+
+  const roots: FindUnusedResult["roots"] = { exported: 0, main: 0, tests: 0, http_handlers: 0, names: 0 };
+  const unused: Entity[] = [];
+  for (const entity of candidates) {
+    if (referenced.has(entity.id) || usedByName(entity)) continue;
+
+    const root = rootKinds.find((kind) => ROOT_RULES[kind](entity));
+    if (root) {
+      roots[root]++;
+      continue;
+    }
+    const m = meta(entity);
+    const owner = m.receiver ?? m.parentClass;
+    const qualified = owner ? `${owner}.${entity.name}` : entity.name;
+    if (rootNames.some((re) => re.test(entity.name) || re.test(qualified))) {
+      roots.names++;
+      continue;
+    }
+    unused.push(entity);
+  }
+
+  unused.sort(
+    (a, b) =>
+      (a.filePath < b.filePath ? -1 : a.filePath > b.filePath ? 1 : 0) ||
+      (a.location?.start?.line ?? 0) - (b.location?.start?.line ?? 0),
+  );
+
+  return {
+    unused: unused.slice(0, limit).map(summarize),
+    candidates: candidates.length,
+    roots,
+    total: unused.length,
+    truncated: unused.length > limit,
+  };
+}
@@ -3,7 +3,7 @@
  */
 
 import type { GraphStorageImpl } from "../storage/graph-storage.js";
-import type { Entity, Relationship } from "../types/storage.js";
+import type { Entity, Relationship, SearchScope } from "../types/storage.js";
 
 function likePattern(input: string): string {
   // Minimal escaping for LIKE; wrap with % for contains semantics
@@ -16,6 +16,8 @@ export async function queryGraphEntities(
   query?: string,
   limit: number = 100,
   offset: number = 0,
+  scope?: SearchScope,
+  afterId?: string,
 ): Promise<{
   entities: Entity[];
   relationships: Relationship[];
@@ -29,12 +31,15 @@ export async function queryGraphEntities(
     type: "entity",
     limit,
     offset,
-    filters: query
-      ? {
-          // Pass LIKE-compatible pattern via RegExp source consumed by storage
-          name: new RegExp(likePattern(query)),
-        }
-      : undefined,
+    afterId,
+    filters:
+      query || scope
+        ? {
+            // Pass LIKE-compatible pattern via RegExp source consumed by storage
+            name: query ? new RegExp(likePattern(query)) : undefined,
+            scope,
+          }
+        : undefined,
   });
 
   return {
@@ -52,11 +57,12 @@ export async function getGraphStats(storage: GraphStorageImpl): Promise<{
   relationships: { total: number; byType: Record<string, number> };
   files: { total: number };
 }> {
-  // Use storage metrics for reliable totals; byType left empty to avoid raw SQL
+  // Totals come from storage metrics; graph_stats reports the breakdown in full
   const metrics = await storage.getMetrics();
+  const breakdown = await storage.getContentBreakdown();
   return {
-    entities: { total: metrics.totalEntities, byType: {} },
-    relationships: { total: metrics.totalRelationships, byType: {} },
+    entities: { total: metrics.totalEntities, byType: breakdown.entitiesByType },
+    relationships: { total: metrics.totalRelationships, byType: breakdown.relationshipsByType },
     files: { total: metrics.totalFiles },
   };
 }
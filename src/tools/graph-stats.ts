@@ -0,0 +1,148 @@
+import { statSync } from "node:fs";
+import { resolve } from "node:path";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+
+export type FileDiskUsage = {
+  path: string;
+  databaseBytes: number;
+  walBytes: number;
+  shmBytes: number;
+};
+
+export type EmbeddingCoverage = {
+  entities: number;
+  embedded: number;
+  missing: number;
+  /** Share of entities with a search vector, 0-1 */
+  ratio: number;
+};
+
+export type GraphStatsReport = {
+  entities: {
+    total: number;
+    byType: Record<string, number>;
+    byLanguage: Record<string, number>;
+    /** `external://` stand-ins for imported symbols the index does not hold */
+    placeholders: number;
+  };
+  relationships: {
+    total: number;
+    byType: Record<string, number>;
+    unresolved: { total: number; external: number; dangling: number; byType: Record<string, number> };
+  };
+  files: { total: number };
+  /** Null when the semantic agent is not running */
+  embeddings: EmbeddingCoverage | null;
+  disk: { totalBytes: number; files: FileDiskUsage[] };
+  lastUpdatedAt: number | null;
+  lastUpdated: string | null;
+  /** Readings that usually mean indexing went wrong */
+  warnings: string[];
+};
+
+export type GraphStatsOptions = {
+  /** Database files behind the graph and the vector store; the same file is listed once */
+  dbPaths: string[];
+  /** How many of the given entity ids have a search vector; omitted when semantic search is off */
+  countEmbedded?: (entityIds: string[]) => Promise<number>;
+};
+
+const COVERAGE_PAGE = 500;
+
+function fileBytes(path: string): number {
+  try {
+    return statSync(path).size;
+  } catch {
+    return 0;
+  }
+}
+
+function diskUsage(path: string): FileDiskUsage {
+  return {
+    path,
+    databaseBytes: fileBytes(path),
+    walBytes: fileBytes(`${path}-wal`),
+    shmBytes: fileBytes(`${path}-shm`),
+  };
+}
+
+const sum = (counts: Record<string, number>) => Object.values(counts).reduce((acc, n) => acc + n, 0);
+
+async function embeddingCoverage(
+  storage: GraphStorageImpl,
+  countEmbedded: (entityIds: string[]) => Promise<number>,
+): Promise<EmbeddingCoverage> {
+  let entities = 0;
+  let embedded = 0;
+  let page: string[] = [];
+  const flush = async () => {
+    embedded += await countEmbedded(page);
+    page = [];
+  };
+  for await (const entity of storage.iterateEntities()) {
+    if (entity.filePath.startsWith("external://")) continue;
+    entities += 1;
+    page.push(entity.id);
+    if (page.length >= COVERAGE_PAGE) await flush();
+  }
+  if (page.length > 0) await flush();
+  return {
+    entities,
+    embedded,
+    missing: entities - embedded,
+    ratio: entities > 0 ? Math.round((embedded / entities) * 1000) / 1000 : 0,
+  };
+}
+
+/**
+ * Counts describing what the index holds: entities by kind and language, relationships by type
+ * with the unresolved ones split out, embedding coverage and the size of the database files.
+ */
+export async function collectGraphStats(
+  storage: GraphStorageImpl,
+  options: GraphStatsOptions,
+): Promise<GraphStatsReport> {
+  const breakdown = await storage.getContentBreakdown();
+  const embeddings = options.countEmbedded ? await embeddingCoverage(storage, options.countEmbedded) : null;
+
+  const files = new Map(options.dbPaths.filter((path) => path !== ":memory:").map((path) => [resolve(path), path]));
+  const disk = Array.from(files.values(), (path) => diskUsage(path));
+
+  const entitiesTotal = sum(breakdown.entitiesByType);
+  const { external, dangling, byType: unresolvedByType } = breakdown.unresolvedRelationships;
+  const relationshipsTotal = sum(breakdown.relationshipsByType);
+
+  const warnings: string[] = [];
+  if (breakdown.files > 0 && entitiesTotal === 0) {
+    warnings.push(`${breakdown.files} files are indexed but no entities were extracted from them`);
+  }
+  if (entitiesTotal > 0 && relationshipsTotal === 0) {
+    warnings.push("Entities were extracted but no relationships between them");
+  }
+  if (embeddings && embeddings.missing > 0) {
+    warnings.push(`${embeddings.missing} of ${embeddings.entities} entities have no embedding yet`);
+  }
+
+  return {
+    entities: {
+      total: entitiesTotal,
+      byType: breakdown.entitiesByType,
+      byLanguage: breakdown.entitiesByLanguage,
+      placeholders: breakdown.placeholderEntities,
+    },
+    relationships: {
+      total: relationshipsTotal,
+      byType: breakdown.relationshipsByType,
+      unresolved: { total: external + dangling, external, dangling, byType: unresolvedByType },
+    },
+    files: { total: breakdown.files },
+    embeddings,
+    disk: {
+      totalBytes: disk.reduce((acc, file) => acc + file.databaseBytes + file.walBytes + file.shmBytes, 0),
+      files: disk,
+    },
+    lastUpdatedAt: breakdown.lastUpdatedAt,
+    lastUpdated: breakdown.lastUpdatedAt === null ? null : new Date(breakdown.lastUpdatedAt).toISOString(),
+    warnings,
+  };
+}
@@ -0,0 +1,288 @@
+import { confidenceOf, minConfidenceOf } from "../core/edge-confidence.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { hasSearchScope, matchesSearchScope } from "../storage/search-filters.js";
+import { withoutTests, withTests } from "../storage/tests-view.js";
+import { type Entity, RelationType, type SearchScope } from "../types/storage.js";
+import { isExternalPlaceholder } from "./find-references.js";
+
+export const GRAPH_QUERY_EDGE_TYPES: readonly string[] = Object.values(RelationType);
+
+/** Hard bounds of one traversal; the tool schema enforces the same values */
+export const GRAPH_QUERY_LIMITS = {
+  maxDepth: 5,
+  maxStartNodes: 50,
+  /** Nodes reached over all depths, start nodes included */
+  maxVisited: 10000,
+  maxResults: 1000,
+} as const;
+
+export type GraphQueryDirection = "out" | "in" | "both";
+
+export type GraphNodeSelector = {
+  /** Exact entity id */
+  id?: string;
+  /** Exact name, or a pattern where `*` matches any run of characters */
+  name?: string;
+  scope?: SearchScope;
+};
+
+export type GraphQuerySpec = {
+  start: GraphNodeSelector;
+  /** Relationship types to follow; all of them when empty */
+  edges?: string[];
+  /** `out` follows edges from a node to its targets, `in` from targets back to their sources */
+  direction?: GraphQueryDirection;
+  minDepth?: number;
+  maxDepth?: number;
+  /**
+   * Which reached nodes are returned; the walk itself passes through any node, except test entities
+   * when neither selector takes tests in
+   */
+  where?: Omit<GraphNodeSelector, "id">;
+  /** Only follow edges bound with at least this confidence (0..1) */
+  minConfidence?: number;
+  /** Return the whole path from the start for each match, not only the last edge */
+  returnPaths?: boolean;
+  limit?: number;
+};
+
+export type GraphQueryNode = {
+  id: string;
+  name: string;
+  type: string;
+  filePath: string;
+  startLine: number | null;
+  endLine: number | null;
+};
+
+export type GraphQueryEdge = {
+  relationship: string;
+  /** `out` when the edge points from the previous node to this one, `in` when it points back */
+  direction: "out" | "in";
+  confidence: number;
+  line: number | null;
+};
+
+export type GraphQueryStep = GraphQueryEdge & { node: GraphQueryNode };
+
+export type GraphQueryMatch = {
+  node: GraphQueryNode;
+  depth: number;
+  /** Id of the start node the match was reached from */
+  start: string;
+  /** Edge the match was reached by; null for a start node (minDepth 0) */
+  via: GraphQueryEdge | null;
+  /** With returnPaths: every step from the start node (excluded) to the match */
+  path?: GraphQueryStep[];
+};
+
+export type GraphQueryTruncation = {
+  reason: "start" | "visited" | "limit";
+  message: string;
+};
+
+export type GraphQueryResult = {
+  start: GraphQueryNode[];
+  matches: GraphQueryMatch[];
+  /** Matches found before `limit` was applied */
+  total: number;
+  visited: number;
+  depthReached: number;
+  truncated: GraphQueryTruncation[];
+};
+
+type ReachedNode = {
+  entity: Entity;
+  depth: number;
+  start: string;
+  parent: string | null;
+  edge: GraphQueryEdge | null;
+};
+
+function summarize(entity: Entity): GraphQueryNode {
+  return {
+    id: entity.id,
+    name: entity.name,
+    type: String(entity.type),
+    filePath: entity.filePath,
+    startLine: entity.location?.start?.line ?? null,
+    endLine: entity.location?.end?.line ?? null,
+  };
+}
+
+function clamp(value: number | undefined, fallback: number, min: number, max: number): number {
+  const n = Math.floor(Number(value ?? fallback));
+  return Math.max(min, Math.min(max, Number.isFinite(n) ? n : fallback));
+}
+
+/** `Delete*` → /^Delete.*$/; names without `*` match exactly */
+function namePattern(name: string): RegExp {
+  const source = name
+    .split("*")
+    .map((part) => part.replace(/[.+?^${}()|[\]\\]/g, "\\$&"))
+    .join(".*");
+  return new RegExp(`^${source}$`);
+}
+
+function matchesSelector(entity: Entity, selector?: Omit<GraphNodeSelector, "id">): boolean {
+  if (!selector) return true;
+  const name = selector.name?.trim();
+  if (name && !namePattern(name).test(entity.name)) return false;
+  return matchesSearchScope(entity, selector.scope);
+}
+
+/**
+ * Why a spec cannot run, or null when it can. A start selector must pin something down (an id, a
+ * name or a path), so a query never starts from every node of the graph.
+ */
+export function graphQueryProblem(spec: GraphQuerySpec): string | null {
+  const { start } = spec;
+  if (!start.id?.trim() && !start.name?.trim() && !start.scope?.pathPrefix && !start.scope?.pathGlob) {
+    return "start needs an id, a name, a pathPrefix or a pathGlob";
+  }
+  const unknown = (spec.edges ?? []).filter((type) => !GRAPH_QUERY_EDGE_TYPES.includes(type));
+  if (unknown.length > 0) return `unknown edge types: ${unknown.join(", ")}`;
+  if (spec.minDepth !== undefined && spec.maxDepth !== undefined && spec.minDepth > spec.maxDepth) {
+    return "minDepth cannot exceed maxDepth";
+  }
+  return null;
+}
+
+async function startNodes(storage: GraphStorageImpl, selector: GraphNodeSelector, cap: number): Promise<Entity[]> {
+  const id = selector.id?.trim();
+  if (id) {
+    const entity = await storage.getEntity(id);
+    return entity && matchesSelector(entity, selector) ? [entity] : [];
+  }
+  const name = selector.name?.trim();
+  const found = await storage.findEntities({
+    type: "entity",
+    filters: {
+      name: name ? (name.includes("*") ? namePattern(name) : name) : undefined,
+      scope: hasSearchScope(selector.scope) ? selector.scope : undefined,
+    },
+    // One over the cap tells a full selection from a cut one
+    limit: cap + 1,
+  });
+  return found.filter((entity) => !isExternalPlaceholder(entity));
+}
+
+/**
+ * Breadth-first walk from the start nodes along the requested edge types and direction, up to
+ * maxDepth hops. Each node is reached once, by a shortest path, which is also what stops the walk
+ * on cycles. External placeholders end a path rather than being stepped onto. Nodes reached at
+ * minDepth or deeper that match `where` are returned nearest first.
+ */
+export async function runGraphQuery(graph: GraphStorageImpl, spec: GraphQuerySpec): Promise<GraphQueryResult> {
+  // Keeping tests out of both ends keeps them out of the paths between as well
+  const testsExcluded = spec.start.scope?.tests === "exclude" && (!spec.where || spec.where.scope?.tests === "exclude");
+  const storage = testsExcluded ? withoutTests(graph) : await withTests(graph);
+  const maxDepth = clamp(spec.maxDepth, 2, 1, GRAPH_QUERY_LIMITS.maxDepth);
+  const minDepth = clamp(spec.minDepth, 1, 0, maxDepth);
+  const limit = clamp(spec.limit, 100, 1, GRAPH_QUERY_LIMITS.maxResults);
+  const direction = spec.direction ?? "out";
+  const types = new Set(spec.edges?.length ? spec.edges : GRAPH_QUERY_EDGE_TYPES);
+  const minConfidence = minConfidenceOf(spec.minConfidence);
+  const truncated: GraphQueryTruncation[] = [];
+
+  const found = await startNodes(storage, spec.start, GRAPH_QUERY_LIMITS.maxStartNodes);
+  const starts = found.slice(0, GRAPH_QUERY_LIMITS.maxStartNodes);
+  if (found.length > starts.length) {
+    truncated.push({
+      reason: "start",
+      message: `start selector matched more than ${GRAPH_QUERY_LIMITS.maxStartNodes} nodes; only the first were walked`,
+    });
+  }
+
+  const reached = new Map<string, ReachedNode>();
+  for (const entity of starts) {
+    reached.set(entity.id, { entity, depth: 0, start: entity.id, parent: null, edge: null });
+  }
+
+  let depthReached = 0;
+  let frontier = starts.map((entity) => entity.id);
+  walk: for (let depth = 1; depth <= maxDepth && frontier.length > 0; depth++) {
+    const next: string[] = [];
+    for (const id of frontier) {
+      const node = reached.get(id)!;
+      for (const rel of await storage.getRelationshipsForEntity(id)) {
+        if (!types.has(String(rel.type))) continue;
+        let neighbor: string;
+        let edgeDirection: "out" | "in";
+        if (direction !== "in" && rel.fromId === id) {
+          neighbor = rel.toId;
+          edgeDirection = "out";
+        } else if (direction !== "out" && rel.toId === id) {
+          neighbor = rel.fromId;
+          edgeDirection = "in";
+        } else {
+          continue;
+        }
+        if (reached.has(neighbor)) continue;
+        const { confidence } = confidenceOf(rel);
+        if (confidence < minConfidence) continue;
+        const entity = await storage.getEntity(neighbor);
+        if (!entity || isExternalPlaceholder(entity)) continue;
+
+        if (reached.size >= GRAPH_QUERY_LIMITS.maxVisited) {
+          truncated.push({
+            reason: "visited",
+            message: `walk stopped at depth ${depth} after reaching ${GRAPH_QUERY_LIMITS.maxVisited} nodes`,
+          });
+          break walk;
+        }
+        const edge: GraphQueryEdge = {
+          relationship: String(rel.type),
+          direction: edgeDirection,
+          confidence,
+          line: rel.metadata?.line ?? null,
+        };
+        reached.set(neighbor, { entity, depth, start: node.start, parent: id, edge });
+        next.push(neighbor);
+        depthReached = depth;
+      }
+    }
+    frontier = next;
+  }
+
+  const pathTo = (id: string): GraphQueryStep[] => {
+    const steps: GraphQueryStep[] = [];
+    for (let node = reached.get(id); node?.edge; node = node.parent ? reached.get(node.parent) : undefined) {
+      steps.push({ ...node.edge, node: summarize(node.entity) });
+    }
+    return steps.reverse();
+  };
+
+  const matching = [...reached.values()]
+    .filter((node) => node.depth >= minDepth && matchesSelector(node.entity, spec.where))
+    .sort(
+      (a, b) =>
+        a.depth - b.depth ||
+        a.entity.filePath.localeCompare(b.entity.filePath) ||
+        (a.entity.location?.start?.line ?? 0) - (b.entity.location?.start?.line ?? 0) ||
+        a.entity.id.localeCompare(b.entity.id),
+    );
+  if (matching.length > limit) {
+    truncated.push({ reason: "limit", message: `${matching.length} matches, returning the nearest ${limit}` });
+  }
+
+  const matches = matching.slice(0, limit).map((node) => {
+    const match: GraphQueryMatch = {
+      node: summarize(node.entity),
+      depth: node.depth,
+      start: node.start,
+      via: node.edge,
+    };
+    if (spec.returnPaths) match.path = pathTo(node.entity.id);
+    return match;
+  });
+
+  return {
+    start: starts.map(summarize),
+    matches,
+    total: matching.length,
+    visited: reached.size,
+    depthReached,
+    truncated,
+  };
+}
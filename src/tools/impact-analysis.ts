@@ -0,0 +1,272 @@
+import { confidenceOf, edgeConfidence, minConfidenceOf } from "../core/edge-confidence.js";
+import { isTestEntity } from "../parsers/test-extractor.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { withoutTests, withTests } from "../storage/tests-view.js";
+import { type Entity, RelationType, type SearchScope } from "../types/storage.js";
+import { packageCallersOf } from "./call-graph.js";
+import { findSymbolDefinitions, isExternalPlaceholder, REFERENCE_KINDS_BY_RELATIONSHIP } from "./find-references.js";
+import { type LocatedEdge, locatedEdge } from "./located-edge.js";
+
+export const DEFAULT_IMPACT_RELATIONSHIPS = Object.keys(REFERENCE_KINDS_BY_RELATIONSHIP);
+
+export type ImpactEntity = {
+  id: string;
+  name: string;
+  type: string;
+  filePath: string;
+  startLine: number | null;
+  endLine: number | null;
+};
+
+export type ImpactPathStep = {
+  entity: ImpactEntity;
+  /** Edge from this step to the next one; null on the final step (the target) */
+  relationship: string | null;
+  line: number | null;
+  /** Confidence of that edge; null on the final step */
+  confidence: number | null;
+};
+
+export type AffectedEntity = {
+  entity: ImpactEntity;
+  depth: number;
+  /** Confidence of the least certain edge on the path */
+  confidence: number;
+  /** Shortest dependency chain, from the affected entity down to the target */
+  path: ImpactPathStep[];
+  /** First edge of that chain: the affected entity to what it depends on, where it does so */
+  edge: LocatedEdge;
+};
+
+export type ImpactFileGroup = {
+  filePath: string;
+  entities: AffectedEntity[];
+};
+
+export type ImpactCycle = {
+  from: ImpactEntity;
+  to: ImpactEntity;
+  relationship: string;
+};
+
+export type ImpactTruncation = {
+  reason: "depth" | "limit";
+  depth: number;
+  message: string;
+};
+
+export type ImpactAnalysisResult = {
+  symbol: string;
+  targets: ImpactEntity[];
+  files: ImpactFileGroup[];
+  totalAffected: number;
+  maxDepth: number;
+  depthReached: number;
+  /** Dependency edges that lead back onto the chain they were reached from */
+  cycles: ImpactCycle[];
+  truncated: ImpactTruncation | null;
+};
+
+export type ImpactAnalysisOptions = {
+  /** Symbol name (optionally qualified) or an exact entity id */
+  symbol: string;
+  filePath?: string;
+  qualifier?: string;
+  entityType?: string;
+  maxDepth?: number;
+  relationshipTypes?: string[];
+  /** Only follow edges bound with at least this confidence (0..1) */
+  minConfidence?: number;
+  /** Leave dependents in test files out of the walk, or report only those */
+  tests?: SearchScope["tests"];
+  limit?: number;
+};
+
+type ReachedNode = {
+  entity: Entity;
+  depth: number;
+  /** The node this one depends on, one step closer to the target */
+  next: string | null;
+  relationship: string | null;
+  line: number | null;
+  confidence: number | null;
+};
+
+type IncomingEdge = { from: Entity; relationship: string; line: number | null; confidence: number };
+
+const MAX_REPORTED_CYCLES = 50;
+
+function summarize(entity: Entity): ImpactEntity {
+  return {
+    id: entity.id,
+    name: entity.name,
+    type: String(entity.type),
+    filePath: entity.filePath,
+    startLine: entity.location?.start?.line ?? null,
+    endLine: entity.location?.end?.line ?? null,
+  };
+}
+
+function clamp(value: number | undefined, fallback: number, max: number): number {
+  return Math.max(1, Math.min(max, Math.floor(Number(value ?? fallback) || fallback)));
+}
+
+async function resolveTargets(storage: GraphStorageImpl, options: ImpactAnalysisOptions): Promise<Entity[]> {
+  const byId = await storage.getEntity(options.symbol.trim());
+  if (byId && !isExternalPlaceholder(byId)) return [byId];
+  return findSymbolDefinitions(storage, options);
+}
+
+/**
+ * Everything that transitively depends on `symbol` through the reverse of the given relationship
+ * types (calls and references by default), found breadth-first so each affected entity carries its
+ * shortest path back to the target. Entities are visited once, which is what stops the walk on
+ * cycles; edges that close a cycle are reported separately.
+ */
+export async function analyzeImpact(
+  graph: GraphStorageImpl,
+  options: ImpactAnalysisOptions,
+): Promise<ImpactAnalysisResult> {
+  // Nothing in production depends on a test, so excluded tests are neither reported nor walked through;
+  // otherwise those stored apart from the main graph are walked with it
+  const everything = await withTests(graph);
+  const storage = options.tests === "exclude" ? withoutTests(graph) : everything;
+  const maxDepth = clamp(options.maxDepth, 5, 20);
+  const limit = clamp(options.limit, 500, 5000);
+  const requestedTypes = options.relationshipTypes?.length ? options.relationshipTypes : DEFAULT_IMPACT_RELATIONSHIPS;
+  const types = new Set(requestedTypes.map((t) => t.toLowerCase()));
+  const minConfidence = minConfidenceOf(options.minConfidence);
+
+  const cache = new Map<string, Entity | null>();
+  const loadEntity = async (id: string): Promise<Entity | null> => {
+    if (!cache.has(id)) cache.set(id, await storage.getEntity(id));
+    return cache.get(id) ?? null;
+  };
+
+  const incomingEdges = async (entity: Entity): Promise<IncomingEdge[]> => {
+    const edges: IncomingEdge[] = [];
+    for (const rel of await storage.getRelationshipsForEntity(entity.id)) {
+      if (rel.toId !== entity.id || !types.has(String(rel.type))) continue;
+      const from = await loadEntity(rel.fromId);
+      if (!from || isExternalPlaceholder(from)) continue;
+      const { confidence } = confidenceOf(rel);
+      edges.push({ from, relationship: String(rel.type), line: rel.metadata?.line ?? null, confidence });
+    }
+    if (types.has(RelationType.CALLS)) {
+      // Bound by name within the Go package when the graph is queried, not when it was indexed
+      const { confidence } = edgeConfidence("name_match");
+      for (const { caller, callSites } of await packageCallersOf(storage, entity)) {
+        edges.push({ from: caller, relationship: RelationType.CALLS, line: callSites[0]?.line ?? null, confidence });
+      }
+    }
+    return edges.filter((edge) => edge.confidence >= minConfidence);
+  };
+
+  // A test asked about by name is still found; only its dependents are left out
+  const targets = await resolveTargets(everything, options);
+  const reached = new Map<string, ReachedNode>();
+  for (const target of targets) {
+    reached.set(target.id, { entity: target, depth: 0, next: null, relationship: null, line: null, confidence: null });
+  }
+
+  const onChain = (candidate: string, start: string): boolean => {
+    for (let id: string | null = start; id; id = reached.get(id)?.next ?? null) {
+      if (id === candidate) return true;
+    }
+    return false;
+  };
+
+  const cycles: ImpactCycle[] = [];
+  let truncated: ImpactTruncation | null = null;
+  let depthReached = 0;
+  let frontier = targets.map((t) => t.id);
+
+  for (let depth = 1; frontier.length > 0 && !truncated; depth++) {
+    const next: string[] = [];
+    for (const id of frontier) {
+      const node = reached.get(id)!;
+      for (const edge of await incomingEdges(node.entity)) {
+        if (reached.has(edge.from.id)) {
+          if (onChain(edge.from.id, id) && cycles.length < MAX_REPORTED_CYCLES) {
+            cycles.push({ from: summarize(edge.from), to: summarize(node.entity), relationship: edge.relationship });
+          }
+          continue;
+        }
+        if (depth > maxDepth) {
+          truncated = { reason: "depth", depth: maxDepth, message: `truncated at depth ${maxDepth}` };
+          break;
+        }
+        if (reached.size - targets.length >= limit) {
+          truncated = { reason: "limit", depth: depth - 1, message: `truncated at depth ${depth - 1}` };
+          break;
+        }
+        reached.set(edge.from.id, {
+          entity: edge.from,
+          depth,
+          next: id,
+          relationship: edge.relationship,
+          line: edge.line,
+          confidence: edge.confidence,
+        });
+        next.push(edge.from.id);
+        depthReached = depth;
+      }
+      if (truncated) break;
+    }
+    frontier = next;
+  }
+
+  const pathFrom = (id: string): ImpactPathStep[] => {
+    const steps: ImpactPathStep[] = [];
+    for (let node = reached.get(id); node; node = node.next ? reached.get(node.next) : undefined) {
+      steps.push({
+        entity: summarize(node.entity),
+        relationship: node.relationship,
+        line: node.line,
+        confidence: node.confidence,
+      });
+    }
+    return steps;
+  };
+
+  const byFile = new Map<string, AffectedEntity[]>();
+  let totalAffected = 0;
+  for (const [id, node] of reached) {
+    if (node.depth === 0) continue;
+    // Production code between the target and a test still shows on the test's path
+    if (options.tests === "only" && !isTestEntity(node.entity)) continue;
+    totalAffected++;
+    const group = byFile.get(node.entity.filePath) ?? [];
+    const path = pathFrom(id);
+    const confidence = Math.min(...path.map((step) => step.confidence ?? 1));
+    const edge = locatedEdge({
+      from: id,
+      to: node.next,
+      type: node.relationship ?? "",
+      direction: "incoming",
+      file: node.entity.filePath,
+      line: node.line,
+    });
+    group.push({ entity: summarize(node.entity), depth: node.depth, confidence, path, edge });
+    byFile.set(node.entity.filePath, group);
+  }
+
+  const files: ImpactFileGroup[] = [];
+  for (const [filePath, entities] of byFile) {
+    entities.sort((a, b) => a.depth - b.depth || (a.entity.startLine ?? 0) - (b.entity.startLine ?? 0));
+    files.push({ filePath, entities });
+  }
+  // Files closest to the target first
+  files.sort((a, b) => a.entities[0]!.depth - b.entities[0]!.depth || a.filePath.localeCompare(b.filePath));
+
+  return {
+    symbol: options.symbol.trim(),
+    targets: targets.map(summarize),
+    files,
+    totalAffected,
+    maxDepth,
+    depthReached,
+    cycles,
+    truncated,
+  };
+}
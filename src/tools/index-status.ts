@@ -0,0 +1,101 @@
+import { lstat, readFile } from "node:fs/promises";
+import { relative } from "node:path";
+import { hashFileContent } from "../parsers/incremental-parser.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import type { FileInfo, IndexRun } from "../types/storage.js";
+import { commitsSince, headCommit } from "../utils/git-worktree.js";
+
+export type StaleFile = {
+  path: string;
+  /** changed: content differs from the indexed copy; added: not indexed yet; deleted: indexed but gone */
+  change: "changed" | "added" | "deleted";
+};
+
+export type IndexGitState = {
+  /** HEAD when the last completed index run started */
+  indexedHead: string | null;
+  currentHead: string;
+  /** Commits on HEAD since indexedHead; null when that commit is unknown here (rebased away, other clone) */
+  commitsSince: number | null;
+};
+
+export type IndexStatusResult = {
+  stale: boolean;
+  counts: { indexed: number; changed: number; added: number; deleted: number };
+  /** Stale files in path order, capped by `limit` */
+  files: StaleFile[];
+  truncated: boolean;
+  /** null outside a git work tree */
+  git: IndexGitState | null;
+  lastRun: IndexRun | null;
+  /** Indexed files whose mtime moved while their content stayed the same */
+  touched: number;
+};
+
+export type IndexStatusOptions = {
+  directory: string;
+  /** Indexable files currently under `directory`, as index discovery would list them */
+  discovered: string[];
+  limit?: number;
+};
+
+function isWithin(directory: string, path: string): boolean {
+  const rel = relative(directory, path);
+  return rel === "" || (!rel.startsWith("..") && !rel.startsWith("/"));
+}
+
+async function currentState(info: FileInfo): Promise<"fresh" | "touched" | "changed" | "deleted"> {
+  const stats = await lstat(info.path).catch(() => null);
+  if (!stats?.isFile()) return "deleted";
+  // Recovery clears the hash of files it wants re-indexed; those are stale whatever their mtime says
+  if (info.hash && info.mtimeMs != null && Math.trunc(stats.mtimeMs) === info.mtimeMs) return "fresh";
+  const content = await readFile(info.path, "utf-8").catch(() => null);
+  if (content === null) return "deleted";
+  return info.hash && hashFileContent(content) === info.hash ? "touched" : "changed";
+}
+
+/**
+ * Whether the files under `directory` still match what was indexed. Files whose mtime is unchanged
+ * since indexing are taken as current; the others are hashed, so a checkout that only touches
+ * files is not reported. Files never indexed count as added.
+ */
+export async function indexStatus(storage: GraphStorageImpl, options: IndexStatusOptions): Promise<IndexStatusResult> {
+  const limit = Math.max(1, Math.min(10000, Number(options.limit ?? 200) || 200));
+  const indexed = (await storage.listIndexedFiles()).filter(
+    (info) => !info.path.startsWith("external://") && isWithin(options.directory, info.path),
+  );
+
+  const stale: StaleFile[] = [];
+  let touched = 0;
+  for (const info of indexed) {
+    const state = await currentState(info);
+    if (state === "touched") touched++;
+    else if (state !== "fresh") stale.push({ path: info.path, change: state });
+  }
+  const known = new Set(indexed.map((info) => info.path));
+  for (const path of options.discovered) {
+    if (!known.has(path)) stale.push({ path, change: "added" });
+  }
+  stale.sort((a, b) => (a.path < b.path ? -1 : a.path > b.path ? 1 : 0));
+
+  const lastRun = (await storage.listIndexRuns(50)).find((run) => run.status === "completed") ?? null;
+  const currentHead = await headCommit(options.directory);
+  let git: IndexGitState | null = null;
+  if (currentHead) {
+    const indexedHead = lastRun?.gitHead ?? null;
+    let since: number | null = null;
+    if (indexedHead) since = indexedHead === currentHead ? 0 : await commitsSince(options.directory, indexedHead);
+    git = { indexedHead, currentHead, commitsSince: since };
+  }
+
+  const count = (change: StaleFile["change"]) => stale.filter((file) => file.change === change).length;
+  return {
+    stale: stale.length > 0,
+    counts: { indexed: indexed.length, changed: count("changed"), added: count("added"), deleted: count("deleted") },
+    files: stale.slice(0, limit),
+    truncated: stale.length > limit,
+    git,
+    lastRun,
+    touched,
+  };
+}
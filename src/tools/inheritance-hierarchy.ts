@@ -0,0 +1,279 @@
+import { INHERITANCE_RELATIONSHIPS, TYPE_KINDS } from "../core/override-resolver.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { type Entity, type Relationship, RelationType } from "../types/storage.js";
+import { findSymbolDefinitions, isExternalPlaceholder } from "./find-references.js";
+
+/** Relations that count towards multiple inheritance; implementing several interfaces does not */
+const BASE_RELATIONS = new Set<string>([RelationType.EXTENDS, RelationType.EMBEDS]);
+
+export type HierarchyDirection = "ancestors" | "descendants" | "both";
+
+export type HierarchyEntity = {
+  /** Null when the type is not in the index (library type, or a name several declarations share) */
+  id: string | null;
+  name: string;
+  type: string | null;
+  filePath: string | null;
+  line: number | null;
+};
+
+export type HierarchyNode = HierarchyEntity & {
+  /** Edge between this node and its parent in the tree (extends, implements, embeds) */
+  relation: string;
+  /** Line of the declaration carrying the edge */
+  edgeLine: number | null;
+  /**
+   * `repeated`: already expanded elsewhere in this tree, reached again through a diamond;
+   * `cycle`: leads back onto its own chain. Neither is expanded again.
+   */
+  seen: "repeated" | "cycle" | null;
+  children: HierarchyNode[];
+};
+
+export type HierarchyRoot = {
+  type: HierarchyEntity;
+  ancestors: HierarchyNode[];
+  descendants: HierarchyNode[];
+};
+
+export type DiamondReport = {
+  direction: "ancestors" | "descendants";
+  /** Type reached through more than one path */
+  entity: HierarchyEntity;
+  /** Distinct types it was reached from */
+  via: HierarchyEntity[];
+};
+
+export type MultipleInheritanceReport = {
+  entity: HierarchyEntity;
+  bases: HierarchyEntity[];
+};
+
+export type InheritanceHierarchyResult = {
+  symbol: string;
+  direction: HierarchyDirection;
+  roots: HierarchyRoot[];
+  diamonds: DiamondReport[];
+  multipleInheritance: MultipleInheritanceReport[];
+  totalNodes: number;
+  maxDepth: number;
+  truncated: boolean;
+};
+
+export type InheritanceHierarchyOptions = {
+  symbol: string;
+  filePath?: string;
+  qualifier?: string;
+  entityType?: string;
+  direction?: HierarchyDirection;
+  maxDepth?: number;
+  limit?: number;
+};
+
+type Link = { entity: HierarchyEntity; stored: Entity | null; relation: string; line: number | null };
+
+function summarize(entity: Entity): HierarchyEntity {
+  return {
+    id: entity.id,
+    name: entity.name,
+    type: String(entity.type),
+    filePath: entity.filePath,
+    line: entity.location?.start?.line ?? null,
+  };
+}
+
+function unresolved(name: string): HierarchyEntity {
+  return { id: null, name, type: null, filePath: null, line: null };
+}
+
+function clamp(value: number | undefined, fallback: number, max: number): number {
+  return Math.max(1, Math.min(max, Math.floor(Number(value ?? fallback) || fallback)));
+}
+
+function byLocation(a: Link, b: Link): number {
+  return (
+    a.entity.name.localeCompare(b.entity.name) ||
+    (a.entity.filePath ?? "").localeCompare(b.entity.filePath ?? "") ||
+    (a.entity.line ?? 0) - (b.entity.line ?? 0)
+  );
+}
+
+/**
+ * Ancestor and descendant trees of a type over extends, implements and embeds edges. Edges that
+ * stopped at a placeholder (a base class imported from another file, a Go type of another package)
+ * are followed when exactly one indexed type carries the name. A type reached twice is expanded
+ * only the first time, which keeps diamonds and cycles finite; both are reported.
+ */
+export async function inheritanceHierarchy(
+  storage: GraphStorageImpl,
+  options: InheritanceHierarchyOptions,
+): Promise<InheritanceHierarchyResult> {
+  const direction = options.direction ?? "both";
+  const maxDepth = clamp(options.maxDepth, 10, 50);
+  const limit = clamp(options.limit, 500, 5000);
+
+  const edgeCache = new Map<string, Relationship[]>();
+  const edgesOf = async (id: string): Promise<Relationship[]> => {
+    if (!edgeCache.has(id)) {
+      const rels = await storage.getRelationshipsForEntity(id);
+      edgeCache.set(id, rels.filter((r) => INHERITANCE_RELATIONSHIPS.includes(String(r.type))));
+    }
+    return edgeCache.get(id)!;
+  };
+
+  const typeCache = new Map<string, Entity | null>();
+  const uniqueType = async (name: string): Promise<Entity | null> => {
+    if (!typeCache.has(name)) {
+      const defs = (await findSymbolDefinitions(storage, { symbol: name })).filter((e) =>
+        TYPE_KINDS.has(String(e.type).toLowerCase()),
+      );
+      typeCache.set(name, defs.length === 1 ? defs[0]! : null);
+    }
+    return typeCache.get(name)!;
+  };
+
+  const parentsOf = async (entity: Entity): Promise<Link[]> => {
+    const links: Link[] = [];
+    for (const rel of await edgesOf(entity.id)) {
+      if (rel.fromId !== entity.id) continue;
+      const target = await storage.getEntity(rel.toId);
+      if (!target) continue;
+      const stored = isExternalPlaceholder(target) ? await uniqueType(target.name) : target;
+      links.push({
+        entity: stored ? summarize(stored) : unresolved(target.name),
+        stored,
+        relation: String(rel.type),
+        line: rel.metadata?.line ?? null,
+      });
+    }
+    return links.sort(byLocation);
+  };
+
+  const childrenOf = async (entity: Entity): Promise<Link[]> => {
+    // Subtypes in other files may still point at a placeholder carrying this type's name
+    const targets = [entity.id];
+    const pkg = (entity.metadata as Record<string, unknown> | undefined)?.package;
+    const names = typeof pkg === "string" && pkg ? [entity.name, `${pkg}.${entity.name}`] : [entity.name];
+    for (const name of names) {
+      const query = await storage.executeQuery({ type: "entity", filters: { name }, limit: 1000 });
+      for (const candidate of query.entities) {
+        if (isExternalPlaceholder(candidate) && (await uniqueType(candidate.name))?.id === entity.id) {
+          targets.push(candidate.id);
+        }
+      }
+    }
+
+    const links: Link[] = [];
+    const seen = new Set<string>();
+    for (const targetId of targets) {
+      for (const rel of await edgesOf(targetId)) {
+        if (rel.toId !== targetId || seen.has(`${rel.fromId}:${rel.type}`)) continue;
+        seen.add(`${rel.fromId}:${rel.type}`);
+        const from = await storage.getEntity(rel.fromId);
+        if (!from || isExternalPlaceholder(from)) continue;
+        links.push({
+          entity: summarize(from),
+          stored: from,
+          relation: String(rel.type),
+          line: rel.metadata?.line ?? null,
+        });
+      }
+    }
+    return links.sort(byLocation);
+  };
+
+  const diamonds = new Map<string, DiamondReport>();
+  const multiple = new Map<string, MultipleInheritanceReport>();
+  let totalNodes = 0;
+  let truncated = false;
+
+  const noteMultiple = (entity: HierarchyEntity, parents: Link[]) => {
+    const bases = parents.filter((p) => BASE_RELATIONS.has(p.relation));
+    if (entity.id && bases.length > 1 && !multiple.has(entity.id)) {
+      multiple.set(entity.id, { entity, bases: bases.map((b) => b.entity) });
+    }
+  };
+
+  const walk = async (
+    root: Entity,
+    dir: "ancestors" | "descendants",
+    next: (entity: Entity) => Promise<Link[]>,
+  ): Promise<HierarchyNode[]> => {
+    // Type id -> the types it was reached from, for diamond detection
+    const reachedFrom = new Map<string, Map<string, HierarchyEntity>>();
+    const expanded = new Set<string>([root.id]);
+
+    const expand = async (entity: Entity, chain: Set<string>, depth: number): Promise<HierarchyNode[]> => {
+      const links = await next(entity);
+      if (dir === "ancestors") noteMultiple(summarize(entity), links);
+      if (depth > maxDepth) {
+        if (links.length > 0) truncated = true;
+        return [];
+      }
+
+      const nodes: HierarchyNode[] = [];
+      for (const link of links) {
+        if (totalNodes >= limit) {
+          truncated = true;
+          break;
+        }
+        totalNodes++;
+        const node: HierarchyNode = {
+          ...link.entity,
+          relation: link.relation,
+          edgeLine: link.line,
+          seen: null,
+          children: [],
+        };
+        nodes.push(node);
+
+        const id = link.entity.id;
+        if (!id || !link.stored) continue;
+        const sources = reachedFrom.get(id) ?? new Map<string, HierarchyEntity>();
+        sources.set(entity.id, summarize(entity));
+        reachedFrom.set(id, sources);
+
+        if (chain.has(id)) {
+          node.seen = "cycle";
+        } else if (expanded.has(id)) {
+          node.seen = "repeated";
+        } else {
+          expanded.add(id);
+          node.children = await expand(link.stored, new Set(chain).add(id), depth + 1);
+        }
+      }
+      return nodes;
+    };
+
+    const tree = await expand(root, new Set([root.id]), 1);
+    for (const [id, sources] of reachedFrom) {
+      if (sources.size < 2 || id === root.id) continue;
+      const entity = await storage.getEntity(id);
+      if (!entity) continue;
+      diamonds.set(`${dir}:${id}`, { direction: dir, entity: summarize(entity), via: [...sources.values()] });
+    }
+    return tree;
+  };
+
+  const roots: HierarchyRoot[] = [];
+  const found = await findSymbolDefinitions(storage, options);
+  const definitions = options.entityType ? found : found.filter((e) => TYPE_KINDS.has(String(e.type).toLowerCase()));
+  for (const definition of definitions) {
+    roots.push({
+      type: summarize(definition),
+      ancestors: direction === "descendants" ? [] : await walk(definition, "ancestors", parentsOf),
+      descendants: direction === "ancestors" ? [] : await walk(definition, "descendants", childrenOf),
+    });
+  }
+
+  return {
+    symbol: options.symbol,
+    direction,
+    roots,
+    diamonds: [...diamonds.values()],
+    multipleInheritance: [...multiple.values()],
+    totalNodes,
+    maxDepth,
+    truncated,
+  };
+}
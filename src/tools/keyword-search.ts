@@ -0,0 +1,188 @@
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import type { Entity, SearchScope } from "../types/storage.js";
+
+export type SearchMode = "semantic" | "keyword" | "hybrid";
+
+export type KeywordHit = {
+  entity: Entity;
+  score: number;
+  exactName: boolean;
+};
+
+export type FusedSearchHit = {
+  id: string;
+  entityId: string | null;
+  content: string;
+  score: number;
+  similarity?: number;
+  /** Cosine similarity to the query; null for hits only the keyword index found */
+  cosine: number | null;
+  metadata: Record<string, unknown>;
+  matchedBy: Array<"semantic" | "keyword">;
+  rankingSignals: { semanticRank: number | null; keywordRank: number | null; keywordScore: number | null };
+};
+
+type SemanticHit = {
+  id: string;
+  content?: string;
+  similarity?: number;
+  cosine?: number;
+  metadata?: Record<string, unknown>;
+};
+
+// Standard RRF damping constant; keeps a single first-place hit from dominating the fused list
+const RRF_K = 60;
+
+/**
+ * Turn free text into an FTS5 query: every word becomes a quoted prefix term, OR-ed together.
+ * Quoting keeps FTS5 operators and punctuation in the input from being interpreted.
+ */
+export function toFtsQuery(query: string): string | null {
+  const words = query.match(/[\p{L}\p{N}]+/gu) ?? [];
+  const unique = Array.from(new Set(words.map((w) => w.toLowerCase())));
+  if (unique.length === 0) return null;
+  return unique.map((w) => `"${w}"*`).join(" OR ");
+}
+
+/**
+ * BM25 keyword search over names, qualified names, signatures and docstrings. Entities whose
+ * name (or qualified name) equals the query are placed first so exact symbol lookups always win.
+ */
+export async function keywordSearch(
+  storage: GraphStorageImpl,
+  query: string,
+  limit: number,
+  scope?: SearchScope,
+): Promise<KeywordHit[]> {
+  const trimmed = query.trim();
+  const match = toFtsQuery(trimmed);
+  if (!match) return [];
+
+  const hits = await storage.searchEntitiesByKeyword(match, Math.max(limit * 2, 50), scope);
+  const lowered = trimmed.toLowerCase();
+  const isExact = (entity: Entity) => {
+    const qualified = (entity.metadata as Record<string, unknown> | undefined)?.qualifiedName;
+    if (entity.name.toLowerCase() === lowered) return true;
+    return typeof qualified === "string" && qualified.toLowerCase() === lowered;
+  };
+
+  return hits
+    .filter((h) => !h.entity.filePath.startsWith("external://"))
+    .map((h) => ({ entity: h.entity, score: h.score, exactName: isExact(h.entity) }))
+    .sort((a, b) => Number(b.exactName) - Number(a.exactName) || b.score - a.score)
+    .slice(0, limit);
+}
+
+/**
+ * Drop semantic hits whose cosine similarity to the query is below `minScore`, so a query with
+ * no good match yields nothing instead of the nearest unrelated vectors. Hits without a cosine
+ * (keyword-only results) are kept: they matched the query text itself.
+ */
+export function filterBySimilarity<T extends { cosine?: number | null }>(hits: T[], minScore: number): T[] {
+  return hits.filter((hit) => typeof hit.cosine !== "number" || hit.cosine >= minScore);
+}
+
+/**
+ * Highest cosine among the hits filterBySimilarity drops, or null when it drops none: how far a
+ * caller would have to lower `minScore` to get anything back.
+ */
+export function bestBelowThreshold(hits: Array<{ cosine?: number | null }>, minScore: number): number | null {
+  let best: number | null = null;
+  for (const { cosine } of hits) {
+    if (typeof cosine === "number" && cosine < minScore && (best === null || cosine > best)) best = cosine;
+  }
+  return best;
+}
+
+/**
+ * Drop semantic hits whose entity is no longer in the graph. Vectors of removed entities are
+ * deleted once the indexer reports the file, but a search may run in between, or against vectors
+ * left from before the graph was reset. Hits that name no entity are kept.
+ */
+export async function dropDeletedEntityHits<T extends { metadata?: Record<string, unknown> | null }>(
+  storage: GraphStorageImpl,
+  hits: T[],
+): Promise<T[]> {
+  const kept: T[] = [];
+  for (const hit of hits) {
+    const entityId = hit.metadata?.entityId;
+    if (typeof entityId === "string" && !(await storage.getEntity(entityId))) continue;
+    kept.push(hit);
+  }
+  return kept;
+}
+
+function keywordHitToResult(hit: KeywordHit): Omit<FusedSearchHit, "score" | "matchedBy" | "rankingSignals"> {
+  const meta = (hit.entity.metadata ?? {}) as Record<string, unknown>;
+  const signature = typeof meta.signature === "string" ? meta.signature : "";
+  return {
+    id: `ent:${hit.entity.id}`,
+    entityId: hit.entity.id,
+    content: signature || `${hit.entity.type} ${hit.entity.name}`,
+    cosine: null,
+    metadata: {
+      path: hit.entity.filePath,
+      type: hit.entity.type,
+      name: hit.entity.name,
+      entityId: hit.entity.id,
+      qualifiedName: meta.qualifiedName,
+      startLine: hit.entity.location?.start?.line,
+    },
+  };
+}
+
+/**
+ * Merge semantic and keyword result lists with reciprocal rank fusion (score = Σ 1 / (k + rank)).
+ * Hits are matched on the graph entity id so the same symbol found by both retrievers is counted once.
+ */
+export function fuseSearchResults(semantic: SemanticHit[], keyword: KeywordHit[], limit: number): FusedSearchHit[] {
+  const fused = new Map<string, FusedSearchHit>();
+
+  semantic.forEach((hit, rank) => {
+    const entityId = typeof hit.metadata?.entityId === "string" ? hit.metadata.entityId : null;
+    const key = entityId ?? hit.id;
+    fused.set(key, {
+      id: hit.id,
+      entityId,
+      content: hit.content ?? "",
+      similarity: hit.similarity,
+      cosine: hit.cosine ?? null,
+      metadata: hit.metadata ?? {},
+      score: 1 / (RRF_K + rank + 1),
+      matchedBy: ["semantic"],
+      rankingSignals: { semanticRank: rank + 1, keywordRank: null, keywordScore: null },
+    });
+  });
+
+  keyword.forEach((hit, rank) => {
+    // An exact identifier match also counts as a first-place semantic vote, so it cannot be
+    // outranked by embedding neighbours that merely look similar
+    const contribution = 1 / (RRF_K + rank + 1) + (hit.exactName ? 1 / (RRF_K + 1) : 0);
+    const existing = fused.get(hit.entity.id);
+    if (existing) {
+      existing.score += contribution;
+      existing.matchedBy.push("keyword");
+      existing.rankingSignals.keywordRank = rank + 1;
+      existing.rankingSignals.keywordScore = hit.score;
+      return;
+    }
+    fused.set(hit.entity.id, {
+      ...keywordHitToResult(hit),
+      score: contribution,
+      matchedBy: ["keyword"],
+      rankingSignals: { semanticRank: null, keywordRank: rank + 1, keywordScore: hit.score },
+    });
+  });
+
+  return Array.from(fused.values())
+    .sort((a, b) => b.score - a.score)
+    .slice(0, limit);
+}
+
+/**
+ * Keyword-only results in the same shape as fused results. They are scored like a fusion with no
+ * semantic side, so ordering by score keeps exact identifier matches first.
+ */
+export function keywordResults(keyword: KeywordHit[]): FusedSearchHit[] {
+  return fuseSearchResults([], keyword, keyword.length);
+}
@@ -0,0 +1,146 @@
+import type { EnvRead } from "../parsers/env-extractor.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { type Entity, EntityType, RelationType } from "../types/storage.js";
+
+export type EnvReader = {
+  id: string;
+  name: string;
+  type: string;
+  filePath: string;
+  startLine: number | null;
+};
+
+export type EnvVarRead = {
+  filePath: string;
+  line: number;
+  column: number;
+  /** `process.env`, `os.Getenv`, `os.environ.get`, ... as written */
+  accessor: string;
+  default: string | null;
+  /** String constant the key was read through */
+  constant: string | null;
+  /** Function or method around the read; null for module-level reads */
+  reader: EnvReader | null;
+};
+
+export type EnvVariable = {
+  name: string;
+  reads: EnvVarRead[];
+  files: number;
+  /** Distinct fallbacks given where the variable is read */
+  defaults: string[];
+};
+
+export type UnresolvedEnvRead = EnvVarRead & {
+  /** Key expression that could not be reduced to a name (`key`, `` `${prefix}_URL` ``) */
+  expression: string;
+};
+
+export type ListEnvVarsResult = {
+  variables: EnvVariable[];
+  unresolved: UnresolvedEnvRead[];
+  total: number;
+  truncated: boolean;
+};
+
+export type ListEnvVarsOptions = {
+  /** Only reads in files under this path */
+  pathPrefix?: string;
+  /** Only variables whose name starts with this (`DB_`), case-insensitive */
+  name?: string;
+  /** Report reads whose key is only known at run time (default true) */
+  includeUnresolved?: boolean;
+  limit?: number;
+};
+
+const PAGE_SIZE = 1000;
+
+async function loadEnvVars(storage: GraphStorageImpl, pathPrefix?: string): Promise<Entity[]> {
+  const variables: Entity[] = [];
+  let afterId: string | undefined;
+  while (true) {
+    const page = await storage.findEntities({
+      type: "entity",
+      filters: { entityType: EntityType.ENV_VAR, ...(pathPrefix ? { scope: { pathPrefix } } : {}) },
+      afterId,
+      limit: PAGE_SIZE,
+    });
+    variables.push(...page);
+    if (page.length < PAGE_SIZE) return variables;
+    afterId = page[page.length - 1]!.id;
+  }
+}
+
+async function readersOf(storage: GraphStorageImpl, variable: Entity): Promise<Map<string, EnvReader>> {
+  const readers = new Map<string, EnvReader>();
+  for (const rel of await storage.getRelationshipsForEntity(variable.id, RelationType.READ_BY)) {
+    if (rel.fromId !== variable.id) continue;
+    const target = await storage.getEntity(rel.toId);
+    if (!target || readers.has(target.name)) continue;
+    readers.set(target.name, {
+      id: target.id,
+      name: target.name,
+      type: String(target.type),
+      filePath: target.filePath,
+      startLine: target.location?.start?.line ?? null,
+    });
+  }
+  return readers;
+}
+
+/**
+ * Every environment variable the indexed code reads, by name, with each place it is read and
+ * the function reading it. Reads whose key is computed at run time are listed apart.
+ */
+export async function listEnvVars(
+  storage: GraphStorageImpl,
+  options: ListEnvVarsOptions = {},
+): Promise<ListEnvVarsResult> {
+  const limit = Math.max(1, Math.min(5000, Number(options.limit ?? 500) || 500));
+  const prefix = options.name?.trim().toUpperCase();
+
+  const byName = new Map<string, EnvVarRead[]>();
+  const unresolved: UnresolvedEnvRead[] = [];
+  for (const variable of await loadEnvVars(storage, options.pathPrefix)) {
+    const meta = (variable.metadata as Record<string, any> | undefined) ?? {};
+    const isUnresolved = meta.unresolved === true;
+    // A name filter asks about known variables, so it leaves the unresolved reads out
+    if (isUnresolved && (options.includeUnresolved === false || prefix)) continue;
+    if (!isUnresolved && prefix && !variable.name.toUpperCase().startsWith(prefix)) continue;
+
+    const readers = await readersOf(storage, variable);
+    const reads = (Array.isArray(meta.reads) ? (meta.reads as EnvRead[]) : []).map(
+      (read): EnvVarRead => ({
+        filePath: variable.filePath,
+        line: read.line,
+        column: read.column,
+        accessor: read.accessor,
+        default: read.default ?? null,
+        constant: read.constant ?? null,
+        reader: (read.reader && readers.get(read.reader)) || null,
+      }),
+    );
+    if (isUnresolved) {
+      unresolved.push(...reads.map((read) => ({ ...read, expression: variable.name })));
+    } else {
+      byName.set(variable.name, [...(byName.get(variable.name) ?? []), ...reads]);
+    }
+  }
+
+  const byLocation = (a: EnvVarRead, b: EnvVarRead) => a.filePath.localeCompare(b.filePath) || a.line - b.line;
+  const variables = Array.from(byName.entries())
+    .sort(([a], [b]) => a.localeCompare(b))
+    .map(([name, reads]) => ({
+      name,
+      reads: reads.sort(byLocation),
+      files: new Set(reads.map((read) => read.filePath)).size,
+      defaults: [...new Set(reads.flatMap((read) => (read.default === null ? [] : [read.default])))],
+    }));
+
+  return {
+    variables: variables.slice(0, limit),
+    unresolved: unresolved.sort(byLocation),
+    total: variables.length,
+    truncated: variables.length > limit,
+  };
+}
@@ -0,0 +1,176 @@
+import { encloses, MethodOwnership, TYPE_KINDS } from "../core/override-resolver.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { type Entity, RelationType } from "../types/storage.js";
+import { findSymbolDefinitions, isExternalPlaceholder } from "./find-references.js";
+
+export type MemberKind = "method" | "field" | "type";
+
+export type ContainerMember = {
+  id: string;
+  name: string;
+  /** Entity type as indexed (method, property, struct, ...) */
+  type: string;
+  kind: MemberKind;
+  signature: string | null;
+  filePath: string;
+  startLine: number | null;
+  endLine: number | null;
+  /** Go methods: whether the receiver is the struct value or a pointer to it */
+  receiver?: "value" | "pointer";
+  /** How the member was tied to its container */
+  via: "contains" | "receiver" | "enclosure";
+};
+
+export type ContainerSummary = {
+  id: string;
+  name: string;
+  type: string;
+  filePath: string;
+  startLine: number | null;
+  endLine: number | null;
+};
+
+export type ContainerMembers = {
+  container: ContainerSummary;
+  members: ContainerMember[];
+  counts: Record<MemberKind, number>;
+  total: number;
+  truncated: boolean;
+};
+
+export type ListMembersResult = {
+  symbol: string;
+  containers: ContainerMembers[];
+};
+
+export type ListMembersOptions = {
+  /** Type name, optionally qualified (`shop.Cart`) */
+  symbol: string;
+  filePath?: string;
+  qualifier?: string;
+  /** Only members of these kinds (default all) */
+  kinds?: MemberKind[];
+  /** Members listed per container (default 500) */
+  limit?: number;
+};
+
+const METHOD_TYPES = new Set([
+  "method",
+  "function",
+  "async_function",
+  "magic_method",
+  "class_method",
+  "static_method",
+  "abstract_method",
+  "constructor",
+]);
+const FIELD_TYPES = new Set(["property", "field", "variable", "constant", "enum_member", "channel"]);
+
+function memberKind(entity: Entity): MemberKind | null {
+  const type = String(entity.type);
+  if (METHOD_TYPES.has(type)) return "method";
+  if (FIELD_TYPES.has(type)) return "field";
+  return TYPE_KINDS.has(type) ? "type" : null;
+}
+
+function summarize(entity: Entity): ContainerSummary {
+  return {
+    id: entity.id,
+    name: entity.name,
+    type: String(entity.type),
+    filePath: entity.filePath,
+    startLine: entity.location?.start?.line ?? null,
+    endLine: entity.location?.end?.line ?? null,
+  };
+}
+
+/** The stored signature, else one put together from the parameters and return or field type */
+export function signatureOf(entity: Entity): string | null {
+  const meta = (entity.metadata ?? {}) as Record<string, any>;
+  if (typeof meta.signature === "string" && meta.signature) return meta.signature;
+  const valueType = [meta.returnType, meta.propertyType, meta.fieldType].find((t) => typeof t === "string" && t);
+  if (!Array.isArray(meta.parameters)) return valueType ? `${entity.name}: ${valueType}` : null;
+  const parameters = meta.parameters.map((p: unknown) => {
+    if (typeof p === "string") return p;
+    const param = (p ?? {}) as { name?: string; type?: string };
+    return param.type ? `${param.name ?? "_"}: ${param.type}` : (param.name ?? "_");
+  });
+  return `${entity.name}(${parameters.join(", ")})${valueType ? `: ${valueType}` : ""}`;
+}
+
+/** Innermost type or method in `entities` whose range holds `entity` */
+function innermostScope(entities: Entity[], entity: Entity): Entity | null {
+  let innermost: Entity | null = null;
+  for (const candidate of entities) {
+    const kind = String(candidate.type);
+    if ((!TYPE_KINDS.has(kind) && !METHOD_TYPES.has(kind)) || !encloses(candidate, entity)) continue;
+    if (!innermost || encloses(innermost, candidate)) innermost = candidate;
+  }
+  return innermost;
+}
+
+/**
+ * Methods, fields and nested types declared in a class, struct or interface. Members come from
+ * `contains` edges (analyzers store them member-to-container, the generic extractor the other way
+ * round), from receivers for Go methods, which may sit in any file of the package, and from
+ * what the type's line range directly encloses for languages that store no edge.
+ */
+export async function listMembers(storage: GraphStorageImpl, options: ListMembersOptions): Promise<ListMembersResult> {
+  const limit = Math.max(1, Math.min(5000, Number(options.limit ?? 500) || 500));
+  const kinds = options.kinds && options.kinds.length > 0 ? new Set(options.kinds) : null;
+  const ownership = new MethodOwnership(storage);
+
+  const containers = (await findSymbolDefinitions(storage, options)).filter((e) => TYPE_KINDS.has(String(e.type)));
+  const result: ContainerMembers[] = [];
+
+  for (const container of containers) {
+    const found = new Map<string, { entity: Entity; via: ContainerMember["via"] }>();
+    const add = (entity: Entity, via: ContainerMember["via"]) => {
+      if (entity.id !== container.id && !found.has(entity.id)) found.set(entity.id, { entity, via });
+    };
+
+    for (const rel of await storage.getRelationshipsForEntity(container.id, RelationType.CONTAINS)) {
+      const other = await storage.getEntity(rel.fromId === container.id ? rel.toId : rel.fromId);
+      if (!other || isExternalPlaceholder(other)) continue;
+      // The file or type that holds the container is on the other end of its own contains edge
+      if (other.filePath === container.filePath && encloses(other, container)) continue;
+      add(other, "contains");
+    }
+    for (const method of await ownership.methodsOf(container)) {
+      add(method, typeof method.metadata?.receiver === "string" ? "receiver" : "enclosure");
+    }
+    const sameFile = await ownership.entitiesOf(container.filePath);
+    for (const entity of sameFile) {
+      if (memberKind(entity) && innermostScope(sameFile, entity)?.id === container.id) add(entity, "enclosure");
+    }
+
+    const members: ContainerMember[] = [];
+    for (const { entity, via } of found.values()) {
+      const kind = memberKind(entity);
+      if (!kind || (kinds && !kinds.has(kind))) continue;
+      const meta = (entity.metadata ?? {}) as Record<string, any>;
+      members.push({
+        ...summarize(entity),
+        kind,
+        signature: signatureOf(entity),
+        ...(entity.filePath.endsWith(".go") && typeof meta.receiver === "string"
+          ? { receiver: meta.pointerReceiver ? ("pointer" as const) : ("value" as const) }
+          : {}),
+        via,
+      });
+    }
+    members.sort((a, b) => a.filePath.localeCompare(b.filePath) || (a.startLine ?? 0) - (b.startLine ?? 0));
+
+    const counts: Record<MemberKind, number> = { method: 0, field: 0, type: 0 };
+    for (const member of members) counts[member.kind] += 1;
+    result.push({
+      container: summarize(container),
+      members: members.slice(0, limit),
+      counts,
+      total: members.length,
+      truncated: members.length > limit,
+    });
+  }
+
+  return { symbol: options.symbol, containers: result };
+}
@@ -0,0 +1,138 @@
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { type Entity, EntityType, RelationType } from "../types/storage.js";
+import { isExternalPlaceholder } from "./find-references.js";
+
+export type RouteHandler = {
+  id: string;
+  name: string;
+  type: string;
+  filePath: string;
+  startLine: number | null;
+  /** false when the handler is only known by name (declared in a file that is not indexed) */
+  resolved: boolean;
+};
+
+export type RouteEndpoint = {
+  id: string;
+  method: string;
+  path: string;
+  framework: string | null;
+  filePath: string;
+  line: number | null;
+  /** Router, app, blueprint or controller the route is registered on, as written */
+  router: string | null;
+  handler: RouteHandler | null;
+  /** The handler is a function literal at the registration itself */
+  inlineHandler: boolean;
+};
+
+export type ListRoutesResult = {
+  routes: RouteEndpoint[];
+  byMethod: Record<string, number>;
+  byFramework: Record<string, number>;
+  total: number;
+  truncated: boolean;
+};
+
+export type ListRoutesOptions = {
+  /** Only routes declared in files under this path */
+  pathPrefix?: string;
+  /** HTTP method, case-insensitive (`ANY` lists registrations that accept every method, `RPC` gRPC methods) */
+  method?: string;
+  /** Only routes whose URL path starts with this (`/api/users`) */
+  path?: string;
+  framework?: string;
+  limit?: number;
+};
+
+const PAGE_SIZE = 1000;
+
+function meta(entity: Entity): Record<string, any> {
+  return (entity.metadata as Record<string, any> | undefined) ?? {};
+}
+
+async function loadRoutes(storage: GraphStorageImpl, pathPrefix?: string): Promise<Entity[]> {
+  const routes: Entity[] = [];
+  let afterId: string | undefined;
+  while (true) {
+    const page = await storage.findEntities({
+      type: "entity",
+      filters: { entityType: EntityType.ROUTE, ...(pathPrefix ? { scope: { pathPrefix } } : {}) },
+      afterId,
+      limit: PAGE_SIZE,
+    });
+    routes.push(...page);
+    if (page.length < PAGE_SIZE) return routes;
+    afterId = page[page.length - 1]!.id;
+  }
+}
+
+async function handlerOf(storage: GraphStorageImpl, route: Entity): Promise<RouteHandler | null> {
+  const edges = await storage.getRelationshipsForEntity(route.id, RelationType.HANDLED_BY);
+  const edge = edges.find((rel) => rel.fromId === route.id);
+  const target = edge ? await storage.getEntity(edge.toId) : null;
+  if (!target) return null;
+  return {
+    id: target.id,
+    name: target.name,
+    type: String(target.type),
+    filePath: target.filePath,
+    startLine: isExternalPlaceholder(target) ? null : (target.location?.start?.line ?? null),
+    resolved: !isExternalPlaceholder(target),
+  };
+}
+
+/**
+ * The HTTP endpoints and gRPC methods of the indexed code, ordered by path and method, each with
+ * the function (or rpc declaration) that handles it. Counts by method and framework cover every
+ * matching route, listed or not.
+ */
+export async function listRoutes(
+  storage: GraphStorageImpl,
+  options: ListRoutesOptions = {},
+): Promise<ListRoutesResult> {
+  const limit = Math.max(1, Math.min(5000, Number(options.limit ?? 500) || 500));
+  const method = options.method?.trim().toUpperCase();
+  const framework = options.framework?.trim().toLowerCase();
+
+  const matching = (await loadRoutes(storage, options.pathPrefix))
+    .filter((route) => {
+      const m = meta(route);
+      if (method && String(m.httpMethod ?? "").toUpperCase() !== method) return false;
+      if (framework && String(m.framework ?? "").toLowerCase() !== framework) return false;
+      return !options.path || String(m.path ?? "").startsWith(options.path);
+    })
+    .sort(
+      (a, b) =>
+        String(meta(a).path).localeCompare(String(meta(b).path)) ||
+        String(meta(a).httpMethod).localeCompare(String(meta(b).httpMethod)) ||
+        a.filePath.localeCompare(b.filePath) ||
+        (a.location?.start?.line ?? 0) - (b.location?.start?.line ?? 0),
+    );
+
+  const byMethod: Record<string, number> = {};
+  const byFramework: Record<string, number> = {};
+  for (const route of matching) {
+    const m = meta(route);
+    byMethod[String(m.httpMethod)] = (byMethod[String(m.httpMethod)] ?? 0) + 1;
+    if (m.framework) byFramework[String(m.framework)] = (byFramework[String(m.framework)] ?? 0) + 1;
+  }
+
+  const routes: RouteEndpoint[] = [];
+  for (const route of matching.slice(0, limit)) {
+    const m = meta(route);
+    routes.push({
+      id: route.id,
+      method: String(m.httpMethod),
+      path: String(m.path),
+      framework: m.framework ? String(m.framework) : null,
+      filePath: route.filePath,
+      line: route.location?.start?.line ?? null,
+      router: typeof m.router === "string" ? m.router : (m.controller ?? null),
+      handler: await handlerOf(storage, route),
+      inlineHandler: m.inlineHandler === true,
+    });
+  }
+
+  return { routes, byMethod, byFramework, total: matching.length, truncated: matching.length > limit };
+}
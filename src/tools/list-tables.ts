@@ -0,0 +1,149 @@
+import { matchSqlTable, type SqlColumn, type SqlForeignKey } from "../parsers/sql-analyzer.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { type Entity, EntityType } from "../types/storage.js";
+
+export type TableColumn = SqlColumn & {
+  /** File of the ALTER TABLE that added the column, when not the table's own */
+  addedIn?: string;
+};
+
+export type TableForeignKey = SqlForeignKey & {
+  /** The referenced table when it is indexed */
+  resolved: { name: string; filePath: string; line: number } | null;
+};
+
+export type SqlTable = {
+  /** Normalised name, schema included when written: `public.users` */
+  name: string;
+  kind: "table" | "view";
+  id: string;
+  filePath: string;
+  line: number;
+  /** False when only ALTER TABLE statements of the table are indexed */
+  defined: boolean;
+  documentation: string | null;
+  columns: TableColumn[];
+  primaryKey: string[];
+  foreignKeys: TableForeignKey[];
+  /** Foreign keys of other tables pointing at this one */
+  referencedBy: Array<{ table: string; columns: string[]; referencedColumns: string[] }>;
+  /** Other files whose ALTER TABLE statements add to the table */
+  alteredIn: string[];
+};
+
+export type ListTablesResult = {
+  tables: SqlTable[];
+  total: number;
+  truncated: boolean;
+};
+
+export type ListTablesOptions = {
+  /** Only tables declared in files under this path */
+  pathPrefix?: string;
+  /** Only tables whose name contains this, case-insensitive */
+  name?: string;
+  kind?: "table" | "view";
+  /** Leave columns out for a shorter overview (default true) */
+  includeColumns?: boolean;
+  limit?: number;
+};
+
+const PAGE_SIZE = 1000;
+
+async function loadTables(storage: GraphStorageImpl, pathPrefix?: string): Promise<Entity[]> {
+  const tables: Entity[] = [];
+  let afterId: string | undefined;
+  while (true) {
+    const page = await storage.findEntities({
+      type: "entity",
+      filters: {
+        entityType: [EntityType.TABLE, EntityType.VIEW],
+        ...(pathPrefix ? { scope: { pathPrefix } } : {}),
+      },
+      afterId,
+      limit: PAGE_SIZE,
+    });
+    tables.push(...page);
+    if (page.length < PAGE_SIZE) return tables;
+    afterId = page[page.length - 1]!.id;
+  }
+}
+
+function meta(entity: Entity): Record<string, any> {
+  return (entity.metadata ?? {}) as Record<string, any>;
+}
+
+const byLocation = (a: Entity, b: Entity) =>
+  a.filePath.localeCompare(b.filePath) || (a.location?.start?.line ?? 0) - (b.location?.start?.line ?? 0);
+
+/**
+ * Tables and views declared in the indexed SQL, one entry per name. A table created by several
+ * migrations is described by the last one in path order, with the columns and foreign keys that
+ * ALTER TABLE statements in later files add to it.
+ */
+export async function listTables(
+  storage: GraphStorageImpl,
+  options: ListTablesOptions = {},
+): Promise<ListTablesResult> {
+  const limit = Math.max(1, Math.min(5000, Number(options.limit ?? 500) || 500));
+  const needle = options.name?.trim().toLowerCase();
+
+  const byName = new Map<string, Entity[]>();
+  for (const entity of (await loadTables(storage, options.pathPrefix)).sort(byLocation)) {
+    const name = meta(entity).qualifiedName;
+    if (typeof name !== "string") continue;
+    byName.set(name, [...(byName.get(name) ?? []), entity]);
+  }
+
+  const tables: SqlTable[] = [];
+  for (const [name, entities] of byName) {
+    const definitions = entities.filter((entity) => meta(entity).altered !== true);
+    const definition = definitions[definitions.length - 1] ?? entities[0]!;
+    // Alterations before the last definition were made to a table it replaced
+    const alterations = entities.filter((entity) => entity !== definition && byLocation(entity, definition) > 0);
+    const columns: TableColumn[] = [...(meta(definition).columns ?? [])];
+    const primaryKey: string[] = [...(meta(definition).primaryKey ?? [])];
+    const foreignKeys: SqlForeignKey[] = [...(meta(definition).foreignKeys ?? [])];
+    for (const alteration of alterations) {
+      const added = (meta(alteration).columns ?? []) as SqlColumn[];
+      columns.push(...added.map((column) => ({ ...column, addedIn: alteration.filePath })));
+      primaryKey.push(...(meta(alteration).primaryKey ?? []));
+      foreignKeys.push(...(meta(alteration).foreignKeys ?? []));
+    }
+    tables.push({
+      name,
+      kind: definition.type === EntityType.VIEW ? "view" : "table",
+      id: definition.id,
+      filePath: definition.filePath,
+      line: definition.location?.start?.line ?? 0,
+      defined: meta(definition).altered !== true,
+      documentation: meta(definition).documentation ?? null,
+      columns,
+      primaryKey: [...new Set(primaryKey)],
+      foreignKeys: foreignKeys.map((fk) => ({ ...fk, resolved: null })),
+      referencedBy: [],
+      alteredIn: [...new Set(alterations.map((alteration) => alteration.filePath))].filter(
+        (file) => file !== definition.filePath,
+      ),
+    });
+  }
+
+  const byKey = new Map(tables.map((table) => [table.name, table]));
+  for (const table of tables) {
+    for (const fk of table.foreignKeys) {
+      const key = matchSqlTable(fk.table, byKey.keys());
+      const target = key ? byKey.get(key) : undefined;
+      if (!target) continue;
+      fk.resolved = { name: target.name, filePath: target.filePath, line: target.line };
+      target.referencedBy.push({ table: table.name, columns: fk.columns, referencedColumns: fk.referencedColumns });
+    }
+  }
+
+  const matching = tables
+    .filter((table) => !options.kind || table.kind === options.kind)
+    .filter((table) => !needle || table.name.toLowerCase().includes(needle))
+    .sort((a, b) => a.name.localeCompare(b.name))
+    .map((table) => (options.includeColumns === false ? { ...table, columns: [] } : table));
+
+  return { tables: matching.slice(0, limit), total: matching.length, truncated: matching.length > limit };
+}
@@ -0,0 +1,110 @@
+import type { CommentMarker, CommentMarkerKind, EnclosingSymbol } from "../parsers/comment-extractor.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { type Entity, EntityType } from "../types/storage.js";
+
+export type TodoEnclosing = {
+  /** null when the declaration is no longer in the index under that name and line */
+  id: string | null;
+  name: string;
+  type: string;
+  line: number;
+};
+
+export type TodoItem = {
+  kind: CommentMarkerKind;
+  text: string;
+  assignee: string | null;
+  filePath: string;
+  line: number;
+  /** The comment entity holding the marker */
+  commentId: string;
+  /** Innermost declaration around the comment; null at file level */
+  enclosing: TodoEnclosing | null;
+};
+
+export type ListTodosResult = {
+  todos: TodoItem[];
+  total: number;
+  byKind: Partial<Record<CommentMarkerKind, number>>;
+  truncated: boolean;
+};
+
+export type ListTodosOptions = {
+  /** Only markers in files under this path */
+  pathPrefix?: string;
+  /** Only these markers (default: TODO, FIXME, HACK and XXX) */
+  kinds?: CommentMarkerKind[];
+  /** Only markers naming this assignee (`TODO(alice)`), case-insensitive */
+  assignee?: string;
+  /** Only markers whose text contains this, case-insensitive */
+  query?: string;
+  limit?: number;
+};
+
+const PAGE_SIZE = 1000;
+
+async function loadComments(storage: GraphStorageImpl, pathPrefix?: string): Promise<Entity[]> {
+  const comments: Entity[] = [];
+  let afterId: string | undefined;
+  while (true) {
+    const page = await storage.findEntities({
+      type: "entity",
+      filters: { entityType: EntityType.COMMENT, ...(pathPrefix ? { scope: { pathPrefix } } : {}) },
+      afterId,
+      limit: PAGE_SIZE,
+    });
+    comments.push(...page.filter((comment) => Array.isArray(comment.metadata?.markers)));
+    if (page.length < PAGE_SIZE) return comments;
+    afterId = page[page.length - 1]!.id;
+  }
+}
+
+/**
+ * TODO, FIXME, HACK and XXX markers in the comments of the indexed code, in file and line order,
+ * each with the declaration it sits in.
+ */
+export async function listTodos(storage: GraphStorageImpl, options: ListTodosOptions = {}): Promise<ListTodosResult> {
+  const limit = Math.max(1, Math.min(5000, Number(options.limit ?? 500) || 500));
+  const kinds = options.kinds?.length ? new Set(options.kinds) : undefined;
+  const assignee = options.assignee?.trim().toLowerCase();
+  const query = options.query?.trim().toLowerCase();
+
+  // One lookup per enclosing declaration, however many markers it holds
+  const declarations = new Map<string, string | null>();
+  const enclosingOf = async (filePath: string, symbol: EnclosingSymbol): Promise<TodoEnclosing> => {
+    const key = `${filePath}|${symbol.name}|${symbol.line}`;
+    if (!declarations.has(key)) {
+      const named = await storage.findEntities({ type: "entity", filters: { filePath, name: symbol.name } });
+      declarations.set(key, named.find((e) => e.location?.start?.line === symbol.line)?.id ?? null);
+    }
+    return { id: declarations.get(key)!, name: symbol.name, type: symbol.type, line: symbol.line };
+  };
+
+  const todos: Array<Omit<TodoItem, "enclosing"> & { symbol?: EnclosingSymbol }> = [];
+  for (const comment of await loadComments(storage, options.pathPrefix)) {
+    const meta = (comment.metadata ?? {}) as Record<string, any>;
+    for (const marker of meta.markers as CommentMarker[]) {
+      if (kinds && !kinds.has(marker.kind)) continue;
+      if (assignee && marker.assignee?.toLowerCase() !== assignee) continue;
+      if (query && !marker.text.toLowerCase().includes(query)) continue;
+      todos.push({
+        kind: marker.kind,
+        text: marker.text,
+        assignee: marker.assignee ?? null,
+        filePath: comment.filePath,
+        line: marker.line,
+        commentId: comment.id,
+        symbol: meta.enclosing as EnclosingSymbol | undefined,
+      });
+    }
+  }
+
+  todos.sort((a, b) => a.filePath.localeCompare(b.filePath) || a.line - b.line);
+  const byKind: Partial<Record<CommentMarkerKind, number>> = {};
+  for (const todo of todos) byKind[todo.kind] = (byKind[todo.kind] ?? 0) + 1;
+  const page: TodoItem[] = [];
+  for (const { symbol, ...todo } of todos.slice(0, limit)) {
+    page.push({ ...todo, enclosing: symbol ? await enclosingOf(todo.filePath, symbol) : null });
+  }
+  return { todos: page, total: todos.length, byKind, truncated: todos.length > limit };
+}
@@ -0,0 +1,41 @@
+/**
+ * The relationship shape shared by list_callers/list_callees, find_references and impact_analysis:
+ * the entity an edge leaves and the one it reaches, its type, which way it runs relative to the
+ * symbol asked about, and where in the source it is established, so clients can link to the line.
+ */
+
+export type EdgeDirection = "incoming" | "outgoing";
+
+export type LocatedEdge = {
+  /** Entity id the edge leaves */
+  from: string;
+  /** Entity id the edge reaches; null when it was never bound to a declaration */
+  to: string | null;
+  type: string;
+  /** `incoming` edges lead to the symbol (callers, references, dependents); `outgoing` lead away */
+  direction: EdgeDirection;
+  /** The call site, import or reference establishing the edge; `line` is null when none was recorded */
+  file: string | null;
+  line: number | null;
+  column: number | null;
+};
+
+export function locatedEdge(edge: {
+  from: string;
+  to: string | null;
+  type: string;
+  direction: EdgeDirection;
+  file?: string | null;
+  line?: number | null;
+  column?: number | null;
+}): LocatedEdge {
+  return {
+    from: edge.from,
+    to: edge.to,
+    type: edge.type,
+    direction: edge.direction,
+    file: edge.file ?? null,
+    line: edge.line ?? null,
+    column: edge.column ?? null,
+  };
+}
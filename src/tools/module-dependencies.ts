@@ -0,0 +1,208 @@
+import { dirname, resolve } from "node:path";
+import { isContainer } from "../core/package-tree.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { RelationType } from "../types/storage.js";
+import {
+  type CycleGranularity,
+  type DetectCyclesOptions,
+  loadModuleGraph,
+  type ModuleKind,
+  stronglyConnectedComponents,
+} from "./detect-cycles.js";
+import { dotId, escapeXml, writeAtomically } from "./export-graph.js";
+import { isExternalPlaceholder } from "./find-references.js";
+
+export type ModuleNode = {
+  id: string;
+  kind: ModuleKind;
+  /** Modules importing this one */
+  fanIn: number;
+  /** Modules this one imports */
+  fanOut: number;
+};
+
+export type ModuleDependency = {
+  from: string;
+  to: string;
+  /** Symbol-level edges behind the dependency, or the import statements when none are bound */
+  weight: number;
+  /** Import statements (or resolved import edges) from `from` to `to` */
+  imports: number;
+  /** Symbol-level edges (imported names, calls, references, heritage) from `from` into `to` */
+  references: number;
+  /** Both modules are in the same import cycle */
+  cyclic: boolean;
+};
+
+export type ModuleDependenciesResult = {
+  granularity: CycleGranularity;
+  modules: ModuleNode[];
+  edges: ModuleDependency[];
+  hasCycles: boolean;
+  /** Strongly connected components with more than one module, each sorted */
+  cycles: string[][];
+};
+
+export type ModuleDependenciesOptions = DetectCyclesOptions & {
+  /** Leave out dependencies lighter than this (default 1) */
+  minWeight?: number;
+};
+
+export type ModuleGraphFormat = "dot" | "graphml";
+
+export type ModuleGraphExportResult = {
+  format: ModuleGraphFormat;
+  outputPath: string;
+  modules: number;
+  edges: number;
+  bytes: number;
+};
+
+// Code-unit order rather than localeCompare, as in detect_cycles
+const byCodeUnit = (a: string, b: string) => (a < b ? -1 : a > b ? 1 : 0);
+
+const edgeKey = (from: string, to: string) => `${from}\u0000${to}`;
+
+/**
+ * Module-level dependencies: the import graph `detect_cycles` works on (Go packages, TS/JS and Python
+ * files or directories), plus the import edges the post-index passes bound between files of the other
+ * languages (C/C++ includes, C#, Ruby, PHP names). Each dependency is weighted by the
+ * symbol-level edges running from one module into the other, so a module that uses one helper of
+ * a dependency weighs less than one built on top of it.
+ */
+export async function moduleDependencies(
+  storage: GraphStorageImpl,
+  options: ModuleDependenciesOptions = {},
+): Promise<ModuleDependenciesResult> {
+  const granularity = options.granularity ?? "file";
+  const minWeight = Math.max(1, options.minWeight ?? 1);
+  const prefix = options.pathPrefix ? resolve(options.pathPrefix) : null;
+  const inScope = (filePath: string) => !prefix || filePath === prefix || filePath.startsWith(`${prefix}/`);
+  const { graph, moduleOf } = await loadModuleGraph(storage, options);
+
+  const fileOf = new Map<string, string>();
+  for await (const entity of storage.iterateEntities()) {
+    if (isExternalPlaceholder(entity) || isContainer(entity) || !inScope(entity.filePath)) continue;
+    fileOf.set(entity.id, entity.filePath);
+  }
+
+  // Files of languages without a module graph of their own are modules at the chosen granularity
+  const otherModule = (filePath: string) => (granularity === "directory" ? dirname(filePath) : filePath);
+  const references = new Map<string, number>();
+  const resolvedImports = new Map<string, number>();
+  for await (const rel of storage.iterateRelationships()) {
+    // File-to-file import edges are the graph's own edges, not symbols used across modules
+    if (rel.type === RelationType.CONTAINS || rel.metadata?.moduleImport !== undefined) continue;
+    const fromFile = fileOf.get(rel.fromId);
+    const toFile = fileOf.get(rel.toId);
+    if (!fromFile || !toFile || fromFile === toFile) continue;
+
+    const graphFrom = moduleOf(fromFile);
+    const graphTo = moduleOf(toFile);
+    const from = graphFrom ?? otherModule(fromFile);
+    const to = graphTo ?? otherModule(toFile);
+    if (from === to) continue;
+    const key = edgeKey(from, to);
+    references.set(key, (references.get(key) ?? 0) + 1);
+
+    if (rel.type === RelationType.IMPORTS && !graphFrom && !graphTo) {
+      graph.addModule(from, granularity);
+      graph.addModule(to, granularity);
+      graph.addEdge(from, to, { filePath: fromFile, line: rel.metadata?.line ?? null, source: toFile });
+      resolvedImports.set(key, (resolvedImports.get(key) ?? 0) + 1);
+    }
+  }
+
+  const neighbors = (node: string) => graph.neighbors(node);
+  const cycles = stronglyConnectedComponents(Array.from(graph.edges.keys()), neighbors)
+    .filter((members) => members.length > 1)
+    .sort((a, b) => byCodeUnit(a[0]!, b[0]!));
+  const component = new Map<string, number>();
+  cycles.forEach((members, i) => {
+    for (const member of members) component.set(member, i);
+  });
+
+  const edges: ModuleDependency[] = [];
+  for (const [from, targets] of graph.edges) {
+    for (const [to, sites] of targets) {
+      const key = edgeKey(from, to);
+      // Other languages reach the graph through their import edges, which are counted as references too
+      const imports = resolvedImports.get(key) ?? sites.length;
+      const refs = references.get(key) ?? 0;
+      const weight = refs > 0 ? refs : imports;
+      if (weight < minWeight) continue;
+      const cyclic = component.has(from) && component.get(from) === component.get(to);
+      edges.push({ from, to, weight, imports, references: refs, cyclic });
+    }
+  }
+  edges.sort((a, b) => byCodeUnit(a.from, b.from) || byCodeUnit(a.to, b.to));
+
+  const fanIn = new Map<string, number>();
+  const fanOut = new Map<string, number>();
+  for (const edge of edges) {
+    fanOut.set(edge.from, (fanOut.get(edge.from) ?? 0) + 1);
+    fanIn.set(edge.to, (fanIn.get(edge.to) ?? 0) + 1);
+  }
+  const modules = Array.from(graph.kinds.entries())
+    .map(([id, kind]) => ({ id, kind, fanIn: fanIn.get(id) ?? 0, fanOut: fanOut.get(id) ?? 0 }))
+    .sort((a, b) => byCodeUnit(a.id, b.id));
+
+  return { granularity, modules, edges, hasCycles: cycles.length > 0, cycles };
+}
+
+/** Graphviz digraph; edges carry their weight as label and pen width, cyclic ones are drawn red */
+export function moduleGraphToDot(result: ModuleDependenciesResult): string {
+  const lines = ["digraph modules {", "  rankdir=LR;", "  node [shape=box, fontsize=10];"];
+  for (const module of result.modules) {
+    lines.push(`  ${dotId(module.id)} [kind=${dotId(module.kind)}];`);
+  }
+  for (const edge of result.edges) {
+    const width = Math.min(8, 1 + Math.log2(edge.weight)).toFixed(1);
+    const attrs = [`weight=${edge.weight}`, `label="${edge.weight}"`, `penwidth=${width}`];
+    if (edge.cyclic) attrs.push('color="red"');
+    lines.push(`  ${dotId(edge.from)} -> ${dotId(edge.to)} [${attrs.join(", ")}];`);
+  }
+  lines.push("}");
+  return `${lines.join("\n")}\n`;
+}
+
+export function moduleGraphToGraphML(result: ModuleDependenciesResult): string {
+  const lines = [
+    '<?xml version="1.0" encoding="UTF-8"?>',
+    '<graphml xmlns="http://graphml.graphdrawing.org/xmlns">',
+    '  <key id="n_kind" for="node" attr.name="kind" attr.type="string"/>',
+    '  <key id="n_fan_in" for="node" attr.name="fanIn" attr.type="int"/>',
+    '  <key id="n_fan_out" for="node" attr.name="fanOut" attr.type="int"/>',
+    '  <key id="e_weight" for="edge" attr.name="weight" attr.type="double"/>',
+    '  <key id="e_imports" for="edge" attr.name="imports" attr.type="int"/>',
+    '  <key id="e_references" for="edge" attr.name="references" attr.type="int"/>',
+    '  <key id="e_cyclic" for="edge" attr.name="cyclic" attr.type="boolean"/>',
+    '  <graph id="modules" edgedefault="directed">',
+  ];
+  for (const module of result.modules) {
+    lines.push(
+      `    <node id="${escapeXml(module.id)}"><data key="n_kind">${module.kind}</data>` +
+        `<data key="n_fan_in">${module.fanIn}</data><data key="n_fan_out">${module.fanOut}</data></node>`,
+    );
+  }
+  for (const edge of result.edges) {
+    lines.push(
+      `    <edge source="${escapeXml(edge.from)}" target="${escapeXml(edge.to)}">` +
+        `<data key="e_weight">${edge.weight}</data><data key="e_imports">${edge.imports}</data>` +
+        `<data key="e_references">${edge.references}</data><data key="e_cyclic">${edge.cyclic}</data></edge>`,
+    );
+  }
+  lines.push("  </graph>", "</graphml>");
+  return `${lines.join("\n")}\n`;
+}
+
+/** Write the aggregated graph to `outputPath` in place of a partial file, as export_graph does */
+export async function exportModuleGraph(
+  result: ModuleDependenciesResult,
+  format: ModuleGraphFormat,
+  outputPath: string,
+): Promise<ModuleGraphExportResult> {
+  const content = format === "dot" ? moduleGraphToDot(result) : moduleGraphToGraphML(result);
+  const bytes = await writeAtomically(outputPath, (emit) => emit(content));
+  return { format, outputPath, modules: result.modules.length, edges: result.edges.length, bytes };
+}
@@ -0,0 +1,250 @@
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { matchesSearchScope } from "../storage/search-filters.js";
+import type { Entity, SearchScope } from "../types/storage.js";
+import { listCallees, listCallers } from "./call-graph.js";
+import { findDefinitionCandidates } from "./find-definition.js";
+import { findReferences } from "./find-references.js";
+import { type HierarchyNode, inheritanceHierarchy } from "./inheritance-hierarchy.js";
+
+export type QueryIntentKind =
+  | "callers"
+  | "callees"
+  | "references"
+  | "definitions"
+  | "implementations"
+  | "file_members";
+
+export type QueryIntent = {
+  kind: QueryIntentKind;
+  /** Symbol for most intents, a file path for file_members */
+  target: string;
+  /** Entity kind named in the question ("functions in file x" → function) */
+  entityType?: string;
+};
+
+export type StructuredQueryItem = {
+  id: string;
+  name: string;
+  type: string;
+  filePath: string;
+  startLine: number | null;
+  endLine: number | null;
+  /** How the item relates to the target: calls, called_by, references, extends, ... */
+  relation: string | null;
+  /** Line of the call site or reference, when there is one */
+  line: number | null;
+};
+
+export type StructuredQueryResult = {
+  intent: QueryIntent;
+  items: StructuredQueryItem[];
+  /** Names the traversal met but could not bind to an indexed entity */
+  unresolved: string[];
+  total: number;
+  truncated: boolean;
+};
+
+const PREFIX = String.raw`(?:(?:find|show(?: me)?|list|get|give me|search(?: for)?)\s+)?(?:(?:all|the|every)\s+)?`;
+const SYMBOL_KIND = "(?:function|method|class|type|interface|struct)";
+const QUOTE = "[`'\"]?";
+const SYMBOL = String.raw`(?:the\s+)?(?:${SYMBOL_KIND}\s+)?(${QUOTE}[\w$.:#]+${QUOTE})(?:\s+${SYMBOL_KIND})?`;
+const PATH = String.raw`(?:the\s+)?(?:file\s+)?(\S+)`;
+const SUBTYPE_VERB = String.raw`(?:implement(?:s|ing)?|extend(?:s|ing)?|inherit(?:s|ing)?\s+from|derived\s+from)`;
+const MEMBER_WORD =
+  "(entities|members|symbols|contents|definitions|functions|methods|classes|interfaces|types|variables|constants)";
+const KIND_WORDS: Record<string, string> = {
+  functions: "function",
+  methods: "method",
+  classes: "class",
+  interfaces: "interface",
+  types: "type",
+  variables: "variable",
+  constants: "constant",
+};
+
+/** Checked in order: "what does X call" must win over the callers patterns */
+const INTENT_PATTERNS: Array<{ kind: QueryIntentKind; pattern: string }> = [
+  { kind: "callees", pattern: String.raw`(?:what|which (?:functions|methods))\s+(?:does|do)\s+${SYMBOL}\s+call` },
+  {
+    kind: "callees",
+    pattern: String.raw`(?:callees|calls|functions called|methods called)\s+(?:of|from|by|made by|in)\s+${SYMBOL}`,
+  },
+  { kind: "callers", pattern: String.raw`(?:functions|methods|code|places)?\s*(?:that|which)\s+calls?\s+${SYMBOL}` },
+  { kind: "callers", pattern: String.raw`(?:who|what|which\s+(?:functions|methods))\s+calls?\s+${SYMBOL}` },
+  { kind: "callers", pattern: String.raw`(?:callers\s+(?:of|for)|calls\s+to)\s+${SYMBOL}` },
+  { kind: "callers", pattern: String.raw`where\s+is\s+${SYMBOL}\s+called(?:\s+from)?` },
+  {
+    kind: "implementations",
+    pattern: String.raw`(?:types|classes|structs)\s+(?:that\s+|which\s+)?${SUBTYPE_VERB}\s+${SYMBOL}`,
+  },
+  {
+    kind: "implementations",
+    pattern: String.raw`(?:implementations|implementers|subclasses|subtypes)\s+(?:of|for)\s+${SYMBOL}`,
+  },
+  { kind: "implementations", pattern: String.raw`(?:what|who)\s+(?:implements|extends|inherits\s+from)\s+${SYMBOL}` },
+  { kind: "references", pattern: String.raw`(?:references|usages|uses)\s+(?:to|of)\s+${SYMBOL}` },
+  { kind: "references", pattern: String.raw`where\s+is\s+${SYMBOL}\s+(?:used|referenced)` },
+  { kind: "references", pattern: String.raw`(?:who|what)\s+(?:uses|references)\s+${SYMBOL}` },
+  { kind: "definitions", pattern: String.raw`where\s+is\s+${SYMBOL}\s+(?:defined|declared)` },
+  { kind: "definitions", pattern: String.raw`(?:go\s+to\s+)?(?:definitions?|declarations?)\s+(?:of|for)\s+${SYMBOL}` },
+  { kind: "file_members", pattern: String.raw`what(?:'s|\s+is)\s+(?:defined\s+)?in\s+${PATH}` },
+  { kind: "file_members", pattern: String.raw`${MEMBER_WORD}\s+(?:in|of|inside)\s+${PATH}` },
+];
+
+const COMPILED = INTENT_PATTERNS.map(({ kind, pattern }) => ({
+  kind,
+  regex: new RegExp(`^${PREFIX}${pattern}$`, "i"),
+}));
+
+function cleanTarget(raw: string): string {
+  return raw.replace(/^[`'"]+|[`'"]+$/g, "").trim();
+}
+
+/**
+ * The graph traversal a question asks for, or null when it matches none of the known phrasings.
+ * Matching is deterministic: the first pattern that covers the whole question wins.
+ */
+export function parseQueryIntent(query: string): QueryIntent | null {
+  const text = query
+    .trim()
+    .replace(/[?.!]+$/, "")
+    .replace(/\s+/g, " ");
+  for (const { kind, regex } of COMPILED) {
+    const match = regex.exec(text);
+    if (!match) continue;
+    // file_members with a kind word captures it first
+    const target = cleanTarget(match[match.length - 1] ?? "");
+    if (!target) continue;
+    const intent: QueryIntent = { kind, target };
+    const kindWord = kind === "file_members" && match.length > 2 ? match[1]?.toLowerCase() : undefined;
+    if (kindWord && KIND_WORDS[kindWord]) intent.entityType = KIND_WORDS[kindWord];
+    return intent;
+  }
+  return null;
+}
+
+type Located = Pick<StructuredQueryItem, "id" | "name" | "type" | "filePath" | "startLine" | "endLine">;
+
+function item(entity: Located, relation: string | null, line: number | null): StructuredQueryItem {
+  return {
+    id: entity.id,
+    name: entity.name,
+    type: entity.type,
+    filePath: entity.filePath,
+    startLine: entity.startLine,
+    endLine: entity.endLine,
+    relation,
+    line,
+  };
+}
+
+function located(entity: Entity): Located {
+  return {
+    id: entity.id,
+    name: entity.name,
+    type: String(entity.type),
+    filePath: entity.filePath,
+    startLine: entity.location?.start?.line ?? null,
+    endLine: entity.location?.end?.line ?? null,
+  };
+}
+
+async function fileMembers(storage: GraphStorageImpl, intent: QueryIntent): Promise<StructuredQueryItem[]> {
+  const wanted = intent.target.replace(/\\/g, "/").replace(/^\.\//, "");
+  const files = (await storage.listIndexedFiles()).map((info) => info.path);
+  const matches = files.filter((p) => {
+    const path = p.replace(/\\/g, "/");
+    return path === wanted || path.endsWith(`/${wanted}`);
+  });
+  if (matches.length === 0) return [];
+  const entities = await storage.findEntities({ type: "entity", filters: { filePath: matches }, limit: 1000 });
+  return entities
+    .filter((e) => !intent.entityType || String(e.type) === intent.entityType)
+    .sort((a, b) => a.filePath.localeCompare(b.filePath) || a.location.start.line - b.location.start.line)
+    .map((e) => item(located(e), "contained_in", null));
+}
+
+function flattenDescendants(nodes: HierarchyNode[], out: StructuredQueryItem[], seen: Set<string>): void {
+  for (const node of nodes) {
+    if (node.id && node.filePath && node.type && !seen.has(node.id)) {
+      seen.add(node.id);
+      out.push(
+        item(
+          { ...node, id: node.id, type: node.type, filePath: node.filePath, startLine: node.line, endLine: null },
+          node.relation,
+          node.edgeLine,
+        ),
+      );
+    }
+    flattenDescendants(node.children, out, seen);
+  }
+}
+
+/** Run the traversal behind an intent and flatten it into one list of entities within `scope` */
+export async function runQueryIntent(
+  storage: GraphStorageImpl,
+  intent: QueryIntent,
+  limit = 50,
+  scope?: SearchScope,
+): Promise<StructuredQueryResult> {
+  const items: StructuredQueryItem[] = [];
+  const unresolved: string[] = [];
+  const symbol = intent.target;
+
+  switch (intent.kind) {
+    case "callers":
+    case "callees": {
+      const result =
+        intent.kind === "callers"
+          ? await listCallers(storage, { symbol, limit: 1000 })
+          : await listCallees(storage, { symbol, limit: 1000 });
+      const relation = intent.kind === "callers" ? "calls" : "called_by";
+      for (const group of result.definitions) {
+        for (const call of group.calls) {
+          if (call.entity) items.push(item(call.entity, relation, call.callSites[0]?.line ?? null));
+          else unresolved.push(call.name);
+        }
+      }
+      for (const call of result.unresolved) unresolved.push(call.name);
+      break;
+    }
+    case "references": {
+      const result = await findReferences(storage, { symbol, limit: 1000 });
+      for (const group of result.definitions) {
+        for (const ref of group.references) {
+          if (ref.from) items.push(item(located(ref.from), ref.relationshipType, ref.line));
+        }
+      }
+      for (const ref of result.unresolved) if (ref.from) unresolved.push(ref.from.name);
+      break;
+    }
+    case "definitions": {
+      for (const candidate of await findDefinitionCandidates(storage, { symbol, limit: 1000 })) {
+        items.push(item(located(candidate.entity), "defines", null));
+      }
+      break;
+    }
+    case "implementations": {
+      const result = await inheritanceHierarchy(storage, { symbol, direction: "descendants" });
+      const seen = new Set<string>();
+      for (const root of result.roots) flattenDescendants(root.descendants, items, seen);
+      break;
+    }
+    case "file_members":
+      items.push(...(await fileMembers(storage, intent)));
+      break;
+  }
+
+  // One entry per entity and relation; the first call site or reference is kept
+  const scoped = items.filter((entry) => matchesSearchScope(entry, scope));
+  const unique = scoped.filter(
+    (entry, i) => scoped.findIndex((o) => o.id === entry.id && o.relation === entry.relation) === i,
+  );
+  return {
+    intent,
+    items: unique.slice(0, limit),
+    unresolved: [...new Set(unresolved)].sort(),
+    total: unique.length,
+    truncated: unique.length > limit,
+  };
+}
@@ -0,0 +1,301 @@
+import { dirname, extname } from "node:path";
+import { listContainers } from "../core/package-tree.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { type Entity, type Relationship, RelationType } from "../types/storage.js";
+
+export type GoPackageRef = {
+  name: string;
+  importPath: string;
+  directory: string;
+};
+
+export type InitCall = {
+  /** Callee as written (`register`, `sql.Register`) */
+  callee: string;
+  /** The called declaration when it is indexed */
+  target: { id: string; name: string; type: string; filePath: string; startLine: number | null } | null;
+  line: number | null;
+};
+
+export type InitVariable = {
+  id: string;
+  name: string;
+  filePath: string;
+  line: number | null;
+  initializer: string;
+  /** Position in the package's variable initialization, from 1 */
+  order: number;
+  /** Variables of the package its initializer uses, directly or through the functions it calls */
+  dependsOn: string[];
+  calls: InitCall[];
+};
+
+export type InitFunction = {
+  id: string;
+  filePath: string;
+  startLine: number | null;
+  endLine: number | null;
+  /** Position among the package's `init` functions, from 1 */
+  order: number;
+  calls: InitCall[];
+};
+
+export type InitImport = {
+  importPath: string;
+  /** Imported only as `import _ "pkg"`, for what initializing it does */
+  sideEffect: boolean;
+  sites: Array<{ filePath: string; line: number | null; blank: boolean }>;
+  /** The imported package when it is indexed, with the size of its own initialization */
+  package: (GoPackageRef & { variables: number; initFunctions: number }) | null;
+};
+
+export type PackageInit = {
+  package: GoPackageRef & { files: string[] };
+  /** Initialized before the package, in import path order */
+  imports: InitImport[];
+  /** Package-level variables with an initializer, in the order Go initializes them */
+  variables: InitVariable[];
+  /** Run after every variable, in file name order and then declaration order */
+  initFunctions: InitFunction[];
+};
+
+export type PackageInitLookup = {
+  result: PackageInit | null;
+  /** Packages matching the query; several when it is ambiguous */
+  candidates: GoPackageRef[];
+};
+
+type LoadedPackage = {
+  container: Entity;
+  files: string[];
+  entities: Entity[];
+  variables: Entity[];
+  initFunctions: Entity[];
+};
+
+// Code-unit order, the order the go command hands a package's files to the compiler in
+const byCodeUnit = (a: string, b: string) => (a < b ? -1 : a > b ? 1 : 0);
+
+function meta(entity: Entity): Record<string, any> {
+  return (entity.metadata ?? {}) as Record<string, any>;
+}
+
+export function refOf(container: Entity): GoPackageRef {
+  return { name: container.name, importPath: String(meta(container).qualifiedName), directory: container.filePath };
+}
+
+function byPosition(a: Entity, b: Entity): number {
+  return (
+    byCodeUnit(a.filePath, b.filePath) ||
+    (a.location?.start?.index ?? 0) - (b.location?.start?.index ?? 0) ||
+    (meta(a).initOrder ?? 0) - (meta(b).initOrder ?? 0)
+  );
+}
+
+async function outgoing(storage: GraphStorageImpl, id: string, type: RelationType): Promise<Relationship[]> {
+  return (await storage.getRelationshipsForEntity(id, type)).filter((rel) => rel.fromId === id);
+}
+
+/**
+ * Go package containers matching `query`: the package of a directory or file path, an import
+ * path, its trailing elements (`store/sql`) or a package name.
+ */
+export async function findPackages(storage: GraphStorageImpl, query: string, path?: string): Promise<Entity[]> {
+  const packages = (await listContainers(storage)).filter((entity) => meta(entity).container === "go_package");
+  if (path) {
+    const dir = extname(path) === ".go" ? dirname(path) : path.replace(/\/+$/, "");
+    const inDir = packages.filter((entity) => entity.filePath === dir);
+    if (inDir.length > 0) return inDir;
+  }
+  const wanted = query.trim().replace(/\/+$/, "");
+  const importPath = (entity: Entity) => String(meta(entity).qualifiedName);
+  for (const match of [
+    (entity: Entity) => importPath(entity) === wanted,
+    (entity: Entity) => importPath(entity).endsWith(`/${wanted}`),
+    (entity: Entity) => entity.name === wanted,
+  ]) {
+    const found = packages.filter(match);
+    if (found.length > 0) return found;
+  }
+  return [];
+}
+
+async function loadPackage(storage: GraphStorageImpl, container: Entity, goFiles: string[]): Promise<LoadedPackage> {
+  const files: string[] = [];
+  const entities: Entity[] = [];
+  // Test files are only compiled into the test binary, which initializes the package differently
+  for (const file of goFiles.filter((path) => dirname(path) === container.filePath && !path.endsWith("_test.go"))) {
+    const declared = await storage.findEntities({ type: "entity", filters: { filePath: file }, limit: 10000 });
+    if (declared.find((entity) => meta(entity).isPackage)?.name !== container.name) continue;
+    files.push(file);
+    entities.push(...declared);
+  }
+  const variables = entities
+    .filter((entity) => String(entity.type) === "variable" && typeof meta(entity).initialValue === "string")
+    .sort(byPosition);
+  const initFunctions = entities
+    .filter((entity) => String(entity.type) === "function" && meta(entity).goInit === true)
+    .sort(byPosition);
+  return { container, files, entities, variables, initFunctions };
+}
+
+async function callsOf(storage: GraphStorageImpl, entity: Entity): Promise<InitCall[]> {
+  const calls: InitCall[] = [];
+  for (const rel of await outgoing(storage, entity.id, RelationType.CALLS)) {
+    const target = await storage.getEntity(rel.toId);
+    const indexed = target && !target.filePath.startsWith("external://") ? target : null;
+    const sites: Array<{ line?: number; callee?: string }> = Array.isArray(rel.metadata?.callSites)
+      ? rel.metadata.callSites
+      : [{ line: rel.metadata?.line }];
+    for (const site of sites) {
+      calls.push({
+        callee: site.callee ?? target?.name ?? rel.toId,
+        target: indexed
+          ? {
+              id: indexed.id,
+              name: indexed.name,
+              type: String(indexed.type),
+              filePath: indexed.filePath,
+              startLine: indexed.location?.start?.line ?? null,
+            }
+          : null,
+        line: typeof site.line === "number" ? site.line : null,
+      });
+    }
+  }
+  return calls.sort((a, b) => (a.line ?? 0) - (b.line ?? 0) || byCodeUnit(a.callee, b.callee));
+}
+
+/**
+ * Variables of the package a variable's initializer reads, directly or in a function or method of
+ * the package it calls, however deep: what Go orders the initialization by.
+ */
+async function dependenciesOf(
+  storage: GraphStorageImpl,
+  variable: Entity,
+  variables: Set<string>,
+  functions: Set<string>,
+): Promise<Set<string>> {
+  const dependencies = new Set<string>();
+  const visited = new Set([variable.id]);
+  const stack = [variable.id];
+  while (stack.length > 0) {
+    const id = stack.pop()!;
+    for (const rel of await outgoing(storage, id, RelationType.ACCESSES)) {
+      if (variables.has(rel.toId) && rel.toId !== variable.id) dependencies.add(rel.toId);
+    }
+    for (const rel of await outgoing(storage, id, RelationType.CALLS)) {
+      if (!functions.has(rel.toId) || visited.has(rel.toId)) continue;
+      visited.add(rel.toId);
+      stack.push(rel.toId);
+    }
+  }
+  return dependencies;
+}
+
+/**
+ * The initialization surface of a Go package: the packages it imports, which Go initializes
+ * first, its package-level variables in initialization order (the earliest declared one whose
+ * dependencies are initialized goes next, files taken in name order), and its `init` functions
+ * in the order they run. Each initializer and `init` lists the calls it makes, and a blank import
+ * is marked as a side-effect dependency with the initialization it pulls in.
+ */
+export async function listPackageInit(
+  storage: GraphStorageImpl,
+  query: string,
+  path?: string,
+): Promise<PackageInitLookup> {
+  const matches = await findPackages(storage, query, path);
+  const candidates = matches.map(refOf).sort((a, b) => byCodeUnit(a.importPath, b.importPath));
+  if (matches.length !== 1) return { result: null, candidates };
+
+  const goFiles = (await storage.listIndexedFiles())
+    .map((info) => info.path)
+    .filter((file) => file.endsWith(".go"))
+    .sort(byCodeUnit);
+  const pkg = await loadPackage(storage, matches[0]!, goFiles);
+
+  const idsOf = (kinds: string[]) =>
+    new Set(pkg.entities.filter((entity) => kinds.includes(String(entity.type))).map((entity) => entity.id));
+  const packageVariables = idsOf(["variable"]);
+  const packageFunctions = idsOf(["function", "method"]);
+  const dependencies = new Map<string, Set<string>>();
+  for (const variable of pkg.variables) {
+    dependencies.set(variable.id, await dependenciesOf(storage, variable, packageVariables, packageFunctions));
+  }
+  const names = new Map(pkg.entities.map((entity) => [entity.id, entity.name]));
+  // Variables without an initializer hold their zero value from the start
+  const initialized = new Set(packageVariables);
+  for (const variable of pkg.variables) initialized.delete(variable.id);
+  const remaining = [...pkg.variables];
+  const variables: InitVariable[] = [];
+  while (remaining.length > 0) {
+    // A cycle would not compile; the edges here can be incomplete, so declaration order breaks it
+    const ready = remaining.findIndex((v) => [...dependencies.get(v.id)!].every((id) => initialized.has(id)));
+    const [variable] = remaining.splice(Math.max(0, ready), 1);
+    initialized.add(variable!.id);
+    variables.push({
+      id: variable!.id,
+      name: variable!.name,
+      filePath: variable!.filePath,
+      line: variable!.location?.start?.line ?? null,
+      initializer: String(meta(variable!).initialValue),
+      order: variables.length + 1,
+      dependsOn: [...dependencies.get(variable!.id)!].map((id) => names.get(id)!).sort(byCodeUnit),
+      calls: await callsOf(storage, variable!),
+    });
+  }
+
+  const initFunctions: InitFunction[] = [];
+  for (const fn of pkg.initFunctions) {
+    initFunctions.push({
+      id: fn.id,
+      filePath: fn.filePath,
+      startLine: fn.location?.start?.line ?? null,
+      endLine: fn.location?.end?.line ?? null,
+      order: initFunctions.length + 1,
+      calls: await callsOf(storage, fn),
+    });
+  }
+
+  const imports = new Map<string, InitImport>();
+  const loaded = new Map<string, LoadedPackage>();
+  for (const clause of pkg.entities.filter((entity) => meta(entity).isPackage)) {
+    for (const rel of await outgoing(storage, clause.id, RelationType.IMPORTS)) {
+      const target = await storage.getEntity(rel.toId);
+      if (!target) continue;
+      const container = meta(target).container === "go_package" ? target : null;
+      const importPath = String(rel.metadata?.importPath ?? (container ? meta(container).qualifiedName : target.name));
+      const blank = rel.metadata?.sideEffect === true || rel.metadata?.alias === "_";
+      let entry = imports.get(importPath);
+      if (!entry) {
+        let imported: InitImport["package"] = null;
+        if (container) {
+          const dependency = loaded.get(container.id) ?? (await loadPackage(storage, container, goFiles));
+          loaded.set(container.id, dependency);
+          imported = {
+            ...refOf(container),
+            variables: dependency.variables.length,
+            initFunctions: dependency.initFunctions.length,
+          };
+        }
+        entry = { importPath, sideEffect: true, sites: [], package: imported };
+        imports.set(importPath, entry);
+      }
+      entry.sideEffect &&= blank;
+      entry.sites.push({ filePath: clause.filePath, line: rel.metadata?.line ?? null, blank });
+    }
+  }
+
+  return {
+    result: {
+      package: { ...refOf(pkg.container), files: pkg.files },
+      imports: [...imports.values()]
+        .map((entry) => ({ ...entry, sites: entry.sites.sort((a, b) => byCodeUnit(a.filePath, b.filePath)) }))
+        .sort((a, b) => byCodeUnit(a.importPath, b.importPath)),
+      variables,
+      initFunctions,
+    },
+    candidates,
+  };
+}
@@ -0,0 +1,156 @@
+import { dirname } from "node:path";
+import { encloses } from "../core/override-resolver.js";
+import { isTestEntity } from "../parsers/test-extractor.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import type { Entity } from "../types/storage.js";
+import { isExternalPlaceholder } from "./find-references.js";
+import { isExported } from "./find-unused.js";
+import { signatureOf } from "./list-members.js";
+import { findPackages, type GoPackageRef, refOf } from "./package-init.js";
+
+export type PublicApiEntity = {
+  id: string;
+  name: string;
+  fqn: string | null;
+  type: string;
+  signature: string | null;
+  /** The exported type it belongs to: an enclosing declaration, or a Go method's receiver */
+  owner: string | null;
+  startLine: number | null;
+  endLine: number | null;
+};
+
+export type PublicApiFile = {
+  filePath: string;
+  entities: PublicApiEntity[];
+};
+
+export type PublicApi = {
+  /** The Go package, when the target named or contained one */
+  package: GoPackageRef | null;
+  /** The file or directory listed otherwise */
+  path: string | null;
+  files: PublicApiFile[];
+  total: number;
+  truncated: boolean;
+};
+
+export type PublicApiLookup = {
+  result: PublicApi | null;
+  /** Go packages matching the target; several when it is ambiguous */
+  candidates: GoPackageRef[];
+};
+
+export type PublicApiOptions = {
+  kinds?: string[];
+  limit?: number;
+};
+
+// Entities that are not declarations anyone could use from outside
+const NON_DECLARATIONS = new Set(["import", "comment", "module", "package", "namespace", "file", "export"]);
+
+function meta(entity: Entity): Record<string, any> {
+  return (entity.metadata as Record<string, any> | undefined) ?? {};
+}
+
+const byCodeUnit = (a: string, b: string) => (a < b ? -1 : a > b ? 1 : 0);
+
+/** `*Store[T]` → `Store` */
+function receiverType(receiver: unknown): string | null {
+  if (typeof receiver !== "string") return null;
+  const name = receiver.replace(/^[\s*&]+/, "").replace(/\[.*$/, "").trim();
+  return name || null;
+}
+
+async function goPackageFiles(storage: GraphStorageImpl, pkg: Entity): Promise<string[]> {
+  const files: string[] = [];
+  const candidates = (await storage.listIndexedFiles())
+    .map((info) => info.path)
+    .filter((file) => file.endsWith(".go") && !file.endsWith("_test.go") && dirname(file) === pkg.filePath)
+    .sort(byCodeUnit);
+  for (const file of candidates) {
+    const declared = await storage.findEntities({ type: "entity", filters: { filePath: file }, limit: 10000 });
+    // A directory can also hold `package main` tools or a `_test` package
+    if (declared.find((entity) => meta(entity).isPackage)?.name === pkg.name) files.push(file);
+  }
+  return files;
+}
+
+/**
+ * The exported surface of a file, a directory or a Go package: every declaration other packages
+ * or modules can use, by the visibility recorded at index time. A member only counts when the
+ * type holding it is exported too, since nothing outside can name it otherwise; test files are
+ * left out.
+ */
+export async function listPublicApi(
+  storage: GraphStorageImpl,
+  target: string,
+  path?: string,
+  options: PublicApiOptions = {},
+): Promise<PublicApiLookup> {
+  const limit = Math.max(1, Math.min(5000, Number(options.limit ?? 500) || 500));
+  const kinds = options.kinds?.length ? new Set(options.kinds) : null;
+
+  const matches = await findPackages(storage, target, path);
+  const candidates = matches.map(refOf).sort((a, b) => byCodeUnit(a.importPath, b.importPath));
+  if (matches.length > 1) return { result: null, candidates };
+
+  let files: string[];
+  let listedPath: string | null = null;
+  if (matches.length === 1) {
+    files = await goPackageFiles(storage, matches[0]!);
+  } else {
+    const prefix = (path ?? target).replace(/[/\\]+$/, "");
+    files = (await storage.listIndexedFiles())
+      .map((info) => info.path)
+      .filter((file) => file === prefix || file.startsWith(`${prefix}/`))
+      .sort(byCodeUnit);
+    listedPath = prefix;
+  }
+  if (files.length === 0) return { result: null, candidates };
+
+  const listed: PublicApiFile[] = [];
+  let total = 0;
+  for (const filePath of files) {
+    const declarations = (await storage.findEntities({ type: "entity", filters: { filePath }, limit: 10000 }))
+      .filter((e) => !NON_DECLARATIONS.has(String(e.type)) && !isExternalPlaceholder(e) && !isTestEntity(e))
+      .sort((a, b) => (a.location?.start?.index ?? 0) - (b.location?.start?.index ?? 0));
+
+    const entities: PublicApiEntity[] = [];
+    for (const entity of declarations) {
+      if (!isExported(entity) || meta(entity).anonymous === true) continue;
+      if (kinds && !kinds.has(String(entity.type))) continue;
+      const enclosing = declarations.filter((outer) => encloses(outer, entity) && !encloses(entity, outer));
+      if (enclosing.some((outer) => !isExported(outer))) continue;
+      // Go methods sit outside their type; an unexported receiver hides them like an enclosing type
+      const receiver = receiverType(meta(entity).receiver);
+      if (receiver && entity.filePath.endsWith(".go") && !/^\p{Lu}/u.test(receiver)) continue;
+
+      total += 1;
+      if (total > limit) continue;
+      const innermost = enclosing[enclosing.length - 1];
+      entities.push({
+        id: entity.id,
+        name: entity.name,
+        fqn: typeof meta(entity).fqn === "string" ? meta(entity).fqn : null,
+        type: String(entity.type),
+        signature: signatureOf(entity) ?? null,
+        owner: innermost?.name ?? receiver,
+        startLine: entity.location?.start?.line ?? null,
+        endLine: entity.location?.end?.line ?? null,
+      });
+    }
+    if (entities.length > 0) listed.push({ filePath, entities });
+  }
+
+  return {
+    result: {
+      package: matches.length === 1 ? refOf(matches[0]!) : null,
+      path: listedPath,
+      files: listed,
+      total,
+      truncated: total > limit,
+    },
+    candidates,
+  };
+}
@@ -0,0 +1,60 @@
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import type { Entity, SnapshotEntity } from "../types/storage.js";
+import { changesBetween, type EntityChangeKind, toSnapshotEntity } from "./diff-graph.js";
+
+export type ReindexedEntity = Pick<SnapshotEntity, "id" | "name" | "type" | "startLine">;
+
+export type FileEntityChanges = {
+  added: ReindexedEntity[];
+  removed: ReindexedEntity[];
+  modified: Array<ReindexedEntity & { changes: EntityChangeKind[] }>;
+  /** Entities whose signature and body are as before, including ones that only moved */
+  unchanged: number;
+};
+
+const PAGE_SIZE = 1000;
+
+/** Every entity stored for `filePath`, however many there are */
+export async function loadFileEntities(storage: GraphStorageImpl, filePath: string): Promise<Entity[]> {
+  const entities: Entity[] = [];
+  let afterId: string | undefined;
+  while (true) {
+    const page = await storage.findEntities({ type: "entity", filters: { filePath }, afterId, limit: PAGE_SIZE });
+    entities.push(...page);
+    if (page.length < PAGE_SIZE) return entities;
+    afterId = page[page.length - 1]!.id;
+  }
+}
+
+const summarize = ({ id, name, type, startLine }: SnapshotEntity): ReindexedEntity => ({ id, name, type, startLine });
+
+const byLine = (a: ReindexedEntity, b: ReindexedEntity) => (a.startLine ?? 0) - (b.startLine ?? 0);
+
+/**
+ * Compare one file's entities before and after it was re-parsed. Entities are matched by stable id
+ * and compared the way diff_graph compares them: by signature and body hash.
+ */
+export function diffFileEntities(before: readonly Entity[], after: readonly Entity[]): FileEntityChanges {
+  const previous = new Map(before.map((entity) => [entity.id, toSnapshotEntity(entity)]));
+  const current = new Map(after.map((entity) => [entity.id, toSnapshotEntity(entity)]));
+  const changes: FileEntityChanges = { added: [], removed: [], modified: [], unchanged: 0 };
+
+  for (const entity of current.values()) {
+    const old = previous.get(entity.id);
+    if (!old) {
+      changes.added.push(summarize(entity));
+      continue;
+    }
+    const kinds = changesBetween(old, entity);
+    if (kinds.length > 0) changes.modified.push({ ...summarize(entity), changes: kinds });
+    else changes.unchanged += 1;
+  }
+  for (const entity of previous.values()) {
+    if (!current.has(entity.id)) changes.removed.push(summarize(entity));
+  }
+
+  changes.added.sort(byLine);
+  changes.removed.sort(byLine);
+  changes.modified.sort(byLine);
+  return changes;
+}
@@ -1,6 +1,7 @@
 import { dirname } from "node:path";
 import type { GraphStorageImpl } from "../storage/graph-storage.js";
 import type { Entity, EntityType } from "../types/storage.js";
+import { splitQualifiedSymbol } from "./find-references.js";
 
 export type ResolveEntityCandidate = { entity: Entity; score: number; reasons: string[] };
 
@@ -12,7 +13,10 @@ export async function resolveEntityCandidates(options: {
   limit: number;
 }): Promise<ResolveEntityCandidate[]> {
   const { storage, name, filePathHint, entityTypes, limit } = options;
-  const exactName = name.trim();
+  // A fully qualified name (`src/users#GetUser`, `example.com/shop/users.GetUser`) is looked up by
+  // its last part, and the declaration it names ranks first
+  const fqn = /[#/]/.test(name) ? name.trim() : null;
+  const exactName = fqn ? splitQualifiedSymbol(fqn).name : name.trim();
   const candidates: Entity[] = [];
 
   const exactQuery = await storage.executeQuery({
@@ -45,6 +49,10 @@ export async function resolveEntityCandidates(options: {
       const reasons: string[] = [];
       let score = 0;
 
+      if (fqn && e.metadata?.fqn === fqn) {
+        score += 200;
+        reasons.push("exact_fqn");
+      }
       if (e.name.toLowerCase() === exactName.toLowerCase()) {
         score += 100;
         reasons.push("exact_name");
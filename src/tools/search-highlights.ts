@@ -0,0 +1,98 @@
+/**
+ * Where a search hit matched, so clients can highlight it. Query words are found in the hit's
+ * snippet the way the keyword index matches them, as a prefix of a word, and also at the start of
+ * a camelCase part (`config` in `parseConfig`). A hit found through a window vector also gets the
+ * line range of that window, the part of the declaration most similar to the query.
+ */
+
+export type SearchHighlight =
+  | {
+      kind: "term";
+      /** Query word matched */
+      term: string;
+      /** Character offsets into `snippet`, end exclusive */
+      start: number;
+      end: number;
+      /** Line in the file */
+      line: number;
+    }
+  | { kind: "chunk"; startLine: number; endLine: number };
+
+type HighlightedHit = {
+  snippet?: string | null;
+  startLine?: number | null;
+  metadata?: Record<string, unknown> | null;
+};
+
+// Past this a snippet is mostly highlighted and the spans stop helping
+const MAX_TERM_SPANS = 100;
+
+/** Lower-cased query words, longest first so `parser` wins over `parse` at the same place */
+export function highlightTerms(query: string): string[] {
+  const words = query.match(/[\p{L}\p{N}]+/gu) ?? [];
+  return Array.from(new Set(words.map((w) => w.toLowerCase()))).sort((a, b) => b.length - a.length);
+}
+
+const isLower = (c: string) => c !== c.toUpperCase();
+const isUpper = (c: string) => c !== c.toLowerCase();
+const isDigit = (c: string) => /\p{N}/u.test(c);
+
+/** Offsets in `word` where a camelCase or letter/digit part starts, 0 included */
+function partStarts(word: string): number[] {
+  const starts = [0];
+  for (let i = 1; i < word.length; i++) {
+    const [prev, cur, next] = [word[i - 1]!, word[i]!, word[i + 1] ?? ""];
+    const camel = isUpper(cur) && (isLower(prev) || (isUpper(prev) && isLower(next)));
+    if (camel || isDigit(cur) !== isDigit(prev)) starts.push(i);
+  }
+  return starts;
+}
+
+/** Spans of `terms` in `text`, whose first line is `firstLine` of its file */
+export function termSpans(text: string, terms: string[], firstLine: number): SearchHighlight[] {
+  if (terms.length === 0) return [];
+  const lineStarts = [0];
+  for (let i = text.indexOf("\n"); i !== -1; i = text.indexOf("\n", i + 1)) lineStarts.push(i + 1);
+  const lineOf = (offset: number) => {
+    let line = 0;
+    while (line + 1 < lineStarts.length && lineStarts[line + 1]! <= offset) line++;
+    return firstLine + line;
+  };
+
+  const spans: SearchHighlight[] = [];
+  for (const match of text.matchAll(/[\p{L}\p{N}]+/gu)) {
+    const word = match[0];
+    const offset = match.index ?? 0;
+    let covered = 0;
+    for (const start of partStarts(word)) {
+      if (start < covered) continue;
+      const rest = word.slice(start).toLowerCase();
+      const term = terms.find((t) => rest.startsWith(t));
+      if (!term) continue;
+      const at = offset + start;
+      spans.push({ kind: "term", term, start: at, end: at + term.length, line: lineOf(offset) });
+      covered = start + term.length;
+      if (spans.length >= MAX_TERM_SPANS) return spans;
+    }
+  }
+  return spans;
+}
+
+/** Add `highlights` to hits that already carry their snippet */
+export function attachSearchHighlights<T extends HighlightedHit>(
+  hits: T[],
+  query: string,
+): Array<T & { highlights: SearchHighlight[] }> {
+  const terms = highlightTerms(query);
+  return hits.map((hit) => {
+    const highlights: SearchHighlight[] = [];
+    const chunk = hit.metadata?.matchedChunk as { startLine?: unknown; endLine?: unknown } | undefined;
+    if (typeof chunk?.startLine === "number" && typeof chunk.endLine === "number") {
+      highlights.push({ kind: "chunk", startLine: chunk.startLine, endLine: chunk.endLine });
+    }
+    if (hit.snippet && typeof hit.startLine === "number") {
+      highlights.push(...termSpans(hit.snippet, terms, hit.startLine));
+    }
+    return { ...hit, highlights };
+  });
+}
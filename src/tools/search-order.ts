@@ -0,0 +1,54 @@
+/**
+ * Search Order - the keys semantic_search sorts and pages its matches by
+ * `score` is the ranking itself; `recency` (the newest commit among the entity's lines, from git
+ * blame), `complexity` (cyclomatic) and `path` reorder the matches found. Every order breaks ties
+ * by entity id, so equal keys come back in one order across calls and pages never overlap.
+ */
+
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import type { ScoreCursor } from "../utils/cursor.js";
+import { resolveHitEntity, type SearchHit } from "./search-snippets.js";
+
+export const SEARCH_SORT_ORDERS = ["score", "recency", "complexity", "path"] as const;
+export type SearchSortBy = (typeof SEARCH_SORT_ORDERS)[number];
+
+export interface SearchSortKeys<T> {
+  keyOf: (hit: T) => ScoreCursor;
+  /** Hits without the sorted value (no blame metadata, no measured complexity, no entity); they sort last */
+  missing: number;
+}
+
+/**
+ * Sort keys of `hits` under `sortBy`, as page cursors: the sorted value (highest first) and the
+ * hit's entity id. Paths sort ascending, so they go into the id part and every hit scores alike.
+ */
+export async function searchSortKeys<T extends SearchHit>(
+  storage: GraphStorageImpl,
+  hits: T[],
+  sortBy: SearchSortBy,
+  scoreKey: (hit: T) => ScoreCursor,
+): Promise<SearchSortKeys<T>> {
+  if (sortBy === "score") return { keyOf: scoreKey, missing: 0 };
+
+  const keys = new Map<T, ScoreCursor>();
+  let missing = 0;
+  for (const hit of hits) {
+    const { id } = scoreKey(hit);
+    const entity = await resolveHitEntity(storage, hit);
+    if (sortBy === "path") {
+      const stored = hit.metadata?.path;
+      const path = entity?.filePath ?? (typeof stored === "string" ? stored : undefined);
+      if (path === undefined) missing += 1;
+      // U+FFFF sorts after any path, so hits without one come last
+      keys.set(hit, { s: 0, id: `${path ?? "\uffff"}\u0000${id}` });
+      continue;
+    }
+
+    const meta = (entity?.metadata ?? {}) as Record<string, unknown>;
+    const time = (meta.lastModified as { time?: unknown } | undefined)?.time;
+    const value = sortBy === "recency" ? time : meta.cyclomaticComplexity;
+    if (typeof value !== "number") missing += 1;
+    keys.set(hit, { s: typeof value === "number" ? value : -1, id });
+  }
+  return { keyOf: (hit) => keys.get(hit) ?? scoreKey(hit), missing };
+}
@@ -0,0 +1,122 @@
+import { readFile } from "node:fs/promises";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import type { Entity } from "../types/storage.js";
+import { extractSourceSnippetFromText } from "../utils/source-snippet.js";
+
+/** `full` returns declaration and body, `signature` only the declaration lines */
+export type SnippetMode = "full" | "signature" | "none";
+
+export type SearchSnippetFields = {
+  filePath: string | null;
+  startLine: number | null;
+  endLine: number | null;
+  snippet: string | null;
+  snippetTruncated: boolean;
+};
+
+export type SearchHit = { entityId?: string | null; metadata?: Record<string, unknown> | null };
+
+export type SearchSnippetOptions = {
+  mode: SnippetMode;
+  maxLines: number;
+  maxBytes?: number;
+  /** Maps a stored path to one readable from this process */
+  resolvePath?: (filePath: string) => string;
+};
+
+// A declaration longer than this is a parameter list, not a signature worth showing whole
+const MAX_SIGNATURE_LINES = 12;
+
+/** Lines from the declaration start up to the one that opens the body (`{`, `:` or `=>`) */
+function signatureLines(text: string, startLine: number, endLine: number): number {
+  const lines = text.split(/\r?\n/);
+  const last = Math.min(endLine, startLine + MAX_SIGNATURE_LINES - 1, lines.length);
+  for (let line = startLine; line <= last; line++) {
+    const trimmed = (lines[line - 1] ?? "").replace(/\s(?:\/\/|#).*$/, "").trimEnd();
+    if (/[{:]$|=>$/.test(trimmed) || trimmed.endsWith(";")) return line - startLine + 1;
+  }
+  return last - startLine + 1;
+}
+
+/** The graph entity a hit stands for, by its entity id or, for older vectors, by path and name */
+export async function resolveHitEntity(storage: GraphStorageImpl, hit: SearchHit): Promise<Entity | null> {
+  const meta = hit.metadata ?? {};
+  const entityId = hit.entityId ?? (typeof meta.entityId === "string" ? meta.entityId : null);
+  if (entityId) {
+    const entity = await storage.getEntity(entityId);
+    if (entity) return entity;
+  }
+  // Embeddings stored before entity ids were attached only carry the path and name
+  if (typeof meta.path === "string" && typeof meta.name === "string") {
+    const found = await storage.findEntities({
+      type: "entity",
+      filters: { filePath: meta.path, name: meta.name },
+      limit: 10,
+    });
+    return found.find((e) => !meta.type || String(e.type) === meta.type) ?? found[0] ?? null;
+  }
+  return null;
+}
+
+/**
+ * Attach the source of each hit's entity with its line range. Files are read once per call;
+ * hits that cannot be bound to an entity, or whose file is gone, keep a null snippet.
+ */
+export async function attachSearchSnippets<T extends SearchHit>(
+  storage: GraphStorageImpl,
+  hits: T[],
+  options: SearchSnippetOptions,
+): Promise<Array<T & SearchSnippetFields>> {
+  const texts = new Map<string, Promise<string | null>>();
+  const readText = (path: string) => {
+    let text = texts.get(path);
+    if (!text) {
+      text = readFile(path, "utf8").catch(() => null);
+      texts.set(path, text);
+    }
+    return text;
+  };
+
+  const out: Array<T & SearchSnippetFields> = [];
+  for (const hit of hits) {
+    const entity = await resolveHitEntity(storage, hit);
+    if (!entity) {
+      const path = hit.metadata?.path;
+      out.push({
+        ...hit,
+        filePath: typeof path === "string" ? (options.resolvePath?.(path) ?? path) : null,
+        startLine: null,
+        endLine: null,
+        snippet: null,
+        snippetTruncated: false,
+      });
+      continue;
+    }
+
+    const filePath = options.resolvePath?.(entity.filePath) ?? entity.filePath;
+    const startLine = entity.location?.start?.line ?? null;
+    const endLine = entity.location?.end?.line ?? startLine;
+    const fields: SearchSnippetFields = { filePath, startLine, endLine, snippet: null, snippetTruncated: false };
+
+    const text = options.mode === "none" || startLine === null ? null : await readText(filePath);
+    if (text !== null && startLine !== null) {
+      // Signature mode narrows the range itself, so dropping the body is not reported as truncation
+      const lastLine =
+        options.mode === "signature"
+          ? startLine + signatureLines(text, startLine, endLine ?? startLine) - 1
+          : (endLine ?? startLine);
+      const extracted = extractSourceSnippetFromText({
+        text,
+        startLine,
+        endLine: lastLine,
+        contextLines: 0,
+        maxBytes: options.maxBytes,
+        maxLines: options.maxLines,
+      });
+      fields.snippet = extracted.snippet;
+      fields.snippetTruncated = extracted.truncated;
+    }
+    out.push({ ...hit, ...fields });
+  }
+  return out;
+}
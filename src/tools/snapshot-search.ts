@@ -0,0 +1,154 @@
+/**
+ * Search over a stored graph snapshot instead of the live index, for semantic_search and query
+ * with `snapshot`. A snapshot keeps names, kinds, locations and signatures, plus the entity search
+ * vectors when the semantic agent was available as it was taken. Keyword matching runs over names
+ * and signatures, semantic ranking is an exact scan of the kept vectors (snapshots have no ANN
+ * index), and hybrid fuses the two by reciprocal rank as the live search does.
+ */
+
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { matchesSearchScope } from "../storage/search-filters.js";
+import type { SearchScope, SnapshotEntity } from "../types/storage.js";
+import type { SearchMode } from "./keyword-search.js";
+import { highlightTerms, termSpans } from "./search-highlights.js";
+
+export type SnapshotSearchHit = {
+  entityId: string;
+  name: string;
+  type: string;
+  filePath: string;
+  startLine: number | null;
+  endLine: number | null;
+  signature: string | null;
+  score: number;
+  /** Cosine similarity to the query; null for hits only keyword matching found */
+  cosine: number | null;
+  matchedBy: Array<"semantic" | "keyword">;
+};
+
+export type SnapshotSearchOptions = {
+  query: string;
+  mode: SearchMode;
+  /** The query embedded by the embedder that made the snapshot's vectors; unused in keyword mode */
+  queryVector?: Float32Array | null;
+  limit: number;
+  scope?: SearchScope;
+  /** Vector matches below this cosine are dropped, as in semantic_search */
+  minScore?: number;
+};
+
+export type SnapshotSearchResult = {
+  hits: SnapshotSearchHit[];
+  belowThreshold: number;
+  bestBelowThreshold: number | null;
+};
+
+// Same damping as the live hybrid search, so scores read alike
+const RRF_K = 60;
+
+function cosineSimilarity(a: Float32Array, b: Float32Array): number {
+  if (a.length !== b.length) return Number.NaN;
+  let dot = 0;
+  let normA = 0;
+  let normB = 0;
+  for (let i = 0; i < a.length; i++) {
+    dot += a[i]! * b[i]!;
+    normA += a[i]! * a[i]!;
+    normB += b[i]! * b[i]!;
+  }
+  return normA === 0 || normB === 0 ? 0 : dot / Math.sqrt(normA * normB);
+}
+
+/** Entities matching query words in their name or signature; exact names first, then most words */
+function keywordMatches(entities: SnapshotEntity[], query: string): Array<{ entity: SnapshotEntity; exact: boolean }> {
+  const terms = highlightTerms(query);
+  if (terms.length === 0) return [];
+  const lowered = query.trim().toLowerCase();
+  const matches: Array<{ entity: SnapshotEntity; exact: boolean; words: number }> = [];
+  for (const entity of entities) {
+    const spans = termSpans(`${entity.name}\n${entity.signature ?? ""}`, terms, 1);
+    const words = new Set(spans.map((span) => (span.kind === "term" ? span.term : ""))).size;
+    if (words > 0) matches.push({ entity, exact: entity.name.toLowerCase() === lowered, words });
+  }
+  return matches.sort(
+    (a, b) =>
+      Number(b.exact) - Number(a.exact) ||
+      b.words - a.words ||
+      a.entity.name.length - b.entity.name.length ||
+      (a.entity.id < b.entity.id ? -1 : a.entity.id > b.entity.id ? 1 : 0),
+  );
+}
+
+/**
+ * Entities of snapshot `label` ranked against the query, best first, at most `limit`. Ties go by
+ * entity id, so repeated calls page the same order.
+ */
+export async function searchSnapshot(
+  storage: GraphStorageImpl,
+  label: string,
+  options: SnapshotSearchOptions,
+): Promise<SnapshotSearchResult> {
+  const minScore = options.minScore ?? -1;
+  const entities = (await storage.getSnapshotEntities(label)).filter(
+    (entity) => !entity.filePath.startsWith("external://") && matchesSearchScope(entity, options.scope),
+  );
+  const byId = new Map(entities.map((entity) => [entity.id, entity]));
+
+  const semantic: Array<{ entity: SnapshotEntity; cosine: number }> = [];
+  let belowThreshold = 0;
+  let bestBelowThreshold: number | null = null;
+  if (options.mode !== "keyword" && options.queryVector) {
+    for await (const { entityId, vector } of storage.iterateSnapshotVectors(label)) {
+      const entity = byId.get(entityId);
+      if (!entity) continue;
+      const cosine = cosineSimilarity(options.queryVector, vector);
+      if (Number.isNaN(cosine)) continue;
+      if (cosine < minScore) {
+        belowThreshold++;
+        bestBelowThreshold = Math.max(bestBelowThreshold ?? -1, cosine);
+        continue;
+      }
+      semantic.push({ entity, cosine });
+    }
+    semantic.sort((a, b) => b.cosine - a.cosine || (a.entity.id < b.entity.id ? -1 : 1));
+  }
+  const keyword = options.mode === "semantic" ? [] : keywordMatches(entities, options.query);
+
+  const fused = new Map<string, SnapshotSearchHit>();
+  const hitFor = (entity: SnapshotEntity) => {
+    let hit = fused.get(entity.id);
+    if (!hit) {
+      hit = {
+        entityId: entity.id,
+        name: entity.name,
+        type: entity.type,
+        filePath: entity.filePath,
+        startLine: entity.startLine,
+        endLine: entity.endLine,
+        signature: entity.signature,
+        score: 0,
+        cosine: null,
+        matchedBy: [],
+      };
+      fused.set(entity.id, hit);
+    }
+    return hit;
+  };
+  semantic.forEach(({ entity, cosine }, rank) => {
+    const hit = hitFor(entity);
+    hit.score += 1 / (RRF_K + rank + 1);
+    hit.cosine = cosine;
+    hit.matchedBy.push("semantic");
+  });
+  keyword.forEach(({ entity, exact }, rank) => {
+    const hit = hitFor(entity);
+    // An exact name also counts as a first-place semantic vote, as in the live hybrid search
+    hit.score += 1 / (RRF_K + rank + 1) + (exact ? 1 / (RRF_K + 1) : 0);
+    hit.matchedBy.push("keyword");
+  });
+
+  const hits = [...fused.values()]
+    .sort((a, b) => b.score - a.score || (a.entityId < b.entityId ? -1 : a.entityId > b.entityId ? 1 : 0))
+    .slice(0, Math.max(0, options.limit));
+  return { hits, belowThreshold, bestBelowThreshold };
+}
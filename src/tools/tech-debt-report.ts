@@ -0,0 +1,142 @@
+import { dirname, relative } from "node:path";
+import type { CommentMarkerKind } from "../parsers/comment-extractor.js";
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import { type BlameLine, blameFiles, type LastModified } from "../utils/git-blame.js";
+import { listTodos, type TodoItem } from "./list-todos.js";
+
+export type DebtItem = TodoItem & {
+  /** Commit that last wrote the marker's line; null when git cannot blame it */
+  introduced: { commit: string; author: string; email: string; date: string } | null;
+  ageDays: number | null;
+};
+
+export type DebtGroup = {
+  /** Author name, or directory relative to the report root ("." for the root itself) */
+  key: string;
+  email?: string;
+  markers: number;
+  /** Markers in the group git could date */
+  dated: number;
+  oldestDays: number | null;
+  averageAgeDays: number | null;
+};
+
+export type TechDebtReport = {
+  /** Oldest first; markers git cannot date come last, in file and line order */
+  items: DebtItem[];
+  byAuthor: DebtGroup[];
+  byDirectory: DebtGroup[];
+  total: number;
+  dated: number;
+  truncated: boolean;
+};
+
+export type TechDebtOptions = {
+  pathPrefix?: string;
+  kinds?: CommentMarkerKind[];
+  /** Directories are grouped relative to this */
+  root?: string;
+  limit?: number;
+  now?: Date;
+  /** Line blame per file; `git blame` unless a test swaps it */
+  blame?: (files: string[]) => Promise<Map<string, Array<BlameLine | null>>>;
+};
+
+// Markers dated and grouped, whatever the page size
+const MAX_MARKERS = 5000;
+
+const DAY_SECONDS = 86_400;
+
+type Dated = Pick<BlameLine, "commit" | "author" | "email" | "time">;
+
+function addTo(groups: Map<string, DebtItem[]>, key: string, item: DebtItem): void {
+  const items = groups.get(key);
+  if (items) items.push(item);
+  else groups.set(key, [item]);
+}
+
+function summarize(key: string, items: DebtItem[], email?: string): DebtGroup {
+  const ages = items.map((item) => item.ageDays).filter((age): age is number => age !== null);
+  return {
+    key,
+    ...(email ? { email } : {}),
+    markers: items.length,
+    dated: ages.length,
+    oldestDays: ages.length ? Math.max(...ages) : null,
+    averageAgeDays: ages.length ? Math.round(ages.reduce((sum, age) => sum + age, 0) / ages.length) : null,
+  };
+}
+
+function byOldest(a: DebtGroup, b: DebtGroup): number {
+  return (b.oldestDays ?? -1) - (a.oldestDays ?? -1) || b.markers - a.markers || a.key.localeCompare(b.key);
+}
+
+/**
+ * TODO/FIXME/HACK/XXX markers dated by `git blame` of their line: who last wrote each one and how
+ * many days ago, oldest first, with totals per author and per directory. A line rewritten since
+ * the marker was added counts from the rewrite. Files git cannot blame fall back to the commit
+ * recorded on the comment at index time (`gitBlame: true`), else stay undated.
+ */
+export async function techDebtReport(
+  storage: GraphStorageImpl,
+  options: TechDebtOptions = {},
+): Promise<TechDebtReport> {
+  const limit = Math.max(1, Math.min(MAX_MARKERS, Number(options.limit ?? 200) || 200));
+  const now = (options.now ?? new Date()).getTime() / 1000;
+  const { todos, total } = await listTodos(storage, {
+    pathPrefix: options.pathPrefix,
+    kinds: options.kinds,
+    limit: MAX_MARKERS,
+  });
+
+  const blames = await (options.blame ?? blameFiles)([...new Set(todos.map((todo) => todo.filePath))]);
+  const stored = new Map<string, LastModified | null>();
+  const items: DebtItem[] = [];
+  for (const todo of todos) {
+    let line: Dated | null | undefined = blames.get(todo.filePath)?.[todo.line - 1];
+    if (!blames.has(todo.filePath)) {
+      if (!stored.has(todo.commentId)) {
+        const comment = await storage.getEntity(todo.commentId);
+        stored.set(todo.commentId, (comment?.metadata?.lastModified as LastModified | undefined) ?? null);
+      }
+      line = stored.get(todo.commentId);
+    }
+    if (!line || typeof line.time !== "number") {
+      items.push({ ...todo, introduced: null, ageDays: null });
+      continue;
+    }
+    items.push({
+      ...todo,
+      introduced: {
+        commit: line.commit,
+        author: line.author,
+        email: line.email,
+        date: new Date(line.time * 1000).toISOString(),
+      },
+      ageDays: Math.max(0, Math.floor((now - line.time) / DAY_SECONDS)),
+    });
+  }
+
+  items.sort(
+    (a, b) => (b.ageDays ?? -1) - (a.ageDays ?? -1) || a.filePath.localeCompare(b.filePath) || a.line - b.line,
+  );
+
+  const authors = new Map<string, DebtItem[]>();
+  const directories = new Map<string, DebtItem[]>();
+  for (const item of items) {
+    if (item.introduced) addTo(authors, item.introduced.email || item.introduced.author, item);
+    const directory = options.root ? relative(options.root, dirname(item.filePath)) || "." : dirname(item.filePath);
+    addTo(directories, directory, item);
+  }
+
+  return {
+    items: items.slice(0, limit),
+    byAuthor: [...authors.values()]
+      .map((authored) => summarize(authored[0]!.introduced!.author, authored, authored[0]!.introduced!.email))
+      .sort(byOldest),
+    byDirectory: [...directories].map(([directory, grouped]) => summarize(directory, grouped)).sort(byOldest),
+    total,
+    dated: items.filter((item) => item.introduced).length,
+    truncated: total > limit,
+  };
+}
@@ -0,0 +1,89 @@
+import type { GraphStorageImpl } from "../storage/graph-storage.js";
+import type { SchemaStatus } from "../storage/schema-migrations.js";
+import type { DanglingRelationship } from "../types/storage.js";
+
+export type VectorEntityRef = { id: string; entityId: string };
+
+export type VerifyIndexOptions = {
+  schema: SchemaStatus;
+  /** Access to the vector store; omitted when the semantic agent is not running */
+  vectors?: {
+    list: () => Promise<VectorEntityRef[]>;
+    remove: (ids: string[]) => Promise<number>;
+  };
+  /** Remove dangling relationships and orphaned vectors instead of only reporting them */
+  repair?: boolean;
+  /** Problem rows listed per check (default 20) */
+  sampleLimit?: number;
+};
+
+export type VerifyIndexReport = {
+  /** True when nothing is left to fix: every check passed, or repair removed what failed */
+  ok: boolean;
+  repair: boolean;
+  relationships: { dangling: number; removed: number; sample: DanglingRelationship[] };
+  /** Null when the vector store could not be checked */
+  vectors: { checked: number; orphaned: number; removed: number; sample: VectorEntityRef[] } | null;
+  schema: SchemaStatus & { ok: boolean };
+  /** One line per failed check, as found before any repair */
+  problems: string[];
+};
+
+const ENTITY_PAGE = 900;
+
+async function orphanedVectors(storage: GraphStorageImpl, refs: VectorEntityRef[]): Promise<VectorEntityRef[]> {
+  const orphaned: VectorEntityRef[] = [];
+  for (let i = 0; i < refs.length; i += ENTITY_PAGE) {
+    const page = refs.slice(i, i + ENTITY_PAGE);
+    const live = await storage.filterExistingEntityIds([...new Set(page.map((ref) => ref.entityId))]);
+    orphaned.push(...page.filter((ref) => !live.has(ref.entityId)));
+  }
+  return orphaned;
+}
+
+/**
+ * Referential checks over the index: every relationship's endpoints are stored entities, every
+ * vector made for an entity still has it, and the database schema is the one this release writes.
+ * With `repair`, dangling relationships and orphaned vectors are deleted; a schema problem is only
+ * reported, since migrations run on start and an index from a newer release must not be rewritten.
+ */
+export async function verifyIndex(storage: GraphStorageImpl, options: VerifyIndexOptions): Promise<VerifyIndexReport> {
+  const repair = options.repair === true;
+  const sampleLimit = Math.max(1, Math.min(1000, Number(options.sampleLimit ?? 20) || 20));
+  const problems: string[] = [];
+
+  const dangling = await storage.findDanglingRelationships(sampleLimit);
+  if (dangling.total > 0) problems.push(`${dangling.total} relationships point at entities that are not stored`);
+  const relationshipsRemoved = repair && dangling.total > 0 ? await storage.deleteDanglingRelationships() : 0;
+
+  let vectors: VerifyIndexReport["vectors"] = null;
+  if (options.vectors) {
+    const refs = await options.vectors.list();
+    const orphaned = await orphanedVectors(storage, refs);
+    if (orphaned.length > 0) problems.push(`${orphaned.length} vectors belong to entities that are not stored`);
+    const removed = repair && orphaned.length > 0 ? await options.vectors.remove(orphaned.map((ref) => ref.id)) : 0;
+    vectors = { checked: refs.length, orphaned: orphaned.length, removed, sample: orphaned.slice(0, sampleLimit) };
+  }
+
+  const { schema } = options;
+  const schemaOk = schema.version === schema.latestVersion && schema.pending.length === 0;
+  if (schema.version > schema.latestVersion) {
+    problems.push(`schema version ${schema.version} is newer than the ${schema.latestVersion} this server supports`);
+  } else if (!schemaOk) {
+    problems.push(`schema version ${schema.version}, expected ${schema.latestVersion}`);
+  }
+
+  const ok =
+    schemaOk &&
+    dangling.total === relationshipsRemoved &&
+    (vectors === null || vectors.orphaned === vectors.removed);
+
+  return {
+    ok,
+    repair,
+    relationships: { dangling: dangling.total, removed: relationshipsRemoved, sample: dangling.relationships },
+    vectors,
+    schema: { ...schema, ok: schemaOk },
+    problems,
+  };
+}
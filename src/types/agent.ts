@@ -47,6 +47,8 @@ export interface AgentTask {
   completedAt?: number;
   error?: Error;
   result?: unknown;
+  /** Aborted when the task is cancelled; long-running handlers check it between batches */
+  signal?: AbortSignal;
 }
 
 export interface Agent {
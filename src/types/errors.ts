@@ -21,3 +21,144 @@ export class AgentBusyError extends Error {
     this.details = details;
   }
 }
+
+/**
+ * Stage an embedding provider failed in: fetching the model or reaching its endpoint (`download`),
+ * loading it into memory (`load`), or running the first inference (`runtime`)
+ */
+export type EmbeddingInitPhase = "download" | "load" | "runtime";
+
+export interface EmbeddingInitDetails {
+  provider: string;
+  model: string;
+  phase: EmbeddingInitPhase;
+  attempts: number;
+  /** False when retrying could not help (missing package, rejected credentials) */
+  retriable: boolean;
+  cause: string;
+}
+
+export class EmbeddingInitError extends Error {
+  public readonly details: EmbeddingInitDetails;
+
+  constructor(details: EmbeddingInitDetails) {
+    super(
+      `Embedding provider ${details.provider} (${details.model}) failed to initialize during ${details.phase} ` +
+        `after ${details.attempts} attempt(s): ${details.cause}`,
+    );
+    this.name = "EmbeddingInitError";
+    this.details = details;
+  }
+}
+
+export interface EmbeddingDimensionDetails {
+  /** Dimension of the vectors in the store */
+  storedDimension: number;
+  /** Dimension the active provider produces */
+  activeDimension: number;
+  provider?: string;
+  model?: string;
+  /** The configured provider is down and its hashing fallback is answering */
+  fallback?: boolean;
+}
+
+export class EmbeddingDimensionError extends Error {
+  public readonly details: EmbeddingDimensionDetails;
+
+  constructor(details: EmbeddingDimensionDetails) {
+    const by = details.provider ? ` (${details.provider}/${details.model})` : "";
+    super(
+      `Reindex required: embedding dimension changed from ${details.storedDimension} to ` +
+        `${details.activeDimension}${by}. ` +
+        (details.fallback
+          ? "The configured provider failed to start and its hashing fallback cannot search the stored vectors; " +
+            "fix the provider and restart"
+          : "Switch back to the provider that built the vectors, or set mcp.embedding.onDimensionChange to " +
+            "reembed and restart to rebuild them"),
+    );
+    this.name = "EmbeddingDimensionError";
+    this.details = details;
+  }
+}
+
+export interface TaskCancelledDetails {
+  taskId?: string;
+  /** What stopped the task: a cancel_task call, the tool deadline, the client abandoning the request, or shutdown */
+  reason: "cancel_task" | "timeout" | "client" | "shutdown";
+  /** Counts of an indexing run up to where it stopped; what it stored stays indexed */
+  partial?: Record<string, unknown>;
+}
+
+export class TaskCancelledError extends Error {
+  public readonly details: TaskCancelledDetails;
+
+  constructor(details: TaskCancelledDetails) {
+    super(`Task ${details.taskId ?? "(unnamed)"} was cancelled (${details.reason})`);
+    this.name = "TaskCancelledError";
+    this.details = details;
+  }
+}
+
+export interface ToolTimeoutDetails {
+  tool: string;
+  timeoutMs: number;
+  /** For indexing: what had been stored when the run stopped */
+  partial?: Record<string, unknown>;
+}
+
+export class ToolTimeoutError extends Error {
+  public readonly details: ToolTimeoutDetails;
+
+  constructor(details: ToolTimeoutDetails) {
+    super(`${details.tool} timed out after ${details.timeoutMs}ms`);
+    this.name = "ToolTimeoutError";
+    this.details = details;
+  }
+}
+
+export interface ParseTimeoutDetails {
+  filePath: string;
+  timeoutMs: number;
+}
+
+/** A file that took longer than the per-file parse timeout; indexing skips it and goes on */
+export class ParseTimeoutError extends Error {
+  public readonly details: ParseTimeoutDetails;
+
+  constructor(details: ParseTimeoutDetails) {
+    super(`Parsing ${details.filePath} timed out after ${details.timeoutMs}ms`);
+    this.name = "ParseTimeoutError";
+    this.details = details;
+  }
+}
+
+export interface SchemaVersionDetails {
+  dbPath: string;
+  /** Newest migration recorded in the database */
+  databaseVersion: number;
+  /** Newest migration this release defines */
+  supportedVersion: number;
+  /** `newer_schema`: the index was written by a later release; `migration_failed`: an upgrade step failed */
+  reason: "newer_schema" | "migration_failed";
+  failedMigration?: number;
+  cause?: string;
+}
+
+export class SchemaVersionError extends Error {
+  public readonly details: SchemaVersionDetails;
+
+  constructor(details: SchemaVersionDetails) {
+    const reindex =
+      "reindex into a new database: delete the file (with its -wal and -shm files) or point database.path " +
+      "elsewhere, then run index again";
+    super(
+      details.reason === "newer_schema"
+        ? `Index ${details.dbPath} has schema version ${details.databaseVersion}, newer than the ` +
+            `${details.supportedVersion} this release supports. Upgrade the server, or ${reindex}`
+        : `Index ${details.dbPath} could not be upgraded from schema version ${details.databaseVersion}: ` +
+            `migration ${details.failedMigration} failed (${details.cause}). To fix, ${reindex}`,
+    );
+    this.name = "SchemaVersionError";
+    this.details = details;
+  }
+}
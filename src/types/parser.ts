@@ -26,6 +26,9 @@ export const SUPPORTED_LANGUAGES = [
   "tsx",
   "jsx",
   "markdown",
+  "proto",
+  "sql",
+  "graphql",
   "python",
   "c",
   "cpp",
@@ -34,6 +37,10 @@ export const SUPPORTED_LANGUAGES = [
   "go",
   "java",
   "kotlin",
+  "ruby",
+  "php",
+  "swift",
+  "scala",
   "vba",
 ] as const;
 export type SupportedLanguage = (typeof SUPPORTED_LANGUAGES)[number];
@@ -88,6 +95,40 @@ export type MagicType =
   | "rshift"
   | "invert";
 
+/**
+ * One argument passed at a call site. Only literals have a value the index can know; a spread
+ * (`...args`, `args...`) stands for any number of arguments.
+ */
+export interface CallArgument {
+  kind: "literal" | "expression" | "spread";
+  /** The literal as written (`nil`, `0`, `"utf-8"`, `-1`); absent for other kinds */
+  value?: string;
+}
+
+/**
+ * A call expression found inside a function or method body
+ */
+export interface ParsedCallSite {
+  /** Bare name of the called function or method (`save` in `repo.save()`) */
+  name: string;
+  /** Text before the final `.`/`::` when the call is qualified (`repo`, `this`, `fmt`) */
+  qualifier?: string;
+  line: number;
+  column: number;
+  /** Arguments in order, when the parser records them */
+  arguments?: CallArgument[];
+  /** Number of arguments; absent when a spread makes it unknown */
+  arity?: number;
+}
+
+/** How one site uses a field or variable: `readwrite` for compound assignments and `++`/`--` */
+export type AccessKind = "read" | "write" | "readwrite";
+
+/** A field (`this.items`) or variable the entity body reads or writes */
+export interface ParsedAccessSite extends ParsedCallSite {
+  access: AccessKind;
+}
+
 /**
  * Represents a parsed entity from the source code
  */
@@ -138,7 +179,14 @@ export interface ParsedEntity {
     | "field"
     | "impl_block"
     | "union"
-    | "crate";
+    | "crate"
+    | "route"
+    | "test"
+    | "env_var"
+    | "channel"
+    | "comment"
+    | "table"
+    | "view";
 
   /** File path containing this entity */
   filePath?: string; // Optional for backward compatibility
@@ -158,6 +206,30 @@ export interface ParsedEntity {
   /** References to other entities */
   references?: string[];
 
+  /** Call expressions in the entity body, in source order */
+  calls?: ParsedCallSite[];
+
+  /** Fields of the entity's class and module-level variables its body reads or writes, in source order */
+  accesses?: ParsedAccessSite[];
+
+  /** Components a React component renders as JSX elements, first use of each */
+  renders?: ParsedCallSite[];
+
+  /** Hooks (`useState`, `useCart`) called by a React component or custom hook */
+  hooks?: ParsedCallSite[];
+
+  /** Function serving a `route` entity: the handler a router call names, or the decorated function */
+  handler?: ParsedCallSite;
+
+  /** Declarations reading an `env_var` entity, at their start lines */
+  readers?: ParsedCallSite[];
+
+  /** Cyclomatic complexity of a function or method body */
+  cyclomaticComplexity?: number;
+
+  /** Doc comment or docstring attached to the declaration, as written (comment markers included) */
+  documentation?: string;
+
   /** Modifiers (e.g., async, static, private) - enhanced for Python */
   modifiers?: string[];
 
@@ -168,6 +240,9 @@ export interface ParsedEntity {
   decorators?: Array<{
     name: string;
     arguments?: string[];
+    /** Decorator expression as written, without the `@` */
+    raw?: string;
+    line?: number;
     isBuiltin?: boolean;
   }>;
 
@@ -226,6 +301,10 @@ export interface ParsedEntity {
     isNamespace?: boolean;
     isRelative?: boolean;
     fromModule?: string;
+    /** Name bound by a default import (`import React from "react"`) */
+    local?: string;
+    /** Name bound by a namespace import (`import * as path from "node:path"`) */
+    namespace?: string;
   };
 
   /** Pattern recognition data for Layer 4 */
@@ -288,6 +367,27 @@ export interface ParseResult {
     message: string;
     location?: { line: number; column: number };
   }>;
+
+  /** Regions skipped because of syntax errors; entities elsewhere in the file are still extracted */
+  syntaxErrors?: SyntaxErrorRange[];
+
+  /** Parsing was abandoned at the per-file timeout; the result holds no entities */
+  timedOut?: boolean;
+}
+
+/** Part of a file tree-sitter could not parse, with byte offsets into the original content */
+export interface SyntaxErrorRange {
+  startByte: number;
+  endByte: number;
+  startLine: number;
+  endLine: number;
+}
+
+/** Syntax errors of one indexed file, as reported with index results */
+export interface FileParseErrors {
+  filePath: string;
+  parseErrors: number;
+  ranges: SyntaxErrorRange[];
 }
 
 /**
@@ -363,7 +463,7 @@ export interface ParserOptions {
   /** Batch size for parallel processing */
   batchSize?: number;
 
-  /** Timeout per file in milliseconds */
+  /** Timeout per file in milliseconds; 0 disables it */
   timeoutMs?: number;
 }
 
@@ -490,12 +590,25 @@ export interface EntityRelationship {
     | "inherits"
     | "implements"
     | "overrides"
+    | "defines"
     | "calls"
     | "imports"
     | "decorates"
+    | "decorated_by"
     | "contains"
     | "references"
     | "embeds"
+    | "renders"
+    | "uses_hook"
+    | "handled_by"
+    | "read_by"
+    | "accesses"
+    | "spawns"
+    | "sends"
+    | "receives"
+    | "foreign_key"
+    | "extension_of"
+    | "resolves"
     | "member_of";
 
   /** Source file path */
@@ -15,6 +15,9 @@
  * implementation
  */
 
+import type { TextEmbedder } from "../semantic/providers/base.js";
+import type { SearchScope } from "./storage.js";
+
 // =============================================================================
 // 2. CONSTANTS AND CONFIGURATION
 // =============================================================================
@@ -45,6 +48,8 @@ export interface SimilarityResult {
   id: string;
   content: string;
   similarity: number;
+  /** Cosine similarity between query and stored vector, in [-1, 1] */
+  cosine?: number;
   metadata?: Record<string, unknown>;
 }
 
@@ -151,12 +156,42 @@ export interface VectorStoreConfig {
   dimensions: number;
   cacheSize?: number;
   walMode?: boolean;
+  /** Wait for another connection's write lock this long before failing; default 10s */
+  busyTimeoutMs?: number;
+  /** Provider/model producing the vectors; a change resets the store so entities get re-embedded */
+  embeddingSource?: EmbeddingSource;
+  /**
+   * What a changed `embeddingSource` dimension does to stored vectors: `reembed` (default) drops
+   * them for re-embedding, `refuse` keeps them and rejects searches and writes until resolved
+   */
+  onDimensionChange?: "reembed" | "refuse";
+  ann?: AnnIndexConfig;
+}
+
+/** HNSW index answering unscoped searches once the store outgrows an exact scan */
+export interface AnnIndexConfig {
+  enabled?: boolean;
+  /** Stores holding fewer searchable vectors are always scanned exactly */
+  minVectors?: number;
+  /** Links per node; more links recall better and cost memory and build time */
+  m?: number;
+  efConstruction?: number;
+  /** Candidates walked per query (at least the requested limit); higher recalls better, slower */
+  efSearch?: number;
+  /** Share of removed vectors still linked in the graph at which it is rebuilt without them */
+  compactRatio?: number;
+}
+
+export interface EmbeddingSource {
+  provider: string;
+  model: string;
+  dimension?: number;
 }
 
 /**
  * Embedding generator configuration
  */
-export type EmbeddingProviderKind = "memory" | "transformers" | "ollama" | "openai" | "cloudru";
+export type EmbeddingProviderKind = "memory" | "transformers" | "ollama" | "openai" | "cloudru" | "custom";
 
 export interface OllamaProviderConfig {
   baseUrl?: string;
@@ -176,6 +211,7 @@ export interface OpenAIProviderConfig {
   concurrency?: number;
   dimensions?: number;
   maxBatchSize?: number;
+  maxRetries?: number;
 }
 
 export interface CloudRUProviderConfig {
@@ -190,6 +226,35 @@ export interface MemoryProviderConfig {
   dimension?: number;
 }
 
+/** Limits shared by every request to a hosted provider (ollama, openai, cloudru) */
+export interface EmbeddingRateLimitConfig {
+  /** Provider requests in flight at once; 0 leaves it to the provider's own concurrency */
+  maxConcurrency?: number;
+  /** Sustained request rate; 0 disables the token bucket */
+  requestsPerSecond?: number;
+  /** Requests allowed back to back after an idle period; defaults to one second's worth */
+  burst?: number;
+}
+
+/** `mcp.embedding.batching`: texts per provider request */
+export interface EmbeddingBatchingConfig {
+  /** A fixed number of texts per request, or "auto" (default) to adapt to observed latency */
+  size?: number | "auto";
+  /** Bounds for adaptive sizing; `max` is further capped by the provider's own request limit */
+  min?: number;
+  max?: number;
+  /** Requests slower than this shrink the batch; defaults per provider */
+  targetLatencyMs?: number;
+  /** Estimated tokens per request; larger batches are split before they are sent */
+  maxTokens?: number;
+}
+
+export interface CustomProviderConfig {
+  embedder?: TextEmbedder;
+  module?: string;
+  options?: Record<string, unknown>;
+}
+
 export interface EmbeddingConfig {
   modelName: string;
   quantized: boolean;
@@ -201,6 +266,20 @@ export interface EmbeddingConfig {
   openai?: OpenAIProviderConfig;
   cloudru?: CloudRUProviderConfig;
   memory?: MemoryProviderConfig;
+  custom?: CustomProviderConfig;
+  /** Further initialization attempts after a failure, and the first retry delay (doubled each time) */
+  initRetries?: number;
+  initBackoffMs?: number;
+  rateLimit?: EmbeddingRateLimitConfig;
+  batching?: EmbeddingBatchingConfig;
+}
+
+/**
+ * Durable vector cache keyed by provider identity and content hash; implemented by graph storage
+ */
+export interface EmbeddingCacheStore {
+  getCachedEmbeddings(providerKey: string, hashes: string[]): Promise<Map<string, Float32Array>>;
+  putCachedEmbeddings(providerKey: string, entries: Array<{ hash: string; vector: Float32Array }>): Promise<void>;
 }
 
 /**
@@ -208,7 +287,7 @@ export interface EmbeddingConfig {
  */
 export interface SemanticOperations {
   // Basic semantic search
-  semanticSearch(query: string, limit?: number): Promise<SemanticResult>;
+  semanticSearch(query: string, limit?: number, scope?: SearchScope): Promise<SemanticResult>;
 
   // Code similarity
   findSimilarCode(code: string, threshold?: number): Promise<SimilarCode[]>;
@@ -13,7 +13,7 @@
 // =============================================================================
 // 1. IMPORTS AND DEPENDENCIES
 // =============================================================================
-import type { ParsedEntity } from "./parser.js";
+import type { AccessKind, CallArgument, ParsedEntity } from "./parser.js";
 export type { ParsedEntity };
 
 // =============================================================================
@@ -43,6 +43,13 @@ export enum EntityType {
   VARIABLE = "variable",
   CONSTANT = "constant",
   PACKAGE = "package",
+  ROUTE = "route",
+  TEST = "test",
+  ENV_VAR = "env_var",
+  CHANNEL = "channel",
+  COMMENT = "comment",
+  TABLE = "table",
+  VIEW = "view",
 }
 
 /**
@@ -57,6 +64,22 @@ export enum RelationType {
   REFERENCES = "references",
   CONTAINS = "contains",
   DEPENDS_ON = "depends_on",
+  EMBEDS = "embeds",
+  DECORATED_BY = "decorated_by",
+  OVERRIDES = "overrides",
+  DEFINES = "defines",
+  RENDERS = "renders",
+  USES_HOOK = "uses_hook",
+  HANDLED_BY = "handled_by",
+  TESTS = "tests",
+  READ_BY = "read_by",
+  ACCESSES = "accesses",
+  SPAWNS = "spawns",
+  SENDS = "sends",
+  RECEIVES = "receives",
+  FOREIGN_KEY = "foreign_key",
+  EXTENSION_OF = "extension_of",
+  RESOLVES = "resolves",
 }
 
 /**
@@ -85,13 +108,23 @@ export interface Entity {
       specifiers: Array<{ local: string; imported?: string }>;
       isDefault?: boolean;
       isNamespace?: boolean;
+      local?: string;
+      namespace?: string;
     };
     // Additional useful fields for all languages
     signature?: string;
     language?: string;
+    cyclomaticComplexity?: number;
+    /** Hash of the entity's source text, set when the file could be read at index time */
+    bodyHash?: string;
+    /** Doc comment or docstring as written; see parsers/doc-comments.ts */
+    documentation?: string;
     decorators?: Array<{
       name: string;
       arguments?: string[];
+      /** Decorator expression as written, without the `@` */
+      raw?: string;
+      line?: number;
       isBuiltin?: boolean;
     }>;
     [key: string]: unknown;
@@ -106,6 +139,35 @@ export interface Entity {
   sizeBytes?: number;
 }
 
+/**
+ * One call expression behind a `calls` edge; repeated calls between the same pair share one edge
+ */
+export interface CallSite {
+  line: number;
+  column?: number;
+  /** Callee as written at the call site (`helper`, `s.store.Save`, `fmt.Println`) */
+  callee?: string;
+  /** Static type of the receiver when the call is a method call on a known type */
+  receiverType?: string;
+  /** Arguments passed, in order; absent for parsers that do not record them */
+  arguments?: CallArgument[];
+  /** Number of arguments passed; absent when a spread argument makes it unknown */
+  arity?: number;
+}
+
+/**
+ * One read or write behind an `accesses` edge; repeated uses between the same pair share one edge
+ */
+export interface AccessSite {
+  line: number;
+  column?: number;
+  access: AccessKind;
+  /** Field or variable as written (`s.users`, `this.items`, `count`) */
+  name?: string;
+  /** Static type of the value whose field is accessed, when known */
+  receiverType?: string;
+}
+
 /**
  * Relationship between entities (enhanced for v2)
  */
@@ -118,6 +180,8 @@ export interface Relationship {
     line?: number;
     column?: number;
     context?: string;
+    callSites?: CallSite[];
+    accessSites?: AccessSite[];
     [key: string]: unknown;
   };
 
@@ -134,11 +198,104 @@ export interface FileInfo {
   hash: string;
   lastIndexed: number;
   entityCount: number;
+  /** Modification time of the file when it was indexed; null for files indexed before it was recorded */
+  mtimeMs?: number | null;
+}
+
+/**
+ * Labelled copy of the index, kept for diffing
+ */
+export interface GraphSnapshot {
+  label: string;
+  description: string | null;
+  createdAt: number;
+  entityCount: number;
+  relationshipCount: number;
+  /** Search vectors kept with the snapshot; 0 when it was taken without them */
+  vectorCount: number;
+  /** Embedder that made them, as provider:model:dimension; null without vectors */
+  vectorSource: string | null;
+}
+
+export interface SnapshotEntity {
+  id: string;
+  name: string;
+  type: string;
+  filePath: string;
+  startLine: number | null;
+  endLine: number | null;
+  signature: string | null;
+  bodyHash: string | null;
+}
+
+export interface SnapshotRelationship {
+  id: string;
+  fromId: string;
+  toId: string;
+  type: string;
+}
+
+export type IndexRunStatus = "running" | "completed" | "cancelled" | "failed" | "interrupted";
+
+/**
+ * One index run as journaled in storage
+ */
+export interface IndexRun {
+  id: string;
+  directory: string | null;
+  status: IndexRunStatus;
+  startedAt: number;
+  finishedAt: number | null;
+  filesTotal: number;
+  filesDone: number;
+  /** When a later start repaired what the run left unfinished */
+  recoveredAt: number | null;
+  /** Commit checked out when the run started; null outside a git work tree */
+  gitHead: string | null;
+  /** Remote URL (without credentials) and requested ref when the run indexed a clone */
+  repository: string | null;
+  ref: string | null;
+}
+
+/**
+ * What startup repaired after runs that never finished their resolution passes
+ */
+export interface IndexRecovery {
+  /** Files whose write was cut short; their partial rows were dropped */
+  droppedFiles: string[];
+  /** Files left stale (written by an unfinished run, or holding edges into a dropped file), to be re-indexed */
+  staleFiles: string[];
+  /** Unfinished runs, marked recovered */
+  runs: IndexRun[];
 }
 
 /**
  * Graph query parameters
  */
+/**
+ * Narrows a search to entity kinds, languages and paths
+ */
+export interface SearchScope {
+  kinds?: string[];
+  languages?: string[];
+  /** Directory or file path; matches the path itself and everything below it */
+  pathPrefix?: string;
+  /** Subtree the search is confined to, matched like pathPrefix; when both are set, both must hold */
+  directory?: string;
+  /** Path glob such as `services/**` or `src/*.py`; `*` may cross directories */
+  pathGlob?: string;
+  /** Index root the entity's file was indexed from (its `root` metadata tag) */
+  root?: string;
+  /** Only documentation sections (Markdown documents and headings), or only everything else */
+  content?: "docs" | "code";
+  /** Leave out anonymous functions, closures and other unnamed declarations */
+  excludeAnonymous?: boolean;
+  /** Leave out declarations of test files, or keep only those; both by default */
+  tests?: "exclude" | "only";
+  /** Only declarations of these visibilities (`metadata.visibility`: public, protected, internal, private) */
+  visibility?: string[];
+}
+
 export interface GraphQuery {
   type: "entity" | "relationship" | "subgraph";
   filters?: {
@@ -146,10 +303,15 @@ export interface GraphQuery {
     relationshipType?: RelationType | RelationType[];
     filePath?: string | string[];
     name?: string | RegExp;
+    scope?: SearchScope;
+    /** Last-modifying author from git blame: a case-insensitive part of the name, or the exact email */
+    author?: string;
   };
   depth?: number;
   limit?: number;
   offset?: number;
+  /** Keyset paging: entities in id order starting after this id ("" for the first page) */
+  afterId?: string;
 }
 
 /**
@@ -235,6 +397,44 @@ export interface StorageMetrics {
   concurrentConnections?: number;
 }
 
+/**
+ * What the graph holds, broken down for graph_stats. Entity counts leave out the `external://`
+ * placeholders unresolved imports point at; those are counted on their own.
+ */
+export interface GraphContentBreakdown {
+  files: number;
+  entitiesByType: Record<string, number>;
+  entitiesByLanguage: Record<string, number>;
+  placeholderEntities: number;
+  relationshipsByType: Record<string, number>;
+  /** Edges ending at a placeholder (`external`) or at an id nothing is stored under (`dangling`) */
+  unresolvedRelationships: { external: number; dangling: number; byType: Record<string, number> };
+  /** Latest file index or entity write, in ms since the epoch */
+  lastUpdatedAt: number | null;
+}
+
+/** A relationship with an endpoint no entity is stored under, found by verify_index */
+export interface DanglingRelationship {
+  id: string;
+  type: string;
+  fromId: string;
+  toId: string;
+  missing: "from" | "to" | "both";
+}
+
+/**
+ * Outcome of compacting the graph database (compact_index)
+ */
+export interface CompactionResult {
+  bytesBefore: number;
+  bytesAfter: number;
+  walBytesBefore: number;
+  vacuumed: boolean;
+  /** Expired query-cache rows deleted before the rebuild */
+  cacheRowsPurged: number;
+  durationMs: number;
+}
+
 /**
  * Batch operation result
  */
@@ -248,6 +448,16 @@ export interface BatchResult {
   timeMs: number;
 }
 
+/**
+ * Batch upsert result: `processed` split into rows inserted, rows rewritten because they changed,
+ * and rows that already held the same values and were left alone
+ */
+export interface UpsertResult extends BatchResult {
+  created: number;
+  updated: number;
+  unchanged: number;
+}
+
 /**
  * Cache entry for query results
  */
@@ -298,6 +508,9 @@ export interface GraphStorage {
   updateFileInfo(info: FileInfo): Promise<void>;
   getFileInfo(path: string): Promise<FileInfo | null>;
   getOutdatedFiles(since: number): Promise<FileInfo[]>;
+  listIndexedFiles(): Promise<FileInfo[]>;
+  listIndexRoots(): Promise<string[]>;
+  findDependentFiles(filePaths: string[]): Promise<string[]>;
   deleteFileData(
     filePath: string,
     options?: {
@@ -305,9 +518,14 @@ export interface GraphStorage {
     },
   ): Promise<{
     entitiesDeleted: number;
+    entitiesRemoved: number;
     relationshipsDeleted: number;
     fileInfoDeleted: number;
   }>;
+  reconcileFileData(
+    filePath: string,
+    current: { entityIds: string[]; relationshipIds: string[] },
+  ): Promise<{ entitiesRemoved: number; relationshipsRemoved: number }>;
 
   // Query operations
   executeQuery(query: GraphQuery): Promise<GraphQueryResult>;
@@ -315,8 +533,15 @@ export interface GraphStorage {
 
   // Maintenance operations
   vacuum(): Promise<void>;
+  compact(options?: { vacuum?: boolean }): Promise<CompactionResult>;
   analyze(): Promise<void>;
   getMetrics(): Promise<StorageMetrics>;
+  getContentBreakdown(): Promise<GraphContentBreakdown>;
+  findDanglingRelationships(limit?: number): Promise<{ total: number; relationships: DanglingRelationship[] }>;
+  deleteDanglingRelationships(): Promise<number>;
+  filterExistingEntityIds(ids: string[]): Promise<Set<string>>;
+  findEntitiesByNamePattern(pattern: string, options?: { scope?: SearchScope; limit?: number }): Promise<Entity[]>;
+  countIncomingRelationships(ids: string[]): Promise<Map<string, number>>;
 }
 
 /**
@@ -400,13 +625,17 @@ export function parsedEntityToEntity(
     filePath,
     location: parsed.location,
     metadata: {
+      // Analyzer-specific details (qualified names, receivers, ...) first; core fields win on conflict.
+      ...parsed.metadata,
       modifiers: parsed.modifiers,
-      returnType: parsed.returnType,
-      parameters: parsed.parameters,
+      returnType: parsed.returnType ?? parsed.metadata?.returnType,
+      parameters: parsed.parameters ?? parsed.metadata?.parameters,
       importData: parsed.importData,
       signature: parsed.signature,
       language: parsed.language,
       decorators: parsed.decorators,
+      documentation: parsed.documentation,
+      cyclomaticComplexity: parsed.cyclomaticComplexity ?? parsed.metadata?.cyclomaticComplexity,
     },
     hash,
   };
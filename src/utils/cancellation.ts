@@ -0,0 +1,35 @@
+/**
+ * Cancellation helpers for agent tasks. A task carries an AbortSignal whose abort reason is the
+ * TaskCancelledError to surface, so every loop that checks it fails with the same error.
+ */
+
+import { TaskCancelledError } from "../types/errors.js";
+
+/** The error a cancelled task fails with; a non-Error abort reason becomes a generic cancellation */
+export function cancellationError(signal: AbortSignal, taskId?: string): Error {
+  return signal.reason instanceof Error ? signal.reason : new TaskCancelledError({ taskId, reason: "cancel_task" });
+}
+
+/** Stop here when `signal` has been aborted; a no-op for tasks that cannot be cancelled */
+export function throwIfCancelled(signal: AbortSignal | undefined, taskId?: string): void {
+  if (signal?.aborted) throw cancellationError(signal, taskId);
+}
+
+export function isCancellation(error: unknown): error is TaskCancelledError {
+  return error instanceof TaskCancelledError;
+}
+
+/**
+ * Controller that also aborts when `parent` does, so a child can be cancelled on its own while
+ * cancelling the parent stops every child. `reason` supplies the error when the parent's abort
+ * reason is not one (the MCP SDK aborts with a plain string).
+ */
+export function linkedAbortController(parent?: AbortSignal, reason?: () => Error): AbortController {
+  const controller = new AbortController();
+  if (!parent) return controller;
+  const forward = () =>
+    controller.abort(parent.reason instanceof Error ? parent.reason : (reason?.() ?? parent.reason));
+  if (parent.aborted) forward();
+  else parent.addEventListener("abort", forward, { once: true });
+  return controller;
+}
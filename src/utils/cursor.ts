@@ -13,3 +13,35 @@ export function decodeCursor<T extends Record<string, unknown>>(cursor?: string)
     return null;
   }
 }
+
+/** Sort key of the last item on a page; the next page starts strictly after it */
+export type ScoreCursor = { s: number; id: string };
+
+/** Highest score first, ties broken by id so equal scores keep one order across calls */
+export function compareScoreThenId(a: ScoreCursor, b: ScoreCursor): number {
+  if (a.s !== b.s) return b.s - a.s;
+  return a.id < b.id ? -1 : a.id > b.id ? 1 : 0;
+}
+
+export function toScoreCursor(state: { s?: unknown; id?: unknown } | null): ScoreCursor | null {
+  if (!state || typeof state.s !== "number" || typeof state.id !== "string") return null;
+  return { s: state.s, id: state.id };
+}
+
+/**
+ * One page of `items` in score-then-id order, resuming after `after`. Paging by sort key rather
+ * than offset means a result that moves or appears between calls cannot be served twice, and
+ * items that keep their score are never skipped.
+ */
+export function pageByScore<T>(
+  items: T[],
+  keyOf: (item: T) => ScoreCursor,
+  after: ScoreCursor | null,
+  pageSize: number,
+): { page: T[]; next: ScoreCursor | null } {
+  const ordered = items.map((item) => ({ item, key: keyOf(item) })).sort((a, b) => compareScoreThenId(a.key, b.key));
+  const rest = after ? ordered.filter((entry) => compareScoreThenId(after, entry.key) < 0) : ordered;
+  const page = rest.slice(0, pageSize);
+  const last = page[page.length - 1];
+  return { page: page.map((entry) => entry.item), next: rest.length > pageSize && last ? last.key : null };
+}
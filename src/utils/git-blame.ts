@@ -0,0 +1,132 @@
+/**
+ * Line ownership from `git blame`, attached to entities during opt-in indexing runs. Blame runs
+ * once per file and costs roughly a history walk per file, so it stays off by default; any git
+ * failure (no repository, untracked file, missing binary) yields no metadata instead of an error.
+ */
+
+import { execFile } from "node:child_process";
+import { basename, dirname, resolve } from "node:path";
+import { promisify } from "node:util";
+
+const execFileAsync = promisify(execFile);
+
+const UNCOMMITTED = /^0{40}$/;
+const BLAME_TIMEOUT_MS = 30_000;
+const BLAME_MAX_BUFFER = 64 * 1024 * 1024;
+
+/** Commit that last touched one line */
+export interface BlameLine {
+  commit: string;
+  author: string;
+  email: string;
+  /** Author time, seconds since the epoch */
+  time: number;
+  summary: string;
+}
+
+/** Stored on an entity as `metadata.lastModified` */
+export interface LastModified {
+  commit: string;
+  author: string;
+  email: string;
+  /** ISO 8601 author date */
+  date: string;
+  /** Author time in seconds, for sorting */
+  time: number;
+  summary: string;
+}
+
+/**
+ * Parse `git blame --line-porcelain` output into one entry per source line (index 0 is line 1).
+ * Lines not committed yet are null.
+ */
+export function parseLinePorcelain(output: string): Array<BlameLine | null> {
+  const lines: Array<BlameLine | null> = [];
+  let current: BlameLine | null = null;
+  for (const line of output.split("\n")) {
+    if (line.startsWith("\t")) {
+      lines.push(current && !UNCOMMITTED.test(current.commit) ? current : null);
+      current = null;
+      continue;
+    }
+    const space = line.indexOf(" ");
+    const key = space === -1 ? line : line.slice(0, space);
+    const value = space === -1 ? "" : line.slice(space + 1);
+    if (!current) {
+      if (/^[0-9a-f]{40}$/.test(key)) current = { commit: key, author: "", email: "", time: 0, summary: "" };
+      continue;
+    }
+    if (key === "author") current.author = value;
+    else if (key === "author-mail") current.email = value.replace(/^<|>$/g, "");
+    else if (key === "author-time") current.time = Number(value) || 0;
+    else if (key === "summary") current.summary = value;
+  }
+  return lines;
+}
+
+/** Blame of every line of `filePath`, or null when git cannot blame it */
+export async function blameFile(filePath: string): Promise<Array<BlameLine | null> | null> {
+  try {
+    const { stdout } = await execFileAsync("git", ["blame", "--line-porcelain", "-w", "--", basename(filePath)], {
+      cwd: dirname(filePath),
+      encoding: "utf8",
+      timeout: BLAME_TIMEOUT_MS,
+      maxBuffer: BLAME_MAX_BUFFER,
+    });
+    return parseLinePorcelain(stdout);
+  } catch {
+    return null;
+  }
+}
+
+/** Blame of each file, running at most `concurrency` git processes at a time; files git cannot blame are left out */
+export async function blameFiles(files: string[], concurrency = 8): Promise<Map<string, Array<BlameLine | null>>> {
+  const blames = new Map<string, Array<BlameLine | null>>();
+  for (let i = 0; i < files.length; i += concurrency) {
+    const chunk = files.slice(i, i + concurrency);
+    const results = await Promise.all(chunk.map((file) => blameFile(file)));
+    results.forEach((blame, j) => {
+      if (blame) blames.set(chunk[j]!, blame);
+    });
+  }
+  return blames;
+}
+
+const workTrees = new Map<string, Promise<boolean>>();
+
+/** Whether `directory` lies inside a git work tree; cached per directory */
+export function isGitWorkTree(directory: string): Promise<boolean> {
+  const key = resolve(directory);
+  let check = workTrees.get(key);
+  if (!check) {
+    check = execFileAsync("git", ["rev-parse", "--is-inside-work-tree"], { cwd: key, encoding: "utf8" }).then(
+      ({ stdout }) => stdout.trim() === "true",
+      () => false,
+    );
+    workTrees.set(key, check);
+  }
+  return check;
+}
+
+/** The newest commit among lines `startLine`..`endLine` (1-based, inclusive), or null if none is committed */
+export function lastModifiedIn(
+  lines: Array<BlameLine | null>,
+  startLine: number,
+  endLine: number,
+): LastModified | null {
+  let newest: BlameLine | null = null;
+  const last = Math.min(Math.max(endLine, startLine), lines.length);
+  for (let line = Math.max(1, startLine); line <= last; line++) {
+    const blame = lines[line - 1];
+    if (blame && (!newest || blame.time > newest.time)) newest = blame;
+  }
+  if (!newest) return null;
+  return {
+    commit: newest.commit,
+    author: newest.author,
+    email: newest.email,
+    date: new Date(newest.time * 1000).toISOString(),
+    time: newest.time,
+    summary: newest.summary,
+  };
+}
@@ -0,0 +1,214 @@
+/**
+ * Shallow checkouts of remote repositories for `index(repository)`. Each URL gets one directory
+ * under the clone cache; indexing it again fetches the requested ref into that directory instead
+ * of cloning again. A clone that fails, or whose first index fails, is removed so the cache never
+ * holds a half-fetched tree.
+ */
+
+import { execFile } from "node:child_process";
+import { createHash } from "node:crypto";
+import { existsSync } from "node:fs";
+import { mkdir, rm } from "node:fs/promises";
+import { join } from "node:path";
+import { promisify } from "node:util";
+import { headCommit } from "./git-worktree.js";
+
+const execFileAsync = promisify(execFile);
+
+const FETCH_TIMEOUT_MS = 10 * 60_000;
+
+export interface RemoteCheckout {
+  /** URL with any credentials removed; what the index records */
+  url: string;
+  /** Ref that was asked for; null for the remote's default branch */
+  ref: string | null;
+  commit: string;
+  directory: string;
+  /** false when this call cloned the directory */
+  reused: boolean;
+}
+
+export class RemoteCheckoutError extends Error {
+  constructor(
+    message: string,
+    public readonly details: { url: string; ref: string | null; stderr?: string },
+  ) {
+    super(message);
+    this.name = "RemoteCheckoutError";
+  }
+}
+
+/** `https://`, `ssh://`, `git://` and `file://` URLs and scp-style `git@host:owner/repo` */
+export function isGitUrl(input: string): boolean {
+  const value = input.trim();
+  return /^(https?|ssh|git|file):\/\/\S+$/i.test(value) || /^[\w.-]+@[\w.-]+:[^\s\\]+$/.test(value);
+}
+
+/**
+ * Drop credentials from a URL so they never reach the index, logs or tool output: any user info
+ * of an http(s) URL (tokens are often passed as the user name), and `user:password@` elsewhere.
+ * An ssh user such as `git@` is kept.
+ */
+export function redactGitUrl(url: string): string {
+  return url
+    .trim()
+    .replace(/^(https?:\/\/)[^/@]+@/i, "$1")
+    .replace(/^([a-z][\w+.-]*:\/\/)[^/@:]+:[^/@]*@/i, "$1");
+}
+
+/**
+ * Git configuration that authenticates with the credentials in the user info of an http(s) URL,
+ * sent as a Basic Authorization header; empty for URLs without them. It is handed to the fetch
+ * through the environment, so the remote stored in the checkout keeps only the redacted URL.
+ */
+export function credentialConfig(url: string): Record<string, string> {
+  const userInfo = /^https?:\/\/([^/@]+)@/i.exec(url.trim())?.[1];
+  if (!userInfo) return {};
+  const decode = (part: string) => {
+    try {
+      return decodeURIComponent(part);
+    } catch {
+      return part;
+    }
+  };
+  // A token alone is usually passed as the user name, with no password
+  const [user = "", ...password] = userInfo.split(":");
+  const basic = Buffer.from(`${decode(user)}:${decode(password.join(":"))}`).toString("base64");
+  return { "http.extraHeader": `Authorization: Basic ${basic}` };
+}
+
+/** Cache directory for `url`: its repository name plus a hash, so same-named repositories stay apart */
+export function checkoutDirFor(cacheDir: string, url: string): string {
+  const redacted = redactGitUrl(url);
+  const name =
+    redacted
+      .replace(/\/+$/, "")
+      .replace(/\.git$/, "")
+      .split(/[/:]/)
+      .pop()
+      ?.replace(/[^\w.-]/g, "_") || "repo";
+  const hash = createHash("sha256").update(redacted).digest("hex").slice(0, 10);
+  return join(cacheDir, `${name}-${hash}`);
+}
+
+/**
+ * Whether `ref` is safe to hand to git fetch: a full commit hash, or a name `git check-ref-format`
+ * accepts. Anything starting with `-` is refused first, since git would read it as an option
+ * (`--upload-pack=<cmd>` runs a command).
+ */
+export async function isValidGitRef(ref: string): Promise<boolean> {
+  const value = ref.trim();
+  if (!value || value.startsWith("-")) return false;
+  if (/^([0-9a-f]{40}|[0-9a-f]{64})$/i.test(value)) return true;
+  try {
+    await execFileAsync("git", ["check-ref-format", "--allow-onelevel", value], { encoding: "utf8" });
+    return true;
+  } catch {
+    return false;
+  }
+}
+
+async function git(
+  cwd: string,
+  args: string[],
+  signal?: AbortSignal,
+  config: Record<string, string> = {},
+): Promise<string> {
+  // GIT_CONFIG_* keeps the values out of the command line, where any local user could read them
+  const entries = Object.entries(config);
+  const configEnv = Object.fromEntries([
+    ...(entries.length > 0 ? [["GIT_CONFIG_COUNT", String(entries.length)]] : []),
+    ...entries.flatMap(([key, value], i) => [
+      [`GIT_CONFIG_KEY_${i}`, key],
+      [`GIT_CONFIG_VALUE_${i}`, value],
+    ]),
+  ]);
+  const { stdout } = await execFileAsync("git", args, {
+    cwd,
+    encoding: "utf8",
+    timeout: FETCH_TIMEOUT_MS,
+    maxBuffer: 16 * 1024 * 1024,
+    signal,
+    // A private repository must fail instead of waiting on a credential prompt nobody can answer
+    env: { ...process.env, GIT_TERMINAL_PROMPT: "0", ...configEnv },
+  });
+  return stdout.trim();
+}
+
+function stderrOf(error: unknown): string | undefined {
+  const stderr = (error as { stderr?: unknown })?.stderr;
+  return typeof stderr === "string" && stderr.trim() ? stderr.trim() : undefined;
+}
+
+// One checkout per directory at a time; a second index of the same URL waits for the first fetch
+const locks = new Map<string, Promise<unknown>>();
+
+async function exclusive<T>(key: string, work: () => Promise<T>): Promise<T> {
+  const previous = locks.get(key) ?? Promise.resolve();
+  const next = previous.catch(() => undefined).then(work);
+  locks.set(key, next);
+  try {
+    return await next;
+  } finally {
+    if (locks.get(key) === next) locks.delete(key);
+  }
+}
+
+/**
+ * Fetch `ref` (branch, tag or full commit hash; the default branch when omitted) of `url` at depth 1
+ * into its cache directory and check it out.
+ */
+export function checkoutRemote(
+  url: string,
+  options: { cacheDir: string; ref?: string | null; signal?: AbortSignal },
+): Promise<RemoteCheckout> {
+  const ref = options.ref?.trim() || null;
+  const directory = checkoutDirFor(options.cacheDir, url);
+  const redacted = redactGitUrl(url);
+
+  return exclusive(directory, async () => {
+    if (ref && !(await isValidGitRef(ref))) {
+      throw new RemoteCheckoutError(`Invalid ref ${JSON.stringify(ref)}`, { url: redacted, ref });
+    }
+    const reused = existsSync(join(directory, ".git"));
+    try {
+      if (!reused) {
+        await mkdir(directory, { recursive: true });
+        await git(directory, ["init", "--quiet"], options.signal);
+        await git(directory, ["remote", "add", "origin", redacted], options.signal);
+      } else {
+        // Also clears a token an older release stored in the remote
+        await git(directory, ["remote", "set-url", "origin", redacted], options.signal);
+      }
+      // Credentials only ever reach git for the fetch itself, never .git/config
+      await git(
+        directory,
+        ["fetch", "--quiet", "--depth", "1", "--end-of-options", "origin", ref ?? "HEAD"],
+        options.signal,
+        credentialConfig(url),
+      );
+      await git(directory, ["checkout", "--quiet", "--force", "--detach", "FETCH_HEAD"], options.signal);
+      await git(directory, ["clean", "--quiet", "-fdx"], options.signal);
+    } catch (error) {
+      if (!reused) await rm(directory, { recursive: true, force: true });
+      throw new RemoteCheckoutError(`Could not fetch ${ref ?? "the default branch"} of ${redacted}`, {
+        url: redacted,
+        ref,
+        stderr: stderrOf(error)?.replaceAll(url.trim(), redacted),
+      });
+    }
+
+    const commit = await headCommit(directory);
+    if (!commit) {
+      if (!reused) await rm(directory, { recursive: true, force: true });
+      throw new RemoteCheckoutError(`Fetched ${redacted} but found no commit checked out`, { url: redacted, ref });
+    }
+    return { url: redacted, ref, commit, directory, reused };
+  });
+}
+
+/** Remove a checkout this process cloned, after its first index failed */
+export async function discardCheckout(checkout: RemoteCheckout): Promise<void> {
+  if (checkout.reused) return;
+  await rm(checkout.directory, { recursive: true, force: true });
+}
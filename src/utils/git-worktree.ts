@@ -0,0 +1,37 @@
+/**
+ * Where a working tree's git history stands, recorded at index time and compared by index_status.
+ * Outside a repository, or without a git binary, every answer is null.
+ */
+
+import { execFile } from "node:child_process";
+import { promisify } from "node:util";
+
+const execFileAsync = promisify(execFile);
+
+const GIT_TIMEOUT_MS = 10_000;
+
+async function git(directory: string, args: string[]): Promise<string | null> {
+  try {
+    const { stdout } = await execFileAsync("git", args, { cwd: directory, encoding: "utf8", timeout: GIT_TIMEOUT_MS });
+    return stdout.trim();
+  } catch {
+    return null;
+  }
+}
+
+/** Full hash of the commit checked out in `directory` */
+export async function headCommit(directory: string): Promise<string | null> {
+  const head = await git(directory, ["rev-parse", "HEAD"]);
+  return head && /^[0-9a-f]{40,64}$/.test(head) ? head : null;
+}
+
+/** Commits reachable from HEAD but not from `since`; null when `since` is unknown to the repository */
+export async function commitsSince(directory: string, since: string): Promise<number | null> {
+  const count = await git(directory, ["rev-list", "--count", `${since}..HEAD`]);
+  return count !== null && /^\d+$/.test(count) ? Number(count) : null;
+}
+
+/** URL of the `origin` remote, as configured (credentials included) */
+export async function originUrl(directory: string): Promise<string | null> {
+  return (await git(directory, ["remote", "get-url", "origin"])) || null;
+}
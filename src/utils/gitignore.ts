@@ -0,0 +1,200 @@
+/**
+ * Gitignore matching for index file discovery
+ *
+ * Follows git's rules: blank lines and `#` comments are skipped, `!` re-includes, a trailing `/`
+ * matches directories only, a pattern with a `/` anywhere but the end is anchored to the directory
+ * of its .gitignore, and `**` spans directories. Rules from deeper .gitignore files override
+ * shallower ones, and within a file the last matching rule wins.
+ *
+ * Like git, a file inside an ignored directory cannot be re-included; callers enforce that by not
+ * descending into directories this reports as ignored.
+ */
+
+import { existsSync, readFileSync } from "node:fs";
+import { dirname, join, relative, resolve } from "node:path";
+
+export type GitignoreRule = {
+  /** Directory of the .gitignore relative to the repository root ("" for the root) */
+  base: string;
+  pattern: string;
+  negated: boolean;
+  directoryOnly: boolean;
+  regex: RegExp;
+};
+
+function toPosix(p: string): string {
+  return p.split("\\").join("/");
+}
+
+function classToRegExp(pattern: string, start: number): { source: string; end: number } | null {
+  let i = start + 1;
+  let source = "[";
+  if (pattern[i] === "!" || pattern[i] === "^") {
+    source += "^";
+    i++;
+  }
+  // A `]` right after the opening bracket is a literal member
+  if (pattern[i] === "]") {
+    source += "\\]";
+    i++;
+  }
+  for (; i < pattern.length; i++) {
+    const ch = pattern[i]!;
+    if (ch === "]") return { source: `${source}]`, end: i };
+    if (ch === "\\" && i + 1 < pattern.length) {
+      source += `\\${pattern[++i]}`;
+      continue;
+    }
+    source += ch === "[" ? "\\[" : ch;
+  }
+  return null;
+}
+
+function patternToRegExp(pattern: string, anchored: boolean): RegExp {
+  let source = anchored ? "" : "(?:.*/)?";
+  for (let i = 0; i < pattern.length; i++) {
+    const ch = pattern[i]!;
+    if (ch === "*" && pattern[i + 1] === "*") {
+      const atSegmentStart = i === 0 || pattern[i - 1] === "/";
+      const next = pattern[i + 2];
+      if (atSegmentStart && next === "/") {
+        // `**/` matches zero or more leading directories
+        source += "(?:.*/)?";
+        i += 2;
+        continue;
+      }
+      if (atSegmentStart && next === undefined) {
+        source += ".*";
+        i++;
+        continue;
+      }
+      // `**` inside a segment is an ordinary `*`
+      source += "[^/]*";
+      i++;
+      continue;
+    }
+    if (ch === "*") {
+      source += "[^/]*";
+      continue;
+    }
+    if (ch === "?") {
+      source += "[^/]";
+      continue;
+    }
+    if (ch === "[") {
+      const cls = classToRegExp(pattern, i);
+      if (cls) {
+        source += cls.source;
+        i = cls.end;
+        continue;
+      }
+    }
+    if (ch === "\\" && i + 1 < pattern.length) {
+      i++;
+    }
+    source += pattern[i]!.replace(/[\\^$.*+?()[\]{}|]/g, "\\$&");
+  }
+  return new RegExp(`^${source}$`);
+}
+
+/**
+ * Parse the contents of one .gitignore file. `base` is the file's directory relative to the
+ * repository root, with `/` separators.
+ */
+export function parseGitignore(content: string, base = ""): GitignoreRule[] {
+  const rules: GitignoreRule[] = [];
+  for (const rawLine of content.split(/\r?\n/)) {
+    // Trailing spaces are dropped unless escaped
+    let line = rawLine.replace(/(?<!\\)\s+$/, "");
+    if (!line || line.startsWith("#")) continue;
+
+    let negated = false;
+    if (line.startsWith("!")) {
+      negated = true;
+      line = line.slice(1);
+    } else if (line.startsWith("\\!") || line.startsWith("\\#")) {
+      line = line.slice(1);
+    }
+
+    let directoryOnly = false;
+    if (line.endsWith("/")) {
+      directoryOnly = true;
+      line = line.replace(/\/+$/, "");
+    }
+    if (!line) continue;
+
+    const anchored = line.includes("/");
+    const pattern = line.startsWith("/") ? line.slice(1) : line;
+    rules.push({ base, pattern: rawLine.trim(), negated, directoryOnly, regex: patternToRegExp(pattern, anchored) });
+  }
+  return rules;
+}
+
+function findRepositoryRoot(dir: string): string | null {
+  let current = dir;
+  while (true) {
+    if (existsSync(join(current, ".git"))) return current;
+    const parent = dirname(current);
+    if (parent === current) return null;
+    current = parent;
+  }
+}
+
+function readRules(file: string, base: string): GitignoreRule[] {
+  try {
+    return parseGitignore(readFileSync(file, "utf8"), base);
+  } catch {
+    return [];
+  }
+}
+
+/**
+ * Answers whether a path is ignored by the .gitignore files that apply to it. When the indexed
+ * directory sits inside a git checkout, the .gitignore files between the checkout root and the
+ * directory apply too, as does `.git/info/exclude`. Files are read lazily and cached per directory.
+ */
+export class GitignoreMatcher {
+  private readonly root: string;
+  private readonly rulesByDir = new Map<string, GitignoreRule[]>();
+  private readonly excludeRules: GitignoreRule[];
+
+  constructor(directory: string) {
+    const dir = resolve(directory);
+    this.root = findRepositoryRoot(dir) ?? dir;
+    this.excludeRules = readRules(join(this.root, ".git", "info", "exclude"), "");
+  }
+
+  private rulesFor(relDir: string): GitignoreRule[] {
+    let rules = this.rulesByDir.get(relDir);
+    if (!rules) {
+      rules = readRules(join(this.root, relDir, ".gitignore"), relDir);
+      this.rulesByDir.set(relDir, rules);
+    }
+    return rules;
+  }
+
+  /**
+   * Whether `path` (absolute, or relative to the working directory) is ignored. Only the path
+   * itself is tested; its parent directories are assumed to have been checked already.
+   */
+  isIgnored(path: string, isDirectory = false): boolean {
+    const relPath = toPosix(relative(this.root, resolve(path)));
+    if (relPath === "" || relPath.startsWith("..")) return false;
+
+    const segments = relPath.split("/");
+    const layers = [this.excludeRules];
+    for (let depth = 0; depth < segments.length; depth++) {
+      layers.push(this.rulesFor(segments.slice(0, depth).join("/")));
+    }
+
+    let ignored = false;
+    for (const rules of layers) {
+      for (const rule of rules) {
+        if (rule.directoryOnly && !isDirectory) continue;
+        const candidate = rule.base ? relPath.slice(rule.base.length + 1) : relPath;
+        if (rule.regex.test(candidate)) ignored = !rule.negated;
+      }
+    }
+    return ignored;
+  }
+}
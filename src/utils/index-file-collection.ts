@@ -1,6 +1,8 @@
-import { readdirSync } from "node:fs";
-import { join, relative, resolve } from "node:path";
+import { closeSync, openSync, readdirSync, readSync, realpathSync, statSync } from "node:fs";
+import { isAbsolute, join, relative, resolve } from "node:path";
+import { isIndexableSourceFile } from "../parsers/content-language.js";
 import { isFileSupported } from "../parsers/language-configs.js";
+import { GitignoreMatcher } from "./gitignore.js";
 
 export const DEFAULT_INDEX_EXCLUDE_PATTERNS = [
   "node_modules/**",
@@ -23,6 +25,13 @@ export const DEFAULT_INDEX_EXCLUDE_PATTERNS = [
   "vendor/**",
   "target/**",
   ".gradle/**",
+  ".build/**",
+  ".swiftpm/**",
+  "DerivedData/**",
+  "Pods/**",
+  ".bsp/**",
+  ".bloop/**",
+  ".metals/**",
   ".idea/**",
   ".vscode/**",
   "**/test/**",
@@ -50,6 +59,50 @@ export const DEFAULT_INDEX_EXCLUDE_PATTERNS = [
   "Thumbs.db",
 ] as const;
 
+export const DEFAULT_MAX_FILE_SIZE_BYTES = 1024 * 1024;
+
+/** Bytes read from the start of a file when looking for a NUL byte or a generated-code header */
+const BINARY_SNIFF_BYTES = 8192;
+
+/** Output of code generators and minifiers, by file name */
+export const GENERATED_FILE_PATTERNS = [
+  "*.pb.go",
+  "*.pb.gw.go",
+  "*_generated.go",
+  "zz_generated.*.go",
+  "*.pb.cc",
+  "*.pb.h",
+  "*_pb2.py",
+  "*_pb2_grpc.py",
+  "*_pb.js",
+  "*_pb.d.ts",
+  "*.g.dart",
+  "*.freezed.dart",
+  "*.designer.cs",
+  "*.g.cs",
+  "*.min.js",
+  "*.min.css",
+  "**/__generated__/**",
+] as const;
+
+/**
+ * Header comments generators write into their output. Only the first lines of a file are checked,
+ * so a handwritten file that merely mentions the convention further down is kept.
+ */
+const GENERATED_HEADER_PATTERNS: RegExp[] = [
+  // The Go convention: https://go.dev/s/generatedcode
+  /^\/\/ Code generated .* DO NOT EDIT\.$/,
+  /@generated\b/,
+  /<auto-generated[\s>]/i,
+  /\b(?:auto-?generated|generated by|code generated)\b.*\bdo not (?:edit|modify)\b/i,
+  /\bdo not (?:edit|modify)\b.*\b(?:auto-?generated|generated by)\b/i,
+];
+const GENERATED_HEADER_LINES = 20;
+
+/** Script and style sheets whose lines run this long on average are taken for minified bundles */
+const MINIFIABLE_EXTENSIONS = [".js", ".mjs", ".cjs", ".css"];
+const MINIFIED_AVERAGE_LINE_LENGTH = 500;
+
 export const DEFAULT_INDEX_PRUNE_DIR_NAMES = [
   "node_modules",
   ".git",
@@ -70,6 +123,13 @@ export const DEFAULT_INDEX_PRUNE_DIR_NAMES = [
   "vendor",
   "target",
   ".gradle",
+  ".build",
+  ".swiftpm",
+  "DerivedData",
+  "Pods",
+  ".bsp",
+  ".bloop",
+  ".metals",
   ".idea",
   ".vscode",
   ".memory_bank",
@@ -77,14 +137,139 @@ export const DEFAULT_INDEX_PRUNE_DIR_NAMES = [
   "temp",
 ] as const;
 
+export type IndexSkipReason = "too_large" | "binary" | "unreadable" | "parse_timeout" | "generated";
+
+/** What gave a `generated` file away: its name, a generator's header comment, or minified lines */
+export type GeneratedFileDetection = {
+  detectedBy: "path" | "header" | "minified";
+  /** The file name pattern, the header line, or the average line length */
+  evidence: string;
+};
+
+export type SkippedIndexFile = {
+  filePath: string;
+  reason: IndexSkipReason;
+  sizeBytes?: number;
+  /** The timeout a `parse_timeout` file ran into */
+  timeoutMs?: number;
+  generated?: GeneratedFileDetection;
+};
+
+export type SymlinkPolicy = {
+  /** Descend into symlinked directories (default false); symlinked files are always considered */
+  followSymlinks?: boolean;
+  /** Accept links whose target lies outside the walked root (default false) */
+  allowOutsideRoot?: boolean;
+};
+
+export type SymlinkStats = {
+  followed: number;
+  /** Directory links left alone because following is off */
+  skipped: number;
+  outsideRoot: number;
+  broken: number;
+  /** Directories reached again by another path, e.g. a link to one of their parents */
+  cycles: number;
+  /** Files reached through a link whose real path was already listed */
+  duplicates: number;
+};
+
+function realpathOrSelf(path: string): string {
+  try {
+    return realpathSync(path);
+  } catch {
+    return resolve(path);
+  }
+}
+
+/**
+ * Symlink rules shared by the directory walks. Every directory a walk enters and every file it
+ * lists is recorded by real path, so a tree reached twice (a cycle, or one directory linked from
+ * two places) is walked once and a file is never listed next to a link to it. Walks should visit
+ * link targets only after the rest of the tree, so files keep their real paths where they have one.
+ */
+export class SymlinkGuard {
+  readonly stats: SymlinkStats = { followed: 0, skipped: 0, outsideRoot: 0, broken: 0, cycles: 0, duplicates: 0 };
+  private readonly realRoot: string;
+  private readonly visitedDirs = new Set<string>();
+  private readonly listedFiles = new Set<string>();
+
+  constructor(
+    root: string,
+    private readonly policy: SymlinkPolicy = {},
+  ) {
+    this.realRoot = realpathOrSelf(root);
+  }
+
+  /** Real path of the walked root, the starting point for real paths of entries below it */
+  get rootRealPath(): string {
+    return this.realRoot;
+  }
+
+  /** False when the directory at `realPath` was walked before */
+  enterDirectory(realPath: string): boolean {
+    if (this.visitedDirs.has(realPath)) {
+      this.stats.cycles += 1;
+      return false;
+    }
+    this.visitedDirs.add(realPath);
+    return true;
+  }
+
+  /** False when the file at `realPath` is already listed under another path */
+  claimFile(realPath: string, viaLink = false): boolean {
+    if (this.listedFiles.has(realPath)) {
+      if (viaLink) this.stats.duplicates += 1;
+      return false;
+    }
+    this.listedFiles.add(realPath);
+    return true;
+  }
+
+  /** The target of the link at `path` when the policy lets the walk use it; null to skip the link */
+  resolveLink(path: string): { realPath: string; isDirectory: boolean } | null {
+    let realPath: string;
+    let isDirectory: boolean;
+    try {
+      realPath = realpathSync(path);
+      isDirectory = statSync(realPath).isDirectory();
+    } catch {
+      this.stats.broken += 1;
+      return null;
+    }
+    const rel = relative(this.realRoot, realPath);
+    if (!this.policy.allowOutsideRoot && (rel.startsWith("..") || isAbsolute(rel))) {
+      this.stats.outsideRoot += 1;
+      return null;
+    }
+    if (isDirectory && !this.policy.followSymlinks) {
+      this.stats.skipped += 1;
+      return null;
+    }
+    if (isDirectory) this.stats.followed += 1;
+    return { realPath, isDirectory };
+  }
+}
+
 type IndexFileCollectionStats = {
   scannedFiles: number;
   includedFiles: number;
   excludedByPatterns: number;
+  excludedByGitignore: number;
   excludedByPruneDir: number;
-  skippedSymlinks: number;
+  symlinks: SymlinkStats;
   skippedUnreadableDirs: number;
   unsupportedExtensions: number;
+  /** Included although their extension is not recognised, by detectLanguageByContent */
+  detectedByContent: number;
+};
+
+export type IndexFileCollectionOptions = {
+  /** Skip paths ignored by the applicable .gitignore files (default true) */
+  respectGitignore?: boolean;
+  symlinks?: SymlinkPolicy;
+  /** Also include files whose extension is unknown but whose content is in a supported language */
+  detectLanguageByContent?: boolean;
 };
 
 export type IndexFileCollectionResult = {
@@ -124,34 +309,93 @@ function globPatternToRegExp(pattern: string): RegExp {
   return new RegExp(`^${source}$`);
 }
 
-export function collectIndexableFiles(targetDir: string, excludePatterns: string[]): IndexFileCollectionResult {
-  const dir = resolve(targetDir);
-  const regexes = excludePatterns
+function compileExcludePatterns(excludePatterns: string[]): RegExp[] {
+  return excludePatterns
     .filter((pattern) => pattern && pattern !== "__batch_processing_enabled__")
     .map((pattern) => globPatternToRegExp(pattern));
+}
+
+function matchesExclude(regexes: RegExp[], relPath: string, isDir: boolean): boolean {
+  const normalized = normalizeGlobPath(relPath);
+  const candidate = isDir && normalized.length > 0 && !normalized.endsWith("/") ? `${normalized}/` : normalized;
+  return regexes.some((re) => re.test(candidate) || re.test(normalized));
+}
 
+function isPrunedDirName(name: string): boolean {
+  const lower = name.toLowerCase();
+  return DEFAULT_INDEX_PRUNE_DIR_NAMES.includes(lower as (typeof DEFAULT_INDEX_PRUNE_DIR_NAMES)[number]);
+}
+
+/**
+ * Single-path version of the rules applied by collectIndexableFiles, for callers that learn about
+ * paths one at a time (e.g. a filesystem watcher) instead of walking the tree. With `isDirectory`
+ * the path is checked as a directory the walk would descend into. .gitignore files are read once
+ * per filter, so edits to them apply to filters created afterwards.
+ */
+export function createIndexPathFilter(
+  targetDir: string,
+  excludePatterns: string[],
+  options: IndexFileCollectionOptions = {},
+): (path: string, isDirectory?: boolean) => boolean {
+  const dir = resolve(targetDir);
+  const regexes = compileExcludePatterns(excludePatterns);
+  const gitignore = options.respectGitignore === false ? null : new GitignoreMatcher(dir);
+
+  return (path: string, isDirectory = false) => {
+    const relPath = normalizeGlobPath(relative(dir, resolve(dir, path)));
+    if (relPath === "" || relPath.startsWith("..")) return false;
+
+    const segments = relPath.split("/");
+    const dirDepth = isDirectory ? segments.length : segments.length - 1;
+    for (let i = 0; i < dirDepth; i++) {
+      if (isPrunedDirName(segments[i]!)) return false;
+      const dirPath = segments.slice(0, i + 1).join("/");
+      if (matchesExclude(regexes, dirPath, true)) return false;
+      if (gitignore?.isIgnored(join(dir, dirPath), true)) return false;
+    }
+    if (isDirectory) return true;
+    if (matchesExclude(regexes, relPath, false)) return false;
+    if (gitignore?.isIgnored(join(dir, relPath), false)) return false;
+
+    return isIndexableSourceFile(path, options.detectLanguageByContent === true);
+  };
+}
+
+export function collectIndexableFiles(
+  targetDir: string,
+  excludePatterns: string[],
+  options: IndexFileCollectionOptions = {},
+): IndexFileCollectionResult {
+  const dir = resolve(targetDir);
+  const regexes = compileExcludePatterns(excludePatterns);
+  const gitignore = options.respectGitignore === false ? null : new GitignoreMatcher(dir);
+
+  const guard = new SymlinkGuard(dir, options.symlinks);
   const stats: IndexFileCollectionStats = {
     scannedFiles: 0,
     includedFiles: 0,
     excludedByPatterns: 0,
+    excludedByGitignore: 0,
     excludedByPruneDir: 0,
-    skippedSymlinks: 0,
+    symlinks: guard.stats,
     skippedUnreadableDirs: 0,
     unsupportedExtensions: 0,
+    detectedByContent: 0,
   };
 
-  const shouldExclude = (relPath: string, isDir: boolean): boolean => {
-    const normalized = normalizeGlobPath(relPath);
-    const candidate = isDir && normalized.length > 0 && !normalized.endsWith("/") ? `${normalized}/` : normalized;
-    return regexes.some((re) => re.test(candidate) || re.test(normalized));
-  };
+  const shouldExclude = (relPath: string, isDir: boolean): boolean => matchesExclude(regexes, relPath, isDir);
 
   const files: string[] = [];
-  const stack: string[] = [dir];
+  // Each directory with its real path; linked ones wait until the rest of the tree is walked
+  const stack: Array<[string, string]> = [[dir, guard.rootRealPath]];
+  const linkedDirs: Array<[string, string]> = [];
+  const linkedFiles: Array<[string, string]> = [];
 
-  while (stack.length > 0) {
-    const current = stack.pop();
-    if (!current) continue;
+  while (stack.length > 0 || linkedDirs.length > 0) {
+    const next = stack.pop() ?? linkedDirs.shift();
+    if (!next) continue;
+    const [current, realCurrent] = next;
+    if (!guard.enterDirectory(realCurrent)) continue;
 
     let entries: Array<{
       name: string;
@@ -170,16 +414,22 @@ export function collectIndexableFiles(targetDir: string, excludePatterns: string
       if (!entry?.name) continue;
       const fullPath = join(current, entry.name);
       const relPath = normalizeGlobPath(relative(dir, fullPath));
+      if (relPath === "" || relPath.startsWith("..")) continue;
 
-      if (entry.isSymbolicLink()) {
-        stats.skippedSymlinks += 1;
+      let isDirectory = entry.isDirectory();
+      let realPath = join(realCurrent, entry.name);
+      const isLink = entry.isSymbolicLink();
+      if (isLink) {
+        const target = guard.resolveLink(fullPath);
+        if (!target) continue;
+        isDirectory = target.isDirectory;
+        realPath = target.realPath;
+      } else if (!isDirectory && !entry.isFile()) {
         continue;
       }
-      if (relPath === "" || relPath.startsWith("..")) continue;
 
-      if (entry.isDirectory()) {
-        const lower = entry.name.toLowerCase();
-        if (DEFAULT_INDEX_PRUNE_DIR_NAMES.includes(lower as (typeof DEFAULT_INDEX_PRUNE_DIR_NAMES)[number])) {
+      if (isDirectory) {
+        if (isPrunedDirName(entry.name)) {
           stats.excludedByPruneDir += 1;
           continue;
         }
@@ -187,27 +437,119 @@ export function collectIndexableFiles(targetDir: string, excludePatterns: string
           stats.excludedByPatterns += 1;
           continue;
         }
-        stack.push(fullPath);
+        if (gitignore?.isIgnored(fullPath, true)) {
+          stats.excludedByGitignore += 1;
+          continue;
+        }
+        (isLink ? linkedDirs : stack).push([fullPath, realPath]);
         continue;
       }
 
-      if (!entry.isFile()) continue;
       stats.scannedFiles += 1;
 
       if (shouldExclude(relPath, false)) {
         stats.excludedByPatterns += 1;
         continue;
       }
+      if (gitignore?.isIgnored(fullPath, false)) {
+        stats.excludedByGitignore += 1;
+        continue;
+      }
 
       if (!isFileSupported(fullPath)) {
-        stats.unsupportedExtensions += 1;
-        continue;
+        if (!isIndexableSourceFile(fullPath, options.detectLanguageByContent === true)) {
+          stats.unsupportedExtensions += 1;
+          continue;
+        }
+        stats.detectedByContent += 1;
       }
 
+      if (isLink) {
+        linkedFiles.push([fullPath, realPath]);
+        continue;
+      }
+      if (!guard.claimFile(realPath)) continue;
       files.push(fullPath);
       stats.includedFiles += 1;
     }
   }
 
+  // Linked files go last, so a file also reachable by its real path is listed under that path
+  for (const [fullPath, realPath] of linkedFiles) {
+    if (!guard.claimFile(realPath, true)) continue;
+    files.push(fullPath);
+    stats.includedFiles += 1;
+  }
+
   return { files, stats };
 }
+
+const generatedPathRegexes = GENERATED_FILE_PATTERNS.map((pattern) => [pattern, globPatternToRegExp(pattern)] as const);
+
+/**
+ * Whether a file looks like generated or minified code, from its path and the text at its start
+ * (`head`, a few KB are enough). Null when nothing says so.
+ */
+export function detectGeneratedFile(filePath: string, head: string): GeneratedFileDetection | null {
+  const normalized = normalizeGlobPath(filePath);
+  for (const [pattern, re] of generatedPathRegexes) {
+    if (re.test(normalized)) return { detectedBy: "path", evidence: pattern };
+  }
+
+  const lines = head.split(/\r?\n/);
+  for (const line of lines.slice(0, GENERATED_HEADER_LINES)) {
+    const trimmed = line.trim();
+    if (GENERATED_HEADER_PATTERNS.some((re) => re.test(trimmed))) {
+      return { detectedBy: "header", evidence: trimmed.slice(0, 120) };
+    }
+  }
+
+  const lower = normalized.toLowerCase();
+  if (MINIFIABLE_EXTENSIONS.some((ext) => lower.endsWith(ext)) && head.length >= 1024) {
+    // A line cut off at the end of `head` counts as a whole one, which only lowers the average
+    const average = Math.round(head.length / lines.length);
+    if (average >= MINIFIED_AVERAGE_LINE_LENGTH) {
+      return { detectedBy: "minified", evidence: `average line length ${average}` };
+    }
+  }
+  return null;
+}
+
+/**
+ * Reason a discovered file should not be parsed, or null when it can be. Files larger than
+ * `maxFileSizeBytes` (0 disables the limit) are skipped before being read, and a NUL byte in the
+ * first few KB marks a file as binary whatever its extension says. With `skipGenerated`, files
+ * detectGeneratedFile recognises as generated or minified are skipped too.
+ */
+export function checkIndexableContent(
+  filePath: string,
+  maxFileSizeBytes: number = DEFAULT_MAX_FILE_SIZE_BYTES,
+  skipGenerated = false,
+): SkippedIndexFile | null {
+  let sizeBytes: number;
+  try {
+    sizeBytes = statSync(filePath).size;
+  } catch {
+    return { filePath, reason: "unreadable" };
+  }
+  if (maxFileSizeBytes > 0 && sizeBytes > maxFileSizeBytes) {
+    return { filePath, reason: "too_large", sizeBytes };
+  }
+
+  let fd: number | null = null;
+  let head: Buffer;
+  try {
+    fd = openSync(filePath, "r");
+    const buffer = Buffer.alloc(Math.min(BINARY_SNIFF_BYTES, sizeBytes));
+    head = buffer.subarray(0, readSync(fd, buffer, 0, buffer.length, 0));
+    if (head.includes(0)) return { filePath, reason: "binary", sizeBytes };
+  } catch {
+    return { filePath, reason: "unreadable", sizeBytes };
+  } finally {
+    if (fd !== null) closeSync(fd);
+  }
+
+  const generated = skipGenerated ? detectGeneratedFile(filePath, head.toString("utf8")) : null;
+  if (generated) return { filePath, reason: "generated", sizeBytes, generated };
+  return null;
+}
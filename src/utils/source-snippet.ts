@@ -11,6 +11,8 @@ export function extractSourceSnippetFromText(params: {
   endLine: number;
   contextLines?: number;
   maxBytes?: number;
+  /** Keep at most this many lines from the start of the snippet */
+  maxLines?: number;
 }): SourceSnippetResult {
   const { text } = params;
   const contextLines = Math.max(0, Math.min(200, Number(params.contextLines ?? 5) || 0));
@@ -23,7 +25,10 @@ export function extractSourceSnippetFromText(params: {
   const entityEnd = Math.max(entityStart, Math.min(totalLines, Number(params.endLine) || entityStart));
 
   const snippetStart = Math.max(1, entityStart - contextLines);
-  const snippetEnd = Math.min(totalLines, entityEnd + contextLines);
+  const fullEnd = Math.min(totalLines, entityEnd + contextLines);
+  const maxLines = params.maxLines ? Math.max(1, Math.floor(params.maxLines)) : Number.POSITIVE_INFINITY;
+  const snippetEnd = Math.min(fullEnd, snippetStart + maxLines - 1);
+  const cutByLines = snippetEnd < fullEnd;
 
   const rawSnippet = lines.slice(snippetStart - 1, snippetEnd).join("\n");
   const rawBytes = Buffer.byteLength(rawSnippet, "utf8");
@@ -33,7 +38,7 @@ export function extractSourceSnippetFromText(params: {
       snippet: rawSnippet,
       snippetRange: { startLine: snippetStart, endLine: snippetEnd },
       entityRange: { startLine: entityStart, endLine: entityEnd },
-      truncated: false,
+      truncated: cutByLines,
     };
   }
 
@@ -0,0 +1,161 @@
+/**
+ * Structured, leveled logging for the server's modules
+ *
+ * Every record carries a level and the module that wrote it (parser, embedding, graph, indexer,
+ * orchestrator, server) and is written to stderr: stdout belongs to the MCP JSON-RPC stream.
+ * Levels are set for all modules and per module, from the `logging` section of the YAML config
+ * or from the environment:
+ *
+ *   LOG_LEVEL=info LOG_MODULES=parser=debug,embedding=warn LOG_FORMAT=json
+ *
+ * The rotated file logger (`logger.ts`) is separate and keeps recording MCP traffic.
+ */
+
+export type LogLevelName = "error" | "warn" | "info" | "debug";
+
+export type LogModule = "parser" | "embedding" | "graph" | "indexer" | "orchestrator" | "server";
+
+export type LogFields = Record<string, unknown>;
+
+export type LogRecord = {
+  time: string;
+  level: LogLevelName;
+  module: LogModule;
+  msg: string;
+  fields: LogFields;
+};
+
+/** Receives every record that passes the level filter, with the line formatted for it */
+export type LogSink = (record: LogRecord, line: string) => void;
+
+export interface LogSettings {
+  /** Level for modules not listed in `modules` */
+  level?: LogLevelName;
+  format?: "json" | "text";
+  modules?: Partial<Record<string, LogLevelName>>;
+  /** false drops records instead of writing them to stderr (a sink set with setLogSink still runs) */
+  enableConsole?: boolean;
+}
+
+export interface ModuleLog {
+  error(msg: string, fields?: LogFields): void;
+  warn(msg: string, fields?: LogFields): void;
+  info(msg: string, fields?: LogFields): void;
+  debug(msg: string, fields?: LogFields): void;
+  /** Whether `level` is written, to skip building fields that would be dropped */
+  enabled(level: LogLevelName): boolean;
+}
+
+const RANK: Record<LogLevelName, number> = { error: 0, warn: 1, info: 2, debug: 3 };
+
+function isLevel(value: unknown): value is LogLevelName {
+  return typeof value === "string" && value in RANK;
+}
+
+/** `parser=debug,embedding=warn` → `{ parser: "debug", embedding: "warn" }`; unknown levels are skipped */
+export function parseModuleLevels(spec: string | undefined): Record<string, LogLevelName> {
+  const levels: Record<string, LogLevelName> = {};
+  for (const part of (spec ?? "").split(",")) {
+    const [module, level] = part.split(/[=:]/).map((s) => s.trim().toLowerCase());
+    if (module && isLevel(level)) levels[module] = level;
+  }
+  return levels;
+}
+
+function settingsFromEnv(env: NodeJS.ProcessEnv): Required<LogSettings> {
+  const level = env.LOG_LEVEL?.toLowerCase();
+  return {
+    level: isLevel(level) ? level : "info",
+    format: env.LOG_FORMAT?.toLowerCase() === "json" ? "json" : "text",
+    modules: parseModuleLevels(env.LOG_MODULES),
+    enableConsole: env.LOG_ENABLE_CONSOLE !== "false",
+  };
+}
+
+let settings = settingsFromEnv(process.env);
+let sink: LogSink | null = null;
+
+/** Apply the `logging` config section; fields left undefined keep their current value */
+export function configureLogging(next: LogSettings): void {
+  settings = {
+    level: isLevel(next.level) ? next.level : settings.level,
+    format: next.format === "json" || next.format === "text" ? next.format : settings.format,
+    modules: next.modules ? { ...settings.modules, ...next.modules } : settings.modules,
+    enableConsole: next.enableConsole ?? settings.enableConsole,
+  };
+}
+
+/** Route records to `next` as well as stderr; null removes it */
+export function setLogSink(next: LogSink | null): void {
+  sink = next;
+}
+
+/** Back to the environment's settings, without a sink (tests) */
+export function resetLogging(): void {
+  settings = settingsFromEnv(process.env);
+  sink = null;
+}
+
+function levelOf(module: LogModule): LogLevelName {
+  const level = settings.modules[module];
+  return isLevel(level) ? level : settings.level;
+}
+
+function serializable(fields: LogFields): LogFields {
+  const out: LogFields = {};
+  for (const [key, value] of Object.entries(fields)) {
+    if (value === undefined) continue;
+    out[key] = value instanceof Error ? { message: value.message, stack: value.stack } : value;
+  }
+  return out;
+}
+
+function textValue(value: unknown): string {
+  if (value !== null && typeof value === "object") {
+    const message = (value as { message?: unknown }).message;
+    return typeof message === "string" && !Array.isArray(value) ? JSON.stringify(message) : JSON.stringify(value);
+  }
+  const text = String(value);
+  return /[\s="]/.test(text) || text === "" ? JSON.stringify(text) : text;
+}
+
+export function formatLogRecord(record: LogRecord, format: "json" | "text"): string {
+  if (format === "json") {
+    const { time, level, module, msg } = record;
+    return JSON.stringify({ time, level, module, msg, ...record.fields });
+  }
+  const fields = Object.entries(record.fields).map(([key, value]) => `${key}=${textValue(value)}`);
+  return [record.time, record.level.toUpperCase().padEnd(5), `[${record.module}]`, record.msg, ...fields].join(" ");
+}
+
+function write(module: LogModule, level: LogLevelName, msg: string, fields?: LogFields): void {
+  if (RANK[level] > RANK[levelOf(module)]) return;
+  const record: LogRecord = { time: new Date().toISOString(), level, module, msg, fields: serializable(fields ?? {}) };
+  const line = formatLogRecord(record, settings.format);
+  if (settings.enableConsole) {
+    try {
+      process.stderr.write(`${line}\n`);
+    } catch {
+      // stderr closed by the client; nothing left to report to
+    }
+  }
+  sink?.(record, line);
+}
+
+const logs = new Map<LogModule, ModuleLog>();
+
+/** Logger for one module; levels are looked up on every call, so later configuration applies */
+export function getLog(module: LogModule): ModuleLog {
+  let log = logs.get(module);
+  if (!log) {
+    log = {
+      error: (msg, fields) => write(module, "error", msg, fields),
+      warn: (msg, fields) => write(module, "warn", msg, fields),
+      info: (msg, fields) => write(module, "info", msg, fields),
+      debug: (msg, fields) => write(module, "debug", msg, fields),
+      enabled: (level) => RANK[level] <= RANK[levelOf(module)],
+    };
+    logs.set(module, log);
+  }
+  return log;
+}
@@ -0,0 +1,98 @@
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { BaseAgent } from "../../src/agents/base.js";
+import { ConductorOrchestrator } from "../../src/agents/conductor-orchestrator.js";
+import type { AgentTask } from "../../src/types/agent.js";
+import { AgentType } from "../../src/types/agent.js";
+import { TaskCancelledError } from "../../src/types/errors.js";
+import { throwIfCancelled } from "../../src/utils/cancellation.js";
+
+class SlowDevAgent extends BaseAgent {
+  constructor() {
+    super(AgentType.DEV, { maxConcurrency: 1, memoryLimit: 4096, priority: 5 });
+  }
+
+  protected async onInitialize(): Promise<void> {}
+  protected async onShutdown(): Promise<void> {}
+
+  protected canProcessTask(task: AgentTask): boolean {
+    return task.type === "index";
+  }
+
+  protected async processTask(task: AgentTask): Promise<unknown> {
+    await new Promise((resolve) => setTimeout(resolve, 100));
+    // Where a real agent would start its next batch
+    throwIfCancelled(task.signal, task.id);
+    return { indexed: task.id };
+  }
+
+  protected async handleMessage(): Promise<void> {}
+}
+
+function indexTask(id: string): AgentTask {
+  return { id, type: "index", priority: 8, payload: { directory: "/tmp/project" }, createdAt: Date.now() };
+}
+
+describe("ConductorOrchestrator delegation", () => {
+  let conductor: ConductorOrchestrator;
+  let agent: SlowDevAgent;
+
+  beforeEach(async () => {
+    conductor = new ConductorOrchestrator();
+    await conductor.initialize();
+    agent = new SlowDevAgent();
+    await agent.initialize();
+  });
+
+  afterEach(async () => {
+    await agent.shutdown();
+    await conductor.shutdown();
+  });
+
+  it("waits for a busy agent instead of returning a queued receipt", async () => {
+    conductor.register(agent);
+    const busy = agent.process(indexTask("direct"));
+
+    const result = (await conductor.process(indexTask("t1"))) as any;
+    await busy;
+
+    expect(result.results).toEqual([{ indexed: "t1-index" }]);
+    expect(conductor.getTaskResult("t1-index")).toMatchObject({ status: "completed", agent: "dev-agent" });
+  });
+
+  it("runs queued subtasks once the agent registers and keeps their result", async () => {
+    const result = (await conductor.process(indexTask("t2"))) as any;
+    expect(result.results[0]).toMatchObject({ status: "queued", taskId: "t2-index" });
+    expect(conductor.getTaskResult("t2-index")?.status).toBe("queued");
+
+    conductor.register(agent);
+    await new Promise((resolve) => setTimeout(resolve, 200));
+
+    expect(conductor.getTaskResult("t2-index")).toMatchObject({
+      status: "completed",
+      result: { indexed: "t2-index" },
+    });
+  });
+
+  it("drops a cancelled queued subtask so it never runs", async () => {
+    await conductor.process(indexTask("t3"));
+
+    expect(conductor.cancelTask("t3-index")).toEqual({ taskId: "t3-index", cancelled: true, status: "cancelled" });
+    conductor.register(agent);
+    await new Promise((resolve) => setTimeout(resolve, 200));
+
+    expect(conductor.getTaskResult("t3-index")?.status).toBe("cancelled");
+    expect(agent.getMetrics().tasksProcessed).toBe(0);
+    expect(conductor.cancelTask("unknown")).toBeUndefined();
+  });
+
+  it("stops a running task and records its subtask as cancelled", async () => {
+    conductor.register(agent);
+    const run = conductor.process(indexTask("t4"));
+    await new Promise((resolve) => setTimeout(resolve, 20));
+
+    expect(conductor.cancelTask("t4")).toMatchObject({ cancelled: true, status: "cancelling" });
+    await expect(run).rejects.toBeInstanceOf(TaskCancelledError);
+    expect(conductor.getTaskResult("t4-index")?.status).toBe("cancelled");
+    expect(conductor.cancelTask("t4-index")).toMatchObject({ cancelled: false, status: "cancelled" });
+  });
+});
@@ -18,7 +18,7 @@ import { afterEach, beforeEach, describe, expect, jest, test } from "@jest/globa
 import { IndexerAgent } from "../../src/agents/indexer-agent.js";
 import { knowledgeBus } from "../../src/core/knowledge-bus.js";
 import { resetCacheManager } from "../../src/storage/cache-manager.js";
-import { resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
 import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
 import { AgentStatus } from "../../src/types/agent.js";
 import type { ParsedEntity, ParseResult } from "../../src/types/parser.js";
@@ -191,6 +191,93 @@ describe("IndexerAgent", () => {
       expect(result.relationships.length).toBeGreaterThan(0);
     });
 
+    test("should resolve provided relationships by parser-assigned ids", async () => {
+      const filePath = "/test/lib.rs";
+      const entities: ParsedEntity[] = [
+        { ...createMockParsedEntity("Widget", "struct"), id: `${filePath}:struct:Widget` },
+        {
+          ...createMockParsedEntity("Render", "trait"),
+          id: `${filePath}:trait:Render`,
+          location: {
+            start: { line: 12, column: 0, index: 120 },
+            end: { line: 14, column: 0, index: 160 },
+          },
+        },
+      ];
+
+      const result = await agent.indexEntities(entities, filePath, [
+        { from: `${filePath}:struct:Widget`, to: `${filePath}:trait:Render`, type: "implements" },
+      ]);
+      expect(result.relationshipsCreated).toBe(1);
+
+      const query: GraphQuery = {
+        type: "relationship",
+        filters: { relationshipType: RelationType.IMPLEMENTS },
+      };
+      const graph = await agent.queryGraph(query);
+      expect(graph.relationships).toHaveLength(1);
+      expect(graph.relationships[0]?.toId.startsWith("external:")).toBe(false);
+    });
+
+    test("should link decorated entities to imported decorators", async () => {
+      const filePath = "/test/service.ts";
+      const entities: ParsedEntity[] = [
+        {
+          ...createMockParsedEntity("@angular/core", "import"),
+          importData: { source: "@angular/core", specifiers: [{ local: "Injectable", imported: "Injectable" }] },
+        },
+        {
+          ...createMockParsedEntity("UserService", "class"),
+          decorators: [{ name: "Injectable", arguments: [], line: 3 }, { name: "Unknown", line: 4 }],
+        },
+      ];
+
+      await agent.indexEntities(entities, filePath);
+
+      const graph = await agent.queryGraph({
+        type: "relationship",
+        filters: { relationshipType: RelationType.DECORATED_BY },
+      });
+      expect(graph.relationships).toHaveLength(1);
+      expect(graph.relationships[0]?.metadata?.decorator).toBe("Injectable");
+    });
+
+    test("should link React components to the components they render and the hooks they use", async () => {
+      const filePath = "/test/Board.tsx";
+      const site = (name: string, line: number) => ({ name, line, column: 4 });
+      const entities: ParsedEntity[] = [
+        {
+          ...createMockParsedEntity("./Canvas", "import"),
+          importData: { source: "./Canvas", specifiers: [{ local: "Canvas", imported: "Canvas" }] },
+        },
+        { ...createMockParsedEntity("useBoard", "function"), metadata: { isHook: true } },
+        {
+          ...createMockParsedEntity("Board", "function"),
+          metadata: { componentKind: "function", propsType: "BoardProps" },
+          renders: [site("Canvas", 5)],
+          hooks: [site("useBoard", 3)],
+        },
+      ];
+
+      await agent.indexEntities(entities, filePath);
+
+      const renders = await agent.queryGraph({
+        type: "relationship",
+        filters: { relationshipType: RelationType.RENDERS },
+      });
+      // The imported component stays a placeholder until the cross-file pass finds `./Canvas`
+      const [canvas] = (await agent.queryGraph({ type: "entity", filters: { name: "Canvas" } })).entities;
+      expect(canvas?.filePath).toBe("external://./Canvas");
+      expect(renders.relationships.map((r) => r.toId)).toEqual([canvas?.id]);
+
+      const hooks = await agent.queryGraph({
+        type: "relationship",
+        filters: { relationshipType: RelationType.USES_HOOK },
+      });
+      const [useBoard] = (await agent.queryGraph({ type: "entity", filters: { name: "useBoard" } })).entities;
+      expect(hooks.relationships.map((r) => r.toId)).toEqual([useBoard?.id]);
+    });
+
     test("should update file info after indexing", async () => {
       const filePath = "/test/tracked-file.ts";
       const entities = [createMockParsedEntity("trackedFunc", "function")];
@@ -313,6 +400,112 @@ describe("IndexerAgent", () => {
       // Deletion should succeed even if entity doesn't exist
       expect(result.failed).toBe(0);
     });
+
+    test("should report entities dropped when a file is replaced", async () => {
+      const filePath = "/test/replace.ts";
+      await agent.indexEntities(
+        [createMockParsedEntity("kept", "function"), createMockParsedEntity("dropped", "function")],
+        filePath,
+      );
+
+      const result = await agent.indexEntities([createMockParsedEntity("kept", "function")], filePath, undefined, {
+        replaceFile: true,
+      });
+
+      expect(result.entitiesRemoved).toBe(1);
+      const remaining = await agent.queryGraph({ type: "entity", filters: { filePath } });
+      expect(remaining.entities.map((e) => e.name)).toEqual(["kept"]);
+    });
+
+    test("should leave the graph as it was when the same file is indexed again", async () => {
+      const filePath = "/test/again.ts";
+      const at = (name: string, line: number): ParsedEntity => ({
+        ...createMockParsedEntity(name, "function"),
+        location: {
+          start: { line, column: 0, index: line * 10 },
+          end: { line: line + 2, column: 0, index: line * 10 + 9 },
+        },
+      });
+      const calls = [
+        { from: "main", to: "load", type: "calls", metadata: { line: 2 } },
+        { from: "main", to: "save", type: "calls", metadata: { line: 3 } },
+      ];
+      const index = (entities: ParsedEntity[], relationships: typeof calls) =>
+        agent.indexEntities(entities, filePath, relationships, { replaceFile: true });
+      const stored = async () => ({
+        entities: (await agent.queryGraph({ type: "entity", filters: { filePath } })).entities.length,
+        calls: (await agent.queryGraph({ type: "relationship", filters: { relationshipType: RelationType.CALLS } }))
+          .relationships.length,
+      });
+
+      const first = await index([at("main", 1), at("load", 10), at("save", 20)], calls);
+      expect(first).toMatchObject({ entitiesCreated: 3, entitiesUpdated: 0, relationshipsCreated: 2 });
+      const before = await stored();
+
+      const second = await index([at("main", 1), at("load", 10), at("save", 20)], calls);
+      expect(second).toMatchObject({
+        entitiesCreated: 0,
+        entitiesUpdated: 0,
+        entitiesUnchanged: 3,
+        entitiesRemoved: 0,
+        relationshipsCreated: 0,
+        relationshipsRemoved: 0,
+      });
+      expect(await stored()).toEqual(before);
+
+      // `save` moved and `main` stopped calling it
+      const third = await index([at("main", 1), at("load", 10), at("save", 30)], calls.slice(0, 1));
+      expect(third).toMatchObject({
+        entitiesCreated: 0,
+        entitiesUpdated: 1,
+        entitiesUnchanged: 2,
+        relationshipsRemoved: 1,
+      });
+      expect(await stored()).toEqual({ entities: 3, calls: 1 });
+    });
+
+    test("should derive entity ids from identity rather than offsets or checkout location", async () => {
+      const at = (name: string, index: number): ParsedEntity => ({
+        ...createMockParsedEntity(name, "function"),
+        language: "typescript",
+        location: { start: { line: 1, column: 0, index }, end: { line: 2, column: 0, index: index + 10 } },
+      });
+      const idsOf = async (filePath: string) =>
+        (await agent.queryGraph({ type: "entity", filters: { filePath } })).entities
+          .sort((a, b) => a.location.start.index - b.location.start.index)
+          .map((e) => e.id);
+
+      await agent.indexEntities([at("load", 0), at("load", 40), at("save", 80)], "/a/repo/src/io.ts", undefined, {
+        rootDir: "/a/repo",
+      });
+      const first = await idsOf("/a/repo/src/io.ts");
+      expect(new Set(first).size).toBe(3);
+
+      // Another checkout, with code inserted above every declaration
+      await agent.indexEntities([at("load", 25), at("load", 65), at("save", 105)], "/b/repo/src/io.ts", undefined, {
+        rootDir: "/b/repo",
+      });
+      expect(await idsOf("/b/repo/src/io.ts")).toEqual(first);
+    });
+
+    test("should find files whose relationships point into changed files", async () => {
+      await agent.indexEntities([createMockParsedEntity("helper", "function")], "/test/lib.ts");
+      await agent.indexEntities([createMockParsedEntity("main", "function")], "/test/app.ts");
+
+      const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+      const [helper] = (await agent.queryGraph({ type: "entity", filters: { filePath: "/test/lib.ts" } })).entities;
+      const [main] = (await agent.queryGraph({ type: "entity", filters: { filePath: "/test/app.ts" } })).entities;
+      await storage.insertRelationship({
+        id: "rel-main-helper",
+        fromId: main!.id,
+        toId: helper!.id,
+        type: RelationType.CALLS,
+      });
+
+      expect(await storage.findDependentFiles(["/test/lib.ts"])).toEqual(["/test/app.ts"]);
+      expect(await storage.findDependentFiles(["/test/lib.ts", "/test/app.ts"])).toEqual([]);
+      expect((await storage.listIndexedFiles()).map((f) => f.path)).toEqual(["/test/app.ts", "/test/lib.ts"]);
+    });
   });
 
   describe("Graph Queries", () => {
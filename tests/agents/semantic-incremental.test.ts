@@ -0,0 +1,244 @@
+import { existsSync, mkdtempSync, rmSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { join } from "node:path";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { SemanticAgent } from "../../src/agents/semantic-agent.js";
+import { knowledgeBus } from "../../src/core/knowledge-bus.js";
+import { VectorStore } from "../../src/semantic/vector-store.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { dropDeletedEntityHits } from "../../src/tools/keyword-search.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+
+const TEST_DB_PATH = "./data/test-semantic-incremental.db";
+const VECTOR_DB_PATH = "./data/test-semantic-incremental-vectors.db";
+const CLEANUP_PATHS = [
+  ...[TEST_DB_PATH, VECTOR_DB_PATH].flatMap((p) => [p, `${p}-shm`, `${p}-wal`]),
+  `${VECTOR_DB_PATH}.hnsw`,
+];
+
+function functionsIn(filePath: string, code: string): ParsedEntity[] {
+  const entities: ParsedEntity[] = [];
+  for (const match of code.matchAll(/function (\w+)\(\) \{[^}]*\}/g)) {
+    const start = match.index ?? 0;
+    const end = start + match[0].length;
+    const line = code.slice(0, start).split("\n").length;
+    entities.push({
+      id: `${filePath}:${match[1]}:${start}`,
+      name: match[1]!,
+      type: "function",
+      filePath,
+      location: { start: { line, column: 0, index: start }, end: { line, column: 0, index: end } },
+    });
+  }
+  return entities;
+}
+
+describe("incremental embedding updates", () => {
+  let dir: string;
+  let store: VectorStore;
+  let agent: any;
+  let embedded: string[][];
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    dir = mkdtempSync(join(tmpdir(), "semantic-incremental-"));
+    store = new VectorStore({ dbPath: VECTOR_DB_PATH, dimensions: 3 });
+    await store.initialize();
+
+    embedded = [];
+    agent = new SemanticAgent();
+    agent.vectorStore = store;
+    agent.embeddingDim = 3;
+    agent.embeddingGen = {
+      getProviderInfo: () => ({ provider: "custom", model: "test" }),
+      async generateBatch(texts: string[]) {
+        embedded.push(texts);
+        return texts.map((t) => new Float32Array([t.length, 1, 0]));
+      },
+    };
+  });
+
+  afterEach(async () => {
+    await store.close();
+    rmSync(dir, { recursive: true, force: true });
+    resetGraphStorage();
+    resetSQLiteManager();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+  });
+
+  async function index(file: string, code: string) {
+    writeFileSync(file, code);
+    await agent.handleNewEntities(functionsIn(file, code), { replaceFiles: true });
+  }
+
+  it("re-embeds only entities whose input changed", async () => {
+    const file = join(dir, "a.ts");
+    await index(file, "function alpha() { return 1; }\nfunction beta() { return 2; }\n");
+    expect(embedded).toHaveLength(1);
+    expect(embedded[0]).toHaveLength(2);
+
+    await index(file, "function alpha() { return 1; }\nfunction beta() { return 2; }\n");
+    expect(embedded).toHaveLength(1);
+
+    await index(file, "function alpha() { return 1; }\nfunction beta() { return 42; }\n");
+    expect(embedded).toHaveLength(2);
+    expect(embedded[1]).toEqual(["beta function\nfunction beta() { return 42; }"]);
+    expect(await store.count()).toBe(2);
+  });
+
+  it("reuses the vector of a moved entity and deletes vectors of removed ones", async () => {
+    const file = join(dir, "b.ts");
+    await index(file, "function alpha() { return 1; }\nfunction beta() { return 2; }\n");
+
+    // beta shifts to a new offset (and so a new id); alpha disappears
+    await index(file, "\n\nfunction beta() { return 2; }\n");
+    expect(embedded).toHaveLength(1);
+
+    const stored = await store.getMetadataByPaths([file]);
+    expect(Array.from(stored.values()).map((m) => m.name)).toEqual(["beta"]);
+    const [id] = Array.from(stored.keys());
+    expect(id).toBe(`ent:${file}:beta:2`);
+  });
+
+  it("embeds function bodies separately and ranks other functions by body similarity", async () => {
+    // One dimension per operator, so bodies doing the same arithmetic point the same way
+    const ops = (t: string) => new Float32Array([(t.match(/\+/g) ?? []).length, (t.match(/\*/g) ?? []).length, 0.1]);
+    agent.embeddingGen = {
+      getProviderInfo: () => ({ provider: "custom", model: "test" }),
+      async generateBatch(texts: string[]) {
+        return texts.map(ops);
+      },
+    };
+    const fn = (name: string, op: string) =>
+      `function ${name}() { let value = 1; value = value ${op} 2 ${op} 3 ${op} 4; return value ${op} 5 ${op} 6; }`;
+    const code = `${[fn("addAll", "+"), fn("sumEvery", "+"), fn("scale", "*")].join("\n")}\n`;
+    const file = join(dir, "c.ts");
+    await index(file, code);
+    expect(await store.count()).toBe(6);
+
+    const [addAll] = functionsIn(file, code);
+    const { matches, bodyVector } = await agent.findSimilarBodies(addAll, { minScore: 0.9 });
+    expect(bodyVector).toBe("stored");
+    expect(matches.map((m: any) => m.metadata?.name)).toEqual(["sumEvery"]);
+    expect(matches[0].cosine).toBeCloseTo(1, 5);
+
+    // Name/doc searches never see body vectors
+    const hits = await store.search(ops("+ +"), 10);
+    expect(hits).toHaveLength(3);
+    expect(hits.every((hit) => !hit.id.startsWith("body:"))).toBe(true);
+  });
+
+  it("warms up once: embeds a probe and builds the ANN index before any search", async () => {
+    await store.close();
+    store = new VectorStore({ dbPath: VECTOR_DB_PATH, dimensions: 3, ann: { minVectors: 1 } });
+    await store.initialize();
+    agent.vectorStore = store;
+    const probes: string[] = [];
+    agent.embeddingGen.generateEmbedding = async (text: string) => {
+      probes.push(text);
+      return new Float32Array([1, 0, 0]);
+    };
+
+    await index(join(dir, "a.ts"), "function alpha() { return 1; }\nfunction beta() { return 2; }\n");
+    expect(agent.isWarm()).toBe(false);
+
+    const [first, second] = await Promise.all([agent.warmup(), agent.warmup()]);
+    expect(second).toBe(first);
+    expect(first.vectors).toMatchObject({ count: 2, ann: { enabled: true, ready: true, size: 2 } });
+    expect(first.embedding).toMatchObject({ provider: "custom", model: "test" });
+    expect(probes).toHaveLength(1);
+    expect(agent.isWarm()).toBe(true);
+
+    await agent.reloadVectorStore();
+    expect(agent.isWarm()).toBe(false);
+  });
+
+  it("never returns entities of a deleted file, through the ANN index too", async () => {
+    await store.close();
+    store = new VectorStore({ dbPath: VECTOR_DB_PATH, dimensions: 3, ann: { minVectors: 1 } });
+    await store.initialize();
+    agent.vectorStore = store;
+
+    const indexer = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await indexer.initialize();
+    const graph = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    const files = {
+      kept: join(dir, "kept.ts"),
+      gone: join(dir, "gone.ts"),
+      stale: join(dir, "stale.ts"),
+    };
+    const sources = {
+      kept: "function keep() { return 1; }\n",
+      gone: "function dropMe() { return 2; }\n",
+      stale: "function lingers() { return 3; }\n",
+    };
+    for (const key of ["kept", "gone", "stale"] as const) {
+      writeFileSync(files[key], sources[key]);
+      await indexer.indexEntities(functionsIn(files[key], sources[key]), files[key]);
+      // What the indexer publishes: the stored entities, keyed by their graph ids
+      const stored = await graph.findEntities({ type: "entity", filters: { filePath: files[key] } });
+      await agent.handleNewEntities(stored, { replaceFiles: true });
+    }
+
+    // The first search starts the ANN build; wait for it so the rest goes through the ANN index
+    const query = new Float32Array([30, 1, 0]);
+    await store.search(query, 10);
+    for (let i = 0; i < 100 && !existsSync(`${VECTOR_DB_PATH}.hnsw`); i++) await new Promise((r) => setTimeout(r, 10));
+    expect(existsSync(`${VECTOR_DB_PATH}.hnsw`)).toBe(true);
+
+    await graph.deleteFileData(files.gone);
+    await agent.removeFileVectors([files.gone]);
+    // The graph lost this file but its vectors were never told, as when a search beats the event
+    await graph.deleteFileData(files.stale);
+
+    const hits = await store.search(query, 10);
+    expect(hits.map((hit) => hit.metadata?.name).sort()).toEqual(["keep", "lingers"]);
+    const live = await dropDeletedEntityHits(graph, hits);
+    expect(live.map((hit) => hit.metadata?.name)).toEqual(["keep"]);
+
+    await indexer.shutdown();
+  });
+
+  it("indexes without embedding and rebuilds every vector from the graph", async () => {
+    const indexer = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await indexer.initialize();
+    const published: unknown[] = [];
+    const subscription = knowledgeBus.subscribe("test", "semantic:new_entities", (entry) => {
+      published.push(entry.data);
+    });
+    const file = join(dir, "c.ts");
+    const code = "function alpha() { return 1; }\nfunction beta() { return 2; }\n";
+    writeFileSync(file, code);
+    await indexer.indexEntities(functionsIn(file, code), file, undefined, { embed: false });
+    knowledgeBus.unsubscribe(subscription);
+    expect(published).toHaveLength(0);
+    expect(await store.count()).toBe(0);
+
+    const progress: Array<[number, number]> = [];
+    const result = await agent.reembedAll({
+      onProgress: (done: number, total: number) => progress.push([done, total]),
+    });
+    expect(result).toMatchObject({ entities: 2, vectors: 2, previousDimension: 3, dimension: 3 });
+    expect(result.provider).toEqual({ provider: "custom", model: "test" });
+    expect(embedded).toHaveLength(1);
+    expect(progress.at(-1)).toEqual([2, 2]);
+    expect(await store.count()).toBe(2);
+
+    const controller = new AbortController();
+    controller.abort();
+    await expect(agent.reembedAll({ signal: controller.signal })).rejects.toThrow();
+    expect(await store.count()).toBe(2);
+
+    await indexer.shutdown();
+  });
+});
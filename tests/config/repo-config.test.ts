@@ -0,0 +1,88 @@
+import { mkdtempSync, rmSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { join } from "node:path";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { loadRepoConfig, RepoConfigError, withRepoConfig } from "../../src/config/repo-config.js";
+
+describe("loadRepoConfig", () => {
+  let root: string;
+
+  beforeEach(() => {
+    root = mkdtempSync(join(tmpdir(), "cgr-repo-config-"));
+  });
+
+  afterEach(() => {
+    rmSync(root, { recursive: true, force: true });
+  });
+
+  const failure = (): RepoConfigError => {
+    try {
+      loadRepoConfig(root);
+    } catch (error) {
+      if (error instanceof RepoConfigError) return error;
+      throw error;
+    }
+    throw new Error("expected a RepoConfigError");
+  };
+
+  it("returns null without a file and reads JSON and YAML files", () => {
+    expect(loadRepoConfig(root)).toBeNull();
+
+    writeFileSync(
+      join(root, ".code-graph-rag.json"),
+      JSON.stringify({ index: { excludePatterns: ["gen/**"], languages: ["go"] }, search: { minScore: 0.3 } }),
+    );
+    expect(loadRepoConfig(root)).toEqual({
+      path: join(root, ".code-graph-rag.json"),
+      config: { index: { excludePatterns: ["gen/**"], languages: ["go"] }, search: { minScore: 0.3 } },
+    });
+
+    rmSync(join(root, ".code-graph-rag.json"));
+    writeFileSync(join(root, ".code-graph-rag.yaml"), "chunking:\n  strategy: window\n  windowLines: 40\n");
+    expect(loadRepoConfig(root)?.config).toEqual({ chunking: { strategy: "window", windowLines: 40 } });
+  });
+
+  it("reports unknown keys with the nearest known one and invalid values by path", () => {
+    writeFileSync(
+      join(root, ".code-graph-rag.yml"),
+      "index:\n  exludePatterns: [dist/**]\n  languages: [cobol]\nembeding:\n  model: x\n",
+    );
+    const error = failure();
+    expect(error.path).toBe(join(root, ".code-graph-rag.yml"));
+    expect(error.errors).toHaveLength(3);
+    expect(error.errors.find((e) => e.startsWith("embeding:"))).toContain('did you mean "embedding"?');
+    expect(error.errors.find((e) => e.startsWith("index.exludePatterns:"))).toContain(
+      'did you mean "excludePatterns"?',
+    );
+    expect(error.errors.some((e) => e.startsWith("index.languages.0:"))).toBe(true);
+  });
+
+  it("rejects a file that does not parse or a second config file", () => {
+    writeFileSync(join(root, ".code-graph-rag.json"), "{ index: ");
+    expect(failure().errors).toHaveLength(1);
+
+    writeFileSync(join(root, ".code-graph-rag.json"), "{}");
+    writeFileSync(join(root, ".code-graph-rag.yaml"), "");
+    expect(failure().errors[0]).toContain("keep only one");
+  });
+});
+
+describe("withRepoConfig", () => {
+  it("lays embedding, chunking, search and indexer settings over the YAML config", () => {
+    const merged = withRepoConfig(
+      {
+        mcp: { embedding: { provider: "ollama", model: "nomic" }, semantic: { chunkStrategy: "entity" } } as any,
+        indexer: { gitBlame: false } as any,
+      },
+      {
+        embedding: { model: "mxbai" },
+        chunking: { strategy: "window", overlapLines: 5 },
+        search: { minScore: 0.35 },
+        index: { gitBlame: true, excludePatterns: ["gen/**"] },
+      },
+    );
+    expect(merged.mcp?.embedding).toMatchObject({ provider: "ollama", model: "mxbai" });
+    expect(merged.mcp?.semantic).toMatchObject({ chunkStrategy: "window", chunkOverlapLines: 5, minScore: 0.35 });
+    expect(merged.indexer).toEqual({ gitBlame: true });
+  });
+});
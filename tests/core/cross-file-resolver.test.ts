@@ -0,0 +1,261 @@
+import { existsSync, mkdirSync, mkdtempSync, rmSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { join } from "node:path";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import {
+  resolveCrossFileImports,
+  resolveRelativeModule,
+  resolveWorkspaceModule,
+  SymbolTable,
+} from "../../src/core/cross-file-resolver.js";
+import { commonRoot, readWorkspacePackages, rootOf } from "../../src/core/workspace-roots.js";
+import type { GraphStorageImpl } from "../../src/storage/graph-storage.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+import { RelationType } from "../../src/types/storage.js";
+
+const TEST_DB_PATH = "./data/test-cross-file-resolver.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function e(name: string, line: number, extra?: Partial<ParsedEntity>): ParsedEntity {
+  return {
+    name,
+    type: "function",
+    location: {
+      start: { line, column: 0, index: line * 10 },
+      end: { line: line + 4, column: 0, index: line * 10 + 5 },
+    },
+    ...extra,
+  } as any;
+}
+
+describe("resolveRelativeModule", () => {
+  const indexed = new Set(["/repo/src/util.ts", "/repo/src/lib/index.tsx"]);
+  const isIndexed = (p: string) => indexed.has(p);
+
+  it("tries extensions, .js-for-.ts and directory index files", () => {
+    expect(resolveRelativeModule("/repo/src/app.ts", "./util", isIndexed)).toBe("/repo/src/util.ts");
+    expect(resolveRelativeModule("/repo/src/app.ts", "./util.js", isIndexed)).toBe("/repo/src/util.ts");
+    expect(resolveRelativeModule("/repo/src/sub/a.ts", "../lib", isIndexed)).toBe("/repo/src/lib/index.tsx");
+    expect(resolveRelativeModule("/repo/src/app.ts", "lodash", isIndexed)).toBeNull();
+    expect(resolveRelativeModule("/repo/src/app.ts", "./missing", isIndexed)).toBeNull();
+  });
+});
+
+describe("resolveWorkspaceModule", () => {
+  const indexed = new Set(["/ws/shared/src/index.ts", "/ws/shared/src/utils.ts", "/ws/ui/lib/main.ts"]);
+  const isIndexed = (p: string) => indexed.has(p);
+  const packages = new Map([
+    ["@acme/shared", { name: "@acme/shared", root: "/ws/shared", entries: ["dist/index.js"] }],
+    ["@acme/ui", { name: "@acme/ui", root: "/ws/ui", entries: ["lib/main.js"] }],
+  ]);
+
+  it("finds a root's sources by its package name and subpaths", () => {
+    expect(resolveWorkspaceModule("@acme/shared", packages, isIndexed)).toBe("/ws/shared/src/index.ts");
+    expect(resolveWorkspaceModule("@acme/shared/utils", packages, isIndexed)).toBe("/ws/shared/src/utils.ts");
+    expect(resolveWorkspaceModule("@acme/ui", packages, isIndexed)).toBe("/ws/ui/lib/main.ts");
+    expect(resolveWorkspaceModule("@acme/shared-extra", packages, isIndexed)).toBeNull();
+    expect(resolveWorkspaceModule("./shared", packages, isIndexed)).toBeNull();
+  });
+
+  it("tags files with the innermost root and bases ids on their common directory", () => {
+    expect(rootOf("/ws/shared/src/a.ts", ["/ws", "/ws/shared"])).toBe("/ws/shared");
+    expect(rootOf("/other/a.ts", ["/ws"])).toBeUndefined();
+    expect(commonRoot(["/ws/api", "/ws/web/app"])).toBe("/ws");
+  });
+});
+
+describe("SymbolTable", () => {
+  it("evicts least recently used files and refuses files larger than its capacity", () => {
+    const table = new SymbolTable(3);
+    const decls = (n: number) => Array.from({ length: n }, (_, i) => ({ id: `d${i}` }) as any);
+
+    expect(table.set("a.ts", decls(2))).toBe(true);
+    expect(table.set("b.ts", decls(1))).toBe(true);
+    table.get("a.ts");
+    expect(table.set("c.ts", decls(1))).toBe(true);
+
+    expect(table.get("b.ts")).toBeUndefined();
+    expect(table.get("a.ts")).toHaveLength(2);
+    expect(table.size).toBe(3);
+    expect(table.set("big.ts", decls(4))).toBe(false);
+  });
+});
+
+describe("resolveCrossFileImports", () => {
+  let agent: IndexerAgent;
+  let storage: GraphStorageImpl;
+  let root: string;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+    storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    root = mkdtempSync(join(tmpdir(), "cgr-cross-file-"));
+    mkdirSync(join(root, "src"));
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    rmSync(root, { recursive: true, force: true });
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("moves import and call edges from placeholders onto the imported definition", async () => {
+    const util = join(root, "src", "util.ts");
+    const app = join(root, "src", "app.ts");
+    writeFileSync(util, "export function format() {}\nexport function shared() {}\n");
+    writeFileSync(app, "import { format as fmt, shared } from './util.js';\n");
+
+    // The importer is stored first, so its edges can only point at placeholders until the second phase
+    await agent.indexEntities(
+      [
+        e("./util.js", 1, {
+          type: "import",
+          importData: {
+            source: "./util.js",
+            specifiers: [
+              { local: "fmt", imported: "format" },
+              { local: "shared", imported: "shared" },
+            ],
+          },
+        }),
+        e("main", 3, { calls: [{ name: "fmt", line: 4, column: 2 }] }),
+      ],
+      app,
+    );
+    await agent.indexEntities([e("format", 1), e("shared", 2), e("shared", 8, { type: "variable" })], util);
+
+    const result = await resolveCrossFileImports(storage, [app]);
+
+    // `shared` is declared twice in util.ts, so it stays unresolved
+    expect(result).toEqual({ filesScanned: 1, importsResolved: 1, relationshipsRetargeted: 2 });
+
+    const [format] = await storage.findEntities({ type: "entity", filters: { name: "format", filePath: util } });
+    const inbound = (await storage.getRelationshipsForEntity(format!.id)).filter((r) => r.toId === format!.id);
+    expect(inbound.map((r) => r.type).sort()).toEqual([RelationType.CALLS, RelationType.IMPORTS]);
+    expect(inbound.find((r) => r.type === RelationType.CALLS)?.metadata?.callSites).toEqual([
+      { line: 4, column: 2, callee: "fmt" },
+    ]);
+
+    // A second pass finds nothing left to move
+    expect((await resolveCrossFileImports(storage)).relationshipsRetargeted).toBe(0);
+  });
+
+  it("binds references to imported names and calls through a namespace import", async () => {
+    const util = join(root, "src", "util.ts");
+    const app = join(root, "src", "app.ts");
+    writeFileSync(util, "export interface Options {}\nexport function format() {}\n");
+    writeFileSync(app, "import { Options } from './util.js';\nimport * as util from './util.js';\n");
+
+    await agent.indexEntities(
+      [
+        e("./util.js", 1, {
+          type: "import",
+          importData: { source: "./util.js", specifiers: [{ local: "Options", imported: "Options" }] },
+        }),
+        e("./util.js", 2, {
+          type: "import",
+          importData: { source: "./util.js", specifiers: [], isNamespace: true, namespace: "util" },
+        }),
+        e("main", 4, {
+          references: ["Options", "opts", "format"],
+          calls: [
+            { name: "format", qualifier: "util", line: 5, column: 2 },
+            { name: "parse", qualifier: "util", line: 6, column: 2 },
+          ],
+        }),
+      ],
+      app,
+    );
+    await agent.indexEntities([e("Options", 1, { type: "interface" }), e("format", 2)], util);
+
+    const result = await resolveCrossFileImports(storage, [app]);
+    expect(result).toEqual({ filesScanned: 1, importsResolved: 2, relationshipsRetargeted: 3 });
+
+    const [main] = await storage.findEntities({ type: "entity", filters: { name: "main", filePath: app } });
+    const [options] = await storage.findEntities({ type: "entity", filters: { name: "Options", filePath: util } });
+    const [format] = await storage.findEntities({ type: "entity", filters: { name: "format", filePath: util } });
+    const outgoing = (await storage.getRelationshipsForEntity(main!.id)).filter((r) => r.fromId === main!.id);
+    expect(outgoing.find((r) => r.type === RelationType.REFERENCES)?.toId).toBe(options!.id);
+    expect(outgoing.find((r) => r.type === RelationType.CALLS && r.toId === format!.id)?.metadata?.resolvedFrom).toBe(
+      "import",
+    );
+    // `util.parse` is not declared in util.ts, so it stays unresolved
+    const parse = outgoing.find((r) => r.metadata?.callSites?.[0]?.callee === "util.parse");
+    expect(parse?.metadata?.resolvedFrom).toBeUndefined();
+  });
+
+  it("falls back to SQLite lookups when declarations do not fit in the symbol table", async () => {
+    const util = join(root, "src", "util.ts");
+    const app = join(root, "src", "app.ts");
+    writeFileSync(util, "export function format() {}\n");
+    writeFileSync(app, "import { format } from './util.js';\n");
+
+    await agent.indexEntities(
+      [
+        e("./util.js", 1, {
+          type: "import",
+          importData: { source: "./util.js", specifiers: [{ local: "format", imported: "format" }] },
+        }),
+        e("main", 3, { calls: [{ name: "format", line: 4, column: 2 }] }),
+      ],
+      app,
+    );
+    await agent.indexEntities([e("format", 1), e("other", 6)], util);
+
+    const result = await resolveCrossFileImports(storage, [app], { symbolCacheSize: 0 });
+    expect(result).toEqual({ filesScanned: 1, importsResolved: 1, relationshipsRetargeted: 2 });
+  });
+
+  it("binds an import of another root by its package name", async () => {
+    const shared = join(root, "shared");
+    const api = join(root, "api");
+    mkdirSync(join(shared, "src"), { recursive: true });
+    mkdirSync(api);
+    writeFileSync(join(shared, "package.json"), JSON.stringify({ name: "@acme/shared", main: "dist/index.js" }));
+    const lib = join(shared, "src", "index.ts");
+    const app = join(api, "app.ts");
+    writeFileSync(lib, "export function format() {}\n");
+    writeFileSync(app, "import { format } from '@acme/shared';\n");
+
+    await agent.indexEntities(
+      [
+        e("@acme/shared", 1, {
+          type: "import",
+          importData: { source: "@acme/shared", specifiers: [{ local: "format", imported: "format" }] },
+        }),
+        e("main", 3, { calls: [{ name: "format", line: 4, column: 2 }] }),
+      ],
+      app,
+      undefined,
+      { rootDir: root, root: api },
+    );
+    await agent.indexEntities([e("format", 1)], lib, undefined, { rootDir: root, root: shared });
+
+    expect(await storage.listIndexRoots()).toEqual([api, shared].sort());
+    expect(readWorkspacePackages([api, shared]).get("@acme/shared")?.root).toBe(shared);
+
+    const result = await resolveCrossFileImports(storage, [app]);
+    expect(result).toEqual({ filesScanned: 1, importsResolved: 1, relationshipsRetargeted: 2 });
+    const [format] = await storage.findEntities({ type: "entity", filters: { name: "format", filePath: lib } });
+    expect(format?.metadata?.root).toBe(shared);
+    const inbound = (await storage.getRelationshipsForEntity(format!.id)).filter((r) => r.toId === format!.id);
+    expect(inbound.every((r) => r.metadata?.resolvedFrom === "workspace")).toBe(true);
+
+    const scoped = await storage.findEntities({ type: "entity", filters: { scope: { root: api } } });
+    expect(new Set(scoped.map((entity) => entity.filePath))).toEqual(new Set([app]));
+  });
+});
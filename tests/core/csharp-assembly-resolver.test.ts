@@ -0,0 +1,195 @@
+import { existsSync, mkdirSync, mkdtempSync, rmSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { join } from "node:path";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { resolveCSharpAssemblies } from "../../src/core/csharp-assembly-resolver.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import { RelationType } from "../../src/types/storage.js";
+import { edge, entity } from "../fixtures/parsed-entities.js";
+import { indexSources } from "../fixtures/parsed-sources.js";
+
+const TEST_DB_PATH = "./data/test-csharp-assembly-resolver.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+describe("resolveCSharpAssemblies", () => {
+  let agent: IndexerAgent;
+  let project: string;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    project = mkdtempSync(join(tmpdir(), "shop-web-"));
+    mkdirSync(join(project, "Controllers"));
+    mkdirSync(join(project, "Services"));
+    writeFileSync(join(project, "Shop.Web.csproj"), '<Project Sdk="Microsoft.NET.Sdk.Web" />');
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    rmSync(project, { recursive: true, force: true });
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("merges partial classes and binds heritage and calls within the assembly", async () => {
+    const controller = join(project, "Controllers/OrdersController.cs");
+    const generated = join(project, "Controllers/OrdersController.g.cs");
+    const contract = join(project, "Services/IOrderService.cs");
+    const service = join(project, "Services/OrderService.cs");
+    const ns = "Shop.Web";
+    const owner = `${ns}.Controllers.OrdersController`;
+
+    await agent.indexEntities(
+      [
+        entity("c:type", "OrdersController", "class", 6, {
+          qualifiedName: owner,
+          isPartial: true,
+          namespace: `${ns}.Controllers`,
+          usings: [`${ns}.Services`],
+          baseTypes: ["ControllerBase"],
+        }),
+        entity("c:field", "_orders", "field", 8, { qualifiedName: `${owner}._orders`, fieldType: "IOrderService" }),
+        entity("c:get", "Get", "method", 10, { qualifiedName: `${owner}.Get` }),
+      ],
+      controller,
+      [
+        edge("c:type", "ControllerBase", "extends"),
+        edge("c:field", "c:type", "contains"),
+        edge("c:get", "c:type", "contains"),
+        edge("c:get", "Find", "calls", { line: 11, column: 8, callee: "_orders.Find", calleeName: "Find" }),
+        edge("c:get", "Audit", "calls", { line: 12, column: 8, callee: "Audit", calleeName: "Audit" }),
+      ],
+    );
+    await agent.indexEntities(
+      [
+        entity("g:type", "OrdersController", "class", 3, {
+          qualifiedName: owner,
+          isPartial: true,
+          namespace: `${ns}.Controllers`,
+          usings: [],
+          baseTypes: ["IDisposable"],
+        }),
+        entity("g:audit", "Audit", "method", 5, { qualifiedName: `${owner}.Audit` }),
+      ],
+      generated,
+      [edge("g:type", "IDisposable", "implements"), edge("g:audit", "g:type", "contains")],
+    );
+    await agent.indexEntities(
+      [
+        entity("i:type", "IOrderService", "interface", 3, { qualifiedName: `${ns}.Services.IOrderService` }),
+        entity("i:find", "Find", "method", 5, { qualifiedName: `${ns}.Services.IOrderService.Find` }),
+      ],
+      contract,
+      [edge("i:find", "i:type", "contains")],
+    );
+    await agent.indexEntities(
+      [
+        entity("s:type", "OrderService", "class", 3, {
+          qualifiedName: `${ns}.Services.OrderService`,
+          namespace: `${ns}.Services`,
+          usings: [],
+          baseTypes: ["IOrderService"],
+        }),
+      ],
+      service,
+      [edge("s:type", "IOrderService", "implements")],
+    );
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    expect(await resolveCSharpAssemblies(storage)).toEqual({
+      filesScanned: 4,
+      partialTypesMerged: 1,
+      heritageResolved: 1,
+      callsResolved: 2,
+    });
+
+    // The fragment in `OrdersController.cs` sorts first and keeps the type
+    const types = await storage.findEntities({ type: "entity", filters: { name: "OrdersController" } });
+    expect(types.map((t) => [t.filePath, t.metadata?.partialFiles])).toEqual([[controller, [controller, generated]]]);
+    const merged = types[0]!;
+
+    const [audit] = await storage.findEntities({ type: "entity", filters: { filePath: generated, name: "Audit" } });
+    const contains = await storage.getRelationshipsForEntity(audit!.id, RelationType.CONTAINS);
+    expect(contains.map((r) => r.toId)).toEqual([merged.id]);
+
+    const heritage = (await storage.getRelationshipsForEntity(merged.id)).filter((r) => r.fromId === merged.id);
+    const names = await Promise.all(heritage.map(async (r) => `${r.type}:${(await storage.getEntity(r.toId))?.name}`));
+    expect(names.sort()).toEqual(["extends:ControllerBase", "implements:IDisposable"]);
+
+    const [impl] = await storage.findEntities({ type: "entity", filters: { filePath: service, name: "OrderService" } });
+    const [contractType] = await storage.findEntities({
+      type: "entity",
+      filters: { filePath: contract, name: "IOrderService" },
+    });
+    const implemented = await storage.getRelationshipsForEntity(impl!.id, RelationType.IMPLEMENTS);
+    expect(implemented.map((r) => r.toId)).toEqual([contractType!.id]);
+
+    // `_orders.Find()` goes through the field's interface, `Audit()` to the other fragment's method
+    const [get] = await storage.findEntities({ type: "entity", filters: { filePath: controller, name: "Get" } });
+    const [find] = await storage.findEntities({ type: "entity", filters: { filePath: contract, name: "Find" } });
+    const calls = await storage.getRelationshipsForEntity(get!.id, RelationType.CALLS);
+    expect(calls.map((r) => r.toId).sort()).toEqual([audit!.id, find!.id].sort());
+    expect(calls.every((r) => r.metadata?.resolvedFrom === "assembly")).toBe(true);
+
+    expect(await resolveCSharpAssemblies(storage)).toEqual({
+      filesScanned: 4,
+      partialTypesMerged: 0,
+      heritageResolved: 0,
+      callsResolved: 0,
+    });
+  });
+
+  it("binds a base class and its inherited method across parsed files", async () => {
+    const base = join(project, "Services/ServiceBase.cs");
+    const service = join(project, "Services/OrderService.cs");
+    await indexSources(agent, {
+      [base]: `namespace Shop.Web.Services
+{
+    public abstract class ServiceBase
+    {
+        protected string Describe(int id)
+        {
+            return "order " + id;
+        }
+    }
+}
+`,
+      [service]: `namespace Shop.Web.Services
+{
+    public class OrderService : ServiceBase
+    {
+        public string Find(int id)
+        {
+            return Describe(id);
+        }
+    }
+}
+`,
+    });
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    expect(await resolveCSharpAssemblies(storage)).toMatchObject({ heritageResolved: 1, callsResolved: 1 });
+
+    const named = async (filePath: string, name: string) =>
+      (await storage.findEntities({ type: "entity", filters: { filePath, name } }))[0]!;
+    const parent = await named(base, "ServiceBase");
+    const child = await named(service, "OrderService");
+    const extendsEdges = await storage.getRelationshipsForEntity(child.id, RelationType.EXTENDS);
+    expect(extendsEdges.map((r) => [r.toId, r.metadata?.resolvedFrom])).toEqual([[parent.id, "assembly"]]);
+
+    const find = await named(service, "Find");
+    const calls = await storage.getRelationshipsForEntity(find.id, RelationType.CALLS);
+    expect(calls.map((r) => r.toId)).toEqual([(await named(base, "Describe")).id]);
+  });
+});
@@ -0,0 +1,412 @@
+import { existsSync, mkdtempSync, rmSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { join } from "node:path";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { resolveGoPackages } from "../../src/core/go-package-resolver.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { listMembers } from "../../src/tools/list-members.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import { RelationType } from "../../src/types/storage.js";
+import { edge, entity } from "../fixtures/parsed-entities.js";
+import { indexSources } from "../fixtures/parsed-sources.js";
+
+const TEST_DB_PATH = "./data/test-go-package-resolver.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+describe("resolveGoPackages", () => {
+  let agent: IndexerAgent;
+  let root: string;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+    root = mkdtempSync(join(tmpdir(), "cgr-go-packages-"));
+    writeFileSync(join(root, "go.mod"), "module example.com/shop\n\ngo 1.22\n");
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    rmSync(root, { recursive: true, force: true });
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("binds qualified names through import aliases and bare names within the package", async () => {
+    const models = join(root, "internal", "models", "user.go");
+    const handler = join(root, "api", "handler.go");
+    const routes = join(root, "api", "routes.go");
+
+    await agent.indexEntities(
+      [
+        entity("m:pkg", "models", "module", 1, { isPackage: true }),
+        entity("m:User", "User", "class", 3, { package: "models" }),
+        entity("m:Find", "Find", "function", 7, { package: "models" }),
+        entity("m:find", "find", "function", 11, { package: "models" }),
+      ],
+      models,
+      [],
+    );
+    await agent.indexEntities(
+      [
+        entity("r:pkg", "api", "module", 1, { isPackage: true }),
+        entity("r:decode", "decode", "function", 3, { package: "api" }),
+      ],
+      routes,
+      [],
+    );
+    await agent.indexEntities(
+      [
+        entity("h:pkg", "api", "module", 1, { isPackage: true }),
+        entity("h:Show", "Show", "function", 5, { package: "api" }),
+      ],
+      handler,
+      [
+        edge("h:pkg", "example.com/shop/internal/models", "imports", { importType: "package", alias: "m", line: 3 }),
+        edge("h:Show", "m.User", "references", { referenceType: "type", referencedName: "m.User", line: 5 }),
+        edge("h:Show", "h:Show:function:m.Find", "calls", { line: 6, callee: "m.Find", calleeName: "Find" }),
+        // Unexported names are not visible outside their package
+        edge("h:Show", "h:Show:function:m.find", "calls", { line: 7, callee: "m.find", calleeName: "find" }),
+        edge("h:Show", "h:Show:function:decode", "calls", { line: 8, callee: "decode", calleeName: "decode" }),
+        edge("h:Show", "h:Show:function:w.Write", "calls", { line: 9, callee: "w.Write", calleeName: "Write" }),
+      ],
+    );
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    expect(await resolveGoPackages(storage, [handler])).toEqual({
+      filesScanned: 2,
+      callsResolved: 2,
+      referencesResolved: 1,
+      accessesResolved: 0,
+      spawnsResolved: 0,
+      channelOpsResolved: 0,
+      membersResolved: 0,
+      implementationsResolved: 0,
+    });
+
+    const named = async (filePath: string, name: string) =>
+      (await storage.findEntities({ type: "entity", filters: { filePath, name } }))[0]!;
+    const show = await named(handler, "Show");
+    const outgoing = (await storage.getRelationshipsForEntity(show.id)).filter((r) => r.fromId === show.id);
+    const bound = outgoing.filter((r) => r.metadata?.resolvedFrom === "package").map((r) => [r.type, r.toId]);
+    expect(bound.sort()).toEqual(
+      [
+        [RelationType.CALLS, (await named(models, "Find")).id],
+        [RelationType.CALLS, (await named(routes, "decode")).id],
+        [RelationType.REFERENCES, (await named(models, "User")).id],
+      ].sort(),
+    );
+
+    // What cannot be attributed to a declaration stays on its placeholder
+    const unbound = outgoing.filter((r) => r.type === RelationType.CALLS && !r.metadata?.resolvedFrom);
+    expect(unbound.map((r) => r.metadata?.callSites?.[0]?.callee).sort()).toEqual(["m.find", "w.Write"]);
+
+    expect((await resolveGoPackages(storage)).callsResolved).toBe(0);
+  });
+
+  it("binds field and package-level variable accesses to their declarations", async () => {
+    const config = join(root, "internal", "config", "config.go");
+    const state = join(root, "api", "state.go");
+    const handler = join(root, "api", "handler.go");
+
+    await agent.indexEntities(
+      [
+        entity("c:pkg", "config", "module", 1, { isPackage: true }),
+        entity("c:Limit", "Limit", "variable", 3, { package: "config" }),
+      ],
+      config,
+      [],
+    );
+    await agent.indexEntities(
+      [
+        entity("s:pkg", "api", "module", 1, { isPackage: true }),
+        entity("s:hits", "hits", "variable", 3, { package: "api" }),
+        entity("s:Server", "Server", "class", 5, { package: "api" }),
+        entity("s:users", "users", "property", 6, { parent: `${state}:type:Server` }),
+        entity("s:Render", "Render", "function", 9, { package: "api" }),
+      ],
+      state,
+      [],
+    );
+    await agent.indexEntities(
+      [
+        entity("h:pkg", "api", "module", 1, { isPackage: true }),
+        entity("h:Handle", "Handle", "function", 5, { package: "api" }),
+      ],
+      handler,
+      [
+        edge("h:pkg", "example.com/shop/internal/config", "imports", { importType: "package", line: 3 }),
+        edge("h:Handle", "hits", "accesses", { access: "readwrite", referencedName: "hits", line: 5 }),
+        edge("h:Handle", "Server.users", "accesses", {
+          access: "write",
+          referencedName: "Server.users",
+          receiverType: "Server",
+          line: 6,
+        }),
+        edge("h:Handle", "config.Limit", "accesses", { access: "read", referencedName: "config.Limit", line: 7 }),
+        // A function used as a value is not a variable
+        edge("h:Handle", "Render", "accesses", { access: "read", referencedName: "Render", line: 8 }),
+      ],
+    );
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    expect(await resolveGoPackages(storage, [handler])).toEqual({
+      filesScanned: 2,
+      callsResolved: 0,
+      referencesResolved: 0,
+      accessesResolved: 3,
+      spawnsResolved: 0,
+      channelOpsResolved: 0,
+      membersResolved: 0,
+      implementationsResolved: 0,
+    });
+
+    const named = async (filePath: string, name: string) =>
+      (await storage.findEntities({ type: "entity", filters: { filePath, name } }))[0]!;
+    const handle = await named(handler, "Handle");
+    const accesses = (await storage.getRelationshipsForEntity(handle.id, RelationType.ACCESSES)).filter(
+      (r) => r.fromId === handle.id,
+    );
+    const byTarget = new Map(accesses.map((r) => [r.toId, r]));
+    expect(byTarget.get((await named(state, "hits")).id)?.metadata).toMatchObject({
+      access: "readwrite",
+      resolvedFrom: "package",
+      accessSites: [{ line: 5, access: "readwrite", name: "hits" }],
+    });
+    expect(byTarget.get((await named(state, "users")).id)?.metadata?.access).toBe("write");
+    expect(byTarget.has((await named(config, "Limit")).id)).toBe(true);
+    expect(byTarget.has((await named(state, "Render")).id)).toBe(false);
+  });
+
+  it("binds goroutine spawns and channel operations across the files of a package", async () => {
+    const workers = join(root, "jobs", "workers.go");
+    const pool = join(root, "jobs", "pool.go");
+
+    await agent.indexEntities(
+      [
+        entity("w:pkg", "jobs", "module", 1, { isPackage: true }),
+        entity("w:events", "events", "variable", 3, { package: "jobs", channel: { elementType: "string" } }),
+        entity("w:drain", "drain", "function", 5, { package: "jobs" }),
+      ],
+      workers,
+      [],
+    );
+    await agent.indexEntities(
+      [
+        entity("p:pkg", "jobs", "module", 1, { isPackage: true }),
+        entity("p:Run", "Run", "function", 3, { package: "jobs" }),
+      ],
+      pool,
+      [
+        edge("p:Run", "p:function:drain", "spawns", { line: 4, callee: "drain", calleeName: "drain" }),
+        edge("p:Run", "p:var:events", "sends", { access: "write", referencedName: "events", line: 5 }),
+        edge("p:Run", "p:var:events", "receives", { access: "read", referencedName: "events", line: 6 }),
+      ],
+    );
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    expect(await resolveGoPackages(storage, [pool])).toMatchObject({ spawnsResolved: 1, channelOpsResolved: 2 });
+
+    const named = async (filePath: string, name: string) =>
+      (await storage.findEntities({ type: "entity", filters: { filePath, name } }))[0]!;
+    const run = await named(pool, "Run");
+    const outgoing = (await storage.getRelationshipsForEntity(run.id)).filter((r) => r.fromId === run.id);
+    const events = (await named(workers, "events")).id;
+    expect(outgoing.map((r) => [r.type, r.toId]).sort()).toEqual(
+      [
+        [RelationType.RECEIVES, events],
+        [RelationType.SENDS, events],
+        [RelationType.SPAWNS, (await named(workers, "drain")).id],
+      ].sort(),
+    );
+    expect(outgoing.find((r) => r.type === RelationType.SENDS)?.metadata?.accessSites).toEqual([
+      { line: 5, access: "write", name: "events" },
+    ]);
+  });
+
+  it("attaches methods to a receiver type declared in another file of the package", async () => {
+    const types = join(root, "api", "types.go");
+    const server = join(root, "api", "server.go");
+
+    await agent.indexEntities(
+      [
+        entity("t:pkg", "api", "module", 1, { isPackage: true }),
+        entity("t:Server", "Server", "class", 3, { package: "api" }),
+      ],
+      types,
+      [],
+    );
+    await agent.indexEntities(
+      [
+        entity("s:pkg", "api", "module", 1, { isPackage: true }),
+        entity("s:Start", "Start", "method", 3, { receiver: "Server", pointerReceiver: true, package: "api" }),
+        entity("s:Name", "Name", "method", 7, { receiver: "Server", pointerReceiver: false, package: "api" }),
+        entity("s:Close", "Close", "method", 11, { receiver: "conn", pointerReceiver: true, package: "api" }),
+      ],
+      server,
+      [
+        edge("s:Start", "s:type:Server", "contains", { memberType: "method", referencedName: "Server" }),
+        edge("s:Name", "s:type:Server", "contains", { memberType: "method", referencedName: "Server" }),
+        // Not declared anywhere in the package
+        edge("s:Close", "s:type:conn", "contains", { memberType: "method", referencedName: "conn" }),
+      ],
+    );
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    expect(await resolveGoPackages(storage, [server])).toMatchObject({ filesScanned: 2, membersResolved: 2 });
+
+    const named = async (filePath: string, name: string) =>
+      (await storage.findEntities({ type: "entity", filters: { filePath, name } }))[0]!;
+    const serverType = await named(types, "Server");
+    const contained = (await storage.getRelationshipsForEntity(serverType.id, RelationType.CONTAINS)).filter(
+      (r) => r.toId === serverType.id,
+    );
+    expect(contained.map((r) => r.metadata?.resolvedFrom)).toEqual(["package", "package"]);
+
+    const close = await named(server, "Close");
+    const [unbound] = await storage.getRelationshipsForEntity(close.id, RelationType.CONTAINS);
+    expect((await storage.getEntity(unbound!.toId))?.filePath.startsWith("external://")).toBe(true);
+
+    const [listed] = (await listMembers(storage, { symbol: "Server" })).containers;
+    expect(listed?.members.map((m) => [m.name, m.receiver, m.via])).toEqual([
+      ["Start", "pointer", "contains"],
+      ["Name", "value", "contains"],
+    ]);
+  });
+
+  it("matches a struct against interfaces with methods from every file of the package", async () => {
+    const store = join(root, "store", "store.go");
+    const methods = join(root, "store", "methods.go");
+    const repo = join(root, "store", "repo.go");
+    const repoId = `${repo}:type:Repository`;
+
+    await agent.indexEntities(
+      [
+        entity("st:pkg", "store", "module", 1, { isPackage: true }),
+        entity("st:Store", "Store", "class", 3, { package: "store" }),
+      ],
+      store,
+      [],
+    );
+    await agent.indexEntities(
+      [
+        entity("r:pkg", "store", "module", 1, { isPackage: true }),
+        entity(repoId, "Repository", "interface", 3, { package: "store" }),
+        entity(`${repoId}:method:Get`, "Get", "method", 4, {
+          isAbstract: true,
+          parent: repoId,
+          parameters: ["key: string"],
+          returnType: "([]byte, error)",
+        }),
+        entity(`${repoId}:method:Put`, "Put", "method", 5, {
+          isAbstract: true,
+          parent: repoId,
+          parameters: ["key: string", "value: []byte"],
+          returnType: "error",
+        }),
+      ],
+      repo,
+      [],
+    );
+    const indexMethods = async (withPut: boolean) =>
+      agent.indexEntities(
+        [
+          entity("m:pkg", "store", "module", 1, { isPackage: true }),
+          entity("m:Get", "Get", "method", 3, {
+            receiver: "Store",
+            pointerReceiver: false,
+            parameters: ["k: string"],
+            returnType: "([]byte, error)",
+          }),
+          ...(withPut
+            ? [
+                entity("m:Put", "Put", "method", 7, {
+                  receiver: "Store",
+                  pointerReceiver: true,
+                  parameters: ["k: string", "v: []byte"],
+                  returnType: "error",
+                }),
+              ]
+            : []),
+        ],
+        methods,
+        [],
+        { replaceFile: true },
+      );
+    await indexMethods(true);
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    expect(await resolveGoPackages(storage, [methods])).toMatchObject({ implementationsResolved: 1 });
+    const storeType = (await storage.findEntities({ type: "entity", filters: { filePath: store, name: "Store" } }))[0]!;
+    const implemented = async () =>
+      (await storage.getRelationshipsForEntity(storeType.id, RelationType.IMPLEMENTS)).filter(
+        (r) => r.fromId === storeType.id,
+      );
+    const [link] = await implemented();
+    expect((await storage.getEntity(link!.toId))?.name).toBe("Repository");
+    expect(link?.metadata).toMatchObject({ satisfiedBy: "*Store", pointerReceiver: true, resolvedFrom: "package" });
+
+    // Found again on a full run, not added twice
+    expect((await resolveGoPackages(storage)).implementationsResolved).toBe(0);
+    expect(await implemented()).toHaveLength(1);
+
+    await indexMethods(false);
+    await resolveGoPackages(storage, [methods]);
+    expect(await implemented()).toHaveLength(0);
+  });
+
+  it("links a struct and its methods parsed from separate files of a package", async () => {
+    const store = join(root, "store", "store.go");
+    const methods = join(root, "store", "methods.go");
+    await indexSources(agent, {
+      [store]: `package store
+
+type Store struct {
+  data map[string][]byte
+}
+
+type Repository interface {
+  Get(key string) ([]byte, error)
+  Put(key string, value []byte) error
+}
+`,
+      [methods]: `package store
+
+func (s Store) Get(k string) ([]byte, error) {
+  return s.data[k], nil
+}
+
+func (s *Store) Put(k string, v []byte) error {
+  s.data[k] = v
+  return nil
+}
+`,
+    });
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    expect(await resolveGoPackages(storage)).toMatchObject({ membersResolved: 2, implementationsResolved: 1 });
+
+    const [listed] = (await listMembers(storage, { symbol: "Store" })).containers;
+    expect(listed?.members.map((m) => [m.name, m.kind, m.receiver, m.via])).toEqual([
+      ["Get", "method", "value", "contains"],
+      ["Put", "method", "pointer", "contains"],
+      ["data", "field", undefined, "contains"],
+    ]);
+
+    const storeType = (await storage.findEntities({ type: "entity", filters: { filePath: store, name: "Store" } }))[0]!;
+    const [link] = (await storage.getRelationshipsForEntity(storeType.id, RelationType.IMPLEMENTS)).filter(
+      (r) => r.fromId === storeType.id,
+    );
+    expect((await storage.getEntity(link!.toId))?.name).toBe("Repository");
+    expect(link?.metadata).toMatchObject({ satisfiedBy: "*Store", pointerReceiver: true, methodCount: 2 });
+  });
+});
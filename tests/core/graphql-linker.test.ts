@@ -0,0 +1,123 @@
+import { existsSync, rmSync } from "node:fs";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { linkGraphqlSchema } from "../../src/core/graphql-linker.js";
+import { GraphqlAnalyzer } from "../../src/parsers/graphql-analyzer.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+import { RelationType } from "../../src/types/storage.js";
+
+const TEST_DB_PATH = "./data/test-graphql-linker.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+const USERS = `type Query {
+  user(id: ID!): User
+}
+
+type User {
+  id: ID!
+  orders: [Order!]!
+}
+`;
+
+const ORDERS = `type Order {
+  id: ID!
+  total: Int
+}
+`;
+
+function code(name: string, type: ParsedEntity["type"], line: number, metadata = {}): ParsedEntity {
+  return {
+    name,
+    type,
+    location: {
+      start: { line, column: 0, index: line * 10 },
+      end: { line: line + 2, column: 0, index: line * 10 + 9 },
+    },
+    metadata,
+  };
+}
+
+describe("linkGraphqlSchema", () => {
+  let agent: IndexerAgent;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("binds types across schema files and links resolvers to the fields they serve", async () => {
+    const users = "/repo/schema/users.graphql";
+    const orders = "/repo/schema/orders.graphql";
+    const analyzer = new GraphqlAnalyzer();
+    for (const [file, sdl] of [
+      [users, USERS],
+      [orders, ORDERS],
+    ] as const) {
+      const { entities, relationships } = analyzer.analyze(sdl, file);
+      await agent.indexEntities(
+        entities.map((e) => ({ ...e, language: "graphql" })),
+        file,
+        relationships,
+      );
+    }
+    await agent.indexEntities(
+      [code("orders", "method", 4, { className: "UserResolver" })],
+      "/repo/src/user.resolver.ts",
+    );
+    await agent.indexEntities(
+      [code("resolveUser", "function", 2, { qualifiedName: "resolvers.Query.resolveUser" })],
+      "/repo/src/schema.ts",
+    );
+    await agent.indexEntities([code("total", "function", 1)], "/repo/src/resolvers/order.ts");
+    // Named like a field, but neither owned by its type nor in resolver code
+    await agent.indexEntities([code("total", "function", 1)], "/repo/src/cart/math.ts");
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    // User.orders -> Order is the only reference its own file could not bind
+    expect(await linkGraphqlSchema(storage)).toEqual({ filesScanned: 2, typesResolved: 1, resolversLinked: 3 });
+
+    const find = async (filePath: string, name: string) =>
+      (await storage.findEntities({ type: "entity", filters: { filePath, name } }))[0]!;
+    const orderType = await find(orders, "Order");
+    const ordersField = await find(users, "orders");
+    const references = (await storage.getRelationshipsForEntity(ordersField.id, RelationType.REFERENCES)).filter(
+      (rel) => rel.fromId === ordersField.id,
+    );
+    expect(references.map((rel) => [rel.toId, rel.metadata?.resolvedFrom])).toEqual([[orderType.id, "graphql"]]);
+
+    const resolvers = async (filePath: string, name: string) => {
+      const field = await find(filePath, name);
+      const edges = await storage.getRelationshipsForEntity(field.id, RelationType.RESOLVES);
+      const found = [];
+      for (const rel of edges.filter((edge) => edge.toId === field.id)) {
+        const resolver = await storage.getEntity(rel.fromId);
+        found.push([resolver?.filePath, rel.metadata?.match, rel.metadata?.confidence]);
+      }
+      return found;
+    };
+    expect(await resolvers(users, "orders")).toEqual([["/repo/src/user.resolver.ts", "owner", 0.7]]);
+    expect(await resolvers(users, "user")).toEqual([["/repo/src/schema.ts", "owner", 0.7]]);
+    expect(await resolvers(orders, "total")).toEqual([["/repo/src/resolvers/order.ts", "name", 0.4]]);
+
+    // Rebuilt, not duplicated, on the next run
+    expect((await linkGraphqlSchema(storage)).resolversLinked).toBe(3);
+    expect(await resolvers(users, "orders")).toHaveLength(1);
+  });
+});
@@ -0,0 +1,113 @@
+import { existsSync, rmSync } from "node:fs";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { linkHeaders } from "../../src/core/header-linker.js";
+import { translationUnitEntity } from "../../src/parsers/c-family.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+import { RelationType } from "../../src/types/storage.js";
+
+const TEST_DB_PATH = "./data/test-header-linker.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function fn(name: string, line: number, isDefinition: boolean, types: string[] = ["int", "int"]): ParsedEntity {
+  return {
+    id: isDefinition ? `def:${name}` : undefined,
+    name,
+    type: "function",
+    location: {
+      start: { line, column: 0, index: line * 10 },
+      end: { line: line + (isDefinition ? 3 : 0), column: 0, index: line * 10 + 9 },
+    },
+    parameters: types.map((type, i) => ({ name: `p${i}`, type })),
+    metadata: { isDefinition },
+  } as ParsedEntity;
+}
+
+describe("linkHeaders", () => {
+  let agent: IndexerAgent;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("binds includes, links definitions to prototypes and moves calls onto the definition", async () => {
+    const header = "/tmp/calc/include/calc.h";
+    const source = "/tmp/calc/src/calc.c";
+    const main = "/tmp/calc/src/main.c";
+    const include = (file: string, path: string) => ({
+      from: translationUnitEntity(file).id!,
+      to: path,
+      type: "imports",
+      targetFile: file,
+      metadata: { line: 1 },
+    });
+
+    await agent.indexEntities([translationUnitEntity(header), fn("add", 3, false)], header, []);
+    await agent.indexEntities([translationUnitEntity(source), fn("add", 3, true, ["int", "int"])], source, [
+      include(source, "calc.h"),
+    ]);
+    await agent.indexEntities([translationUnitEntity(main), fn("main", 3, true, [])], main, [
+      include(main, "../include/calc.h"),
+      include(main, "stdio.h"),
+      {
+        from: "def:main",
+        to: "add",
+        type: "calls",
+        targetFile: main,
+        metadata: { line: 4, column: 2, callee: "add", calleeName: "add" },
+      },
+    ]);
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    // `calc.h` from src/ only matches by suffix; `stdio.h` is not indexed and stays a placeholder
+    expect(await linkHeaders(storage)).toEqual({
+      filesScanned: 3,
+      includesResolved: 2,
+      definitionsLinked: 1,
+      callsRetargeted: 1,
+    });
+
+    const [prototype] = await storage.findEntities({ type: "entity", filters: { filePath: header, name: "add" } });
+    const [definition] = await storage.findEntities({ type: "entity", filters: { filePath: source, name: "add" } });
+    const [caller] = await storage.findEntities({ type: "entity", filters: { filePath: main, name: "main" } });
+    const defines = await storage.findRelationships({
+      type: "relationship",
+      filters: { relationshipType: RelationType.DEFINES },
+    });
+    expect(defines.map((r) => [r.fromId, r.toId])).toEqual([[definition!.id, prototype!.id]]);
+
+    const calls = await storage.getRelationshipsForEntity(caller!.id, RelationType.CALLS);
+    expect(calls.map((r) => r.toId)).toEqual([definition!.id]);
+
+    const [unit] = await storage.findEntities({ type: "entity", filters: { filePath: main, name: "main.c" } });
+    const imports = await storage.getRelationshipsForEntity(unit!.id, RelationType.IMPORTS);
+    const targets = await Promise.all(imports.map(async (r) => (await storage.getEntity(r.toId))?.filePath));
+    expect(targets.sort()).toEqual(["external:///tmp/calc/src/main.c", header]);
+
+    // A second run finds everything bound and rebuilds the same defines edge
+    expect(await linkHeaders(storage)).toEqual({
+      filesScanned: 3,
+      includesResolved: 0,
+      definitionsLinked: 1,
+      callsRetargeted: 0,
+    });
+  });
+});
@@ -0,0 +1,247 @@
+import { existsSync, mkdirSync, mkdtempSync, rmSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { dirname, join } from "node:path";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { resolveGoPackages } from "../../src/core/go-package-resolver.js";
+import { pythonModuleIndex, resolveImports, resolvePythonModule } from "../../src/core/import-resolver.js";
+import { buildPackageTree } from "../../src/core/package-tree.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { detectCycles } from "../../src/tools/detect-cycles.js";
+import { findUnusedImports } from "../../src/tools/find-unused-imports.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+import { EntityType, RelationType } from "../../src/types/storage.js";
+import { edge, entity } from "../fixtures/parsed-entities.js";
+import { indexSources } from "../fixtures/parsed-sources.js";
+
+const TEST_DB_PATH = "./data/test-import-resolver.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function importOf(line: number, importData: Record<string, unknown>): ParsedEntity {
+  return {
+    name: String(importData.source),
+    type: "import",
+    location: {
+      start: { line, column: 0, index: line * 10 },
+      end: { line, column: 20, index: line * 10 + 9 },
+    },
+    importData,
+  } as any;
+}
+
+describe("resolveImports", () => {
+  let agent: IndexerAgent;
+  let root: string;
+
+  const write = (path: string, content = "") => {
+    mkdirSync(dirname(join(root, path)), { recursive: true });
+    writeFileSync(join(root, path), content);
+    return join(root, path);
+  };
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+    root = mkdtempSync(join(tmpdir(), "cgr-imports-"));
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    rmSync(root, { recursive: true, force: true });
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("resolves Python modules from the importing file, relative names and unique suffixes", () => {
+    const index = pythonModuleIndex([
+      "/repo/src/shop/__init__.py",
+      "/repo/src/shop/models.py",
+      "/repo/src/shop/models.pyi",
+      "/repo/src/shop/api/views.py",
+      "/repo/tests/test_views.py",
+    ]);
+    const views = "/repo/src/shop/api/views.py";
+
+    expect(resolvePythonModule(views, "..models", index)).toBe("/repo/src/shop/models.py");
+    expect(resolvePythonModule(views, "..", index)).toBe("/repo/src/shop/__init__.py");
+    expect(resolvePythonModule(views, "shop.models", index)).toBe("/repo/src/shop/models.py");
+    expect(resolvePythonModule("/repo/tests/test_views.py", "shop.api.views", index)).toBe(views);
+    expect(resolvePythonModule(views, "os.path", index)).toBeNull();
+  });
+
+  it("links TS/JS and Python imports to the files they load and tags the rest external", async () => {
+    const a = write("web/a.ts");
+    const b = write("web/b.ts");
+    await agent.indexEntities(
+      [
+        importOf(1, { source: "./b.js", specifiers: [{ local: "render", imported: "render" }] }),
+        importOf(2, { source: "lodash", specifiers: [], isDefault: true, local: "_" }),
+      ],
+      a,
+    );
+    await agent.indexEntities([importOf(1, { source: "./a", specifiers: [] })], b);
+
+    const init = write("app/__init__.py");
+    const models = write("app/models.py");
+    const util = write("app/util.py");
+    const main = write("main.py");
+    await agent.indexEntities(
+      [importOf(1, { source: ".util", specifiers: [{ local: "slug" }], fromModule: ".util" })],
+      models,
+    );
+    await agent.indexEntities(
+      [importOf(1, { source: ".models", specifiers: [{ local: "User", imported: "User" }], fromModule: ".models" })],
+      util,
+    );
+    await agent.indexEntities([], init);
+    await agent.indexEntities(
+      [
+        importOf(1, { source: "os", specifiers: [{ local: "os" }] }),
+        importOf(2, { source: "app.util", specifiers: [{ local: "app.util" }] }),
+        importOf(3, { source: "app", specifiers: [{ local: "models", imported: "models" }], fromModule: "app" }),
+      ],
+      main,
+    );
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    expect(await resolveImports(storage, undefined, { roots: [root] })).toEqual({
+      filesScanned: 5,
+      importsResolved: 6,
+      importsExternal: 2,
+    });
+
+    const moduleImports = async (filePath: string) => {
+      const edges: Array<[string, string]> = [];
+      const filters = { filePath, entityType: EntityType.IMPORT };
+      for (const imp of await storage.findEntities({ type: "entity", filters })) {
+        for (const rel of await storage.getRelationshipsForEntity(imp.id, RelationType.IMPORTS)) {
+          if (rel.fromId !== imp.id || rel.metadata?.moduleImport === undefined) continue;
+          const target = rel.metadata.external ? `external:${(await storage.getEntity(rel.toId))?.name}` : "";
+          edges.push([rel.metadata.moduleImport, target || rel.metadata.resolvedFile]);
+        }
+      }
+      return edges.sort();
+    };
+    expect(await moduleImports(a)).toEqual([
+      ["./b.js", b],
+      ["lodash", "external:lodash"],
+    ]);
+    expect(await moduleImports(main)).toEqual([
+      ["app", init],
+      ["app.models", models],
+      ["app.util", util],
+      ["os", "external:os"],
+    ]);
+
+    // `import app.util` now points at the module only, not at a placeholder named after it
+    const [utilImport] = await storage.findEntities({ type: "entity", filters: { filePath: main, name: "app.util" } });
+    const utilEdges = await storage.getRelationshipsForEntity(utilImport!.id, RelationType.IMPORTS);
+    expect(utilEdges.map((rel) => rel.metadata?.resolvedFile)).toEqual([util]);
+
+    // Re-running for one file changes nothing
+    await resolveImports(storage, [b], { roots: [root] });
+    expect(await moduleImports(a)).toEqual([
+      ["./b.js", b],
+      ["lodash", "external:lodash"],
+    ]);
+
+    const cycles = await detectCycles(storage);
+    expect(cycles.cycles.map((cycle) => cycle.members)).toEqual([
+      [models, util],
+      [a, b],
+    ]);
+  });
+
+  it("links the imports of parsed TypeScript files to the files they load", async () => {
+    const a = join(root, "web", "a.ts");
+    const b = join(root, "web", "b.ts");
+    await indexSources(agent, {
+      [a]: `import _ from "lodash";
+import { render } from "./b.js";
+
+export function main(): string {
+  return render(_.identity("ok"));
+}
+`,
+      [b]: `export function render(text: string): string {
+  return text;
+}
+`,
+    });
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    expect(await resolveImports(storage, undefined, { roots: [root] })).toMatchObject({
+      importsResolved: 1,
+      importsExternal: 1,
+    });
+
+    const edges: Array<[string, string]> = [];
+    const filters = { filePath: a, entityType: EntityType.IMPORT };
+    for (const imp of await storage.findEntities({ type: "entity", filters })) {
+      for (const rel of await storage.getRelationshipsForEntity(imp.id, RelationType.IMPORTS)) {
+        if (rel.fromId !== imp.id || rel.metadata?.moduleImport === undefined) continue;
+        edges.push([rel.metadata.moduleImport, rel.metadata.external ? "external" : rel.metadata.resolvedFile]);
+      }
+    }
+    expect(edges.sort()).toEqual([
+      ["./b.js", b],
+      ["lodash", "external"],
+    ]);
+  });
+
+  it("binds Go imports to package containers and names them by the declared package", async () => {
+    write("go.mod", "module example.com/shop\n\ngo 1.22\n");
+    const handler = write("api/handler.go");
+    const store = write("internal/store/open.go");
+    await agent.indexEntities(
+      [
+        entity("s:pkg", "db", "module", 1, { isPackage: true }),
+        entity("s:Open", "Open", "function", 3, { package: "db" }),
+      ],
+      store,
+      [],
+    );
+    await agent.indexEntities(
+      [
+        entity("h:pkg", "api", "module", 1, { isPackage: true }),
+        entity("h:Show", "Show", "function", 6, { package: "api" }),
+      ],
+      handler,
+      [
+        edge("h:pkg", "example.com/shop/internal/store", "imports", { importType: "package", line: 3 }),
+        edge("h:pkg", "fmt", "imports", { importType: "package", line: 4 }),
+        edge("h:Show", "h:Show:function:db.Open", "calls", { line: 7, callee: "db.Open", calleeName: "Open" }),
+      ],
+    );
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    await buildPackageTree(storage, { roots: [root] });
+
+    expect(await resolveImports(storage)).toEqual({ filesScanned: 2, importsResolved: 1, importsExternal: 1 });
+
+    const [clause] = await storage.findEntities({ type: "entity", filters: { filePath: handler, name: "api" } });
+    const imports = (await storage.getRelationshipsForEntity(clause!.id, RelationType.IMPORTS))
+      .filter((rel) => rel.fromId === clause!.id)
+      .map((rel) => [rel.metadata?.importPath ?? null, rel.metadata?.external ?? false]);
+    expect(imports.sort()).toEqual([
+      [null, true],
+      ["example.com/shop/internal/store", false],
+    ]);
+
+    // The package declares `db`, which is what the import binds; `fmt` is not used anywhere
+    const unused = await findUnusedImports(storage, { checkSource: false });
+    expect(unused.unused.map((u) => [u.name, u.import])).toEqual([["fmt", "fmt"]]);
+
+    // The package resolver still reads bound imports
+    expect((await resolveGoPackages(storage, [handler])).callsResolved).toBe(1);
+  });
+});
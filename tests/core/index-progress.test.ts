@@ -0,0 +1,48 @@
+import { describe, expect, it } from "@jest/globals";
+import { type IndexProgress, IndexProgressTracker, overallPercent } from "../../src/core/index-progress.js";
+
+describe("IndexProgressTracker", () => {
+  it("tracks files through the phases and estimates the time left", async () => {
+    const tracker = new IndexProgressTracker();
+    const seen: IndexProgress[] = [];
+    const stop = tracker.onProgress("run-1", (p) => seen.push(p));
+
+    tracker.start("run-1");
+    tracker.report("run-1", { phase: "parsing", totalFiles: 40, filesProcessed: 0 });
+    await new Promise((resolve) => setTimeout(resolve, 20));
+    tracker.report("run-1", { filesProcessed: 10 });
+
+    const parsing = tracker.get("run-1")!;
+    expect(parsing).toMatchObject({ phase: "parsing", filesProcessed: 10, totalFiles: 40, percent: 25 });
+    expect(parsing.etaMs).toBeGreaterThan(0);
+    expect(parsing.filesPerSecond).toBeGreaterThan(0);
+
+    tracker.report("run-1", { phase: "resolving", filesProcessed: 40 });
+    expect(tracker.get("run-1")?.etaMs).toBeNull();
+    tracker.finish("run-1", "completed");
+    // A late report from a task that outlived its run changes nothing
+    tracker.report("run-1", { phase: "parsing", filesProcessed: 5 });
+
+    expect(tracker.get("run-1")).toMatchObject({ phase: "completed", percent: 100 });
+    expect(seen.map((p) => p.phase)).toEqual(["discovering", "parsing", "parsing", "resolving", "completed"]);
+    const overall = seen.map(overallPercent);
+    expect(overall).toEqual([...overall].sort((a, b) => (a ?? 0) - (b ?? 0)));
+    stop();
+  });
+
+  it("ignores runs it never started and returns the latest run", () => {
+    const tracker = new IndexProgressTracker();
+    tracker.report("unknown", { phase: "parsing", totalFiles: 3 });
+    expect(tracker.get("unknown")).toBeUndefined();
+
+    tracker.start("a");
+    tracker.start("b");
+    expect(tracker.running()?.taskId).toBe("b");
+    tracker.finish("b", "cancelled", "stopped");
+    expect(tracker.latest()).toMatchObject({ taskId: "b", phase: "cancelled", error: "stopped" });
+    expect(tracker.running()?.taskId).toBe("a");
+    tracker.finish("a", "failed");
+    expect(tracker.running()).toBeUndefined();
+    expect(overallPercent(tracker.latest()!)).toBeNull();
+  });
+});
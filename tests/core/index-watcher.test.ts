@@ -0,0 +1,78 @@
+import { mkdirSync, mkdtempSync, renameSync, rmSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { join } from "node:path";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexWatcher, type WatchBatch } from "../../src/core/index-watcher.js";
+import { DEFAULT_INDEX_EXCLUDE_PATTERNS } from "../../src/utils/index-file-collection.js";
+
+describe("IndexWatcher", () => {
+  let root: string;
+  let batches: WatchBatch[];
+  let watcher: IndexWatcher;
+
+  beforeEach(() => {
+    root = mkdtempSync(join(tmpdir(), "cgr-watch-"));
+    mkdirSync(join(root, "src"));
+    writeFileSync(join(root, "src", "app.ts"), "export const x = 1;\n");
+    batches = [];
+    watcher = new IndexWatcher({
+      directory: root,
+      excludePatterns: [...DEFAULT_INDEX_EXCLUDE_PATTERNS],
+      debounceMs: 60_000,
+      onBatch: async (batch) => {
+        batches.push(batch);
+      },
+    });
+  });
+
+  afterEach(() => {
+    watcher.stop();
+    rmSync(root, { recursive: true, force: true });
+  });
+
+  it("coalesces repeated saves into a single batch", async () => {
+    const file = join(root, "src", "app.ts");
+    watcher.record(file);
+    watcher.record(file);
+    watcher.record(file);
+    await watcher.flush();
+
+    expect(batches).toEqual([{ changed: [file], deleted: [], rescan: false }]);
+    expect(watcher.getStatus()).toMatchObject({ pending: 0, batches: 1, filesChanged: 1 });
+  });
+
+  it("resolves atomic saves and short-lived files by their final state", async () => {
+    const file = join(root, "src", "app.ts");
+    const temp = join(root, "src", "app.ts.tmp");
+    const scratch = join(root, "src", "scratch.ts");
+
+    writeFileSync(temp, "export const x = 2;\n");
+    watcher.record(temp);
+    renameSync(temp, file);
+    watcher.record(file);
+    writeFileSync(scratch, "export {};\n");
+    watcher.record(scratch);
+    rmSync(scratch);
+    watcher.record(scratch);
+    await watcher.flush();
+
+    expect(batches).toHaveLength(1);
+    expect(batches[0]?.changed).toEqual([file]);
+    expect(batches[0]?.deleted).toEqual([scratch]);
+  });
+
+  it("ignores excluded paths and requests a rescan for directory changes", async () => {
+    mkdirSync(join(root, "node_modules"));
+    watcher.record(join(root, "node_modules", "dep.js"));
+    watcher.record(join(root, "README.txt"));
+    await watcher.flush();
+    expect(batches).toHaveLength(0);
+
+    watcher.start();
+    rmSync(join(root, "src"), { recursive: true });
+    watcher.record(join(root, "src"));
+    await watcher.flush();
+
+    expect(batches).toEqual([{ changed: [], deleted: [], rescan: true }]);
+  });
+});
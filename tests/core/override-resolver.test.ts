@@ -0,0 +1,109 @@
+import { existsSync, rmSync } from "node:fs";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { isCompatibleOverride, overrideRule, resolveOverrides } from "../../src/core/override-resolver.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { findOverrides } from "../../src/tools/find-overrides.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+import { type Entity, RelationType } from "../../src/types/storage.js";
+
+const TEST_DB_PATH = "./data/test-override-resolver.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function e(name: string, type: string, start: number, end: number): ParsedEntity {
+  return {
+    name,
+    type,
+    location: {
+      start: { line: start, column: 0, index: start * 10 },
+      end: { line: end, column: 0, index: end * 10 },
+    },
+  } as any;
+}
+
+function method(parameters: string[], returnType: string): Entity {
+  return { name: "Read", metadata: { parameters, returnType } } as any;
+}
+
+describe("isCompatibleOverride", () => {
+  it("applies the signature rule of the owner's language", () => {
+    expect(overrideRule("io/reader.go")).toBe("exact");
+    expect(overrideRule("src/Shape.java")).toBe("parameters");
+    expect(overrideRule("shapes.py")).toBe("name");
+
+    const base = method(["p: []byte"], "(int, error)");
+    expect(isCompatibleOverride(method(["buf: []byte"], "(int, error)"), base, "exact")).toBe(true);
+    expect(isCompatibleOverride(method(["buf: []byte"], "int"), base, "exact")).toBe(false);
+    // Covariant returns are fine where only parameters have to agree
+    expect(isCompatibleOverride(method(["buf: []byte"], "int"), base, "parameters")).toBe(true);
+    expect(isCompatibleOverride(method(["buf: string"], "(int, error)"), base, "parameters")).toBe(false);
+  });
+});
+
+describe("resolveOverrides", () => {
+  let agent: IndexerAgent;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("links methods to the nearest base declaration across files", async () => {
+    await agent.indexEntities(
+      [
+        e("Shape", "class", 1, 20),
+        e("area", "function", 2, 4),
+        e("describe", "function", 5, 7),
+        e("Square", "class", 30, 40),
+        e("area", "function", 31, 33),
+      ],
+      "/tmp/shapes.py",
+      [{ from: "Square", to: "Shape", type: "extends", metadata: { line: 30 } }],
+    );
+    // The base class lives in another file, so the edge stops at a placeholder until resolution
+    await agent.indexEntities([e("Cube", "class", 1, 10), e("area", "function", 2, 4)], "/tmp/cube.py", [
+      { from: "Cube", to: "Square", type: "extends", metadata: { line: 1 } },
+    ]);
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    expect(await resolveOverrides(storage)).toEqual({ methodsScanned: 4, overridesFound: 2 });
+    // Rebuilding writes the same edges again
+    expect((await resolveOverrides(storage)).overridesFound).toBe(2);
+    const edges = await storage.findRelationships({
+      type: "relationship",
+      filters: { relationshipType: RelationType.OVERRIDES },
+    });
+    expect(edges).toHaveLength(2);
+
+    const result = await findOverrides(storage, { symbol: "Shape.area" });
+    expect(result.definitions).toHaveLength(1);
+    const overriding = result.definitions[0]?.overriddenBy ?? [];
+    expect(overriding.map((o) => [o.owner?.name, o.method.filePath, o.depth, o.rule])).toEqual([
+      ["Square", "/tmp/shapes.py", 1, "name"],
+      ["Cube", "/tmp/cube.py", 2, "name"],
+    ]);
+
+    const byType = await findOverrides(storage, { symbol: "Shape" });
+    expect(byType.types[0]?.methods.map((m) => [m.method.name, m.overriddenBy])).toEqual([
+      ["area", 1],
+      ["describe", 0],
+    ]);
+    expect(byType.types[0]?.neverOverridden).toEqual(["describe"]);
+  });
+});
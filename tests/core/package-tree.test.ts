@@ -0,0 +1,136 @@
+import { existsSync, mkdirSync, mkdtempSync, rmSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { join } from "node:path";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { buildPackageTree, listContainers } from "../../src/core/package-tree.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+import { RelationType } from "../../src/types/storage.js";
+
+const TEST_DB_PATH = "./data/test-package-tree.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function entity(id: string, name: string, type: ParsedEntity["type"], start: number, end: number, metadata = {}) {
+  return {
+    id,
+    name,
+    type,
+    location: { start: { line: start, column: 0, index: start * 10 }, end: { line: end, column: 0, index: end * 10 } },
+    metadata,
+  } as ParsedEntity;
+}
+
+describe("buildPackageTree", () => {
+  let agent: IndexerAgent;
+  let root: string;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+    root = mkdtempSync(join(tmpdir(), "cgr-package-tree-"));
+    writeFileSync(join(root, "go.mod"), "module example.com/shop\n\ngo 1.22\n");
+    mkdirSync(join(root, "py", "app", "models"), { recursive: true });
+    writeFileSync(join(root, "py", "app", "__init__.py"), "");
+    writeFileSync(join(root, "py", "app", "models", "__init__.py"), "");
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    rmSync(root, { recursive: true, force: true });
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("builds containers per directory and links them to subdirectories and top-level declarations", async () => {
+    const orders = join(root, "internal", "orders", "orders.go");
+    const user = join(root, "py", "app", "models", "user.py");
+
+    await agent.indexEntities(
+      [
+        entity("o:pkg", "orders", "module", 1, 1, { isPackage: true }),
+        entity("o:Order", "Order", "class", 3, 6),
+        entity("o:Total", "Total", "method", 8, 10, { receiver: "Order" }),
+        entity("o:Place", "Place", "function", 12, 20),
+        entity("o:local", "tmp", "variable", 14, 14),
+      ],
+      orders,
+      [],
+    );
+    await agent.indexEntities(
+      [entity("u:User", "User", "class", 1, 9), entity("u:save", "save", "method", 3, 5, { parentClass: "User" })],
+      user,
+      [],
+    );
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    const build = await buildPackageTree(storage, { roots: [root] });
+    expect(build).toMatchObject({ containersRemoved: 0, edgesRemoved: 0 });
+    expect(build.containers).toBe(build.containersAdded);
+
+    const containers = await listContainers(storage);
+    const byDir = new Map(containers.map((c) => [c.filePath, c]));
+    expect([...byDir.keys()].sort()).toEqual(
+      [
+        root,
+        join(root, "internal"),
+        join(root, "internal", "orders"),
+        join(root, "py"),
+        join(root, "py", "app"),
+        join(root, "py", "app", "models"),
+      ].sort(),
+    );
+    expect(byDir.get(join(root, "internal", "orders"))!.metadata).toMatchObject({
+      container: "go_package",
+      qualifiedName: "example.com/shop/internal/orders",
+    });
+    expect(byDir.get(join(root, "py", "app", "models"))!.metadata).toMatchObject({
+      container: "python_package",
+      qualifiedName: "app.models",
+    });
+    expect(byDir.get(join(root, "internal"))!.metadata).toMatchObject({
+      container: "directory",
+      qualifiedName: "internal",
+    });
+    expect(byDir.get(root)!.metadata.qualifiedName).toBe(".");
+
+    const held = async (dir: string) => {
+      const id = byDir.get(dir)!.id;
+      const rels = await storage.getRelationshipsForEntity(id, RelationType.CONTAINS);
+      const names: string[] = [];
+      for (const rel of rels.filter((r) => r.fromId === id)) {
+        names.push((await storage.getEntity(rel.toId))!.name);
+      }
+      return names.sort();
+    };
+    // Methods belong to their type and locals to their function
+    expect(await held(join(root, "internal", "orders"))).toEqual(["Order", "Place"]);
+    expect(await held(join(root, "internal"))).toEqual(["orders"]);
+    expect(await held(join(root, "py", "app", "models"))).toEqual(["User"]);
+
+    // Nothing changed, nothing is rewritten
+    expect(await buildPackageTree(storage, { roots: [root] })).toEqual({
+      containers: build.containers,
+      containersAdded: 0,
+      containersRemoved: 0,
+      edgesAdded: 0,
+      edgesRemoved: 0,
+    });
+
+    // Removing the only file of a branch drops its containers
+    await storage.deleteFileData(user);
+    const pruned = await buildPackageTree(storage, { roots: [root] });
+    expect(pruned.containersRemoved).toBe(3);
+    expect((await listContainers(storage)).map((c) => c.filePath)).not.toContain(join(root, "py"));
+  });
+});
@@ -0,0 +1,187 @@
+import { existsSync, mkdtempSync, rmSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { join } from "node:path";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { resolvePhpNamespaces } from "../../src/core/php-namespace-resolver.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import { RelationType } from "../../src/types/storage.js";
+import { edge, entity } from "../fixtures/parsed-entities.js";
+import { indexSources } from "../fixtures/parsed-sources.js";
+
+const TEST_DB_PATH = "./data/test-php-namespace-resolver.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+describe("resolvePhpNamespaces", () => {
+  let agent: IndexerAgent;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("binds qualified names, inherited methods and imported functions across namespaces", async () => {
+    const model = "/tmp/shop/src/Models/Model.php";
+    const user = "/tmp/shop/src/Models/User.php";
+    const helpers = "/tmp/shop/src/Support/helpers.php";
+    const controller = "/tmp/shop/src/Http/UserController.php";
+    const none = { classes: {}, functions: {} };
+
+    await agent.indexEntities(
+      [
+        entity("m:type", "Model", "class", 3, {
+          qualifiedName: "App\\Models\\Model",
+          namespace: "App\\Models",
+          imports: none,
+          interfaces: [],
+          traits: ["App\\Models\\Finds"],
+        }),
+        entity("m:trait", "Finds", "trait", 9, { qualifiedName: "App\\Models\\Finds", interfaces: [], traits: [] }),
+        entity("m:find", "query", "method", 10, {
+          qualifiedName: "App\\Models\\Finds::query",
+          className: "App\\Models\\Finds",
+        }),
+      ],
+      model,
+      [edge("m:type", "m:trait", "embeds"), edge("m:find", "m:trait", "contains")],
+    );
+    await agent.indexEntities(
+      [
+        entity("u:type", "User", "class", 3, {
+          qualifiedName: "App\\Models\\User",
+          namespace: "App\\Models",
+          imports: none,
+          superclass: "App\\Models\\Model",
+          interfaces: [],
+          traits: [],
+        }),
+      ],
+      user,
+      [edge("u:type", "App\\Models\\Model", "extends")],
+    );
+    await agent.indexEntities(
+      [entity("h:view", "view", "function", 3, { qualifiedName: "App\\Support\\view", namespace: "App\\Support" })],
+      helpers,
+      [],
+    );
+    await agent.indexEntities(
+      [
+        entity("c:ns", "App\\Http", "module", 1, { isNamespace: true, qualifiedName: "App\\Http" }),
+        entity("c:type", "UserController", "class", 5, {
+          qualifiedName: "App\\Http\\UserController",
+          namespace: "App\\Http",
+          imports: { classes: { user: "App\\Models\\User" }, functions: { view: "App\\Support\\view" } },
+          interfaces: [],
+          traits: [],
+        }),
+        entity("c:index", "index", "method", 7, {
+          qualifiedName: "App\\Http\\UserController::index",
+          className: "App\\Http\\UserController",
+        }),
+      ],
+      controller,
+      [
+        edge("c:ns", "App\\Models\\User", "imports"),
+        edge("c:index", "c:type", "contains"),
+        edge("c:index", "App\\Models\\User", "references"),
+        edge("c:index", "query", "calls", {
+          line: 8,
+          column: 8,
+          callee: "User::query",
+          calleeName: "query",
+          receiverType: "App\\Models\\User",
+        }),
+        edge("c:index", "view", "calls", { line: 9, column: 8, callee: "view", calleeName: "view" }),
+        edge("c:index", "strlen", "calls", { line: 10, column: 8, callee: "strlen", calleeName: "strlen" }),
+      ],
+    );
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    expect(await resolvePhpNamespaces(storage)).toEqual({ filesScanned: 4, namesResolved: 3, callsResolved: 2 });
+
+    const [parent] = await storage.findEntities({ type: "entity", filters: { filePath: model, name: "Model" } });
+    const [child] = await storage.findEntities({ type: "entity", filters: { filePath: user, name: "User" } });
+    const extendsEdges = await storage.getRelationshipsForEntity(child!.id, RelationType.EXTENDS);
+    expect(extendsEdges.map((r) => [r.toId, r.metadata?.resolvedFrom])).toEqual([[parent!.id, "php"]]);
+
+    // `User::query()` finds the method in the trait used by the parent class
+    const [index] = await storage.findEntities({ type: "entity", filters: { filePath: controller, name: "index" } });
+    const [query] = await storage.findEntities({ type: "entity", filters: { filePath: model, name: "query" } });
+    const [view] = await storage.findEntities({ type: "entity", filters: { filePath: helpers, name: "view" } });
+    const calls = await storage.getRelationshipsForEntity(index!.id, RelationType.CALLS);
+    const targets = await Promise.all(calls.map(async (r) => (await storage.getEntity(r.toId))?.filePath));
+    // `strlen()` is a builtin and stays at its placeholder
+    expect(targets.map((path) => (path?.startsWith("external://") ? "external" : path)).sort()).toEqual(
+      [model, helpers, "external"].sort(),
+    );
+    expect(calls.map((r) => r.toId)).toEqual(expect.arrayContaining([query!.id, view!.id]));
+
+    expect(await resolvePhpNamespaces(storage)).toEqual({ filesScanned: 4, namesResolved: 0, callsResolved: 0 });
+  });
+
+  it("binds a parent class and an inherited method across parsed files", async () => {
+    const root = mkdtempSync(join(tmpdir(), "cgr-php-"));
+    const model = join(root, "src", "Models", "Model.php");
+    const user = join(root, "src", "Models", "User.php");
+    try {
+      await indexSources(agent, {
+        [model]: `<?php
+
+namespace App\\Models;
+
+class Model
+{
+    public function save()
+    {
+        return true;
+    }
+}
+`,
+        [user]: `<?php
+
+namespace App\\Models;
+
+class User extends Model
+{
+    public function register()
+    {
+        return $this->save();
+    }
+}
+`,
+      });
+      const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+      expect(await resolvePhpNamespaces(storage)).toMatchObject({ namesResolved: 1, callsResolved: 1 });
+
+      const named = async (filePath: string, name: string) =>
+        (await storage.findEntities({ type: "entity", filters: { filePath, name } }))[0]!;
+      const parent = await named(model, "Model");
+      const child = await named(user, "User");
+      const extendsEdges = await storage.getRelationshipsForEntity(child.id, RelationType.EXTENDS);
+      expect(extendsEdges.map((r) => [r.toId, r.metadata?.resolvedFrom])).toEqual([[parent.id, "php"]]);
+
+      const register = await named(user, "register");
+      const calls = await storage.getRelationshipsForEntity(register.id, RelationType.CALLS);
+      expect(calls.map((r) => r.toId)).toEqual([(await named(model, "save")).id]);
+    } finally {
+      rmSync(root, { recursive: true, force: true });
+    }
+  });
+});
@@ -0,0 +1,105 @@
+import { existsSync, rmSync } from "node:fs";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { linkProtoFiles } from "../../src/core/proto-linker.js";
+import { ProtoAnalyzer } from "../../src/parsers/proto-analyzer.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { listRoutes } from "../../src/tools/list-routes.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import { RelationType } from "../../src/types/storage.js";
+
+const TEST_DB_PATH = "./data/test-proto-linker.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+const COMMON = `syntax = "proto3";
+package acme.common;
+
+message Money {
+  int64 cents = 1;
+}
+`;
+
+const BILLING = `syntax = "proto3";
+package acme.billing;
+
+import "acme/common/money.proto";
+
+message Invoice {
+  acme.common.Money total = 1;
+}
+
+service Billing {
+  rpc Charge(Invoice) returns (common.Money);
+}
+`;
+
+describe("linkProtoFiles", () => {
+  let agent: IndexerAgent;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("binds imports to the imported file and types to their declaring message", async () => {
+    const money = "/repo/proto/acme/common/money.proto";
+    const billing = "/repo/proto/acme/billing/billing.proto";
+    const analyzer = new ProtoAnalyzer();
+    for (const [file, schema] of [
+      [money, COMMON],
+      [billing, BILLING],
+    ] as const) {
+      const { entities, relationships } = analyzer.analyze(schema, file);
+      await agent.indexEntities(
+        entities.map((e) => ({ ...e, language: "proto" })),
+        file,
+        relationships,
+      );
+    }
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    expect(await linkProtoFiles(storage)).toEqual({ filesScanned: 2, importsResolved: 1, typesResolved: 2 });
+
+    const find = async (filePath: string, name: string) =>
+      (await storage.findEntities({ type: "entity", filters: { filePath, name } }))[0]!;
+    const moneyType = await find(money, "Money");
+    const outgoing = async (filePath: string, name: string, type: RelationType) => {
+      const entity = await find(filePath, name);
+      const edges = await storage.getRelationshipsForEntity(entity.id, type);
+      return edges.filter((rel) => rel.fromId === entity.id);
+    };
+
+    const imports = await outgoing(billing, "billing.proto", RelationType.IMPORTS);
+    expect(imports.map((rel) => rel.toId)).toEqual([(await find(money, "money.proto")).id]);
+
+    // `acme.common.Money` written in full, `common.Money` relative to the `acme` package
+    const total = await outgoing(billing, "total", RelationType.REFERENCES);
+    expect(total.map((rel) => [rel.toId, rel.metadata?.resolvedFrom])).toEqual([[moneyType.id, "proto"]]);
+    const charge = await outgoing(billing, "Charge", RelationType.REFERENCES);
+    expect(charge.map((rel) => rel.toId).sort()).toEqual([moneyType.id, (await find(billing, "Invoice")).id].sort());
+
+    const { routes } = await listRoutes(storage, { method: "rpc" });
+    expect(routes).toEqual([
+      expect.objectContaining({
+        path: "/acme.billing.Billing/Charge",
+        framework: "grpc",
+        handler: expect.objectContaining({ name: "Charge", type: "method", resolved: true }),
+      }),
+    ]);
+  });
+});
@@ -0,0 +1,100 @@
+import { existsSync, mkdtempSync, rmSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { join } from "node:path";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { fullyQualifiedName } from "../../src/core/qualified-names.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { findDefinitionCandidates } from "../../src/tools/find-definition.js";
+import { findSymbolDefinitions } from "../../src/tools/find-references.js";
+import { resolveEntityCandidates } from "../../src/tools/resolve-entity.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { Entity } from "../../src/types/storage.js";
+import { entity } from "../fixtures/parsed-entities.js";
+
+const TEST_DB_PATH = "./data/test-qualified-names.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+describe("fully qualified names", () => {
+  let agent: IndexerAgent;
+  let root: string;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+    root = mkdtempSync(join(tmpdir(), "cgr-qualified-names-"));
+    writeFileSync(join(root, "go.mod"), "module example.com/shop\n\ngo 1.22\n");
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    rmSync(root, { recursive: true, force: true });
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("qualifies Go declarations by import path and others by file", () => {
+    const go = join(root, "internal", "users", "service.go");
+    const ts = join(root, "src", "user.service.ts");
+    const options = { rootDir: root, goModules: new Map() };
+    const fqn = (filePath: string, name: string, type: string, metadata: Record<string, unknown> = {}) =>
+      fullyQualifiedName({ name, type: type as Entity["type"], filePath, metadata }, options);
+
+    expect(fqn(go, "users", "module", { isPackage: true })).toBe("example.com/shop/internal/users");
+    expect(fqn(go, "Get", "method", { receiver: "Service", qualifiedName: "Service.Get" })).toBe(
+      "example.com/shop/internal/users.Service.Get",
+    );
+    expect(fqn(go, "db", "property", { parent: `${go}:type:Service` })).toBe(
+      "example.com/shop/internal/users.Service.db",
+    );
+    expect(fqn(ts, "get", "method", { className: "Users" })).toBe("src/user.service#Users.get");
+    expect(fqn(ts, "x", "import")).toBeNull();
+  });
+
+  it("gives same-named symbols of different packages distinct names, each resolvable on its own", async () => {
+    const billing = join(root, "billing", "users.go");
+    const accounts = join(root, "accounts", "users.go");
+    const web = join(root, "web", "users.ts");
+    const admin = join(root, "admin", "users.ts");
+    await agent.indexEntities([entity("b:GetUser", "GetUser", "function", 3, { package: "users" })], billing, [], {
+      rootDir: root,
+    });
+    await agent.indexEntities([entity("a:GetUser", "GetUser", "function", 9, { package: "users" })], accounts, [], {
+      rootDir: root,
+    });
+    await agent.indexEntities([entity("w:GetUser", "GetUser", "function", 1)], web, [], { rootDir: root });
+    await agent.indexEntities([entity("d:GetUser", "GetUser", "function", 1)], admin, [], { rootDir: root });
+
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    const all = await findSymbolDefinitions(storage, { symbol: "GetUser" });
+    expect(all.map((e) => e.metadata.fqn).sort()).toEqual([
+      "admin/users#GetUser",
+      "example.com/shop/accounts.GetUser",
+      "example.com/shop/billing.GetUser",
+      "web/users#GetUser",
+    ]);
+
+    for (const [fqn, file] of [
+      ["example.com/shop/billing.GetUser", billing],
+      ["example.com/shop/accounts.GetUser", accounts],
+      ["web/users#GetUser", web],
+      ["admin/users#GetUser", admin],
+    ] as const) {
+      expect((await findSymbolDefinitions(storage, { symbol: fqn })).map((e) => e.filePath)).toEqual([file]);
+      const [definition] = await findDefinitionCandidates(storage, { symbol: fqn, limit: 5 });
+      expect(definition?.entity.filePath).toBe(file);
+      const [best] = await resolveEntityCandidates({ storage, name: fqn, limit: 5 });
+      expect(best?.entity.filePath).toBe(file);
+      expect(best?.reasons).toContain("exact_fqn");
+    }
+  });
+});
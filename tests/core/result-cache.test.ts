@@ -0,0 +1,94 @@
+import { existsSync, rmSync } from "node:fs";
+import { afterEach, describe, expect, it } from "@jest/globals";
+import { ResultCache, resultCacheKey } from "../../src/core/result-cache.js";
+import { SQLiteManager } from "../../src/storage/sqlite-manager.js";
+
+const TEST_DB_PATH = "./data/test-result-cache.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+describe("resultCacheKey", () => {
+  it("ignores whitespace around and within the query and the order of parameters", () => {
+    const key = resultCacheKey("query", "  token   refresh ", { pageSize: 10, scope: { kinds: ["function"] } }, "m/a");
+    expect(resultCacheKey("query", "token refresh", { scope: { kinds: ["function"] }, pageSize: 10 }, "m/a")).toBe(
+      key,
+    );
+    expect(resultCacheKey("query", "token refresh", { pageSize: 20, scope: { kinds: ["function"] } }, "m/a")).not.toBe(
+      key,
+    );
+    expect(resultCacheKey("semantic_search", "token refresh", { pageSize: 10 }, "m/a")).not.toBe(
+      resultCacheKey("semantic_search", "token refresh", { pageSize: 10 }, "m/b"),
+    );
+  });
+});
+
+describe("ResultCache", () => {
+  it("answers until the index signature changes and keeps nothing computed across a write", () => {
+    let signature = "1";
+    const cache = new ResultCache({ maxEntries: 10, maxBytes: 1024 }, () => signature);
+
+    const generation = cache.currentGeneration();
+    cache.set("a", { items: [1, 2] }, generation);
+    const hit = cache.get<{ items: number[] }>("a");
+    expect(hit).toEqual({ items: [1, 2] });
+    // Each hit is a copy
+    hit!.items.push(3);
+    expect(cache.get("a")).toEqual({ items: [1, 2] });
+
+    signature = "2";
+    expect(cache.get("a")).toBeUndefined();
+    expect(cache.getStats()).toMatchObject({ generation: 1, entries: 0, invalidations: 1 });
+
+    // Computed before the write, stored after it
+    const before = cache.currentGeneration();
+    signature = "3";
+    cache.set("b", { items: [] }, before);
+    expect(cache.get("b")).toBeUndefined();
+  });
+
+  it("evicts the least recently used results beyond its caps", () => {
+    const cache = new ResultCache({ maxEntries: 2, maxBytes: 64 }, () => "1");
+    const generation = cache.currentGeneration();
+    cache.set("a", "x", generation);
+    cache.set("b", "y", generation);
+    cache.get("a");
+    cache.set("c", "z", generation);
+    expect([cache.get("a"), cache.get("b"), cache.get("c")]).toEqual(["x", undefined, "z"]);
+
+    cache.set("big", "w".repeat(100), generation);
+    expect(cache.get("big")).toBeUndefined();
+
+    const disabled = new ResultCache({ maxEntries: 0, maxBytes: 64 }, () => "1");
+    disabled.set("a", "x", disabled.currentGeneration());
+    expect(disabled.get("a")).toBeUndefined();
+  });
+});
+
+describe("SQLiteManager.getWriteSignature", () => {
+  afterEach(() => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+  });
+
+  it("changes with writes of this and other connections, and not with reads", () => {
+    const writer = new SQLiteManager({ path: TEST_DB_PATH });
+    const other = new SQLiteManager({ path: TEST_DB_PATH });
+    writer.initialize();
+    other.initialize();
+    writer.getConnection().exec("CREATE TABLE IF NOT EXISTS notes (body TEXT)");
+
+    const initial = writer.getWriteSignature();
+    writer.getConnection().prepare("SELECT COUNT(*) FROM notes").get();
+    expect(writer.getWriteSignature()).toBe(initial);
+
+    writer.getConnection().prepare("INSERT INTO notes (body) VALUES (?)").run("one");
+    const afterOwn = writer.getWriteSignature();
+    expect(afterOwn).not.toBe(initial);
+
+    other.getConnection().prepare("INSERT INTO notes (body) VALUES (?)").run("two");
+    expect(writer.getWriteSignature()).not.toBe(afterOwn);
+
+    other.close();
+    writer.close();
+  });
+});
@@ -0,0 +1,173 @@
+import { existsSync, mkdtempSync, rmSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { join } from "node:path";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { resolveRubyConstants } from "../../src/core/ruby-constant-resolver.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import { RelationType } from "../../src/types/storage.js";
+import { edge, entity } from "../fixtures/parsed-entities.js";
+import { indexSources } from "../fixtures/parsed-sources.js";
+
+const TEST_DB_PATH = "./data/test-ruby-constant-resolver.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+describe("resolveRubyConstants", () => {
+  let agent: IndexerAgent;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("merges reopened classes and binds superclasses, mixins, associations and calls", async () => {
+    const base = "/tmp/blog/app/models/application_record.rb";
+    const concern = "/tmp/blog/app/models/concerns/named.rb";
+    const user = "/tmp/blog/app/models/user.rb";
+    const extension = "/tmp/blog/lib/ext/user.rb";
+    const post = "/tmp/blog/app/models/post.rb";
+
+    await agent.indexEntities(
+      [entity("a:type", "ApplicationRecord", "class", 1, { qualifiedName: "ApplicationRecord", mixins: [] })],
+      base,
+      [],
+    );
+    await agent.indexEntities(
+      [
+        entity("n:type", "Named", "module", 1, { qualifiedName: "Named", mixins: [] }),
+        entity("n:name", "display_name", "method", 2, { qualifiedName: "Named#display_name" }),
+      ],
+      concern,
+      [edge("n:name", "n:type", "contains")],
+    );
+    await agent.indexEntities(
+      [
+        entity("u:type", "User", "class", 1, { qualifiedName: "User", superclass: "ApplicationRecord", mixins: [] }),
+        entity("u:greet", "greet", "method", 4, { qualifiedName: "User#greet" }),
+      ],
+      user,
+      [
+        edge("u:type", "ApplicationRecord", "extends"),
+        edge("u:type", "Post", "references"),
+        edge("u:greet", "u:type", "contains"),
+        edge("u:greet", "display_name", "calls", {
+          line: 5,
+          column: 4,
+          callee: "display_name",
+          calleeName: "display_name",
+        }),
+      ],
+    );
+    await agent.indexEntities(
+      [
+        entity("x:type", "User", "class", 1, { qualifiedName: "User", mixins: [{ kind: "include", module: "Named" }] }),
+        entity("x:admins", "admins", "method", 4, { qualifiedName: "User.admins", isClassMethod: true }),
+      ],
+      extension,
+      [
+        edge("x:type", "Named", "implements"),
+        edge("x:admins", "x:type", "contains"),
+        edge("x:admins", "where", "calls", { line: 5, column: 4, callee: "where", calleeName: "where" }),
+      ],
+    );
+    await agent.indexEntities(
+      [entity("p:type", "Post", "class", 1, { qualifiedName: "Post", superclass: "ApplicationRecord", mixins: [] })],
+      post,
+      [edge("p:type", "ApplicationRecord", "extends"), edge("p:type", "User", "references")],
+    );
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    expect(await resolveRubyConstants(storage)).toEqual({
+      filesScanned: 5,
+      reopenedMerged: 1,
+      constantsResolved: 5,
+      callsResolved: 1,
+    });
+
+    // `app/models/user.rb` sorts first and keeps the class
+    const users = await storage.findEntities({ type: "entity", filters: { name: "User" } });
+    const merged = users.find((u) => u.filePath === user)!;
+    expect(users.map((u) => u.filePath)).not.toContain(extension);
+    expect(merged.metadata?.reopenedFiles).toEqual([user, extension]);
+
+    const outgoing = (await storage.getRelationshipsForEntity(merged.id)).filter((r) => r.fromId === merged.id);
+    const names = await Promise.all(
+      outgoing.map(async (r) => `${r.type}:${(await storage.getEntity(r.toId))?.filePath}`),
+    );
+    expect(names.sort()).toEqual([`extends:${base}`, `implements:${concern}`, `references:${post}`]);
+
+    const [admins] = await storage.findEntities({ type: "entity", filters: { filePath: extension, name: "admins" } });
+    const contains = await storage.getRelationshipsForEntity(admins!.id, RelationType.CONTAINS);
+    expect(contains.map((r) => r.toId)).toEqual([merged.id]);
+
+    // `display_name` comes from the module included by the other fragment
+    const [greet] = await storage.findEntities({ type: "entity", filters: { filePath: user, name: "greet" } });
+    const [named] = await storage.findEntities({
+      type: "entity",
+      filters: { filePath: concern, name: "display_name" },
+    });
+    const calls = await storage.getRelationshipsForEntity(greet!.id, RelationType.CALLS);
+    expect(calls.map((r) => [r.toId, r.metadata?.resolvedFrom])).toEqual([[named!.id, "ruby"]]);
+
+    expect(await resolveRubyConstants(storage)).toEqual({
+      filesScanned: 5,
+      reopenedMerged: 0,
+      constantsResolved: 0,
+      callsResolved: 0,
+    });
+  });
+
+  it("binds a superclass and an inherited class method across parsed files", async () => {
+    const root = mkdtempSync(join(tmpdir(), "cgr-ruby-"));
+    const base = join(root, "app", "models", "application_record.rb");
+    const user = join(root, "app", "models", "user.rb");
+    try {
+      await indexSources(agent, {
+        [base]: `class ApplicationRecord
+  def self.where(*conditions)
+    conditions
+  end
+end
+`,
+        [user]: `class User < ApplicationRecord
+  def self.admins
+    where(admin: true)
+  end
+end
+`,
+      });
+      const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+      expect(await resolveRubyConstants(storage)).toMatchObject({ constantsResolved: 1, callsResolved: 1 });
+
+      const named = async (filePath: string, name: string) =>
+        (await storage.findEntities({ type: "entity", filters: { filePath, name } }))[0]!;
+      const parent = await named(base, "ApplicationRecord");
+      const child = await named(user, "User");
+      const extendsEdges = await storage.getRelationshipsForEntity(child.id, RelationType.EXTENDS);
+      expect(extendsEdges.map((r) => [r.toId, r.metadata?.resolvedFrom])).toEqual([[parent.id, "ruby"]]);
+
+      const admins = await named(user, "admins");
+      const calls = await storage.getRelationshipsForEntity(admins.id, RelationType.CALLS);
+      expect(calls.map((r) => r.toId)).toEqual([(await named(base, "where")).id]);
+    } finally {
+      rmSync(root, { recursive: true, force: true });
+    }
+  });
+});
@@ -0,0 +1,103 @@
+import { describe, expect, it } from "@jest/globals";
+import { ServerActivity } from "../../src/core/server-activity.js";
+import { AgentBusyError, TaskCancelledError } from "../../src/types/errors.js";
+
+function deferred() {
+  let release!: () => void;
+  const done = new Promise<void>((resolve) => {
+    release = resolve;
+  });
+  return { done, release };
+}
+
+describe("ServerActivity", () => {
+  it("runs writes one at a time in call order while reads go ahead", async () => {
+    const activity = new ServerActivity();
+    const order: string[] = [];
+    const first = deferred();
+
+    const writes = [
+      activity.write("index", async () => {
+        order.push("index:start");
+        await first.done;
+        order.push("index:end");
+      }),
+      activity.write("update_index", async () => {
+        order.push("update_index");
+      }),
+      activity.write("compact_index", async () => {
+        order.push("compact_index");
+      }),
+    ];
+    await new Promise((resolve) => setTimeout(resolve, 0));
+    await activity.read("semantic_search", async () => {
+      order.push("semantic_search");
+    });
+
+    expect(activity.snapshot()).toMatchObject({
+      state: "indexing",
+      writer: { tool: "index" },
+      queuedWrites: [{ tool: "update_index" }, { tool: "compact_index" }],
+    });
+    first.release();
+    await Promise.all(writes);
+
+    expect(order).toEqual(["index:start", "semantic_search", "index:end", "update_index", "compact_index"]);
+    expect(activity.snapshot()).toMatchObject({ state: "idle", writer: null, queuedWrites: [], activeReads: 0 });
+  });
+
+  it("drops a write from the queue when its call is cancelled", async () => {
+    const activity = new ServerActivity();
+    const running = deferred();
+    const ran: string[] = [];
+    const first = activity.write("index", () => running.done);
+    const controller = new AbortController();
+    const cancelled = activity.write("update_index", async () => ran.push("update_index"), controller.signal);
+    const next = activity.write("reindex_file", async () => ran.push("reindex_file"));
+
+    controller.abort("client went away");
+    await expect(cancelled).rejects.toBeInstanceOf(TaskCancelledError);
+    expect(activity.snapshot().queuedWrites.map((write) => write.tool)).toEqual(["reindex_file"]);
+
+    running.release();
+    await Promise.all([first, next]);
+    expect(ran).toEqual(["reindex_file"]);
+  });
+
+  it("refuses writes past the queue limit with queue_full", async () => {
+    const activity = new ServerActivity(1);
+    const running = deferred();
+    const first = activity.write("index", () => running.done);
+    const queued = activity.write("update_index", async () => "done");
+
+    const refused = activity.write("clean_index", async () => "never");
+    await expect(refused).rejects.toBeInstanceOf(AgentBusyError);
+    await refused.catch((error: AgentBusyError) =>
+      expect(error.details).toMatchObject({ agentId: "write-queue", reason: "queue_full", maxQueue: 1 }),
+    );
+
+    running.release();
+    await first;
+    await expect(queued).resolves.toBe("done");
+  });
+
+  it("reports querying while only reads run, leaving out the caller", async () => {
+    const activity = new ServerActivity();
+    const search = deferred();
+    const pending = activity.read("semantic_search", () => search.done);
+
+    await activity.read("graph_stats", async () => {
+      expect(activity.snapshot("graph_stats")).toMatchObject({
+        state: "querying",
+        activeReads: 1,
+        readsByTool: { semantic_search: 1 },
+      });
+    });
+    search.release();
+    await pending;
+
+    await activity.read("graph_stats", async () => {
+      expect(activity.snapshot("graph_stats")).toMatchObject({ state: "idle", activeReads: 0, readsByTool: {} });
+    });
+  });
+});
@@ -0,0 +1,53 @@
+import { describe, expect, it } from "@jest/globals";
+import { SessionMetrics } from "../../src/core/session-metrics.js";
+
+describe("SessionMetrics", () => {
+  it("counts calls, errors and latencies per tool and splits queries from writes", () => {
+    const metrics = new SessionMetrics();
+    for (const ms of [10, 20, 30, 40]) metrics.recordToolCall("semantic_search", ms, { success: true });
+    metrics.recordToolCall("semantic_search", 5, { success: true, cached: true });
+    metrics.recordToolCall("index", 900, { success: false, errorType: "timeout", write: true });
+
+    const snapshot = metrics.snapshot();
+    expect(snapshot.tools.semantic_search).toMatchObject({
+      calls: 5,
+      errors: 0,
+      cached: 1,
+      totalMs: 105,
+      avgMs: 21,
+      p50Ms: 20,
+      p95Ms: 40,
+      maxMs: 40,
+    });
+    expect(snapshot.tools.index).toMatchObject({ calls: 1, errors: 1, errorTypes: { timeout: 1 } });
+    expect(snapshot.totals).toEqual({ calls: 6, queries: 5, writes: 1, errors: 1, cached: 1, totalMs: 1005 });
+  });
+
+  it("adds up parsing, storing and embedding work, and starts over on reset", () => {
+    const metrics = new SessionMetrics();
+    metrics.recordParse(12);
+    metrics.recordParse(8, true);
+    metrics.recordIndexedFile(40, 55, 2, 30);
+    metrics.recordEmbedding(10, 4, 1, 200);
+    metrics.recordEmbedding(1, 0, 0, 1);
+
+    const snapshot = metrics.snapshot();
+    expect(snapshot.parsing).toEqual({ files: 2, errors: 1, totalMs: 20, avgMs: 10 });
+    expect(snapshot.indexing).toEqual({ files: 1, entities: 40, relationships: 55, removedEntities: 2, totalMs: 30 });
+    expect(snapshot.embeddings).toEqual({
+      calls: 2,
+      texts: 11,
+      cacheHits: 7,
+      embedded: 4,
+      fallbacks: 1,
+      hitRate: 7 / 11,
+      totalMs: 201,
+    });
+
+    metrics.reset();
+    const fresh = metrics.snapshot();
+    expect(fresh.tools).toEqual({});
+    expect(fresh.parsing.files).toBe(0);
+    expect(fresh.embeddings.texts).toBe(0);
+  });
+});
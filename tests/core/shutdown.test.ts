@@ -0,0 +1,37 @@
+import { describe, expect, it } from "@jest/globals";
+import { ShutdownCoordinator, ShuttingDownError } from "../../src/core/shutdown.js";
+import { TaskCancelledError } from "../../src/types/errors.js";
+
+/** Work that runs until `signal` aborts, then fails with its reason */
+function untilAborted(signal: AbortSignal): Promise<string> {
+  return new Promise((_, reject) => {
+    signal.addEventListener("abort", () => setTimeout(() => reject(signal.reason), 5), { once: true });
+  });
+}
+
+describe("ShutdownCoordinator", () => {
+  it("aborts tracked work with a shutdown cancellation and waits for it to settle", async () => {
+    const lifecycle = new ShutdownCoordinator();
+    const finished = lifecycle.track(async () => "done");
+    const running = lifecycle.track(() => untilAborted(lifecycle.signal));
+    await expect(finished).resolves.toBe("done");
+    expect(lifecycle.pending).toBe(1);
+
+    const report = await lifecycle.shutdown(1000);
+
+    expect(report).toMatchObject({ drained: true, abandoned: 0 });
+    await expect(running).rejects.toBeInstanceOf(TaskCancelledError);
+    expect((lifecycle.signal.reason as TaskCancelledError).details.reason).toBe("shutdown");
+    expect(lifecycle.stopping).toBe(true);
+  });
+
+  it("refuses new work once stopping and gives up on work that outlives the timeout", async () => {
+    const lifecycle = new ShutdownCoordinator();
+    void lifecycle.track(() => new Promise(() => undefined));
+
+    const first = lifecycle.shutdown(20);
+    expect(lifecycle.shutdown(5000)).toBe(first);
+    expect(await first).toMatchObject({ drained: false, abandoned: 1 });
+    await expect(lifecycle.track(async () => "late")).rejects.toBeInstanceOf(ShuttingDownError);
+  });
+});
@@ -0,0 +1,92 @@
+import { existsSync, rmSync } from "node:fs";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { linkSqlTables } from "../../src/core/sql-linker.js";
+import { SqlAnalyzer } from "../../src/parsers/sql-analyzer.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import { RelationType } from "../../src/types/storage.js";
+
+const TEST_DB_PATH = "./data/test-sql-linker.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+const USERS = `CREATE TABLE users (
+  id serial PRIMARY KEY,
+  email text NOT NULL
+);
+`;
+
+const ORDERS = `CREATE TABLE orders (
+  id serial PRIMARY KEY,
+  user_id integer NOT NULL REFERENCES users (id)
+);
+
+ALTER TABLE users ADD COLUMN last_order_id integer REFERENCES orders (id);
+
+CREATE VIEW user_orders AS SELECT u.email, o.id FROM users u JOIN orders o ON o.user_id = u.id;
+`;
+
+describe("linkSqlTables", () => {
+  let agent: IndexerAgent;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("binds foreign keys and queries to the table an earlier migration created", async () => {
+    const users = "/repo/db/migrations/001_users.sql";
+    const orders = "/repo/db/migrations/002_orders.sql";
+    const analyzer = new SqlAnalyzer();
+    for (const [file, ddl] of [
+      [users, USERS],
+      [orders, ORDERS],
+    ] as const) {
+      const { entities, relationships } = analyzer.analyze(ddl, file);
+      await agent.indexEntities(
+        entities.map((e) => ({ ...e, language: "sql" })),
+        file,
+        relationships,
+      );
+    }
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    // orders -> users and user_orders -> users; the other edges were bound in their own file
+    expect(await linkSqlTables(storage)).toEqual({ filesScanned: 2, tablesResolved: 2 });
+
+    const find = async (filePath: string, name: string) =>
+      (await storage.findEntities({ type: "entity", filters: { filePath, name } }))[0]!;
+    const usersTable = await find(users, "users");
+    const ordersTable = await find(orders, "orders");
+    const outgoing = async (name: string, type: RelationType) => {
+      const entity = await find(orders, name);
+      const edges = await storage.getRelationshipsForEntity(entity.id, type);
+      return edges.filter((rel) => rel.fromId === entity.id);
+    };
+
+    const foreignKeys = await outgoing("orders", RelationType.FOREIGN_KEY);
+    expect(foreignKeys.map((rel) => [rel.toId, rel.metadata?.resolvedFrom])).toEqual([[usersTable.id, "sql"]]);
+    const read = await outgoing("user_orders", RelationType.REFERENCES);
+    expect(read.map((rel) => rel.toId).sort()).toEqual([usersTable.id, ordersTable.id].sort());
+
+    // The ALTER TABLE stand-in is never a target; its own foreign key stays on orders
+    const alteredUsers = await find(orders, "users");
+    expect(alteredUsers.metadata).toMatchObject({ altered: true });
+    expect((await outgoing("users", RelationType.FOREIGN_KEY)).map((rel) => rel.toId)).toEqual([ordersTable.id]);
+  });
+});
@@ -0,0 +1,193 @@
+import { existsSync, rmSync } from "node:fs";
+import { afterEach, beforeEach, describe, expect, it, jest } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { ConfigLoader, getConfig } from "../../src/config/yaml-config.js";
+import { linkTests } from "../../src/core/test-linker.js";
+import { isTestDeclaration, isTestFile } from "../../src/parsers/test-extractor.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { findTestedBy, findTestsFor } from "../../src/tools/find-tests.js";
+import { analyzeImpact } from "../../src/tools/impact-analysis.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+import { RelationType } from "../../src/types/storage.js";
+
+const TEST_DB_PATH = "./data/test-test-linker.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function e(name: string, type: string, start: number, end: number, extra?: Partial<ParsedEntity>): ParsedEntity {
+  return {
+    name,
+    type,
+    location: {
+      start: { line: start, column: 0, index: start * 10 },
+      end: { line: end, column: 0, index: end * 10 },
+    },
+    ...extra,
+  } as any;
+}
+
+function call(name: string, line: number) {
+  return { name, line, column: 2 };
+}
+
+describe("test detection", () => {
+  it("recognises test files and test declarations by runner convention", () => {
+    expect(isTestFile("/app/src/users.test.ts")).toBe(true);
+    expect(isTestFile("/app/src/__tests__/users.js")).toBe(true);
+    expect(isTestFile("/app/pkg/parse_test.go")).toBe(true);
+    expect(isTestFile("/app/tests/test_users.py")).toBe(true);
+    expect(isTestFile("/app/src/test/java/UsersTest.java")).toBe(true);
+    expect(isTestFile("/app/src/testing.ts")).toBe(false);
+    expect(isTestFile("/app/pkg/parse.go")).toBe(false);
+
+    const go = (name: string, metadata = {}) => ({ name, type: "function", filePath: "/p/x_test.go", metadata });
+    expect(isTestDeclaration(go("TestParse"))).toBe(true);
+    expect(isTestDeclaration(go("BenchmarkParse"))).toBe(true);
+    expect(isTestDeclaration(go("Testify"))).toBe(false);
+    expect(isTestDeclaration(go("TestParse", { receiver: "suite" }))).toBe(false);
+    expect(isTestDeclaration({ name: "test_create", type: "function", filePath: "/p/test_users.py" })).toBe(true);
+    expect(isTestDeclaration({ name: "make_user", type: "function", filePath: "/p/test_users.py" })).toBe(false);
+    expect(
+      isTestDeclaration({
+        name: "creates",
+        type: "method",
+        filePath: "/p/src/test/java/UsersTest.java",
+        metadata: { decorators: [{ name: "Test" }] },
+      }),
+    ).toBe(true);
+  });
+});
+
+describe("linkTests", () => {
+  let agent: IndexerAgent;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  async function indexProject() {
+    await agent.indexEntities(
+      [
+        e("createUser", "function", 1, 5, { calls: [call("validate", 2)] }),
+        e("validate", "function", 7, 9),
+      ],
+      "/tmp/app/src/users.ts",
+    );
+    // Calls to imported names stay on placeholders here; the linker binds them by unique name
+    await agent.indexEntities(
+      [
+        e("./users", "import", 1, 1, { importData: { source: "./users", specifiers: [{ local: "createUser" }] } }),
+        e("makeUser", "function", 3, 5, { calls: [call("createUser", 4)] }),
+        e("creates a user", "test", 8, 10, {
+          calls: [call("makeUser", 9)],
+          metadata: { framework: "jest", suite: ["users"], fullName: "users creates a user" },
+        }),
+        e("rejects bad input", "test", 12, 14, {
+          calls: [call("validate", 13)],
+          metadata: { framework: "jest", suite: ["users"], fullName: "users rejects bad input" },
+        }),
+      ],
+      "/tmp/app/src/users.test.ts",
+    );
+    await agent.indexEntities([e("Parse", "function", 3, 9)], "/tmp/app/pkg/parse.go");
+    await agent.indexEntities([e("TestParse", "function", 5, 12)], "/tmp/app/pkg/parse_test.go", [
+      { from: "TestParse", to: "Parse", type: "calls", metadata: { line: 6 } },
+    ]);
+    return getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+  }
+
+  it("links tests to the production code they reach, through test helpers", async () => {
+    const storage = await indexProject();
+
+    expect(await linkTests(storage)).toEqual({ testsScanned: 3, linksFound: 3 });
+    // Rebuilding writes the same edges again
+    expect((await linkTests(storage)).linksFound).toBe(3);
+    const edges = await storage.findRelationships({
+      type: "relationship",
+      filters: { relationshipType: RelationType.TESTS },
+    });
+    expect(edges).toHaveLength(3);
+
+    const created = await findTestedBy(storage, { test: "creates a user" });
+    expect(created.tests).toHaveLength(1);
+    expect(created.tests[0]?.suite).toEqual(["users"]);
+    expect(created.tests[0]?.covers.map((c) => [c.entity.name, c.line, c.via, c.helper])).toEqual([
+      ["createUser", 9, "calls", "makeUser"],
+    ]);
+
+    const goTests = await findTestedBy(storage, { filePath: "/tmp/app/pkg/parse_test.go" });
+    expect(goTests.tests.map((t) => [t.test.name, t.framework, t.covers.map((c) => c.entity.name)])).toEqual([
+      ["TestParse", "go", ["Parse"]],
+    ]);
+  });
+
+  it("finds the tests of a symbol and, with depth, the tests of its callers", async () => {
+    const storage = await indexProject();
+    await linkTests(storage);
+
+    const direct = await findTestsFor(storage, { symbol: "validate" });
+    expect(direct.definitions).toHaveLength(1);
+    expect(direct.definitions[0]?.tests.map((t) => [t.test.name, t.framework, t.line, t.depth])).toEqual([
+      ["rejects bad input", "jest", 13, 1],
+    ]);
+
+    const viaCallers = await findTestsFor(storage, { symbol: "validate", depth: 2 });
+    expect(viaCallers.definitions[0]?.tests.map((t) => [t.test.name, t.depth, t.through?.name ?? null])).toEqual([
+      ["rejects bad input", 1, null],
+      ["creates a user", 2, "createUser"],
+    ]);
+
+    const limited = await findTestsFor(storage, { symbol: "validate", depth: 2, limit: 1 });
+    expect(limited.total).toBe(1);
+    expect(limited.truncated).toBe(true);
+  });
+
+  it("links test files stored apart from the main graph under indexer.includeTests: false", async () => {
+    const base = getConfig();
+    const config = jest
+      .spyOn(ConfigLoader.getInstance(), "getConfig")
+      .mockReturnValue({ ...base, indexer: { ...base.indexer, includeTests: false } });
+    const storage = await indexProject().finally(() => config.mockRestore());
+    const spec = { type: "entity" as const, filters: { filePath: "/tmp/app/src/users.test.ts" } };
+    expect(await storage.findEntities(spec)).toEqual([]);
+    expect((await storage.findTestEntities(spec)).map((entity) => entity.name).sort()).toEqual([
+      "./users",
+      "creates a user",
+      "makeUser",
+      "rejects bad input",
+    ]);
+
+    expect(await linkTests(storage)).toEqual({ testsScanned: 3, linksFound: 3 });
+    const direct = await findTestsFor(storage, { symbol: "validate" });
+    expect(direct.definitions[0]?.tests.map((t) => [t.test.name, t.framework, t.line, t.depth])).toEqual([
+      ["rejects bad input", "jest", 13, 1],
+    ]);
+
+    // Their calls are bound to the production code, so a walk from it reaches them
+    const tests = await analyzeImpact(storage, { symbol: "Parse", tests: "only" });
+    expect(tests.files.flatMap((file) => file.entities.map((affected) => affected.entity.name))).toEqual(["TestParse"]);
+    expect((await analyzeImpact(storage, { symbol: "Parse", tests: "exclude" })).totalAffected).toBe(0);
+
+    // Indexed again with tests included, the file moves into the main graph
+    await agent.indexEntities([e("TestParse", "function", 5, 12)], "/tmp/app/pkg/parse_test.go");
+    const goSpec = { type: "entity" as const, filters: { filePath: "/tmp/app/pkg/parse_test.go" } };
+    expect(await storage.findTestEntities(goSpec)).toEqual([]);
+    expect((await storage.findEntities(goSpec)).map((entity) => entity.name)).toEqual(["TestParse"]);
+  });
+});
@@ -0,0 +1,53 @@
+// Expected cyclomatic complexity is noted on each function; see tests/parsers/complexity.test.ts
+package sample
+
+// 1
+func Simple() int { return 1 }
+
+// 8: if, &&, for, if, ||, two expression cases
+func Classify(n int, ok bool) string {
+	if n < 0 && ok {
+		return "neg"
+	}
+	for i := 0; i < n; i++ {
+		if i%2 == 0 || !ok {
+			continue
+		}
+	}
+	switch {
+	case n == 0:
+		return "zero"
+	case n > 100:
+		return "big"
+	default:
+	}
+	return "pos"
+}
+
+// 4: select case, two type cases; the func literal is not counted
+func Wait(ch chan int, v interface{}) int {
+	select {
+	case x := <-ch:
+		return x
+	default:
+	}
+	switch v.(type) {
+	case int:
+		return 1
+	case string:
+	}
+	f := func() int {
+		if v == nil {
+			return 1
+		}
+		return 0
+	}
+	return f()
+}
+
+type Box struct{}
+
+// 2: &&
+func (b *Box) Open(x int) bool {
+	return x > 0 && x < 10
+}
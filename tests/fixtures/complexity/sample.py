@@ -0,0 +1,35 @@
+# Expected cyclomatic complexity is noted on each function; see tests/parsers/complexity.test.ts
+
+
+# 1
+def simple():
+    return 1
+
+
+# 12: for, if, and, elif, or, while, conditional expression, two excepts, comprehension for and if
+def branchy(items, flag):
+    total = 0
+    for item in items:
+        if item > 10 and flag:
+            total += item
+        elif item < 0 or not flag:
+            total -= item
+    while total > 100:
+        total //= 2
+    try:
+        total = total if flag else 0
+    except ValueError:
+        total = 0
+    except KeyError:
+        pass
+    return [x for x in items if x]
+
+
+# 1; inner is 2
+def outer():
+    def inner(x):
+        if x:
+            return 1
+        return 0
+
+    return inner
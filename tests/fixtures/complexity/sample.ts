@@ -0,0 +1,53 @@
+// Expected cyclomatic complexity is noted on each function; see tests/parsers/complexity.test.ts
+
+// 1
+export function straight(a: number): number {
+  return a + 1;
+}
+
+// 11: for-of, if, &&, else-if, ||, two cases, ternary, catch, ??
+export function branchy(items: number[], flag?: boolean): number | undefined {
+  let total: number | undefined = 0;
+  for (const item of items) {
+    if (item > 10 && flag) {
+      total += item;
+    } else if (item < 0 || !flag) {
+      total -= item;
+    }
+  }
+  switch (total) {
+    case 0:
+      return -1;
+    case 1:
+      return 1;
+    default:
+      break;
+  }
+  try {
+    total = flag ? total : undefined;
+  } catch {
+    total = 0;
+  }
+  return total ?? 0;
+}
+
+// 1: the branch lives in the nested callback
+export function outer(values: string[]): string[] {
+  return values.map(function pick(v) {
+    if (v) return v;
+    return "";
+  });
+}
+
+export class Counter {
+  // 3: while, do-while
+  bump(n: number): number {
+    while (n > 0) {
+      n--;
+    }
+    do {
+      n++;
+    } while (n < 3);
+    return n;
+  }
+}
@@ -0,0 +1,31 @@
+import type { EntityRelationship, ParsedEntity } from "../../src/types/parser.js";
+
+/** Parsed entity spanning three lines from `line`, as an analyzer would emit it */
+export function entity(
+  id: string,
+  name: string,
+  type: ParsedEntity["type"],
+  line: number,
+  metadata: Record<string, unknown> = {},
+): ParsedEntity {
+  return {
+    id,
+    name,
+    type,
+    location: {
+      start: { line, column: 0, index: line * 10 },
+      end: { line: line + 2, column: 0, index: line * 10 + 9 },
+    },
+    metadata,
+  };
+}
+
+/** Relationship between analyzer ids, as an analyzer would emit it */
+export function edge(
+  from: string,
+  to: string,
+  type: string,
+  metadata: Record<string, unknown> = {},
+): EntityRelationship {
+  return { from, to, type: type as EntityRelationship["type"], metadata };
+}
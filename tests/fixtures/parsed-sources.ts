@@ -0,0 +1,20 @@
+import { mkdirSync, writeFileSync } from "node:fs";
+import { dirname } from "node:path";
+import type { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { TreeSitterParser } from "../../src/parsers/tree-sitter-parser.js";
+
+/**
+ * Write each source to disk, parse it and index what the analyzer emits the way an index run
+ * hands it to the indexer, so resolver tests see real ids, placeholders and metadata
+ */
+export async function indexSources(agent: IndexerAgent, sources: Record<string, string>): Promise<void> {
+  const parser = new TreeSitterParser();
+  await parser.initialize();
+  for (const [filePath, code] of Object.entries(sources)) {
+    mkdirSync(dirname(filePath), { recursive: true });
+    writeFileSync(filePath, code);
+    const parsed = await parser.parse(filePath, code, `fixture:${filePath}`);
+    const relationships = (parsed.relationships ?? []).map((rel) => ({ ...rel, targetFile: filePath }));
+    await agent.indexEntities(parsed.entities, filePath, relationships, { replaceFile: true });
+  }
+}
@@ -28,5 +28,6 @@ describe("Core tool handlers use response envelope helpers", () => {
 
     expect(src).toContain('toolFail("agent_busy"');
     expect(src).toContain('toolFail("tool_error"');
+    expect(src).toContain('toolFail("timeout"');
   });
 });
@@ -0,0 +1,52 @@
+import { collapseAnonymousEntities } from "../../src/parsers/anonymous-entities";
+import { TreeSitterParser } from "../../src/parsers/tree-sitter-parser";
+
+function at(start: number, end: number, line: number) {
+  return { start: { line, column: 0, index: start }, end: { line, column: 0, index: end } };
+}
+
+describe("anonymous entities", () => {
+  it("tags callbacks and indexes identical copies once", async () => {
+    const parser = new TreeSitterParser();
+    await parser.initialize();
+    const code = `items.forEach((item) => {
+  console.log(item);
+});
+others.forEach((item) => {
+  console.log(item);
+});
+rows.map((row) => row.id);
+function handle(item) {
+  console.log(item);
+}
+`;
+    const res = await parser.parse("loops.js", code, "hash");
+    const anonymous = res.entities.filter((e) => e.metadata?.isAnonymous);
+
+    expect(anonymous.map((e) => [e.name, e.location.start.line])).toEqual([
+      ["<anonymous>", 1],
+      ["<anonymous>", 7],
+    ]);
+    expect(anonymous[0]?.metadata).toMatchObject({ duplicates: 1, duplicateLines: [4] });
+    expect(anonymous[1]?.metadata?.duplicates).toBeUndefined();
+    expect(res.entities.find((e) => e.name === "handle")?.metadata?.isAnonymous).toBeUndefined();
+  });
+
+  it("never collapses named entities and moves the edges of dropped copies", () => {
+    const source = "f(() => 1); g(() => 1); const a = () => 1; const b = () => 1;";
+    const entities: any[] = [
+      { id: "x:1", name: "<anonymous>", type: "function", location: at(2, 9, 1) },
+      { id: "x:2", name: "<anonymous>", type: "function", location: at(14, 21, 1) },
+      { id: "a", name: "a", type: "function", location: at(34, 41, 1) },
+      { id: "b", name: "b", type: "function", location: at(53, 60, 1) },
+    ];
+    const relationships = [
+      { from: "x:2", to: "a", type: "calls" },
+      { from: "x:1", to: "x:2", type: "references" },
+    ];
+
+    const collapsed = collapseAnonymousEntities(entities, relationships, source);
+    expect(collapsed.entities.map((e) => e.id)).toEqual(["x:1", "a", "b"]);
+    expect(collapsed.relationships).toEqual([{ from: "x:1", to: "a", type: "calls" }]);
+  });
+});
@@ -228,6 +228,46 @@ extern int global_variable;
     expect(result.entities).toBeDefined();
   });
 
+  it("should separate prototypes from definitions and bind calls in the file", async () => {
+    const code = `
+#include "util.h"
+
+static int helper(const char *name);
+
+int helper(const char *name) {
+  int length = strlen(name);
+  return length;
+}
+
+int main(void) {
+  return helper("x") + log_value(1);
+}
+    `;
+
+    const result = await parser.parse("src/main.c", code, "test-hash");
+    const helpers = result.entities.filter((e) => e.name === "helper");
+    expect(helpers.map((e) => e.metadata?.isDefinition)).toEqual([false, true]);
+    expect(helpers.map((e) => e.parameters)).toEqual([
+      [{ name: "name", type: "const char *" }],
+      [{ name: "name", type: "const char *" }],
+    ]);
+    expect(result.entities.find((e) => e.name === "main")?.parameters).toEqual([]);
+    // Locals of a function body are not entities
+    expect(result.entities.some((e) => e.name === "length")).toBe(false);
+
+    const unit = result.entities.find((e) => e.metadata?.translationUnit);
+    expect(unit).toMatchObject({ name: "main.c", type: "module" });
+    const includes = result.relationships?.filter((r) => r.type === "imports");
+    expect(includes?.map((r) => [r.from, r.to])).toEqual([[unit?.id, "util.h"]]);
+
+    const calls = result.relationships?.filter((r) => r.type === "calls");
+    expect(calls?.map((r) => [r.from, r.to])).toEqual([
+      ["src/main.c:function:helper", "strlen"],
+      ["src/main.c:function:main", "src/main.c:function:helper"],
+      ["src/main.c:function:main", "log_value"],
+    ]);
+  });
+
   it("should handle multiple includes", async () => {
     const code = `
 #include <stdio.h>
@@ -0,0 +1,75 @@
+import { TreeSitterParser } from "../../src/parsers/tree-sitter-parser";
+import type { ParsedEntity } from "../../src/types/parser";
+
+function comments(entities: ParsedEntity[]) {
+  return entities.filter((e) => e.type === "comment");
+}
+
+describe("comment extraction", () => {
+  let parser: TreeSitterParser;
+
+  beforeAll(async () => {
+    parser = new TreeSitterParser();
+    await parser.initialize();
+  });
+
+  afterEach(() => {
+    parser.clearCache();
+  });
+
+  it("keeps standalone comments, merges line runs and records markers", async () => {
+    const code = `// Config loader for the service
+import { readFile } from "fs";
+
+/** Loads the config. */
+export function load(path: string) {
+  // TODO(alice): cache parsed files
+  // across calls
+  const raw = readFile(path); // FIXME handle ENOENT
+  // eslint-disable-next-line no-console
+  return raw;
+}
+`;
+    const res = await parser.parse("config.ts", code, "hash");
+    const found = comments(res.entities);
+
+    expect(found.map((c) => [c.location.start.line, c.location.end.line, c.name])).toEqual([
+      [1, 1, "Config loader for the service"],
+      [6, 7, "TODO(alice): cache parsed files"],
+      [8, 8, "FIXME handle ENOENT"],
+    ]);
+    expect(found[0]?.metadata?.enclosing).toBeUndefined();
+    expect(found[1]?.documentation).toBe("// TODO(alice): cache parsed files\n// across calls");
+    expect(found[1]?.metadata).toMatchObject({
+      style: "line",
+      markers: [{ kind: "TODO", line: 6, text: "cache parsed files", assignee: "alice" }],
+      enclosing: { name: "load", type: "function", line: 5 },
+    });
+    expect(found[2]?.metadata).toMatchObject({ trailing: true, markers: [{ kind: "FIXME", text: "handle ENOENT" }] });
+  });
+
+  it("leaves Go doc comments on their declaration and skips directives", async () => {
+    const code = `package store
+
+// cacheSize bounds the LRU.
+const cacheSize = 128
+
+func Get(key string) string {
+  /* HACK: linear scan until the index lands */
+  return key
+}
+
+//go:generate stringer -type=Kind
+`;
+    const res = await parser.parse("store.go", code, "hash");
+    const found = comments(res.entities);
+
+    expect(found).toHaveLength(1);
+    expect(found[0]?.metadata).toMatchObject({
+      style: "block",
+      markers: [{ kind: "HACK", line: 7, text: "linear scan until the index lands" }],
+      enclosing: { name: "Get", line: 6 },
+    });
+    expect(res.entities.find((e) => e.name === "cacheSize")?.documentation).toBe("// cacheSize bounds the LRU.");
+  });
+});
@@ -0,0 +1,39 @@
+import { readFileSync } from "node:fs";
+import { join } from "node:path";
+import { TreeSitterParser } from "../../src/parsers/tree-sitter-parser";
+
+const FIXTURES = join(process.cwd(), "tests", "fixtures", "complexity");
+
+describe("cyclomatic complexity", () => {
+  let parser: TreeSitterParser;
+
+  beforeAll(async () => {
+    parser = new TreeSitterParser();
+    await parser.initialize();
+  });
+
+  afterEach(() => {
+    parser.clearCache();
+  });
+
+  async function complexities(file: string): Promise<Record<string, number | undefined>> {
+    const result = await parser.parse(file, readFileSync(join(FIXTURES, file), "utf8"), `complexity-${file}`);
+    const byName: Record<string, number | undefined> = {};
+    for (const entity of result.entities) {
+      if (entity.cyclomaticComplexity !== undefined) byName[entity.name] = entity.cyclomaticComplexity;
+    }
+    return byName;
+  }
+
+  it("counts TypeScript decision points per function", async () => {
+    expect(await complexities("sample.ts")).toMatchObject({ straight: 1, branchy: 11, outer: 1, pick: 2, bump: 3 });
+  });
+
+  it("counts Go decision points per function and method", async () => {
+    expect(await complexities("sample.go")).toEqual({ Simple: 1, Classify: 8, Wait: 4, Open: 2 });
+  });
+
+  it("counts Python decision points per function", async () => {
+    expect(await complexities("sample.py")).toMatchObject({ simple: 1, branchy: 12, outer: 1, inner: 2 });
+  });
+});
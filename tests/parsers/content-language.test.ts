@@ -0,0 +1,49 @@
+import { detectLanguageFromContent, indexLanguageOf } from "../../src/parsers/content-language";
+
+describe("detectLanguageFromContent", () => {
+  it("reads the interpreter from a shebang, through env and its flags", () => {
+    expect(detectLanguageFromContent("#!/usr/bin/env python3\nprint('hi')\n")).toEqual({
+      language: "python",
+      detector: "shebang",
+    });
+    expect(detectLanguageFromContent("#!/usr/bin/env -S node --no-warnings\n")?.language).toBe("javascript");
+    expect(detectLanguageFromContent("#!/usr/local/bin/ruby -w\n")?.language).toBe("ruby");
+    expect(detectLanguageFromContent("#!/bin/bash\necho hi\n")).toBeNull();
+  });
+
+  it("reads Emacs and vim modelines", () => {
+    expect(detectLanguageFromContent("# -*- coding: utf-8; mode: python -*-\nx = 1\n")).toEqual({
+      language: "python",
+      detector: "modeline",
+    });
+    expect(detectLanguageFromContent("// vim: set ft=javascript:\nrun();\n")?.language).toBe("javascript");
+    expect(detectLanguageFromContent("# -*- coding: utf-8 -*-\n")).toBeNull();
+  });
+
+  it("scores tokens only for files without an extension", () => {
+    const python = "import os\nimport sys\n\ndef main():\n    pass\n\nif __name__ == '__main__':\n    main()\n";
+
+    expect(detectLanguageFromContent(python, "bin/tool")).toEqual({ language: "python", detector: "heuristic" });
+    expect(detectLanguageFromContent(python, "notes.txt")).toBeNull();
+    expect(detectLanguageFromContent("require 'json'\nmodule Tasks\n  def run\n  end\nend\n")?.language).toBe("ruby");
+    expect(detectLanguageFromContent("Remember to water the plants.\n")).toBeNull();
+  });
+});
+
+describe("indexLanguageOf", () => {
+  it("keeps files of the requested languages and their JSX flavours", () => {
+    const go = new Set(["go"]);
+    expect(indexLanguageOf("/repo/server/main.go", go, false)).toBe("go");
+    expect(indexLanguageOf("/repo/web/app.ts", go, false)).toBeNull();
+
+    const typescript = new Set(["typescript"]);
+    expect(indexLanguageOf("/repo/web/App.tsx", typescript, false)).toBe("tsx");
+    expect(indexLanguageOf("/repo/web/app.js", typescript, false)).toBeNull();
+  });
+
+  it("counts C headers as whichever of C and C++ is requested", () => {
+    expect(indexLanguageOf("/repo/include/api.h", new Set(["cpp"]), false)).toBe("cpp");
+    expect(indexLanguageOf("/repo/include/api.h", new Set(["c", "cpp"]), false)).toBe("c");
+    expect(indexLanguageOf("/repo/include/api.h", new Set(["go"]), false)).toBeNull();
+  });
+});
@@ -318,6 +318,68 @@ enum class Status : int {
     });
   });
 
+  describe("Declarations and Calls", () => {
+    it("should qualify out-of-line definitions and bind calls by scope", async () => {
+      const code = `
+#include <string>
+#include "widget.h"
+
+namespace ui {
+    class Widget {
+    public:
+        Widget();
+        void draw(const std::string& label);
+        void reset();
+    };
+
+    void log(int level);
+    void helper() {}
+}
+
+namespace {
+    void hidden() {}
+}
+
+void ui::Widget::draw(const std::string& label) {
+    helper();
+    this->reset();
+    hidden();
+    renderer.flush();
+}
+      `;
+
+      const result = await parser.parse("src/widget.cpp", code, "hash16");
+      const named = (name: string) => result.entities.filter((e) => e.name === name);
+
+      expect(named("ui::Widget::Widget")[0]?.metadata?.isDefinition).toBe(false);
+      expect(named("ui::log")[0]).toMatchObject({ type: "function", parameters: [{ name: "level", type: "int" }] });
+      expect(named("hidden")[0]?.metadata?.isDefinition).toBe(true);
+      const draw = named("ui::Widget::draw");
+      expect(draw.map((e) => [e.type, e.metadata?.isDefinition])).toEqual([
+        ["method", false],
+        ["method", true],
+      ]);
+      expect(draw[1]?.parameters).toEqual([{ name: "label", type: "const std::string&" }]);
+
+      const includes = result.relationships?.filter((r) => r.type === "imports").map((r) => r.to);
+      expect(includes).toEqual(["string", "widget.h"]);
+
+      const calls = result.relationships?.filter((r) => r.type === "calls" && r.from === draw[1]?.id);
+      expect(calls?.map((r) => r.to)).toEqual([
+        "src/widget.cpp:function:ui::helper",
+        "ui::Widget::reset",
+        "src/widget.cpp:function:hidden",
+        "flush",
+      ]);
+    });
+
+    it("should parse headers with C++ constructs as C++", async () => {
+      const result = await parser.parse("include/widget.h", "namespace ui {\nclass Widget {};\n}\n", "hash17");
+      expect(result.language).toBe("cpp");
+      expect(result.entities.find((e) => e.name === "ui::Widget")?.type).toBe("class");
+    });
+  });
+
   describe("Circuit Breakers", () => {
     it("should handle deeply nested classes without stack overflow", async () => {
       // Generate deeply nested classes
@@ -100,7 +100,7 @@ describe("CSharpAnalyzer", () => {
       const mockNode = createMockImplementationNode("TestClass", "ITestInterface");
       const result = await analyzer.analyze(mockNode, "test.cs");
 
-      expect(result.relationships.some((r) => r.type === "extends")).toBe(true);
+      expect(result.relationships.some((r) => r.type === "implements" && r.to === "ITestInterface")).toBe(true);
     });
   });
 
@@ -189,6 +189,58 @@ describe("CSharpAnalyzer", () => {
     });
   });
 
+  describe("Declarations and Calls", () => {
+    it("qualifies names by namespace and enclosing type", async () => {
+      const result = await analyzer.analyze(createMockControllerUnit(), "test.cs");
+      const byId = new Map(result.entities.map((e) => [e.id, e]));
+
+      expect(byId.get("test.cs:namespace:Shop.Orders")?.type).toBe("module");
+      expect(byId.get("test.cs:class:OrdersController")?.metadata?.qualifiedName).toBe("Shop.Orders.OrdersController");
+      expect(byId.get("test.cs:class:OrdersController.OrderStore")?.metadata?.qualifiedName).toBe(
+        "Shop.Orders.OrdersController.OrderStore",
+      );
+      expect(byId.get("test.cs:class:OrdersController:constructor:OrdersController")?.metadata).toMatchObject({
+        isConstructor: true,
+        className: "OrdersController",
+        parameters: [{ name: "store", type: "OrderStore" }],
+      });
+      expect(byId.get("test.cs:class:OrdersController:field:_store")?.metadata?.fieldType).toBe("OrderStore");
+    });
+
+    it("keeps attributes as decorators", async () => {
+      const result = await analyzer.analyze(createMockControllerUnit(), "test.cs");
+      const controller = result.entities.find((e) => e.id === "test.cs:class:OrdersController");
+
+      expect(controller?.decorators).toEqual([
+        expect.objectContaining({ name: "ApiController", arguments: undefined }),
+        expect.objectContaining({ name: "Route", arguments: ['"api/orders"'] }),
+      ]);
+      expect(controller?.metadata?.attributes).toEqual(["ApiController", "Route"]);
+      // Attributes of members stay on the members
+      expect(result.entities.find((e) => e.name === "Get")?.metadata?.attributes).toEqual(["HttpGet"]);
+    });
+
+    it("binds heritage and calls to declarations in the same file", async () => {
+      const result = await analyzer.analyze(createMockControllerUnit(), "test.cs");
+      const edge = (type: string, from: string) =>
+        result.relationships.filter((r) => r.type === type && r.from === from).map((r) => r.to);
+      const controller = "test.cs:class:OrdersController";
+
+      expect(edge("extends", controller)).toEqual(["ControllerBase"]);
+      expect(edge("implements", controller)).toEqual(["test.cs:interface:IAudited"]);
+      expect(result.entities.find((e) => e.id === controller)?.inheritance).toEqual({
+        baseClasses: ["ControllerBase"],
+        interfaces: ["IAudited"],
+      });
+      expect(edge("contains", `${controller}.OrderStore`)).toEqual([controller]);
+      expect(edge("calls", `${controller}:method:Get`)).toEqual([
+        `${controller}:method:Audit`,
+        `${controller}.OrderStore:method:Load`,
+        "Log",
+      ]);
+    });
+  });
+
   describe("Performance Metrics", () => {
     it("should track parsing metrics", async () => {
       const mockNode = createMockComplexNode();
@@ -472,3 +524,119 @@ function createMockComplexNode(): TreeSitterNode {
   node.child = (i: number) => childs[i] ?? null;
   return node;
 }
+
+/** Node with named fields; children are the given nodes followed by any field not among them */
+function treeNode(
+  type: string,
+  text: string,
+  fields: Record<string, TreeSitterNode> = {},
+  children: TreeSitterNode[] = [],
+): TreeSitterNode {
+  const node = makeNode(type, text);
+  const all = [...children, ...Object.values(fields).filter((f) => !children.includes(f))];
+  node.childForFieldName = (field: string) => fields[field] ?? null;
+  node.childCount = all.length;
+  node.child = (i: number) => all[i] ?? null;
+  return node;
+}
+
+function attributeList(name: string, args: string[] = []): TreeSitterNode {
+  const argumentList = args.length
+    ? [treeNode("attribute_argument_list", "", {}, args.map((a) => makeNode("attribute_argument", a)))]
+    : [];
+  const attribute = treeNode("attribute", name, { name: createIdentifierNode(name) }, argumentList);
+  return treeNode("attribute_list", `[${name}]`, {}, [attribute]);
+}
+
+function call(name: string, receiver?: string): TreeSitterNode {
+  const fn = receiver
+    ? treeNode("member_access_expression", `${receiver}.${name}`, {
+        expression: makeNode(receiver === "this" ? "this_expression" : "identifier", receiver),
+        name: createIdentifierNode(name),
+      })
+    : createIdentifierNode(name);
+  const args = makeNode("argument_list", "()");
+  return treeNode("invocation_expression", `${fn.text}()`, { function: fn, arguments: args });
+}
+
+function method(name: string, members: TreeSitterNode[] = [], calls: TreeSitterNode[] = []): TreeSitterNode {
+  return treeNode(
+    "method_declaration",
+    name,
+    {
+      type: createIdentifierNode("void"),
+      name: createIdentifierNode(name),
+      parameters: createParametersList([]),
+      body: treeNode("block", "{}", {}, calls),
+    },
+    members,
+  );
+}
+
+/**
+ * namespace Shop.Orders {
+ *   interface IAudited { void Audit(); }
+ *   [ApiController] [Route("api/orders")]
+ *   public class OrdersController : ControllerBase, IAudited {
+ *     private readonly OrderStore _store;
+ *     public OrdersController(OrderStore store) {}
+ *     [HttpGet] public void Get() { this.Audit(); _store.Load(); Log(); }
+ *     public void Audit() {}
+ *     class OrderStore { public void Load() {} }
+ *   }
+ * }
+ */
+function createMockControllerUnit(): TreeSitterNode {
+  const audited = treeNode("interface_declaration", "IAudited", {
+    name: createIdentifierNode("IAudited"),
+    body: treeNode("declaration_list", "", {}, [method("Audit")]),
+  });
+
+  const store = treeNode("class_declaration", "OrderStore", {
+    name: createIdentifierNode("OrderStore"),
+    body: treeNode("declaration_list", "", {}, [method("Load")]),
+  });
+  const field = treeNode("field_declaration", "", {}, [
+    createModifierNode("private"),
+    createModifierNode("readonly"),
+    treeNode("variable_declaration", "", { type: createIdentifierNode("OrderStore") }, [
+      treeNode("variable_declarator", "_store", { name: createIdentifierNode("_store") }),
+    ]),
+  ]);
+  const ctor = treeNode(
+    "constructor_declaration",
+    "OrdersController",
+    {
+      name: createIdentifierNode("OrdersController"),
+      parameters: createParametersList([{ name: "store", type: "OrderStore" }]),
+    },
+    [createModifierNode("public")],
+  );
+  const get = method(
+    "Get",
+    [attributeList("HttpGet"), createModifierNode("public")],
+    [call("Audit", "this"), call("Load", "_store"), call("Log")],
+  );
+
+  const controller = treeNode(
+    "class_declaration",
+    "OrdersController",
+    {
+      name: createIdentifierNode("OrdersController"),
+      bases: treeNode("base_list", ": ControllerBase, IAudited", {}, [
+        makeNode(":", ":"),
+        createIdentifierNode("ControllerBase"),
+        makeNode(",", ","),
+        createIdentifierNode("IAudited"),
+      ]),
+      body: treeNode("declaration_list", "", {}, [field, ctor, get, method("Audit"), store]),
+    },
+    [attributeList("ApiController"), attributeList("Route", ['"api/orders"']), createModifierNode("public")],
+  );
+
+  const ns = treeNode("namespace_declaration", "", {
+    name: makeNode("qualified_name", "Shop.Orders"),
+    body: treeNode("declaration_list", "", {}, [audited, controller]),
+  });
+  return treeNode("compilation_unit", "", {}, [ns]);
+}
@@ -0,0 +1,105 @@
+import Parser from "tree-sitter";
+import { getConfig, validateConfig } from "../../src/config/yaml-config";
+import {
+  CustomQueryError,
+  compileCustomQueries,
+  runCustomQueries,
+  validateCustomQueries,
+} from "../../src/parsers/custom-queries";
+import { loadGrammar } from "../../src/parsers/grammar-loader";
+import { TreeSitterParser } from "../../src/parsers/tree-sitter-parser";
+
+async function run(language: "go" | "typescript", file: string, code: string, specs: any[]) {
+  const parser = new TreeSitterParser();
+  await parser.initialize();
+  const { entities } = await parser.parse(file, code, "hash");
+  const grammar = loadGrammar(language);
+  const raw = new Parser();
+  raw.setLanguage(grammar);
+  const tree = raw.parse(code);
+  const queries = compileCustomQueries(language, grammar, specs);
+  const created = runCustomQueries(queries, tree.rootNode as any, code, file, entities);
+  return { entities, created };
+}
+
+describe("custom tree-sitter queries", () => {
+  it("turns each match of a kind query into an entity", async () => {
+    const code = `package gen
+
+//go:generate stringer -type=Color
+type Color int
+
+//go:generate mockgen -source=store.go
+func Store() {}
+`;
+    const { created } = await run("go", "gen.go", code, [
+      { name: "go-generate", query: '((comment) @name (#match? @name "^//go:generate"))', kind: "go_generate" },
+    ]);
+
+    expect(created.map((e) => [e.name, e.type, e.location.start.line])).toEqual([
+      ["//go:generate stringer -type=Color", "go_generate", 3],
+      ["//go:generate mockgen -source=store.go", "go_generate", 6],
+    ]);
+    expect(created[0]?.metadata).toMatchObject({ customQuery: "go-generate" });
+  });
+
+  it("records attribute matches on the declaration below or around them", async () => {
+    const code = `// @deprecated use fetchAll
+function fetchOne() {
+  return 1;
+}
+
+function fetchAll() {
+  // @deprecated inner note
+  return [];
+}
+`;
+    const { entities, created } = await run("typescript", "api.ts", code, [
+      { name: "deprecated", query: '((comment) @value (#match? @value "@deprecated"))', attribute: "deprecated" },
+    ]);
+
+    expect(created).toEqual([]);
+    const attributes = (name: string) => entities.find((e) => e.name === name)?.metadata?.attributes;
+    expect(attributes("fetchOne")).toEqual({ deprecated: ["// @deprecated use fetchAll"] });
+    expect(attributes("fetchAll")).toEqual({ deprecated: ["// @deprecated inner note"] });
+  });
+
+  it("reports query syntax errors with the language and query name", () => {
+    const errors = validateCustomQueries({
+      go: [{ name: "broken", query: "((function_declaration @fn)", kind: "x" }],
+      typescript: [{ name: "unknown-node", query: "(no_such_node) @x", kind: "y" }],
+    });
+
+    expect(errors).toHaveLength(2);
+    expect(errors[0]).toMatch(/^Custom query "broken" for go: /);
+    expect(errors[1]).toMatch(/^Custom query "unknown-node" for typescript: /);
+    expect(() => compileCustomQueries("go", loadGrammar("go"), [{ name: "bad", query: "(", kind: "x" }])).toThrow(
+      CustomQueryError,
+    );
+  });
+
+  it("rejects malformed query entries in the config", () => {
+    const base = getConfig();
+    const config = {
+      ...base,
+      parser: {
+        ...base.parser,
+        customQueries: {
+          go: [
+            { name: "both", query: "(comment) @c", kind: "a", attribute: "b" },
+            { name: "upper", query: "(comment) @c", kind: "GoThing" },
+            { name: "empty", query: " ", kind: "a" },
+          ],
+          cobol: [{ name: "x", query: "(x) @x", kind: "x" }],
+        },
+      },
+    };
+
+    expect(validateConfig(config).errors).toEqual([
+      "parser.customQueries.go[both]: set exactly one of kind (emit entities) or attribute (annotate entities)",
+      'parser.customQueries.go[upper]: kind must be lower_snake_case, got "GoThing"',
+      "parser.customQueries.go[empty]: query is required",
+      'parser.customQueries: unknown language "cobol"',
+    ]);
+  });
+});
@@ -0,0 +1,72 @@
+import { afterAll, beforeAll, describe, expect, it } from "@jest/globals";
+import { leadingDocComment, stripCommentMarkers } from "../../src/parsers/doc-comments.js";
+import { TreeSitterParser } from "../../src/parsers/tree-sitter-parser.js";
+
+describe("leadingDocComment", () => {
+  it("collects a contiguous // block directly above a Go declaration", () => {
+    const lines = [
+      "package users",
+      "",
+      "// GetUser retrieves a user by ID.",
+      "// It returns nil when none exists.",
+      "func GetUser() {}",
+    ];
+    expect(leadingDocComment(lines, 5, "go")).toBe(
+      "// GetUser retrieves a user by ID.\n// It returns nil when none exists.",
+    );
+  });
+
+  it("requires adjacency and, for TypeScript, a JSDoc opener", () => {
+    expect(leadingDocComment(["// detached", "", "func F() {}"], 3, "go")).toBeUndefined();
+    expect(leadingDocComment(["// plain comment", "function f() {}"], 2, "typescript")).toBeUndefined();
+    expect(leadingDocComment(["/* block */", "function f() {}"], 2, "typescript")).toBeUndefined();
+    const jsdoc = ["/**", " * Adds two numbers.", " */", "@memo", "function f() {}"];
+    expect(leadingDocComment(jsdoc, 5, "typescript")).toBe("/**\n* Adds two numbers.\n*/");
+  });
+});
+
+describe("stripCommentMarkers", () => {
+  it("removes comment markers and docstring quotes", () => {
+    expect(stripCommentMarkers("/**\n * Adds two numbers.\n * @param a first\n */")).toBe(
+      "Adds two numbers.\n@param a first",
+    );
+    expect(stripCommentMarkers("// GetUser retrieves a user by ID.\n// Second line.")).toBe(
+      "GetUser retrieves a user by ID.\nSecond line.",
+    );
+    expect(stripCommentMarkers('"""Load the config.\n\n    Falls back to defaults.\n    """')).toBe(
+      "Load the config.\n\nFalls back to defaults.",
+    );
+  });
+});
+
+describe("TreeSitterParser documentation", () => {
+  let parser: TreeSitterParser;
+
+  beforeAll(async () => {
+    parser = new TreeSitterParser();
+    await parser.initialize();
+  });
+
+  afterAll(() => {
+    parser.clearCache();
+  });
+
+  it("attaches Go doc comments, JSDoc and Python docstrings to their entities", async () => {
+    const go = await parser.parse(
+      "users.go",
+      "package users\n\n// GetUser retrieves a user by ID\nfunc GetUser(id int) {}\n",
+      "doc-go",
+    );
+    expect(go.entities.find((e) => e.name === "GetUser")?.documentation).toBe("// GetUser retrieves a user by ID");
+
+    const ts = await parser.parse(
+      "math.ts",
+      "/** Adds two numbers */\nexport function add(a: number, b: number) {\n  return a + b;\n}\n",
+      "doc-ts",
+    );
+    expect(ts.entities.find((e) => e.name === "add")?.documentation).toBe("/** Adds two numbers */");
+
+    const py = await parser.parse("config.py", 'def load():\n    """Load the config."""\n    return {}\n', "doc-py");
+    expect(py.entities.find((e) => e.name === "load")?.documentation).toBe('"""Load the config."""');
+  });
+});
@@ -0,0 +1,87 @@
+import { getConfig, validateConfig } from "../../src/config/yaml-config";
+import { entityKindFilter, filterEntityKinds } from "../../src/parsers/entity-kinds";
+import { TreeSitterParser } from "../../src/parsers/tree-sitter-parser";
+
+describe("per-language entity kinds", () => {
+  it("picks the language rule, then its flavour's, then the wildcard", () => {
+    const rules = {
+      "*": { exclude: ["comment"] },
+      typescript: { exclude: ["variable"] },
+      java: { include: ["class"] },
+    };
+
+    expect(entityKindFilter(undefined, "go")).toBeNull();
+    expect(entityKindFilter({}, "go")).toBeNull();
+    expect(entityKindFilter({ go: {} }, "go")).toBeNull();
+
+    const tsx = entityKindFilter(rules, "tsx")!;
+    expect([tsx("variable"), tsx("comment"), tsx("function")]).toEqual([false, true, true]);
+    const go = entityKindFilter(rules, "go")!;
+    expect([go("comment"), go("variable")]).toEqual([false, true]);
+    const java = entityKindFilter(rules, "java")!;
+    expect([java("class"), java("method")]).toEqual([true, false]);
+  });
+
+  it("drops the excluded kinds of a parsed file and their relationships", async () => {
+    const parser = new TreeSitterParser();
+    await parser.initialize();
+    const code = `package shapes
+
+const Unit = 1
+
+type Rect struct {
+	W, H int
+}
+
+func (r Rect) Area() int { return r.W * r.H }
+`;
+    const full = await parser.parse("shape.go", code, "hash");
+    parser.clearCache();
+    (parser as any).entityKindRules = { go: { exclude: ["property", "constant"] } };
+    const res = await parser.parse("shape.go", code, "hash");
+
+    const types = new Set(res.entities.map((e) => e.type));
+    expect(types.has("property")).toBe(false);
+    expect(types.has("constant")).toBe(false);
+    expect(res.entities.map((e) => e.name)).toEqual(expect.arrayContaining(["Rect", "Area"]));
+
+    const excluded = full.entities.filter((e) => e.type === "property" || e.type === "constant");
+    const dropped = new Set(excluded.map((e) => e.id));
+    expect(dropped.size).toBeGreaterThan(0);
+    const relationships = ((res as any).relationships ?? []) as Array<{ from: string; to: string }>;
+    expect(relationships.filter((rel) => dropped.has(rel.from) || dropped.has(rel.to))).toEqual([]);
+  });
+
+  it("drops only the edges that touch a dropped entity", () => {
+    const entities: any[] = [
+      { id: "a", name: "a", type: "function" },
+      { id: "b", name: "b", type: "variable" },
+    ];
+    const relationships = [
+      { from: "a", to: "b", type: "references" },
+      { from: "a", to: "print", type: "calls" },
+    ];
+    const kept = filterEntityKinds(entities, relationships, (kind) => kind !== "variable");
+    expect(kept.entities.map((e) => e.id)).toEqual(["a"]);
+    expect(kept.relationships).toEqual([{ from: "a", to: "print", type: "calls" }]);
+
+    const all = filterEntityKinds(entities, relationships, () => true);
+    expect(all.relationships).toBe(relationships);
+  });
+
+  it("rejects rules for unknown languages and non-list kinds", () => {
+    const base = getConfig();
+    const config = {
+      ...base,
+      parser: {
+        ...base.parser,
+        entityKinds: { "*": { exclude: ["comment"] }, cobol: { exclude: ["paragraph"] }, go: { include: "function" } },
+      },
+    } as any;
+
+    expect(validateConfig(config).errors).toEqual([
+      'parser.entityKinds: unknown language "cobol"',
+      "parser.entityKinds.go.include must be a list of entity kinds",
+    ]);
+  });
+});
@@ -0,0 +1,100 @@
+import { TreeSitterParser } from "../../src/parsers/tree-sitter-parser";
+import type { EntityRelationship, ParsedEntity } from "../../src/types/parser";
+
+function envVars(entities: ParsedEntity[]) {
+  return entities
+    .filter((e) => e.type === "env_var")
+    .map((e) => [
+      e.name,
+      e.metadata?.unresolved ?? false,
+      e.metadata?.reads.map((r: any) => [r.line, r.accessor, r.default ?? null, r.constant ?? null, r.reader ?? null]),
+    ]);
+}
+
+describe("env var extraction", () => {
+  let parser: TreeSitterParser;
+
+  beforeAll(async () => {
+    parser = new TreeSitterParser();
+    await parser.initialize();
+  });
+
+  afterEach(() => {
+    parser.clearCache();
+  });
+
+  it("reads process.env accesses, destructuring, defaults and constant keys", async () => {
+    const code = `const DB_KEY = "DATABASE_URL";
+const { HOST = "localhost", LOG_LEVEL } = process.env;
+
+export function connect() {
+  const port = process.env.PORT ?? "5432";
+  return open(process.env[DB_KEY], port);
+}
+
+export function secret(name) {
+  return process.env[name] || process.env["API_TOKEN"];
+}
+`;
+    const res = await parser.parse("config.ts", code, "hash");
+
+    expect(envVars(res.entities)).toEqual([
+      ["HOST", false, [[2, "process.env", "localhost", null, null]]],
+      ["LOG_LEVEL", false, [[2, "process.env", null, null, null]]],
+      ["PORT", false, [[5, "process.env", "5432", null, "connect"]]],
+      ["DATABASE_URL", false, [[6, "process.env", null, "DB_KEY", "connect"]]],
+      ["name", true, [[10, "process.env", null, null, "secret"]]],
+      ["API_TOKEN", false, [[10, "process.env", null, null, "secret"]]],
+    ]);
+    expect(res.entities.find((e) => e.name === "PORT")?.readers).toMatchObject([{ name: "connect", line: 4 }]);
+  });
+
+  it("reads Go os.Getenv and LookupEnv through constants", async () => {
+    const code = `package config
+
+import "os"
+
+const portKey = "PORT"
+
+func Load() string {
+  if v, ok := os.LookupEnv("APP_ENV"); ok {
+    return v
+  }
+  return os.Getenv(portKey)
+}
+`;
+    const res = await parser.parse("config.go", code, "hash");
+    const relationships = (res as any).relationships as EntityRelationship[] | undefined;
+
+    expect(envVars(res.entities)).toEqual([
+      ["APP_ENV", false, [[8, "os.LookupEnv", null, null, "Load"]]],
+      ["PORT", false, [[11, "os.Getenv", null, "portKey", "Load"]]],
+    ]);
+    // Go supplies its own edges, so the reader edges come with them
+    expect(relationships?.filter((r) => r.type === "read_by").map((r) => r.to)).toEqual(["Load", "Load"]);
+  });
+
+  it("reads Python os.environ and getenv, including bare imports from os", async () => {
+    const code = `import os
+from os import environ
+
+SECRET = "SECRET_KEY"
+
+def settings():
+    return {
+        "debug": os.getenv("DEBUG", "0"),
+        "secret": environ[SECRET],
+        "db": os.environ.get("DB_URL"),
+        "dynamic": os.environ[f"{prefix}_HOST"],
+    }
+`;
+    const res = await parser.parse("settings.py", code, "hash");
+
+    expect(envVars(res.entities)).toEqual([
+      ["DEBUG", false, [[8, "os.getenv", "0", null, "settings"]]],
+      ["SECRET_KEY", false, [[9, "environ", null, "SECRET", "settings"]]],
+      ["DB_URL", false, [[10, "os.environ.get", null, null, "settings"]]],
+      ['f"{prefix}_HOST"', true, [[11, "os.environ", null, null, "settings"]]],
+    ]);
+  });
+});
@@ -133,6 +133,52 @@ func (r *Rectangle) Area() float64 {
     const areaMethod = result.entities.find((e) => e.name === "Area" && e.type === "method");
     expect(areaMethod).toBeDefined();
     expect(areaMethod?.metadata?.receiver).toBe("Rectangle");
+    expect(areaMethod?.metadata?.qualifiedName).toBe("Rectangle.Area");
+    expect(result.relationships).toContainEqual(
+      expect.objectContaining({ from: areaMethod?.id, to: `${filePath}:type:Rectangle`, type: "contains" }),
+    );
+  });
+
+  it("should link structs to the interfaces their method sets satisfy", async () => {
+    const code = `
+package service
+
+type UserService interface {
+  GetUser(id int) (*User, error)
+  DeleteUser(id int) error
+}
+
+type User struct {
+  ID int
+}
+
+type UserServiceImpl struct {
+  users map[int]*User
+}
+
+func (s *UserServiceImpl) GetUser(userID int) (*User, error) {
+  return s.users[userID], nil
+}
+
+func (s *UserServiceImpl) DeleteUser(id int) error {
+  return nil
+}
+
+type ReadOnly struct{}
+
+func (r ReadOnly) GetUser(id int) (*User, error) {
+  return nil, nil
+}
+    `;
+
+    const filePath = "service.go";
+    const result = await parser.parse(filePath, code, "go-hash-impl");
+
+    const implementsRels = result.relationships?.filter((r) => r.type === "implements") ?? [];
+    expect(implementsRels).toHaveLength(1);
+    expect(implementsRels[0]?.from).toBe("service.go:type:UserServiceImpl");
+    expect(implementsRels[0]?.to).toBe("service.go:type:UserService");
+    expect(implementsRels[0]?.metadata?.satisfiedBy).toBe("*UserServiceImpl");
   });
 
   it("should parse constants and variables", async () => {
@@ -178,6 +224,50 @@ var globalCounter int
     expect(globalCounterVar?.metadata?.isPublic).toBe(false);
   });
 
+  it("should record init functions, variable initializers and blank imports", async () => {
+    const code = `
+package server
+
+import (
+  _ "github.com/lib/pq"
+  "fmt"
+)
+
+var limit = defaultLimit()
+
+var _ = register("a")
+var _ = register("b")
+
+func init() {
+  fmt.Println(limit)
+}
+
+func init() {}
+    `;
+
+    const result = await parser.parse("server.go", code, "go-hash-init");
+    const inits = result.entities.filter((e) => e.name === "init");
+    expect(inits.map((e) => [e.id, e.metadata?.goInit, e.metadata?.initOrder])).toEqual([
+      ["server.go:function:init", true, 1],
+      ["server.go:function:init#2", true, 2],
+    ]);
+
+    const calls = result.relationships?.filter((r) => r.type === "calls") ?? [];
+    expect(calls.filter((r) => r.from.includes(":var:")).map((r) => [r.from, r.metadata?.callee])).toEqual([
+      ["server.go:var:limit", "defaultLimit"],
+      ["server.go:var:_", "register"],
+      ["server.go:var:_#2", "register"],
+    ]);
+    const accesses = result.relationships?.filter((r) => r.type === "accesses") ?? [];
+    expect(accesses.map((r) => [r.from, r.to])).toEqual([["server.go:function:init", "server.go:var:limit"]]);
+
+    const imports = result.relationships?.filter((r) => r.type === "imports") ?? [];
+    expect(imports.map((r) => [r.to, r.metadata?.sideEffect ?? false])).toEqual([
+      ["github.com/lib/pq", true],
+      ["fmt", false],
+    ]);
+  });
+
   it("should parse type aliases and embedded types", async () => {
     const code = `
 package types
@@ -207,7 +297,7 @@ type Admin struct {
     expect(userIDType).toBeDefined();
 
     // Check for struct embedding relationship
-    const embedRelations = result.relationships?.filter((r) => r.type === "contains" && r.metadata?.embeddingType);
+    const embedRelations = result.relationships?.filter((r) => r.type === "embeds" && r.metadata?.embeddingType);
     expect(embedRelations?.length).toBeGreaterThan(0);
 
     const adminEmbeds = embedRelations?.find((r) => r.from.includes("Admin"));
@@ -215,6 +305,57 @@ type Admin struct {
     expect(adminEmbeds?.to).toContain("User");
   });
 
+  it("should record pointer and interface embeddings and promote embedded methods", async () => {
+    const code = `
+package io
+
+type Reader interface {
+  Read(p []byte) (int, error)
+}
+
+type Closer interface {
+  Close() error
+}
+
+type ReadCloser interface {
+  Reader
+  Closer
+}
+
+type file struct{}
+
+func (f *file) Read(p []byte) (int, error) {
+  return 0, nil
+}
+
+func (f *file) Close() error {
+  return nil
+}
+
+type LoggedFile struct {
+  *file
+  prefix string
+}
+    `;
+
+    const filePath = "embed.go";
+    const result = await parser.parse(filePath, code, "go-hash-embed");
+
+    const embeds = result.relationships?.filter((r) => r.type === "embeds") ?? [];
+    const pointerEmbed = embeds.find((r) => r.from === "embed.go:type:LoggedFile");
+    expect(pointerEmbed?.to).toBe("embed.go:type:file");
+    expect(pointerEmbed?.metadata?.isPointer).toBe(true);
+
+    const interfaceEmbeds = embeds.filter((r) => r.from === "embed.go:type:ReadCloser").map((r) => r.to);
+    expect(interfaceEmbeds).toEqual(["embed.go:type:Reader", "embed.go:type:Closer"]);
+
+    // Embedding *file promotes its pointer methods to the LoggedFile value type
+    const loggedImpl = result.relationships?.find(
+      (r) => r.type === "implements" && r.from === "embed.go:type:LoggedFile" && r.to === "embed.go:type:ReadCloser",
+    );
+    expect(loggedImpl?.metadata?.satisfiedBy).toBe("LoggedFile");
+  });
+
   it("should handle goroutines and channels", async () => {
     const code = `
 package concurrent
@@ -259,4 +400,297 @@ func handleResult(result string) {
     expect(functionNames).toContain("processItem");
     expect(functionNames).toContain("handleResult");
   });
+
+  it("should resolve method calls through the receiver type", async () => {
+    const code = `
+package store
+
+import "fmt"
+
+type Store struct{}
+
+func (s *Store) Save() {}
+
+func (s *Store) Flush() {
+  s.Save()
+}
+
+func main() {
+  st := &Store{}
+  st.Save()
+  fmt.Println("done")
+}
+    `;
+
+    const result = await parser.parse("store.go", code, "go-hash-6");
+    const calls = result.relationships?.filter((r) => r.type === "calls") ?? [];
+
+    const viaReceiver = calls.filter((r) => r.to === "store.go:method:Store:Save");
+    expect(viaReceiver.map((r) => r.metadata?.receiverType)).toEqual(["Store", "Store"]);
+
+    const println = calls.find((r) => r.metadata?.callee === "fmt.Println");
+    expect(println?.metadata?.callType).toBe("qualified");
+    expect(println?.metadata?.calleeName).toBe("Println");
+  });
+
+  it("should record the arguments of each call, with literal values", async () => {
+    const code = `
+package db
+
+func open(name string, opts *Options, extra ...string) {}
+
+func load(names []string) {
+  open("users", nil)
+  open(dsn(), &Options{}, names...)
+  open(\`raw\`, nil, "a", "b")
+}
+    `;
+
+    const result = await parser.parse("db.go", code, "go-hash-args");
+    const calls = result.relationships?.filter((r) => r.type === "calls" && r.metadata?.callee === "open") ?? [];
+
+    expect(calls.map((r) => [r.metadata?.line, r.metadata?.arity])).toEqual([
+      [7, 2],
+      [8, undefined],
+      [9, 4],
+    ]);
+    expect(calls[0]?.metadata?.arguments).toEqual([
+      { kind: "literal", value: '"users"' },
+      { kind: "literal", value: "nil" },
+    ]);
+    expect(calls[1]?.metadata?.arguments).toEqual([{ kind: "expression" }, { kind: "expression" }, { kind: "spread" }]);
+  });
+
+  it("should skip files the target platform does not build and tag constrained ones", async () => {
+    const plan9 = await parser.parse("dial_plan9.go", "package net\n\nfunc Dial() {}\n", "go-hash-7");
+    expect(plan9.entities).toHaveLength(0);
+
+    const generator = "//go:build ignore\n\npackage main\n\nfunc main() {}\n";
+    expect((await parser.parse("gen.go", generator, "go-hash-8")).entities).toHaveLength(0);
+
+    const portable = "//go:build unix || windows\n\npackage net\n\nfunc Dial() {}\n";
+    const result = await parser.parse("dial.go", portable, "go-hash-9");
+    const dial = result.entities.find((e) => e.name === "Dial");
+    expect(dial?.metadata?.goBuild).toEqual({ constraint: "unix || windows", test: false, matchesTarget: true });
+  });
+
+  it("should capture type parameters and resolve instantiations to the generic declaration", async () => {
+    const code = `
+package collections
+
+type List[T any] struct {
+  items []T
+}
+
+func (l *List[T]) Push(v T) {
+  l.items = append(l.items, v)
+}
+
+func Map[T, U any](xs []T, f func(T) U) []U {
+  return nil
+}
+
+func Keys[K comparable, V any](m map[K]V) []K {
+  return nil
+}
+
+func use() {
+  l := &List[int]{}
+  l.Push(1)
+  Map[int, string](nil, nil)
+  Keys[string](nil)
+}
+    `;
+
+    const result = await parser.parse("collections.go", code, "go-hash-10");
+    const byName = (name: string) => result.entities.find((e) => e.name === name);
+
+    expect(byName("Map")?.signature).toBe("func Map[T, U any](xs []T, f func(T) U) []U");
+    expect(byName("Map")?.metadata?.typeParameters).toEqual([
+      { name: "T", constraint: "any" },
+      { name: "U", constraint: "any" },
+    ]);
+    expect(byName("Keys")?.metadata?.typeParameters).toEqual([
+      { name: "K", constraint: "comparable" },
+      { name: "V", constraint: "any" },
+    ]);
+    expect(byName("List")?.signature).toBe("type List[T any] struct");
+
+    const push = byName("Push");
+    expect(push?.id).toBe("collections.go:method:List:Push");
+    expect(push?.metadata?.receiverTypeParameters).toEqual(["T"]);
+
+    const calls = result.relationships?.filter((r) => r.from === "collections.go:function:use") ?? [];
+    expect(calls.find((r) => r.metadata?.calleeName === "Map")).toMatchObject({
+      to: "collections.go:function:Map",
+      metadata: { typeArguments: "[int, string]" },
+    });
+    expect(calls.find((r) => r.metadata?.calleeName === "Keys")).toMatchObject({
+      to: "collections.go:function:Keys",
+      metadata: { typeArguments: "[string]" },
+    });
+    expect(calls.find((r) => r.metadata?.calleeName === "Push")?.to).toBe("collections.go:method:List:Push");
+  });
+
+  it("should record the types a function names as references", async () => {
+    const code = `
+package api
+
+import (
+  "net/http"
+  m "example.com/shop/internal/models"
+)
+
+type Config struct{}
+
+func Show[T any](w http.ResponseWriter, cfg *Config, v T) (*m.User, error) {
+  var u m.User
+  return &u, nil
+}
+    `;
+
+    const result = await parser.parse("show.go", code, "go-hash-11");
+    const references = result.relationships?.filter((r) => r.type === "references") ?? [];
+
+    expect(references.map((r) => [r.to, r.metadata?.referencedName]).sort()).toEqual([
+      ["http.ResponseWriter", "http.ResponseWriter"],
+      ["m.User", "m.User"],
+      ["show.go:type:Config", "Config"],
+    ]);
+    expect(references.every((r) => r.from === "show.go:function:Show")).toBe(true);
+  });
+
+  it("should record field and package-level variable accesses as reads and writes", async () => {
+    const code = `
+package store
+
+import "example.com/shop/internal/config"
+
+var hits int
+
+type Server struct {
+  users map[int]*User
+}
+
+type User struct{ ID int }
+
+func NewServer() *Server {
+  return &Server{users: make(map[int]*User)}
+}
+
+func (s *Server) Add(u *User) {
+  s.users[u.ID] = u
+  hits++
+  _ = config.Limit
+}
+
+func (s *Server) Get(id int) *User {
+  return s.users[id]
+}
+    `;
+
+    const result = await parser.parse("store.go", code, "go-hash-12");
+    const accesses = result.relationships?.filter((r) => r.type === "accesses") ?? [];
+
+    expect(accesses.map((r) => [r.from, r.to, r.metadata?.access]).sort()).toEqual(
+      [
+        ["store.go:function:NewServer", "store.go:type:Server:field:users", "write"],
+        ["store.go:method:Server:Add", "store.go:type:Server:field:users", "write"],
+        ["store.go:method:Server:Add", "store.go:type:User:field:ID", "read"],
+        ["store.go:method:Server:Add", "store.go:var:hits", "readwrite"],
+        ["store.go:method:Server:Add", "config.Limit", "read"],
+        ["store.go:method:Server:Get", "store.go:type:Server:field:users", "read"],
+      ].sort(),
+    );
+    expect(accesses.find((r) => r.to.endsWith(":field:ID"))?.metadata).toMatchObject({
+      referencedName: "User.ID",
+      receiverType: "User",
+    });
+  });
+
+  it("should record goroutine spawns and channel sends and receives", async () => {
+    const code = `
+package jobs
+
+var events = make(chan string)
+
+type Pool struct {
+  done chan struct{}
+}
+
+func (p *Pool) Run(jobs <-chan int) {
+  results := make(chan int, 4)
+  go p.drain(results)
+  go func(n int) {
+    results <- n
+    events <- "started"
+  }(len(jobs))
+  for j := range jobs {
+    results <- j
+  }
+  <-p.done
+}
+
+func (p *Pool) drain(results chan int) {
+  for {
+    _ = <-results
+  }
+}
+    `;
+
+    const result = await parser.parse("jobs.go", code, "go-hash-13");
+    const entities = result.entities ?? [];
+    const relationships = result.relationships ?? [];
+    const run = "jobs.go:method:Pool:Run";
+    const goroutine = "jobs.go:goroutine:Pool.Run.func1";
+
+    expect(entities.find((e) => e.id === goroutine)).toMatchObject({
+      name: "Pool.Run.func1",
+      type: "function",
+      metadata: { goroutine: true, spawnedBy: run },
+    });
+    expect(entities.find((e) => e.id === `${run}:chan:results`)?.metadata).toMatchObject({
+      elementType: "int",
+      direction: "bidirectional",
+      buffered: true,
+      capacity: "4",
+      scope: "local",
+    });
+    expect(entities.find((e) => e.id === `${run}:chan:jobs`)?.metadata).toMatchObject({
+      direction: "receive",
+      scope: "parameter",
+    });
+    expect(entities.find((e) => e.id === "jobs.go:var:events")?.metadata?.channel).toMatchObject({
+      elementType: "string",
+      buffered: false,
+    });
+    expect(entities.find((e) => e.id === "jobs.go:type:Pool:field:done")?.metadata?.channel).toBeDefined();
+
+    const edges = (type: string) =>
+      relationships
+        .filter((r) => r.type === type)
+        .map((r) => [r.from, r.to])
+        .sort();
+    expect(edges("spawns")).toEqual(
+      [
+        [run, goroutine],
+        [run, "jobs.go:method:Pool:drain"],
+      ].sort(),
+    );
+    expect(edges("sends")).toEqual(
+      [
+        [goroutine, `${run}:chan:results`],
+        [goroutine, "jobs.go:var:events"],
+        [run, `${run}:chan:results`],
+      ].sort(),
+    );
+    expect(edges("receives")).toEqual(
+      [
+        [run, `${run}:chan:jobs`],
+        [run, "jobs.go:type:Pool:field:done"],
+        ["jobs.go:method:Pool:drain", "jobs.go:method:Pool:drain:chan:results"],
+      ].sort(),
+    );
+    expect(relationships.find((r) => r.type === "sends" && r.from === run)?.metadata?.access).toBe("write");
+  });
 });
@@ -0,0 +1,50 @@
+import { describe, expect, it } from "@jest/globals";
+import {
+  type GoBuildTarget,
+  goFileConstraints,
+  parseGoBuildExpression,
+} from "../../src/parsers/go-build-constraints.js";
+
+const linux: GoBuildTarget = { mode: "target", goos: "linux", goarch: "amd64", tags: ["cgo"], includeTests: true };
+
+describe("goFileConstraints", () => {
+  it.each([
+    ["pkg/file_windows.go", "windows", false],
+    ["pkg/file_linux_arm64.go", "linux && arm64", false],
+    ["pkg/file_amd64_test.go", "amd64", true],
+    ["pkg/linux.go", null, true],
+    ["pkg/file_unix.go", null, true],
+  ])("reads the file name suffix of %s", (filePath, expression, matchesTarget) => {
+    expect(goFileConstraints(filePath, [], linux)).toMatchObject({ expression, matchesTarget });
+  });
+
+  it("evaluates //go:build expressions against the target", () => {
+    const check = (line: string, target = linux) => goFileConstraints("a.go", [line], target).matchesTarget;
+    expect(check("//go:build linux && (amd64 || arm64)")).toBe(true);
+    expect(check("//go:build !cgo")).toBe(false);
+    expect(check("//go:build unix")).toBe(true);
+    expect(check("//go:build linux", { ...linux, goos: "android" })).toBe(true);
+    expect(check("//go:build integration")).toBe(false);
+    expect(check("//go:build integration", { ...linux, tags: ["integration"] })).toBe(true);
+    expect(check("//go:build linux &&")).toBe(false);
+  });
+
+  it("falls back to legacy +build lines, all of which must hold", () => {
+    const lines = ["// +build linux darwin", "// +build amd64,!cgo"];
+    expect(goFileConstraints("a.go", lines, linux)).toMatchObject({
+      expression: "(linux || darwin) && amd64 && !cgo",
+      matchesTarget: false,
+    });
+    expect(goFileConstraints("a.go", lines, { ...linux, tags: [] }).matchesTarget).toBe(true);
+  });
+
+  it("combines the file name with the build line and can leave out tests", () => {
+    const result = goFileConstraints("x_linux_test.go", ["//go:build !race"], { ...linux, includeTests: false });
+    expect(result).toEqual({ expression: "linux && !race", test: true, matchesTarget: false });
+  });
+
+  it("rejects malformed expressions", () => {
+    expect(parseGoBuildExpression("(linux")).toBeNull();
+    expect(parseGoBuildExpression("linux || !")).toBeNull();
+  });
+});
@@ -0,0 +1,75 @@
+import { describe, expect, it } from "@jest/globals";
+import { type GoMethodSetEntity, goImplementations, goMethodSignature } from "../../src/parsers/go-method-sets.js";
+
+function type(name: string, kind: "class" | "interface", metadata = {}): GoMethodSetEntity {
+  return { id: `store.go:type:${name}`, name, type: kind, metadata };
+}
+
+function method(name: string, metadata: Record<string, unknown>): GoMethodSetEntity {
+  return { name, type: "method", metadata: { parameters: [], ...metadata } };
+}
+
+const byId = (iface: GoMethodSetEntity) => iface.id ?? "";
+
+describe("goMethodSignature", () => {
+  it("compares parameter types and result without names or spacing", () => {
+    const named = method("Get", { parameters: ["id: string", "opts: map[string] int"], returnType: "(*User, error)" });
+    const bare = method("Get", { parameters: ["string", "map[string]int"], returnType: "(*User,error)" });
+    expect(goMethodSignature(named)).toBe(goMethodSignature(bare));
+  });
+});
+
+describe("goImplementations", () => {
+  it("records whether the value or the pointer type satisfies an interface", () => {
+    const found = goImplementations(
+      [
+        type("Reader", "interface"),
+        method("Read", { isAbstract: true, parent: "store.go:type:Reader", returnType: "error" }),
+        type("Closer", "interface"),
+        method("Close", { isAbstract: true, parent: "store.go:type:Closer" }),
+        type("File", "class"),
+        method("Read", { receiver: "File", returnType: "error" }),
+        method("Close", { receiver: "File", pointerReceiver: true }),
+      ],
+      byId,
+    );
+
+    expect(found.map(({ struct, iface, pointerReceiver }) => [struct.name, iface.name, pointerReceiver])).toEqual([
+      ["File", "Reader", false],
+      ["File", "Closer", true],
+    ]);
+  });
+
+  it("promotes embedded methods and skips interfaces embedding an undeclared type", () => {
+    const found = goImplementations(
+      [
+        type("Named", "interface"),
+        method("Name", { isAbstract: true, parent: "store.go:type:Named", returnType: "string" }),
+        type("Entity", "interface", { embeddedTypes: [{ name: "Named", pointer: false }] }),
+        method("ID", { isAbstract: true, parent: "store.go:type:Entity", returnType: "int" }),
+        type("Printable", "interface", { embeddedTypes: [{ name: "fmt.Stringer", pointer: false }] }),
+        type("Base", "class"),
+        method("Name", { receiver: "Base", pointerReceiver: true, returnType: "string" }),
+        type("User", "class", { embeddedTypes: [{ name: "Base", pointer: true }] }),
+        method("ID", { receiver: "User", returnType: "int" }),
+      ],
+      byId,
+    );
+
+    const pairs = found.map(({ struct, iface, pointerReceiver }) => `${struct.name}->${iface.name}:${pointerReceiver}`);
+    expect(pairs).toEqual(["Base->Named:true", "User->Named:false", "User->Entity:false"]);
+  });
+
+  it("rejects a method whose signature differs from the interface's", () => {
+    const found = goImplementations(
+      [
+        type("Saver", "interface"),
+        method("Save", { isAbstract: true, parent: "store.go:type:Saver", returnType: "error" }),
+        type("User", "class"),
+        method("Save", { receiver: "User", returnType: "bool" }),
+      ],
+      byId,
+    );
+    expect(found).toEqual([]);
+  });
+});
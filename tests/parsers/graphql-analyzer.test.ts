@@ -0,0 +1,124 @@
+import { describe, expect, it } from "@jest/globals";
+import { GraphqlAnalyzer } from "../../src/parsers/graphql-analyzer.js";
+import { TreeSitterParser } from "../../src/parsers/tree-sitter-parser.js";
+
+const SCHEMA = `schema { query: RootQuery mutation: Mutation }
+
+# A customer of the shop
+type User implements Node @key(fields: "id") {
+  id: ID!
+  "Orders, newest first"
+  orders(first: Int = 10, status: [OrderStatus!]): [Order!]! @deprecated(reason: "use orderPage")
+}
+
+type RootQuery {
+  user(id: ID!): User
+  search(filter: SearchInput = { term: "x", limit: 3 }): [SearchResult]
+}
+
+type Mutation {
+  """
+  Registers a user
+  """
+  createUser(input: CreateUserInput!): User!
+}
+
+input SearchInput { term: String! limit: Int = 5 }
+
+enum OrderStatus { OPEN "Paid and shipped" CLOSED @deprecated }
+
+union SearchResult = | User | Order
+
+scalar DateTime
+
+interface Node { id: ID! }
+
+extend type User { nickname: String }
+
+query GetUser($id: ID!) { user(id: $id) { id } }
+`;
+
+describe("GraphqlAnalyzer", () => {
+  const { entities, relationships } = new GraphqlAnalyzer().analyze(SCHEMA, "/repo/schema.graphql");
+  const byQualified = (name: string) => entities.find((e) => e.metadata?.qualifiedName === name);
+  const edgesFrom = (id: string | undefined, type: string) =>
+    relationships.filter((rel) => rel.from === id && rel.type === type);
+
+  it("extracts types, interfaces, inputs, enums, unions, scalars and their fields", () => {
+    expect(byQualified("User")).toMatchObject({ type: "class", documentation: "A customer of the shop" });
+    expect(byQualified("User")?.metadata).toMatchObject({ graphqlKind: "type", implements: ["Node"] });
+    expect(byQualified("Node")?.type).toBe("interface");
+    expect(byQualified("SearchInput")?.type).toBe("struct");
+    expect(byQualified("OrderStatus")?.type).toBe("enum");
+    expect(byQualified("SearchResult")).toMatchObject({
+      type: "union",
+      signature: "union SearchResult = User | Order",
+    });
+    expect(byQualified("DateTime")?.type).toBe("typedef");
+    expect(byQualified("OrderStatus.CLOSED")).toMatchObject({
+      type: "enum_variant",
+      documentation: "Paid and shipped",
+      metadata: { deprecated: true },
+    });
+
+    expect(byQualified("User.orders")).toMatchObject({
+      type: "field",
+      signature: "orders(first: Int = 10, status: [OrderStatus!]): [Order!]!",
+      returnType: "[Order!]!",
+      documentation: "Orders, newest first",
+      metadata: { parentType: "User", deprecated: "use orderPage" },
+    });
+    expect(byQualified("SearchInput.limit")).toMatchObject({
+      signature: "limit: Int = 5",
+      metadata: { graphqlKind: "input_field" },
+    });
+    // Executable documents are not part of the schema
+    expect(entities.some((e) => e.name === "GetUser")).toBe(false);
+  });
+
+  it("models fields of the root types the schema block names as operations", () => {
+    expect(byQualified("RootQuery.user")).toMatchObject({
+      type: "method",
+      parameters: [{ name: "id", type: "ID!" }],
+      metadata: { graphqlKind: "operation", operation: "query" },
+    });
+    expect(byQualified("Mutation.createUser")).toMatchObject({
+      documentation: "Registers a user",
+      metadata: { operation: "mutation" },
+    });
+    expect(byQualified("RootQuery.search")?.metadata?.arguments).toEqual([
+      { name: "filter", type: "SearchInput", defaultValue: '{ term: "x", limit: 3 }' },
+    ]);
+  });
+
+  it("links fields and operations to their types, binding those declared in the file", () => {
+    const user = byQualified("User")?.id;
+    const orders = edgesFrom(byQualified("User.orders")?.id, "references");
+    expect(orders.map((rel) => [rel.to, rel.metadata?.role])).toEqual([
+      ["Order", "type"],
+      [byQualified("OrderStatus")?.id, "argument"],
+    ]);
+    // Built-in scalars are not linked
+    expect(edgesFrom(byQualified("User.id")?.id, "references")).toEqual([]);
+
+    const createUser = edgesFrom(byQualified("Mutation.createUser")?.id, "references");
+    expect(createUser.map((rel) => [rel.to, rel.metadata?.role])).toEqual([
+      [user, "return"],
+      ["CreateUserInput", "argument"],
+    ]);
+    expect(edgesFrom(user, "implements").map((rel) => rel.to)).toEqual([byQualified("Node")?.id]);
+    expect(edgesFrom(byQualified("SearchResult")?.id, "references").map((rel) => rel.to)).toEqual([user, "Order"]);
+
+    const extension = entities.find((e) => e.metadata?.extension === true);
+    expect(edgesFrom(extension?.id, "extension_of").map((rel) => rel.to)).toEqual([user]);
+    expect(edgesFrom(extension?.id, "contains").map((rel) => rel.to)).toEqual([byQualified("User.nickname")?.id]);
+  });
+
+  it("is used by TreeSitterParser for .graphql files", async () => {
+    const parser = new TreeSitterParser();
+    await parser.initialize();
+    const result = await parser.parse("/repo/schema.graphql", SCHEMA, "graphql-hash-1");
+    expect(result.language).toBe("graphql");
+    expect(result.entities.some((e) => e.type === "module" && e.name === "schema.graphql")).toBe(true);
+  });
+});
@@ -0,0 +1,111 @@
+import { TreeSitterParser } from "../../src/parsers/tree-sitter-parser";
+import type { EntityRelationship } from "../../src/types/parser";
+
+describe("JavaAnalyzer", () => {
+  let parser: TreeSitterParser;
+
+  beforeAll(async () => {
+    parser = new TreeSitterParser();
+    await parser.initialize();
+  });
+
+  afterEach(() => {
+    parser.clearCache();
+  });
+
+  const code = `
+    package com.acme.users;
+
+    import java.io.Serializable;
+    import org.springframework.web.bind.annotation.RestController;
+
+    interface Handler<T> {
+      String PREFIX = "/users";
+      void handle(T input);
+    }
+
+    enum Role implements Serializable { ADMIN, MEMBER }
+
+    abstract class BaseController {
+      protected void audit() {}
+    }
+
+    @RestController
+    public class UserController extends BaseController implements Handler<String>, Serializable {
+      private final String name = "users";
+
+      @Override
+      public void handle(String input) {
+        validate(input);
+        this.audit();
+        Role.valueOf(input).toString();
+      }
+
+      private void validate(String input) {}
+    }
+  `;
+
+  it("extracts packages, types, members and package-qualified names", async () => {
+    const res = await parser.parse("UserController.java", code, "hash");
+    expect(res.language).toBe("java");
+
+    const byId = new Map(res.entities.map((e) => [e.id, e]));
+    const controller = byId.get("UserController.java:class:UserController");
+
+    expect(byId.get("UserController.java:package:com.acme.users")?.type).toBe("module");
+    expect(byId.get("UserController.java:interface:Handler")?.type).toBe("interface");
+    expect(byId.get("UserController.java:enum:Role")?.type).toBe("enum");
+    expect(byId.get("UserController.java:enum:Role:constant:ADMIN")?.type).toBe("constant");
+    expect(byId.get("UserController.java:class:Handler:field:PREFIX")?.type).toBe("constant");
+    expect(byId.get("UserController.java:class:UserController:field:name")?.type).toBe("constant");
+    expect(controller?.metadata?.qualifiedName).toBe("com.acme.users.UserController");
+    expect(controller?.modifiers).toEqual(["public"]);
+    expect(controller?.inheritance).toEqual({
+      baseClasses: ["BaseController"],
+      interfaces: ["Handler", "Serializable"],
+    });
+    expect(byId.get("UserController.java:class:UserController:method:handle")?.metadata?.qualifiedName).toBe(
+      "com.acme.users.UserController.handle",
+    );
+  });
+
+  it("keeps annotations as decorators and links imported ones", async () => {
+    const res = await parser.parse("UserController.java", code, "hash");
+    const relationships = (res as any).relationships as EntityRelationship[];
+    const handle = res.entities.find((e) => e.id === "UserController.java:class:UserController:method:handle");
+
+    expect(handle?.decorators).toEqual([expect.objectContaining({ name: "Override", raw: "Override" })]);
+    expect(handle?.modifiers).toEqual(["public"]);
+
+    const decorated = relationships.filter((r) => r.type === "decorated_by");
+    expect(decorated).toEqual([
+      expect.objectContaining({
+        from: "UserController.java:class:UserController",
+        to: "RestController",
+        targetFile: "org.springframework.web.bind.annotation",
+      }),
+    ]);
+  });
+
+  it("binds heritage and calls to declarations in the same file", async () => {
+    const res = await parser.parse("UserController.java", code, "hash");
+    const relationships = (res as any).relationships as EntityRelationship[];
+    const edge = (type: string, from: string) =>
+      relationships.filter((r) => r.type === type && r.from === from).map((r) => r.to);
+    const controller = "UserController.java:class:UserController";
+
+    expect(edge("extends", controller)).toEqual(["UserController.java:class:BaseController"]);
+    expect(edge("implements", controller)).toEqual(["UserController.java:interface:Handler", "Serializable"]);
+    expect(edge("implements", "UserController.java:enum:Role")).toEqual(["Serializable"]);
+    expect(edge("contains", "UserController.java:class:Handler:method:handle")).toEqual([
+      "UserController.java:interface:Handler",
+    ]);
+    expect(edge("calls", `${controller}:method:handle`)).toEqual([
+      `${controller}:method:validate`,
+      "UserController.java:class:BaseController:method:audit",
+      // `Role.valueOf(input).toString()`: the outer call comes first; neither is declared here
+      "toString",
+      "valueOf",
+    ]);
+  });
+});
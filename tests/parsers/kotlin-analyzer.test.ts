@@ -1,5 +1,9 @@
+import { mkdirSync, mkdtempSync, readFileSync, rmSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { dirname, join } from "node:path";
 import { TreeSitterParser } from "../../src/parsers/tree-sitter-parser";
 import type { EntityRelationship } from "../../src/types/parser";
+import { collectIndexableFiles, DEFAULT_INDEX_EXCLUDE_PATTERNS } from "../../src/utils/index-file-collection";
 
 describe("KotlinAnalyzer", () => {
   let parser: TreeSitterParser;
@@ -62,4 +66,116 @@ describe("KotlinAnalyzer", () => {
       relationships?.some((r) => r.type === "calls" && r.from === "sample.kt:class:A:method:f" && r.to === "g"),
     ).toBe(true);
   });
+
+  it("attributes extension functions and properties to their receiver type", async () => {
+    const code = `
+      class Money(val cents: Long)
+
+      fun Money.format(): String = "$" + cents / 100
+      fun <T> List<T>.second(): T = this[1]
+      val Money.isZero: Boolean get() = cents == 0L
+    `;
+
+    const res = await parser.parse("ext.kt", code, "hash");
+    const relationships = ((res as any).relationships ?? []) as EntityRelationship[];
+    const extensions = relationships.filter((r) => r.type === "extension_of").map((r) => [r.from, r.to]);
+
+    expect(extensions).toEqual([
+      ["ext.kt:function:Money.format", "Money"],
+      ["ext.kt:function:List.second", "List"],
+      ["ext.kt:property:Money.isZero", "Money"],
+    ]);
+    const second = res.entities.find((e) => e.id === "ext.kt:function:List.second");
+    expect(second?.metadata).toMatchObject({ isExtension: true, receiverName: "List" });
+  });
+
+  it("captures data class components and annotations", async () => {
+    const code = `
+      annotation class Audited(val reason: String)
+
+      @Audited("billing")
+      data class Invoice(val id: Long, @field:Transient val total: Double, var note: String? = null)
+    `;
+
+    const res = await parser.parse("model.kt", code, "hash");
+    const invoice = res.entities.find((e) => e.id === "model.kt:class:Invoice");
+
+    expect(invoice?.metadata?.isData).toBe(true);
+    expect(invoice?.metadata?.components.map((c: any) => [c.name, c.index])).toEqual([
+      ["id", 1],
+      ["total", 2],
+      ["note", 3],
+    ]);
+    expect(invoice?.metadata?.annotations).toEqual([{ name: "Audited", arguments: ['"billing"'] }]);
+
+    const total = res.entities.find((e) => e.id === "model.kt:class:Invoice:property:total");
+    expect(total?.metadata).toMatchObject({ componentIndex: 2, annotations: [{ name: "Transient", target: "field" }] });
+
+    const relationships = ((res as any).relationships ?? []) as EntityRelationship[];
+    expect(
+      relationships.some(
+        (r) => r.type === "decorated_by" && r.from === "model.kt:class:Invoice" && r.to === "model.kt:class:Audited",
+      ),
+    ).toBe(true);
+  });
+
+  it("extracts declarations from every Kotlin file of a Gradle project", async () => {
+    const root = mkdtempSync(join(tmpdir(), "kotlin-gradle-"));
+    const files: Record<string, string> = {
+      "settings.gradle.kts": `rootProject.name = "shop"\ninclude(":app")\n`,
+      "app/build.gradle.kts": `plugins {
+  kotlin("jvm") version "1.9.24"
+}
+
+dependencies {
+  implementation(kotlin("stdlib"))
+}
+`,
+      "app/src/main/kotlin/com/example/shop/Cart.kt": `package com.example.shop
+
+interface Priced {
+  val price: Long
+}
+
+data class Item(val sku: String, override val price: Long) : Priced
+
+object Cart {
+  private val items = mutableListOf<Item>()
+
+  fun add(item: Item) { items.add(item) }
+
+  fun total(): Long = items.sumOf { it.price }
+}
+`,
+      "app/src/main/kotlin/com/example/shop/Main.kt": `package com.example.shop
+
+fun main() {
+  Cart.add(Item("a-1", 250))
+  println(Cart.total())
+}
+`,
+    };
+    try {
+      for (const [path, content] of Object.entries(files)) {
+        mkdirSync(dirname(join(root, path)), { recursive: true });
+        writeFileSync(join(root, path), content);
+      }
+
+      const { files: found } = collectIndexableFiles(root, [...DEFAULT_INDEX_EXCLUDE_PATTERNS]);
+      expect(found.map((f) => f.slice(root.length + 1)).sort()).toEqual(Object.keys(files).sort());
+
+      const byFile = new Map<string, string[]>();
+      for (const file of found) {
+        const res = await parser.parse(file, readFileSync(file, "utf8"), "hash");
+        expect(res.language).toBe("kotlin");
+        byFile.set(file.slice(root.length + 1), res.entities.map((e) => e.name));
+      }
+      expect(byFile.get("app/src/main/kotlin/com/example/shop/Cart.kt")).toEqual(
+        expect.arrayContaining(["Priced", "Item", "Cart", "add", "total"]),
+      );
+      expect(byFile.get("app/src/main/kotlin/com/example/shop/Main.kt")).toContain("main");
+    } finally {
+      rmSync(root, { recursive: true, force: true });
+    }
+  });
 });
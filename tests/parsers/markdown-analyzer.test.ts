@@ -22,4 +22,41 @@ Some text
     expect(result.entities.some((e) => e.type === "heading" && e.name === "Section A")).toBe(true);
     expect(result.relationships?.some((r) => r.type === "contains")).toBe(true);
   });
+
+  it("spans each heading over its section and stores the prose and heading path", async () => {
+    const parser = new TreeSitterParser();
+    await parser.initialize();
+
+    const md = `Intro before any heading.
+
+# Setup
+
+Install the CLI.
+
+\`\`\`sh
+# not a heading
+npm install
+\`\`\`
+
+## Database
+
+Point DATABASE_URL at Postgres.
+
+# Usage
+`;
+
+    const result = await parser.parse("docs/guide.md", md, "md-hash-2");
+    const headings = result.entities.filter((e) => e.type === "heading");
+
+    expect(headings.map((e) => [e.name, e.location.start.line, e.location.end.line])).toEqual([
+      ["Setup", 3, 10],
+      ["Database", 12, 14],
+      ["Usage", 16, 16],
+    ]);
+    expect(headings[0]?.documentation).toContain("npm install");
+    expect(headings[1]?.metadata?.headingPath).toEqual(["Setup", "Database"]);
+    expect(headings[1]?.documentation).toBe("Point DATABASE_URL at Postgres.");
+    expect(headings[2]?.documentation).toBeUndefined();
+    expect(result.entities.find((e) => e.type === "document")?.documentation).toBe("Intro before any heading.");
+  });
 });
@@ -0,0 +1,46 @@
+import { IncrementalParser, timedOutParseResult } from "../../src/parsers/incremental-parser";
+import { TreeSitterParser } from "../../src/parsers/tree-sitter-parser";
+import { ParseTimeoutError } from "../../src/types/errors";
+
+// A generated bundle: big enough that tree-sitter cannot get through it in a millisecond
+const bundle = Array.from(
+  { length: 20000 },
+  (_, i) => `var m${i} = function (x) { return [x, ${i}].map((v) => v * 2).filter(Boolean); };`,
+).join("\n");
+
+describe("parse timeout", () => {
+  it("abandons a tree-sitter parse at the deadline and leaves the parser usable", async () => {
+    const parser = new TreeSitterParser();
+    await parser.initialize();
+
+    await expect(parser.parse("bundle.js", bundle, "hash", undefined, 1)).rejects.toBeInstanceOf(ParseTimeoutError);
+
+    // The abandoned parse is reset, not resumed on the next file
+    const res = await parser.parse("small.js", "function ok() { return 1; }\n", "hash", undefined, 1000);
+    expect(res.entities.map((e) => e.name)).toContain("ok");
+  });
+
+  it("returns a timed-out result that is not cached", async () => {
+    const parser = new IncrementalParser();
+    await parser.initialize();
+
+    const timedOut = await parser.parseFile("bundle.js", bundle, { timeoutMs: 1 });
+    expect(timedOut.timedOut).toBe(true);
+    expect(timedOut.entities).toEqual([]);
+    expect(timedOut.errors?.[0]?.message).toBe("Parsing bundle.js timed out after 1ms");
+
+    // Without a timeout the same content parses in full
+    const parsed = await parser.parseFile("bundle.js", bundle, { timeoutMs: 0 });
+    expect(parsed.timedOut).toBeUndefined();
+    expect(parsed.fromCache).toBeFalsy();
+    expect(parsed.entities.some((e) => e.name === "m19999")).toBe(true);
+  });
+
+  it("labels a timed-out file with the language of its path", () => {
+    const languageOf = (filePath: string) =>
+      timedOutParseResult(new ParseTimeoutError({ filePath, timeoutMs: 1 }), 1).language;
+    expect(languageOf("/repo/store/store.go")).toBe("go");
+    expect(languageOf("/repo/tools/gen.py")).toBe("python");
+    expect(languageOf("/repo/web/app.js")).toBe("javascript");
+  });
+});
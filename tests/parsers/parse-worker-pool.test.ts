@@ -0,0 +1,18 @@
+import { availableParallelism } from "node:os";
+import { describe, expect, it } from "@jest/globals";
+import { resolveParseWorkerScript, resolveWorkerPoolSize } from "../../src/parsers/parse-worker-pool.js";
+
+describe("parse worker pool configuration", () => {
+  it("uses a positive configured size and one worker per CPU otherwise", () => {
+    expect(resolveWorkerPoolSize(3)).toBe(3);
+    expect(resolveWorkerPoolSize(2.7)).toBe(2);
+    expect(resolveWorkerPoolSize(0)).toBe(availableParallelism());
+    expect(resolveWorkerPoolSize(undefined)).toBe(availableParallelism());
+    expect(resolveWorkerPoolSize(Number.NaN)).toBe(availableParallelism());
+  });
+
+  it("finds no compiled worker when running from TypeScript sources", () => {
+    // The parser agent then parses on the main thread
+    expect(resolveParseWorkerScript()).toBeNull();
+  });
+});
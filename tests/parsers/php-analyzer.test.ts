@@ -0,0 +1,150 @@
+import { TreeSitterParser } from "../../src/parsers/tree-sitter-parser";
+import type { EntityRelationship } from "../../src/types/parser";
+
+describe("PhpAnalyzer", () => {
+  let parser: TreeSitterParser;
+
+  beforeAll(async () => {
+    parser = new TreeSitterParser();
+    await parser.initialize();
+  });
+
+  afterEach(() => {
+    parser.clearCache();
+  });
+
+  const code = `<?php
+
+namespace App\\Http\\Controllers;
+
+use App\\Models\\User;
+use App\\Repositories\\{UserRepository as Users, AuditLog};
+use function App\\Support\\view;
+
+interface Renders
+{
+    public function render(): string;
+}
+
+trait LogsActions
+{
+    protected function log(string $message): void
+    {
+    }
+}
+
+/**
+ * Lists and shows users
+ */
+#[Route('/users', methods: ['GET'])]
+final class UserController extends Controller implements Renders
+{
+    use LogsActions;
+
+    public const PER_PAGE = 20;
+
+    protected ?AuditLog $audit = null;
+
+    public function __construct(private Users $users)
+    {
+    }
+
+    public function index(int $page = 1): string
+    {
+        $this->log('index');
+        $this->users->paginate(self::PER_PAGE);
+        User::query();
+        view('users.index');
+        return $this->render();
+    }
+
+    public function render(): string
+    {
+        return parent::render();
+    }
+}
+`;
+
+  it("extracts namespaced types, members and attributes", async () => {
+    const res = await parser.parse("UserController.php", code, "hash");
+    expect(res.language).toBe("php");
+
+    const byId = new Map(res.entities.map((e) => [e.id, e]));
+    const controller = byId.get("UserController.php:class:UserController");
+
+    expect(byId.get("UserController.php:namespace:App\\Http\\Controllers")?.type).toBe("module");
+    expect(byId.get("UserController.php:interface:Renders")?.type).toBe("interface");
+    expect(byId.get("UserController.php:trait:LogsActions")?.type).toBe("trait");
+    expect(controller?.metadata?.qualifiedName).toBe("App\\Http\\Controllers\\UserController");
+    expect(controller?.metadata?.superclass).toBe("App\\Http\\Controllers\\Controller");
+    expect(controller?.modifiers).toEqual(["final"]);
+    expect(controller?.documentation).toContain("Lists and shows users");
+    expect(controller?.decorators?.map((d) => d.name)).toEqual(["Route"]);
+
+    expect(byId.get("UserController.php:class:UserController:method:index")?.parameters).toEqual([
+      { name: "page", type: "int", optional: true, defaultValue: "1" },
+    ]);
+    expect(byId.get("UserController.php:class:UserController:constant:PER_PAGE")?.type).toBe("constant");
+    expect(byId.get("UserController.php:class:UserController:property:audit")?.metadata?.propertyType).toBe(
+      "App\\Repositories\\AuditLog",
+    );
+    // Promoted constructor parameters declare properties
+    expect(byId.get("UserController.php:class:UserController:property:users")?.metadata?.propertyType).toBe(
+      "App\\Repositories\\UserRepository",
+    );
+  });
+
+  it("links heritage, imports and calls by resolved name", async () => {
+    const res = await parser.parse("UserController.php", code, "hash");
+    const relationships = (res as any).relationships as EntityRelationship[];
+    const edge = (type: string, from: string) =>
+      relationships.filter((r) => r.type === type && r.from === from).map((r) => r.to);
+    const controller = "UserController.php:class:UserController";
+
+    expect(edge("imports", "UserController.php:namespace:App\\Http\\Controllers")).toEqual([
+      "App\\Models\\User",
+      "App\\Repositories\\UserRepository",
+      "App\\Repositories\\AuditLog",
+      "App\\Support\\view",
+    ]);
+    expect(edge("extends", controller)).toEqual(["App\\Http\\Controllers\\Controller"]);
+    expect(edge("implements", controller)).toEqual(["UserController.php:interface:Renders"]);
+    expect(edge("embeds", controller)).toEqual(["UserController.php:trait:LogsActions"]);
+    expect(edge("decorated_by", controller)).toEqual(["App\\Http\\Controllers\\Route"]);
+
+    const calls = relationships.filter((r) => r.type === "calls" && r.from === `${controller}:method:index`);
+    expect(calls.map((r) => [r.to, r.metadata?.receiverType])).toEqual([
+      ["UserController.php:trait:LogsActions:method:log", "App\\Http\\Controllers\\UserController"],
+      ["paginate", "App\\Repositories\\UserRepository"],
+      ["query", "App\\Models\\User"],
+      ["view", undefined],
+      [`${controller}:method:render`, "App\\Http\\Controllers\\UserController"],
+    ]);
+    expect(edge("calls", `${controller}:method:render`)).toEqual(["render"]);
+  });
+
+  it("keeps declarations from every block of a template mixing HTML and PHP", async () => {
+    const template = `<html>
+<?php function title(): string { return "Users"; } ?>
+<h1><?= title() ?></h1>
+<?php
+class Row
+{
+    public function cells(): array { return []; }
+}
+?>
+</html>
+`;
+    const res = await parser.parse("users.phtml", template, "hash");
+    const ids = res.entities.map((e) => e.id);
+
+    expect(res.language).toBe("php");
+    expect(ids).toEqual(
+      expect.arrayContaining([
+        "users.phtml:function:title",
+        "users.phtml:class:Row",
+        "users.phtml:class:Row:method:cells",
+      ]),
+    );
+  });
+});
@@ -0,0 +1,124 @@
+import { describe, expect, it } from "@jest/globals";
+import { ProtoAnalyzer, protoTypeCandidates } from "../../src/parsers/proto-analyzer.js";
+import { TreeSitterParser } from "../../src/parsers/tree-sitter-parser.js";
+
+const SCHEMA = `syntax = "proto3";
+
+package shop.v1;
+
+import "google/protobuf/timestamp.proto";
+option go_package = "example.com/shop/v1;shopv1";
+
+// A product in the catalog
+message Product {
+  string id = 1;
+  google.protobuf.Timestamp created_at = 2;
+  repeated Tag tags = 3; // trailing, not documentation
+  map<string, Tag> by_label = 4;
+  oneof price {
+    int64 cents = 5;
+    string quote = 6;
+  }
+
+  message Tag {
+    string label = 1 [deprecated = true];
+  }
+}
+
+enum Status {
+  STATUS_UNSPECIFIED = 0;
+  ACTIVE = 1;
+}
+
+service Catalog {
+  /* Looks one product up */
+  rpc GetProduct(GetProductRequest) returns (Product);
+  rpc Watch(stream WatchRequest) returns (stream Product) {
+    option idempotency_level = NO_SIDE_EFFECTS;
+  }
+}
+
+message GetProductRequest { string id = 1; }
+`;
+
+describe("ProtoAnalyzer", () => {
+  const { entities, relationships } = new ProtoAnalyzer().analyze(SCHEMA, "/repo/shop.proto");
+  const byName = (name: string, type?: string) =>
+    entities.find((e) => e.name === name && (type === undefined || e.type === type));
+
+  it("extracts messages, fields, enums, services and RPC methods with qualified names", () => {
+    expect(byName("Product", "struct")?.metadata).toMatchObject({
+      protoKind: "message",
+      qualifiedName: "shop.v1.Product",
+      package: "shop.v1",
+    });
+    expect(byName("Product", "struct")?.documentation).toBe("// A product in the catalog");
+    expect(byName("Tag", "struct")?.metadata?.qualifiedName).toBe("shop.v1.Product.Tag");
+    expect(byName("tags")).toMatchObject({
+      type: "field",
+      signature: "repeated Tag tags = 3",
+      modifiers: ["repeated"],
+    });
+    expect(byName("tags")?.documentation).toBeUndefined();
+    expect(byName("by_label")?.metadata).toMatchObject({ fieldType: "map<string, Tag>", fieldNumber: 4 });
+    expect(byName("quote")?.metadata).toMatchObject({ oneof: "price", className: "Product" });
+    expect(byName("ACTIVE")).toMatchObject({ type: "enum_variant", metadata: { value: 1 } });
+    expect(byName("Catalog")?.type).toBe("interface");
+    expect(byName("GetProduct")).toMatchObject({
+      type: "method",
+      signature: "rpc GetProduct(GetProductRequest) returns (Product)",
+      returnType: "Product",
+      documentation: "/* Looks one product up */",
+      metadata: { qualifiedName: "shop.v1.Catalog.GetProduct", grpcPath: "/shop.v1.Catalog/GetProduct" },
+    });
+    expect(byName("Watch")?.metadata).toMatchObject({ clientStreaming: true, serverStreaming: true });
+    expect(byName("Watch")?.location.end.line).toBe(34);
+  });
+
+  it("binds types declared in the file and leaves the others for the linker", () => {
+    const references = relationships.filter((r) => r.type === "references");
+    const to = (from: string) => references.filter((r) => r.from === byName(from)?.id).map((r) => r.to);
+
+    expect(to("tags")).toEqual([byName("Tag", "struct")?.id]);
+    expect(to("by_label")).toEqual([byName("Tag", "struct")?.id]);
+    expect(to("created_at")).toEqual(["google.protobuf.Timestamp"]);
+    expect(to("GetProduct")).toEqual([byName("GetProductRequest")?.id, byName("Product", "struct")?.id]);
+    expect(to("Watch")).toEqual(["WatchRequest", byName("Product", "struct")?.id]);
+    expect(to("id")).toEqual([]);
+
+    expect(relationships).toContainEqual(
+      expect.objectContaining({
+        from: "/repo/shop.proto:proto_file",
+        to: "google/protobuf/timestamp.proto",
+        type: "imports",
+      }),
+    );
+  });
+
+  it("lists every RPC as a gRPC route handled by its declaration", () => {
+    const routes = entities.filter((e) => e.type === "route");
+    expect(routes.map((r) => r.name)).toEqual(["RPC /shop.v1.Catalog/GetProduct", "RPC /shop.v1.Catalog/Watch"]);
+    expect(routes[1]?.metadata).toMatchObject({ httpMethod: "RPC", framework: "grpc", streaming: "bidi" });
+    expect(relationships).toContainEqual(
+      expect.objectContaining({ from: routes[0]?.id, to: byName("GetProduct")?.id, type: "handled_by" }),
+    );
+  });
+
+  it("resolves names from the innermost scope outwards", () => {
+    expect(protoTypeCandidates("Tag", "shop.v1.Product")).toEqual([
+      "shop.v1.Product.Tag",
+      "shop.v1.Tag",
+      "shop.Tag",
+      "Tag",
+    ]);
+    expect(protoTypeCandidates(".shop.v1.Tag", "shop.v1.Product")).toEqual(["shop.v1.Tag"]);
+  });
+
+  it("is used by TreeSitterParser for .proto files", async () => {
+    const parser = new TreeSitterParser();
+    await parser.initialize();
+    const result = await parser.parse("/repo/shop.proto", SCHEMA, "proto-hash-1");
+    expect(result.language).toBe("proto");
+    expect(result.entities.some((e) => e.type === "module" && e.name === "shop.proto")).toBe(true);
+  });
+});
@@ -6,6 +6,7 @@
 
 import { beforeEach, describe, expect, test } from "@jest/globals";
 import { PythonAnalyzer } from "../../src/parsers/python-analyzer.js";
+import { TreeSitterParser } from "../../src/parsers/tree-sitter-parser.js";
 
 describe("PythonAnalyzer", () => {
   let analyzer: PythonAnalyzer;
@@ -210,4 +211,63 @@ describe("PythonAnalyzer", () => {
       expect(results.some((r: any) => r.type === "reference")).toBe(true);
     });
   });
+
+  describe("decorators", () => {
+    const code = `
+import pytest
+from flask import Blueprint
+from .auth import login_required
+
+bp = Blueprint("api", __name__)
+
+def traced(fn):
+    return fn
+
+@bp.route("/users/<int:id>", methods=["GET"])
+@login_required
+@traced
+def get_user(id):
+    return id
+
+@pytest.fixture(scope="module")
+def client():
+    return None
+
+@dataclass(frozen=True)
+class Point:
+    x: int
+`;
+
+    test("keeps stacked and call-style decorators in source order with raw arguments", async () => {
+      const parser = new TreeSitterParser();
+      await parser.initialize();
+      const result = await parser.parse("app/views.py", code, "py-decorators");
+
+      const getUser = result.entities.filter((e) => e.name === "get_user");
+      expect(getUser).toHaveLength(1);
+      expect(getUser[0]!.decorators?.map((d) => d.name)).toEqual(["bp.route", "login_required", "traced"]);
+      expect(getUser[0]!.decorators?.[0]).toMatchObject({
+        arguments: ['"/users/<int:id>"', 'methods=["GET"]'],
+        raw: 'bp.route("/users/<int:id>", methods=["GET"])',
+      });
+
+      const point = result.entities.find((e) => e.name === "Point");
+      expect(point?.decorators?.[0]).toMatchObject({ name: "dataclass", arguments: ["frozen=True"] });
+    });
+
+    test("links decorated definitions to decorators that name a known symbol", async () => {
+      const parser = new TreeSitterParser();
+      await parser.initialize();
+      const result = await parser.parse("app/views.py", code, "py-decorators-rels");
+
+      const decoratedBy = (result.relationships ?? [])
+        .filter((r) => r.type === "decorated_by")
+        .map((r) => [r.from, r.to, r.targetFile]);
+      expect(decoratedBy).toEqual([
+        ["get_user", "login_required", ".auth"],
+        ["get_user", "traced", undefined],
+        ["client", "fixture", "pytest"],
+      ]);
+    });
+  });
 });
@@ -0,0 +1,142 @@
+import { joinRoutePath } from "../../src/parsers/route-extractor";
+import { TreeSitterParser } from "../../src/parsers/tree-sitter-parser";
+import type { EntityRelationship, ParsedEntity } from "../../src/types/parser";
+
+function routes(entities: ParsedEntity[]) {
+  return entities
+    .filter((e) => e.type === "route")
+    .map((e) => [e.name, e.metadata?.framework, e.metadata?.handler ?? null, e.location.start.line]);
+}
+
+describe("route extraction", () => {
+  let parser: TreeSitterParser;
+
+  beforeAll(async () => {
+    parser = new TreeSitterParser();
+    await parser.initialize();
+  });
+
+  afterEach(() => {
+    parser.clearCache();
+  });
+
+  it("joins prefixes and paths", () => {
+    expect(joinRoutePath("users", ":id")).toBe("/users/:id");
+    expect(joinRoutePath("/api/", "/users/")).toBe("/api/users/");
+    expect(joinRoutePath(undefined, undefined)).toBe("/");
+  });
+
+  it("reads Express router calls and leaves HTTP clients alone", async () => {
+    const code = `import express from "express";
+import { showUser } from "./handlers/users";
+
+const router = express.Router();
+
+router.get("/users/:id", requireAuth, showUser);
+router.post("/users", asyncHandler(users.create));
+router
+  .route("/items")
+  .get(listItems)
+  .delete((req, res) => res.sendStatus(204));
+
+axios.get("/users", config);
+cache.get("/users");
+
+function listItems(req, res) {}
+`;
+    const res = await parser.parse("routes.js", code, "hash");
+
+    expect(routes(res.entities)).toEqual([
+      ["GET /users/:id", "express", "showUser", 6],
+      ["POST /users", "express", "users.create", 7],
+      ["DELETE /items", "express", null, 8],
+      ["GET /items", "express", "listItems", 8],
+    ]);
+    const route = res.entities.find((e) => e.name === "GET /users/:id");
+    expect(route?.handler).toMatchObject({ name: "showUser", line: 6 });
+    expect(res.entities.find((e) => e.name === "DELETE /items")?.metadata?.inlineHandler).toBe(true);
+  });
+
+  it("combines NestJS controller and method decorators", async () => {
+    const code = `import { Controller, Get, Post } from "@nestjs/common";
+
+@Controller("users")
+export class UsersController {
+  @Get()
+  findAll() {}
+
+  @Get(":id")
+  findOne() {}
+
+  @Post()
+  create() {}
+}
+`;
+    const res = await parser.parse("users.controller.ts", code, "hash");
+
+    expect(routes(res.entities)).toEqual([
+      ["GET /users", "nestjs", "findAll", 6],
+      ["GET /users/:id", "nestjs", "findOne", 9],
+      ["POST /users", "nestjs", "create", 12],
+    ]);
+  });
+
+  it("reads Flask and FastAPI decorators under their router prefix", async () => {
+    const code = `from fastapi import APIRouter
+from pydantic import BaseModel
+
+router = APIRouter(prefix="/users")
+
+class UserOut(BaseModel):
+    id: int
+
+@router.get("/{user_id}")
+async def read_user(user_id: int):
+    return {}
+
+@router.api_route("/", methods=["GET", "POST"])
+def users():
+    return []
+`;
+    const res = await parser.parse("users.py", code, "hash");
+    const relationships = (res as any).relationships as EntityRelationship[] | undefined;
+
+    expect(routes(res.entities).map(([name, framework, handler]) => [name, framework, handler])).toEqual([
+      ["GET /users/{user_id}", "fastapi", "read_user"],
+      ["GET /users/", "fastapi", "users"],
+      ["POST /users/", "fastapi", "users"],
+    ]);
+    // Python supplies its own edges, so the handler edges come with them
+    const handled = relationships?.filter((r) => r.type === "handled_by").map((r) => r.to);
+    expect(handled).toEqual(["read_user", "users", "users"]);
+  });
+
+  it("reads Go mux registrations, including method patterns and gorilla Methods", async () => {
+    const code = `package main
+
+import (
+  "net/http"
+
+  "github.com/gorilla/mux"
+)
+
+func main() {
+  http.HandleFunc("GET /health", health)
+  r := mux.NewRouter()
+  r.HandleFunc("/users", h.Users).Methods("GET", "POST")
+  r.Handle("/static/", http.FileServer(http.Dir("./public")))
+  http.Get("http://example.com/")
+}
+
+func health(w http.ResponseWriter, r *http.Request) {}
+`;
+    const res = await parser.parse("main.go", code, "hash");
+
+    expect(routes(res.entities)).toEqual([
+      ["GET /health", "gorilla", "health", 10],
+      ["GET /users", "gorilla", "h.Users", 12],
+      ["POST /users", "gorilla", "h.Users", 12],
+      ["ANY /static/", "gorilla", null, 13],
+    ]);
+  });
+});
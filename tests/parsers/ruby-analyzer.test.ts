@@ -0,0 +1,125 @@
+import { TreeSitterParser } from "../../src/parsers/tree-sitter-parser";
+import type { EntityRelationship } from "../../src/types/parser";
+
+describe("RubyAnalyzer", () => {
+  let parser: TreeSitterParser;
+
+  beforeAll(async () => {
+    parser = new TreeSitterParser();
+    await parser.initialize();
+  });
+
+  afterEach(() => {
+    parser.clearCache();
+  });
+
+  const code = `
+module Shop
+  module Auditable
+    def audit(event)
+      log(event)
+    end
+  end
+
+  module Finders
+    def find_recent(limit = 10)
+    end
+  end
+
+  # An order placed by a customer
+  class Order < ApplicationRecord
+    include Auditable
+    extend Finders
+
+    has_many :line_items, dependent: :destroy
+    belongs_to :buyer, class_name: "Customer"
+    attr_accessor :note
+
+    def self.latest
+      find_recent(5)
+    end
+
+    def total
+      audit(:total)
+      self.subtotal
+      LineItem.build(note)
+    end
+
+    private
+
+    def subtotal
+    end
+  end
+
+  class LineItem < ApplicationRecord
+    belongs_to :order
+
+    class << self
+      def build(attrs)
+      end
+    end
+  end
+end
+
+class Shop::Order
+  def refund(amount)
+  end
+end
+`;
+
+  it("extracts modules, classes and methods by qualified name", async () => {
+    const res = await parser.parse("order.rb", code, "hash");
+    expect(res.language).toBe("ruby");
+
+    const byId = new Map(res.entities.map((e) => [e.id, e]));
+    const order = byId.get("order.rb:class:Shop::Order");
+
+    expect(byId.get("order.rb:module:Shop")?.type).toBe("module");
+    expect(byId.get("order.rb:module:Shop::Auditable")?.metadata?.qualifiedName).toBe("Shop::Auditable");
+    expect(order?.type).toBe("class");
+    expect(order?.metadata?.superclass).toBe("ApplicationRecord");
+    expect(order?.documentation).toBe("# An order placed by a customer");
+    expect(byId.get("order.rb:class:Shop::Order:classmethod:latest")?.metadata?.qualifiedName).toBe(
+      "Shop::Order.latest",
+    );
+    expect(byId.get("order.rb:class:Shop::Order:method:total")?.metadata?.qualifiedName).toBe("Shop::Order#total");
+    expect(byId.get("order.rb:class:Shop::Order:method:subtotal")?.modifiers).toEqual(["private"]);
+    expect(byId.get("order.rb:class:Shop::Order:property:note")?.type).toBe("property");
+    expect(byId.get("order.rb:class:Shop::LineItem:classmethod:build")?.metadata?.isClassMethod).toBe(true);
+    expect(byId.get("order.rb:module:Shop::Finders:method:find_recent")?.parameters).toEqual([
+      { name: "limit", optional: true, defaultValue: "10" },
+    ]);
+  });
+
+  it("extends a class reopened in the same file", async () => {
+    const res = await parser.parse("order.rb", code, "hash");
+    const relationships = (res as any).relationships as EntityRelationship[];
+
+    expect(res.entities.filter((e) => e.metadata?.qualifiedName === "Shop::Order")).toHaveLength(1);
+    expect(
+      relationships.find((r) => r.type === "contains" && r.from === "order.rb:class:Shop::Order:method:refund")?.to,
+    ).toBe("order.rb:class:Shop::Order");
+  });
+
+  it("links superclasses, mixins, associations and calls", async () => {
+    const res = await parser.parse("order.rb", code, "hash");
+    const relationships = (res as any).relationships as EntityRelationship[];
+    const edge = (type: string, from: string) =>
+      relationships.filter((r) => r.type === type && r.from === from).map((r) => r.to);
+    const order = "order.rb:class:Shop::Order";
+
+    expect(edge("extends", order)).toEqual(["ApplicationRecord"]);
+    expect(edge("implements", order)).toEqual(["order.rb:module:Shop::Auditable", "order.rb:module:Shop::Finders"]);
+    // `line_items` names `LineItem`, found in the enclosing module; `Customer` is declared elsewhere
+    expect(edge("references", order)).toEqual(["order.rb:class:Shop::LineItem", "Customer"]);
+    expect(edge("references", "order.rb:class:Shop::LineItem")).toEqual([order]);
+
+    expect(edge("calls", `${order}:method:total`)).toEqual([
+      "order.rb:module:Shop::Auditable:method:audit",
+      `${order}:method:subtotal`,
+      "order.rb:class:Shop::LineItem:classmethod:build",
+    ]);
+    // Class methods see the instance methods of extended modules
+    expect(edge("calls", `${order}:classmethod:latest`)).toEqual(["order.rb:module:Shop::Finders:method:find_recent"]);
+  });
+});
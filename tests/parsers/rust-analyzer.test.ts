@@ -112,6 +112,43 @@ describe("RustAnalyzer", () => {
       expect(result.relationships.some((r) => r.type === "extends")).toBe(true);
     });
 
+    it("should link trait impls to the local struct and trait ids", async () => {
+      const root = createMockNode("source_file", "");
+      const structNode = createMockNode("struct_item", "Widget");
+      const traitNode = createMockNode("trait_item", "Render");
+      const implNode = createMockImplNode("Widget", "Render");
+      root.childCount = 3;
+      root.child = (i: number) => [structNode, traitNode, implNode][i] ?? null;
+
+      const result = await analyzer.analyze(root, "test.rs");
+      const impls = result.relationships.filter((r) => r.type === "implements");
+
+      expect(impls).toHaveLength(1);
+      expect(impls[0]?.from).toBe("test.rs:struct:Widget");
+      expect(impls[0]?.to).toBe("test.rs:trait:Render");
+    });
+
+    it("should qualify names declared inside nested modules", async () => {
+      const outer = createMockNode("mod_item", "net");
+      const inner = createMockNode("mod_item", "http");
+      const structNode = createMockNode("struct_item", "Client");
+      inner.parent = outer;
+      structNode.parent = inner;
+      inner.childCount = 1;
+      inner.child = () => structNode;
+      outer.childCount = 1;
+      outer.child = () => inner;
+
+      const result = await analyzer.analyze(outer, "test.rs");
+      const client = result.entities.find((e) => e.type === "struct");
+      const innerModule = result.entities.find((e) => e.type === "module" && e.name === "http");
+
+      expect(client?.id).toBe("test.rs:struct:net::http::Client");
+      expect(client?.metadata?.qualifiedName).toBe("net::http::Client");
+      expect(client?.metadata?.modulePath).toEqual(["net", "http"]);
+      expect(innerModule?.metadata?.qualifiedName).toBe("net::http");
+    });
+
     it("should extract module containment", async () => {
       const mockNode = createMockNestedModuleNode();
       const result = await analyzer.analyze(mockNode, "test.rs");
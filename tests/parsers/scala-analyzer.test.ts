@@ -0,0 +1,218 @@
+import { mkdirSync, mkdtempSync, readFileSync, rmSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { dirname, join } from "node:path";
+import { TreeSitterParser } from "../../src/parsers/tree-sitter-parser";
+import type { EntityRelationship } from "../../src/types/parser";
+import { collectIndexableFiles, DEFAULT_INDEX_EXCLUDE_PATTERNS } from "../../src/utils/index-file-collection";
+
+describe("ScalaAnalyzer", () => {
+  let parser: TreeSitterParser;
+
+  beforeAll(async () => {
+    parser = new TreeSitterParser();
+    await parser.initialize();
+  });
+
+  afterEach(() => {
+    parser.clearCache();
+  });
+
+  const code = `
+package com.shop
+package orders
+
+import scala.concurrent.{ExecutionContext, Future => Later}
+import com.shop.json._
+
+/** Something that has a price */
+trait Priced {
+  def price: Long
+  def discounted(percent: Int): Long = price - price * percent / 100
+}
+
+trait Audited
+
+abstract class Entity(val id: String)
+
+/** An order line */
+case class Line(sku: String, quantity: Int = 1)
+
+final case class Order(id: String, lines: List[Line]) extends Entity(id) with Priced with Audited {
+  def price: Long = lines.map(_.quantity).sum
+  def total(implicit ec: ExecutionContext): Later[Long] = Later(discounted(10))
+  def withLine(line: Line): Order = copy(lines = lines :+ line)
+}
+
+object Order {
+  val empty: Order = Order("")
+  def apply(id: String): Order = new Order(id, Nil)
+  implicit def fromLine(line: Line): Order = Order("line").withLine(line)
+}
+
+object Orders {
+  implicit val ordering: Ordering[Order] = Ordering.by(_.id)
+  def first(): Order = Order.apply("first")
+
+  implicit class RichLine(line: Line) {
+    def label: String = line.sku
+  }
+}
+`;
+  const file = "Order.scala";
+
+  it("extracts packages, types, members and imports", async () => {
+    const res = await parser.parse(file, code, "hash");
+    expect(res.language).toBe("scala");
+
+    const byId = new Map(res.entities.map((e) => [e.id, e]));
+    expect(res.entities.filter((e) => e.type === "module").map((e) => e.name)).toEqual([
+      "com.shop",
+      "com.shop.orders",
+    ]);
+
+    const order = byId.get(`${file}:class:Order`);
+    expect(order?.modifiers).toEqual(["final", "case"]);
+    expect(order?.metadata).toMatchObject({ scalaKind: "case class", isCase: true, package: "com.shop.orders" });
+    expect(byId.get(`${file}:trait:Priced`)?.type).toBe("trait");
+    expect(byId.get(`${file}:trait:Priced`)?.documentation).toBe("/** Something that has a price */");
+    expect(byId.get(`${file}:trait:Priced:method:price`)?.metadata?.isAbstract).toBe(true);
+    expect(byId.get(`${file}:object:Orders`)?.metadata?.scalaKind).toBe("object");
+    expect(byId.get(`${file}:class:Orders.RichLine`)?.metadata?.parentClass).toBe("Orders");
+
+    // Case class parameters are fields; other classes only expose `val`/`var` parameters
+    expect(byId.get(`${file}:class:Line`)?.parameters).toEqual([
+      { name: "sku", type: "String" },
+      { name: "quantity", type: "Int", optional: true, defaultValue: "1" },
+    ]);
+    expect(byId.get(`${file}:class:Line:property:quantity`)?.metadata).toMatchObject({
+      propertyType: "Int",
+      mutability: "val",
+      isConstructorParameter: true,
+    });
+    expect(byId.get(`${file}:class:Entity:property:id`)?.metadata?.className).toBe("Entity");
+    expect(byId.get(`${file}:class:Orders.RichLine:property:line`)).toBeUndefined();
+    expect(byId.get(`${file}:object:Order:property:empty`)?.metadata).toMatchObject({
+      propertyType: "Order",
+      isStatic: true,
+    });
+
+    const imports = res.entities.filter((e) => e.type === "import");
+    expect(imports.map((e) => [e.name, e.importData?.specifiers, e.importData?.isNamespace ?? false])).toEqual([
+      [
+        "scala.concurrent",
+        [
+          { local: "ExecutionContext", imported: "ExecutionContext" },
+          { local: "Later", imported: "Future", alias: "Later" },
+        ],
+        false,
+      ],
+      ["com.shop.json", [], true],
+    ]);
+  });
+
+  it("records implicit parameters, values and conversions", async () => {
+    const res = await parser.parse(file, code, "hash");
+    const byId = new Map(res.entities.map((e) => [e.id, e]));
+
+    expect(byId.get(`${file}:class:Order:method:total`)?.metadata?.implicitParameters).toEqual([
+      { name: "ec", type: "ExecutionContext" },
+    ]);
+    expect(byId.get(`${file}:object:Order:method:fromLine`)?.metadata).toMatchObject({
+      isImplicit: true,
+      implicitConversion: { from: "Line", to: "Order" },
+    });
+    expect(byId.get(`${file}:object:Orders:property:ordering`)?.metadata).toMatchObject({
+      isImplicit: true,
+      propertyType: "Ordering[Order]",
+    });
+    expect(byId.get(`${file}:class:Orders.RichLine`)?.metadata?.implicitConversion).toEqual({
+      from: "Line",
+      to: "RichLine",
+    });
+    expect(byId.get(`${file}:class:Order:method:withLine`)?.metadata?.isImplicit).toBeUndefined();
+  });
+
+  it("links parents, mixins, companions and calls", async () => {
+    const res = await parser.parse(file, code, "hash");
+    const relationships = (res as any).relationships as EntityRelationship[];
+    const byId = new Map(res.entities.map((e) => [e.id, e]));
+    const edge = (type: string, from: string) =>
+      relationships.filter((r) => r.type === type && r.from === from).map((r) => r.to);
+    const order = `${file}:class:Order`;
+    const companion = `${file}:object:Order`;
+
+    expect(edge("extends", order)).toEqual([`${file}:class:Entity`]);
+    expect(edge("implements", order)).toEqual([`${file}:trait:Priced`, `${file}:trait:Audited`]);
+    expect(byId.get(order)?.metadata).toMatchObject({ superclass: "Entity", mixins: ["Priced", "Audited"] });
+
+    expect(edge("references", companion)).toEqual([order]);
+    expect(byId.get(companion)?.metadata).toMatchObject({ isCompanion: true, companionOf: order });
+    expect(byId.get(order)?.metadata?.companion).toBe(companion);
+    expect(byId.get(`${file}:object:Orders`)?.metadata?.isCompanion).toBeUndefined();
+
+    // Mixed-in members, the companion's `apply` and `Name.member` on objects bind within the file
+    expect(edge("calls", `${order}:method:total`)).toEqual(["Later", `${file}:trait:Priced:method:discounted`]);
+    expect(edge("calls", `${companion}:property:empty`)).toEqual([`${companion}:method:apply`]);
+    expect(edge("calls", `${file}:object:Orders:method:first`)).toEqual([`${companion}:method:apply`]);
+    expect(edge("calls", `${order}:method:withLine`)).toEqual(["copy"]);
+  });
+
+  it("indexes an sbt project", async () => {
+    const root = mkdtempSync(join(tmpdir(), "sbt-"));
+    const files: Record<string, string> = {
+      "build.sbt": `ThisBuild / scalaVersion := "2.13.14"
+
+lazy val root = (project in file(".")).settings(name := "shop")
+`,
+      "project/plugins.sbt": `addSbtPlugin("org.scalameta" % "sbt-scalafmt" % "2.5.2")
+`,
+      "src/main/scala/shop/Item.scala": `package shop
+
+case class Item(id: String, price: Long)
+
+object Item {
+  def free(id: String): Item = Item(id, 0)
+}
+`,
+      "src/main/scala/shop/Main.scala": `package shop
+
+object Main extends App {
+  println(Item.free("a"))
+}
+`,
+    };
+    const ignored = [
+      "target/scala-2.13/src_managed/Generated.scala",
+      "project/target/Build.scala",
+      ".bloop/shop/Generated.scala",
+      ".metals/readonly/Predef.scala",
+    ];
+    try {
+      for (const [path, content] of Object.entries(files)) {
+        mkdirSync(dirname(join(root, path)), { recursive: true });
+        writeFileSync(join(root, path), content);
+      }
+      for (const path of ignored) {
+        mkdirSync(dirname(join(root, path)), { recursive: true });
+        writeFileSync(join(root, path), "object Generated\n");
+      }
+
+      const { files: found } = collectIndexableFiles(root, [...DEFAULT_INDEX_EXCLUDE_PATTERNS]);
+      expect(found.map((f) => f.slice(root.length + 1)).sort()).toEqual(Object.keys(files).sort());
+
+      const byFile = new Map<string, string[]>();
+      for (const path of found) {
+        const res = await parser.parse(path, readFileSync(path, "utf8"), "hash");
+        expect(res.language).toBe("scala");
+        byFile.set(path.slice(root.length + 1), res.entities.map((e) => e.name));
+      }
+      expect(byFile.get("src/main/scala/shop/Item.scala")).toEqual(
+        expect.arrayContaining(["shop", "Item", "id", "price", "free"]),
+      );
+      expect(byFile.get("src/main/scala/shop/Main.scala")).toEqual(expect.arrayContaining(["shop", "Main"]));
+      expect(byFile.get("build.sbt")).toContain("root");
+    } finally {
+      rmSync(root, { recursive: true, force: true });
+    }
+  });
+});
@@ -0,0 +1,203 @@
+import { describe, expect, it } from "@jest/globals";
+import { matchSqlTable, SqlAnalyzer } from "../../src/parsers/sql-analyzer.js";
+import { TreeSitterParser } from "../../src/parsers/tree-sitter-parser.js";
+
+const POSTGRES = `-- Accounts that can sign in
+CREATE TABLE IF NOT EXISTS public.users (
+  id bigserial PRIMARY KEY,
+  email varchar(255) NOT NULL UNIQUE, -- login name
+  "displayName" text,
+  created_at timestamp with time zone NOT NULL DEFAULT now(),
+  org_id integer REFERENCES orgs (id) ON DELETE CASCADE
+);
+
+COMMENT ON COLUMN public.users.email IS 'Lower-cased on write';
+
+CREATE TABLE orders (
+  id serial,
+  user_id bigint NOT NULL,
+  total numeric(10, 2) DEFAULT 0,
+  CONSTRAINT orders_pkey PRIMARY KEY (id),
+  CONSTRAINT orders_user_fk FOREIGN KEY (user_id) REFERENCES public.users (id) ON DELETE SET NULL
+);
+
+ALTER TABLE orders ADD COLUMN note text;
+
+CREATE OR REPLACE VIEW active_users AS
+  SELECT u.id, u.email, count(o.id) AS orders
+  FROM users u
+  LEFT JOIN orders o ON o.user_id = u.id
+  WHERE EXTRACT(year FROM u.created_at) > 2020
+  GROUP BY u.id;
+
+CREATE FUNCTION order_total(p_user bigint, since date DEFAULT now()) RETURNS numeric
+LANGUAGE plpgsql AS $$
+DECLARE
+  result numeric;
+BEGIN
+  SELECT sum(total) INTO result FROM orders WHERE user_id = p_user;
+  RETURN result;
+END;
+$$;
+`;
+
+const MYSQL = `DROP TABLE IF EXISTS \`products\`;
+CREATE TABLE \`products\` (
+  \`id\` int unsigned NOT NULL AUTO_INCREMENT,
+  \`key\` varchar(64) NOT NULL COMMENT 'Stable SKU',
+  \`category_id\` int DEFAULT NULL,
+  \`price\` decimal(10,2) NOT NULL DEFAULT '0.00',
+  \`updated_at\` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (\`id\`),
+  UNIQUE KEY \`products_key\` (\`key\`),
+  KEY \`category_idx\` (\`category_id\`),
+  CONSTRAINT \`products_category_fk\` FOREIGN KEY (\`category_id\`) REFERENCES \`categories\` (\`id\`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='Catalog entries';
+
+DELIMITER //
+CREATE DEFINER=\`root\`@\`localhost\` PROCEDURE restock(IN p_id INT, IN amount INT)
+BEGIN
+  IF amount > 0 THEN
+    UPDATE products SET stock = stock + amount WHERE id = p_id;
+  END IF;
+  INSERT INTO audit_log (product_id) VALUES (p_id);
+END //
+DELIMITER ;
+
+CREATE PROCEDURE noop() BEGIN SELECT 1; SELECT 2; END;
+CREATE TABLE after_noop (id int);
+`;
+
+describe("SqlAnalyzer", () => {
+  describe("Postgres", () => {
+    const { entities, relationships } = new SqlAnalyzer().analyze(POSTGRES, "/repo/db/001_init.sql");
+    const byName = (name: string, type?: string) =>
+      entities.find((e) => e.name === name && (type === undefined || e.type === type));
+    const edges = (from: string, type: string) =>
+      relationships.filter((r) => r.from === byName(from)?.id && r.type === type).map((r) => r.to);
+
+    it("extracts tables with their columns, keys and documentation", () => {
+      const users = byName("users", "table");
+      expect(users?.metadata).toMatchObject({ sqlKind: "table", qualifiedName: "public.users", schema: "public" });
+      expect(users?.documentation).toBe("-- Accounts that can sign in");
+      expect(users?.metadata?.columns).toEqual([
+        { name: "id", dataType: "bigserial", nullable: false, primaryKey: true, autoIncrement: true },
+        { name: "email", dataType: "varchar(255)", nullable: false, unique: true, comment: "Lower-cased on write" },
+        { name: "displayName", dataType: "text", nullable: true },
+        { name: "created_at", dataType: "timestamp with time zone", nullable: false, default: "now()" },
+        { name: "org_id", dataType: "integer", nullable: true },
+      ]);
+      expect(users?.metadata?.foreignKeys).toEqual([
+        { columns: ["org_id"], table: "orgs", referencedColumns: ["id"], onDelete: "CASCADE" },
+      ]);
+
+      expect(byName("email", "field")).toMatchObject({
+        signature: "email varchar(255) NOT NULL UNIQUE",
+        documentation: "Lower-cased on write",
+        metadata: { sqlKind: "column", qualifiedName: "public.users.email", table: "public.users", className: "users" },
+      });
+      expect(byName("displayName")?.metadata?.qualifiedName).toBe("public.users.displayName");
+      expect(edges("users", "contains")).toHaveLength(5);
+    });
+
+    it("reads table-level constraints and ALTER TABLE additions", () => {
+      const orders = byName("orders", "table");
+      expect(orders?.signature).toBe("TABLE orders(id serial, user_id bigint, total numeric(10, 2), note text)");
+      expect(orders?.metadata?.primaryKey).toEqual(["id"]);
+      expect(orders?.metadata?.columns?.[0]).toMatchObject({ name: "id", primaryKey: true, nullable: false });
+      expect(orders?.metadata?.columns?.[2]).toMatchObject({ default: "0" });
+      expect(orders?.metadata?.foreignKeys).toEqual([
+        {
+          columns: ["user_id"],
+          table: "public.users",
+          referencedColumns: ["id"],
+          constraint: "orders_user_fk",
+          onDelete: "SET NULL",
+        },
+      ]);
+      expect(entities.filter((e) => e.type === "table").map((e) => e.name)).toEqual(["users", "orders"]);
+    });
+
+    it("links foreign keys to tables of the file and leaves the others for the linker", () => {
+      expect(edges("orders", "foreign_key")).toEqual([byName("users", "table")?.id]);
+      expect(edges("users", "foreign_key")).toEqual(["sql:orgs"]);
+      expect(relationships.find((r) => r.to === "sql:orgs")?.metadata?.referencedName).toBe("orgs");
+    });
+
+    it("records the tables views and routines query", () => {
+      expect(byName("active_users")).toMatchObject({ type: "view", modifiers: ["or replace"] });
+      const tables = [byName("users", "table")?.id, byName("orders", "table")?.id];
+      expect(edges("active_users", "references")).toEqual(tables);
+
+      expect(byName("order_total")).toMatchObject({
+        type: "function",
+        signature: "FUNCTION order_total(p_user bigint, since date DEFAULT now()) RETURNS numeric",
+        returnType: "numeric",
+        parameters: [
+          { name: "p_user", type: "bigint" },
+          { name: "since", type: "date", defaultValue: "now()", optional: true },
+        ],
+        metadata: { sqlKind: "function", routineLanguage: "plpgsql" },
+      });
+      expect(edges("order_total", "references")).toEqual([byName("orders", "table")?.id]);
+    });
+  });
+
+  describe("MySQL", () => {
+    const { entities, relationships } = new SqlAnalyzer().analyze(MYSQL, "/repo/db/dump.sql");
+    const byName = (name: string) => entities.find((e) => e.name === name);
+
+    it("reads backquoted names, column options and table options", () => {
+      const products = byName("products");
+      expect(products?.documentation).toBe("Catalog entries");
+      expect(products?.metadata?.primaryKey).toEqual(["id"]);
+      expect(products?.metadata?.columns).toEqual([
+        { name: "id", dataType: "int unsigned", nullable: false, autoIncrement: true, primaryKey: true },
+        { name: "key", dataType: "varchar(64)", nullable: false, comment: "Stable SKU" },
+        { name: "category_id", dataType: "int", nullable: true, default: "NULL" },
+        { name: "price", dataType: "decimal(10, 2)", nullable: false, default: "'0.00'" },
+        { name: "updated_at", dataType: "timestamp", nullable: false, default: "CURRENT_TIMESTAMP" },
+      ]);
+      expect(products?.metadata?.foreignKeys).toEqual([
+        {
+          columns: ["category_id"],
+          table: "categories",
+          referencedColumns: ["id"],
+          constraint: "products_category_fk",
+        },
+      ]);
+      expect(byName("key")?.documentation).toBe("Stable SKU");
+    });
+
+    it("splits routine bodies at DELIMITER and at the END of BEGIN blocks", () => {
+      const restock = byName("restock");
+      expect(restock).toMatchObject({
+        type: "function",
+        signature: "PROCEDURE restock(IN p_id INT, IN amount INT)",
+        metadata: { sqlKind: "procedure" },
+      });
+      expect(restock?.parameters?.map((p) => p.name)).toEqual(["p_id", "amount"]);
+      expect(
+        relationships.filter((r) => r.from === restock?.id && r.type === "references").map((r) => r.to),
+      ).toEqual([byName("products")?.id, "sql:audit_log"]);
+
+      expect(byName("noop")?.metadata?.sqlKind).toBe("procedure");
+      expect(byName("after_noop")?.type).toBe("table");
+    });
+  });
+
+  it("matches table names with or without their schema", () => {
+    expect(matchSqlTable("users", ["public.users", "orders"])).toBe("public.users");
+    expect(matchSqlTable("public.users", ["users"])).toBe("users");
+    expect(matchSqlTable("users", ["public.users", "audit.users"])).toBeUndefined();
+    expect(matchSqlTable("audit.users", ["public.users"])).toBeUndefined();
+  });
+
+  it("is used by TreeSitterParser for .sql files", async () => {
+    const parser = new TreeSitterParser();
+    await parser.initialize();
+    const result = await parser.parse("/repo/db/001_init.sql", POSTGRES, "sql-hash-1");
+    expect(result.language).toBe("sql");
+    expect(result.entities.some((e) => e.type === "table" && e.name === "orders")).toBe(true);
+  });
+});
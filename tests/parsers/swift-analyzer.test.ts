@@ -0,0 +1,213 @@
+import { mkdirSync, mkdtempSync, readFileSync, rmSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { dirname, join } from "node:path";
+import { TreeSitterParser } from "../../src/parsers/tree-sitter-parser";
+import type { EntityRelationship } from "../../src/types/parser";
+import { collectIndexableFiles, DEFAULT_INDEX_EXCLUDE_PATTERNS } from "../../src/utils/index-file-collection";
+
+describe("SwiftAnalyzer", () => {
+  let parser: TreeSitterParser;
+
+  beforeAll(async () => {
+    parser = new TreeSitterParser();
+    await parser.initialize();
+  });
+
+  afterEach(() => {
+    parser.clearCache();
+  });
+
+  const code = `
+import Foundation
+@testable import ShopCore
+
+protocol Priced {
+  var price: Int { get }
+  func discounted(by percent: Int) -> Int
+}
+
+extension Priced {
+  func discounted(by percent: Int) -> Int {
+    price - price * percent / 100
+  }
+}
+
+@propertyWrapper
+struct Clamped {
+  var wrappedValue: Int
+}
+
+/// A shopping cart
+@MainActor
+final class Cart: BaseStore, Priced, ObservableObject {
+  enum Step: String, CaseIterable {
+    case browsing, paying = "pay"
+    case done
+  }
+
+  struct Line {
+    let sku: String
+  }
+
+  @Published private(set) var lines: [Line] = []
+  @Clamped var quantity: Int = 1
+
+  var price: Int {
+    lines.count * 100
+  }
+
+  init(lines: [Line]) {
+    self.lines = lines
+  }
+
+  func add(_ line: Line, at index: Int = 0) {
+    lines.insert(line, at: index)
+    refresh()
+  }
+
+  func checkout() -> Int {
+    discounted(by: 10)
+  }
+}
+
+class BaseStore {
+  func refresh() {}
+}
+
+extension Cart.Line: Codable {
+  static func empty() -> Cart.Line {
+    Cart.Line(sku: "")
+  }
+}
+`;
+
+  it("extracts types, members and nested types by qualified name", async () => {
+    const res = await parser.parse("Cart.swift", code, "hash");
+    expect(res.language).toBe("swift");
+
+    const byId = new Map(res.entities.map((e) => [e.id, e]));
+    const cart = byId.get("Cart.swift:class:Cart");
+
+    expect(cart?.type).toBe("class");
+    expect(cart?.documentation).toBe("/// A shopping cart");
+    expect(cart?.modifiers).toContain("final");
+    expect(cart?.metadata?.attributes).toEqual([{ name: "MainActor" }]);
+    expect(byId.get("Cart.swift:protocol:Priced")?.type).toBe("interface");
+    expect(byId.get("Cart.swift:enum:Cart.Step")?.metadata).toMatchObject({
+      qualifiedName: "Cart.Step",
+      parentClass: "Cart",
+      rawType: "String",
+      conformances: ["CaseIterable"],
+    });
+    expect(byId.get("Cart.swift:enum:Cart.Step:case:paying")?.metadata?.rawValue).toBe('"pay"');
+    expect(byId.get("Cart.swift:struct:Cart.Line")?.metadata?.qualifiedName).toBe("Cart.Line");
+
+    const add = byId.get("Cart.swift:class:Cart:method:add(_:at:)");
+    expect(add?.metadata?.qualifiedName).toBe("Cart.add");
+    expect(add?.parameters).toEqual([
+      { name: "line", type: "Line" },
+      { name: "index", type: "Int", optional: true, defaultValue: "0" },
+    ]);
+    expect(byId.get("Cart.swift:class:Cart:method:init(lines:)")?.metadata?.isConstructor).toBe(true);
+    expect(byId.get("Cart.swift:class:Cart:method:checkout()")?.returnType).toBe("Int");
+
+    const imports = res.entities.filter((e) => e.type === "import");
+    expect(imports.map((e) => [e.name, e.metadata?.isTestable ?? false])).toEqual([
+      ["Foundation", false],
+      ["ShopCore", true],
+    ]);
+  });
+
+  it("keeps property wrappers and attributes as metadata", async () => {
+    const res = await parser.parse("Cart.swift", code, "hash");
+    const relationships = (res as any).relationships as EntityRelationship[];
+    const byId = new Map(res.entities.map((e) => [e.id, e]));
+
+    expect(byId.get("Cart.swift:class:Cart:property:lines")?.metadata).toMatchObject({
+      propertyWrappers: ["Published"],
+      propertyType: "[Line]",
+      mutability: "var",
+    });
+    expect(byId.get("Cart.swift:class:Cart:property:price")?.metadata?.isComputed).toBe(true);
+    // Only wrappers declared in the file get an edge
+    expect(relationships.filter((r) => r.type === "decorated_by").map((r) => [r.from, r.to])).toEqual([
+      ["Cart.swift:class:Cart:property:quantity", "Cart.swift:struct:Clamped"],
+    ]);
+  });
+
+  it("links superclasses, conformances, extensions and calls", async () => {
+    const res = await parser.parse("Cart.swift", code, "hash");
+    const relationships = (res as any).relationships as EntityRelationship[];
+    const edge = (type: string, from: string) =>
+      relationships.filter((r) => r.type === type && r.from === from).map((r) => r.to);
+    const cart = "Cart.swift:class:Cart";
+    const lineExtension = "Cart.swift:extension:Cart.Line";
+
+    expect(edge("extends", cart)).toEqual(["Cart.swift:class:BaseStore"]);
+    expect(edge("implements", cart)).toEqual(["Cart.swift:protocol:Priced", "ObservableObject"]);
+    expect(edge("extension_of", "Cart.swift:extension:Priced")).toEqual(["Cart.swift:protocol:Priced"]);
+    expect(edge("extension_of", lineExtension)).toEqual(["Cart.swift:struct:Cart.Line"]);
+    expect(edge("implements", lineExtension)).toEqual(["Codable"]);
+
+    const factory = res.entities.find((e) => e.id === `${lineExtension}:method:empty()`);
+    expect(factory?.metadata).toMatchObject({ implType: "Line", extensionOf: "Cart.Line", isStatic: true });
+
+    expect(edge("calls", `${cart}:method:add(_:at:)`)).toEqual([
+      "insert",
+      "Cart.swift:class:BaseStore:method:refresh()",
+    ]);
+    // Members of conformed protocols are found too
+    expect(edge("calls", `${cart}:method:checkout()`)).toEqual(["Cart.swift:protocol:Priced:method:discounted(by:)"]);
+  });
+
+  it("indexes a SwiftPM package", async () => {
+    const root = mkdtempSync(join(tmpdir(), "swiftpm-"));
+    const files: Record<string, string> = {
+      "Package.swift": `// swift-tools-version:5.9
+import PackageDescription
+
+let package = Package(name: "Shop", targets: [.executableTarget(name: "Shop")])
+`,
+      "Sources/Shop/Item.swift": `struct Item: Identifiable {
+  let id: String
+  var price: Int
+}
+`,
+      "Sources/Shop/Item+Formatting.swift": `extension Item {
+  var label: String { "\\(id): \\(price)" }
+}
+`,
+      "Sources/Shop/main.swift": `func main() {
+  print(Item(id: "a", price: 1).label)
+}
+`,
+    };
+    const ignored = [".build/debug/Shop.swift", "DerivedData/Shop/Generated.swift"];
+    try {
+      for (const [path, content] of Object.entries(files)) {
+        mkdirSync(dirname(join(root, path)), { recursive: true });
+        writeFileSync(join(root, path), content);
+      }
+      for (const path of ignored) {
+        mkdirSync(dirname(join(root, path)), { recursive: true });
+        writeFileSync(join(root, path), "struct Generated {}\n");
+      }
+
+      const { files: found } = collectIndexableFiles(root, [...DEFAULT_INDEX_EXCLUDE_PATTERNS]);
+      expect(found.map((f) => f.slice(root.length + 1)).sort()).toEqual(Object.keys(files).sort());
+
+      const byFile = new Map<string, string[]>();
+      for (const file of found) {
+        const res = await parser.parse(file, readFileSync(file, "utf8"), "hash");
+        expect(res.language).toBe("swift");
+        byFile.set(file.slice(root.length + 1), res.entities.map((e) => e.name));
+      }
+      expect(byFile.get("Sources/Shop/Item.swift")).toEqual(expect.arrayContaining(["Item", "id", "price"]));
+      expect(byFile.get("Sources/Shop/Item+Formatting.swift")).toEqual(expect.arrayContaining(["Item", "label"]));
+      expect(byFile.get("Sources/Shop/main.swift")).toContain("main");
+      expect(byFile.get("Package.swift")).toContain("package");
+    } finally {
+      rmSync(root, { recursive: true, force: true });
+    }
+  });
+});
@@ -0,0 +1,58 @@
+import { TreeSitterParser } from "../../src/parsers/tree-sitter-parser";
+
+describe("test case extraction", () => {
+  let parser: TreeSitterParser;
+
+  beforeAll(async () => {
+    parser = new TreeSitterParser();
+    await parser.initialize();
+  });
+
+  afterEach(() => {
+    parser.clearCache();
+  });
+
+  it("reads Jest cases under their describe blocks with the calls they make", async () => {
+    const code = `import { createUser, validate } from "../src/users";
+
+describe("users", () => {
+  describe("createUser", () => {
+    it("stores the user", async () => {
+      const user = await createUser({ name: "a" });
+      expect(user.id).toBeDefined();
+    });
+
+    it.skip(\`rejects \${"empty"} names\`, () => {
+      expect(() => validate("")).toThrow();
+    });
+  });
+});
+
+test("renders the badge", () => {
+  render(<Badge user={makeUser()} />);
+});
+`;
+    const res = await parser.parse("users.test.tsx", code, "hash");
+    const tests = res.entities.filter((e) => e.type === "test");
+
+    expect(tests.map((t) => [t.name, t.metadata?.suite, t.location.start.line])).toEqual([
+      ["stores the user", ["users", "createUser"], 5],
+      ['rejects ${"empty"} names', ["users", "createUser"], 10],
+      ["renders the badge", [], 16],
+    ]);
+    // Assertions belong to the runner; the code under test is what remains
+    expect(tests[0]?.calls?.map((c) => c.name)).toEqual(["createUser"]);
+    expect(tests[0]?.metadata).toMatchObject({ framework: "jest", fullName: "users createUser stores the user" });
+    expect(tests[1]?.metadata?.modifier).toBe("skip");
+    expect(tests[2]?.calls?.map((c) => c.name)).toEqual(["render", "makeUser"]);
+    expect(tests[2]?.renders?.map((c) => c.name)).toEqual(["Badge"]);
+  });
+
+  it("leaves it() calls outside test files alone", async () => {
+    const code = `it("is not a test here", () => run());
+`;
+    const res = await parser.parse("src/runner.ts", code, "hash");
+
+    expect(res.entities.filter((e) => e.type === "test")).toEqual([]);
+  });
+});
@@ -1,3 +1,4 @@
+import { diagnoseGrammars } from "../../src/parsers/grammar-loader";
 import { TreeSitterParser } from "../../src/parsers/tree-sitter-parser";
 import type { SupportedLanguage } from "../../src/types/parser";
 
@@ -26,6 +27,10 @@ describe("Language loaders resolve grammars correctly", () => {
     { file: "a.go", code: "package main\nfunc main(){}", expected: "go" },
     { file: "a.java", code: "class A { public static void main(String[] a){} }", expected: "java" },
     { file: "a.kt", code: "package p\nclass A { fun f(x:Int):Int { return x } }", expected: "kotlin" },
+    { file: "a.rb", code: "class A\n  def f(x)\n    x\n  end\nend", expected: "ruby" },
+    { file: "a.php", code: "<?php\nclass A { function f($x) { return $x; } }", expected: "php" },
+    { file: "a.swift", code: "struct A {\n  func f(x: Int) -> Int { return x }\n}", expected: "swift" },
+    { file: "a.scala", code: "object A {\n  def f(x: Int): Int = x\n}", expected: "scala" },
   ];
 
   it.each(samples)("loads grammar for %s and parses", async ({ file, code, expected }) => {
@@ -36,9 +41,278 @@ describe("Language loaders resolve grammars correctly", () => {
       expected === "jsx" ||
       expected === "typescript" ||
       expected === "tsx" ||
-      expected === "kotlin"
+      expected === "kotlin" ||
+      expected === "ruby" ||
+      expected === "php" ||
+      expected === "swift" ||
+      expected === "scala"
     ) {
       expect(res.entities.length).toBeGreaterThan(0);
     }
   });
 });
+
+describe("TypeScript decorators and generics", () => {
+  let parser: TreeSitterParser;
+
+  beforeAll(async () => {
+    parser = new TreeSitterParser();
+    await parser.initialize();
+  });
+
+  const code = `import { Component, Injectable, Input } from "@angular/core";
+
+@Injectable({ providedIn: "root" })
+export class Store<T extends Entity = Entity> {
+  @Input() name: string;
+
+  @Memo
+  @Log("debug")
+  select<K extends keyof T>(key: K): T[K] {
+    return this.state[key];
+  }
+}
+`;
+
+  it("records class, method and field decorators with their arguments", async () => {
+    const res = await parser.parse("store.ts", code, "ts-decorators");
+
+    const store = res.entities.find((e) => e.name === "Store");
+    expect(store?.decorators).toEqual([
+      { name: "Injectable", arguments: ['{ providedIn: "root" }'], raw: 'Injectable({ providedIn: "root" })', line: 3 },
+    ]);
+
+    const select = res.entities.find((e) => e.name === "select");
+    expect(select?.decorators?.map((d) => d.name)).toEqual(["Memo", "Log"]);
+    expect(select?.decorators?.[1]?.arguments).toEqual(['"debug"']);
+
+    const field = res.entities.find((e) => e.name === "name" && e.type === "property");
+    expect(field?.decorators?.[0]).toMatchObject({ name: "Input", arguments: [] });
+  });
+
+  it("captures generic type parameters with constraints and defaults", async () => {
+    const res = await parser.parse("store.ts", code, "ts-generics");
+
+    expect(res.entities.find((e) => e.name === "Store")?.metadata?.typeParameters).toEqual([
+      { name: "T", constraint: "Entity", default: "Entity" },
+    ]);
+    expect(res.entities.find((e) => e.name === "select")?.metadata?.typeParameters).toEqual([
+      { name: "K", constraint: "keyof T", default: undefined },
+    ]);
+  });
+
+  it("records extends and implements clauses without type arguments", async () => {
+    const res = await parser.parse(
+      "repo.ts",
+      `interface Readable<T> extends Source<T>, Closeable {}
+class UserRepo extends BaseRepo<User> implements Readable<User>, Disposable {}
+`,
+      "ts-heritage",
+    );
+
+    expect(res.entities.find((e) => e.name === "Readable")?.inheritance).toEqual({
+      baseClasses: ["Source", "Closeable"],
+    });
+    expect(res.entities.find((e) => e.name === "UserRepo")?.inheritance).toEqual({
+      baseClasses: ["BaseRepo"],
+      interfaces: ["Readable", "Disposable"],
+    });
+  });
+});
+
+describe("React components", () => {
+  let parser: TreeSitterParser;
+
+  beforeAll(async () => {
+    parser = new TreeSitterParser();
+    await parser.initialize();
+  });
+
+  const code = `import { Component, memo, useState } from "react";
+import { Canvas } from "./Canvas";
+
+interface BoardProps {
+  size: number;
+}
+
+export function useZoom(initial: number) {
+  const [zoom, setZoom] = useState(initial);
+  return { zoom, setZoom };
+}
+
+export const Board = ({ size }: BoardProps) => {
+  const { zoom } = useZoom(1);
+  return (
+    <>
+      <Toolbar />
+      {[1, 2].map((i) => <Canvas key={i} zoom={zoom} />)}
+      <div className="status">{size}</div>
+    </>
+  );
+};
+
+export const Toolbar: React.FC<ToolbarProps> = memo(() => <UI.Button label="Save" />);
+
+export class Legend extends Component<LegendProps> {
+  render() {
+    return <Canvas zoom={1} />;
+  }
+}
+`;
+
+  it("parses .tsx with the TSX grammar and marks function and class components", async () => {
+    const res = await parser.parse("Board.tsx", code, "react-components");
+    expect(res.language).toBe("tsx");
+
+    const board = res.entities.find((e) => e.name === "Board" && e.type === "function");
+    expect(board?.metadata).toMatchObject({ componentKind: "function", propsType: "BoardProps" });
+    expect(res.entities.find((e) => e.name === "Toolbar" && e.type === "function")?.metadata).toMatchObject({
+      componentKind: "function",
+      propsType: "ToolbarProps",
+    });
+    expect(res.entities.find((e) => e.name === "Legend")?.metadata).toMatchObject({
+      componentKind: "class",
+      propsType: "LegendProps",
+    });
+    expect(res.entities.find((e) => e.name === "useZoom" && e.type === "function")?.metadata?.isHook).toBe(true);
+  });
+
+  it("records rendered components and hooks, skipping host elements", async () => {
+    const res = await parser.parse("Board.tsx", code, "react-renders");
+    const rendered = (name: string) =>
+      res.entities
+        .find((e) => e.name === name && (e.type === "function" || e.type === "class"))
+        ?.renders?.map((r) => (r.qualifier ? `${r.qualifier}.${r.name}` : r.name));
+
+    // Elements inside `map` callbacks count for the component around them
+    expect(rendered("Board")).toEqual(["Toolbar", "Canvas"]);
+    expect(rendered("Toolbar")).toEqual(["UI.Button"]);
+    expect(rendered("Legend")).toEqual(["Canvas"]);
+    expect(res.entities.find((e) => e.name === "Board" && e.type === "function")?.hooks?.map((h) => h.name)).toEqual([
+      "useZoom",
+    ]);
+    expect(res.entities.find((e) => e.name === "useZoom" && e.type === "function")?.hooks?.map((h) => h.name)).toEqual(
+      ["useState"],
+    );
+  });
+});
+
+describe("Field and variable accesses", () => {
+  let parser: TreeSitterParser;
+
+  beforeAll(async () => {
+    parser = new TreeSitterParser();
+    await parser.initialize();
+  });
+
+  it("records this-fields and free names a method reads and writes, leaving out locals and calls", async () => {
+    const code = `let count = 0;
+const LIMIT = 10;
+
+export class Cart {
+  items: string[] = [];
+
+  add(item: string) {
+    const { size } = this.options;
+    this.items.push(item);
+    count += 1;
+    this.total = size;
+    return this.items.length < LIMIT;
+  }
+}
+`;
+    const res = await parser.parse("cart.ts", code, "ts-accesses");
+    const add = res.entities.find((e) => e.name === "add");
+
+    expect(add?.accesses?.map((site) => [site.name, site.qualifier ?? null, site.access])).toEqual([
+      ["options", "this", "read"],
+      ["items", "this", "read"],
+      ["count", null, "readwrite"],
+      ["total", "this", "write"],
+      ["items", "this", "read"],
+      ["LIMIT", null, "read"],
+    ]);
+  });
+
+  it("records call arguments, keeping literal values and marking spreads", async () => {
+    const code = `export function sync(ids: string[], retries: number) {
+  fetchAll(ids, null, -1);
+  api.post(\`/sync\`, { ids }, ...rest);
+  log(\`synced \${ids.length}\`);
+}
+`;
+    const res = await parser.parse("sync.ts", code, "ts-call-arguments");
+    const calls = res.entities.find((e) => e.name === "sync")?.calls ?? [];
+
+    expect(calls.map((call) => [call.name, call.arity])).toEqual([
+      ["fetchAll", 3],
+      ["post", undefined],
+      ["log", 1],
+    ]);
+    expect(calls[0]?.arguments).toEqual([
+      { kind: "expression" },
+      { kind: "literal", value: "null" },
+      { kind: "literal", value: "-1" },
+    ]);
+    expect(calls[1]?.arguments).toEqual([
+      { kind: "literal", value: "`/sync`" },
+      { kind: "expression" },
+      { kind: "spread" },
+    ]);
+    expect(calls[2]?.arguments).toEqual([{ kind: "expression" }]);
+  });
+});
+
+describe("Syntax error recovery", () => {
+  let parser: TreeSitterParser;
+
+  beforeAll(async () => {
+    parser = new TreeSitterParser();
+    await parser.initialize();
+  });
+
+  it("extracts the valid declarations around a broken one and reports what was skipped", async () => {
+    const code = `export function first(a: number) {
+  return a + 1;
+}
+
+function broken( {
+  return ;;
+}}
+
+export function last() {
+  return 2;
+}
+`;
+    const res = await parser.parse("broken.ts", code, "ts-broken");
+
+    const names = res.entities.map((e) => e.name);
+    expect(names).toEqual(expect.arrayContaining(["first", "last"]));
+    expect(res.entities.find((e) => e.name === "last")?.location.start.line).toBe(9);
+
+    expect(res.syntaxErrors?.length).toBeGreaterThan(0);
+    for (const range of res.syntaxErrors ?? []) {
+      expect(range.endByte).toBeGreaterThan(range.startByte);
+      expect(range.endByte).toBeLessThanOrEqual(Buffer.byteLength(code));
+      expect(range.startLine).toBeGreaterThanOrEqual(4);
+    }
+  });
+
+  it("leaves syntaxErrors unset for a clean file", async () => {
+    const res = await parser.parse("clean.ts", "export const x = 1;\n", "ts-clean");
+    expect(res.syntaxErrors).toBeUndefined();
+  });
+});
+
+describe("Grammar diagnostics", () => {
+  it("reports every supported grammar as loaded with the path it was found at", () => {
+    const grammars = diagnoseGrammars();
+
+    expect(grammars.map((g) => g.language)).toEqual(expect.arrayContaining(["typescript", "go", "python"]));
+    for (const g of grammars) {
+      expect(g.error).toBeUndefined();
+      expect(g.loaded).toBe(true);
+      expect(g.path).toContain(g.module);
+    }
+  });
+});
@@ -0,0 +1,113 @@
+import { TreeSitterParser } from "../../src/parsers/tree-sitter-parser";
+import { classifyVisibility, withVisibility } from "../../src/parsers/visibility";
+import type { ParsedEntity } from "../../src/types/parser";
+
+function declared(name: string, type: string, modifiers: string[] = [], metadata = {}) {
+  return { name, type, modifiers, metadata } as ParsedEntity;
+}
+
+describe("entity visibility", () => {
+  it("classifies declarations by each language's rules", () => {
+    expect(classifyVisibility(declared("Open", "function"), "go")).toBe("public");
+    expect(classifyVisibility(declared("open", "function"), "go")).toBe("internal");
+
+    expect(classifyVisibility(declared("load", "function", ["export"]), "typescript")).toBe("public");
+    expect(classifyVisibility(declared("helper", "function"), "typescript")).toBe("private");
+    expect(classifyVisibility(declared("render", "method"), "tsx")).toBe("public");
+    expect(classifyVisibility(declared("cache", "property", ["protected"]), "typescript")).toBe("protected");
+    expect(classifyVisibility(declared("#count", "field"), "javascript")).toBe("private");
+
+    expect(classifyVisibility(declared("Repo", "class"), "java")).toBe("internal");
+    expect(classifyVisibility(declared("save", "method", ["public"]), "java")).toBe("public");
+    expect(classifyVisibility(declared("Repo", "class"), "csharp")).toBe("internal");
+    expect(classifyVisibility(declared("Save", "method"), "csharp")).toBe("private");
+    expect(classifyVisibility(declared("Save", "method", ["protected", "internal"]), "csharp")).toBe("protected");
+    expect(classifyVisibility(declared("Cache", "class", ["internal"]), "kotlin")).toBe("internal");
+    expect(classifyVisibility(declared("Store", "struct"), "swift")).toBe("internal");
+    expect(classifyVisibility(declared("Store", "struct", ["fileprivate"]), "swift")).toBe("private");
+
+    expect(classifyVisibility(declared("_parse", "function"), "python")).toBe("private");
+    expect(classifyVisibility(declared("__init__", "method"), "python")).toBe("public");
+    expect(classifyVisibility(declared("helper", "function", ["static"]), "c")).toBe("private");
+
+    expect(classifyVisibility(declared("os", "import"), "go")).toBeUndefined();
+    expect(classifyVisibility(declared("query", "function"), "sql")).toBeUndefined();
+  });
+
+  it("normalizes Rust's pub forms and keeps the written one", () => {
+    const [crate, pub, own] = withVisibility(
+      [
+        declared("Pool", "struct", [], { visibility: "pub(crate)" }),
+        declared("connect", "function", [], { visibility: "pub" }),
+        declared("retry", "function"),
+      ],
+      "rust",
+    );
+    expect(crate?.metadata).toMatchObject({ visibility: "internal", visibilityModifier: "pub(crate)" });
+    expect(pub?.metadata).toMatchObject({ visibility: "public", visibilityModifier: "pub" });
+    expect(own?.metadata).toEqual({ visibility: "private" });
+  });
+
+  describe("at parse time", () => {
+    let parser: TreeSitterParser;
+
+    beforeAll(async () => {
+      parser = new TreeSitterParser();
+      await parser.initialize();
+    });
+
+    afterEach(() => {
+      parser.clearCache();
+    });
+
+    it("tags TypeScript exports, access modifiers and module-local declarations", async () => {
+      const code = `export interface Options {
+  retries: number;
+}
+
+export type Mode = "fast" | "safe";
+
+export class Client {
+  private secret(): string {
+    return "";
+  }
+
+  protected reset(): void {}
+
+  send(): void {}
+}
+
+function helper(): void {}
+`;
+      const result = await parser.parse("/tmp/client.ts", code, "visibility-ts");
+      const visibilities = (name: string) => [
+        ...new Set(result.entities.filter((e) => e.name === name).map((e) => e.metadata?.visibility)),
+      ];
+
+      expect(visibilities("Options")).toContain("public");
+      expect(visibilities("Mode")).toContain("public");
+      expect(visibilities("Client")).toContain("public");
+      expect(visibilities("secret")).toEqual(["private"]);
+      expect(visibilities("reset")).toEqual(["protected"]);
+      expect(visibilities("send")).toEqual(["public"]);
+      expect(visibilities("helper")).toEqual(["private"]);
+    });
+
+    it("tags Go declarations by capitalization", async () => {
+      const code = `package store
+
+type Store struct{}
+
+func (s *Store) Get() {}
+
+func open() {}
+`;
+      const result = await parser.parse("/tmp/store/store.go", code, "visibility-go");
+      const visibility = (name: string) => result.entities.find((e) => e.name === name)?.metadata?.visibility;
+
+      expect(visibility("Store")).toBe("public");
+      expect(visibility("Get")).toBe("public");
+      expect(visibility("open")).toBe("internal");
+    });
+  });
+});
@@ -0,0 +1,117 @@
+import { describe, expect, it } from "@jest/globals";
+import { AdaptiveBatchSizer, batchLimitsFor, isBatchTooLarge, splitBatch } from "../../src/semantic/adaptive-batching.js";
+import { EmbeddingGenerator } from "../../src/semantic/embedding-generator.js";
+import type { TextEmbedder } from "../../src/semantic/providers/base.js";
+import { HttpError } from "../../src/semantic/providers/http-engine.js";
+
+const LIMITS = { max: 64, targetLatencyMs: 1000 };
+
+describe("AdaptiveBatchSizer", () => {
+  it("doubles while calls are fast and throughput improves, then steps back once it stops", () => {
+    const sizer = new AdaptiveBatchSizer({ initial: 8 }, LIMITS);
+    expect(sizer.record(8, 100)).toMatchObject({ next: 16, reason: "grow" });
+    expect(sizer.record(16, 120)).toMatchObject({ next: 32, reason: "grow" });
+    // Twice the texts in twice the time: no gain over 16
+    expect(sizer.record(32, 240)).toMatchObject({ next: 16, reason: "saturated" });
+    expect(sizer.record(16, 120)).toMatchObject({ next: 16, reason: "hold" });
+  });
+
+  it("halves on a call over the target latency and ignores small fast calls", () => {
+    const sizer = new AdaptiveBatchSizer({ initial: 32 }, LIMITS);
+    expect(sizer.record(32, 1500)).toMatchObject({ next: 16, reason: "slow" });
+    expect(sizer.record(3, 10)).toMatchObject({ next: 16, reason: "partial" });
+  });
+
+  it("stays within min, max and the provider's own limit", () => {
+    const sizer = new AdaptiveBatchSizer({ initial: 4, min: 4, max: 48 }, LIMITS, 40);
+    expect(sizer.bounds()).toEqual({ min: 4, max: 40 });
+    sizer.record(4, 1500);
+    expect(sizer.current()).toBe(4);
+    sizer.reset(500);
+    expect(sizer.current()).toBe(40);
+  });
+
+  it("never moves a fixed size", () => {
+    const sizer = new AdaptiveBatchSizer({ size: 20, initial: 8 }, LIMITS);
+    expect(sizer.adaptive).toBe(false);
+    expect(sizer.record(20, 5000)).toMatchObject({ next: 20, reason: "fixed" });
+  });
+
+  it("caps the size below a batch the provider rejected", () => {
+    const sizer = new AdaptiveBatchSizer({ initial: 32 }, LIMITS);
+    sizer.rejected(32);
+    expect(sizer.current()).toBe(16);
+    expect(sizer.bounds().max).toBe(31);
+    expect(sizer.fits(31)).toBe(true);
+    expect(sizer.fits(32)).toBe(false);
+  });
+});
+
+describe("batching helpers", () => {
+  it("splits by count and by estimated tokens, keeping order", () => {
+    expect(splitBatch(["a", "b", "c"], 2)).toEqual([["a", "b"], ["c"]]);
+    const long = "x".repeat(30); // 10 tokens
+    expect(splitBatch([long, long, "y", long], 10, 20)).toEqual([[long, long], ["y", long]]);
+    // A single text over the budget still goes out on its own
+    expect(splitBatch([long], 10, 5)).toEqual([[long]]);
+  });
+
+  it("recognizes size rejections", () => {
+    expect(isBatchTooLarge(new HttpError(413, "Payload Too Large"))).toBe(true);
+    const overContext = new HttpError(400, "Bad Request", '{"error":"maximum context length exceeded"}');
+    expect(isBatchTooLarge(overContext)).toBe(true);
+    expect(isBatchTooLarge(new HttpError(400, "Bad Request", '{"error":"unknown model"}'))).toBe(false);
+    expect(isBatchTooLarge(new Error("connection reset"))).toBe(false);
+  });
+
+  it("falls back to the custom bounds for unknown providers", () => {
+    expect(batchLimitsFor("something-else")).toEqual(batchLimitsFor("custom"));
+  });
+});
+
+describe("EmbeddingGenerator batching", () => {
+  function limitedEmbedder(limit: number, calls: number[]): TextEmbedder {
+    return {
+      dimension: 4,
+      async embed(texts: string[]) {
+        calls.push(texts.length);
+        if (texts.length > limit) throw new Error(`too many inputs: ${texts.length} > ${limit}`);
+        return texts.map((t) => [t.length, 1, 2, 3]);
+      },
+    };
+  }
+
+  it("splits a batch the provider rejects as too large and keeps every text", async () => {
+    const calls: number[] = [];
+    const generator = new EmbeddingGenerator({
+      provider: "custom",
+      batchSize: 8,
+      custom: { embedder: limitedEmbedder(3, calls) },
+    });
+    await generator.initialize();
+
+    const texts = Array.from({ length: 8 }, (_, i) => `text number ${i}`);
+    const { embeddings, sources } = await generator.generateBatchWithSources(texts);
+    expect(embeddings.map((e) => e[0])).toEqual(texts.map((t) => t.length));
+    expect(sources.every((s) => !s.fallback)).toBe(true);
+    expect(calls.filter((n) => n > 3)).toEqual([8, 4]);
+    expect(generator.getBatching()?.max).toBe(3);
+    await generator.cleanup();
+  });
+
+  it("sends exactly the configured size when sizing is fixed", async () => {
+    const calls: number[] = [];
+    const generator = new EmbeddingGenerator({
+      provider: "custom",
+      batchSize: 5,
+      batching: { size: 5 },
+      custom: { embedder: limitedEmbedder(100, calls) },
+    });
+    await generator.initialize();
+
+    await generator.generateBatch(Array.from({ length: 12 }, (_, i) => `fixed ${i}`));
+    expect(calls).toEqual([5, 5, 2]);
+    expect(generator.getBatching()).toMatchObject({ adaptive: false, batchSize: 5 });
+    await generator.cleanup();
+  });
+});
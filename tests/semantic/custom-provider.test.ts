@@ -0,0 +1,69 @@
+import { describe, expect, it } from "@jest/globals";
+import { EmbeddingGenerator } from "../../src/semantic/embedding-generator.js";
+import type { TextEmbedder } from "../../src/semantic/providers/base.js";
+import { CustomProvider } from "../../src/semantic/providers/custom-provider.js";
+import { createProvider } from "../../src/semantic/providers/factory.js";
+
+function fixedEmbedder(dimension: number, calls: string[][] = []): TextEmbedder {
+  return {
+    dimension,
+    name: "fixed",
+    model: "fixed-test",
+    async embed(texts: string[]) {
+      calls.push(texts);
+      return texts.map((t) => Array.from({ length: dimension }, (_, i) => (t.length + i) / 100));
+    },
+  };
+}
+
+describe("CustomProvider", () => {
+  it("adapts a TextEmbedder to the provider interface", async () => {
+    const provider = new CustomProvider({ embedder: fixedEmbedder(4) });
+    await provider.initialize();
+
+    expect(provider.getDimension()).toBe(4);
+    expect(provider.info).toMatchObject({ name: "fixed", model: "fixed-test" });
+
+    const [a, b] = await provider.embedBatch(["ab", "abc"]);
+    expect(a).toBeInstanceOf(Float32Array);
+    expect(Array.from(a ?? [])).toEqual(Array.from(Float32Array.from([0.02, 0.03, 0.04, 0.05])));
+    expect(b?.length).toBe(4);
+  });
+
+  it("rejects vectors that do not match the reported dimension", async () => {
+    const embedder: TextEmbedder = {
+      dimension: 3,
+      async embed(texts: string[]) {
+        return texts.map(() => [1, 2]);
+      },
+    };
+    const provider = new CustomProvider({ embedder });
+    await provider.initialize();
+
+    await expect(provider.embed("x")).rejects.toThrow("expected 3");
+  });
+
+  it("requires an embedder or a module when created from config", () => {
+    expect(() => createProvider({ provider: "custom", modelName: "n/a" })).toThrow("custom.module");
+  });
+
+  it("routes EmbeddingGenerator through an injected embedder", async () => {
+    const calls: string[][] = [];
+    const generator = new EmbeddingGenerator({
+      provider: "custom",
+      batchSize: 8,
+      custom: { embedder: fixedEmbedder(6, calls) },
+    });
+    await generator.initialize();
+
+    expect(generator.getDimension()).toBe(6);
+    const batch = await generator.generateBatch(["first", "second"]);
+    expect(batch.map((v) => v.length)).toEqual([6, 6]);
+
+    // Second request for the same text is served from cache
+    await generator.generateEmbedding("first");
+    expect(calls).toEqual([["first", "second"]]);
+
+    await generator.cleanup();
+  });
+});
@@ -0,0 +1,80 @@
+import { existsSync, rmSync } from "node:fs";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { EmbeddingGenerator } from "../../src/semantic/embedding-generator.js";
+import type { TextEmbedder } from "../../src/semantic/providers/base.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import type { GraphStorageImpl } from "../../src/storage/graph-storage.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+
+const TEST_DB_PATH = "./data/test-embedding-cache.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function countingEmbedder(model: string, calls: string[][]): TextEmbedder {
+  return {
+    dimension: 3,
+    name: "counting",
+    model,
+    async embed(texts: string[]) {
+      calls.push(texts);
+      return texts.map((t) => [t.length, 1, 2]);
+    },
+  };
+}
+
+async function generatorFor(storage: GraphStorageImpl, embedder: TextEmbedder): Promise<EmbeddingGenerator> {
+  const generator = new EmbeddingGenerator({ provider: "custom", batchSize: 8, custom: { embedder } });
+  await generator.initialize();
+  generator.setPersistentCache(storage);
+  return generator;
+}
+
+describe("persistent embedding cache", () => {
+  let storage: GraphStorageImpl;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+  });
+
+  afterEach(() => {
+    resetGraphStorage();
+    resetSQLiteManager();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+  });
+
+  it("reuses stored vectors across generator instances and only embeds misses", async () => {
+    const calls: string[][] = [];
+    const first = await generatorFor(storage, countingEmbedder("v1", calls));
+    await first.generateBatch(["alpha", "beta"]);
+    await first.cleanup();
+
+    const second = await generatorFor(storage, countingEmbedder("v1", calls));
+    const vectors = await second.generateBatch(["alpha", "beta", "gamma"]);
+
+    expect(calls).toEqual([["alpha", "beta"], ["gamma"]]);
+    expect(Array.from(vectors[0] ?? [])).toEqual([5, 1, 2]);
+    expect(second.getCacheStats()).toMatchObject({ persistentHits: 2, misses: 1 });
+    await second.cleanup();
+  });
+
+  it("does not share vectors between models and prunes entries from other models", async () => {
+    const calls: string[][] = [];
+    const v1 = await generatorFor(storage, countingEmbedder("v1", calls));
+    await v1.generateEmbedding("alpha");
+    await v1.cleanup();
+
+    const v2 = await generatorFor(storage, countingEmbedder("v2", calls));
+    await v2.generateEmbedding("alpha");
+
+    expect(calls).toEqual([["alpha"], ["alpha"]]);
+    expect(await storage.pruneEmbeddingCache(v2.getCacheKey())).toBe(1);
+    expect(await storage.pruneEmbeddingCache(v2.getCacheKey())).toBe(0);
+    await v2.cleanup();
+  });
+});
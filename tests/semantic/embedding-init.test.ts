@@ -0,0 +1,65 @@
+import { mkdtempSync, rmSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { join } from "node:path";
+import { afterAll, describe, expect, it } from "@jest/globals";
+import { EmbeddingGenerator } from "../../src/semantic/embedding-generator.js";
+
+// Embedder module whose factory fails `failures` times per key before returning an embedder
+const dir = mkdtempSync(join(tmpdir(), "embedding-init-"));
+const flakyModule = join(dir, "flaky-embedder.mjs");
+writeFileSync(
+  flakyModule,
+  `const calls = (globalThis.__flakyEmbedderCalls ??= {});
+export default function create({ key, failures }) {
+  calls[key] = (calls[key] ?? 0) + 1;
+  if (calls[key] <= failures) throw new Error("fetch failed: ECONNRESET");
+  return { dimension: 3, name: "flaky", model: key, embed: async (texts) => texts.map(() => [1, 0, 0]) };
+}
+`,
+);
+
+function flakyGenerator(key: string, failures: number, initRetries: number): EmbeddingGenerator {
+  return new EmbeddingGenerator({
+    provider: "custom",
+    custom: { module: flakyModule, options: { key, failures } },
+    initRetries,
+    initBackoffMs: 0,
+  });
+}
+
+describe("EmbeddingGenerator initialization retries", () => {
+  afterAll(() => rmSync(dir, { recursive: true, force: true }));
+
+  it("retries a transient failure and keeps the provider once it starts", async () => {
+    const generator = flakyGenerator("recovers", 2, 2);
+    await Promise.all([generator.initialize(), generator.initialize()]);
+
+    expect(generator.getProviderInfo()).toMatchObject({ provider: "flaky", fallback: false });
+    expect((globalThis as any).__flakyEmbedderCalls.recovers).toBe(3);
+    await generator.cleanup();
+  });
+
+  it("reports the stage and attempts once retries run out", async () => {
+    const generator = flakyGenerator("gives-up", 5, 1);
+    await generator.initialize();
+
+    expect(generator.getProviderInfo()).toMatchObject({
+      fallback: true,
+      initFailure: { provider: "custom", phase: "download", attempts: 2, retriable: true },
+    });
+    await generator.cleanup();
+  });
+
+  it("does not retry a provider that is not installed", async () => {
+    const generator = new EmbeddingGenerator({
+      provider: "custom",
+      custom: { module: join(dir, "missing.mjs") },
+      initRetries: 3,
+      initBackoffMs: 0,
+    });
+    await generator.initialize();
+
+    expect(generator.getProviderInfo().initFailure).toMatchObject({ phase: "load", attempts: 1, retriable: false });
+    await generator.cleanup();
+  });
+});
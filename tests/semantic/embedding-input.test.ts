@@ -0,0 +1,84 @@
+import { describe, expect, it } from "@jest/globals";
+import {
+  composeEmbeddingInput,
+  EMBEDDING_INPUT_PRESETS,
+  type EmbeddingInputParts,
+  entityNameParts,
+  resolveEmbeddingInput,
+  surroundingComments,
+} from "../../src/semantic/embedding-input.js";
+
+const parts: EmbeddingInputParts = {
+  header: "drawShape method (ctx: Canvas): void",
+  name: "drawShape",
+  qualifiedName: "CanvasView.drawShape",
+  path: "ui/CanvasView.ts",
+  signature: "(ctx: Canvas): void",
+  doc: "Paints the shape onto the canvas",
+  body: "drawShape(ctx) { ctx.fill(); }",
+  comments: "",
+};
+
+describe("resolveEmbeddingInput", () => {
+  it("falls back to the default preset and lets fields override any preset", () => {
+    expect(resolveEmbeddingInput()).toBe(EMBEDDING_INPUT_PRESETS.default);
+    expect(resolveEmbeddingInput({ preset: "bogus" })).toBe(EMBEDDING_INPUT_PRESETS.default);
+    expect(resolveEmbeddingInput({ preset: "name-doc" })).toBe(EMBEDDING_INPUT_PRESETS["name-doc"]);
+    expect(
+      resolveEmbeddingInput({
+        preset: "full-body",
+        fields: ["path", { field: "signature", weight: 7 }, { field: "body", weight: 0 }, "bogus"],
+      }),
+    ).toEqual([
+      { field: "path", weight: 1 },
+      { field: "signature", weight: 3 },
+    ]);
+  });
+});
+
+describe("composeEmbeddingInput", () => {
+  it("keeps the text embedded before compositions were configurable", () => {
+    expect(composeEmbeddingInput(resolveEmbeddingInput(), parts)).toBe(
+      [parts.header, parts.doc, parts.body].join("\n"),
+    );
+    expect(composeEmbeddingInput(resolveEmbeddingInput(), { ...parts, doc: "", body: "" })).toBe(parts.header);
+  });
+
+  it("orders fields as configured and repeats weighted ones", () => {
+    const text = composeEmbeddingInput(EMBEDDING_INPUT_PRESETS["signature-heavy"], parts);
+    expect(text.split("\n")).toEqual([
+      "CanvasView.drawShape",
+      "(ctx: Canvas): void",
+      "(ctx: Canvas): void",
+      "Paints the shape onto the canvas",
+      "drawShape(ctx) { ctx.fill(); }",
+    ]);
+  });
+});
+
+describe("entity parts", () => {
+  it("qualifies names by their owner and shortens the path to its directory", () => {
+    const start = { name: "Start", filePath: "/repo/server/http.go", metadata: { receiver: "Server" } };
+    expect(entityNameParts(start)).toEqual({ qualifiedName: "Server.Start", path: "server/http.go" });
+    expect(entityNameParts({ name: "run", filePath: "main.py", metadata: { qualifiedName: "app.run" } })).toEqual({
+      qualifiedName: "app.run",
+      path: "main.py",
+    });
+  });
+
+  it("collects comments above and inside a declaration but not its doc again", () => {
+    const source = [
+      "import x from 'x';",
+      "// Renders the toolbar",
+      "// above the canvas",
+      "function toolbar() {",
+      "  // buttons first",
+      "  --count;",
+      "  return 1;",
+      "}",
+    ].join("\n");
+    const code = source.split("\n").slice(3).join("\n");
+    expect(surroundingComments(source, 4, code)).toBe("Renders the toolbar\nabove the canvas\nbuttons first");
+    expect(surroundingComments(source, 4, code, "// Renders the toolbar\n// above the canvas")).toBe("buttons first");
+  });
+});
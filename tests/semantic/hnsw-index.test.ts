@@ -0,0 +1,113 @@
+import { existsSync, mkdtempSync, rmSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { join } from "node:path";
+import { afterAll, describe, expect, it } from "@jest/globals";
+import { HnswIndex } from "../../src/semantic/hnsw-index.js";
+import { VectorStore } from "../../src/semantic/vector-store.js";
+
+// Deterministic vectors scattered around a handful of centres, like embeddings of related code
+function clusteredVectors(count: number, dimensions: number, seed = 7): Float32Array[] {
+  let state = seed;
+  const random = () => {
+    state = (state * 16807) % 2147483647;
+    return state / 2147483647 - 0.5;
+  };
+  const centres = Array.from({ length: 8 }, () => Float32Array.from({ length: dimensions }, random));
+  return Array.from({ length: count }, (_, i) => Float32Array.from(centres[i % 8]!, (x) => x + random()));
+}
+
+function exactTop(query: Float32Array, vectors: Float32Array[], k: number): string[] {
+  const cosine = (a: Float32Array, b: Float32Array) => {
+    let dot = 0;
+    let na = 0;
+    let nb = 0;
+    for (let i = 0; i < a.length; i++) {
+      dot += a[i]! * b[i]!;
+      na += a[i]! * a[i]!;
+      nb += b[i]! * b[i]!;
+    }
+    return dot / Math.sqrt(na * nb);
+  };
+  return vectors
+    .map((v, i) => ({ id: `v${i}`, score: cosine(query, v) }))
+    .sort((a, b) => b.score - a.score)
+    .slice(0, k)
+    .map((r) => r.id);
+}
+
+describe("HnswIndex", () => {
+  const vectors = clusteredVectors(1500, 32);
+  const index = new HnswIndex(32, { m: 12, efConstruction: 80 });
+  vectors.forEach((v, i) => index.add(`v${i}`, v));
+
+  it("finds nearly all exact nearest neighbours", () => {
+    let found = 0;
+    for (let q = 0; q < 50; q++) {
+      const query = vectors[q * 29]!;
+      const approx = new Set(index.search(query, 10, 64).map((m) => m.id));
+      found += exactTop(query, vectors, 10).filter((id) => approx.has(id)).length;
+    }
+    expect(found / 500).toBeGreaterThan(0.95);
+    expect(index.search(vectors[3]!, 1)[0]).toMatchObject({ id: "v3" });
+    expect(index.search(vectors[3]!, 1)[0]!.cosine).toBeCloseTo(1, 5);
+  });
+
+  it("never returns removed or replaced vectors and survives a save and load", () => {
+    const copy = HnswIndex.deserialize(index.serialize({ generation: 3 }));
+    expect(copy.meta).toEqual({ generation: 3 });
+    expect(copy.index.size).toBe(1500);
+
+    copy.index.remove("v3");
+    copy.index.add("v4", vectors[3]!);
+    const hits = copy.index.search(vectors[3]!, 5).map((m) => m.id);
+    expect(hits).not.toContain("v3");
+    expect(hits[0]).toBe("v4");
+
+    // Removing most vectors compacts the graph; the rest stay searchable
+    for (let i = 0; i < 1400; i++) copy.index.remove(`v${i}`);
+    expect(copy.index.size).toBe(100);
+    expect(copy.index.search(vectors[1450]!, 1)[0]?.id).toBe("v1450");
+  });
+
+  it("rebuilds once removed vectors pass the compaction ratio", () => {
+    const small = new HnswIndex(32, { m: 12, compactRatio: 0.2 });
+    vectors.slice(0, 500).forEach((v, i) => small.add(`v${i}`, v));
+    for (let i = 0; i < 100; i++) small.remove(`v${i}`);
+    expect(small.deletedCount).toBe(100);
+    small.remove("v100");
+    expect(small.deletedCount).toBe(0);
+    expect(small.size).toBe(399);
+    expect(small.search(vectors[100]!, 5).map((m) => m.id)).not.toContain("v100");
+  });
+});
+
+describe("VectorStore ANN search", () => {
+  const dir = mkdtempSync(join(tmpdir(), "vector-ann-"));
+  const dbPath = join(dir, "vectors.db");
+  const vectors = clusteredVectors(300, 16, 11);
+  const open = () => new VectorStore({ dbPath, dimensions: 16, ann: { minVectors: 100, efSearch: 64 } });
+
+  afterAll(() => rmSync(dir, { recursive: true, force: true }));
+
+  it("builds the index once the store is large enough and reloads it from disk", async () => {
+    const store = open();
+    await store.initialize();
+    await store.insertBatch(vectors.map((vector, i) => ({ id: `v${i}`, content: `entity ${i}`, vector })));
+
+    // The first search starts the build in the background and is answered exactly
+    expect((await store.search(vectors[5]!, 3))[0]?.id).toBe("v5");
+    for (let i = 0; i < 100 && !existsSync(`${dbPath}.hnsw`); i++) await new Promise((r) => setTimeout(r, 10));
+    expect(existsSync(`${dbPath}.hnsw`)).toBe(true);
+    await store.close();
+
+    const reopened = open();
+    await reopened.initialize();
+    const hits = await reopened.search(vectors[42]!, 5);
+    expect(hits[0]).toMatchObject({ id: "v42", content: "entity 42" });
+    expect(hits[0]!.cosine).toBeCloseTo(1, 5);
+
+    await reopened.delete("v42");
+    expect((await reopened.search(vectors[42]!, 5)).map((h) => h.id)).not.toContain("v42");
+    await reopened.close();
+  });
+});
@@ -0,0 +1,73 @@
+import { describe, expect, it } from "@jest/globals";
+import { EmbeddingGenerator } from "../../src/semantic/embedding-generator.js";
+import type { TextEmbedder } from "../../src/semantic/providers/base.js";
+import { MEMORY_MODEL, MemoryProvider } from "../../src/semantic/providers/memory-provider.js";
+
+function cosine(a: Float32Array, b: Float32Array): number {
+  let dot = 0;
+  for (let i = 0; i < a.length; i++) dot += (a[i] ?? 0) * (b[i] ?? 0);
+  return dot;
+}
+
+describe("MemoryProvider", () => {
+  it("places texts with shared identifiers closer than unrelated ones", async () => {
+    const provider = new MemoryProvider({ dimension: 256 });
+    const [query, related, unrelated] = await provider.embedBatch([
+      "parse config file",
+      "function parseConfigFile(path) { return readConfig(path) }",
+      "class RenderButton extends Widget",
+    ]);
+
+    expect(cosine(query!, related!)).toBeGreaterThan(cosine(query!, unrelated!));
+    expect(Array.from(await provider.embed("parse config file"))).toEqual(Array.from(query!));
+    expect(cosine(query!, query!)).toBeCloseTo(1, 5);
+  });
+
+  it("returns a unit vector for text without words", async () => {
+    const vector = await new MemoryProvider({ dimension: 16 }).embed("{}();");
+    expect(cosine(vector, vector)).toBeCloseTo(1, 5);
+  });
+});
+
+describe("EmbeddingGenerator fallback", () => {
+  it("initializes on the hashing fallback when the configured provider cannot load", async () => {
+    const generator = new EmbeddingGenerator({ provider: "custom", custom: { module: "./does-not-exist.js" } });
+    await generator.initialize();
+
+    expect(generator.getProviderInfo()).toMatchObject({ provider: "memory", model: MEMORY_MODEL, fallback: true });
+    const { embeddings, sources } = await generator.generateBatchWithSources(["alpha", "beta"]);
+    expect(embeddings).toHaveLength(2);
+    expect(sources).toEqual([
+      { provider: "memory", model: MEMORY_MODEL, fallback: true },
+      { provider: "memory", model: MEMORY_MODEL, fallback: true },
+    ]);
+
+    await generator.cleanup();
+  });
+
+  it("marks vectors the provider failed on and retries them on the next request", async () => {
+    let failing = true;
+    const embedder: TextEmbedder = {
+      dimension: 4,
+      name: "flaky",
+      model: "flaky-test",
+      async embed(texts: string[]) {
+        if (failing) throw new Error("model offline");
+        return texts.map(() => [1, 0, 0, 0]);
+      },
+    };
+    const generator = new EmbeddingGenerator({ provider: "custom", custom: { embedder } });
+    await generator.initialize();
+
+    const first = await generator.generateBatchWithSources(["alpha"]);
+    expect(first.sources).toEqual([{ provider: "memory", model: MEMORY_MODEL, fallback: true }]);
+    expect(first.embeddings[0]).toHaveLength(4);
+
+    failing = false;
+    const second = await generator.generateBatchWithSources(["alpha"]);
+    expect(second.sources).toEqual([{ provider: "flaky", model: "flaky-test", fallback: false }]);
+    expect(Array.from(second.embeddings[0] ?? [])).toEqual([1, 0, 0, 0]);
+
+    await generator.cleanup();
+  });
+});
@@ -0,0 +1,81 @@
+import { afterEach, describe, expect, it, jest } from "@jest/globals";
+import { OpenAIProvider } from "../../src/semantic/providers/openai-provider.js";
+
+function embeddingsResponse(vectors: number[][], status = 200): Response {
+  const data = vectors.map((embedding, index) => ({ object: "embedding", index, embedding }));
+  return new Response(JSON.stringify({ object: "list", data }), { status });
+}
+
+describe("OpenAIProvider", () => {
+  const originalKey = process.env.OPENAI_API_KEY;
+  const originalMcpKey = process.env.MCP_EMBEDDING_API_KEY;
+
+  afterEach(() => {
+    jest.restoreAllMocks();
+    process.env.OPENAI_API_KEY = originalKey;
+    process.env.MCP_EMBEDDING_API_KEY = originalMcpKey;
+    if (originalKey === undefined) delete process.env.OPENAI_API_KEY;
+    if (originalMcpKey === undefined) delete process.env.MCP_EMBEDDING_API_KEY;
+  });
+
+  it("fails initialization with an actionable message when no key is configured", async () => {
+    delete process.env.OPENAI_API_KEY;
+    delete process.env.MCP_EMBEDDING_API_KEY;
+    const provider = new OpenAIProvider({ model: "text-embedding-3-small" });
+
+    await expect(provider.initialize()).rejects.toThrow("OPENAI_API_KEY");
+  });
+
+  it("reads the key from the environment and reports the model's native dimension", async () => {
+    process.env.OPENAI_API_KEY = "sk-env";
+    const provider = new OpenAIProvider({ model: "text-embedding-3-large" });
+
+    await expect(provider.initialize()).resolves.toBeUndefined();
+    expect(provider.getDimension()).toBe(3072);
+  });
+
+  it("retries transient failures with backoff and keeps the returned dimension", async () => {
+    const fetchMock = jest
+      .spyOn(globalThis, "fetch")
+      .mockResolvedValueOnce(new Response("rate limited", { status: 429 }))
+      .mockResolvedValueOnce(new Response("upstream", { status: 503 }))
+      .mockResolvedValueOnce(embeddingsResponse([[0.1, 0.2, 0.3]]));
+
+    const provider = new OpenAIProvider({ model: "text-embedding-3-small", apiKey: "sk-test", backoffMs: 1 });
+    await provider.initialize();
+    const vector = await provider.embed("hello");
+
+    expect(fetchMock).toHaveBeenCalledTimes(3);
+    expect(vector.length).toBe(3);
+    expect(provider.getDimension()).toBe(3);
+  });
+
+  it("does not retry authentication errors", async () => {
+    const fetchMock = jest
+      .spyOn(globalThis, "fetch")
+      .mockResolvedValue(new Response('{"error":{"message":"bad key"}}', { status: 401 }));
+
+    const provider = new OpenAIProvider({ model: "text-embedding-3-small", apiKey: "sk-bad", backoffMs: 1 });
+    await expect(provider.embedBatch(["a", "b"])).rejects.toThrow("HTTP 401");
+    expect(fetchMock).toHaveBeenCalledTimes(1);
+  });
+
+  it("splits batches at maxBatchSize and orders results by index", async () => {
+    const fetchMock = jest.spyOn(globalThis, "fetch").mockImplementation(async (_url, init) => {
+      const body = JSON.parse(String((init as RequestInit).body));
+      const inputs = body.input as string[];
+      const data = inputs.map((text, index) => ({ index, embedding: [text.length, index] })).reverse();
+      return new Response(JSON.stringify({ data }), { status: 200 });
+    });
+
+    const provider = new OpenAIProvider({ model: "text-embedding-3-small", apiKey: "sk-test", maxBatchSize: 2 });
+    const vectors = await provider.embedBatch(["a", "bb", "ccc"]);
+
+    expect(fetchMock).toHaveBeenCalledTimes(2);
+    expect(vectors.map((v) => Array.from(v))).toEqual([
+      [1, 0],
+      [2, 1],
+      [3, 0],
+    ]);
+  });
+});
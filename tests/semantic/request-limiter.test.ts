@@ -0,0 +1,79 @@
+import { afterEach, describe, expect, it, jest } from "@jest/globals";
+import { OpenAIProvider } from "../../src/semantic/providers/openai-provider.js";
+import { RequestLimiter } from "../../src/semantic/providers/request-limiter.js";
+
+function delay(ms: number): Promise<void> {
+  return new Promise((r) => setTimeout(r, ms));
+}
+
+function embeddingsResponse(vector: number[]): Response {
+  return new Response(JSON.stringify({ data: [{ index: 0, embedding: vector }] }), { status: 200 });
+}
+
+describe("RequestLimiter", () => {
+  afterEach(() => {
+    jest.restoreAllMocks();
+  });
+
+  it("lets a burst through and spaces the rest to the rate", async () => {
+    const limiter = new RequestLimiter({ requestsPerSecond: 20, burst: 2 });
+    const start = Date.now();
+    const started: number[] = [];
+
+    await Promise.all(Array.from({ length: 4 }, () => limiter.schedule(async () => started.push(Date.now() - start))));
+
+    expect(started[1]).toBeLessThan(40);
+    expect(started[3]).toBeGreaterThanOrEqual(90);
+  });
+
+  it("caps requests in flight", async () => {
+    const limiter = new RequestLimiter({ maxConcurrency: 2 });
+    let active = 0;
+    let peak = 0;
+
+    await Promise.all(
+      Array.from({ length: 6 }, () =>
+        limiter.schedule(async () => {
+          peak = Math.max(peak, ++active);
+          await delay(10);
+          active--;
+        }),
+      ),
+    );
+
+    expect(peak).toBe(2);
+  });
+
+  it("gives up a queued request when its signal aborts", async () => {
+    const limiter = new RequestLimiter({ maxConcurrency: 1 });
+    const release = await limiter.acquire();
+    const controller = new AbortController();
+    const waiting = limiter.acquire(controller.signal);
+    controller.abort(new Error("stop"));
+
+    await expect(waiting).rejects.toThrow("stop");
+    release();
+    const next = await limiter.acquire();
+    next();
+  });
+
+  it("holds back every request after a 429 instead of only the one that got it", async () => {
+    const limiter = new RequestLimiter();
+    const pause = jest.spyOn(limiter, "pause");
+    const fetchMock = jest
+      .spyOn(globalThis, "fetch")
+      .mockResolvedValueOnce(new Response("slow down", { status: 429, headers: { "Retry-After": "0.1" } }))
+      .mockResolvedValue(embeddingsResponse([0.1, 0.2]));
+    const provider = new OpenAIProvider({ model: "text-embedding-3-small", apiKey: "sk-test", limiter });
+    const start = Date.now();
+
+    const first = provider.embed("a");
+    await delay(5);
+    const second = await limiter.schedule(async () => Date.now() - start);
+    await first;
+
+    expect(pause).toHaveBeenCalledWith(100);
+    expect(second).toBeGreaterThanOrEqual(95);
+    expect(fetchMock).toHaveBeenCalledTimes(2);
+  });
+});
@@ -0,0 +1,58 @@
+import { mkdtempSync, rmSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { join } from "node:path";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { VectorStore } from "../../src/semantic/vector-store.js";
+import { EmbeddingDimensionError } from "../../src/types/errors.js";
+
+const small = { provider: "ollama", model: "nomic-embed-text" };
+const large = { provider: "openai", model: "text-embedding-3-small" };
+
+describe("VectorStore dimension changes", () => {
+  let dir: string;
+  let dbPath: string;
+
+  beforeEach(async () => {
+    dir = mkdtempSync(join(tmpdir(), "vector-dims-"));
+    dbPath = join(dir, "vectors.db");
+    const store = new VectorStore({ dbPath, dimensions: 4, embeddingSource: small });
+    await store.initialize();
+    await store.insert({ id: "a", content: "alpha", vector: new Float32Array([1, 0, 0, 0]) });
+    await store.close();
+  });
+
+  afterEach(() => rmSync(dir, { recursive: true, force: true }));
+
+  it("drops the old vectors for re-embedding by default", async () => {
+    const store = new VectorStore({ dbPath, dimensions: 8, embeddingSource: large });
+    await store.initialize();
+
+    expect(await store.count()).toBe(0);
+    expect(store.getDimensions()).toBe(8);
+    expect(store.getEmbeddingSourceChange()?.previous).toMatchObject({ ...small, dimension: 4 });
+    expect(store.getDimensionChange()).toBeNull();
+    await store.close();
+  });
+
+  it("keeps the old vectors and refuses searches and writes with refuse", async () => {
+    const store = new VectorStore({ dbPath, dimensions: 8, embeddingSource: large, onDimensionChange: "refuse" });
+    await store.initialize();
+
+    expect(await store.count()).toBe(1);
+    expect(store.getDimensionChange()).toEqual({ stored: 4, active: 8 });
+    const search = store.search(new Float32Array(8), 5);
+    await expect(search).rejects.toBeInstanceOf(EmbeddingDimensionError);
+    await expect(search).rejects.toThrow("Reindex required: embedding dimension changed from 4 to 8");
+    await expect(store.insert({ id: "b", content: "beta", vector: new Float32Array(8) })).rejects.toMatchObject({
+      details: { storedDimension: 4, activeDimension: 8 },
+    });
+    await store.close();
+
+    // Switching back finds its own vectors still recorded as the store's source
+    const original = new VectorStore({ dbPath, dimensions: 4, embeddingSource: small });
+    await original.initialize();
+    expect(original.getEmbeddingSourceChange()).toBeNull();
+    expect((await original.search(new Float32Array([1, 0, 0, 0]), 1))[0]?.id).toBe("a");
+    await original.close();
+  });
+});
@@ -0,0 +1,68 @@
+import { mkdtempSync, rmSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { join } from "node:path";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import Database from "better-sqlite3";
+import { VectorStore } from "../../src/semantic/vector-store.js";
+
+describe("VectorStore scoped search", () => {
+  let dir: string;
+  let store: VectorStore;
+
+  beforeEach(async () => {
+    dir = mkdtempSync(join(tmpdir(), "vector-scope-"));
+    store = new VectorStore({ dbPath: join(dir, "vectors.db"), dimensions: 2 });
+    await store.initialize();
+  });
+
+  afterEach(async () => {
+    await store.close();
+    rmSync(dir, { recursive: true, force: true });
+  });
+
+  it("drops out-of-scope hits before the limit, so every result comes from inside the directory", async () => {
+    // The closest vectors all live outside the directory; the ones inside rank below them
+    const outside = Array.from({ length: 20 }, (_, i) => ({
+      id: `out${i}`,
+      content: `outside ${i}`,
+      vector: new Float32Array([1, i / 1000]),
+      metadata: { type: "function", name: `out${i}`, path: `/repo/api/orders/o${i}.ts` },
+    }));
+    const inside = Array.from({ length: 4 }, (_, i) => ({
+      id: `in${i}`,
+      content: `inside ${i}`,
+      vector: new Float32Array([1, 0.5 + i / 10]),
+      metadata: { type: "function", name: `in${i}`, path: `/repo/api/users/u${i}.ts` },
+    }));
+    await store.insertBatch([...outside, ...inside]);
+    const query = new Float32Array([1, 0]);
+
+    expect((await store.search(query, 3)).every((hit) => hit.id.startsWith("out"))).toBe(true);
+
+    const scoped = await store.search(query, 3, { directory: "/repo/api/users" });
+    expect(scoped.map((hit) => hit.id)).toEqual(["in0", "in1", "in2"]);
+
+    // pathPrefix narrows alongside directory instead of replacing it
+    const both = await store.search(query, 3, { pathPrefix: "/repo/api", directory: "/repo/api/users/" });
+    expect(both.map((hit) => hit.id)).toEqual(["in0", "in1", "in2"]);
+    expect(await store.search(query, 3, { pathPrefix: "/repo/web", directory: "/repo/api/users" })).toEqual([]);
+  });
+
+  it("tags vectors of test files embedded before the test tag existed", async () => {
+    await store.insertBatch([
+      { id: "spec", content: "spec", vector: new Float32Array([1, 0]), metadata: { path: "/repo/users.test.ts" } },
+      { id: "prod", content: "prod", vector: new Float32Array([1, 0.1]), metadata: { path: "/repo/users.ts" } },
+    ]);
+    await store.close();
+    // A store written by an older release: no tag on the vectors, no record of the backfill
+    const db = new Database(join(dir, "vectors.db"));
+    db.prepare("DELETE FROM vector_store_meta WHERE key = 'test_tags'").run();
+    db.close();
+
+    store = new VectorStore({ dbPath: join(dir, "vectors.db"), dimensions: 2 });
+    await store.initialize();
+    const query = new Float32Array([1, 0]);
+    expect((await store.search(query, 5, { tests: "exclude" })).map((hit) => hit.id)).toEqual(["prod"]);
+    expect((await store.search(query, 5, { tests: "only" })).map((hit) => hit.id)).toEqual(["spec"]);
+  });
+});
@@ -0,0 +1,119 @@
+import { existsSync, readFileSync, rmSync, writeFileSync } from "node:fs";
+import { gunzipSync, gzipSync } from "node:zlib";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import {
+  exportIndex,
+  IndexArchiveError,
+  importIndex,
+  readIndexArchiveManifest,
+  supportedSchemaVersion,
+} from "../../src/storage/index-archive.js";
+import { runMigrations } from "../../src/storage/schema-migrations.js";
+import { SQLiteManager } from "../../src/storage/sqlite-manager.js";
+
+const SOURCE_DB = "./data/test-index-archive-source.db";
+const TARGET_DB = "./data/test-index-archive-target.db";
+const ARCHIVE = "./data/test-index-archive.cgrag";
+const CLEANUP_PATHS = [SOURCE_DB, TARGET_DB].flatMap((p) => [p, `${p}-shm`, `${p}-wal`]).concat(ARCHIVE);
+
+function createIndex(path: string, root: string): void {
+  const manager = new SQLiteManager({ path });
+  manager.initialize();
+  runMigrations(manager);
+  const db = manager.getConnection();
+  db.prepare("INSERT INTO files (path, hash, last_indexed, entity_count) VALUES (?, 'h', 1, 1)").run(`${root}/a.ts`);
+  db.prepare(
+    `INSERT INTO entities (id, name, type, file_path, location, metadata, hash, created_at, updated_at)
+     VALUES ('e1', 'run', 'function', ?, '{}', ?, 'h', 1, 1)`,
+  ).run(`${root}/a.ts`, JSON.stringify({ root }));
+  // A sibling directory sharing the prefix must not move
+  const sibling = `${root}-old/b.ts`;
+  db.prepare("INSERT INTO files (path, hash, last_indexed, entity_count) VALUES (?, 'h', 1, 0)").run(sibling);
+  manager.close();
+}
+
+function count(path: string, sql: string, ...params: unknown[]): number {
+  const manager = new SQLiteManager({ path });
+  manager.initialize();
+  try {
+    return (manager.getConnection().prepare(sql).get(...params) as { count: number }).count;
+  } finally {
+    manager.close();
+  }
+}
+
+const source = { directory: "/ci/work/repo", repository: "https://github.com/acme/repo.git", commit: "abc123" };
+
+describe("index archives", () => {
+  beforeEach(() => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    createIndex(SOURCE_DB, "/ci/work/repo");
+  });
+
+  afterEach(() => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+  });
+
+  it("round-trips an index and moves its paths to the local directory", async () => {
+    const exported = await exportIndex({ path: ARCHIVE, graphDb: SOURCE_DB, source });
+    expect(exported.manifest).toMatchObject({
+      schemaVersion: supportedSchemaVersion(),
+      source,
+      counts: { files: 2, entities: 1, relationships: 0 },
+    });
+    expect(await readIndexArchiveManifest(ARCHIVE)).toEqual(exported.manifest);
+
+    const imported = await importIndex({ path: ARCHIVE, graphDb: TARGET_DB, directory: "/home/dev/repo" });
+
+    expect(imported.rebased).toEqual({ from: "/ci/work/repo", to: "/home/dev/repo", rows: 3 });
+    expect(count(TARGET_DB, "SELECT COUNT(*) AS count FROM files WHERE path = ?", "/home/dev/repo/a.ts")).toBe(1);
+    expect(count(TARGET_DB, "SELECT COUNT(*) AS count FROM files WHERE path = ?", "/ci/work/repo-old/b.ts")).toBe(1);
+    expect(
+      count(
+        TARGET_DB,
+        "SELECT COUNT(*) AS count FROM entities WHERE file_path = ? AND json_extract(metadata, '$.root') = ?",
+        "/home/dev/repo/a.ts",
+        "/home/dev/repo",
+      ),
+    ).toBe(1);
+  });
+
+  it("refuses a damaged archive and leaves the local index alone", async () => {
+    createIndex(TARGET_DB, "/home/dev/repo");
+    await exportIndex({ path: ARCHIVE, graphDb: SOURCE_DB, source });
+    const raw = gunzipSync(readFileSync(ARCHIVE));
+    raw[raw.length - 100] = raw[raw.length - 100]! ^ 0xff;
+    writeFileSync(ARCHIVE, gzipSync(raw));
+
+    await expect(importIndex({ path: ARCHIVE, graphDb: TARGET_DB, directory: "/home/dev/repo" })).rejects.toMatchObject(
+      { reason: "checksum_mismatch" },
+    );
+    expect(count(TARGET_DB, "SELECT COUNT(*) AS count FROM files WHERE path = ?", "/home/dev/repo/a.ts")).toBe(1);
+  });
+
+  it("refuses an archive from a newer schema", async () => {
+    await exportIndex({ path: ARCHIVE, graphDb: SOURCE_DB, source });
+    const raw = gunzipSync(readFileSync(ARCHIVE));
+    const length = raw.readUInt32BE(8);
+    const manifest = JSON.parse(raw.subarray(12, 12 + length).toString("utf8"));
+    manifest.schemaVersion = supportedSchemaVersion() + 1;
+    const header = Buffer.from(JSON.stringify(manifest));
+    const size = Buffer.alloc(4);
+    size.writeUInt32BE(header.length);
+    writeFileSync(ARCHIVE, gzipSync(Buffer.concat([raw.subarray(0, 8), size, header, raw.subarray(12 + length)])));
+
+    const attempt = importIndex({ path: ARCHIVE, graphDb: TARGET_DB, directory: "/home/dev/repo" });
+    await expect(attempt).rejects.toBeInstanceOf(IndexArchiveError);
+    await expect(attempt).rejects.toMatchObject({ reason: "incompatible_schema" });
+  });
+
+  it("rejects a file that is not an archive", async () => {
+    writeFileSync(ARCHIVE, gzipSync(Buffer.from("not an index")));
+
+    await expect(readIndexArchiveManifest(ARCHIVE)).rejects.toMatchObject({ reason: "invalid_archive" });
+  });
+});
@@ -0,0 +1,108 @@
+import { existsSync, rmSync } from "node:fs";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+import { RelationType } from "../../src/types/storage.js";
+
+const TEST_DB_PATH = "./data/test-index-recovery.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function e(name: string, line: number): ParsedEntity {
+  return {
+    name,
+    type: "function",
+    location: {
+      start: { line, column: 0, index: line * 10 },
+      end: { line: line + 1, column: 0, index: line * 10 + 5 },
+    },
+  } as any;
+}
+
+const tick = () => new Promise((resolve) => setTimeout(resolve, 5));
+
+describe("recoverInterruptedIndex", () => {
+  let agent: IndexerAgent;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("drops a half-written file and marks what the killed run left unresolved as stale", async () => {
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.indexEntities([e("target", 1), e("parse", 10)], "/tmp/lib.ts", [
+      { from: "parse", to: "target", type: "calls", metadata: { line: 11 } },
+    ]);
+    await tick();
+
+    await storage.startIndexRun({ id: "run-1", directory: "/tmp" });
+    await agent.indexEntities([e("main", 1)], "/tmp/main.ts");
+    const [main] = (await storage.executeQuery({ type: "entity", filters: { name: "main" } })).entities;
+    const [target] = (await storage.executeQuery({ type: "entity", filters: { name: "target" } })).entities;
+    await storage.insertRelationships([
+      { id: "main-target", fromId: main!.id, toId: target!.id, type: RelationType.CALLS, metadata: { line: 2 } },
+    ]);
+    // Killed while lib.ts was being rewritten: its journal row outlives the process
+    await storage.beginFileWrite("/tmp/lib.ts");
+
+    const recovery = await storage.recoverInterruptedIndex(() => false);
+
+    expect(recovery.droppedFiles).toEqual(["/tmp/lib.ts"]);
+    expect(recovery.staleFiles).toEqual(["/tmp/main.ts"]);
+    expect(recovery.runs).toMatchObject([{ id: "run-1", status: "interrupted", directory: "/tmp" }]);
+    expect(recovery.runs[0]?.recoveredAt).toEqual(expect.any(Number));
+    expect(await storage.getFileInfo("/tmp/lib.ts")).toBeNull();
+    expect((await storage.executeQuery({ type: "entity", filters: { name: "target" } })).entities).toEqual([]);
+    // An empty hash never matches, so the next update_index re-indexes main.ts
+    expect(await storage.getFileInfo("/tmp/main.ts")).toMatchObject({ hash: "", lastIndexed: 0 });
+
+    expect(await storage.recoverInterruptedIndex(() => false)).toEqual({ droppedFiles: [], staleFiles: [], runs: [] });
+    expect((await storage.listIndexRuns())[0]).toMatchObject({ id: "run-1", status: "interrupted" });
+  });
+
+  it("leaves live runs alone and forgets writes that reached their files entry", async () => {
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    await storage.startIndexRun({ id: "run-2" });
+    await storage.beginFileWrite("/tmp/lib.ts");
+
+    expect(await storage.recoverInterruptedIndex(() => true)).toEqual({ droppedFiles: [], staleFiles: [], runs: [] });
+
+    // The indexer journals the write itself and closes it with the files entry
+    await agent.indexEntities([e("target", 1)], "/tmp/lib.ts");
+    await storage.finishIndexRun("run-2", "completed", { filesDone: 1, filesTotal: 1 });
+
+    expect(await storage.recoverInterruptedIndex(() => false)).toEqual({ droppedFiles: [], staleFiles: [], runs: [] });
+    expect(await storage.listIndexRuns(1)).toMatchObject([
+      { id: "run-2", status: "completed", filesDone: 1, filesTotal: 1, recoveredAt: null },
+    ]);
+  });
+
+  it("lists running runs of other live processes", async () => {
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    await storage.startIndexRun({ id: "run-3", directory: "/tmp" });
+    await storage.startIndexRun({ id: "run-4" });
+    await storage.finishIndexRun("run-4", "completed", { filesDone: 0, filesTotal: 0 });
+
+    expect(await storage.listForeignIndexRuns(() => true)).toMatchObject([
+      { id: "run-3", status: "running", pid: process.pid },
+    ]);
+    expect(await storage.listForeignIndexRuns(() => false)).toEqual([]);
+  });
+});
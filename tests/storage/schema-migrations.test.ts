@@ -0,0 +1,162 @@
+import { existsSync, rmSync } from "node:fs";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { type Migration, migrations, runMigrations, SchemaMigration } from "../../src/storage/schema-migrations.js";
+import { SQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { SchemaVersionError } from "../../src/types/errors.js";
+
+const TEST_DB_PATH = "./data/test-schema-migrations.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+const LATEST = Math.max(...migrations.map((m) => m.version));
+
+// The table, index or column each migration is responsible for
+const CREATES: Record<number, { table: string; column?: string }> = {
+  1: { table: "entities" },
+  2: { table: "entities", column: "complexity_score" },
+  3: { table: "entity_search" },
+  4: { table: "embedding_cache" },
+  5: { table: "graph_snapshots" },
+  6: { table: "index_runs" },
+  7: { table: "files", column: "mtime_ms" },
+  8: { table: "index_runs", column: "repository" },
+  9: { table: "idx_entities_name_nocase" },
+  10: { table: "snapshot_vectors" },
+  12: { table: "test_entities" },
+};
+// Migrations that only rewrite rows; each has its own test below
+const BACKFILLS = new Set([11]);
+
+describe("SchemaMigration", () => {
+  let manager: SQLiteManager;
+
+  const has = ({ table, column }: { table: string; column?: string }) => {
+    const db = manager.getConnection();
+    if (!db.prepare("SELECT 1 FROM sqlite_master WHERE name = ?").get(table)) return false;
+    if (!column) return true;
+    return (db.prepare(`PRAGMA table_info(${table})`).all() as Array<{ name: string }>).some((c) => c.name === column);
+  };
+
+  beforeEach(() => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    manager = new SQLiteManager({ path: TEST_DB_PATH });
+    manager.initialize();
+  });
+
+  afterEach(() => {
+    manager.close();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+  });
+
+  it("brings a new database to the latest version", () => {
+    runMigrations(manager);
+
+    const migration = new SchemaMigration(manager);
+    expect(migration.status()).toEqual({ version: LATEST, latestVersion: LATEST, pending: [], modified: [] });
+    expect(migration.verifyIntegrity()).toBe(true);
+    expect(manager.getConnection().pragma("user_version", { simple: true })).toBe(LATEST);
+  });
+
+  it.each(migrations.filter((m) => !BACKFILLS.has(m.version)).map((m) => [m.version, m.description] as const))(
+    "applies migration %i (%s) on top of the ones before it",
+    (version) => {
+      new SchemaMigration(manager, migrations.filter((m) => m.version < version)).migrate();
+      const expected = CREATES[version];
+      expect(expected).toBeDefined();
+      expect(has(expected!)).toBe(false);
+
+      const migration = new SchemaMigration(manager, migrations.filter((m) => m.version <= version));
+      migration.migrate();
+      expect(has(expected!)).toBe(true);
+      expect(migration.status()).toMatchObject({ version, pending: [] });
+
+      // Running it again is a no-op
+      migration.migrate();
+      expect(migration.getHistory()).toHaveLength(migrations.filter((m) => m.version <= version).length);
+    },
+  );
+
+  it("adopts an unversioned database whose tables already have the later columns", () => {
+    const db = manager.getConnection();
+    db.exec(migrations[0]!.up as string);
+    db.exec("ALTER TABLE entities ADD COLUMN language TEXT");
+    db.prepare(
+      "INSERT INTO entities (id, name, type, file_path, location, hash, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+    ).run("e1", "main", "function", "/src/main.ts", "{}", "h", 1, 1);
+
+    runMigrations(manager);
+
+    expect(new SchemaMigration(manager).status().pending).toEqual([]);
+    expect(db.prepare("SELECT language FROM entities WHERE id = 'e1'").get()).toEqual({ language: "typescript" });
+  });
+
+  it("tags entities of test files indexed before the test tag existed", () => {
+    new SchemaMigration(manager, migrations.filter((m) => m.version < 11)).migrate();
+    const db = manager.getConnection();
+    const insert = db.prepare(
+      "INSERT INTO entities (id, name, type, file_path, location, metadata, hash, created_at, updated_at) VALUES (?, ?, 'function', ?, '{}', ?, 'h', 1, 1)",
+    );
+    insert.run("spec", "rejectsEmpty", "/src/parse.test.ts", '{"async":true}');
+    insert.run("untagged", "TestParse", "/pkg/parse_test.go", null);
+    insert.run("prod", "parse", "/src/parse.ts", "{}");
+
+    runMigrations(manager);
+
+    const tags = db
+      .prepare("SELECT id, json_extract(metadata, '$.isTest') AS test, metadata FROM entities ORDER BY id")
+      .all();
+    expect(tags).toEqual([
+      { id: "prod", test: null, metadata: "{}" },
+      { id: "spec", test: 1, metadata: '{"async":true,"isTest":true}' },
+      { id: "untagged", test: 1, metadata: '{"isTest":true}' },
+    ]);
+  });
+
+  it("refuses a database written by a newer release", () => {
+    runMigrations(manager);
+    manager
+      .getConnection()
+      .prepare("INSERT INTO migrations (version, applied_at, checksum) VALUES (?, ?, ?)")
+      .run(LATEST + 1, Date.now(), "future");
+
+    expect(() => runMigrations(manager)).toThrow(SchemaVersionError);
+    try {
+      runMigrations(manager);
+    } catch (error) {
+      expect((error as SchemaVersionError).details).toMatchObject({
+        reason: "newer_schema",
+        databaseVersion: LATEST + 1,
+        supportedVersion: LATEST,
+      });
+      expect((error as Error).message).toContain(TEST_DB_PATH);
+    }
+  });
+
+  it("rolls a failed migration back and names it", () => {
+    runMigrations(manager);
+    const broken: Migration = {
+      version: LATEST + 1,
+      description: "Broken step",
+      up: "CREATE TABLE half_done (id TEXT); INSERT INTO missing_table VALUES (1);",
+    };
+    const migration = new SchemaMigration(manager, [...migrations, broken]);
+
+    let failure: SchemaVersionError | undefined;
+    try {
+      migration.migrate();
+    } catch (error) {
+      failure = error as SchemaVersionError;
+    }
+    expect(failure).toBeInstanceOf(SchemaVersionError);
+    expect(failure?.details).toMatchObject({
+      reason: "migration_failed",
+      failedMigration: LATEST + 1,
+      databaseVersion: LATEST,
+    });
+    expect(failure?.details.cause).toContain("missing_table");
+    expect(has({ table: "half_done" })).toBe(false);
+    expect(migration.status()).toMatchObject({ version: LATEST, pending: [LATEST + 1] });
+  });
+});
@@ -0,0 +1,68 @@
+import { describe, expect, it } from "@jest/globals";
+import { type BatchRequest, isReadOnlyRequest, runBatch } from "../../src/tools/batch.js";
+
+const tick = () => new Promise((resolve) => setTimeout(resolve, 5));
+
+describe("runBatch", () => {
+  it("returns results in request order with ids and per-item failures", async () => {
+    const requests: BatchRequest[] = [
+      { tool: "find_definition", args: { name: "a" }, id: "def" },
+      { tool: "list_callers", args: { name: "missing" } },
+      { tool: "list_callees", args: { name: "a" }, id: "callees" },
+    ];
+
+    const batch = await runBatch(requests, async (request) => {
+      await tick();
+      if (request.args?.name === "missing") throw new Error("not found");
+      return { success: true, data: { tool: request.tool } };
+    });
+
+    expect(batch.results.map((r) => [r.index, r.id, r.tool, r.success])).toEqual([
+      [0, "def", "find_definition", true],
+      [1, undefined, "list_callers", false],
+      [2, "callees", "list_callees", true],
+    ]);
+    expect(batch.results[1]).toMatchObject({ errorType: "tool_error", error: "not found" });
+    expect(batch).toMatchObject({ succeeded: 2, failed: 1 });
+  });
+
+  it("runs read-only requests concurrently but writes alone and in place", async () => {
+    const events: string[] = [];
+    let running = 0;
+    let peak = 0;
+    const requests: BatchRequest[] = [
+      { tool: "find_references" },
+      { tool: "list_callers" },
+      { tool: "list_callees" },
+      { tool: "reindex_file" },
+      { tool: "find_definition" },
+    ];
+
+    await runBatch(
+      requests,
+      async (request, index) => {
+        running++;
+        peak = Math.max(peak, running);
+        events.push(`start:${index}`);
+        await tick();
+        events.push(`end:${index}`);
+        running--;
+        return { success: true, data: request.tool };
+      },
+      2,
+    );
+
+    expect(peak).toBe(2);
+    // The write starts only after every read before it ended, and the read after it waits for it
+    const at = (event: string) => events.indexOf(event);
+    expect(at("start:3")).toBeGreaterThan(Math.max(at("end:0"), at("end:1"), at("end:2")));
+    expect(at("start:4")).toBeGreaterThan(at("end:3"));
+  });
+
+  it("treats metrics as a write when it resets the counters", () => {
+    expect(isReadOnlyRequest({ tool: "metrics" })).toBe(true);
+    expect(isReadOnlyRequest({ tool: "metrics", args: { reset: false } })).toBe(true);
+    expect(isReadOnlyRequest({ tool: "metrics", args: { reset: true } })).toBe(false);
+    expect(isReadOnlyRequest({ tool: "reindex_file" })).toBe(false);
+  });
+});
@@ -0,0 +1,89 @@
+import { existsSync, mkdtempSync, rmSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { join } from "node:path";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { buildPackageTree } from "../../src/core/package-tree.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { browse } from "../../src/tools/browse.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import { entity } from "../fixtures/parsed-entities.js";
+
+const TEST_DB_PATH = "./data/test-browse.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+describe("browse", () => {
+  let agent: IndexerAgent;
+  let root: string;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+    root = mkdtempSync(join(tmpdir(), "cgr-browse-"));
+    writeFileSync(join(root, "go.mod"), "module example.com/shop\n\ngo 1.22\n");
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    rmSync(root, { recursive: true, force: true });
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("walks from the roots down to the declarations of a package and of a file", async () => {
+    const cart = join(root, "cart", "cart.go");
+    const items = join(root, "cart", "items.go");
+    const main = join(root, "main.go");
+    await agent.indexEntities([entity("m:pkg", "main", "module", 1, { isPackage: true })], main, []);
+    await agent.indexEntities(
+      [entity("c:pkg", "cart", "module", 1, { isPackage: true }), entity("c:Cart", "Cart", "class", 3)],
+      cart,
+      [],
+    );
+    await agent.indexEntities(
+      [
+        entity("i:pkg", "cart", "module", 1, { isPackage: true }),
+        entity("i:Item", "Item", "class", 3),
+        entity("i:Add", "Add", "function", 7),
+      ],
+      items,
+      [],
+    );
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    await buildPackageTree(storage, { roots: [root] });
+
+    const roots = (await browse(storage))!;
+    expect(roots.container).toBeNull();
+    expect(roots.children).toEqual([expect.objectContaining({ name: "main", kind: "go_package", children: 1 })]);
+
+    const top = (await browse(storage, { path: root }))!;
+    expect(top.children).toEqual([expect.objectContaining({ name: "cart", kind: "go_package", children: 3 })]);
+
+    const pkg = (await browse(storage, { path: "example.com/shop/cart" }))!;
+    expect(pkg.container).toMatchObject({ name: "cart", kind: "go_package", path: join(root, "cart") });
+    expect(pkg.children.map((c) => [c.name, c.line])).toEqual([
+      ["Cart", 3],
+      ["Item", 3],
+      ["Add", 7],
+    ]);
+
+    const file = (await browse(storage, { path: items }))!;
+    expect(file.container).toMatchObject({ id: null, kind: "module", qualifiedName: "example.com/shop/cart/items.go" });
+    expect(file.children.map((c) => c.name)).toEqual(["Item", "Add"]);
+
+    const page = (await browse(storage, { path: join(root, "cart"), limit: 1 }))!;
+    expect(page).toMatchObject({ total: 3, truncated: true });
+    expect(page.children).toHaveLength(1);
+
+    expect(await browse(storage, { path: join(root, "missing") })).toBeNull();
+  });
+});
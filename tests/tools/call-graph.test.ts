@@ -0,0 +1,209 @@
+import { existsSync, rmSync } from "node:fs";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { listCallees, listCallers } from "../../src/tools/call-graph.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+
+const TEST_DB_PATH = "./data/test-tool-call-graph.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function e(name: string, line: number, extra?: Partial<ParsedEntity>): ParsedEntity {
+  return {
+    name,
+    type: "function",
+    location: {
+      start: { line, column: 0, index: line * 10 },
+      end: { line: line + 4, column: 0, index: line * 10 + 5 },
+    },
+    ...extra,
+  } as any;
+}
+
+describe("call graph", () => {
+  let agent: IndexerAgent;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("lists same-file callers and callees with every call site", async () => {
+    const render = e("render", 10, {
+      calls: [
+        { name: "format", line: 11, column: 4 },
+        { name: "format", line: 13, column: 4 },
+        { name: "log", qualifier: "console", line: 12, column: 4 },
+      ],
+    });
+    await agent.indexEntities([e("format", 1), render], "/tmp/view.ts");
+
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    const callers = await listCallers(storage, { symbol: "format" });
+    expect(callers.definitions).toHaveLength(1);
+    expect(callers.definitions[0]?.calls).toHaveLength(1);
+    expect(callers.definitions[0]?.calls[0]).toMatchObject({ name: "render", resolution: "direct" });
+    expect(callers.definitions[0]?.calls[0]?.callSites.map((s) => s.line)).toEqual([11, 13]);
+    expect(callers.definitions[0]?.calls[0]?.edge).toEqual({
+      from: callers.definitions[0]?.calls[0]?.entity?.id,
+      to: callers.definitions[0]?.definition.id,
+      type: "calls",
+      direction: "incoming",
+      file: "/tmp/view.ts",
+      line: 11,
+      column: 4,
+    });
+
+    const callees = await listCallees(storage, { symbol: "render" });
+    const calls = callees.definitions[0]?.calls ?? [];
+    expect(calls.find((c) => c.name === "format")?.resolution).toBe("direct");
+    expect(calls.find((c) => c.name === "console.log")).toMatchObject({ entity: null, resolution: "unresolved" });
+    expect(calls.find((c) => c.name === "console.log")?.edge).toMatchObject({
+      from: callees.definitions[0]?.definition.id,
+      to: null,
+      direction: "outgoing",
+      line: 12,
+    });
+  });
+
+  it("scores each call by how it was bound and filters by minConfidence", async () => {
+    await agent.indexEntities([e("format", 1), e("render", 10), e("format", 30), e("parse", 40)], "/tmp/view.py", [
+      { from: "render", to: "format", type: "calls", metadata: { line: 11, callee: "format", calleeName: "format" } },
+      { from: "render", to: "parse", type: "calls", metadata: { line: 12, callee: "parse", calleeName: "parse" } },
+      {
+        from: "render",
+        to: "shlex.quote",
+        type: "calls",
+        metadata: { line: 13, callee: "shlex.quote", calleeName: "quote" },
+      },
+    ]);
+
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    const callees = await listCallees(storage, { symbol: "render" });
+    const scored = (callees.definitions[0]?.calls ?? [])
+      .map((c) => [c.name, c.derivation, c.confidence])
+      .sort((a, b) => String(a[0]).localeCompare(String(b[0])));
+    // Two functions are named format; the nearer one is only a name match
+    expect(scored).toEqual([
+      ["format", "name_match", 0.7],
+      ["parse", "resolved", 1],
+      ["shlex.quote", "heuristic", 0.4],
+    ]);
+
+    const confident = await listCallees(storage, { symbol: "render", minConfidence: 0.5 });
+    expect(confident.definitions[0]?.calls.map((c) => c.name).sort()).toEqual(["format", "parse"]);
+    expect(confident.total).toBe(2);
+
+    const callers = await listCallers(storage, { symbol: "format", filePath: "/tmp/view.py", minConfidence: 0.8 });
+    expect(callers.total).toBe(0);
+  });
+
+  it("resolves Go calls across files of one package, including methods by receiver type", async () => {
+    await agent.indexEntities(
+      [
+        e("Save", 1, { type: "method", metadata: { receiver: "Store" } } as any),
+        e("newStore", 8),
+        e("Save", 20, { type: "method", metadata: { receiver: "Cache" } } as any),
+      ],
+      "/tmp/app/store.go",
+    );
+    await agent.indexEntities([e("main", 1)], "/tmp/app/main.go", [
+      {
+        from: "main",
+        to: "newStore",
+        type: "calls",
+        metadata: { line: 2, callee: "newStore", calleeName: "newStore" },
+      },
+      {
+        from: "main",
+        to: "/tmp/app/main.go:method:Store:Save",
+        type: "calls",
+        metadata: { line: 3, callee: "s.Save", calleeName: "Save", receiverType: "Store" },
+      },
+      {
+        from: "main",
+        to: "fmt.Println",
+        type: "calls",
+        metadata: { line: 4, callee: "fmt.Println", calleeName: "Println" },
+      },
+    ]);
+    await agent.indexEntities([e("newStore", 1)], "/tmp/other/store.go");
+
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    const callees = await listCallees(storage, { symbol: "main" });
+    const calls = callees.definitions[0]?.calls ?? [];
+    expect(calls.find((c) => c.name === "newStore")).toMatchObject({
+      resolution: "package",
+      entity: { filePath: "/tmp/app/store.go" },
+    });
+    expect(calls.find((c) => c.name === "Save")).toMatchObject({
+      resolution: "package",
+      entity: { receiver: "Store", startLine: 1 },
+    });
+    expect(calls.find((c) => c.name === "fmt.Println")?.resolution).toBe("unresolved");
+
+    const callers = await listCallers(storage, { symbol: "Save", package: "Store" });
+    expect(callers.definitions).toHaveLength(1);
+    expect(callers.definitions[0]?.calls[0]?.callSites[0]).toMatchObject({ filePath: "/tmp/app/main.go", line: 3 });
+
+    const otherPackage = await listCallers(storage, { symbol: "newStore", filePath: "/tmp/other/store.go" });
+    expect(otherPackage.definitions[0]?.calls).toHaveLength(0);
+  });
+
+  it("keeps the arguments of each call site and finds callers passing a literal", async () => {
+    const nilArgs = [{ kind: "expression" }, { kind: "literal", value: "nil" }];
+    await agent.indexEntities([e("open", 1), e("load", 10), e("save", 20), e("forward", 30)], "/tmp/db/db.go", [
+      {
+        from: "load",
+        to: "open",
+        type: "calls",
+        metadata: { line: 11, callee: "open", calleeName: "open", arguments: nilArgs, arity: 2 },
+      },
+      {
+        from: "save",
+        to: "open",
+        type: "calls",
+        metadata: {
+          line: 21,
+          callee: "open",
+          calleeName: "open",
+          arguments: [{ kind: "literal", value: '"db"' }, { kind: "expression" }],
+          arity: 2,
+        },
+      },
+      {
+        from: "forward",
+        to: "open",
+        type: "calls",
+        metadata: { line: 31, callee: "open", calleeName: "open", arguments: [{ kind: "spread" }] },
+      },
+    ]);
+
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    const callers = (await listCallers(storage, { symbol: "open" })).definitions[0]?.calls ?? [];
+    const siteOf = (caller: string) => callers.find((c) => c.name === caller)?.callSites[0];
+    expect(siteOf("load")).toMatchObject({ line: 11, arguments: nilArgs, arity: 2 });
+    expect(siteOf("forward")).toMatchObject({ arguments: [{ kind: "spread" }], arity: null });
+
+    const passingNil = await listCallers(storage, { symbol: "open", argument: { position: 2, value: "nil" } });
+    expect(passingNil.definitions[0]?.calls.map((c) => c.name)).toEqual(["load"]);
+    const named = await listCallers(storage, { symbol: "open", argument: { position: 1, value: '"db"' } });
+    expect(named.definitions[0]?.calls.map((c) => c.name)).toEqual(["save"]);
+  });
+});
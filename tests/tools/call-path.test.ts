@@ -0,0 +1,110 @@
+import { existsSync, rmSync } from "node:fs";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { findCallPaths } from "../../src/tools/call-path.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+
+const TEST_DB_PATH = "./data/test-tool-call-path.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function fn(name: string, line: number, calls: string[] = []): ParsedEntity {
+  return {
+    name,
+    type: "function",
+    location: {
+      start: { line, column: 0, index: line * 10 },
+      end: { line: line + 4, column: 0, index: line * 10 + 5 },
+    },
+    calls: calls.map((callee, i) => ({ name: callee, line: line + 1 + i, column: 2 })),
+  } as any;
+}
+
+describe("call paths", () => {
+  let agent: IndexerAgent;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+
+    // handle → validate → save and handle → audit → save; save ⇄ retry
+    await agent.indexEntities(
+      [
+        fn("handle", 1, ["validate", "audit"]),
+        fn("validate", 10, ["save"]),
+        fn("audit", 20, ["save"]),
+        fn("save", 30, ["retry"]),
+        fn("retry", 40, ["save"]),
+        fn("unused", 50),
+      ],
+      "/tmp/service.ts",
+    );
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("returns every shortest path with the call site of each hop", async () => {
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    const result = await findCallPaths(storage, { from: "handle", to: "save" });
+
+    expect(result.length).toBe(2);
+    expect(result.totalPaths).toBe(2);
+    expect(result.truncated).toBeNull();
+    const names = result.paths.map((path) => path.steps.map((step) => step.entity.name)).sort();
+    expect(names).toEqual([
+      ["handle", "audit", "save"],
+      ["handle", "validate", "save"],
+    ]);
+
+    const viaValidate = result.paths.find((path) => path.steps[1]?.entity.name === "validate")!;
+    expect(viaValidate.steps[0]?.call?.callSites.map((site) => site.line)).toEqual([2]);
+    expect(viaValidate.steps[0]?.call?.edge).toMatchObject({
+      from: viaValidate.steps[0]?.entity.id,
+      to: viaValidate.steps[1]?.entity.id,
+      type: "calls",
+      direction: "outgoing",
+      file: "/tmp/service.ts",
+      line: 2,
+    });
+    expect(viaValidate.steps[2]?.call).toBeNull();
+
+    const limited = await findCallPaths(storage, { from: "handle", to: "save", limit: 1 });
+    expect(limited.paths).toHaveLength(1);
+    expect(limited.truncated).toMatchObject({ reason: "limit" });
+  });
+
+  it("terminates on recursion and tells a depth cut-off from no path at all", async () => {
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    const recursion = await findCallPaths(storage, { from: "save", to: "save" });
+    expect(recursion.paths.map((path) => path.steps.map((step) => step.entity.name))).toEqual([
+      ["save", "retry", "save"],
+    ]);
+
+    const cut = await findCallPaths(storage, { from: "handle", to: "retry", maxDepth: 2 });
+    expect(cut.paths).toEqual([]);
+    expect(cut.length).toBeNull();
+    expect(cut.truncated).toMatchObject({ reason: "depth", depth: 2 });
+    expect((await findCallPaths(storage, { from: "handle", to: "retry" })).length).toBe(3);
+
+    // save and retry only call each other: the search runs dry without reaching handle
+    const none = await findCallPaths(storage, { from: "save", to: "handle" });
+    expect(none.paths).toEqual([]);
+    expect(none.truncated).toBeNull();
+    expect((await findCallPaths(storage, { from: "unused", to: "save" })).paths).toEqual([]);
+  });
+});
@@ -0,0 +1,105 @@
+import { existsSync, rmSync } from "node:fs";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import type { GraphStorageImpl } from "../../src/storage/graph-storage.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { camelCaseMatch, completeSymbol } from "../../src/tools/complete-symbol.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+
+const TEST_DB_PATH = "./data/test-tool-complete-symbol.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function e(name: string, line: number, type: ParsedEntity["type"] = "function"): ParsedEntity {
+  return {
+    name,
+    type,
+    location: {
+      start: { line, column: 0, index: line * 10 },
+      end: { line: line + 1, column: 0, index: line * 10 + 5 },
+    },
+  } as any;
+}
+
+describe("completeSymbol", () => {
+  let agent: IndexerAgent;
+  let storage: GraphStorageImpl;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+
+    await agent.indexEntities(
+      [
+        e("GetUser", 1),
+        e("getUsage", 10),
+        e("GeoUtil", 20, "class"),
+        e("GetUserById", 30),
+        e("purge", 40),
+        e("UserCache", 50, "class"),
+      ],
+      "/tmp/users.ts",
+      [
+        { from: "purge", to: "GetUserById", type: "calls", metadata: { line: 41 } },
+        { from: "UserCache", to: "GetUserById", type: "references", metadata: { line: 51 } },
+      ],
+    );
+    await agent.indexEntities([e("GetUser", 1)], "/tmp/api.go");
+    storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("completes camel-case initials, types first and then by usage", async () => {
+    const result = await completeSymbol(storage, "GU");
+
+    expect(result.completions.map((c) => [c.name, c.match])).toEqual([
+      ["GeoUtil", "camel_case"],
+      ["GetUserById", "camel_case"],
+      ["GetUser", "camel_case"],
+      ["getUsage", "camel_case"],
+    ]);
+    expect(result.completions.find((c) => c.name === "GetUserById")?.usages).toBe(2);
+    expect(result.completions.find((c) => c.name === "GetUser")?.definitions).toBe(2);
+  });
+
+  it("ranks the prefix as typed ahead of other casings", async () => {
+    const result = await completeSymbol(storage, "getUs", { limit: 2 });
+
+    expect(result.completions.map((c) => [c.name, c.match])).toEqual([
+      ["getUsage", "prefix"],
+      ["GetUserById", "prefix_ignore_case"],
+    ]);
+    expect(result).toMatchObject({ total: 3, truncated: true });
+  });
+
+  it("filters by kind and language", async () => {
+    const classes = await completeSymbol(storage, "GU", { scope: { kinds: ["class"] } });
+    expect(classes.completions.map((c) => c.name)).toEqual(["GeoUtil"]);
+
+    const go = await completeSymbol(storage, "GetU", { scope: { languages: ["go"] } });
+    expect(go.completions.map((c) => [c.name, c.filePath, c.definitions])).toEqual([["GetUser", "/tmp/api.go", 1]]);
+  });
+
+  it("matches each query run against the start of a word", () => {
+    expect(camelCaseMatch("GU", "GetUserById")).toBe(true);
+    expect(camelCaseMatch("getUsBy", "GetUserById")).toBe(true);
+    expect(camelCaseMatch("hs", "HTTPServer")).toBe(true);
+    expect(camelCaseMatch("g_u", "get_user")).toBe(true);
+    expect(camelCaseMatch("UB", "GetUserById")).toBe(false);
+    expect(camelCaseMatch("gx", "getUser")).toBe(false);
+  });
+});
@@ -0,0 +1,130 @@
+import { existsSync, mkdtempSync, rmSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { join } from "node:path";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { detectCycles, stronglyConnectedComponents } from "../../src/tools/detect-cycles.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+
+const TEST_DB_PATH = "./data/test-tool-detect-cycles.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function importOf(source: string, line: number): ParsedEntity {
+  return {
+    name: `import:${source}`,
+    type: "import",
+    location: {
+      start: { line, column: 0, index: line * 10 },
+      end: { line, column: 20, index: line * 10 + 20 },
+    },
+    importData: { source, specifiers: [{ local: "x" }] },
+  } as any;
+}
+
+function goPackage(filePath: string, name: string): ParsedEntity {
+  return {
+    id: `${filePath}:package:${name}`,
+    name,
+    type: "module",
+    location: { start: { line: 1, column: 0, index: 0 }, end: { line: 1, column: 10, index: 10 } },
+    metadata: { isPackage: true },
+  } as any;
+}
+
+describe("detectCycles", () => {
+  let agent: IndexerAgent;
+  let root: string;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    root = mkdtempSync(join(tmpdir(), "detect-cycles-"));
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    rmSync(root, { recursive: true, force: true });
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("finds strongly connected components in sorted order", () => {
+    const graph: Record<string, string[]> = { a: ["b"], b: ["c"], c: ["a"], d: ["d", "a"], e: ["f"], f: ["e"] };
+    const components = stronglyConnectedComponents(Object.keys(graph), (n) => graph[n] ?? []);
+    expect(components.filter((c) => c.length > 1)).toEqual([
+      ["a", "b", "c"],
+      ["e", "f"],
+    ]);
+  });
+
+  it("reports TS file cycles with the imports that form them, and collapses to directories", async () => {
+    await agent.indexEntities([importOf("./b.js", 1)], join(root, "src/a.ts"));
+    await agent.indexEntities([importOf("./c", 2)], join(root, "src/b.ts"));
+    await agent.indexEntities([importOf("./a.js", 3), importOf("../lib/util.js", 4)], join(root, "src/c.ts"));
+    await agent.indexEntities([importOf("../src/a.js", 1), importOf("lodash", 2)], join(root, "lib/util.ts"));
+
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    const byFile = await detectCycles(storage);
+    expect(byFile.hasCycles).toBe(true);
+    expect(byFile.cycles).toHaveLength(1);
+    const [cycle] = byFile.cycles;
+    expect(cycle?.members).toHaveLength(4);
+    expect(cycle?.modules).toEqual(["lib/util.ts", "src/a.ts", "src/b.ts", "src/c.ts"].map((p) => join(root, p)));
+    expect(cycle?.edges[3]).toEqual({
+      from: join(root, "src/c.ts"),
+      to: join(root, "lib/util.ts"),
+      imports: [{ filePath: join(root, "src/c.ts"), line: 4, source: "../lib/util.js" }],
+    });
+
+    const byDir = await detectCycles(storage, { granularity: "directory" });
+    expect(byDir.cycles.map((c) => c.modules)).toEqual([[join(root, "lib"), join(root, "src")]]);
+    expect(await detectCycles(storage, { granularity: "directory" })).toEqual(byDir);
+  });
+
+  it("links Go packages by import path from go.mod", async () => {
+    writeFileSync(join(root, "go.mod"), "module example.com/app\n\ngo 1.22\n");
+    const a = join(root, "a/a.go");
+    const b = join(root, "b/b.go");
+    await agent.indexEntities([goPackage(a, "a")], a, [
+      { from: `${a}:package:a`, to: "example.com/app/b", type: "imports", metadata: { line: 3 } },
+      { from: `${a}:package:a`, to: "fmt", type: "imports", metadata: { line: 4 } },
+    ]);
+    await agent.indexEntities([goPackage(b, "b")], b, [
+      { from: `${b}:package:b`, to: "example.com/app/a", type: "imports", metadata: { line: 5 } },
+    ]);
+
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    const result = await detectCycles(storage);
+    expect(result.cycles).toEqual([
+      {
+        kind: "package",
+        modules: ["example.com/app/a", "example.com/app/b"],
+        members: ["example.com/app/a", "example.com/app/b"],
+        internalEdges: 2,
+        edges: [
+          {
+            from: "example.com/app/a",
+            to: "example.com/app/b",
+            imports: [{ filePath: a, line: 3, source: "example.com/app/b" }],
+          },
+          {
+            from: "example.com/app/b",
+            to: "example.com/app/a",
+            imports: [{ filePath: b, line: 5, source: "example.com/app/a" }],
+          },
+        ],
+      },
+    ]);
+  });
+});
@@ -0,0 +1,135 @@
+import { existsSync, mkdtempSync, rmSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { join } from "node:path";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import type { GraphStorageImpl } from "../../src/storage/graph-storage.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { diffGraph } from "../../src/tools/diff-graph.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+
+const TEST_DB_PATH = "./data/test-tool-diff-graph.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+/** One entity per `function name(...) {...}` in the source, with offsets into it */
+function functionsIn(code: string): ParsedEntity[] {
+  return Array.from(code.matchAll(/function (\w+)(\([^)]*\)) \{[^}]*\}/g), (match) => {
+    const start = match.index ?? 0;
+    const line = code.slice(0, start).split("\n").length;
+    return {
+      name: match[1]!,
+      type: "function",
+      signature: match[2],
+      language: "typescript",
+      location: {
+        start: { line, column: 0, index: start },
+        end: { line, column: match[0].length, index: start + match[0].length },
+      },
+    } as ParsedEntity;
+  });
+}
+
+describe("diffGraph", () => {
+  let agent: IndexerAgent;
+  let storage: GraphStorageImpl;
+  let root: string;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+    storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    root = mkdtempSync(join(tmpdir(), "diff-graph-"));
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    rmSync(root, { recursive: true, force: true });
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  async function index(file: string, code: string, calls: Array<[string, string]> = []) {
+    const path = join(root, file);
+    writeFileSync(path, code);
+    const relationships = calls.map(([from, to]) => ({ from, to, type: "calls" }));
+    await agent.indexEntities(functionsIn(code), path, relationships, { replaceFile: true, rootDir: root });
+    return path;
+  }
+
+  it("reports added, removed and modified entities and relationships per file", async () => {
+    const app = await index(
+      "app.ts",
+      "function main() { run(); }\nfunction run() { return 1; }\nfunction old() { return 0; }\n",
+      [["main", "run"]],
+    );
+    const util = await index("util.ts", "function pad(s) { return s; }\n");
+    await storage.createSnapshot("base");
+
+    // run's body changes, pad's signature changes, old disappears, helper and main → helper appear
+    await index(
+      "app.ts",
+      "function main() { run(); helper(); }\nfunction run() { return 2; }\nfunction helper() { return 3; }\n",
+      [
+        ["main", "run"],
+        ["main", "helper"],
+      ],
+    );
+    await index("util.ts", "function pad(s, n) { return s; }\n");
+    await storage.createSnapshot("head");
+
+    const result = await diffGraph(storage, { from: "base", to: "head" });
+
+    expect(result.summary).toEqual({
+      entitiesAdded: 1,
+      entitiesRemoved: 1,
+      entitiesModified: 3,
+      relationshipsAdded: 1,
+      relationshipsRemoved: 0,
+      filesChanged: 2,
+    });
+    expect(result.files.map((f) => f.filePath)).toEqual([app, util]);
+
+    const [appDiff, utilDiff] = result.files;
+    expect(appDiff!.added.map((e) => e.name)).toEqual(["helper"]);
+    expect(appDiff!.removed.map((e) => e.name)).toEqual(["old"]);
+    expect(appDiff!.modified.map((m) => [m.after.name, m.changes])).toEqual([
+      ["main", ["body"]],
+      ["run", ["body"]],
+    ]);
+    expect(appDiff!.addedRelationships.map((r) => [r.from.name, r.type, r.to.name])).toEqual([
+      ["main", "calls", "helper"],
+    ]);
+    expect(utilDiff!.modified).toEqual([
+      expect.objectContaining({ changes: ["signature", "body"], before: expect.objectContaining({ signature: "(s)" }) }),
+    ]);
+  });
+
+  it("compares a snapshot against the live index and keeps snapshots independent of it", async () => {
+    await index("a.ts", "function one() { return 1; }\n");
+    const snapshot = await storage.createSnapshot("v1", { description: "before" });
+    expect(snapshot).toEqual(expect.objectContaining({ label: "v1", entityCount: 1, description: "before" }));
+    await expect(storage.createSnapshot("v1")).rejects.toThrow("already exists");
+
+    await index("a.ts", "\n\nfunction one() { return 1; }\nfunction two() { return 2; }\n");
+    const live = await diffGraph(storage, { from: "v1" });
+
+    // `one` moved down two lines but kept its id and body, so it is not reported
+    expect(live.to).toBeNull();
+    expect(live.summary.entitiesModified).toBe(0);
+    expect(live.files.flatMap((f) => f.added.map((e) => e.name))).toEqual(["two"]);
+    expect((await storage.getSnapshot("v1"))?.entityCount).toBe(1);
+
+    expect(await storage.deleteSnapshot("v1")).toBe(true);
+    expect(await storage.listSnapshots()).toEqual([]);
+  });
+});
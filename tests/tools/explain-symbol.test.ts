@@ -0,0 +1,119 @@
+import { existsSync, mkdtempSync, rmSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { join } from "node:path";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { explainSymbol } from "../../src/tools/explain-symbol.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+
+const TEST_DB_PATH = "./data/test-tool-explain-symbol.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+const SOURCE = `/** Formats a price for display */
+function format(cents: number): string {
+  return pad(cents);
+}
+
+function pad(value: number): string {
+  return String(value);
+}
+
+function render() {
+  return format(1);
+}
+`;
+
+function fn(name: string, start: number, end: number, extra?: Partial<ParsedEntity>): ParsedEntity {
+  return {
+    name,
+    type: "function",
+    location: { start: { line: start, column: 0, index: start * 10 }, end: { line: end, column: 0, index: end * 10 } },
+    ...extra,
+  } as ParsedEntity;
+}
+
+describe("explainSymbol", () => {
+  let agent: IndexerAgent;
+  let dir: string;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+    dir = mkdtempSync(join(tmpdir(), "cgr-explain-"));
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    rmSync(dir, { recursive: true, force: true });
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("bundles the declaration, documentation, source, callers, callees and similar entities", async () => {
+    const file = join(dir, "price.ts");
+    writeFileSync(file, SOURCE);
+    await agent.indexEntities(
+      [
+        fn("format", 2, 4, {
+          signature: "format(cents: number): string",
+          returnType: "string",
+          documentation: "/** Formats a price for display */",
+          calls: [{ name: "pad", line: 3, column: 9 }],
+        }),
+        fn("pad", 6, 8),
+        fn("render", 10, 12, { calls: [{ name: "format", line: 11, column: 9 }] }),
+      ],
+      file,
+    );
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    const pad = (await storage.findEntities({ type: "entity", filters: { name: "pad" } }))[0]!;
+
+    const result = await explainSymbol(storage, {
+      symbol: "format",
+      findSimilar: async () => [{ entity: pad, score: 0.8 }],
+    });
+
+    expect(result?.symbol).toMatchObject({
+      name: "format",
+      type: "function",
+      signature: "format(cents: number): string",
+      returnType: "string",
+      startLine: 2,
+    });
+    expect(result?.documentation).toBe("/** Formats a price for display */");
+    expect(result?.source?.snippet).toContain("return pad(cents);");
+    expect(result?.callers).toMatchObject({ total: 1, truncated: false });
+    expect(result?.callers.items.map((call) => call.name)).toEqual(["render"]);
+    expect(result?.callees.items.map((call) => call.name)).toEqual(["pad"]);
+    expect(result?.similar).toEqual([expect.objectContaining({ name: "pad", score: 0.8 })]);
+    expect(result?.alternatives).toEqual([]);
+  });
+
+  it("explains the declaration nearest the given file and lists the others", async () => {
+    const near = join(dir, "a", "util.ts");
+    const far = join(dir, "b", "util.ts");
+    await agent.indexEntities([fn("helper", 1, 3)], near);
+    await agent.indexEntities([fn("helper", 1, 3)], far);
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    const result = await explainSymbol(storage, { symbol: "helper", filePath: far });
+    expect(result?.symbol.filePath).toBe(far);
+    expect(result?.alternatives.map((alt) => alt.filePath)).toEqual([near]);
+    // The files were never written and no similarity search was offered
+    expect(result?.source).toBeNull();
+    expect(result?.similar).toBeNull();
+
+    expect(await explainSymbol(storage, { symbol: "missing" })).toBeNull();
+  });
+});
@@ -0,0 +1,196 @@
+import { existsSync, mkdtempSync, readFileSync, rmSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { join } from "node:path";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { resolveCrossFileImports } from "../../src/core/cross-file-resolver.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import {
+  escapeXml,
+  exportCypher,
+  exportGraph,
+  exportGraphML,
+  type GraphExportProgress,
+} from "../../src/tools/export-graph.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+
+const TEST_DB_PATH = "./data/test-tool-export-graph.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function entity(name: string, line: number, extra: Partial<ParsedEntity> = {}): ParsedEntity {
+  return {
+    name,
+    type: "function",
+    location: {
+      start: { line, column: 0, index: line * 10 },
+      end: { line: line + 2, column: 0, index: line * 10 + 5 },
+    },
+    ...extra,
+  } as any;
+}
+
+describe("exportGraphML", () => {
+  let agent: IndexerAgent;
+  let outDir: string;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+    outDir = mkdtempSync(join(tmpdir(), "cgr-export-"));
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    rmSync(outDir, { recursive: true, force: true });
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("writes nodes with attributes and typed edges, identically on re-export", async () => {
+    await agent.indexEntities(
+      [entity("main", 1, { references: ["helper"] }), entity("helper", 10)],
+      "/tmp/app&co.ts",
+    );
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    const first = join(outDir, "graph.graphml");
+    const second = join(outDir, "again.graphml");
+    const result = await exportGraphML(storage, first);
+    await exportGraphML(storage, second);
+
+    const xml = readFileSync(first, "utf8");
+    expect(result.nodes).toBeGreaterThanOrEqual(2);
+    expect(result.bytes).toBe(Buffer.byteLength(xml));
+    expect(xml.startsWith('<?xml version="1.0" encoding="UTF-8"?>')).toBe(true);
+    expect(xml).toContain('<key id="n_kind" for="node" attr.name="kind" attr.type="string"/>');
+    expect(xml).toContain('<data key="n_name">helper</data>');
+    expect(xml).toContain('<data key="n_file">/tmp/app&amp;co.ts</data>');
+    expect(xml).toContain('<data key="n_start_line">10</data>');
+    expect(xml.trimEnd().endsWith("</graphml>")).toBe(true);
+    expect(readFileSync(second, "utf8")).toBe(xml);
+    expect(existsSync(`${first}.${process.pid}.tmp`)).toBe(false);
+  });
+
+  it("emits batched, idempotent Cypher keyed on qualified name", async () => {
+    await agent.indexEntities(
+      [
+        entity("main", 1, { references: ["helper"] }),
+        entity("helper", 10),
+        entity("dup", 20, { type: "class" } as any),
+        entity("dup", 30, { type: "class" } as any),
+      ],
+      "/tmp/app.ts",
+    );
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    const out = join(outDir, "graph.cypher");
+    const result = await exportCypher(storage, out);
+    const cypher = readFileSync(out, "utf8");
+
+    expect(result).toMatchObject({ format: "cypher", nodes: 4 });
+    expect(cypher).toContain("REQUIRE n.qualifiedName IS UNIQUE;");
+    expect(cypher).toContain("MERGE (n:CodeEntity {qualifiedName: row.qualifiedName})\nSET n:`Function`, n += row;");
+    expect(cypher).toContain("SET n:`Class`, n += row;");
+    // One UNWIND per label, not one statement per node
+    expect(cypher.match(/MERGE \(n:CodeEntity/g)).toHaveLength(2);
+    expect(cypher).toContain('qualifiedName: "/tmp/app.ts::helper"');
+    expect(cypher).toContain('qualifiedName: "/tmp/app.ts::dup@20:0"');
+    expect(cypher).toContain('qualifiedName: "/tmp/app.ts::dup@30:0"');
+    if (result.edges > 0) {
+      expect(cypher).toContain("MERGE (a)-[r:`CALLS`]->(b)");
+    }
+  });
+
+  it("writes DOT and one-document JSON, reporting progress at the end of each phase", async () => {
+    await agent.indexEntities([entity("main", 1, { references: ["helper"] }), entity('say "hi"', 10)], "/tmp/app.ts");
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    const progress: GraphExportProgress[] = [];
+    const json = await exportGraph(storage, "json", join(outDir, "graph.json"), {
+      onProgress: (p) => progress.push(p),
+    });
+    const document = JSON.parse(readFileSync(join(outDir, "graph.json"), "utf8"));
+    expect(document.nodes).toHaveLength(json.nodes);
+    expect(document.edges).toHaveLength(json.edges);
+    expect(document.nodes.find((n: any) => n.name === "main")).toMatchObject({
+      kind: "function",
+      file: "/tmp/app.ts",
+      startLine: 1,
+      endLine: 3,
+    });
+    expect(progress.map((p) => p.phase)).toEqual(["nodes", "edges"]);
+    expect(progress[1]).toMatchObject({
+      nodes: json.nodes,
+      edges: json.edges,
+      totalNodes: json.nodes,
+      totalEdges: json.edges,
+    });
+
+    const dot = await exportGraph(storage, "dot", join(outDir, "graph.dot"));
+    const text = readFileSync(join(outDir, "graph.dot"), "utf8");
+    expect(dot).toMatchObject({ format: "dot", nodes: json.nodes, edges: json.edges });
+    expect(text.startsWith('digraph "code-graph" {')).toBe(true);
+    expect(text).toContain('label="say \\"hi\\"", kind="function", file="/tmp/app.ts", startLine=10');
+    expect(text.match(/ -> /g) ?? []).toHaveLength(json.edges);
+  });
+
+  it("exports byte-identical graphs whatever order files were indexed and resolved in", async () => {
+    const util = join(outDir, "util.ts");
+    const app = join(outDir, "app.ts");
+    writeFileSync(util, "export function format() {}\nexport function parse() {}\n");
+    writeFileSync(app, "import { format } from './util.js';\n");
+    const parsed = (file: string): ParsedEntity[] =>
+      file === util
+        ? [entity("format", 1), entity("parse", 5, { calls: [{ name: "format", line: 6, column: 2 }] })]
+        : [
+            entity("./util.js", 1, {
+              type: "import",
+              importData: { source: "./util.js", specifiers: [{ local: "format", imported: "format" }] },
+            } as any),
+            entity("main", 3, { calls: [{ name: "format", line: 4, column: 2 }], references: ["helper"] }),
+            entity("helper", 10),
+          ];
+
+    // Each file is resolved as it is indexed, the way incremental runs and watchers do it
+    const exportAfter = async (order: string[], name: string) => {
+      if (agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+      for (const p of CLEANUP_PATHS) {
+        if (existsSync(p)) rmSync(p);
+      }
+      resetGraphStorage();
+      resetSQLiteManager();
+      agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+      await agent.initialize();
+      const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+      for (const file of order) {
+        await agent.indexEntities(parsed(file), file);
+        await resolveCrossFileImports(storage, [file]);
+      }
+      await exportGraphML(storage, join(outDir, `${name}.graphml`));
+      await exportCypher(storage, join(outDir, `${name}.cypher`));
+      return [readFileSync(join(outDir, `${name}.graphml`)), readFileSync(join(outDir, `${name}.cypher`))];
+    };
+
+    const [graphml, cypher] = await exportAfter([util, app], "forward");
+    const [reversedGraphml, reversedCypher] = await exportAfter([app, util], "reversed");
+
+    expect(reversedGraphml?.equals(graphml!)).toBe(true);
+    expect(reversedCypher?.equals(cypher!)).toBe(true);
+    // The call from app.ts reached format() in both runs
+    expect(graphml?.toString("utf8").match(/<data key="e_type">calls<\/data>/g)).toHaveLength(2);
+  });
+
+  it("escapes markup and drops characters XML cannot represent", () => {
+    expect(escapeXml('a<b> & "c"\u0001')).toBe("a&lt;b&gt; &amp; &quot;c&quot;");
+  });
+});
@@ -0,0 +1,147 @@
+import { existsSync, mkdtempSync, rmSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { join } from "node:path";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import type { GraphStorageImpl } from "../../src/storage/graph-storage.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { fileSummary, leadingFileComment } from "../../src/tools/file-summary.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+import { RelationType } from "../../src/types/storage.js";
+
+const TEST_DB_PATH = "./data/test-tool-file-summary.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+const CART_SOURCE = `// Copyright 2024 Shop Inc.
+
+/**
+ * Shopping cart helpers.
+ */
+
+import { Money, round } from "./money";
+import lodash from "lodash";
+
+export class Cart {
+  private items: string[] = [];
+  add(item: string) {}
+}
+
+export function total(cart: Cart): Money {
+  return round(0);
+}
+
+function helper() {}
+`;
+
+function e(name: string, start: number, end: number, type: string, extra: Partial<ParsedEntity> = {}): ParsedEntity {
+  return {
+    name,
+    type,
+    location: {
+      start: { line: start, column: 0, index: start * 10 },
+      end: { line: end, column: 0, index: end * 10 + 5 },
+    },
+    ...extra,
+  } as any;
+}
+
+describe("fileSummary", () => {
+  let agent: IndexerAgent;
+  let storage: GraphStorageImpl;
+  let dir: string;
+  let cartPath: string;
+  let moneyPath: string;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    dir = mkdtempSync(join(tmpdir(), "file-summary-"));
+    cartPath = join(dir, "cart.ts");
+    moneyPath = join(dir, "money.ts");
+    writeFileSync(cartPath, CART_SOURCE);
+    writeFileSync(moneyPath, "export type Money = number;\n");
+
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+    await agent.indexEntities([e("Money", 1, 1, "type", { modifiers: ["export"] })], moneyPath);
+    await agent.indexEntities(
+      [
+        e("./money", 7, 7, "import", {
+          importData: {
+            source: "./money",
+            specifiers: [
+              { local: "Money", imported: "Money" },
+              { local: "round", imported: "round" },
+            ],
+          },
+        } as any),
+        e("lodash", 8, 8, "import", { importData: { source: "lodash", specifiers: [], local: "lodash" } } as any),
+        e("Cart", 10, 13, "class", { modifiers: ["export"] }),
+        e("items", 11, 11, "property", { modifiers: ["private"] }),
+        e("add", 12, 12, "method"),
+        e("total", 15, 17, "function", {
+          modifiers: ["export"],
+          parameters: [{ name: "cart", type: "Cart" }],
+          returnType: "Money",
+        }),
+        e("helper", 19, 19, "function"),
+      ],
+      cartPath,
+    );
+    storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    const find = (filePath: string, name: string) =>
+      storage.findEntities({ type: "entity", filters: { filePath, name } });
+    const [moneyImport] = await find(cartPath, "./money");
+    const [money] = await find(moneyPath, "Money");
+    await storage.insertRelationships([
+      {
+        id: "cart-money",
+        fromId: moneyImport!.id,
+        toId: money!.id,
+        type: RelationType.IMPORTS,
+        metadata: { line: 7, resolvedFile: moneyPath },
+      },
+    ]);
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    rmSync(dir, { recursive: true, force: true });
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("lists imports, exported top-level declarations and private counts", async () => {
+    const summary = await fileSummary(storage, cartPath);
+
+    expect(summary?.documentation).toBe("/**\n * Shopping cart helpers.\n */");
+    expect(summary?.imports).toEqual([
+      { source: "./money", names: ["Money", "round"], line: 7, resolved: [moneyPath] },
+      { source: "lodash", names: ["lodash"], line: 8, resolved: [] },
+    ]);
+    expect(summary?.exports.map((entry) => [entry.name, entry.type, entry.signature])).toEqual([
+      ["Cart", "class", "export class Cart"],
+      ["total", "function", "total(cart: Cart): Money"],
+    ]);
+    // helper is not exported; only the private field counts among members, `add` is public
+    expect(summary?.private).toEqual({ topLevel: 1, members: 1 });
+    expect(await fileSummary(storage, join(dir, "missing.ts"))).toBeNull();
+  });
+
+  it("reads the comment block opening a file", () => {
+    expect(leadingFileComment("/** Adds numbers */\nexport function add() {}\n")).toBeNull();
+    expect(leadingFileComment('#!/usr/bin/env python\n"""Tools."""\nimport os\n')).toBe('"""Tools."""');
+    expect(leadingFileComment("// SPDX-License-Identifier: MIT\n\npackage main\n")).toBeNull();
+    expect(leadingFileComment("// Package helpers.\n// More.\n\nconst x = 1;\n")).toBe(
+      "// Package helpers.\n// More.",
+    );
+  });
+});
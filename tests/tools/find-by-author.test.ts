@@ -0,0 +1,102 @@
+import { existsSync, rmSync } from "node:fs";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { findByAuthor } from "../../src/tools/find-by-author.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+import type { BlameLine } from "../../src/utils/git-blame.js";
+
+const TEST_DB_PATH = "./data/test-tool-find-by-author.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function fn(name: string, start: number, end: number): ParsedEntity {
+  return {
+    name,
+    type: "function",
+    location: {
+      start: { line: start, column: 0, index: start * 10 },
+      end: { line: end, column: 0, index: end * 10 },
+    },
+  };
+}
+
+function blame(author: string, time: number, commit = author[0]!.repeat(40)): BlameLine {
+  return { commit, author, email: `${author.toLowerCase()}@example.com`, time, summary: `${author}'s change` };
+}
+
+describe("findByAuthor", () => {
+  let agent: IndexerAgent;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  async function indexWithBlame() {
+    const ada = blame("Ada", 1_700_000_000);
+    const grace = blame("Grace", 1_710_000_000);
+    // Lines 1-3 by Ada, 4-5 by Grace, 6 by Ada
+    await agent.indexEntities([fn("parse", 1, 3), fn("render", 2, 5), fn("close", 6, 6)], "/tmp/app/src/ui.ts", [], {
+      blame: [ada, ada, ada, grace, grace, ada],
+    });
+    await agent.indexEntities([fn("helper", 1, 2)], "/tmp/app/lib/util.ts", [], { blame: [grace, grace] });
+    await agent.indexEntities([fn("untracked", 1, 2)], "/tmp/app/lib/new.ts");
+    return getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+  }
+
+  it("attributes each entity to the newest commit among its lines", async () => {
+    const storage = await indexWithBlame();
+    const result = await findByAuthor(storage, { author: "grace" });
+
+    expect(result.entities.map((e) => e.name).sort()).toEqual(["helper", "render"]);
+    expect(result.entities[0]!.lastModified).toMatchObject({ author: "Grace", email: "grace@example.com" });
+    expect(result.authors).toEqual([
+      { author: "Grace", email: "grace@example.com", entities: 2, lastDate: new Date(1_710_000_000_000).toISOString() },
+    ]);
+  });
+
+  it("matches the exact email and narrows by directory and date", async () => {
+    const storage = await indexWithBlame();
+
+    const byEmail = await findByAuthor(storage, { author: "ADA@example.com" });
+    expect(byEmail.entities.map((e) => e.name).sort()).toEqual(["close", "parse"]);
+
+    const inLib = await findByAuthor(storage, { author: "grace", pathPrefix: "/tmp/app/lib" });
+    expect(inLib.entities.map((e) => e.name)).toEqual(["helper"]);
+
+    const recent = await findByAuthor(storage, { since: new Date(1_705_000_000_000) });
+    expect(recent.entities.map((e) => e.name).sort()).toEqual(["helper", "render"]);
+  });
+
+  it("summarises every author and sorts by author or age", async () => {
+    const storage = await indexWithBlame();
+
+    const all = await findByAuthor(storage, { sort: "author" });
+    expect(all.blamed).toBe(4);
+    expect(all.entities.map((e) => e.lastModified.author)).toEqual(["Ada", "Ada", "Grace", "Grace"]);
+    expect(all.authors.map((a) => [a.author, a.entities])).toEqual([
+      ["Ada", 2],
+      ["Grace", 2],
+    ]);
+
+    const oldest = await findByAuthor(storage, { sort: "oldest", limit: 1 });
+    expect(oldest.entities[0]!.lastModified.author).toBe("Ada");
+    expect(oldest.truncated).toBe(true);
+  });
+});
@@ -0,0 +1,128 @@
+import { existsSync, rmSync } from "node:fs";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import {
+  findBySignature,
+  normalizeSignatureType,
+  parseSignaturePattern,
+  type SignaturePattern,
+} from "../../src/tools/find-by-signature.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+
+const TEST_DB_PATH = "./data/test-tool-find-by-signature.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function fn(name: string, line: number, extra: Partial<ParsedEntity>): ParsedEntity {
+  return {
+    name,
+    type: "function",
+    location: {
+      start: { line, column: 0, index: line * 10 },
+      end: { line: line + 3, column: 0, index: line * 10 + 5 },
+    },
+    ...extra,
+  } as any;
+}
+
+describe("parseSignaturePattern", () => {
+  it("reads Go, arrow and Rust shapes into normalized positions", () => {
+    expect(parseSignaturePattern("func(int) (*models.User, error)")).toEqual({
+      parameters: ["int"],
+      returns: ["*User", "error"],
+    });
+    expect(parseSignaturePattern("(id: string, *) => Promise<db.User>")).toEqual({
+      parameters: ["string", "*"],
+      returns: ["Promise<User>"],
+    });
+    expect(parseSignaturePattern("fn(&str, ...) -> ()")).toEqual({ parameters: ["&str", "..."], returns: [] });
+    expect(parseSignaturePattern("func(map[string]int)")).toEqual({ parameters: ["map[string]int"], returns: null });
+    expect(parseSignaturePattern("User")).toBeNull();
+  });
+
+  it("strips qualifiers wherever they appear in a type", () => {
+    expect(normalizeSignatureType("map[string]*m.User")).toBe("map[string]*User");
+    expect(normalizeSignatureType("java.util.List<com.shop.Item>")).toBe("List<Item>");
+    expect(normalizeSignatureType("std::string")).toBe("string");
+    expect(normalizeSignatureType("interface {}")).toBe("any");
+  });
+});
+
+describe("findBySignature", () => {
+  let agent: IndexerAgent;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+
+    await agent.indexEntities(
+      [
+        fn("LoadUser", 1, { metadata: { parameters: ["id: int"], returnType: "(*models.User, error)" } } as any),
+        fn("FindUser", 10, {
+          type: "method",
+          metadata: {
+            receiver: "Repo",
+            parameters: ["ctx: context.Context", "id: int"],
+            returnType: "(u *User, err error)",
+          },
+        } as any),
+        fn("Save", 20, { metadata: { parameters: ["u: *User"], returnType: "error" } } as any),
+        fn("Reset", 30, { metadata: { parameters: [] } } as any),
+      ],
+      "/tmp/sig/users.go",
+    );
+    await agent.indexEntities(
+      [fn("fetchUser", 1, { parameters: [{ name: "id", type: "number" }], returnType: "Promise<api.User>" })],
+      "/tmp/sig/api.ts",
+    );
+    await agent.indexEntities([fn("load_user", 1, { parameters: [{ name: "user_id" }] })], "/tmp/sig/users.py");
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  const find = async (signature: string, options: { requireTypes?: boolean } = {}) => {
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    const pattern = parseSignaturePattern(signature) as SignaturePattern;
+    return (await findBySignature(storage, { pattern, ...options })).matches.map((m) => [m.name, m.partial]);
+  };
+
+  it("matches result types regardless of package qualifiers and result names", async () => {
+    expect(await find("func(int) (*User, error)")).toEqual([
+      ["LoadUser", false],
+      ["load_user", true],
+    ]);
+    expect(await find("func(int) (*User, error)", { requireTypes: true })).toEqual([["LoadUser", false]]);
+    expect(await find("func(*, int) (*User, *)")).toEqual([["FindUser", false]]);
+    expect(await find("(number) => Promise<User>")).toEqual([
+      ["fetchUser", false],
+      ["load_user", true],
+    ]);
+  });
+
+  it("treats a missing Go result as no result and ... as any parameters", async () => {
+    expect(await find("func() ()")).toEqual([["Reset", false]]);
+    expect(await find("func(...) error", { requireTypes: true })).toEqual([["Save", false]]);
+    expect((await find("func(...)")).map(([name]) => name)).toEqual([
+      "fetchUser",
+      "LoadUser",
+      "FindUser",
+      "Save",
+      "Reset",
+      "load_user",
+    ]);
+  });
+});
@@ -0,0 +1,48 @@
+import { describe, expect, it } from "@jest/globals";
+import { rankDefinitionsByProximity } from "../../src/tools/find-definition.js";
+import type { Entity } from "../../src/types/storage.js";
+import { EntityType } from "../../src/types/storage.js";
+
+function entity(id: string, filePath: string, type: EntityType = EntityType.FUNCTION): Entity {
+  return {
+    id,
+    name: "Config",
+    type,
+    filePath,
+    location: {
+      start: { line: 3, column: 0, index: 30 },
+      end: { line: 9, column: 0, index: 90 },
+    },
+    metadata: {},
+    hash: "h",
+    createdAt: 0,
+    updatedAt: 0,
+  };
+}
+
+describe("rankDefinitionsByProximity", () => {
+  it("prefers the same file, then the same directory, then shared path depth", () => {
+    const ranked = rankDefinitionsByProximity(
+      [
+        entity("far", "/repo/vendor/lib/config.ts"),
+        entity("sibling", "/repo/src/server/config.ts"),
+        entity("same", "/repo/src/server/app.ts"),
+        entity("cousin", "/repo/src/client/config.ts"),
+      ],
+      "/repo/src/server/app.ts",
+    );
+
+    expect(ranked.map((c) => c.entity.id)).toEqual(["same", "sibling", "cousin", "far"]);
+    expect(ranked[0]?.reasons).toContain("same_file");
+    expect(ranked[0]?.range).toEqual({ startLine: 3, endLine: 9 });
+  });
+
+  it("ranks declarations above imports without file context", () => {
+    const ranked = rankDefinitionsByProximity([
+      entity("imp", "/repo/a.ts", EntityType.IMPORT),
+      entity("decl", "/repo/b.ts", EntityType.CLASS),
+    ]);
+
+    expect(ranked[0]?.entity.id).toBe("decl");
+  });
+});
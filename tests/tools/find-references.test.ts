@@ -0,0 +1,140 @@
+import { existsSync, rmSync } from "node:fs";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { findReferences, splitQualifiedSymbol } from "../../src/tools/find-references.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+
+const TEST_DB_PATH = "./data/test-tool-find-references.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function e(name: string, line: number, metadata?: Record<string, unknown>): ParsedEntity {
+  return {
+    name,
+    type: "function",
+    location: {
+      start: { line, column: 0, index: line * 10 },
+      end: { line: line + 2, column: 0, index: line * 10 + 5 },
+    },
+    metadata,
+  } as any;
+}
+
+describe("findReferences", () => {
+  let agent: IndexerAgent;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    const sqlite = getSQLiteManager({ path: TEST_DB_PATH });
+    agent = new IndexerAgent(sqlite);
+    await agent.initialize();
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("splits qualified symbols", () => {
+    expect(splitQualifiedSymbol("net::http::Client")).toEqual({ name: "Client", qualifier: "net::http" });
+    expect(splitQualifiedSymbol("users.Get")).toEqual({ name: "Get", qualifier: "users" });
+    expect(splitQualifiedSymbol("plain")).toEqual({ name: "plain", qualifier: null });
+  });
+
+  it("returns call sites grouped per definition", async () => {
+    await agent.indexEntities([e("format", 1), e("render", 10)], "/tmp/a.ts", [
+      { from: "render", to: "format", type: "calls", metadata: { line: 11 } },
+    ]);
+    await agent.indexEntities([e("format", 1)], "/tmp/b.ts");
+
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    const result = await findReferences(storage, { symbol: "format" });
+
+    expect(result.definitions).toHaveLength(2);
+    const inA = result.definitions.find((d) => d.definition.filePath === "/tmp/a.ts");
+    expect(inA?.references).toHaveLength(1);
+    expect(inA?.references[0]?.kind).toBe("call");
+    expect(inA?.references[0]?.from?.name).toBe("render");
+    expect(inA?.references[0]?.range).toEqual({ startLine: 11, endLine: 11 });
+    expect(inA?.references[0]?.edge).toMatchObject({
+      from: inA?.references[0]?.fromId,
+      to: inA?.definition.id,
+      type: "calls",
+      direction: "incoming",
+      file: "/tmp/a.ts",
+      line: 11,
+    });
+
+    const narrowed = await findReferences(storage, { symbol: "format", filePath: "/tmp/b.ts" });
+    expect(narrowed.definitions).toHaveLength(1);
+    expect(narrowed.definitions[0]?.references).toHaveLength(0);
+  });
+
+  it("reports by-name cross-file references as unresolved", async () => {
+    await agent.indexEntities([e("main", 1)], "/tmp/main.ts", [
+      { from: "main", to: "helper", type: "calls", targetFile: "/tmp/util.ts", metadata: { line: 2 } },
+    ]);
+
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    const result = await findReferences(storage, { symbol: "helper" });
+
+    expect(result.definitions).toHaveLength(0);
+    expect(result.unresolved).toHaveLength(1);
+    expect(result.unresolved[0]?.resolved).toBe(false);
+    expect(result.unresolved[0]?.filePath).toBe("/tmp/main.ts");
+  });
+
+  it("reports reads and writes of class fields and module-level variables", async () => {
+    const at = (start: number, end: number) => ({
+      start: { line: start, column: 0, index: start * 10 },
+      end: { line: end, column: 0, index: end * 10 },
+    });
+    const entities = [
+      { name: "count", type: "variable", location: at(1, 1) },
+      { name: "Cart", type: "class", location: at(3, 12) },
+      { name: "items", type: "property", location: at(4, 4) },
+      {
+        name: "add",
+        type: "method",
+        location: at(6, 11),
+        accesses: [
+          { name: "items", qualifier: "this", line: 7, column: 4, access: "read" },
+          { name: "items", qualifier: "this", line: 8, column: 4, access: "write" },
+          { name: "count", line: 9, column: 4, access: "readwrite" },
+          // Not a field of the class, nor a module-level variable
+          { name: "console", line: 10, column: 4, access: "read" },
+        ],
+      },
+    ] as ParsedEntity[];
+    await agent.indexEntities(entities, "/tmp/cart.ts");
+
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    const items = await findReferences(storage, { symbol: "items" });
+    expect(items.definitions[0]?.references).toEqual([
+      expect.objectContaining({
+        kind: "access",
+        access: "readwrite",
+        accessSites: [
+          { line: 7, column: 4, access: "read", name: "this.items" },
+          { line: 8, column: 4, access: "write", name: "this.items" },
+        ],
+      }),
+    ]);
+
+    const writes = await findReferences(storage, { symbol: "count", access: "write" });
+    expect(writes.definitions[0]?.references.map((ref) => [ref.from?.name, ref.access])).toEqual([
+      ["add", "readwrite"],
+    ]);
+    expect((await findReferences(storage, { symbol: "console" })).definitions).toHaveLength(0);
+  });
+});
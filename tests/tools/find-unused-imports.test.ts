@@ -0,0 +1,139 @@
+import { existsSync, mkdtempSync, rmSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { join } from "node:path";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { goPackageName } from "../../src/core/go-modules.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { findUnusedImports } from "../../src/tools/find-unused-imports.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+
+const TEST_DB_PATH = "./data/test-tool-find-unused-imports.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function at(line: number, lines = 1) {
+  return {
+    start: { line, column: 0, index: line * 10 },
+    end: { line: line + lines - 1, column: 0, index: line * 10 + 5 },
+  };
+}
+
+function imp(line: number, importData: Record<string, unknown>): ParsedEntity {
+  return { name: String(importData.source), type: "import", location: at(line), importData } as any;
+}
+
+function fn(name: string, line: number, extra: Record<string, unknown> = {}): ParsedEntity {
+  return { name, type: "function", location: at(line, 3), ...extra } as any;
+}
+
+describe("findUnusedImports", () => {
+  let agent: IndexerAgent;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("reports TS names without uses and never side-effect imports", async () => {
+    await agent.indexEntities(
+      [
+        imp(1, {
+          source: "react",
+          specifiers: [
+            { local: "useState", imported: "useState" },
+            { local: "useEffect", imported: "useEffect" },
+          ],
+        }),
+        imp(2, { source: "node:path", specifiers: [], isDefault: true, local: "path" }),
+        imp(3, { source: "node:fs", specifiers: [], isDefault: true, isNamespace: true, namespace: "fs" }),
+        imp(4, { source: "./styles.css", specifiers: [], isDefault: false }),
+        fn("render", 10, {
+          calls: [
+            { name: "useState", line: 11, column: 2 },
+            { name: "join", qualifier: "path", line: 12, column: 2 },
+          ],
+        }),
+      ],
+      "/tmp/app/src/page.ts",
+    );
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    const result = await findUnusedImports(storage, { pathPrefix: "/tmp/app", checkSource: false });
+
+    expect(result.unused.map((u) => [u.name, u.import, u.kind, u.line])).toEqual([
+      ["useEffect", "react", "named", 1],
+      ["fs", "node:fs", "namespace", 3],
+    ]);
+    expect(result).toMatchObject({ files: 1, checked: 4, sideEffect: 1, total: 2, truncated: false });
+  });
+
+  it("checks Go packages by alias or package name and skips blank imports", async () => {
+    const goImport = (to: string, line: number, alias?: string) => ({
+      from: "main",
+      to,
+      type: "imports",
+      metadata: { importType: "package", line, ...(alias ? { alias } : {}) },
+    });
+    await agent.indexEntities([fn("main", 6)], "/tmp/app/cmd/main.go", [
+      goImport("net/http", 2),
+      goImport("github.com/lib/pq", 3, "_"),
+      goImport("gopkg.in/yaml.v3", 4),
+      { from: "main", to: "Get", type: "calls", metadata: { line: 7, callee: "http.Get", calleeName: "Get" } },
+    ]);
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    const result = await findUnusedImports(storage, { checkSource: false });
+
+    expect(result.unused.map((u) => [u.name, u.import, u.kind])).toEqual([["yaml", "gopkg.in/yaml.v3", "package"]]);
+    expect(result.sideEffect).toBe(1);
+  });
+
+  it("keeps names the source mentions outside its imports", async () => {
+    const dir = mkdtempSync(join(tmpdir(), "cgr-unused-imports-"));
+    const file = join(dir, "types.ts");
+    writeFileSync(file, 'import { Config, Logger } from "./config";\n\nexport function load(c: Config) {}\n');
+    await agent.indexEntities(
+      [
+        imp(1, {
+          source: "./config",
+          specifiers: [
+            { local: "Config", imported: "Config" },
+            { local: "Logger", imported: "Logger" },
+          ],
+        }),
+        fn("load", 3),
+      ],
+      file,
+    );
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    const result = await findUnusedImports(storage, { pathPrefix: dir });
+
+    expect(result.unused.map((u) => u.name)).toEqual(["Logger"]);
+    expect(result.usedOutsideGraph).toBe(1);
+    rmSync(dir, { recursive: true, force: true });
+  });
+
+  it("derives Go package names from import paths", () => {
+    expect(goPackageName("net/http")).toBe("http");
+    expect(goPackageName("github.com/jackc/pgx/v5")).toBe("pgx");
+    expect(goPackageName("gopkg.in/yaml.v3")).toBe("yaml");
+    expect(goPackageName("github.com/mattn/go-sqlite3")).toBe("sqlite3");
+  });
+});
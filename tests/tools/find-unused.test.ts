@@ -0,0 +1,110 @@
+import { existsSync, rmSync } from "node:fs";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { findUnused } from "../../src/tools/find-unused.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+
+const TEST_DB_PATH = "./data/test-tool-find-unused.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function e(name: string, line: number, extra?: Record<string, unknown>): ParsedEntity {
+  return {
+    name,
+    type: "function",
+    location: {
+      start: { line, column: 0, index: line * 10 },
+      end: { line: line + 2, column: 0, index: line * 10 + 5 },
+    },
+    ...extra,
+  } as any;
+}
+
+const goFunc = (name: string, line: number, metadata: Record<string, unknown> = {}) =>
+  e(name, line, { metadata: { isPublic: /^[A-Z]/.test(name), ...metadata } });
+
+describe("findUnused", () => {
+  let agent: IndexerAgent;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  async function indexGoService() {
+    await agent.indexEntities(
+      [
+        goFunc("main", 1),
+        goFunc("init", 5),
+        goFunc("helper", 10),
+        goFunc("orphan", 20),
+        goFunc("Exported", 30),
+        goFunc("serveIndex", 40, { isHttpHandler: true }),
+        goFunc("legacyRoute", 50),
+      ],
+      "/tmp/svc/main.go",
+      [
+        { from: "main", to: "helper", type: "calls", metadata: { line: 2 } },
+        { from: "main", to: "shared", type: "calls", metadata: { line: 3, callee: "shared", calleeName: "shared" } },
+      ],
+    );
+    await agent.indexEntities([goFunc("shared", 1), goFunc("lonely", 10)], "/tmp/svc/util.go");
+    await agent.indexEntities([goFunc("lonely", 1)], "/tmp/other/util.go", [
+      { from: "lonely", to: "lonely2", type: "calls", metadata: { line: 2, callee: "lonely", calleeName: "lonely" } },
+    ]);
+    await agent.indexEntities([goFunc("TestHelper", 1), goFunc("fixture", 10)], "/tmp/svc/main_test.go");
+    return getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+  }
+
+  it("reports unreferenced Go functions and keeps exported names, main/init, tests and handlers", async () => {
+    const storage = await indexGoService();
+
+    const result = await findUnused(storage, { pathPrefix: "/tmp/svc" });
+
+    expect(result.unused.map((u) => `${u.filePath}:${u.name}`)).toEqual([
+      "/tmp/svc/main.go:orphan",
+      "/tmp/svc/main.go:legacyRoute",
+      "/tmp/svc/main_test.go:fixture",
+      "/tmp/svc/util.go:lonely",
+    ]);
+    expect(result.roots).toMatchObject({ exported: 2, main: 2, tests: 0, http_handlers: 1 });
+  });
+
+  it("lets the caller choose the roots", async () => {
+    const storage = await indexGoService();
+
+    const strict = await findUnused(storage, { pathPrefix: "/tmp/svc", roots: ["main"], rootNames: ["legacy*"] });
+    const names = strict.unused.map((u) => u.name);
+    expect(names).toEqual(expect.arrayContaining(["Exported", "serveIndex", "TestHelper", "orphan"]));
+    expect(names).not.toContain("legacyRoute");
+    expect(names).not.toContain("main");
+    expect(strict.roots.names).toBe(1);
+  });
+
+  it("treats by-name references from other files as uses outside Go", async () => {
+    await agent.indexEntities([e("format", 1), e("dead", 10)], "/tmp/web/util.ts");
+    const render = e("render", 1, { calls: [{ name: "format", line: 2, column: 4 }] });
+    await agent.indexEntities([render], "/tmp/web/view.ts");
+
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    const result = await findUnused(storage, { pathPrefix: "/tmp/web", roots: [] });
+
+    expect(result.unused.map((u) => u.name)).toEqual(["dead", "render"]);
+  });
+});
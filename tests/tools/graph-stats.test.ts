@@ -0,0 +1,104 @@
+import { existsSync, rmSync } from "node:fs";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import type { GraphStorageImpl } from "../../src/storage/graph-storage.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { collectGraphStats } from "../../src/tools/graph-stats.js";
+import { type Entity, EntityType, RelationType } from "../../src/types/storage.js";
+
+const TEST_DB_PATH = "./data/test-tool-graph-stats.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function entity(id: string, type: EntityType, filePath: string): Entity {
+  return {
+    id,
+    name: id,
+    type,
+    filePath,
+    location: { start: { line: 1, column: 0 }, end: { line: 2, column: 0 } },
+    hash: "h",
+    createdAt: 1000,
+    updatedAt: 1000,
+  };
+}
+
+describe("collectGraphStats", () => {
+  let storage: GraphStorageImpl;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+  });
+
+  afterEach(() => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("breaks the graph down and counts unresolved edges apart", async () => {
+    await storage.insertEntities([
+      entity("main", EntityType.FUNCTION, "/repo/main.ts"),
+      entity("helper", EntityType.FUNCTION, "/repo/util.py"),
+      entity("Config", EntityType.CLASS, "/repo/main.ts"),
+      entity("react", EntityType.IMPORT, "external://react"),
+    ]);
+    await storage.insertRelationships([
+      { id: "r1", fromId: "main", toId: "Config", type: RelationType.REFERENCES },
+      { id: "r2", fromId: "main", toId: "react", type: RelationType.IMPORTS },
+    ]);
+    // An edge left pointing at an id that was never stored
+    const db = getSQLiteManager({ path: TEST_DB_PATH }).getConnection();
+    db.pragma("foreign_keys = OFF");
+    db.prepare("INSERT INTO relationships (id, from_id, to_id, type) VALUES (?, ?, ?, ?)").run(
+      "r3",
+      "main",
+      "missing",
+      RelationType.CALLS,
+    );
+    db.pragma("foreign_keys = ON");
+    await storage.updateFileInfo({ path: "/repo/main.ts", hash: "f", lastIndexed: 5000, entityCount: 2 });
+    await storage.updateFileInfo({ path: "/repo/util.py", hash: "g", lastIndexed: 4000, entityCount: 1 });
+
+    const stats = await collectGraphStats(storage, {
+      dbPaths: [TEST_DB_PATH, TEST_DB_PATH],
+      countEmbedded: async (ids) => ids.filter((id) => id !== "helper").length,
+    });
+
+    expect(stats.entities).toEqual({
+      total: 3,
+      byType: { function: 2, class: 1 },
+      byLanguage: { typescript: 2, python: 1 },
+      placeholders: 1,
+    });
+    expect(stats.relationships).toEqual({
+      total: 3,
+      byType: { references: 1, imports: 1, calls: 1 },
+      unresolved: { total: 2, external: 1, dangling: 1, byType: { imports: 1, calls: 1 } },
+    });
+    expect(stats.files.total).toBe(2);
+    expect(stats.embeddings).toEqual({ entities: 3, embedded: 2, missing: 1, ratio: 0.667 });
+    expect(stats.lastUpdatedAt).toBe(5000);
+    expect(stats.lastUpdated).toBe(new Date(5000).toISOString());
+    // The same file given twice is measured once
+    expect(stats.disk.files).toHaveLength(1);
+    expect(stats.disk.totalBytes).toBeGreaterThan(0);
+    expect(stats.warnings).toEqual(["1 of 3 entities have no embedding yet"]);
+  });
+
+  it("warns when files were indexed without producing entities", async () => {
+    await storage.updateFileInfo({ path: "/repo/empty.go", hash: "x", lastIndexed: 1, entityCount: 0 });
+
+    const stats = await collectGraphStats(storage, { dbPaths: [TEST_DB_PATH] });
+
+    expect(stats.entities.total).toBe(0);
+    expect(stats.embeddings).toBeNull();
+    expect(stats.warnings).toEqual(["1 files are indexed but no entities were extracted from them"]);
+  });
+});
@@ -0,0 +1,148 @@
+import { existsSync, rmSync } from "node:fs";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import type { GraphStorageImpl } from "../../src/storage/graph-storage.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { graphQueryProblem, runGraphQuery } from "../../src/tools/graph-traversal.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+import { RelationType } from "../../src/types/storage.js";
+
+const TEST_DB_PATH = "./data/test-tool-graph-traversal.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function e(name: string, line: number, type: ParsedEntity["type"] = "function"): ParsedEntity {
+  return {
+    name,
+    type,
+    location: {
+      start: { line, column: 0, index: line * 10 },
+      end: { line: line + 1, column: 0, index: line * 10 + 5 },
+    },
+  } as any;
+}
+
+describe("runGraphQuery", () => {
+  let agent: IndexerAgent;
+  let storage: GraphStorageImpl;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+
+    // cli → purge → removeAccount → DeleteUser, and UserService references removeAccount
+    await agent.indexEntities(
+      [e("DeleteUser", 1), e("removeAccount", 10), e("purge", 20), e("UserService", 30, "class")],
+      "/tmp/users.ts",
+      [
+        { from: "removeAccount", to: "DeleteUser", type: "calls", metadata: { line: 11 } },
+        { from: "purge", to: "removeAccount", type: "calls", metadata: { line: 21 } },
+        { from: "UserService", to: "removeAccount", type: "references", metadata: { line: 31 } },
+      ],
+    );
+    await agent.indexEntities([e("cli", 1)], "/tmp/cli.ts");
+    storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    const [cli] = await storage.findEntities({ type: "entity", filters: { name: "cli" } });
+    const [purge] = await storage.findEntities({ type: "entity", filters: { name: "purge" } });
+    await storage.insertRelationships([
+      { id: "cli-purge", fromId: cli!.id, toId: purge!.id, type: RelationType.CALLS, metadata: { line: 2 } },
+    ]);
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("finds the functions within two hops of calling a symbol", async () => {
+    const result = await runGraphQuery(storage, {
+      start: { name: "DeleteUser" },
+      edges: ["calls"],
+      direction: "in",
+      maxDepth: 2,
+      where: { scope: { kinds: ["function"] } },
+      returnPaths: true,
+    });
+
+    expect(result.start.map((node) => node.name)).toEqual(["DeleteUser"]);
+    expect(result.matches.map((m) => [m.node.name, m.depth])).toEqual([
+      ["removeAccount", 1],
+      ["purge", 2],
+    ]);
+    expect(result.matches[0]?.via).toEqual({ relationship: "calls", direction: "in", confidence: 1, line: 11 });
+    expect(result.matches[1]?.path?.map((step) => step.node.name)).toEqual(["removeAccount", "purge"]);
+    // cli is three hops away; UserService only references removeAccount
+    expect(result.depthReached).toBe(2);
+    expect(result.truncated).toEqual([]);
+  });
+
+  it("walks outgoing edges, optionally returning the start nodes, and filters by name pattern", async () => {
+    const out = await runGraphQuery(storage, { start: { name: "purge" }, minDepth: 0, maxDepth: 3 });
+    expect(out.matches.map((m) => [m.node.name, m.depth, m.via?.direction ?? null])).toEqual([
+      ["purge", 0, null],
+      ["removeAccount", 1, "out"],
+      ["DeleteUser", 2, "out"],
+    ]);
+
+    const both = await runGraphQuery(storage, {
+      start: { name: "removeAccount" },
+      direction: "both",
+      maxDepth: 1,
+      where: { name: "*e*" },
+    });
+    expect(both.matches.map((m) => m.node.name)).toEqual(["DeleteUser", "purge", "UserService"]);
+  });
+
+  it("keeps test entities out of the walk when both selectors exclude them", async () => {
+    // audit → purgeFixture (a test helper) → removeAccount → DeleteUser
+    await agent.indexEntities([e("purgeFixture", 1)], "/tmp/users.test.ts");
+    await agent.indexEntities([e("audit", 1)], "/tmp/audit.ts");
+    const id = async (name: string) => (await storage.findEntities({ type: "entity", filters: { name } }))[0]!.id;
+    await storage.insertRelationships([
+      {
+        id: "fixture-remove",
+        fromId: await id("purgeFixture"),
+        toId: await id("removeAccount"),
+        type: RelationType.CALLS,
+      },
+      { id: "audit-fixture", fromId: await id("audit"), toId: await id("purgeFixture"), type: RelationType.CALLS },
+    ]);
+    const spec = (tests?: "exclude") => ({
+      start: { name: "DeleteUser", scope: tests && { tests } },
+      edges: ["calls"],
+      direction: "in" as const,
+      maxDepth: 3,
+      where: tests && { scope: { tests } },
+    });
+
+    const all = await runGraphQuery(storage, spec());
+    expect(all.matches.map((m) => m.node.name)).toEqual(["removeAccount", "purgeFixture", "purge", "audit", "cli"]);
+
+    const production = await runGraphQuery(storage, spec("exclude"));
+    expect(production.matches.map((m) => m.node.name)).toEqual(["removeAccount", "purge", "cli"]);
+  });
+
+  it("rejects unbounded or malformed specs and reports truncation", async () => {
+    expect(graphQueryProblem({ start: {} })).toBe("start needs an id, a name, a pathPrefix or a pathGlob");
+    expect(graphQueryProblem({ start: { name: "x" }, edges: ["calls", "owns"] })).toBe("unknown edge types: owns");
+    expect(graphQueryProblem({ start: { name: "x" }, minDepth: 3, maxDepth: 2 })).toBe(
+      "minDepth cannot exceed maxDepth",
+    );
+    expect(graphQueryProblem({ start: { scope: { pathPrefix: "/tmp" } }, edges: ["calls"] })).toBeNull();
+
+    const capped = await runGraphQuery(storage, { start: { name: "cli" }, maxDepth: 5, limit: 2 });
+    expect(capped.total).toBe(3);
+    expect(capped.matches.map((m) => m.node.name)).toEqual(["purge", "removeAccount"]);
+    expect(capped.truncated.map((t) => t.reason)).toEqual(["limit"]);
+  });
+});
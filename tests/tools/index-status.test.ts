@@ -0,0 +1,94 @@
+import { existsSync, mkdtempSync, rmSync, unlinkSync, utimesSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { join } from "node:path";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { hashFileContent } from "../../src/parsers/incremental-parser.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { indexStatus } from "../../src/tools/index-status.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+
+const TEST_DB_PATH = "./data/test-tool-index-status.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function fn(name: string): ParsedEntity {
+  return {
+    name,
+    type: "function",
+    location: { start: { line: 1, column: 0, index: 0 }, end: { line: 1, column: 10, index: 10 } },
+  };
+}
+
+describe("indexStatus", () => {
+  let agent: IndexerAgent;
+  let dir: string;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    dir = mkdtempSync(join(tmpdir(), "index-status-"));
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    rmSync(dir, { recursive: true, force: true });
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  async function indexFile(name: string, content: string) {
+    const path = join(dir, name);
+    writeFileSync(path, content);
+    utimesSync(path, 1_700_000_000, 1_700_000_000);
+    await agent.indexEntities([fn(name.replace(/\..*$/, ""))], path, [], { fileHash: hashFileContent(content) });
+    return path;
+  }
+
+  it("reports nothing stale right after indexing", async () => {
+    const a = await indexFile("a.ts", "export const a = 1;\n");
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    const status = await indexStatus(storage, { directory: dir, discovered: [a] });
+    expect(status.stale).toBe(false);
+    expect(status.counts).toEqual({ indexed: 1, changed: 0, added: 0, deleted: 0 });
+    expect(status.files).toEqual([]);
+  });
+
+  it("lists changed, added and deleted files, ignoring files only touched", async () => {
+    const changed = await indexFile("changed.ts", "export const v = 1;\n");
+    const deleted = await indexFile("deleted.ts", "export const d = 1;\n");
+    const touched = await indexFile("touched.ts", "export const t = 1;\n");
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    writeFileSync(changed, "export const v = 2;\n");
+    utimesSync(changed, 1_700_000_100, 1_700_000_100);
+    unlinkSync(deleted);
+    utimesSync(touched, 1_700_000_200, 1_700_000_200);
+    const added = join(dir, "added.ts");
+    writeFileSync(added, "export const n = 1;\n");
+
+    const status = await indexStatus(storage, { directory: dir, discovered: [added, changed, touched] });
+    expect(status.stale).toBe(true);
+    expect(status.files).toEqual([
+      { path: added, change: "added" },
+      { path: changed, change: "changed" },
+      { path: deleted, change: "deleted" },
+    ]);
+    expect(status.counts).toEqual({ indexed: 3, changed: 1, added: 1, deleted: 1 });
+    expect(status.touched).toBe(1);
+
+    const capped = await indexStatus(storage, { directory: dir, discovered: [added], limit: 1 });
+    expect(capped.files).toHaveLength(1);
+    expect(capped.truncated).toBe(true);
+  });
+});
@@ -0,0 +1,103 @@
+import { existsSync, rmSync } from "node:fs";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { inheritanceHierarchy } from "../../src/tools/inheritance-hierarchy.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+
+const TEST_DB_PATH = "./data/test-tool-inheritance-hierarchy.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function cls(name: string, line: number): ParsedEntity {
+  return {
+    name,
+    type: "class",
+    location: {
+      start: { line, column: 0, index: line * 10 },
+      end: { line: line + 4, column: 0, index: line * 10 + 5 },
+    },
+  } as any;
+}
+
+describe("inheritanceHierarchy", () => {
+  let agent: IndexerAgent;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("expands a diamond once and reports it along with the multiple inheritance", async () => {
+    await agent.indexEntities(
+      [cls("Base", 1), cls("Left", 10), cls("Right", 20), cls("Child", 30)],
+      "/tmp/shapes.py",
+      [
+        { from: "Left", to: "Base", type: "extends", metadata: { line: 10 } },
+        { from: "Right", to: "Base", type: "extends", metadata: { line: 20 } },
+        { from: "Child", to: "Left", type: "extends", metadata: { line: 30 } },
+        { from: "Child", to: "Right", type: "extends", metadata: { line: 30 } },
+      ],
+    );
+    // Subclass in another file: its edge stops at a placeholder named after the base
+    await agent.indexEntities([cls("Leaf", 1)], "/tmp/leaf.py", [
+      { from: "Leaf", to: "Child", type: "extends", metadata: { line: 1 } },
+    ]);
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    const result = await inheritanceHierarchy(storage, { symbol: "Child" });
+
+    const [root] = result.roots;
+    expect(root?.type).toMatchObject({ name: "Child", filePath: "/tmp/shapes.py", line: 30 });
+    expect(root?.ancestors.map((n) => n.name)).toEqual(["Left", "Right"]);
+    expect(root?.ancestors[0]?.children).toEqual([
+      expect.objectContaining({ name: "Base", relation: "extends", seen: null, line: 1 }),
+    ]);
+    expect(root?.ancestors[1]?.children[0]).toMatchObject({ name: "Base", seen: "repeated", children: [] });
+    expect(root?.descendants).toEqual([
+      expect.objectContaining({ name: "Leaf", filePath: "/tmp/leaf.py", line: 1, relation: "extends" }),
+    ]);
+
+    expect(result.diamonds).toHaveLength(1);
+    expect(result.diamonds[0]?.entity.name).toBe("Base");
+    expect(result.diamonds[0]?.via.map((v) => v.name).sort()).toEqual(["Left", "Right"]);
+    expect(result.multipleInheritance).toEqual([
+      expect.objectContaining({ entity: expect.objectContaining({ name: "Child" }) }),
+    ]);
+    expect(result.truncated).toBe(false);
+  });
+
+  it("stops on cycles and keeps library bases as unresolved leaves", async () => {
+    await agent.indexEntities([cls("A", 1), cls("B", 10)], "/tmp/loop.py", [
+      { from: "A", to: "B", type: "extends", metadata: { line: 1 } },
+      { from: "B", to: "A", type: "extends", metadata: { line: 10 } },
+      { from: "B", to: "Exception", type: "extends", metadata: { line: 10 } },
+    ]);
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    const result = await inheritanceHierarchy(storage, { symbol: "A", direction: "ancestors" });
+
+    const [b] = result.roots[0]?.ancestors ?? [];
+    expect(b?.name).toBe("B");
+    expect(b?.children.map((n) => [n.name, n.seen, n.id === null])).toEqual([
+      ["A", "cycle", false],
+      ["Exception", null, true],
+    ]);
+    expect(result.roots[0]?.descendants).toEqual([]);
+  });
+});
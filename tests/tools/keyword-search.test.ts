@@ -0,0 +1,181 @@
+import { existsSync, rmSync } from "node:fs";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import {
+  bestBelowThreshold,
+  filterBySimilarity,
+  fuseSearchResults,
+  keywordSearch,
+  toFtsQuery,
+} from "../../src/tools/keyword-search.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+
+const TEST_DB_PATH = "./data/test-tool-keyword-search.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function fn(name: string, line: number, signature?: string): ParsedEntity {
+  return {
+    name,
+    type: "function",
+    signature,
+    location: {
+      start: { line, column: 0, index: line * 10 },
+      end: { line: line + 2, column: 0, index: line * 10 + 5 },
+    },
+  } as any;
+}
+
+describe("toFtsQuery", () => {
+  it("quotes words as prefix terms and drops FTS operators", () => {
+    expect(toFtsQuery("parseConfig")).toBe('"parseconfig"*');
+    expect(toFtsQuery('load "user" OR NEAR(')).toBe('"load"* OR "user"* OR "or"* OR "near"*');
+    expect(toFtsQuery("  ::  ")).toBeNull();
+  });
+});
+
+describe("fuseSearchResults", () => {
+  it("puts an exact keyword match above semantic neighbours", () => {
+    const semantic = [
+      { id: "ent:a", similarity: 0.91, metadata: { entityId: "a", name: "loadSettings" } },
+      { id: "ent:b", similarity: 0.9, metadata: { entityId: "b", name: "readOptions" } },
+    ];
+    const exact = {
+      entity: {
+        id: "c",
+        name: "parseConfig",
+        type: "function",
+        filePath: "/src/config.ts",
+        location: { start: { line: 1, column: 0, index: 0 }, end: { line: 3, column: 0, index: 10 } },
+        metadata: {},
+      },
+      score: 4.2,
+      exactName: true,
+    } as any;
+
+    const fused = fuseSearchResults(semantic, [exact], 10);
+
+    expect(fused[0]?.entityId).toBe("c");
+    expect(fused[0]?.matchedBy).toEqual(["keyword"]);
+    expect(fused.map((h) => h.entityId)).toEqual(["c", "a", "b"]);
+  });
+
+  it("merges hits found by both retrievers", () => {
+    const semantic = [{ id: "ent:a", similarity: 0.8, metadata: { entityId: "a" } }];
+    const keyword = [
+      { entity: { id: "a", name: "a", type: "function", filePath: "/x.ts", metadata: {} }, score: 1, exactName: false },
+    ] as any;
+
+    const fused = fuseSearchResults(semantic, keyword, 10);
+
+    expect(fused).toHaveLength(1);
+    expect(fused[0]?.matchedBy).toEqual(["semantic", "keyword"]);
+    expect(fused[0]?.rankingSignals).toMatchObject({ semanticRank: 1, keywordRank: 1 });
+  });
+
+  it("keeps the cosine of semantic hits and drops those below the threshold", () => {
+    const semantic = filterBySimilarity(
+      [
+        { id: "ent:a", similarity: 0.9, cosine: 0.62, metadata: { entityId: "a" } },
+        { id: "ent:b", similarity: 0.7, cosine: 0.08, metadata: { entityId: "b" } },
+      ],
+      0.2,
+    );
+    const keyword = [
+      { entity: { id: "c", name: "c", type: "function", filePath: "/x.ts", metadata: {} }, score: 1, exactName: false },
+    ] as any;
+
+    const fused = fuseSearchResults(semantic, keyword, 10);
+
+    expect(fused.map((h) => [h.entityId, h.cosine])).toEqual([
+      ["a", 0.62],
+      ["c", null],
+    ]);
+    expect(filterBySimilarity(fused, 0.9).map((h) => h.entityId)).toEqual(["c"]);
+  });
+
+  it("reports the best score a threshold dropped", () => {
+    const hits = [{ cosine: 0.62 }, { cosine: 0.08 }, { cosine: 0.15 }, { cosine: null }];
+    expect(bestBelowThreshold(hits, 0.7)).toBe(0.62);
+    expect(bestBelowThreshold(hits, 0.2)).toBe(0.15);
+    expect(bestBelowThreshold(hits, 0.05)).toBeNull();
+  });
+});
+
+describe("keywordSearch", () => {
+  let agent: IndexerAgent;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("ranks the exact identifier first and matches signatures", async () => {
+    await agent.indexEntities(
+      [
+        fn("parseConfigFile", 1, "function parseConfigFile(path: string)"),
+        fn("parseConfig", 10, "function parseConfig(raw: string): Config"),
+        fn("render", 20, "function render(config: Config)"),
+      ],
+      "/tmp/config.ts",
+    );
+
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    const hits = await keywordSearch(storage, "parseConfig", 10);
+
+    expect(hits[0]?.entity.name).toBe("parseConfig");
+    expect(hits[0]?.exactName).toBe(true);
+    expect(hits.map((h) => h.entity.name)).toContain("parseConfigFile");
+
+    const bySignature = await keywordSearch(storage, "Config", 10);
+    expect(bySignature.map((h) => h.entity.name)).toContain("render");
+  });
+
+  it("keeps the index in sync when a file is re-indexed", async () => {
+    await agent.indexEntities([fn("oldName", 1)], "/tmp/a.ts");
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    expect(await keywordSearch(storage, "oldName", 5)).toHaveLength(1);
+
+    await storage.deleteFileData("/tmp/a.ts");
+    await agent.indexEntities([fn("newName", 1)], "/tmp/a.ts");
+
+    expect(await keywordSearch(storage, "oldName", 5)).toHaveLength(0);
+    expect((await keywordSearch(storage, "newName", 5))[0]?.entity.name).toBe("newName");
+  });
+
+  it("applies kind, language and path scope before the limit", async () => {
+    const store = { ...fn("UserStore", 5), type: "class" } as ParsedEntity;
+    await agent.indexEntities([fn("loadUser", 1), store], "/repo/api/user.ts");
+    await agent.indexEntities([fn("load_user", 1)], "/repo/services/user.py");
+    await agent.indexEntities([fn("loadUserCache", 1)], "/repo/api-old/cache.ts");
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    const python = await keywordSearch(storage, "load", 1, { languages: ["python"] });
+    expect(python.map((h) => h.entity.name)).toEqual(["load_user"]);
+
+    const classes = await keywordSearch(storage, "user", 10, { kinds: ["class"] });
+    expect(classes.map((h) => h.entity.name)).toEqual(["UserStore"]);
+
+    const underApi = await keywordSearch(storage, "load", 10, { pathPrefix: "/repo/api" });
+    expect(underApi.map((h) => h.entity.name)).toEqual(["loadUser"]);
+
+    const globbed = await keywordSearch(storage, "load", 10, { pathGlob: "services/**/*.py" });
+    expect(globbed.map((h) => h.entity.name)).toEqual(["load_user"]);
+  });
+});
@@ -0,0 +1,116 @@
+import { existsSync, rmSync } from "node:fs";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { listEnvVars } from "../../src/tools/list-env-vars.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+
+const TEST_DB_PATH = "./data/test-tool-list-env-vars.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function e(name: string, type: ParsedEntity["type"], line: number, extra?: Partial<ParsedEntity>): ParsedEntity {
+  return {
+    name,
+    type,
+    location: {
+      start: { line, column: 0, index: line * 10 },
+      end: { line: line + 4, column: 0, index: line * 10 + 5 },
+    },
+    ...extra,
+  };
+}
+
+function envVar(filePath: string, name: string, reads: Array<Record<string, unknown>>, readers: string[] = []) {
+  const unresolved = name.startsWith("?");
+  const key = unresolved ? name.slice(1) : name;
+  return e(key, "env_var", Number(reads[0]?.line ?? 1), {
+    id: `${filePath}:env:${name}`,
+    readers: readers.map((reader) => ({ name: reader, line: 1, column: 0 })),
+    metadata: { variable: unresolved ? null : key, reads, ...(unresolved ? { unresolved: true } : {}) },
+  });
+}
+
+describe("listEnvVars", () => {
+  let agent: IndexerAgent;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  async function indexService() {
+    const config = "/tmp/svc/src/config.ts";
+    await agent.indexEntities(
+      [
+        e("connect", "function", 1),
+        envVar(config, "PORT", [{ line: 2, column: 15, accessor: "process.env", default: "8080", reader: "connect" }], [
+          "connect",
+        ]),
+        envVar(config, "DATABASE_URL", [{ line: 3, column: 8, accessor: "process.env", constant: "DB_KEY" }]),
+        envVar(config, "?key", [{ line: 9, column: 2, accessor: "process.env" }]),
+      ],
+      config,
+    );
+    // Languages with an analyzer hand over their own read_by edges
+    const main = "/tmp/svc/cmd/main.go";
+    await agent.indexEntities(
+      [e("main", "function", 3), envVar(main, "PORT", [{ line: 4, column: 9, accessor: "os.Getenv", reader: "main" }])],
+      main,
+      [{ from: `${main}:env:PORT`, to: "main", type: "read_by", metadata: { line: 3 } }],
+    );
+    return getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+  }
+
+  it("groups reads by variable across files with their readers and defaults", async () => {
+    const storage = await indexService();
+
+    const result = await listEnvVars(storage);
+
+    expect(result.variables.map((v) => [v.name, v.files, v.defaults])).toEqual([
+      ["DATABASE_URL", 1, []],
+      ["PORT", 2, ["8080"]],
+    ]);
+    const port = result.variables[1]!;
+    expect(port.reads.map((r) => [r.filePath, r.line, r.accessor, r.reader?.name ?? null])).toEqual([
+      ["/tmp/svc/cmd/main.go", 4, "os.Getenv", "main"],
+      ["/tmp/svc/src/config.ts", 2, "process.env", "connect"],
+    ]);
+    expect(result.variables[0]?.reads[0]).toMatchObject({ constant: "DB_KEY", reader: null });
+    expect(result.unresolved).toEqual([
+      expect.objectContaining({ expression: "key", filePath: "/tmp/svc/src/config.ts", line: 9 }),
+    ]);
+  });
+
+  it("filters by name prefix and directory", async () => {
+    const storage = await indexService();
+
+    const db = await listEnvVars(storage, { name: "data" });
+    expect(db.variables.map((v) => v.name)).toEqual(["DATABASE_URL"]);
+    expect(db.unresolved).toEqual([]);
+
+    const go = await listEnvVars(storage, { pathPrefix: "/tmp/svc/cmd" });
+    expect(go.variables.map((v) => [v.name, v.reads.length])).toEqual([["PORT", 1]]);
+
+    const limited = await listEnvVars(storage, { limit: 1, includeUnresolved: false });
+    expect(limited.variables).toHaveLength(1);
+    expect(limited.total).toBe(2);
+    expect(limited.truncated).toBe(true);
+    expect(limited.unresolved).toEqual([]);
+  });
+});
@@ -0,0 +1,127 @@
+import { existsSync, rmSync } from "node:fs";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { listMembers } from "../../src/tools/list-members.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+
+const TEST_DB_PATH = "./data/test-tool-list-members.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function e(name: string, type: string, start: number, end: number, extra: Partial<ParsedEntity> = {}): ParsedEntity {
+  return {
+    name,
+    type,
+    location: {
+      start: { line: start, column: 0, index: start * 10 },
+      end: { line: end, column: 0, index: end * 10 },
+    },
+    ...extra,
+  } as any;
+}
+
+describe("listMembers", () => {
+  let agent: IndexerAgent;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("lists struct fields and methods from every file of a Go package with their receiver kind", async () => {
+    const file = "/tmp/lm/server.go";
+    const field = (name: string, line: number, fieldType: string) =>
+      e(name, "property", line, line, {
+        id: `${file}:type:Server:field:${name}`,
+        metadata: { fieldType, parent: `${file}:type:Server` },
+      } as any);
+    await agent.indexEntities(
+      [
+        e("Server", "struct", 3, 7, { id: `${file}:type:Server` } as any),
+        field("Addr", 4, "string"),
+        field("handler", 5, "http.Handler"),
+        e("Name", "method", 9, 11, {
+          metadata: { receiver: "Server", pointerReceiver: false, parameters: [], returnType: "string" },
+        } as any),
+      ],
+      file,
+      ["Addr", "handler"].map((name) => ({
+        from: `${file}:type:Server:field:${name}`,
+        to: `${file}:type:Server`,
+        type: "contains",
+      })) as any,
+    );
+    await agent.indexEntities(
+      [
+        e("Start", "method", 1, 5, {
+          metadata: {
+            receiver: "Server",
+            pointerReceiver: true,
+            parameters: ["ctx context.Context"],
+            returnType: "error",
+          },
+        } as any),
+      ],
+      "/tmp/lm/handlers.go",
+    );
+
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    const { containers } = await listMembers(storage, { symbol: "Server" });
+    expect(containers).toHaveLength(1);
+    const [server] = containers;
+    expect(server!.counts).toEqual({ method: 2, field: 2, type: 0 });
+    expect(server!.members.map((m) => [m.name, m.kind, m.via, m.receiver ?? null])).toEqual([
+      ["Start", "method", "receiver", "pointer"],
+      ["Addr", "field", "contains", null],
+      ["handler", "field", "contains", null],
+      ["Name", "method", "receiver", "value"],
+    ]);
+    expect(server!.members.find((m) => m.name === "Start")!.signature).toBe("Start(ctx context.Context): error");
+    expect(server!.members.find((m) => m.name === "Addr")!.signature).toBe("Addr: string");
+
+    const fields = await listMembers(storage, { symbol: "Server", kinds: ["field"] });
+    expect(fields.containers[0]!.members.map((m) => m.name)).toEqual(["Addr", "handler"]);
+  });
+
+  it("falls back to what the class directly encloses when no edge ties the members", async () => {
+    await agent.indexEntities(
+      [
+        e("Cart", "class", 1, 10),
+        e("total", "method", 2, 4),
+        e("sum", "variable", 3, 3),
+        e("items", "property", 5, 5),
+        e("helper", "function", 20, 22),
+      ],
+      "/tmp/cart.ts",
+    );
+
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    const [cart] = (await listMembers(storage, { symbol: "Cart" })).containers;
+    // The local variable belongs to the method, the top-level function to the file
+    expect(cart!.members.map((m) => [m.name, m.kind, m.via])).toEqual([
+      ["total", "method", "enclosure"],
+      ["items", "field", "enclosure"],
+    ]);
+
+    const truncated = await listMembers(storage, { symbol: "Cart", limit: 1 });
+    expect(truncated.containers[0]!.members).toHaveLength(1);
+    expect(truncated.containers[0]!.truncated).toBe(true);
+    expect((await listMembers(storage, { symbol: "Missing" })).containers).toEqual([]);
+  });
+});
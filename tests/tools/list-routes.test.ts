@@ -0,0 +1,106 @@
+import { existsSync, rmSync } from "node:fs";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { listRoutes } from "../../src/tools/list-routes.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+
+const TEST_DB_PATH = "./data/test-tool-list-routes.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function e(name: string, type: ParsedEntity["type"], line: number, extra?: Partial<ParsedEntity>): ParsedEntity {
+  return {
+    name,
+    type,
+    location: {
+      start: { line, column: 0, index: line * 10 },
+      end: { line: line + 2, column: 0, index: line * 10 + 5 },
+    },
+    ...extra,
+  };
+}
+
+function route(method: string, path: string, line: number, handler?: string, framework = "express"): ParsedEntity {
+  return e(`${method} ${path}`, "route", line, {
+    id: `route:${line}`,
+    ...(handler ? { handler: { name: handler, line, column: 0 } } : {}),
+    metadata: { httpMethod: method, path, framework, router: "router", ...(handler ? { handler } : {}) },
+  });
+}
+
+describe("listRoutes", () => {
+  let agent: IndexerAgent;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  async function indexService() {
+    await agent.indexEntities(
+      [
+        e("./handlers", "import", 1, { importData: { source: "./handlers", specifiers: [{ local: "showUser" }] } }),
+        route("GET", "/users/:id", 3, "showUser"),
+        route("POST", "/users", 4, "createUser"),
+        route("DELETE", "/users/:id", 5),
+        e("createUser", "function", 10),
+      ],
+      "/tmp/api/src/routes.js",
+    );
+    // Languages with an analyzer hand over their own handled_by edges
+    await agent.indexEntities(
+      [route("GET", "/health", 4, "health", "net/http"), e("health", "function", 8)],
+      "/tmp/api/cmd/main.go",
+      [{ from: "route:4", to: "health", type: "handled_by", metadata: { line: 8 } }],
+    );
+    return getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+  }
+
+  it("lists every endpoint by path with its handler", async () => {
+    const storage = await indexService();
+
+    const result = await listRoutes(storage);
+
+    expect(result.routes.map((r) => [r.method, r.path, r.handler?.name ?? null, r.handler?.resolved ?? null])).toEqual([
+      ["GET", "/health", "health", true],
+      ["POST", "/users", "createUser", true],
+      ["DELETE", "/users/:id", null, null],
+      // Imported handlers point at the module's placeholder until the cross-file pass binds them
+      ["GET", "/users/:id", "showUser", false],
+    ]);
+    expect(result.routes[0]?.handler).toMatchObject({ filePath: "/tmp/api/cmd/main.go", startLine: 8 });
+    expect(result.byMethod).toEqual({ GET: 2, POST: 1, DELETE: 1 });
+    expect(result.byFramework).toEqual({ express: 3, "net/http": 1 });
+    expect(result.truncated).toBe(false);
+  });
+
+  it("filters by method, path, framework and directory", async () => {
+    const storage = await indexService();
+
+    expect((await listRoutes(storage, { method: "get" })).routes.map((r) => r.path)).toEqual(["/health", "/users/:id"]);
+    expect((await listRoutes(storage, { path: "/users/" })).total).toBe(2);
+    expect((await listRoutes(storage, { framework: "net/http" })).routes.map((r) => r.path)).toEqual(["/health"]);
+    expect((await listRoutes(storage, { pathPrefix: "/tmp/api/src" })).total).toBe(3);
+
+    const limited = await listRoutes(storage, { limit: 1 });
+    expect(limited.routes).toHaveLength(1);
+    expect(limited.total).toBe(4);
+    expect(limited.truncated).toBe(true);
+  });
+});
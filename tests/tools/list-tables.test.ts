@@ -0,0 +1,118 @@
+import { existsSync, rmSync } from "node:fs";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { SqlAnalyzer } from "../../src/parsers/sql-analyzer.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { listTables } from "../../src/tools/list-tables.js";
+import { AgentStatus } from "../../src/types/agent.js";
+
+const TEST_DB_PATH = "./data/test-tool-list-tables.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+const USERS_FILE = "/tmp/app/db/migrations/001_users.sql";
+const ORDERS_FILE = "/tmp/app/db/migrations/002_orders.sql";
+
+const MIGRATIONS: Array<[string, string]> = [
+  [
+    USERS_FILE,
+    `-- People who can sign in
+CREATE TABLE users (
+  id serial PRIMARY KEY,
+  email text NOT NULL
+);
+`,
+  ],
+  [
+    ORDERS_FILE,
+    `CREATE TABLE orders (
+  id serial PRIMARY KEY,
+  user_id integer NOT NULL REFERENCES users (id)
+);
+
+ALTER TABLE users ADD COLUMN last_order_id integer REFERENCES orders (id);
+
+CREATE VIEW user_orders AS SELECT u.email, o.id FROM users u JOIN orders o ON o.user_id = u.id;
+`,
+  ],
+];
+
+describe("listTables", () => {
+  let agent: IndexerAgent;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+
+    const analyzer = new SqlAnalyzer();
+    for (const [file, ddl] of MIGRATIONS) {
+      const { entities, relationships } = analyzer.analyze(ddl, file);
+      await agent.indexEntities(
+        entities.map((e) => ({ ...e, language: "sql" })),
+        file,
+        relationships,
+      );
+    }
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("merges later ALTER TABLE additions into the table and resolves foreign keys both ways", async () => {
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    const result = await listTables(storage);
+
+    expect(result.tables.map((t) => [t.name, t.kind])).toEqual([
+      ["orders", "table"],
+      ["user_orders", "view"],
+      ["users", "table"],
+    ]);
+    expect(result.total).toBe(3);
+
+    const users = result.tables.find((t) => t.name === "users")!;
+    expect(users).toMatchObject({
+      filePath: USERS_FILE,
+      line: 2,
+      defined: true,
+      documentation: "-- People who can sign in",
+      primaryKey: ["id"],
+      alteredIn: [ORDERS_FILE],
+    });
+    expect(users.columns.map((c) => [c.name, c.dataType, c.addedIn ?? null])).toEqual([
+      ["id", "serial", null],
+      ["email", "text", null],
+      ["last_order_id", "integer", ORDERS_FILE],
+    ]);
+    expect(users.foreignKeys).toEqual([
+      {
+        columns: ["last_order_id"],
+        table: "orders",
+        referencedColumns: ["id"],
+        resolved: { name: "orders", filePath: ORDERS_FILE, line: 1 },
+      },
+    ]);
+    expect(users.referencedBy).toEqual([{ table: "orders", columns: ["user_id"], referencedColumns: ["id"] }]);
+  });
+
+  it("filters by name and kind and can leave columns out", async () => {
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    expect((await listTables(storage, { name: "USER" })).tables.map((t) => t.name)).toEqual(["user_orders", "users"]);
+    expect((await listTables(storage, { kind: "view" })).tables.map((t) => t.name)).toEqual(["user_orders"]);
+
+    const brief = await listTables(storage, { includeColumns: false, limit: 1 });
+    expect(brief.tables).toEqual([expect.objectContaining({ name: "orders", columns: [] })]);
+    expect(brief).toMatchObject({ total: 3, truncated: true });
+  });
+});
@@ -0,0 +1,101 @@
+import { existsSync, rmSync } from "node:fs";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { listTodos } from "../../src/tools/list-todos.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+
+const TEST_DB_PATH = "./data/test-tool-list-todos.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function e(name: string, type: ParsedEntity["type"], line: number, extra?: Partial<ParsedEntity>): ParsedEntity {
+  return {
+    name,
+    type,
+    location: {
+      start: { line, column: 0, index: line * 10 },
+      end: { line: line + 4, column: 0, index: line * 10 + 5 },
+    },
+    ...extra,
+  };
+}
+
+function comment(line: number, markers: Array<Record<string, unknown>>, enclosing?: Record<string, unknown>) {
+  const text = markers.map((m) => `${m.kind}: ${m.text}`).join("\n") || "plain prose";
+  return e(text.split("\n")[0]!, "comment", line, {
+    documentation: `// ${text}`,
+    metadata: { style: "line", ...(markers.length ? { markers } : {}), ...(enclosing ? { enclosing } : {}) },
+  });
+}
+
+describe("listTodos", () => {
+  let agent: IndexerAgent;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("lists markers in file order with their enclosing declaration", async () => {
+    const api = "/tmp/svc/src/api.ts";
+    const db = "/tmp/svc/lib/db.ts";
+    await agent.indexEntities(
+      [
+        e("handle", "function", 3),
+        comment(4, [{ kind: "TODO", line: 4, text: "validate input", assignee: "alice" }], {
+          name: "handle",
+          type: "function",
+          line: 3,
+        }),
+        comment(1, []),
+      ],
+      api,
+    );
+    await agent.indexEntities(
+      [
+        comment(2, [
+          { kind: "FIXME", line: 2, text: "leaks connections" },
+          { kind: "HACK", line: 3, text: "retry twice" },
+        ]),
+      ],
+      db,
+    );
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    const all = await listTodos(storage);
+    expect(all.todos.map((t) => [t.filePath, t.line, t.kind, t.text])).toEqual([
+      [db, 2, "FIXME", "leaks connections"],
+      [db, 3, "HACK", "retry twice"],
+      [api, 4, "TODO", "validate input"],
+    ]);
+    expect(all.byKind).toEqual({ TODO: 1, FIXME: 1, HACK: 1 });
+    const handle = (await storage.findEntities({ type: "entity", filters: { filePath: api, name: "handle" } }))[0]!;
+    expect(all.todos[2]).toMatchObject({
+      assignee: "alice",
+      enclosing: { id: handle.id, name: "handle", type: "function", line: 3 },
+    });
+    expect(all.todos[0]?.enclosing).toBeNull();
+
+    expect((await listTodos(storage, { kinds: ["FIXME", "TODO"], pathPrefix: "/tmp/svc/lib" })).total).toBe(1);
+    expect((await listTodos(storage, { assignee: "Alice" })).todos.map((t) => t.line)).toEqual([4]);
+    expect((await listTodos(storage, { query: "retry" })).todos.map((t) => t.kind)).toEqual(["HACK"]);
+    const page = await listTodos(storage, { limit: 2 });
+    expect([page.todos.length, page.total, page.truncated]).toEqual([2, 3, true]);
+  });
+});
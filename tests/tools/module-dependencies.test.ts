@@ -0,0 +1,133 @@
+import { existsSync, mkdtempSync, rmSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { join } from "node:path";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { moduleDependencies, moduleGraphToDot, moduleGraphToGraphML } from "../../src/tools/module-dependencies.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+import { RelationType } from "../../src/types/storage.js";
+
+const TEST_DB_PATH = "./data/test-tool-module-dependencies.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function e(name: string, line: number): ParsedEntity {
+  return {
+    name,
+    type: "function",
+    location: {
+      start: { line, column: 0, index: line * 10 },
+      end: { line: line + 2, column: 0, index: line * 10 + 5 },
+    },
+  };
+}
+
+function importOf(source: string, line: number): ParsedEntity {
+  return {
+    name: `import:${source}`,
+    type: "import",
+    location: {
+      start: { line, column: 0, index: line * 10 },
+      end: { line, column: 20, index: line * 10 + 20 },
+    },
+    importData: { source, specifiers: [{ local: "x" }] },
+  } as any;
+}
+
+describe("moduleDependencies", () => {
+  let agent: IndexerAgent;
+  let root: string;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    root = mkdtempSync(join(tmpdir(), "module-deps-"));
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    rmSync(root, { recursive: true, force: true });
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  async function indexProject() {
+    const a = join(root, "src/a.ts");
+    const b = join(root, "src/b.ts");
+    await agent.indexEntities([importOf("./b.js", 1), importOf("../lib/util.js", 2), e("run", 5)], a);
+    await agent.indexEntities([importOf("./a.js", 1), e("helper", 3), e("Thing", 6)], b);
+    await agent.indexEntities([e("util", 1)], join(root, "lib/util.ts"));
+    await agent.indexEntities([e("main", 3)], join(root, "native/main.c"));
+    await agent.indexEntities([e("parse", 1)], join(root, "native/parse.h"));
+
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    const byName = async (name: string) =>
+      (await storage.executeQuery({ type: "entity", filters: { name } })).entities[0]!.id;
+    // Cross-file edges the post-index passes would bind
+    await storage.insertRelationships([
+      { id: "run-helper", fromId: await byName("run"), toId: await byName("helper"), type: RelationType.CALLS },
+      { id: "run-thing", fromId: await byName("run"), toId: await byName("Thing"), type: RelationType.REFERENCES },
+      { id: "main-parse", fromId: await byName("main"), toId: await byName("parse"), type: RelationType.IMPORTS },
+    ]);
+    return storage;
+  }
+
+  it("weights file dependencies by the symbols used across them and flags cycles", async () => {
+    const storage = await indexProject();
+
+    const result = await moduleDependencies(storage);
+    const rel = (p: string) => p.slice(root.length + 1);
+
+    expect(result.edges.map((d) => [rel(d.from), rel(d.to), d.weight, d.imports, d.references, d.cyclic])).toEqual([
+      ["native/main.c", "native/parse.h", 1, 1, 1, false],
+      ["src/a.ts", "lib/util.ts", 1, 1, 0, false],
+      ["src/a.ts", "src/b.ts", 2, 1, 2, true],
+      ["src/b.ts", "src/a.ts", 1, 1, 0, true],
+    ]);
+    expect(result.hasCycles).toBe(true);
+    expect(result.cycles).toEqual([[join(root, "src/a.ts"), join(root, "src/b.ts")]]);
+    expect(result.modules.find((m) => m.id === join(root, "src/a.ts"))).toMatchObject({ fanIn: 1, fanOut: 2 });
+
+    const heavy = await moduleDependencies(storage, { minWeight: 2 });
+    expect(heavy.edges.map((d) => [rel(d.from), rel(d.to)])).toEqual([["src/a.ts", "src/b.ts"]]);
+  });
+
+  it("collapses to directories", async () => {
+    const storage = await indexProject();
+
+    const result = await moduleDependencies(storage, { granularity: "directory" });
+
+    // a.ts and b.ts now share a module, as do main.c and the header it includes
+    expect(result.edges.map((d) => [d.from, d.to, d.weight, d.cyclic])).toEqual([
+      [join(root, "src"), join(root, "lib"), 1, false],
+    ]);
+    expect(result.hasCycles).toBe(false);
+  });
+
+  it("renders DOT and GraphML with weights and cyclic edges", async () => {
+    const result = await moduleDependencies(await indexProject());
+    const a = join(root, "src/a.ts");
+    const b = join(root, "src/b.ts");
+
+    const dot = moduleGraphToDot(result);
+    expect(dot.startsWith("digraph modules {")).toBe(true);
+    expect(dot).toContain(`"${a}" -> "${b}" [weight=2, label="2", penwidth=2.0, color="red"];`);
+
+    const graphml = moduleGraphToGraphML(result);
+    expect(graphml).toContain(`<node id="${a}"><data key="n_kind">file</data>`);
+    expect(graphml).toContain(
+      `<edge source="${a}" target="${b}"><data key="e_weight">2</data><data key="e_imports">1</data>` +
+        `<data key="e_references">2</data><data key="e_cyclic">true</data></edge>`,
+    );
+  });
+});
@@ -0,0 +1,101 @@
+import { existsSync, rmSync } from "node:fs";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { parseQueryIntent, runQueryIntent } from "../../src/tools/nl-query.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+
+const TEST_DB_PATH = "./data/test-tool-nl-query.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function e(name: string, line: number, extra?: Partial<ParsedEntity>): ParsedEntity {
+  return {
+    name,
+    type: "function",
+    location: {
+      start: { line, column: 0, index: line * 10 },
+      end: { line: line + 4, column: 0, index: line * 10 + 5 },
+    },
+    ...extra,
+  } as any;
+}
+
+describe("parseQueryIntent", () => {
+  it.each([
+    ["functions that call parseConfig", "callers", "parseConfig"],
+    ["Who calls the function `parseConfig`?", "callers", "parseConfig"],
+    ["where is parseConfig called from", "callers", "parseConfig"],
+    ["what does parseConfig call", "callees", "parseConfig"],
+    ["show me the callees of Server.start", "callees", "Server.start"],
+    ["types implementing Storage", "implementations", "Storage"],
+    ["find all subclasses of BaseAgent", "implementations", "BaseAgent"],
+    ["references to MAX_QUERY_LIMIT", "references", "MAX_QUERY_LIMIT"],
+    ["where is Entity used?", "references", "Entity"],
+    ["where is indexFile defined", "definitions", "indexFile"],
+    ["what's in src/index.ts", "file_members", "src/index.ts"],
+  ])("%s", (query, kind, target) => {
+    expect(parseQueryIntent(query)).toEqual({ kind, target });
+  });
+
+  it("keeps the entity kind asked for in a file", () => {
+    expect(parseQueryIntent("list the classes in file src/agents/base.ts")).toEqual({
+      kind: "file_members",
+      target: "src/agents/base.ts",
+      entityType: "class",
+    });
+  });
+
+  it("leaves open-ended questions to semantic search", () => {
+    expect(parseQueryIntent("how does authentication work")).toBeNull();
+    expect(parseQueryIntent("functions that call")).toBeNull();
+  });
+});
+
+describe("runQueryIntent", () => {
+  let agent: IndexerAgent;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("answers callers and file members from the graph", async () => {
+    const render = e("render", 10, { calls: [{ name: "format", line: 11, column: 4 }] });
+    const print = e("print", 20, { calls: [{ name: "format", line: 21, column: 4 }] });
+    await agent.indexEntities([e("format", 1), render, print, e("View", 30, { type: "class" })], "/tmp/app/view.ts");
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    const callers = await runQueryIntent(storage, parseQueryIntent("functions that call format")!);
+    expect(callers.items.map((i) => [i.name, i.relation, i.line]).sort()).toEqual([
+      ["print", "calls", 21],
+      ["render", "calls", 11],
+    ]);
+    expect(callers.truncated).toBe(false);
+
+    const members = await runQueryIntent(storage, parseQueryIntent("what's in app/view.ts")!);
+    expect(members.items.map((i) => i.name)).toEqual(["format", "render", "print", "View"]);
+
+    const classes = await runQueryIntent(storage, parseQueryIntent("classes in app/view.ts")!, 10);
+    expect(classes.items.map((i) => i.name)).toEqual(["View"]);
+
+    const limited = await runQueryIntent(storage, parseQueryIntent("what's in view.ts")!, 2);
+    expect(limited).toMatchObject({ total: 4, truncated: true });
+    expect(limited.items).toHaveLength(2);
+  });
+});
@@ -0,0 +1,136 @@
+import { existsSync, mkdirSync, mkdtempSync, rmSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { dirname, join } from "node:path";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { resolveGoPackages } from "../../src/core/go-package-resolver.js";
+import { resolveImports } from "../../src/core/import-resolver.js";
+import { buildPackageTree } from "../../src/core/package-tree.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { listPackageInit } from "../../src/tools/package-init.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import { edge, entity } from "../fixtures/parsed-entities.js";
+
+const TEST_DB_PATH = "./data/test-package-init.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+describe("listPackageInit", () => {
+  let agent: IndexerAgent;
+  let root: string;
+
+  const write = (path: string) => {
+    mkdirSync(dirname(join(root, path)), { recursive: true });
+    writeFileSync(join(root, path), "");
+    return join(root, path);
+  };
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+    root = mkdtempSync(join(tmpdir(), "cgr-package-init-"));
+    writeFileSync(join(root, "go.mod"), "module example.com/app\n\ngo 1.22\n");
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    rmSync(root, { recursive: true, force: true });
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("orders variables by their dependencies, init functions by file and marks blank imports", async () => {
+    const driver = write("drivers/postgres/driver.go");
+    const config = write("server/config.go");
+    const limits = write("server/limits.go");
+    const configTest = write("server/config_test.go");
+
+    await agent.indexEntities(
+      [
+        entity("d:pkg", "postgres", "module", 1, { isPackage: true }),
+        entity("d:init", "init", "function", 5, { package: "postgres", goInit: true, initOrder: 1 }),
+      ],
+      driver,
+      [edge("d:init", "sql.Register", "calls", { line: 6, callee: "sql.Register", calleeName: "Register" })],
+    );
+    await agent.indexEntities(
+      [
+        entity("c:pkg", "server", "module", 1, { isPackage: true }),
+        entity("c:total", "total", "variable", 6, { package: "server", initialValue: "base + extra" }),
+        entity("c:base", "base", "variable", 7, { package: "server", initialValue: "compute()" }),
+        entity("c:compute", "compute", "function", 9, { package: "server" }),
+        entity("c:init", "init", "function", 13, { package: "server", goInit: true, initOrder: 1 }),
+      ],
+      config,
+      [
+        edge("c:pkg", "example.com/app/drivers/postgres", "imports", { alias: "_", sideEffect: true, line: 3 }),
+        edge("c:pkg", "fmt", "imports", { line: 4 }),
+        edge("c:total", "c:base", "accesses", { access: "read", referencedName: "base", line: 6 }),
+        edge("c:total", "c:var:extra", "accesses", { access: "read", referencedName: "extra", line: 6 }),
+        edge("c:base", "c:compute", "calls", { line: 7, callee: "compute", calleeName: "compute" }),
+        edge("c:compute", "c:var:limit", "accesses", { access: "read", referencedName: "limit", line: 10 }),
+        edge("c:init", "fmt.Println", "calls", { line: 14, callee: "fmt.Println", calleeName: "Println" }),
+      ],
+    );
+    await agent.indexEntities(
+      [
+        entity("l:pkg", "server", "module", 1, { isPackage: true }),
+        entity("l:limit", "limit", "variable", 3, { package: "server", initialValue: "10" }),
+        entity("l:extra", "extra", "variable", 4, { package: "server" }),
+        entity("l:init", "init", "function", 6, { package: "server", goInit: true, initOrder: 1 }),
+      ],
+      limits,
+      [edge("l:init", "compute", "calls", { line: 7, callee: "compute", calleeName: "compute" })],
+    );
+    await agent.indexEntities(
+      [
+        entity("t:pkg", "server", "module", 1, { isPackage: true }),
+        entity("t:init", "init", "function", 3, { package: "server", goInit: true, initOrder: 1 }),
+      ],
+      configTest,
+      [],
+    );
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    await buildPackageTree(storage, { roots: [root] });
+    await resolveImports(storage);
+    await resolveGoPackages(storage);
+
+    const { result, candidates } = await listPackageInit(storage, "example.com/app/server");
+    expect(candidates.map((c) => c.importPath)).toEqual(["example.com/app/server"]);
+    expect(result?.package.files).toEqual([config, limits]);
+
+    // limit has no dependencies; base reads it through compute(); total needs base
+    expect(result?.variables.map((v) => [v.order, v.name, v.dependsOn])).toEqual([
+      [1, "limit", []],
+      [2, "base", ["limit"]],
+      [3, "total", ["base", "extra"]],
+    ]);
+    expect(result?.variables[1]?.calls.map((call) => [call.callee, call.target?.name])).toEqual([
+      ["compute", "compute"],
+    ]);
+
+    expect(result?.initFunctions.map((fn) => [fn.order, fn.filePath, fn.calls.map((c) => c.callee)])).toEqual([
+      [1, config, ["fmt.Println"]],
+      [2, limits, ["compute"]],
+    ]);
+
+    const imports = result?.imports.map((imp) => [imp.importPath, imp.sideEffect, imp.package?.initFunctions ?? null]);
+    expect(imports).toEqual([
+      ["example.com/app/drivers/postgres", true, 1],
+      ["fmt", false, null],
+    ]);
+
+    // A file of the package, or the last elements of its import path, find it too
+    expect((await listPackageInit(storage, config, config)).result?.package.importPath).toBe("example.com/app/server");
+    expect((await listPackageInit(storage, "postgres")).result?.initFunctions).toHaveLength(1);
+    expect(await listPackageInit(storage, "missing")).toEqual({ result: null, candidates: [] });
+  });
+});
@@ -0,0 +1,141 @@
+import { existsSync, mkdirSync, mkdtempSync, rmSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { dirname, join } from "node:path";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { buildPackageTree } from "../../src/core/package-tree.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { listPublicApi } from "../../src/tools/public-api.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+
+const TEST_DB_PATH = "./data/test-public-api.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function entity(name: string, type: ParsedEntity["type"], lines: [number, number], metadata = {}): ParsedEntity {
+  return {
+    name,
+    type,
+    location: {
+      start: { line: lines[0], column: 0, index: lines[0] * 100 },
+      end: { line: lines[1], column: 0, index: lines[1] * 100 + 50 },
+    },
+    metadata,
+  };
+}
+
+describe("listPublicApi", () => {
+  let agent: IndexerAgent;
+  let root: string;
+
+  const write = (path: string) => {
+    mkdirSync(dirname(join(root, path)), { recursive: true });
+    writeFileSync(join(root, path), "");
+    return join(root, path);
+  };
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+    root = mkdtempSync(join(tmpdir(), "cgr-public-api-"));
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    rmSync(root, { recursive: true, force: true });
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("lists a directory's exports, leaving out members of module-local types and tests", async () => {
+    const client = write("src/client.ts");
+    const clientTest = write("src/client.test.ts");
+
+    await agent.indexEntities(
+      [
+        entity("Client", "class", [1, 10], { visibility: "public" }),
+        entity("send", "method", [2, 4], { visibility: "public" }),
+        entity("secret", "method", [5, 7], { visibility: "private" }),
+        entity("Pool", "class", [12, 20], { visibility: "private" }),
+        entity("acquire", "method", [13, 15], { visibility: "public" }),
+        entity("connect", "function", [22, 24], { visibility: "public" }),
+      ],
+      client,
+    );
+    await agent.indexEntities([entity("mockClient", "function", [1, 3], { visibility: "public" })], clientTest);
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    const { result } = await listPublicApi(storage, join(root, "src"));
+    expect(result?.package).toBeNull();
+    expect(result?.files.map((file) => file.filePath)).toEqual([client]);
+    expect(result?.files[0]?.entities.map((e) => [e.name, e.owner])).toEqual([
+      ["Client", null],
+      ["send", "Client"],
+      ["connect", null],
+    ]);
+    expect(result?.total).toBe(3);
+
+    const functions = (await listPublicApi(storage, client, client, { kinds: ["function"] })).result;
+    expect(functions?.files[0]?.entities.map((e) => e.name)).toEqual(["connect"]);
+    const limited = (await listPublicApi(storage, client, client, { limit: 1 })).result;
+    expect([limited?.total, limited?.truncated]).toEqual([3, true]);
+    expect(await listPublicApi(storage, join(root, "missing"))).toEqual({ result: null, candidates: [] });
+  });
+
+  it("covers a Go package's files and hides methods of unexported receivers", async () => {
+    writeFileSync(join(root, "go.mod"), "module example.com/app\n\ngo 1.22\n");
+    const store = write("store/store.go");
+    const cache = write("store/cache.go");
+    const storeTest = write("store/store_test.go");
+
+    await agent.indexEntities(
+      [
+        entity("store", "module", [1, 1], { isPackage: true }),
+        entity("Store", "struct", [3, 5], { visibility: "public" }),
+        entity("Get", "method", [7, 9], { visibility: "public", receiver: "*Store" }),
+        entity("open", "function", [11, 13], { visibility: "internal" }),
+      ],
+      store,
+    );
+    await agent.indexEntities(
+      [
+        entity("store", "module", [1, 1], { isPackage: true }),
+        entity("lru", "struct", [3, 5], { visibility: "internal" }),
+        entity("Evict", "method", [7, 9], { visibility: "public", receiver: "*lru[K]" }),
+      ],
+      cache,
+    );
+    await agent.indexEntities(
+      [
+        entity("store", "module", [1, 1], { isPackage: true }),
+        entity("TestGet", "function", [3, 5], { visibility: "public" }),
+      ],
+      storeTest,
+    );
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    await buildPackageTree(storage, { roots: [root] });
+
+    const { result, candidates } = await listPublicApi(storage, "example.com/app/store");
+    expect(candidates.map((c) => c.importPath)).toEqual(["example.com/app/store"]);
+    expect(result?.package?.importPath).toBe("example.com/app/store");
+    expect(result?.files.map((file) => [file.filePath, file.entities.map((e) => [e.name, e.owner])])).toEqual([
+      [
+        store,
+        [
+          ["Store", null],
+          ["Get", "Store"],
+        ],
+      ],
+    ]);
+    expect((await listPublicApi(storage, store, store)).result?.package?.name).toBe("store");
+  });
+});
@@ -0,0 +1,116 @@
+import { existsSync, mkdtempSync, rmSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { join } from "node:path";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import type { GraphStorageImpl } from "../../src/storage/graph-storage.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { diffFileEntities, loadFileEntities } from "../../src/tools/reindex-file.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+import { type Entity, EntityType } from "../../src/types/storage.js";
+
+const TEST_DB_PATH = "./data/test-tool-reindex-file.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+/** One entity per `function name(...) {...}` in the source, with offsets into it */
+function functionsIn(code: string): ParsedEntity[] {
+  return Array.from(code.matchAll(/function (\w+)(\([^)]*\)) \{[^}]*\}/g), (match) => {
+    const start = match.index ?? 0;
+    const line = code.slice(0, start).split("\n").length;
+    return {
+      name: match[1]!,
+      type: "function",
+      signature: match[2],
+      language: "typescript",
+      location: {
+        start: { line, column: 0, index: start },
+        end: { line, column: match[0].length, index: start + match[0].length },
+      },
+    } as ParsedEntity;
+  });
+}
+
+describe("reindex_file entity diff", () => {
+  let agent: IndexerAgent;
+  let storage: GraphStorageImpl;
+  let root: string;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+    storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    root = mkdtempSync(join(tmpdir(), "reindex-file-"));
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    rmSync(root, { recursive: true, force: true });
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  async function index(path: string, code: string) {
+    writeFileSync(path, code);
+    await agent.indexEntities(functionsIn(code), path, [], { replaceFile: true, rootDir: root });
+  }
+
+  it("lists added, removed and modified entities and counts the rest as unchanged", async () => {
+    const file = join(root, "session.ts");
+    await index(file, "function keep() { return 1; }\nfunction edit() { return 2; }\nfunction drop() { return 3; }\n");
+    const before = await loadFileEntities(storage, file);
+
+    // `keep` only moves down a line; `edit` gets a new body; `drop` goes; `fresh` is new
+    await index(file, "function fresh(a) { return a; }\nfunction keep() { return 1; }\nfunction edit() { return; }\n");
+    const changes = diffFileEntities(before, await loadFileEntities(storage, file));
+
+    expect(changes.added.map((e) => e.name)).toEqual(["fresh"]);
+    expect(changes.removed.map((e) => e.name)).toEqual(["drop"]);
+    expect(changes.modified).toEqual([expect.objectContaining({ name: "edit", changes: ["body"] })]);
+    expect(changes.unchanged).toBe(1);
+  });
+
+  it("reports nothing when the file is re-indexed as it was", async () => {
+    const file = join(root, "same.ts");
+    const code = "function a() { return 1; }\nfunction b() { return 2; }\n";
+    await index(file, code);
+    const before = await loadFileEntities(storage, file);
+    await index(file, code);
+
+    expect(diffFileEntities(before, await loadFileEntities(storage, file))).toEqual({
+      added: [],
+      removed: [],
+      modified: [],
+      unchanged: 2,
+    });
+  });
+
+  it("loads every entity of a file past the query limit", async () => {
+    const file = join(root, "generated.ts");
+    const now = Date.now();
+    const entities: Entity[] = Array.from({ length: 1205 }, (_, i) => ({
+      id: `gen-${String(i).padStart(4, "0")}`,
+      name: `value${i}`,
+      type: EntityType.CONSTANT,
+      filePath: file,
+      location: { start: { line: i + 1, column: 0 }, end: { line: i + 1, column: 10 } },
+      hash: "h",
+      createdAt: now,
+      updatedAt: now,
+    }));
+    await storage.insertEntities(entities);
+
+    const loaded = await loadFileEntities(storage, file);
+    expect(loaded).toHaveLength(1205);
+    expect(new Set(loaded.map((e) => e.id)).size).toBe(1205);
+  });
+});
@@ -0,0 +1,44 @@
+import { describe, expect, it } from "@jest/globals";
+import { attachSearchHighlights, highlightTerms, termSpans } from "../../src/tools/search-highlights.js";
+
+const SNIPPET = `export function parseConfig(path: string) {
+  // Reads the YAML config
+  return loadHTTPConfig(path);
+}`;
+
+describe("search highlights", () => {
+  it("splits the query into distinct words, longest first", () => {
+    expect(highlightTerms("parse Config, parser ")).toEqual(["config", "parser", "parse"]);
+    expect(highlightTerms("  ")).toEqual([]);
+  });
+
+  it("finds query words at word starts and camelCase parts", () => {
+    const spans = termSpans(SNIPPET, highlightTerms("config parse"), 10);
+    const found = spans.map((span) =>
+      span.kind === "term" ? [span.term, span.line, SNIPPET.slice(span.start, span.end)] : [],
+    );
+    expect(found).toEqual([
+      ["parse", 10, "parse"],
+      ["config", 10, "Config"],
+      ["config", 11, "config"],
+      ["config", 12, "Config"],
+    ]);
+    // Only part starts count: "fig" inside "Config" is not a match
+    expect(termSpans(SNIPPET, ["fig"], 1)).toEqual([]);
+  });
+
+  it("adds the matched window of chunked hits and skips hits without a snippet", () => {
+    const [windowed, bare] = attachSearchHighlights(
+      [
+        { snippet: SNIPPET, startLine: 1, metadata: { matchedChunk: { index: 1, startLine: 2, endLine: 3 } } },
+        { snippet: null, startLine: null, metadata: {} },
+      ],
+      "yaml",
+    );
+    expect(windowed!.highlights).toEqual([
+      { kind: "chunk", startLine: 2, endLine: 3 },
+      { kind: "term", term: "yaml", start: SNIPPET.indexOf("YAML"), end: SNIPPET.indexOf("YAML") + 4, line: 2 },
+    ]);
+    expect(bare!.highlights).toEqual([]);
+  });
+});
@@ -0,0 +1,98 @@
+import { existsSync, rmSync } from "node:fs";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { searchSortKeys } from "../../src/tools/search-order.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+import { pageByScore } from "../../src/utils/cursor.js";
+import type { BlameLine } from "../../src/utils/git-blame.js";
+
+const TEST_DB_PATH = "./data/test-tool-search-order.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function fn(name: string, line: number, complexity?: number): ParsedEntity {
+  return {
+    name,
+    type: "function",
+    location: {
+      start: { line, column: 0, index: line * 10 },
+      end: { line, column: 20, index: line * 10 + 9 },
+    },
+    ...(complexity !== undefined ? { cyclomaticComplexity: complexity } : {}),
+  };
+}
+
+function blame(time: number): BlameLine {
+  return { commit: "a".repeat(40), author: "Ada", email: "ada@example.com", time, summary: "change" };
+}
+
+type Hit = { id: string; entityId: string; score: number; metadata: Record<string, unknown> };
+
+describe("searchSortKeys", () => {
+  let agent: IndexerAgent;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  async function hits(): Promise<Hit[]> {
+    await agent.indexEntities([fn("parse", 1, 4), fn("render", 2, 9)], "/tmp/app/src/ui.ts", [], {
+      blame: [blame(1_700_000_000), blame(1_710_000_000)],
+    });
+    await agent.indexEntities([fn("helper", 1, 4)], "/tmp/app/lib/util.ts", [], { blame: [blame(1_710_000_000)] });
+    await agent.indexEntities([fn("fresh", 1)], "/tmp/app/lib/new.ts");
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    const all = await storage.findEntities({ type: "entity", filters: {}, limit: 100 });
+    // Near-ties: every hit scores alike
+    return all
+      .filter((e) => e.type === "function")
+      .map((e) => ({ id: `vec:${e.name}`, entityId: e.id, score: 0.5, metadata: { name: e.name } }));
+  }
+
+  const scoreKey = (hit: Hit) => ({ s: hit.score, id: hit.entityId });
+  const names = (page: Hit[]) => page.map((hit) => hit.metadata.name);
+
+  it("orders by recency, complexity or path and breaks ties by entity id", async () => {
+    const found = await hits();
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    const ordered = async (sortBy: "score" | "recency" | "complexity" | "path") => {
+      const { keyOf, missing } = await searchSortKeys(storage, found, sortBy, scoreKey);
+      return { names: names(pageByScore(found, keyOf, null, 10).page), missing };
+    };
+    const byId = [...found].sort((a, b) => (a.entityId < b.entityId ? -1 : 1));
+    const tied = (...group: string[]) => byId.map((h) => h.metadata.name).filter((n) => group.includes(String(n)));
+
+    expect(await ordered("score")).toEqual({ names: names(byId), missing: 0 });
+    expect(await ordered("recency")).toEqual({ names: [...tied("render", "helper"), "parse", "fresh"], missing: 1 });
+    expect(await ordered("complexity")).toEqual({ names: ["render", ...tied("parse", "helper"), "fresh"], missing: 1 });
+    expect((await ordered("path")).names).toEqual(["fresh", "helper", ...tied("parse", "render")]);
+  });
+
+  it("pages a sorted order without repeating or skipping", async () => {
+    const found = await hits();
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    const { keyOf } = await searchSortKeys(storage, found, "recency", scoreKey);
+
+    const first = pageByScore(found, keyOf, null, 2);
+    const second = pageByScore(found, keyOf, first.next, 2);
+    expect([...names(first.page), ...names(second.page)]).toEqual(names(pageByScore(found, keyOf, null, 10).page));
+    expect(second.next).toBeNull();
+  });
+});
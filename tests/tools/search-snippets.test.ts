@@ -0,0 +1,75 @@
+import { existsSync, rmSync } from "node:fs";
+import { join } from "node:path";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { attachSearchSnippets } from "../../src/tools/search-snippets.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+
+const TEST_DB_PATH = "./data/test-tool-search-snippets.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+const FIXTURE = join(process.cwd(), "tests", "fixtures", "entity-source.ts");
+
+function e(name: string, line: number): ParsedEntity {
+  return {
+    name,
+    type: "function",
+    location: {
+      start: { line, column: 0, index: line * 10 },
+      end: { line: line + 2, column: 0, index: line * 10 + 5 },
+    },
+  } as any;
+}
+
+describe("attachSearchSnippets", () => {
+  let agent: IndexerAgent;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+    await agent.indexEntities([e("add", 1), e("mul", 5)], FIXTURE);
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("attaches body or signature with the entity's line range", async () => {
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    const add = (await storage.findEntities({ type: "entity", filters: { name: "add" }, limit: 1 }))[0]!;
+    const hits = [
+      { id: "ent:add", entityId: add.id, metadata: {} },
+      // Older embeddings only know the path and name
+      { id: "vec:1", metadata: { path: FIXTURE, name: "mul" } },
+      { id: "vec:2", metadata: { path: "/gone.ts", name: "nothing" } },
+    ];
+
+    const full = await attachSearchSnippets(storage, hits, { mode: "full", maxLines: 40 });
+    expect(full[0]).toMatchObject({ filePath: FIXTURE, startLine: 1, endLine: 3, snippetTruncated: false });
+    expect(full[0]?.snippet).toBe("export function add(a: number, b: number) {\n  return a + b;\n}");
+    expect(full[1]).toMatchObject({ startLine: 5, snippet: expect.stringContaining("return a * b;") });
+    expect(full[2]).toMatchObject({ filePath: "/gone.ts", startLine: null, snippet: null });
+
+    const signatures = await attachSearchSnippets(storage, hits.slice(0, 1), { mode: "signature", maxLines: 40 });
+    expect(signatures[0]).toMatchObject({
+      snippet: "export function add(a: number, b: number) {",
+      snippetTruncated: false,
+    });
+
+    const capped = await attachSearchSnippets(storage, hits.slice(0, 1), { mode: "full", maxLines: 2 });
+    expect(capped[0]).toMatchObject({ snippetTruncated: true, endLine: 3 });
+    expect(capped[0]?.snippet?.split("\n")).toHaveLength(2);
+  });
+});
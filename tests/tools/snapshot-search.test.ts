@@ -0,0 +1,126 @@
+import { existsSync, rmSync } from "node:fs";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import type { GraphStorageImpl } from "../../src/storage/graph-storage.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { searchSnapshot } from "../../src/tools/snapshot-search.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+
+const TEST_DB_PATH = "./data/test-tool-snapshot-search.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+function fn(name: string, line: number, signature: string): ParsedEntity {
+  return {
+    name,
+    type: "function",
+    signature,
+    location: {
+      start: { line, column: 0, index: line * 10 },
+      end: { line: line + 3, column: 0, index: line * 10 + 9 },
+    },
+  } as ParsedEntity;
+}
+
+// One axis per topic, so the nearest vector to a query is known
+const TOPICS: Record<string, number[]> = {
+  chargeCard: [1, 0, 0],
+  refundPayment: [0.8, 0.6, 0],
+  renderInvoice: [0, 0, 1],
+};
+
+describe("searchSnapshot", () => {
+  let agent: IndexerAgent;
+  let storage: GraphStorageImpl;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+    storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    await agent.indexEntities(
+      [fn("chargeCard", 1, "(card: Card)"), fn("refundPayment", 5, "(id: string)")],
+      "/repo/billing.ts",
+    );
+    await agent.indexEntities([fn("renderInvoice", 1, "(invoice: Invoice)")], "/repo/invoice.ts");
+    await storage.createSnapshot("v1");
+    const entities = await storage.getSnapshotEntities("v1");
+    async function* batches() {
+      yield entities.map((e) => ({ entityId: e.id, vector: new Float32Array(TOPICS[e.name]!) }));
+    }
+    await storage.saveSnapshotVectors("v1", "test:model:3", batches());
+
+    // The live index moves on: chargeCard is gone, so only the snapshot still knows it
+    await agent.indexEntities([fn("refundPayment", 5, "(id: string)")], "/repo/billing.ts", [], {
+      replaceFile: true,
+    });
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("keeps vectors for the snapshot's own entities and records their source", async () => {
+    expect(await storage.getSnapshot("v1")).toMatchObject({
+      entityCount: 3,
+      vectorCount: 3,
+      vectorSource: "test:model:3",
+    });
+    const live = await storage.executeQuery({ type: "entity", filters: { name: "chargeCard" } });
+    expect(live.entities).toEqual([]);
+  });
+
+  it("ranks the snapshot's entities by the vectors kept with it", async () => {
+    const { hits, belowThreshold } = await searchSnapshot(storage, "v1", {
+      query: "take money",
+      mode: "semantic",
+      queryVector: new Float32Array([1, 0, 0]),
+      limit: 10,
+      minScore: 0.2,
+    });
+
+    expect(hits.map((hit) => [hit.name, hit.matchedBy])).toEqual([
+      ["chargeCard", ["semantic"]],
+      ["refundPayment", ["semantic"]],
+    ]);
+    expect(hits[0]!.cosine).toBeCloseTo(1);
+    expect(hits[0]!.filePath).toBe("/repo/billing.ts");
+    expect(belowThreshold).toBe(1);
+  });
+
+  it("matches names and signatures without vectors and fuses both in hybrid mode", async () => {
+    const keyword = await searchSnapshot(storage, "v1", { query: "invoice", mode: "keyword", limit: 10 });
+    expect(keyword.hits.map((hit) => hit.name)).toEqual(["renderInvoice"]);
+
+    const hybrid = await searchSnapshot(storage, "v1", {
+      query: "chargeCard",
+      mode: "hybrid",
+      queryVector: new Float32Array([0.8, 0.6, 0]),
+      limit: 10,
+      minScore: 0.2,
+      scope: { pathPrefix: "/repo/billing.ts" },
+    });
+    expect(hybrid.hits.map((hit) => [hit.name, hit.matchedBy])).toEqual([
+      ["chargeCard", ["semantic", "keyword"]],
+      ["refundPayment", ["semantic"]],
+    ]);
+  });
+
+  it("drops the vectors with the snapshot", async () => {
+    await storage.deleteSnapshot("v1");
+    const left: unknown[] = [];
+    for await (const row of storage.iterateSnapshotVectors("v1")) left.push(row);
+    expect(left).toEqual([]);
+  });
+});
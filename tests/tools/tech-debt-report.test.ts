@@ -0,0 +1,115 @@
+import { existsSync, rmSync } from "node:fs";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import { IndexerAgent } from "../../src/agents/indexer-agent.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { techDebtReport } from "../../src/tools/tech-debt-report.js";
+import { AgentStatus } from "../../src/types/agent.js";
+import type { ParsedEntity } from "../../src/types/parser.js";
+import type { BlameLine } from "../../src/utils/git-blame.js";
+
+const TEST_DB_PATH = "./data/test-tool-tech-debt-report.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+
+const NOW = new Date("2026-01-31T00:00:00Z");
+const DAY = 86_400;
+
+function comment(line: number, markers: Array<Record<string, unknown>>) {
+  return {
+    name: `${markers[0]!.kind}: ${markers[0]!.text}`,
+    type: "comment",
+    location: {
+      start: { line, column: 0, index: line * 10 },
+      end: { line, column: 0, index: line * 10 + 5 },
+    },
+    metadata: { style: "line", markers },
+  } as ParsedEntity;
+}
+
+function blameBy(author: string, daysAgo: number): BlameLine {
+  return {
+    commit: author[0]!.toLowerCase().repeat(40),
+    author,
+    email: `${author.toLowerCase()}@example.com`,
+    time: NOW.getTime() / 1000 - daysAgo * DAY,
+    summary: "wip",
+  };
+}
+
+describe("techDebtReport", () => {
+  let agent: IndexerAgent;
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    agent = new IndexerAgent(getSQLiteManager({ path: TEST_DB_PATH }));
+    await agent.initialize();
+
+    await agent.indexEntities(
+      [
+        comment(2, [{ kind: "TODO", line: 2, text: "validate input" }]),
+        comment(5, [{ kind: "FIXME", line: 5, text: "leaks connections" }]),
+      ],
+      "/repo/src/api.ts",
+    );
+    await agent.indexEntities([comment(1, [{ kind: "HACK", line: 1, text: "retry twice" }])], "/repo/lib/db.ts");
+    // Not blamed at report time; only the index-time blame of the comment dates it
+    await agent.indexEntities([comment(3, [{ kind: "XXX", line: 3, text: "unclear" }])], "/repo/lib/cache.ts", [], {
+      blame: [null, null, blameBy("Ada", 10)],
+    });
+    await agent.indexEntities([comment(1, [{ kind: "TODO", line: 1, text: "drop" }])], "/repo/tmp.ts");
+  });
+
+  afterEach(async () => {
+    if (agent && agent.status !== AgentStatus.SHUTDOWN) await agent.shutdown();
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  const blame = async (files: string[]) => {
+    const lines = new Map<string, Array<BlameLine | null>>([
+      ["/repo/src/api.ts", [null, blameBy("Ada", 400), null, null, blameBy("Grace", 30)]],
+      ["/repo/lib/db.ts", [blameBy("Grace", 90)]],
+      // Blamed, but the marker line is not committed yet
+      ["/repo/tmp.ts", [null]],
+    ]);
+    return new Map([...lines].filter(([file]) => files.includes(file)));
+  };
+
+  it("dates each marker by its line's blame, oldest first", async () => {
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    const report = await techDebtReport(storage, { root: "/repo", now: NOW, blame });
+
+    expect(report.items.map((item) => [item.text, item.introduced?.author ?? null, item.ageDays])).toEqual([
+      ["validate input", "Ada", 400],
+      ["retry twice", "Grace", 90],
+      ["leaks connections", "Grace", 30],
+      ["unclear", "Ada", 10],
+      ["drop", null, null],
+    ]);
+    expect(report).toMatchObject({ total: 5, dated: 4, truncated: false });
+  });
+
+  it("groups markers by author and by directory", async () => {
+    const storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+    const report = await techDebtReport(storage, { root: "/repo", now: NOW, blame, limit: 1 });
+
+    expect(report.items).toHaveLength(1);
+    expect(report.truncated).toBe(true);
+    expect(report.byAuthor).toEqual([
+      { key: "Ada", email: "ada@example.com", markers: 2, dated: 2, oldestDays: 400, averageAgeDays: 205 },
+      { key: "Grace", email: "grace@example.com", markers: 2, dated: 2, oldestDays: 90, averageAgeDays: 60 },
+    ]);
+    expect(report.byDirectory.map((group) => [group.key, group.markers, group.oldestDays])).toEqual([
+      ["src", 2, 400],
+      ["lib", 2, 90],
+      [".", 1, null],
+    ]);
+  });
+});
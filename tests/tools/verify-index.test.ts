@@ -0,0 +1,115 @@
+import { existsSync, rmSync } from "node:fs";
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import type { GraphStorageImpl } from "../../src/storage/graph-storage.js";
+import { getGraphStorage, resetGraphStorage } from "../../src/storage/graph-storage-factory.js";
+import type { SchemaStatus } from "../../src/storage/schema-migrations.js";
+import { getSQLiteManager, resetSQLiteManager } from "../../src/storage/sqlite-manager.js";
+import { type VectorEntityRef, verifyIndex } from "../../src/tools/verify-index.js";
+import { type Entity, EntityType, RelationType } from "../../src/types/storage.js";
+
+const TEST_DB_PATH = "./data/test-tool-verify-index.db";
+const CLEANUP_PATHS = [TEST_DB_PATH, `${TEST_DB_PATH}-shm`, `${TEST_DB_PATH}-wal`];
+const CURRENT: SchemaStatus = { version: 9, latestVersion: 9, pending: [], modified: [] };
+
+function entity(id: string): Entity {
+  return {
+    id,
+    name: id,
+    type: EntityType.FUNCTION,
+    filePath: "/repo/main.ts",
+    location: { start: { line: 1, column: 0 }, end: { line: 2, column: 0 } },
+    hash: "h",
+    createdAt: 1000,
+    updatedAt: 1000,
+  };
+}
+
+describe("verifyIndex", () => {
+  let storage: GraphStorageImpl;
+  let vectors: VectorEntityRef[];
+  const vectorStore = {
+    list: async () => vectors,
+    remove: async (ids: string[]) => {
+      const before = vectors.length;
+      vectors = vectors.filter((ref) => !ids.includes(ref.id));
+      return before - vectors.length;
+    },
+  };
+
+  beforeEach(async () => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+    storage = await getGraphStorage(getSQLiteManager({ path: TEST_DB_PATH }));
+
+    await storage.insertEntities([entity("main"), entity("helper")]);
+    await storage.insertRelationships([{ id: "r1", fromId: "main", toId: "helper", type: RelationType.CALLS }]);
+    // Edges an interrupted run left behind, written past the foreign keys
+    const db = getSQLiteManager({ path: TEST_DB_PATH }).getConnection();
+    db.pragma("foreign_keys = OFF");
+    const insert = db.prepare("INSERT INTO relationships (id, from_id, to_id, type) VALUES (?, ?, ?, ?)");
+    insert.run("r2", "main", "gone", RelationType.CALLS);
+    insert.run("r3", "lost", "helper", RelationType.REFERENCES);
+    db.pragma("foreign_keys = ON");
+    vectors = [
+      { id: "ent:main", entityId: "main" },
+      { id: "ent:gone", entityId: "gone" },
+      { id: "body:gone", entityId: "gone" },
+    ];
+  });
+
+  afterEach(() => {
+    for (const p of CLEANUP_PATHS) {
+      if (existsSync(p)) rmSync(p);
+    }
+    resetGraphStorage();
+    resetSQLiteManager();
+  });
+
+  it("reports dangling relationships and orphaned vectors without touching them", async () => {
+    const report = await verifyIndex(storage, { schema: CURRENT, vectors: vectorStore });
+
+    expect(report.ok).toBe(false);
+    expect(report.relationships).toEqual({
+      dangling: 2,
+      removed: 0,
+      sample: [
+        { id: "r2", type: RelationType.CALLS, fromId: "main", toId: "gone", missing: "to" },
+        { id: "r3", type: RelationType.REFERENCES, fromId: "lost", toId: "helper", missing: "from" },
+      ],
+    });
+    expect(report.vectors).toMatchObject({ checked: 3, orphaned: 2, removed: 0 });
+    expect(report.vectors?.sample.map((ref) => ref.id)).toEqual(["ent:gone", "body:gone"]);
+    expect(report.problems).toEqual([
+      "2 relationships point at entities that are not stored",
+      "2 vectors belong to entities that are not stored",
+    ]);
+    expect((await storage.findDanglingRelationships()).total).toBe(2);
+  });
+
+  it("repairs what it found and leaves the rest of the graph alone", async () => {
+    const repaired = await verifyIndex(storage, { schema: CURRENT, vectors: vectorStore, repair: true });
+    expect(repaired.ok).toBe(true);
+    expect(repaired.relationships.removed).toBe(2);
+    expect(repaired.vectors?.removed).toBe(2);
+    expect(vectors).toEqual([{ id: "ent:main", entityId: "main" }]);
+    expect((await storage.getRelationshipsForEntity("main")).map((r) => r.id)).toEqual(["r1"]);
+
+    const again = await verifyIndex(storage, { schema: CURRENT, vectors: vectorStore });
+    expect(again.ok).toBe(true);
+    expect(again.problems).toEqual([]);
+  });
+
+  it("reports a schema mismatch that repair cannot fix", async () => {
+    const report = await verifyIndex(storage, {
+      schema: { version: 11, latestVersion: 9, pending: [], modified: [] },
+      repair: true,
+    });
+    expect(report.ok).toBe(false);
+    expect(report.vectors).toBeNull();
+    expect(report.schema.ok).toBe(false);
+    expect(report.problems).toContain("schema version 11 is newer than the 9 this server supports");
+  });
+});
@@ -0,0 +1,61 @@
+import { describe, expect, it } from "@jest/globals";
+import { codeWindows, collapseChunkHits, resolveChunking } from "../../src/semantic/chunking.js";
+
+function lines(count: number): string {
+  return Array.from({ length: count }, (_, i) => `line ${i + 1}`).join("\n");
+}
+
+describe("resolveChunking", () => {
+  it("defaults to one vector per entity and keeps the overlap below the window", () => {
+    expect(resolveChunking()).toEqual({ strategy: "entity", windowLines: 60, overlapLines: 0 });
+    expect(resolveChunking({ strategy: "bogus" as any, windowLines: 10, overlapLines: 40 })).toEqual({
+      strategy: "entity",
+      windowLines: 10,
+      overlapLines: 9,
+    });
+  });
+});
+
+describe("codeWindows", () => {
+  const options = resolveChunking({ strategy: "window", windowLines: 10, overlapLines: 2 });
+
+  it("leaves code that fits in one window alone", () => {
+    expect(codeWindows(lines(10), 1, options)).toEqual([]);
+  });
+
+  it("overlaps consecutive windows and numbers them by file line", () => {
+    const windows = codeWindows(lines(26), 101, options);
+    expect(windows.map((w) => [w.index, w.startLine, w.endLine])).toEqual([
+      [0, 101, 110],
+      [1, 109, 118],
+      [2, 117, 126],
+    ]);
+    expect(windows[1]!.text.split("\n")[0]).toBe("line 9");
+  });
+
+  it("merges a short tail into the window before it", () => {
+    const windows = codeWindows(lines(20), 1, options);
+    expect(windows.map((w) => [w.startLine, w.endLine])).toEqual([
+      [1, 10],
+      [9, 20],
+    ]);
+  });
+});
+
+describe("collapseChunkHits", () => {
+  it("returns each declaration once, under its own id, at its best score", () => {
+    const hits = [
+      { id: "chunk:ent:a:1", similarity: 0.9, metadata: { kind: "chunk", parentId: "ent:a", chunk: { index: 1 } } },
+      { id: "ent:b", similarity: 0.8, metadata: { entityId: "b" } },
+      { id: "ent:a", similarity: 0.7, metadata: { entityId: "a" } },
+      { id: "chunk:ent:a:0", similarity: 0.6, metadata: { kind: "chunk", parentId: "ent:a", chunk: { index: 0 } } },
+    ] as any[];
+
+    const collapsed = collapseChunkHits(hits);
+    expect(collapsed.map((hit) => [hit.id, hit.similarity])).toEqual([
+      ["ent:a", 0.9],
+      ["ent:b", 0.8],
+    ]);
+    expect(collapsed[0]!.metadata).toEqual({ matchedChunk: { index: 1 } });
+  });
+});
@@ -0,0 +1,37 @@
+import { describe, expect, it } from "@jest/globals";
+import { decodeCursor, encodeCursor, pageByScore, toScoreCursor } from "../../src/utils/cursor.js";
+
+type Hit = { id: string; score: number };
+const key = (hit: Hit) => ({ s: hit.score, id: hit.id });
+
+describe("pageByScore", () => {
+  const hits: Hit[] = [
+    { id: "c", score: 0.5 },
+    { id: "a", score: 0.9 },
+    { id: "b", score: 0.5 },
+    { id: "d", score: 0.1 },
+  ];
+
+  it("orders by score, then id, and resumes after the cursor", () => {
+    const first = pageByScore(hits, key, null, 2);
+    expect(first.page.map((h) => h.id)).toEqual(["a", "b"]);
+    expect(first.next).toEqual({ s: 0.5, id: "b" });
+
+    const cursor = toScoreCursor(decodeCursor(encodeCursor({ ...first.next })));
+    const second = pageByScore(hits, key, cursor, 2);
+    expect(second.page.map((h) => h.id)).toEqual(["c", "d"]);
+    expect(second.next).toBeNull();
+  });
+
+  it("neither repeats nor skips when a new hit ranks ahead of the cursor", () => {
+    const first = pageByScore(hits, key, null, 2);
+    const changed = [...hits, { id: "e", score: 0.95 }];
+    const second = pageByScore(changed, key, first.next, 2);
+    expect(second.page.map((h) => h.id)).toEqual(["c", "d"]);
+  });
+
+  it("ignores cursors without a score key", () => {
+    expect(toScoreCursor({ s: "0.5", id: "b" })).toBeNull();
+    expect(toScoreCursor(null)).toBeNull();
+  });
+});
@@ -0,0 +1,54 @@
+import { describe, expect, it } from "@jest/globals";
+import { lastModifiedIn, parseLinePorcelain } from "../../src/utils/git-blame.js";
+
+const A = "a".repeat(40);
+const B = "b".repeat(40);
+const UNCOMMITTED = "0".repeat(40);
+
+function porcelainLine(commit: string, author: string, time: number, source: string, lineNo: number): string {
+  return [
+    `${commit} ${lineNo} ${lineNo} 1`,
+    `author ${author}`,
+    `author-mail <${author.toLowerCase()}@example.com>`,
+    `author-time ${time}`,
+    "author-tz +0000",
+    `committer ${author}`,
+    `summary change by ${author}`,
+    "filename src/app.ts",
+    `\t${source}`,
+  ].join("\n");
+}
+
+const output = [
+  porcelainLine(A, "Ada", 1_700_000_000, "export function start() {", 1),
+  porcelainLine(B, "Grace", 1_710_000_000, "  return run();", 2),
+  porcelainLine(A, "Ada", 1_700_000_000, "}", 3),
+  porcelainLine(UNCOMMITTED, "Not Committed Yet", 1_720_000_000, "// wip", 4),
+].join("\n");
+
+describe("git blame", () => {
+  it("parses line porcelain into one entry per line, without uncommitted lines", () => {
+    const lines = parseLinePorcelain(output);
+    expect(lines).toHaveLength(4);
+    expect(lines[0]).toEqual({
+      commit: A,
+      author: "Ada",
+      email: "ada@example.com",
+      time: 1_700_000_000,
+      summary: "change by Ada",
+    });
+    expect(lines[1]?.author).toBe("Grace");
+    expect(lines[3]).toBeNull();
+  });
+
+  it("attributes a line range to its newest commit", () => {
+    const lines = parseLinePorcelain(output);
+    expect(lastModifiedIn(lines, 1, 3)).toMatchObject({
+      commit: B,
+      author: "Grace",
+      date: new Date(1_710_000_000 * 1000).toISOString(),
+    });
+    expect(lastModifiedIn(lines, 3, 3)?.author).toBe("Ada");
+    expect(lastModifiedIn(lines, 4, 9)).toBeNull();
+  });
+});
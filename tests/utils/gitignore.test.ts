@@ -0,0 +1,73 @@
+import { mkdirSync, mkdtempSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { join } from "node:path";
+import { describe, expect, it } from "@jest/globals";
+import { GitignoreMatcher, parseGitignore } from "../../src/utils/gitignore.js";
+
+function matcherFor(files: Record<string, string>): { root: string; matcher: GitignoreMatcher } {
+  const root = mkdtempSync(join(tmpdir(), "cgr-gitignore-"));
+  mkdirSync(join(root, ".git", "info"), { recursive: true });
+  for (const [path, content] of Object.entries(files)) {
+    mkdirSync(join(root, path, ".."), { recursive: true });
+    writeFileSync(join(root, path), content);
+  }
+  return { root, matcher: new GitignoreMatcher(root) };
+}
+
+describe("parseGitignore", () => {
+  it("skips comments and blank lines and honors escapes", () => {
+    const rules = parseGitignore("# comment\n\n\\#literal\n\\!bang\n*.log   \n");
+    expect(rules.map((r) => r.negated)).toEqual([false, false, false]);
+    expect(rules[0]!.regex.test("#literal")).toBe(true);
+    expect(rules[1]!.regex.test("!bang")).toBe(true);
+    expect(rules[2]!.regex.test("deep/dir/debug.log")).toBe(true);
+  });
+});
+
+describe("GitignoreMatcher", () => {
+  it("matches unanchored, anchored and double-star patterns", () => {
+    const { root, matcher } = matcherFor({
+      ".gitignore": "*.gen.ts\n/config.local.ts\ndocs/**/draft.md\nlogs/**\n",
+    });
+    const ignored = (p: string, dir = false) => matcher.isIgnored(join(root, p), dir);
+
+    expect(ignored("src/deep/api.gen.ts")).toBe(true);
+    expect(ignored("config.local.ts")).toBe(true);
+    expect(ignored("src/config.local.ts")).toBe(false);
+    expect(ignored("docs/draft.md")).toBe(true);
+    expect(ignored("docs/a/b/draft.md")).toBe(true);
+    expect(ignored("logs/today.txt")).toBe(true);
+    expect(ignored("src/app.ts")).toBe(false);
+  });
+
+  it("applies directory-only patterns to directories only", () => {
+    const { root, matcher } = matcherFor({ ".gitignore": "generated/\n" });
+
+    expect(matcher.isIgnored(join(root, "src", "generated"), true)).toBe(true);
+    expect(matcher.isIgnored(join(root, "src", "generated"), false)).toBe(false);
+  });
+
+  it("lets negations and nested .gitignore files override earlier rules", () => {
+    const { root, matcher } = matcherFor({
+      ".gitignore": "*.js\n!keep.js\n",
+      "packages/web/.gitignore": "!*.js\nlocal.ts\n",
+      ".git/info/exclude": "scratch.ts\n",
+    });
+    const ignored = (p: string) => matcher.isIgnored(join(root, p));
+
+    expect(ignored("src/index.js")).toBe(true);
+    expect(ignored("src/keep.js")).toBe(false);
+    expect(ignored("packages/web/bundle.js")).toBe(false);
+    expect(ignored("packages/web/local.ts")).toBe(true);
+    expect(ignored("packages/api/local.ts")).toBe(false);
+    expect(ignored("scratch.ts")).toBe(true);
+  });
+
+  it("applies .gitignore files above the indexed directory inside the same checkout", () => {
+    const { root } = matcherFor({ ".gitignore": "*.snap\n", "app/src/main.ts": "" });
+    const matcher = new GitignoreMatcher(join(root, "app"));
+
+    expect(matcher.isIgnored(join(root, "app", "src", "view.snap"))).toBe(true);
+    expect(matcher.isIgnored(join(root, "app", "src", "main.ts"))).toBe(false);
+  });
+});
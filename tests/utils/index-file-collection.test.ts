@@ -1,8 +1,13 @@
-import { mkdirSync, mkdtempSync, writeFileSync } from "node:fs";
+import { mkdirSync, mkdtempSync, symlinkSync, writeFileSync } from "node:fs";
 import { tmpdir } from "node:os";
 import { join } from "node:path";
 import { describe, expect, it } from "@jest/globals";
-import { collectIndexableFiles, DEFAULT_INDEX_EXCLUDE_PATTERNS } from "../../src/utils/index-file-collection.js";
+import {
+  checkIndexableContent,
+  collectIndexableFiles,
+  createIndexPathFilter,
+  DEFAULT_INDEX_EXCLUDE_PATTERNS,
+} from "../../src/utils/index-file-collection.js";
 
 describe("collectIndexableFiles", () => {
   it("includes markdown by default and respects default excludes", () => {
@@ -33,4 +38,157 @@ describe("collectIndexableFiles", () => {
     expect(result.stats.includedFiles).toBe(result.files.length);
     expect(result.stats.scannedFiles).toBeGreaterThanOrEqual(result.files.length);
   });
+
+  it("skips .gitignore'd paths unless disabled", () => {
+    const root = mkdtempSync(join(tmpdir(), "cgr-index-"));
+
+    mkdirSync(join(root, "src", "generated"), { recursive: true });
+    writeFileSync(join(root, ".gitignore"), "generated/\n*.gen.ts\n!keep.gen.ts\n");
+    writeFileSync(join(root, "src", "app.ts"), "export const x = 1;\n");
+    writeFileSync(join(root, "src", "api.gen.ts"), "export const y = 1;\n");
+    writeFileSync(join(root, "src", "keep.gen.ts"), "export const z = 1;\n");
+    writeFileSync(join(root, "src", "generated", "schema.ts"), "export const s = 1;\n");
+
+    const names = (files: string[]) => files.map((f) => f.slice(root.length + 1).split("\\").join("/")).sort();
+
+    const result = collectIndexableFiles(root, [...DEFAULT_INDEX_EXCLUDE_PATTERNS]);
+    expect(names(result.files)).toEqual(["src/app.ts", "src/keep.gen.ts"]);
+    expect(result.stats.excludedByGitignore).toBe(2);
+
+    const all = collectIndexableFiles(root, [...DEFAULT_INDEX_EXCLUDE_PATTERNS], { respectGitignore: false });
+    expect(names(all.files)).toEqual(["src/api.gen.ts", "src/app.ts", "src/generated/schema.ts", "src/keep.gen.ts"]);
+
+    const accepts = createIndexPathFilter(root, [...DEFAULT_INDEX_EXCLUDE_PATTERNS]);
+    expect(accepts(join(root, "src", "generated", "schema.ts"))).toBe(false);
+    expect(accepts(join(root, "src", "keep.gen.ts"))).toBe(true);
+  });
+
+  it("lists each real file once and leaves directory links and links out of the root alone", () => {
+    const root = mkdtempSync(join(tmpdir(), "cgr-index-"));
+    const outside = mkdtempSync(join(tmpdir(), "cgr-outside-"));
+
+    mkdirSync(join(root, "src"), { recursive: true });
+    mkdirSync(join(root, "lib"), { recursive: true });
+    mkdirSync(join(root, "node_modules", "pkg"), { recursive: true });
+    writeFileSync(join(root, "src", "app.ts"), "export const x = 1;\n");
+    writeFileSync(join(root, "lib", "util.ts"), "export const u = 1;\n");
+    writeFileSync(join(root, "node_modules", "pkg", "index.ts"), "export const p = 1;\n");
+    writeFileSync(join(outside, "ext.ts"), "export const e = 1;\n");
+    symlinkSync(join(root, "src", "app.ts"), join(root, "src", "alias.ts"));
+    symlinkSync(join(root, "lib"), join(root, "src", "linked"), "dir");
+    symlinkSync(join(root, "node_modules", "pkg", "index.ts"), join(root, "src", "pkg.ts"));
+    symlinkSync(join(outside, "ext.ts"), join(root, "src", "ext.ts"));
+    symlinkSync(join(root, "missing.ts"), join(root, "src", "broken.ts"));
+
+    const names = (files: string[]) => files.map((f) => f.slice(root.length + 1).split("\\").join("/")).sort();
+    const result = collectIndexableFiles(root, [...DEFAULT_INDEX_EXCLUDE_PATTERNS]);
+
+    // pkg.ts is the only path to a file the walk prunes, so the link is kept
+    expect(names(result.files)).toEqual(["lib/util.ts", "src/app.ts", "src/pkg.ts"]);
+    expect(result.stats.symlinks).toMatchObject({ skipped: 1, outsideRoot: 1, broken: 1, duplicates: 1 });
+
+    const allowed = collectIndexableFiles(root, [...DEFAULT_INDEX_EXCLUDE_PATTERNS], {
+      symlinks: { allowOutsideRoot: true },
+    });
+    expect(names(allowed.files)).toContain("src/ext.ts");
+  });
+
+  it("follows directory links when asked without walking a directory twice", () => {
+    const root = mkdtempSync(join(tmpdir(), "cgr-index-"));
+    const outside = mkdtempSync(join(tmpdir(), "cgr-outside-"));
+
+    mkdirSync(join(root, "a"), { recursive: true });
+    writeFileSync(join(root, "a", "x.ts"), "export const x = 1;\n");
+    writeFileSync(join(outside, "o.ts"), "export const o = 1;\n");
+    symlinkSync(root, join(root, "a", "loop"), "dir");
+    symlinkSync(join(root, "a"), join(root, "c"), "dir");
+    symlinkSync(outside, join(root, "b"), "dir");
+
+    const names = (files: string[]) => files.map((f) => f.slice(root.length + 1).split("\\").join("/")).sort();
+    const followed = collectIndexableFiles(root, [...DEFAULT_INDEX_EXCLUDE_PATTERNS], {
+      symlinks: { followSymlinks: true },
+    });
+    expect(names(followed.files)).toEqual(["a/x.ts"]);
+    expect(followed.stats.symlinks).toMatchObject({ followed: 2, cycles: 2, outsideRoot: 1 });
+
+    const everywhere = collectIndexableFiles(root, [...DEFAULT_INDEX_EXCLUDE_PATTERNS], {
+      symlinks: { followSymlinks: true, allowOutsideRoot: true },
+    });
+    expect(names(everywhere.files)).toEqual(["a/x.ts", "b/o.ts"]);
+  });
+
+  it("includes extensionless scripts by their content only when asked", () => {
+    const root = mkdtempSync(join(tmpdir(), "cgr-index-"));
+
+    mkdirSync(join(root, "bin"), { recursive: true });
+    writeFileSync(join(root, "bin", "deploy"), "#!/usr/bin/env python3\nimport sys\n");
+    writeFileSync(join(root, "bin", "run.sh"), "#!/bin/sh\necho run\n");
+    writeFileSync(join(root, "LICENSE"), "MIT License\n");
+
+    expect(collectIndexableFiles(root, []).files).toEqual([]);
+
+    const result = collectIndexableFiles(root, [], { detectLanguageByContent: true });
+    expect(result.files).toEqual([join(root, "bin", "deploy")]);
+    expect(result.stats).toMatchObject({ detectedByContent: 1, unsupportedExtensions: 2 });
+    expect(createIndexPathFilter(root, [], { detectLanguageByContent: true })(join(root, "bin", "deploy"))).toBe(true);
+  });
+});
+
+describe("createIndexPathFilter", () => {
+  it("applies the same rules as collection to single paths", () => {
+    const root = mkdtempSync(join(tmpdir(), "cgr-filter-"));
+    const accepts = createIndexPathFilter(root, [...DEFAULT_INDEX_EXCLUDE_PATTERNS]);
+
+    expect(accepts(join(root, "src", "app.ts"))).toBe(true);
+    expect(accepts(join(root, "node_modules", "left-pad", "index.js"))).toBe(false);
+    expect(accepts(join(root, "notes.txt"))).toBe(false);
+    expect(accepts(join(root, "src"), true)).toBe(true);
+    expect(accepts(join(root, "node_modules"), true)).toBe(false);
+    expect(accepts(join(tmpdir(), "elsewhere.ts"))).toBe(false);
+  });
+});
+
+describe("checkIndexableContent", () => {
+  it("skips files over the size limit and files containing NUL bytes", () => {
+    const root = mkdtempSync(join(tmpdir(), "cgr-content-"));
+    const source = join(root, "app.ts");
+    const large = join(root, "bundle.js");
+    const binary = join(root, "image.ts");
+
+    writeFileSync(source, "export const x = 1;\n");
+    writeFileSync(large, "x".repeat(2048));
+    writeFileSync(binary, Buffer.from([0x47, 0x49, 0x46, 0x00, 0x01]));
+
+    expect(checkIndexableContent(source, 1024)).toBeNull();
+    expect(checkIndexableContent(large, 1024)).toEqual({ filePath: large, reason: "too_large", sizeBytes: 2048 });
+    expect(checkIndexableContent(large, 0)).toBeNull();
+    expect(checkIndexableContent(binary, 1024)).toEqual({ filePath: binary, reason: "binary", sizeBytes: 5 });
+    expect(checkIndexableContent(join(root, "missing.ts"))?.reason).toBe("unreadable");
+  });
+
+  it("skips generated and minified files only when asked to", () => {
+    const root = mkdtempSync(join(tmpdir(), "cgr-generated-"));
+    const handwritten = join(root, "service.go");
+    const header = join(root, "enum_string.go");
+    const protobuf = join(root, "user.pb.go");
+    const minified = join(root, "vendor.js");
+    const mentioned = join(root, "notes.ts");
+
+    writeFileSync(handwritten, "package users\n\nfunc Find() {}\n");
+    writeFileSync(header, "// Code generated by \"stringer -type=Kind\"; DO NOT EDIT.\n\npackage users\n");
+    writeFileSync(protobuf, "package users\n");
+    writeFileSync(minified, `${"var a=1;".repeat(300)}\n${"var b=2;".repeat(300)}\n`);
+    writeFileSync(mentioned, `${"// filler\n".repeat(25)}// "Code generated ... DO NOT EDIT." files are skipped\n`);
+
+    expect(checkIndexableContent(handwritten, 0, true)).toBeNull();
+    expect(checkIndexableContent(header, 0, true)).toMatchObject({
+      reason: "generated",
+      generated: { detectedBy: "header", evidence: '// Code generated by "stringer -type=Kind"; DO NOT EDIT.' },
+    });
+    expect(checkIndexableContent(protobuf, 0, true)?.generated).toEqual({ detectedBy: "path", evidence: "*.pb.go" });
+    expect(checkIndexableContent(minified, 0, true)?.generated?.detectedBy).toBe("minified");
+    // Only a file's first lines can carry a generator header
+    expect(checkIndexableContent(mentioned, 0, true)).toBeNull();
+    expect(checkIndexableContent(header, 0)).toBeNull();
+  });
 });
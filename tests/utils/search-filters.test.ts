@@ -0,0 +1,152 @@
+import { describe, expect, it } from "@jest/globals";
+import { matchesSearchScope, searchScopeSql, toSqliteGlob } from "../../src/storage/search-filters.js";
+
+describe("searchScopeSql", () => {
+  it("returns null without filters", () => {
+    expect(searchScopeSql(undefined, { kind: "type", path: "file_path" })).toBeNull();
+    expect(searchScopeSql({ kinds: [] }, { kind: "type", path: "file_path" })).toBeNull();
+  });
+
+  it("compiles every filter into one predicate", () => {
+    const compiled = searchScopeSql(
+      { kinds: ["class"], languages: ["go"], pathPrefix: "/repo/pkg/" },
+      { kind: "e.type", path: "e.file_path" },
+    );
+    expect(compiled?.sql).toBe(
+      "e.type IN (?) AND (e.file_path GLOB ?) AND (e.file_path = ? OR substr(e.file_path, 1, ?) = ?)",
+    );
+    expect(compiled?.params).toEqual(["class", "*.go", "/repo/pkg", 10, "/repo/pkg/"]);
+  });
+
+  it("compiles pathPrefix and directory into one clause each", () => {
+    const scope = { pathPrefix: "/repo/api", directory: "/repo/api/users/" };
+    const compiled = searchScopeSql(scope, { kind: "type", path: "p" });
+    expect(compiled).toEqual({
+      sql: "(p = ? OR substr(p, 1, ?) = ?) AND (p = ? OR substr(p, 1, ?) = ?)",
+      params: ["/repo/api", 10, "/repo/api/", "/repo/api/users", 16, "/repo/api/users/"],
+    });
+  });
+
+  it("matches nothing for an unknown language", () => {
+    expect(searchScopeSql({ languages: ["cobol"] }, { kind: "type", path: "file_path" })?.sql).toBe("0");
+  });
+
+  it("compares the root tag when a root column is given", () => {
+    const columns = { kind: "type", path: "file_path", root: "json_extract(metadata, '$.root')" };
+    expect(searchScopeSql({ root: "/ws/api/" }, columns)).toEqual({
+      sql: "json_extract(metadata, '$.root') = ?",
+      params: ["/ws/api"],
+    });
+    expect(searchScopeSql({ root: "/ws/api" }, { kind: "type", path: "file_path" })?.sql).toBe("0");
+  });
+
+  it("splits documentation sections from code by kind", () => {
+    expect(searchScopeSql({ content: "docs" }, { kind: "type", path: "file_path" })).toEqual({
+      sql: "type IN (?,?)",
+      params: ["document", "heading"],
+    });
+    expect(searchScopeSql({ content: "code" }, { kind: "type", path: "file_path" })?.sql).toBe(
+      "COALESCE(type, '') NOT IN (?,?)",
+    );
+  });
+
+  it("leaves out anonymous entities by name and tag", () => {
+    const anonymous = "json_extract(metadata, '$.isAnonymous')";
+    const columns = { kind: "type", path: "file_path", name: "name", anonymous };
+    expect(searchScopeSql({ excludeAnonymous: true }, columns)).toEqual({
+      sql: "COALESCE(name, '') NOT IN (?,?,?) AND COALESCE(json_extract(metadata, '$.isAnonymous'), 0) = 0",
+      params: ["<anonymous>", "<lambda>", "anonymous"],
+    });
+  });
+
+  it("filters on the test tag, and finds no tests without one", () => {
+    const columns = { kind: "type", path: "file_path", test: "is_test" };
+    expect(searchScopeSql({ tests: "exclude" }, columns)?.sql).toBe("COALESCE(is_test, 0) <> 1");
+    expect(searchScopeSql({ tests: "only" }, columns)?.sql).toBe("COALESCE(is_test, 0) = 1");
+    expect(searchScopeSql({ tests: "only" }, { kind: "type", path: "file_path" })?.sql).toBe("0");
+  });
+
+  it("keeps the requested visibilities", () => {
+    const columns = { kind: "type", path: "file_path", visibility: "visibility" };
+    expect(searchScopeSql({ visibility: ["public", "protected"] }, columns)).toEqual({
+      sql: "visibility IN (?,?)",
+      params: ["public", "protected"],
+    });
+    expect(searchScopeSql({ visibility: ["public"] }, { kind: "type", path: "file_path" })?.sql).toBe("0");
+  });
+});
+
+describe("matchesSearchScope", () => {
+  const entity = (type: string, filePath: string) => ({ type, filePath });
+
+  it("keeps C and C++ apart by extension case", () => {
+    expect(matchesSearchScope(entity("function", "/src/a.c"), { languages: ["c"] })).toBe(true);
+    expect(matchesSearchScope(entity("function", "/src/a.C"), { languages: ["c"] })).toBe(false);
+    expect(matchesSearchScope(entity("function", "/src/a.tsx"), { languages: ["typescript"] })).toBe(true);
+  });
+
+  it("treats a prefix as a directory, not a string prefix", () => {
+    const scope = { pathPrefix: "/repo/api" };
+    expect(matchesSearchScope(entity("function", "/repo/api/user.ts"), scope)).toBe(true);
+    expect(matchesSearchScope(entity("function", "/repo/api-old/user.ts"), scope)).toBe(false);
+  });
+
+  it("requires both pathPrefix and directory when both are set", () => {
+    const scope = { pathPrefix: "/repo/api", directory: "/repo/api/users" };
+    expect(matchesSearchScope(entity("function", "/repo/api/users/get.ts"), scope)).toBe(true);
+    expect(matchesSearchScope(entity("function", "/repo/api/orders/get.ts"), scope)).toBe(false);
+    const disjoint = { ...scope, pathPrefix: "/repo/web" };
+    expect(matchesSearchScope(entity("function", "/repo/web/users/get.ts"), disjoint)).toBe(false);
+  });
+
+  it("matches relative globs at any depth", () => {
+    expect(toSqliteGlob("services/**/*.py")).toBe("*/services/*.py");
+    const scope = { pathGlob: "services/**/*.py", kinds: ["function"] };
+    expect(matchesSearchScope(entity("function", "/repo/services/auth/login.py"), scope)).toBe(true);
+    expect(matchesSearchScope(entity("class", "/repo/services/auth/login.py"), scope)).toBe(false);
+    expect(matchesSearchScope(entity("function", "/repo/web/services.py"), scope)).toBe(false);
+  });
+
+  it("keeps entities tagged with the requested root", () => {
+    const tagged = { type: "function", filePath: "/ws/api/user.ts", metadata: { root: "/ws/api" } };
+    expect(matchesSearchScope(tagged, { root: "/ws/api" })).toBe(true);
+    expect(matchesSearchScope(tagged, { root: "/ws/web" })).toBe(false);
+    expect(matchesSearchScope(entity("function", "/ws/api/user.ts"), { root: "/ws/api" })).toBe(false);
+  });
+
+  it("keeps docs or code only", () => {
+    expect(matchesSearchScope(entity("heading", "/repo/README.md"), { content: "docs" })).toBe(true);
+    expect(matchesSearchScope(entity("function", "/repo/a.ts"), { content: "docs" })).toBe(false);
+    expect(matchesSearchScope(entity("document", "/repo/README.md"), { content: "code" })).toBe(false);
+    expect(matchesSearchScope(entity("function", "/repo/a.ts"), { content: "code" })).toBe(true);
+  });
+
+  it("drops anonymous entities only when asked to", () => {
+    const callback = { type: "function", name: "<anonymous>", filePath: "/repo/a.ts" };
+    const objectExpression = { type: "class", name: "Impl", filePath: "/repo/a.kt", metadata: { isAnonymous: true } };
+    expect(matchesSearchScope(callback, { excludeAnonymous: true })).toBe(false);
+    expect(matchesSearchScope(objectExpression, { excludeAnonymous: true })).toBe(false);
+    expect(matchesSearchScope(callback, {})).toBe(true);
+    expect(matchesSearchScope({ ...callback, name: "handler" }, { excludeAnonymous: true })).toBe(true);
+  });
+
+  it("tells test files apart by each language's convention", () => {
+    const tests = ["/r/store_test.go", "/r/ui/button.test.tsx", "/r/test_api.py", "/r/src/UserServiceTest.java"];
+    const production = ["/r/store.go", "/r/ui/button.tsx", "/r/api.py", "/r/src/UserService.java", "/r/latest.py"];
+    for (const path of tests) {
+      expect(matchesSearchScope(entity("function", path), { tests: "only" })).toBe(true);
+      expect(matchesSearchScope(entity("function", path), { tests: "exclude" })).toBe(false);
+    }
+    for (const path of production) {
+      expect(matchesSearchScope(entity("function", path), { tests: "only" })).toBe(false);
+      expect(matchesSearchScope(entity("function", path), { tests: "exclude" })).toBe(true);
+    }
+  });
+
+  it("matches the visibility tag, leaving untagged entities out", () => {
+    const exported = { ...entity("function", "/r/api.go"), metadata: { visibility: "public" } };
+    expect(matchesSearchScope(exported, { visibility: ["public"] })).toBe(true);
+    expect(matchesSearchScope(exported, { visibility: ["internal", "private"] })).toBe(false);
+    expect(matchesSearchScope(entity("import", "/r/api.go"), { visibility: ["public"] })).toBe(false);
+  });
+});
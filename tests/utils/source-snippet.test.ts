@@ -18,4 +18,13 @@ describe("extractSourceSnippetFromText", () => {
     expect(res.truncated).toBe(true);
     expect(res.snippet.length).toBeLessThanOrEqual(1024);
   });
+
+  it("keeps the first lines when maxLines cuts the range", () => {
+    const text = ["l1", "l2", "l3", "l4", "l5", "l6"].join("\n");
+    const res = extractSourceSnippetFromText({ text, startLine: 2, endLine: 6, contextLines: 0, maxLines: 2 });
+    expect(res.snippet).toBe(["l2", "l3"].join("\n"));
+    expect(res.snippetRange).toEqual({ startLine: 2, endLine: 3 });
+    expect(res.entityRange).toEqual({ startLine: 2, endLine: 6 });
+    expect(res.truncated).toBe(true);
+  });
 });
@@ -0,0 +1,62 @@
+import { afterEach, beforeEach, describe, expect, it } from "@jest/globals";
+import {
+  configureLogging,
+  getLog,
+  type LogRecord,
+  parseModuleLevels,
+  resetLogging,
+  setLogSink,
+} from "../../src/utils/structured-log.js";
+
+describe("structured log", () => {
+  let records: Array<{ record: LogRecord; line: string }>;
+
+  beforeEach(() => {
+    resetLogging();
+    records = [];
+    configureLogging({ level: "info", format: "text", modules: {}, enableConsole: false });
+    setLogSink((record, line) => records.push({ record, line }));
+  });
+
+  afterEach(() => {
+    resetLogging();
+  });
+
+  it("parses per-module levels and skips unknown ones", () => {
+    expect(parseModuleLevels("parser=debug, Embedding=WARN,graph=loud,,orchestrator:error")).toEqual({
+      parser: "debug",
+      embedding: "warn",
+      orchestrator: "error",
+    });
+    expect(parseModuleLevels(undefined)).toEqual({});
+  });
+
+  it("filters by the module's level, falling back to the global one", () => {
+    configureLogging({ modules: { parser: "debug", embedding: "error" } });
+
+    getLog("parser").debug("parsed file", { entities: 3 });
+    getLog("embedding").warn("embed failed");
+    getLog("graph").debug("dropped");
+    getLog("graph").info("graph storage ready");
+
+    expect(records.map(({ record }) => [record.module, record.level, record.msg])).toEqual([
+      ["parser", "debug", "parsed file"],
+      ["graph", "info", "graph storage ready"],
+    ]);
+    expect(getLog("parser").enabled("debug")).toBe(true);
+    expect(getLog("graph").enabled("debug")).toBe(false);
+  });
+
+  it("formats records as text or JSON with their fields", () => {
+    getLog("parser").info("loaded grammar", { language: "go", grammar: "/opt/tree sitter/go", missing: undefined });
+    expect(records[0]!.line).toMatch(
+      /^\S+Z INFO {2}\[parser\] loaded grammar language=go grammar="\/opt\/tree sitter\/go"$/,
+    );
+
+    configureLogging({ format: "json" });
+    getLog("orchestrator").error("task failed", { task: "t1", error: new Error("boom") });
+    const parsed = JSON.parse(records[1]!.line);
+    expect(parsed).toMatchObject({ level: "error", module: "orchestrator", msg: "task failed", task: "t1" });
+    expect(parsed.error.message).toBe("boom");
+  });
+});
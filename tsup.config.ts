@@ -3,6 +3,8 @@ import { defineConfig } from "tsup";
 export default defineConfig({
   entry: {
     index: "src/index.ts",
+    // Loaded by ParseWorkerPool from next to the bundle
+    "parse-worker": "src/parsers/parse-worker.ts",
   },
   sourcemap: true,
   clean: true,